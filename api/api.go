@@ -0,0 +1,16 @@
+// Package api holds the OpenAPI description of the customer-facing API and
+// the example payloads the mock server returns for it.
+package api
+
+import "embed"
+
+// Spec is the OpenAPI 3 document (openapi.json)
+//
+//go:embed openapi.json
+var Spec []byte
+
+// Fixtures holds one fixtures/<operationId>.json file per operation, mapping
+// response status codes to example bodies
+//
+//go:embed fixtures/*.json
+var Fixtures embed.FS
@@ -0,0 +1,100 @@
+// Command backfill-wishlist populates denormalized product name, slug and image
+// on wishlist items created before those fields were stored.
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/joho/godotenv"
+	"github.com/Ecom-micro-template/service-customer/internal/config"
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/catalogclient"
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/persistence"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func main() {
+	batchSize := flag.Int("batch-size", catalogclient.MaxBatchSize, "number of products fetched from the catalog per request")
+	pause := flag.Duration("pause", 200*time.Millisecond, "delay between batches to spare the catalog service")
+	dryRun := flag.Bool("dry-run", false, "report what would be updated without writing")
+	flag.Parse()
+
+	if *batchSize <= 0 || *batchSize > catalogclient.MaxBatchSize {
+		log.Fatalf("batch-size must be between 1 and %d", catalogclient.MaxBatchSize)
+	}
+
+	if os.Getenv("APP_ENV") != "production" {
+		godotenv.Load()
+	}
+	cfg := config.Load()
+
+	db, err := gorm.Open(postgres.Open(cfg.Database.GetDSN()), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Warn),
+	})
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+
+	repo := persistence.NewWishlistRepository(db)
+	catalog := catalogclient.NewClient(getEnv("CATALOG_SERVICE_URL", "http://ecommerce-catalog:8002"))
+	ctx := context.Background()
+
+	var scanned, updatedProducts, missingProducts int
+	var updatedItems int64
+	cursor := uuid.Nil
+	for {
+		ids, err := repo.ListProductIDsMissingInfo(ctx, cursor, *batchSize)
+		if err != nil {
+			log.Fatalf("Failed to list wishlist products: %v", err)
+		}
+		if len(ids) == 0 {
+			break
+		}
+		cursor = ids[len(ids)-1]
+		scanned += len(ids)
+
+		products, err := catalog.GetProducts(ctx, ids)
+		if err != nil {
+			log.Fatalf("Failed to fetch products from catalog: %v", err)
+		}
+
+		for _, id := range ids {
+			product, ok := products[id]
+			if !ok {
+				missingProducts++
+				continue
+			}
+			updatedProducts++
+			if *dryRun {
+				continue
+			}
+			count, err := repo.UpdateProductInfo(ctx, id, product.Name, product.Slug, product.Image)
+			if err != nil {
+				log.Fatalf("Failed to update wishlist items for product %s: %v", id, err)
+			}
+			updatedItems += count
+		}
+
+		log.Printf("Processed %d products (cursor %s)", scanned, cursor)
+		time.Sleep(*pause)
+	}
+
+	log.Printf("✅ Backfill complete: %d products scanned, %d updated (%d wishlist items), %d not found in catalog",
+		scanned, updatedProducts, updatedItems, missingProducts)
+	if *dryRun {
+		log.Println("Dry run: no changes were written")
+	}
+}
+
+func getEnv(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
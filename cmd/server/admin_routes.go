@@ -0,0 +1,223 @@
+package main
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/Ecom-micro-template/service-customer/internal/handlers"
+)
+
+// adminHandlers serves the admin API
+type adminHandlers struct {
+	activityHandler        *handlers.AdminActivityHandler
+	addressHandler         *handlers.AdminAddressHandler
+	analyticsHandler       *handlers.AdminAnalyticsHandler
+	auditHandler           *handlers.AdminAuditHandler
+	backInStockHandler     *handlers.AdminBackInStockHandler
+	blocklistHandler       *handlers.AdminBlocklistHandler
+	customerHandler        *handlers.AdminCustomerHandler
+	customerLimitHandler   *handlers.AdminCustomerLimitHandler
+	duplicateHandler       *handlers.AdminDuplicateHandler
+	impersonationHandler   *handlers.AdminImpersonationHandler
+	marketingSyncHandler   *handlers.AdminMarketingSyncHandler
+	mergeHandler           *handlers.AdminMergeHandler
+	permissionHandler      *handlers.AdminPermissionHandler
+	regionHandler          *handlers.AdminRegionHandler
+	scheduledExportHandler *handlers.AdminScheduledExportHandler
+	segmentBulkHandler     *handlers.AdminSegmentBulkHandler
+	segmentHistoryHandler  *handlers.AdminSegmentHistoryHandler
+	segmentRuleHandler     *handlers.AdminSegmentRuleHandler
+	systemHandler          *handlers.AdminSystemHandler
+	walletHandler          *handlers.AdminWalletHandler
+	webhookHandler         *handlers.AdminWebhookHandler
+
+	// Middleware: region scoping of sales agents and Idempotency-Key support
+	regionScope      gin.HandlerFunc
+	customerInRegion gin.HandlerFunc
+	idempotency      gin.HandlerFunc
+}
+
+// registerAdminRoutes registers the admin API on admin, which already
+// authenticates the admin and enforces adminPermissionPolicy
+func registerAdminRoutes(admin *gin.RouterGroup, h adminHandlers) {
+	// The admin's permissions, for the admin UI
+	admin.GET("/permissions", h.permissionHandler.GetPermissions)
+
+	// Audit log of admin mutations
+	admin.GET("/audit-logs", h.auditHandler.ListAuditLogs)
+
+	// Customer management
+	// Sales agents only see customers in their assigned regions
+	adminCustomers := admin.Group("/customers")
+	adminCustomers.Use(h.regionScope)
+	adminCustomers.Use(h.customerInRegion)
+	{
+		adminCustomers.GET("", h.customerHandler.GetCustomers)
+		adminCustomers.GET("/stats", h.customerHandler.GetCustomerStats)
+		adminCustomers.GET("/analytics/cohorts", h.analyticsHandler.GetCohorts)
+		adminCustomers.GET("/analytics/growth", h.analyticsHandler.GetGrowth)
+
+		// Review queue of probable duplicates from the duplicate_detection
+		// job; it spans regions, so region-scoped agents can't see it
+		adminCustomers.GET("/duplicates", h.duplicateHandler.ListDuplicates)
+		adminCustomers.POST("/duplicates/:candidateId/dismiss", h.duplicateHandler.DismissDuplicate)
+
+		adminCustomers.GET("/export", h.customerHandler.ExportCustomers)
+		adminCustomers.POST("/exports", h.customerHandler.CreateExportJob)
+		adminCustomers.GET("/exports/:exportId", h.customerHandler.GetExportJob)
+		adminCustomers.GET("/lookup", h.customerHandler.LookupCustomer)
+		adminCustomers.GET("/tags", h.customerHandler.SuggestTags)
+		adminCustomers.GET("/columns", h.customerHandler.GetCustomerColumns)
+		adminCustomers.PUT("/columns", h.customerHandler.UpdateCustomerColumns)
+		adminCustomers.DELETE("/columns", h.customerHandler.ResetCustomerColumns)
+		adminCustomers.GET("/views", h.customerHandler.GetCustomerViews)
+		adminCustomers.POST("/views", h.customerHandler.CreateCustomerView)
+		adminCustomers.GET("/views/:viewId", h.customerHandler.GetCustomerView)
+		adminCustomers.PUT("/views/:viewId", h.customerHandler.UpdateCustomerView)
+		adminCustomers.DELETE("/views/:viewId", h.customerHandler.DeleteCustomerView)
+		adminCustomers.POST("", h.idempotency, h.customerHandler.CreateCustomer)
+		adminCustomers.GET("/:id", h.customerHandler.GetCustomer)
+		adminCustomers.PUT("/:id", h.customerHandler.UpdateCustomer)
+		adminCustomers.DELETE("/:id", h.customerHandler.DeleteCustomer)
+		adminCustomers.POST("/:id/reveal", h.customerHandler.RevealCustomerPII)
+		adminCustomers.GET("/:id/orders", h.customerHandler.GetCustomerOrders)
+		adminCustomers.GET("/:id/notes", h.customerHandler.GetCustomerNotes)
+		adminCustomers.GET("/:id/notes/count", h.customerHandler.CountCustomerNotes)
+		adminCustomers.POST("/:id/notes", h.customerHandler.AddCustomerNote)
+		adminCustomers.PUT("/:id/notes/:noteId", h.customerHandler.UpdateCustomerNote)
+		adminCustomers.DELETE("/:id/notes/:noteId", h.customerHandler.DeleteCustomerNote)
+		adminCustomers.POST("/:id/notes/:noteId/pin", h.customerHandler.PinCustomerNote)
+		adminCustomers.DELETE("/:id/notes/:noteId/pin", h.customerHandler.UnpinCustomerNote)
+		adminCustomers.POST("/:id/notes/:noteId/attachments", h.customerHandler.UploadNoteAttachment)
+		adminCustomers.DELETE("/:id/notes/:noteId/attachments/:attachmentId", h.customerHandler.DeleteNoteAttachment)
+		adminCustomers.GET("/:id/tags", h.customerHandler.GetCustomerTags)
+		adminCustomers.POST("/:id/tags", h.customerHandler.AddCustomerTags)
+		adminCustomers.DELETE("/:id/tags/:tag", h.customerHandler.RemoveCustomerTag)
+		adminCustomers.GET("/:id/activity", h.customerHandler.GetCustomerActivity)
+		adminCustomers.GET("/:id/timeline", h.customerHandler.GetCustomerTimeline)
+		adminCustomers.GET("/:id/activity/pinned", h.customerHandler.GetPinnedActivity)
+		adminCustomers.POST("/:id/activity/:activityId/pin", h.customerHandler.PinActivity)
+		adminCustomers.DELETE("/:id/activity/:activityId/pin", h.customerHandler.UnpinActivity)
+		adminCustomers.POST("/:id/segments", h.customerHandler.AssignSegment)
+		adminCustomers.POST("/:id/merge/preview", h.mergeHandler.PreviewMerge)
+		adminCustomers.POST("/:id/merge", h.mergeHandler.MergeCustomer)
+
+		// "View as customer" impersonation, audit-logged per session and request
+		adminCustomers.POST("/:id/impersonate", h.impersonationHandler.StartImpersonation)
+		adminCustomers.GET("/:id/impersonations", h.impersonationHandler.ListImpersonations)
+
+		// Deleted addresses (support)
+		adminCustomers.GET("/:id/addresses/deleted", h.addressHandler.ListDeletedAddresses)
+
+		// Store credit wallet
+		adminCustomers.GET("/:id/wallet", h.walletHandler.GetWallet)
+		adminCustomers.POST("/:id/wallet/credit", h.walletHandler.Grant)
+		adminCustomers.POST("/:id/wallet/debit", h.walletHandler.Deduct)
+
+		// Per-customer caps on addresses, wishlist items and measurements
+		adminCustomers.GET("/:id/limits", h.customerLimitHandler.GetLimits)
+		adminCustomers.PUT("/:id/limits", h.customerLimitHandler.SetLimits)
+		adminCustomers.DELETE("/:id/limits", h.customerLimitHandler.ResetLimits)
+	}
+
+	// Activity feed across all customers (region-scoped like customer management)
+	activity := admin.Group("/activity")
+	activity.Use(h.regionScope)
+	{
+		activity.GET("", h.activityHandler.ListActivity)
+		activity.GET("/export", h.activityHandler.ExportActivity)
+	}
+
+	// Segment management
+	segments := admin.Group("/segments")
+	{
+		segments.GET("", h.customerHandler.GetSegments)
+		segments.GET("/rules", h.segmentRuleHandler.ListRules)
+		segments.POST("/rules", h.segmentRuleHandler.CreateRule)
+		segments.POST("/rules/simulate", h.segmentRuleHandler.SimulateRules)
+		segments.DELETE("/rules/:ruleId", h.segmentRuleHandler.DeleteRule)
+		segments.POST("", h.customerHandler.CreateSegment)
+		segments.POST("/preview", h.customerHandler.PreviewSegment)
+		segments.PUT("/:id", h.customerHandler.UpdateSegment)
+		segments.DELETE("/:id", h.customerHandler.DeleteSegment)
+		segments.POST("/:id/customers/bulk", h.segmentBulkHandler.BulkUpdate)
+		segments.GET("/:id/customers/bulk/:jobId", h.segmentBulkHandler.GetJob)
+		segments.GET("/:id/history", h.segmentHistoryHandler.ListHistory)
+	}
+
+	// Sales region assignments; scoped roles may not change their own
+	regionAssignments := admin.Group("/region-assignments")
+	{
+		regionAssignments.GET("/:adminId", h.regionHandler.GetAssignment)
+		regionAssignments.PUT("/:adminId", h.regionHandler.SetAssignment)
+	}
+
+	// Impersonation sessions
+	impersonations := admin.Group("/impersonations")
+	{
+		impersonations.GET("/:sessionId/requests", h.impersonationHandler.ListImpersonationRequests)
+		impersonations.DELETE("/:sessionId", h.impersonationHandler.RevokeImpersonation)
+	}
+
+	// Fraud blocklist of emails, phones and addresses; it spans
+	// regions, so region-scoped agents can't manage it
+	blocklist := admin.Group("/blocklist")
+	{
+		blocklist.GET("", h.blocklistHandler.ListBlocklist)
+		blocklist.POST("", h.blocklistHandler.CreateBlocklistEntry)
+		blocklist.DELETE("/:entryId", h.blocklistHandler.DeleteBlocklistEntry)
+	}
+
+	// Webhook subscriptions for customer events
+	webhookSubscriptions := admin.Group("/webhooks")
+	{
+		webhookSubscriptions.GET("", h.webhookHandler.ListWebhooks)
+		webhookSubscriptions.POST("", h.webhookHandler.CreateWebhook)
+		webhookSubscriptions.GET("/:webhookId", h.webhookHandler.GetWebhook)
+		webhookSubscriptions.PUT("/:webhookId", h.webhookHandler.UpdateWebhook)
+		webhookSubscriptions.DELETE("/:webhookId", h.webhookHandler.DeleteWebhook)
+		webhookSubscriptions.GET("/:webhookId/deliveries", h.webhookHandler.ListDeliveries)
+		webhookSubscriptions.GET("/:webhookId/deliveries/:deliveryId", h.webhookHandler.GetDelivery)
+		webhookSubscriptions.POST("/:webhookId/deliveries/:deliveryId/redeliver", h.webhookHandler.Redeliver)
+	}
+
+	// Recurring customer exports delivered by email link, S3 or SFTP
+	scheduledExports := admin.Group("/scheduled-exports")
+	{
+		scheduledExports.GET("", h.scheduledExportHandler.ListExports)
+		scheduledExports.POST("", h.scheduledExportHandler.CreateExport)
+		scheduledExports.GET("/:exportId", h.scheduledExportHandler.GetExport)
+		scheduledExports.PUT("/:exportId", h.scheduledExportHandler.UpdateExport)
+		scheduledExports.DELETE("/:exportId", h.scheduledExportHandler.DeleteExport)
+		scheduledExports.GET("/:exportId/runs", h.scheduledExportHandler.ListRuns)
+		scheduledExports.POST("/:exportId/run", h.scheduledExportHandler.RunExport)
+	}
+
+	// Email marketing platform sync
+	marketingSync := admin.Group("/marketing-sync")
+	{
+		marketingSync.GET("", h.marketingSyncHandler.Status)
+		marketingSync.GET("/:provider/contacts", h.marketingSyncHandler.ListContacts)
+		marketingSync.POST("/:provider/resync", h.marketingSyncHandler.Resync)
+	}
+
+	// System status
+	system := admin.Group("/system")
+	{
+		system.GET("/slo", h.systemHandler.GetSLO)
+		system.GET("/notifications", h.systemHandler.GetNotificationMetrics)
+		system.GET("/legacy-crm/drift", h.systemHandler.GetLegacyCRMDrift)
+	}
+
+	// Back-in-Stock Admin (HI-001)
+	backInStock := admin.Group("/back-in-stock")
+	{
+		backInStock.GET("/stats", h.backInStockHandler.GetStats)
+		backInStock.GET("/subscriptions", h.backInStockHandler.ListSubscriptions)
+		backInStock.GET("/export", h.backInStockHandler.ExportSubscriptions)
+		backInStock.GET("/demand", h.backInStockHandler.GetDemand)
+		backInStock.GET("/products/:productId/subscriptions", h.backInStockHandler.GetByProduct)
+		backInStock.POST("/products/:productId/notify", h.backInStockHandler.NotifyProduct)
+		backInStock.POST("/mark-notified", h.backInStockHandler.MarkAsNotified)
+		backInStock.POST("/test-notification", h.backInStockHandler.SendTestNotification)
+		backInStock.DELETE("/cleanup", h.backInStockHandler.Cleanup)
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/google/uuid"
+	"github.com/spf13/cobra"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/orderclient"
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/persistence"
+	"gorm.io/gorm/logger"
+)
+
+// newBackfillCustomerStatsCommand recalculates every customer's total_orders,
+// total_spent and first and last order dates from their paid orders in service-order, for customers
+// whose orders predate the order event subscriber or whose events were missed
+func newBackfillCustomerStatsCommand() *cobra.Command {
+	var (
+		batchSize  int
+		dryRun     bool
+		orderToken string
+	)
+	cmd := &cobra.Command{
+		Use:   "backfill-customer-stats",
+		Short: "Recalculate customers' order totals from their paid orders in service-order",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if batchSize <= 0 {
+				return errors.New("batch-size must be positive")
+			}
+
+			cfg := loadConfig()
+			db, err := openDatabase(cfg, logger.Warn)
+			if err != nil {
+				return fmt.Errorf("connect to database: %w", err)
+			}
+
+			authorization := ""
+			if orderToken != "" {
+				authorization = "Bearer " + orderToken
+			}
+			orders := orderclient.NewClient(getEnv("ORDER_SERVICE_URL", "http://ecommerce-order:8005"))
+			totals := func(ctx context.Context, customerID uuid.UUID) (domain.OrderTotals, error) {
+				return orders.CustomerOrderTotals(ctx, customerID.String(), authorization)
+			}
+
+			repo := persistence.NewOrderStatsRepository(db)
+			var total domain.OrderStatsBackfillResult
+			after := uuid.Nil
+			for {
+				if err := cmd.Context().Err(); err != nil {
+					return fmt.Errorf("stopped after %d customers: %w", total.Customers, err)
+				}
+				next, page, err := repo.Backfill(cmd.Context(), after, batchSize, dryRun, totals)
+				if err != nil {
+					return fmt.Errorf("stopped after %d customers: %w", total.Customers, err)
+				}
+				total.Customers += page.Customers
+				total.Corrected += page.Corrected
+				if next == uuid.Nil {
+					break
+				}
+				log.Printf("Checked %d customers", total.Customers)
+				after = next
+			}
+
+			if dryRun {
+				log.Printf("✅ Dry run: %d of %d customers have wrong order totals, nothing changed", total.Corrected, total.Customers)
+			} else {
+				log.Printf("✅ Corrected the order totals of %d of %d customers", total.Corrected, total.Customers)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().IntVar(&batchSize, "batch-size", 500, "customers loaded per batch")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "count customers with wrong totals without changing them")
+	cmd.Flags().StringVar(&orderToken, "order-token", os.Getenv("ORDER_SERVICE_TOKEN"), "bearer token for the service-order admin API")
+	return cmd
+}
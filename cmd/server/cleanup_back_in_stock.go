@@ -0,0 +1,74 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/persistence"
+	"gorm.io/gorm/logger"
+)
+
+// newCleanupBackInStockCommand does what DELETE /admin/back-in-stock/cleanup
+// and one run of the expiry job do: it deletes notified subscriptions older
+// than the retention period and expires pending ones past their expiry
+func newCleanupBackInStockCommand() *cobra.Command {
+	var (
+		olderThanDays int
+		batchSize     int
+		skipExpiry    bool
+	)
+	cmd := &cobra.Command{
+		Use:   "cleanup-back-in-stock",
+		Short: "Delete old notified and expired back-in-stock subscriptions",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if olderThanDays < 1 {
+				return errors.New("older-than-days must be at least 1")
+			}
+
+			cfg := loadConfig()
+			db, err := openDatabase(cfg, logger.Warn)
+			if err != nil {
+				return fmt.Errorf("connect to database: %w", err)
+			}
+			ctx := cmd.Context()
+			repo := persistence.NewBackInStockRepository(db).WithSubscriptionTTL(cfg.BackInStock.SubscriptionTTL)
+
+			if batchSize < 1 {
+				batchSize = cfg.Scheduler.BackInStockCleanupBatch
+			}
+			deleted, err := repo.DeleteOldNotified(ctx, olderThanDays, batchSize, func(batch persistence.CleanupBatch) {
+				log.Printf("Batch %d: deleted %d subscriptions", batch.Batch, batch.Deleted)
+			})
+			if err != nil {
+				return fmt.Errorf("delete notified subscriptions (%d deleted): %w", deleted, err)
+			}
+			log.Printf("Deleted %d subscriptions notified more than %d days ago", deleted, olderThanDays)
+
+			if skipExpiry {
+				return nil
+			}
+			now := time.Now()
+			expired, err := repo.ExpirePending(ctx, now)
+			if err != nil {
+				return fmt.Errorf("expire subscriptions: %w", err)
+			}
+			log.Printf("Expired %d customer subscriptions", expired)
+
+			guestRepo := persistence.NewGuestBackInStockRepository(db).WithSubscriptionTTL(cfg.BackInStock.SubscriptionTTL)
+			expired, err = guestRepo.ExpirePending(ctx, now)
+			if err != nil {
+				return fmt.Errorf("expire guest subscriptions: %w", err)
+			}
+			log.Printf("Expired %d guest subscriptions", expired)
+			return nil
+		},
+	}
+	cmd.Flags().IntVar(&olderThanDays, "older-than-days", 30, "delete subscriptions notified more than this many days ago")
+	cmd.Flags().IntVar(&batchSize, "batch-size", 0, "subscriptions to delete per statement (default BACK_IN_STOCK_CLEANUP_BATCH_SIZE)")
+	cmd.Flags().BoolVar(&skipExpiry, "skip-expiry", false, "don't expire pending subscriptions past their expiry")
+	return cmd
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/joho/godotenv"
+	"github.com/Ecom-micro-template/service-customer/internal/config"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/persistence"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// loadConfig reads .env outside production and loads the configuration
+func loadConfig() *config.Config {
+	if os.Getenv("APP_ENV") != "production" {
+		godotenv.Load()
+	}
+	return config.Load()
+}
+
+// openDatabase connects to the customer database with connection pooling,
+// with the PII columns encrypted by the configured keys
+func openDatabase(cfg *config.Config, level logger.LogLevel) (*gorm.DB, error) {
+	keys, err := columnKeyring(cfg.Encryption)
+	if err != nil {
+		return nil, fmt.Errorf("load PII encryption keys: %w", err)
+	}
+	if !keys.Enabled() {
+		log.Println("⚠️  PII_ENCRYPTION_KEYS not set; PII columns are stored in plaintext")
+	}
+	domain.UseColumnCipher(keys)
+
+	db, err := gorm.Open(postgres.Open(cfg.Database.GetDSN()), &gorm.Config{
+		Logger:      queryLogger(cfg.Database, level),
+		PrepareStmt: cfg.Database.PrepareStmt,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	configurePool(sqlDB, cfg.Database)
+	return db, nil
+}
+
+// configurePool sizes a database connection pool
+func configurePool(sqlDB *sql.DB, cfg config.DatabaseConfig) {
+	sqlDB.SetMaxOpenConns(cfg.MaxOpenConns)
+	sqlDB.SetMaxIdleConns(cfg.MaxIdleConns)
+	sqlDB.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	sqlDB.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+}
+
+// queryLogger logs queries at level, and any slower than the slow query
+// threshold at warn. Bound parameters are left out of the logged SQL, so
+// customer PII doesn't reach the logs.
+func queryLogger(cfg config.DatabaseConfig, level logger.LogLevel) logger.Interface {
+	return logger.New(log.New(os.Stdout, "\r\n", log.LstdFlags), logger.Config{
+		SlowThreshold:        cfg.SlowQueryThreshold,
+		LogLevel:             level,
+		ParameterizedQueries: true,
+		Colorful:             true,
+	})
+}
+
+// openReplica connects to the read replica at DB_REPLICA_DSN and routes the
+// queries marked with persistence.Replica to it. It returns nil without a
+// replica; the primary then serves those queries.
+func openReplica(cfg *config.Config, db *gorm.DB, level logger.LogLevel) (*sql.DB, error) {
+	if cfg.Database.ReplicaDSN == "" {
+		return nil, nil
+	}
+	replica, err := gorm.Open(postgres.Open(cfg.Database.ReplicaDSN), &gorm.Config{
+		Logger: queryLogger(cfg.Database, level),
+	})
+	if err != nil {
+		return nil, err
+	}
+	sqlDB, err := replica.DB()
+	if err != nil {
+		return nil, err
+	}
+	configurePool(sqlDB, cfg.Database)
+
+	if err := persistence.UseReplica(db, postgres.New(postgres.Config{Conn: sqlDB})); err != nil {
+		sqlDB.Close()
+		return nil, err
+	}
+	return sqlDB, nil
+}
+
+// queryTimeouts returns the customer repository's timeouts per query class
+func queryTimeouts(cfg config.DatabaseConfig) persistence.QueryTimeouts {
+	return persistence.QueryTimeouts{
+		Lookup: cfg.LookupTimeout,
+		List:   cfg.ListTimeout,
+		Report: cfg.ReportTimeout,
+		Export: cfg.ExportTimeout,
+	}
+}
+
+// migrateSchema creates the schemas and auto-migrates the models this
+// service owns. Indexes on large tables and backfills are left to the
+// migrate command.
+func migrateSchema(db *gorm.DB) error {
+	// Create schema if it doesn't exist
+	if err := db.Exec("CREATE SCHEMA IF NOT EXISTS customer").Error; err != nil {
+		return err
+	}
+	log.Println("✅ Customer schema ready")
+
+	// Create crm schema for measurements (if using separate schema)
+	if err := db.Exec("CREATE SCHEMA IF NOT EXISTS crm").Error; err != nil {
+		return err
+	}
+	log.Println("✅ CRM schema ready")
+
+	// Auto-migrate models
+	if err := db.AutoMigrate(
+		&domain.Profile{},
+		&domain.Address{},
+		&domain.WishlistItem{},
+		&domain.ArchivedWishlistItem{},
+		&domain.RecentlyViewedProduct{},
+		&domain.SavedSearch{},
+		&domain.NotificationPreferences{},
+		&domain.NotificationDelivery{},
+		&domain.CustomerMeasurement{},     // Day 96
+		&domain.BackInStockSubscription{}, // HI-001
+		&domain.GuestBackInStockSubscription{},
+		&domain.NotificationRetry{},
+		&domain.CustomerWallet{},
+		&domain.WalletTransaction{},
+		&domain.WalletReservation{},
+		&domain.AccountMerge{},
+		&domain.CustomerSegment{},
+		&domain.SegmentRule{},
+		&domain.SegmentBulkJob{},
+		&domain.SegmentMembershipEvent{},
+		&domain.AdminRegionAssignment{},
+		&domain.ImpersonationSession{},
+		&domain.ImpersonationRequest{},
+		&domain.AuditLog{},
+		&domain.NoteAttachment{},
+		&domain.CustomerTag{},
+		&domain.CustomerTagAssignment{},
+		&domain.CustomerListView{},
+		&domain.CustomerColumnPreference{},
+		&domain.WebhookDelivery{},
+		&domain.CustomerLimitOverride{},
+		&domain.IdempotencyKey{},
+		&domain.EmailChange{},
+		&domain.OrderStatsEntry{},
+		&domain.DuplicateMatchKey{},
+		&domain.DuplicateCandidate{},
+		&domain.BlocklistEntry{},
+		&domain.WebhookSubscription{},
+		&domain.OutboundWebhookDelivery{},
+		&domain.OutboundWebhookAttempt{},
+		&domain.ScheduledExport{},
+		&domain.ScheduledExportRun{},
+		&domain.CustomerExportJob{},
+		&domain.MarketingConsent{},
+		&domain.MarketingSyncState{},
+	); err != nil {
+		return err
+	}
+
+	// The customers table is shared with other services, so only the
+	// columns this service maintains on it are added
+	if db.Migrator().HasTable(&domain.Customer{}) {
+		for _, field := range customerColumns {
+			if db.Migrator().HasColumn(&domain.Customer{}, field) {
+				continue
+			}
+			if err := db.Migrator().AddColumn(&domain.Customer{}, field); err != nil {
+				return err
+			}
+		}
+		// The phone is encrypted, which doesn't fit its original varchar(20)
+		if err := widenCustomerPhone(db); err != nil {
+			return err
+		}
+		// Cohort analytics read materialized views over customers and
+		// order stats; cohort_refresh keeps them current
+		if err := persistence.CreateCohortViews(db); err != nil {
+			return err
+		}
+	}
+	log.Println("✅ Database migrations completed")
+	return nil
+}
+
+// customerColumns are the domain.Customer fields derived by this service from
+// order events and the scoring jobs, and the phone's blind index; their
+// indexes are OnlineIndexes
+var customerColumns = []string{
+	"FirstOrderAt", "LastOrderAt",
+	"RecencyScore", "FrequencyScore", "MonetaryScore", "RFMSegment", "LifetimeValue", "ScoredAt",
+	"ChurnRisk", "ChurnFlaggedAt",
+	"PhoneIndex",
+}
+
+// widenCustomerPhone changes the type of public.customers.phone to text
+// unless it already is
+func widenCustomerPhone(db *gorm.DB) error {
+	columns, err := db.Migrator().ColumnTypes(&domain.Customer{})
+	if err != nil {
+		return err
+	}
+	for _, column := range columns {
+		if column.Name() == "phone" && !strings.EqualFold(column.DatabaseTypeName(), "text") {
+			return db.Migrator().AlterColumn(&domain.Customer{}, "Phone")
+		}
+	}
+	return nil
+}
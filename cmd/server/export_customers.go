@@ -0,0 +1,123 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"slices"
+
+	"github.com/google/uuid"
+	"github.com/spf13/cobra"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/persistence"
+	"gorm.io/gorm/logger"
+)
+
+// newExportCustomersCommand writes the admin customer export without the
+// endpoint's row cap, paging through the customers so memory use stays flat
+func newExportCustomersCommand() *cobra.Command {
+	var (
+		format    string
+		output    string
+		columns   []string
+		status    string
+		search    string
+		tags      []string
+		batchSize int
+	)
+	cmd := &cobra.Command{
+		Use:   "export-customers",
+		Short: "Export customers as CSV or JSON",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if format != "csv" && format != "json" {
+				return errors.New("format must be csv or json")
+			}
+			if batchSize <= 0 {
+				return errors.New("batch-size must be positive")
+			}
+			columns, err := domain.ParseCustomerColumns(columns)
+			if err != nil {
+				return err
+			}
+			tags, err := domain.NormalizeTagNames(tags)
+			if err != nil {
+				return err
+			}
+
+			cfg := loadConfig()
+			db, err := openDatabase(cfg, logger.Warn)
+			if err != nil {
+				return fmt.Errorf("connect to database: %w", err)
+			}
+			ctx := cmd.Context()
+			customerRepo := persistence.NewCustomerRepository(db, queryTimeouts(cfg.Database))
+			tagRepo := persistence.NewCustomerTagRepository(db)
+
+			out := io.Writer(os.Stdout)
+			if output != "" && output != "-" {
+				file, err := os.Create(output)
+				if err != nil {
+					return err
+				}
+				defer file.Close()
+				out = file
+			}
+			writer := domain.NewCustomerWriter(out, format, columns)
+
+			filter := domain.CustomerListFilter{Status: status, Search: search, Tags: tags, Limit: batchSize, Sort: domain.Sort{Field: "created_at", Direction: domain.SortAsc}}
+			var after *domain.Cursor
+			exported := 0
+			for {
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+				page, next, err := customerRepo.ListAdminAfter(ctx, filter, after)
+				if err != nil {
+					return fmt.Errorf("list customers: %w", err)
+				}
+				if slices.Contains(columns, "tags") && len(page) > 0 {
+					ids := make([]uuid.UUID, len(page))
+					for i := range page {
+						ids[i] = page[i].ID
+					}
+					byCustomer, err := tagRepo.NamesByCustomers(ctx, ids)
+					if err != nil {
+						return fmt.Errorf("load customer tags: %w", err)
+					}
+					for i := range page {
+						page[i].Tags = byCustomer[page[i].ID]
+					}
+				}
+				for i := range page {
+					if err := writer.Write(&page[i]); err != nil {
+						return err
+					}
+				}
+				exported += len(page)
+
+				if next == "" {
+					break
+				}
+				if after, err = domain.DecodeCursor(next); err != nil {
+					return err
+				}
+			}
+			if err := writer.Close(); err != nil {
+				return err
+			}
+			log.Printf("✅ Exported %d customers", exported)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&format, "format", "csv", "output format: csv or json")
+	cmd.Flags().StringVarP(&output, "output", "o", "", "file to write to (default stdout)")
+	cmd.Flags().StringSliceVar(&columns, "columns", domain.DefaultCustomerColumns, "columns to export")
+	cmd.Flags().StringVar(&status, "status", "", "only customers with this status")
+	cmd.Flags().StringVar(&search, "search", "", "only customers whose name or email contains this")
+	cmd.Flags().StringSliceVar(&tags, "tags", nil, "only customers with all of these tags")
+	cmd.Flags().IntVar(&batchSize, "batch-size", 1000, "customers loaded per page")
+	return cmd
+}
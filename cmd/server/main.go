@@ -2,24 +2,45 @@ package main
 
 import (
 	"context"
+	"errors"
 	"log"
 	"net/http"
+	"net/url"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/joho/godotenv"
+	"github.com/google/uuid"
 	"github.com/nats-io/nats.go"
+	"github.com/spf13/cobra"
 	libmiddleware "github.com/Ecom-micro-template/lib-common-go/middleware"
 	"github.com/Ecom-micro-template/lib-common-go/monitoring"
 	"github.com/Ecom-micro-template/service-customer/internal/config"
 	"github.com/Ecom-micro-template/service-customer/internal/events"
+	"github.com/Ecom-micro-template/service-customer/internal/graph"
 	"github.com/Ecom-micro-template/service-customer/internal/handlers"
 	"github.com/Ecom-micro-template/service-customer/internal/middleware"
 	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/Ecom-micro-template/service-customer/internal/domain/address"
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/addressvalidation"
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/catalogclient"
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/exportdelivery"
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/filestore"
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/inventoryclient"
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/jwks"
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/legacycrm"
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/marketingsync"
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/metrics"
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/notificationclient"
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/orderclient"
 	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/persistence"
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/tracing"
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/webhookclient"
+	"github.com/Ecom-micro-template/service-customer/internal/jobs"
+	"github.com/Ecom-micro-template/service-customer/internal/warmup"
 	"go.uber.org/zap"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
@@ -33,72 +54,106 @@ var (
 )
 
 func main() {
-	// Load environment
-	if os.Getenv("APP_ENV") != "production" {
-		godotenv.Load()
+	root := &cobra.Command{
+		Use:   "server",
+		Short: "Customer service",
+		Long: "Customer service API server and the maintenance tasks ops run against\n" +
+			"its database. Without a command the API server is started.",
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			serve()
+			return nil
+		},
+	}
+	root.AddCommand(
+		newServeCommand(),
+		newMigrateCommand(),
+		newSeedCommand(),
+		newRecomputeSegmentsCommand(),
+		newBackfillCustomerStatsCommand(),
+		newExportCustomersCommand(),
+		newCleanupBackInStockCommand(),
+		newReencryptPIICommand(),
+	)
+
+	// Maintenance commands stop between batches on Ctrl-C; progress so far is kept
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if err := root.ExecuteContext(ctx); err != nil {
+		stop()
+		os.Exit(1)
+	}
+}
+
+func newServeCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "serve",
+		Short: "Run the API server and its background jobs",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			serve()
+			return nil
+		},
 	}
+}
 
+func serve() {
 	if os.Getenv("APP_ENV") == "production" {
 		gin.SetMode(gin.ReleaseMode)
 	}
 
 	// Load configuration
-	cfg = config.Load()
+	cfg = loadConfig()
 	log.Println("✅ Configuration loaded")
 
 	// Initialize database
 	var err error
-	db, err = gorm.Open(postgres.Open(cfg.Database.GetDSN()), &gorm.Config{
-		Logger: logger.Default.LogMode(logger.Info),
-	})
+	db, err = openDatabase(cfg, logger.Info)
 	if err != nil {
 		log.Fatalf("Failed to connect to database: %v", err)
 	}
-
-	// Configure connection pooling for production performance
 	sqlDB, err := db.DB()
 	if err != nil {
 		log.Fatalf("Failed to get underlying sql.DB: %v", err)
 	}
-	sqlDB.SetMaxIdleConns(10)
-	sqlDB.SetMaxOpenConns(50)
-	sqlDB.SetConnMaxLifetime(time.Hour)
-	sqlDB.SetConnMaxIdleTime(10 * time.Minute)
+
+	// Query timing and pool usage for /metrics
+	if err := db.Use(metrics.GORMPlugin{}); err != nil {
+		log.Fatalf("Failed to register database metrics: %v", err)
+	}
+	if err := db.Use(tracing.GORMPlugin{}); err != nil {
+		log.Fatalf("Failed to register database tracing: %v", err)
+	}
+	metrics.RegisterDBPool(metrics.Default, "db", sqlDB)
 
 	log.Println("✅ Database connected with connection pooling")
 
-	// Create schema if it doesn't exist
-	if err := db.Exec("CREATE SCHEMA IF NOT EXISTS customer").Error; err != nil {
-		log.Fatalf("Failed to create customer schema: %v", err)
+	// Optional read replica for admin listings, exports, stats and analytics.
+	// Without one, or if it is down at startup, they read from the primary.
+	replicaDB, err := openReplica(cfg, db, logger.Info)
+	if err != nil {
+		log.Printf("⚠️  Warning: Read replica unavailable, reading from the primary: %v", err)
+	} else if replicaDB != nil {
+		metrics.RegisterDBPool(metrics.Default, "db_replica", replicaDB)
+		log.Println("✅ Read replica connected")
 	}
-	log.Println("✅ Customer schema ready")
 
-	// Create crm schema for measurements (if using separate schema)
-	if err := db.Exec("CREATE SCHEMA IF NOT EXISTS crm").Error; err != nil {
-		log.Fatalf("Failed to create crm schema: %v", err)
+	if err := migrateSchema(db); err != nil {
+		log.Fatalf("Failed to migrate database: %v", err)
 	}
-	log.Println("✅ CRM schema ready")
 
-	// Auto-migrate models
-	if err := db.AutoMigrate(
-		&domain.Profile{},
-		&domain.Address{},
-		&domain.WishlistItem{},
-		&domain.CustomerMeasurement{},      // Day 96
-		&domain.BackInStockSubscription{}, // HI-001
-	); err != nil {
-		log.Fatalf("Failed to migrate database: %v", err)
+	// Indexes on large tables, such as the variant-specific wishlist unique
+	// index (CUS-001), are built concurrently by the migrate command rather than here
+	if missing, err := persistence.MissingOnlineIndexes(context.Background(), db); err != nil {
+		log.Printf("⚠️  Warning: Failed to check indexes: %v", err)
+	} else if len(missing) > 0 {
+		log.Printf("⚠️  Warning: Indexes %s are missing; run server migrate", strings.Join(missing, ", "))
 	}
-	log.Println("✅ Database migrations completed")
 
-	// Add unique constraint for wishlist (CUS-001: variant-specific)
-	// Drop old index first (if exists), then create new one with variant support
-	db.Exec(`DROP INDEX IF EXISTS customer.idx_wishlist_user_product`)
-	if err := db.Exec(`
-		CREATE UNIQUE INDEX IF NOT EXISTS idx_wishlist_user_product_variant
-		ON customer.wishlist_items(user_id, product_id, COALESCE(variant_id, '00000000-0000-0000-0000-000000000000'))
-	`).Error; err != nil {
-		log.Printf("⚠️  Warning: Failed to create unique index on wishlist: %v", err)
+	// CHECK constraints keeping status, gender and address label columns
+	// within their enums
+	if err := persistence.EnsureEnumConstraints(db); err != nil {
+		log.Printf("⚠️  Warning: %v", err)
 	}
 
 	// Initialize zap logger
@@ -128,52 +183,554 @@ func main() {
 	}
 	defer sentryMonitor.Flush(2 * time.Second)
 
+	// OpenTelemetry tracing; trace context is always propagated, spans are
+	// only exported when an OTLP endpoint is configured
+	shutdownTracing, tracingErr := tracing.Init(context.Background(), tracing.Config{
+		Endpoint:    cfg.Tracing.Endpoint,
+		ServiceName: cfg.Tracing.ServiceName,
+		Version:     cfg.Sentry.Release,
+		Environment: cfg.Sentry.Environment,
+		SampleRatio: cfg.Tracing.SampleRatio,
+	})
+	if tracingErr != nil {
+		zapLogger.Warn("Failed to initialize tracing exporter", zap.Error(tracingErr))
+		shutdownTracing = func(context.Context) error { return nil }
+	}
+
 	// Initialize repositories
-	customerRepo := persistence.NewCustomerRepository(db)
+	customerRepo := persistence.NewCustomerRepository(db, queryTimeouts(cfg.Database))
+
+	// Legacy CRM bridge: mirror customer and address writes while the legacy
+	// CRM is still in use, and report drift between the two databases
+	var legacyCRM *legacycrm.Bridge
+	if cfg.LegacyCRM.DSN != "" {
+		legacyDB, legacyErr := gorm.Open(postgres.Open(cfg.LegacyCRM.DSN), &gorm.Config{
+			Logger: logger.Default.LogMode(logger.Warn),
+		})
+		if legacyErr != nil {
+			log.Printf("⚠️  Legacy CRM connection failed: %v (dual-write disabled)", legacyErr)
+		} else {
+			legacyCRM = legacycrm.New(db, legacyDB, legacycrm.Config{
+				DualWrite:      cfg.LegacyCRM.DualWrite,
+				CustomersTable: cfg.LegacyCRM.CustomersTable,
+				AddressesTable: cfg.LegacyCRM.AddressesTable,
+				Timeout:        cfg.LegacyCRM.Timeout,
+			}, zapLogger)
+			customerRepo = persistence.NewMirroredCustomerRepository(customerRepo, legacyCRM)
+			log.Printf("✅ Legacy CRM connected (dual-write: %t)", cfg.LegacyCRM.DualWrite)
+		}
+	}
+
+	// Customer events for webhook subscribers such as external CRMs; segment
+	// rule repositories queue segment changes themselves
+	webhookRepo := persistence.NewWebhookSubscriptionRepository(db)
+	customerRepo = persistence.NewWebhookCustomerRepository(customerRepo, webhookRepo, zapLogger)
+
+	// Profile, segment and consent changes for the email marketing platforms
+	marketingProviders, marketingNames, marketingErr := marketingProviders(cfg.Marketing)
+	if marketingErr != nil {
+		log.Printf("⚠️  Warning: Marketing sync disabled: %v", marketingErr)
+	}
+	marketingSyncRepo := persistence.NewMarketingSyncRepository(db, marketingNames)
+	customerRepo = persistence.NewMarketingCustomerRepository(customerRepo, marketingSyncRepo, zapLogger)
+
+	// One notification client is shared so every sender counts towards the
+	// same circuit breaker and metrics
+	notificationClient := notificationclient.New(notificationclient.Config{
+		BaseURL:          cfg.Notification.URL,
+		Timeout:          cfg.Notification.Timeout,
+		MaxRetries:       cfg.Notification.MaxRetries,
+		RetryBackoff:     cfg.Notification.RetryBackoff,
+		MaxRetryBackoff:  cfg.Notification.MaxRetryBackoff,
+		BreakerThreshold: cfg.Notification.BreakerThreshold,
+		BreakerCooldown:  cfg.Notification.BreakerCooldown,
+	}, zapLogger)
+
+	// Per-customer caps on addresses, wishlist items and measurements
+	customerLimits := persistence.NewCustomerLimitRepository(db, domain.CustomerLimits{
+		Addresses:     cfg.Limits.MaxAddresses,
+		WishlistItems: cfg.Limits.MaxWishlistItems,
+		Measurements:  cfg.Limits.MaxMeasurements,
+	})
 
 	// Initialize handlers
 	profileHandler := handlers.NewProfileHandler(db)
-	addressHandler := handlers.NewAddressHandler(db)
-	wishlistHandler := handlers.NewWishlistHandler(db)
+	emailChangeHandler := handlers.NewEmailChangeHandler(db,
+		getEnv("EMAIL_CHANGE_VERIFY_URL", "http://localhost:3000/account/email-change/confirm")).
+		WithSender(notificationClient)
+	activityHandler := handlers.NewActivityHandler(db)
+	addressValidator, err := addressvalidation.New(cfg.Address.Provider, cfg.Address.APIKey)
+	if err != nil {
+		log.Fatalf("Invalid address validation config: %v", err)
+	}
+	addressHandler := handlers.NewAddressHandler(db).
+		WithValidator(addressValidator).
+		WithCountryPolicy(address.NewCountryPolicy(cfg.Address.AllowedCountries)).
+		WithLimits(customerLimits)
+	if legacyCRM != nil {
+		addressHandler.WithMirror(legacyCRM)
+	}
+	catalog := catalogclient.NewClient(getEnv("CATALOG_SERVICE_URL", "http://ecommerce-catalog:8002"))
+	wishlistHandler := handlers.NewWishlistHandler(db).
+		WithCatalog(catalog).
+		WithInventory(inventoryclient.NewClient(getEnv("INVENTORY_SERVICE_URL", "http://ecommerce-inventory:8007"), cfg.Wishlist.StockCacheTTL)).
+		WithLimits(customerLimits)
+	recentlyViewedHandler := handlers.NewRecentlyViewedHandler(db).
+		WithLimit(cfg.RecentViews.Limit).
+		WithCatalog(catalog)
+	savedSearchHandler := handlers.NewSavedSearchHandler(db)
+	dataPortabilityHandler := handlers.NewDataPortabilityHandler(db).
+		WithCountryPolicy(address.NewCountryPolicy(cfg.Address.AllowedCountries)).
+		WithLimits(customerLimits)
+	if legacyCRM != nil {
+		dataPortabilityHandler.WithMirror(legacyCRM)
+	}
 	orderHistoryHandler := handlers.NewOrderHistoryHandler()
-	measurementHandler := handlers.NewMeasurementHandler(db)           // Day 96
-	backInStockHandler := handlers.NewBackInStockHandler(db)           // HI-001
-	adminBackInStockHandler := handlers.NewAdminBackInStockHandler(db) // HI-001
-	adminCustomerHandler := handlers.NewAdminCustomerHandler(customerRepo, zapLogger)
+	measurementHandler := handlers.NewMeasurementHandler(db).WithLimits(customerLimits) // Day 96
+	backInStockHandler := handlers.NewBackInStockHandler(db).
+		WithSubscriptionTTL(cfg.BackInStock.SubscriptionTTL) // HI-001
+	adminBackInStockHandler := handlers.NewAdminBackInStockHandler(db).
+		WithNotificationSender(notificationClient) // HI-001
+	guestBackInStockHandler := handlers.NewGuestBackInStockHandler(db,
+		getEnv("BACK_IN_STOCK_CONFIRM_URL", "http://localhost:3000/back-in-stock/confirm")).
+		WithConfirmationSender(notificationClient).
+		WithSubscriptionTTL(cfg.BackInStock.SubscriptionTTL)
+	adminCustomerHandler := handlers.NewAdminCustomerHandler(customerRepo, zapLogger).
+		WithOrderClient(orderclient.NewClient(getEnv("ORDER_SERVICE_URL", "http://ecommerce-order:8005"))).
+		WithSegmentRules(persistence.NewSegmentRuleRepository(db).WithWebhooks().WithMarketingSync(marketingSyncRepo)).
+		WithTags(persistence.NewCustomerTagRepository(db)).
+		WithViews(persistence.NewCustomerViewRepository(db)).
+		WithColumnPreferences(persistence.NewCustomerColumnPreferenceRepository(db)).
+		WithSegmentCounts(persistence.NewSegmentCounts(db, domain.SegmentCountTTL))
+
+	// File store for note attachments, avatars and emailed exports; all are
+	// disabled if it can't be set up
+	var exportFiles filestore.Store
+	fileStore, err := filestore.New(filestore.Config{
+		Provider:      cfg.Storage.Provider,
+		LocalDir:      cfg.Storage.LocalDir,
+		PublicURL:     cfg.Storage.PublicURL,
+		SigningSecret: cfg.Storage.SigningSecret,
+		Endpoint:      cfg.Storage.Endpoint,
+		Region:        cfg.Storage.Region,
+		Bucket:        cfg.Storage.Bucket,
+		AccessKey:     cfg.Storage.AccessKey,
+		SecretKey:     cfg.Storage.SecretKey,
+		PathStyle:     cfg.Storage.PathStyle,
+	})
+	if err != nil {
+		log.Printf("⚠️  Warning: Note attachments and avatar uploads disabled: %v", err)
+	} else {
+		if localStore, ok := fileStore.(*filestore.LocalStore); ok {
+			localStore.WithPublicPrefix(domain.AvatarKeyPrefix)
+		}
+		profileHandler.WithAvatars(fileStore, cfg.Avatar.BaseURL, cfg.Avatar.MaxSize)
+		adminCustomerHandler.WithNoteAttachments(persistence.NewNoteAttachmentRepository(db), fileStore, domain.NoteAttachmentPolicy{
+			MaxSize:      cfg.Attachments.MaxSize,
+			AllowedTypes: cfg.Attachments.AllowedTypes,
+			URLTTL:       cfg.Attachments.URLTTL,
+		})
+		exportFiles = fileStore
+		log.Printf("✅ Note attachments and avatars stored with the %s provider", cfg.Storage.Provider)
+	}
+	adminSegmentRuleHandler := handlers.NewAdminSegmentRuleHandler(db, zapLogger)
+	adminSegmentBulkHandler := handlers.NewAdminSegmentBulkHandler(
+		persistence.NewSegmentBulkRepository(db).WithWebhooks().WithMarketingSync(marketingSyncRepo),
+		customerRepo, zapLogger)
+	adminSegmentHistoryHandler := handlers.NewAdminSegmentHistoryHandler(db, zapLogger)
+	adminAnalyticsHandler := handlers.NewAdminAnalyticsHandler(db, zapLogger)
+	adminDuplicateHandler := handlers.NewAdminDuplicateHandler(db, zapLogger)
+	adminBlocklistHandler := handlers.NewAdminBlocklistHandler(db, zapLogger)
+	adminWebhookHandler := handlers.NewAdminWebhookHandler(db, zapLogger)
+	scheduledExportJob := jobs.NewScheduledExportJob(
+		persistence.NewScheduledExportRepository(db),
+		customerRepo,
+		persistence.NewCustomerTagRepository(db),
+		exportdelivery.New(exportFiles, notificationClient, cfg.Scheduler.ScheduledExportLinkTTL),
+		notificationClient,
+		zapLogger,
+	)
+	adminScheduledExportHandler := handlers.NewAdminScheduledExportHandler(db, scheduledExportJob, zapLogger)
+	var customerExportJob *jobs.CustomerExportJob
+	if exportFiles != nil {
+		customerExportRepo := persistence.NewCustomerExportJobRepository(db)
+		customerExportJob = jobs.NewCustomerExportJob(
+			customerExportRepo,
+			customerRepo,
+			persistence.NewCustomerTagRepository(db),
+			exportFiles,
+			cfg.Exports.PageSize,
+			cfg.Exports.Interval,
+			zapLogger,
+		)
+		adminCustomerHandler.WithExportJobs(customerExportRepo, exportFiles, customerExportJob, cfg.Exports.URLTTL)
+	}
+	marketingConsentHandler := handlers.NewMarketingConsentHandler(marketingSyncRepo)
+	notificationPreferenceHandler := handlers.NewNotificationPreferenceHandler(db)
+	adminMarketingSyncHandler := handlers.NewAdminMarketingSyncHandler(marketingSyncRepo, zapLogger)
+	adminRegionHandler := handlers.NewAdminRegionHandler(db, zapLogger)
+	adminImpersonationHandler := handlers.NewAdminImpersonationHandler(db, customerRepo, cfg.JWT.Secret, zapLogger)
+	adminAddressHandler := handlers.NewAdminAddressHandler(db)
+	adminMergeHandler := handlers.NewAdminMergeHandler(db, customerRepo, zapLogger)
+	adminAuditHandler := handlers.NewAdminAuditHandler(db, zapLogger)
+	adminActivityHandler := handlers.NewAdminActivityHandler(db, zapLogger)
+	walletHandler := handlers.NewWalletHandler(db)
+	adminWalletHandler := handlers.NewAdminWalletHandler(db)
+	adminCustomerLimitHandler := handlers.NewAdminCustomerLimitHandler(customerLimits)
+	internalWalletHandler := handlers.NewInternalWalletHandler(db)
+	accountGraph, err := graph.NewSchema(graph.NewResolver(db, customerRepo, zapLogger), os.Getenv("APP_ENV") != "production")
+	if err != nil {
+		log.Fatalf("Failed to build GraphQL schema: %v", err)
+	}
+	graphQLHandler := handlers.NewGraphQLHandler(accountGraph)
+	internalFraudCheckHandler := handlers.NewInternalFraudCheckHandler(db)
+	// Customers' notification preferences, shared by every customer notifier
+	notificationPolicy := events.NewNotificationPolicy(persistence.NewNotificationPreferenceRepository(db), zapLogger)
+	internalNotificationCheckHandler := handlers.NewInternalNotificationCheckHandler(notificationPolicy)
+	internalCustomerLookupHandler := handlers.NewInternalCustomerLookupHandler(db)
+
+	// Central table of the permission each admin route requires
+	rolePermissions, err := loadRolePermissions(cfg.Permissions.RolesFile)
+	if err != nil {
+		log.Fatalf("Failed to load admin role permissions: %v", err)
+	}
+	permissionPolicy := adminPermissionPolicy("/api/v1/admin", rolePermissions)
+	adminPermissionHandler := handlers.NewAdminPermissionHandler(permissionPolicy)
+
+	// Startup warm-up: prime connections, segment data and hot queries before
+	// the readiness probe lets traffic in
+	readinessHandler := handlers.NewReadinessHandler(db).WithReplica(replicaDB)
+	if cfg.Warmup.Enabled {
+		segmentRuleRepo := persistence.NewSegmentRuleRepository(db)
+		warmer := warmup.New(cfg.Warmup.Timeout, zapLogger).
+			Add("database_pool", warmup.ConnectionPool(sqlDB, cfg.Warmup.Connections)).
+			Add("segment_definitions", func(ctx context.Context) error {
+				_, err := segmentRuleRepo.List(ctx)
+				return err
+			}).
+			Add("reference_data", func(ctx context.Context) error {
+				_, err := customerRepo.GetSegments(ctx)
+				return err
+			}).
+			Add("prepared_statements", warmQueries(db))
+		readinessHandler.WithWarmer(warmer)
+		go warmer.Run(context.Background())
+	}
 
 	// HI-001: Initialize NATS for back-in-stock events
 	var natsErr error
 	natsClient, natsErr = nats.Connect(cfg.NATS.URL)
+	readinessHandler.WithNATS(natsClient)
+
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+	// Set when wishlist analytics events are on; drained on shutdown
+	var wishlistAnalytics *events.WishlistAnalyticsPublisher
+
+	// Publish wishlist item added/removed events for analytics: customers'
+	// changes, imported items and items archived by the retention job
+	if natsErr == nil && cfg.Wishlist.AnalyticsEvents {
+		wishlistAnalytics = events.NewWishlistAnalyticsPublisher(
+			natsClient,
+			cfg.Wishlist.AnalyticsQueueSize,
+			cfg.Wishlist.AnalyticsBatchSize,
+			cfg.Wishlist.AnalyticsFlushInterval,
+			zapLogger,
+		)
+		go wishlistAnalytics.Run(jobsCtx)
+		wishlistHandler.WithAnalytics(wishlistAnalytics)
+		dataPortabilityHandler.WithWishlistEvents(wishlistAnalytics)
+		log.Println("✅ Publishing wishlist analytics events")
+	}
+
+	// Periodic maintenance; with several replicas only the one holding the
+	// scheduler advisory lock runs the jobs
+	if cfg.Scheduler.Enabled {
+		leader := jobs.NewAdvisoryLockLeader(sqlDB, jobs.SchedulerLockKey, cfg.Scheduler.LeaderInterval, zapLogger)
+		go leader.Run(jobsCtx)
+		metrics.Default.NewGaugeFunc("scheduler_leader", "1 if this replica runs the scheduled jobs.", func() float64 {
+			if leader.IsLeader() {
+				return 1
+			}
+			return 0
+		})
+
+		scheduler := jobs.NewScheduler(jobsCtx, leader, zapLogger)
+		// "Still interested?" nudges go out over NATS; without it the
+		// retention job only archives items that were already nudged
+		var wishlistNudger jobs.WishlistNudger
+		if natsErr == nil {
+			wishlistNudger = events.NewWishlistEventPublisher(natsClient, zapLogger)
+		}
+		wishlistRetentionRepo := persistence.NewWishlistRepository(db)
+		if wishlistAnalytics != nil {
+			wishlistRetentionRepo.WithEvents(wishlistAnalytics)
+		}
+		// Without a file store there are no export files to delete
+		exportRetention := cfg.Scheduler.ExportRetention
+		if exportFiles == nil {
+			exportRetention.Enabled = false
+		}
+		scheduledJobs := []struct {
+			name string
+			job  config.ScheduledJobConfig
+			run  func(ctx context.Context) error
+		}{
+			// Expire back-in-stock subscriptions that were never restocked
+			{"back_in_stock_expiry", cfg.Scheduler.BackInStockExpiry, jobs.NewBackInStockExpiryJob(
+				persistence.NewBackInStockRepository(db).WithSubscriptionTTL(cfg.BackInStock.SubscriptionTTL),
+				persistence.NewGuestBackInStockRepository(db).WithSubscriptionTTL(cfg.BackInStock.SubscriptionTTL),
+				zapLogger,
+			).RunOnce},
+			{"back_in_stock_cleanup", cfg.Scheduler.BackInStockCleanup, jobs.NewBackInStockCleanupJob(
+				persistence.NewBackInStockRepository(db),
+				cfg.Scheduler.BackInStockRetentionDays,
+				cfg.Scheduler.BackInStockCleanupBatch,
+				zapLogger,
+			).RunOnce},
+			{"segment_recompute", cfg.Scheduler.SegmentRecompute, jobs.NewSegmentRecomputeJob(
+				customerRepo,
+				persistence.NewSegmentRuleRepository(db).WithWebhooks().WithMarketingSync(marketingSyncRepo),
+				zapLogger,
+			).RunOnce},
+			{"idempotency_key_cleanup", cfg.Scheduler.IdempotencyKeyCleanup, jobs.NewIdempotencyKeyCleanupJob(
+				persistence.NewIdempotencyRepository(db),
+				zapLogger,
+			).RunOnce},
+			{"rfm_scoring", cfg.Scheduler.RFMScoring, jobs.NewRFMScoringJob(
+				persistence.NewRFMRepository(db),
+				persistence.NewSegmentRuleRepository(db).WithWebhooks().WithMarketingSync(marketingSyncRepo),
+				zapLogger,
+			).RunOnce},
+			{"churn_risk", cfg.Scheduler.ChurnRisk, jobs.NewChurnRiskJob(
+				persistence.NewChurnRepository(db),
+				domain.ChurnPolicy{MinOrders: cfg.Scheduler.ChurnMinOrders, InactiveDays: cfg.Scheduler.ChurnInactiveDays},
+				zapLogger,
+			).RunOnce},
+			{"cohort_refresh", cfg.Scheduler.CohortRefresh, jobs.NewCohortRefreshJob(
+				persistence.NewAnalyticsRepository(db),
+				zapLogger,
+			).RunOnce},
+			{"duplicate_detection", cfg.Scheduler.DuplicateDetection, jobs.NewDuplicateDetectionJob(
+				persistence.NewDuplicateRepository(db),
+				zapLogger,
+			).RunOnce},
+			{"recently_viewed_cleanup", cfg.Scheduler.RecentViewsCleanup, jobs.NewRecentlyViewedCleanupJob(
+				persistence.NewRecentlyViewedRepository(db),
+				cfg.Scheduler.RecentViewsRetentionDays,
+				zapLogger,
+			).RunOnce},
+			{"wishlist_retention", cfg.Scheduler.WishlistRetention, jobs.NewWishlistRetentionJob(
+				wishlistRetentionRepo,
+				wishlistNudger,
+				domain.WishlistRetentionPolicy{StaleMonths: cfg.Scheduler.WishlistStaleMonths, ConfirmDays: cfg.Scheduler.WishlistConfirmDays},
+				zapLogger,
+			).RunOnce},
+			{"scheduled_exports", cfg.Scheduler.ScheduledExports, scheduledExportJob.RunOnce},
+			{"export_retention", exportRetention, jobs.NewCustomerExportRetentionJob(
+				persistence.NewCustomerExportJobRepository(db),
+				exportFiles,
+				cfg.Scheduler.ExportRetentionDays,
+				zapLogger,
+			).RunOnce},
+		}
+
+		for _, j := range scheduledJobs {
+			if !j.job.Enabled {
+				continue
+			}
+			if err := scheduler.Add(j.name, j.job.Schedule, j.run); err != nil {
+				log.Fatalf("Failed to schedule job: %v", err)
+			}
+		}
+		go scheduler.Run()
+	}
+
+	// Resend back-in-stock notifications that failed, with exponential backoff
+	notificationRetryRepo := persistence.NewNotificationRetryRepository(db)
+	notificationRetryPolicy := domain.NotificationRetryPolicy{
+		MaxAttempts: cfg.BackInStock.RetryMaxAttempts,
+		Backoff:     cfg.BackInStock.RetryBackoff,
+		MaxBackoff:  cfg.BackInStock.RetryMaxBackoff,
+	}
+	go jobs.NewNotificationRetryJob(
+		notificationRetryRepo,
+		persistence.NewBackInStockRepository(db),
+		persistence.NewGuestBackInStockRepository(db),
+		notificationClient,
+		notificationRetryPolicy,
+		cfg.BackInStock.RetryInterval,
+		zapLogger,
+	).WithNotificationPolicy(notificationPolicy).Run(jobsCtx)
+
+	// Run background customer exports queued by admins
+	if customerExportJob != nil {
+		go customerExportJob.Run(jobsCtx)
+	}
+
+	// Send queued customer events to webhook subscriptions, with exponential backoff
+	go jobs.NewWebhookDeliveryJob(
+		webhookRepo,
+		webhookclient.New(cfg.Webhooks.Timeout),
+		domain.NotificationRetryPolicy{
+			MaxAttempts: cfg.Webhooks.RetryMaxAttempts,
+			Backoff:     cfg.Webhooks.RetryBackoff,
+			MaxBackoff:  cfg.Webhooks.RetryMaxBackoff,
+		},
+		cfg.Webhooks.DeliveryInterval,
+		zapLogger,
+	).Run(jobsCtx)
+
+	// Push changed customers to the email marketing platforms
+	if len(marketingProviders) > 0 {
+		go jobs.NewMarketingSyncJob(
+			marketingSyncRepo,
+			marketingProviders,
+			domain.NotificationRetryPolicy{
+				MaxAttempts: cfg.Marketing.RetryMaxAttempts,
+				Backoff:     cfg.Marketing.RetryBackoff,
+				MaxBackoff:  cfg.Marketing.RetryMaxBackoff,
+			},
+			cfg.Marketing.Interval,
+			cfg.Marketing.BatchPause,
+			zapLogger,
+		).Run(jobsCtx)
+	}
+
+	// Back-in-stock processing, shared by the NATS consumer and the inventory webhook
+	backInStockRepo := persistence.NewBackInStockRepository(db).WithSubscriptionTTL(cfg.BackInStock.SubscriptionTTL)
+	guestBackInStockRepo := persistence.NewGuestBackInStockRepository(db).WithSubscriptionTTL(cfg.BackInStock.SubscriptionTTL)
+	backInStockSubscriber := events.NewBackInStockSubscriber(
+		natsClient,
+		backInStockRepo,
+		notificationClient,
+		zapLogger,
+	).WithGuestSubscriptions(guestBackInStockRepo).
+		WithThrottle(domain.BackInStockThrottle{
+			Limit:  cfg.BackInStock.NotifyLimit,
+			Window: cfg.BackInStock.NotifyWindow,
+		}).
+		WithRetryQueue(notificationRetryRepo, notificationRetryPolicy).
+		WithNotificationPolicy(notificationPolicy).
+		WithPartialRestocks(cfg.BackInStock.PartialNotify).
+		WithConsumer(jetStreamConsumer(cfg.NATS.Restock))
+	adminBackInStockHandler.WithProductNotifier(backInStockSubscriber)
+	inventoryWebhookHandler := handlers.NewInventoryWebhookHandler(
+		persistence.NewWebhookDeliveryRepository(db),
+		backInStockSubscriber,
+		cfg.BackInStock.WebhookSecret,
+		cfg.BackInStock.WebhookTolerance,
+		zapLogger,
+	)
+
 	if natsErr != nil {
 		log.Printf("⚠️  NATS connection failed: %v (back-in-stock events disabled)", natsErr)
 	} else {
 		log.Println("✅ NATS connected")
 
-		// Initialize back-in-stock repository and subscriber
-		backInStockRepo := persistence.NewBackInStockRepository(db)
-		notificationClient := events.NewSimpleNotificationClient(
-			getEnv("NOTIFICATION_SERVICE_URL", "http://localhost:8006"),
+		// Subscribe to restock events (JetStream durable consumer)
+		if err := backInStockSubscriber.Subscribe(); err != nil {
+			log.Printf("⚠️  Failed to subscribe to restock events: %v", err)
+		} else {
+			log.Printf("✅ Subscribed to inventory.product.restocked events (durable %s)", cfg.NATS.Restock.Durable)
+		}
+
+		// Attach guest back-in-stock subscriptions to accounts registered with the same email
+		registrationSubscriber := events.NewRegistrationSubscriber(natsClient, guestBackInStockRepo, zapLogger)
+		if err := registrationSubscriber.Subscribe(); err != nil {
+			log.Printf("⚠️  Failed to subscribe to user registered events: %v", err)
+		} else {
+			log.Println("✅ Subscribed to auth.user.registered events")
+		}
+
+		// Sign-ins, for the customer's security log
+		loginSubscriber := events.NewLoginSubscriber(natsClient, persistence.NewActivityRepository(db), zapLogger)
+		if err := loginSubscriber.Subscribe(); err != nil {
+			log.Printf("⚠️  Failed to subscribe to user logged in events: %v", err)
+		} else {
+			log.Println("✅ Subscribed to auth.user.logged_in events")
+		}
+
+		// Move customer data over when auth merges two accounts
+		accountMergeSubscriber := events.NewAccountMergeSubscriber(
+			natsClient,
+			persistence.NewAccountMergeRepository(db),
+			zapLogger,
+		)
+		if err := accountMergeSubscriber.Subscribe(); err != nil {
+			log.Printf("⚠️  Failed to subscribe to account merge events: %v", err)
+		} else {
+			log.Println("✅ Subscribed to auth.accounts.merged events")
+		}
+
+		// Customer order totals and automatic segment assignment on order milestones
+		orderSubscriber := events.NewOrderSubscriber(
+			natsClient,
+			persistence.NewOrderStatsRepository(db),
+			persistence.NewSegmentRuleRepository(db).WithWebhooks().WithMarketingSync(marketingSyncRepo),
 			zapLogger,
 		)
-		backInStockSubscriber := events.NewBackInStockSubscriber(
+		if err := orderSubscriber.Subscribe(); err != nil {
+			log.Printf("⚠️  Failed to subscribe to order events: %v", err)
+		} else {
+			log.Println("✅ Subscribed to order.completed and order.refunded events")
+		}
+
+		// Keep denormalized wishlist product info in sync with the catalog
+		productSubscriber := events.NewProductSubscriber(
 			natsClient,
-			backInStockRepo,
-			notificationClient,
+			persistence.NewWishlistRepository(db),
 			zapLogger,
 		)
+		if err := productSubscriber.Subscribe(); err != nil {
+			log.Printf("⚠️  Failed to subscribe to product updated events: %v", err)
+		} else {
+			log.Println("✅ Subscribed to catalog.product.updated events")
+		}
 
-		// Subscribe to restock events
-		if err := backInStockSubscriber.Subscribe(); err != nil {
-			log.Printf("⚠️  Failed to subscribe to restock events: %v", err)
+		// Subscribe notify_restock wishlist items to back-in-stock when they sell out
+		if cfg.BackInStock.FromWishlist {
+			wishlistRestockSubscriber := events.NewWishlistRestockSubscriber(
+				natsClient,
+				persistence.NewWishlistRepository(db),
+				backInStockRepo,
+				zapLogger,
+			).WithConsumer(jetStreamConsumer(cfg.NATS.OutOfStock))
+			if err := wishlistRestockSubscriber.Subscribe(); err != nil {
+				log.Printf("⚠️  Failed to subscribe to out of stock events: %v", err)
+			} else {
+				log.Println("✅ Subscribed to inventory.product.out_of_stock events")
+			}
+		}
+
+		// Alert customers to new products matching their saved searches
+		savedSearchSubscriber := events.NewSavedSearchSubscriber(
+			natsClient,
+			persistence.NewSavedSearchRepository(db),
+			events.NewSavedSearchEventPublisher(natsClient, zapLogger),
+			zapLogger,
+		).WithNotificationPolicy(notificationPolicy)
+		if err := savedSearchSubscriber.Subscribe(); err != nil {
+			log.Printf("⚠️  Failed to subscribe to product published events: %v", err)
 		} else {
-			log.Println("✅ Subscribed to inventory.product.restocked events")
+			log.Println("✅ Subscribed to catalog.product.published events")
 		}
+
+		// Notify customers when a measurement update changes their derived size
+		if getEnv("SIZE_DRIFT_NOTIFICATIONS", "true") == "true" {
+			measurementHandler.WithSizeDriftNotifier(events.NewMeasurementEventPublisher(natsClient, zapLogger))
+		}
+
+		// Notify staff @mentioned in customer notes
+		adminCustomerHandler.WithNoteMentions(events.NewNoteEventPublisher(natsClient, zapLogger))
 	}
 
 	// Setup router
 	router := gin.New()
 
-	// Apply global middleware
+	// Apply global middleware; tracing first so recovered panics end up in
+	// the request span as 500s
+	router.Use(tracing.Middleware())
 	router.Use(sentryMonitor.GinMiddleware())
 	router.Use(sentryMonitor.RecoveryMiddleware())
 	router.Use(gin.Logger())
@@ -185,55 +742,224 @@ func main() {
 	// Security headers
 	router.Use(libmiddleware.SecurityHeaders())
 
+	// JSON key casing: clients may request snake or camel keys; /api/v2 (see
+	// the server handler) defaults to snake_case
+	router.Use(middleware.JSONCaseMiddleware())
+
 	// Input validation
 	router.Use(libmiddleware.InputValidation())
 
-	// Rate limiting (50 requests per minute)
-	rateLimiter := libmiddleware.NewRateLimiter(50, 100)
-	rateLimiter.CleanupLimiters()
+	// SLO tracking; recorded before load shedding so shed requests count as errors
+	sloObjectives := make(map[string]metrics.Objective, len(cfg.SLO.Endpoints))
+	for endpoint, objective := range cfg.SLO.Endpoints {
+		sloObjectives[endpoint] = sloObjective(objective)
+	}
+	sloTracker := metrics.NewSLOTracker(sloObjective(cfg.SLO.Default), sloObjectives)
+	router.Use(sloTracker.Middleware())
+	router.Use(metrics.HTTPMiddleware())
+	adminSystemHandler := handlers.NewAdminSystemHandler(sloTracker).
+		WithNotificationClient(notificationClient).
+		WithLegacyCRM(legacyCRM)
 
-	// Health check
-	router.GET("/health", func(c *gin.Context) {
-		c.JSON(http.StatusOK, gin.H{
-			"status":  "healthy",
-			"service": "customer",
-			"time":    time.Now().UTC(),
-		})
-	})
+	// Load shedding: health and internal checkout calls are never shed,
+	// exports and stats are shed before regular traffic
+	loadShedder := middleware.NewLoadShedder(middleware.LoadShedConfig{
+		MaxConcurrent: cfg.LoadShed.MaxConcurrent,
+		MaxQueue:      cfg.LoadShed.MaxQueue,
+		QueueTimeout:  cfg.LoadShed.QueueTimeout,
+		RetryAfter:    cfg.LoadShed.RetryAfter,
+	}).
+		SetPriority("/health", middleware.PriorityCritical).
+		SetPriority("/ready", middleware.PriorityCritical).
+		SetPriority("/metrics", middleware.PriorityCritical).
+		SetPriority("/internal/v1", middleware.PriorityCritical).
+		SetPriority("/api/v1/internal/webhooks", middleware.PriorityCritical).
+		SetPriority("/api/v1/admin/customers/export", middleware.PriorityLow).
+		SetPriority("/api/v1/admin/customers/exports/", middleware.PriorityNormal). // export progress polling
+		SetPriority("/api/v1/admin/customers/stats", middleware.PriorityLow).
+		SetPriority("/api/v1/admin/customers/analytics/cohorts", middleware.PriorityLow).
+		SetPriority("/api/v1/admin/customers/analytics/growth", middleware.PriorityLow).
+		SetPriority("/api/v1/admin/back-in-stock/stats", middleware.PriorityLow).
+		SetPriority("/api/v1/admin/back-in-stock/export", middleware.PriorityLow).
+		SetPriority("/api/v1/admin/back-in-stock/demand", middleware.PriorityLow).
+		SetPriority("/api/v1/customer/recently-viewed", middleware.PriorityLow)
+	router.Use(loadShedder.Middleware())
+
+	// Rate limiting per user (per IP on public routes), mounted on the API
+	// groups below after authentication; internal calls and webhooks are
+	// not limited
+	rateLimiter := middleware.NewRateLimiter(middleware.RateLimitConfig{
+		Read:  middleware.RateLimit{PerMinute: cfg.RateLimit.ReadPerMinute, Burst: cfg.RateLimit.ReadBurst},
+		Write: middleware.RateLimit{PerMinute: cfg.RateLimit.WritePerMinute, Burst: cfg.RateLimit.WriteBurst},
+	}).
+		SetLimit("/api/v1/public/back-in-stock", middleware.RateLimit{PerMinute: cfg.RateLimit.GuestSignupPerMinute, Burst: cfg.RateLimit.GuestSignupBurst}).
+		SetLimit("/api/v1/public/back-in-stock/confirm", middleware.RateLimit{PerMinute: cfg.RateLimit.ReadPerMinute, Burst: cfg.RateLimit.ReadBurst}).
+		SetLimit("/api/v1/customer/email-change", middleware.RateLimit{PerMinute: 5, Burst: 2}).
+		SetLimit("/api/v1/graphql", middleware.RateLimit{PerMinute: cfg.RateLimit.ReadPerMinute, Burst: cfg.RateLimit.ReadBurst}).
+		SetLimit("/api/v1/customer/wishlist/check-batch", middleware.RateLimit{PerMinute: cfg.RateLimit.ReadPerMinute, Burst: cfg.RateLimit.ReadBurst}).
+		SetLimit("/api/v1/customer/back-in-stock/check-batch", middleware.RateLimit{PerMinute: cfg.RateLimit.ReadPerMinute, Burst: cfg.RateLimit.ReadBurst}).
+		SetLimit("/api/v1/customer/recently-viewed", middleware.RateLimit{PerMinute: cfg.RateLimit.ReadPerMinute, Burst: cfg.RateLimit.ReadBurst}).
+		SetLimit("/api/v1/admin/customers/export", middleware.RateLimit{PerMinute: 10, Burst: 3}).
+		SetLimit("/api/v1/admin/customers/exports/", middleware.RateLimit{PerMinute: cfg.RateLimit.ReadPerMinute, Burst: cfg.RateLimit.ReadBurst}).
+		SetLimit("/api/v1/admin/activity/export", middleware.RateLimit{PerMinute: 10, Burst: 3}).
+		SetLimit("/api/v1/admin/back-in-stock/export", middleware.RateLimit{PerMinute: 10, Burst: 3})
+	go rateLimiter.Run(jobsCtx)
+
+	// Idempotency-Key support on creates that mobile clients retry on flaky
+	// networks; mounted per route after authentication
+	idempotency := middleware.NewIdempotency(
+		persistence.NewIdempotencyRepository(db).WithLockTimeout(cfg.Idempotency.LockTimeout),
+		cfg.Idempotency.TTL,
+	).Middleware()
+
+	// Liveness and readiness probes; /health and /ready are kept for
+	// existing checks
+	router.GET("/health/live", readinessHandler.Live)
+	router.GET("/health/ready", readinessHandler.Ready)
+	router.GET("/health", readinessHandler.Live)
+	router.GET("/ready", readinessHandler.Ready)
+
+	// Prometheus scrape endpoint
+	router.GET("/metrics", gin.WrapH(metrics.Default.Handler()))
+
+	// Signed download links and avatars of locally stored files
+	if localStore, ok := fileStore.(*filestore.LocalStore); ok {
+		if publicURL, err := url.Parse(cfg.Storage.PublicURL); err == nil {
+			filesPath := strings.TrimRight(publicURL.Path, "/")
+			router.GET(filesPath+"/*key", gin.WrapH(http.StripPrefix(filesPath, localStore)))
+		}
+	}
+
+	// OpenAPI spec, and Swagger UI outside production
+	openAPIHandler, err := handlers.NewOpenAPIHandler("/api/v1/openapi.json")
+	if err != nil {
+		log.Fatalf("Failed to build OpenAPI spec: %v", err)
+	}
+	router.GET("/api/v1/openapi.json", openAPIHandler.Spec)
+	if os.Getenv("APP_ENV") != "production" {
+		router.GET("/api/v1/docs", openAPIHandler.UI)
+	}
+
+	// Error catalog the type of every problem response points to
+	problemHandler := handlers.NewProblemHandler()
+	router.GET("/api/v1/problems", problemHandler.ListProblems)
+	router.GET("/api/v1/problems/:type", problemHandler.GetProblem)
 
 	// API v1 routes
 	v1 := router.Group("/api/v1")
 	{
+		// Public routes (no account required)
+		public := v1.Group("/public")
+		public.Use(rateLimiter.Middleware())
+		{
+			public.POST("/back-in-stock", guestBackInStockHandler.Subscribe)
+			public.GET("/back-in-stock/confirm", guestBackInStockHandler.Confirm)
+		}
+
+		// Webhooks from sources that can't publish to NATS; authenticated by
+		// their HMAC signature rather than a user token
+		webhooks := v1.Group("/internal/webhooks")
+		{
+			webhooks.POST("/inventory", inventoryWebhookHandler.Receive)
+		}
+
+		// Key customer actions logged to the admin activity timeline
+		const customerRoutes = "/api/v1/customer"
+		activityTracker := middleware.NewActivityTracker(persistence.NewActivityRepository(db)).
+			Track(http.MethodPut, customerRoutes+"/profile", domain.ActivityTypeProfile, "Profile updated").
+			Track(http.MethodPost, customerRoutes+"/email-change/confirm", domain.ActivityTypeProfile, "Email changed").
+			Track(http.MethodPost, customerRoutes+"/profile/avatar", domain.ActivityTypeProfile, "Avatar updated").
+			Track(http.MethodPost, customerRoutes+"/addresses", domain.ActivityTypeAddress, "Address added").
+			Track(http.MethodPost, customerRoutes+"/addresses/import-from-order/:orderId", domain.ActivityTypeAddress, "Address imported from order").
+			Track(http.MethodPut, customerRoutes+"/addresses/:id", domain.ActivityTypeAddress, "Address updated").
+			Track(http.MethodDelete, customerRoutes+"/addresses/:id", domain.ActivityTypeAddress, "Address deleted").
+			Track(http.MethodPut, customerRoutes+"/addresses/:id/default", domain.ActivityTypeAddress, "Default address changed").
+			Track(http.MethodPost, customerRoutes+"/addresses/:id/restore", domain.ActivityTypeAddress, "Address restored").
+			Track(http.MethodPost, customerRoutes+"/wishlist", domain.ActivityTypeWishlist, "Added to wishlist").
+			Track(http.MethodPost, customerRoutes+"/wishlist/import", domain.ActivityTypeWishlist, "Wishlist imported").
+			Track(http.MethodPost, customerRoutes+"/wishlist/confirm", domain.ActivityTypeWishlist, "Kept stale wishlist items").
+			Track(http.MethodPost, customerRoutes+"/measurements", domain.ActivityTypeMeasurement, "Measurement added").
+			Track(http.MethodPut, customerRoutes+"/measurements/:id", domain.ActivityTypeMeasurement, "Measurement updated").
+			Track(http.MethodDelete, customerRoutes+"/measurements/:id", domain.ActivityTypeMeasurement, "Measurement deleted").
+			Track(http.MethodPut, customerRoutes+"/measurements/:id/set-default", domain.ActivityTypeMeasurement, "Default measurement changed").
+			Track(http.MethodPost, customerRoutes+"/back-in-stock", domain.ActivityTypeBackInStock, "Subscribed to back-in-stock alert").
+			Track(http.MethodPost, customerRoutes+"/data-import", domain.ActivityTypeProfile, "Account data imported").
+			Track(http.MethodPut, customerRoutes+"/marketing-consent", domain.ActivityTypeProfile, "Marketing consent changed").
+			Track(http.MethodPut, customerRoutes+"/notification-preferences", domain.ActivityTypeProfile, "Notification preferences changed")
+
+		// Tokens signed with JWT_SECRET or, when JWT_JWKS_URL is set, by the identity provider
+		jwtConfig := middleware.JWTConfig{
+			Secret:     cfg.JWT.Secret,
+			Algorithms: cfg.JWT.Algorithms,
+			Issuer:     cfg.JWT.Issuer,
+			Audience:   cfg.JWT.Audience,
+			ClockSkew:  cfg.JWT.ClockSkew,
+		}
+		if cfg.JWT.JWKSURL != "" {
+			jwtConfig.Keys = jwks.NewKeySet(cfg.JWT.JWKSURL, cfg.JWT.JWKSCacheTTL, zapLogger)
+		}
+		authMiddleware := middleware.JWTAuthMiddleware(jwtConfig)
+
 		// Customer routes (protected)
 		customer := v1.Group("/customer")
-		customer.Use(middleware.AuthMiddleware(cfg.JWT.Secret))
+		customer.Use(authMiddleware)
+		customer.Use(middleware.ImpersonationMiddleware(persistence.NewImpersonationRepository(db)))
+		customer.Use(rateLimiter.Middleware())
+		customer.Use(activityTracker.Middleware())
 		{
 			// Profile
 			customer.GET("/profile", profileHandler.GetProfile)
 			customer.PUT("/profile", profileHandler.UpdateProfile)
+			customer.POST("/profile/avatar", profileHandler.UploadAvatar)
+			customer.POST("/email-change", emailChangeHandler.Request)
+			customer.POST("/email-change/confirm", emailChangeHandler.Confirm)
+			customer.GET("/activity", activityHandler.List)
 
 			// Addresses
 			customer.GET("/addresses", addressHandler.ListAddresses)
-			customer.POST("/addresses", addressHandler.CreateAddress)
+			customer.POST("/addresses", idempotency, addressHandler.CreateAddress)
+			customer.POST("/addresses/validate", addressHandler.ValidateAddress)
+			customer.POST("/addresses/import-from-order/:orderId", addressHandler.ImportFromOrder)
 			customer.PUT("/addresses/:id", addressHandler.UpdateAddress)
 			customer.DELETE("/addresses/:id", addressHandler.DeleteAddress)
 			customer.PUT("/addresses/:id/default", addressHandler.SetDefaultAddress)
+			customer.POST("/addresses/:id/restore", addressHandler.RestoreAddress)
 
 			// Wishlist (CUS-001: variant-specific support)
 			customer.GET("/wishlist", wishlistHandler.GetWishlist)
 			customer.GET("/wishlist/count", wishlistHandler.GetWishlistCount)
+			customer.GET("/wishlist/stock-status", wishlistHandler.GetStockStatus)
 			customer.GET("/wishlist/check/:productId", wishlistHandler.CheckWishlist)
-			customer.POST("/wishlist", wishlistHandler.AddToWishlist)
+			customer.POST("/wishlist/check-batch", wishlistHandler.CheckWishlistBatch)
+			customer.POST("/wishlist", idempotency, wishlistHandler.AddToWishlist)
+			customer.POST("/wishlist/import", wishlistHandler.ImportWishlist)
+			customer.POST("/wishlist/confirm", wishlistHandler.ConfirmWishlist)
 			customer.DELETE("/wishlist/:productId", wishlistHandler.RemoveFromWishlist)
 			customer.DELETE("/wishlist/items/:itemId", wishlistHandler.RemoveWishlistItem)
 			customer.PATCH("/wishlist/items/:itemId", wishlistHandler.UpdateWishlistItem)
 
+			// Recently viewed products, recorded by the storefront product page
+			customer.GET("/recently-viewed", recentlyViewedHandler.GetRecentlyViewed)
+			customer.POST("/recently-viewed", recentlyViewedHandler.RecordView)
+			customer.DELETE("/recently-viewed", recentlyViewedHandler.ClearRecentlyViewed)
+
+			// Saved searches with new-arrival alerts
+			customer.GET("/saved-searches", savedSearchHandler.ListSavedSearches)
+			customer.POST("/saved-searches", savedSearchHandler.SaveSearch)
+			customer.PUT("/saved-searches/:id", savedSearchHandler.UpdateSavedSearch)
+			customer.DELETE("/saved-searches/:id", savedSearchHandler.DeleteSavedSearch)
+
+			// Data portability
+			customer.GET("/data-export", dataPortabilityHandler.ExportData)
+			customer.POST("/data-import", dataPortabilityHandler.ImportData)
+
 			// Order History
 			customer.GET("/orders", orderHistoryHandler.GetOrderHistory)
 
 			// Measurements (Day 96)
 			customer.GET("/measurements", measurementHandler.List)
 			customer.POST("/measurements", measurementHandler.Create)
+			customer.GET("/measurements/profiles", measurementHandler.ListProfiles)
 			customer.GET("/measurements/:id", measurementHandler.GetByID)
 			customer.PUT("/measurements/:id", measurementHandler.Update)
 			customer.DELETE("/measurements/:id", measurementHandler.Delete)
@@ -241,53 +967,176 @@ func main() {
 
 			// Back-in-Stock Notifications (HI-001)
 			customer.GET("/back-in-stock", backInStockHandler.GetSubscriptions)
-			customer.POST("/back-in-stock", backInStockHandler.Subscribe)
+			customer.POST("/back-in-stock", idempotency, backInStockHandler.Subscribe)
 			customer.GET("/back-in-stock/check/:productId", backInStockHandler.IsSubscribed)
+			customer.POST("/back-in-stock/check-batch", backInStockHandler.IsSubscribedBatch)
 			customer.DELETE("/back-in-stock/:productId", backInStockHandler.Unsubscribe)
 			customer.DELETE("/back-in-stock/subscriptions/:id", backInStockHandler.UnsubscribeByID)
+
+			// Store credit wallet
+			customer.GET("/wallet", walletHandler.GetWallet)
+			customer.GET("/wallet/transactions", walletHandler.GetTransactions)
+
+			// Marketing consent
+			customer.GET("/marketing-consent", marketingConsentHandler.GetConsent)
+			customer.PUT("/marketing-consent", marketingConsentHandler.UpdateConsent)
+
+			// Notification schedule: channel, quiet hours, weekly cap and opt-outs
+			customer.GET("/notification-preferences", notificationPreferenceHandler.GetPreferences)
+			customer.PUT("/notification-preferences", notificationPreferenceHandler.UpdatePreferences)
+		}
+
+		// Account page graph: a customer's profile, addresses, wishlist,
+		// measurements and back-in-stock subscriptions in one query
+		graphQL := v1.Group("/graphql")
+		graphQL.Use(authMiddleware)
+		graphQL.Use(middleware.ImpersonationMiddleware(persistence.NewImpersonationRepository(db)))
+		graphQL.Use(rateLimiter.Middleware())
+		graphQL.Use(permissionPolicy.Resolve())
+		{
+			graphQL.GET("", graphQLHandler.Query)
+			graphQL.POST("", graphQLHandler.Query)
 		}
 
+		// Every admin create/update/delete is audit-logged with before/after snapshots
+		adminRoutes := "/api/v1/admin"
+		auditRepo := persistence.NewAuditLogRepository(db)
+		adminRegionRepo := persistence.NewAdminRegionRepository(db)
+		auditTrail := middleware.NewAuditTrail(auditRepo).
+			Entity(domain.AuditEntityCustomer, auditRepo.Snapshot(&domain.Customer{}, "id")).
+			Entity(domain.AuditEntitySegment, auditRepo.Snapshot(&domain.CustomerSegment{}, "id")).
+			Entity(domain.AuditEntitySegmentRule, auditRepo.Snapshot(&domain.SegmentRule{}, "id")).
+			Entity(domain.AuditEntityCustomerNote, auditRepo.Snapshot(&domain.CustomerNote{}, "id")).
+			Entity(domain.AuditEntityNoteAttachment, auditRepo.Snapshot(&domain.NoteAttachment{}, "id")).
+			Entity(domain.AuditEntityActivity, auditRepo.Snapshot(&domain.CustomerActivity{}, "id")).
+			Entity(domain.AuditEntityImpersonation, auditRepo.Snapshot(&domain.ImpersonationSession{}, "id")).
+			Entity(domain.AuditEntityWallet, auditRepo.Snapshot(&domain.CustomerWallet{}, "customer_id")).
+			Entity(domain.AuditEntityCustomerView, auditRepo.Snapshot(&domain.CustomerListView{}, "id")).
+			Entity(domain.AuditEntityCustomerLimit, auditRepo.Snapshot(&domain.CustomerLimitOverride{}, "customer_id")).
+			Entity(domain.AuditEntityDuplicate, auditRepo.Snapshot(&domain.DuplicateCandidate{}, "id")).
+			Entity(domain.AuditEntityBlocklist, auditRepo.Snapshot(&domain.BlocklistEntry{}, "id")).
+			Entity(domain.AuditEntityWebhook, auditRepo.Snapshot(&domain.WebhookSubscription{}, "id")).
+			Entity(domain.AuditEntityScheduledExport, auditRepo.Snapshot(&domain.ScheduledExport{}, "id")).
+			Entity(domain.AuditEntityCustomerExport, auditRepo.Snapshot(&domain.CustomerExportJob{}, "id")).
+			Entity(domain.AuditEntityRegion, func(ctx context.Context, adminID uuid.UUID) (interface{}, error) {
+				states, err := adminRegionRepo.GetStates(ctx, adminID)
+				return gin.H{"states": states}, err
+			}).
+			Audit(http.MethodPost, adminRoutes+"/customers", domain.AuditEntityCustomer, domain.AuditActionCreate, "").
+			Audit(http.MethodPut, adminRoutes+"/customers/:id", domain.AuditEntityCustomer, domain.AuditActionUpdate, "id").
+			Audit(http.MethodDelete, adminRoutes+"/customers/:id", domain.AuditEntityCustomer, domain.AuditActionDelete, "id").
+			Audit(http.MethodPost, adminRoutes+"/customers/:id/reveal", domain.AuditEntityCustomer, "reveal_pii", "id").
+			Audit(http.MethodPost, adminRoutes+"/customers/exports", domain.AuditEntityCustomerExport, domain.AuditActionCreate, "").
+			Audit(http.MethodPost, adminRoutes+"/customers/:id/notes", domain.AuditEntityCustomerNote, domain.AuditActionCreate, "").
+			Audit(http.MethodPut, adminRoutes+"/customers/:id/notes/:noteId", domain.AuditEntityCustomerNote, domain.AuditActionUpdate, "noteId").
+			Audit(http.MethodDelete, adminRoutes+"/customers/:id/notes/:noteId", domain.AuditEntityCustomerNote, domain.AuditActionDelete, "noteId").
+			Audit(http.MethodPost, adminRoutes+"/customers/:id/notes/:noteId/pin", domain.AuditEntityCustomerNote, "pin", "noteId").
+			Audit(http.MethodDelete, adminRoutes+"/customers/:id/notes/:noteId/pin", domain.AuditEntityCustomerNote, "unpin", "noteId").
+			Audit(http.MethodPost, adminRoutes+"/customers/:id/notes/:noteId/attachments", domain.AuditEntityNoteAttachment, domain.AuditActionCreate, "").
+			Audit(http.MethodDelete, adminRoutes+"/customers/:id/notes/:noteId/attachments/:attachmentId", domain.AuditEntityNoteAttachment, domain.AuditActionDelete, "attachmentId").
+			Audit(http.MethodPost, adminRoutes+"/customers/:id/activity/:activityId/pin", domain.AuditEntityActivity, "pin", "activityId").
+			Audit(http.MethodDelete, adminRoutes+"/customers/:id/activity/:activityId/pin", domain.AuditEntityActivity, "unpin", "activityId").
+			Audit(http.MethodPost, adminRoutes+"/customers/:id/segments", domain.AuditEntityCustomer, "assign_segments", "id").
+			Audit(http.MethodPost, adminRoutes+"/customers/:id/tags", domain.AuditEntityCustomer, "tag", "id").
+			Audit(http.MethodDelete, adminRoutes+"/customers/:id/tags/:tag", domain.AuditEntityCustomer, "untag", "id").
+			Audit(http.MethodPost, adminRoutes+"/customers/views", domain.AuditEntityCustomerView, domain.AuditActionCreate, "").
+			Audit(http.MethodPut, adminRoutes+"/customers/views/:viewId", domain.AuditEntityCustomerView, domain.AuditActionUpdate, "viewId").
+			Audit(http.MethodDelete, adminRoutes+"/customers/views/:viewId", domain.AuditEntityCustomerView, domain.AuditActionDelete, "viewId").
+			Audit(http.MethodPost, adminRoutes+"/customers/:id/merge", domain.AuditEntityCustomer, "merge", "id").
+			Audit(http.MethodPost, adminRoutes+"/customers/duplicates/:candidateId/dismiss", domain.AuditEntityDuplicate, "dismiss", "candidateId").
+			Audit(http.MethodPost, adminRoutes+"/customers/:id/impersonate", domain.AuditEntityCustomer, "impersonate", "id").
+			Audit(http.MethodPost, adminRoutes+"/customers/:id/wallet/credit", domain.AuditEntityWallet, "credit", "id").
+			Audit(http.MethodPost, adminRoutes+"/customers/:id/wallet/debit", domain.AuditEntityWallet, "debit", "id").
+			Audit(http.MethodPut, adminRoutes+"/customers/:id/limits", domain.AuditEntityCustomerLimit, domain.AuditActionUpdate, "id").
+			Audit(http.MethodDelete, adminRoutes+"/customers/:id/limits", domain.AuditEntityCustomerLimit, domain.AuditActionDelete, "id").
+			Audit(http.MethodPost, adminRoutes+"/segments", domain.AuditEntitySegment, domain.AuditActionCreate, "").
+			Audit(http.MethodPut, adminRoutes+"/segments/:id", domain.AuditEntitySegment, domain.AuditActionUpdate, "id").
+			Audit(http.MethodDelete, adminRoutes+"/segments/:id", domain.AuditEntitySegment, domain.AuditActionDelete, "id").
+			Audit(http.MethodPost, adminRoutes+"/segments/:id/customers/bulk", domain.AuditEntitySegment, domain.AuditActionBulk, "id").
+			Audit(http.MethodPost, adminRoutes+"/segments/rules", domain.AuditEntitySegmentRule, domain.AuditActionCreate, "").
+			Audit(http.MethodDelete, adminRoutes+"/segments/rules/:ruleId", domain.AuditEntitySegmentRule, domain.AuditActionDelete, "ruleId").
+			Audit(http.MethodPut, adminRoutes+"/region-assignments/:adminId", domain.AuditEntityRegion, domain.AuditActionUpdate, "adminId").
+			Audit(http.MethodDelete, adminRoutes+"/impersonations/:sessionId", domain.AuditEntityImpersonation, "revoke", "sessionId").
+			Audit(http.MethodPost, adminRoutes+"/blocklist", domain.AuditEntityBlocklist, domain.AuditActionCreate, "").
+			Audit(http.MethodDelete, adminRoutes+"/blocklist/:entryId", domain.AuditEntityBlocklist, domain.AuditActionDelete, "entryId").
+			Audit(http.MethodPost, adminRoutes+"/webhooks", domain.AuditEntityWebhook, domain.AuditActionCreate, "").
+			Audit(http.MethodPut, adminRoutes+"/webhooks/:webhookId", domain.AuditEntityWebhook, domain.AuditActionUpdate, "webhookId").
+			Audit(http.MethodDelete, adminRoutes+"/webhooks/:webhookId", domain.AuditEntityWebhook, domain.AuditActionDelete, "webhookId").
+			Audit(http.MethodPost, adminRoutes+"/webhooks/:webhookId/deliveries/:deliveryId/redeliver", domain.AuditEntityWebhook, "redeliver", "webhookId").
+			Audit(http.MethodPost, adminRoutes+"/scheduled-exports", domain.AuditEntityScheduledExport, domain.AuditActionCreate, "").
+			Audit(http.MethodPut, adminRoutes+"/scheduled-exports/:exportId", domain.AuditEntityScheduledExport, domain.AuditActionUpdate, "exportId").
+			Audit(http.MethodDelete, adminRoutes+"/scheduled-exports/:exportId", domain.AuditEntityScheduledExport, domain.AuditActionDelete, "exportId").
+			Audit(http.MethodPost, adminRoutes+"/scheduled-exports/:exportId/run", domain.AuditEntityScheduledExport, "run", "exportId").
+			Audit(http.MethodPost, adminRoutes+"/marketing-sync/:provider/resync", domain.AuditEntityMarketingSync, "resync", "").
+			Audit(http.MethodPost, adminRoutes+"/back-in-stock/mark-notified", domain.AuditEntityBackInStock, domain.AuditActionBulk, "").
+			Audit(http.MethodPost, adminRoutes+"/back-in-stock/test-notification", domain.AuditEntityBackInStock, "test_notification", "").
+			Audit(http.MethodDelete, adminRoutes+"/back-in-stock/cleanup", domain.AuditEntityBackInStock, domain.AuditActionBulk, "").
+			Ignore(http.MethodPost, adminRoutes+"/customers/:id/merge/preview").
+			Ignore(http.MethodPost, adminRoutes+"/segments/rules/simulate")
+
 		// Admin routes (require admin middleware)
 		admin := v1.Group("/admin")
-		admin.Use(middleware.AuthMiddleware(cfg.JWT.Secret))
+		admin.Use(authMiddleware)
+		admin.Use(middleware.BlockImpersonation())
 		admin.Use(libmiddleware.RequireAdmin())
-		{
-			// Customer management
-			adminCustomers := admin.Group("/customers")
-			{
-				adminCustomers.GET("", adminCustomerHandler.GetCustomers)
-				adminCustomers.GET("/stats", adminCustomerHandler.GetCustomerStats)
-				adminCustomers.GET("/export", adminCustomerHandler.ExportCustomers)
-				adminCustomers.POST("", adminCustomerHandler.CreateCustomer)
-				adminCustomers.GET("/:id", adminCustomerHandler.GetCustomer)
-				adminCustomers.PUT("/:id", adminCustomerHandler.UpdateCustomer)
-				adminCustomers.DELETE("/:id", adminCustomerHandler.DeleteCustomer)
-				adminCustomers.GET("/:id/orders", adminCustomerHandler.GetCustomerOrders)
-				adminCustomers.GET("/:id/notes", adminCustomerHandler.GetCustomerNotes)
-				adminCustomers.POST("/:id/notes", adminCustomerHandler.AddCustomerNote)
-				adminCustomers.GET("/:id/activity", adminCustomerHandler.GetCustomerActivity)
-				adminCustomers.POST("/:id/segments", adminCustomerHandler.AssignSegment)
-			}
+		admin.Use(permissionPolicy.Middleware())
+		admin.Use(rateLimiter.Middleware())
+		admin.Use(auditTrail.Middleware())
+		registerAdminRoutes(admin, adminHandlers{
+			activityHandler:        adminActivityHandler,
+			addressHandler:         adminAddressHandler,
+			analyticsHandler:       adminAnalyticsHandler,
+			auditHandler:           adminAuditHandler,
+			backInStockHandler:     adminBackInStockHandler,
+			blocklistHandler:       adminBlocklistHandler,
+			customerHandler:        adminCustomerHandler,
+			customerLimitHandler:   adminCustomerLimitHandler,
+			duplicateHandler:       adminDuplicateHandler,
+			impersonationHandler:   adminImpersonationHandler,
+			marketingSyncHandler:   adminMarketingSyncHandler,
+			mergeHandler:           adminMergeHandler,
+			permissionHandler:      adminPermissionHandler,
+			regionHandler:          adminRegionHandler,
+			scheduledExportHandler: adminScheduledExportHandler,
+			segmentBulkHandler:     adminSegmentBulkHandler,
+			segmentHistoryHandler:  adminSegmentHistoryHandler,
+			segmentRuleHandler:     adminSegmentRuleHandler,
+			systemHandler:          adminSystemHandler,
+			walletHandler:          adminWalletHandler,
+			webhookHandler:         adminWebhookHandler,
+			regionScope:            middleware.RegionScopeMiddleware(adminRegionRepo, cfg.Region.ScopedRoles),
+			customerInRegion:       middleware.CustomerInRegion(customerRepo),
+			idempotency:            idempotency,
+		})
+	}
 
-			// Segment management
-			segments := admin.Group("/segments")
-			{
-				segments.GET("", adminCustomerHandler.GetSegments)
-				segments.POST("", adminCustomerHandler.CreateSegment)
-				segments.PUT("/:id", adminCustomerHandler.UpdateSegment)
-				segments.DELETE("/:id", adminCustomerHandler.DeleteSegment)
-			}
+	// Internal routes (service-to-service, per-service tokens)
+	if cfg.Internal.APIKey != "" {
+		log.Println("⚠️  Warning: INTERNAL_API_KEY is deprecated; give each calling service a token in INTERNAL_SERVICE_TOKENS")
+	}
+	internal := router.Group("/internal/v1")
+	internal.Use(middleware.ServiceAuthMiddleware(cfg.Internal.ServiceTokens, cfg.Internal.APIKey))
+	{
+		// Store credit reservations used by the order service
+		internal.POST("/wallet/reservations", internalWalletHandler.Reserve)
+		internal.POST("/wallet/reservations/:id/capture", internalWalletHandler.Capture)
+		internal.POST("/wallet/reservations/:id/release", internalWalletHandler.Release)
 
-			// Back-in-Stock Admin (HI-001)
-			backInStock := admin.Group("/back-in-stock")
-			{
-				backInStock.GET("/stats", adminBackInStockHandler.GetStats)
-				backInStock.GET("/subscriptions", adminBackInStockHandler.ListSubscriptions)
-				backInStock.GET("/products/:productId/subscriptions", adminBackInStockHandler.GetByProduct)
-				backInStock.POST("/mark-notified", adminBackInStockHandler.MarkAsNotified)
-				backInStock.DELETE("/cleanup", adminBackInStockHandler.Cleanup)
-			}
-		}
+		// Blocklist check run by the order service before accepting an order
+		internal.POST("/customers/fraud-check", internalFraudCheckHandler.Check)
+
+		// Notification preference check for notifications sent by other
+		// services, such as product review reminders
+		internal.POST("/customers/notification-check", internalNotificationCheckHandler.Check)
+
+		// Customer reads for other services: names on order lists and
+		// birthday greetings
+		internal.POST("/customers/batch", internalCustomerLookupHandler.GetBatch)
+		internal.GET("/customers/birthdays", internalCustomerLookupHandler.Birthdays)
+	}
+
+	for _, route := range permissionPolicy.Unmapped(router.Routes(), "/api/v1/admin") {
+		log.Printf("⚠️  Warning: %s has no permission in adminPermissionPolicy; it is denied to everyone", route)
 	}
 
 	// Start server
@@ -298,7 +1147,7 @@ func main() {
 
 	srv := &http.Server{
 		Addr:         ":" + port,
-		Handler:      router,
+		Handler:      middleware.CanonicalJSONAlias(router, "/api/v2", "/api/v1"),
 		ReadTimeout:  15 * time.Second,
 		WriteTimeout: 15 * time.Second,
 		IdleTimeout:  60 * time.Second,
@@ -317,6 +1166,20 @@ func main() {
 	<-quit
 
 	log.Println("Shutting down server...")
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	// In-flight requests finish first, since they may still queue analytics
+	// events and publish to NATS
+	if err := srv.Shutdown(ctx); err != nil {
+		log.Printf("⚠️  Server forced to shutdown: %v", err)
+	}
+	stopJobs()
+
+	// Publish what analytics events are still queued before NATS goes away
+	if wishlistAnalytics != nil {
+		wishlistAnalytics.Wait()
+	}
 
 	// HI-001: Close NATS connection
 	if natsClient != nil {
@@ -324,11 +1187,8 @@ func main() {
 		log.Println("NATS connection closed")
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
-
-	if err := srv.Shutdown(ctx); err != nil {
-		log.Fatalf("Server forced to shutdown: %v", err)
+	if err := shutdownTracing(ctx); err != nil {
+		log.Printf("⚠️  Failed to flush traces: %v", err)
 	}
 
 	log.Println("Server exited")
@@ -341,3 +1201,79 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+// warmQueries runs the queries behind the busiest customer endpoints once, so
+// their plans (and prepared statements when DB_PREPARE_STATEMENTS is on) are
+// ready before the first real request. A random ID matches no rows.
+func warmQueries(db *gorm.DB) func(ctx context.Context) error {
+	id := uuid.New()
+	return warmup.All(
+		func(ctx context.Context) error {
+			_, err := persistence.NewProfileRepository(db).GetByUserID(ctx, id)
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil
+			}
+			return err
+		},
+		func(ctx context.Context) error {
+			_, err := persistence.NewAddressRepository(db).ListByUserID(ctx, id)
+			return err
+		},
+		func(ctx context.Context) error {
+			_, err := persistence.NewWishlistRepository(db).ListByUserID(ctx, id)
+			return err
+		},
+		func(ctx context.Context) error {
+			_, _, err := persistence.NewWalletRepository(db).ListTransactions(ctx, id, 1, 20)
+			return err
+		},
+	)
+}
+
+// jetStreamConsumer converts a configured consumer to the events package's consumer
+func jetStreamConsumer(consumer config.JetStreamConsumerConfig) events.JetStreamConsumer {
+	return events.JetStreamConsumer{
+		Stream:            consumer.Stream,
+		Durable:           consumer.Durable,
+		MaxDeliver:        consumer.MaxDeliver,
+		Backoff:           consumer.Backoff,
+		DeadLetterSubject: consumer.DeadLetterSubject,
+	}
+}
+
+// sloObjective converts a configured objective (percentages) to a metrics objective (fractions)
+func sloObjective(objective config.SLOObjective) metrics.Objective {
+	return metrics.Objective{
+		Availability:     objective.Availability / 100,
+		LatencyThreshold: objective.LatencyThreshold,
+		LatencyTarget:    objective.LatencyTarget / 100,
+	}
+}
+
+// marketingProviders creates the configured marketing platforms and returns
+// them with their names, which the sync queue is kept for
+func marketingProviders(cfg config.MarketingSyncConfig) ([]marketingsync.Provider, []string, error) {
+	providers, err := marketingsync.New(marketingsync.Config{
+		Providers: cfg.Providers,
+		Mailchimp: marketingsync.ProviderConfig{
+			APIKey:   cfg.MailchimpAPIKey,
+			ListID:   cfg.MailchimpListID,
+			FieldMap: cfg.MailchimpFieldMap,
+		},
+		Klaviyo: marketingsync.ProviderConfig{
+			APIKey:   cfg.KlaviyoAPIKey,
+			ListID:   cfg.KlaviyoListID,
+			FieldMap: cfg.KlaviyoFieldMap,
+		},
+		Timeout: cfg.Timeout,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	names := make([]string, 0, len(providers))
+	for _, provider := range providers {
+		names = append(names, provider.Name())
+	}
+	return providers, names, nil
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/persistence"
+	"gorm.io/gorm/logger"
+)
+
+// newMigrateCommand runs the schema changes too slow or lock-heavy to run at
+// server boot: indexes on large tables are built with CREATE INDEX
+// CONCURRENTLY and backfills update rows in small batches, saving progress so
+// an interrupted run picks up where it stopped. It is safe to run repeatedly.
+func newMigrateCommand() *cobra.Command {
+	var (
+		batchSize     int
+		pause         time.Duration
+		skipIndexes   bool
+		skipBackfills bool
+	)
+	cmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "Migrate the schema, build indexes concurrently and run backfills",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if batchSize <= 0 {
+				return errors.New("batch-size must be positive")
+			}
+
+			cfg := loadConfig()
+			db, err := openDatabase(cfg, logger.Warn)
+			if err != nil {
+				return fmt.Errorf("connect to database: %w", err)
+			}
+			if err := migrateSchema(db); err != nil {
+				return fmt.Errorf("migrate database: %w", err)
+			}
+
+			ctx := cmd.Context()
+			if !skipIndexes {
+				for _, index := range persistence.OnlineIndexes() {
+					log.Printf("Building index %s on %s", index.Name, index.Table)
+					started := time.Now()
+					if err := persistence.CreateIndexConcurrently(ctx, db, index); err != nil {
+						return fmt.Errorf("build index %s: %w", index.Name, err)
+					}
+					log.Printf("Index %s ready (%s)", index.Name, time.Since(started).Round(time.Millisecond))
+				}
+			}
+
+			if !skipBackfills {
+				if err := db.AutoMigrate(&persistence.BackfillProgress{}); err != nil {
+					return fmt.Errorf("create backfill progress table: %w", err)
+				}
+				runner := persistence.NewBackfillRunner(db, batchSize, pause).
+					OnBatch(func(p persistence.BackfillProgress) {
+						log.Printf("Backfill %s: %d rows updated (last key %s)", p.Name, p.Rows, p.LastKey)
+					})
+				for _, backfill := range persistence.Backfills(cfg.BackInStock.SubscriptionTTL) {
+					log.Printf("Running backfill %s", backfill.Name)
+					progress, err := runner.Run(ctx, backfill)
+					if err != nil {
+						return fmt.Errorf("backfill %s stopped after %d rows: %w", backfill.Name, progress.Rows, err)
+					}
+					log.Printf("Backfill %s complete: %d rows updated", backfill.Name, progress.Rows)
+				}
+			}
+
+			log.Println("✅ Migrations complete")
+			return nil
+		},
+	}
+	cmd.Flags().IntVar(&batchSize, "batch-size", 1000, "rows updated per backfill batch")
+	cmd.Flags().DurationVar(&pause, "pause", 100*time.Millisecond, "delay between backfill batches to spare the database")
+	cmd.Flags().BoolVar(&skipIndexes, "skip-indexes", false, "don't build indexes")
+	cmd.Flags().BoolVar(&skipBackfills, "skip-backfills", false, "don't run backfills")
+	return cmd
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/Ecom-micro-template/service-customer/internal/middleware"
+)
+
+// loadRolePermissions reads the permissions of each role from the JSON file
+// at path ({"ROLE": ["customers:read", ...]}), or returns the defaults when
+// path is empty. The file replaces the defaults entirely.
+func loadRolePermissions(path string) (map[string][]string, error) {
+	if path == "" {
+		return domain.DefaultRolePermissions, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var roles map[string][]string
+	if err := json.Unmarshal(data, &roles); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	if err := domain.ValidateRolePermissions(roles); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return roles, nil
+}
+
+// adminPermissionPolicy is the permission each admin route requires. Every
+// route registered under the admin group must be listed here; unlisted
+// routes are denied and reported at startup.
+func adminPermissionPolicy(adminRoutes string, roles map[string][]string) *middleware.PermissionPolicy {
+	customers := adminRoutes + "/customers"
+	return middleware.NewPermissionPolicy(roles).
+		Allow(http.MethodGet, adminRoutes+"/permissions").
+		Require(http.MethodGet, adminRoutes+"/audit-logs", domain.PermissionAuditRead).
+
+		// Customer management
+		Require(http.MethodGet, customers, domain.PermissionCustomersRead).
+		Require(http.MethodGet, customers+"/stats", domain.PermissionCustomersRead).
+		Require(http.MethodGet, customers+"/analytics/cohorts", domain.PermissionCustomersRead).
+		Require(http.MethodGet, customers+"/analytics/growth", domain.PermissionCustomersRead).
+		Require(http.MethodGet, customers+"/duplicates", domain.PermissionDuplicatesReview).
+		Require(http.MethodPost, customers+"/duplicates/:candidateId/dismiss", domain.PermissionDuplicatesReview).
+		Require(http.MethodGet, customers+"/export", domain.PermissionCustomersExport).
+		Require(http.MethodPost, customers+"/exports", domain.PermissionCustomersExport).
+		Require(http.MethodGet, customers+"/exports/:exportId", domain.PermissionCustomersExport).
+		Require(http.MethodGet, customers+"/lookup", domain.PermissionCustomersRead).
+		Require(http.MethodGet, customers+"/tags", domain.PermissionCustomersRead).
+		Allow(http.MethodGet, customers+"/columns").
+		Allow(http.MethodPut, customers+"/columns").
+		Allow(http.MethodDelete, customers+"/columns").
+		Allow(http.MethodGet, customers+"/views").
+		Allow(http.MethodPost, customers+"/views").
+		Allow(http.MethodGet, customers+"/views/:viewId").
+		Allow(http.MethodPut, customers+"/views/:viewId").
+		Allow(http.MethodDelete, customers+"/views/:viewId").
+		Require(http.MethodPost, customers, domain.PermissionCustomersWrite).
+		Require(http.MethodGet, customers+"/:id", domain.PermissionCustomersRead).
+		Require(http.MethodPut, customers+"/:id", domain.PermissionCustomersWrite).
+		Require(http.MethodDelete, customers+"/:id", domain.PermissionCustomersDelete).
+		Require(http.MethodPost, customers+"/:id/reveal", domain.PermissionCustomersRevealPII).
+		Require(http.MethodGet, customers+"/:id/orders", domain.PermissionCustomersRead).
+		Require(http.MethodGet, customers+"/:id/notes", domain.PermissionCustomersRead).
+		Require(http.MethodGet, customers+"/:id/notes/count", domain.PermissionCustomersRead).
+		Require(http.MethodPost, customers+"/:id/notes", domain.PermissionCustomersWrite).
+		Require(http.MethodPut, customers+"/:id/notes/:noteId", domain.PermissionCustomersWrite).
+		Require(http.MethodDelete, customers+"/:id/notes/:noteId", domain.PermissionCustomersWrite).
+		Require(http.MethodPost, customers+"/:id/notes/:noteId/pin", domain.PermissionCustomersWrite).
+		Require(http.MethodDelete, customers+"/:id/notes/:noteId/pin", domain.PermissionCustomersWrite).
+		Require(http.MethodPost, customers+"/:id/notes/:noteId/attachments", domain.PermissionCustomersWrite).
+		Require(http.MethodDelete, customers+"/:id/notes/:noteId/attachments/:attachmentId", domain.PermissionCustomersWrite).
+		Require(http.MethodGet, customers+"/:id/tags", domain.PermissionCustomersRead).
+		Require(http.MethodPost, customers+"/:id/tags", domain.PermissionCustomersWrite).
+		Require(http.MethodDelete, customers+"/:id/tags/:tag", domain.PermissionCustomersWrite).
+		Require(http.MethodGet, customers+"/:id/activity", domain.PermissionCustomersRead).
+		Require(http.MethodGet, customers+"/:id/timeline", domain.PermissionCustomersRead).
+		Require(http.MethodGet, customers+"/:id/activity/pinned", domain.PermissionCustomersRead).
+		Require(http.MethodPost, customers+"/:id/activity/:activityId/pin", domain.PermissionCustomersWrite).
+		Require(http.MethodDelete, customers+"/:id/activity/:activityId/pin", domain.PermissionCustomersWrite).
+		Require(http.MethodPost, customers+"/:id/segments", domain.PermissionCustomersWrite).
+		Require(http.MethodPost, customers+"/:id/merge/preview", domain.PermissionCustomersRead).
+		Require(http.MethodPost, customers+"/:id/merge", domain.PermissionCustomersDelete).
+		Require(http.MethodPost, customers+"/:id/impersonate", domain.PermissionCustomersImpersonate).
+		Require(http.MethodGet, customers+"/:id/impersonations", domain.PermissionCustomersRead).
+		Require(http.MethodGet, customers+"/:id/addresses/deleted", domain.PermissionCustomersRead).
+		Require(http.MethodGet, customers+"/:id/wallet", domain.PermissionCustomersRead).
+		Require(http.MethodPost, customers+"/:id/wallet/credit", domain.PermissionWalletManage).
+		Require(http.MethodPost, customers+"/:id/wallet/debit", domain.PermissionWalletManage).
+		Require(http.MethodGet, customers+"/:id/limits", domain.PermissionCustomersRead).
+		Require(http.MethodPut, customers+"/:id/limits", domain.PermissionCustomersWrite).
+		Require(http.MethodDelete, customers+"/:id/limits", domain.PermissionCustomersWrite).
+
+		// Activity feed across all customers
+		Require(http.MethodGet, adminRoutes+"/activity", domain.PermissionActivityRead).
+		Require(http.MethodGet, adminRoutes+"/activity/export", domain.PermissionCustomersExport).
+
+		// Segments
+		Require(http.MethodGet, adminRoutes+"/segments", domain.PermissionCustomersRead).
+		Require(http.MethodGet, adminRoutes+"/segments/rules", domain.PermissionCustomersRead).
+		Require(http.MethodPost, adminRoutes+"/segments/rules", domain.PermissionSegmentsManage).
+		Require(http.MethodPost, adminRoutes+"/segments/rules/simulate", domain.PermissionSegmentsManage).
+		Require(http.MethodDelete, adminRoutes+"/segments/rules/:ruleId", domain.PermissionSegmentsManage).
+		Require(http.MethodPost, adminRoutes+"/segments", domain.PermissionSegmentsManage).
+		Require(http.MethodPost, adminRoutes+"/segments/preview", domain.PermissionSegmentsManage).
+		Require(http.MethodPut, adminRoutes+"/segments/:id", domain.PermissionSegmentsManage).
+		Require(http.MethodDelete, adminRoutes+"/segments/:id", domain.PermissionSegmentsManage).
+		Require(http.MethodPost, adminRoutes+"/segments/:id/customers/bulk", domain.PermissionSegmentsManage).
+		Require(http.MethodGet, adminRoutes+"/segments/:id/customers/bulk/:jobId", domain.PermissionSegmentsManage).
+		Require(http.MethodGet, adminRoutes+"/segments/:id/history", domain.PermissionCustomersRead).
+
+		// Sales regions and impersonation sessions
+		Require(http.MethodGet, adminRoutes+"/region-assignments/:adminId", domain.PermissionRegionsManage).
+		Require(http.MethodPut, adminRoutes+"/region-assignments/:adminId", domain.PermissionRegionsManage).
+		Require(http.MethodGet, adminRoutes+"/impersonations/:sessionId/requests", domain.PermissionCustomersRead).
+		Require(http.MethodDelete, adminRoutes+"/impersonations/:sessionId", domain.PermissionCustomersImpersonate).
+
+		// Fraud blocklist
+		Require(http.MethodGet, adminRoutes+"/blocklist", domain.PermissionBlocklistManage).
+		Require(http.MethodPost, adminRoutes+"/blocklist", domain.PermissionBlocklistManage).
+		Require(http.MethodDelete, adminRoutes+"/blocklist/:entryId", domain.PermissionBlocklistManage).
+
+		// Webhook subscriptions
+		Require(http.MethodGet, adminRoutes+"/webhooks", domain.PermissionWebhooksManage).
+		Require(http.MethodPost, adminRoutes+"/webhooks", domain.PermissionWebhooksManage).
+		Require(http.MethodGet, adminRoutes+"/webhooks/:webhookId", domain.PermissionWebhooksManage).
+		Require(http.MethodPut, adminRoutes+"/webhooks/:webhookId", domain.PermissionWebhooksManage).
+		Require(http.MethodDelete, adminRoutes+"/webhooks/:webhookId", domain.PermissionWebhooksManage).
+		Require(http.MethodGet, adminRoutes+"/webhooks/:webhookId/deliveries", domain.PermissionWebhooksManage).
+		Require(http.MethodGet, adminRoutes+"/webhooks/:webhookId/deliveries/:deliveryId", domain.PermissionWebhooksManage).
+		Require(http.MethodPost, adminRoutes+"/webhooks/:webhookId/deliveries/:deliveryId/redeliver", domain.PermissionWebhooksManage).
+
+		// Scheduled customer exports
+		Require(http.MethodGet, adminRoutes+"/scheduled-exports", domain.PermissionCustomersExport).
+		Require(http.MethodPost, adminRoutes+"/scheduled-exports", domain.PermissionCustomersExport).
+		Require(http.MethodGet, adminRoutes+"/scheduled-exports/:exportId", domain.PermissionCustomersExport).
+		Require(http.MethodPut, adminRoutes+"/scheduled-exports/:exportId", domain.PermissionCustomersExport).
+		Require(http.MethodDelete, adminRoutes+"/scheduled-exports/:exportId", domain.PermissionCustomersExport).
+		Require(http.MethodGet, adminRoutes+"/scheduled-exports/:exportId/runs", domain.PermissionCustomersExport).
+		Require(http.MethodPost, adminRoutes+"/scheduled-exports/:exportId/run", domain.PermissionCustomersExport).
+
+		// Email marketing platform sync
+		Require(http.MethodGet, adminRoutes+"/marketing-sync", domain.PermissionMarketingManage).
+		Require(http.MethodGet, adminRoutes+"/marketing-sync/:provider/contacts", domain.PermissionMarketingManage).
+		Require(http.MethodPost, adminRoutes+"/marketing-sync/:provider/resync", domain.PermissionMarketingManage).
+
+		// System status
+		Require(http.MethodGet, adminRoutes+"/system/slo", domain.PermissionSystemRead).
+		Require(http.MethodGet, adminRoutes+"/system/notifications", domain.PermissionSystemRead).
+		Require(http.MethodGet, adminRoutes+"/system/legacy-crm/drift", domain.PermissionSystemRead).
+
+		// Back-in-stock
+		Require(http.MethodGet, adminRoutes+"/back-in-stock/stats", domain.PermissionBackInStockManage).
+		Require(http.MethodGet, adminRoutes+"/back-in-stock/subscriptions", domain.PermissionBackInStockManage).
+		Require(http.MethodGet, adminRoutes+"/back-in-stock/export", domain.PermissionBackInStockManage).
+		Require(http.MethodGet, adminRoutes+"/back-in-stock/demand", domain.PermissionBackInStockManage).
+		Require(http.MethodGet, adminRoutes+"/back-in-stock/products/:productId/subscriptions", domain.PermissionBackInStockManage).
+		Require(http.MethodPost, adminRoutes+"/back-in-stock/products/:productId/notify", domain.PermissionBackInStockManage).
+		Require(http.MethodPost, adminRoutes+"/back-in-stock/mark-notified", domain.PermissionBackInStockManage).
+		Require(http.MethodPost, adminRoutes+"/back-in-stock/test-notification", domain.PermissionBackInStockManage).
+		Require(http.MethodDelete, adminRoutes+"/back-in-stock/cleanup", domain.PermissionBackInStockManage)
+}
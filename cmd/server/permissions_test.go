@@ -0,0 +1,50 @@
+package main
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testAdminRoutes = "/api/v1/admin"
+
+// adminRoutes registers the admin API as serve does, with nothing behind
+// the routes, and returns them
+func adminRoutes(t *testing.T) gin.RoutesInfo {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	pass := func(c *gin.Context) { c.Next() }
+	registerAdminRoutes(router.Group(testAdminRoutes), adminHandlers{
+		regionScope:      pass,
+		customerInRegion: pass,
+		idempotency:      pass,
+	})
+	routes := router.Routes()
+	require.NotEmpty(t, routes)
+	return routes
+}
+
+func TestAdminPermissionPolicy_CoversEveryAdminRoute(t *testing.T) {
+	policy := adminPermissionPolicy(testAdminRoutes, domain.DefaultRolePermissions)
+	assert.Empty(t, policy.Unmapped(adminRoutes(t), testAdminRoutes),
+		"every admin route needs an entry in adminPermissionPolicy")
+}
+
+func TestAdminPermissionPolicy_ListsOnlyRegisteredRoutes(t *testing.T) {
+	registered := make(map[string]bool)
+	for _, route := range adminRoutes(t) {
+		registered[route.Method+" "+route.Path] = true
+	}
+
+	for _, route := range adminPermissionPolicy(testAdminRoutes, domain.DefaultRolePermissions).Routes() {
+		key := route.Method + " " + route.Route
+		assert.True(t, registered[key], "%s is in adminPermissionPolicy but not registered", key)
+		if route.Permission != "" {
+			assert.True(t, slices.Contains(domain.Permissions, route.Permission), "%s requires unknown permission %q", key, route.Permission)
+		}
+	}
+}
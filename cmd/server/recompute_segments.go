@@ -0,0 +1,82 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/persistence"
+	"github.com/Ecom-micro-template/service-customer/internal/jobs"
+	"go.uber.org/zap"
+	"gorm.io/gorm/logger"
+)
+
+// newRecomputeSegmentsCommand re-applies the segment rules to every customer,
+// for instance after rules were added or changed, which otherwise only take
+// effect on a customer's next order or status change
+func newRecomputeSegmentsCommand() *cobra.Command {
+	var (
+		triggers  []string
+		batchSize int
+		dryRun    bool
+	)
+	cmd := &cobra.Command{
+		Use:   "recompute-segments",
+		Short: "Re-apply the segment rules to every customer",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if batchSize <= 0 {
+				return errors.New("batch-size must be positive")
+			}
+			valid := []string{domain.SegmentTriggerOrderCompleted, domain.SegmentTriggerStatusChanged, domain.SegmentTriggerRFMScored}
+			for _, trigger := range triggers {
+				if !slices.Contains(valid, trigger) {
+					return fmt.Errorf("unknown trigger %q; use one of %s", trigger, strings.Join(valid, ", "))
+				}
+			}
+
+			cfg := loadConfig()
+			db, err := openDatabase(cfg, logger.Warn)
+			if err != nil {
+				return fmt.Errorf("connect to database: %w", err)
+			}
+			// Changed customers are queued for the marketing sync job of the server
+			_, marketingNames, err := marketingProviders(cfg.Marketing)
+			if err != nil {
+				log.Printf("Marketing sync disabled: %v", err)
+			}
+			marketingSync := persistence.NewMarketingSyncRepository(db, marketingNames)
+
+			result, err := jobs.NewSegmentRecomputeJob(
+				persistence.NewCustomerRepository(db, queryTimeouts(cfg.Database)),
+				persistence.NewSegmentRuleRepository(db).WithWebhooks().WithMarketingSync(marketingSync),
+				zap.NewNop(),
+			).WithTriggers(triggers).
+				WithBatchSize(batchSize).
+				WithDryRun(dryRun).
+				OnPage(func(r jobs.SegmentRecomputeResult) {
+					log.Printf("Recomputed segments of %d customers", r.Customers)
+				}).
+				Recompute(cmd.Context())
+			if err != nil {
+				return fmt.Errorf("stopped after %d customers: %w", result.Customers, err)
+			}
+
+			if dryRun {
+				log.Printf("✅ Dry run: %d segment decisions for %d customers, nothing changed", result.Decisions, result.Customers)
+			} else {
+				log.Printf("✅ Applied %d segment decisions to %d customers", result.Decisions, result.Customers)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringSliceVar(&triggers, "trigger", []string{domain.SegmentTriggerOrderCompleted, domain.SegmentTriggerStatusChanged, domain.SegmentTriggerRFMScored},
+		"rule triggers to evaluate, in order")
+	cmd.Flags().IntVar(&batchSize, "batch-size", 500, "customers loaded per page")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "evaluate the rules without changing assignments")
+	return cmd
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/Ecom-micro-template/service-customer/internal/config"
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/encryption"
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/persistence"
+	"gorm.io/gorm/logger"
+)
+
+// columnKeyring loads the keys of the encrypted PII columns from
+// PII_ENCRYPTION_KEYS, or the file at PII_ENCRYPTION_KEYS_FILE, and the
+// blind index key from PII_BLIND_INDEX_KEY
+func columnKeyring(cfg config.EncryptionConfig) (*encryption.Keyring, error) {
+	value := cfg.Keys
+	if cfg.KeysFile != "" {
+		data, err := os.ReadFile(cfg.KeysFile)
+		if err != nil {
+			return nil, err
+		}
+		value = string(data)
+	}
+	keys, err := encryption.ParseKeys(value)
+	if err != nil {
+		return nil, err
+	}
+	keyring, err := encryption.NewKeyring(keys, cfg.PrimaryKey)
+	if err != nil || !keyring.Enabled() {
+		return keyring, err
+	}
+	if cfg.IndexKey == "" {
+		return nil, errors.New("PII_BLIND_INDEX_KEY is required with PII_ENCRYPTION_KEYS")
+	}
+	indexKey, err := base64.StdEncoding.DecodeString(strings.TrimSpace(cfg.IndexKey))
+	if err != nil {
+		return nil, fmt.Errorf("PII_BLIND_INDEX_KEY: %w", err)
+	}
+	return keyring.WithIndexKey(indexKey)
+}
+
+// newReencryptPIICommand encrypts the PII columns still in plaintext, after
+// encryption is first enabled, or encrypted with an old key, after the
+// primary key is rotated. The old key can be removed once it completes.
+func newReencryptPIICommand() *cobra.Command {
+	var batchSize int
+	cmd := &cobra.Command{
+		Use:   "reencrypt-pii",
+		Short: "Encrypt the PII columns with the primary encryption key",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if batchSize <= 0 {
+				return errors.New("batch-size must be positive")
+			}
+
+			cfg := loadConfig()
+			keys, err := columnKeyring(cfg.Encryption)
+			if err != nil {
+				return fmt.Errorf("load encryption keys: %w", err)
+			}
+			if !keys.Enabled() {
+				return errors.New("PII_ENCRYPTION_KEYS is not set")
+			}
+			db, err := openDatabase(cfg, logger.Warn)
+			if err != nil {
+				return fmt.Errorf("connect to database: %w", err)
+			}
+			if err := migrateSchema(db); err != nil {
+				return fmt.Errorf("migrate database: %w", err)
+			}
+			ctx := cmd.Context()
+
+			// Birthdays must be read from the dates before they are encrypted
+			if err := db.AutoMigrate(&persistence.BackfillProgress{}); err != nil {
+				return fmt.Errorf("create backfill progress table: %w", err)
+			}
+			backfill := persistence.BirthdayBackfill()
+			if _, err := persistence.NewBackfillRunner(db, batchSize, 0).Run(ctx, backfill); err != nil {
+				return fmt.Errorf("backfill %s: %w", backfill.Name, err)
+			}
+
+			reencryptor := persistence.NewReencryptor(db, keys, batchSize).
+				OnBatch(func(column persistence.EncryptedColumn, rows int64) {
+					log.Printf("Re-encrypted %d rows of %s.%s", rows, column.Table, column.Column)
+				})
+			for _, column := range persistence.EncryptedColumns() {
+				started := time.Now()
+				rows, err := reencryptor.Run(ctx, column)
+				if err != nil {
+					return fmt.Errorf("re-encrypt %s.%s stopped after %d rows: %w", column.Table, column.Column, rows, err)
+				}
+				log.Printf("%s.%s done: %d rows re-encrypted (%s)", column.Table, column.Column, rows, time.Since(started).Round(time.Millisecond))
+			}
+
+			log.Printf("✅ PII columns encrypted with key %s", keys.PrimaryKeyID())
+			return nil
+		},
+	}
+	cmd.Flags().IntVar(&batchSize, "batch-size", 500, "rows rewritten per transaction")
+	return cmd
+}
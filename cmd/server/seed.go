@@ -0,0 +1,171 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/google/uuid"
+	"github.com/spf13/cobra"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/Ecom-micro-template/service-customer/internal/domain/shared"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/logger"
+)
+
+// seedNamespace derives fixed IDs for the sample rows, so seeding twice
+// leaves the data as it was
+var seedNamespace = uuid.NewSHA1(uuid.NameSpaceURL, []byte("https://github.com/Ecom-micro-template/service-customer/seed"))
+
+func seedID(name string) uuid.UUID {
+	return uuid.NewSHA1(seedNamespace, []byte(name))
+}
+
+// newSeedCommand inserts sample customers, profiles, addresses, segments and
+// a segment rule for local development. Existing rows are left alone.
+func newSeedCommand() *cobra.Command {
+	var force bool
+	cmd := &cobra.Command{
+		Use:   "seed",
+		Short: "Insert sample data for local development",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if os.Getenv("APP_ENV") == "production" && !force {
+				return errors.New("refusing to seed a production database; pass --force to do it anyway")
+			}
+
+			cfg := loadConfig()
+			db, err := openDatabase(cfg, logger.Warn)
+			if err != nil {
+				return fmt.Errorf("connect to database: %w", err)
+			}
+			if err := migrateSchema(db); err != nil {
+				return fmt.Errorf("migrate database: %w", err)
+			}
+			if err := seed(db.WithContext(cmd.Context())); err != nil {
+				return err
+			}
+			log.Println("✅ Sample data seeded")
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&force, "force", false, "seed even when APP_ENV is production")
+	return cmd
+}
+
+type seedCustomer struct {
+	key         string
+	firstName   string
+	lastName    string
+	email       string
+	phone       string
+	status      shared.CustomerStatus
+	totalOrders int
+	totalSpent  float64
+	city        string
+	state       string
+	postcode    string
+}
+
+var seedCustomers = []seedCustomer{
+	{"aisyah", "Aisyah", "Rahman", "aisyah@example.com", "+60123456701", shared.StatusActive, 12, 1840.50, "Kuala Lumpur", "Wilayah Persekutuan", "50450"},
+	{"daniel", "Daniel", "Tan", "daniel@example.com", "+60123456702", shared.StatusActive, 3, 215.00, "George Town", "Pulau Pinang", "10200"},
+	{"priya", "Priya", "Nair", "priya@example.com", "+60123456703", shared.StatusActive, 0, 0, "Johor Bahru", "Johor", "80000"},
+	{"farid", "Farid", "Ismail", "farid@example.com", "+60123456704", shared.StatusInactive, 1, 89.90, "Shah Alam", "Selangor", "40000"},
+	{"mei", "Mei Ling", "Wong", "meiling@example.com", "+60123456705", shared.StatusBlocked, 5, 640.00, "Kota Kinabalu", "Sabah", "88000"},
+}
+
+func seed(db *gorm.DB) error {
+	skipExisting := clause.OnConflict{DoNothing: true}
+	return db.Transaction(func(tx *gorm.DB) error {
+		vip := domain.CustomerSegment{
+			ID:          seedID("segment/vip"),
+			Name:        "VIP",
+			Description: "Customers who spent over RM 1,000",
+			Color:       "#d4af37",
+			IsActive:    true,
+		}
+		newsletter := domain.CustomerSegment{
+			ID:          seedID("segment/newsletter"),
+			Name:        "Newsletter",
+			Description: "Customers receiving the marketing newsletter",
+			Color:       "#3b82f6",
+			IsActive:    true,
+			IsMarketing: true,
+		}
+		if err := tx.Clauses(skipExisting).Create(&[]domain.CustomerSegment{vip, newsletter}).Error; err != nil {
+			return fmt.Errorf("seed segments: %w", err)
+		}
+
+		minSpent := 1000.0
+		rule := domain.SegmentRule{
+			ID:            seedID("segment-rule/vip"),
+			Name:          "VIP after RM 1,000 spent",
+			Trigger:       domain.SegmentTriggerOrderCompleted,
+			Action:        domain.SegmentActionAssign,
+			SegmentID:     &vip.ID,
+			MinTotalSpent: &minSpent,
+			IsActive:      true,
+		}
+		if err := tx.Clauses(skipExisting).Create(&rule).Error; err != nil {
+			return fmt.Errorf("seed segment rule: %w", err)
+		}
+
+		// The customers table is shared with other services and may not
+		// exist in a fresh local database
+		hasCustomers := tx.Migrator().HasTable(&domain.Customer{})
+		if !hasCustomers {
+			log.Println("⚠️  Table public.customers doesn't exist; seeding profiles and addresses only")
+		}
+
+		for _, c := range seedCustomers {
+			id := seedID("customer/" + c.key)
+			if hasCustomers {
+				customer := domain.Customer{
+					ID:          id,
+					Email:       c.email,
+					FirstName:   c.firstName,
+					LastName:    c.lastName,
+					Phone:       c.phone,
+					Status:      c.status,
+					TotalOrders: c.totalOrders,
+					TotalSpent:  c.totalSpent,
+				}
+				if err := tx.Clauses(skipExisting).Create(&customer).Error; err != nil {
+					return fmt.Errorf("seed customer %s: %w", c.email, err)
+				}
+			}
+
+			profile := domain.Profile{
+				ID:       id,
+				FullName: c.firstName + " " + c.lastName,
+				Email:    c.email,
+				Phone:    c.phone,
+			}
+			if err := tx.Clauses(skipExisting).Create(&profile).Error; err != nil {
+				return fmt.Errorf("seed profile %s: %w", c.email, err)
+			}
+
+			address := domain.Address{
+				ID:            seedID("address/" + c.key),
+				UserID:        id,
+				Label:         shared.AddressLabelHome,
+				RecipientName: profile.FullName,
+				Phone:         c.phone,
+				AddressLine1:  "1 Jalan Contoh",
+				City:          c.city,
+				State:         c.state,
+				Postcode:      c.postcode,
+				Country:       "MY",
+				IsDefault:     true,
+			}
+			if err := tx.Clauses(skipExisting).Create(&address).Error; err != nil {
+				return fmt.Errorf("seed address %s: %w", c.email, err)
+			}
+		}
+		log.Printf("Seeded %d customers, 2 segments and 1 segment rule", len(seedCustomers))
+		return nil
+	})
+}
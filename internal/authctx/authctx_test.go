@@ -0,0 +1,83 @@
+package authctx
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func testContext() (*gin.Context, *httptest.ResponseRecorder) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+	return c, w
+}
+
+func TestSet(t *testing.T) {
+	c, _ := testContext()
+	principal := &Principal{ID: uuid.New(), Role: "MANAGER"}
+	Set(c, principal)
+
+	assert.Same(t, principal, Get(c))
+	assert.Same(t, principal, FromContext(c.Request.Context()))
+	assert.Equal(t, principal.ID, UserID(c))
+	assert.Equal(t, "MANAGER", Role(c))
+
+	// Permissions granted later are seen through either context
+	principal.Permissions = []string{"customers:read"}
+	assert.True(t, FromContext(c.Request.Context()).HasPermission("customers:read"))
+}
+
+func TestPrincipal_HasRole(t *testing.T) {
+	principal := &Principal{Role: "Sales_Agent"}
+	assert.True(t, principal.HasRole("ADMIN", "SALES_AGENT"))
+	assert.False(t, principal.HasRole("ADMIN"))
+	assert.False(t, principal.HasRole())
+}
+
+func TestUnauthenticated(t *testing.T) {
+	c, _ := testContext()
+	// Only the principal counts, never a raw context key or header
+	c.Set("user_id", uuid.New())
+	c.Request.Header.Set("X-User-ID", uuid.NewString())
+
+	var principal *Principal
+	assert.Nil(t, Get(c))
+	assert.Nil(t, FromContext(context.Background()))
+	assert.Equal(t, uuid.Nil, UserID(c))
+	assert.Empty(t, Role(c))
+	assert.False(t, principal.HasRole("ADMIN"))
+	assert.False(t, principal.HasPermission("customers:read"))
+}
+
+func TestAuthenticatedUserID(t *testing.T) {
+	for name, principal := range map[string]*Principal{
+		"not set":  nil,
+		"nil UUID": {ID: uuid.Nil, Role: "ADMIN"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			c, w := testContext()
+			if principal != nil {
+				Set(c, principal)
+			}
+
+			_, ok := AuthenticatedUserID(c)
+			assert.False(t, ok)
+			assert.Equal(t, http.StatusUnauthorized, w.Code)
+		})
+	}
+
+	c, w := testContext()
+	user := uuid.New()
+	Set(c, &Principal{ID: user})
+	userID, ok := AuthenticatedUserID(c)
+	assert.True(t, ok)
+	assert.Equal(t, user, userID)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
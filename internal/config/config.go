@@ -4,17 +4,44 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
 // Config holds all configuration for the application
 type Config struct {
-	Server   ServerConfig
-	Database DatabaseConfig
-	JWT      JWTConfig
-	NATS     NATSConfig
-	Sentry   SentryConfig
+	Server       ServerConfig
+	Database     DatabaseConfig
+	JWT          JWTConfig
+	NATS         NATSConfig
+	Sentry       SentryConfig
+	Tracing      TracingConfig
+	Internal     InternalConfig
+	Address      AddressValidationConfig
+	LoadShed     LoadShedConfig
+	RateLimit    RateLimitConfig
+	SLO          SLOConfig
+	BackInStock  BackInStockConfig
+	Warmup       WarmupConfig
+	Scheduler    SchedulerConfig
+	Notification NotificationConfig
+	Region       RegionConfig
+	Permissions  PermissionsConfig
+	Encryption   EncryptionConfig
+	LegacyCRM    LegacyCRMConfig
+	Wishlist     WishlistConfig
+	RecentViews  RecentViewsConfig
+	Limits       CustomerLimitsConfig
+	Idempotency  IdempotencyConfig
+	Storage      StorageConfig
+	Attachments  AttachmentConfig
+	Avatar       AvatarConfig
+	Exports      ExportJobConfig
+	Webhooks     WebhookConfig
+	Marketing    MarketingSyncConfig
 }
 
 // SentryConfig holds Sentry error tracking configuration
@@ -24,6 +51,14 @@ type SentryConfig struct {
 	Release     string
 }
 
+// TracingConfig holds OpenTelemetry tracing configuration. Tracing is off
+// without an OTLP endpoint.
+type TracingConfig struct {
+	Endpoint    string // OTLP/HTTP collector, e.g. http://otel-collector:4318
+	ServiceName string
+	SampleRatio float64 // fraction of new traces sampled; incoming sampled traces are always kept
+}
+
 // ServerConfig holds server configuration
 type ServerConfig struct {
 	Port string
@@ -32,22 +67,325 @@ type ServerConfig struct {
 
 // DatabaseConfig holds database configuration
 type DatabaseConfig struct {
-	Host     string
-	Port     string
-	User     string
-	Password string
-	DBName   string
-	SSLMode  string
+	Host        string
+	Port        string
+	User        string
+	Password    string
+	DBName      string
+	SSLMode     string
+	PrepareStmt bool // cache prepared statements per query
+
+	// ReplicaDSN is a read replica for admin listings, exports, stats and
+	// analytics; empty sends them to the primary
+	ReplicaDSN string
+
+	// Connection pool of each database, the primary and the replica alike
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+
+	// SlowQueryThreshold is the run time past which a query is logged, with
+	// its bound parameters left out; zero disables the log
+	SlowQueryThreshold time.Duration
+
+	// Per query class timeouts of the customer repository
+	LookupTimeout time.Duration
+	ListTimeout   time.Duration
+	ReportTimeout time.Duration
+	ExportTimeout time.Duration
 }
 
 // JWTConfig holds JWT configuration
 type JWTConfig struct {
 	Secret string
+
+	// Identity provider tokens, verified with the RSA keys published at
+	// JWKSURL; disabled when empty
+	JWKSURL      string
+	JWKSCacheTTL time.Duration
+	Issuer       string
+	Audience     string
+	ClockSkew    time.Duration
+	Algorithms   []string
+}
+
+// InternalConfig holds configuration for service-to-service endpoints
+type InternalConfig struct {
+	// ServiceTokens are the tokens of the services allowed to call internal
+	// endpoints, by service name
+	ServiceTokens map[string]string
+
+	// APIKey is the shared key used before service tokens; still accepted
+	// until every caller has its own token
+	APIKey string
+}
+
+// AddressValidationConfig holds address validation/geocoding provider configuration
+type AddressValidationConfig struct {
+	Provider         string // "google" or "none"
+	APIKey           string
+	AllowedCountries []string // ISO codes or names; empty allows every country
+}
+
+// LoadShedConfig holds overload protection configuration
+type LoadShedConfig struct {
+	MaxConcurrent int // 0 disables load shedding
+	MaxQueue      int
+	QueueTimeout  time.Duration
+	RetryAfter    time.Duration
+}
+
+// RateLimitConfig holds per-client request limits; a 0 rate disables that limit
+type RateLimitConfig struct {
+	ReadPerMinute  int
+	ReadBurst      int
+	WritePerMinute int
+	WriteBurst     int
+	// Guest back-in-stock signups, which send a confirmation email
+	GuestSignupPerMinute int
+	GuestSignupBurst     int
+}
+
+// WishlistConfig holds wishlist configuration
+type WishlistConfig struct {
+	StockCacheTTL time.Duration // how long stock badges are cached per item
+
+	// Item added/removed events for the analytics pipeline, published in
+	// batches in the background
+	AnalyticsEvents        bool
+	AnalyticsQueueSize     int // events waiting to be published; more are dropped
+	AnalyticsBatchSize     int
+	AnalyticsFlushInterval time.Duration
+}
+
+// RecentViewsConfig holds recently viewed products configuration
+type RecentViewsConfig struct {
+	Limit int // products kept per customer; older views are dropped
+}
+
+// CustomerLimitsConfig holds the default per-customer caps, which admins can
+// override per customer; 0 disables a cap
+type CustomerLimitsConfig struct {
+	MaxAddresses     int
+	MaxWishlistItems int // also enforced by imports
+	MaxMeasurements  int
+}
+
+// IdempotencyConfig holds how long responses to requests with an
+// Idempotency-Key are replayed
+type IdempotencyConfig struct {
+	TTL         time.Duration
+	LockTimeout time.Duration // a claim by a request that never finished is taken over after this
+}
+
+// StorageConfig holds the file store used for uploads such as note attachments
+type StorageConfig struct {
+	Provider string // "local", "s3" or "minio"
+
+	// Local provider; PublicURL is where this service serves the files
+	LocalDir      string
+	PublicURL     string
+	SigningSecret string
+
+	// S3 and MinIO providers; an empty Endpoint means AWS S3 in Region
+	Endpoint  string
+	Region    string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+	PathStyle bool
+}
+
+// AttachmentConfig holds customer note attachment limits
+type AttachmentConfig struct {
+	MaxSize      int64    // bytes per file
+	AllowedTypes []string // MIME types, detected from the file content
+	URLTTL       time.Duration
+}
+
+// AvatarConfig holds customer avatar upload limits
+type AvatarConfig struct {
+	MaxSize int64  // bytes per upload
+	BaseURL string // public URL avatars are served from, e.g. a CDN in front of the bucket
+}
+
+// ExportJobConfig holds background customer export settings
+type ExportJobConfig struct {
+	Interval time.Duration // how often the worker looks for queued exports
+	PageSize int           // customers loaded per page
+	URLTTL   time.Duration // how long download links work
+}
+
+// BackInStockConfig holds back-in-stock subscription expiry and throttling configuration
+type BackInStockConfig struct {
+	SubscriptionTTL time.Duration
+	NotifyLimit     int // notifications per recipient per NotifyWindow; 0 disables throttling
+	NotifyWindow    time.Duration
+
+	// Notify only as many subscribers as units restocked, longest waiting
+	// first, when the restock is smaller than the waiting list
+	PartialNotify bool
+
+	// Subscribe notify_restock wishlist items to back-in-stock when their
+	// product sells out
+	FromWishlist bool
+
+	// Failed notification sends are retried with exponential backoff
+	RetryMaxAttempts int // including the original send
+	RetryBackoff     time.Duration
+	RetryMaxBackoff  time.Duration
+	RetryInterval    time.Duration // how often the retry job looks for due sends
+
+	// Restock webhook for inventory sources that can't publish to NATS;
+	// disabled while the secret is empty
+	WebhookSecret    string
+	WebhookTolerance time.Duration // allowed clock skew on the signed timestamp
+}
+
+// WebhookConfig holds the delivery settings of outbound customer event webhooks
+type WebhookConfig struct {
+	Timeout          time.Duration // per attempt
+	RetryMaxAttempts int           // including the first attempt
+	RetryBackoff     time.Duration
+	RetryMaxBackoff  time.Duration
+	DeliveryInterval time.Duration // how often the delivery job looks for due deliveries
+}
+
+// MarketingSyncConfig holds the email marketing platform sync settings
+type MarketingSyncConfig struct {
+	Providers        []string      // "mailchimp" and/or "klaviyo"; empty disables the sync
+	Interval         time.Duration // how often the sync job looks for changed customers
+	BatchPause       time.Duration // between two batches to the same platform
+	Timeout          time.Duration // per request
+	RetryMaxAttempts int
+	RetryBackoff     time.Duration
+	RetryMaxBackoff  time.Duration
+
+	// Field maps are platform field=customer attribute pairs
+	MailchimpAPIKey   string
+	MailchimpListID   string
+	MailchimpFieldMap string
+	KlaviyoAPIKey     string
+	KlaviyoListID     string // consent subscribes to this list; empty syncs profiles only
+	KlaviyoFieldMap   string
+}
+
+// SLOConfig holds availability and latency objectives. Availability and
+// LatencyTarget are percentages, e.g. 99.9.
+type SLOConfig struct {
+	Default   SLOObjective
+	Endpoints map[string]SLOObjective // keyed by "METHOD /route/template"
+}
+
+// SLOObjective is the objective of one endpoint
+type SLOObjective struct {
+	Availability     float64
+	LatencyThreshold time.Duration
+	LatencyTarget    float64
+}
+
+// NotificationConfig holds the notification service client configuration
+type NotificationConfig struct {
+	URL              string
+	Timeout          time.Duration // per attempt
+	MaxRetries       int
+	RetryBackoff     time.Duration // doubled after every retry, up to MaxRetryBackoff
+	MaxRetryBackoff  time.Duration
+	BreakerThreshold int // consecutive failed sends that open the circuit breaker; 0 disables it
+	BreakerCooldown  time.Duration
+}
+
+// RegionConfig holds sales region scoping configuration for admin users
+type RegionConfig struct {
+	ScopedRoles []string // roles limited to customers in their assigned states
+}
+
+// PermissionsConfig holds the admin permission policy configuration
+type PermissionsConfig struct {
+	RolesFile string // JSON file of each role's permissions; empty uses domain.DefaultRolePermissions
+}
+
+// EncryptionConfig holds the keys of the PII columns encrypted at rest,
+// formatted as "id=base64key,id=base64key" with 32-byte keys. New values are
+// encrypted with PrimaryKey; the other keys only decrypt values not yet
+// re-encrypted. Without keys the columns are stored in plaintext.
+// IndexKey is the base64 32-byte key of the blind indexes of encrypted
+// columns; it is required with keys and can't be rotated.
+type EncryptionConfig struct {
+	Keys       string
+	KeysFile   string // read instead of Keys when set, e.g. a secret mounted from a KMS
+	PrimaryKey string // may be empty with a single key
+	IndexKey   string
+}
+
+// LegacyCRMConfig holds the connection to the legacy CRM database that
+// customer and address writes are mirrored to during the migration
+type LegacyCRMConfig struct {
+	DSN            string // empty disables the bridge
+	DualWrite      bool   // mirror writes; turn off once the migration is complete
+	CustomersTable string
+	AddressesTable string
+	Timeout        time.Duration // per mirrored write
+}
+
+// WarmupConfig holds startup warm-up configuration
+type WarmupConfig struct {
+	Enabled     bool
+	Timeout     time.Duration
+	Connections int // database connections opened ahead of traffic
+}
+
+// SchedulerConfig holds the periodic maintenance job configuration.
+// Schedules are cron expressions or descriptors such as "@every 1h".
+type SchedulerConfig struct {
+	Enabled        bool
+	LeaderInterval time.Duration // how often followers try to take over leadership
+
+	BackInStockCleanup       ScheduledJobConfig
+	BackInStockRetentionDays int // notified subscriptions older than this are deleted
+	BackInStockCleanupBatch  int // subscriptions deleted per statement
+	BackInStockExpiry        ScheduledJobConfig
+	SegmentRecompute         ScheduledJobConfig
+	IdempotencyKeyCleanup    ScheduledJobConfig
+	RFMScoring               ScheduledJobConfig
+	ChurnRisk                ScheduledJobConfig
+	ChurnMinOrders           int // orders that make a customer a frequent buyer
+	ChurnInactiveDays        int // days without orders before a frequent buyer is high churn risk
+	CohortRefresh            ScheduledJobConfig
+	DuplicateDetection       ScheduledJobConfig
+	WishlistRetention        ScheduledJobConfig
+	WishlistStaleMonths      int // months after adding or confirming an item before the customer is asked if they still want it
+	WishlistConfirmDays      int // days the customer has to confirm before the item is archived
+	RecentViewsCleanup       ScheduledJobConfig
+	RecentViewsRetentionDays int // recently viewed products older than this are deleted
+	ScheduledExports         ScheduledJobConfig
+	ScheduledExportLinkTTL   time.Duration // how long emailed export download links work
+	ExportRetention          ScheduledJobConfig
+	ExportRetentionDays      int // background export files finished more than this long ago are deleted
+}
+
+// ScheduledJobConfig enables and schedules one job
+type ScheduledJobConfig struct {
+	Enabled  bool
+	Schedule string
 }
 
 // NATSConfig holds NATS configuration
 type NATSConfig struct {
-	URL string
+	URL        string
+	Restock    JetStreamConsumerConfig
+	OutOfStock JetStreamConsumerConfig
+}
+
+// JetStreamConsumerConfig holds the durable JetStream consumer configuration
+// for an event subject
+type JetStreamConsumerConfig struct {
+	Stream            string // created for the subject if no stream captures it yet
+	Durable           string
+	MaxDeliver        int
+	Backoff           []time.Duration // redelivery delays by attempt; the last one repeats
+	DeadLetterSubject string
 }
 
 // Load loads configuration from environment variables
@@ -63,24 +401,239 @@ func Load() *Config {
 			Env:  getEnv("APP_ENV", "development"),
 		},
 		Database: DatabaseConfig{
-			Host:     getEnv("DB_HOST", "localhost"),
-			Port:     getEnv("DB_PORT", "5432"),
-			User:     getEnv("DB_USER", "postgres"),
-			Password: getEnv("DB_PASSWORD", "postgres"),
-			DBName:   getEnv("DB_NAME", "customer_db"),
-			SSLMode:  getEnv("DB_SSLMODE", "disable"),
+			Host:        getEnv("DB_HOST", "localhost"),
+			Port:        getEnv("DB_PORT", "5432"),
+			User:        getEnv("DB_USER", "postgres"),
+			Password:    getEnv("DB_PASSWORD", "postgres"),
+			DBName:      getEnv("DB_NAME", "customer_db"),
+			SSLMode:     getEnv("DB_SSLMODE", "disable"),
+			PrepareStmt: getEnvBool("DB_PREPARE_STATEMENTS", false),
+			ReplicaDSN:  getEnv("DB_REPLICA_DSN", ""),
+
+			MaxOpenConns:       getEnvInt("DB_MAX_OPEN_CONNS", 50),
+			MaxIdleConns:       getEnvInt("DB_MAX_IDLE_CONNS", 10),
+			ConnMaxLifetime:    getEnvDuration("DB_CONN_MAX_LIFETIME", time.Hour),
+			ConnMaxIdleTime:    getEnvDuration("DB_CONN_MAX_IDLE_TIME", 10*time.Minute),
+			SlowQueryThreshold: getEnvDuration("DB_SLOW_QUERY_THRESHOLD", 200*time.Millisecond),
+
+			LookupTimeout: getEnvDuration("DB_LOOKUP_TIMEOUT", 5*time.Second),
+			ListTimeout:   getEnvDuration("DB_LIST_TIMEOUT", 10*time.Second),
+			ReportTimeout: getEnvDuration("DB_REPORT_TIMEOUT", 30*time.Second),
+			ExportTimeout: getEnvDuration("DB_EXPORT_TIMEOUT", 2*time.Minute),
 		},
 		JWT: JWTConfig{
-			Secret: getEnv("JWT_SECRET", "your-secret-key"),
+			Secret:       getEnv("JWT_SECRET", "your-secret-key"),
+			JWKSURL:      getEnv("JWT_JWKS_URL", ""),
+			JWKSCacheTTL: getEnvDuration("JWT_JWKS_CACHE_TTL", time.Hour),
+			Issuer:       getEnv("JWT_ISSUER", ""),
+			Audience:     getEnv("JWT_AUDIENCE", ""),
+			ClockSkew:    getEnvDuration("JWT_CLOCK_SKEW", 30*time.Second),
+			Algorithms:   splitList(getEnv("JWT_ALGORITHMS", "HS256,RS256")),
 		},
 		NATS: NATSConfig{
 			URL: getEnv("NATS_URL", "nats://localhost:4222"),
+			Restock: JetStreamConsumerConfig{
+				Stream:            getEnv("NATS_RESTOCK_STREAM", "INVENTORY_RESTOCK"),
+				Durable:           getEnv("NATS_RESTOCK_DURABLE", "customer-back-in-stock"),
+				MaxDeliver:        getEnvInt("NATS_RESTOCK_MAX_DELIVER", 5),
+				Backoff:           getEnvDurations("NATS_RESTOCK_BACKOFF", []time.Duration{5 * time.Second, 30 * time.Second, 2 * time.Minute, 10 * time.Minute}),
+				DeadLetterSubject: getEnv("NATS_RESTOCK_DEAD_LETTER_SUBJECT", "customer.dlq.inventory.product.restocked"),
+			},
+			OutOfStock: JetStreamConsumerConfig{
+				Stream:            getEnv("NATS_OUT_OF_STOCK_STREAM", "INVENTORY_OUT_OF_STOCK"),
+				Durable:           getEnv("NATS_OUT_OF_STOCK_DURABLE", "customer-wishlist-restock"),
+				MaxDeliver:        getEnvInt("NATS_OUT_OF_STOCK_MAX_DELIVER", 5),
+				Backoff:           getEnvDurations("NATS_OUT_OF_STOCK_BACKOFF", []time.Duration{5 * time.Second, 30 * time.Second, 2 * time.Minute, 10 * time.Minute}),
+				DeadLetterSubject: getEnv("NATS_OUT_OF_STOCK_DEAD_LETTER_SUBJECT", "customer.dlq.inventory.product.out_of_stock"),
+			},
 		},
 		Sentry: SentryConfig{
 			DSN:         getEnv("SENTRY_DSN", ""),
 			Environment: getEnv("APP_ENV", "development"),
 			Release:     getEnv("APP_VERSION", "1.0.0"),
 		},
+		Tracing: TracingConfig{
+			Endpoint:    getEnv("OTEL_EXPORTER_OTLP_ENDPOINT", ""),
+			ServiceName: getEnv("OTEL_SERVICE_NAME", "service-customer"),
+			SampleRatio: getEnvFloat("OTEL_TRACES_SAMPLE_RATIO", 0.1),
+		},
+
+		Internal: InternalConfig{
+			ServiceTokens: parseServiceTokens(getEnv("INTERNAL_SERVICE_TOKENS", "")),
+			APIKey:        getEnv("INTERNAL_API_KEY", ""),
+		},
+		Address: AddressValidationConfig{
+			Provider:         getEnv("ADDRESS_VALIDATION_PROVIDER", "none"),
+			APIKey:           getEnv("ADDRESS_VALIDATION_API_KEY", ""),
+			AllowedCountries: splitList(getEnv("ADDRESS_ALLOWED_COUNTRIES", "")),
+		},
+		LoadShed: LoadShedConfig{
+			MaxConcurrent: getEnvInt("LOAD_SHED_MAX_CONCURRENT", 200),
+			MaxQueue:      getEnvInt("LOAD_SHED_MAX_QUEUE", 100),
+			QueueTimeout:  getEnvDuration("LOAD_SHED_QUEUE_TIMEOUT", 500*time.Millisecond),
+			RetryAfter:    getEnvDuration("LOAD_SHED_RETRY_AFTER", 5*time.Second),
+		},
+		RateLimit: RateLimitConfig{
+			ReadPerMinute:        getEnvInt("RATE_LIMIT_READ_PER_MINUTE", 120),
+			ReadBurst:            getEnvInt("RATE_LIMIT_READ_BURST", 60),
+			WritePerMinute:       getEnvInt("RATE_LIMIT_WRITE_PER_MINUTE", 50),
+			WriteBurst:           getEnvInt("RATE_LIMIT_WRITE_BURST", 20),
+			GuestSignupPerMinute: getEnvInt("RATE_LIMIT_GUEST_SIGNUP_PER_MINUTE", 5),
+			GuestSignupBurst:     getEnvInt("RATE_LIMIT_GUEST_SIGNUP_BURST", 5),
+		},
+		SLO: SLOConfig{
+			Default: SLOObjective{
+				Availability:     getEnvFloat("SLO_DEFAULT_AVAILABILITY", 99.5),
+				LatencyThreshold: getEnvDuration("SLO_DEFAULT_LATENCY", 500*time.Millisecond),
+				LatencyTarget:    getEnvFloat("SLO_DEFAULT_LATENCY_TARGET", 95),
+			},
+			Endpoints: parseSLOEndpoints(getEnv("SLO_ENDPOINTS", "")),
+		},
+		Warmup: WarmupConfig{
+			Enabled:     getEnvBool("WARMUP_ENABLED", false),
+			Timeout:     getEnvDuration("WARMUP_TIMEOUT", 30*time.Second),
+			Connections: getEnvInt("WARMUP_CONNECTIONS", 10),
+		},
+		Notification: NotificationConfig{
+			URL:              getEnv("NOTIFICATION_SERVICE_URL", "http://localhost:8006"),
+			Timeout:          getEnvDuration("NOTIFICATION_TIMEOUT", 5*time.Second),
+			MaxRetries:       getEnvInt("NOTIFICATION_MAX_RETRIES", 3),
+			RetryBackoff:     getEnvDuration("NOTIFICATION_RETRY_BACKOFF", 200*time.Millisecond),
+			MaxRetryBackoff:  getEnvDuration("NOTIFICATION_MAX_RETRY_BACKOFF", 5*time.Second),
+			BreakerThreshold: getEnvInt("NOTIFICATION_BREAKER_THRESHOLD", 5),
+			BreakerCooldown:  getEnvDuration("NOTIFICATION_BREAKER_COOLDOWN", 30*time.Second),
+		},
+		Region: RegionConfig{
+			ScopedRoles: splitList(getEnv("REGION_SCOPED_ROLES", "SALES_AGENT")),
+		},
+		Permissions: PermissionsConfig{
+			RolesFile: getEnv("ADMIN_ROLE_PERMISSIONS_FILE", ""),
+		},
+		Encryption: EncryptionConfig{
+			Keys:       getEnv("PII_ENCRYPTION_KEYS", ""),
+			KeysFile:   getEnv("PII_ENCRYPTION_KEYS_FILE", ""),
+			PrimaryKey: getEnv("PII_ENCRYPTION_PRIMARY_KEY", ""),
+			IndexKey:   getEnv("PII_BLIND_INDEX_KEY", ""),
+		},
+		LegacyCRM: LegacyCRMConfig{
+			DSN:            getEnv("LEGACY_CRM_DSN", ""),
+			DualWrite:      getEnvBool("LEGACY_CRM_DUAL_WRITE", false),
+			CustomersTable: getEnv("LEGACY_CRM_CUSTOMERS_TABLE", "customers"),
+			AddressesTable: getEnv("LEGACY_CRM_ADDRESSES_TABLE", "customer_addresses"),
+			Timeout:        getEnvDuration("LEGACY_CRM_TIMEOUT", 5*time.Second),
+		},
+		Scheduler: SchedulerConfig{
+			Enabled:                  getEnvBool("SCHEDULER_ENABLED", true),
+			LeaderInterval:           getEnvDuration("SCHEDULER_LEADER_INTERVAL", 15*time.Second),
+			BackInStockCleanup:       scheduledJob("JOB_BACK_IN_STOCK_CLEANUP", "0 3 * * *"),
+			BackInStockRetentionDays: getEnvInt("BACK_IN_STOCK_RETENTION_DAYS", 30),
+			BackInStockCleanupBatch:  getEnvInt("BACK_IN_STOCK_CLEANUP_BATCH_SIZE", 1000),
+			// BACK_IN_STOCK_EXPIRY_INTERVAL is the previous setting for this job
+			BackInStockExpiry:     scheduledJob("JOB_BACK_IN_STOCK_EXPIRY", "@every "+getEnvDuration("BACK_IN_STOCK_EXPIRY_INTERVAL", time.Hour).String()),
+			SegmentRecompute:      scheduledJob("JOB_SEGMENT_RECOMPUTE", "30 3 * * *"),
+			IdempotencyKeyCleanup: scheduledJob("JOB_IDEMPOTENCY_KEY_CLEANUP", "@every 1h"),
+			// Before segment_recompute, so it sees the new scores
+			RFMScoring:         scheduledJob("JOB_RFM_SCORING", "0 3 * * *"),
+			ChurnRisk:          scheduledJob("JOB_CHURN_RISK", "15 3 * * *"),
+			ChurnMinOrders:     getEnvInt("CHURN_MIN_ORDERS", 3),
+			ChurnInactiveDays:  getEnvInt("CHURN_INACTIVE_DAYS", 90),
+			CohortRefresh:      scheduledJob("JOB_COHORT_REFRESH", "@every 1h"),
+			DuplicateDetection: scheduledJob("JOB_DUPLICATE_DETECTION", "45 3 * * *"),
+			// Mid-morning, when the "still interested?" email is likely to be read
+			WishlistRetention:   scheduledJob("JOB_WISHLIST_RETENTION", "0 10 * * *"),
+			WishlistStaleMonths: getEnvInt("WISHLIST_STALE_MONTHS", 6),
+			WishlistConfirmDays: getEnvInt("WISHLIST_CONFIRM_DAYS", 14),
+			// Old views say little about what a customer wants now
+			RecentViewsCleanup:       scheduledJob("JOB_RECENTLY_VIEWED_CLEANUP", "0 4 * * *"),
+			RecentViewsRetentionDays: getEnvInt("RECENTLY_VIEWED_RETENTION_DAYS", 90),
+			// Checks for due exports; each export has its own schedule
+			ScheduledExports:       scheduledJob("JOB_SCHEDULED_EXPORTS", "@every 1m"),
+			ScheduledExportLinkTTL: getEnvDuration("SCHEDULED_EXPORT_LINK_TTL", 7*24*time.Hour),
+			// Exported files hold customer PII
+			ExportRetention:     scheduledJob("JOB_EXPORT_RETENTION", "30 4 * * *"),
+			ExportRetentionDays: getEnvInt("CUSTOMER_EXPORT_RETENTION_DAYS", 7),
+		},
+		BackInStock: BackInStockConfig{
+			SubscriptionTTL:  getEnvDuration("BACK_IN_STOCK_SUBSCRIPTION_TTL", 90*24*time.Hour),
+			NotifyLimit:      getEnvInt("BACK_IN_STOCK_NOTIFY_LIMIT", 3),
+			NotifyWindow:     getEnvDuration("BACK_IN_STOCK_NOTIFY_WINDOW", time.Hour),
+			PartialNotify:    getEnvBool("BACK_IN_STOCK_PARTIAL_NOTIFY", false),
+			FromWishlist:     getEnvBool("BACK_IN_STOCK_FROM_WISHLIST", true),
+			RetryMaxAttempts: getEnvInt("BACK_IN_STOCK_RETRY_MAX_ATTEMPTS", 8),
+			RetryBackoff:     getEnvDuration("BACK_IN_STOCK_RETRY_BACKOFF", time.Minute),
+			RetryMaxBackoff:  getEnvDuration("BACK_IN_STOCK_RETRY_MAX_BACKOFF", 6*time.Hour),
+			RetryInterval:    getEnvDuration("BACK_IN_STOCK_RETRY_INTERVAL", time.Minute),
+			WebhookSecret:    getEnv("INVENTORY_WEBHOOK_SECRET", ""),
+			WebhookTolerance: getEnvDuration("INVENTORY_WEBHOOK_TOLERANCE", 5*time.Minute),
+		},
+		Webhooks: WebhookConfig{
+			Timeout:          getEnvDuration("WEBHOOK_TIMEOUT", 10*time.Second),
+			RetryMaxAttempts: getEnvInt("WEBHOOK_RETRY_MAX_ATTEMPTS", 10),
+			RetryBackoff:     getEnvDuration("WEBHOOK_RETRY_BACKOFF", 30*time.Second),
+			RetryMaxBackoff:  getEnvDuration("WEBHOOK_RETRY_MAX_BACKOFF", 6*time.Hour),
+			DeliveryInterval: getEnvDuration("WEBHOOK_DELIVERY_INTERVAL", 10*time.Second),
+		},
+		Marketing: MarketingSyncConfig{
+			Providers:         splitList(getEnv("MARKETING_SYNC_PROVIDERS", "")),
+			Interval:          getEnvDuration("MARKETING_SYNC_INTERVAL", time.Minute),
+			BatchPause:        getEnvDuration("MARKETING_SYNC_BATCH_PAUSE", time.Second),
+			Timeout:           getEnvDuration("MARKETING_SYNC_TIMEOUT", 30*time.Second),
+			RetryMaxAttempts:  getEnvInt("MARKETING_SYNC_RETRY_MAX_ATTEMPTS", 8),
+			RetryBackoff:      getEnvDuration("MARKETING_SYNC_RETRY_BACKOFF", time.Minute),
+			RetryMaxBackoff:   getEnvDuration("MARKETING_SYNC_RETRY_MAX_BACKOFF", 6*time.Hour),
+			MailchimpAPIKey:   getEnv("MAILCHIMP_API_KEY", ""),
+			MailchimpListID:   getEnv("MAILCHIMP_LIST_ID", ""),
+			MailchimpFieldMap: getEnv("MAILCHIMP_FIELD_MAP", "FNAME=first_name,LNAME=last_name,PHONE=phone,SEGMENTS=segments"),
+			KlaviyoAPIKey:     getEnv("KLAVIYO_API_KEY", ""),
+			KlaviyoListID:     getEnv("KLAVIYO_LIST_ID", ""),
+			KlaviyoFieldMap:   getEnv("KLAVIYO_FIELD_MAP", "segments=segments,total_orders=total_orders,lifetime_value=lifetime_value,rfm_segment=rfm_segment"),
+		},
+		Wishlist: WishlistConfig{
+			StockCacheTTL:          getEnvDuration("WISHLIST_STOCK_CACHE_TTL", 30*time.Second),
+			AnalyticsEvents:        getEnvBool("WISHLIST_ANALYTICS_EVENTS", false),
+			AnalyticsQueueSize:     getEnvInt("WISHLIST_ANALYTICS_QUEUE_SIZE", 10000),
+			AnalyticsBatchSize:     getEnvInt("WISHLIST_ANALYTICS_BATCH_SIZE", 100),
+			AnalyticsFlushInterval: getEnvDuration("WISHLIST_ANALYTICS_FLUSH_INTERVAL", time.Second),
+		},
+		RecentViews: RecentViewsConfig{
+			Limit: getEnvInt("RECENTLY_VIEWED_LIMIT", 50),
+		},
+		Limits: CustomerLimitsConfig{
+			MaxAddresses: getEnvInt("CUSTOMER_MAX_ADDRESSES", 20),
+			// WISHLIST_MAX_ITEMS is the previous setting for this cap
+			MaxWishlistItems: getEnvInt("CUSTOMER_MAX_WISHLIST_ITEMS", getEnvInt("WISHLIST_MAX_ITEMS", 500)),
+			MaxMeasurements:  getEnvInt("CUSTOMER_MAX_MEASUREMENTS", 10),
+		},
+		Idempotency: IdempotencyConfig{
+			TTL:         getEnvDuration("IDEMPOTENCY_KEY_TTL", 24*time.Hour),
+			LockTimeout: getEnvDuration("IDEMPOTENCY_LOCK_TIMEOUT", time.Minute),
+		},
+		Storage: StorageConfig{
+			Provider:      getEnv("STORAGE_PROVIDER", "local"),
+			LocalDir:      getEnv("STORAGE_LOCAL_DIR", "./data/files"),
+			PublicURL:     getEnv("STORAGE_PUBLIC_URL", "http://localhost:8004/api/v1/files"),
+			SigningSecret: getEnv("STORAGE_SIGNING_SECRET", getEnv("JWT_SECRET", "your-secret-key")),
+			Endpoint:      getEnv("STORAGE_ENDPOINT", ""),
+			Region:        getEnv("STORAGE_REGION", "us-east-1"),
+			Bucket:        getEnv("STORAGE_BUCKET", ""),
+			AccessKey:     getEnv("STORAGE_ACCESS_KEY", ""),
+			SecretKey:     getEnv("STORAGE_SECRET_KEY", ""),
+			PathStyle:     getEnvBool("STORAGE_PATH_STYLE", false),
+		},
+		Attachments: AttachmentConfig{
+			MaxSize:      int64(getEnvInt("NOTE_ATTACHMENT_MAX_SIZE", 10<<20)),
+			AllowedTypes: splitList(getEnv("NOTE_ATTACHMENT_TYPES", "image/png,image/jpeg,image/gif,image/webp,application/pdf")),
+			URLTTL:       getEnvDuration("NOTE_ATTACHMENT_URL_TTL", 15*time.Minute),
+		},
+		Avatar: AvatarConfig{
+			MaxSize: int64(getEnvInt("AVATAR_MAX_SIZE", 5<<20)),
+			BaseURL: getEnv("AVATAR_BASE_URL", getEnv("STORAGE_PUBLIC_URL", "http://localhost:8004/api/v1/files")),
+		},
+		Exports: ExportJobConfig{
+			Interval: getEnvDuration("CUSTOMER_EXPORT_INTERVAL", 10*time.Second),
+			PageSize: getEnvInt("CUSTOMER_EXPORT_PAGE_SIZE", 1000),
+			URLTTL:   getEnvDuration("CUSTOMER_EXPORT_URL_TTL", time.Hour),
+		},
 	}
 }
 
@@ -99,3 +652,126 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+// getEnvInt gets an integer environment variable or returns a default value
+// scheduledJob reads <prefix>_ENABLED and <prefix>_SCHEDULE
+func scheduledJob(prefix, defaultSchedule string) ScheduledJobConfig {
+	return ScheduledJobConfig{
+		Enabled:  getEnvBool(prefix+"_ENABLED", true),
+		Schedule: getEnv(prefix+"_SCHEDULE", defaultSchedule),
+	}
+}
+
+func getEnvInt(key string, defaultValue int) int {
+	if value, err := strconv.Atoi(os.Getenv(key)); err == nil {
+		return value
+	}
+	return defaultValue
+}
+
+// getEnvBool gets a boolean environment variable or returns a default value
+func getEnvBool(key string, defaultValue bool) bool {
+	if value, err := strconv.ParseBool(os.Getenv(key)); err == nil {
+		return value
+	}
+	return defaultValue
+}
+
+// getEnvDuration gets a duration environment variable (e.g. "500ms") or returns a default value
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	if value, err := time.ParseDuration(os.Getenv(key)); err == nil {
+		return value
+	}
+	return defaultValue
+}
+
+// getEnvDurations gets a comma-separated list of durations (e.g. "5s,30s,2m") or
+// returns a default value if the variable is unset or malformed
+func getEnvDurations(key string, defaultValue []time.Duration) []time.Duration {
+	items := splitList(os.Getenv(key))
+	if len(items) == 0 {
+		return defaultValue
+	}
+
+	durations := make([]time.Duration, 0, len(items))
+	for _, item := range items {
+		d, err := time.ParseDuration(item)
+		if err != nil {
+			log.Printf("⚠️  Invalid duration %q in %s, using defaults", item, key)
+			return defaultValue
+		}
+		durations = append(durations, d)
+	}
+	return durations
+}
+
+// getEnvFloat gets a float environment variable or returns a default value
+func getEnvFloat(key string, defaultValue float64) float64 {
+	if value, err := strconv.ParseFloat(os.Getenv(key), 64); err == nil {
+		return value
+	}
+	return defaultValue
+}
+
+// parseSLOEndpoints parses per-endpoint objectives in the form
+// "GET /api/v1/customer/profile=99.9:300ms:99;POST /internal/v1/wallet/reservations=99.95:200ms:99"
+// (availability %, latency threshold, % of requests under the threshold).
+// Malformed entries are logged and skipped.
+func parseSLOEndpoints(value string) map[string]SLOObjective {
+	objectives := make(map[string]SLOObjective)
+	for _, entry := range strings.Split(value, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		endpoint, spec, found := strings.Cut(entry, "=")
+		parts := strings.Split(spec, ":")
+		if !found || len(parts) != 3 {
+			log.Printf("Ignoring malformed SLO_ENDPOINTS entry %q", entry)
+			continue
+		}
+
+		availability, err1 := strconv.ParseFloat(parts[0], 64)
+		threshold, err2 := time.ParseDuration(parts[1])
+		latencyTarget, err3 := strconv.ParseFloat(parts[2], 64)
+		if err1 != nil || err2 != nil || err3 != nil {
+			log.Printf("Ignoring malformed SLO_ENDPOINTS entry %q", entry)
+			continue
+		}
+
+		objectives[strings.TrimSpace(endpoint)] = SLOObjective{
+			Availability:     availability,
+			LatencyThreshold: threshold,
+			LatencyTarget:    latencyTarget,
+		}
+	}
+	return objectives
+}
+
+// parseServiceTokens parses INTERNAL_SERVICE_TOKENS, formatted as
+// "service=token,service=token"
+func parseServiceTokens(value string) map[string]string {
+	tokens := make(map[string]string)
+	for _, entry := range splitList(value) {
+		service, token, found := strings.Cut(entry, "=")
+		service, token = strings.TrimSpace(service), strings.TrimSpace(token)
+		if !found || service == "" || token == "" {
+			log.Printf("Ignoring malformed INTERNAL_SERVICE_TOKENS entry for %q", service)
+			continue
+		}
+		tokens[service] = token
+	}
+	return tokens
+}
+
+// splitList splits a comma-separated value, dropping empty entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
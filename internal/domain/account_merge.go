@@ -0,0 +1,153 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Merge sources: who asked for the merge
+const (
+	MergeSourceAuth  = "auth"  // the auth service merged two identities
+	MergeSourceAdmin = "admin" // an admin merged a duplicate customer
+)
+
+// AccountMerge records that customer-side data of a secondary identity was
+// moved to a primary identity, either after the auth service merged the
+// accounts or when an admin merged a duplicate customer. It doubles as the
+// merge audit record. The unique secondary ID makes redelivered merge events
+// a no-op.
+type AccountMerge struct {
+	ID              uuid.UUID  `gorm:"type:uuid;primary_key" json:"id"`
+	PrimaryUserID   uuid.UUID  `gorm:"type:uuid;not null;index" json:"primary_user_id"`
+	SecondaryUserID uuid.UUID  `gorm:"type:uuid;not null;uniqueIndex" json:"secondary_user_id"`
+	Source          string     `gorm:"type:varchar(20);default:'auth'" json:"source"`
+	MergedBy        *uuid.UUID `gorm:"type:uuid" json:"merged_by,omitempty"`
+
+	// Number of records moved to the primary account
+	WishlistItems int `gorm:"default:0" json:"wishlist_items"`
+	Subscriptions int `gorm:"default:0" json:"subscriptions"`
+	Addresses     int `gorm:"default:0" json:"addresses"`
+	Measurements  int `gorm:"default:0" json:"measurements"`
+	Notes         int `gorm:"default:0" json:"notes"`
+
+	// Order totals of the duplicate added to the primary customer (admin merges)
+	Orders int     `gorm:"default:0" json:"orders"`
+	Spent  float64 `gorm:"type:decimal(12,2);default:0" json:"spent"`
+
+	MergedAt time.Time `json:"merged_at"`
+}
+
+func (m *AccountMerge) BeforeCreate(tx *gorm.DB) error {
+	if m.ID == uuid.Nil {
+		m.ID = uuid.New()
+	}
+	return nil
+}
+
+func (AccountMerge) TableName() string {
+	return "customer.account_merges"
+}
+
+// Fields an admin can resolve when two customers are merged
+const (
+	MergeFieldFirstName      = "first_name"
+	MergeFieldLastName       = "last_name"
+	MergeFieldPhone          = "phone"
+	MergeFieldAvatarURL      = "avatar_url"
+	MergeFieldDefaultAddress = "default_address"
+)
+
+// Merge winners: which account's value survives a conflict
+const (
+	MergeWinnerPrimary   = "primary"
+	MergeWinnerSecondary = "secondary"
+)
+
+// mergeProfileFields are the customer columns compared in a merge preview
+var mergeProfileFields = []string{MergeFieldFirstName, MergeFieldLastName, MergeFieldPhone, MergeFieldAvatarURL}
+
+// MergeConflict is a field where the two accounts hold different values.
+// Winner is the side that is kept when the merge request does not say otherwise.
+type MergeConflict struct {
+	Field     string      `json:"field"`
+	Primary   interface{} `json:"primary"`
+	Secondary interface{} `json:"secondary"`
+	Winner    string      `json:"winner"`
+}
+
+// MergePreview describes what merging the secondary customer into the primary would change
+type MergePreview struct {
+	PrimaryID     uuid.UUID       `json:"primary_id"`
+	SecondaryID   uuid.UUID       `json:"secondary_id"`
+	AlreadyMerged bool            `json:"already_merged"`
+	Conflicts     []MergeConflict `json:"conflicts"`
+}
+
+// MergeCustomerRequest is the body of an admin merge or merge preview.
+// Winners maps a conflict field to "primary" or "secondary".
+type MergeCustomerRequest struct {
+	SecondaryID uuid.UUID         `json:"secondary_id" binding:"required"`
+	Winners     map[string]string `json:"winners"`
+}
+
+// ValidateMergeWinners checks that winners only names known fields and sides
+func ValidateMergeWinners(winners map[string]string) error {
+	for field, winner := range winners {
+		if field != MergeFieldDefaultAddress && MergeProfileFieldValue(&Customer{}, field) == nil {
+			return fmt.Errorf("unknown merge field %q", field)
+		}
+		if winner != MergeWinnerPrimary && winner != MergeWinnerSecondary {
+			return fmt.Errorf("winner for %q must be %q or %q", field, MergeWinnerPrimary, MergeWinnerSecondary)
+		}
+	}
+	return nil
+}
+
+// MergeProfileFieldValue returns a pointer to the customer field behind a
+// merge field name, or nil if the name is not a profile field
+func MergeProfileFieldValue(c *Customer, field string) *string {
+	switch field {
+	case MergeFieldFirstName:
+		return &c.FirstName
+	case MergeFieldLastName:
+		return &c.LastName
+	case MergeFieldPhone:
+		return &c.Phone
+	case MergeFieldAvatarURL:
+		return &c.AvatarURL
+	}
+	return nil
+}
+
+// DetectMergeConflicts compares two customers and their default addresses
+// (either address may be nil). Empty values never conflict, since the merge
+// keeps whichever side is set. The primary account wins by default.
+func DetectMergeConflicts(primary, secondary *Customer, primaryAddress, secondaryAddress *Address) []MergeConflict {
+	conflicts := []MergeConflict{}
+	for _, field := range mergeProfileFields {
+		primaryValue := *MergeProfileFieldValue(primary, field)
+		secondaryValue := *MergeProfileFieldValue(secondary, field)
+		if primaryValue == "" || secondaryValue == "" || primaryValue == secondaryValue {
+			continue
+		}
+		conflicts = append(conflicts, MergeConflict{
+			Field:     field,
+			Primary:   primaryValue,
+			Secondary: secondaryValue,
+			Winner:    MergeWinnerPrimary,
+		})
+	}
+
+	if primaryAddress != nil && secondaryAddress != nil && !primaryAddress.SameLocation(secondaryAddress) {
+		conflicts = append(conflicts, MergeConflict{
+			Field:     MergeFieldDefaultAddress,
+			Primary:   primaryAddress,
+			Secondary: secondaryAddress,
+			Winner:    MergeWinnerPrimary,
+		})
+	}
+	return conflicts
+}
@@ -0,0 +1,40 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Activity feed page sizes
+const (
+	DefaultActivityFeedLimit = 50
+	MaxActivityFeedLimit     = 200
+	MaxActivityExportRows    = 100000
+)
+
+// ActivityFeedFilter narrows the activity feed across all customers
+type ActivityFeedFilter struct {
+	Types      []string
+	CustomerID *uuid.UUID
+	ActorID    *uuid.UUID // only activities performed by this admin
+	DateFrom   *time.Time
+	DateTo     *time.Time
+	Region     *RegionScope // nil sees every customer
+	Sort       Sort         // zero is newest first
+	After      *Cursor
+	Limit      int
+}
+
+// ActivityFeedSort whitelists the sorts of the activity feed and its export.
+// The feed is paged by a created_at cursor, so only the direction varies.
+var ActivityFeedSort = NewSortFields(Sort{Field: "created_at", Direction: SortDesc}, map[string]string{
+	"created_at": "created_at",
+})
+
+// ActivityFeedPage is one page of the activity feed. NextCursor is empty on
+// the last page.
+type ActivityFeedPage struct {
+	Items      []CustomerActivity `json:"items"`
+	NextCursor string             `json:"next_cursor,omitempty"`
+}
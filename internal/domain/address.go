@@ -9,28 +9,50 @@
 package domain
 
 import (
+	"errors"
+	"strings"
 	"time"
+	"unicode"
 
 	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/domain/address"
+	"github.com/Ecom-micro-template/service-customer/internal/domain/shared"
 	"gorm.io/gorm"
 )
 
+// AddressRestoreWindow is how long a deleted address can be restored
+const AddressRestoreWindow = 30 * 24 * time.Hour
+
+// ErrAddressRestoreExpired is returned when restoring an address deleted
+// longer ago than AddressRestoreWindow
+var ErrAddressRestoreExpired = errors.New("address was deleted too long ago to restore")
+
 // Address represents a customer shipping/billing address
 type Address struct {
-	ID            uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
-	UserID        uuid.UUID `gorm:"type:uuid;not null;index" json:"user_id"`
-	Label         string    `gorm:"type:varchar(50)" json:"label"` // Home, Office, Other
-	RecipientName string    `gorm:"type:varchar(200);not null" json:"recipient_name"`
-	Phone         string    `gorm:"type:varchar(50);not null" json:"phone"`
-	AddressLine1  string    `gorm:"type:varchar(500);not null" json:"address_line1"`
-	AddressLine2  string    `gorm:"type:varchar(500)" json:"address_line2,omitempty"`
-	City          string    `gorm:"type:varchar(100);not null" json:"city"`
-	State         string    `gorm:"type:varchar(100);not null" json:"state"`
-	Postcode      string    `gorm:"type:varchar(20);not null" json:"postcode"`
-	Country       string    `gorm:"type:varchar(100);not null;default:'USA'" json:"country"`
-	IsDefault     bool      `gorm:"default:false" json:"is_default"`
-	CreatedAt     time.Time `json:"created_at"`
-	UpdatedAt     time.Time `json:"updated_at"`
+	ID            uuid.UUID           `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	UserID        uuid.UUID           `gorm:"type:uuid;not null;index" json:"user_id"`
+	Label         shared.AddressLabel `gorm:"type:varchar(50)" json:"label"`
+	RecipientName string              `gorm:"type:varchar(200);not null" json:"recipient_name"`
+	Phone         string              `gorm:"type:text;not null;serializer:encrypted" json:"phone"`
+	AddressLine1  string              `gorm:"type:varchar(500);not null" json:"address_line1"`
+	AddressLine2  string              `gorm:"type:varchar(500)" json:"address_line2,omitempty"`
+	City          string              `gorm:"type:varchar(100);not null" json:"city"`
+	State         string              `gorm:"type:varchar(100);not null" json:"state"`
+	Postcode      string              `gorm:"type:varchar(20);not null" json:"postcode"`
+	Country       string              `gorm:"type:varchar(100);not null;default:'USA'" json:"country"`
+	IsDefault     bool                `gorm:"default:false" json:"is_default"`
+
+	// Geocoding from the address validation provider
+	Latitude    *float64   `gorm:"type:decimal(10,7)" json:"latitude,omitempty"`
+	Longitude   *float64   `gorm:"type:decimal(10,7)" json:"longitude,omitempty"`
+	ValidatedAt *time.Time `json:"validated_at,omitempty"`
+
+	// Version for optimistic locking, see ErrVersionConflict
+	Version int64 `gorm:"not null;default:1" json:"version"`
+
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
 }
 
 // TableName specifies the table name for Address
@@ -43,5 +65,38 @@ func (a *Address) BeforeCreate(tx *gorm.DB) error {
 	if a.ID == uuid.Nil {
 		a.ID = uuid.New()
 	}
+	if a.Version == 0 {
+		a.Version = 1
+	}
 	return nil
 }
+
+// SameLocation reports whether two addresses point at the same place, ignoring
+// case, punctuation and spacing differences. Recipient, phone and label are
+// not compared.
+func (a *Address) SameLocation(other *Address) bool {
+	return normalizeAddressPart(a.AddressLine1) == normalizeAddressPart(other.AddressLine1) &&
+		normalizeAddressPart(a.AddressLine2) == normalizeAddressPart(other.AddressLine2) &&
+		normalizeAddressPart(a.City) == normalizeAddressPart(other.City) &&
+		normalizeAddressPart(a.Postcode) == normalizeAddressPart(other.Postcode) &&
+		normalizeCountry(a.Country) == normalizeCountry(other.Country)
+}
+
+// normalizeAddressPart lower-cases s, drops punctuation and collapses whitespace
+func normalizeAddressPart(s string) string {
+	s = strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return ' '
+	}, s)
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// normalizeCountry maps country names and codes to a comparable value
+func normalizeCountry(country string) string {
+	if code := address.CountryCode(country); code != "" {
+		return code
+	}
+	return normalizeAddressPart(country)
+}
@@ -71,6 +71,15 @@ func NewAddress(params AddressParams) (*Address, error) {
 		return nil, errors.New("postcode is required")
 	}
 
+	country := params.Country
+	if country == "" {
+		country = "Malaysia"
+	}
+
+	if err := NewCountryPolicy(nil).Validate(country, params.Postcode, params.Phone); err != nil {
+		return nil, err
+	}
+
 	phone, _ := shared.NewPhone(params.Phone)
 
 	label, err := ParseAddressType(params.Label)
@@ -83,11 +92,6 @@ func NewAddress(params AddressParams) (*Address, error) {
 		id = uuid.New()
 	}
 
-	country := params.Country
-	if country == "" {
-		country = "Malaysia"
-	}
-
 	now := time.Now()
 	return &Address{
 		id:            id,
@@ -137,6 +141,20 @@ func (a *Address) FullAddress() string {
 
 // Update updates the address details.
 func (a *Address) Update(params AddressParams) error {
+	country, postcode, phone := a.country, a.postcode, a.phone.Value()
+	if params.Country != "" {
+		country = params.Country
+	}
+	if params.Postcode != "" {
+		postcode = params.Postcode
+	}
+	if params.Phone != "" {
+		phone = params.Phone
+	}
+	if err := NewCountryPolicy(nil).Validate(country, postcode, phone); err != nil {
+		return err
+	}
+
 	if params.RecipientName != "" {
 		a.recipientName = strings.TrimSpace(params.RecipientName)
 	}
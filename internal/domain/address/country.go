@@ -0,0 +1,160 @@
+package address
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// FieldError identifies a single invalid address field
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationError is returned when one or more address fields fail the
+// country-specific rules.
+type ValidationError struct {
+	Fields []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	parts := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		parts[i] = f.Field + ": " + f.Message
+	}
+	return ErrInvalidAddress.Error() + ": " + strings.Join(parts, "; ")
+}
+
+// Unwrap allows errors.Is(err, ErrInvalidAddress)
+func (e *ValidationError) Unwrap() error {
+	return ErrInvalidAddress
+}
+
+// countryRule holds the postcode and phone formats of a country.
+// Phone patterns match the number with spaces, dashes, dots and parentheses removed.
+type countryRule struct {
+	postcode        *regexp.Regexp
+	postcodeExample string
+	phone           *regexp.Regexp
+	phoneExample    string
+}
+
+var countryRules = map[string]countryRule{
+	"MY": {
+		postcode:        regexp.MustCompile(`^\d{5}$`),
+		postcodeExample: "50450",
+		phone:           regexp.MustCompile(`^(?:\+?60|0)(?:1\d{8,9}|[3-9]\d{7,8})$`),
+		phoneExample:    "+60 12-345 6789",
+	},
+	"SG": {
+		postcode:        regexp.MustCompile(`^\d{6}$`),
+		postcodeExample: "238859",
+		phone:           regexp.MustCompile(`^(?:\+?65)?[689]\d{7}$`),
+		phoneExample:    "+65 9123 4567",
+	},
+	"ID": {
+		postcode:        regexp.MustCompile(`^\d{5}$`),
+		postcodeExample: "10310",
+		phone:           regexp.MustCompile(`^(?:\+?62|0)(?:8\d{7,11}|[2-7]\d{6,9})$`),
+		phoneExample:    "+62 812-3456-7890",
+	},
+	"US": {
+		postcode:        regexp.MustCompile(`^\d{5}(?:-\d{4})?$`),
+		postcodeExample: "94105 or 94105-1234",
+		phone:           regexp.MustCompile(`^(?:\+?1)?[2-9]\d{2}[2-9]\d{6}$`),
+		phoneExample:    "+1 415-555-0123",
+	},
+}
+
+// countryCodes maps common country names to ISO 3166-1 alpha-2 codes
+var countryCodes = map[string]string{
+	"malaysia":       "MY",
+	"singapore":      "SG",
+	"brunei":         "BN",
+	"indonesia":      "ID",
+	"thailand":       "TH",
+	"usa":            "US",
+	"united states":  "US",
+	"united kingdom": "GB",
+	"uk":             "GB",
+	"australia":      "AU",
+}
+
+var phoneSeparators = strings.NewReplacer(" ", "", "-", "", ".", "", "(", "", ")", "")
+
+// CountryCode returns the ISO 3166-1 alpha-2 code for a country name or code.
+// Returns an empty string if the country is unknown.
+func CountryCode(country string) string {
+	country = strings.TrimSpace(country)
+	if len(country) == 2 {
+		return strings.ToUpper(country)
+	}
+	return countryCodes[strings.ToLower(country)]
+}
+
+// CountryPolicy validates addresses against the countries the store ships to
+// and each country's postcode and phone formats.
+type CountryPolicy struct {
+	allowed map[string]bool
+}
+
+// NewCountryPolicy creates a policy allowing the given countries (names or
+// ISO codes). An empty list allows every country.
+func NewCountryPolicy(allowed []string) CountryPolicy {
+	policy := CountryPolicy{}
+	for _, country := range allowed {
+		if code := CountryCode(country); code != "" {
+			if policy.allowed == nil {
+				policy.allowed = make(map[string]bool)
+			}
+			policy.allowed[code] = true
+		}
+	}
+	return policy
+}
+
+// Allows reports whether addresses in the country are accepted
+func (p CountryPolicy) Allows(country string) bool {
+	if len(p.allowed) == 0 {
+		return true
+	}
+	return p.allowed[CountryCode(country)]
+}
+
+// Validate checks the country, postcode and phone of an address.
+// Countries without format rules only get the allow-list check, and an empty
+// phone is skipped (required fields are enforced by the caller).
+// Returns a *ValidationError listing every offending field, or nil.
+func (p CountryPolicy) Validate(country, postcode, phone string) error {
+	if !p.Allows(country) {
+		return &ValidationError{Fields: []FieldError{{
+			Field:   "country",
+			Message: fmt.Sprintf("we do not deliver to %q", strings.TrimSpace(country)),
+		}}}
+	}
+
+	rule, ok := countryRules[CountryCode(country)]
+	if !ok {
+		return nil
+	}
+
+	var fields []FieldError
+	if !rule.postcode.MatchString(strings.TrimSpace(postcode)) {
+		fields = append(fields, FieldError{
+			Field:   "postcode",
+			Message: "invalid postcode format, expected e.g. " + rule.postcodeExample,
+		})
+	}
+	if phone = phoneSeparators.Replace(strings.TrimSpace(phone)); phone != "" && !rule.phone.MatchString(phone) {
+		fields = append(fields, FieldError{
+			Field:   "phone",
+			Message: "invalid phone number for this country, expected e.g. " + rule.phoneExample,
+		})
+	}
+
+	if len(fields) > 0 {
+		return &ValidationError{Fields: fields}
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package address
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// invalidFields returns the fields err reports as invalid, in order
+func invalidFields(t *testing.T, err error) []string {
+	t.Helper()
+	if err == nil {
+		return nil
+	}
+	var validationErr *ValidationError
+	require.True(t, errors.As(err, &validationErr), "got %v", err)
+	assert.ErrorIs(t, err, ErrInvalidAddress)
+	fields := make([]string, len(validationErr.Fields))
+	for i, f := range validationErr.Fields {
+		fields[i] = f.Field
+	}
+	return fields
+}
+
+func TestCountryPolicy_ValidatePostcode(t *testing.T) {
+	tests := []struct {
+		country  string
+		postcode string
+		valid    bool
+	}{
+		{"MY", "50450", true},
+		{"Malaysia", " 50450 ", true},
+		{"MY", "5045", false},
+		{"MY", "504500", false},
+		{"SG", "238859", true},
+		{"SG", "23885", false},
+		{"ID", "10310", true},
+		{"ID", "1031A", false},
+		{"US", "94105", true},
+		{"US", "94105-1234", true},
+		{"US", "94105-12", false},
+		{"US", "9410", false},
+	}
+	policy := NewCountryPolicy(nil)
+	for _, tt := range tests {
+		t.Run(tt.country+" "+tt.postcode, func(t *testing.T) {
+			fields := invalidFields(t, policy.Validate(tt.country, tt.postcode, ""))
+			if tt.valid {
+				assert.Empty(t, fields)
+			} else {
+				assert.Equal(t, []string{"postcode"}, fields)
+			}
+		})
+	}
+}
+
+func TestCountryPolicy_ValidatePhone(t *testing.T) {
+	tests := []struct {
+		country  string
+		postcode string
+		phone    string
+		valid    bool
+	}{
+		{"MY", "50450", "+60 12-345 6789", true},
+		{"MY", "50450", "012-345 6789", true},
+		{"MY", "50450", "03-2161 1234", true},
+		{"MY", "50450", "(03) 2161.1234", true},
+		{"MY", "50450", "+65 9123 4567", false},
+		{"MY", "50450", "12345", false},
+		{"SG", "238859", "+65 9123 4567", true},
+		{"SG", "238859", "6123 4567", true},
+		{"SG", "238859", "5123 4567", false},
+		{"ID", "10310", "+62 812-3456-7890", true},
+		{"ID", "10310", "0812 3456 7890", true},
+		{"ID", "10310", "+60 12-345 6789", false},
+		{"US", "94105", "+1 415-555-0123", true},
+		{"US", "94105", "(415) 555-0123", true},
+		{"US", "94105", "115-555-0123", false},
+		{"MY", "50450", "", true},
+	}
+	policy := NewCountryPolicy(nil)
+	for _, tt := range tests {
+		t.Run(tt.country+" "+tt.phone, func(t *testing.T) {
+			fields := invalidFields(t, policy.Validate(tt.country, tt.postcode, tt.phone))
+			if tt.valid {
+				assert.Empty(t, fields)
+			} else {
+				assert.Equal(t, []string{"phone"}, fields)
+			}
+		})
+	}
+}
+
+func TestCountryPolicy_ValidateReportsEveryField(t *testing.T) {
+	err := NewCountryPolicy(nil).Validate("SG", "50450", "+60 12-345 6789")
+	assert.Equal(t, []string{"postcode", "phone"}, invalidFields(t, err))
+}
+
+func TestCountryPolicy_UnknownCountryFallsBack(t *testing.T) {
+	tests := []struct {
+		name    string
+		country string
+	}{
+		{"known code without rules", "TH"},
+		{"known name without rules", "Australia"},
+		{"unknown code", "ZZ"},
+		{"unknown name", "Atlantis"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Only the allow list applies; any postcode and phone pass
+			assert.NoError(t, NewCountryPolicy(nil).Validate(tt.country, "x", "not a phone"))
+		})
+	}
+}
+
+func TestCountryPolicy_AllowList(t *testing.T) {
+	policy := NewCountryPolicy([]string{"Malaysia", "sg"})
+
+	assert.NoError(t, policy.Validate("MY", "50450", ""))
+	assert.NoError(t, policy.Validate("Singapore", "238859", ""))
+	assert.Equal(t, []string{"country"}, invalidFields(t, policy.Validate("US", "94105", "")))
+	assert.Equal(t, []string{"country"}, invalidFields(t, policy.Validate("Atlantis", "x", "")),
+		"unknown countries are rejected when a list is set")
+}
@@ -0,0 +1,53 @@
+package domain
+
+import (
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// AdminRegionAssignment assigns a state to an admin user, e.g. a sales agent
+// who may only see customers whose default address is in that state
+type AdminRegionAssignment struct {
+	ID          uuid.UUID  `gorm:"type:uuid;primary_key" json:"id"`
+	AdminUserID uuid.UUID  `gorm:"type:uuid;not null;uniqueIndex:idx_admin_region_state" json:"admin_user_id"`
+	State       string     `gorm:"type:varchar(100);not null;uniqueIndex:idx_admin_region_state" json:"state"`
+	AssignedBy  *uuid.UUID `gorm:"type:uuid" json:"assigned_by,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+func (a *AdminRegionAssignment) BeforeCreate(tx *gorm.DB) error {
+	if a.ID == uuid.Nil {
+		a.ID = uuid.New()
+	}
+	return nil
+}
+
+func (AdminRegionAssignment) TableName() string {
+	return "public.admin_region_assignments"
+}
+
+// RegionScope limits an admin user to customers whose default address is in
+// one of the states. A scope without states matches no customer.
+type RegionScope struct {
+	States []string `json:"states"`
+}
+
+// NormalizedStates returns the states lower-cased and trimmed for
+// case-insensitive matching against address states
+func (s *RegionScope) NormalizedStates() []string {
+	states := make([]string, 0, len(s.States))
+	for _, state := range s.States {
+		if state = strings.ToLower(strings.TrimSpace(state)); state != "" {
+			states = append(states, state)
+		}
+	}
+	return states
+}
+
+// SetRegionAssignmentInput replaces the states assigned to an admin user
+type SetRegionAssignmentInput struct {
+	States []string `json:"states"`
+}
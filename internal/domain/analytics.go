@@ -0,0 +1,187 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// Cohort analytics limits
+const (
+	DefaultCohortMonths = 12
+	MaxCohortMonths     = 36
+)
+
+// Growth series intervals
+const (
+	GrowthIntervalDay  = "day"
+	GrowthIntervalWeek = "week"
+)
+
+// MaxGrowthPoints caps the points of one growth series
+const MaxGrowthPoints = 366
+
+// Analytics errors
+var (
+	ErrInvalidGrowthInterval = errors.New("interval must be day or week")
+	ErrInvalidGrowthRange    = errors.New("to must not be before from")
+	ErrGrowthRangeTooLong    = fmt.Errorf("a growth series has at most %d points", MaxGrowthPoints)
+)
+
+// CohortSize is a row of the customer.customer_cohort_sizes materialized
+// view: the customers who signed up in a month and how many of them bought
+type CohortSize struct {
+	Cohort       time.Time `gorm:"type:date"`
+	Customers    int64
+	Buyers       int64
+	RepeatBuyers int64
+}
+
+// TableName specifies the materialized view of CohortSize
+func (CohortSize) TableName() string {
+	return "customer.customer_cohort_sizes"
+}
+
+// CohortActivity is a row of the customer.customer_cohort_activity
+// materialized view: how many customers of a cohort completed an order
+// MonthOffset months after the month they signed up
+type CohortActivity struct {
+	Cohort          time.Time `gorm:"type:date"`
+	MonthOffset     int
+	ActiveCustomers int64
+}
+
+// TableName specifies the materialized view of CohortActivity
+func (CohortActivity) TableName() string {
+	return "customer.customer_cohort_activity"
+}
+
+// CohortRetention is the share of a cohort that completed an order in the
+// month MonthOffset months after signing up; month 0 is the signup month
+type CohortRetention struct {
+	MonthOffset     int     `json:"month_offset"`
+	ActiveCustomers int64   `json:"active_customers"`
+	Rate            float64 `json:"rate"`
+}
+
+// Cohort is the customers who signed up in one month, with their
+// repeat-purchase rate and monthly retention up to the current month
+type Cohort struct {
+	Month              string            `json:"month"` // YYYY-MM
+	Customers          int64             `json:"customers"`
+	Buyers             int64             `json:"buyers"`
+	RepeatBuyers       int64             `json:"repeat_buyers"`
+	RepeatPurchaseRate float64           `json:"repeat_purchase_rate"` // of buyers
+	Retention          []CohortRetention `json:"retention"`
+}
+
+// NewCohort builds a cohort from its size and activity rows, filling in the
+// months without activity up to now
+func NewCohort(size CohortSize, activity []CohortActivity, now time.Time) Cohort {
+	cohort := Cohort{
+		Month:              size.Cohort.Format("2006-01"),
+		Customers:          size.Customers,
+		Buyers:             size.Buyers,
+		RepeatBuyers:       size.RepeatBuyers,
+		RepeatPurchaseRate: rate(size.RepeatBuyers, size.Buyers),
+	}
+
+	months := (now.Year()-size.Cohort.Year())*12 + int(now.Month()-size.Cohort.Month())
+	active := make(map[int]int64, len(activity))
+	for _, a := range activity {
+		active[a.MonthOffset] = a.ActiveCustomers
+	}
+	cohort.Retention = make([]CohortRetention, 0, months+1)
+	for offset := 0; offset <= months; offset++ {
+		cohort.Retention = append(cohort.Retention, CohortRetention{
+			MonthOffset:     offset,
+			ActiveCustomers: active[offset],
+			Rate:            rate(active[offset], size.Customers),
+		})
+	}
+	return cohort
+}
+
+// GrowthPoint is the customers who signed up and made their first order in
+// the interval starting at Start, and the customer total at its end
+type GrowthPoint struct {
+	Start          time.Time `json:"start"`
+	Signups        int64     `json:"signups"`
+	FirstPurchases int64     `json:"first_purchases"`
+	TotalCustomers int64     `json:"total_customers"`
+}
+
+// GrowthSeries is customer growth per day or week
+type GrowthSeries struct {
+	Interval string        `json:"interval"`
+	From     time.Time     `json:"from"`
+	To       time.Time     `json:"to"`
+	Points   []GrowthPoint `json:"points"`
+}
+
+// GrowthRange validates a growth series request and returns the start of
+// the first and last intervals. Weeks start on Monday.
+func GrowthRange(interval string, from, to time.Time) (time.Time, time.Time, error) {
+	if interval != GrowthIntervalDay && interval != GrowthIntervalWeek {
+		return time.Time{}, time.Time{}, ErrInvalidGrowthInterval
+	}
+	if to.Before(from) {
+		return time.Time{}, time.Time{}, ErrInvalidGrowthRange
+	}
+	from, to = truncateInterval(interval, from), truncateInterval(interval, to)
+	days := int(to.Sub(from).Hours() / 24)
+	if interval == GrowthIntervalWeek {
+		days /= 7
+	}
+	if days+1 > MaxGrowthPoints {
+		return time.Time{}, time.Time{}, ErrGrowthRangeTooLong
+	}
+	return from, to, nil
+}
+
+// NewGrowthSeries builds the series from from to to from the signups and
+// first purchases per interval, keyed by the interval's start date
+// (YYYY-MM-DD). The running total starts from the customers who signed up
+// before from.
+func NewGrowthSeries(interval string, from, to time.Time, before int64, signups, firstPurchases map[string]int64) GrowthSeries {
+	series := GrowthSeries{Interval: interval, From: from, To: to, Points: []GrowthPoint{}}
+	total := before
+	for start := from; !start.After(to); start = GrowthIntervalEnd(interval, start) {
+		day := start.Format("2006-01-02")
+		total += signups[day]
+		series.Points = append(series.Points, GrowthPoint{
+			Start:          start,
+			Signups:        signups[day],
+			FirstPurchases: firstPurchases[day],
+			TotalCustomers: total,
+		})
+	}
+	return series
+}
+
+// GrowthIntervalEnd returns the start of the interval after the one starting
+// at start
+func GrowthIntervalEnd(interval string, start time.Time) time.Time {
+	if interval == GrowthIntervalWeek {
+		return start.AddDate(0, 0, 7)
+	}
+	return start.AddDate(0, 0, 1)
+}
+
+func truncateInterval(interval string, t time.Time) time.Time {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	if interval == GrowthIntervalWeek {
+		// Monday is the first day of the week, as for Postgres date_trunc
+		day = day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+	}
+	return day
+}
+
+// rate returns part/whole rounded to 4 decimals, or 0 for an empty whole
+func rate(part, whole int64) float64 {
+	if whole == 0 {
+		return 0
+	}
+	return math.Round(float64(part)/float64(whole)*10000) / 10000
+}
@@ -0,0 +1,118 @@
+package domain
+
+import (
+	"reflect"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Audited admin actions. Routes may also use a more specific action, e.g. "merge".
+const (
+	AuditActionCreate = "create"
+	AuditActionUpdate = "update"
+	AuditActionDelete = "delete"
+	AuditActionBulk   = "bulk"
+)
+
+// Audited entity types
+const (
+	AuditEntityCustomer        = "customer"
+	AuditEntityCustomerNote    = "customer_note"
+	AuditEntityNoteAttachment  = "note_attachment"
+	AuditEntityActivity        = "customer_activity"
+	AuditEntitySegment         = "segment"
+	AuditEntitySegmentRule     = "segment_rule"
+	AuditEntityRegion          = "region_assignment"
+	AuditEntityImpersonation   = "impersonation"
+	AuditEntityWallet          = "wallet"
+	AuditEntityBackInStock     = "back_in_stock_subscription"
+	AuditEntityCustomerView    = "customer_list_view"
+	AuditEntityCustomerLimit   = "customer_limit"
+	AuditEntityDuplicate       = "duplicate_candidate"
+	AuditEntityBlocklist       = "blocklist_entry"
+	AuditEntityWebhook         = "webhook_subscription"
+	AuditEntityMarketingSync   = "marketing_sync"
+	AuditEntityScheduledExport = "scheduled_export"
+	AuditEntityCustomerExport  = "customer_export"
+)
+
+// auditIgnoredFields change on every write and would otherwise show up in every diff
+var auditIgnoredFields = map[string]bool{"updated_at": true, "version": true}
+
+// AuditLog records one admin mutation: who did what to which entity, with
+// the entity as it was before and after the change
+type AuditLog struct {
+	ID         uuid.UUID  `gorm:"type:uuid;primary_key" json:"id"`
+	ActorID    uuid.UUID  `gorm:"type:uuid;not null;index" json:"actor_id"`
+	ActorRole  string     `gorm:"type:varchar(50)" json:"actor_role,omitempty"`
+	Action     string     `gorm:"type:varchar(50);not null;index" json:"action"`
+	EntityType string     `gorm:"type:varchar(50);not null;index:idx_audit_logs_entity" json:"entity_type"`
+	EntityID   *uuid.UUID `gorm:"type:uuid;index:idx_audit_logs_entity" json:"entity_id,omitempty"`
+	Method     string     `gorm:"type:varchar(10);not null" json:"method"`
+	Path       string     `gorm:"type:varchar(255);not null" json:"path"`
+	Status     int        `json:"status"`
+
+	Before  map[string]any         `gorm:"type:jsonb;serializer:json" json:"before,omitempty"`
+	After   map[string]any         `gorm:"type:jsonb;serializer:json" json:"after,omitempty"`
+	Changes map[string]AuditChange `gorm:"type:jsonb;serializer:json" json:"changes,omitempty"`
+	Request map[string]any         `gorm:"type:jsonb;serializer:json" json:"request,omitempty"` // request body, e.g. the IDs of a bulk action
+
+	IPAddress string    `gorm:"type:varchar(45)" json:"ip_address,omitempty"`
+	RequestID string    `gorm:"type:varchar(100)" json:"request_id,omitempty"`
+	CreatedAt time.Time `gorm:"index" json:"created_at"`
+}
+
+func (a *AuditLog) BeforeCreate(tx *gorm.DB) error {
+	if a.ID == uuid.Nil {
+		a.ID = uuid.New()
+	}
+	return nil
+}
+
+func (AuditLog) TableName() string {
+	return "customer.audit_logs"
+}
+
+// AuditChange is the old and new value of one changed field
+type AuditChange struct {
+	From any `json:"from"`
+	To   any `json:"to"`
+}
+
+// AuditLogFilter narrows the audit log listing
+type AuditLogFilter struct {
+	EntityType string
+	EntityID   *uuid.UUID
+	ActorID    *uuid.UUID
+	Action     string
+	DateFrom   *time.Time
+	DateTo     *time.Time
+	Page       int
+	Limit      int
+}
+
+// DiffAuditSnapshots returns the top-level fields that differ between two
+// snapshots. A nil snapshot (before a create, after a delete) is treated as
+// having no fields.
+func DiffAuditSnapshots(before, after map[string]any) map[string]AuditChange {
+	changes := make(map[string]AuditChange)
+	for field, from := range before {
+		if to, ok := after[field]; !ok || !reflect.DeepEqual(from, to) {
+			changes[field] = AuditChange{From: from, To: after[field]}
+		}
+	}
+	for field, to := range after {
+		if _, ok := before[field]; !ok {
+			changes[field] = AuditChange{From: nil, To: to}
+		}
+	}
+	for field := range auditIgnoredFields {
+		delete(changes, field)
+	}
+	if len(changes) == 0 {
+		return nil
+	}
+	return changes
+}
@@ -0,0 +1,45 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// AvatarSize is a standard square size avatars are cropped and resized to
+type AvatarSize struct {
+	Name   string
+	Pixels int
+}
+
+// AvatarSizes are the sizes stored for every avatar, smallest first. The
+// profile picture URL is the "medium" one.
+var AvatarSizes = []AvatarSize{
+	{Name: "small", Pixels: 64},
+	{Name: "medium", Pixels: 256},
+	{Name: "large", Pixels: 512},
+}
+
+// ProfilePictureSize names the avatar size returned as Profile.ProfilePicture
+const ProfilePictureSize = "medium"
+
+// DefaultAvatarMaxSize bounds an uploaded avatar when no limit is configured
+const DefaultAvatarMaxSize = 5 << 20
+
+// Avatar upload errors
+var (
+	ErrAvatarTooLarge = errors.New("avatar is too large")
+	ErrAvatarInvalid  = errors.New("avatar must be a JPEG, PNG, GIF or WebP image")
+)
+
+// AvatarKeyPrefix is the file store prefix of all avatars, which are served
+// publicly unlike other files
+const AvatarKeyPrefix = "avatars/"
+
+// AvatarKey returns the file store key of one size of an avatar. Every
+// upload gets a new upload ID, so a key's content never changes and CDNs
+// can cache it indefinitely.
+func AvatarKey(userID, uploadID uuid.UUID, size string) string {
+	return fmt.Sprintf("%s%s/%s/%s.jpg", AvatarKeyPrefix, userID, uploadID, size)
+}
@@ -8,6 +8,7 @@
 package domain
 
 import (
+	"errors"
 	"time"
 
 	"github.com/google/uuid"
@@ -16,6 +17,10 @@ import (
 
 // HI-001: Back-in-Stock Subscription Model
 
+// DefaultBackInStockSubscriptionTTL is how long a subscription waits for a
+// restock before it expires
+const DefaultBackInStockSubscriptionTTL = 90 * 24 * time.Hour
+
 // BackInStockSubscription represents a customer's subscription to be notified
 // when an out-of-stock product becomes available again
 type BackInStockSubscription struct {
@@ -35,6 +40,10 @@ type BackInStockSubscription struct {
 	IsNotified         bool       `gorm:"default:false" json:"isNotified"`
 	NotificationSentAt *time.Time `json:"notificationSentAt,omitempty"`
 
+	// Pending subscriptions are removed by the expiry job after this time;
+	// subscribing again renews it
+	ExpiresAt *time.Time `gorm:"index:idx_bis_expires" json:"expiresAt,omitempty"`
+
 	// Timestamps
 	CreatedAt time.Time      `json:"createdAt"`
 	UpdatedAt time.Time      `json:"updatedAt"`
@@ -48,6 +57,13 @@ func (BackInStockSubscription) TableName() string {
 	return "customer.back_in_stock_subscriptions"
 }
 
+func (s *BackInStockSubscription) BeforeCreate(tx *gorm.DB) error {
+	if s.ID == uuid.Nil {
+		s.ID = uuid.New()
+	}
+	return nil
+}
+
 // BackInStockSubscribeInput is the request body for subscribing
 type BackInStockSubscribeInput struct {
 	ProductID    string `json:"productId" binding:"required"`
@@ -66,6 +82,18 @@ type BackInStockStats struct {
 	SentNotifications    int64 `json:"sentNotifications"`
 	UniqueProducts       int64 `json:"uniqueProducts"`
 	UniqueCustomers      int64 `json:"uniqueCustomers"`
+
+	// Failed sends queued for retry, and those that ran out of attempts
+	RetryingNotifications int64 `json:"retryingNotifications"`
+	FailedNotifications   int64 `json:"failedNotifications"`
+}
+
+// BackInStockThrottle limits how many back-in-stock emails one customer (or
+// guest email) receives within Window. Notifications over the limit stay
+// pending and go out on a later restock. A zero Limit disables throttling.
+type BackInStockThrottle struct {
+	Limit  int
+	Window time.Duration
 }
 
 // BackInStockNotification is the data sent to notification service
@@ -82,4 +110,184 @@ type BackInStockNotification struct {
 	VariantSKU     string `json:"variantSku,omitempty"`
 	VariantName    string `json:"variantName,omitempty"`
 	StockQuantity  int    `json:"stockQuantity"`
+	// Channel is the customer's preferred channel; empty means email
+	Channel string `json:"channel,omitempty"`
+	// IsTest marks admin test sends; CustomerEmail is then the admin's address
+	IsTest bool `json:"isTest,omitempty"`
+}
+
+// Notification builds the notification sent to the subscriber when the
+// product is restocked with the given quantity
+func (s *BackInStockSubscription) Notification(stockQuantity int) BackInStockNotification {
+	notification := BackInStockNotification{
+		SubscriptionID: s.ID.String(),
+		CustomerID:     s.CustomerID.String(),
+		ProductID:      s.ProductID.String(),
+		ProductName:    s.ProductName,
+		ProductSlug:    s.ProductSlug,
+		ProductImage:   s.ProductImage,
+		VariantSKU:     s.VariantSKU,
+		VariantName:    s.VariantName,
+		StockQuantity:  stockQuantity,
+	}
+	if s.VariantID != nil {
+		notification.VariantID = s.VariantID.String()
+	}
+	if s.Customer != nil {
+		notification.CustomerEmail = s.Customer.Email
+		notification.CustomerName = s.Customer.FirstName + " " + s.Customer.LastName
+	}
+	return notification
+}
+
+// BackInStockTestNotificationInput is the request body for an admin test send
+type BackInStockTestNotificationInput struct {
+	SubscriptionID string `json:"subscription_id" binding:"required"`
+	Email          string `json:"email" binding:"required,email"`
+	StockQuantity  int    `json:"stock_quantity"`
+}
+
+// BackInStockNotifyInput is the request body for notifying a product's
+// subscribers by hand. Limit 0 notifies everyone waiting.
+type BackInStockNotifyInput struct {
+	VariantID     string `json:"variant_id"`
+	Limit         int    `json:"limit" binding:"min=0"`
+	StockQuantity int    `json:"stock_quantity"`
+	DryRun        bool   `json:"dry_run"`
+}
+
+// BackInStockNotifyResult is what notifying a product's subscribers by hand
+// did, or with DryRun would do
+type BackInStockNotifyResult struct {
+	Waiting    int                    `json:"waiting"`
+	Selected   int                    `json:"selected"` // tried, longest waiting first; for dry runs the limit longest waiting
+	Notified   int                    `json:"notified"` // up to the limit; those throttled, held back or failed make way for the next in line
+	DryRun     bool                   `json:"dry_run"`
+	Recipients []BackInStockRecipient `json:"recipients,omitempty"` // dry runs only
+}
+
+// BackInStockRecipient is a subscriber a dry run would notify
+type BackInStockRecipient struct {
+	SubscriptionID uuid.UUID  `json:"subscription_id"`
+	Subscriber     string     `json:"subscriber"` // customer or guest
+	CustomerID     *uuid.UUID `json:"customer_id,omitempty"`
+	Email          string     `json:"email"`
+	SubscribedAt   time.Time  `json:"subscribed_at"`
+}
+
+// BackInStockRecipients lists the subscribers of customer and guest
+// subscriptions
+func BackInStockRecipients(subscriptions []BackInStockSubscription, guests []GuestBackInStockSubscription) []BackInStockRecipient {
+	recipients := make([]BackInStockRecipient, 0, len(subscriptions)+len(guests))
+	for _, s := range subscriptions {
+		recipient := BackInStockRecipient{SubscriptionID: s.ID, Subscriber: "customer", CustomerID: &s.CustomerID, SubscribedAt: s.CreatedAt}
+		if s.Customer != nil {
+			recipient.Email = s.Customer.Email
+		}
+		recipients = append(recipients, recipient)
+	}
+	for _, s := range guests {
+		recipients = append(recipients, BackInStockRecipient{SubscriptionID: s.ID, Subscriber: "guest", Email: s.Email, SubscribedAt: s.CreatedAt})
+	}
+	return recipients
+}
+
+// Guest (email-only) back-in-stock subscriptions
+
+// GuestConfirmationTTL is how long a guest has to confirm a subscription by email
+const GuestConfirmationTTL = 48 * time.Hour
+
+// MaxGuestSubscriptionsPerEmail caps the open guest subscriptions for one email address
+const MaxGuestSubscriptionsPerEmail = 20
+
+var (
+	// ErrGuestTokenInvalid is returned when a confirmation token is unknown or expired
+	ErrGuestTokenInvalid = errors.New("confirmation token is invalid or expired")
+	// ErrGuestSubscriptionLimit is returned when an email has too many open subscriptions
+	ErrGuestSubscriptionLimit = errors.New("too many back-in-stock subscriptions for this email")
+)
+
+// GuestBackInStockSubscription is a back-in-stock subscription made without an
+// account. It only receives notifications once the email is confirmed, and is
+// moved to the customer's account if they later register with the same email.
+type GuestBackInStockSubscription struct {
+	ID        uuid.UUID  `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	Email     string     `gorm:"size:255;not null;index:idx_guest_bis_email" json:"email"`
+	ProductID uuid.UUID  `gorm:"type:uuid;not null;index:idx_guest_bis_product" json:"productId"`
+	VariantID *uuid.UUID `gorm:"type:uuid" json:"variantId,omitempty"`
+
+	// Denormalized product info for quick access
+	ProductName  string `gorm:"size:255" json:"productName"`
+	ProductSlug  string `gorm:"size:255" json:"productSlug"`
+	ProductImage string `gorm:"size:500" json:"productImage,omitempty"`
+	VariantSKU   string `gorm:"size:100" json:"variantSku,omitempty"`
+	VariantName  string `gorm:"size:255" json:"variantName,omitempty"`
+
+	// Email confirmation; only the SHA-256 of the token is stored
+	TokenHash      string     `gorm:"size:64;uniqueIndex" json:"-"`
+	TokenExpiresAt time.Time  `json:"-"`
+	ConfirmedAt    *time.Time `json:"confirmedAt,omitempty"`
+
+	// Notification tracking
+	IsNotified         bool       `gorm:"default:false" json:"isNotified"`
+	NotificationSentAt *time.Time `json:"notificationSentAt,omitempty"`
+
+	// Pending subscriptions are removed by the expiry job after this time
+	ExpiresAt *time.Time `gorm:"index:idx_guest_bis_expires" json:"expiresAt,omitempty"`
+
+	// Timestamps
+	CreatedAt time.Time      `json:"createdAt"`
+	UpdatedAt time.Time      `json:"updatedAt"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+func (GuestBackInStockSubscription) TableName() string {
+	return "customer.guest_back_in_stock_subscriptions"
+}
+
+func (s *GuestBackInStockSubscription) BeforeCreate(tx *gorm.DB) error {
+	if s.ID == uuid.Nil {
+		s.ID = uuid.New()
+	}
+	return nil
+}
+
+// Notification builds the notification sent to the guest when the product is
+// restocked with the given quantity
+func (s *GuestBackInStockSubscription) Notification(stockQuantity int) BackInStockNotification {
+	notification := BackInStockNotification{
+		SubscriptionID: s.ID.String(),
+		CustomerEmail:  s.Email,
+		ProductID:      s.ProductID.String(),
+		ProductName:    s.ProductName,
+		ProductSlug:    s.ProductSlug,
+		ProductImage:   s.ProductImage,
+		VariantSKU:     s.VariantSKU,
+		VariantName:    s.VariantName,
+		StockQuantity:  stockQuantity,
+	}
+	if s.VariantID != nil {
+		notification.VariantID = s.VariantID.String()
+	}
+	return notification
+}
+
+// IsConfirmed reports whether the guest confirmed their email
+func (s *GuestBackInStockSubscription) IsConfirmed() bool {
+	return s.ConfirmedAt != nil
+}
+
+// GuestBackInStockSubscribeInput is the request body for a guest subscription
+type GuestBackInStockSubscribeInput struct {
+	Email string `json:"email" binding:"required,email"`
+	BackInStockSubscribeInput
+}
+
+// BackInStockConfirmation is the confirm-email request sent to the notification service
+type BackInStockConfirmation struct {
+	Email       string    `json:"email"`
+	ProductName string    `json:"productName"`
+	VariantName string    `json:"variantName,omitempty"`
+	ConfirmURL  string    `json:"confirmUrl"`
+	ExpiresAt   time.Time `json:"expiresAt"`
 }
@@ -0,0 +1,92 @@
+package domain
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Back-in-stock demand report limits
+const (
+	DefaultBackInStockDemandLimit = 50
+	MaxBackInStockDemandLimit     = 500
+)
+
+// How the back-in-stock demand report is ranked
+const (
+	BackInStockDemandByWaiting = "waiting" // most waiting subscribers first
+	BackInStockDemandByWait    = "wait"    // longest average wait first
+)
+
+// BackInStockDemandSort whitelists the rankings of the demand report, most
+// waiting subscribers first by default
+var BackInStockDemandSort = NewSortFields(Sort{Field: BackInStockDemandByWaiting, Direction: SortDesc}, map[string]string{
+	BackInStockDemandByWaiting: "waiting",
+	BackInStockDemandByWait:    "average_wait",
+})
+
+// BackInStockExportHeader is the header row of the pending subscription
+// export; see BackInStockSubscription.ExportRow
+var BackInStockExportHeader = []string{
+	"subscription_id", "subscriber", "customer_id", "email", "name",
+	"product_id", "product_name", "variant_id", "variant_sku", "variant_name",
+	"subscribed_at", "expires_at", "waiting_days",
+}
+
+// ExportRow is the subscription's row in the pending subscription export as
+// of now, with the customer's email masked when maskPII is set
+func (s *BackInStockSubscription) ExportRow(now time.Time, maskPII bool) []string {
+	var email, name string
+	if s.Customer != nil {
+		email, name = s.Customer.Email, s.Customer.FirstName+" "+s.Customer.LastName
+	}
+	if maskPII {
+		email = MaskEmail(email)
+	}
+	return backInStockExportRow(s.ID, "customer", s.CustomerID.String(), email, name, s.ProductID, s.VariantID,
+		s.ProductName, s.VariantSKU, s.VariantName, s.CreatedAt, s.ExpiresAt, now)
+}
+
+// ExportRow is the guest subscription's row in the pending subscription
+// export as of now, with the email masked when maskPII is set
+func (s *GuestBackInStockSubscription) ExportRow(now time.Time, maskPII bool) []string {
+	email := s.Email
+	if maskPII {
+		email = MaskEmail(email)
+	}
+	return backInStockExportRow(s.ID, "guest", "", email, "", s.ProductID, s.VariantID,
+		s.ProductName, s.VariantSKU, s.VariantName, s.CreatedAt, s.ExpiresAt, now)
+}
+
+func backInStockExportRow(id uuid.UUID, subscriber, customerID, email, name string, productID uuid.UUID, variantID *uuid.UUID,
+	productName, variantSKU, variantName string, subscribedAt time.Time, expiresAt *time.Time, now time.Time) []string {
+	variant, expires := "", ""
+	if variantID != nil {
+		variant = variantID.String()
+	}
+	if expiresAt != nil {
+		expires = expiresAt.UTC().Format(time.RFC3339)
+	}
+	return []string{
+		id.String(), subscriber, customerID, email, name,
+		productID.String(), productName, variant, variantSKU, variantName,
+		subscribedAt.UTC().Format(time.RFC3339), expires,
+		strconv.Itoa(int(now.Sub(subscribedAt).Hours() / 24)),
+	}
+}
+
+// BackInStockDemand is how many customers and confirmed guests are waiting
+// for a product or variant to be restocked, and for how long
+type BackInStockDemand struct {
+	ProductID          uuid.UUID  `json:"productId"`
+	VariantID          *uuid.UUID `json:"variantId,omitempty"`
+	ProductName        string     `json:"productName"`
+	VariantSKU         string     `json:"variantSku,omitempty"`
+	VariantName        string     `json:"variantName,omitempty"`
+	Waiting            int        `json:"waiting"`
+	Customers          int        `json:"customers"`
+	Guests             int        `json:"guests"`
+	AverageWaitDays    float64    `json:"averageWaitDays"`
+	OldestSubscribedAt time.Time  `json:"oldestSubscribedAt"`
+}
@@ -0,0 +1,227 @@
+package domain
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// What a blocklist entry matches
+const (
+	BlocklistKindEmail   = "email"
+	BlocklistKindPhone   = "phone"
+	BlocklistKindAddress = "address"
+)
+
+// Who added a blocklist entry
+const (
+	BlocklistSourceAdmin           = "admin"            // an admin flagged the value
+	BlocklistSourceCustomerBlocked = "customer_blocked" // the customer it belongs to was blocked
+)
+
+// FraudCheckKindCustomer is the kind of the match reported when the
+// customer placing the order is blocked
+const FraudCheckKindCustomer = "customer"
+
+// Blocklist errors
+var (
+	ErrInvalidBlocklistEmail   = errors.New("value must be an email address")
+	ErrInvalidBlocklistPhone   = errors.New("value must be a phone number")
+	ErrBlocklistAddressMissing = errors.New("address is required for address entries")
+	ErrBlocklistExpiryPast     = errors.New("expires_at must be in the future")
+)
+
+// BlocklistEntry flags an email, phone number or address as fraudulent.
+// Value is normalized so variants of the same identifier match: emails as
+// for duplicate detection, phones as a PhoneBlindIndex and addresses as an
+// AddressFingerprint.
+// Entries stop matching at ExpiresAt; nil never expires.
+type BlocklistEntry struct {
+	ID         uuid.UUID  `gorm:"type:uuid;primary_key" json:"id"`
+	Kind       string     `gorm:"type:varchar(20);not null;uniqueIndex:idx_blocklist_entries_kind_value" json:"kind"`
+	Value      string     `gorm:"type:varchar(255);not null;uniqueIndex:idx_blocklist_entries_kind_value" json:"value"`
+	Label      string     `gorm:"type:varchar(500)" json:"label"` // the value as entered, for display
+	Reason     string     `gorm:"type:varchar(255);not null" json:"reason"`
+	Source     string     `gorm:"type:varchar(20);not null;default:'admin'" json:"source"`
+	CustomerID *uuid.UUID `gorm:"type:uuid;index" json:"customer_id,omitempty"` // the blocked customer, for customer_blocked entries
+	CreatedBy  *uuid.UUID `gorm:"type:uuid" json:"created_by,omitempty"`
+	ExpiresAt  *time.Time `gorm:"index" json:"expires_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+// BeforeCreate hook to ensure UUID is set
+func (e *BlocklistEntry) BeforeCreate(tx *gorm.DB) error {
+	if e.ID == uuid.Nil {
+		e.ID = uuid.New()
+	}
+	return nil
+}
+
+// TableName specifies the table name for BlocklistEntry
+func (BlocklistEntry) TableName() string {
+	return "customer.blocklist_entries"
+}
+
+// Active reports whether the entry still matches at now
+func (e *BlocklistEntry) Active(now time.Time) bool {
+	return e.ExpiresAt == nil || e.ExpiresAt.After(now)
+}
+
+// BlocklistAddress is an address to flag or check
+type BlocklistAddress struct {
+	AddressLine1 string `json:"address_line1" binding:"required,max=500"`
+	AddressLine2 string `json:"address_line2" binding:"max=500"`
+	City         string `json:"city" binding:"max=100"`
+	Postcode     string `json:"postcode" binding:"required,max=20"`
+	Country      string `json:"country" binding:"required,max=100"`
+}
+
+// AddressFingerprint identifies the place an address points at, ignoring
+// case, punctuation and spacing like Address.SameLocation
+func AddressFingerprint(a BlocklistAddress) string {
+	sum := sha256.Sum256([]byte(strings.Join([]string{
+		normalizeAddressPart(a.AddressLine1),
+		normalizeAddressPart(a.AddressLine2),
+		normalizeAddressPart(a.City),
+		normalizeAddressPart(a.Postcode),
+		normalizeCountry(a.Country),
+	}, "|")))
+	return hex.EncodeToString(sum[:])
+}
+
+// String formats the address on one line
+func (a BlocklistAddress) String() string {
+	parts := []string{}
+	for _, part := range []string{a.AddressLine1, a.AddressLine2, a.City, a.Postcode, a.Country} {
+		if part = strings.TrimSpace(part); part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// CreateBlocklistEntryRequest flags a value. Email and phone entries take
+// value, address entries take address.
+type CreateBlocklistEntryRequest struct {
+	Kind      string            `json:"kind" binding:"required,oneof=email phone address"`
+	Value     string            `json:"value" binding:"max=255"`
+	Address   *BlocklistAddress `json:"address"`
+	Reason    string            `json:"reason" binding:"required,max=255"`
+	ExpiresAt *time.Time        `json:"expires_at"`
+}
+
+// Entry builds the blocklist entry of the request, normalizing its value
+func (r *CreateBlocklistEntryRequest) Entry(now time.Time) (*BlocklistEntry, error) {
+	if r.ExpiresAt != nil && !r.ExpiresAt.After(now) {
+		return nil, ErrBlocklistExpiryPast
+	}
+	entry := &BlocklistEntry{
+		Kind:      r.Kind,
+		Label:     strings.TrimSpace(r.Value),
+		Reason:    r.Reason,
+		Source:    BlocklistSourceAdmin,
+		ExpiresAt: r.ExpiresAt,
+	}
+	switch r.Kind {
+	case BlocklistKindEmail:
+		entry.Value = NormalizeDuplicateEmail(r.Value)
+		if entry.Value == "" {
+			return nil, ErrInvalidBlocklistEmail
+		}
+	case BlocklistKindPhone:
+		entry.Value = PhoneBlindIndex(r.Value)
+		if entry.Value == "" {
+			return nil, ErrInvalidBlocklistPhone
+		}
+	case BlocklistKindAddress:
+		if r.Address == nil {
+			return nil, ErrBlocklistAddressMissing
+		}
+		entry.Value = AddressFingerprint(*r.Address)
+		entry.Label = r.Address.String()
+	}
+	return entry, nil
+}
+
+// BlocklistFilter filters the admin blocklist
+type BlocklistFilter struct {
+	Kind           string
+	IncludeExpired bool
+	Page           int
+	Limit          int
+}
+
+// FraudCheckRequest is what the order service knows about an order's buyer.
+// Every field is optional; the customer is matched by ID, the rest against
+// the blocklist.
+type FraudCheckRequest struct {
+	CustomerID      *uuid.UUID        `json:"customer_id"`
+	Email           string            `json:"email" binding:"max=255"`
+	Phone           string            `json:"phone" binding:"max=50"`
+	ShippingAddress *BlocklistAddress `json:"shipping_address"`
+	BillingAddress  *BlocklistAddress `json:"billing_address"`
+}
+
+// FraudCheckMatch is one reason an order is flagged. Field is the request
+// field that matched.
+type FraudCheckMatch struct {
+	Kind      string     `json:"kind"`
+	Field     string     `json:"field"`
+	Reason    string     `json:"reason"`
+	EntryID   *uuid.UUID `json:"entry_id,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// FraudCheckResult tells the order service whether to stop an order
+type FraudCheckResult struct {
+	Flagged bool              `json:"flagged"`
+	Matches []FraudCheckMatch `json:"matches"`
+}
+
+// BlocklistEntriesFor returns the entries that flag a blocked customer's
+// email, phone and addresses
+func BlocklistEntriesFor(customer *Customer, addresses []Address, reason string, blockedBy *uuid.UUID) []BlocklistEntry {
+	customerID := customer.ID
+	entry := func(kind, value, label string) BlocklistEntry {
+		return BlocklistEntry{
+			Kind:       kind,
+			Value:      value,
+			Label:      label,
+			Reason:     reason,
+			Source:     BlocklistSourceCustomerBlocked,
+			CustomerID: &customerID,
+			CreatedBy:  blockedBy,
+		}
+	}
+
+	var entries []BlocklistEntry
+	if email := NormalizeDuplicateEmail(customer.Email); email != "" {
+		entries = append(entries, entry(BlocklistKindEmail, email, customer.Email))
+	}
+	if phone := customer.PhoneLookup(); phone != "" {
+		entries = append(entries, entry(BlocklistKindPhone, phone, customer.Phone))
+	}
+	seen := make(map[string]bool)
+	for _, a := range addresses {
+		address := BlocklistAddress{
+			AddressLine1: a.AddressLine1,
+			AddressLine2: a.AddressLine2,
+			City:         a.City,
+			Postcode:     a.Postcode,
+			Country:      a.Country,
+		}
+		fingerprint := AddressFingerprint(address)
+		if seen[fingerprint] {
+			continue
+		}
+		seen[fingerprint] = true
+		entries = append(entries, entry(BlocklistKindAddress, fingerprint, address.String()))
+	}
+	return entries
+}
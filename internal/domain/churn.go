@@ -0,0 +1,68 @@
+package domain
+
+import (
+	"fmt"
+	"slices"
+	"time"
+)
+
+// Churn risk levels. Customers who were never frequent buyers have none.
+const (
+	ChurnRiskLow    = "low"
+	ChurnRiskMedium = "medium" // quiet for half the inactivity period
+	ChurnRiskHigh   = "high"   // quiet for the whole inactivity period
+)
+
+// ChurnRiskLevels lists the churn risk levels, from lowest to highest
+var ChurnRiskLevels = []string{ChurnRiskLow, ChurnRiskMedium, ChurnRiskHigh}
+
+// ErrInvalidChurnRisk is returned for a churn risk filter naming an unknown
+// level
+var ErrInvalidChurnRisk = fmt.Errorf("churn_risk must be one of %v", ChurnRiskLevels)
+
+// ValidChurnRisk reports whether level is a churn risk level
+func ValidChurnRisk(level string) bool {
+	return slices.Contains(ChurnRiskLevels, level)
+}
+
+// ChurnPolicy decides when a frequent buyer who stopped ordering is at risk
+// of churning
+type ChurnPolicy struct {
+	MinOrders    int // orders that make a customer a frequent buyer
+	InactiveDays int // days without orders before a frequent buyer is high risk
+}
+
+// DefaultChurnPolicy flags customers with 3 or more orders and none in the
+// last 90 days
+var DefaultChurnPolicy = ChurnPolicy{MinOrders: 3, InactiveDays: 90}
+
+// Risk returns the customer's churn risk at now, or "" if they aren't a
+// frequent buyer
+func (p ChurnPolicy) Risk(customer *Customer, now time.Time) string {
+	if customer.TotalOrders < p.MinOrders || customer.LastOrderAt == nil {
+		return ""
+	}
+	days := int(now.Sub(*customer.LastOrderAt).Hours() / 24)
+	switch {
+	case days >= p.InactiveDays:
+		return ChurnRiskHigh
+	case days >= p.InactiveDays/2:
+		return ChurnRiskMedium
+	default:
+		return ChurnRiskLow
+	}
+}
+
+// ChurnActivityTitle is the title of the activity recorded when a customer
+// is flagged as high churn risk
+func (p ChurnPolicy) ChurnActivityTitle(customer *Customer) string {
+	return fmt.Sprintf("Flagged as churn risk: %d orders, none in %d days", customer.TotalOrders, p.InactiveDays)
+}
+
+// ChurnRiskResult counts the customers a churn analysis went through, those
+// newly flagged as high risk and those no longer at high risk
+type ChurnRiskResult struct {
+	Customers int
+	Flagged   int
+	Cleared   int
+}
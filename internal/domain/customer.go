@@ -9,23 +9,51 @@
 package domain
 
 import (
+	"errors"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/domain/shared"
 	"gorm.io/gorm"
 )
 
 // Customer represents a customer in the system
 type Customer struct {
-	ID          uuid.UUID      `gorm:"type:uuid;primary_key" json:"id"`
-	Email       string         `gorm:"uniqueIndex;not null" json:"email"`
-	FirstName   string         `gorm:"type:varchar(100)" json:"first_name"`
-	LastName    string         `gorm:"type:varchar(100)" json:"last_name"`
-	Phone       string         `gorm:"type:varchar(20)" json:"phone,omitempty"`
-	AvatarURL   string         `gorm:"type:varchar(500)" json:"avatar_url,omitempty"`
-	Status      string         `gorm:"type:varchar(20);default:'active'" json:"status"`
-	TotalOrders int            `gorm:"default:0" json:"total_orders"`
-	TotalSpent  float64        `gorm:"type:decimal(12,2);default:0" json:"total_spent"`
+	ID          uuid.UUID             `gorm:"type:uuid;primary_key" json:"id"`
+	Email       string                `gorm:"uniqueIndex;not null" json:"email"`
+	FirstName   string                `gorm:"type:varchar(100)" json:"first_name"`
+	LastName    string                `gorm:"type:varchar(100)" json:"last_name"`
+	Phone       string                `gorm:"type:text;serializer:encrypted" json:"phone,omitempty"`
+	AvatarURL   string                `gorm:"type:varchar(500)" json:"avatar_url,omitempty"`
+	Status      shared.CustomerStatus `gorm:"type:varchar(20);default:'active'" json:"status"`
+	TotalOrders int                   `gorm:"default:0" json:"total_orders"`
+	TotalSpent  float64               `gorm:"type:decimal(12,2);default:0" json:"total_spent"`
+
+	// Kept up to date from order events, like TotalOrders and TotalSpent
+	FirstOrderAt *time.Time `json:"first_order_at,omitempty"`
+	LastOrderAt  *time.Time `json:"last_order_at,omitempty"`
+
+	// RFM scores from 1 to 5 (0 without orders) and predicted lifetime
+	// value, set by the rfm_scoring job; see RFMThresholds
+	RecencyScore   int        `gorm:"default:0" json:"recency_score"`
+	FrequencyScore int        `gorm:"default:0" json:"frequency_score"`
+	MonetaryScore  int        `gorm:"default:0" json:"monetary_score"`
+	RFMSegment     string     `gorm:"type:varchar(30)" json:"rfm_segment,omitempty"`
+	LifetimeValue  float64    `gorm:"type:decimal(12,2);default:0" json:"lifetime_value"`
+	ScoredAt       *time.Time `json:"scored_at,omitempty"`
+
+	// Set by the churn_risk job for frequent buyers; see ChurnPolicy.
+	// ChurnFlaggedAt is when the risk last became high.
+	ChurnRisk      string     `gorm:"type:varchar(10)" json:"churn_risk,omitempty"`
+	ChurnFlaggedAt *time.Time `json:"churn_flagged_at,omitempty"`
+
+	// PhoneBlindIndex of Phone, for equality lookups on the encrypted
+	// column; set in BeforeSave
+	PhoneIndex string `gorm:"type:varchar(64)" json:"-"`
 
 	// Version for optimistic locking
 	Version int64 `gorm:"column:version;default:1" json:"version"`
@@ -33,6 +61,12 @@ type Customer struct {
 	CreatedAt time.Time      `json:"created_at"`
 	UpdatedAt time.Time      `json:"updated_at"`
 	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+
+	// Tag names, filled in by the admin customer list
+	Tags []string `gorm:"-" json:"tags,omitempty"`
+
+	// Set when Email and Phone are masked for the admin; see MaskPII
+	PIIMasked bool `gorm:"-" json:"pii_masked,omitempty"`
 }
 
 func (c *Customer) BeforeCreate(tx *gorm.DB) error {
@@ -42,6 +76,21 @@ func (c *Customer) BeforeCreate(tx *gorm.DB) error {
 	return nil
 }
 
+// BeforeSave keeps the phone's blind index in step with the phone
+func (c *Customer) BeforeSave(tx *gorm.DB) error {
+	c.PhoneIndex = PhoneBlindIndex(c.Phone)
+	return nil
+}
+
+// PhoneLookup returns the blind index of the phone, computing it for a
+// customer saved before the index was added
+func (c *Customer) PhoneLookup() string {
+	if c.PhoneIndex != "" {
+		return c.PhoneIndex
+	}
+	return PhoneBlindIndex(c.Phone)
+}
+
 // BeforeUpdate hook with optimistic locking
 func (c *Customer) BeforeUpdate(tx *gorm.DB) error {
 	tx.Statement.Where("version = ?", c.Version)
@@ -57,22 +106,22 @@ func (Customer) TableName() string {
 
 // Activate activates the customer
 func (c *Customer) Activate() {
-	c.Status = "active"
+	c.Status = shared.StatusActive
 }
 
 // Deactivate deactivates the customer
 func (c *Customer) Deactivate() {
-	c.Status = "inactive"
+	c.Status = shared.StatusInactive
 }
 
 // Suspend suspends the customer
 func (c *Customer) Suspend() {
-	c.Status = "suspended"
+	c.Status = shared.StatusSuspended
 }
 
 // IsActive checks if customer is active
 func (c *Customer) IsActive() bool {
-	return c.Status == "active"
+	return c.Status == shared.StatusActive
 }
 
 // IncrementOrders increments order count and adds to total spent
@@ -96,10 +145,45 @@ type CreateCustomerRequest struct {
 
 // UpdateCustomerRequest represents a request to update a customer
 type UpdateCustomerRequest struct {
-	FirstName *string `json:"first_name,omitempty"`
-	LastName  *string `json:"last_name,omitempty"`
-	Phone     *string `json:"phone,omitempty"`
-	Status    *string `json:"status,omitempty"`
+	FirstName *string                `json:"first_name,omitempty"`
+	LastName  *string                `json:"last_name,omitempty"`
+	Phone     *string                `json:"phone,omitempty"`
+	Status    *shared.CustomerStatus `json:"status,omitempty"`
+
+	// Admin making the change, recorded on the status change activity
+	UpdatedBy *uuid.UUID `json:"-"`
+}
+
+// Customer note categories
+const (
+	NoteCategoryGeneral  = "general"
+	NoteCategorySupport  = "support"
+	NoteCategoryOrder    = "order"
+	NoteCategoryBilling  = "billing"
+	NoteCategoryShipping = "shipping"
+	NoteCategoryVIP      = "vip"
+	NoteCategoryFraud    = "fraud"
+)
+
+// NoteCategories lists the valid customer note categories
+var NoteCategories = []string{
+	NoteCategoryGeneral,
+	NoteCategorySupport,
+	NoteCategoryOrder,
+	NoteCategoryBilling,
+	NoteCategoryShipping,
+	NoteCategoryVIP,
+	NoteCategoryFraud,
+}
+
+// IsValidNoteCategory checks if a note category is supported
+func IsValidNoteCategory(category string) bool {
+	for _, c := range NoteCategories {
+		if c == category {
+			return true
+		}
+	}
+	return false
 }
 
 // CustomerNote represents a note on a customer
@@ -107,9 +191,58 @@ type CustomerNote struct {
 	ID         uuid.UUID  `gorm:"type:uuid;primary_key" json:"id"`
 	CustomerID uuid.UUID  `gorm:"type:uuid;index" json:"customer_id"`
 	Note       string     `gorm:"type:text" json:"note"`
+	Category   string     `gorm:"type:varchar(50);default:'general';index" json:"category"`
 	IsPrivate  bool       `gorm:"default:false" json:"is_private"`
 	CreatedBy  *uuid.UUID `gorm:"type:uuid" json:"created_by,omitempty"`
 	CreatedAt  time.Time  `json:"created_at"`
+
+	// Set when the author or an admin edits the note
+	EditedAt *time.Time `json:"edited_at,omitempty"`
+	EditedBy *uuid.UUID `gorm:"type:uuid" json:"edited_by,omitempty"`
+
+	// Pinned notes are listed first
+	PinnedAt *time.Time `gorm:"index" json:"pinned_at,omitempty"`
+	PinnedBy *uuid.UUID `gorm:"type:uuid" json:"pinned_by,omitempty"`
+
+	// Loaded separately from customer.note_attachments
+	Attachments []NoteAttachment `gorm:"-" json:"attachments,omitempty"`
+}
+
+// UpdateCustomerNoteRequest represents a request to edit a customer note
+type UpdateCustomerNoteRequest struct {
+	Note      *string `json:"note,omitempty"`
+	Category  *string `json:"category,omitempty"`
+	IsPrivate *bool   `json:"is_private,omitempty"`
+}
+
+// mentionPattern matches @handles of staff mentioned in a note. A handle
+// directly after a word character is part of an email address, not a mention.
+var mentionPattern = regexp.MustCompile(`(?:^|[^\w@])@([A-Za-z0-9][A-Za-z0-9._-]{0,63})`)
+
+// Mentions returns the lowercased staff handles @mentioned in the note, in
+// order of first mention
+func (n *CustomerNote) Mentions() []string {
+	return ParseMentions(n.Note)
+}
+
+// ParseMentions returns the lowercased @handles in text, in order of first mention
+func ParseMentions(text string) []string {
+	var mentions []string
+	seen := make(map[string]bool)
+	for _, match := range mentionPattern.FindAllStringSubmatch(text, -1) {
+		handle := strings.ToLower(strings.TrimRight(match[1], "._-"))
+		if handle == "" || seen[handle] {
+			continue
+		}
+		seen[handle] = true
+		mentions = append(mentions, handle)
+	}
+	return mentions
+}
+
+// IsPinned reports whether the note is pinned
+func (n *CustomerNote) IsPinned() bool {
+	return n.PinnedAt != nil
 }
 
 func (n *CustomerNote) BeforeCreate(tx *gorm.DB) error {
@@ -131,6 +264,47 @@ type CustomerActivity struct {
 	Title      string    `gorm:"type:varchar(255)" json:"title"`
 	Details    string    `gorm:"type:text" json:"details,omitempty"`
 	CreatedAt  time.Time `json:"created_at"`
+
+	// Request metadata, set for activities tracked automatically from API calls
+	IPAddress string `gorm:"type:varchar(45)" json:"ip_address,omitempty"`
+	UserAgent string `gorm:"type:varchar(500)" json:"user_agent,omitempty"`
+	RequestID string `gorm:"type:varchar(100)" json:"request_id,omitempty"`
+
+	// Admin who performed the action: status changes, and requests made while
+	// impersonating the customer
+	ActorID *uuid.UUID `gorm:"type:uuid;index" json:"actor_id,omitempty"`
+
+	// Pinned activities are listed first on the timeline
+	PinnedAt *time.Time `gorm:"index" json:"pinned_at,omitempty"`
+	PinnedBy *uuid.UUID `gorm:"type:uuid" json:"pinned_by,omitempty"`
+}
+
+// Activity types
+const (
+	ActivityTypeProfile     = "profile"
+	ActivityTypeAddress     = "address"
+	ActivityTypeWishlist    = "wishlist"
+	ActivityTypeMeasurement = "measurement"
+	ActivityTypeBackInStock = "back_in_stock"
+	// Recorded for sign-ins reported by the auth service
+	ActivityTypeLogin = "login"
+	// Recorded for completed and refunded orders reported by service-order
+	ActivityTypeOrder = "order"
+	// Recorded when an admin changes the customer's status
+	ActivityTypeStatusChange = "status_change"
+	// Recorded when the churn_risk job flags a customer as high risk
+	ActivityTypeChurnRisk = "churn_risk"
+)
+
+// MaxPinnedActivities caps the pinned activities per customer so pins stay meaningful
+const MaxPinnedActivities = 5
+
+// ErrPinnedActivityLimit is returned when a customer already has MaxPinnedActivities pins
+var ErrPinnedActivityLimit = errors.New("pinned activity limit reached")
+
+// IsPinned reports whether the activity is pinned
+func (a *CustomerActivity) IsPinned() bool {
+	return a.PinnedAt != nil
 }
 
 func (a *CustomerActivity) BeforeCreate(tx *gorm.DB) error {
@@ -151,6 +325,7 @@ type CustomerSegment struct {
 	Description string    `gorm:"type:text" json:"description,omitempty"`
 	Color       string    `gorm:"type:varchar(7)" json:"color,omitempty"`
 	IsActive    bool      `gorm:"default:true" json:"is_active"`
+	IsMarketing bool      `gorm:"default:false" json:"is_marketing"` // removed automatically when a customer is blocked
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
 }
@@ -185,6 +360,24 @@ func (CustomerSegmentAssignment) TableName() string {
 	return "public.customer_segment_assignments"
 }
 
+// CustomerNoteFilter represents filters for listing and counting a customer's notes
+type CustomerNoteFilter struct {
+	Category  string
+	IsPrivate *bool
+	CreatedBy *uuid.UUID
+	DateFrom  *time.Time
+	DateTo    *time.Time
+	Page      int
+	Limit     int
+}
+
+// CustomerNoteCounts summarizes the notes matching a filter
+type CustomerNoteCounts struct {
+	Total      int64            `json:"total"`
+	Private    int64            `json:"private"`
+	ByCategory map[string]int64 `json:"by_category"`
+}
+
 // CustomerListFilter represents filters for customer listing
 type CustomerListFilter struct {
 	Status    string     `form:"status"`
@@ -195,9 +388,77 @@ type CustomerListFilter struct {
 	OrdersMax *int       `form:"orders_max"`
 	SpentMin  *float64   `form:"spent_min"`
 	SpentMax  *float64   `form:"spent_max"`
+	CLVMin    *float64   `form:"clv_min"`
+	CLVMax    *float64   `form:"clv_max"`
+	ChurnRisk string     `form:"churn_risk"`
 	Search    string     `form:"search"`
 	Page      int        `form:"page"`
 	Limit     int        `form:"limit"`
-	SortBy    string     `form:"sort_by"`
-	SortOrder string     `form:"sort_order"`
+
+	// Sort is parsed with CustomerSort; the zero Sort is its default
+	Sort Sort `form:"-"`
+
+	// Tags are normalized tag names; customers must carry all of them
+	Tags []string `form:"-"`
+
+	// RFMSegments are parsed with ParseRFMSegments; customers must be in one
+	// of them
+	RFMSegments []string `form:"-"`
+
+	// Region is set from the admin's region assignment, never from the query
+	// string; nil means the admin may see every customer
+	Region *RegionScope `form:"-"`
+}
+
+// ParseCustomerExportFilter reads the customer filters of an export or bulk
+// change query: status, segment, search, tags and the score filters. The
+// region is left for the caller to set.
+func ParseCustomerExportFilter(query url.Values) (CustomerListFilter, error) {
+	filter := CustomerListFilter{
+		Status:  query.Get("status"),
+		Segment: query.Get("segment"),
+		Search:  query.Get("search"),
+	}
+	if raw := query.Get("tags"); raw != "" {
+		tags, err := NormalizeTagNames(strings.Split(raw, ","))
+		if err != nil {
+			return filter, err
+		}
+		filter.Tags = tags
+	}
+	return filter, ParseCustomerScoreFilters(query, &filter)
+}
+
+// ParseCustomerScoreFilters sets the RFM segment, lifetime value and churn risk
+// filters from the rfm_segment, clv_min, clv_max and churn_risk query
+// parameters
+func ParseCustomerScoreFilters(query url.Values, filter *CustomerListFilter) error {
+	if churnRisk := query.Get("churn_risk"); churnRisk != "" {
+		if !ValidChurnRisk(churnRisk) {
+			return ErrInvalidChurnRisk
+		}
+		filter.ChurnRisk = churnRisk
+	}
+
+	segments, err := ParseRFMSegments(query.Get("rfm_segment"))
+	if err != nil {
+		return err
+	}
+	filter.RFMSegments = segments
+
+	if raw := query.Get("clv_min"); raw != "" {
+		clvMin, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return errors.New("clv_min must be a number")
+		}
+		filter.CLVMin = &clvMin
+	}
+	if raw := query.Get("clv_max"); raw != "" {
+		clvMax, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return errors.New("clv_max must be a number")
+		}
+		filter.CLVMax = &clvMax
+	}
+	return nil
 }
@@ -24,6 +24,7 @@ const (
 	ActivityTypeAddress     = "address"
 	ActivityTypeProfile     = "profile"
 	ActivityTypeMeasurement = "measurement"
+	ActivityTypeBackInStock = "back_in_stock"
 )
 
 // NewCustomerActivity creates a new CustomerActivity.
@@ -0,0 +1,245 @@
+package domain
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MaxCustomerExportRows caps a single admin customer export
+const MaxCustomerExportRows = 10000
+
+// Customer column selection errors
+var (
+	ErrNoCustomerColumns     = errors.New("select at least one column")
+	ErrUnknownCustomerColumn = errors.New("unknown column")
+)
+
+// CustomerColumn describes a column of the admin customer list and export
+type CustomerColumn struct {
+	Key      string `json:"key"`
+	Label    string `json:"label"`
+	Sortable bool   `json:"sortable"`
+	Default  bool   `json:"default"`
+
+	value func(*Customer) string
+}
+
+// Value returns the column's value for a customer as exported to CSV
+func (col CustomerColumn) Value(c *Customer) string {
+	return col.value(c)
+}
+
+// CustomerColumns are the admin customer list columns in display order. The
+// admin UI reads them from GET /admin/customers/columns rather than keeping
+// its own copy.
+var CustomerColumns = []CustomerColumn{
+	{Key: "id", Label: "ID", value: func(c *Customer) string { return c.ID.String() }},
+	{Key: "email", Label: "Email", Sortable: true, Default: true, value: func(c *Customer) string { return c.Email }},
+	{Key: "first_name", Label: "First name", Sortable: true, Default: true, value: func(c *Customer) string { return c.FirstName }},
+	{Key: "last_name", Label: "Last name", Sortable: true, Default: true, value: func(c *Customer) string { return c.LastName }},
+	{Key: "phone", Label: "Phone", value: func(c *Customer) string { return c.Phone }},
+	{Key: "status", Label: "Status", Sortable: true, Default: true, value: func(c *Customer) string { return string(c.Status) }},
+	{Key: "total_orders", Label: "Orders", Sortable: true, Default: true, value: func(c *Customer) string { return strconv.Itoa(c.TotalOrders) }},
+	{Key: "total_spent", Label: "Total spent", Sortable: true, Default: true, value: func(c *Customer) string { return strconv.FormatFloat(c.TotalSpent, 'f', 2, 64) }},
+	{Key: "rfm_segment", Label: "RFM segment", Sortable: true, value: func(c *Customer) string { return c.RFMSegment }},
+	{Key: "rfm_score", Label: "RFM score", value: func(c *Customer) string { return c.RFMScores().String() }},
+	{Key: "lifetime_value", Label: "Lifetime value", Sortable: true, value: func(c *Customer) string { return strconv.FormatFloat(c.LifetimeValue, 'f', 2, 64) }},
+	{Key: "churn_risk", Label: "Churn risk", Sortable: true, value: func(c *Customer) string { return c.ChurnRisk }},
+	{Key: "tags", Label: "Tags", value: func(c *Customer) string { return strings.Join(c.Tags, ", ") }},
+	{Key: "created_at", Label: "Created", Sortable: true, Default: true, value: func(c *Customer) string { return c.CreatedAt.UTC().Format(time.RFC3339) }},
+	{Key: "updated_at", Label: "Updated", Sortable: true, value: func(c *Customer) string { return c.UpdatedAt.UTC().Format(time.RFC3339) }},
+}
+
+// CustomerSortFields are the columns the admin customer list can be sorted by
+var CustomerSortFields = customerColumnKeys(func(col CustomerColumn) bool { return col.Sortable })
+
+// CustomerSort whitelists the sorts of the admin customer list and export;
+// each sortable column sorts on itself, newest first by default
+var CustomerSort = NewSortFields(Sort{Field: "created_at", Direction: SortDesc}, customerSortColumns())
+
+// DefaultCustomerColumns are shown to admins who haven't chosen their own
+var DefaultCustomerColumns = customerColumnKeys(func(col CustomerColumn) bool { return col.Default })
+
+// LookupCustomerColumn returns the column with the given key
+func LookupCustomerColumn(key string) (CustomerColumn, bool) {
+	for _, col := range CustomerColumns {
+		if col.Key == key {
+			return col, true
+		}
+	}
+	return CustomerColumn{}, false
+}
+
+// ParseCustomerColumns validates a column selection, dropping duplicates but
+// keeping the given order
+func ParseCustomerColumns(keys []string) ([]string, error) {
+	seen := make(map[string]bool, len(keys))
+	columns := make([]string, 0, len(keys))
+	for _, key := range keys {
+		key = strings.TrimSpace(key)
+		if key == "" || seen[key] {
+			continue
+		}
+		if _, ok := LookupCustomerColumn(key); !ok {
+			return nil, fmt.Errorf("%w %q", ErrUnknownCustomerColumn, key)
+		}
+		seen[key] = true
+		columns = append(columns, key)
+	}
+	if len(columns) == 0 {
+		return nil, ErrNoCustomerColumns
+	}
+	return columns, nil
+}
+
+func customerSortColumns() map[string]string {
+	columns := make(map[string]string, len(CustomerSortFields))
+	for _, key := range CustomerSortFields {
+		columns[key] = key
+	}
+	return columns
+}
+
+func customerColumnKeys(include func(CustomerColumn) bool) []string {
+	var keys []string
+	for _, col := range CustomerColumns {
+		if include(col) {
+			keys = append(keys, col.Key)
+		}
+	}
+	return keys
+}
+
+// CustomerExport is the payload of a JSON customer export: one row per
+// customer, keyed by column
+type CustomerExport struct {
+	Columns []string            `json:"columns"`
+	Rows    []map[string]string `json:"rows"`
+}
+
+// NewCustomerExport projects the customers onto already validated columns for
+// a JSON export
+func NewCustomerExport(customers []Customer, columns []string) CustomerExport {
+	defs := customerColumnDefs(columns)
+	rows := make([]map[string]string, len(customers))
+	for i := range customers {
+		row := make(map[string]string, len(defs))
+		for _, col := range defs {
+			row[col.Key] = col.Value(&customers[i])
+		}
+		rows[i] = row
+	}
+	return CustomerExport{Columns: columns, Rows: rows}
+}
+
+// WriteCustomersCSV writes the customers as CSV, with a header row of the
+// already validated columns
+func WriteCustomersCSV(w io.Writer, customers []Customer, columns []string) error {
+	defs := customerColumnDefs(columns)
+	writer := csv.NewWriter(w)
+	writer.Write(columns)
+	for i := range customers {
+		row := make([]string, len(defs))
+		for j, col := range defs {
+			row[j] = col.Value(&customers[i])
+		}
+		writer.Write(row)
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// CustomerWriter streams customers as CSV with a header row, or as a JSON
+// array of objects keyed by column, so exports of any size use flat memory
+type CustomerWriter struct {
+	columns []CustomerColumn
+	csv     *csv.Writer
+	out     io.Writer
+	rows    int
+}
+
+// NewCustomerWriter starts a customer export in format, "csv" or "json", with
+// the already validated columns
+func NewCustomerWriter(out io.Writer, format string, columns []string) *CustomerWriter {
+	w := &CustomerWriter{out: out, columns: customerColumnDefs(columns)}
+	if format == "csv" {
+		w.csv = csv.NewWriter(out)
+		header := make([]string, len(w.columns))
+		for i, col := range w.columns {
+			header[i] = col.Key
+		}
+		w.csv.Write(header)
+	}
+	return w
+}
+
+// Write adds a customer to the export
+func (w *CustomerWriter) Write(c *Customer) error {
+	if w.csv != nil {
+		row := make([]string, len(w.columns))
+		for i, col := range w.columns {
+			row[i] = col.Value(c)
+		}
+		return w.csv.Write(row)
+	}
+
+	row := make(map[string]string, len(w.columns))
+	for _, col := range w.columns {
+		row[col.Key] = col.Value(c)
+	}
+	data, err := json.Marshal(row)
+	if err != nil {
+		return err
+	}
+	separator := ",\n"
+	if w.rows == 0 {
+		separator = "[\n"
+	}
+	w.rows++
+	_, err = fmt.Fprintf(w.out, "%s  %s", separator, data)
+	return err
+}
+
+// Close flushes the output and closes the JSON array
+func (w *CustomerWriter) Close() error {
+	if w.csv != nil {
+		w.csv.Flush()
+		return w.csv.Error()
+	}
+	if w.rows == 0 {
+		_, err := io.WriteString(w.out, "[]\n")
+		return err
+	}
+	_, err := io.WriteString(w.out, "\n]\n")
+	return err
+}
+
+// customerColumnDefs looks up already validated column keys
+func customerColumnDefs(columns []string) []CustomerColumn {
+	defs := make([]CustomerColumn, 0, len(columns))
+	for _, key := range columns {
+		if col, ok := LookupCustomerColumn(key); ok {
+			defs = append(defs, col)
+		}
+	}
+	return defs
+}
+
+// CustomerColumnPreference is an admin's chosen customer list columns
+type CustomerColumnPreference struct {
+	AdminID   uuid.UUID `gorm:"type:uuid;primaryKey" json:"admin_id"`
+	Columns   []string  `gorm:"type:jsonb;serializer:json;not null" json:"columns"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (CustomerColumnPreference) TableName() string {
+	return "customer.customer_column_preferences"
+}
@@ -0,0 +1,99 @@
+package domain
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Customer export job statuses
+const (
+	CustomerExportPending   = "pending"
+	CustomerExportRunning   = "running"
+	CustomerExportCompleted = "completed"
+	CustomerExportFailed    = "failed"
+)
+
+// CustomerExportKeyPrefix is where customer export files are kept in the file
+// store
+const CustomerExportKeyPrefix = "exports/customers/"
+
+// CustomerExportJob exports the customers matching Filter to a file in the
+// file store, in the background and without the row cap of the synchronous
+// export. Region and MaskPII are taken from the admin who requested it.
+// Processed counts the customers written so far, of Total when it was
+// queued.
+type CustomerExportJob struct {
+	ID          uuid.UUID    `gorm:"type:uuid;primary_key" json:"id"`
+	Status      string       `gorm:"type:varchar(20);not null;index" json:"status"`
+	Format      string       `gorm:"type:varchar(10);not null" json:"format"`
+	Columns     []string     `gorm:"type:jsonb;serializer:json" json:"columns"`
+	Filter      string       `gorm:"type:text" json:"filter"` // filters of GET /admin/customers/export, as a query string
+	Region      *RegionScope `gorm:"type:jsonb;serializer:json" json:"region,omitempty"`
+	MaskPII     bool         `gorm:"not null;default:false" json:"mask_pii"`
+	Total       int64        `gorm:"not null;default:0" json:"total"`
+	Processed   int64        `gorm:"not null;default:0" json:"processed"`
+	FileKey     string       `gorm:"type:varchar(255)" json:"-"`
+	Bytes       int64        `gorm:"not null;default:0" json:"bytes"`
+	Error       string       `gorm:"type:text" json:"error,omitempty"`
+	Attempts    int          `gorm:"not null;default:0" json:"attempts"`
+	RequestedBy *uuid.UUID   `gorm:"type:uuid;index" json:"requested_by,omitempty"`
+	CreatedAt   time.Time    `json:"created_at"`
+	UpdatedAt   time.Time    `json:"updated_at"`
+	StartedAt   *time.Time   `json:"started_at,omitempty"`
+	CompletedAt *time.Time   `json:"completed_at,omitempty"`
+}
+
+func (j *CustomerExportJob) BeforeCreate(tx *gorm.DB) error {
+	if j.ID == uuid.Nil {
+		j.ID = uuid.New()
+	}
+	return nil
+}
+
+func (CustomerExportJob) TableName() string {
+	return "customer.customer_export_jobs"
+}
+
+// Done reports whether the job has finished, successfully or not
+func (j *CustomerExportJob) Done() bool {
+	return j.Status == CustomerExportCompleted || j.Status == CustomerExportFailed
+}
+
+// Progress is the percentage of the customers written so far
+func (j *CustomerExportJob) Progress() int {
+	switch {
+	case j.Status == CustomerExportCompleted:
+		return 100
+	case j.Total <= 0:
+		return 0
+	}
+	return int(min(j.Processed*100/j.Total, 99))
+}
+
+// CustomerFilter parses the job's filter, limited to its region
+func (j *CustomerExportJob) CustomerFilter() (CustomerListFilter, error) {
+	query, err := url.ParseQuery(j.Filter)
+	if err != nil {
+		return CustomerListFilter{}, fmt.Errorf("invalid filter: %w", err)
+	}
+	filter, err := ParseCustomerExportFilter(query)
+	filter.Region = j.Region
+	return filter, err
+}
+
+// Key is where the job's file is stored
+func (j *CustomerExportJob) Key() string {
+	return fmt.Sprintf("%s%s/customers-%s.%s", CustomerExportKeyPrefix, j.ID, j.CreatedAt.UTC().Format("20060102-150405"), j.Format)
+}
+
+// ContentType is the MIME type of the job's file
+func (j *CustomerExportJob) ContentType() string {
+	if j.Format == "json" {
+		return "application/json"
+	}
+	return "text/csv; charset=utf-8"
+}
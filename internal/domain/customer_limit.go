@@ -0,0 +1,78 @@
+package domain
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Default per-customer caps; the wishlist default is DefaultWishlistMaxItems
+const (
+	DefaultMaxAddresses    = 20
+	DefaultMaxMeasurements = 10
+)
+
+// ErrMeasurementLimit is returned when a customer already stores as many
+// measurements as their limit allows
+var ErrMeasurementLimit = errors.New("measurement limit reached")
+
+// CustomerLimits caps how many records one customer can store, protecting
+// the tables from scripted abuse. A zero field means no cap.
+type CustomerLimits struct {
+	Addresses     int `json:"addresses"`
+	WishlistItems int `json:"wishlist_items"`
+	Measurements  int `json:"measurements"`
+}
+
+// DefaultCustomerLimits returns the caps used when none are configured
+func DefaultCustomerLimits() CustomerLimits {
+	return CustomerLimits{
+		Addresses:     DefaultMaxAddresses,
+		WishlistItems: DefaultWishlistMaxItems,
+		Measurements:  DefaultMaxMeasurements,
+	}
+}
+
+// CustomerLimitOverride replaces the default caps of one customer, e.g. a
+// reseller shipping to many addresses. Nil fields keep the default.
+type CustomerLimitOverride struct {
+	CustomerID    uuid.UUID  `gorm:"type:uuid;primary_key" json:"customer_id"`
+	Addresses     *int       `json:"addresses"`
+	WishlistItems *int       `json:"wishlist_items"`
+	Measurements  *int       `json:"measurements"`
+	Reason        string     `gorm:"type:varchar(255)" json:"reason"`
+	UpdatedBy     *uuid.UUID `gorm:"type:uuid" json:"updated_by,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+func (CustomerLimitOverride) TableName() string {
+	return "customer.customer_limit_overrides"
+}
+
+// Apply returns limits with the override's caps in place of the defaults
+func (o *CustomerLimitOverride) Apply(limits CustomerLimits) CustomerLimits {
+	if o == nil {
+		return limits
+	}
+	if o.Addresses != nil {
+		limits.Addresses = *o.Addresses
+	}
+	if o.WishlistItems != nil {
+		limits.WishlistItems = *o.WishlistItems
+	}
+	if o.Measurements != nil {
+		limits.Measurements = *o.Measurements
+	}
+	return limits
+}
+
+// CustomerLimitOverrideRequest sets a customer's caps. Omitted caps keep the
+// default; 0 removes the cap.
+type CustomerLimitOverrideRequest struct {
+	Addresses     *int   `json:"addresses" binding:"omitempty,min=0,max=10000"`
+	WishlistItems *int   `json:"wishlist_items" binding:"omitempty,min=0,max=10000"`
+	Measurements  *int   `json:"measurements" binding:"omitempty,min=0,max=10000"`
+	Reason        string `json:"reason" binding:"required,max=255"`
+}
@@ -0,0 +1,54 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MaxCustomerBatchSize is the most customers one batch lookup can ask for
+const MaxCustomerBatchSize = 100
+
+// Limits of a birthday feed page
+const (
+	DefaultBirthdayFeedLimit = 100
+	MaxBirthdayFeedLimit     = 500
+)
+
+// CustomerBatchRequest looks up customers by ID for another service, such as
+// the order service rendering customer names on an order list
+type CustomerBatchRequest struct {
+	IDs []uuid.UUID `json:"ids" binding:"required,min=1,max=100"`
+}
+
+// CustomerBatchResult is the customers found, in the order asked for, and
+// the IDs of customers that don't exist or were deleted
+type CustomerBatchResult struct {
+	Customers []Customer  `json:"customers"`
+	Missing   []uuid.UUID `json:"missing"`
+}
+
+// BirthdayCustomer is a customer whose birthday is on the day of a birthday
+// feed, for services sending birthday greetings or rewards
+type BirthdayCustomer struct {
+	CustomerID  uuid.UUID `json:"customer_id"`
+	FullName    string    `json:"full_name"`
+	Email       string    `json:"email"`
+	DateOfBirth time.Time `json:"date_of_birth"`
+}
+
+// BirthdayFormat is the layout of Profile.Birthday
+const BirthdayFormat = "01-02"
+
+// Birthdays returns the Profile.Birthday values celebrated on the date:
+// customers born on 29 February celebrate on 28 February outside leap years
+func Birthdays(date time.Time) []string {
+	if date.Month() == time.February && date.Day() == 28 && !isLeapYear(date.Year()) {
+		return []string{"02-28", "02-29"}
+	}
+	return []string{date.Format(BirthdayFormat)}
+}
+
+func isLeapYear(year int) bool {
+	return year%4 == 0 && (year%100 != 0 || year%400 == 0)
+}
@@ -9,18 +9,49 @@
 package domain
 
 import (
+	"errors"
+	"math"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/domain/shared"
 	"gorm.io/gorm"
 )
 
+// MeasurementProfileSelf is the profile person for the account holder's own measurements
+const MeasurementProfileSelf = "self"
+
+// MaxMeasurementProfiles caps how many people (including the account holder)
+// one account can keep measurements for
+const MaxMeasurementProfiles = 10
+
+// ErrMeasurementProfileLimit is returned when a new person would exceed MaxMeasurementProfiles
+var ErrMeasurementProfileLimit = errors.New("measurement profile limit reached")
+
+// Length units accepted for measurements. Lengths are always stored in centimetres.
+const (
+	MeasurementUnitCM   = "cm"
+	MeasurementUnitInch = "inch"
+)
+
+const centimetresPerInch = 2.54
+
+// IsValidMeasurementUnit reports whether unit is a supported length unit
+func IsValidMeasurementUnit(unit string) bool {
+	return unit == MeasurementUnitCM || unit == MeasurementUnitInch
+}
+
 // CustomerMeasurement represents body measurements for a customer
 type CustomerMeasurement struct {
-	ID     uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
-	UserID uuid.UUID `gorm:"type:uuid;not null;index" json:"user_id" binding:"required"`
-	Name   *string   `gorm:"type:varchar(100)" json:"name,omitempty"`                    // e.g., "My Baju Kurung Size"
-	Gender string    `gorm:"type:varchar(20);not null" json:"gender" binding:"required"` // men, women
+	ID     uuid.UUID     `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	UserID uuid.UUID     `gorm:"type:uuid;not null;index" json:"user_id" binding:"required"`
+	Name   *string       `gorm:"type:varchar(100)" json:"name,omitempty"` // e.g., "My Baju Kurung Size"
+	Gender shared.Gender `gorm:"type:varchar(20);not null" json:"gender" binding:"required"`
+
+	// Who the measurements belong to: "self" or a family member label such as "Spouse" or "Aisyah".
+	// Each person has their own default measurement.
+	ProfilePerson string `gorm:"type:varchar(50);not null;default:'self';index" json:"profile_person"`
 
 	// Upper body measurements (cm)
 	Bust          *float64 `gorm:"type:decimal(5,1)" json:"bust,omitempty"`
@@ -41,8 +72,16 @@ type CustomerMeasurement struct {
 	Height *float64 `gorm:"type:decimal(5,1)" json:"height,omitempty"`
 	Weight *float64 `gorm:"type:decimal(5,1)" json:"weight,omitempty"`
 
-	Notes     *string   `gorm:"type:text" json:"notes,omitempty"`
+	// Ready-to-wear size derived from the measurements above (XS..3XL)
+	StandardSize *string `gorm:"type:varchar(10)" json:"standard_size,omitempty"`
+
+	// Unit the customer entered the measurements in; used as their display unit.
+	// Stored lengths are always cm. In responses this is the unit of the returned values.
+	Unit string `gorm:"type:varchar(10);not null;default:'cm'" json:"unit"`
+
+	Notes     *string   `gorm:"type:text;serializer:encrypted" json:"notes,omitempty"`
 	IsDefault bool      `gorm:"default:false" json:"is_default"`
+	Version   int64     `gorm:"not null;default:1" json:"version"` // optimistic locking, see ErrVersionConflict
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
@@ -57,5 +96,93 @@ func (cm *CustomerMeasurement) BeforeCreate(tx *gorm.DB) error {
 	if cm.ID == uuid.Nil {
 		cm.ID = uuid.New()
 	}
+	if cm.Version == 0 {
+		cm.Version = 1
+	}
+	if cm.ProfilePerson == "" {
+		cm.ProfilePerson = MeasurementProfileSelf
+	}
+	if cm.Unit == "" {
+		cm.Unit = MeasurementUnitCM
+	}
 	return nil
 }
+
+// lengthFields returns the length measurements; weight is kg and never converted
+func (cm *CustomerMeasurement) lengthFields() []**float64 {
+	return []**float64{
+		&cm.Bust, &cm.Chest, &cm.Waist, &cm.Hip, &cm.ShoulderWidth, &cm.ArmLength,
+		&cm.Inseam, &cm.Outseam, &cm.Thigh, &cm.Neck, &cm.Wrist, &cm.Height,
+	}
+}
+
+// NormalizeLengths converts lengths entered in unit to centimetres for storage
+// and remembers unit as the customer's display unit
+func (cm *CustomerMeasurement) NormalizeLengths(unit string) {
+	if unit == MeasurementUnitInch {
+		cm.scaleLengths(centimetresPerInch)
+	}
+	cm.Unit = unit
+}
+
+// InUnit returns a copy of the measurement with lengths expressed in unit
+func (cm CustomerMeasurement) InUnit(unit string) CustomerMeasurement {
+	if unit == MeasurementUnitInch {
+		cm.scaleLengths(1 / centimetresPerInch)
+	}
+	cm.Unit = unit
+	return cm
+}
+
+// scaleLengths multiplies every set length by factor, rounded to one decimal.
+// Values are replaced rather than modified in place so copies sharing the
+// pointers are left untouched.
+func (cm *CustomerMeasurement) scaleLengths(factor float64) {
+	for _, field := range cm.lengthFields() {
+		if *field != nil {
+			value := math.Round(**field*factor*10) / 10
+			*field = &value
+		}
+	}
+}
+
+// NormalizeProfilePerson trims a profile person label; empty labels and any
+// casing of "self" refer to the account holder
+func NormalizeProfilePerson(person string) string {
+	person = strings.TrimSpace(person)
+	if person == "" || strings.EqualFold(person, MeasurementProfileSelf) {
+		return MeasurementProfileSelf
+	}
+	return person
+}
+
+// DeriveStandardSize recalculates StandardSize from the current measurements
+// and returns the previous value so callers can detect size drift.
+func (cm *CustomerMeasurement) DeriveStandardSize() (previous *string) {
+	previous = cm.StandardSize
+
+	size := shared.NewBodyMeasurement(shared.BodyMeasurementParams{
+		Gender: shared.Gender(cm.Gender),
+		Bust:   cm.Bust,
+		Chest:  cm.Chest,
+		Waist:  cm.Waist,
+		Hip:    cm.Hip,
+	}).StandardSize()
+
+	if size.IsEmpty() {
+		cm.StandardSize = nil
+	} else {
+		value := size.String()
+		cm.StandardSize = &value
+	}
+	return previous
+}
+
+// StandardSizeChanged reports whether a previously derived size differs from the current one.
+// A measurement that never had a size is not considered drift.
+func (cm *CustomerMeasurement) StandardSizeChanged(previous *string) bool {
+	if previous == nil {
+		return false
+	}
+	return cm.StandardSize == nil || *cm.StandardSize != *previous
+}
@@ -0,0 +1,91 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/Ecom-micro-template/service-customer/internal/domain/shared"
+	"github.com/stretchr/testify/assert"
+)
+
+func length(value float64) *float64 {
+	return &value
+}
+
+func size(value string) *string {
+	return &value
+}
+
+func TestCustomerMeasurement_DeriveStandardSize(t *testing.T) {
+	tests := []struct {
+		name        string
+		measurement CustomerMeasurement
+		unit        string // unit the lengths were entered in
+		want        *string
+	}{
+		{
+			name:        "centimetres",
+			measurement: CustomerMeasurement{Gender: shared.GenderWomen, Bust: length(94), Waist: length(70)},
+			unit:        MeasurementUnitCM,
+			want:        size("L"),
+		},
+		{
+			// 37 in is 94 cm, the lower bound of L
+			name:        "inches converted before sizing",
+			measurement: CustomerMeasurement{Gender: shared.GenderWomen, Bust: length(37)},
+			unit:        MeasurementUnitInch,
+			want:        size("L"),
+		},
+		{
+			// 36.9 in is 93.7 cm, still M
+			name:        "inches just below a bound",
+			measurement: CustomerMeasurement{Gender: shared.GenderWomen, Bust: length(36.9)},
+			unit:        MeasurementUnitInch,
+			want:        size("M"),
+		},
+		{
+			name:        "no key measurements",
+			measurement: CustomerMeasurement{Gender: shared.GenderMen, Height: length(175), Weight: length(70)},
+			unit:        MeasurementUnitCM,
+			want:        nil,
+		},
+		{
+			name:        "size cleared when key measurements are removed",
+			measurement: CustomerMeasurement{Gender: shared.GenderMen, StandardSize: size("M")},
+			unit:        MeasurementUnitCM,
+			want:        nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			measurement := tt.measurement
+			previous := measurement.StandardSize
+			measurement.NormalizeLengths(tt.unit)
+
+			assert.Equal(t, previous, measurement.DeriveStandardSize())
+			assert.Equal(t, tt.want, measurement.StandardSize)
+		})
+	}
+}
+
+func TestCustomerMeasurement_StandardSizeChanged(t *testing.T) {
+	tests := []struct {
+		name     string
+		previous *string
+		current  *string
+		want     bool
+	}{
+		{"never sized", nil, size("M"), false},
+		{"never sized and still none", nil, nil, false},
+		{"same size", size("M"), size("M"), false},
+		{"larger size", size("M"), size("L"), true},
+		{"size no longer derivable", size("M"), nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			measurement := CustomerMeasurement{StandardSize: tt.current}
+			assert.Equal(t, tt.want, measurement.StandardSizeChanged(tt.previous))
+		})
+	}
+}
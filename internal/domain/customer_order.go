@@ -0,0 +1,35 @@
+package domain
+
+import "time"
+
+// CustomerOrderItem is an item of a customer's order, as listed by service-order
+type CustomerOrderItem struct {
+	ID          string  `json:"id"`
+	ProductID   string  `json:"product_id"`
+	ProductName string  `json:"product_name"`
+	SKU         string  `json:"sku"`
+	Quantity    int     `json:"quantity"`
+	UnitPrice   float64 `json:"unit_price"`
+	Total       float64 `json:"total"`
+	ImageURL    string  `json:"image_url"`
+}
+
+// CustomerOrderSummary is a summarized order of a customer, as listed by
+// service-order
+type CustomerOrderSummary struct {
+	ID            string              `json:"id"`
+	OrderNum      string              `json:"order_number"`
+	Total         float64             `json:"total"`
+	Subtotal      float64             `json:"subtotal"`
+	Status        string              `json:"status"`
+	PaymentStatus string              `json:"payment_status"`
+	Items         []CustomerOrderItem `json:"items"`
+	CreatedAt     time.Time           `json:"created_at"`
+}
+
+// CustomerOrderPage is one page of a customer's orders, newest first
+type CustomerOrderPage struct {
+	Orders []CustomerOrderSummary
+	Total  int64
+	Stale  bool // served from cache because service-order could not be reached
+}
@@ -0,0 +1,132 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+// Customer stats periods
+const (
+	StatsPeriod7Days  = "7d"
+	StatsPeriod30Days = "30d"
+	StatsPeriod90Days = "90d"
+)
+
+// DefaultStatsPeriod is used when no period is requested
+const DefaultStatsPeriod = StatsPeriod30Days
+
+var statsPeriodDays = map[string]int{
+	StatsPeriod7Days:  7,
+	StatsPeriod30Days: 30,
+	StatsPeriod90Days: 90,
+}
+
+// Customer stats errors
+var (
+	ErrInvalidStatsPeriod = errors.New("period must be 7d, 30d or 90d")
+	ErrInvalidTimezone    = errors.New("timezone must be an IANA time zone such as Asia/Kuala_Lumpur")
+)
+
+// StatsWindow is the period covered by the customer stats, in whole days of
+// Location ending with today, and the period of the same length before it
+type StatsWindow struct {
+	Period   string
+	Location *time.Location
+	// Start and End bound the period; End is the start of tomorrow and
+	// exclusive
+	Start time.Time
+	End   time.Time
+	// PreviousStart starts the previous period, which ends at Start
+	PreviousStart time.Time
+	// Today and Month are the start of the current day and month
+	Today time.Time
+	Month time.Time
+}
+
+// NewStatsWindow returns the window of period ending with the day of now in
+// loc. Days are calendar days, so a period spanning a DST change still
+// covers whole days.
+func NewStatsWindow(period string, loc *time.Location, now time.Time) (StatsWindow, error) {
+	days, ok := statsPeriodDays[period]
+	if !ok {
+		return StatsWindow{}, ErrInvalidStatsPeriod
+	}
+	now = now.In(loc)
+	year, month, day := now.Date()
+	return StatsWindow{
+		Period:        period,
+		Location:      loc,
+		Start:         time.Date(year, month, day+1-days, 0, 0, 0, 0, loc),
+		End:           time.Date(year, month, day+1, 0, 0, 0, 0, loc),
+		PreviousStart: time.Date(year, month, day+1-2*days, 0, 0, 0, 0, loc),
+		Today:         time.Date(year, month, day, 0, 0, 0, 0, loc),
+		Month:         time.Date(year, month, 1, 0, 0, 0, 0, loc),
+	}, nil
+}
+
+// LoadStatsTimezone returns the IANA time zone name, or UTC when name is
+// empty
+func LoadStatsTimezone(name string) (*time.Location, error) {
+	if name == "Local" {
+		return nil, ErrInvalidTimezone
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, ErrInvalidTimezone
+	}
+	return loc, nil
+}
+
+// StatsDelta compares a count in the selected period with the previous one.
+// Change is the relative change, or nil when the previous period is empty.
+type StatsDelta struct {
+	Current  int64    `json:"current"`
+	Previous int64    `json:"previous"`
+	Change   *float64 `json:"change"`
+}
+
+// NewStatsDelta compares current with previous
+func NewStatsDelta(current, previous int64) StatsDelta {
+	delta := StatsDelta{Current: current, Previous: previous}
+	if previous > 0 {
+		change := rate(current-previous, previous)
+		delta.Change = &change
+	}
+	return delta
+}
+
+// StatsPoint is the customers who signed up and made their first order on
+// one day of the stats window
+type StatsPoint struct {
+	Date           string `json:"date"` // YYYY-MM-DD
+	NewCustomers   int64  `json:"new_customers"`
+	FirstPurchases int64  `json:"first_purchases"`
+}
+
+// NewStatsSeries counts the signups and first purchases per day of the
+// window's period, including days without any
+func NewStatsSeries(window StatsWindow, signups, firstPurchases []time.Time) []StatsPoint {
+	perDay := func(times []time.Time) map[string]int64 {
+		counts := make(map[string]int64)
+		for _, t := range times {
+			counts[t.In(window.Location).Format("2006-01-02")]++
+		}
+		return counts
+	}
+	newCustomers, firsts := perDay(signups), perDay(firstPurchases)
+
+	series := []StatsPoint{}
+	year, month, day := window.Start.Date()
+	for i := 0; ; i++ {
+		start := time.Date(year, month, day+i, 0, 0, 0, 0, window.Location)
+		if !start.Before(window.End) {
+			return series
+		}
+		date := start.Format("2006-01-02")
+		series = append(series, StatsPoint{
+			Date:           date,
+			NewCustomers:   newCustomers[date],
+			FirstPurchases: firsts[date],
+		})
+	}
+}
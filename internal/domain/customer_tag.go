@@ -0,0 +1,99 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// MaxCustomerTags caps the tags on one customer
+const MaxCustomerTags = 20
+
+// Customer tag errors
+var (
+	ErrInvalidTagName   = errors.New("tags are 1-50 letters, digits, '-', '_', '.' or ':'")
+	ErrCustomerTagLimit = fmt.Errorf("a customer can have at most %d tags", MaxCustomerTags)
+)
+
+// tagNamePattern matches normalized tag names
+var tagNamePattern = regexp.MustCompile(`^[\p{Ll}\p{Lo}\p{N}][\p{Ll}\p{Lo}\p{N}._:-]{0,49}$`)
+
+// CustomerTag is a free-form label, such as "wholesale-inquiry", that admins
+// put on customers. Unlike segments, tags have no conditions or settings and
+// are created on first use.
+type CustomerTag struct {
+	ID        uuid.UUID  `gorm:"type:uuid;primary_key" json:"id"`
+	Name      string     `gorm:"type:varchar(50);not null;uniqueIndex" json:"name"`
+	CreatedBy *uuid.UUID `gorm:"type:uuid" json:"created_by,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+func (t *CustomerTag) BeforeCreate(tx *gorm.DB) error {
+	if t.ID == uuid.Nil {
+		t.ID = uuid.New()
+	}
+	return nil
+}
+
+func (CustomerTag) TableName() string {
+	return "customer.customer_tags"
+}
+
+// CustomerTagAssignment puts a tag on a customer
+type CustomerTagAssignment struct {
+	ID         uuid.UUID  `gorm:"type:uuid;primary_key" json:"id"`
+	CustomerID uuid.UUID  `gorm:"type:uuid;not null;uniqueIndex:idx_customer_tag_assignment" json:"customer_id"`
+	TagID      uuid.UUID  `gorm:"type:uuid;not null;uniqueIndex:idx_customer_tag_assignment;index" json:"tag_id"`
+	AssignedBy *uuid.UUID `gorm:"type:uuid" json:"assigned_by,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+func (a *CustomerTagAssignment) BeforeCreate(tx *gorm.DB) error {
+	if a.ID == uuid.Nil {
+		a.ID = uuid.New()
+	}
+	return nil
+}
+
+func (CustomerTagAssignment) TableName() string {
+	return "customer.customer_tag_assignments"
+}
+
+// TagSuggestion is a tag offered by autocomplete with the number of
+// customers carrying it
+type TagSuggestion struct {
+	Name      string `json:"name"`
+	Customers int64  `json:"customers"`
+}
+
+// NormalizeTagName lower-cases a tag and joins words with "-", so
+// "Wholesale Inquiry" and "wholesale-inquiry" are the same tag
+func NormalizeTagName(name string) (string, error) {
+	name = strings.Join(strings.Fields(strings.ToLower(name)), "-")
+	if !tagNamePattern.MatchString(name) {
+		return "", ErrInvalidTagName
+	}
+	return name, nil
+}
+
+// NormalizeTagNames normalizes and de-duplicates tag names, keeping their order
+func NormalizeTagNames(names []string) ([]string, error) {
+	seen := make(map[string]bool, len(names))
+	normalized := make([]string, 0, len(names))
+	for _, name := range names {
+		tag, err := NormalizeTagName(name)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q", err, name)
+		}
+		if !seen[tag] {
+			seen[tag] = true
+			normalized = append(normalized, tag)
+		}
+	}
+	return normalized, nil
+}
@@ -0,0 +1,196 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/domain/shared"
+	"gorm.io/gorm"
+)
+
+// MaxCustomerViews caps the saved views one admin can own
+const MaxCustomerViews = 50
+
+// Customer list view errors
+var (
+	ErrCustomerViewNameTaken = errors.New("you already have a view with this name")
+	ErrCustomerViewLimit     = fmt.Errorf("an admin can save at most %d views", MaxCustomerViews)
+)
+
+// CustomerListView is a named, saved filter, sort and column layout for the
+// admin customer list. A shared view is visible to every admin but only its
+// owner can change it.
+type CustomerListView struct {
+	ID        uuid.UUID           `gorm:"type:uuid;primary_key" json:"id"`
+	OwnerID   uuid.UUID           `gorm:"type:uuid;not null;uniqueIndex:idx_customer_view_owner_name" json:"owner_id"`
+	Name      string              `gorm:"type:varchar(100);not null;uniqueIndex:idx_customer_view_owner_name" json:"name"`
+	IsShared  bool                `gorm:"default:false;index" json:"is_shared"`
+	Filters   CustomerViewFilters `gorm:"type:jsonb;serializer:json" json:"filters"`
+	SortBy    string              `gorm:"type:varchar(50)" json:"sort_by,omitempty"`
+	SortOrder string              `gorm:"type:varchar(4)" json:"sort_order,omitempty"`
+	Columns   []string            `gorm:"type:jsonb;serializer:json" json:"columns,omitempty"`
+	CreatedAt time.Time           `json:"created_at"`
+	UpdatedAt time.Time           `json:"updated_at"`
+}
+
+func (v *CustomerListView) BeforeCreate(tx *gorm.DB) error {
+	if v.ID == uuid.Nil {
+		v.ID = uuid.New()
+	}
+	return nil
+}
+
+func (CustomerListView) TableName() string {
+	return "customer.customer_list_views"
+}
+
+// CustomerViewFilters are the saved GET /admin/customers filters, named after
+// their query parameters
+type CustomerViewFilters struct {
+	Status    string   `json:"status,omitempty"`
+	Segment   string   `json:"segment,omitempty"`
+	Search    string   `json:"search,omitempty"`
+	DateFrom  string   `json:"date_from,omitempty"` // YYYY-MM-DD
+	DateTo    string   `json:"date_to,omitempty"`
+	OrdersMin *int     `json:"orders_min,omitempty"`
+	OrdersMax *int     `json:"orders_max,omitempty"`
+	SpentMin  *float64 `json:"spent_min,omitempty"`
+	SpentMax  *float64 `json:"spent_max,omitempty"`
+	CLVMin    *float64 `json:"clv_min,omitempty"`
+	CLVMax    *float64 `json:"clv_max,omitempty"`
+	ChurnRisk string   `json:"churn_risk,omitempty"`
+	Tags      []string `json:"tags,omitempty"`
+
+	RFMSegments []string `json:"rfm_segments,omitempty"`
+}
+
+// SaveCustomerViewRequest creates or replaces a saved view
+type SaveCustomerViewRequest struct {
+	Name      string              `json:"name" binding:"required,max=100"`
+	Filters   CustomerViewFilters `json:"filters"`
+	SortBy    string              `json:"sort_by"`
+	SortOrder string              `json:"sort_order"`
+	Columns   []string            `json:"columns"`
+	IsShared  bool                `json:"is_shared"`
+}
+
+// Apply validates the request and copies it onto the view, normalizing tags
+// and sort order
+func (r *SaveCustomerViewRequest) Apply(view *CustomerListView) error {
+	name := strings.TrimSpace(r.Name)
+	if name == "" {
+		return errors.New("name is required")
+	}
+
+	f := r.Filters
+	if f.Status != "" && !shared.CustomerStatus(f.Status).IsValid() {
+		return fmt.Errorf("%w: %q", shared.ErrInvalidCustomerStatus, f.Status)
+	}
+	for _, d := range []struct{ field, value string }{{"date_from", f.DateFrom}, {"date_to", f.DateTo}} {
+		if d.value == "" {
+			continue
+		}
+		if _, err := time.Parse("2006-01-02", d.value); err != nil {
+			return fmt.Errorf("%s must be a YYYY-MM-DD date", d.field)
+		}
+	}
+	if f.OrdersMin != nil && f.OrdersMax != nil && *f.OrdersMin > *f.OrdersMax {
+		return errors.New("orders_min is greater than orders_max")
+	}
+	if f.SpentMin != nil && f.SpentMax != nil && *f.SpentMin > *f.SpentMax {
+		return errors.New("spent_min is greater than spent_max")
+	}
+	if f.CLVMin != nil && f.CLVMax != nil && *f.CLVMin > *f.CLVMax {
+		return errors.New("clv_min is greater than clv_max")
+	}
+	if f.ChurnRisk != "" && !ValidChurnRisk(f.ChurnRisk) {
+		return ErrInvalidChurnRisk
+	}
+	if len(f.RFMSegments) > 0 {
+		segments, err := ParseRFMSegments(strings.Join(f.RFMSegments, ","))
+		if err != nil {
+			return err
+		}
+		f.RFMSegments = segments
+	}
+	tags, err := NormalizeTagNames(f.Tags)
+	if err != nil {
+		return err
+	}
+	f.Tags = tags
+
+	if _, err := CustomerSort.Parse(r.SortBy, r.SortOrder); err != nil {
+		return err
+	}
+	sortOrder := strings.ToLower(r.SortOrder)
+	columns := r.Columns
+	if len(columns) > 0 {
+		if columns, err = ParseCustomerColumns(columns); err != nil {
+			return err
+		}
+	}
+
+	view.Name = name
+	view.Filters = f
+	view.SortBy = r.SortBy
+	view.SortOrder = sortOrder
+	view.Columns = columns
+	view.IsShared = r.IsShared
+	return nil
+}
+
+// Query returns the view as GET /admin/customers query parameters. Columns
+// are included for the export.
+func (v *CustomerListView) Query() url.Values {
+	q := url.Values{}
+	set := func(key, value string) {
+		if value != "" {
+			q.Set(key, value)
+		}
+	}
+	f := v.Filters
+	set("status", f.Status)
+	set("segment", f.Segment)
+	set("search", f.Search)
+	set("date_from", f.DateFrom)
+	set("date_to", f.DateTo)
+	if f.OrdersMin != nil {
+		set("orders_min", strconv.Itoa(*f.OrdersMin))
+	}
+	if f.OrdersMax != nil {
+		set("orders_max", strconv.Itoa(*f.OrdersMax))
+	}
+	if f.SpentMin != nil {
+		set("spent_min", strconv.FormatFloat(*f.SpentMin, 'f', -1, 64))
+	}
+	if f.SpentMax != nil {
+		set("spent_max", strconv.FormatFloat(*f.SpentMax, 'f', -1, 64))
+	}
+	if f.CLVMin != nil {
+		set("clv_min", strconv.FormatFloat(*f.CLVMin, 'f', -1, 64))
+	}
+	if f.CLVMax != nil {
+		set("clv_max", strconv.FormatFloat(*f.CLVMax, 'f', -1, 64))
+	}
+	set("rfm_segment", strings.Join(f.RFMSegments, ","))
+	set("churn_risk", f.ChurnRisk)
+	set("tags", strings.Join(f.Tags, ","))
+	set("sort_by", v.SortBy)
+	set("sort_order", v.SortOrder)
+	set("columns", strings.Join(v.Columns, ","))
+	return q
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,532 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/domain/address"
+	"github.com/Ecom-micro-template/service-customer/internal/domain/shared"
+)
+
+// Customer data export document format. Imports accept any version up to
+// DataExportVersion.
+const (
+	DataExportFormat  = "customer-data-export"
+	DataExportVersion = 1
+)
+
+// MaxDataImportRecords caps the records per section of an imported document
+const MaxDataImportRecords = 500
+
+// Data import record statuses
+const (
+	DataImportAdded         = "added" // also reported for records a dry run would add
+	DataImportDuplicate     = "duplicate"
+	DataImportInvalid       = "invalid"
+	DataImportQuotaExceeded = "quota_exceeded"
+)
+
+// ErrUnsupportedDataExport is returned for a document that isn't a customer
+// data export this service understands
+var ErrUnsupportedDataExport = errors.New("not a supported customer data export")
+
+// CustomerDataExport is the portable copy of a customer's addresses, wishlist
+// and measurements. It carries no IDs so it can be imported into any account,
+// e.g. after an account is split by region.
+type CustomerDataExport struct {
+	Format       string                 `json:"format"`
+	Version      int                    `json:"version"`
+	ExportedAt   time.Time              `json:"exported_at"`
+	Addresses    []PortableAddress      `json:"addresses"`
+	Wishlist     []PortableWishlistItem `json:"wishlist"`
+	Measurements []PortableMeasurement  `json:"measurements"`
+}
+
+// PortableAddress is an address in a data export
+type PortableAddress struct {
+	Label         string `json:"label"`
+	RecipientName string `json:"recipient_name"`
+	Phone         string `json:"phone"`
+	AddressLine1  string `json:"address_line1"`
+	AddressLine2  string `json:"address_line2,omitempty"`
+	City          string `json:"city"`
+	State         string `json:"state"`
+	Postcode      string `json:"postcode"`
+	Country       string `json:"country"`
+	IsDefault     bool   `json:"is_default"`
+}
+
+// PortableWishlistItem is a wishlist item in a data export
+type PortableWishlistItem struct {
+	ProductID     uuid.UUID  `json:"product_id"`
+	VariantID     *uuid.UUID `json:"variant_id,omitempty"`
+	VariantSKU    *string    `json:"variant_sku,omitempty"`
+	VariantName   *string    `json:"variant_name,omitempty"`
+	ProductName   *string    `json:"product_name,omitempty"`
+	ProductSlug   *string    `json:"product_slug,omitempty"`
+	ProductImage  *string    `json:"product_image,omitempty"`
+	PriceAtAdd    float64    `json:"price_at_add"`
+	NotifyOnSale  bool       `json:"notify_on_sale"`
+	NotifyRestock bool       `json:"notify_restock"`
+}
+
+// PortableMeasurement is a measurement in a data export. Lengths are always
+// centimetres; Unit is only the owner's display unit.
+type PortableMeasurement struct {
+	Name          *string  `json:"name,omitempty"`
+	ProfilePerson string   `json:"profile_person"`
+	Gender        string   `json:"gender"`
+	Bust          *float64 `json:"bust,omitempty"`
+	Chest         *float64 `json:"chest,omitempty"`
+	Waist         *float64 `json:"waist,omitempty"`
+	Hip           *float64 `json:"hip,omitempty"`
+	ShoulderWidth *float64 `json:"shoulder_width,omitempty"`
+	ArmLength     *float64 `json:"arm_length,omitempty"`
+	Inseam        *float64 `json:"inseam,omitempty"`
+	Outseam       *float64 `json:"outseam,omitempty"`
+	Thigh         *float64 `json:"thigh,omitempty"`
+	Neck          *float64 `json:"neck,omitempty"`
+	Wrist         *float64 `json:"wrist,omitempty"`
+	Height        *float64 `json:"height,omitempty"`
+	Weight        *float64 `json:"weight,omitempty"`
+	Unit          string   `json:"unit"`
+	Notes         *string  `json:"notes,omitempty"`
+	IsDefault     bool     `json:"is_default"`
+}
+
+// NewCustomerDataExport builds the export document of a customer's data
+func NewCustomerDataExport(addresses []Address, wishlist []WishlistItem, measurements []CustomerMeasurement) *CustomerDataExport {
+	export := &CustomerDataExport{
+		Format:       DataExportFormat,
+		Version:      DataExportVersion,
+		ExportedAt:   time.Now().UTC(),
+		Addresses:    make([]PortableAddress, 0, len(addresses)),
+		Wishlist:     make([]PortableWishlistItem, 0, len(wishlist)),
+		Measurements: make([]PortableMeasurement, 0, len(measurements)),
+	}
+	for _, a := range addresses {
+		export.Addresses = append(export.Addresses, PortableAddress{
+			Label:         string(a.Label),
+			RecipientName: a.RecipientName,
+			Phone:         a.Phone,
+			AddressLine1:  a.AddressLine1,
+			AddressLine2:  a.AddressLine2,
+			City:          a.City,
+			State:         a.State,
+			Postcode:      a.Postcode,
+			Country:       a.Country,
+			IsDefault:     a.IsDefault,
+		})
+	}
+	for _, w := range wishlist {
+		export.Wishlist = append(export.Wishlist, PortableWishlistItem{
+			ProductID:     w.ProductID,
+			VariantID:     w.VariantID,
+			VariantSKU:    w.VariantSKU,
+			VariantName:   w.VariantName,
+			ProductName:   w.ProductName,
+			ProductSlug:   w.ProductSlug,
+			ProductImage:  w.ProductImage,
+			PriceAtAdd:    w.PriceAtAdd,
+			NotifyOnSale:  w.NotifyOnSale,
+			NotifyRestock: w.NotifyRestock,
+		})
+	}
+	for _, m := range measurements {
+		export.Measurements = append(export.Measurements, PortableMeasurement{
+			Name:          m.Name,
+			ProfilePerson: m.ProfilePerson,
+			Gender:        string(m.Gender),
+			Bust:          m.Bust,
+			Chest:         m.Chest,
+			Waist:         m.Waist,
+			Hip:           m.Hip,
+			ShoulderWidth: m.ShoulderWidth,
+			ArmLength:     m.ArmLength,
+			Inseam:        m.Inseam,
+			Outseam:       m.Outseam,
+			Thigh:         m.Thigh,
+			Neck:          m.Neck,
+			Wrist:         m.Wrist,
+			Height:        m.Height,
+			Weight:        m.Weight,
+			Unit:          m.Unit,
+			Notes:         m.Notes,
+			IsDefault:     m.IsDefault,
+		})
+	}
+	return export
+}
+
+// Validate checks the document format and section sizes. Individual records
+// are validated when the import is planned.
+func (e *CustomerDataExport) Validate() error {
+	if e.Format != DataExportFormat || e.Version < 1 || e.Version > DataExportVersion {
+		return ErrUnsupportedDataExport
+	}
+	sections := []struct {
+		name  string
+		count int
+	}{
+		{"addresses", len(e.Addresses)},
+		{"wishlist", len(e.Wishlist)},
+		{"measurements", len(e.Measurements)},
+	}
+	for _, section := range sections {
+		if section.count > MaxDataImportRecords {
+			return fmt.Errorf("%s has more than %d records", section.name, MaxDataImportRecords)
+		}
+	}
+	return nil
+}
+
+// DataImportResult is the outcome of one imported record. Index is the
+// record's position in its section of the document.
+type DataImportResult struct {
+	Index   int    `json:"index"`
+	Status  string `json:"status"`
+	Message string `json:"message,omitempty"`
+}
+
+// DataImportSection is the outcome of one section of an import
+type DataImportSection struct {
+	Added         int                `json:"added"`
+	Duplicate     int                `json:"duplicate"`
+	Invalid       int                `json:"invalid"`
+	QuotaExceeded int                `json:"quota_exceeded"`
+	Results       []DataImportResult `json:"results"`
+}
+
+func (s *DataImportSection) add(index int, status, message string) {
+	switch status {
+	case DataImportAdded:
+		s.Added++
+	case DataImportDuplicate:
+		s.Duplicate++
+	case DataImportInvalid:
+		s.Invalid++
+	case DataImportQuotaExceeded:
+		s.QuotaExceeded++
+	}
+	s.Results = append(s.Results, DataImportResult{Index: index, Status: status, Message: message})
+}
+
+// DataImportSummary is the outcome of a data import or dry run
+type DataImportSummary struct {
+	DryRun       bool              `json:"dry_run"`
+	Addresses    DataImportSection `json:"addresses"`
+	Wishlist     DataImportSection `json:"wishlist"`
+	Measurements DataImportSection `json:"measurements"`
+}
+
+// DataImportOptions are the account limits an import must respect
+type DataImportOptions struct {
+	Countries address.CountryPolicy
+	Limits    CustomerLimits // zero fields for no limit
+}
+
+// DataImportPlan holds the records an import creates and the outcome of
+// every record in the document
+type DataImportPlan struct {
+	Addresses    []Address
+	Wishlist     []WishlistItem
+	Measurements []CustomerMeasurement
+	Summary      DataImportSummary
+}
+
+// PlanDataImport decides which records of export to add to the user's
+// account given what the account already holds. Records already in the
+// account or earlier in the document are duplicates. Imported records only
+// become the default when the account has no default of its own.
+func PlanDataImport(userID uuid.UUID, export *CustomerDataExport, existing []Address, wishlist []WishlistItem, measurements []CustomerMeasurement, opts DataImportOptions) *DataImportPlan {
+	plan := &DataImportPlan{}
+	plan.planAddresses(userID, export.Addresses, existing, opts.Countries, opts.Limits.Addresses)
+	plan.planWishlist(userID, export.Wishlist, wishlist, opts.Limits.WishlistItems)
+	plan.planMeasurements(userID, export.Measurements, measurements, opts.Limits.Measurements)
+	return plan
+}
+
+func (p *DataImportPlan) planAddresses(userID uuid.UUID, records []PortableAddress, existing []Address, countries address.CountryPolicy, maxAddresses int) {
+	section := &p.Summary.Addresses
+	section.Results = make([]DataImportResult, 0, len(records))
+
+	known := append([]Address{}, existing...)
+	hasDefault := false
+	for _, a := range existing {
+		hasDefault = hasDefault || a.IsDefault
+	}
+
+	for i, record := range records {
+		if err := record.validate(countries); err != nil {
+			section.add(i, DataImportInvalid, err.Error())
+			continue
+		}
+		label, _ := record.label()
+		imported := Address{
+			UserID:        userID,
+			Label:         label,
+			RecipientName: strings.TrimSpace(record.RecipientName),
+			Phone:         strings.TrimSpace(record.Phone),
+			AddressLine1:  strings.TrimSpace(record.AddressLine1),
+			AddressLine2:  strings.TrimSpace(record.AddressLine2),
+			City:          strings.TrimSpace(record.City),
+			State:         strings.TrimSpace(record.State),
+			Postcode:      strings.TrimSpace(record.Postcode),
+			Country:       strings.TrimSpace(record.Country),
+			IsDefault:     record.IsDefault && !hasDefault,
+		}
+		if containsLocation(known, &imported) {
+			section.add(i, DataImportDuplicate, "")
+			continue
+		}
+		if maxAddresses > 0 && len(known) >= maxAddresses {
+			section.add(i, DataImportQuotaExceeded, address.ErrMaxAddresses.Error())
+			continue
+		}
+		hasDefault = hasDefault || imported.IsDefault
+		known = append(known, imported)
+		p.Addresses = append(p.Addresses, imported)
+		section.add(i, DataImportAdded, "")
+	}
+}
+
+func containsLocation(addresses []Address, a *Address) bool {
+	for i := range addresses {
+		if addresses[i].SameLocation(a) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *DataImportPlan) planWishlist(userID uuid.UUID, records []PortableWishlistItem, existing []WishlistItem, maxItems int) {
+	section := &p.Summary.Wishlist
+	section.Results = make([]DataImportResult, 0, len(records))
+
+	known := make(map[string]bool, len(existing))
+	for i := range existing {
+		known[existing[i].GetUniqueKey()] = true
+	}
+	count := len(existing)
+
+	for i, record := range records {
+		if err := record.validate(); err != nil {
+			section.add(i, DataImportInvalid, err.Error())
+			continue
+		}
+		imported := WishlistItem{
+			UserID:        userID,
+			ProductID:     record.ProductID,
+			VariantID:     record.VariantID,
+			VariantSKU:    record.VariantSKU,
+			VariantName:   record.VariantName,
+			ProductName:   record.ProductName,
+			ProductSlug:   record.ProductSlug,
+			ProductImage:  record.ProductImage,
+			PriceAtAdd:    record.PriceAtAdd,
+			NotifyOnSale:  record.NotifyOnSale,
+			NotifyRestock: record.NotifyRestock,
+		}
+		if known[imported.GetUniqueKey()] {
+			section.add(i, DataImportDuplicate, "")
+			continue
+		}
+		if maxItems > 0 && count >= maxItems {
+			section.add(i, DataImportQuotaExceeded, ErrWishlistFull.Error())
+			continue
+		}
+		known[imported.GetUniqueKey()] = true
+		count++
+		p.Wishlist = append(p.Wishlist, imported)
+		section.add(i, DataImportAdded, "")
+	}
+}
+
+func (p *DataImportPlan) planMeasurements(userID uuid.UUID, records []PortableMeasurement, existing []CustomerMeasurement, maxMeasurements int) {
+	section := &p.Summary.Measurements
+	section.Results = make([]DataImportResult, 0, len(records))
+
+	known := make(map[string]bool, len(existing))
+	people := make(map[string]bool)
+	defaults := make(map[string]bool)
+	for i := range existing {
+		known[existing[i].contentKey()] = true
+		people[existing[i].ProfilePerson] = true
+		if existing[i].IsDefault {
+			defaults[existing[i].ProfilePerson] = true
+		}
+	}
+	count := len(existing)
+
+	for i, record := range records {
+		if err := record.validate(); err != nil {
+			section.add(i, DataImportInvalid, err.Error())
+			continue
+		}
+		unit := record.Unit
+		if unit == "" {
+			unit = MeasurementUnitCM
+		}
+		imported := CustomerMeasurement{
+			UserID:        userID,
+			Name:          record.Name,
+			ProfilePerson: NormalizeProfilePerson(record.ProfilePerson),
+			Gender:        shared.Gender(record.Gender),
+			Bust:          record.Bust,
+			Chest:         record.Chest,
+			Waist:         record.Waist,
+			Hip:           record.Hip,
+			ShoulderWidth: record.ShoulderWidth,
+			ArmLength:     record.ArmLength,
+			Inseam:        record.Inseam,
+			Outseam:       record.Outseam,
+			Thigh:         record.Thigh,
+			Neck:          record.Neck,
+			Wrist:         record.Wrist,
+			Height:        record.Height,
+			Weight:        record.Weight,
+			Unit:          unit,
+			Notes:         record.Notes,
+		}
+		if known[imported.contentKey()] {
+			section.add(i, DataImportDuplicate, "")
+			continue
+		}
+		if maxMeasurements > 0 && count >= maxMeasurements {
+			section.add(i, DataImportQuotaExceeded, ErrMeasurementLimit.Error())
+			continue
+		}
+		if !people[imported.ProfilePerson] && len(people) >= MaxMeasurementProfiles {
+			section.add(i, DataImportQuotaExceeded, ErrMeasurementProfileLimit.Error())
+			continue
+		}
+		imported.IsDefault = record.IsDefault && !defaults[imported.ProfilePerson]
+		imported.DeriveStandardSize()
+
+		known[imported.contentKey()] = true
+		count++
+		people[imported.ProfilePerson] = true
+		defaults[imported.ProfilePerson] = defaults[imported.ProfilePerson] || imported.IsDefault
+		p.Measurements = append(p.Measurements, imported)
+		section.add(i, DataImportAdded, "")
+	}
+}
+
+// contentKey identifies a measurement by person, name, gender and values, so
+// re-importing the same measurement is detected as a duplicate
+func (cm *CustomerMeasurement) contentKey() string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(cm.ProfilePerson))
+	b.WriteString("|")
+	if cm.Name != nil {
+		b.WriteString(strings.ToLower(strings.TrimSpace(*cm.Name)))
+	}
+	b.WriteString("|" + cm.Gender.String())
+	for _, field := range append(cm.lengthFields(), &cm.Weight) {
+		if *field != nil {
+			fmt.Fprintf(&b, "|%.1f", **field)
+		} else {
+			b.WriteString("|-")
+		}
+	}
+	return b.String()
+}
+
+// validate checks an imported address like the address endpoints do
+func (a *PortableAddress) validate(countries address.CountryPolicy) error {
+	required := []struct {
+		field, value string
+		max          int
+	}{
+		{"recipient_name", a.RecipientName, 200},
+		{"phone", a.Phone, 50},
+		{"address_line1", a.AddressLine1, 500},
+		{"city", a.City, 100},
+		{"state", a.State, 100},
+		{"postcode", a.Postcode, 20},
+		{"country", a.Country, 100},
+	}
+	for _, f := range required {
+		value := strings.TrimSpace(f.value)
+		if value == "" {
+			return fmt.Errorf("%s is required", f.field)
+		}
+		if len(value) > f.max {
+			return fmt.Errorf("%s is longer than %d characters", f.field, f.max)
+		}
+	}
+	if _, err := a.label(); err != nil {
+		return err
+	}
+	if len(strings.TrimSpace(a.AddressLine2)) > 500 {
+		return errors.New("address_line2 is longer than 500 characters")
+	}
+	return countries.Validate(a.Country, a.Postcode, a.Phone)
+}
+
+// label returns the record's address label; exports may leave it empty
+func (a *PortableAddress) label() (shared.AddressLabel, error) {
+	if strings.TrimSpace(a.Label) == "" {
+		return "", nil
+	}
+	return shared.ParseAddressLabel(a.Label)
+}
+
+func (w *PortableWishlistItem) validate() error {
+	if w.ProductID == uuid.Nil {
+		return errors.New("product_id is required")
+	}
+	if w.PriceAtAdd < 0 {
+		return errors.New("price_at_add must not be negative")
+	}
+	lengths := []struct {
+		field string
+		value *string
+		max   int
+	}{
+		{"variant_sku", w.VariantSKU, 50},
+		{"variant_name", w.VariantName, 100},
+		{"product_name", w.ProductName, 255},
+		{"product_slug", w.ProductSlug, 255},
+		{"product_image", w.ProductImage, 500},
+	}
+	for _, f := range lengths {
+		if f.value != nil && len(*f.value) > f.max {
+			return fmt.Errorf("%s is longer than %d characters", f.field, f.max)
+		}
+	}
+	return nil
+}
+
+// maxMeasurementValue is the largest value a decimal(5,1) column holds
+const maxMeasurementValue = 9999.9
+
+func (m *PortableMeasurement) validate() error {
+	if _, err := shared.ParseGender(m.Gender); err != nil {
+		return err
+	}
+	if m.Unit != "" && !IsValidMeasurementUnit(m.Unit) {
+		return fmt.Errorf("unit must be %s or %s", MeasurementUnitCM, MeasurementUnitInch)
+	}
+	if len(strings.TrimSpace(m.ProfilePerson)) > 50 {
+		return errors.New("profile_person is longer than 50 characters")
+	}
+	if m.Name != nil && len(*m.Name) > 100 {
+		return errors.New("name is longer than 100 characters")
+	}
+
+	values := CustomerMeasurement{
+		Bust: m.Bust, Chest: m.Chest, Waist: m.Waist, Hip: m.Hip, ShoulderWidth: m.ShoulderWidth,
+		ArmLength: m.ArmLength, Inseam: m.Inseam, Outseam: m.Outseam, Thigh: m.Thigh,
+		Neck: m.Neck, Wrist: m.Wrist, Height: m.Height, Weight: m.Weight,
+	}
+	for _, field := range append(values.lengthFields(), &values.Weight) {
+		if *field != nil && (**field <= 0 || **field > maxMeasurementValue) {
+			return errors.New("measurements must be positive and at most 9999.9")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,202 @@
+package domain
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/domain/shared"
+	"gorm.io/gorm"
+)
+
+// Reasons two customers are probable duplicates
+const (
+	DuplicateReasonPhone       = "phone"        // same normalized phone number
+	DuplicateReasonEmail       = "email"        // same email once tags, dots and domain aliases are removed
+	DuplicateReasonNameAddress = "name_address" // same name and an address at the same place
+)
+
+// Review states of a duplicate candidate
+const (
+	DuplicateStatusPending   = "pending"
+	DuplicateStatusMerged    = "merged"
+	DuplicateStatusDismissed = "dismissed"
+)
+
+// MaxDuplicateGroup is the most customers a match key may be shared by to
+// count as a duplicate. Keys shared more widely are placeholders such as a
+// store phone number, not the same person.
+const MaxDuplicateGroup = 5
+
+// ErrDuplicateCandidateReviewed is returned when dismissing a duplicate
+// candidate that is no longer pending
+var ErrDuplicateCandidateReviewed = errors.New("duplicate candidate has already been reviewed")
+
+// DuplicateMatchKey is one normalized value of a customer that the
+// duplicate_detection job compares between customers
+type DuplicateMatchKey struct {
+	CustomerID uuid.UUID `gorm:"type:uuid;primaryKey"`
+	Kind       string    `gorm:"type:varchar(20);primaryKey;index:idx_duplicate_match_keys_kind_key"`
+	Key        string    `gorm:"type:text;primaryKey;index:idx_duplicate_match_keys_kind_key"`
+}
+
+// TableName specifies the table name for DuplicateMatchKey
+func (DuplicateMatchKey) TableName() string {
+	return "customer.duplicate_match_keys"
+}
+
+// DuplicateCandidate is a pair of customers who are probably the same
+// person, queued for an admin to merge or dismiss. CustomerID is the lower
+// of the two IDs, so each pair is queued once.
+type DuplicateCandidate struct {
+	ID          uuid.UUID  `gorm:"type:uuid;primary_key" json:"id"`
+	CustomerID  uuid.UUID  `gorm:"type:uuid;not null;uniqueIndex:idx_duplicate_candidates_pair" json:"customer_id"`
+	DuplicateID uuid.UUID  `gorm:"type:uuid;not null;uniqueIndex:idx_duplicate_candidates_pair;index" json:"duplicate_id"`
+	Reasons     []string   `gorm:"type:jsonb;serializer:json" json:"reasons"`
+	Status      string     `gorm:"type:varchar(20);not null;default:'pending';index" json:"status"`
+	DetectedAt  time.Time  `json:"detected_at"`
+	ReviewedBy  *uuid.UUID `gorm:"type:uuid" json:"reviewed_by,omitempty"`
+	ReviewedAt  *time.Time `json:"reviewed_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+
+	// Both customers, the suggested primary first, filled in by the review
+	// queue
+	Customers []Customer `gorm:"-" json:"customers,omitempty"`
+}
+
+// BeforeCreate hook to ensure UUID is set
+func (d *DuplicateCandidate) BeforeCreate(tx *gorm.DB) error {
+	if d.ID == uuid.Nil {
+		d.ID = uuid.New()
+	}
+	return nil
+}
+
+// TableName specifies the table name for DuplicateCandidate
+func (DuplicateCandidate) TableName() string {
+	return "customer.duplicate_candidates"
+}
+
+// DuplicateDetectionResult counts the customers a duplicate detection run
+// went through, the probable duplicate pairs found and those newly queued
+// for review
+type DuplicateDetectionResult struct {
+	Customers int
+	Pairs     int
+	Queued    int
+}
+
+// DuplicateMatchKeys returns the phone blind index and the normalized email
+// and name-and-address keys of a customer with their addresses
+func DuplicateMatchKeys(customer *Customer, addresses []Address) []DuplicateMatchKey {
+	keys := []DuplicateMatchKey{}
+	add := func(kind, key string) {
+		for _, existing := range keys {
+			if existing.Kind == kind && existing.Key == key {
+				return
+			}
+		}
+		keys = append(keys, DuplicateMatchKey{CustomerID: customer.ID, Kind: kind, Key: key})
+	}
+
+	if phone := customer.PhoneLookup(); phone != "" {
+		add(DuplicateReasonPhone, phone)
+	}
+	if email := NormalizeDuplicateEmail(customer.Email); email != "" {
+		add(DuplicateReasonEmail, email)
+	}
+	name := normalizeAddressPart(customer.FirstName + " " + customer.LastName)
+	if name == "" {
+		return keys
+	}
+	for _, a := range addresses {
+		line := normalizeAddressPart(a.AddressLine1)
+		if line == "" {
+			continue
+		}
+		add(DuplicateReasonNameAddress, strings.Join([]string{
+			name, line, normalizeAddressPart(a.Postcode), normalizeCountry(a.Country),
+		}, "|"))
+	}
+	return keys
+}
+
+// PhoneBlindIndexColumn scopes the blind index of customer phones
+const PhoneBlindIndexColumn = "customers.phone"
+
+// PhoneBlindIndex is the lookup key of a phone number, the blind index of
+// its normalized form, shared by duplicate detection and the blocklist so
+// neither stores the number itself. It returns "" for numbers too short to
+// identify anyone.
+func PhoneBlindIndex(phone string) string {
+	return BlindIndex(NormalizeDuplicatePhone(phone), PhoneBlindIndexColumn)
+}
+
+// NormalizeDuplicatePhone reduces a phone number to its digits with the
+// country code. Numbers in the national format (leading 0) are taken to be
+// Malaysian. It returns "" for numbers too short to identify anyone.
+func NormalizeDuplicatePhone(phone string) string {
+	p, err := shared.NewPhone(phone)
+	if err != nil {
+		return ""
+	}
+	digits := strings.TrimPrefix(p.Normalized(), "+")
+	switch {
+	case strings.HasPrefix(digits, "00"):
+		digits = digits[2:]
+	case strings.HasPrefix(digits, "0"):
+		digits = "60" + digits[1:]
+	}
+	if len(digits) < 9 {
+		return ""
+	}
+	return digits
+}
+
+// emailDomainAliases maps email domains to the domain whose mailboxes they
+// deliver to
+var emailDomainAliases = map[string]string{
+	"googlemail.com": "gmail.com",
+}
+
+// NormalizeDuplicateEmail lower-cases an email and drops +tags from the
+// local part, and for Gmail the dots, which the provider ignores
+func NormalizeDuplicateEmail(email string) string {
+	local, domain, ok := strings.Cut(strings.ToLower(strings.TrimSpace(email)), "@")
+	if !ok || local == "" || domain == "" {
+		return ""
+	}
+	if alias, ok := emailDomainAliases[domain]; ok {
+		domain = alias
+	}
+	local, _, _ = strings.Cut(local, "+")
+	if domain == "gmail.com" {
+		local = strings.ReplaceAll(local, ".", "")
+	}
+	if local == "" {
+		return ""
+	}
+	return local + "@" + domain
+}
+
+// DuplicatePair orders two customer IDs the way DuplicateCandidate stores
+// them
+func DuplicatePair(a, b uuid.UUID) (uuid.UUID, uuid.UUID) {
+	if a.String() > b.String() {
+		return b, a
+	}
+	return a, b
+}
+
+// SortDuplicates puts the customer to keep first: the one with more orders,
+// then the older account
+func SortDuplicates(customers []Customer) {
+	sort.SliceStable(customers, func(i, j int) bool {
+		if customers[i].TotalOrders != customers[j].TotalOrders {
+			return customers[i].TotalOrders > customers[j].TotalOrders
+		}
+		return customers[i].CreatedAt.Before(customers[j].CreatedAt)
+	})
+}
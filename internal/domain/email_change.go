@@ -0,0 +1,77 @@
+package domain
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// EmailChangeTTL is how long a customer has to verify a new email address
+const EmailChangeTTL = 24 * time.Hour
+
+var (
+	// ErrEmailChangeTokenInvalid is returned when a verification token is unknown,
+	// expired or was issued to another customer
+	ErrEmailChangeTokenInvalid = errors.New("email verification token is invalid or expired")
+	// ErrEmailInUse is returned when the new email already belongs to another customer
+	ErrEmailInUse = errors.New("email is already used by another account")
+	// ErrEmailUnchanged is returned when the new email is the current one
+	ErrEmailUnchanged = errors.New("new email is the same as the current email")
+	// ErrEmailChangeRequired is returned when a profile update changes the email
+	// instead of going through verification
+	ErrEmailChangeRequired = errors.New("email must be changed through POST /customer/email-change")
+)
+
+// EmailChange is a customer's pending change of email address. The profile
+// keeps the old email until the new one is verified with the emailed token;
+// requesting another change replaces the pending one.
+type EmailChange struct {
+	ID        uuid.UUID `gorm:"type:uuid;primaryKey" json:"id"`
+	UserID    uuid.UUID `gorm:"type:uuid;not null;uniqueIndex" json:"user_id"`
+	OldEmail  string    `gorm:"type:varchar(200)" json:"old_email"`
+	NewEmail  string    `gorm:"type:varchar(200);not null" json:"new_email"`
+	TokenHash string    `gorm:"size:64;uniqueIndex" json:"-"` // only the SHA-256 of the token is stored
+	ExpiresAt time.Time `gorm:"not null" json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TableName specifies the table name for EmailChange
+func (EmailChange) TableName() string {
+	return "customer.email_changes"
+}
+
+// BeforeCreate hook to ensure UUID is set
+func (e *EmailChange) BeforeCreate(tx *gorm.DB) error {
+	if e.ID == uuid.Nil {
+		e.ID = uuid.New()
+	}
+	return nil
+}
+
+// RequestEmailChangeInput is the request body for starting an email change
+type RequestEmailChangeInput struct {
+	Email string `json:"email" binding:"required,email,max=200"`
+}
+
+// ConfirmEmailChangeInput is the request body for verifying a new email
+type ConfirmEmailChangeInput struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// EmailChangeVerification is the verify-email request sent to the
+// notification service for the new address
+type EmailChangeVerification struct {
+	Email     string    `json:"email"`
+	VerifyURL string    `json:"verifyUrl"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// EmailChangeNotice is the security notice sent to the old address once the
+// change is applied
+type EmailChangeNotice struct {
+	Email     string    `json:"email"`
+	NewEmail  string    `json:"newEmail"` // masked, e.g. j***e@example.com
+	ChangedAt time.Time `json:"changedAt"`
+}
@@ -0,0 +1,184 @@
+package domain
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync/atomic"
+	"time"
+
+	"gorm.io/gorm/schema"
+)
+
+// ColumnCipher encrypts the columns tagged serializer:encrypted;
+// *encryption.Keyring implements it
+type ColumnCipher interface {
+	// Encrypt binds the value to binding, see EncryptionBinding
+	Encrypt(plaintext, binding string) (string, error)
+	// Decrypt returns the plaintext of an encrypted value, or the value as
+	// it is when it was stored before encryption was enabled
+	Decrypt(value, binding string) (string, error)
+	// BlindIndex returns a keyed hash of a value for equality lookups
+	BlindIndex(value, column string) string
+}
+
+// EncryptionBinding is the additional data an encrypted value is bound to:
+// its table, column and row, so a ciphertext copied to another row or
+// column fails to decrypt
+func EncryptionBinding(table, column, rowID string) string {
+	return table + "." + column + "#" + rowID
+}
+
+// BlindIndex returns the lookup key of a value of an encrypted column: a
+// keyed hash once keys are configured, the value itself until then
+func BlindIndex(value, column string) string {
+	if cipher := columnCipher.Load(); cipher != nil {
+		return (*cipher).BlindIndex(value, column)
+	}
+	return value
+}
+
+// columnCipher is set at startup; until then values are stored in plaintext
+var columnCipher atomic.Pointer[ColumnCipher]
+
+func init() {
+	schema.RegisterSerializer("encrypted", encryptedColumn{})
+}
+
+// UseColumnCipher sets the cipher of the encrypted columns; nil stores
+// them in plaintext
+func UseColumnCipher(cipher ColumnCipher) {
+	if cipher == nil {
+		columnCipher.Store(nil)
+		return
+	}
+	columnCipher.Store(&cipher)
+}
+
+// encryptedColumn is the GORM serializer of sensitive string and time
+// columns. They are stored as text, encrypted unless no keys are configured,
+// and read back in plaintext, so repositories never see the ciphertext.
+type encryptedColumn struct{}
+
+// encryptedTimeLayouts are the formats a decrypted time is parsed from: the
+// one Value writes, and Postgres' text form of a timestamptz for rows
+// converted from a timestamp column and not yet re-encrypted
+var encryptedTimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999Z07",
+	time.DateOnly,
+}
+
+func (encryptedColumn) Scan(ctx context.Context, field *schema.Field, dst reflect.Value, dbValue interface{}) error {
+	target := reflect.New(field.FieldType)
+	if dbValue != nil {
+		var stored string
+		switch v := dbValue.(type) {
+		case string:
+			stored = v
+		case []byte:
+			stored = string(v)
+		case time.Time:
+			stored = v.Format(time.RFC3339Nano)
+		default:
+			return fmt.Errorf("unsupported value %T in encrypted column %s", dbValue, field.DBName)
+		}
+		plaintext, err := decryptColumn(stored, columnBinding(ctx, field, dst))
+		if err != nil {
+			return fmt.Errorf("decrypt %s: %w", field.DBName, err)
+		}
+		// An empty value of a pointer field is read as nil
+		if plaintext != "" || field.FieldType.Kind() != reflect.Pointer {
+			if err := setPlaintext(target.Elem(), plaintext); err != nil {
+				return fmt.Errorf("decrypt %s: %w", field.DBName, err)
+			}
+		}
+	}
+	field.ReflectValueOf(ctx, dst).Set(target.Elem())
+	return nil
+}
+
+func (encryptedColumn) Value(ctx context.Context, field *schema.Field, dst reflect.Value, fieldValue interface{}) (interface{}, error) {
+	var plaintext string
+	switch v := fieldValue.(type) {
+	case string:
+		plaintext = v
+	case *string:
+		if v == nil {
+			return nil, nil
+		}
+		plaintext = *v
+	case time.Time:
+		plaintext = v.Format(time.RFC3339Nano)
+	case *time.Time:
+		if v == nil {
+			return nil, nil
+		}
+		plaintext = v.Format(time.RFC3339Nano)
+	default:
+		return nil, fmt.Errorf("unsupported type %T for encrypted column %s", fieldValue, field.DBName)
+	}
+	// Empty values reveal nothing and keep NOT NULL columns valid
+	if plaintext == "" {
+		return "", nil
+	}
+	if columnCipher.Load() != nil {
+		if _, zero := field.Schema.PrioritizedPrimaryField.ValueOf(ctx, dst); zero {
+			return nil, fmt.Errorf("encrypt %s: the row has no ID to bind it to", field.DBName)
+		}
+	}
+	return encryptColumn(plaintext, columnBinding(ctx, field, dst))
+}
+
+// columnBinding is the EncryptionBinding of a field of the row dst. The row
+// ID is read first in every query, so it is set by the time the encrypted
+// columns are scanned.
+func columnBinding(ctx context.Context, field *schema.Field, dst reflect.Value) string {
+	id, _ := field.Schema.PrioritizedPrimaryField.ValueOf(ctx, dst)
+	return EncryptionBinding(field.Schema.Table, field.DBName, fmt.Sprint(id))
+}
+
+// encryptColumn encrypts a value of an encrypted column with the configured
+// cipher, or returns it as it is without one
+func encryptColumn(plaintext, binding string) (string, error) {
+	if cipher := columnCipher.Load(); cipher != nil {
+		return (*cipher).Encrypt(plaintext, binding)
+	}
+	return plaintext, nil
+}
+
+func decryptColumn(value, binding string) (string, error) {
+	if cipher := columnCipher.Load(); cipher != nil {
+		return (*cipher).Decrypt(value, binding)
+	}
+	return value, nil
+}
+
+// setPlaintext sets a string, time or pointer to either from its plaintext
+func setPlaintext(target reflect.Value, plaintext string) error {
+	if target.Kind() == reflect.Pointer {
+		value := reflect.New(target.Type().Elem())
+		if err := setPlaintext(value.Elem(), plaintext); err != nil {
+			return err
+		}
+		target.Set(value)
+		return nil
+	}
+
+	switch target.Interface().(type) {
+	case string:
+		target.SetString(plaintext)
+	case time.Time:
+		for _, layout := range encryptedTimeLayouts {
+			if t, err := time.Parse(layout, plaintext); err == nil {
+				target.Set(reflect.ValueOf(t))
+				return nil
+			}
+		}
+		return fmt.Errorf("invalid time %q", plaintext)
+	default:
+		return fmt.Errorf("unsupported type %s", target.Type())
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// MaxIdempotencyKeyLength bounds the Idempotency-Key header
+const MaxIdempotencyKeyLength = 255
+
+// IdempotencyKey records the response to a request sent with an
+// Idempotency-Key header, so a client retrying after a timeout gets the
+// original response instead of creating a duplicate. StatusCode is 0 while
+// the first request is still being handled.
+type IdempotencyKey struct {
+	ID          uuid.UUID `gorm:"type:uuid;primaryKey" json:"id"`
+	Scope       string    `gorm:"type:varchar(100);not null;uniqueIndex:idx_idempotency_keys_scope_key,priority:1" json:"scope"` // the caller, e.g. the customer ID
+	Key         string    `gorm:"column:idempotency_key;type:varchar(255);not null;uniqueIndex:idx_idempotency_keys_scope_key,priority:2" json:"key"`
+	Method      string    `gorm:"type:varchar(10);not null" json:"method"`
+	Path        string    `gorm:"type:varchar(255);not null" json:"path"`
+	RequestHash string    `gorm:"type:varchar(64);not null" json:"request_hash"` // SHA-256 of the method, path and body
+
+	StatusCode  int    `gorm:"not null;default:0" json:"status_code"`
+	ContentType string `gorm:"type:varchar(100)" json:"content_type,omitempty"`
+	Body        []byte `json:"-"`
+
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `gorm:"not null;index" json:"expires_at"`
+}
+
+func (IdempotencyKey) TableName() string {
+	return "customer.idempotency_keys"
+}
+
+func (k *IdempotencyKey) BeforeCreate(tx *gorm.DB) error {
+	if k.ID == uuid.Nil {
+		k.ID = uuid.New()
+	}
+	return nil
+}
+
+// Completed reports whether the response of the first request is stored
+func (k *IdempotencyKey) Completed() bool {
+	return k.StatusCode != 0
+}
@@ -0,0 +1,119 @@
+package domain
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Impersonation scopes
+const (
+	ImpersonationScopeRead  = "read"  // safe methods only
+	ImpersonationScopeWrite = "write" // any customer endpoint
+)
+
+// Impersonation token lifetimes
+const (
+	DefaultImpersonationTTL = 15 * time.Minute
+	MaxImpersonationTTL     = time.Hour
+)
+
+// ErrImpersonationInactive is returned for expired or revoked impersonation sessions
+var ErrImpersonationInactive = errors.New("impersonation session expired or revoked")
+
+// ImpersonationSession is an admin's time-boxed "view as customer" session.
+// Every session is kept as the audit record of who impersonated whom and why.
+type ImpersonationSession struct {
+	ID         uuid.UUID  `gorm:"type:uuid;primary_key" json:"id"`
+	AdminID    uuid.UUID  `gorm:"type:uuid;not null;index" json:"admin_id"`
+	CustomerID uuid.UUID  `gorm:"type:uuid;not null;index" json:"customer_id"`
+	Reason     string     `gorm:"type:text;not null" json:"reason"`
+	Scope      string     `gorm:"type:varchar(10);not null;default:'read'" json:"scope"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	RevokedBy  *uuid.UUID `gorm:"type:uuid" json:"revoked_by,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+func (s *ImpersonationSession) BeforeCreate(tx *gorm.DB) error {
+	if s.ID == uuid.Nil {
+		s.ID = uuid.New()
+	}
+	return nil
+}
+
+func (ImpersonationSession) TableName() string {
+	return "customer.impersonation_sessions"
+}
+
+// IsActive reports whether the session can still be used at now
+func (s *ImpersonationSession) IsActive(now time.Time) bool {
+	return s.RevokedAt == nil && now.Before(s.ExpiresAt)
+}
+
+// Allows reports whether the session's scope permits a request with the given method
+func (s *ImpersonationSession) Allows(method string) bool {
+	if s.Scope == ImpersonationScopeWrite {
+		return true
+	}
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	return false
+}
+
+// ImpersonationRequest is one API call made with an impersonation token
+type ImpersonationRequest struct {
+	ID         uuid.UUID `gorm:"type:uuid;primary_key" json:"id"`
+	SessionID  uuid.UUID `gorm:"type:uuid;not null;index" json:"session_id"`
+	AdminID    uuid.UUID `gorm:"type:uuid;not null" json:"admin_id"`
+	CustomerID uuid.UUID `gorm:"type:uuid;not null" json:"customer_id"`
+	Method     string    `gorm:"type:varchar(10)" json:"method"`
+	Path       string    `gorm:"type:varchar(500)" json:"path"`
+	Status     int       `json:"status"`
+	IPAddress  string    `gorm:"type:varchar(45)" json:"ip_address,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+func (r *ImpersonationRequest) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == uuid.Nil {
+		r.ID = uuid.New()
+	}
+	return nil
+}
+
+func (ImpersonationRequest) TableName() string {
+	return "customer.impersonation_requests"
+}
+
+// StartImpersonationRequest is the body of an impersonation token request.
+// Write access must be asked for explicitly; tokens are read-only by default.
+type StartImpersonationRequest struct {
+	Reason          string `json:"reason" binding:"required,min=10,max=500"`
+	Write           bool   `json:"write"`
+	DurationMinutes int    `json:"duration_minutes" binding:"omitempty,min=1"`
+}
+
+// TTL returns the requested token lifetime, defaulted and capped
+func (r *StartImpersonationRequest) TTL() time.Duration {
+	if r.DurationMinutes <= 0 {
+		return DefaultImpersonationTTL
+	}
+	ttl := time.Duration(r.DurationMinutes) * time.Minute
+	if ttl > MaxImpersonationTTL {
+		return MaxImpersonationTTL
+	}
+	return ttl
+}
+
+// Scope returns the session scope the request asks for
+func (r *StartImpersonationRequest) Scope() string {
+	if r.Write {
+		return ImpersonationScopeWrite
+	}
+	return ImpersonationScopeRead
+}
@@ -0,0 +1,87 @@
+package domain
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Inventory webhook events. Only restocks are acted on; other events an
+// inventory source sends, such as low stock alerts, are acknowledged and ignored.
+const (
+	InventoryEventRestocked = "product.restocked"
+)
+
+// WebhookSourceInventory identifies inventory webhook deliveries
+const WebhookSourceInventory = "inventory"
+
+// WebhookSignaturePrefix prefixes the hex HMAC in the signature header
+const WebhookSignaturePrefix = "sha256="
+
+// InventoryWebhookPayload is the body of POST /api/v1/internal/webhooks/inventory
+type InventoryWebhookPayload struct {
+	EventID     string  `json:"event_id"`
+	Event       string  `json:"event"`
+	ProductID   string  `json:"product_id"`
+	VariantID   string  `json:"variant_id,omitempty"`
+	WarehouseID string  `json:"warehouse_id"`
+	Quantity    float64 `json:"quantity"`
+	ProductName string  `json:"product_name,omitempty"`
+	ProductSlug string  `json:"product_slug,omitempty"`
+}
+
+// Validate returns every problem with the payload; none means it is valid.
+// Product fields are only checked for restock events.
+func (p *InventoryWebhookPayload) Validate() []string {
+	var problems []string
+	if id := strings.TrimSpace(p.EventID); id == "" {
+		problems = append(problems, "event_id is required")
+	} else if len(id) > 100 {
+		problems = append(problems, "event_id is longer than 100 characters")
+	}
+	if strings.TrimSpace(p.Event) == "" {
+		problems = append(problems, "event is required")
+	}
+	if p.Event != InventoryEventRestocked {
+		return problems
+	}
+
+	if _, err := uuid.Parse(p.ProductID); err != nil {
+		problems = append(problems, "product_id must be a UUID")
+	}
+	if p.VariantID != "" {
+		if _, err := uuid.Parse(p.VariantID); err != nil {
+			problems = append(problems, "variant_id must be a UUID")
+		}
+	}
+	if p.Quantity <= 0 {
+		problems = append(problems, "quantity must be greater than 0")
+	}
+	return problems
+}
+
+// WebhookSignature returns the signature header value for a webhook body:
+// "sha256=" and the hex HMAC-SHA256 of "<timestamp>.<body>". Signing the
+// timestamp keeps an old delivery from being replayed with a fresh one.
+func WebhookSignature(secret []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return WebhookSignaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// WebhookDelivery records a received webhook event so a replay of it is ignored
+type WebhookDelivery struct {
+	Source     string    `gorm:"type:varchar(50);primaryKey" json:"source"`
+	EventID    string    `gorm:"type:varchar(100);primaryKey" json:"event_id"`
+	ReceivedAt time.Time `gorm:"not null;index" json:"received_at"`
+}
+
+func (WebhookDelivery) TableName() string {
+	return "customer.webhook_deliveries"
+}
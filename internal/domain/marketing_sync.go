@@ -0,0 +1,209 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Marketing sync states
+const (
+	MarketingSyncPending = "pending" // changed since the last push, or being retried
+	MarketingSyncSynced  = "synced"
+	MarketingSyncFailed  = "failed" // the retry policy gave up; see LastError
+)
+
+// MarketingConsentSourceCustomer marks consent given by the customer
+const MarketingConsentSourceCustomer = "customer"
+
+// MarketingConsent records whether a customer agreed to receive marketing.
+// Only customers with a consent record are synced to marketing platforms, so
+// customers who never opted in don't end up in the audience.
+type MarketingConsent struct {
+	CustomerID uuid.UUID `gorm:"type:uuid;primary_key" json:"customer_id"`
+	EmailOptIn bool      `gorm:"default:false" json:"email_opt_in"`
+	SMSOptIn   bool      `gorm:"default:false" json:"sms_opt_in"`
+	Source     string    `gorm:"type:varchar(20);not null;default:'customer'" json:"source"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// TableName specifies the table name for MarketingConsent
+func (MarketingConsent) TableName() string {
+	return "customer.marketing_consents"
+}
+
+// UpdateMarketingConsentRequest changes a customer's consent; omitted fields are kept
+type UpdateMarketingConsentRequest struct {
+	EmailOptIn *bool `json:"email_opt_in"`
+	SMSOptIn   *bool `json:"sms_opt_in"`
+}
+
+// MarketingSyncState tracks one customer's sync to one marketing platform.
+// Changes to the customer reset it to pending; the sync job claims pending
+// states in batches with ClaimID so replicas don't push the same customer.
+type MarketingSyncState struct {
+	Provider      string     `gorm:"type:varchar(20);primaryKey;index:idx_marketing_sync_states_due,priority:1" json:"provider"`
+	CustomerID    uuid.UUID  `gorm:"type:uuid;primaryKey" json:"customer_id"`
+	Status        string     `gorm:"type:varchar(20);not null;default:'pending';index:idx_marketing_sync_states_due,priority:2" json:"status"`
+	Attempts      int        `gorm:"default:0" json:"attempts"`
+	LastError     string     `gorm:"type:text" json:"last_error,omitempty"`
+	NextAttemptAt time.Time  `gorm:"index:idx_marketing_sync_states_due,priority:3" json:"next_attempt_at"`
+	ClaimID       *uuid.UUID `gorm:"type:uuid;index" json:"-"`
+	ChangedAt     time.Time  `json:"changed_at"`
+	SyncedAt      *time.Time `json:"synced_at,omitempty"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// TableName specifies the table name for MarketingSyncState
+func (MarketingSyncState) TableName() string {
+	return "customer.marketing_sync_states"
+}
+
+// MarketingSyncOutcome is the result of pushing one customer. Error is empty
+// on success; otherwise NextAttemptAt is the retry, or nil to give up.
+type MarketingSyncOutcome struct {
+	CustomerID    uuid.UUID
+	Error         string
+	NextAttemptAt *time.Time
+}
+
+// MarketingSyncStatus summarizes the sync to one marketing platform
+type MarketingSyncStatus struct {
+	Provider      string     `json:"provider"`
+	Pending       int64      `json:"pending"`
+	Synced        int64      `json:"synced"`
+	Failed        int64      `json:"failed"`
+	LastSyncedAt  *time.Time `json:"last_synced_at,omitempty"`
+	NextAttemptAt *time.Time `json:"next_attempt_at,omitempty"` // earliest pending push
+}
+
+// ResyncMarketingRequest queues customers to be pushed again. Without
+// customer IDs every failed customer is queued.
+type ResyncMarketingRequest struct {
+	CustomerIDs []uuid.UUID `json:"customer_ids" binding:"omitempty,max=1000"`
+}
+
+// Customer attributes that can be mapped to marketing platform fields
+const (
+	MarketingAttrFirstName     = "first_name"
+	MarketingAttrLastName      = "last_name"
+	MarketingAttrFullName      = "full_name"
+	MarketingAttrPhone         = "phone"
+	MarketingAttrStatus        = "status"
+	MarketingAttrTotalOrders   = "total_orders"
+	MarketingAttrTotalSpent    = "total_spent"
+	MarketingAttrLifetimeValue = "lifetime_value"
+	MarketingAttrRFMSegment    = "rfm_segment"
+	MarketingAttrChurnRisk     = "churn_risk"
+	MarketingAttrFirstOrderAt  = "first_order_at"
+	MarketingAttrLastOrderAt   = "last_order_at"
+	MarketingAttrSegments      = "segments"
+	MarketingAttrEmailOptIn    = "email_opt_in"
+	MarketingAttrSMSOptIn      = "sms_opt_in"
+	MarketingAttrCreatedAt     = "created_at"
+)
+
+// MarketingContact is a customer as pushed to a marketing platform.
+// Attributes holds every MarketingAttr value; a MarketingFieldMap picks the
+// ones a platform receives and names them.
+type MarketingContact struct {
+	CustomerID uuid.UUID
+	Email      string
+	FirstName  string
+	LastName   string
+	Phone      string
+	EmailOptIn bool
+	SMSOptIn   bool
+	Segments   []string // names of the customer's marketing segments
+	Attributes map[string]interface{}
+}
+
+// NewMarketingContact builds the contact for a customer
+func NewMarketingContact(customer *Customer, consent *MarketingConsent, segments []string) MarketingContact {
+	if segments == nil {
+		segments = []string{}
+	}
+	formatTime := func(t *time.Time) interface{} {
+		if t == nil {
+			return nil
+		}
+		return t.UTC().Format(time.RFC3339)
+	}
+
+	return MarketingContact{
+		CustomerID: customer.ID,
+		Email:      customer.Email,
+		FirstName:  customer.FirstName,
+		LastName:   customer.LastName,
+		Phone:      customer.Phone,
+		EmailOptIn: consent.EmailOptIn,
+		SMSOptIn:   consent.SMSOptIn,
+		Segments:   segments,
+		Attributes: map[string]interface{}{
+			MarketingAttrFirstName:     customer.FirstName,
+			MarketingAttrLastName:      customer.LastName,
+			MarketingAttrFullName:      strings.TrimSpace(customer.GetFullName()),
+			MarketingAttrPhone:         customer.Phone,
+			MarketingAttrStatus:        string(customer.Status),
+			MarketingAttrTotalOrders:   customer.TotalOrders,
+			MarketingAttrTotalSpent:    customer.TotalSpent,
+			MarketingAttrLifetimeValue: customer.LifetimeValue,
+			MarketingAttrRFMSegment:    customer.RFMSegment,
+			MarketingAttrChurnRisk:     customer.ChurnRisk,
+			MarketingAttrFirstOrderAt:  formatTime(customer.FirstOrderAt),
+			MarketingAttrLastOrderAt:   formatTime(customer.LastOrderAt),
+			MarketingAttrSegments:      segments,
+			MarketingAttrEmailOptIn:    consent.EmailOptIn,
+			MarketingAttrSMSOptIn:      consent.SMSOptIn,
+			MarketingAttrCreatedAt:     formatTime(&customer.CreatedAt),
+		},
+	}
+}
+
+// MarketingFieldMap maps marketing platform field names to the customer
+// attributes they are filled from
+type MarketingFieldMap map[string]string
+
+// ParseMarketingFieldMap parses a field map in the form
+// "FNAME=first_name,LNAME=last_name"
+func ParseMarketingFieldMap(value string) (MarketingFieldMap, error) {
+	fields := make(MarketingFieldMap)
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		field, attribute, found := strings.Cut(entry, "=")
+		field, attribute = strings.TrimSpace(field), strings.TrimSpace(attribute)
+		if !found || field == "" {
+			return nil, fmt.Errorf("malformed field mapping %q", entry)
+		}
+		if _, ok := NewMarketingContact(&Customer{}, &MarketingConsent{}, nil).Attributes[attribute]; !ok {
+			return nil, fmt.Errorf("unknown customer attribute %q in field mapping %q", attribute, entry)
+		}
+		fields[field] = attribute
+	}
+	return fields, nil
+}
+
+// Values returns the mapped fields of a contact. Empty values are left out
+// so they don't clear what the platform already has.
+func (m MarketingFieldMap) Values(contact MarketingContact) map[string]interface{} {
+	values := make(map[string]interface{}, len(m))
+	for field, attribute := range m {
+		switch v := contact.Attributes[attribute].(type) {
+		case nil:
+		case string:
+			if v != "" {
+				values[field] = v
+			}
+		default:
+			values[field] = v
+		}
+	}
+	return values
+}
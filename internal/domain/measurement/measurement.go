@@ -104,6 +104,9 @@ func (m *CustomerMeasurement) UpdatedAt() time.Time                { return m.up
 // Convenience getters
 func (m *CustomerMeasurement) Name() string          { return m.measurement.Name() }
 func (m *CustomerMeasurement) Gender() shared.Gender { return m.measurement.Gender() }
+func (m *CustomerMeasurement) StandardSize() shared.StandardSize {
+	return m.measurement.StandardSize()
+}
 
 // --- Behavior Methods ---
 
@@ -0,0 +1,118 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// MaxNoteAttachments caps the files attached to one note
+const MaxNoteAttachments = 10
+
+// DefaultNoteAttachmentTypes are the MIME types accepted when none are configured
+var DefaultNoteAttachmentTypes = []string{"image/png", "image/jpeg", "image/gif", "image/webp", "application/pdf"}
+
+// Note attachment errors
+var (
+	ErrNoteAttachmentTooLarge = errors.New("attachment is too large")
+	ErrNoteAttachmentType     = errors.New("attachment type is not allowed")
+	ErrNoteAttachmentLimit    = fmt.Errorf("a note can have at most %d attachments", MaxNoteAttachments)
+)
+
+// NoteAttachment is a file, such as a screenshot or invoice, attached to a
+// customer note. The file itself lives in the file store under StorageKey.
+type NoteAttachment struct {
+	ID          uuid.UUID  `gorm:"type:uuid;primary_key" json:"id"`
+	NoteID      uuid.UUID  `gorm:"type:uuid;not null;index" json:"note_id"`
+	CustomerID  uuid.UUID  `gorm:"type:uuid;not null;index" json:"customer_id"`
+	FileName    string     `gorm:"type:varchar(255);not null" json:"file_name"`
+	ContentType string     `gorm:"type:varchar(100);not null" json:"content_type"`
+	Size        int64      `gorm:"not null" json:"size"`
+	StorageKey  string     `gorm:"type:varchar(500);not null" json:"-"`
+	UploadedBy  *uuid.UUID `gorm:"type:uuid" json:"uploaded_by,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+
+	// Time-limited link to the file, set when attachments are listed
+	DownloadURL string `gorm:"-" json:"download_url,omitempty"`
+}
+
+func (NoteAttachment) TableName() string {
+	return "customer.note_attachments"
+}
+
+func (a *NoteAttachment) BeforeCreate(tx *gorm.DB) error {
+	if a.ID == uuid.Nil {
+		a.ID = uuid.New()
+	}
+	return nil
+}
+
+// attachmentExtPattern matches file extensions safe to keep in storage keys
+var attachmentExtPattern = regexp.MustCompile(`^\.[a-z0-9]{1,10}$`)
+
+// NoteAttachmentKey returns the file store key of an attachment. It keeps the
+// file name's extension so downloads get a sensible name and type.
+func NoteAttachmentKey(a *NoteAttachment) string {
+	ext := strings.ToLower(path.Ext(a.FileName))
+	if !attachmentExtPattern.MatchString(ext) {
+		ext = ""
+	}
+	return fmt.Sprintf("notes/%s/%s/%s%s", a.CustomerID, a.NoteID, a.ID, ext)
+}
+
+// CleanAttachmentName strips directories and control characters from an
+// uploaded file name and bounds its length
+func CleanAttachmentName(name string) string {
+	name = path.Base(strings.ReplaceAll(name, `\`, "/"))
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) || r == '"' {
+			return -1
+		}
+		return r
+	}, name)
+	name = strings.TrimSpace(name)
+	if name == "" || name == "." || name == "/" {
+		return "attachment"
+	}
+	if runes := []rune(name); len(runes) > 255 {
+		name = string(runes[:255])
+	}
+	return name
+}
+
+// NoteAttachmentPolicy limits the size and type of uploaded attachments
+type NoteAttachmentPolicy struct {
+	MaxSize      int64
+	AllowedTypes []string // MIME types; empty means DefaultNoteAttachmentTypes
+	URLTTL       time.Duration
+}
+
+// Check returns ErrNoteAttachmentTooLarge or ErrNoteAttachmentType if a file
+// of the given size and content type may not be attached
+func (p NoteAttachmentPolicy) Check(size int64, contentType string) error {
+	if size <= 0 || (p.MaxSize > 0 && size > p.MaxSize) {
+		return ErrNoteAttachmentTooLarge
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return ErrNoteAttachmentType
+	}
+	allowed := p.AllowedTypes
+	if len(allowed) == 0 {
+		allowed = DefaultNoteAttachmentTypes
+	}
+	for _, t := range allowed {
+		if strings.EqualFold(t, mediaType) {
+			return nil
+		}
+	}
+	return ErrNoteAttachmentType
+}
@@ -0,0 +1,252 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Notification types customers can schedule or opt out of
+const (
+	NotificationTypeBackInStock    = "back_in_stock"
+	NotificationTypePriceDrop      = "price_drop"
+	NotificationTypeSavedSearch    = "saved_search"
+	NotificationTypeReviewReminder = "review_reminder"
+)
+
+// NotificationTypes lists every notification type
+var NotificationTypes = []string{
+	NotificationTypeBackInStock,
+	NotificationTypePriceDrop,
+	NotificationTypeSavedSearch,
+	NotificationTypeReviewReminder,
+}
+
+// Notification channels
+const (
+	NotificationChannelEmail = "email"
+	NotificationChannelSMS   = "sms"
+	NotificationChannelPush  = "push"
+)
+
+// NotificationChannels lists every notification channel
+var NotificationChannels = []string{NotificationChannelEmail, NotificationChannelSMS, NotificationChannelPush}
+
+// Reasons a notification is held back
+const (
+	NotifyReasonOptedOut    = "opted_out"
+	NotifyReasonQuietHours  = "quiet_hours"
+	NotifyReasonWeeklyLimit = "weekly_limit"
+)
+
+// DefaultNotificationTimezone is used for quiet hours until a customer sets
+// their own timezone
+const DefaultNotificationTimezone = "Asia/Kuala_Lumpur"
+
+// MaxNotificationsPerWeek is the highest weekly cap a customer can set
+const MaxNotificationsPerWeek = 100
+
+// NotificationWeek is the rolling window the weekly cap is counted over
+const NotificationWeek = 7 * 24 * time.Hour
+
+// ErrInvalidNotificationType is returned for a type not in NotificationTypes
+var ErrInvalidNotificationType = errors.New("unknown notification type")
+
+// NotificationPreferences is a customer's notification schedule in the
+// preference center: the channel they prefer, hours not to be disturbed, a
+// weekly cap and the notification types they opted out of. Customers without
+// a record get DefaultNotificationPreferences.
+type NotificationPreferences struct {
+	CustomerID      uuid.UUID `gorm:"type:uuid;primary_key" json:"customer_id"`
+	Channel         string    `gorm:"type:varchar(10);not null;default:'email'" json:"channel"`
+	QuietHoursStart string    `gorm:"type:varchar(5)" json:"quiet_hours_start,omitempty"` // HH:MM
+	QuietHoursEnd   string    `gorm:"type:varchar(5)" json:"quiet_hours_end,omitempty"`
+	Timezone        string    `gorm:"type:varchar(50);not null;default:'Asia/Kuala_Lumpur'" json:"timezone"`
+	MaxPerWeek      int       `gorm:"default:0" json:"max_per_week"` // 0 means no cap
+	OptedOut        []string  `gorm:"type:jsonb;serializer:json" json:"opted_out"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// TableName specifies the table name for NotificationPreferences
+func (NotificationPreferences) TableName() string {
+	return "customer.notification_preferences"
+}
+
+// DefaultNotificationPreferences sends every notification by email, at any
+// hour and without a cap
+func DefaultNotificationPreferences(customerID uuid.UUID) *NotificationPreferences {
+	return &NotificationPreferences{
+		CustomerID: customerID,
+		Channel:    NotificationChannelEmail,
+		Timezone:   DefaultNotificationTimezone,
+		OptedOut:   []string{},
+	}
+}
+
+// UpdateNotificationPreferencesRequest changes a customer's notification
+// schedule; omitted fields are kept. Empty quiet hours turn them off and an
+// empty opted_out list opts back in to everything.
+type UpdateNotificationPreferencesRequest struct {
+	Channel         *string  `json:"channel"`
+	QuietHoursStart *string  `json:"quiet_hours_start"`
+	QuietHoursEnd   *string  `json:"quiet_hours_end"`
+	Timezone        *string  `json:"timezone"`
+	MaxPerWeek      *int     `json:"max_per_week" binding:"omitempty,min=0"`
+	OptedOut        []string `json:"opted_out"`
+}
+
+// Apply validates the request and copies it onto the preferences
+func (r *UpdateNotificationPreferencesRequest) Apply(p *NotificationPreferences) error {
+	next := *p
+	if r.Channel != nil {
+		channel := strings.ToLower(strings.TrimSpace(*r.Channel))
+		if !slices.Contains(NotificationChannels, channel) {
+			return fmt.Errorf("channel must be one of %s", strings.Join(NotificationChannels, ", "))
+		}
+		next.Channel = channel
+	}
+	if r.QuietHoursStart != nil {
+		next.QuietHoursStart = strings.TrimSpace(*r.QuietHoursStart)
+	}
+	if r.QuietHoursEnd != nil {
+		next.QuietHoursEnd = strings.TrimSpace(*r.QuietHoursEnd)
+	}
+	if (next.QuietHoursStart == "") != (next.QuietHoursEnd == "") {
+		return errors.New("quiet_hours_start and quiet_hours_end must be set together")
+	}
+	for _, t := range []struct{ field, value string }{{"quiet_hours_start", next.QuietHoursStart}, {"quiet_hours_end", next.QuietHoursEnd}} {
+		if t.value == "" {
+			continue
+		}
+		if _, err := time.Parse("15:04", t.value); err != nil {
+			return fmt.Errorf("%s must be an HH:MM time", t.field)
+		}
+	}
+	if r.Timezone != nil {
+		timezone := strings.TrimSpace(*r.Timezone)
+		if _, err := time.LoadLocation(timezone); err != nil || timezone == "" {
+			return fmt.Errorf("unknown timezone %q", timezone)
+		}
+		next.Timezone = timezone
+	}
+	if r.MaxPerWeek != nil {
+		if *r.MaxPerWeek > MaxNotificationsPerWeek {
+			return fmt.Errorf("max_per_week can be at most %d", MaxNotificationsPerWeek)
+		}
+		next.MaxPerWeek = *r.MaxPerWeek
+	}
+	if r.OptedOut != nil {
+		optedOut := []string{}
+		for _, t := range r.OptedOut {
+			t = strings.ToLower(strings.TrimSpace(t))
+			if !slices.Contains(NotificationTypes, t) {
+				return fmt.Errorf("%w %q", ErrInvalidNotificationType, t)
+			}
+			if !slices.Contains(optedOut, t) {
+				optedOut = append(optedOut, t)
+			}
+		}
+		next.OptedOut = optedOut
+	}
+
+	*p = next
+	return nil
+}
+
+// NotificationDecision is the outcome of checking a notification against the
+// customer's preferences. A notification that is not allowed should stay
+// pending rather than be dropped, except when the customer opted out.
+type NotificationDecision struct {
+	Allowed bool       `json:"allowed"`
+	Channel string     `json:"channel"`
+	Reason  string     `json:"reason,omitempty"`
+	RetryAt *time.Time `json:"retry_at,omitempty"` // end of the quiet hours
+}
+
+// Decide checks a notification of the given type against the preferences,
+// given how many notifications the customer got in the last week
+func (p *NotificationPreferences) Decide(notificationType string, now time.Time, sentThisWeek int64) NotificationDecision {
+	decision := NotificationDecision{Channel: p.Channel}
+	if decision.Channel == "" {
+		decision.Channel = NotificationChannelEmail
+	}
+
+	switch {
+	case slices.Contains(p.OptedOut, notificationType):
+		decision.Reason = NotifyReasonOptedOut
+	case p.MaxPerWeek > 0 && sentThisWeek >= int64(p.MaxPerWeek):
+		decision.Reason = NotifyReasonWeeklyLimit
+	default:
+		if until, quiet := p.QuietUntil(now); quiet {
+			decision.Reason = NotifyReasonQuietHours
+			decision.RetryAt = &until
+		} else {
+			decision.Allowed = true
+		}
+	}
+	return decision
+}
+
+// QuietUntil reports whether now falls in the customer's quiet hours and, if
+// so, when they end. Quiet hours may span midnight, e.g. 22:00 to 08:00.
+func (p *NotificationPreferences) QuietUntil(now time.Time) (time.Time, bool) {
+	start, errStart := time.Parse("15:04", p.QuietHoursStart)
+	end, errEnd := time.Parse("15:04", p.QuietHoursEnd)
+	if errStart != nil || errEnd != nil || start.Equal(end) {
+		return time.Time{}, false
+	}
+	loc, err := time.LoadLocation(p.Timezone)
+	if err != nil {
+		loc, _ = time.LoadLocation(DefaultNotificationTimezone)
+	}
+
+	local := now.In(loc)
+	minute := local.Hour()*60 + local.Minute()
+	startMinute := start.Hour()*60 + start.Minute()
+	endMinute := end.Hour()*60 + end.Minute()
+
+	var quiet bool
+	if startMinute < endMinute {
+		quiet = minute >= startMinute && minute < endMinute
+	} else {
+		quiet = minute >= startMinute || minute < endMinute
+	}
+	if !quiet {
+		return time.Time{}, false
+	}
+
+	until := time.Date(local.Year(), local.Month(), local.Day(), end.Hour(), end.Minute(), 0, 0, loc)
+	if !until.After(local) {
+		until = until.AddDate(0, 0, 1)
+	}
+	return until, true
+}
+
+// NotificationDelivery records a notification sent to a customer, for the
+// weekly cap. Records older than NotificationWeek are pruned as new ones are
+// written.
+type NotificationDelivery struct {
+	ID         uuid.UUID `gorm:"type:uuid;primary_key" json:"id"`
+	CustomerID uuid.UUID `gorm:"type:uuid;not null;index:idx_notification_deliveries_customer_sent,priority:1" json:"customer_id"`
+	Type       string    `gorm:"type:varchar(30);not null" json:"type"`
+	Channel    string    `gorm:"type:varchar(10);not null" json:"channel"`
+	SentAt     time.Time `gorm:"not null;index:idx_notification_deliveries_customer_sent,priority:2" json:"sent_at"`
+}
+
+func (d *NotificationDelivery) BeforeCreate(tx *gorm.DB) error {
+	if d.ID == uuid.Nil {
+		d.ID = uuid.New()
+	}
+	return nil
+}
+
+// TableName specifies the table name for NotificationDelivery
+func (NotificationDelivery) TableName() string {
+	return "customer.notification_deliveries"
+}
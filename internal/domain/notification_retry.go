@@ -0,0 +1,74 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Notification retry statuses
+const (
+	NotificationRetryPending = "pending" // waiting for the next attempt
+	NotificationRetryFailed  = "failed"  // out of attempts; left for admins to inspect
+)
+
+// NotificationRetry is a back-in-stock notification that failed to send and
+// is retried by the retry job with exponential backoff
+type NotificationRetry struct {
+	ID             uuid.UUID `gorm:"type:uuid;primaryKey" json:"id"`
+	SubscriptionID uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_notification_retry_subscription" json:"subscriptionId"`
+	IsGuest        bool      `gorm:"default:false" json:"isGuest"`
+	StockQuantity  int       `json:"stockQuantity"`
+
+	Status        string    `gorm:"size:20;not null;default:'pending';index:idx_notification_retry_due,priority:1" json:"status"`
+	Attempts      int       `gorm:"default:0" json:"attempts"`
+	LastError     string    `gorm:"type:text" json:"lastError,omitempty"`
+	NextAttemptAt time.Time `gorm:"index:idx_notification_retry_due,priority:2" json:"nextAttemptAt"`
+
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+func (NotificationRetry) TableName() string {
+	return "customer.back_in_stock_notification_retries"
+}
+
+func (r *NotificationRetry) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == uuid.Nil {
+		r.ID = uuid.New()
+	}
+	return nil
+}
+
+// NotificationRetryPolicy bounds how often and how long a failed notification
+// is retried. The delay doubles after every failed attempt up to MaxBackoff.
+type NotificationRetryPolicy struct {
+	MaxAttempts int // including the original send
+	Backoff     time.Duration
+	MaxBackoff  time.Duration
+}
+
+// DefaultNotificationRetryPolicy retries for roughly a day before giving up
+var DefaultNotificationRetryPolicy = NotificationRetryPolicy{
+	MaxAttempts: 8,
+	Backoff:     time.Minute,
+	MaxBackoff:  6 * time.Hour,
+}
+
+// NextAttempt returns when to retry after the given number of failed
+// attempts, or false once the attempts are used up
+func (p NotificationRetryPolicy) NextAttempt(attempts int, now time.Time) (time.Time, bool) {
+	if attempts >= p.MaxAttempts {
+		return time.Time{}, false
+	}
+
+	delay := p.Backoff
+	for i := 1; i < attempts && delay < p.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > p.MaxBackoff {
+		delay = p.MaxBackoff
+	}
+	return now.Add(delay), true
+}
@@ -0,0 +1,110 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Order stats entry kinds
+const (
+	OrderStatsCompleted = "completed"
+	OrderStatsRefunded  = "refunded"
+)
+
+// OrderPaymentStatusPaid is the service-order payment status of orders
+// counted in a customer's totals when they are backfilled
+const OrderPaymentStatusPaid = "paid"
+
+// OrderStatsEntry records one order event applied to a customer's
+// TotalOrders and TotalSpent. EventKey is unique, so an event delivered
+// twice is only counted once.
+type OrderStatsEntry struct {
+	ID          uuid.UUID `gorm:"type:uuid;primaryKey" json:"id"`
+	EventKey    string    `gorm:"type:varchar(150);not null;uniqueIndex" json:"event_key"` // kind:order or kind:refund ID
+	CustomerID  uuid.UUID `gorm:"type:uuid;not null;index" json:"customer_id"`
+	OrderID     string    `gorm:"type:varchar(100);not null" json:"order_id"`
+	OrderNumber string    `gorm:"type:varchar(100)" json:"order_number,omitempty"`
+	Kind        string    `gorm:"type:varchar(20);not null" json:"kind"`
+	Orders      int       `gorm:"not null" json:"orders"`                    // change to TotalOrders
+	Amount      float64   `gorm:"type:decimal(12,2);not null" json:"amount"` // change to TotalSpent
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// TableName specifies the table name for OrderStatsEntry
+func (OrderStatsEntry) TableName() string {
+	return "customer.order_stats_entries"
+}
+
+// BeforeCreate hook to ensure UUID is set
+func (e *OrderStatsEntry) BeforeCreate(tx *gorm.DB) error {
+	if e.ID == uuid.Nil {
+		e.ID = uuid.New()
+	}
+	return nil
+}
+
+// OrderCompleted returns the entry adding a paid order to a customer's totals
+func OrderCompleted(customerID uuid.UUID, orderID, orderNumber string, total float64) *OrderStatsEntry {
+	return &OrderStatsEntry{
+		EventKey:    OrderStatsCompleted + ":" + orderID,
+		CustomerID:  customerID,
+		OrderID:     orderID,
+		OrderNumber: orderNumber,
+		Kind:        OrderStatsCompleted,
+		Orders:      1,
+		Amount:      total,
+	}
+}
+
+// OrderRefunded returns the entry taking a refund off a customer's totals.
+// A full refund also stops the order counting towards TotalOrders. refundID
+// distinguishes partial refunds of the same order; without it only one
+// refund per order is applied.
+func OrderRefunded(customerID uuid.UUID, orderID, orderNumber, refundID string, amount float64, full bool) *OrderStatsEntry {
+	key := orderID
+	if refundID != "" {
+		key = refundID
+	}
+	entry := &OrderStatsEntry{
+		EventKey:    OrderStatsRefunded + ":" + key,
+		CustomerID:  customerID,
+		OrderID:     orderID,
+		OrderNumber: orderNumber,
+		Kind:        OrderStatsRefunded,
+		Amount:      -amount,
+	}
+	if full {
+		entry.Orders = -1
+	}
+	return entry
+}
+
+// ActivityTitle is the title of the activity recorded with the entry
+func (e *OrderStatsEntry) ActivityTitle() string {
+	reference := e.OrderNumber
+	if reference == "" {
+		reference = e.OrderID
+	}
+	if e.Kind == OrderStatsRefunded {
+		return "Order " + reference + " refunded"
+	}
+	return "Order " + reference + " completed"
+}
+
+// OrderTotals are a customer's paid orders as recorded by service-order: how
+// many, their total and when the first and last were placed
+type OrderTotals struct {
+	Orders       int
+	Spent        float64
+	FirstOrderAt *time.Time
+	LastOrderAt  *time.Time
+}
+
+// OrderStatsBackfillResult counts the customers a backfill went through and
+// those whose totals were wrong
+type OrderStatsBackfillResult struct {
+	Customers int
+	Corrected int
+}
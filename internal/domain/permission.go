@@ -0,0 +1,100 @@
+package domain
+
+import (
+	"fmt"
+	"slices"
+)
+
+// Admin permissions, each required by one or more admin routes
+const (
+	PermissionCustomersRead        = "customers:read"
+	PermissionCustomersWrite       = "customers:write"
+	PermissionCustomersDelete      = "customers:delete" // delete and merge
+	PermissionCustomersExport      = "customers:export"
+	PermissionCustomersImpersonate = "customers:impersonate"
+	PermissionCustomersPII         = "customers:pii"        // see unmasked email and phone
+	PermissionCustomersRevealPII   = "customers:reveal_pii" // unmask one customer at a time, audited
+	PermissionDuplicatesReview     = "duplicates:review"
+	PermissionNotesModerate        = "notes:moderate" // edit and delete other staff's notes and views
+	PermissionWalletManage         = "wallet:manage"
+	PermissionSegmentsManage       = "segments:manage"
+	PermissionActivityRead         = "activity:read"
+	PermissionAuditRead            = "audit:read"
+	PermissionRegionsManage        = "regions:manage"
+	PermissionBlocklistManage      = "blocklist:manage"
+	PermissionWebhooksManage       = "webhooks:manage"
+	PermissionMarketingManage      = "marketing:manage"
+	PermissionSystemRead           = "system:read"
+	PermissionBackInStockManage    = "back_in_stock:manage"
+)
+
+// PermissionAll granted to a role grants every permission
+const PermissionAll = "*"
+
+// Permissions lists every admin permission
+var Permissions = []string{
+	PermissionCustomersRead, PermissionCustomersWrite, PermissionCustomersDelete, PermissionCustomersExport,
+	PermissionCustomersImpersonate, PermissionCustomersPII, PermissionCustomersRevealPII, PermissionDuplicatesReview,
+	PermissionNotesModerate, PermissionWalletManage, PermissionSegmentsManage, PermissionActivityRead,
+	PermissionAuditRead, PermissionRegionsManage, PermissionBlocklistManage, PermissionWebhooksManage,
+	PermissionMarketingManage, PermissionSystemRead, PermissionBackInStockManage,
+}
+
+// staffPermissions are granted to every staff role
+var staffPermissions = []string{
+	PermissionCustomersRead, PermissionCustomersWrite, PermissionCustomersDelete, PermissionCustomersExport,
+	PermissionCustomersRevealPII, PermissionWalletManage, PermissionSegmentsManage, PermissionActivityRead,
+	PermissionAuditRead, PermissionSystemRead, PermissionBackInStockManage,
+}
+
+// DefaultRolePermissions are the permissions of each admin role unless
+// replaced by ADMIN_ROLE_PERMISSIONS_FILE. Roles match case-insensitively.
+var DefaultRolePermissions = map[string][]string{
+	"ADMIN":       {PermissionAll},
+	"SUPERADMIN":  {PermissionAll},
+	"SUPER_ADMIN": {PermissionAll},
+	"MANAGER": slices.Concat(staffPermissions, []string{
+		PermissionCustomersImpersonate, PermissionCustomersPII, PermissionDuplicatesReview,
+		PermissionRegionsManage, PermissionBlocklistManage, PermissionWebhooksManage, PermissionMarketingManage,
+	}),
+	"SUPPORT": slices.Concat(staffPermissions, []string{
+		PermissionCustomersImpersonate, PermissionDuplicatesReview, PermissionBlocklistManage,
+	}),
+	"STAFF_ORDERS": staffPermissions,
+	"SALES_AGENT":  staffPermissions,
+}
+
+// RoutePermission is the permission an admin route requires; an empty
+// permission lets every admin in
+type RoutePermission struct {
+	Method     string `json:"method"`
+	Route      string `json:"route"`
+	Permission string `json:"permission,omitempty"`
+}
+
+// PermissionPolicyView is the admin's own permissions and the policy, for
+// the admin UI to hide what the admin can't do
+type PermissionPolicyView struct {
+	Role        string              `json:"role"`
+	Permissions []string            `json:"permissions"`
+	Roles       map[string][]string `json:"roles"`
+	Routes      []RoutePermission   `json:"routes"`
+}
+
+// ValidateRolePermissions rejects unknown permissions, so a typo in the
+// roles file fails at startup instead of silently denying access
+func ValidateRolePermissions(roles map[string][]string) error {
+	known := make(map[string]bool, len(Permissions)+1)
+	known[PermissionAll] = true
+	for _, permission := range Permissions {
+		known[permission] = true
+	}
+	for role, permissions := range roles {
+		for _, permission := range permissions {
+			if !known[permission] {
+				return fmt.Errorf("role %s: unknown permission %q", role, permission)
+			}
+		}
+	}
+	return nil
+}
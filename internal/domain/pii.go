@@ -0,0 +1,67 @@
+package domain
+
+import (
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/domain/shared"
+)
+
+// MaskEmail masks an email for admins without PermissionCustomersPII, e.g.
+// "john.doe@example.com" -> "j***e@example.com". Values that aren't valid
+// emails are masked entirely.
+//
+// MaskedEmail leaves local parts of up to 2 characters as they are, so
+// those keep only their first character.
+func MaskEmail(email string) string {
+	if email == "" {
+		return ""
+	}
+	e, err := shared.NewEmail(email)
+	if err != nil {
+		return "***"
+	}
+	if len(e.LocalPart()) <= 2 {
+		return e.LocalPart()[:1] + "***@" + e.Domain()
+	}
+	return e.MaskedEmail()
+}
+
+// MaskPhone masks a phone number, keeping the last 4 digits, e.g.
+// "+60123456789" -> "+60****6789"
+func MaskPhone(phone string) string {
+	if phone == "" {
+		return ""
+	}
+	p, err := shared.NewPhone(phone)
+	if err != nil || len(p.Normalized()) <= 6 {
+		return "****"
+	}
+	return p.MaskedPhone()
+}
+
+// IsMaskedPII reports whether a value was masked by MaskEmail or MaskPhone,
+// so a masked value sent back unchanged isn't saved over the real one
+func IsMaskedPII(value string) bool {
+	return strings.Contains(value, "***")
+}
+
+// MaskPII masks the customer's email and phone
+func (c *Customer) MaskPII() {
+	c.Email = MaskEmail(c.Email)
+	c.Phone = MaskPhone(c.Phone)
+	c.PIIMasked = true
+}
+
+// RevealPIIRequest is the request to see a customer's unmasked email and
+// phone. The reason is kept in the audit log.
+type RevealPIIRequest struct {
+	Reason string `json:"reason" binding:"required,max=500"`
+}
+
+// CustomerPII is a customer's unmasked email and phone
+type CustomerPII struct {
+	CustomerID uuid.UUID `json:"customer_id"`
+	Email      string    `json:"email"`
+	Phone      string    `json:"phone,omitempty"`
+}
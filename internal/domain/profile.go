@@ -11,21 +11,26 @@ package domain
 import (
 	"time"
 
+	"github.com/Ecom-micro-template/service-customer/internal/domain/shared"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
 // Profile represents a customer profile
 type Profile struct {
-	ID             uuid.UUID  `gorm:"type:uuid;primary_key" json:"id"`
-	FullName       string     `gorm:"type:varchar(200)" json:"full_name"`
-	Email          string     `gorm:"type:varchar(200);uniqueIndex" json:"email"`
-	Phone          string     `gorm:"type:varchar(50)" json:"phone"`
-	DateOfBirth    *time.Time `json:"date_of_birth,omitempty"`
-	Gender         string     `gorm:"type:varchar(20)" json:"gender,omitempty"` // male, female, other
-	ProfilePicture string     `gorm:"type:varchar(500)" json:"profile_picture,omitempty"`
-	CreatedAt      time.Time  `json:"created_at"`
-	UpdatedAt      time.Time  `json:"updated_at"`
+	ID             uuid.UUID            `gorm:"type:uuid;primary_key" json:"id"`
+	FullName       string               `gorm:"type:varchar(200)" json:"full_name"`
+	Email          string               `gorm:"type:varchar(200);uniqueIndex" json:"email"`
+	Phone          string               `gorm:"type:text;serializer:encrypted" json:"phone"`
+	DateOfBirth    *time.Time           `gorm:"type:text;serializer:encrypted" json:"date_of_birth,omitempty"`
+	Birthday       string               `gorm:"type:char(5);index" json:"-"` // MM-DD of DateOfBirth for the birthday feed, which can't read the encrypted date
+	Gender         shared.ProfileGender `gorm:"type:varchar(20)" json:"gender,omitempty"`
+	ProfilePicture string               `gorm:"type:varchar(500)" json:"profile_picture,omitempty"`
+	AvatarID       *uuid.UUID           `gorm:"type:uuid" json:"-"`                                     // upload the avatar files belong to, see AvatarKey
+	AvatarURLs     map[string]string    `gorm:"serializer:json;type:text" json:"avatar_urls,omitempty"` // by AvatarSize name
+	Version        int64                `gorm:"not null;default:1" json:"version"`                      // optimistic locking, see ErrVersionConflict
+	CreatedAt      time.Time            `json:"created_at"`
+	UpdatedAt      time.Time            `json:"updated_at"`
 }
 
 // TableName specifies the table name for Profile
@@ -38,5 +43,17 @@ func (p *Profile) BeforeCreate(tx *gorm.DB) error {
 	if p.ID == uuid.Nil {
 		p.ID = uuid.New()
 	}
+	if p.Version == 0 {
+		p.Version = 1
+	}
+	return nil
+}
+
+// BeforeSave keeps Birthday in step with DateOfBirth
+func (p *Profile) BeforeSave(tx *gorm.DB) error {
+	p.Birthday = ""
+	if p.DateOfBirth != nil {
+		p.Birthday = p.DateOfBirth.Format(BirthdayFormat)
+	}
 	return nil
 }
@@ -0,0 +1,41 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// DefaultRecentlyViewedLimit is how many products are kept per customer
+const DefaultRecentlyViewedLimit = 50
+
+// RecentlyViewedProduct is a product a customer viewed on the storefront.
+// Each product is kept once, at its latest view, and only the customer's
+// most recent views are kept.
+type RecentlyViewedProduct struct {
+	ID        uuid.UUID  `gorm:"type:uuid;primary_key" json:"id"`
+	UserID    uuid.UUID  `gorm:"type:uuid;not null;uniqueIndex:idx_recently_viewed_user_product" json:"user_id"`
+	ProductID uuid.UUID  `gorm:"type:uuid;not null;uniqueIndex:idx_recently_viewed_user_product" json:"product_id"`
+	VariantID *uuid.UUID `gorm:"type:uuid" json:"variant_id,omitempty"` // the variant last viewed, if any
+	ViewedAt  time.Time  `gorm:"not null;index" json:"viewed_at"`
+}
+
+// TableName specifies the table name for RecentlyViewedProduct
+func (RecentlyViewedProduct) TableName() string {
+	return "customer.recently_viewed_products"
+}
+
+// BeforeCreate hook to ensure UUID is set
+func (v *RecentlyViewedProduct) BeforeCreate(tx *gorm.DB) error {
+	if v.ID == uuid.Nil {
+		v.ID = uuid.New()
+	}
+	return nil
+}
+
+// RecordViewInput is a product view reported by the storefront
+type RecordViewInput struct {
+	ProductID uuid.UUID  `json:"product_id" binding:"required"`
+	VariantID *uuid.UUID `json:"variant_id"`
+}
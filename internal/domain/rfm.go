@@ -0,0 +1,168 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"time"
+)
+
+// RFM segments, from best to worst. Customers without orders have none.
+const (
+	RFMSegmentChampions   = "champions"   // bought recently and often
+	RFMSegmentLoyal       = "loyal"       // buy regularly
+	RFMSegmentNew         = "new"         // first orders were recent
+	RFMSegmentPotential   = "potential"   // recent, but not yet regular
+	RFMSegmentAtRisk      = "at_risk"     // used to buy often, not lately
+	RFMSegmentHibernating = "hibernating" // haven't bought in a while
+	RFMSegmentLost        = "lost"        // haven't bought in over a year
+)
+
+// RFMSegments lists the RFM segments, from best to worst
+var RFMSegments = []string{
+	RFMSegmentChampions, RFMSegmentLoyal, RFMSegmentNew, RFMSegmentPotential,
+	RFMSegmentAtRisk, RFMSegmentHibernating, RFMSegmentLost,
+}
+
+// ErrInvalidRFMSegment is returned for an RFM segment filter naming an unknown
+// segment
+var ErrInvalidRFMSegment = errors.New("unknown RFM segment")
+
+// ParseRFMSegments parses a comma separated list of RFM segments, dropping
+// blanks and duplicates
+func ParseRFMSegments(raw string) ([]string, error) {
+	var segments []string
+	for _, segment := range strings.Split(raw, ",") {
+		segment = strings.ToLower(strings.TrimSpace(segment))
+		if segment == "" || slices.Contains(segments, segment) {
+			continue
+		}
+		if !slices.Contains(RFMSegments, segment) {
+			return nil, fmt.Errorf("%w %q", ErrInvalidRFMSegment, segment)
+		}
+		segments = append(segments, segment)
+	}
+	return segments, nil
+}
+
+// CLVHorizonYears is how many years ahead the lifetime value projects a
+// customer's yearly spend
+const CLVHorizonYears = 3
+
+// RFMThresholds are the lower bounds of scores 5, 4, 3 and 2 of each
+// dimension; anything below scores 1. Recency is in days since the last
+// order and scores higher the fewer days it is.
+type RFMThresholds struct {
+	RecencyDays [4]int
+	Frequency   [4]int
+	Monetary    [4]float64
+}
+
+// DefaultRFMThresholds suit a fashion store selling mostly in MYR
+var DefaultRFMThresholds = RFMThresholds{
+	RecencyDays: [4]int{30, 90, 180, 365},
+	Frequency:   [4]int{10, 5, 3, 2},
+	Monetary:    [4]float64{5000, 2000, 1000, 300},
+}
+
+// RFMScores are a customer's recency, frequency and monetary scores from 1 to
+// 5, their RFM segment and predicted lifetime value. Customers without orders
+// score 0 and have no segment.
+type RFMScores struct {
+	Recency       int     `json:"recency"`
+	Frequency     int     `json:"frequency"`
+	Monetary      int     `json:"monetary"`
+	Segment       string  `json:"segment,omitempty"`
+	LifetimeValue float64 `json:"lifetime_value"`
+}
+
+// String returns the scores in the usual "RFM" notation, e.g. "545"
+func (s RFMScores) String() string {
+	return fmt.Sprintf("%d%d%d", s.Recency, s.Frequency, s.Monetary)
+}
+
+// RFMScores returns the scores stored on the customer
+func (c *Customer) RFMScores() RFMScores {
+	return RFMScores{
+		Recency:       c.RecencyScore,
+		Frequency:     c.FrequencyScore,
+		Monetary:      c.MonetaryScore,
+		Segment:       c.RFMSegment,
+		LifetimeValue: c.LifetimeValue,
+	}
+}
+
+// Score computes the customer's RFM scores at now from their order totals
+func (t RFMThresholds) Score(customer *Customer, now time.Time) RFMScores {
+	if customer.TotalOrders <= 0 || customer.LastOrderAt == nil {
+		return RFMScores{}
+	}
+
+	days := int(now.Sub(*customer.LastOrderAt).Hours() / 24)
+	scores := RFMScores{Recency: 1, Frequency: 1, Monetary: 1}
+	for i, bound := range t.RecencyDays {
+		if days <= bound {
+			scores.Recency = 5 - i
+			break
+		}
+	}
+	for i, bound := range t.Frequency {
+		if customer.TotalOrders >= bound {
+			scores.Frequency = 5 - i
+			break
+		}
+	}
+	for i, bound := range t.Monetary {
+		if customer.TotalSpent >= bound {
+			scores.Monetary = 5 - i
+			break
+		}
+	}
+	scores.Segment = rfmSegment(scores.Recency, scores.Frequency)
+	scores.LifetimeValue = lifetimeValue(customer, now, scores.Recency)
+	return scores
+}
+
+func rfmSegment(recency, frequency int) string {
+	switch {
+	case recency >= 4 && frequency >= 4:
+		return RFMSegmentChampions
+	case recency >= 3 && frequency >= 3:
+		return RFMSegmentLoyal
+	case recency >= 4 && frequency == 1:
+		return RFMSegmentNew
+	case recency >= 3:
+		return RFMSegmentPotential
+	case frequency >= 3:
+		return RFMSegmentAtRisk
+	case recency == 2:
+		return RFMSegmentHibernating
+	default:
+		return RFMSegmentLost
+	}
+}
+
+// lifetimeValue is what the customer spent so far plus their yearly spend
+// projected over CLVHorizonYears, weighted by how recently they bought. The
+// yearly spend of customers who started buying less than a year ago is
+// their spend so far, so a first order isn't extrapolated.
+func lifetimeValue(customer *Customer, now time.Time, recency int) float64 {
+	first := customer.LastOrderAt
+	if customer.FirstOrderAt != nil {
+		first = customer.FirstOrderAt
+	}
+	years := math.Max(now.Sub(*first).Hours()/24/365, 1)
+	yearly := customer.TotalSpent / years
+	value := customer.TotalSpent + yearly*CLVHorizonYears*float64(recency)/5
+	return math.Round(value*100) / 100
+}
+
+// RFMScoringResult counts the customers a scoring run went through, those
+// whose RFM segment changed and the segment decisions that followed
+type RFMScoringResult struct {
+	Customers int
+	Changed   int
+	Decisions int
+}
@@ -0,0 +1,172 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// MaxSavedSearches caps the catalog searches one customer can save
+const MaxSavedSearches = 20
+
+// SavedSearchAlertCooldown is the least time between two new-arrival alerts
+// for the same saved search, so a large catalog import sends one email
+// rather than one per product
+const SavedSearchAlertCooldown = 24 * time.Hour
+
+// Saved search errors
+var (
+	ErrSavedSearchLimit = fmt.Errorf("a customer can save at most %d searches", MaxSavedSearches)
+	ErrSavedSearchEmpty = errors.New("a saved search needs a query or at least one filter")
+)
+
+// SavedSearch is a catalog search a customer saved. When alerts are enabled
+// the customer is notified of newly published products that match it.
+type SavedSearch struct {
+	ID             uuid.UUID          `gorm:"type:uuid;primary_key" json:"id"`
+	UserID         uuid.UUID          `gorm:"type:uuid;not null;index" json:"user_id"`
+	Name           string             `gorm:"type:varchar(100);not null" json:"name"`
+	Query          string             `gorm:"type:varchar(200)" json:"query"`
+	Filters        SavedSearchFilters `gorm:"type:jsonb;serializer:json" json:"filters"`
+	AlertsEnabled  bool               `gorm:"default:true;index" json:"alerts_enabled"`
+	LastNotifiedAt *time.Time         `json:"last_notified_at,omitempty"`
+	CreatedAt      time.Time          `json:"created_at"`
+	UpdatedAt      time.Time          `json:"updated_at"`
+}
+
+func (s *SavedSearch) BeforeCreate(tx *gorm.DB) error {
+	if s.ID == uuid.Nil {
+		s.ID = uuid.New()
+	}
+	return nil
+}
+
+func (SavedSearch) TableName() string {
+	return "customer.saved_searches"
+}
+
+// SavedSearchFilters are the catalog search filters saved with the query.
+// Categories and brands are slugs; a product matches when it is in any of
+// the listed ones.
+type SavedSearchFilters struct {
+	Categories []string `json:"categories,omitempty"`
+	Brands     []string `json:"brands,omitempty"`
+	MinPrice   *float64 `json:"min_price,omitempty"`
+	MaxPrice   *float64 `json:"max_price,omitempty"`
+}
+
+// SaveSearchRequest creates or replaces a saved search
+type SaveSearchRequest struct {
+	Name          string             `json:"name" binding:"max=100"`
+	Query         string             `json:"query" binding:"max=200"`
+	Filters       SavedSearchFilters `json:"filters"`
+	AlertsEnabled *bool              `json:"alerts_enabled"`
+}
+
+// Apply validates the request and copies it onto the search. The name
+// defaults to the query, and alerts stay as they were when not given.
+func (r *SaveSearchRequest) Apply(search *SavedSearch) error {
+	query := strings.Join(strings.Fields(r.Query), " ")
+	f := r.Filters
+	f.Categories = normalizeSlugs(f.Categories)
+	f.Brands = normalizeSlugs(f.Brands)
+	if f.MinPrice != nil && *f.MinPrice < 0 || f.MaxPrice != nil && *f.MaxPrice < 0 {
+		return errors.New("prices cannot be negative")
+	}
+	if f.MinPrice != nil && f.MaxPrice != nil && *f.MinPrice > *f.MaxPrice {
+		return errors.New("min_price is greater than max_price")
+	}
+	if query == "" && len(f.Categories) == 0 && len(f.Brands) == 0 && f.MinPrice == nil && f.MaxPrice == nil {
+		return ErrSavedSearchEmpty
+	}
+
+	name := strings.TrimSpace(r.Name)
+	if name == "" {
+		name = query
+	}
+	if name == "" {
+		name = "Saved search"
+	}
+
+	search.Name = name
+	search.Query = query
+	search.Filters = f
+	if r.AlertsEnabled != nil {
+		search.AlertsEnabled = *r.AlertsEnabled
+	} else if search.CreatedAt.IsZero() {
+		search.AlertsEnabled = true
+	}
+	return nil
+}
+
+// PublishedProduct is a product the catalog has just published, as matched
+// against saved searches
+type PublishedProduct struct {
+	ID          uuid.UUID
+	Name        string
+	Slug        string
+	Image       string
+	Description string
+	Brand       string
+	Categories  []string
+	Tags        []string
+	Price       float64
+}
+
+// Matches reports whether the product satisfies the search: every query term
+// appears in its name, description, brand or tags, and it passes every filter
+func (s *SavedSearch) Matches(p PublishedProduct) bool {
+	f := s.Filters
+	if len(f.Categories) > 0 && !containsAny(f.Categories, p.Categories) {
+		return false
+	}
+	if len(f.Brands) > 0 && !containsAny(f.Brands, []string{p.Brand}) {
+		return false
+	}
+	if f.MinPrice != nil && p.Price < *f.MinPrice {
+		return false
+	}
+	if f.MaxPrice != nil && p.Price > *f.MaxPrice {
+		return false
+	}
+
+	text := strings.ToLower(strings.Join(append([]string{p.Name, p.Description, p.Brand}, p.Tags...), " "))
+	for _, term := range strings.Fields(strings.ToLower(s.Query)) {
+		if !strings.Contains(text, term) {
+			return false
+		}
+	}
+	return true
+}
+
+// normalizeSlugs lowercases and trims slugs, dropping blanks and duplicates
+func normalizeSlugs(slugs []string) []string {
+	var out []string
+	seen := make(map[string]bool, len(slugs))
+	for _, slug := range slugs {
+		slug = strings.ToLower(strings.TrimSpace(slug))
+		if slug == "" || seen[slug] {
+			continue
+		}
+		seen[slug] = true
+		out = append(out, slug)
+	}
+	return out
+}
+
+// containsAny reports whether any of values is in want, ignoring case
+func containsAny(want, values []string) bool {
+	for _, v := range values {
+		v = strings.ToLower(strings.TrimSpace(v))
+		for _, w := range want {
+			if v == w {
+				return true
+			}
+		}
+	}
+	return false
+}
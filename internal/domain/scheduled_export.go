@@ -0,0 +1,347 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/robfig/cron/v3"
+	"gorm.io/gorm"
+)
+
+// Scheduled export destinations
+const (
+	ExportTargetEmail = "email" // a download link is emailed to the recipients
+	ExportTargetS3    = "s3"    // the file is uploaded to an S3-compatible bucket
+	ExportTargetSFTP  = "sftp"  // the file is uploaded to an SFTP server
+)
+
+// Scheduled export run statuses
+const (
+	ExportRunRunning   = "running"
+	ExportRunSucceeded = "succeeded"
+	ExportRunFailed    = "failed"
+)
+
+// What started a scheduled export run
+const (
+	ExportRunScheduled = "schedule"
+	ExportRunManual    = "manual"
+)
+
+// DefaultExportLinkTTL is how long emailed export download links last
+const DefaultExportLinkTTL = 7 * 24 * time.Hour
+
+// ScheduledExportSort whitelists the sorts of the scheduled export list,
+// oldest first by default
+var ScheduledExportSort = NewSortFields(Sort{Field: "created_at", Direction: SortAsc}, map[string]string{
+	"created_at":  "created_at",
+	"name":        "name",
+	"next_run_at": "next_run_at",
+	"last_run_at": "last_run_at",
+})
+
+// ScheduledExportRunSort whitelists the sorts of an export's run history,
+// latest first by default
+var ScheduledExportRunSort = NewSortFields(Sort{Field: "started_at", Direction: SortDesc}, map[string]string{
+	"started_at": "started_at",
+	"rows":       "rows",
+	"bytes":      "bytes",
+	"status":     "status",
+})
+
+// Scheduled export errors
+var (
+	ErrInvalidExportSchedule = errors.New("schedule must be a cron expression or a descriptor such as @weekly")
+	ErrInvalidExportTarget   = errors.New("invalid export destination")
+)
+
+// ScheduledExport exports the customers matching Filter on Schedule and
+// delivers the file to Target. Region and MaskPII are taken from the admin
+// who last saved the export, so its files never show more than they could.
+type ScheduledExport struct {
+	ID          uuid.UUID    `gorm:"type:uuid;primary_key" json:"id"`
+	Name        string       `gorm:"type:varchar(100);not null" json:"name"`
+	Filter      string       `gorm:"type:text" json:"filter"` // filters of GET /admin/customers/export, as a query string
+	Format      string       `gorm:"type:varchar(10);not null" json:"format"`
+	Columns     []string     `gorm:"type:jsonb;serializer:json" json:"columns"`
+	Schedule    string       `gorm:"type:varchar(100);not null" json:"schedule"`
+	Target      ExportTarget `gorm:"embedded;embeddedPrefix:target_" json:"target"`
+	AlertEmails []string     `gorm:"type:jsonb;serializer:json" json:"alert_emails"` // told when a run fails
+	Region      *RegionScope `gorm:"type:jsonb;serializer:json" json:"region,omitempty"`
+	MaskPII     bool         `gorm:"not null;default:false" json:"mask_pii"`
+	IsActive    bool         `gorm:"default:true" json:"is_active"`
+
+	NextRunAt           *time.Time `gorm:"index" json:"next_run_at,omitempty"`
+	LastRunAt           *time.Time `json:"last_run_at,omitempty"`
+	LastStatus          string     `gorm:"type:varchar(20)" json:"last_status,omitempty"`
+	ConsecutiveFailures int        `gorm:"not null;default:0" json:"consecutive_failures"`
+
+	CreatedBy *uuid.UUID `gorm:"type:uuid" json:"created_by,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+func (e *ScheduledExport) BeforeCreate(tx *gorm.DB) error {
+	if e.ID == uuid.Nil {
+		e.ID = uuid.New()
+	}
+	return nil
+}
+
+func (ScheduledExport) TableName() string {
+	return "customer.scheduled_exports"
+}
+
+// ExportTarget is where a scheduled export is delivered. Path is the key
+// prefix in the bucket or the directory on the SFTP server. Secret is the S3
+// secret key or the SFTP password; neither it nor PrivateKey is ever
+// returned by the API.
+type ExportTarget struct {
+	Type       string   `gorm:"type:varchar(10);not null" json:"type"`
+	Recipients []string `gorm:"type:jsonb;serializer:json" json:"recipients,omitempty"` // email
+
+	Bucket    string `gorm:"type:varchar(100)" json:"bucket,omitempty"` // s3
+	Region    string `gorm:"type:varchar(50)" json:"region,omitempty"`
+	Endpoint  string `gorm:"type:varchar(255)" json:"endpoint,omitempty"` // empty for AWS S3
+	AccessKey string `gorm:"type:varchar(255)" json:"access_key,omitempty"`
+
+	Host     string `gorm:"type:varchar(255)" json:"host,omitempty"` // sftp, host:port
+	Username string `gorm:"type:varchar(100)" json:"username,omitempty"`
+	HostKey  string `gorm:"type:varchar(100)" json:"host_key,omitempty"` // SHA256 fingerprint, as printed by ssh-keygen -l
+
+	Path       string `gorm:"type:varchar(255)" json:"path,omitempty"`
+	Secret     string `gorm:"type:varchar(255)" json:"-"`
+	PrivateKey string `gorm:"type:text" json:"-"`
+}
+
+// Validate checks that the target has what its type needs
+func (t *ExportTarget) Validate() error {
+	switch t.Type {
+	case ExportTargetEmail:
+		if len(t.Recipients) == 0 {
+			return fmt.Errorf("%w: email needs recipients", ErrInvalidExportTarget)
+		}
+	case ExportTargetS3:
+		if t.Bucket == "" || t.AccessKey == "" || t.Secret == "" {
+			return fmt.Errorf("%w: s3 needs a bucket, access_key and secret", ErrInvalidExportTarget)
+		}
+		if t.Endpoint == "" && t.Region == "" {
+			return fmt.Errorf("%w: s3 needs a region or an endpoint", ErrInvalidExportTarget)
+		}
+	case ExportTargetSFTP:
+		if t.Host == "" || t.Username == "" || t.HostKey == "" {
+			return fmt.Errorf("%w: sftp needs a host, username and host_key", ErrInvalidExportTarget)
+		}
+		if t.Secret == "" && t.PrivateKey == "" {
+			return fmt.Errorf("%w: sftp needs a password (secret) or a private_key", ErrInvalidExportTarget)
+		}
+	default:
+		return fmt.Errorf("%w: type must be email, s3 or sftp", ErrInvalidExportTarget)
+	}
+	if strings.Contains(t.Path, "..") {
+		return fmt.Errorf("%w: path must not contain ..", ErrInvalidExportTarget)
+	}
+	return nil
+}
+
+// FilePath joins the target's path and a file name
+func (t *ExportTarget) FilePath(filename string) string {
+	return path.Join(t.Path, filename)
+}
+
+// ParseExportSchedule parses a five-field cron expression or a descriptor
+// such as "@weekly", optionally prefixed with "CRON_TZ=<zone> "
+func ParseExportSchedule(spec string) (cron.Schedule, error) {
+	schedule, err := cron.ParseStandard(spec)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidExportSchedule, err)
+	}
+	return schedule, nil
+}
+
+// ScheduleNext sets NextRunAt to the first scheduled time after t, or clears
+// it when the export is paused
+func (e *ScheduledExport) ScheduleNext(t time.Time) error {
+	if !e.IsActive {
+		e.NextRunAt = nil
+		return nil
+	}
+	schedule, err := ParseExportSchedule(e.Schedule)
+	if err != nil {
+		return err
+	}
+	next := schedule.Next(t)
+	e.NextRunAt = &next
+	return nil
+}
+
+// CustomerFilter returns the export's customer filter, limited to its region
+func (e *ScheduledExport) CustomerFilter() (CustomerListFilter, error) {
+	query, err := url.ParseQuery(strings.TrimPrefix(e.Filter, "?"))
+	if err != nil {
+		return CustomerListFilter{}, fmt.Errorf("invalid filter: %w", err)
+	}
+	filter, err := ParseCustomerExportFilter(query)
+	filter.Region = e.Region
+	filter.Page, filter.Limit = 1, MaxCustomerExportRows
+	return filter, err
+}
+
+// Filename names the file of a run started at t
+func (e *ScheduledExport) Filename(t time.Time) string {
+	return fmt.Sprintf("customers-%s.%s", t.UTC().Format("20060102-150405"), e.Format)
+}
+
+// ContentType is the MIME type of the export's files
+func (e *ScheduledExport) ContentType() string {
+	if e.Format == "json" {
+		return "application/json"
+	}
+	return "text/csv; charset=utf-8"
+}
+
+// ScheduledExportRun is one run of a scheduled export. Location is the
+// object key or remote path the file was delivered to.
+type ScheduledExportRun struct {
+	ID         uuid.UUID  `gorm:"type:uuid;primary_key" json:"id"`
+	ExportID   uuid.UUID  `gorm:"type:uuid;not null;index" json:"export_id"`
+	Trigger    string     `gorm:"type:varchar(10);not null" json:"trigger"`
+	Status     string     `gorm:"type:varchar(20);not null" json:"status"`
+	Rows       int        `gorm:"not null;default:0" json:"rows"`
+	Bytes      int64      `gorm:"not null;default:0" json:"bytes"`
+	Location   string     `gorm:"type:varchar(500)" json:"location,omitempty"`
+	Error      string     `gorm:"type:text" json:"error,omitempty"`
+	StartedAt  time.Time  `gorm:"not null" json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+func (r *ScheduledExportRun) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == uuid.Nil {
+		r.ID = uuid.New()
+	}
+	return nil
+}
+
+func (ScheduledExportRun) TableName() string {
+	return "customer.scheduled_export_runs"
+}
+
+// ScheduledExportRequest creates or replaces a scheduled export. When
+// replacing, an omitted secret or private key keeps the saved one.
+type ScheduledExportRequest struct {
+	Name        string              `json:"name" binding:"required,max=100"`
+	Filter      string              `json:"filter"`
+	Format      string              `json:"format" binding:"omitempty,oneof=csv json"`
+	Columns     []string            `json:"columns"`
+	Schedule    string              `json:"schedule" binding:"required,max=100"`
+	Target      ExportTargetRequest `json:"target" binding:"required"`
+	AlertEmails []string            `json:"alert_emails" binding:"omitempty,dive,email"`
+	IsActive    *bool               `json:"is_active"`
+}
+
+// ExportTargetRequest is the destination of a ScheduledExportRequest
+type ExportTargetRequest struct {
+	Type       string   `json:"type" binding:"required,oneof=email s3 sftp"`
+	Recipients []string `json:"recipients" binding:"omitempty,dive,email"`
+	Bucket     string   `json:"bucket" binding:"max=100"`
+	Region     string   `json:"region" binding:"max=50"`
+	Endpoint   string   `json:"endpoint" binding:"omitempty,url,max=255"`
+	AccessKey  string   `json:"access_key" binding:"max=255"`
+	Host       string   `json:"host" binding:"omitempty,hostname_port,max=255"`
+	Username   string   `json:"username" binding:"max=100"`
+	HostKey    string   `json:"host_key" binding:"omitempty,startswith=SHA256:,max=100"`
+	Path       string   `json:"path" binding:"max=255"`
+	Secret     string   `json:"secret" binding:"max=255"`
+	PrivateKey string   `json:"private_key"`
+}
+
+// Apply sets the export's definition from the request, validating its
+// filter, columns, schedule and target. Secrets left empty keep the
+// export's current ones.
+func (r *ScheduledExportRequest) Apply(e *ScheduledExport) error {
+	e.Name = r.Name
+	e.Filter = strings.TrimPrefix(r.Filter, "?")
+	e.Format = r.Format
+	if e.Format == "" {
+		e.Format = "csv"
+	}
+	e.Schedule = strings.TrimSpace(r.Schedule)
+	e.AlertEmails = r.AlertEmails
+	if r.IsActive != nil {
+		e.IsActive = *r.IsActive
+	}
+
+	if _, err := e.CustomerFilter(); err != nil {
+		return err
+	}
+	e.Columns = DefaultCustomerColumns
+	if len(r.Columns) > 0 {
+		columns, err := ParseCustomerColumns(r.Columns)
+		if err != nil {
+			return err
+		}
+		e.Columns = columns
+	}
+	if _, err := ParseExportSchedule(e.Schedule); err != nil {
+		return err
+	}
+
+	secret, privateKey := e.Target.Secret, e.Target.PrivateKey
+	if e.Target.Type != r.Target.Type {
+		secret, privateKey = "", ""
+	}
+	if r.Target.Secret != "" {
+		secret = r.Target.Secret
+	}
+	if r.Target.PrivateKey != "" {
+		privateKey = r.Target.PrivateKey
+	}
+	e.Target = ExportTarget{
+		Type:       r.Target.Type,
+		Recipients: r.Target.Recipients,
+		Bucket:     r.Target.Bucket,
+		Region:     r.Target.Region,
+		Endpoint:   r.Target.Endpoint,
+		AccessKey:  r.Target.AccessKey,
+		Host:       r.Target.Host,
+		Username:   r.Target.Username,
+		HostKey:    r.Target.HostKey,
+		Path:       r.Target.Path,
+		Secret:     secret,
+		PrivateKey: privateKey,
+	}
+	return e.Target.Validate()
+}
+
+// ExportFile is a finished scheduled export ready for delivery
+type ExportFile struct {
+	Name        string
+	ContentType string
+	Body        []byte
+	Rows        int
+}
+
+// ScheduledExportLink is the email sent to the recipients of an email export
+type ScheduledExportLink struct {
+	Recipients []string  `json:"recipients"`
+	ExportName string    `json:"exportName"`
+	Filename   string    `json:"filename"`
+	Rows       int       `json:"rows"`
+	URL        string    `json:"url"`
+	ExpiresAt  time.Time `json:"expiresAt"`
+}
+
+// ScheduledExportFailure is the alert sent when a scheduled export run fails
+type ScheduledExportFailure struct {
+	Recipients          []string  `json:"recipients"`
+	ExportID            string    `json:"exportId"`
+	ExportName          string    `json:"exportName"`
+	Error               string    `json:"error"`
+	ConsecutiveFailures int       `json:"consecutiveFailures"`
+	FailedAt            time.Time `json:"failedAt"`
+}
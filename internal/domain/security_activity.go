@@ -0,0 +1,103 @@
+package domain
+
+import (
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SecurityActivityTypes are the activity types shown on a customer's own
+// security log: changes to their account and sign-ins
+var SecurityActivityTypes = []string{ActivityTypeProfile, ActivityTypeAddress, ActivityTypeLogin}
+
+// SecurityActivityWindow is how far back a customer's security log goes
+const SecurityActivityWindow = 90 * 24 * time.Hour
+
+// Security log page sizes
+const (
+	DefaultSecurityActivityLimit = 20
+	MaxSecurityActivityLimit     = 100
+)
+
+// SecurityEvent is a customer's view of one of their activities. Internal
+// request IDs and the identity of support staff are left out; BySupport
+// only tells the customer the change was made on their behalf.
+type SecurityEvent struct {
+	ID        uuid.UUID `json:"id"`
+	Type      string    `json:"type"`
+	Title     string    `json:"title"`
+	IPAddress string    `json:"ip_address,omitempty"`
+	UserAgent string    `json:"user_agent,omitempty"`
+	Device    string    `json:"device,omitempty"`
+	BySupport bool      `json:"by_support"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// NewSecurityEvent returns the customer's view of an activity
+func NewSecurityEvent(activity CustomerActivity) SecurityEvent {
+	return SecurityEvent{
+		ID:        activity.ID,
+		Type:      activity.Type,
+		Title:     activity.Title,
+		IPAddress: activity.IPAddress,
+		UserAgent: activity.UserAgent,
+		Device:    DescribeDevice(activity.UserAgent),
+		BySupport: activity.ActorID != nil,
+		CreatedAt: activity.CreatedAt,
+	}
+}
+
+// userAgentBrowsers and userAgentSystems are checked in order, so tokens
+// that other user agents also carry (Chrome and Safari) come last
+var (
+	userAgentBrowsers = []struct{ token, name string }{
+		{"Edg/", "Edge"},
+		{"OPR/", "Opera"},
+		{"SamsungBrowser/", "Samsung Internet"},
+		{"Firefox/", "Firefox"},
+		{"FxiOS/", "Firefox"},
+		{"CriOS/", "Chrome"},
+		{"Chrome/", "Chrome"},
+		{"Safari/", "Safari"},
+		{"okhttp/", "Android app"},
+		{"CFNetwork/", "iOS app"},
+	}
+	userAgentSystems = []struct{ token, name string }{
+		{"iPhone", "iPhone"},
+		{"iPad", "iPad"},
+		{"Android", "Android"},
+		{"Windows", "Windows"},
+		{"Mac OS X", "macOS"},
+		{"Macintosh", "macOS"},
+		{"CrOS", "ChromeOS"},
+		{"Linux", "Linux"},
+	}
+)
+
+// DescribeDevice summarizes a user agent for people, e.g. "Chrome on
+// Windows". Returns "" when neither the browser nor the system is known.
+func DescribeDevice(userAgent string) string {
+	var browser, system string
+	for _, b := range userAgentBrowsers {
+		if strings.Contains(userAgent, b.token) {
+			browser = b.name
+			break
+		}
+	}
+	for _, s := range userAgentSystems {
+		if strings.Contains(userAgent, s.token) {
+			system = s.name
+			break
+		}
+	}
+
+	switch {
+	case browser != "" && system != "":
+		return browser + " on " + system
+	case browser != "":
+		return browser
+	default:
+		return system
+	}
+}
@@ -0,0 +1,128 @@
+package domain
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Bulk segment limits
+const (
+	MaxBulkSegmentCustomers = 10000 // customers per bulk change
+	BulkSegmentInlineLimit  = 1000  // larger changes run in the background
+	BulkSegmentBatchSize    = 500   // customers per transaction
+)
+
+// Bulk segment actions
+const (
+	BulkSegmentAssign = "assign"
+	BulkSegmentRemove = "remove"
+)
+
+// Where the customers of a bulk segment change came from
+const (
+	BulkSegmentByIDs    = "ids"
+	BulkSegmentByFilter = "filter"
+	BulkSegmentByCSV    = "csv" // uploaded CSV of emails
+)
+
+// Bulk segment job statuses
+const (
+	BulkSegmentPending   = "pending"
+	BulkSegmentRunning   = "running"
+	BulkSegmentCompleted = "completed"
+	BulkSegmentFailed    = "failed"
+)
+
+// Bulk segment errors
+var (
+	ErrBulkSegmentEmpty    = errors.New("no customers to change")
+	ErrBulkSegmentTooLarge = fmt.Errorf("bulk segment changes are limited to %d customers", MaxBulkSegmentCustomers)
+)
+
+// emailHeaders are first-row values treated as a header rather than an email
+var emailHeaders = map[string]bool{"email": true, "emails": true, "email_address": true}
+
+// SegmentBulkJob assigns a segment to, or removes it from, many customers in
+// batches. Processed counts the customers gone through so far, of which
+// Changed were assigned or removed and Unchanged already were; NotFound
+// counts IDs and emails without a customer.
+type SegmentBulkJob struct {
+	ID          uuid.UUID  `gorm:"type:uuid;primary_key" json:"id"`
+	SegmentID   uuid.UUID  `gorm:"type:uuid;not null;index" json:"segment_id"`
+	Action      string     `gorm:"type:varchar(10);not null" json:"action"`
+	Source      string     `gorm:"type:varchar(10);not null" json:"source"`
+	Status      string     `gorm:"type:varchar(20);not null" json:"status"`
+	Total       int        `gorm:"not null" json:"total"`
+	Processed   int        `gorm:"not null;default:0" json:"processed"`
+	Changed     int        `gorm:"not null;default:0" json:"changed"`
+	Unchanged   int        `gorm:"not null;default:0" json:"unchanged"`
+	NotFound    int        `gorm:"not null;default:0" json:"not_found"`
+	Error       string     `gorm:"type:text" json:"error,omitempty"`
+	CreatedBy   *uuid.UUID `gorm:"type:uuid" json:"created_by,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+}
+
+func (j *SegmentBulkJob) BeforeCreate(tx *gorm.DB) error {
+	if j.ID == uuid.Nil {
+		j.ID = uuid.New()
+	}
+	return nil
+}
+
+func (SegmentBulkJob) TableName() string {
+	return "public.customer_segment_bulk_jobs"
+}
+
+// Done reports whether the job has finished, successfully or not
+func (j *SegmentBulkJob) Done() bool {
+	return j.Status == BulkSegmentCompleted || j.Status == BulkSegmentFailed
+}
+
+// ParseBulkSegmentEmails reads the emails of a bulk segment CSV: the first
+// column of each row, with an optional header row. Emails are lowercased and
+// duplicates dropped; rows that aren't an email are returned as invalid.
+func ParseBulkSegmentEmails(r io.Reader) (emails, invalid []string, err error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	seen := make(map[string]bool)
+	for row := 1; ; row++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid CSV: %w", err)
+		}
+
+		value := strings.ToLower(strings.TrimSpace(strings.TrimPrefix(record[0], "\ufeff")))
+		if value == "" || (row == 1 && emailHeaders[value]) || seen[value] {
+			continue
+		}
+		seen[value] = true
+		if address, err := mail.ParseAddress(value); err != nil || address.Address != value {
+			invalid = append(invalid, value)
+			continue
+		}
+		if len(emails) == MaxBulkSegmentCustomers {
+			return nil, nil, ErrBulkSegmentTooLarge
+		}
+		emails = append(emails, value)
+	}
+
+	if len(emails) == 0 {
+		return nil, nil, ErrBulkSegmentEmpty
+	}
+	return emails, invalid, nil
+}
@@ -0,0 +1,68 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Segment membership changes
+const (
+	SegmentMembershipEntered = "entered"
+	SegmentMembershipLeft    = "left"
+)
+
+// What changed a customer's segment membership
+const (
+	SegmentSourceManual = "manual" // an admin set the customer's segments
+	SegmentSourceRule   = "rule"   // a segment rule fired
+	SegmentSourceBulk   = "bulk"   // a bulk segment change
+)
+
+// SegmentMembershipEvent records a customer entering or leaving a segment.
+// RuleID is set for rule changes, JobID for bulk changes and ActorID for
+// changes made by an admin.
+type SegmentMembershipEvent struct {
+	ID         uuid.UUID  `gorm:"type:uuid;primary_key" json:"id"`
+	CustomerID uuid.UUID  `gorm:"type:uuid;not null;index:idx_segment_membership_events_customer,priority:1" json:"customer_id"`
+	SegmentID  uuid.UUID  `gorm:"type:uuid;not null;index:idx_segment_membership_events_segment,priority:1" json:"segment_id"`
+	Change     string     `gorm:"type:varchar(10);not null" json:"change"`
+	Source     string     `gorm:"type:varchar(10);not null" json:"source"`
+	RuleID     *uuid.UUID `gorm:"type:uuid" json:"rule_id,omitempty"`
+	JobID      *uuid.UUID `gorm:"type:uuid" json:"job_id,omitempty"`
+	ActorID    *uuid.UUID `gorm:"type:uuid" json:"actor_id,omitempty"`
+	OccurredAt time.Time  `gorm:"not null;index:idx_segment_membership_events_customer,priority:2;index:idx_segment_membership_events_segment,priority:2" json:"occurred_at"`
+}
+
+func (e *SegmentMembershipEvent) BeforeCreate(tx *gorm.DB) error {
+	if e.ID == uuid.Nil {
+		e.ID = uuid.New()
+	}
+	if e.OccurredAt.IsZero() {
+		e.OccurredAt = time.Now()
+	}
+	return nil
+}
+
+func (SegmentMembershipEvent) TableName() string {
+	return "public.segment_membership_events"
+}
+
+// TimelineTitle describes the change on the customer timeline
+func (e *SegmentMembershipEvent) TimelineTitle() string {
+	if e.Change == SegmentMembershipLeft {
+		return "Removed from segment"
+	}
+	return "Added to segment"
+}
+
+// SegmentHistoryFilter selects the membership events of a segment
+type SegmentHistoryFilter struct {
+	Change   string // entered or left; empty for both
+	Source   string // empty for every source
+	DateFrom *time.Time
+	DateTo   *time.Time
+	Page     int
+	Limit    int
+}
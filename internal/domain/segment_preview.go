@@ -0,0 +1,82 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Ecom-micro-template/service-customer/internal/domain/shared"
+)
+
+// SegmentPreviewSampleSize is how many matching customers a segment preview
+// returns
+const SegmentPreviewSampleSize = 20
+
+// SegmentCountTTL is how long segment member counts are cached; listed
+// counts may lag assignments by up to this long
+const SegmentCountTTL = 5 * time.Minute
+
+// SegmentConditions select the customers of a segment with the filters of the
+// admin customer list. Unset conditions match every customer.
+type SegmentConditions struct {
+	Status      string   `json:"status,omitempty"`
+	Tags        []string `json:"tags,omitempty"` // customers must carry all of them
+	OrdersMin   *int     `json:"orders_min,omitempty"`
+	OrdersMax   *int     `json:"orders_max,omitempty"`
+	SpentMin    *float64 `json:"spent_min,omitempty"`
+	SpentMax    *float64 `json:"spent_max,omitempty"`
+	CLVMin      *float64 `json:"clv_min,omitempty"`
+	CLVMax      *float64 `json:"clv_max,omitempty"`
+	ChurnRisk   string   `json:"churn_risk,omitempty"`
+	RFMSegments []string `json:"rfm_segments,omitempty"` // customers must be in one of them
+}
+
+// Filter validates the conditions and returns them as a customer list filter
+func (s SegmentConditions) Filter() (CustomerListFilter, error) {
+	filter := CustomerListFilter{
+		OrdersMin: s.OrdersMin,
+		OrdersMax: s.OrdersMax,
+		SpentMin:  s.SpentMin,
+		SpentMax:  s.SpentMax,
+		CLVMin:    s.CLVMin,
+		CLVMax:    s.CLVMax,
+	}
+	if s.Status != "" {
+		if !shared.CustomerStatus(s.Status).IsValid() {
+			return filter, fmt.Errorf("invalid status %q", s.Status)
+		}
+		filter.Status = s.Status
+	}
+	if s.ChurnRisk != "" {
+		if !ValidChurnRisk(s.ChurnRisk) {
+			return filter, ErrInvalidChurnRisk
+		}
+		filter.ChurnRisk = s.ChurnRisk
+	}
+	if len(s.Tags) > 0 {
+		tags, err := NormalizeTagNames(s.Tags)
+		if err != nil {
+			return filter, err
+		}
+		filter.Tags = tags
+	}
+	segments, err := ParseRFMSegments(strings.Join(s.RFMSegments, ","))
+	if err != nil {
+		return filter, err
+	}
+	filter.RFMSegments = segments
+	return filter, nil
+}
+
+// SegmentWithCount is a segment listed with the number of customers in it
+type SegmentWithCount struct {
+	CustomerSegment
+	MemberCount int64 `json:"member_count"`
+}
+
+// SegmentPreview is the outcome of evaluating segment conditions before the
+// segment is saved
+type SegmentPreview struct {
+	Count  int64      `json:"count"`
+	Sample []Customer `json:"sample"` // up to SegmentPreviewSampleSize matches
+}
@@ -0,0 +1,182 @@
+package domain
+
+import (
+	"sort"
+	"time"
+
+	"github.com/Ecom-micro-template/service-customer/internal/domain/shared"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Segment rule triggers
+const (
+	SegmentTriggerOrderCompleted = "order_completed" // an order was paid/completed
+	SegmentTriggerStatusChanged  = "status_changed"  // an admin changed the customer status
+	SegmentTriggerRFMScored      = "rfm_scored"      // the rfm_scoring job moved the customer to another RFM segment
+)
+
+// Segment rule actions
+const (
+	SegmentActionAssign            = "assign"
+	SegmentActionUnassign          = "unassign"
+	SegmentActionUnassignMarketing = "unassign_marketing" // remove from every marketing segment
+)
+
+// SegmentRule assigns or removes a segment when a trigger fires and the
+// customer matches all set conditions. When rules disagree about a segment,
+// the rule with the highest priority wins.
+type SegmentRule struct {
+	ID        uuid.UUID  `gorm:"type:uuid;primary_key" json:"id"`
+	Name      string     `gorm:"type:varchar(100);not null" json:"name"`
+	Trigger   string     `gorm:"type:varchar(30);not null;index" json:"trigger"`
+	Action    string     `gorm:"type:varchar(30);not null" json:"action"`
+	SegmentID *uuid.UUID `gorm:"type:uuid" json:"segment_id,omitempty"` // required for assign/unassign
+	Priority  int        `gorm:"default:0" json:"priority"`
+	IsActive  bool       `gorm:"default:true" json:"is_active"`
+
+	// Conditions; unset conditions always match
+	MinOrders     *int                   `json:"min_orders,omitempty"`
+	MaxOrders     *int                   `json:"max_orders,omitempty"`
+	MinTotalSpent *float64               `gorm:"type:decimal(12,2)" json:"min_total_spent,omitempty"`
+	Status        *shared.CustomerStatus `gorm:"type:varchar(20)" json:"status,omitempty"`
+	RFMSegment    *string                `gorm:"type:varchar(30)" json:"rfm_segment,omitempty"`
+	MinRecency    *int                   `json:"min_recency,omitempty"` // RFM scores, 1 to 5
+	MinFrequency  *int                   `json:"min_frequency,omitempty"`
+	MinMonetary   *int                   `json:"min_monetary,omitempty"`
+	MinCLV        *float64               `gorm:"type:decimal(12,2)" json:"min_clv,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (r *SegmentRule) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == uuid.Nil {
+		r.ID = uuid.New()
+	}
+	return nil
+}
+
+func (SegmentRule) TableName() string {
+	return "public.customer_segment_rules"
+}
+
+// Matches reports whether the rule fires for the trigger and customer
+func (r *SegmentRule) Matches(trigger string, customer *Customer) bool {
+	if !r.IsActive || r.Trigger != trigger {
+		return false
+	}
+	if r.MinOrders != nil && customer.TotalOrders < *r.MinOrders {
+		return false
+	}
+	if r.MaxOrders != nil && customer.TotalOrders > *r.MaxOrders {
+		return false
+	}
+	if r.MinTotalSpent != nil && customer.TotalSpent < *r.MinTotalSpent {
+		return false
+	}
+	if r.Status != nil && customer.Status != *r.Status {
+		return false
+	}
+	if r.RFMSegment != nil && customer.RFMSegment != *r.RFMSegment {
+		return false
+	}
+	if r.MinRecency != nil && customer.RecencyScore < *r.MinRecency {
+		return false
+	}
+	if r.MinFrequency != nil && customer.FrequencyScore < *r.MinFrequency {
+		return false
+	}
+	if r.MinMonetary != nil && customer.MonetaryScore < *r.MinMonetary {
+		return false
+	}
+	if r.MinCLV != nil && customer.LifetimeValue < *r.MinCLV {
+		return false
+	}
+	return true
+}
+
+// SegmentDecision is the outcome for one segment after evaluating the rules
+type SegmentDecision struct {
+	SegmentID uuid.UUID `json:"segment_id"`
+	Assign    bool      `json:"assign"`
+	RuleID    uuid.UUID `json:"rule_id"`
+	RuleName  string    `json:"rule_name"`
+}
+
+// SegmentEvaluation lists the rules that fired and the resulting decisions
+type SegmentEvaluation struct {
+	CustomerID   uuid.UUID         `json:"customer_id"`
+	Trigger      string            `json:"trigger"`
+	MatchedRules []SegmentRule     `json:"matched_rules"`
+	Decisions    []SegmentDecision `json:"decisions"`
+}
+
+// EvaluateSegmentRules decides which segments to assign or remove.
+// Rules are applied from highest to lowest priority and the first rule to
+// decide a segment wins. marketingSegments are the segments removed by
+// unassign_marketing rules.
+func EvaluateSegmentRules(rules []SegmentRule, trigger string, customer *Customer, marketingSegments []uuid.UUID) *SegmentEvaluation {
+	sorted := make([]SegmentRule, len(rules))
+	copy(sorted, rules)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Priority > sorted[j].Priority
+	})
+
+	evaluation := &SegmentEvaluation{
+		CustomerID:   customer.ID,
+		Trigger:      trigger,
+		MatchedRules: []SegmentRule{},
+		Decisions:    []SegmentDecision{},
+	}
+	decided := make(map[uuid.UUID]bool)
+	decide := func(rule SegmentRule, segmentID uuid.UUID, assign bool) {
+		if decided[segmentID] {
+			return
+		}
+		decided[segmentID] = true
+		evaluation.Decisions = append(evaluation.Decisions, SegmentDecision{
+			SegmentID: segmentID,
+			Assign:    assign,
+			RuleID:    rule.ID,
+			RuleName:  rule.Name,
+		})
+	}
+
+	for _, rule := range sorted {
+		if !rule.Matches(trigger, customer) {
+			continue
+		}
+		evaluation.MatchedRules = append(evaluation.MatchedRules, rule)
+
+		switch rule.Action {
+		case SegmentActionAssign, SegmentActionUnassign:
+			if rule.SegmentID != nil {
+				decide(rule, *rule.SegmentID, rule.Action == SegmentActionAssign)
+			}
+		case SegmentActionUnassignMarketing:
+			for _, segmentID := range marketingSegments {
+				decide(rule, segmentID, false)
+			}
+		}
+	}
+	return evaluation
+}
+
+// CreateSegmentRuleRequest is the request body for creating a segment rule
+type CreateSegmentRuleRequest struct {
+	Name          string                 `json:"name" binding:"required"`
+	Trigger       string                 `json:"trigger" binding:"required,oneof=order_completed status_changed rfm_scored"`
+	Action        string                 `json:"action" binding:"required,oneof=assign unassign unassign_marketing"`
+	SegmentID     *uuid.UUID             `json:"segment_id"`
+	Priority      int                    `json:"priority"`
+	MinOrders     *int                   `json:"min_orders"`
+	MaxOrders     *int                   `json:"max_orders"`
+	MinTotalSpent *float64               `json:"min_total_spent"`
+	Status        *shared.CustomerStatus `json:"status"`
+	RFMSegment    *string                `json:"rfm_segment" binding:"omitempty,oneof=champions loyal new potential at_risk hibernating lost"`
+	MinRecency    *int                   `json:"min_recency" binding:"omitempty,min=1,max=5"`
+	MinFrequency  *int                   `json:"min_frequency" binding:"omitempty,min=1,max=5"`
+	MinMonetary   *int                   `json:"min_monetary" binding:"omitempty,min=1,max=5"`
+	MinCLV        *float64               `json:"min_clv" binding:"omitempty,min=0"`
+}
@@ -0,0 +1,51 @@
+package shared
+
+import (
+	"errors"
+	"strings"
+)
+
+// AddressLabel is the kind of place a saved address is, as stored on
+// customer.addresses and shown to customers.
+type AddressLabel string
+
+const (
+	AddressLabelHome   AddressLabel = "Home"
+	AddressLabelOffice AddressLabel = "Office"
+	AddressLabelOther  AddressLabel = "Other"
+)
+
+// ErrInvalidAddressLabel is returned for invalid address labels.
+var ErrInvalidAddressLabel = errors.New("invalid address label")
+
+// AllAddressLabels returns all valid address labels.
+func AllAddressLabels() []AddressLabel {
+	return []AddressLabel{AddressLabelHome, AddressLabelOffice, AddressLabelOther}
+}
+
+// IsValid returns true if the label is valid.
+func (l AddressLabel) IsValid() bool {
+	switch l {
+	case AddressLabelHome, AddressLabelOffice, AddressLabelOther:
+		return true
+	default:
+		return false
+	}
+}
+
+// String returns the string representation.
+func (l AddressLabel) String() string {
+	return string(l)
+}
+
+// ParseAddressLabel parses a string into an AddressLabel, ignoring case, so
+// "home" and "HOME" both become AddressLabelHome.
+func ParseAddressLabel(s string) (AddressLabel, error) {
+	trimmed := strings.TrimSpace(s)
+	for _, l := range AllAddressLabels() {
+		if strings.EqualFold(trimmed, string(l)) {
+			return l, nil
+		}
+	}
+	return "", invalidEnum(ErrInvalidAddressLabel, s, AllAddressLabels())
+}
@@ -0,0 +1,108 @@
+package shared
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"strings"
+)
+
+// Enum columns are strict on write and lenient on read: Value refuses values
+// outside the enum, so nothing bad reaches the database through GORM, while
+// Scan accepts whatever is stored so a legacy row can't make a read fail.
+// The matching CHECK constraints are created by
+// persistence.EnsureEnumConstraints.
+
+// scanEnum reads an enum column, treating NULL as ""
+func scanEnum(src interface{}) (string, error) {
+	switch v := src.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case []byte:
+		return string(v), nil
+	default:
+		return "", fmt.Errorf("cannot scan %T into an enum", src)
+	}
+}
+
+// EnumValues returns the values of an enum as strings, for CHECK constraints
+// and error messages
+func EnumValues[T ~string](values []T) []string {
+	out := make([]string, len(values))
+	for i, v := range values {
+		out[i] = string(v)
+	}
+	return out
+}
+
+// invalidEnum wraps err with the offending value and the allowed ones
+func invalidEnum[T ~string](err error, value string, allowed []T) error {
+	return fmt.Errorf("%w: %q (must be one of %s)", err, value, strings.Join(EnumValues(allowed), ", "))
+}
+
+// Scan implements sql.Scanner.
+func (s *CustomerStatus) Scan(src interface{}) error {
+	v, err := scanEnum(src)
+	*s = CustomerStatus(v)
+	return err
+}
+
+// Value implements driver.Valuer.
+func (s CustomerStatus) Value() (driver.Value, error) {
+	if !s.IsValid() {
+		return nil, invalidEnum(ErrInvalidCustomerStatus, string(s), AllCustomerStatuses())
+	}
+	return string(s), nil
+}
+
+// Scan implements sql.Scanner.
+func (g *Gender) Scan(src interface{}) error {
+	v, err := scanEnum(src)
+	*g = Gender(v)
+	return err
+}
+
+// Value implements driver.Valuer.
+func (g Gender) Value() (driver.Value, error) {
+	if !g.IsValid() {
+		return nil, invalidEnum(ErrInvalidGender, string(g), AllGenders())
+	}
+	return string(g), nil
+}
+
+// Scan implements sql.Scanner.
+func (g *ProfileGender) Scan(src interface{}) error {
+	v, err := scanEnum(src)
+	*g = ProfileGender(v)
+	return err
+}
+
+// Value implements driver.Valuer. An unset gender is stored as NULL.
+func (g ProfileGender) Value() (driver.Value, error) {
+	if g == "" {
+		return nil, nil
+	}
+	if !g.IsValid() {
+		return nil, invalidEnum(ErrInvalidProfileGender, string(g), AllProfileGenders())
+	}
+	return string(g), nil
+}
+
+// Scan implements sql.Scanner.
+func (l *AddressLabel) Scan(src interface{}) error {
+	v, err := scanEnum(src)
+	*l = AddressLabel(v)
+	return err
+}
+
+// Value implements driver.Valuer. An unset label is stored as NULL.
+func (l AddressLabel) Value() (driver.Value, error) {
+	if l == "" {
+		return nil, nil
+	}
+	if !l.IsValid() {
+		return nil, invalidEnum(ErrInvalidAddressLabel, string(l), AllAddressLabels())
+	}
+	return string(l), nil
+}
@@ -0,0 +1,82 @@
+package shared
+
+import (
+	"errors"
+	"strings"
+)
+
+// Gender represents gender for measurement purposes.
+type Gender string
+
+const (
+	GenderMen   Gender = "men"
+	GenderWomen Gender = "women"
+)
+
+// ErrInvalidGender is returned for invalid measurement genders.
+var ErrInvalidGender = errors.New("invalid gender")
+
+// AllGenders returns all valid measurement genders.
+func AllGenders() []Gender {
+	return []Gender{GenderMen, GenderWomen}
+}
+
+// IsValid returns true if the gender is valid.
+func (g Gender) IsValid() bool {
+	return g == GenderMen || g == GenderWomen
+}
+
+// String returns the string representation.
+func (g Gender) String() string {
+	return string(g)
+}
+
+// ParseGender parses a string into a measurement Gender.
+func ParseGender(s string) (Gender, error) {
+	g := Gender(strings.ToLower(strings.TrimSpace(s)))
+	if !g.IsValid() {
+		return "", invalidEnum(ErrInvalidGender, s, AllGenders())
+	}
+	return g, nil
+}
+
+// ProfileGender is the gender a customer gives on their profile.
+type ProfileGender string
+
+const (
+	ProfileGenderMale   ProfileGender = "male"
+	ProfileGenderFemale ProfileGender = "female"
+	ProfileGenderOther  ProfileGender = "other"
+)
+
+// ErrInvalidProfileGender is returned for invalid profile genders.
+var ErrInvalidProfileGender = errors.New("invalid profile gender")
+
+// AllProfileGenders returns all valid profile genders.
+func AllProfileGenders() []ProfileGender {
+	return []ProfileGender{ProfileGenderMale, ProfileGenderFemale, ProfileGenderOther}
+}
+
+// IsValid returns true if the gender is valid.
+func (g ProfileGender) IsValid() bool {
+	switch g {
+	case ProfileGenderMale, ProfileGenderFemale, ProfileGenderOther:
+		return true
+	default:
+		return false
+	}
+}
+
+// String returns the string representation.
+func (g ProfileGender) String() string {
+	return string(g)
+}
+
+// ParseProfileGender parses a string into a ProfileGender.
+func ParseProfileGender(s string) (ProfileGender, error) {
+	g := ProfileGender(strings.ToLower(strings.TrimSpace(s)))
+	if !g.IsValid() {
+		return "", invalidEnum(ErrInvalidProfileGender, s, AllProfileGenders())
+	}
+	return g, nil
+}
@@ -1,13 +1,5 @@
 package shared
 
-// Gender represents gender for measurement purposes.
-type Gender string
-
-const (
-	GenderMen   Gender = "men"
-	GenderWomen Gender = "women"
-)
-
 // BodyMeasurement represents body measurements for tailoring.
 // All measurements are in centimeters unless otherwise specified.
 type BodyMeasurement struct {
@@ -103,5 +103,6 @@ func (p Phone) MaskedPhone() string {
 		return normalized
 	}
 	visible := 4
-	return normalized[:len(normalized)-visible-4] + "****" + normalized[len(normalized)-visible:]
+	prefix := max(len(normalized)-visible-4, 0)
+	return normalized[:prefix] + "****" + normalized[len(normalized)-visible:]
 }
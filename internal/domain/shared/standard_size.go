@@ -0,0 +1,85 @@
+package shared
+
+// StandardSize represents a ready-to-wear garment size.
+type StandardSize string
+
+// Standard size constants, smallest first
+const (
+	SizeXS   StandardSize = "XS"
+	SizeS    StandardSize = "S"
+	SizeM    StandardSize = "M"
+	SizeL    StandardSize = "L"
+	SizeXL   StandardSize = "XL"
+	SizeXXL  StandardSize = "XXL"
+	Size3XL  StandardSize = "3XL"
+	SizeNone StandardSize = ""
+)
+
+// sizeOrder lists sizes from smallest to largest.
+var sizeOrder = []StandardSize{SizeXS, SizeS, SizeM, SizeL, SizeXL, SizeXXL, Size3XL}
+
+// sizeChart holds the upper bound (cm, exclusive) of each size up to XXL for
+// one body measurement; anything at or above the last bound is 3XL.
+type sizeChart [6]float64
+
+// Size charts used by the storefront size guide.
+var (
+	womenBustChart  = sizeChart{82, 88, 94, 100, 106, 112}
+	womenWaistChart = sizeChart{66, 72, 78, 84, 90, 96}
+	womenHipChart   = sizeChart{90, 96, 102, 108, 114, 120}
+	menChestChart   = sizeChart{86, 92, 98, 104, 110, 116}
+	menWaistChart   = sizeChart{72, 78, 84, 90, 96, 102}
+	menHipChart     = sizeChart{88, 94, 100, 106, 112, 118}
+)
+
+func (c sizeChart) index(value float64) int {
+	for i, upper := range c {
+		if value < upper {
+			return i
+		}
+	}
+	return len(sizeOrder) - 1
+}
+
+// StandardSize derives the ready-to-wear size from the body measurements.
+// Each available key measurement is looked up in the size chart and the
+// largest resulting size wins, so the garment fits everywhere.
+// Returns SizeNone if none of the key measurements are set.
+func (m BodyMeasurement) StandardSize() StandardSize {
+	type lookup struct {
+		value *float64
+		chart sizeChart
+	}
+
+	var lookups []lookup
+	if m.gender == GenderWomen {
+		lookups = []lookup{{m.bust, womenBustChart}, {m.waist, womenWaistChart}, {m.hip, womenHipChart}}
+	} else {
+		lookups = []lookup{{m.chest, menChestChart}, {m.waist, menWaistChart}, {m.hip, menHipChart}}
+	}
+
+	best := -1
+	for _, l := range lookups {
+		if l.value == nil || *l.value <= 0 {
+			continue
+		}
+		if idx := l.chart.index(*l.value); idx > best {
+			best = idx
+		}
+	}
+
+	if best < 0 {
+		return SizeNone
+	}
+	return sizeOrder[best]
+}
+
+// String returns the string representation.
+func (s StandardSize) String() string {
+	return string(s)
+}
+
+// IsEmpty returns true if no size could be derived.
+func (s StandardSize) IsEmpty() bool {
+	return s == SizeNone
+}
@@ -0,0 +1,39 @@
+package shared
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func cm(value float64) *float64 {
+	return &value
+}
+
+func TestBodyMeasurement_StandardSize(t *testing.T) {
+	tests := []struct {
+		name   string
+		params BodyMeasurementParams
+		want   StandardSize
+	}{
+		{"women below the smallest bound", BodyMeasurementParams{Gender: GenderWomen, Bust: cm(81.9)}, SizeXS},
+		{"women on a bound take the next size", BodyMeasurementParams{Gender: GenderWomen, Bust: cm(82)}, SizeS},
+		{"women just below the 3XL bound", BodyMeasurementParams{Gender: GenderWomen, Bust: cm(111.9)}, SizeXXL},
+		{"women on the 3XL bound", BodyMeasurementParams{Gender: GenderWomen, Bust: cm(112)}, Size3XL},
+		{"women far above the chart", BodyMeasurementParams{Gender: GenderWomen, Hip: cm(160)}, Size3XL},
+		{"men on a waist bound", BodyMeasurementParams{Gender: GenderMen, Waist: cm(84)}, SizeL},
+		{"men ignore bust", BodyMeasurementParams{Gender: GenderMen, Bust: cm(120), Chest: cm(90)}, SizeS},
+		{"women ignore chest", BodyMeasurementParams{Gender: GenderWomen, Chest: cm(120), Waist: cm(70)}, SizeS},
+		{"largest size wins", BodyMeasurementParams{Gender: GenderWomen, Bust: cm(84), Waist: cm(80), Hip: cm(95)}, SizeL},
+		{"missing fields are skipped", BodyMeasurementParams{Gender: GenderMen, Chest: nil, Hip: cm(95)}, SizeM},
+		{"zero measurements are skipped", BodyMeasurementParams{Gender: GenderWomen, Bust: cm(0), Waist: cm(67)}, SizeS},
+		{"no key measurements", BodyMeasurementParams{Gender: GenderWomen}, SizeNone},
+		{"only zero measurements", BodyMeasurementParams{Gender: GenderMen, Chest: cm(0), Waist: cm(-1)}, SizeNone},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, NewBodyMeasurement(tt.params).StandardSize())
+		})
+	}
+}
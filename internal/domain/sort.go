@@ -0,0 +1,93 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// SortDirection orders a listing ascending or descending
+type SortDirection string
+
+const (
+	SortAsc  SortDirection = "asc"
+	SortDesc SortDirection = "desc"
+)
+
+// ErrInvalidSort is returned for a sort field or direction a listing doesn't
+// allow
+var ErrInvalidSort = errors.New("invalid sort")
+
+// Sort is how a listing is ordered. Field is one of the listing's
+// SortFields, never a column name taken from the request.
+type Sort struct {
+	Field     string
+	Direction SortDirection
+}
+
+// Desc reports whether the sort is descending
+func (s Sort) Desc() bool {
+	return s.Direction == SortDesc
+}
+
+// SortFields whitelists the fields a listing can be sorted by. Handlers
+// parse requested sorts with Parse and repositories resolve them to a column
+// with Resolve, so only whitelisted columns ever reach ORDER BY.
+type SortFields struct {
+	columns map[string]string
+	Default Sort
+}
+
+// NewSortFields whitelists fields, each mapped to the column it sorts on
+func NewSortFields(def Sort, columns map[string]string) SortFields {
+	return SortFields{columns: columns, Default: def}
+}
+
+// Fields lists the allowed fields alphabetically
+func (f SortFields) Fields() []string {
+	fields := make([]string, 0, len(f.columns))
+	for field := range f.columns {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+// Parse validates a requested sort_by and sort_order. Either may be empty,
+// falling back to the default field and direction.
+func (f SortFields) Parse(sortBy, sortOrder string) (Sort, error) {
+	s := f.Default
+	if sortBy != "" {
+		if _, ok := f.columns[sortBy]; !ok {
+			return Sort{}, fmt.Errorf("%w: sort_by must be one of %s", ErrInvalidSort, strings.Join(f.Fields(), ", "))
+		}
+		s.Field = sortBy
+	}
+	if sortOrder != "" {
+		switch direction := SortDirection(strings.ToLower(sortOrder)); direction {
+		case SortAsc, SortDesc:
+			s.Direction = direction
+		default:
+			return Sort{}, fmt.Errorf("%w: sort_order must be asc or desc", ErrInvalidSort)
+		}
+	}
+	return s, nil
+}
+
+// Resolve returns the column and direction a sort orders by. The zero Sort
+// means the default; any field or direction outside the whitelist is an
+// error.
+func (f SortFields) Resolve(s Sort) (column string, desc bool, err error) {
+	if s == (Sort{}) {
+		s = f.Default
+	}
+	if s.Direction != SortAsc && s.Direction != SortDesc {
+		return "", false, fmt.Errorf("%w: direction %q", ErrInvalidSort, s.Direction)
+	}
+	column, ok := f.columns[s.Field]
+	if !ok {
+		return "", false, fmt.Errorf("%w: field %q", ErrInvalidSort, s.Field)
+	}
+	return column, s.Desc(), nil
+}
@@ -0,0 +1,109 @@
+package domain
+
+import (
+	"sort"
+	"time"
+)
+
+// Timeline entry types
+const (
+	TimelineTypeActivity     = "activity"
+	TimelineTypeNote         = "note"
+	TimelineTypeStatusChange = "status_change"
+	TimelineTypeSegment      = "segment"
+	TimelineTypeOrder        = "order"
+)
+
+// TimelineTypes lists every timeline entry type
+var TimelineTypes = []string{
+	TimelineTypeActivity,
+	TimelineTypeNote,
+	TimelineTypeStatusChange,
+	TimelineTypeSegment,
+	TimelineTypeOrder,
+}
+
+// MaxTimelineDepth bounds page*limit; every source is read up to this many
+// entries to merge a page
+const MaxTimelineDepth = 1000
+
+// IsValidTimelineType reports whether t is a known timeline entry type
+func IsValidTimelineType(t string) bool {
+	for _, timelineType := range TimelineTypes {
+		if timelineType == t {
+			return true
+		}
+	}
+	return false
+}
+
+// TimelineEntry is one event on the customer 360 timeline
+type TimelineEntry struct {
+	ID         string      `json:"id"`
+	Type       string      `json:"type"`
+	Title      string      `json:"title"`
+	Details    string      `json:"details,omitempty"`
+	OccurredAt time.Time   `json:"occurred_at"`
+	Data       interface{} `json:"data,omitempty"` // the underlying activity, note, segment or order
+}
+
+// TimelineFilter selects timeline entry types and a page; no types means all
+type TimelineFilter struct {
+	Types []string
+	Page  int
+	Limit int
+}
+
+// Includes reports whether entries of type t are requested
+func (f TimelineFilter) Includes(t string) bool {
+	if len(f.Types) == 0 {
+		return true
+	}
+	for _, timelineType := range f.Types {
+		if timelineType == t {
+			return true
+		}
+	}
+	return false
+}
+
+// Depth is the number of entries each source must provide to build the page
+func (f TimelineFilter) Depth() int {
+	return f.Page * f.Limit
+}
+
+// Timeline is a page of the customer 360 timeline
+type Timeline struct {
+	Entries []TimelineEntry `json:"entries"`
+	Page    int             `json:"page"`
+	Limit   int             `json:"limit"`
+	Total   int64           `json:"total"`
+	// Unavailable lists requested types whose source could not be reached;
+	// they are missing from Entries and Total
+	Unavailable []string `json:"unavailable,omitempty"`
+}
+
+// SortTimeline orders entries newest first
+func SortTimeline(entries []TimelineEntry) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].OccurredAt.Equal(entries[j].OccurredAt) {
+			return entries[i].ID > entries[j].ID
+		}
+		return entries[i].OccurredAt.After(entries[j].OccurredAt)
+	})
+}
+
+// TimelinePage sorts entries newest first and returns the filter's page
+func TimelinePage(entries []TimelineEntry, filter TimelineFilter) []TimelineEntry {
+	SortTimeline(entries)
+
+	start := (filter.Page - 1) * filter.Limit
+	if start >= len(entries) {
+		return []TimelineEntry{}
+	}
+	end := start + filter.Limit
+	if end > len(entries) {
+		end = len(entries)
+	}
+	return entries[start:end]
+}
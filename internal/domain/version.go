@@ -0,0 +1,7 @@
+package domain
+
+import "errors"
+
+// ErrVersionConflict is returned when a record changed since the version an
+// update was based on, e.g. the same address edited from two devices
+var ErrVersionConflict = errors.New("record was modified by another request")
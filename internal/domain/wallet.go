@@ -0,0 +1,141 @@
+package domain
+
+import (
+	"errors"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Store credit / wallet errors
+var (
+	ErrInvalidWalletAmount     = errors.New("amount must be greater than zero with at most 2 decimal places")
+	ErrInsufficientStoreCredit = errors.New("insufficient store credit")
+	ErrReservationNotFound     = errors.New("wallet reservation not found")
+	ErrReservationNotPending   = errors.New("wallet reservation is no longer pending")
+)
+
+// Wallet transaction types
+const (
+	WalletTxnCredit  = "credit"  // admin grant, refund to store credit
+	WalletTxnDebit   = "debit"   // admin deduction
+	WalletTxnReserve = "reserve" // held for an order, not yet spent
+	WalletTxnCapture = "capture" // reserved credit spent by an order
+	WalletTxnRelease = "release" // reserved credit returned to the customer
+)
+
+// ValidWalletAmount reports whether amount is positive and in whole cents,
+// as the ledger stores amounts as decimal(12,2)
+func ValidWalletAmount(amount float64) bool {
+	cents := amount * 100
+	return amount > 0 && math.Abs(cents-math.Round(cents)) < 1e-6
+}
+
+// RoundCents rounds a wallet balance to whole cents, so float arithmetic on
+// balances doesn't drift from what the ledger stores
+func RoundCents(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
+
+// Wallet reservation statuses
+const (
+	ReservationPending  = "pending"
+	ReservationCaptured = "captured"
+	ReservationReleased = "released"
+)
+
+// CustomerWallet holds a customer's store credit balance.
+// Balance is the total credit owned by the customer; Reserved is the part of
+// it currently held for orders that have not been captured or released yet.
+type CustomerWallet struct {
+	ID         uuid.UUID `gorm:"type:uuid;primary_key" json:"id"`
+	CustomerID uuid.UUID `gorm:"type:uuid;not null;uniqueIndex" json:"customer_id"`
+	Balance    float64   `gorm:"type:decimal(12,2);not null;default:0" json:"balance"`
+	Reserved   float64   `gorm:"type:decimal(12,2);not null;default:0" json:"reserved"`
+	Currency   string    `gorm:"type:varchar(3);not null;default:'MYR'" json:"currency"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+func (w *CustomerWallet) BeforeCreate(tx *gorm.DB) error {
+	if w.ID == uuid.Nil {
+		w.ID = uuid.New()
+	}
+	return nil
+}
+
+func (CustomerWallet) TableName() string {
+	return "customer.customer_wallets"
+}
+
+// Available returns the credit that can still be spent or reserved
+func (w *CustomerWallet) Available() float64 {
+	return w.Balance - w.Reserved
+}
+
+// WalletTransaction is an immutable ledger entry for a wallet movement
+type WalletTransaction struct {
+	ID            uuid.UUID  `gorm:"type:uuid;primary_key" json:"id"`
+	WalletID      uuid.UUID  `gorm:"type:uuid;not null;index" json:"wallet_id"`
+	CustomerID    uuid.UUID  `gorm:"type:uuid;not null;index" json:"customer_id"`
+	Type          string     `gorm:"type:varchar(20);not null" json:"type"`
+	Amount        float64    `gorm:"type:decimal(12,2);not null" json:"amount"`
+	BalanceAfter  float64    `gorm:"type:decimal(12,2);not null" json:"balance_after"`
+	ReservedAfter float64    `gorm:"type:decimal(12,2);not null" json:"reserved_after"`
+	Reason        string     `gorm:"type:varchar(255)" json:"reason,omitempty"`
+	Reference     string     `gorm:"type:varchar(100);index" json:"reference,omitempty"` // e.g. order number
+	ReservationID *uuid.UUID `gorm:"type:uuid;index" json:"reservation_id,omitempty"`
+	CreatedBy     *uuid.UUID `gorm:"type:uuid" json:"created_by,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+func (t *WalletTransaction) BeforeCreate(tx *gorm.DB) error {
+	if t.ID == uuid.Nil {
+		t.ID = uuid.New()
+	}
+	return nil
+}
+
+func (WalletTransaction) TableName() string {
+	return "customer.wallet_transactions"
+}
+
+// WalletReservation is credit held for an order until it is captured or released
+type WalletReservation struct {
+	ID         uuid.UUID  `gorm:"type:uuid;primary_key" json:"id"`
+	WalletID   uuid.UUID  `gorm:"type:uuid;not null;index" json:"wallet_id"`
+	CustomerID uuid.UUID  `gorm:"type:uuid;not null;index" json:"customer_id"`
+	Reference  string     `gorm:"type:varchar(100);not null;index" json:"reference"`
+	Amount     float64    `gorm:"type:decimal(12,2);not null" json:"amount"`
+	Status     string     `gorm:"type:varchar(20);not null;default:'pending'" json:"status"`
+	CapturedAt *time.Time `json:"captured_at,omitempty"`
+	ReleasedAt *time.Time `json:"released_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+func (r *WalletReservation) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == uuid.Nil {
+		r.ID = uuid.New()
+	}
+	return nil
+}
+
+func (WalletReservation) TableName() string {
+	return "customer.wallet_reservations"
+}
+
+// WalletAdjustmentRequest is the admin request body for granting or deducting credit
+type WalletAdjustmentRequest struct {
+	Amount float64 `json:"amount" binding:"required,gt=0"`
+	Reason string  `json:"reason" binding:"required"`
+}
+
+// WalletReserveRequest is the internal request body used by the order service
+type WalletReserveRequest struct {
+	CustomerID uuid.UUID `json:"customer_id" binding:"required"`
+	Amount     float64   `json:"amount" binding:"required,gt=0"`
+	Reference  string    `json:"reference" binding:"required"`
+}
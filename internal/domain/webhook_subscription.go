@@ -0,0 +1,182 @@
+package domain
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Customer events delivered to webhook subscriptions
+const (
+	WebhookEventCustomerCreated        = "customer.created"
+	WebhookEventCustomerUpdated        = "customer.updated"
+	WebhookEventCustomerSegmentChanged = "customer.segment_changed"
+)
+
+// WebhookEventTypes lists the events a subscription can receive
+var WebhookEventTypes = []string{
+	WebhookEventCustomerCreated,
+	WebhookEventCustomerUpdated,
+	WebhookEventCustomerSegmentChanged,
+}
+
+// Outbound webhook delivery states
+const (
+	OutboundWebhookPending   = "pending"
+	OutboundWebhookDelivered = "delivered"
+	OutboundWebhookFailed    = "failed" // the retry policy gave up
+)
+
+// WebhookSubscription sends customer events to an external system, such as
+// a CRM without NATS access. Each delivery is signed with Secret like the
+// inventory webhook (see WebhookSignature).
+type WebhookSubscription struct {
+	ID         uuid.UUID  `gorm:"type:uuid;primary_key" json:"id"`
+	Name       string     `gorm:"type:varchar(100);not null" json:"name"`
+	URL        string     `gorm:"type:varchar(500);not null" json:"url"`
+	Secret     string     `gorm:"type:varchar(100);not null" json:"-"`
+	EventTypes []string   `gorm:"type:jsonb;serializer:json" json:"event_types"`
+	IsActive   bool       `gorm:"default:true" json:"is_active"`
+	CreatedBy  *uuid.UUID `gorm:"type:uuid" json:"created_by,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+// BeforeCreate hook to ensure UUID is set
+func (s *WebhookSubscription) BeforeCreate(tx *gorm.DB) error {
+	if s.ID == uuid.Nil {
+		s.ID = uuid.New()
+	}
+	return nil
+}
+
+// TableName specifies the table name for WebhookSubscription
+func (WebhookSubscription) TableName() string {
+	return "customer.webhook_subscriptions"
+}
+
+// Subscribes reports whether the subscription receives eventType
+func (s *WebhookSubscription) Subscribes(eventType string) bool {
+	if !s.IsActive {
+		return false
+	}
+	for _, t := range s.EventTypes {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// CreatedWebhookSubscription is a new subscription with its secret, which is
+// only shown once
+type CreatedWebhookSubscription struct {
+	*WebhookSubscription
+	Secret string `json:"secret"`
+}
+
+// NewWebhookSecret generates a signing secret for a subscription
+func NewWebhookSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(b), nil
+}
+
+// CreateWebhookSubscriptionRequest registers a webhook. A secret is generated
+// when none is given.
+type CreateWebhookSubscriptionRequest struct {
+	Name       string   `json:"name" binding:"required,max=100"`
+	URL        string   `json:"url" binding:"required,url,max=500"`
+	EventTypes []string `json:"event_types" binding:"required,min=1,dive,oneof=customer.created customer.updated customer.segment_changed"`
+	Secret     string   `json:"secret" binding:"omitempty,min=16,max=100"`
+}
+
+// UpdateWebhookSubscriptionRequest changes a webhook; omitted fields are kept
+type UpdateWebhookSubscriptionRequest struct {
+	Name       *string  `json:"name" binding:"omitempty,max=100"`
+	URL        *string  `json:"url" binding:"omitempty,url,max=500"`
+	EventTypes []string `json:"event_types" binding:"omitempty,min=1,dive,oneof=customer.created customer.updated customer.segment_changed"`
+	IsActive   *bool    `json:"is_active"`
+}
+
+// WebhookEvent is the body POSTed to a subscription's URL. ID is the same for
+// every attempt and redelivery, so receivers can drop duplicates.
+type WebhookEvent struct {
+	ID         uuid.UUID   `json:"id"`
+	Type       string      `json:"type"`
+	OccurredAt time.Time   `json:"occurred_at"`
+	Data       interface{} `json:"data"`
+}
+
+// CustomerSegmentsChanged is the data of a customer.segment_changed event
+type CustomerSegmentsChanged struct {
+	CustomerID uuid.UUID   `json:"customer_id"`
+	SegmentIDs []uuid.UUID `json:"segment_ids"` // the customer's segments after the change
+}
+
+// OutboundWebhookDelivery is one event queued for one subscription. The
+// delivery job sends it until it succeeds or the retry policy gives up.
+type OutboundWebhookDelivery struct {
+	ID             uuid.UUID  `gorm:"type:uuid;primary_key" json:"id"`
+	SubscriptionID uuid.UUID  `gorm:"type:uuid;not null;index" json:"subscription_id"`
+	EventID        uuid.UUID  `gorm:"type:uuid;not null;index" json:"event_id"`
+	EventType      string     `gorm:"type:varchar(50);not null" json:"event_type"`
+	Payload        string     `gorm:"type:text;not null" json:"payload"` // the signed JSON body
+	Status         string     `gorm:"type:varchar(20);not null;default:'pending';index:idx_outbound_webhook_deliveries_due,priority:1" json:"status"`
+	Attempts       int        `gorm:"default:0" json:"attempts"`
+	NextAttemptAt  time.Time  `gorm:"index:idx_outbound_webhook_deliveries_due,priority:2" json:"next_attempt_at"`
+	LastStatusCode int        `json:"last_status_code,omitempty"`
+	LastError      string     `gorm:"type:text" json:"last_error,omitempty"`
+	DeliveredAt    *time.Time `json:"delivered_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// BeforeCreate hook to ensure UUID is set
+func (d *OutboundWebhookDelivery) BeforeCreate(tx *gorm.DB) error {
+	if d.ID == uuid.Nil {
+		d.ID = uuid.New()
+	}
+	return nil
+}
+
+// TableName specifies the table name for OutboundWebhookDelivery
+func (OutboundWebhookDelivery) TableName() string {
+	return "customer.outbound_webhook_deliveries"
+}
+
+// OutboundWebhookAttempt logs one attempt to send a delivery
+type OutboundWebhookAttempt struct {
+	ID           uuid.UUID `gorm:"type:uuid;primary_key" json:"id"`
+	DeliveryID   uuid.UUID `gorm:"type:uuid;not null;index" json:"delivery_id"`
+	StatusCode   int       `json:"status_code,omitempty"` // 0 when no response was received
+	Error        string    `gorm:"type:text" json:"error,omitempty"`
+	ResponseBody string    `gorm:"type:text" json:"response_body,omitempty"` // truncated
+	DurationMs   int64     `json:"duration_ms"`
+	AttemptedAt  time.Time `gorm:"not null" json:"attempted_at"`
+}
+
+// BeforeCreate hook to ensure UUID is set
+func (a *OutboundWebhookAttempt) BeforeCreate(tx *gorm.DB) error {
+	if a.ID == uuid.Nil {
+		a.ID = uuid.New()
+	}
+	return nil
+}
+
+// TableName specifies the table name for OutboundWebhookAttempt
+func (OutboundWebhookAttempt) TableName() string {
+	return "customer.outbound_webhook_attempts"
+}
+
+// OutboundWebhookDeliveryDetail is a delivery with its attempt log, newest
+// attempt first
+type OutboundWebhookDeliveryDetail struct {
+	OutboundWebhookDelivery
+	Log []OutboundWebhookAttempt `json:"log"`
+}
@@ -30,11 +30,19 @@ type WishlistItem struct {
 	PriceAtAdd   float64 `gorm:"type:decimal(10,2);default:0" json:"price_at_add"`
 	NotifyOnSale bool    `gorm:"default:false" json:"notify_on_sale"`
 
+	// Subscribe to back-in-stock when the product goes out of stock
+	NotifyRestock bool `gorm:"default:false" json:"notify_restock"`
+
 	// Denormalized product info for display without joining
 	ProductName  *string `gorm:"type:varchar(255)" json:"product_name,omitempty"`
 	ProductSlug  *string `gorm:"type:varchar(255)" json:"product_slug,omitempty"`
 	ProductImage *string `gorm:"type:varchar(500)" json:"product_image,omitempty"`
 
+	// Retention: the customer is asked whether they still want stale items
+	// and unconfirmed ones are archived (WishlistRetentionPolicy)
+	NudgedAt    *time.Time `json:"nudged_at,omitempty"`
+	ConfirmedAt *time.Time `json:"confirmed_at,omitempty"`
+
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
@@ -59,3 +67,42 @@ func (w *WishlistItem) GetUniqueKey() string {
 	}
 	return w.ProductID.String() + "-nil"
 }
+
+// MaxMembershipChecks bounds the products one batch membership check takes,
+// e.g. a page of the product grid
+const MaxMembershipChecks = 100
+
+// ProductRef identifies a product, or one variant of it, in a batch
+// membership check
+type ProductRef struct {
+	ProductID uuid.UUID  `json:"product_id" binding:"required"`
+	VariantID *uuid.UUID `json:"variant_id"`
+}
+
+// Key is the product's key in a membership map: the product ID, or
+// "<product ID>:<variant ID>" for a variant
+func (r ProductRef) Key() string {
+	if r.VariantID == nil {
+		return r.ProductID.String()
+	}
+	return r.ProductID.String() + ":" + r.VariantID.String()
+}
+
+// MembershipCheckRequest lists the products to check, at most
+// MaxMembershipChecks
+type MembershipCheckRequest struct {
+	Items []ProductRef `json:"items" binding:"required,min=1,max=100,dive"`
+}
+
+// ProductIDs returns the distinct product IDs of refs
+func ProductIDs(refs []ProductRef) []uuid.UUID {
+	seen := make(map[uuid.UUID]bool, len(refs))
+	ids := make([]uuid.UUID, 0, len(refs))
+	for _, ref := range refs {
+		if !seen[ref.ProductID] {
+			seen[ref.ProductID] = true
+			ids = append(ids, ref.ProductID)
+		}
+	}
+	return ids
+}
@@ -0,0 +1,161 @@
+package domain
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// Wishlist size limits
+const (
+	DefaultWishlistMaxItems = 500 // items per customer
+	MaxWishlistImportRows   = 200 // rows per import file
+)
+
+// How a wishlist import row identifies its product
+const (
+	WishlistImportBySKU       = "sku"
+	WishlistImportBySlug      = "slug"       // from a product URL
+	WishlistImportByProductID = "product_id" // from a product URL ending in the product ID
+)
+
+// Wishlist import row statuses
+const (
+	WishlistImportAdded         = "added"
+	WishlistImportNotFound      = "not_found"
+	WishlistImportDuplicate     = "duplicate" // already in the wishlist or earlier in the file
+	WishlistImportInvalid       = "invalid"
+	WishlistImportQuotaExceeded = "quota_exceeded"
+)
+
+// Wishlist import errors
+var (
+	ErrWishlistImportEmpty    = errors.New("import file has no SKUs or product URLs")
+	ErrWishlistImportTooLarge = fmt.Errorf("import file has more than %d rows", MaxWishlistImportRows)
+	ErrWishlistFull           = errors.New("wishlist is full")
+)
+
+// skuPattern matches catalog SKUs, which fit WishlistItem.VariantSKU
+var skuPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,49}$`)
+
+// importHeaders are first-row values treated as a header rather than a SKU
+var importHeaders = map[string]bool{"sku": true, "skus": true, "url": true, "urls": true, "product": true, "product_url": true}
+
+// WishlistImportEntry is one row of a wishlist import file. Kind is empty
+// when the value is neither a SKU nor a product URL.
+type WishlistImportEntry struct {
+	Row       int
+	Value     string
+	Kind      string
+	Key       string // SKU or slug
+	ProductID uuid.UUID
+}
+
+// WishlistImportResult is the outcome of one import row
+type WishlistImportResult struct {
+	Row         int        `json:"row"`
+	Value       string     `json:"value"`
+	Status      string     `json:"status"`
+	ProductID   *uuid.UUID `json:"product_id,omitempty"`
+	VariantID   *uuid.UUID `json:"variant_id,omitempty"`
+	ProductName string     `json:"product_name,omitempty"`
+	Message     string     `json:"message,omitempty"`
+}
+
+// WishlistImportSummary is the outcome of a wishlist import
+type WishlistImportSummary struct {
+	Added         int                    `json:"added"`
+	NotFound      int                    `json:"not_found"`
+	Duplicate     int                    `json:"duplicate"`
+	Invalid       int                    `json:"invalid"`
+	QuotaExceeded int                    `json:"quota_exceeded"`
+	Results       []WishlistImportResult `json:"results"`
+}
+
+// Add records a row result and counts it by status
+func (s *WishlistImportSummary) Add(result WishlistImportResult) {
+	switch result.Status {
+	case WishlistImportAdded:
+		s.Added++
+	case WishlistImportNotFound:
+		s.NotFound++
+	case WishlistImportDuplicate:
+		s.Duplicate++
+	case WishlistImportInvalid:
+		s.Invalid++
+	case WishlistImportQuotaExceeded:
+		s.QuotaExceeded++
+	}
+	s.Results = append(s.Results, result)
+}
+
+// ParseWishlistImport reads a CSV (or plain list) of SKUs and product URLs,
+// one per row in the first column. Blank rows and a header row are skipped.
+func ParseWishlistImport(r io.Reader) ([]WishlistImportEntry, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	var entries []WishlistImportEntry
+	for row := 1; ; row++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid CSV: %w", err)
+		}
+
+		value := strings.TrimSpace(strings.TrimPrefix(record[0], "\ufeff"))
+		if value == "" || (row == 1 && importHeaders[strings.ToLower(value)]) {
+			continue
+		}
+		if len(entries) == MaxWishlistImportRows {
+			return nil, ErrWishlistImportTooLarge
+		}
+
+		entry := WishlistImportEntry{Row: row, Value: value}
+		entry.Kind, entry.Key, entry.ProductID = parseImportValue(value)
+		entries = append(entries, entry)
+	}
+
+	if len(entries) == 0 {
+		return nil, ErrWishlistImportEmpty
+	}
+	return entries, nil
+}
+
+// parseImportValue classifies a row value. Product URLs are identified by
+// their last path segment, e.g. https://shop.example.com/products/baju-kurung.
+func parseImportValue(value string) (kind, key string, productID uuid.UUID) {
+	if !strings.Contains(value, "/") {
+		if skuPattern.MatchString(value) {
+			return WishlistImportBySKU, value, uuid.Nil
+		}
+		return "", "", uuid.Nil
+	}
+
+	parsed, err := url.Parse(value)
+	if err != nil {
+		return "", "", uuid.Nil
+	}
+	if parsed.Scheme != "" && parsed.Scheme != "http" && parsed.Scheme != "https" {
+		return "", "", uuid.Nil
+	}
+
+	segments := strings.Split(strings.Trim(parsed.Path, "/"), "/")
+	last := strings.TrimSuffix(segments[len(segments)-1], ".html")
+	if last == "" {
+		return "", "", uuid.Nil
+	}
+	if id, err := uuid.Parse(last); err == nil {
+		return WishlistImportByProductID, "", id
+	}
+	return WishlistImportBySlug, strings.ToLower(last), uuid.Nil
+}
@@ -0,0 +1,84 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// WishlistRetentionPolicy decides when a wishlist item is stale enough to
+// ask the customer whether they still want it, and how long they have to
+// confirm before it is archived
+type WishlistRetentionPolicy struct {
+	StaleMonths int // months since the item was added or last confirmed
+	ConfirmDays int // days after the nudge before an unconfirmed item is archived
+}
+
+// StaleBefore returns the time before which an added or confirmed item is stale
+func (p WishlistRetentionPolicy) StaleBefore(now time.Time) time.Time {
+	return now.AddDate(0, -p.StaleMonths, 0)
+}
+
+// ArchiveAt returns when an item nudged at nudgedAt is archived unless the
+// customer confirms it
+func (p WishlistRetentionPolicy) ArchiveAt(nudgedAt time.Time) time.Time {
+	return nudgedAt.AddDate(0, 0, p.ConfirmDays)
+}
+
+// ArchiveBefore returns the time before which nudged items are archived at now
+func (p WishlistRetentionPolicy) ArchiveBefore(now time.Time) time.Time {
+	return now.AddDate(0, 0, -p.ConfirmDays)
+}
+
+// ArchivedWishlistItem is a wishlist item the customer didn't confirm they
+// still wanted. It keeps the item's ID.
+type ArchivedWishlistItem struct {
+	ID           uuid.UUID  `gorm:"type:uuid;primary_key" json:"id"`
+	UserID       uuid.UUID  `gorm:"type:uuid;not null;index" json:"user_id"`
+	ProductID    uuid.UUID  `gorm:"type:uuid;not null" json:"product_id"`
+	VariantID    *uuid.UUID `gorm:"type:uuid" json:"variant_id,omitempty"`
+	VariantSKU   *string    `gorm:"type:varchar(50)" json:"variant_sku,omitempty"`
+	VariantName  *string    `gorm:"type:varchar(100)" json:"variant_name,omitempty"`
+	PriceAtAdd   float64    `gorm:"type:decimal(10,2);default:0" json:"price_at_add"`
+	ProductName  *string    `gorm:"type:varchar(255)" json:"product_name,omitempty"`
+	ProductSlug  *string    `gorm:"type:varchar(255)" json:"product_slug,omitempty"`
+	ProductImage *string    `gorm:"type:varchar(500)" json:"product_image,omitempty"`
+	AddedAt      time.Time  `json:"added_at"`
+	NudgedAt     time.Time  `json:"nudged_at"`
+	ArchivedAt   time.Time  `gorm:"index" json:"archived_at"`
+}
+
+// TableName specifies the table name for ArchivedWishlistItem
+func (ArchivedWishlistItem) TableName() string {
+	return "customer.archived_wishlist_items"
+}
+
+// NewArchivedWishlistItem archives a nudged wishlist item at now
+func NewArchivedWishlistItem(item WishlistItem, now time.Time) ArchivedWishlistItem {
+	archived := ArchivedWishlistItem{
+		ID:           item.ID,
+		UserID:       item.UserID,
+		ProductID:    item.ProductID,
+		VariantID:    item.VariantID,
+		VariantSKU:   item.VariantSKU,
+		VariantName:  item.VariantName,
+		PriceAtAdd:   item.PriceAtAdd,
+		ProductName:  item.ProductName,
+		ProductSlug:  item.ProductSlug,
+		ProductImage: item.ProductImage,
+		AddedAt:      item.CreatedAt,
+		ArchivedAt:   now,
+	}
+	if item.NudgedAt != nil {
+		archived.NudgedAt = *item.NudgedAt
+	}
+	return archived
+}
+
+// WishlistRetentionResult counts the customers asked about stale items, the
+// items they were asked about and the unconfirmed items archived
+type WishlistRetentionResult struct {
+	Customers int
+	Nudged    int
+	Archived  int
+}
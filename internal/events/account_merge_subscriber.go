@@ -0,0 +1,107 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nats-io/nats.go"
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/metrics"
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/persistence"
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/tracing"
+	"go.uber.org/zap"
+)
+
+// AccountsMergedEvent is published by the auth service when two identities
+// are merged into one
+type AccountsMergedEvent struct {
+	PrimaryUserID   string `json:"primary_user_id"`
+	SecondaryUserID string `json:"secondary_user_id"`
+}
+
+// AccountMergeSubscriber remaps customer data when accounts are merged
+type AccountMergeSubscriber struct {
+	nc        *nats.Conn
+	mergeRepo *persistence.AccountMergeRepository
+	logger    *zap.Logger
+}
+
+// NewAccountMergeSubscriber creates a new subscriber
+func NewAccountMergeSubscriber(
+	nc *nats.Conn,
+	mergeRepo *persistence.AccountMergeRepository,
+	logger *zap.Logger,
+) *AccountMergeSubscriber {
+	return &AccountMergeSubscriber{
+		nc:        nc,
+		mergeRepo: mergeRepo,
+		logger:    logger,
+	}
+}
+
+// Subscribe starts listening for account merge events
+func (s *AccountMergeSubscriber) Subscribe() error {
+	_, err := s.nc.Subscribe("auth.accounts.merged", func(msg *nats.Msg) {
+		ctx, span := tracing.StartConsume(msg)
+		err := s.handleAccountsMergedEvent(ctx, msg.Data)
+		tracing.End(span, err)
+		metrics.RecordMessage(msg.Subject, err)
+	})
+	if err != nil {
+		s.logger.Error("Failed to subscribe to auth.accounts.merged", zap.Error(err))
+		return err
+	}
+
+	s.logger.Info("Subscribed to auth.accounts.merged events")
+	return nil
+}
+
+// handleAccountsMergedEvent processes an accounts merged event
+func (s *AccountMergeSubscriber) handleAccountsMergedEvent(ctx context.Context, data []byte) error {
+	var event AccountsMergedEvent
+	if err := json.Unmarshal(data, &event); err != nil {
+		s.logger.Error("Failed to unmarshal accounts merged event", zap.Error(err))
+		return err
+	}
+
+	primaryID, err := uuid.Parse(event.PrimaryUserID)
+	if err != nil {
+		s.logger.Error("Invalid primary user ID in event", zap.Error(err))
+		return err
+	}
+	secondaryID, err := uuid.Parse(event.SecondaryUserID)
+	if err != nil {
+		s.logger.Error("Invalid secondary user ID in event", zap.Error(err))
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	record, merged, err := s.mergeRepo.Merge(ctx, primaryID, secondaryID)
+	if err != nil {
+		s.logger.Error("Failed to merge customer accounts",
+			zap.String("primary_user_id", event.PrimaryUserID),
+			zap.String("secondary_user_id", event.SecondaryUserID),
+			zap.Error(err))
+		return err
+	}
+
+	if !merged {
+		s.logger.Info("Accounts already merged, skipping",
+			zap.String("secondary_user_id", event.SecondaryUserID),
+			zap.Time("merged_at", record.MergedAt))
+		return nil
+	}
+
+	s.logger.Info("Merged customer accounts",
+		zap.String("primary_user_id", event.PrimaryUserID),
+		zap.String("secondary_user_id", event.SecondaryUserID),
+		zap.Int("wishlist_items", record.WishlistItems),
+		zap.Int("subscriptions", record.Subscriptions),
+		zap.Int("addresses", record.Addresses),
+		zap.Int("measurements", record.Measurements),
+		zap.Int("notes", record.Notes))
+	return nil
+}
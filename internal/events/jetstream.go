@@ -0,0 +1,115 @@
+package events
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+// errMalformedEvent marks events that can never be processed, so they are
+// dead-lettered right away instead of being redelivered
+var errMalformedEvent = errors.New("malformed event")
+
+// JetStreamConsumer configures a durable JetStream consumer
+type JetStreamConsumer struct {
+	Stream            string // created for the subject if no stream captures it yet
+	Durable           string // also used as the queue group, so replicas share the work
+	MaxDeliver        int
+	Backoff           []time.Duration // redelivery delays by attempt; the last one repeats
+	DeadLetterSubject string
+}
+
+// Dead-letter message headers
+const (
+	HeaderDeadLetterSubject    = "X-Original-Subject"
+	HeaderDeadLetterError      = "X-Error"
+	HeaderDeadLetterDeliveries = "X-Deliveries"
+)
+
+// ensureStream creates a file-backed stream for subject unless an existing
+// stream already captures it (e.g. one owned by the publishing service)
+func ensureStream(js nats.JetStreamContext, name, subject string) error {
+	_, err := js.StreamNameBySubject(subject)
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, nats.ErrNoMatchingStream) {
+		return err
+	}
+
+	_, err = js.AddStream(&nats.StreamConfig{
+		Name:     name,
+		Subjects: []string{subject},
+		Storage:  nats.FileStorage,
+	})
+	return err
+}
+
+// subscribeOptions returns the durable, explicitly acked consumer options.
+// JetStream requires MaxDeliver to exceed the number of backoff steps.
+func (c JetStreamConsumer) subscribeOptions() []nats.SubOpt {
+	backoff := c.Backoff
+	if c.MaxDeliver > 0 && len(backoff) >= c.MaxDeliver {
+		backoff = backoff[:c.MaxDeliver-1]
+	}
+
+	opts := []nats.SubOpt{
+		nats.Durable(c.Durable),
+		nats.ManualAck(),
+		nats.AckExplicit(),
+		nats.MaxDeliver(c.MaxDeliver),
+		// A new consumer starts with new events rather than replaying the stream
+		nats.DeliverNew(),
+	}
+	if len(backoff) > 0 {
+		opts = append(opts, nats.BackOff(backoff))
+	}
+	return opts
+}
+
+// retryDelay returns how long to wait before redelivering after the given attempt
+func (c JetStreamConsumer) retryDelay(delivered uint64) time.Duration {
+	if len(c.Backoff) == 0 {
+		return 0
+	}
+	i := int(delivered) - 1
+	if i < 0 {
+		i = 0
+	}
+	if i >= len(c.Backoff) {
+		i = len(c.Backoff) - 1
+	}
+	return c.Backoff[i]
+}
+
+// finish acks a processed message. A failed one is retried with backoff, or
+// dead-lettered and terminated if it is malformed or out of deliveries.
+func (c JetStreamConsumer) finish(nc *nats.Conn, msg *nats.Msg, err error) error {
+	if err == nil {
+		return msg.Ack()
+	}
+
+	var delivered uint64 = 1
+	if meta, metaErr := msg.Metadata(); metaErr == nil {
+		delivered = meta.NumDelivered
+	}
+
+	if !errors.Is(err, errMalformedEvent) && (c.MaxDeliver <= 0 || delivered < uint64(c.MaxDeliver)) {
+		return msg.NakWithDelay(c.retryDelay(delivered))
+	}
+
+	if c.DeadLetterSubject != "" {
+		dead := nats.NewMsg(c.DeadLetterSubject)
+		dead.Data = msg.Data
+		dead.Header.Set(HeaderDeadLetterSubject, msg.Subject)
+		dead.Header.Set(HeaderDeadLetterError, err.Error())
+		dead.Header.Set(HeaderDeadLetterDeliveries, strconv.FormatUint(delivered, 10))
+		if pubErr := nc.PublishMsg(dead); pubErr != nil {
+			return fmt.Errorf("publish to dead-letter subject: %w", pubErr)
+		}
+	}
+	return msg.Term()
+}
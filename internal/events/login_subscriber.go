@@ -0,0 +1,113 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nats-io/nats.go"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/metrics"
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/persistence"
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/tracing"
+	"go.uber.org/zap"
+)
+
+// UserLoggedInSubject is the subject auth publishes successful sign-ins on
+const UserLoggedInSubject = "auth.user.logged_in"
+
+// UserLoggedInEvent is published by the auth service when a customer signs in
+type UserLoggedInEvent struct {
+	UserID     string    `json:"user_id"`
+	Method     string    `json:"method,omitempty"` // e.g. password, google, otp
+	IPAddress  string    `json:"ip_address,omitempty"`
+	UserAgent  string    `json:"user_agent,omitempty"`
+	LoggedInAt time.Time `json:"logged_in_at"`
+}
+
+// LoginSubscriber records sign-ins on the customer's activity timeline, where
+// they show up in the customer's security log
+type LoginSubscriber struct {
+	nc       *nats.Conn
+	activity *persistence.ActivityRepository
+	logger   *zap.Logger
+}
+
+// NewLoginSubscriber creates a new subscriber
+func NewLoginSubscriber(
+	nc *nats.Conn,
+	activity *persistence.ActivityRepository,
+	logger *zap.Logger,
+) *LoginSubscriber {
+	return &LoginSubscriber{
+		nc:       nc,
+		activity: activity,
+		logger:   logger,
+	}
+}
+
+// Subscribe starts listening for user logged in events. Replicas share a queue
+// group so each sign-in is recorded once.
+func (s *LoginSubscriber) Subscribe() error {
+	_, err := s.nc.QueueSubscribe(UserLoggedInSubject, "customer-login-activity", func(msg *nats.Msg) {
+		ctx, span := tracing.StartConsume(msg)
+		err := s.handleUserLoggedInEvent(ctx, msg.Data)
+		tracing.End(span, err)
+		metrics.RecordMessage(msg.Subject, err)
+	})
+	if err != nil {
+		s.logger.Error("Failed to subscribe to "+UserLoggedInSubject, zap.Error(err))
+		return err
+	}
+
+	s.logger.Info("Subscribed to " + UserLoggedInSubject + " events")
+	return nil
+}
+
+// handleUserLoggedInEvent records the sign-in with the device it came from
+func (s *LoginSubscriber) handleUserLoggedInEvent(ctx context.Context, data []byte) error {
+	var event UserLoggedInEvent
+	if err := json.Unmarshal(data, &event); err != nil {
+		s.logger.Error("Failed to unmarshal user logged in event", zap.Error(err))
+		return err
+	}
+
+	userID, err := uuid.Parse(event.UserID)
+	if err != nil {
+		s.logger.Error("Invalid user ID in event", zap.Error(err))
+		return err
+	}
+
+	loggedInAt := event.LoggedInAt
+	if loggedInAt.IsZero() {
+		loggedInAt = time.Now()
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	err = s.activity.Record(ctx, &domain.CustomerActivity{
+		CustomerID: userID,
+		Type:       domain.ActivityTypeLogin,
+		Title:      "Signed in",
+		Details:    event.Method,
+		IPAddress:  truncate(event.IPAddress, 45),
+		UserAgent:  truncate(event.UserAgent, 500),
+		CreatedAt:  loggedInAt,
+	})
+	if err != nil {
+		s.logger.Error("Failed to record sign-in",
+			zap.String("user_id", event.UserID),
+			zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+func truncate(s string, n int) string {
+	if len(s) > n {
+		return s[:n]
+	}
+	return s
+}
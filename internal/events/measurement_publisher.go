@@ -0,0 +1,71 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/tracing"
+	"go.uber.org/zap"
+)
+
+// SubjectMeasurementSizeChanged is published when a customer's derived standard size changes
+const SubjectMeasurementSizeChanged = "customer.measurement.size_changed"
+
+// MeasurementSizeChangedEvent is consumed by the notification service to tell the
+// customer that previously recommended sizes may no longer fit
+type MeasurementSizeChangedEvent struct {
+	CustomerID      string    `json:"customer_id"`
+	MeasurementID   string    `json:"measurement_id"`
+	MeasurementName string    `json:"measurement_name,omitempty"`
+	PreviousSize    string    `json:"previous_size"`
+	NewSize         string    `json:"new_size,omitempty"`
+	OccurredAt      time.Time `json:"occurred_at"`
+}
+
+// MeasurementEventPublisher publishes measurement events to NATS
+type MeasurementEventPublisher struct {
+	nc     *nats.Conn
+	logger *zap.Logger
+}
+
+// NewMeasurementEventPublisher creates a new measurement event publisher
+func NewMeasurementEventPublisher(nc *nats.Conn, logger *zap.Logger) *MeasurementEventPublisher {
+	return &MeasurementEventPublisher{
+		nc:     nc,
+		logger: logger,
+	}
+}
+
+// NotifySizeChanged publishes a size changed event for the measurement
+func (p *MeasurementEventPublisher) NotifySizeChanged(ctx context.Context, measurement *domain.CustomerMeasurement, previousSize string) error {
+	event := MeasurementSizeChangedEvent{
+		CustomerID:    measurement.UserID.String(),
+		MeasurementID: measurement.ID.String(),
+		PreviousSize:  previousSize,
+		OccurredAt:    time.Now().UTC(),
+	}
+	if measurement.Name != nil {
+		event.MeasurementName = *measurement.Name
+	}
+	if measurement.StandardSize != nil {
+		event.NewSize = *measurement.StandardSize
+	}
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	if err := tracing.Publish(ctx, p.nc, SubjectMeasurementSizeChanged, data); err != nil {
+		return err
+	}
+
+	p.logger.Info("Published measurement size changed event",
+		zap.String("customer_id", event.CustomerID),
+		zap.String("previous_size", event.PreviousSize),
+		zap.String("new_size", event.NewSize))
+	return nil
+}
@@ -0,0 +1,77 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/tracing"
+	"go.uber.org/zap"
+)
+
+// SubjectNoteMentioned is published when staff are @mentioned in a customer note
+const SubjectNoteMentioned = "customer.note.mentioned"
+
+// noteExcerptLength bounds the note text carried in mention events
+const noteExcerptLength = 200
+
+// NoteMentionedEvent is consumed by the notification service, which resolves
+// the handles to staff accounts and notifies them
+type NoteMentionedEvent struct {
+	NoteID     string    `json:"note_id"`
+	CustomerID string    `json:"customer_id"`
+	AuthorID   string    `json:"author_id"`
+	Mentions   []string  `json:"mentions"`
+	Excerpt    string    `json:"excerpt"`
+	IsPrivate  bool      `json:"is_private"`
+	OccurredAt time.Time `json:"occurred_at"`
+}
+
+// NoteEventPublisher publishes customer note events to NATS
+type NoteEventPublisher struct {
+	nc     *nats.Conn
+	logger *zap.Logger
+}
+
+// NewNoteEventPublisher creates a new note event publisher
+func NewNoteEventPublisher(nc *nats.Conn, logger *zap.Logger) *NoteEventPublisher {
+	return &NoteEventPublisher{
+		nc:     nc,
+		logger: logger,
+	}
+}
+
+// NotifyMentioned publishes a mention event for the given handles
+func (p *NoteEventPublisher) NotifyMentioned(ctx context.Context, note *domain.CustomerNote, authorID string, mentions []string) error {
+	excerpt := []rune(note.Note)
+	if len(excerpt) > noteExcerptLength {
+		excerpt = append(excerpt[:noteExcerptLength], '…')
+	}
+
+	event := NoteMentionedEvent{
+		NoteID:     note.ID.String(),
+		CustomerID: note.CustomerID.String(),
+		AuthorID:   authorID,
+		Mentions:   mentions,
+		Excerpt:    string(excerpt),
+		IsPrivate:  note.IsPrivate,
+		OccurredAt: time.Now().UTC(),
+	}
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	if err := tracing.Publish(ctx, p.nc, SubjectNoteMentioned, data); err != nil {
+		return err
+	}
+
+	p.logger.Info("Published note mentioned event",
+		zap.String("note_id", event.NoteID),
+		zap.String("customer_id", event.CustomerID),
+		zap.Strings("mentions", mentions))
+	return nil
+}
@@ -0,0 +1,58 @@
+package events
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/persistence"
+	"go.uber.org/zap"
+)
+
+// NotificationPolicy applies customers' notification preferences (opt-outs,
+// quiet hours, preferred channel and weekly cap) to every customer
+// notification this service sends, so each notifier doesn't keep its own
+// rules
+type NotificationPolicy struct {
+	prefs  *persistence.NotificationPreferenceRepository
+	logger *zap.Logger
+}
+
+// NewNotificationPolicy creates a new notification policy
+func NewNotificationPolicy(prefs *persistence.NotificationPreferenceRepository, logger *zap.Logger) *NotificationPolicy {
+	return &NotificationPolicy{
+		prefs:  prefs,
+		logger: logger,
+	}
+}
+
+// ShouldNotify decides whether the customer gets a notification of the given
+// type now, and on which channel
+func (p *NotificationPolicy) ShouldNotify(ctx context.Context, customerID uuid.UUID, notificationType string) (domain.NotificationDecision, error) {
+	prefs, err := p.prefs.Get(ctx, customerID)
+	if err != nil {
+		return domain.NotificationDecision{}, err
+	}
+
+	now := time.Now()
+	var sent int64
+	if prefs.MaxPerWeek > 0 {
+		if sent, err = p.prefs.CountSentSince(ctx, customerID, now.Add(-domain.NotificationWeek)); err != nil {
+			return domain.NotificationDecision{}, err
+		}
+	}
+	return prefs.Decide(notificationType, now, sent), nil
+}
+
+// Sent counts a notification that went out toward the customer's weekly cap.
+// Failing to record it is logged rather than returned, as the notification
+// itself was delivered.
+func (p *NotificationPolicy) Sent(ctx context.Context, customerID uuid.UUID, notificationType, channel string) {
+	if err := p.prefs.RecordSent(ctx, customerID, notificationType, channel, time.Now()); err != nil {
+		p.logger.Error("Failed to record notification delivery",
+			zap.String("customer_id", customerID.String()),
+			zap.String("type", notificationType),
+			zap.Error(err))
+	}
+}
@@ -0,0 +1,177 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nats-io/nats.go"
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/metrics"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/persistence"
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/tracing"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// Order subjects published by service-order
+const (
+	OrderCompletedSubject = "order.completed"
+	OrderRefundedSubject  = "order.refunded"
+)
+
+// OrderCompletedEvent is published by service-order when an order is paid
+type OrderCompletedEvent struct {
+	OrderID     string  `json:"order_id"`
+	OrderNumber string  `json:"order_number,omitempty"`
+	CustomerID  string  `json:"customer_id"`
+	Total       float64 `json:"total"`
+}
+
+// OrderRefundedEvent is published by service-order when an order is refunded,
+// fully or in part
+type OrderRefundedEvent struct {
+	OrderID     string  `json:"order_id"`
+	OrderNumber string  `json:"order_number,omitempty"`
+	RefundID    string  `json:"refund_id,omitempty"`
+	CustomerID  string  `json:"customer_id"`
+	Amount      float64 `json:"amount"`
+	FullRefund  bool    `json:"full_refund"`
+}
+
+// OrderSubscriber keeps customers' order totals in step with their orders and
+// applies segment rules when customer milestones happen
+type OrderSubscriber struct {
+	nc        *nats.Conn
+	statsRepo *persistence.OrderStatsRepository
+	ruleRepo  *persistence.SegmentRuleRepository
+	logger    *zap.Logger
+}
+
+// NewOrderSubscriber creates a new subscriber
+func NewOrderSubscriber(
+	nc *nats.Conn,
+	statsRepo *persistence.OrderStatsRepository,
+	ruleRepo *persistence.SegmentRuleRepository,
+	logger *zap.Logger,
+) *OrderSubscriber {
+	return &OrderSubscriber{
+		nc:        nc,
+		statsRepo: statsRepo,
+		ruleRepo:  ruleRepo,
+		logger:    logger,
+	}
+}
+
+// Subscribe starts listening for order completed and refunded events
+func (s *OrderSubscriber) Subscribe() error {
+	handlers := map[string]func(context.Context, []byte) error{
+		OrderCompletedSubject: s.handleOrderCompletedEvent,
+		OrderRefundedSubject:  s.handleOrderRefundedEvent,
+	}
+	for subject, handle := range handlers {
+		_, err := s.nc.Subscribe(subject, func(msg *nats.Msg) {
+			ctx, span := tracing.StartConsume(msg)
+			err := handle(ctx, msg.Data)
+			tracing.End(span, err)
+			metrics.RecordMessage(msg.Subject, err)
+		})
+		if err != nil {
+			s.logger.Error("Failed to subscribe to "+subject, zap.Error(err))
+			return err
+		}
+	}
+
+	s.logger.Info("Subscribed to order.completed and order.refunded events")
+	return nil
+}
+
+// handleOrderCompletedEvent adds the order to the customer's totals, then runs
+// the order_completed segment rules against the updated totals
+func (s *OrderSubscriber) handleOrderCompletedEvent(ctx context.Context, data []byte) error {
+	var event OrderCompletedEvent
+	if err := json.Unmarshal(data, &event); err != nil {
+		s.logger.Error("Failed to unmarshal order completed event", zap.Error(err))
+		return err
+	}
+
+	customerID, err := uuid.Parse(event.CustomerID)
+	if err != nil {
+		s.logger.Error("Invalid customer ID in event", zap.Error(err))
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	entry := domain.OrderCompleted(customerID, event.OrderID, event.OrderNumber, event.Total)
+	if err := s.applyStats(ctx, entry); err != nil {
+		return err
+	}
+
+	evaluation, err := s.ruleRepo.Apply(ctx, customerID, domain.SegmentTriggerOrderCompleted)
+	if err != nil {
+		s.logger.Error("Failed to apply segment rules",
+			zap.String("customer_id", event.CustomerID),
+			zap.String("order_id", event.OrderID),
+			zap.Error(err))
+		return err
+	}
+
+	if len(evaluation.Decisions) > 0 {
+		s.logger.Info("Applied segment rules",
+			zap.String("customer_id", event.CustomerID),
+			zap.Int("rules", len(evaluation.MatchedRules)),
+			zap.Int("changes", len(evaluation.Decisions)))
+	}
+	return nil
+}
+
+// handleOrderRefundedEvent takes the refund off the customer's totals
+func (s *OrderSubscriber) handleOrderRefundedEvent(ctx context.Context, data []byte) error {
+	var event OrderRefundedEvent
+	if err := json.Unmarshal(data, &event); err != nil {
+		s.logger.Error("Failed to unmarshal order refunded event", zap.Error(err))
+		return err
+	}
+
+	customerID, err := uuid.Parse(event.CustomerID)
+	if err != nil {
+		s.logger.Error("Invalid customer ID in event", zap.Error(err))
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	entry := domain.OrderRefunded(customerID, event.OrderID, event.OrderNumber, event.RefundID, event.Amount, event.FullRefund)
+	return s.applyStats(ctx, entry)
+}
+
+// applyStats applies an order stats entry, logging duplicates and customers
+// this service doesn't know
+func (s *OrderSubscriber) applyStats(ctx context.Context, entry *domain.OrderStatsEntry) error {
+	applied, err := s.statsRepo.Apply(ctx, entry)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		s.logger.Warn("Order for unknown customer; run backfill-customer-stats once the customer exists",
+			zap.String("customer_id", entry.CustomerID.String()),
+			zap.String("order_id", entry.OrderID))
+		return err
+	}
+	if err != nil {
+		s.logger.Error("Failed to update customer order stats",
+			zap.String("customer_id", entry.CustomerID.String()),
+			zap.String("order_id", entry.OrderID),
+			zap.String("kind", entry.Kind),
+			zap.Error(err))
+		return err
+	}
+
+	if !applied {
+		s.logger.Info("Order event already applied",
+			zap.String("event_key", entry.EventKey))
+	}
+	return nil
+}
@@ -0,0 +1,186 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nats-io/nats.go"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/metrics"
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/persistence"
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/tracing"
+	"go.uber.org/zap"
+)
+
+// OutOfStockSubject is the subject inventory publishes sold-out products on
+const OutOfStockSubject = "inventory.product.out_of_stock"
+
+// ProductOutOfStockEvent is published by the inventory service when a product
+// or variant sells out
+type ProductOutOfStockEvent struct {
+	ProductID   string `json:"product_id"`
+	VariantID   string `json:"variant_id,omitempty"`
+	WarehouseID string `json:"warehouse_id,omitempty"`
+}
+
+// WishlistRestockSubscriber subscribes customers to back-in-stock for the
+// wishlist items they asked to hear about (notify_restock) when the product
+// sells out, so they are told once it is restocked
+type WishlistRestockSubscriber struct {
+	nc           *nats.Conn
+	wishlistRepo *persistence.WishlistRepository
+	backInStock  *persistence.BackInStockRepository
+	consumer     JetStreamConsumer
+	logger       *zap.Logger
+}
+
+// NewWishlistRestockSubscriber creates a new subscriber
+func NewWishlistRestockSubscriber(
+	nc *nats.Conn,
+	wishlistRepo *persistence.WishlistRepository,
+	backInStock *persistence.BackInStockRepository,
+	logger *zap.Logger,
+) *WishlistRestockSubscriber {
+	return &WishlistRestockSubscriber{
+		nc:           nc,
+		wishlistRepo: wishlistRepo,
+		backInStock:  backInStock,
+		consumer: JetStreamConsumer{
+			Stream:            "INVENTORY_OUT_OF_STOCK",
+			Durable:           "customer-wishlist-restock",
+			MaxDeliver:        5,
+			Backoff:           []time.Duration{5 * time.Second, 30 * time.Second, 2 * time.Minute, 10 * time.Minute},
+			DeadLetterSubject: "customer.dlq." + OutOfStockSubject,
+		},
+		logger: logger,
+	}
+}
+
+// WithConsumer sets the JetStream stream, durable consumer and redelivery policy
+func (s *WishlistRestockSubscriber) WithConsumer(consumer JetStreamConsumer) *WishlistRestockSubscriber {
+	s.consumer = consumer
+	return s
+}
+
+// Subscribe starts consuming out of stock events from a durable JetStream
+// consumer, so events published while the service is down are delivered once
+// it is back. Replicas share the consumer, so each event is handled once; a
+// message is acked only after the subscriptions were made, and failures are
+// redelivered with backoff and dead-lettered once MaxDeliver is reached.
+func (s *WishlistRestockSubscriber) Subscribe() error {
+	js, err := s.nc.JetStream()
+	if err != nil {
+		s.logger.Error("Failed to get JetStream context", zap.Error(err))
+		return err
+	}
+
+	if err := ensureStream(js, s.consumer.Stream, OutOfStockSubject); err != nil {
+		s.logger.Error("Failed to ensure out of stock stream", zap.String("stream", s.consumer.Stream), zap.Error(err))
+		return err
+	}
+	if s.consumer.DeadLetterSubject != "" {
+		if err := ensureStream(js, s.consumer.Stream+"_DLQ", s.consumer.DeadLetterSubject); err != nil {
+			s.logger.Error("Failed to ensure out of stock dead-letter stream", zap.Error(err))
+			return err
+		}
+	}
+
+	_, err = js.QueueSubscribe(OutOfStockSubject, s.consumer.Durable, func(msg *nats.Msg) {
+		ctx, span := tracing.StartConsume(msg)
+		err := s.handleOutOfStockEvent(ctx, msg.Data)
+		tracing.End(span, err)
+		metrics.RecordMessage(msg.Subject, err)
+		if err != nil {
+			s.logger.Error("Failed to process out of stock event", zap.Error(err))
+		}
+		if err := s.consumer.finish(s.nc, msg, err); err != nil {
+			s.logger.Error("Failed to acknowledge out of stock event", zap.Error(err))
+		}
+	}, s.consumer.subscribeOptions()...)
+	if err != nil {
+		s.logger.Error("Failed to subscribe to "+OutOfStockSubject, zap.Error(err))
+		return err
+	}
+
+	s.logger.Info("Subscribed to "+OutOfStockSubject+" events",
+		zap.String("durable", s.consumer.Durable))
+	return nil
+}
+
+// handleOutOfStockEvent parses an out of stock event and processes it
+func (s *WishlistRestockSubscriber) handleOutOfStockEvent(ctx context.Context, data []byte) error {
+	var event ProductOutOfStockEvent
+	if err := json.Unmarshal(data, &event); err != nil {
+		return fmt.Errorf("%w: %v", errMalformedEvent, err)
+	}
+
+	productID, err := uuid.Parse(event.ProductID)
+	if err != nil {
+		return fmt.Errorf("%w: invalid product ID: %v", errMalformedEvent, err)
+	}
+	var variantID *uuid.UUID
+	if event.VariantID != "" {
+		vid, err := uuid.Parse(event.VariantID)
+		if err != nil {
+			return fmt.Errorf("%w: invalid variant ID: %v", errMalformedEvent, err)
+		}
+		variantID = &vid
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	return s.ProcessOutOfStock(ctx, productID, variantID)
+}
+
+// ProcessOutOfStock subscribes the owners of the product's notify_restock
+// wishlist items to back-in-stock, each for the variant on their wishlist. A
+// variant selling out only affects items for that variant. Existing pending
+// subscriptions get a fresh expiry rather than a duplicate, so a redelivered
+// event is harmless.
+func (s *WishlistRestockSubscriber) ProcessOutOfStock(ctx context.Context, productID uuid.UUID, variantID *uuid.UUID) error {
+	items, err := s.wishlistRepo.ListRestockWatchers(ctx, productID, variantID)
+	if err != nil {
+		return fmt.Errorf("list wishlist items for product %s: %w", productID, err)
+	}
+
+	subscribed, failed := 0, 0
+	for _, item := range items {
+		if _, err := s.backInStock.Subscribe(ctx, item.UserID, wishlistSubscribeInput(item)); err != nil {
+			s.logger.Error("Failed to subscribe wishlist item to back-in-stock",
+				zap.String("wishlist_item_id", item.ID.String()),
+				zap.Error(err))
+			failed++
+			continue
+		}
+		subscribed++
+	}
+
+	if subscribed > 0 {
+		s.logger.Info("Subscribed wishlist items to back-in-stock",
+			zap.String("product_id", productID.String()),
+			zap.Int("items", subscribed))
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d wishlist back-in-stock subscriptions failed", failed, len(items))
+	}
+	return nil
+}
+
+// wishlistSubscribeInput is the back-in-stock subscription for a wishlist item
+func wishlistSubscribeInput(item domain.WishlistItem) domain.BackInStockSubscribeInput {
+	input := domain.BackInStockSubscribeInput{
+		ProductID:    item.ProductID.String(),
+		ProductName:  stringValue(item.ProductName),
+		ProductSlug:  stringValue(item.ProductSlug),
+		ProductImage: stringValue(item.ProductImage),
+		VariantSKU:   stringValue(item.VariantSKU),
+		VariantName:  stringValue(item.VariantName),
+	}
+	if item.VariantID != nil {
+		input.VariantID = item.VariantID.String()
+	}
+	return input
+}
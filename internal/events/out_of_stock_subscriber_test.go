@@ -0,0 +1,57 @@
+package events
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/persistence"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestProcessOutOfStock(t *testing.T) {
+	db := openTestDB(t, &domain.Customer{}, &domain.WishlistItem{}, &domain.BackInStockSubscription{})
+	subscriber := NewWishlistRestockSubscriber(nil, persistence.NewWishlistRepository(db), persistence.NewBackInStockRepository(db), zap.NewNop())
+	productID := uuid.New()
+
+	watching := func(notifyRestock bool) uuid.UUID {
+		item := domain.WishlistItem{ID: uuid.New(), UserID: uuid.New(), ProductID: productID, NotifyRestock: notifyRestock}
+		require.NoError(t, db.Create(&item).Error)
+		return item.UserID
+	}
+	watcher, alreadySubscribed, notWatching := watching(true), watching(true), watching(false)
+
+	existing := domain.BackInStockSubscription{CustomerID: alreadySubscribed, ProductID: productID}
+	require.NoError(t, db.Create(&existing).Error)
+
+	subscribedCustomers := func() []uuid.UUID {
+		var subs []domain.BackInStockSubscription
+		require.NoError(t, db.Where("product_id = ?", productID).Find(&subs).Error)
+		customers := make([]uuid.UUID, len(subs))
+		for i, s := range subs {
+			customers[i] = s.CustomerID
+		}
+		return customers
+	}
+
+	require.NoError(t, subscriber.ProcessOutOfStock(context.Background(), productID, nil))
+	assert.ElementsMatch(t, []uuid.UUID{watcher, alreadySubscribed}, subscribedCustomers(),
+		"only notify_restock items, without duplicating the existing subscription")
+	assert.NotContains(t, subscribedCustomers(), notWatching)
+
+	// A redelivered event changes nothing
+	require.NoError(t, subscriber.ProcessOutOfStock(context.Background(), productID, nil))
+	assert.ElementsMatch(t, []uuid.UUID{watcher, alreadySubscribed}, subscribedCustomers())
+}
+
+func TestHandleOutOfStockEvent_MalformedEventsAreNotRetried(t *testing.T) {
+	subscriber := NewWishlistRestockSubscriber(nil, nil, nil, zap.NewNop())
+
+	for _, data := range []string{`not json`, `{"product_id": "nope"}`, `{"product_id": "` + uuid.NewString() + `", "variant_id": "nope"}`} {
+		err := subscriber.handleOutOfStockEvent(context.Background(), []byte(data))
+		assert.ErrorIs(t, err, errMalformedEvent, data)
+	}
+}
@@ -0,0 +1,92 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nats-io/nats.go"
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/metrics"
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/persistence"
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/tracing"
+	"go.uber.org/zap"
+)
+
+// ProductUpdatedEvent is published by the catalog service when product details change
+type ProductUpdatedEvent struct {
+	ProductID string `json:"product_id"`
+	Name      string `json:"name"`
+	Slug      string `json:"slug"`
+	Image     string `json:"image"`
+}
+
+// ProductSubscriber keeps denormalized product info on wishlist items fresh
+type ProductSubscriber struct {
+	nc           *nats.Conn
+	wishlistRepo *persistence.WishlistRepository
+	logger       *zap.Logger
+}
+
+// NewProductSubscriber creates a new subscriber
+func NewProductSubscriber(
+	nc *nats.Conn,
+	wishlistRepo *persistence.WishlistRepository,
+	logger *zap.Logger,
+) *ProductSubscriber {
+	return &ProductSubscriber{
+		nc:           nc,
+		wishlistRepo: wishlistRepo,
+		logger:       logger,
+	}
+}
+
+// Subscribe starts listening for product updated events
+func (s *ProductSubscriber) Subscribe() error {
+	_, err := s.nc.Subscribe("catalog.product.updated", func(msg *nats.Msg) {
+		ctx, span := tracing.StartConsume(msg)
+		err := s.handleProductUpdatedEvent(ctx, msg.Data)
+		tracing.End(span, err)
+		metrics.RecordMessage(msg.Subject, err)
+	})
+	if err != nil {
+		s.logger.Error("Failed to subscribe to catalog.product.updated", zap.Error(err))
+		return err
+	}
+
+	s.logger.Info("Subscribed to catalog.product.updated events")
+	return nil
+}
+
+// handleProductUpdatedEvent copies the new product details onto wishlist items
+func (s *ProductSubscriber) handleProductUpdatedEvent(ctx context.Context, data []byte) error {
+	var event ProductUpdatedEvent
+	if err := json.Unmarshal(data, &event); err != nil {
+		s.logger.Error("Failed to unmarshal product updated event", zap.Error(err))
+		return err
+	}
+
+	productID, err := uuid.Parse(event.ProductID)
+	if err != nil {
+		s.logger.Error("Invalid product ID in event", zap.Error(err))
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	updated, err := s.wishlistRepo.UpdateProductInfo(ctx, productID, event.Name, event.Slug, event.Image)
+	if err != nil {
+		s.logger.Error("Failed to refresh wishlist product info",
+			zap.String("product_id", event.ProductID),
+			zap.Error(err))
+		return err
+	}
+
+	if updated > 0 {
+		s.logger.Info("Refreshed wishlist product info",
+			zap.String("product_id", event.ProductID),
+			zap.Int64("items", updated))
+	}
+	return nil
+}
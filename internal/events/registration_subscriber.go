@@ -0,0 +1,94 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nats-io/nats.go"
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/metrics"
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/persistence"
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/tracing"
+	"go.uber.org/zap"
+)
+
+// UserRegisteredEvent is published by the auth service when a new account is created
+type UserRegisteredEvent struct {
+	UserID string `json:"user_id"`
+	Email  string `json:"email"`
+}
+
+// RegistrationSubscriber attaches guest activity to newly registered customers
+type RegistrationSubscriber struct {
+	nc        *nats.Conn
+	guestRepo *persistence.GuestBackInStockRepository
+	logger    *zap.Logger
+}
+
+// NewRegistrationSubscriber creates a new subscriber
+func NewRegistrationSubscriber(
+	nc *nats.Conn,
+	guestRepo *persistence.GuestBackInStockRepository,
+	logger *zap.Logger,
+) *RegistrationSubscriber {
+	return &RegistrationSubscriber{
+		nc:        nc,
+		guestRepo: guestRepo,
+		logger:    logger,
+	}
+}
+
+// Subscribe starts listening for user registered events
+func (s *RegistrationSubscriber) Subscribe() error {
+	_, err := s.nc.Subscribe("auth.user.registered", func(msg *nats.Msg) {
+		ctx, span := tracing.StartConsume(msg)
+		err := s.handleUserRegisteredEvent(ctx, msg.Data)
+		tracing.End(span, err)
+		metrics.RecordMessage(msg.Subject, err)
+	})
+	if err != nil {
+		s.logger.Error("Failed to subscribe to auth.user.registered", zap.Error(err))
+		return err
+	}
+
+	s.logger.Info("Subscribed to auth.user.registered events")
+	return nil
+}
+
+// handleUserRegisteredEvent moves guest back-in-stock subscriptions made with
+// the registered email onto the new account
+func (s *RegistrationSubscriber) handleUserRegisteredEvent(ctx context.Context, data []byte) error {
+	var event UserRegisteredEvent
+	if err := json.Unmarshal(data, &event); err != nil {
+		s.logger.Error("Failed to unmarshal user registered event", zap.Error(err))
+		return err
+	}
+
+	userID, err := uuid.Parse(event.UserID)
+	if err != nil {
+		s.logger.Error("Invalid user ID in event", zap.Error(err))
+		return err
+	}
+	if event.Email == "" {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	merged, err := s.guestRepo.MergeIntoCustomer(ctx, event.Email, userID)
+	if err != nil {
+		s.logger.Error("Failed to merge guest back-in-stock subscriptions",
+			zap.String("user_id", event.UserID),
+			zap.Error(err))
+		return err
+	}
+
+	if merged > 0 {
+		s.logger.Info("Merged guest back-in-stock subscriptions",
+			zap.String("user_id", event.UserID),
+			zap.Int("subscriptions", merged))
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nats-io/nats.go"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/tracing"
+	"go.uber.org/zap"
+)
+
+// SubjectSavedSearchMatched is published when a newly published product
+// matches a customer's saved searches
+const SubjectSavedSearchMatched = "customer.saved_search.matched"
+
+// SavedSearchMatchedEvent is consumed by the notification service to send the
+// new-arrival alert
+type SavedSearchMatchedEvent struct {
+	CustomerID string               `json:"customer_id"`
+	Channel    string               `json:"channel"`
+	Product    MatchedProduct       `json:"product"`
+	Searches   []MatchedSavedSearch `json:"searches"`
+	OccurredAt time.Time            `json:"occurred_at"`
+}
+
+// MatchedProduct is the new product in a SavedSearchMatchedEvent
+type MatchedProduct struct {
+	ProductID string  `json:"product_id"`
+	Name      string  `json:"name"`
+	Slug      string  `json:"slug,omitempty"`
+	Image     string  `json:"image,omitempty"`
+	Price     float64 `json:"price"`
+}
+
+// MatchedSavedSearch is one of the customer's searches the product matched
+type MatchedSavedSearch struct {
+	SearchID string `json:"search_id"`
+	Name     string `json:"name"`
+	Query    string `json:"query,omitempty"`
+}
+
+// SavedSearchEventPublisher publishes saved search events to NATS
+type SavedSearchEventPublisher struct {
+	nc     *nats.Conn
+	logger *zap.Logger
+}
+
+// NewSavedSearchEventPublisher creates a new saved search event publisher
+func NewSavedSearchEventPublisher(nc *nats.Conn, logger *zap.Logger) *SavedSearchEventPublisher {
+	return &SavedSearchEventPublisher{
+		nc:     nc,
+		logger: logger,
+	}
+}
+
+// NotifySavedSearchMatch publishes a matched event for the customer's
+// searches the product matched
+func (p *SavedSearchEventPublisher) NotifySavedSearchMatch(ctx context.Context, customerID uuid.UUID, channel string, product domain.PublishedProduct, searches []domain.SavedSearch) error {
+	event := SavedSearchMatchedEvent{
+		CustomerID: customerID.String(),
+		Channel:    channel,
+		Product: MatchedProduct{
+			ProductID: product.ID.String(),
+			Name:      product.Name,
+			Slug:      product.Slug,
+			Image:     product.Image,
+			Price:     product.Price,
+		},
+		Searches:   make([]MatchedSavedSearch, len(searches)),
+		OccurredAt: time.Now().UTC(),
+	}
+	for i, search := range searches {
+		event.Searches[i] = MatchedSavedSearch{
+			SearchID: search.ID.String(),
+			Name:     search.Name,
+			Query:    search.Query,
+		}
+	}
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	if err := tracing.Publish(ctx, p.nc, SubjectSavedSearchMatched, data); err != nil {
+		return err
+	}
+
+	p.logger.Info("Published saved search matched event",
+		zap.String("customer_id", event.CustomerID),
+		zap.String("product_id", event.Product.ProductID),
+		zap.Int("searches", len(searches)))
+	return nil
+}
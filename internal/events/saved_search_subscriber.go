@@ -0,0 +1,213 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nats-io/nats.go"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/metrics"
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/persistence"
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/tracing"
+	"go.uber.org/zap"
+)
+
+// ProductPublishedSubject is the subject the catalog publishes new products on
+const ProductPublishedSubject = "catalog.product.published"
+
+// savedSearchBatchSize is how many saved searches are matched per query
+const savedSearchBatchSize = 500
+
+// ProductPublishedEvent is published by the catalog service when a product
+// goes live
+type ProductPublishedEvent struct {
+	ProductID   string   `json:"product_id"`
+	Name        string   `json:"name"`
+	Slug        string   `json:"slug"`
+	Image       string   `json:"image"`
+	Description string   `json:"description,omitempty"`
+	Brand       string   `json:"brand,omitempty"`
+	Categories  []string `json:"categories,omitempty"` // category slugs
+	Tags        []string `json:"tags,omitempty"`
+	Price       float64  `json:"price"`
+}
+
+// SavedSearchNotifier sends a customer the new product that matched their
+// saved searches on the given channel
+type SavedSearchNotifier interface {
+	NotifySavedSearchMatch(ctx context.Context, customerID uuid.UUID, channel string, product domain.PublishedProduct, searches []domain.SavedSearch) error
+}
+
+// SavedSearchSubscriber matches newly published products against customers'
+// saved searches and sends new-arrival alerts
+type SavedSearchSubscriber struct {
+	nc       *nats.Conn
+	searches *persistence.SavedSearchRepository
+	notifier SavedSearchNotifier
+	policy   *NotificationPolicy
+	logger   *zap.Logger
+}
+
+// NewSavedSearchSubscriber creates a new subscriber
+func NewSavedSearchSubscriber(
+	nc *nats.Conn,
+	searches *persistence.SavedSearchRepository,
+	notifier SavedSearchNotifier,
+	logger *zap.Logger,
+) *SavedSearchSubscriber {
+	return &SavedSearchSubscriber{
+		nc:       nc,
+		searches: searches,
+		notifier: notifier,
+		logger:   logger,
+	}
+}
+
+// WithNotificationPolicy applies customers' notification preferences. Searches
+// whose alert is held back are not marked as notified, so a later matching
+// product alerts them.
+func (s *SavedSearchSubscriber) WithNotificationPolicy(policy *NotificationPolicy) *SavedSearchSubscriber {
+	s.policy = policy
+	return s
+}
+
+// Subscribe starts listening for product published events. Replicas share a
+// queue group so each product is matched, and alerted, once.
+func (s *SavedSearchSubscriber) Subscribe() error {
+	_, err := s.nc.QueueSubscribe(ProductPublishedSubject, "customer-saved-search-alerts", func(msg *nats.Msg) {
+		ctx, span := tracing.StartConsume(msg)
+		err := s.handleProductPublishedEvent(ctx, msg.Data)
+		tracing.End(span, err)
+		metrics.RecordMessage(msg.Subject, err)
+	})
+	if err != nil {
+		s.logger.Error("Failed to subscribe to "+ProductPublishedSubject, zap.Error(err))
+		return err
+	}
+
+	s.logger.Info("Subscribed to " + ProductPublishedSubject + " events")
+	return nil
+}
+
+// handleProductPublishedEvent alerts the customers whose saved searches match
+// the new product
+func (s *SavedSearchSubscriber) handleProductPublishedEvent(ctx context.Context, data []byte) error {
+	var event ProductPublishedEvent
+	if err := json.Unmarshal(data, &event); err != nil {
+		s.logger.Error("Failed to unmarshal product published event", zap.Error(err))
+		return err
+	}
+
+	productID, err := uuid.Parse(event.ProductID)
+	if err != nil {
+		s.logger.Error("Invalid product ID in event", zap.Error(err))
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Minute)
+	defer cancel()
+	return s.ProcessPublished(ctx, domain.PublishedProduct{
+		ID:          productID,
+		Name:        event.Name,
+		Slug:        event.Slug,
+		Image:       event.Image,
+		Description: event.Description,
+		Brand:       event.Brand,
+		Categories:  event.Categories,
+		Tags:        event.Tags,
+		Price:       event.Price,
+	})
+}
+
+// ProcessPublished sends one alert per customer listing every saved search
+// the product matched. Searches that alerted within the cooldown are skipped,
+// and searches are marked as notified once their alert went out.
+func (s *SavedSearchSubscriber) ProcessPublished(ctx context.Context, product domain.PublishedProduct) error {
+	now := time.Now()
+	matched := map[uuid.UUID][]domain.SavedSearch{}
+	var customers []uuid.UUID
+
+	after := uuid.Nil
+	for {
+		batch, err := s.searches.ListAlertable(ctx, after, savedSearchBatchSize, now.Add(-domain.SavedSearchAlertCooldown))
+		if err != nil {
+			return fmt.Errorf("list saved searches: %w", err)
+		}
+		for _, search := range batch {
+			if !search.Matches(product) {
+				continue
+			}
+			if _, ok := matched[search.UserID]; !ok {
+				customers = append(customers, search.UserID)
+			}
+			matched[search.UserID] = append(matched[search.UserID], search)
+		}
+		if len(batch) < savedSearchBatchSize {
+			break
+		}
+		after = batch[len(batch)-1].ID
+	}
+
+	if len(customers) == 0 {
+		s.logger.Debug("No saved searches match published product",
+			zap.String("product_id", product.ID.String()))
+		return nil
+	}
+
+	var notifiedIDs []uuid.UUID
+	failed, heldBack := 0, 0
+	for _, customerID := range customers {
+		searches := matched[customerID]
+		channel := domain.NotificationChannelEmail
+		if s.policy != nil {
+			decision, err := s.policy.ShouldNotify(ctx, customerID, domain.NotificationTypeSavedSearch)
+			if err != nil {
+				s.logger.Error("Failed to check notification preferences",
+					zap.String("customer_id", customerID.String()),
+					zap.Error(err))
+				failed++
+				continue
+			}
+			if !decision.Allowed {
+				s.logger.Debug("Held back saved search alert",
+					zap.String("customer_id", customerID.String()),
+					zap.String("reason", decision.Reason))
+				heldBack++
+				continue
+			}
+			channel = decision.Channel
+		}
+
+		if err := s.notifier.NotifySavedSearchMatch(ctx, customerID, channel, product, searches); err != nil {
+			s.logger.Error("Failed to send saved search alert",
+				zap.String("customer_id", customerID.String()),
+				zap.String("product_id", product.ID.String()),
+				zap.Error(err))
+			failed++
+			continue
+		}
+		if s.policy != nil {
+			s.policy.Sent(ctx, customerID, domain.NotificationTypeSavedSearch, channel)
+		}
+		for _, search := range searches {
+			notifiedIDs = append(notifiedIDs, search.ID)
+		}
+	}
+
+	if err := s.searches.MarkNotified(ctx, notifiedIDs, now); err != nil {
+		return fmt.Errorf("mark saved searches as notified: %w", err)
+	}
+	s.logger.Info("Sent saved search alerts",
+		zap.String("product_id", product.ID.String()),
+		zap.Int("customers", len(customers)-failed-heldBack),
+		zap.Int("held_back", heldBack),
+		zap.Int("searches", len(notifiedIDs)))
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d saved search alerts failed", failed, len(customers))
+	}
+	return nil
+}
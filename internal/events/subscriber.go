@@ -3,17 +3,24 @@ package events
 import (
 	"context"
 	"encoding/json"
+	"fmt"
+	"slices"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/nats-io/nats.go"
 	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/metrics"
 	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/persistence"
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/tracing"
 	"go.uber.org/zap"
 )
 
 // HI-001: Back-in-Stock Event Subscriber
 
+// RestockedSubject is the subject inventory publishes restock events on
+const RestockedSubject = "inventory.product.restocked"
+
 // ProductRestockedEvent represents a product back in stock event from inventory service
 type ProductRestockedEvent struct {
 	ProductID   string  `json:"product_id"`
@@ -28,13 +35,20 @@ type ProductRestockedEvent struct {
 type BackInStockSubscriber struct {
 	nc                 *nats.Conn
 	backInStockRepo    *persistence.BackInStockRepository
+	guestRepo          *persistence.GuestBackInStockRepository
 	notificationClient NotificationClient
+	throttle           domain.BackInStockThrottle
+	policy             *NotificationPolicy
+	partialRestocks    bool
+	retries            *persistence.NotificationRetryRepository
+	retryPolicy        domain.NotificationRetryPolicy
+	consumer           JetStreamConsumer
 	logger             *zap.Logger
 }
 
 // NotificationClient interface for sending notifications
 type NotificationClient interface {
-	SendBackInStockNotification(notification domain.BackInStockNotification) error
+	SendBackInStockNotification(ctx context.Context, notification domain.BackInStockNotification) error
 }
 
 // NewBackInStockSubscriber creates a new subscriber
@@ -48,45 +62,131 @@ func NewBackInStockSubscriber(
 		nc:                 nc,
 		backInStockRepo:    backInStockRepo,
 		notificationClient: notificationClient,
-		logger:             logger,
+		consumer: JetStreamConsumer{
+			Stream:            "INVENTORY_RESTOCK",
+			Durable:           "customer-back-in-stock",
+			MaxDeliver:        5,
+			Backoff:           []time.Duration{5 * time.Second, 30 * time.Second, 2 * time.Minute, 10 * time.Minute},
+			DeadLetterSubject: "customer.dlq." + RestockedSubject,
+		},
+		logger: logger,
 	}
 }
 
-// Subscribe starts listening for restock events
+// WithGuestSubscriptions also notifies confirmed guest (email-only) subscribers
+func (s *BackInStockSubscriber) WithGuestSubscriptions(guestRepo *persistence.GuestBackInStockRepository) *BackInStockSubscriber {
+	s.guestRepo = guestRepo
+	return s
+}
+
+// WithRetryQueue queues failed sends for the notification retry job instead
+// of failing the event, so one unreachable inbox doesn't redeliver the restock
+// to everyone else
+func (s *BackInStockSubscriber) WithRetryQueue(retries *persistence.NotificationRetryRepository, policy domain.NotificationRetryPolicy) *BackInStockSubscriber {
+	s.retries = retries
+	s.retryPolicy = policy
+	return s
+}
+
+// WithThrottle limits how many notifications one recipient gets per window, so
+// a stock level flapping in and out of stock doesn't flood their inbox
+func (s *BackInStockSubscriber) WithThrottle(throttle domain.BackInStockThrottle) *BackInStockSubscriber {
+	s.throttle = throttle
+	return s
+}
+
+// WithNotificationPolicy applies customers' notification preferences to
+// registered subscribers. Notifications held back by the preferences stay
+// pending and go out on a later restock.
+func (s *BackInStockSubscriber) WithNotificationPolicy(policy *NotificationPolicy) *BackInStockSubscriber {
+	s.policy = policy
+	return s
+}
+
+// WithPartialRestocks notifies only as many subscribers as there are units
+// restocked, longest waiting first, when fewer units came in than people are
+// waiting. The rest stay pending for a later restock.
+func (s *BackInStockSubscriber) WithPartialRestocks(enabled bool) *BackInStockSubscriber {
+	s.partialRestocks = enabled
+	return s
+}
+
+// WithConsumer sets the JetStream stream, durable consumer and redelivery policy
+func (s *BackInStockSubscriber) WithConsumer(consumer JetStreamConsumer) *BackInStockSubscriber {
+	s.consumer = consumer
+	return s
+}
+
+// Subscribe starts consuming restock events from a durable JetStream consumer,
+// so events published while the service is down are delivered once it is back.
+// A message is acked only after its notifications went out; failures are
+// redelivered with backoff and dead-lettered once MaxDeliver is reached.
 func (s *BackInStockSubscriber) Subscribe() error {
-	_, err := s.nc.Subscribe("inventory.product.restocked", func(msg *nats.Msg) {
-		s.handleRestockedEvent(msg.Data)
-	})
+	js, err := s.nc.JetStream()
 	if err != nil {
-		s.logger.Error("Failed to subscribe to inventory.product.restocked", zap.Error(err))
+		s.logger.Error("Failed to get JetStream context", zap.Error(err))
 		return err
 	}
 
-	s.logger.Info("Subscribed to inventory.product.restocked events")
+	if err := ensureStream(js, s.consumer.Stream, RestockedSubject); err != nil {
+		s.logger.Error("Failed to ensure restock stream", zap.String("stream", s.consumer.Stream), zap.Error(err))
+		return err
+	}
+	if s.consumer.DeadLetterSubject != "" {
+		if err := ensureStream(js, s.consumer.Stream+"_DLQ", s.consumer.DeadLetterSubject); err != nil {
+			s.logger.Error("Failed to ensure restock dead-letter stream", zap.Error(err))
+			return err
+		}
+	}
+
+	_, err = js.QueueSubscribe(RestockedSubject, s.consumer.Durable, func(msg *nats.Msg) {
+		ctx, span := tracing.StartConsume(msg)
+		err := s.handleRestockedEvent(ctx, msg.Data)
+		tracing.End(span, err)
+		metrics.RecordMessage(msg.Subject, err)
+		if err != nil {
+			s.logger.Error("Failed to process restocked event", zap.Error(err))
+		}
+		if err := s.consumer.finish(s.nc, msg, err); err != nil {
+			s.logger.Error("Failed to acknowledge restocked event", zap.Error(err))
+		}
+	}, s.consumer.subscribeOptions()...)
+	if err != nil {
+		s.logger.Error("Failed to subscribe to "+RestockedSubject, zap.Error(err))
+		return err
+	}
+
+	s.logger.Info("Subscribed to "+RestockedSubject+" events",
+		zap.String("durable", s.consumer.Durable))
 	return nil
 }
 
-// handleRestockedEvent processes a product restocked event
-func (s *BackInStockSubscriber) handleRestockedEvent(data []byte) {
+// handleRestockedEvent processes a product restocked event from NATS
+func (s *BackInStockSubscriber) handleRestockedEvent(ctx context.Context, data []byte) error {
 	var event ProductRestockedEvent
 	if err := json.Unmarshal(data, &event); err != nil {
-		s.logger.Error("Failed to unmarshal restocked event", zap.Error(err))
-		return
+		return fmt.Errorf("%w: %v", errMalformedEvent, err)
 	}
 
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	return s.ProcessRestock(ctx, event)
+}
+
+// ProcessRestock notifies the subscribers of a restocked product. It is the
+// one back-in-stock path for restocks from NATS and the inventory webhook.
+// Subscriptions that were notified are marked before an error is returned, so
+// a redelivery only retries the failed ones.
+func (s *BackInStockSubscriber) ProcessRestock(ctx context.Context, event ProductRestockedEvent) error {
 	s.logger.Info("Processing product restocked event",
 		zap.String("product_id", event.ProductID),
 		zap.String("variant_id", event.VariantID),
 		zap.Float64("quantity", event.Quantity))
 
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
-
 	// Parse product ID
 	productID, err := uuid.Parse(event.ProductID)
 	if err != nil {
-		s.logger.Error("Invalid product ID in event", zap.Error(err))
-		return
+		return fmt.Errorf("%w: invalid product ID: %v", errMalformedEvent, err)
 	}
 
 	// Parse variant ID if present
@@ -94,106 +194,365 @@ func (s *BackInStockSubscriber) handleRestockedEvent(data []byte) {
 	if event.VariantID != "" {
 		vid, err := uuid.Parse(event.VariantID)
 		if err != nil {
-			s.logger.Error("Invalid variant ID in event", zap.Error(err))
-			return
+			return fmt.Errorf("%w: invalid variant ID: %v", errMalformedEvent, err)
 		}
 		variantID = &vid
 	}
 
-	// Get all pending subscriptions for this product/variant
+	subscriptions, guests, err := s.pending(ctx, productID, variantID)
+	if err != nil {
+		return err
+	}
+	units := 0
+	if s.partialRestocks {
+		units = s.restockUnits(subscriptions, guests, event)
+	}
+
+	_, _, err = s.notify(ctx, subscriptions, guests, int(event.Quantity), units)
+	return err
+}
+
+// NotifyProduct notifies the pending subscribers of a product without a
+// restock event, for when the event was missed or stock was adjusted by hand.
+// At most limit subscribers are notified, longest waiting first, unless limit
+// is 0; subscribers who can't be notified make way for those behind them.
+// With dryRun nothing is sent; the result lists the limit longest waiting.
+func (s *BackInStockSubscriber) NotifyProduct(ctx context.Context, productID uuid.UUID, variantID *uuid.UUID, stockQuantity, limit int, dryRun bool) (*domain.BackInStockNotifyResult, error) {
+	subscriptions, guests, err := s.pending(ctx, productID, variantID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &domain.BackInStockNotifyResult{Waiting: len(subscriptions) + len(guests), DryRun: dryRun}
+	if dryRun {
+		if limit > 0 {
+			subscriptions, guests = split(firstInLine(subscriptions, guests, limit))
+		}
+		result.Selected = len(subscriptions) + len(guests)
+		result.Recipients = domain.BackInStockRecipients(subscriptions, guests)
+		return result, nil
+	}
+
+	s.logger.Info("Notifying back-in-stock subscribers manually",
+		zap.String("product_id", productID.String()),
+		zap.Int("limit", limit),
+		zap.Int("waiting", result.Waiting))
+	result.Selected, result.Notified, err = s.notify(ctx, subscriptions, guests, stockQuantity, limit)
+	return result, err
+}
+
+// pending returns the pending subscriptions of customers and confirmed
+// guests for a product, both oldest first. Subscriptions with a queued retry
+// are left to the retry job, so they aren't sent twice.
+func (s *BackInStockSubscriber) pending(ctx context.Context, productID uuid.UUID, variantID *uuid.UUID) ([]domain.BackInStockSubscription, []domain.GuestBackInStockSubscription, error) {
 	subscriptions, err := s.backInStockRepo.GetByProduct(ctx, productID, variantID)
 	if err != nil {
-		s.logger.Error("Failed to get subscriptions for product",
-			zap.String("product_id", event.ProductID),
-			zap.Error(err))
-		return
+		return nil, nil, fmt.Errorf("get subscriptions for product %s: %w", productID, err)
+	}
+	var guests []domain.GuestBackInStockSubscription
+	if s.guestRepo != nil {
+		if guests, err = s.guestRepo.GetConfirmedByProduct(ctx, productID, variantID); err != nil {
+			return nil, nil, fmt.Errorf("get guest subscriptions for product %s: %w", productID, err)
+		}
+	}
+	if s.retries == nil || len(subscriptions)+len(guests) == 0 {
+		return subscriptions, guests, nil
 	}
 
-	if len(subscriptions) == 0 {
-		s.logger.Debug("No pending subscriptions for restocked product",
-			zap.String("product_id", event.ProductID))
-		return
+	ids := make([]uuid.UUID, 0, len(subscriptions)+len(guests))
+	for _, sub := range subscriptions {
+		ids = append(ids, sub.ID)
 	}
+	for _, guest := range guests {
+		ids = append(ids, guest.ID)
+	}
+	queued, err := s.retries.Queued(ctx, ids)
+	if err != nil {
+		return nil, nil, fmt.Errorf("get queued retries for product %s: %w", productID, err)
+	}
+	subscriptions = slices.DeleteFunc(subscriptions, func(sub domain.BackInStockSubscription) bool { return queued[sub.ID] })
+	guests = slices.DeleteFunc(guests, func(guest domain.GuestBackInStockSubscription) bool { return queued[guest.ID] })
+	return subscriptions, guests, nil
+}
 
-	s.logger.Info("Found subscriptions to notify",
+// restockUnits is how many subscribers a restock of event.Quantity units
+// goes to, longest waiting first, or 0 for everyone. A restock without a
+// quantity, or with enough units for everyone waiting, goes to everyone.
+func (s *BackInStockSubscriber) restockUnits(subscriptions []domain.BackInStockSubscription, guests []domain.GuestBackInStockSubscription, event ProductRestockedEvent) int {
+	units := int(event.Quantity)
+	waiting := len(subscriptions) + len(guests)
+	if units <= 0 || units >= waiting {
+		return 0
+	}
+
+	metrics.BackInStockPartialRestocks.Inc()
+	s.logger.Info("Restock smaller than its waiting list; notifying the longest waiting",
 		zap.String("product_id", event.ProductID),
-		zap.Int("count", len(subscriptions)))
+		zap.Int("units", units),
+		zap.Int("waiting", waiting))
+	return units
+}
 
-	// Send notifications and mark as notified
-	var notifiedIDs []uuid.UUID
-	for _, sub := range subscriptions {
-		// Build notification
-		notification := domain.BackInStockNotification{
-			SubscriptionID: sub.ID.String(),
-			CustomerID:     sub.CustomerID.String(),
-			ProductID:      sub.ProductID.String(),
-			ProductName:    sub.ProductName,
-			ProductSlug:    sub.ProductSlug,
-			ProductImage:   sub.ProductImage,
-			StockQuantity:  int(event.Quantity),
-		}
-
-		if sub.VariantID != nil {
-			notification.VariantID = sub.VariantID.String()
-		}
-		notification.VariantSKU = sub.VariantSKU
-		notification.VariantName = sub.VariantName
-
-		// Get customer info if available
-		if sub.Customer != nil {
-			notification.CustomerEmail = sub.Customer.Email
-			notification.CustomerName = sub.Customer.FirstName + " " + sub.Customer.LastName
-		}
-
-		// Send notification
-		if s.notificationClient != nil {
-			if err := s.notificationClient.SendBackInStockNotification(notification); err != nil {
-				s.logger.Error("Failed to send notification",
-					zap.String("subscription_id", sub.ID.String()),
-					zap.Error(err))
-				continue
+// queued is one pending subscription in the waiting list of customers and
+// guests; exactly one of the two is set
+type queued struct {
+	customer *domain.BackInStockSubscription
+	guest    *domain.GuestBackInStockSubscription
+}
+
+func (q queued) createdAt() time.Time {
+	if q.guest != nil {
+		return q.guest.CreatedAt
+	}
+	return q.customer.CreatedAt
+}
+
+// firstInLine merges the subscriptions of customers and guests, both oldest
+// first, into one waiting list and returns its first n. Customers go first
+// when they subscribed at the same time as a guest.
+func firstInLine(subscriptions []domain.BackInStockSubscription, guests []domain.GuestBackInStockSubscription, n int) []queued {
+	line := make([]queued, 0, min(max(n, 0), len(subscriptions)+len(guests)))
+	customers, guestCount := 0, 0
+	for len(line) < n && len(line) < len(subscriptions)+len(guests) {
+		if guestCount == len(guests) ||
+			(customers < len(subscriptions) && !guests[guestCount].CreatedAt.Before(subscriptions[customers].CreatedAt)) {
+			line = append(line, queued{customer: &subscriptions[customers]})
+			customers++
+		} else {
+			line = append(line, queued{guest: &guests[guestCount]})
+			guestCount++
+		}
+	}
+	return line
+}
+
+// split separates a waiting list into its customer and guest subscriptions
+func split(line []queued) ([]domain.BackInStockSubscription, []domain.GuestBackInStockSubscription) {
+	var subscriptions []domain.BackInStockSubscription
+	var guests []domain.GuestBackInStockSubscription
+	for _, q := range line {
+		if q.guest != nil {
+			guests = append(guests, *q.guest)
+		} else {
+			subscriptions = append(subscriptions, *q.customer)
+		}
+	}
+	return subscriptions, guests
+}
+
+// sendResult is what became of one subscriber's notification
+type sendResult int
+
+const (
+	resultSent    sendResult = iota
+	resultSkipped            // throttled or held back; stays pending
+	resultRetried            // failed and queued for the retry job
+	resultFailed
+)
+
+// notify works through the waiting list of customer and guest subscriptions,
+// longest waiting first, until limit notifications went out, or through all
+// of it when limit is 0. Subscribers who are throttled, held back or whose
+// send fails don't count toward the limit, so the next in line takes their
+// place; those left once the limit is reached stay pending. It reports how
+// many subscribers it tried and how many were notified.
+func (s *BackInStockSubscriber) notify(ctx context.Context, subscriptions []domain.BackInStockSubscription, guests []domain.GuestBackInStockSubscription, stockQuantity, limit int) (int, int, error) {
+	line := firstInLine(subscriptions, guests, len(subscriptions)+len(guests))
+	if len(line) == 0 {
+		s.logger.Debug("No pending subscriptions to notify")
+		return 0, 0, nil
+	}
+
+	s.logger.Info("Found subscriptions to notify",
+		zap.Int("customers", len(subscriptions)),
+		zap.Int("guests", len(guests)))
+
+	var notifiedIDs, notifiedGuestIDs []uuid.UUID
+	tried, failed := 0, 0
+	sent, sentToGuests := map[string]int64{}, map[string]int64{}
+	for i, next := range line {
+		if limit > 0 && len(notifiedIDs)+len(notifiedGuestIDs) >= limit {
+			for _, rest := range line[i:] {
+				metrics.RecordNotification(rest.guest != nil, metrics.NotificationWaitlisted)
 			}
+			break
 		}
 
-		notifiedIDs = append(notifiedIDs, sub.ID)
+		tried++
+		var result sendResult
+		if next.guest != nil {
+			if result = s.sendGuest(ctx, *next.guest, stockQuantity, sentToGuests); result == resultSent {
+				notifiedGuestIDs = append(notifiedGuestIDs, next.guest.ID)
+			}
+		} else {
+			if result = s.sendCustomer(ctx, *next.customer, stockQuantity, sent); result == resultSent {
+				notifiedIDs = append(notifiedIDs, next.customer.ID)
+			}
+		}
+		if result == resultFailed {
+			failed++
+		}
 	}
 
 	// Mark subscriptions as notified in batch
+	notified := 0
 	if len(notifiedIDs) > 0 {
 		if err := s.backInStockRepo.MarkMultipleAsNotified(ctx, notifiedIDs); err != nil {
-			s.logger.Error("Failed to mark subscriptions as notified", zap.Error(err))
-		} else {
-			s.logger.Info("Marked subscriptions as notified",
-				zap.Int("count", len(notifiedIDs)))
+			return tried, notified, fmt.Errorf("mark subscriptions as notified: %w", err)
+		}
+		notified += len(notifiedIDs)
+	}
+	if len(notifiedGuestIDs) > 0 {
+		if err := s.guestRepo.MarkMultipleAsNotified(ctx, notifiedGuestIDs); err != nil {
+			return tried, notified, fmt.Errorf("mark guest subscriptions as notified: %w", err)
 		}
+		notified += len(notifiedGuestIDs)
+	}
+	if notified > 0 {
+		s.logger.Info("Marked subscriptions as notified",
+			zap.Int("customers", len(notifiedIDs)),
+			zap.Int("guests", len(notifiedGuestIDs)))
+	}
+
+	if failed > 0 {
+		return tried, notified, fmt.Errorf("%d of %d back-in-stock notifications failed", failed, tried)
 	}
+	return tried, notified, nil
 }
 
-// SimpleNotificationClient is a basic HTTP client for notifications
-type SimpleNotificationClient struct {
-	baseURL string
-	logger  *zap.Logger
+// sendCustomer notifies a customer subscriber, subject to the throttle and
+// their notification preferences
+func (s *BackInStockSubscriber) sendCustomer(ctx context.Context, sub domain.BackInStockSubscription, stockQuantity int, sent map[string]int64) sendResult {
+	customerID := sub.CustomerID
+	if !s.allow(ctx, sent, customerID.String(), func(ctx context.Context, since time.Time) (int64, error) {
+		return s.backInStockRepo.CountNotifiedSince(ctx, customerID, since)
+	}) {
+		s.logger.Info("Throttled back-in-stock notification",
+			zap.String("subscription_id", sub.ID.String()),
+			zap.String("customer_id", customerID.String()))
+		metrics.RecordNotification(false, metrics.NotificationThrottled)
+		return resultSkipped
+	}
+
+	notification := sub.Notification(stockQuantity)
+	if s.policy != nil {
+		decision, err := s.policy.ShouldNotify(ctx, customerID, domain.NotificationTypeBackInStock)
+		if err != nil {
+			s.logger.Error("Failed to check notification preferences",
+				zap.String("subscription_id", sub.ID.String()),
+				zap.Error(err))
+			return resultFailed
+		}
+		if !decision.Allowed {
+			s.logger.Info("Held back back-in-stock notification",
+				zap.String("subscription_id", sub.ID.String()),
+				zap.String("customer_id", customerID.String()),
+				zap.String("reason", decision.Reason))
+			metrics.RecordNotification(false, metrics.NotificationDeferred)
+			return resultSkipped
+		}
+		notification.Channel = decision.Channel
+	}
+
+	// Send notification
+	if s.notificationClient != nil {
+		if err := s.notificationClient.SendBackInStockNotification(ctx, notification); err != nil {
+			s.logger.Error("Failed to send notification",
+				zap.String("subscription_id", sub.ID.String()),
+				zap.Error(err))
+			metrics.RecordNotification(false, metrics.NotificationFailed)
+			if s.queueRetry(ctx, sub.ID, false, notification.StockQuantity, err) {
+				return resultRetried
+			}
+			return resultFailed
+		}
+	}
+
+	metrics.RecordNotification(false, metrics.NotificationSent)
+	if s.policy != nil {
+		s.policy.Sent(ctx, customerID, domain.NotificationTypeBackInStock, notification.Channel)
+	}
+	sent[customerID.String()]++
+	return resultSent
 }
 
-// NewSimpleNotificationClient creates a new notification client
-func NewSimpleNotificationClient(baseURL string, logger *zap.Logger) *SimpleNotificationClient {
-	return &SimpleNotificationClient{
-		baseURL: baseURL,
-		logger:  logger,
+// sendGuest notifies a confirmed guest subscriber, subject to the throttle
+func (s *BackInStockSubscriber) sendGuest(ctx context.Context, sub domain.GuestBackInStockSubscription, stockQuantity int, sent map[string]int64) sendResult {
+	email := sub.Email
+	if !s.allow(ctx, sent, email, func(ctx context.Context, since time.Time) (int64, error) {
+		return s.guestRepo.CountNotifiedSince(ctx, email, since)
+	}) {
+		s.logger.Info("Throttled guest back-in-stock notification",
+			zap.String("subscription_id", sub.ID.String()))
+		metrics.RecordNotification(true, metrics.NotificationThrottled)
+		return resultSkipped
 	}
+
+	notification := sub.Notification(stockQuantity)
+
+	if s.notificationClient != nil {
+		if err := s.notificationClient.SendBackInStockNotification(ctx, notification); err != nil {
+			s.logger.Error("Failed to send guest notification",
+				zap.String("subscription_id", sub.ID.String()),
+				zap.Error(err))
+			metrics.RecordNotification(true, metrics.NotificationFailed)
+			if s.queueRetry(ctx, sub.ID, true, notification.StockQuantity, err) {
+				return resultRetried
+			}
+			return resultFailed
+		}
+	}
+
+	metrics.RecordNotification(true, metrics.NotificationSent)
+	sent[email]++
+	return resultSent
 }
 
-// SendBackInStockNotification sends a back-in-stock notification
-func (c *SimpleNotificationClient) SendBackInStockNotification(notification domain.BackInStockNotification) error {
-	// In a real implementation, this would make an HTTP call to the notification service
-	// For now, we'll log the notification
-	c.logger.Info("Sending back-in-stock notification",
-		zap.String("customer_email", notification.CustomerEmail),
-		zap.String("product_name", notification.ProductName),
-		zap.Int("stock_quantity", notification.StockQuantity))
+// queueRetry hands a failed send to the retry queue. It reports false when
+// there is no queue or the retry couldn't be stored; the send then counts as
+// failed and the event is redelivered.
+func (s *BackInStockSubscriber) queueRetry(ctx context.Context, subscriptionID uuid.UUID, isGuest bool, stockQuantity int, sendErr error) bool {
+	if s.retries == nil {
+		return false
+	}
+	next, ok := s.retryPolicy.NextAttempt(1, time.Now())
+	if !ok {
+		return false
+	}
 
-	// TODO: Implement actual HTTP call to notification service
-	// POST to c.baseURL + "/api/v1/notifications/back-in-stock"
+	err := s.retries.Enqueue(ctx, &domain.NotificationRetry{
+		SubscriptionID: subscriptionID,
+		IsGuest:        isGuest,
+		StockQuantity:  stockQuantity,
+		Attempts:       1,
+		LastError:      sendErr.Error(),
+		NextAttemptAt:  next,
+	})
+	if err != nil {
+		s.logger.Error("Failed to queue notification retry",
+			zap.String("subscription_id", subscriptionID.String()),
+			zap.Error(err))
+		return false
+	}
+	return true
+}
 
-	return nil
+// allow reports whether a recipient is still under the notification throttle.
+// sent holds each recipient's count for the current event; the count from
+// earlier events is loaded with countSince the first time a recipient is seen.
+// If it can't be loaded the notification is sent rather than lost.
+func (s *BackInStockSubscriber) allow(ctx context.Context, sent map[string]int64, recipient string, countSince func(context.Context, time.Time) (int64, error)) bool {
+	if s.throttle.Limit <= 0 {
+		return true
+	}
+
+	if _, seen := sent[recipient]; !seen {
+		count, err := countSince(ctx, time.Now().Add(-s.throttle.Window))
+		if err != nil {
+			s.logger.Error("Failed to count recent back-in-stock notifications", zap.Error(err))
+			return true
+		}
+		sent[recipient] = count
+	}
+	return sent[recipient] < int64(s.throttle.Limit)
 }
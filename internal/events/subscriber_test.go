@@ -0,0 +1,294 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/persistence"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// openTestDB opens an in-memory SQLite database for the models, with the
+// same adjustments as the persistence tests: no schemas in table names and
+// no Postgres defaults
+func openTestDB(t *testing.T, models ...interface{}) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+
+	for _, model := range models {
+		stmt := &gorm.Statement{DB: db}
+		require.NoError(t, stmt.Parse(model))
+		stmt.Schema.Table = strings.ReplaceAll(stmt.Schema.Table, ".", "_")
+		for _, field := range stmt.Schema.Fields {
+			if strings.HasSuffix(field.DefaultValue, "()") {
+				field.DefaultValue = ""
+				field.HasDefaultValue = false
+				field.DefaultValueInterface = nil
+			}
+		}
+	}
+	require.NoError(t, db.AutoMigrate(models...))
+	return db
+}
+
+// recordingNotifier records the subscriptions it was asked to notify and
+// fails the sends of those in fail
+type recordingNotifier struct {
+	sent []string
+	fail map[string]bool
+}
+
+func (n *recordingNotifier) SendBackInStockNotification(_ context.Context, notification domain.BackInStockNotification) error {
+	if n.fail[notification.SubscriptionID] {
+		return errors.New("notification service unavailable")
+	}
+	n.sent = append(n.sent, notification.SubscriptionID)
+	return nil
+}
+
+// backInStockFixture is a product with customers and confirmed guests waiting
+// for it, one minute apart in the order given
+type backInStockFixture struct {
+	db         *gorm.DB
+	productID  uuid.UUID
+	subscriber *BackInStockSubscriber
+	notifier   *recordingNotifier
+}
+
+func newBackInStockFixture(t *testing.T) *backInStockFixture {
+	db := openTestDB(t, &domain.Customer{}, &domain.BackInStockSubscription{}, &domain.GuestBackInStockSubscription{}, &domain.NotificationRetry{})
+	notifier := &recordingNotifier{fail: map[string]bool{}}
+	subscriber := NewBackInStockSubscriber(nil, persistence.NewBackInStockRepository(db), notifier, zap.NewNop()).
+		WithGuestSubscriptions(persistence.NewGuestBackInStockRepository(db))
+	return &backInStockFixture{db: db, productID: uuid.New(), subscriber: subscriber, notifier: notifier}
+}
+
+// wait subscribes a customer ("c") or confirmed guest ("g") for each
+// letter of who, longest waiting first, and returns their subscription IDs
+func (f *backInStockFixture) wait(t *testing.T, who string) []string {
+	start := time.Now().Add(-time.Hour)
+	ids := make([]string, len(who))
+	for i, kind := range who {
+		createdAt := start.Add(time.Duration(i) * time.Minute)
+		if kind == 'g' {
+			guest := domain.GuestBackInStockSubscription{
+				Email: uuid.NewString() + "@example.com", ProductID: f.productID,
+				TokenHash: uuid.NewString(), ConfirmedAt: &createdAt, CreatedAt: createdAt,
+			}
+			require.NoError(t, f.db.Create(&guest).Error)
+			ids[i] = guest.ID.String()
+			continue
+		}
+		customer := domain.Customer{ID: uuid.New(), Email: uuid.NewString() + "@example.com"}
+		require.NoError(t, f.db.Create(&customer).Error)
+		sub := domain.BackInStockSubscription{CustomerID: customer.ID, ProductID: f.productID, CreatedAt: createdAt}
+		require.NoError(t, f.db.Create(&sub).Error)
+		ids[i] = sub.ID.String()
+	}
+	return ids
+}
+
+// notified returns the IDs of the subscriptions marked notified
+func (f *backInStockFixture) notified(t *testing.T) []string {
+	var ids []string
+	var subs []domain.BackInStockSubscription
+	require.NoError(t, f.db.Where("is_notified = ?", true).Find(&subs).Error)
+	for _, s := range subs {
+		ids = append(ids, s.ID.String())
+	}
+	var guests []domain.GuestBackInStockSubscription
+	require.NoError(t, f.db.Where("is_notified = ?", true).Find(&guests).Error)
+	for _, g := range guests {
+		ids = append(ids, g.ID.String())
+	}
+	return ids
+}
+
+func TestFirstInLine(t *testing.T) {
+	base := time.Now()
+	at := func(minute int) time.Time { return base.Add(time.Duration(minute) * time.Minute) }
+	customer := func(minute int) domain.BackInStockSubscription {
+		return domain.BackInStockSubscription{ID: uuid.New(), CreatedAt: at(minute)}
+	}
+	guest := func(minute int) domain.GuestBackInStockSubscription {
+		return domain.GuestBackInStockSubscription{ID: uuid.New(), CreatedAt: at(minute)}
+	}
+
+	tests := []struct {
+		name          string
+		subscriptions []domain.BackInStockSubscription
+		guests        []domain.GuestBackInStockSubscription
+		n             int
+		want          string // "c" or "g" and the minute subscribed, in line order
+	}{
+		{
+			name:          "customers and guests interleaved by subscription time",
+			subscriptions: []domain.BackInStockSubscription{customer(1), customer(4), customer(5)},
+			guests:        []domain.GuestBackInStockSubscription{guest(2), guest(3), guest(6)},
+			n:             4,
+			want:          "c1 g2 g3 c4",
+		},
+		{
+			name:          "n larger than the waiting list",
+			subscriptions: []domain.BackInStockSubscription{customer(2)},
+			guests:        []domain.GuestBackInStockSubscription{guest(1)},
+			n:             10,
+			want:          "g1 c2",
+		},
+		{
+			name:          "n of 0",
+			subscriptions: []domain.BackInStockSubscription{customer(1)},
+			guests:        []domain.GuestBackInStockSubscription{guest(2)},
+			n:             0,
+			want:          "",
+		},
+		{
+			name:          "customers first on ties",
+			subscriptions: []domain.BackInStockSubscription{customer(1), customer(2)},
+			guests:        []domain.GuestBackInStockSubscription{guest(1), guest(2)},
+			n:             3,
+			want:          "c1 g1 c2",
+		},
+		{
+			name:   "guests only",
+			guests: []domain.GuestBackInStockSubscription{guest(1), guest(2)},
+			n:      1,
+			want:   "g1",
+		},
+		{
+			name: "nobody waiting",
+			n:    3,
+			want: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, q := range firstInLine(tt.subscriptions, tt.guests, tt.n) {
+				kind := "c"
+				if q.guest != nil {
+					kind = "g"
+				}
+				got = append(got, kind+strconv.Itoa(int(q.createdAt().Sub(base).Minutes())))
+			}
+			assert.Equal(t, tt.want, strings.Join(got, " "))
+		})
+	}
+}
+
+func TestProcessRestock_PartialRestockSkipsThoseWhoCantBeNotified(t *testing.T) {
+	f := newBackInStockFixture(t)
+	f.subscriber.WithPartialRestocks(true)
+	ids := f.wait(t, "cgcgc")
+
+	// The first in line can't be reached; the restock of two units goes to
+	// the next two instead of being used up
+	f.notifier.fail[ids[0]] = true
+	err := f.subscriber.ProcessRestock(context.Background(), ProductRestockedEvent{ProductID: f.productID.String(), Quantity: 2})
+	require.Error(t, err, "the failed send is redelivered")
+
+	assert.Equal(t, []string{ids[1], ids[2]}, f.notifier.sent)
+	assert.ElementsMatch(t, []string{ids[1], ids[2]}, f.notified(t))
+
+	// The redelivery only goes to those still pending, up to the units
+	delete(f.notifier.fail, ids[0])
+	f.notifier.sent = nil
+	require.NoError(t, f.subscriber.ProcessRestock(context.Background(), ProductRestockedEvent{ProductID: f.productID.String(), Quantity: 2}))
+	assert.Equal(t, []string{ids[0], ids[3]}, f.notifier.sent)
+}
+
+func TestProcessRestock_PartialRestockSkipsThrottled(t *testing.T) {
+	f := newBackInStockFixture(t)
+	f.subscriber.WithPartialRestocks(true).WithThrottle(domain.BackInStockThrottle{Limit: 1, Window: time.Hour})
+	ids := f.wait(t, "ccc")
+
+	// The first in line was notified of another product a moment ago
+	var first domain.BackInStockSubscription
+	require.NoError(t, f.db.First(&first, "id = ?", ids[0]).Error)
+	sentAt := time.Now()
+	require.NoError(t, f.db.Create(&domain.BackInStockSubscription{
+		CustomerID: first.CustomerID, ProductID: uuid.New(), IsNotified: true, NotificationSentAt: &sentAt,
+	}).Error)
+
+	require.NoError(t, f.subscriber.ProcessRestock(context.Background(), ProductRestockedEvent{ProductID: f.productID.String(), Quantity: 1}))
+	assert.Equal(t, []string{ids[1]}, f.notifier.sent)
+}
+
+func TestNotifyProduct_DryRunSendsNothing(t *testing.T) {
+	f := newBackInStockFixture(t)
+	ids := f.wait(t, "cgc")
+
+	result, err := f.subscriber.NotifyProduct(context.Background(), f.productID, nil, 5, 2, true)
+	require.NoError(t, err)
+	assert.True(t, result.DryRun)
+	assert.Equal(t, 3, result.Waiting)
+	assert.Equal(t, 2, result.Selected)
+	assert.Zero(t, result.Notified)
+	require.Len(t, result.Recipients, 2)
+	var recipients []string
+	for _, r := range result.Recipients {
+		recipients = append(recipients, r.SubscriptionID.String())
+		assert.NotEmpty(t, r.Email)
+	}
+	assert.ElementsMatch(t, ids[:2], recipients, "the longest waiting")
+
+	assert.Empty(t, f.notifier.sent)
+	assert.Empty(t, f.notified(t))
+}
+
+func TestNotifyProduct_LimitCountsOnlySentNotifications(t *testing.T) {
+	f := newBackInStockFixture(t)
+	ids := f.wait(t, "cgcc")
+	f.notifier.fail[ids[1]] = true
+
+	result, err := f.subscriber.NotifyProduct(context.Background(), f.productID, nil, 5, 2, false)
+	require.Error(t, err)
+	assert.Equal(t, 4, result.Waiting)
+	assert.Equal(t, 3, result.Selected)
+	assert.Equal(t, 2, result.Notified)
+	assert.Equal(t, []string{ids[0], ids[2]}, f.notifier.sent)
+	assert.ElementsMatch(t, []string{ids[0], ids[2]}, f.notified(t))
+}
+
+func TestNotifyProduct_NoLimitNotifiesEveryone(t *testing.T) {
+	f := newBackInStockFixture(t)
+	ids := f.wait(t, "gcg")
+
+	result, err := f.subscriber.NotifyProduct(context.Background(), f.productID, nil, 5, 0, false)
+	require.NoError(t, err)
+	assert.Equal(t, 3, result.Notified)
+	assert.Equal(t, ids, f.notifier.sent)
+}
+
+func TestProcessRestock_LeavesQueuedRetriesToTheRetryJob(t *testing.T) {
+	f := newBackInStockFixture(t)
+	retries := persistence.NewNotificationRetryRepository(f.db)
+	f.subscriber.WithRetryQueue(retries, domain.DefaultNotificationRetryPolicy)
+	ids := f.wait(t, "cgc")
+
+	// The first send to the guest fails and is queued for retry
+	f.notifier.fail[ids[1]] = true
+	require.NoError(t, f.subscriber.ProcessRestock(context.Background(), ProductRestockedEvent{ProductID: f.productID.String()}))
+	assert.Equal(t, []string{ids[0], ids[2]}, f.notifier.sent)
+
+	// Another restock doesn't send to the guest again while the retry is queued
+	delete(f.notifier.fail, ids[1])
+	f.notifier.sent = nil
+	require.NoError(t, f.subscriber.ProcessRestock(context.Background(), ProductRestockedEvent{ProductID: f.productID.String()}))
+	assert.Empty(t, f.notifier.sent)
+
+	guestID := uuid.MustParse(ids[1])
+	queued, err := retries.Queued(context.Background(), []uuid.UUID{guestID})
+	require.NoError(t, err)
+	assert.True(t, queued[guestID])
+}
@@ -0,0 +1,167 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nats-io/nats.go"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/metrics"
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/tracing"
+	"go.uber.org/zap"
+)
+
+// Wishlist analytics subjects, consumed by the analytics pipeline for
+// wishlist-to-purchase conversion
+const (
+	SubjectWishlistItemAdded   = "customer.wishlist.item_added"
+	SubjectWishlistItemRemoved = "customer.wishlist.item_removed"
+)
+
+// wishlistAnalyticsFlushTimeout bounds how long a batch waits for the server
+// to acknowledge it
+const wishlistAnalyticsFlushTimeout = 5 * time.Second
+
+// WishlistItemEvent is published when a customer adds a product to, or
+// removes it from, their wishlist
+type WishlistItemEvent struct {
+	EventID    string    `json:"event_id"`
+	CustomerID string    `json:"customer_id"`
+	ItemID     string    `json:"item_id"`
+	ProductID  string    `json:"product_id"`
+	VariantID  string    `json:"variant_id,omitempty"`
+	VariantSKU string    `json:"variant_sku,omitempty"`
+	PriceAtAdd float64   `json:"price_at_add"`
+	AddedAt    time.Time `json:"added_at"`
+	OccurredAt time.Time `json:"occurred_at"`
+}
+
+type queuedWishlistEvent struct {
+	ctx     context.Context
+	subject string
+	event   WishlistItemEvent
+}
+
+// WishlistAnalyticsPublisher publishes wishlist item events in the
+// background, so a slow or unreachable NATS server doesn't add latency to
+// wishlist requests. Events are queued and published in batches by Run;
+// when the queue is full they are dropped and counted.
+type WishlistAnalyticsPublisher struct {
+	nc            *nats.Conn
+	queue         chan queuedWishlistEvent
+	batchSize     int
+	flushInterval time.Duration
+	done          chan struct{}
+	logger        *zap.Logger
+}
+
+// NewWishlistAnalyticsPublisher creates a publisher queueing up to queueSize
+// events and publishing up to batchSize of them at a time, at least every
+// flushInterval
+func NewWishlistAnalyticsPublisher(nc *nats.Conn, queueSize, batchSize int, flushInterval time.Duration, logger *zap.Logger) *WishlistAnalyticsPublisher {
+	return &WishlistAnalyticsPublisher{
+		nc:            nc,
+		queue:         make(chan queuedWishlistEvent, max(queueSize, 1)),
+		batchSize:     max(batchSize, 1),
+		flushInterval: flushInterval,
+		done:          make(chan struct{}),
+		logger:        logger,
+	}
+}
+
+// ItemAdded queues an item added event
+func (p *WishlistAnalyticsPublisher) ItemAdded(ctx context.Context, item domain.WishlistItem) {
+	p.enqueue(ctx, SubjectWishlistItemAdded, item)
+}
+
+// ItemRemoved queues an item removed event
+func (p *WishlistAnalyticsPublisher) ItemRemoved(ctx context.Context, item domain.WishlistItem) {
+	p.enqueue(ctx, SubjectWishlistItemRemoved, item)
+}
+
+func (p *WishlistAnalyticsPublisher) enqueue(ctx context.Context, subject string, item domain.WishlistItem) {
+	event := WishlistItemEvent{
+		EventID:    uuid.NewString(),
+		CustomerID: item.UserID.String(),
+		ItemID:     item.ID.String(),
+		ProductID:  item.ProductID.String(),
+		VariantSKU: stringValue(item.VariantSKU),
+		PriceAtAdd: item.PriceAtAdd,
+		AddedAt:    item.CreatedAt.UTC(),
+		OccurredAt: time.Now().UTC(),
+	}
+	if item.VariantID != nil {
+		event.VariantID = item.VariantID.String()
+	}
+
+	select {
+	case p.queue <- queuedWishlistEvent{ctx: context.WithoutCancel(ctx), subject: subject, event: event}:
+	default:
+		metrics.EventsDropped.Inc(subject)
+	}
+}
+
+// Run publishes queued events until ctx is done, then publishes what is
+// still queued and returns
+func (p *WishlistAnalyticsPublisher) Run(ctx context.Context) {
+	defer close(p.done)
+	ticker := time.NewTicker(p.flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]queuedWishlistEvent, 0, p.batchSize)
+	for {
+		select {
+		case <-ctx.Done():
+			for {
+				select {
+				case queued := <-p.queue:
+					if batch = append(batch, queued); len(batch) == p.batchSize {
+						batch = p.publish(batch)
+					}
+				default:
+					p.publish(batch)
+					return
+				}
+			}
+		case queued := <-p.queue:
+			if batch = append(batch, queued); len(batch) == p.batchSize {
+				batch = p.publish(batch)
+			}
+		case <-ticker.C:
+			batch = p.publish(batch)
+		}
+	}
+}
+
+// Wait blocks until Run has published what was queued when it stopped
+func (p *WishlistAnalyticsPublisher) Wait() {
+	<-p.done
+}
+
+// publish sends a batch and waits for the server to have it, returning the
+// emptied batch for reuse
+func (p *WishlistAnalyticsPublisher) publish(batch []queuedWishlistEvent) []queuedWishlistEvent {
+	if len(batch) == 0 {
+		return batch
+	}
+
+	failed := 0
+	for _, queued := range batch {
+		data, err := json.Marshal(queued.event)
+		if err == nil {
+			err = tracing.Publish(queued.ctx, p.nc, queued.subject, data)
+		}
+		if err != nil {
+			failed++
+		}
+	}
+	if err := p.nc.FlushTimeout(wishlistAnalyticsFlushTimeout); err != nil {
+		p.logger.Warn("Failed to flush wishlist analytics events", zap.Int("events", len(batch)), zap.Error(err))
+	}
+	if failed > 0 {
+		p.logger.Warn("Failed to publish wishlist analytics events", zap.Int("failed", failed), zap.Int("events", len(batch)))
+	}
+	return batch[:0]
+}
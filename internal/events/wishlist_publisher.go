@@ -0,0 +1,100 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nats-io/nats.go"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/tracing"
+	"go.uber.org/zap"
+)
+
+// SubjectWishlistStillInterested is published when a customer is asked
+// whether they still want items saved long ago
+const SubjectWishlistStillInterested = "customer.wishlist.still_interested"
+
+// WishlistStillInterestedEvent is consumed by the notification service to
+// send the "still interested?" email. Items not confirmed by ArchiveAt are
+// archived.
+type WishlistStillInterestedEvent struct {
+	CustomerID string                        `json:"customer_id"`
+	Items      []StillInterestedWishlistItem `json:"items"`
+	ArchiveAt  time.Time                     `json:"archive_at"`
+	OccurredAt time.Time                     `json:"occurred_at"`
+}
+
+// StillInterestedWishlistItem is one stale item in a WishlistStillInterestedEvent
+type StillInterestedWishlistItem struct {
+	ItemID       string    `json:"item_id"`
+	ProductID    string    `json:"product_id"`
+	VariantID    string    `json:"variant_id,omitempty"`
+	VariantName  string    `json:"variant_name,omitempty"`
+	ProductName  string    `json:"product_name,omitempty"`
+	ProductSlug  string    `json:"product_slug,omitempty"`
+	ProductImage string    `json:"product_image,omitempty"`
+	AddedAt      time.Time `json:"added_at"`
+}
+
+// WishlistEventPublisher publishes wishlist events to NATS
+type WishlistEventPublisher struct {
+	nc     *nats.Conn
+	logger *zap.Logger
+}
+
+// NewWishlistEventPublisher creates a new wishlist event publisher
+func NewWishlistEventPublisher(nc *nats.Conn, logger *zap.Logger) *WishlistEventPublisher {
+	return &WishlistEventPublisher{
+		nc:     nc,
+		logger: logger,
+	}
+}
+
+// NotifyStillInterested publishes a still interested event for the
+// customer's stale items
+func (p *WishlistEventPublisher) NotifyStillInterested(ctx context.Context, customerID uuid.UUID, items []domain.WishlistItem, archiveAt time.Time) error {
+	event := WishlistStillInterestedEvent{
+		CustomerID: customerID.String(),
+		Items:      make([]StillInterestedWishlistItem, len(items)),
+		ArchiveAt:  archiveAt.UTC(),
+		OccurredAt: time.Now().UTC(),
+	}
+	for i, item := range items {
+		event.Items[i] = StillInterestedWishlistItem{
+			ItemID:       item.ID.String(),
+			ProductID:    item.ProductID.String(),
+			VariantName:  stringValue(item.VariantName),
+			ProductName:  stringValue(item.ProductName),
+			ProductSlug:  stringValue(item.ProductSlug),
+			ProductImage: stringValue(item.ProductImage),
+			AddedAt:      item.CreatedAt.UTC(),
+		}
+		if item.VariantID != nil {
+			event.Items[i].VariantID = item.VariantID.String()
+		}
+	}
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	if err := tracing.Publish(ctx, p.nc, SubjectWishlistStillInterested, data); err != nil {
+		return err
+	}
+
+	p.logger.Info("Published wishlist still interested event",
+		zap.String("customer_id", event.CustomerID),
+		zap.Int("items", len(items)))
+	return nil
+}
+
+// stringValue returns the string s points to, or "" if it is nil
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
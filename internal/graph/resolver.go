@@ -0,0 +1,187 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	graphql "github.com/graph-gophers/graphql-go"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/persistence"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// Resolver is the root of the graph
+type Resolver struct {
+	profiles     *persistence.ProfileRepository
+	addresses    *persistence.AddressRepository
+	wishlist     *persistence.WishlistRepository
+	measurements *persistence.MeasurementRepository
+	backInStock  *persistence.BackInStockRepository
+	customers    persistence.CustomerRepository
+	logger       *zap.Logger
+}
+
+// NewResolver creates the root resolver
+func NewResolver(db *gorm.DB, customers persistence.CustomerRepository, logger *zap.Logger) *Resolver {
+	return &Resolver{
+		profiles:     persistence.NewProfileRepository(db),
+		addresses:    persistence.NewAddressRepository(db),
+		wishlist:     persistence.NewWishlistRepository(db),
+		measurements: persistence.NewMeasurementRepository(db),
+		backInStock:  persistence.NewBackInStockRepository(db),
+		customers:    customers,
+		logger:       logger,
+	}
+}
+
+// Me resolves the signed-in customer's account
+func (r *Resolver) Me(ctx context.Context) (*accountResolver, error) {
+	viewer, ok := viewerFrom(ctx)
+	if !ok {
+		return nil, &AccessError{Field: "me"}
+	}
+	return &accountResolver{root: r, customerID: viewer.CustomerID}, nil
+}
+
+// Account resolves any customer's account for staff
+func (r *Resolver) Account(ctx context.Context, args struct{ CustomerID graphql.ID }) (*accountResolver, error) {
+	if err := requireStaff(ctx, "account"); err != nil {
+		return nil, err
+	}
+	customerID, err := uuid.Parse(string(args.CustomerID))
+	if err != nil {
+		return nil, errors.New("customerId must be a UUID")
+	}
+	return &accountResolver{root: r, customerID: customerID}, nil
+}
+
+// loadFailed logs a failed lookup and returns an error that doesn't leak
+// database details to the client
+func (r *Resolver) loadFailed(field string, customerID uuid.UUID, err error) error {
+	r.logger.Error("Failed to resolve GraphQL field",
+		zap.String("field", field), zap.String("customer_id", customerID.String()), zap.Error(err))
+	return fmt.Errorf("failed to load %s", field)
+}
+
+type accountResolver struct {
+	root       *Resolver
+	customerID uuid.UUID
+}
+
+func (a *accountResolver) CustomerID() graphql.ID {
+	return id(a.customerID)
+}
+
+func (a *accountResolver) Profile(ctx context.Context) (*profileResolver, error) {
+	profile, err := a.root.profiles.GetByUserID(ctx, a.customerID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, a.root.loadFailed("profile", a.customerID, err)
+	}
+	return &profileResolver{profile}, nil
+}
+
+func (a *accountResolver) Addresses(ctx context.Context) ([]*addressResolver, error) {
+	addresses, err := a.root.addresses.ListByUserID(ctx, a.customerID)
+	if err != nil {
+		return nil, a.root.loadFailed("addresses", a.customerID, err)
+	}
+	resolvers := make([]*addressResolver, len(addresses))
+	for i := range addresses {
+		resolvers[i] = &addressResolver{&addresses[i]}
+	}
+	return resolvers, nil
+}
+
+func (a *accountResolver) DefaultAddress(ctx context.Context) (*addressResolver, error) {
+	addresses, err := a.Addresses(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, address := range addresses {
+		if address.a.IsDefault {
+			return address, nil
+		}
+	}
+	return nil, nil
+}
+
+func (a *accountResolver) Wishlist(ctx context.Context) ([]*wishlistItemResolver, error) {
+	items, err := a.root.wishlist.ListByUserID(ctx, a.customerID)
+	if err != nil {
+		return nil, a.root.loadFailed("wishlist", a.customerID, err)
+	}
+	resolvers := make([]*wishlistItemResolver, len(items))
+	for i := range items {
+		resolvers[i] = &wishlistItemResolver{&items[i]}
+	}
+	return resolvers, nil
+}
+
+func (a *accountResolver) Measurements(ctx context.Context, args struct {
+	Person *string
+	Unit   *string
+}) ([]*measurementResolver, error) {
+	unit := ""
+	if args.Unit != nil {
+		unit = *args.Unit
+		if !domain.IsValidMeasurementUnit(unit) {
+			return nil, errors.New("unit must be cm or inch")
+		}
+	}
+	person := ""
+	if args.Person != nil && *args.Person != "" {
+		person = domain.NormalizeProfilePerson(*args.Person)
+	}
+
+	measurements, err := a.root.measurements.GetByUserID(ctx, a.customerID, person)
+	if err != nil {
+		return nil, a.root.loadFailed("measurements", a.customerID, err)
+	}
+	resolvers := make([]*measurementResolver, len(measurements))
+	for i, measurement := range measurements {
+		// Like the REST API: the requested unit, else the one it was entered in
+		display := unit
+		if display == "" {
+			display = measurement.Unit
+		}
+		if display == "" {
+			display = domain.MeasurementUnitCM
+		}
+		converted := measurement.InUnit(display)
+		resolvers[i] = &measurementResolver{&converted}
+	}
+	return resolvers, nil
+}
+
+func (a *accountResolver) BackInStockSubscriptions(ctx context.Context) ([]*backInStockResolver, error) {
+	subscriptions, err := a.root.backInStock.GetByCustomer(ctx, a.customerID)
+	if err != nil {
+		return nil, a.root.loadFailed("backInStockSubscriptions", a.customerID, err)
+	}
+	resolvers := make([]*backInStockResolver, len(subscriptions))
+	for i := range subscriptions {
+		resolvers[i] = &backInStockResolver{&subscriptions[i]}
+	}
+	return resolvers, nil
+}
+
+// Insights is staff only: customers don't see their scores and churn risk
+func (a *accountResolver) Insights(ctx context.Context) (*insightsResolver, error) {
+	if err := requireStaff(ctx, "insights"); err != nil {
+		return nil, err
+	}
+	customer, err := a.root.customers.GetByID(ctx, a.customerID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, a.root.loadFailed("insights", a.customerID, err)
+	}
+	return &insightsResolver{customer}, nil
+}
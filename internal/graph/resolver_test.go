@@ -0,0 +1,136 @@
+package graph
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	graphql "github.com/graph-gophers/graphql-go"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/Ecom-micro-template/service-customer/internal/domain/shared"
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/persistence"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// openTestDB opens an in-memory SQLite database for the models, with the
+// same adjustments as the persistence tests: no schemas in table names and
+// no Postgres defaults
+func openTestDB(t *testing.T, models ...interface{}) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+
+	for _, model := range models {
+		stmt := &gorm.Statement{DB: db}
+		require.NoError(t, stmt.Parse(model))
+		stmt.Schema.Table = strings.ReplaceAll(stmt.Schema.Table, ".", "_")
+		for _, field := range stmt.Schema.Fields {
+			if strings.HasSuffix(field.DefaultValue, "()") {
+				field.DefaultValue = ""
+				field.HasDefaultValue = false
+				field.DefaultValueInterface = nil
+			}
+		}
+	}
+	require.NoError(t, db.AutoMigrate(models...))
+	return db
+}
+
+func testSchema(t *testing.T) (*graphql.Schema, *gorm.DB) {
+	db := openTestDB(t, &domain.Customer{}, &domain.Profile{}, &domain.Address{}, &domain.WishlistItem{},
+		&domain.CustomerMeasurement{}, &domain.BackInStockSubscription{})
+	schema, err := NewSchema(NewResolver(db, persistence.NewCustomerRepository(db, persistence.DefaultQueryTimeouts()), zap.NewNop()), false)
+	require.NoError(t, err)
+	return schema, db
+}
+
+// exec runs the query and returns its data and the paths of the fields that
+// were refused
+func exec(t *testing.T, schema *graphql.Schema, viewer Viewer, query string, variables map[string]interface{}) (map[string]interface{}, []string) {
+	result := schema.Exec(WithViewer(context.Background(), viewer), query, "", variables)
+	var forbidden []string
+	for _, err := range result.Errors {
+		require.Equal(t, "FORBIDDEN", err.Extensions["code"], err.Message)
+		path := make([]string, len(err.Path))
+		for i, segment := range err.Path {
+			path[i] = fmt.Sprint(segment)
+		}
+		forbidden = append(forbidden, strings.Join(path, "."))
+	}
+	var data map[string]interface{}
+	require.NoError(t, json.Unmarshal(result.Data, &data))
+	return data, forbidden
+}
+
+func TestAccountGraph_CustomerSeesOwnAccount(t *testing.T) {
+	schema, db := testSchema(t)
+	customerID := uuid.New()
+	other := uuid.New()
+	inches := 100.0
+
+	require.NoError(t, db.Create(&domain.Customer{ID: customerID, Email: "aisyah@example.com", TotalSpent: 420}).Error)
+	require.NoError(t, db.Create(&domain.Profile{ID: customerID, FullName: "Nur Aisyah", Email: "aisyah@example.com"}).Error)
+	require.NoError(t, db.Create(&domain.Address{UserID: customerID, RecipientName: "Aisyah", Phone: "0123456789",
+		AddressLine1: "1 Jalan Ampang", City: "Kuala Lumpur", State: "WP", Postcode: "50450", Country: "MY", IsDefault: true}).Error)
+	require.NoError(t, db.Create(&domain.Address{UserID: other, RecipientName: "Farid", Phone: "0198765432",
+		AddressLine1: "2 Jalan Tun Razak", City: "Kuala Lumpur", State: "WP", Postcode: "50400", Country: "MY"}).Error)
+	require.NoError(t, db.Create(&domain.WishlistItem{UserID: customerID, ProductID: uuid.New(), PriceAtAdd: 89.9}).Error)
+	require.NoError(t, db.Create(&domain.CustomerMeasurement{UserID: customerID, Gender: shared.GenderWomen, Waist: &inches, Unit: domain.MeasurementUnitInch}).Error)
+	require.NoError(t, db.Create(&domain.BackInStockSubscription{CustomerID: customerID, ProductID: uuid.New(), ProductName: "Baju Kurung", ProductSlug: "baju-kurung", CreatedAt: time.Now()}).Error)
+
+	data, forbidden := exec(t, schema, Viewer{CustomerID: customerID}, `{
+		me {
+			customerId
+			profile { fullName }
+			addresses { recipientName }
+			defaultAddress { city }
+			wishlist { priceAtAdd }
+			measurements(unit: "cm") { waist unit }
+			entered: measurements { waist unit }
+			backInStockSubscriptions { productName }
+			insights { totalSpent }
+		}
+	}`, nil)
+
+	me := data["me"].(map[string]interface{})
+	assert.Equal(t, customerID.String(), me["customerId"])
+	assert.Equal(t, "Nur Aisyah", me["profile"].(map[string]interface{})["fullName"])
+	assert.Len(t, me["addresses"], 1, "only the customer's own addresses")
+	assert.Equal(t, "Kuala Lumpur", me["defaultAddress"].(map[string]interface{})["city"])
+	assert.Equal(t, 89.9, me["wishlist"].([]interface{})[0].(map[string]interface{})["priceAtAdd"])
+	assert.Equal(t, map[string]interface{}{"waist": 100.0, "unit": "cm"}, me["measurements"].([]interface{})[0])
+	entered := me["entered"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, "inch", entered["unit"], "in the unit it was entered in by default")
+	assert.InDelta(t, 39.4, entered["waist"], 0.05)
+	assert.Equal(t, "Baju Kurung", me["backInStockSubscriptions"].([]interface{})[0].(map[string]interface{})["productName"])
+	assert.Nil(t, me["insights"], "insights are staff only")
+	assert.Equal(t, []string{"me.insights"}, forbidden)
+
+	data, forbidden = exec(t, schema, Viewer{CustomerID: customerID}, `query($id: ID!) { account(customerId: $id) { customerId } }`,
+		map[string]interface{}{"id": other.String()})
+	assert.Nil(t, data["account"])
+	assert.Equal(t, []string{"account"}, forbidden, "customers can't look up other accounts")
+}
+
+func TestAccountGraph_StaffSeesAnyAccount(t *testing.T) {
+	schema, db := testSchema(t)
+	customerID := uuid.New()
+	require.NoError(t, db.Create(&domain.Customer{ID: customerID, Email: "farid@example.com", TotalOrders: 3, TotalSpent: 150, RFMSegment: "loyal"}).Error)
+
+	data, forbidden := exec(t, schema, Viewer{CustomerID: uuid.New(), Staff: true},
+		`query($id: ID!) { account(customerId: $id) { profile { fullName } addresses { id } insights { totalOrders rfmSegment churnRisk } } }`,
+		map[string]interface{}{"id": customerID.String()})
+
+	assert.Empty(t, forbidden)
+	account := data["account"].(map[string]interface{})
+	assert.Nil(t, account["profile"], "no profile saved yet")
+	assert.Empty(t, account["addresses"])
+	assert.Equal(t, map[string]interface{}{"totalOrders": float64(3), "rfmSegment": "loyal", "churnRisk": nil}, account["insights"])
+}
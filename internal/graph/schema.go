@@ -0,0 +1,34 @@
+// Package graph serves the customer account as a single GraphQL graph, so
+// the storefront account page can load the profile, addresses, wishlist,
+// measurements and back-in-stock subscriptions in one round trip. The graph
+// is read-only; changes go through the REST endpoints.
+package graph
+
+import (
+	_ "embed"
+
+	graphql "github.com/graph-gophers/graphql-go"
+)
+
+//go:embed schema.graphql
+var schemaSDL string
+
+// Query limits, so a single request can't fan out without bound
+const (
+	maxDepth       = 6
+	maxQueryLength = 8 << 10
+)
+
+// NewSchema parses the schema against the resolver. Introspection is left
+// off in production, like the Swagger UI.
+func NewSchema(resolver *Resolver, introspection bool) (*graphql.Schema, error) {
+	opts := []graphql.SchemaOpt{
+		graphql.UseStringDescriptions(),
+		graphql.MaxDepth(maxDepth),
+		graphql.MaxQueryLength(maxQueryLength),
+	}
+	if !introspection {
+		opts = append(opts, graphql.DisableIntrospection())
+	}
+	return graphql.ParseSchema(schemaSDL, resolver, opts...)
+}
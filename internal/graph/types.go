@@ -0,0 +1,147 @@
+package graph
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	graphql "github.com/graph-gophers/graphql-go"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+)
+
+type profileResolver struct{ p *domain.Profile }
+
+func (r *profileResolver) ID() graphql.ID             { return id(r.p.ID) }
+func (r *profileResolver) FullName() string           { return r.p.FullName }
+func (r *profileResolver) Email() string              { return r.p.Email }
+func (r *profileResolver) Phone() string              { return r.p.Phone }
+func (r *profileResolver) DateOfBirth() *graphql.Time { return optionalTime(r.p.DateOfBirth) }
+func (r *profileResolver) Gender() *string            { return optionalString(string(r.p.Gender)) }
+func (r *profileResolver) ProfilePicture() *string    { return optionalString(r.p.ProfilePicture) }
+func (r *profileResolver) Version() int32             { return int32(r.p.Version) }
+func (r *profileResolver) CreatedAt() graphql.Time    { return graphql.Time{Time: r.p.CreatedAt} }
+func (r *profileResolver) UpdatedAt() graphql.Time    { return graphql.Time{Time: r.p.UpdatedAt} }
+func (r *profileResolver) AvatarURL(args struct{ Size string }) *string {
+	return optionalString(r.p.AvatarURLs[args.Size])
+}
+
+type addressResolver struct{ a *domain.Address }
+
+func (r *addressResolver) ID() graphql.ID             { return id(r.a.ID) }
+func (r *addressResolver) Label() string              { return string(r.a.Label) }
+func (r *addressResolver) RecipientName() string      { return r.a.RecipientName }
+func (r *addressResolver) Phone() string              { return r.a.Phone }
+func (r *addressResolver) AddressLine1() string       { return r.a.AddressLine1 }
+func (r *addressResolver) AddressLine2() *string      { return optionalString(r.a.AddressLine2) }
+func (r *addressResolver) City() string               { return r.a.City }
+func (r *addressResolver) State() string              { return r.a.State }
+func (r *addressResolver) Postcode() string           { return r.a.Postcode }
+func (r *addressResolver) Country() string            { return r.a.Country }
+func (r *addressResolver) IsDefault() bool            { return r.a.IsDefault }
+func (r *addressResolver) Latitude() *float64         { return r.a.Latitude }
+func (r *addressResolver) Longitude() *float64        { return r.a.Longitude }
+func (r *addressResolver) ValidatedAt() *graphql.Time { return optionalTime(r.a.ValidatedAt) }
+func (r *addressResolver) Version() int32             { return int32(r.a.Version) }
+func (r *addressResolver) CreatedAt() graphql.Time    { return graphql.Time{Time: r.a.CreatedAt} }
+func (r *addressResolver) UpdatedAt() graphql.Time    { return graphql.Time{Time: r.a.UpdatedAt} }
+
+type wishlistItemResolver struct{ w *domain.WishlistItem }
+
+func (r *wishlistItemResolver) ID() graphql.ID          { return id(r.w.ID) }
+func (r *wishlistItemResolver) ProductID() graphql.ID   { return id(r.w.ProductID) }
+func (r *wishlistItemResolver) VariantID() *graphql.ID  { return optionalID(r.w.VariantID) }
+func (r *wishlistItemResolver) VariantSku() *string     { return r.w.VariantSKU }
+func (r *wishlistItemResolver) VariantName() *string    { return r.w.VariantName }
+func (r *wishlistItemResolver) ProductName() *string    { return r.w.ProductName }
+func (r *wishlistItemResolver) ProductSlug() *string    { return r.w.ProductSlug }
+func (r *wishlistItemResolver) ProductImage() *string   { return r.w.ProductImage }
+func (r *wishlistItemResolver) PriceAtAdd() float64     { return r.w.PriceAtAdd }
+func (r *wishlistItemResolver) NotifyOnSale() bool      { return r.w.NotifyOnSale }
+func (r *wishlistItemResolver) NotifyRestock() bool     { return r.w.NotifyRestock }
+func (r *wishlistItemResolver) CreatedAt() graphql.Time { return graphql.Time{Time: r.w.CreatedAt} }
+
+type measurementResolver struct{ m *domain.CustomerMeasurement }
+
+func (r *measurementResolver) ID() graphql.ID          { return id(r.m.ID) }
+func (r *measurementResolver) Name() *string           { return r.m.Name }
+func (r *measurementResolver) Gender() string          { return string(r.m.Gender) }
+func (r *measurementResolver) ProfilePerson() string   { return r.m.ProfilePerson }
+func (r *measurementResolver) Bust() *float64          { return r.m.Bust }
+func (r *measurementResolver) Chest() *float64         { return r.m.Chest }
+func (r *measurementResolver) Waist() *float64         { return r.m.Waist }
+func (r *measurementResolver) Hip() *float64           { return r.m.Hip }
+func (r *measurementResolver) ShoulderWidth() *float64 { return r.m.ShoulderWidth }
+func (r *measurementResolver) ArmLength() *float64     { return r.m.ArmLength }
+func (r *measurementResolver) Inseam() *float64        { return r.m.Inseam }
+func (r *measurementResolver) Outseam() *float64       { return r.m.Outseam }
+func (r *measurementResolver) Thigh() *float64         { return r.m.Thigh }
+func (r *measurementResolver) Neck() *float64          { return r.m.Neck }
+func (r *measurementResolver) Wrist() *float64         { return r.m.Wrist }
+func (r *measurementResolver) Height() *float64        { return r.m.Height }
+func (r *measurementResolver) Weight() *float64        { return r.m.Weight }
+func (r *measurementResolver) StandardSize() *string   { return r.m.StandardSize }
+func (r *measurementResolver) Unit() string            { return r.m.Unit }
+func (r *measurementResolver) Notes() *string          { return r.m.Notes }
+func (r *measurementResolver) IsDefault() bool         { return r.m.IsDefault }
+func (r *measurementResolver) Version() int32          { return int32(r.m.Version) }
+func (r *measurementResolver) CreatedAt() graphql.Time { return graphql.Time{Time: r.m.CreatedAt} }
+func (r *measurementResolver) UpdatedAt() graphql.Time { return graphql.Time{Time: r.m.UpdatedAt} }
+
+type backInStockResolver struct {
+	s *domain.BackInStockSubscription
+}
+
+func (r *backInStockResolver) ID() graphql.ID           { return id(r.s.ID) }
+func (r *backInStockResolver) ProductID() graphql.ID    { return id(r.s.ProductID) }
+func (r *backInStockResolver) VariantID() *graphql.ID   { return optionalID(r.s.VariantID) }
+func (r *backInStockResolver) ProductName() string      { return r.s.ProductName }
+func (r *backInStockResolver) ProductSlug() string      { return r.s.ProductSlug }
+func (r *backInStockResolver) ProductImage() *string    { return optionalString(r.s.ProductImage) }
+func (r *backInStockResolver) VariantSku() *string      { return optionalString(r.s.VariantSKU) }
+func (r *backInStockResolver) VariantName() *string     { return optionalString(r.s.VariantName) }
+func (r *backInStockResolver) IsNotified() bool         { return r.s.IsNotified }
+func (r *backInStockResolver) CreatedAt() graphql.Time  { return graphql.Time{Time: r.s.CreatedAt} }
+func (r *backInStockResolver) ExpiresAt() *graphql.Time { return optionalTime(r.s.ExpiresAt) }
+func (r *backInStockResolver) NotificationSentAt() *graphql.Time {
+	return optionalTime(r.s.NotificationSentAt)
+}
+
+type insightsResolver struct{ c *domain.Customer }
+
+func (r *insightsResolver) Status() string              { return string(r.c.Status) }
+func (r *insightsResolver) TotalOrders() int32          { return int32(r.c.TotalOrders) }
+func (r *insightsResolver) TotalSpent() float64         { return r.c.TotalSpent }
+func (r *insightsResolver) FirstOrderAt() *graphql.Time { return optionalTime(r.c.FirstOrderAt) }
+func (r *insightsResolver) LastOrderAt() *graphql.Time  { return optionalTime(r.c.LastOrderAt) }
+func (r *insightsResolver) RecencyScore() int32         { return int32(r.c.RecencyScore) }
+func (r *insightsResolver) FrequencyScore() int32       { return int32(r.c.FrequencyScore) }
+func (r *insightsResolver) MonetaryScore() int32        { return int32(r.c.MonetaryScore) }
+func (r *insightsResolver) RfmSegment() *string         { return optionalString(r.c.RFMSegment) }
+func (r *insightsResolver) LifetimeValue() float64      { return r.c.LifetimeValue }
+func (r *insightsResolver) ChurnRisk() *string          { return optionalString(r.c.ChurnRisk) }
+
+func id(u uuid.UUID) graphql.ID {
+	return graphql.ID(u.String())
+}
+
+func optionalID(u *uuid.UUID) *graphql.ID {
+	if u == nil {
+		return nil
+	}
+	value := id(*u)
+	return &value
+}
+
+func optionalTime(t *time.Time) *graphql.Time {
+	if t == nil {
+		return nil
+	}
+	return &graphql.Time{Time: *t}
+}
+
+// optionalString maps the empty strings the models store for "unset" to null
+func optionalString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
@@ -0,0 +1,50 @@
+package graph
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+// Viewer is who a query is resolved for. Customers see their own account;
+// staff may look up any customer and see the fields marked staff only.
+type Viewer struct {
+	CustomerID uuid.UUID
+	Staff      bool
+}
+
+type viewerKey struct{}
+
+// WithViewer returns a context resolving queries for viewer
+func WithViewer(ctx context.Context, viewer Viewer) context.Context {
+	return context.WithValue(ctx, viewerKey{}, viewer)
+}
+
+// viewerFrom returns the viewer of the query; without one nothing is visible
+func viewerFrom(ctx context.Context) (Viewer, bool) {
+	viewer, ok := ctx.Value(viewerKey{}).(Viewer)
+	return viewer, ok && viewer.CustomerID != uuid.Nil
+}
+
+// AccessError is returned for a field the viewer may not read. The field
+// resolves to null and the rest of the query is still answered.
+type AccessError struct {
+	Field string
+}
+
+func (e *AccessError) Error() string {
+	return "not authorized to read " + e.Field
+}
+
+// Extensions adds the error code to the GraphQL error
+func (e *AccessError) Extensions() map[string]any {
+	return map[string]any{"code": "FORBIDDEN"}
+}
+
+// requireStaff returns an AccessError unless the viewer is staff
+func requireStaff(ctx context.Context, field string) error {
+	if viewer, ok := viewerFrom(ctx); ok && viewer.Staff {
+		return nil
+	}
+	return &AccessError{Field: field}
+}
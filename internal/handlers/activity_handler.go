@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/Ecom-micro-template/service-customer/internal/authctx"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/persistence"
+	"github.com/Ecom-micro-template/service-customer/internal/response"
+	"gorm.io/gorm"
+)
+
+// ActivityHandler serves customers their own security log, so they can spot
+// changes and sign-ins they didn't make
+type ActivityHandler struct {
+	repo *persistence.ActivityRepository
+}
+
+// NewActivityHandler creates a new activity handler
+func NewActivityHandler(db *gorm.DB) *ActivityHandler {
+	return &ActivityHandler{repo: persistence.NewActivityRepository(db)}
+}
+
+// List returns the customer's recent profile and address changes and sign-ins, newest first
+// GET /api/v1/customer/activity
+// Query: limit, cursor. Pass the returned next_cursor as cursor to get the next page.
+func (h *ActivityHandler) List(c *gin.Context) {
+	userID, ok := authctx.AuthenticatedUserID(c)
+	if !ok {
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(domain.DefaultSecurityActivityLimit)))
+	if limit < 1 || limit > domain.MaxSecurityActivityLimit {
+		limit = domain.DefaultSecurityActivityLimit
+	}
+	after, _, err := cursorQuery(c.Request.URL.Query())
+	if err != nil {
+		response.Fail(c, response.CodeInvalidCursor, err.Error())
+		return
+	}
+
+	page, err := h.repo.SecurityLog(c.Request.Context(), userID, after, limit)
+	if err != nil {
+		response.InternalServerError(c, "Failed to retrieve activity")
+		return
+	}
+
+	events := make([]domain.SecurityEvent, 0, len(page.Items))
+	for _, activity := range page.Items {
+		events = append(events, domain.NewSecurityEvent(activity))
+	}
+	response.CursorPaginated(c, "", events, limit, page.NextCursor)
+}
@@ -1,28 +1,71 @@
 package handlers
 
 import (
+	"errors"
+	"log"
 	"net/http"
+	"os"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
-	"github.com/Ecom-micro-template/service-customer/internal/middleware"
+	"github.com/Ecom-micro-template/service-customer/internal/authctx"
 	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/Ecom-micro-template/service-customer/internal/domain/shared"
+	addressdomain "github.com/Ecom-micro-template/service-customer/internal/domain/address"
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/addressvalidation"
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/orderclient"
 	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/persistence"
+	"github.com/Ecom-micro-template/service-customer/internal/response"
 	"gorm.io/gorm"
 )
 
 // AddressHandler handles address-related requests
 type AddressHandler struct {
-	repo *persistence.AddressRepository
+	repo      *persistence.AddressRepository
+	validator addressvalidation.Validator
+	countries addressdomain.CountryPolicy
+	orders    *orderclient.Client
 }
 
 // NewAddressHandler creates a new address handler
 func NewAddressHandler(db *gorm.DB) *AddressHandler {
+	orderURL := os.Getenv("ORDER_SERVICE_URL")
+	if orderURL == "" {
+		orderURL = "http://ecommerce-order:8005"
+	}
+
 	return &AddressHandler{
-		repo: persistence.NewAddressRepository(db),
+		repo:      persistence.NewAddressRepository(db).WithLimits(persistence.NewCustomerLimitRepository(db, domain.DefaultCustomerLimits())),
+		validator: addressvalidation.NewNoopValidator(),
+		orders:    orderclient.NewClient(orderURL),
 	}
 }
 
+// WithValidator sets the address validation/geocoding provider
+func (h *AddressHandler) WithValidator(validator addressvalidation.Validator) *AddressHandler {
+	h.validator = validator
+	return h
+}
+
+// WithMirror mirrors address writes, e.g. to the legacy CRM during migration
+func (h *AddressHandler) WithMirror(mirror persistence.CustomerMirror) *AddressHandler {
+	h.repo.WithMirror(mirror)
+	return h
+}
+
+// WithLimits sets the per-customer caps, including how many addresses a customer can keep
+func (h *AddressHandler) WithLimits(limits *persistence.CustomerLimitRepository) *AddressHandler {
+	h.repo.WithLimits(limits)
+	return h
+}
+
+// WithCountryPolicy sets the allowed countries and per-country postcode/phone rules
+func (h *AddressHandler) WithCountryPolicy(policy addressdomain.CountryPolicy) *AddressHandler {
+	h.countries = policy
+	return h
+}
+
 // CreateAddressRequest represents the request body for creating an address
 type CreateAddressRequest struct {
 	Label         string `json:"label" binding:"required"`
@@ -49,47 +92,64 @@ type UpdateAddressRequest struct {
 	Postcode      string `json:"postcode"`
 	Country       string `json:"country"`
 	IsDefault     *bool  `json:"is_default"`
+	Version       *int64 `json:"version"` // optimistic locking; If-Match takes precedence
+}
+
+// AddressList is the payload of the address listing
+type AddressList struct {
+	Addresses []domain.Address `json:"addresses"`
+	Count     int              `json:"count"`
+}
+
+// ImportedAddress represents the result of importing an order address;
+// imported is false when an equivalent address was already saved
+type ImportedAddress struct {
+	Imported bool            `json:"imported"`
+	Address  *domain.Address `json:"address"`
 }
 
 // ListAddresses retrieves all addresses for the customer
 // GET /api/v1/customer/addresses
 func (h *AddressHandler) ListAddresses(c *gin.Context) {
-	userID, ok := middleware.GetUserID(c)
+	userID, ok := authctx.AuthenticatedUserID(c)
 	if !ok {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
 		return
 	}
 
 	addresses, err := h.repo.ListByUserID(c.Request.Context(), userID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve addresses"})
+		response.InternalServerError(c, "Failed to retrieve addresses")
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"addresses": addresses,
-		"count":     len(addresses),
+	response.OK(c, "", AddressList{
+		Addresses: addresses,
+		Count:     len(addresses),
 	})
 }
 
 // CreateAddress creates a new address
 // POST /api/v1/customer/addresses
 func (h *AddressHandler) CreateAddress(c *gin.Context) {
-	userID, ok := middleware.GetUserID(c)
+	userID, ok := authctx.AuthenticatedUserID(c)
 	if !ok {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
 		return
 	}
 
 	var req CreateAddressRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		response.Invalid(c, err)
+		return
+	}
+	label, err := shared.ParseAddressLabel(req.Label)
+	if err != nil {
+		response.BadRequest(c, err.Error(), nil)
 		return
 	}
 
 	address := &domain.Address{
 		UserID:        userID,
-		Label:         req.Label,
+		Label:         label,
 		RecipientName: req.RecipientName,
 		Phone:         req.Phone,
 		AddressLine1:  req.AddressLine1,
@@ -101,35 +161,41 @@ func (h *AddressHandler) CreateAddress(c *gin.Context) {
 		IsDefault:     req.IsDefault,
 	}
 
+	if err := h.countries.Validate(address.Country, address.Postcode, address.Phone); err != nil {
+		writeAddressFieldErrors(c, err)
+		return
+	}
+
+	if result, ok := h.validateAddress(c, address); !ok {
+		response.FailWith(c, response.CodeAddressRejected, "Address could not be validated", result)
+		return
+	}
+
 	if err := h.repo.Create(c.Request.Context(), address); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create address"})
+		response.FromError(c, err, "", "Failed to create address")
 		return
 	}
 
-	c.JSON(http.StatusCreated, gin.H{
-		"message": "Address created successfully",
-		"address": address,
-	})
+	response.Created(c, "Address created successfully", address)
 }
 
 // UpdateAddress updates an existing address
 // PUT /api/v1/customer/addresses/:id
 func (h *AddressHandler) UpdateAddress(c *gin.Context) {
-	userID, ok := middleware.GetUserID(c)
+	userID, ok := authctx.AuthenticatedUserID(c)
 	if !ok {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
 		return
 	}
 
 	addressID, err := uuid.Parse(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid address ID"})
+		response.BadRequest(c, "Invalid address ID", nil)
 		return
 	}
 
 	var req UpdateAddressRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		response.Invalid(c, err)
 		return
 	}
 
@@ -137,16 +203,24 @@ func (h *AddressHandler) UpdateAddress(c *gin.Context) {
 	address, err := h.repo.GetByID(c.Request.Context(), addressID, userID)
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Address not found"})
+			response.Fail(c, response.CodeAddressNotFound, "Address not found")
 			return
 		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve address"})
+		response.InternalServerError(c, "Failed to retrieve address")
+		return
+	}
+	if !checkVersion(c, req.Version, address.Version) {
 		return
 	}
 
 	// Update fields
 	if req.Label != "" {
-		address.Label = req.Label
+		label, err := shared.ParseAddressLabel(req.Label)
+		if err != nil {
+			response.BadRequest(c, err.Error(), nil)
+			return
+		}
+		address.Label = label
 	}
 	if req.RecipientName != "" {
 		address.RecipientName = req.RecipientName
@@ -176,67 +250,330 @@ func (h *AddressHandler) UpdateAddress(c *gin.Context) {
 		address.IsDefault = *req.IsDefault
 	}
 
+	if err := h.countries.Validate(address.Country, address.Postcode, address.Phone); err != nil {
+		writeAddressFieldErrors(c, err)
+		return
+	}
+
+	if result, ok := h.validateAddress(c, address); !ok {
+		response.FailWith(c, response.CodeAddressRejected, "Address could not be validated", result)
+		return
+	}
+
 	if err := h.repo.Update(c.Request.Context(), address); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update address"})
+		response.FromError(c, err, response.CodeAddressNotFound, "Failed to update address")
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"message": "Address updated successfully",
-		"address": address,
-	})
+	setVersionETag(c, address.Version)
+	response.OK(c, "Address updated successfully", address)
 }
 
 // DeleteAddress deletes an address
 // DELETE /api/v1/customer/addresses/:id
 func (h *AddressHandler) DeleteAddress(c *gin.Context) {
-	userID, ok := middleware.GetUserID(c)
+	userID, ok := authctx.AuthenticatedUserID(c)
 	if !ok {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
 		return
 	}
 
 	addressID, err := uuid.Parse(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid address ID"})
+		response.BadRequest(c, "Invalid address ID", nil)
 		return
 	}
 
 	if err := h.repo.Delete(c.Request.Context(), addressID, userID); err != nil {
 		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Address not found"})
+			response.Fail(c, response.CodeAddressNotFound, "Address not found")
 			return
 		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete address"})
+		response.InternalServerError(c, "Failed to delete address")
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Address deleted successfully"})
+	response.Deleted(c, "Address deleted successfully")
 }
 
 // SetDefaultAddress sets an address as the default
 // PUT /api/v1/customer/addresses/:id/default
 func (h *AddressHandler) SetDefaultAddress(c *gin.Context) {
-	userID, ok := middleware.GetUserID(c)
+	userID, ok := authctx.AuthenticatedUserID(c)
 	if !ok {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
 		return
 	}
 
 	addressID, err := uuid.Parse(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid address ID"})
+		response.BadRequest(c, "Invalid address ID", nil)
 		return
 	}
 
 	if err := h.repo.SetDefault(c.Request.Context(), addressID, userID); err != nil {
 		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Address not found"})
+			response.Fail(c, response.CodeAddressNotFound, "Address not found")
+			return
+		}
+		response.InternalServerError(c, "Failed to set default address")
+		return
+	}
+
+	response.Done(c, http.StatusOK, "Default address set successfully")
+}
+
+// RestoreAddress restores an address deleted within the last 30 days
+// POST /api/v1/customer/addresses/:id/restore
+func (h *AddressHandler) RestoreAddress(c *gin.Context) {
+	userID, ok := authctx.AuthenticatedUserID(c)
+	if !ok {
+		return
+	}
+
+	addressID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "Invalid address ID", nil)
+		return
+	}
+
+	address, err := h.repo.Restore(c.Request.Context(), addressID, userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			response.Fail(c, response.CodeAddressNotFound, "Deleted address not found")
+		case errors.Is(err, domain.ErrAddressRestoreExpired):
+			response.Fail(c, response.CodeAddressRestoreExpired, "Address was deleted more than 30 days ago and can no longer be restored")
+		default:
+			response.FromError(c, err, "", "Failed to restore address")
+		}
+		return
+	}
+
+	response.OK(c, "Address restored successfully", address)
+}
+
+// ImportAddressRequest represents the optional request body for importing an order address
+type ImportAddressRequest struct {
+	Label     string `json:"label"`
+	IsDefault bool   `json:"is_default"`
+}
+
+// ImportFromOrder saves the shipping address of one of the customer's orders
+// to their address book, unless an equivalent address is already saved
+// POST /api/v1/customer/addresses/import-from-order/:orderId
+func (h *AddressHandler) ImportFromOrder(c *gin.Context) {
+	userID, ok := authctx.AuthenticatedUserID(c)
+	if !ok {
+		return
+	}
+
+	var req ImportAddressRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.Invalid(c, err)
+			return
+		}
+	}
+	label := shared.AddressLabelHome
+	if req.Label != "" {
+		parsed, err := shared.ParseAddressLabel(req.Label)
+		if err != nil {
+			response.BadRequest(c, err.Error(), nil)
+			return
+		}
+		label = parsed
+	}
+
+	order, err := h.orders.GetOrder(c.Request.Context(), c.Param("orderId"), userID.String(), c.GetHeader("Authorization"))
+	if err != nil {
+		if errors.Is(err, orderclient.ErrOrderNotFound) {
+			response.Fail(c, response.CodeOrderNotFound, "Order not found")
 			return
 		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to set default address"})
+		log.Printf("⚠️  Failed to fetch order %s: %v", c.Param("orderId"), err)
+		response.ServiceUnavailable(c, "Order service unavailable")
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Default address set successfully"})
+	shipping := order.ShippingAddress
+	if shipping.Address == "" {
+		response.Fail(c, response.CodeOrderNoShippingAddress, "Order has no shipping address")
+		return
+	}
+
+	address := &domain.Address{
+		UserID:        userID,
+		Label:         label,
+		RecipientName: shipping.Name,
+		Phone:         shipping.Phone,
+		AddressLine1:  shipping.Address,
+		AddressLine2:  shipping.AddressLine2,
+		City:          shipping.City,
+		State:         shipping.State,
+		Postcode:      shipping.Postcode,
+		Country:       shipping.Country,
+		IsDefault:     req.IsDefault,
+	}
+	if address.Country == "" {
+		address.Country = "Malaysia"
+	}
+
+	existing, err := h.repo.ListByUserID(c.Request.Context(), userID)
+	if err != nil {
+		response.InternalServerError(c, "Failed to retrieve addresses")
+		return
+	}
+	for i := range existing {
+		if existing[i].SameLocation(address) {
+			response.OK(c, "Address already in address book", ImportedAddress{
+				Imported: false,
+				Address:  &existing[i],
+			})
+			return
+		}
+	}
+	if len(existing) == 0 {
+		address.IsDefault = true
+	}
+
+	if err := h.countries.Validate(address.Country, address.Postcode, address.Phone); err != nil {
+		writeAddressFieldErrors(c, err)
+		return
+	}
+
+	if result, ok := h.validateAddress(c, address); !ok {
+		response.FailWith(c, response.CodeAddressRejected, "Address could not be validated", result)
+		return
+	}
+
+	if err := h.repo.Create(c.Request.Context(), address); err != nil {
+		response.FromError(c, err, "", "Failed to create address")
+		return
+	}
+
+	response.Created(c, "Address imported successfully", ImportedAddress{
+		Imported: true,
+		Address:  address,
+	})
+}
+
+// ValidateAddress validates and normalizes an address without saving it
+// POST /api/v1/customer/addresses/validate
+func (h *AddressHandler) ValidateAddress(c *gin.Context) {
+	var req addressvalidation.Input
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Invalid(c, err)
+		return
+	}
+
+	if err := h.countries.Validate(req.Country, req.Postcode, ""); err != nil {
+		writeAddressFieldErrors(c, err)
+		return
+	}
+
+	result, err := h.validator.Validate(c.Request.Context(), req)
+	if err != nil {
+		response.ServiceUnavailable(c, "Address validation unavailable")
+		return
+	}
+
+	response.OK(c, "", result)
+}
+
+// validateAddress runs the validation provider and applies its normalized fields
+// and coordinates to the address. It returns false only when the provider
+// rejected the address; provider outages are logged and the address is saved as entered.
+func (h *AddressHandler) validateAddress(c *gin.Context, address *domain.Address) (*addressvalidation.Result, bool) {
+	result, err := h.validator.Validate(c.Request.Context(), addressvalidation.Input{
+		AddressLine1: address.AddressLine1,
+		AddressLine2: address.AddressLine2,
+		City:         address.City,
+		State:        address.State,
+		Postcode:     address.Postcode,
+		Country:      address.Country,
+	})
+	if err != nil {
+		log.Printf("⚠️  Address validation (%s) failed: %v", h.validator.Name(), err)
+		return nil, true
+	}
+	if !result.Valid {
+		return result, false
+	}
+
+	address.AddressLine1 = result.Normalized.AddressLine1
+	address.AddressLine2 = result.Normalized.AddressLine2
+	address.City = result.Normalized.City
+	address.State = result.Normalized.State
+	address.Postcode = result.Normalized.Postcode
+	address.Country = result.Normalized.Country
+	address.Latitude = result.Latitude
+	address.Longitude = result.Longitude
+
+	now := time.Now()
+	address.ValidatedAt = &now
+	return result, true
+}
+
+// AdminAddressHandler handles admin address requests
+type AdminAddressHandler struct {
+	repo *persistence.AddressRepository
+}
+
+// NewAdminAddressHandler creates a new admin address handler
+func NewAdminAddressHandler(db *gorm.DB) *AdminAddressHandler {
+	return &AdminAddressHandler{
+		repo: persistence.NewAddressRepository(db),
+	}
+}
+
+// DeletedAddress is a deleted address as shown to support staff
+type DeletedAddress struct {
+	domain.Address
+	DeletedAt       time.Time `json:"deleted_at"`
+	RestorableUntil time.Time `json:"restorable_until"`
+	Restorable      bool      `json:"restorable"`
+}
+
+// ListDeletedAddresses lists a customer's deleted addresses
+// GET /api/v1/admin/customers/:id/addresses/deleted
+func (h *AdminAddressHandler) ListDeletedAddresses(c *gin.Context) {
+	customerID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "Invalid customer ID", nil)
+		return
+	}
+
+	addresses, err := h.repo.ListDeleted(c.Request.Context(), customerID)
+	if err != nil {
+		response.InternalServerError(c, "Failed to retrieve deleted addresses")
+		return
+	}
+
+	now := time.Now()
+	deleted := make([]DeletedAddress, 0, len(addresses))
+	for _, address := range addresses {
+		restorableUntil := address.DeletedAt.Time.Add(domain.AddressRestoreWindow)
+		deleted = append(deleted, DeletedAddress{
+			Address:         address,
+			DeletedAt:       address.DeletedAt.Time,
+			RestorableUntil: restorableUntil,
+			Restorable:      now.Before(restorableUntil),
+		})
+	}
+
+	response.OK(c, "", deleted)
+}
+
+// writeAddressFieldErrors responds with 422 and the offending fields for
+// country rule violations
+func writeAddressFieldErrors(c *gin.Context, err error) {
+	var validationErr *addressdomain.ValidationError
+	if errors.As(err, &validationErr) {
+		fields := make([]response.FieldError, 0, len(validationErr.Fields))
+		for _, field := range validationErr.Fields {
+			fields = append(fields, response.FieldError{Field: field.Field, Message: field.Message})
+		}
+		response.Fields(c, response.CodeAddressInvalid, "Invalid address", fields)
+		return
+	}
+	response.InternalServerError(c, "Failed to validate address")
 }
@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/persistence"
+	"github.com/Ecom-micro-template/service-customer/internal/response"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// AdminAnalyticsHandler serves customer cohort and growth analytics to the
+// admin dashboard
+type AdminAnalyticsHandler struct {
+	repo   *persistence.AnalyticsRepository
+	logger *zap.Logger
+}
+
+// NewAdminAnalyticsHandler creates a new analytics handler
+func NewAdminAnalyticsHandler(db *gorm.DB, logger *zap.Logger) *AdminAnalyticsHandler {
+	return &AdminAnalyticsHandler{
+		repo:   persistence.NewAnalyticsRepository(db),
+		logger: logger,
+	}
+}
+
+// GetCohorts returns monthly signup cohorts with their retention and
+// repeat-purchase rates, newest first
+// GET /api/v1/admin/customers/analytics/cohorts
+// Query: months (default 12, max 36)
+func (h *AdminAnalyticsHandler) GetCohorts(c *gin.Context) {
+	months, err := strconv.Atoi(c.DefaultQuery("months", strconv.Itoa(domain.DefaultCohortMonths)))
+	if err != nil || months < 1 || months > domain.MaxCohortMonths {
+		response.BadRequest(c, "months must be between 1 and "+strconv.Itoa(domain.MaxCohortMonths), nil)
+		return
+	}
+
+	cohorts, err := h.repo.Cohorts(c.Request.Context(), months, time.Now())
+	if err != nil {
+		h.logger.Error("Failed to get customer cohorts", zap.Error(err))
+		response.InternalServerError(c, "Failed to retrieve customer cohorts")
+		return
+	}
+
+	response.OK(c, "Customer cohorts retrieved", cohorts)
+}
+
+// GetGrowth returns signups, first purchases and the customer total per day
+// or week
+// GET /api/v1/admin/customers/analytics/growth
+// Query: interval (day or week, default day), from, to (YYYY-MM-DD; default
+// the 30 days up to to, or today)
+func (h *AdminAnalyticsHandler) GetGrowth(c *gin.Context) {
+	to := time.Now().UTC()
+	if raw := c.Query("to"); raw != "" {
+		parsed, err := time.Parse("2006-01-02", raw)
+		if err != nil {
+			response.BadRequest(c, "to must be a YYYY-MM-DD date", nil)
+			return
+		}
+		to = parsed
+	}
+	from := to.AddDate(0, 0, -29)
+	if raw := c.Query("from"); raw != "" {
+		parsed, err := time.Parse("2006-01-02", raw)
+		if err != nil {
+			response.BadRequest(c, "from must be a YYYY-MM-DD date", nil)
+			return
+		}
+		from = parsed
+	}
+
+	series, err := h.repo.Growth(c.Request.Context(), c.DefaultQuery("interval", domain.GrowthIntervalDay), from, to)
+	if errors.Is(err, domain.ErrInvalidGrowthInterval) || errors.Is(err, domain.ErrInvalidGrowthRange) || errors.Is(err, domain.ErrGrowthRangeTooLong) {
+		response.BadRequest(c, err.Error(), nil)
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to get customer growth", zap.Error(err))
+		response.InternalServerError(c, "Failed to retrieve customer growth")
+		return
+	}
+
+	response.OK(c, "Customer growth retrieved", series)
+}
@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/response"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/persistence"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// AdminAuditHandler serves the admin audit log
+type AdminAuditHandler struct {
+	repo   *persistence.AuditLogRepository
+	logger *zap.Logger
+}
+
+// NewAdminAuditHandler creates a new admin audit handler
+func NewAdminAuditHandler(db *gorm.DB, logger *zap.Logger) *AdminAuditHandler {
+	return &AdminAuditHandler{
+		repo:   persistence.NewAuditLogRepository(db),
+		logger: logger,
+	}
+}
+
+// ListAuditLogs handles GET /admin/audit-logs
+// Query: page, limit, entity_type, entity_id, actor_id, action, date_from, date_to
+func (h *AdminAuditHandler) ListAuditLogs(c *gin.Context) {
+	filter, err := parseAuditLogFilter(c)
+	if err != nil {
+		response.BadRequest(c, err.Error(), nil)
+		return
+	}
+
+	entries, total, err := h.repo.List(c.Request.Context(), filter)
+	if err != nil {
+		h.logger.Error("Failed to list audit logs", zap.Error(err))
+		response.InternalServerError(c, "Failed to retrieve audit logs")
+		return
+	}
+
+	response.Paginated(c, entries, filter.Page, filter.Limit, total)
+}
+
+func parseAuditLogFilter(c *gin.Context) (domain.AuditLogFilter, error) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	filter := domain.AuditLogFilter{
+		EntityType: c.Query("entity_type"),
+		Action:     c.Query("action"),
+		Page:       page,
+		Limit:      limit,
+	}
+	if entityIDStr := c.Query("entity_id"); entityIDStr != "" {
+		entityID, err := uuid.Parse(entityIDStr)
+		if err != nil {
+			return filter, errors.New("Invalid entity_id")
+		}
+		filter.EntityID = &entityID
+	}
+	if actorIDStr := c.Query("actor_id"); actorIDStr != "" {
+		actorID, err := uuid.Parse(actorIDStr)
+		if err != nil {
+			return filter, errors.New("Invalid actor_id")
+		}
+		filter.ActorID = &actorID
+	}
+	if dateFromStr := c.Query("date_from"); dateFromStr != "" {
+		dateFrom, err := time.Parse("2006-01-02", dateFromStr)
+		if err != nil {
+			return filter, errors.New("date_from must be YYYY-MM-DD")
+		}
+		filter.DateFrom = &dateFrom
+	}
+	if dateToStr := c.Query("date_to"); dateToStr != "" {
+		dateTo, err := time.Parse("2006-01-02", dateToStr)
+		if err != nil {
+			return filter, errors.New("date_to must be YYYY-MM-DD")
+		}
+		dateTo = dateTo.Add(24*time.Hour - time.Second)
+		filter.DateTo = &dateTo
+	}
+	return filter, nil
+}
@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/Ecom-micro-template/service-customer/internal/authctx"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/Ecom-micro-template/service-customer/internal/response"
+)
+
+// BackInStockDemandReport is the payload of the demand report
+type BackInStockDemandReport struct {
+	Products    []domain.BackInStockDemand `json:"products"`
+	SortBy      string                     `json:"sortBy"`
+	GeneratedAt time.Time                  `json:"generatedAt"`
+}
+
+// ExportSubscriptions streams the pending, unexpired subscriptions of
+// customers and confirmed guests as CSV, longest waiting first. Emails are
+// masked unless the admin may see customer PII.
+// GET /api/v1/admin/back-in-stock/export
+func (h *AdminBackInStockHandler) ExportSubscriptions(c *gin.Context) {
+	ctx := c.Request.Context()
+	now := time.Now()
+	maskPII := !authctx.Get(c).HasPermission(domain.PermissionCustomersPII)
+
+	// Fail before the first byte is written if the query itself is broken
+	subscriptions, next, err := h.repo.PendingAfter(ctx, nil, exportBatchSize, now)
+	if err != nil {
+		response.InternalServerError(c, "Failed to export subscriptions")
+		return
+	}
+
+	filename := fmt.Sprintf("back-in-stock-%s.csv", now.UTC().Format("20060102-150405"))
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+
+	writer := csv.NewWriter(c.Writer)
+	writer.Write(domain.BackInStockExportHeader)
+	rows := 0
+	for {
+		for i := range subscriptions {
+			writer.Write(subscriptions[i].ExportRow(now, maskPII))
+		}
+		rows += len(subscriptions)
+		writer.Flush()
+
+		if next == "" {
+			break
+		}
+		after, _ := domain.DecodeCursor(next)
+		if subscriptions, next, err = h.repo.PendingAfter(ctx, after, exportBatchSize, now); err != nil {
+			// Headers are gone; all we can do is cut the file short
+			log.Printf("⚠️  Back-in-stock export aborted after %d rows: %v", rows, err)
+			return
+		}
+	}
+
+	var after *domain.Cursor
+	for {
+		guests, next, err := h.guestRepo.ConfirmedPendingAfter(ctx, after, exportBatchSize, now)
+		if err != nil {
+			log.Printf("⚠️  Back-in-stock export aborted after %d rows: %v", rows, err)
+			return
+		}
+		for i := range guests {
+			writer.Write(guests[i].ExportRow(now, maskPII))
+		}
+		rows += len(guests)
+		writer.Flush()
+
+		if next == "" {
+			break
+		}
+		after, _ = domain.DecodeCursor(next)
+	}
+}
+
+// GetDemand ranks products by how many customers and confirmed guests are
+// waiting for a restock (?sort=waiting, the default) or by how long they
+// have waited on average (?sort=wait), so restocks can be prioritized;
+// ?sort_order=asc reverses the ranking
+// GET /api/v1/admin/back-in-stock/demand
+func (h *AdminBackInStockHandler) GetDemand(c *gin.Context) {
+	sort, ok := listSort(c, domain.BackInStockDemandSort, c.Query("sort"), c.Query("sort_order"))
+	if !ok {
+		return
+	}
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(domain.DefaultBackInStockDemandLimit)))
+	if limit < 1 || limit > domain.MaxBackInStockDemandLimit {
+		limit = domain.DefaultBackInStockDemandLimit
+	}
+
+	now := time.Now()
+	products, err := h.repo.Demand(c.Request.Context(), sort, limit, now)
+	if err != nil {
+		response.InternalServerError(c, "Failed to get demand")
+		return
+	}
+
+	response.OK(c, "", BackInStockDemandReport{
+		Products:    products,
+		SortBy:      sort.Field,
+		GeneratedAt: now.UTC(),
+	})
+}
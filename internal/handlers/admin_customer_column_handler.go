@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/Ecom-micro-template/service-customer/internal/authctx"
+	"github.com/Ecom-micro-template/service-customer/internal/response"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/persistence"
+	"go.uber.org/zap"
+)
+
+// WithColumnPreferences enables per-admin customer list columns
+func (h *AdminCustomerHandler) WithColumnPreferences(repo *persistence.CustomerColumnPreferenceRepository) *AdminCustomerHandler {
+	h.columnPrefs = repo
+	return h
+}
+
+// CustomerColumnsRequest is the body of PUT /admin/customers/columns
+type CustomerColumnsRequest struct {
+	Columns []string `json:"columns" binding:"required,min=1"`
+}
+
+// CustomerColumnsResponse lists every column and the ones the admin shows
+type CustomerColumnsResponse struct {
+	Available []domain.CustomerColumn `json:"available"`
+	Selected  []string                `json:"selected"`
+	Custom    bool                    `json:"custom"` // false when Selected is the default set
+}
+
+// GetCustomerColumns handles GET /admin/customers/columns
+func (h *AdminCustomerHandler) GetCustomerColumns(c *gin.Context) {
+	saved, err := h.columnPrefs.Columns(c.Request.Context(), authctx.UserID(c))
+	if err != nil {
+		h.logger.Error("Failed to get customer column preference", zap.Error(err))
+		response.InternalServerError(c, "Failed to retrieve columns")
+		return
+	}
+
+	response.OK(c, "Columns retrieved", h.columnsResponse(saved))
+}
+
+// UpdateCustomerColumns handles PUT /admin/customers/columns
+// Saves the admin's columns in the given order.
+func (h *AdminCustomerHandler) UpdateCustomerColumns(c *gin.Context) {
+	var req CustomerColumnsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Invalid(c, err)
+		return
+	}
+	columns, err := domain.ParseCustomerColumns(req.Columns)
+	if err != nil {
+		response.BadRequest(c, err.Error(), nil)
+		return
+	}
+
+	if err := h.columnPrefs.Save(c.Request.Context(), authctx.UserID(c), columns); err != nil {
+		h.logger.Error("Failed to save customer column preference", zap.Error(err))
+		response.InternalServerError(c, "Failed to save columns")
+		return
+	}
+
+	response.Updated(c, "Columns saved", h.columnsResponse(columns))
+}
+
+// ResetCustomerColumns handles DELETE /admin/customers/columns
+// Goes back to the default columns.
+func (h *AdminCustomerHandler) ResetCustomerColumns(c *gin.Context) {
+	if err := h.columnPrefs.Reset(c.Request.Context(), authctx.UserID(c)); err != nil {
+		h.logger.Error("Failed to reset customer column preference", zap.Error(err))
+		response.InternalServerError(c, "Failed to reset columns")
+		return
+	}
+
+	response.OK(c, "Columns reset", h.columnsResponse(nil))
+}
+
+func (h *AdminCustomerHandler) columnsResponse(saved []string) CustomerColumnsResponse {
+	resp := CustomerColumnsResponse{
+		Available: domain.CustomerColumns,
+		Selected:  saved,
+		Custom:    len(saved) > 0,
+	}
+	if !resp.Custom {
+		resp.Selected = domain.DefaultCustomerColumns
+	}
+	return resp
+}
+
+// exportColumns returns the columns to export: ?columns= (or the view's), then
+// the admin's saved columns, then the defaults
+func (h *AdminCustomerHandler) exportColumns(c *gin.Context, query url.Values) ([]string, error) {
+	if raw := query.Get("columns"); raw != "" {
+		return domain.ParseCustomerColumns(strings.Split(raw, ","))
+	}
+	if h.columnPrefs != nil {
+		saved, err := h.columnPrefs.Columns(c.Request.Context(), authctx.UserID(c))
+		if err != nil {
+			return nil, err
+		}
+		// Columns may have been removed since they were saved
+		if columns, err := domain.ParseCustomerColumns(saved); err == nil {
+			return columns, nil
+		}
+	}
+	return domain.DefaultCustomerColumns, nil
+}
+
+// writeCustomersCSV streams the customers as CSV with one column per key
+func writeCustomersCSV(c *gin.Context, customers []domain.Customer, columns []string) {
+	filename := fmt.Sprintf("customers-%s.csv", time.Now().UTC().Format("20060102-150405"))
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	c.Status(http.StatusOK)
+
+	domain.WriteCustomersCSV(c.Writer, customers, columns)
+}
+
+// customerListSort parses the ?sort_by= and ?sort_order= of a customer list
+// against the whitelist of sortable columns, writing a 400 when it fails
+func customerListSort(c *gin.Context, query url.Values) (domain.Sort, bool) {
+	return listSort(c, domain.CustomerSort, query.Get("sort_by"), query.Get("sort_order"))
+}
+
+// listSort parses a requested sort field and direction against a listing's
+// whitelist, writing a 400 listing what is allowed when it fails
+func listSort(c *gin.Context, fields domain.SortFields, sortBy, sortOrder string) (domain.Sort, bool) {
+	sort, err := fields.Parse(sortBy, sortOrder)
+	if err != nil {
+		response.FailWith(c, response.CodeInvalidSort, err.Error(), gin.H{
+			"sort_by":    fields.Fields(),
+			"sort_order": []domain.SortDirection{domain.SortAsc, domain.SortDesc},
+		})
+		return domain.Sort{}, false
+	}
+	return sort, true
+}
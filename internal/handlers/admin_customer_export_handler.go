@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/authctx"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/persistence"
+	"github.com/Ecom-micro-template/service-customer/internal/middleware"
+	"github.com/Ecom-micro-template/service-customer/internal/response"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// defaultExportURLTTL is how long export download links work when not set
+const defaultExportURLTTL = time.Hour
+
+// ExportFileSigner signs download links to finished export files
+type ExportFileSigner interface {
+	SignedURL(key string, ttl time.Duration) (string, error)
+}
+
+// CustomerExportQueue is told when an export job is queued, so it can start
+// without waiting for its next poll
+type CustomerExportQueue interface {
+	Wake()
+}
+
+// CustomerExportJobResponse is a background export with its progress, and a
+// download link once it has completed
+type CustomerExportJobResponse struct {
+	*domain.CustomerExportJob
+	Progress    int        `json:"progress"` // percent
+	DownloadURL string     `json:"download_url,omitempty"`
+	ExpiresAt   *time.Time `json:"download_expires_at,omitempty"`
+}
+
+// WithExportJobs enables background customer exports to the file store
+func (h *AdminCustomerHandler) WithExportJobs(repo *persistence.CustomerExportJobRepository, files ExportFileSigner, queue CustomerExportQueue, urlTTL time.Duration) *AdminCustomerHandler {
+	h.exportJobs = repo
+	h.exportFiles = files
+	h.exportQueue = queue
+	h.exportURLTTL = urlTTL
+	if h.exportURLTTL <= 0 {
+		h.exportURLTTL = defaultExportURLTTL
+	}
+	return h
+}
+
+// CreateExportJob handles POST /admin/customers/exports
+// It takes the query parameters of GET /admin/customers/export, except that
+// there is no row cap and customers are exported oldest first. The export
+// runs in the background; its progress and, once done, its download link are
+// at the Location of the job.
+func (h *AdminCustomerHandler) CreateExportJob(c *gin.Context) {
+	if h.exportJobs == nil {
+		response.ServiceUnavailable(c, "Background exports are not enabled")
+		return
+	}
+	query, ok := h.customerListQuery(c)
+	if !ok {
+		return
+	}
+	format := queryDefault(query, "format", "csv")
+	if format != "csv" && format != "json" {
+		response.BadRequest(c, "format must be csv or json", nil)
+		return
+	}
+	columns, err := h.exportColumns(c, query)
+	if err != nil {
+		if code, ok := response.CodeOf(err); ok {
+			response.Fail(c, code, err.Error())
+			return
+		}
+		h.logger.Error("Failed to get customer column preference", zap.Error(err))
+		response.InternalServerError(c, "Failed to start export")
+		return
+	}
+	if _, err := customerFilter(c, query); err != nil {
+		response.BadRequest(c, err.Error(), nil)
+		return
+	}
+	for _, key := range []string{"format", "columns", "sort_by", "sort_order", "page", "limit"} {
+		query.Del(key)
+	}
+
+	job := &domain.CustomerExportJob{
+		Format:  format,
+		Columns: columns,
+		Filter:  query.Encode(),
+		Region:  middleware.GetRegionScope(c),
+		MaskPII: !authctx.Get(c).HasPermission(domain.PermissionCustomersPII),
+	}
+	if adminID := authctx.UserID(c); adminID != uuid.Nil {
+		job.RequestedBy = &adminID
+	}
+	if err := h.exportJobs.Create(c.Request.Context(), job); err != nil {
+		h.logger.Error("Failed to create customer export job", zap.Error(err))
+		response.InternalServerError(c, "Failed to start export")
+		return
+	}
+	if h.exportQueue != nil {
+		h.exportQueue.Wake()
+	}
+
+	c.Header("Location", fmt.Sprintf("%s/%s", strings.TrimSuffix(c.Request.URL.Path, "/"), job.ID))
+	response.Queued(c, "Customer export started", CustomerExportJobResponse{CustomerExportJob: job})
+}
+
+// GetExportJob handles GET /admin/customers/exports/:exportId
+// Admins only see their own exports.
+func (h *AdminCustomerHandler) GetExportJob(c *gin.Context) {
+	if h.exportJobs == nil {
+		response.ServiceUnavailable(c, "Background exports are not enabled")
+		return
+	}
+	id, err := uuid.Parse(c.Param("exportId"))
+	if err != nil {
+		response.BadRequest(c, "Invalid export ID", nil)
+		return
+	}
+
+	job, err := h.exportJobs.Get(c.Request.Context(), id)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		h.logger.Error("Failed to get customer export job", zap.Error(err))
+		response.InternalServerError(c, "Failed to retrieve export")
+		return
+	}
+	if job == nil || job.RequestedBy == nil || *job.RequestedBy != authctx.UserID(c) {
+		response.Fail(c, response.CodeCustomerExportNotFound, "Export not found")
+		return
+	}
+
+	resp := CustomerExportJobResponse{CustomerExportJob: job, Progress: job.Progress()}
+	if job.Status == domain.CustomerExportCompleted && job.FileKey != "" {
+		url, err := h.exportFiles.SignedURL(job.FileKey, h.exportURLTTL)
+		if err != nil {
+			h.logger.Error("Failed to sign customer export download", zap.Error(err))
+			response.InternalServerError(c, "Failed to retrieve export")
+			return
+		}
+		expires := time.Now().Add(h.exportURLTTL).UTC()
+		resp.DownloadURL, resp.ExpiresAt = url, &expires
+	}
+
+	response.OK(c, "", resp)
+}
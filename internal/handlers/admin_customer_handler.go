@@ -1,20 +1,69 @@
 package handlers
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
-	"github.com/Ecom-micro-template/lib-common-go/response"
+	"github.com/Ecom-micro-template/service-customer/internal/authctx"
+	"github.com/Ecom-micro-template/service-customer/internal/response"
 	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/Ecom-micro-template/service-customer/internal/domain/shared"
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/orderclient"
 	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/persistence"
+	"github.com/Ecom-micro-template/service-customer/internal/middleware"
 	"go.uber.org/zap"
+	"gorm.io/gorm"
 )
 
 type AdminCustomerHandler struct {
 	customerRepo persistence.CustomerRepository
+	orders       CustomerOrdersProvider
+	segmentRules *persistence.SegmentRuleRepository
+	mentions     NoteMentionNotifier
 	logger       *zap.Logger
+
+	// Note attachments; see WithNoteAttachments
+	attachments      *persistence.NoteAttachmentRepository
+	files            NoteFileStore
+	attachmentPolicy domain.NoteAttachmentPolicy
+
+	// Free-form customer tags; see WithTags
+	tags *persistence.CustomerTagRepository
+
+	// Saved customer list views; see WithViews
+	views *persistence.CustomerViewRepository
+
+	// Per-admin list and export columns; see WithColumnPreferences
+	columnPrefs *persistence.CustomerColumnPreferenceRepository
+
+	// Cached segment member counts; see WithSegmentCounts
+	segmentCounts *persistence.SegmentCounts
+
+	// Background exports to the file store; see WithExportJobs
+	exportJobs   *persistence.CustomerExportJobRepository
+	exportFiles  ExportFileSigner
+	exportQueue  CustomerExportQueue
+	exportURLTTL time.Duration
+}
+
+// CustomerOrdersProvider reads customers' orders, which are owned by
+// service-order; *orderclient.Client calls its API
+type CustomerOrdersProvider interface {
+	ListCustomerOrders(ctx context.Context, customerID, authorization string, page, limit int) (*domain.CustomerOrderPage, error)
+	CustomerIDByOrderNumber(ctx context.Context, orderNumber, authorization string) (string, error)
+}
+
+// NoteMentionNotifier tells staff they were @mentioned in a customer note
+type NoteMentionNotifier interface {
+	NotifyMentioned(ctx context.Context, note *domain.CustomerNote, authorID string, mentions []string) error
 }
 
 func NewAdminCustomerHandler(customerRepo persistence.CustomerRepository, logger *zap.Logger) *AdminCustomerHandler {
@@ -24,28 +73,61 @@ func NewAdminCustomerHandler(customerRepo persistence.CustomerRepository, logger
 	}
 }
 
+// WithOrderClient sets where customers' orders are read from, for order
+// listings, the timeline and order number lookups
+func (h *AdminCustomerHandler) WithOrderClient(orders CustomerOrdersProvider) *AdminCustomerHandler {
+	h.orders = orders
+	return h
+}
+
+// WithSegmentRules enables automatic segment updates when an admin changes a customer's status
+func (h *AdminCustomerHandler) WithSegmentRules(rules *persistence.SegmentRuleRepository) *AdminCustomerHandler {
+	h.segmentRules = rules
+	return h
+}
+
+// WithNoteMentions enables notifications for staff @mentioned in customer notes
+func (h *AdminCustomerHandler) WithNoteMentions(notifier NoteMentionNotifier) *AdminCustomerHandler {
+	h.mentions = notifier
+	return h
+}
+
+// WithSegmentCounts lists segments with their cached member counts
+func (h *AdminCustomerHandler) WithSegmentCounts(counts *persistence.SegmentCounts) *AdminCustomerHandler {
+	h.segmentCounts = counts
+	return h
+}
+
 // GetCustomers handles GET /admin/customers
+// ?view=<id> applies a saved view; see customerListQuery. ?cursor= switches to
+// cursor pagination; see cursorQuery.
 func (h *AdminCustomerHandler) GetCustomers(c *gin.Context) {
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	query, ok := h.customerListQuery(c)
+	if !ok {
+		return
+	}
+	page, _ := strconv.Atoi(queryDefault(query, "page", "1"))
+	limit, _ := strconv.Atoi(queryDefault(query, "limit", "20"))
 
 	filter := domain.CustomerListFilter{
-		Status:    c.Query("status"),
-		Segment:   c.Query("segment"),
-		Search:    c.Query("search"),
-		Page:      page,
-		Limit:     limit,
-		SortBy:    c.DefaultQuery("sort_by", "created_at"),
-		SortOrder: c.DefaultQuery("sort_order", "desc"),
+		Status:  query.Get("status"),
+		Segment: query.Get("segment"),
+		Search:  query.Get("search"),
+		Page:    page,
+		Limit:   limit,
+		Region:  middleware.GetRegionScope(c),
+	}
+	if filter.Sort, ok = customerListSort(c, query); !ok {
+		return
 	}
 
 	// Parse date filters
-	if dateFromStr := c.Query("date_from"); dateFromStr != "" {
+	if dateFromStr := query.Get("date_from"); dateFromStr != "" {
 		if dateFrom, err := time.Parse("2006-01-02", dateFromStr); err == nil {
 			filter.DateFrom = &dateFrom
 		}
 	}
-	if dateToStr := c.Query("date_to"); dateToStr != "" {
+	if dateToStr := query.Get("date_to"); dateToStr != "" {
 		if dateTo, err := time.Parse("2006-01-02", dateToStr); err == nil {
 			dateTo = dateTo.Add(24*time.Hour - time.Second)
 			filter.DateTo = &dateTo
@@ -53,39 +135,91 @@ func (h *AdminCustomerHandler) GetCustomers(c *gin.Context) {
 	}
 
 	// Parse order count filters
-	if ordersMinStr := c.Query("orders_min"); ordersMinStr != "" {
+	if ordersMinStr := query.Get("orders_min"); ordersMinStr != "" {
 		if ordersMin, err := strconv.Atoi(ordersMinStr); err == nil {
 			filter.OrdersMin = &ordersMin
 		}
 	}
-	if ordersMaxStr := c.Query("orders_max"); ordersMaxStr != "" {
+	if ordersMaxStr := query.Get("orders_max"); ordersMaxStr != "" {
 		if ordersMax, err := strconv.Atoi(ordersMaxStr); err == nil {
 			filter.OrdersMax = &ordersMax
 		}
 	}
 
 	// Parse spending filters
-	if spentMinStr := c.Query("spent_min"); spentMinStr != "" {
+	if spentMinStr := query.Get("spent_min"); spentMinStr != "" {
 		if spentMin, err := strconv.ParseFloat(spentMinStr, 64); err == nil {
 			filter.SpentMin = &spentMin
 		}
 	}
-	if spentMaxStr := c.Query("spent_max"); spentMaxStr != "" {
+	if spentMaxStr := query.Get("spent_max"); spentMaxStr != "" {
 		if spentMax, err := strconv.ParseFloat(spentMaxStr, 64); err == nil {
 			filter.SpentMax = &spentMax
 		}
 	}
 
-	customers, total, err := h.customerRepo.ListAdmin(filter)
+	tags, err := parseTagFilter(query.Get("tags"))
 	if err != nil {
-		h.logger.Error("Failed to list customers", zap.Error(err))
+		response.BadRequest(c, err.Error(), nil)
+		return
+	}
+	filter.Tags = tags
+	if err := domain.ParseCustomerScoreFilters(query, &filter); err != nil {
+		response.BadRequest(c, err.Error(), nil)
+		return
+	}
+
+	after, useCursor, err := cursorQuery(query)
+	if err != nil {
+		response.Fail(c, response.CodeInvalidCursor, err.Error())
+		return
+	}
+	if useCursor {
+		h.listCustomersAfter(c, filter, after)
+		return
+	}
+
+	customers, total, err := h.customerRepo.ListAdmin(c.Request.Context(), filter)
+	if err != nil {
+		h.queryFailed(c, err, "Failed to list customers", "Failed to retrieve customers")
+		return
+	}
+	if err := h.loadCustomerTags(c.Request.Context(), customers); err != nil {
+		h.logger.Error("Failed to load customer tags", zap.Error(err))
 		response.InternalServerError(c, "Failed to retrieve customers")
 		return
 	}
 
+	maskListPII(c, customers)
 	response.Paginated(c, customers, page, limit, total)
 }
 
+// listCustomersAfter serves GET /admin/customers?cursor=. Keyset pages are
+// ordered by created_at, so other sort columns are rejected.
+func (h *AdminCustomerHandler) listCustomersAfter(c *gin.Context, filter domain.CustomerListFilter, after *domain.Cursor) {
+	if filter.Sort.Field != "created_at" {
+		response.Fail(c, response.CodeInvalidSort, "Cursor pagination only supports sort_by=created_at")
+		return
+	}
+	if filter.Limit < 1 || filter.Limit > 100 {
+		filter.Limit = 20
+	}
+
+	customers, next, err := h.customerRepo.ListAdminAfter(c.Request.Context(), filter, after)
+	if err != nil {
+		h.queryFailed(c, err, "Failed to list customers", "Failed to retrieve customers")
+		return
+	}
+	if err := h.loadCustomerTags(c.Request.Context(), customers); err != nil {
+		h.logger.Error("Failed to load customer tags", zap.Error(err))
+		response.InternalServerError(c, "Failed to retrieve customers")
+		return
+	}
+
+	maskListPII(c, customers)
+	response.CursorPaginated(c, "Customers retrieved", customers, filter.Limit, next)
+}
+
 // GetCustomer handles GET /admin/customers/:id
 func (h *AdminCustomerHandler) GetCustomer(c *gin.Context) {
 	customerID, err := uuid.Parse(c.Param("id"))
@@ -94,39 +228,162 @@ func (h *AdminCustomerHandler) GetCustomer(c *gin.Context) {
 		return
 	}
 
-	customer, err := h.customerRepo.GetByID(customerID)
+	customer, err := h.customerRepo.GetByID(c.Request.Context(), customerID)
 	if err != nil {
 		h.logger.Error("Failed to get customer", zap.Error(err))
-		response.NotFound(c, "Customer not found")
+		response.Fail(c, response.CodeCustomerNotFound, "Customer not found")
 		return
 	}
 
+	maskPII(c, customer)
 	response.OK(c, "Customer retrieved", customer)
 }
 
+// maskPII masks the customer's email and phone unless the admin may see
+// them; see RevealCustomerPII
+func maskPII(c *gin.Context, customer *domain.Customer) {
+	if !authctx.Get(c).HasPermission(domain.PermissionCustomersPII) {
+		customer.MaskPII()
+	}
+}
+
+// maskListPII is maskPII for a list of customers
+func maskListPII(c *gin.Context, customers []domain.Customer) {
+	if authctx.Get(c).HasPermission(domain.PermissionCustomersPII) {
+		return
+	}
+	for i := range customers {
+		customers[i].MaskPII()
+	}
+}
+
+// RevealCustomerPII handles POST /admin/customers/:id/reveal, returning the
+// unmasked email and phone to an admin who only sees them masked. The audit
+// trail records who revealed which customer and the reason given.
+func (h *AdminCustomerHandler) RevealCustomerPII(c *gin.Context) {
+	customerID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "Invalid customer ID", nil)
+		return
+	}
+
+	var req domain.RevealPIIRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Invalid(c, err)
+		return
+	}
+
+	customer, err := h.customerRepo.GetByID(c.Request.Context(), customerID)
+	if err != nil {
+		h.logger.Error("Failed to get customer", zap.Error(err))
+		response.Fail(c, response.CodeCustomerNotFound, "Customer not found")
+		return
+	}
+
+	response.OK(c, "Customer contact details revealed", domain.CustomerPII{
+		CustomerID: customer.ID,
+		Email:      customer.Email,
+		Phone:      customer.Phone,
+	})
+}
+
+// inRegion reports whether the customer falls within the admin's region
+// scope. Customers outside it are reported as not found so agents can't probe
+// for them.
+func (h *AdminCustomerHandler) inRegion(c *gin.Context, customerID uuid.UUID) bool {
+	region := middleware.GetRegionScope(c)
+	if region == nil {
+		return true
+	}
+
+	ok, err := h.customerRepo.InRegion(c.Request.Context(), customerID, region)
+	if err != nil {
+		h.logger.Error("Failed to check customer region", zap.Error(err))
+		return false
+	}
+	return ok
+}
+
+// CustomerLookup is the payload of a customer lookup by order number
+type CustomerLookup struct {
+	OrderNumber string           `json:"order_number"`
+	Customer    *domain.Customer `json:"customer"`
+}
+
+// LookupCustomer handles GET /admin/customers/lookup?order_number=...
+func (h *AdminCustomerHandler) LookupCustomer(c *gin.Context) {
+	orderNumber := c.Query("order_number")
+	if orderNumber == "" {
+		response.BadRequest(c, "order_number is required", nil)
+		return
+	}
+	if h.orders == nil {
+		response.ServiceUnavailable(c, "Order lookup is not configured")
+		return
+	}
+
+	customerIDStr, err := h.orders.CustomerIDByOrderNumber(c.Request.Context(), orderNumber, c.GetHeader("Authorization"))
+	if err != nil {
+		if errors.Is(err, orderclient.ErrOrderNotFound) {
+			response.Fail(c, response.CodeOrderNotFound, "Order not found")
+			return
+		}
+		if errors.Is(err, orderclient.ErrOrderAccessDenied) {
+			response.Fail(c, response.CodeOrderAccessDenied, "Not allowed to look up orders")
+			return
+		}
+		h.logger.Error("Failed to resolve order number", zap.String("order_number", orderNumber), zap.Error(err))
+		response.ServiceUnavailable(c, "Order service unavailable")
+		return
+	}
+
+	customerID, err := uuid.Parse(customerIDStr)
+	if err != nil {
+		h.logger.Error("Order has invalid customer ID", zap.String("order_number", orderNumber), zap.String("customer_id", customerIDStr))
+		response.Fail(c, response.CodeCustomerNotFound, "Customer not found")
+		return
+	}
+
+	customer, err := h.customerRepo.GetByID(c.Request.Context(), customerID)
+	if err != nil {
+		h.logger.Error("Failed to get customer", zap.Error(err))
+		response.Fail(c, response.CodeCustomerNotFound, "Customer not found")
+		return
+	}
+	if !h.inRegion(c, customerID) {
+		response.Fail(c, response.CodeCustomerNotFound, "Customer not found")
+		return
+	}
+
+	maskPII(c, customer)
+	response.OK(c, "Customer retrieved", CustomerLookup{
+		OrderNumber: orderNumber,
+		Customer:    customer,
+	})
+}
+
 // CreateCustomer handles POST /admin/customers
 func (h *AdminCustomerHandler) CreateCustomer(c *gin.Context) {
 	var req domain.CreateCustomerRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		response.BadRequest(c, "Invalid request", err.Error())
+		response.Invalid(c, err)
 		return
 	}
 
 	// Get admin user ID
 	var createdBy *uuid.UUID
-	if userID, exists := c.Get("user_id"); exists {
-		if uid, ok := userID.(uuid.UUID); ok {
-			createdBy = &uid
-		}
+	if adminID := authctx.UserID(c); adminID != uuid.Nil {
+		createdBy = &adminID
 	}
 
-	customer, err := h.customerRepo.Create(&req, createdBy)
+	customer, err := h.customerRepo.Create(c.Request.Context(), &req, createdBy)
 	if err != nil {
 		h.logger.Error("Failed to create customer", zap.Error(err))
 		response.InternalServerError(c, "Failed to create customer")
 		return
 	}
 
+	maskPII(c, customer)
 	response.Created(c, "Customer created successfully", customer)
 }
 
@@ -140,17 +397,35 @@ func (h *AdminCustomerHandler) UpdateCustomer(c *gin.Context) {
 
 	var req domain.UpdateCustomerRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		response.BadRequest(c, "Invalid request", err.Error())
+		response.Invalid(c, err)
+		return
+	}
+	if req.Status != nil && !req.Status.IsValid() {
+		response.BadRequest(c, "Invalid request", shared.ErrInvalidCustomerStatus.Error())
 		return
 	}
+	// A form filled in from a masked customer sends the masked phone back
+	if req.Phone != nil && domain.IsMaskedPII(*req.Phone) {
+		req.Phone = nil
+	}
+	if adminID := authctx.UserID(c); adminID != uuid.Nil {
+		req.UpdatedBy = &adminID
+	}
 
-	customer, err := h.customerRepo.Update(customerID, &req)
+	customer, err := h.customerRepo.Update(c.Request.Context(), customerID, &req)
 	if err != nil {
 		h.logger.Error("Failed to update customer", zap.Error(err))
 		response.InternalServerError(c, "Failed to update customer")
 		return
 	}
 
+	if req.Status != nil && h.segmentRules != nil {
+		if _, err := h.segmentRules.Apply(c.Request.Context(), customerID, domain.SegmentTriggerStatusChanged); err != nil {
+			h.logger.Error("Failed to apply segment rules", zap.String("customer_id", customerID.String()), zap.Error(err))
+		}
+	}
+
+	maskPII(c, customer)
 	response.Updated(c, "Customer updated successfully", customer)
 }
 
@@ -162,7 +437,7 @@ func (h *AdminCustomerHandler) DeleteCustomer(c *gin.Context) {
 		return
 	}
 
-	if err := h.customerRepo.Delete(customerID); err != nil {
+	if err := h.customerRepo.Delete(c.Request.Context(), customerID); err != nil {
 		h.logger.Error("Failed to delete customer", zap.Error(err))
 		response.InternalServerError(c, "Failed to delete customer")
 		return
@@ -181,15 +456,39 @@ func (h *AdminCustomerHandler) GetCustomerOrders(c *gin.Context) {
 
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+	if h.orders == nil {
+		response.ServiceUnavailable(c, "Order listing is not configured")
+		return
+	}
 
-	orders, total, err := h.customerRepo.GetCustomerOrders(customerID, page, limit)
+	orders, err := h.orders.ListCustomerOrders(c.Request.Context(), customerID.String(), c.GetHeader("Authorization"), page, limit)
 	if err != nil {
-		h.logger.Error("Failed to get customer orders", zap.Error(err))
-		response.InternalServerError(c, "Failed to retrieve customer orders")
+		if errors.Is(err, orderclient.ErrOrderAccessDenied) {
+			response.Fail(c, response.CodeOrderAccessDenied, "Not allowed to list orders")
+			return
+		}
+		h.logger.Error("Failed to get customer orders", zap.String("customer_id", customerID.String()), zap.Error(err))
+		response.ServiceUnavailable(c, "Order service unavailable")
 		return
 	}
 
-	response.Paginated(c, orders, page, limit, total)
+	if orders.Stale {
+		c.Header("Warning", `110 - "Response is Stale"`)
+	}
+	response.Paginated(c, orders.Orders, page, limit, orders.Total)
+}
+
+// AddCustomerNoteRequest represents the request body for adding a note
+type AddCustomerNoteRequest struct {
+	Note      string `json:"note" binding:"required"`
+	Category  string `json:"category"`
+	IsPrivate bool   `json:"is_private"`
 }
 
 // AddCustomerNote handles POST /admin/customers/:id/notes
@@ -200,35 +499,202 @@ func (h *AdminCustomerHandler) AddCustomerNote(c *gin.Context) {
 		return
 	}
 
-	var req struct {
-		Note      string `json:"note" binding:"required"`
-		IsPrivate bool   `json:"is_private"`
-	}
-
+	var req AddCustomerNoteRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		response.BadRequest(c, "Invalid request", err.Error())
+		response.Invalid(c, err)
+		return
+	}
+	if req.Category != "" && !domain.IsValidNoteCategory(req.Category) {
+		response.BadRequest(c, "Invalid note category", gin.H{"allowed": domain.NoteCategories})
 		return
 	}
 
 	// Get admin user ID
-	var createdBy uuid.UUID
-	if userID, exists := c.Get("user_id"); exists {
-		if uid, ok := userID.(uuid.UUID); ok {
-			createdBy = uid
-		}
-	}
+	createdBy := authctx.UserID(c)
 
-	note, err := h.customerRepo.AddNote(customerID, req.Note, req.IsPrivate, createdBy)
+	note, err := h.customerRepo.AddNote(c.Request.Context(), customerID, req.Note, req.Category, req.IsPrivate, createdBy)
 	if err != nil {
 		h.logger.Error("Failed to add customer note", zap.Error(err))
 		response.InternalServerError(c, "Failed to add customer note")
 		return
 	}
 
+	h.notifyMentions(c, note, note.Mentions())
 	response.Created(c, "Customer note added successfully", note)
 }
 
+// UpdateCustomerNote handles PUT /admin/customers/:id/notes/:noteId
+// Only the note's author or an admin may edit it. Staff newly @mentioned by
+// the edit are notified.
+func (h *AdminCustomerHandler) UpdateCustomerNote(c *gin.Context) {
+	customerID, noteID, ok := parseNoteParams(c)
+	if !ok {
+		return
+	}
+
+	var req domain.UpdateCustomerNoteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Invalid(c, err)
+		return
+	}
+	if req.Note != nil && strings.TrimSpace(*req.Note) == "" {
+		response.BadRequest(c, "Note cannot be empty", nil)
+		return
+	}
+	if req.Category != nil && !domain.IsValidNoteCategory(*req.Category) {
+		response.BadRequest(c, "Invalid note category", gin.H{"allowed": domain.NoteCategories})
+		return
+	}
+
+	existing, ok := h.editableNote(c, customerID, noteID)
+	if !ok {
+		return
+	}
+
+	note, err := h.customerRepo.UpdateNote(c.Request.Context(), customerID, noteID, &req, authctx.UserID(c))
+	if err != nil {
+		h.logger.Error("Failed to update customer note", zap.Error(err))
+		response.InternalServerError(c, "Failed to update customer note")
+		return
+	}
+
+	previous := make(map[string]bool)
+	for _, handle := range existing.Mentions() {
+		previous[handle] = true
+	}
+	var added []string
+	for _, handle := range note.Mentions() {
+		if !previous[handle] {
+			added = append(added, handle)
+		}
+	}
+	h.notifyMentions(c, note, added)
+
+	response.Updated(c, "Customer note updated successfully", note)
+}
+
+// DeleteCustomerNote handles DELETE /admin/customers/:id/notes/:noteId
+// Only the note's author or an admin may delete it.
+func (h *AdminCustomerHandler) DeleteCustomerNote(c *gin.Context) {
+	customerID, noteID, ok := parseNoteParams(c)
+	if !ok {
+		return
+	}
+	if _, ok := h.editableNote(c, customerID, noteID); !ok {
+		return
+	}
+
+	if err := h.customerRepo.DeleteNote(c.Request.Context(), customerID, noteID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			response.Fail(c, response.CodeNoteNotFound, "Note not found")
+			return
+		}
+		h.logger.Error("Failed to delete customer note", zap.Error(err))
+		response.InternalServerError(c, "Failed to delete customer note")
+		return
+	}
+	h.deleteNoteAttachments(c.Request.Context(), noteID)
+
+	response.Deleted(c, "Customer note deleted successfully")
+}
+
+// PinCustomerNote handles POST /admin/customers/:id/notes/:noteId/pin
+func (h *AdminCustomerHandler) PinCustomerNote(c *gin.Context) {
+	customerID, noteID, ok := parseNoteParams(c)
+	if !ok {
+		return
+	}
+
+	note, err := h.customerRepo.PinNote(c.Request.Context(), customerID, noteID, authctx.UserID(c))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			response.Fail(c, response.CodeNoteNotFound, "Note not found")
+			return
+		}
+		h.logger.Error("Failed to pin customer note", zap.Error(err))
+		response.InternalServerError(c, "Failed to pin customer note")
+		return
+	}
+
+	response.Updated(c, "Note pinned", note)
+}
+
+// UnpinCustomerNote handles DELETE /admin/customers/:id/notes/:noteId/pin
+func (h *AdminCustomerHandler) UnpinCustomerNote(c *gin.Context) {
+	customerID, noteID, ok := parseNoteParams(c)
+	if !ok {
+		return
+	}
+
+	note, err := h.customerRepo.UnpinNote(c.Request.Context(), customerID, noteID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			response.Fail(c, response.CodeNoteNotFound, "Note not found")
+			return
+		}
+		h.logger.Error("Failed to unpin customer note", zap.Error(err))
+		response.InternalServerError(c, "Failed to unpin customer note")
+		return
+	}
+
+	response.Updated(c, "Note unpinned", note)
+}
+
+func parseNoteParams(c *gin.Context) (customerID, noteID uuid.UUID, ok bool) {
+	customerID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "Invalid customer ID", nil)
+		return uuid.Nil, uuid.Nil, false
+	}
+	noteID, err = uuid.Parse(c.Param("noteId"))
+	if err != nil {
+		response.BadRequest(c, "Invalid note ID", nil)
+		return uuid.Nil, uuid.Nil, false
+	}
+	return customerID, noteID, true
+}
+
+// editableNote loads a note the current user may change: their own, or any
+// note for admins
+func (h *AdminCustomerHandler) editableNote(c *gin.Context, customerID, noteID uuid.UUID) (*domain.CustomerNote, bool) {
+	note, err := h.customerRepo.GetNote(c.Request.Context(), customerID, noteID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			response.Fail(c, response.CodeNoteNotFound, "Note not found")
+			return nil, false
+		}
+		h.logger.Error("Failed to get customer note", zap.Error(err))
+		response.InternalServerError(c, "Failed to get customer note")
+		return nil, false
+	}
+
+	userID := authctx.UserID(c)
+	isAuthor := note.CreatedBy != nil && *note.CreatedBy == userID && userID != uuid.Nil
+	if !isAuthor && !authctx.Get(c).HasPermission(domain.PermissionNotesModerate) {
+		response.Forbidden(c, "Only the note's author or an admin can change it")
+		return nil, false
+	}
+	return note, true
+}
+
+// notifyMentions tells @mentioned staff about the note. The note is already
+// saved, so a failure is only logged.
+func (h *AdminCustomerHandler) notifyMentions(c *gin.Context, note *domain.CustomerNote, mentions []string) {
+	if h.mentions == nil || len(mentions) == 0 {
+		return
+	}
+	authorID := authctx.UserID(c).String()
+	if err := h.mentions.NotifyMentioned(c.Request.Context(), note, authorID, mentions); err != nil {
+		h.logger.Warn("Failed to notify mentioned staff",
+			zap.String("note_id", note.ID.String()),
+			zap.Strings("mentions", mentions),
+			zap.Error(err))
+	}
+}
+
 // GetCustomerNotes handles GET /admin/customers/:id/notes
+// Query: page, limit, category, is_private, created_by, date_from, date_to
+// Attachments come with download links that expire after a few minutes.
 func (h *AdminCustomerHandler) GetCustomerNotes(c *gin.Context) {
 	customerID, err := uuid.Parse(c.Param("id"))
 	if err != nil {
@@ -236,17 +702,106 @@ func (h *AdminCustomerHandler) GetCustomerNotes(c *gin.Context) {
 		return
 	}
 
-	notes, err := h.customerRepo.GetNotes(customerID)
+	filter, err := parseNoteFilter(c)
+	if err != nil {
+		response.BadRequest(c, err.Error(), nil)
+		return
+	}
+
+	notes, total, err := h.customerRepo.GetNotes(c.Request.Context(), customerID, filter)
 	if err != nil {
-		h.logger.Error("Failed to get customer notes", zap.Error(err))
+		h.queryFailed(c, err, "Failed to get customer notes", "Failed to retrieve customer notes")
+		return
+	}
+	if err := h.loadNoteAttachments(c.Request.Context(), notes); err != nil {
+		h.logger.Error("Failed to get customer note attachments", zap.Error(err))
 		response.InternalServerError(c, "Failed to retrieve customer notes")
 		return
 	}
 
-	response.OK(c, "Customer notes retrieved", notes)
+	response.Paginated(c, notes, filter.Page, filter.Limit, total)
+}
+
+// CountCustomerNotes handles GET /admin/customers/:id/notes/count
+// Accepts the same filters as GetCustomerNotes
+func (h *AdminCustomerHandler) CountCustomerNotes(c *gin.Context) {
+	customerID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "Invalid customer ID", nil)
+		return
+	}
+
+	filter, err := parseNoteFilter(c)
+	if err != nil {
+		response.BadRequest(c, err.Error(), nil)
+		return
+	}
+
+	counts, err := h.customerRepo.CountNotes(c.Request.Context(), customerID, filter)
+	if err != nil {
+		h.logger.Error("Failed to count customer notes", zap.Error(err))
+		response.InternalServerError(c, "Failed to count customer notes")
+		return
+	}
+
+	response.OK(c, "Customer note counts retrieved", counts)
+}
+
+// parseNoteFilter reads note list filters from the query string
+func parseNoteFilter(c *gin.Context) (domain.CustomerNoteFilter, error) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	filter := domain.CustomerNoteFilter{
+		Category: c.Query("category"),
+		Page:     page,
+		Limit:    limit,
+	}
+	if filter.Category != "" && !domain.IsValidNoteCategory(filter.Category) {
+		return filter, errors.New("Invalid note category")
+	}
+
+	if isPrivateStr := c.Query("is_private"); isPrivateStr != "" {
+		isPrivate, err := strconv.ParseBool(isPrivateStr)
+		if err != nil {
+			return filter, errors.New("is_private must be true or false")
+		}
+		filter.IsPrivate = &isPrivate
+	}
+	if createdByStr := c.Query("created_by"); createdByStr != "" {
+		createdBy, err := uuid.Parse(createdByStr)
+		if err != nil {
+			return filter, errors.New("Invalid created_by ID")
+		}
+		filter.CreatedBy = &createdBy
+	}
+	if dateFromStr := c.Query("date_from"); dateFromStr != "" {
+		dateFrom, err := time.Parse("2006-01-02", dateFromStr)
+		if err != nil {
+			return filter, errors.New("date_from must be YYYY-MM-DD")
+		}
+		filter.DateFrom = &dateFrom
+	}
+	if dateToStr := c.Query("date_to"); dateToStr != "" {
+		dateTo, err := time.Parse("2006-01-02", dateToStr)
+		if err != nil {
+			return filter, errors.New("date_to must be YYYY-MM-DD")
+		}
+		dateTo = dateTo.Add(24*time.Hour - time.Second)
+		filter.DateTo = &dateTo
+	}
+	return filter, nil
 }
 
 // GetCustomerActivity handles GET /admin/customers/:id/activity
+// ?cursor= switches to cursor pagination (see cursorQuery), newest first
+// without lifting pinned activities to the top.
 func (h *AdminCustomerHandler) GetCustomerActivity(c *gin.Context) {
 	customerID, err := uuid.Parse(c.Param("id"))
 	if err != nil {
@@ -257,43 +812,309 @@ func (h *AdminCustomerHandler) GetCustomerActivity(c *gin.Context) {
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
 
-	activity, total, err := h.customerRepo.GetActivity(customerID, page, limit)
+	after, useCursor, err := cursorQuery(c.Request.URL.Query())
+	if err != nil {
+		response.Fail(c, response.CodeInvalidCursor, err.Error())
+		return
+	}
+	if useCursor {
+		if limit < 1 || limit > domain.MaxActivityFeedLimit {
+			limit = domain.DefaultActivityFeedLimit
+		}
+		activity, next, err := h.customerRepo.GetActivityAfter(c.Request.Context(), customerID, after, limit)
+		if err != nil {
+			h.queryFailed(c, err, "Failed to get customer activity", "Failed to retrieve customer activity")
+			return
+		}
+		response.CursorPaginated(c, "Customer activity retrieved", activity, limit, next)
+		return
+	}
+
+	activity, total, err := h.customerRepo.GetActivity(c.Request.Context(), customerID, page, limit)
 	if err != nil {
-		h.logger.Error("Failed to get customer activity", zap.Error(err))
-		response.InternalServerError(c, "Failed to retrieve customer activity")
+		h.queryFailed(c, err, "Failed to get customer activity", "Failed to retrieve customer activity")
 		return
 	}
 
 	response.Paginated(c, activity, page, limit, total)
 }
 
+// GetCustomerTimeline handles GET /admin/customers/:id/timeline
+// Merges activities, notes, status changes, segment assignments and orders
+// into one feed, newest first. ?types=note,order limits the entry types.
+func (h *AdminCustomerHandler) GetCustomerTimeline(c *gin.Context) {
+	customerID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "Invalid customer ID", nil)
+		return
+	}
+
+	filter, err := parseTimelineFilter(c)
+	if err != nil {
+		response.BadRequest(c, err.Error(), gin.H{"allowed_types": domain.TimelineTypes, "max_depth": domain.MaxTimelineDepth})
+		return
+	}
+
+	entries, total, err := h.customerRepo.GetTimeline(c.Request.Context(), customerID, filter)
+	if err != nil {
+		h.queryFailed(c, err, "Failed to get customer timeline", "Failed to retrieve customer timeline")
+		return
+	}
+
+	// Orders live in service-order; without it the rest of the timeline is still useful
+	var unavailable []string
+	if filter.Includes(domain.TimelineTypeOrder) {
+		orderEntries, orderTotal, err := h.orderTimeline(c, customerID, filter.Depth())
+		if err != nil {
+			h.logger.Warn("Orders unavailable for customer timeline", zap.String("customer_id", customerID.String()), zap.Error(err))
+			unavailable = append(unavailable, domain.TimelineTypeOrder)
+		} else {
+			entries = append(entries, orderEntries...)
+			total += orderTotal
+		}
+	}
+
+	response.OK(c, "Customer timeline retrieved", domain.Timeline{
+		Entries:     domain.TimelinePage(entries, filter),
+		Page:        filter.Page,
+		Limit:       filter.Limit,
+		Total:       total,
+		Unavailable: unavailable,
+	})
+}
+
+// orderTimeline returns the customer's most recent orders as timeline entries
+func (h *AdminCustomerHandler) orderTimeline(c *gin.Context, customerID uuid.UUID, limit int) ([]domain.TimelineEntry, int64, error) {
+	if h.orders == nil {
+		return nil, 0, errors.New("order client not configured")
+	}
+
+	orders, err := h.orders.ListCustomerOrders(c.Request.Context(), customerID.String(), c.GetHeader("Authorization"), 1, limit)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	entries := make([]domain.TimelineEntry, 0, len(orders.Orders))
+	for _, order := range orders.Orders {
+		entries = append(entries, domain.TimelineEntry{
+			ID:         order.ID,
+			Type:       domain.TimelineTypeOrder,
+			Title:      "Order " + order.OrderNum + " placed",
+			Details:    fmt.Sprintf("%s, total %.2f", order.Status, order.Total),
+			OccurredAt: order.CreatedAt,
+			Data:       order,
+		})
+	}
+	return entries, orders.Total, nil
+}
+
+// parseTimelineFilter reads the timeline query parameters
+func parseTimelineFilter(c *gin.Context) (domain.TimelineFilter, error) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	filter := domain.TimelineFilter{Page: page, Limit: limit}
+	if filter.Depth() > domain.MaxTimelineDepth {
+		return filter, fmt.Errorf("page * limit must not exceed %d", domain.MaxTimelineDepth)
+	}
+
+	if typesStr := c.Query("types"); typesStr != "" {
+		for _, t := range strings.Split(typesStr, ",") {
+			t = strings.TrimSpace(t)
+			if !domain.IsValidTimelineType(t) {
+				return filter, fmt.Errorf("Invalid timeline type %q", t)
+			}
+			filter.Types = append(filter.Types, t)
+		}
+	}
+	return filter, nil
+}
+
+// PinnedActivities is the payload of a customer's pinned activity, with the
+// most that may be pinned
+type PinnedActivities struct {
+	Activities []domain.CustomerActivity `json:"activities"`
+	Limit      int                       `json:"limit"`
+}
+
+// GetPinnedActivity handles GET /admin/customers/:id/activity/pinned
+func (h *AdminCustomerHandler) GetPinnedActivity(c *gin.Context) {
+	customerID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "Invalid customer ID", nil)
+		return
+	}
+
+	activities, err := h.customerRepo.GetPinnedActivities(c.Request.Context(), customerID)
+	if err != nil {
+		h.logger.Error("Failed to get pinned activity", zap.Error(err))
+		response.InternalServerError(c, "Failed to retrieve pinned activity")
+		return
+	}
+
+	response.OK(c, "Pinned activity retrieved", PinnedActivities{
+		Activities: activities,
+		Limit:      domain.MaxPinnedActivities,
+	})
+}
+
+// PinActivity handles POST /admin/customers/:id/activity/:activityId/pin
+func (h *AdminCustomerHandler) PinActivity(c *gin.Context) {
+	customerID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "Invalid customer ID", nil)
+		return
+	}
+	activityID, err := uuid.Parse(c.Param("activityId"))
+	if err != nil {
+		response.BadRequest(c, "Invalid activity ID", nil)
+		return
+	}
+
+	// Get admin user ID
+	pinnedBy := authctx.UserID(c)
+
+	activity, err := h.customerRepo.PinActivity(c.Request.Context(), customerID, activityID, pinnedBy)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			response.Fail(c, response.CodeActivityNotFound, "Activity not found")
+			return
+		}
+		if errors.Is(err, domain.ErrPinnedActivityLimit) {
+			response.Fail(c, response.CodePinnedActivityLimit, fmt.Sprintf("A customer can have at most %d pinned activities; unpin one first", domain.MaxPinnedActivities))
+			return
+		}
+		h.logger.Error("Failed to pin activity", zap.Error(err))
+		response.InternalServerError(c, "Failed to pin activity")
+		return
+	}
+
+	response.Updated(c, "Activity pinned", activity)
+}
+
+// UnpinActivity handles DELETE /admin/customers/:id/activity/:activityId/pin
+func (h *AdminCustomerHandler) UnpinActivity(c *gin.Context) {
+	customerID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "Invalid customer ID", nil)
+		return
+	}
+	activityID, err := uuid.Parse(c.Param("activityId"))
+	if err != nil {
+		response.BadRequest(c, "Invalid activity ID", nil)
+		return
+	}
+
+	activity, err := h.customerRepo.UnpinActivity(c.Request.Context(), customerID, activityID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			response.Fail(c, response.CodeActivityNotFound, "Activity not found")
+			return
+		}
+		h.logger.Error("Failed to unpin activity", zap.Error(err))
+		response.InternalServerError(c, "Failed to unpin activity")
+		return
+	}
+
+	response.Updated(c, "Activity unpinned", activity)
+}
+
 // GetSegments handles GET /admin/segments
 func (h *AdminCustomerHandler) GetSegments(c *gin.Context) {
-	segments, err := h.customerRepo.GetSegments()
+	segments, err := h.customerRepo.GetSegments(c.Request.Context())
 	if err != nil {
 		h.logger.Error("Failed to get segments", zap.Error(err))
 		response.InternalServerError(c, "Failed to retrieve customer segments")
 		return
 	}
 
-	response.OK(c, "Customer segments retrieved", segments)
+	var counts map[uuid.UUID]int64
+	if h.segmentCounts != nil {
+		if counts, err = h.segmentCounts.Get(c.Request.Context()); err != nil {
+			h.logger.Error("Failed to count segment members", zap.Error(err))
+			response.InternalServerError(c, "Failed to retrieve customer segments")
+			return
+		}
+	}
+	listed := make([]domain.SegmentWithCount, 0, len(segments))
+	for _, segment := range segments {
+		listed = append(listed, domain.SegmentWithCount{CustomerSegment: segment, MemberCount: counts[segment.ID]})
+	}
+
+	response.OK(c, "Customer segments retrieved", listed)
 }
 
-// CreateSegment handles POST /admin/segments
-func (h *AdminCustomerHandler) CreateSegment(c *gin.Context) {
-	var req struct {
-		Name        string      `json:"name" binding:"required"`
-		Description string      `json:"description"`
-		Conditions  interface{} `json:"conditions"` // JSON conditions for dynamic segments
-		Color       string      `json:"color"`
+// PreviewSegmentRequest represents the request body for previewing a segment
+type PreviewSegmentRequest struct {
+	Conditions domain.SegmentConditions `json:"conditions"`
+}
+
+// PreviewSegment handles POST /admin/segments/preview
+// It counts the customers matching the conditions, within the admin's
+// region, and returns a sample of them without saving anything.
+func (h *AdminCustomerHandler) PreviewSegment(c *gin.Context) {
+	var req PreviewSegmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Invalid(c, err)
+		return
+	}
+	filter, err := req.Conditions.Filter()
+	if err != nil {
+		response.BadRequest(c, err.Error(), nil)
+		return
 	}
+	filter.Region = middleware.GetRegionScope(c)
+	filter.Page, filter.Limit = 1, domain.SegmentPreviewSampleSize
 
+	customers, total, err := h.customerRepo.ListAdmin(c.Request.Context(), filter)
+	if err != nil {
+		h.queryFailed(c, err, "Failed to preview segment", "Failed to preview segment")
+		return
+	}
+	if customers == nil {
+		customers = []domain.Customer{}
+	}
+	maskListPII(c, customers)
+
+	response.OK(c, "", domain.SegmentPreview{Count: total, Sample: customers})
+}
+
+// CreateSegmentRequest represents the request body for creating a segment
+type CreateSegmentRequest struct {
+	Name        string      `json:"name" binding:"required"`
+	Description string      `json:"description"`
+	Conditions  interface{} `json:"conditions"` // JSON conditions for dynamic segments
+	Color       string      `json:"color"`
+}
+
+// UpdateSegmentRequest represents the request body for updating a segment
+type UpdateSegmentRequest struct {
+	Name        *string     `json:"name,omitempty"`
+	Description *string     `json:"description,omitempty"`
+	Conditions  interface{} `json:"conditions,omitempty"`
+	Color       *string     `json:"color,omitempty"`
+}
+
+// AssignSegmentsRequest represents the request body for assigning segments
+type AssignSegmentsRequest struct {
+	SegmentIDs []uuid.UUID `json:"segment_ids" binding:"required"`
+}
+
+// CreateSegment handles POST /admin/segments
+func (h *AdminCustomerHandler) CreateSegment(c *gin.Context) {
+	var req CreateSegmentRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		response.BadRequest(c, "Invalid request", err.Error())
+		response.Invalid(c, err)
 		return
 	}
 
-	segment, err := h.customerRepo.CreateSegment(req.Name, req.Description, req.Conditions, req.Color)
+	segment, err := h.customerRepo.CreateSegment(c.Request.Context(), req.Name, req.Description, req.Conditions, req.Color)
 	if err != nil {
 		h.logger.Error("Failed to create segment", zap.Error(err))
 		response.InternalServerError(c, "Failed to create customer segment")
@@ -311,19 +1132,13 @@ func (h *AdminCustomerHandler) UpdateSegment(c *gin.Context) {
 		return
 	}
 
-	var req struct {
-		Name        *string     `json:"name,omitempty"`
-		Description *string     `json:"description,omitempty"`
-		Conditions  interface{} `json:"conditions,omitempty"`
-		Color       *string     `json:"color,omitempty"`
-	}
-
+	var req UpdateSegmentRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		response.BadRequest(c, "Invalid request", err.Error())
+		response.Invalid(c, err)
 		return
 	}
 
-	segment, err := h.customerRepo.UpdateSegment(segmentID, req.Name, req.Description, req.Conditions, req.Color)
+	segment, err := h.customerRepo.UpdateSegment(c.Request.Context(), segmentID, req.Name, req.Description, req.Conditions, req.Color)
 	if err != nil {
 		h.logger.Error("Failed to update segment", zap.Error(err))
 		response.InternalServerError(c, "Failed to update customer segment")
@@ -341,7 +1156,7 @@ func (h *AdminCustomerHandler) DeleteSegment(c *gin.Context) {
 		return
 	}
 
-	if err := h.customerRepo.DeleteSegment(segmentID); err != nil {
+	if err := h.customerRepo.DeleteSegment(c.Request.Context(), segmentID); err != nil {
 		h.logger.Error("Failed to delete segment", zap.Error(err))
 		response.InternalServerError(c, "Failed to delete customer segment")
 		return
@@ -358,16 +1173,13 @@ func (h *AdminCustomerHandler) AssignSegment(c *gin.Context) {
 		return
 	}
 
-	var req struct {
-		SegmentIDs []uuid.UUID `json:"segment_ids" binding:"required"`
-	}
-
+	var req AssignSegmentsRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		response.BadRequest(c, "Invalid request", err.Error())
+		response.Invalid(c, err)
 		return
 	}
 
-	if err := h.customerRepo.AssignSegments(customerID, req.SegmentIDs); err != nil {
+	if err := h.customerRepo.AssignSegments(c.Request.Context(), customerID, req.SegmentIDs); err != nil {
 		h.logger.Error("Failed to assign segments", zap.Error(err))
 		response.InternalServerError(c, "Failed to assign customer segments")
 		return
@@ -377,33 +1189,109 @@ func (h *AdminCustomerHandler) AssignSegment(c *gin.Context) {
 }
 
 // ExportCustomers handles GET /admin/customers/export
+// ?format=csv (the default) downloads a CSV file; ?format=json returns the same
+// rows as objects. Columns come from ?columns=, a saved view or the admin's
+// saved columns, in that order; see exportColumns. ?view=<id> applies a saved
+// view's filters, as on GET /admin/customers.
+// Deprecated in favour of the background exports of POST
+// /admin/customers/exports, which have no row cap.
 func (h *AdminCustomerHandler) ExportCustomers(c *gin.Context) {
-	format := c.DefaultQuery("format", "csv")
+	c.Header("Deprecation", "true")
+	c.Header("Link", `</api/v1/admin/customers/exports>; rel="successor-version"`)
+	query, ok := h.customerListQuery(c)
+	if !ok {
+		return
+	}
+	format := queryDefault(query, "format", "csv")
+	if format != "csv" && format != "json" {
+		response.BadRequest(c, "format must be csv or json", nil)
+		return
+	}
+	columns, err := h.exportColumns(c, query)
+	if err != nil {
+		if code, ok := response.CodeOf(err); ok {
+			response.Fail(c, code, err.Error())
+			return
+		}
+		h.logger.Error("Failed to get customer column preference", zap.Error(err))
+		response.InternalServerError(c, "Failed to export customers")
+		return
+	}
 
-	filter := domain.CustomerListFilter{
-		Status:  c.Query("status"),
-		Segment: c.Query("segment"),
-		Search:  c.Query("search"),
+	filter, err := customerFilter(c, query)
+	if err != nil {
+		response.BadRequest(c, err.Error(), nil)
+		return
+	}
+	filter.Page, filter.Limit = 1, domain.MaxCustomerExportRows
+	if filter.Sort, ok = customerListSort(c, query); !ok {
+		return
 	}
 
-	data, err := h.customerRepo.Export(filter, format)
+	data, err := h.customerRepo.Export(c.Request.Context(), filter, format)
 	if err != nil {
-		h.logger.Error("Failed to export customers", zap.Error(err))
-		response.InternalServerError(c, "Failed to export customers")
+		h.queryFailed(c, err, "Failed to export customers", "Failed to export customers")
 		return
 	}
+	customers, _ := data.([]domain.Customer)
+	if slices.Contains(columns, "tags") {
+		if err := h.loadCustomerTags(c.Request.Context(), customers); err != nil {
+			h.logger.Error("Failed to load customer tags", zap.Error(err))
+			response.InternalServerError(c, "Failed to export customers")
+			return
+		}
+	}
 
-	response.OK(c, "Customers exported successfully", data)
+	maskListPII(c, customers)
+	if format == "csv" {
+		writeCustomersCSV(c, customers, columns)
+		return
+	}
+	response.OK(c, "Customers exported successfully", domain.NewCustomerExport(customers, columns))
+}
+
+// customerFilter returns the customer filters of an export or bulk change
+// query, limited to the admin's region; see domain.ParseCustomerExportFilter
+func customerFilter(c *gin.Context, query url.Values) (domain.CustomerListFilter, error) {
+	filter, err := domain.ParseCustomerExportFilter(query)
+	filter.Region = middleware.GetRegionScope(c)
+	return filter, err
 }
 
 // GetCustomerStats handles GET /admin/customers/stats
+// Query: period (7d, 30d or 90d, default 30d), timezone (IANA name, default
+// UTC)
 func (h *AdminCustomerHandler) GetCustomerStats(c *gin.Context) {
-	stats, err := h.customerRepo.GetStats()
+	loc, err := domain.LoadStatsTimezone(c.Query("timezone"))
+	if err != nil {
+		response.BadRequest(c, err.Error(), nil)
+		return
+	}
+	window, err := domain.NewStatsWindow(c.DefaultQuery("period", domain.DefaultStatsPeriod), loc, time.Now())
+	if err != nil {
+		response.BadRequest(c, err.Error(), nil)
+		return
+	}
+
+	stats, err := h.customerRepo.GetStats(c.Request.Context(), window)
 	if err != nil {
-		h.logger.Error("Failed to get customer stats", zap.Error(err))
-		response.InternalServerError(c, "Failed to retrieve customer statistics")
+		h.queryFailed(c, err, "Failed to get customer stats", "Failed to retrieve customer statistics")
 		return
 	}
 
 	response.OK(c, "Customer statistics retrieved", stats)
 }
+
+// queryFailed logs and writes the problem of a failed customer query: a 503
+// when it ran past its timeout, otherwise a 500 with detail. A query
+// cancelled because the client disconnected, such as an abandoned export, is
+// not an error and gets no response, since nobody is left to read it.
+func (h *AdminCustomerHandler) queryFailed(c *gin.Context, err error, message, detail string) {
+	if errors.Is(c.Request.Context().Err(), context.Canceled) {
+		h.logger.Info(message+": client disconnected", zap.String("path", c.Request.URL.Path))
+		c.Abort()
+		return
+	}
+	h.logger.Error(message, zap.Error(err))
+	response.FromError(c, err, "", detail)
+}
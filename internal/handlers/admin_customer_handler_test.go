@@ -0,0 +1,193 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/authctx"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/persistence"
+	"github.com/Ecom-micro-template/service-customer/internal/middleware"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// stubCustomerRepository serves one customer and records updates; other
+// methods aren't used by these tests
+type stubCustomerRepository struct {
+	persistence.CustomerRepository
+	customer *domain.Customer
+	updated  *domain.UpdateCustomerRequest
+}
+
+func (r *stubCustomerRepository) GetByID(_ context.Context, id uuid.UUID) (*domain.Customer, error) {
+	customer := *r.customer
+	return &customer, nil
+}
+
+func (r *stubCustomerRepository) Update(_ context.Context, id uuid.UUID, req *domain.UpdateCustomerRequest) (*domain.Customer, error) {
+	r.updated = req
+	customer := *r.customer
+	if req.Phone != nil {
+		customer.Phone = *req.Phone
+	}
+	return &customer, nil
+}
+
+// recordedAudit keeps the audit log entries recorded
+type recordedAudit struct {
+	entries []*domain.AuditLog
+}
+
+func (a *recordedAudit) Record(_ context.Context, entry *domain.AuditLog) error {
+	a.entries = append(a.entries, entry)
+	return nil
+}
+
+// adminCustomerRouter serves the customer detail, update and reveal routes
+// to an admin with permissions, behind the audit trail
+func adminCustomerRouter(repo persistence.CustomerRepository, audit middleware.AuditRecorder, adminID uuid.UUID, permissions ...string) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	handler := NewAdminCustomerHandler(repo, zap.NewNop())
+	router := gin.New()
+	customers := router.Group("/admin/customers")
+	customers.Use(func(c *gin.Context) {
+		authctx.Set(c, &authctx.Principal{ID: adminID, Role: "SUPPORT", Permissions: permissions})
+	})
+	customers.Use(middleware.NewAuditTrail(audit).
+		Audit(http.MethodPost, "/admin/customers/:id/reveal", domain.AuditEntityCustomer, "reveal_pii", "id").
+		Middleware())
+	customers.GET("/:id", handler.GetCustomer)
+	customers.PUT("/:id", handler.UpdateCustomer)
+	customers.POST("/:id/reveal", handler.RevealCustomerPII)
+	return router
+}
+
+func piiCustomer() *domain.Customer {
+	return &domain.Customer{ID: uuid.New(), Email: "aisyah.rahman@example.com", Phone: "+60123456789", FirstName: "Aisyah"}
+}
+
+func decodeData(t *testing.T, w *httptest.ResponseRecorder, data interface{}) {
+	t.Helper()
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &struct {
+		Data interface{} `json:"data"`
+	}{Data: data}))
+}
+
+func TestAdminCustomerHandler_GetCustomerMasksPII(t *testing.T) {
+	customer := piiCustomer()
+	repo := &stubCustomerRepository{customer: customer}
+	require.NotEqual(t, customer.Email, domain.MaskEmail(customer.Email))
+	require.NotEqual(t, customer.Phone, domain.MaskPhone(customer.Phone))
+
+	tests := []struct {
+		name        string
+		permissions []string
+		email       string
+		phone       string
+		masked      bool
+	}{
+		{"without customers:pii", []string{domain.PermissionCustomersRead}, domain.MaskEmail(customer.Email), domain.MaskPhone(customer.Phone), true},
+		{"with customers:pii", []string{domain.PermissionCustomersRead, domain.PermissionCustomersPII}, customer.Email, customer.Phone, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router := adminCustomerRouter(repo, &recordedAudit{}, uuid.New(), tt.permissions...)
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/customers/"+customer.ID.String(), nil))
+			require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+			var got domain.Customer
+			decodeData(t, w, &got)
+			assert.Equal(t, tt.email, got.Email)
+			assert.Equal(t, tt.phone, got.Phone)
+			assert.Equal(t, tt.masked, got.PIIMasked)
+		})
+	}
+}
+
+func TestAdminCustomerHandler_RevealIsAudited(t *testing.T) {
+	customer := piiCustomer()
+	audit := &recordedAudit{}
+	adminID := uuid.New()
+	router := adminCustomerRouter(&stubCustomerRepository{customer: customer}, audit, adminID, domain.PermissionCustomersRevealPII)
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/admin/customers/"+customer.ID.String()+"/reveal",
+		bytes.NewBufferString(`{"reason": "Customer called about a missing parcel"}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var pii domain.CustomerPII
+	decodeData(t, w, &pii)
+	assert.Equal(t, customer.Email, pii.Email)
+	assert.Equal(t, customer.Phone, pii.Phone)
+
+	require.Len(t, audit.entries, 1)
+	entry := audit.entries[0]
+	assert.Equal(t, "reveal_pii", entry.Action)
+	assert.Equal(t, adminID, entry.ActorID)
+	require.NotNil(t, entry.EntityID)
+	assert.Equal(t, customer.ID, *entry.EntityID)
+	assert.Equal(t, "Customer called about a missing parcel", entry.Request["reason"])
+}
+
+func TestAdminCustomerHandler_RevealRequiresReason(t *testing.T) {
+	customer := piiCustomer()
+	audit := &recordedAudit{}
+	router := adminCustomerRouter(&stubCustomerRepository{customer: customer}, audit, uuid.New(), domain.PermissionCustomersRevealPII)
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/admin/customers/"+customer.ID.String()+"/reveal", bytes.NewBufferString(`{}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+	assert.NotEqual(t, http.StatusOK, w.Code)
+	assert.Empty(t, audit.entries, "a refused reveal isn't recorded")
+}
+
+func TestAdminCustomerHandler_UpdateDropsMaskedPhone(t *testing.T) {
+	customer := piiCustomer()
+
+	tests := []struct {
+		name      string
+		phone     string
+		wantPhone *string
+	}{
+		{"masked phone sent back from the form", domain.MaskPhone(customer.Phone), nil},
+		{"fully masked phone", "****", nil},
+		{"new phone", "+60198765432", strPtr("+60198765432")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &stubCustomerRepository{customer: customer}
+			router := adminCustomerRouter(repo, &recordedAudit{}, uuid.New(), domain.PermissionCustomersWrite)
+
+			body, _ := json.Marshal(map[string]string{"first_name": "Aisyah", "phone": tt.phone})
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPut, "/admin/customers/"+customer.ID.String(), bytes.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			router.ServeHTTP(w, req)
+			require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+			require.NotNil(t, repo.updated)
+			assert.Equal(t, tt.wantPhone, repo.updated.Phone)
+			require.NotNil(t, repo.updated.FirstName, "the rest of the update still applies")
+
+			var got domain.Customer
+			decodeData(t, w, &got)
+			assert.True(t, got.PIIMasked, "the response stays masked without customers:pii")
+		})
+	}
+}
+
+func strPtr(s string) *string {
+	return &s
+}
@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/authctx"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/persistence"
+	"github.com/Ecom-micro-template/service-customer/internal/response"
+	"gorm.io/gorm"
+)
+
+// AdminCustomerLimitHandler lets admins view and override a customer's caps
+// on addresses, wishlist items and measurements
+type AdminCustomerLimitHandler struct {
+	limits *persistence.CustomerLimitRepository
+}
+
+// NewAdminCustomerLimitHandler creates a new admin customer limit handler
+func NewAdminCustomerLimitHandler(limits *persistence.CustomerLimitRepository) *AdminCustomerLimitHandler {
+	return &AdminCustomerLimitHandler{limits: limits}
+}
+
+// CustomerLimitStatus is the payload of a customer limit lookup: the caps
+// in effect, the defaults they derive from and what the customer stores
+type CustomerLimitStatus struct {
+	Limits   domain.CustomerLimits         `json:"limits"`
+	Defaults domain.CustomerLimits         `json:"defaults"`
+	Usage    domain.CustomerLimits         `json:"usage"`
+	Override *domain.CustomerLimitOverride `json:"override"`
+}
+
+// GetLimits returns a customer's caps and usage
+// GET /api/v1/admin/customers/:id/limits
+func (h *AdminCustomerLimitHandler) GetLimits(c *gin.Context) {
+	customerID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "Invalid customer ID", nil)
+		return
+	}
+
+	status, err := h.status(c, customerID)
+	if err != nil {
+		response.InternalServerError(c, "Failed to retrieve customer limits")
+		return
+	}
+
+	response.OK(c, "", status)
+}
+
+// SetLimits overrides a customer's caps
+// PUT /api/v1/admin/customers/:id/limits
+func (h *AdminCustomerLimitHandler) SetLimits(c *gin.Context) {
+	customerID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "Invalid customer ID", nil)
+		return
+	}
+
+	var req domain.CustomerLimitOverrideRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Invalid(c, err)
+		return
+	}
+
+	override := &domain.CustomerLimitOverride{
+		CustomerID:    customerID,
+		Addresses:     req.Addresses,
+		WishlistItems: req.WishlistItems,
+		Measurements:  req.Measurements,
+		Reason:        req.Reason,
+	}
+	if adminID := authctx.UserID(c); adminID != uuid.Nil {
+		override.UpdatedBy = &adminID
+	}
+	if err := h.limits.SaveOverride(c.Request.Context(), override); err != nil {
+		response.InternalServerError(c, "Failed to update customer limits")
+		return
+	}
+
+	status, err := h.status(c, customerID)
+	if err != nil {
+		response.InternalServerError(c, "Failed to retrieve customer limits")
+		return
+	}
+
+	response.OK(c, "Customer limits updated", status)
+}
+
+// ResetLimits removes a customer's override so the defaults apply again
+// DELETE /api/v1/admin/customers/:id/limits
+func (h *AdminCustomerLimitHandler) ResetLimits(c *gin.Context) {
+	customerID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "Invalid customer ID", nil)
+		return
+	}
+
+	if err := h.limits.DeleteOverride(c.Request.Context(), customerID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			response.Fail(c, response.CodeLimitOverrideNotFound, "Customer has no limit override")
+			return
+		}
+		response.InternalServerError(c, "Failed to reset customer limits")
+		return
+	}
+
+	response.OK(c, "Customer limits reset to the defaults", nil)
+}
+
+// status looks up the caps, override and usage of a customer
+func (h *AdminCustomerLimitHandler) status(c *gin.Context, customerID uuid.UUID) (*CustomerLimitStatus, error) {
+	ctx := c.Request.Context()
+	override, err := h.limits.GetOverride(ctx, customerID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	usage, err := h.limits.Usage(ctx, customerID)
+	if err != nil {
+		return nil, err
+	}
+	return &CustomerLimitStatus{
+		Limits:   override.Apply(h.limits.Defaults()),
+		Defaults: h.limits.Defaults(),
+		Usage:    usage,
+		Override: override,
+	}, nil
+}
@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/authctx"
+	"github.com/Ecom-micro-template/service-customer/internal/response"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/persistence"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// WithTags enables free-form customer tags
+func (h *AdminCustomerHandler) WithTags(repo *persistence.CustomerTagRepository) *AdminCustomerHandler {
+	h.tags = repo
+	return h
+}
+
+// CustomerTagsRequest is the body of POST /admin/customers/:id/tags
+type CustomerTagsRequest struct {
+	Tags []string `json:"tags" binding:"required,min=1"`
+}
+
+// GetCustomerTags handles GET /admin/customers/:id/tags
+func (h *AdminCustomerHandler) GetCustomerTags(c *gin.Context) {
+	customerID, ok := h.taggableCustomer(c)
+	if !ok {
+		return
+	}
+
+	tags, err := h.tags.ListForCustomer(c.Request.Context(), customerID)
+	if err != nil {
+		h.logger.Error("Failed to list customer tags", zap.Error(err))
+		response.InternalServerError(c, "Failed to retrieve tags")
+		return
+	}
+
+	response.OK(c, "Tags retrieved", tags)
+}
+
+// AddCustomerTags handles POST /admin/customers/:id/tags
+// Tags are created on first use; ones the customer already has are ignored.
+func (h *AdminCustomerHandler) AddCustomerTags(c *gin.Context) {
+	customerID, ok := h.taggableCustomer(c)
+	if !ok {
+		return
+	}
+
+	var req CustomerTagsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Invalid(c, err)
+		return
+	}
+	names, err := domain.NormalizeTagNames(req.Tags)
+	if err != nil {
+		response.Fail(c, response.CodeInvalidTagName, err.Error())
+		return
+	}
+
+	ctx := c.Request.Context()
+	assignedBy := authctx.UserID(c)
+	if err := h.tags.AddToCustomer(ctx, customerID, names, &assignedBy); err != nil {
+		if code, ok := response.CodeOf(err); ok {
+			response.Fail(c, code, err.Error())
+			return
+		}
+		h.logger.Error("Failed to tag customer", zap.Error(err))
+		response.InternalServerError(c, "Failed to add tags")
+		return
+	}
+
+	tags, err := h.tags.ListForCustomer(ctx, customerID)
+	if err != nil {
+		h.logger.Error("Failed to list customer tags", zap.Error(err))
+		response.InternalServerError(c, "Failed to retrieve tags")
+		return
+	}
+
+	response.OK(c, "Tags added", tags)
+}
+
+// RemoveCustomerTag handles DELETE /admin/customers/:id/tags/:tag
+func (h *AdminCustomerHandler) RemoveCustomerTag(c *gin.Context) {
+	customerID, ok := h.taggableCustomer(c)
+	if !ok {
+		return
+	}
+	name, err := domain.NormalizeTagName(c.Param("tag"))
+	if err != nil {
+		response.BadRequest(c, err.Error(), nil)
+		return
+	}
+
+	if err := h.tags.RemoveFromCustomer(c.Request.Context(), customerID, name); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			response.Fail(c, response.CodeCustomerTagNotFound, "Customer does not have this tag")
+			return
+		}
+		h.logger.Error("Failed to untag customer", zap.Error(err))
+		response.InternalServerError(c, "Failed to remove tag")
+		return
+	}
+
+	response.Deleted(c, "Tag removed successfully")
+}
+
+// SuggestTags handles GET /admin/customers/tags?q=whole&limit=10
+// It autocompletes tag names, most used first.
+func (h *AdminCustomerHandler) SuggestTags(c *gin.Context) {
+	if h.tags == nil {
+		response.ServiceUnavailable(c, "Customer tags are not enabled")
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if limit < 1 || limit > 50 {
+		limit = 10
+	}
+	prefix := strings.Join(strings.Fields(strings.ToLower(c.Query("q"))), "-")
+
+	suggestions, err := h.tags.Suggest(c.Request.Context(), prefix, limit)
+	if err != nil {
+		h.logger.Error("Failed to suggest tags", zap.Error(err))
+		response.InternalServerError(c, "Failed to retrieve tags")
+		return
+	}
+
+	response.OK(c, "Tags retrieved", suggestions)
+}
+
+// taggableCustomer parses the customer ID and checks tags are enabled and the
+// customer exists, writing the error response if not; the region is checked
+// by middleware.CustomerInRegion
+func (h *AdminCustomerHandler) taggableCustomer(c *gin.Context) (uuid.UUID, bool) {
+	if h.tags == nil {
+		response.ServiceUnavailable(c, "Customer tags are not enabled")
+		return uuid.Nil, false
+	}
+	customerID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "Invalid customer ID", nil)
+		return uuid.Nil, false
+	}
+	if _, err := h.customerRepo.GetByID(c.Request.Context(), customerID); err != nil {
+		response.Fail(c, response.CodeCustomerNotFound, "Customer not found")
+		return uuid.Nil, false
+	}
+	return customerID, true
+}
+
+// loadCustomerTags fills in the tag names of listed customers
+func (h *AdminCustomerHandler) loadCustomerTags(ctx context.Context, customers []domain.Customer) error {
+	if h.tags == nil || len(customers) == 0 {
+		return nil
+	}
+
+	ids := make([]uuid.UUID, len(customers))
+	for i := range customers {
+		ids[i] = customers[i].ID
+	}
+	byCustomer, err := h.tags.NamesByCustomers(ctx, ids)
+	if err != nil {
+		return err
+	}
+	for i := range customers {
+		customers[i].Tags = byCustomer[customers[i].ID]
+	}
+	return nil
+}
+
+// parseTagFilter reads the comma-separated ?tags= filter
+func parseTagFilter(raw string) ([]string, error) {
+	if raw == "" {
+		return nil, nil
+	}
+	return domain.NormalizeTagNames(strings.Split(raw, ","))
+}
@@ -0,0 +1,202 @@
+package handlers
+
+import (
+	"errors"
+	"net/url"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/authctx"
+	"github.com/Ecom-micro-template/service-customer/internal/response"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/persistence"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// WithViews enables saved customer list views
+func (h *AdminCustomerHandler) WithViews(repo *persistence.CustomerViewRepository) *AdminCustomerHandler {
+	h.views = repo
+	return h
+}
+
+// GetCustomerViews handles GET /admin/customers/views
+// Returns the admin's own views first, then views shared by other admins.
+func (h *AdminCustomerHandler) GetCustomerViews(c *gin.Context) {
+	views, err := h.views.ListVisible(c.Request.Context(), authctx.UserID(c))
+	if err != nil {
+		h.logger.Error("Failed to list customer views", zap.Error(err))
+		response.InternalServerError(c, "Failed to retrieve views")
+		return
+	}
+
+	response.OK(c, "Views retrieved", views)
+}
+
+// GetCustomerView handles GET /admin/customers/views/:viewId
+func (h *AdminCustomerHandler) GetCustomerView(c *gin.Context) {
+	view, ok := h.visibleView(c)
+	if !ok {
+		return
+	}
+
+	response.OK(c, "View retrieved", view)
+}
+
+// CreateCustomerView handles POST /admin/customers/views
+func (h *AdminCustomerHandler) CreateCustomerView(c *gin.Context) {
+	var req domain.SaveCustomerViewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Invalid(c, err)
+		return
+	}
+
+	ownerID := authctx.UserID(c)
+	if ownerID == uuid.Nil {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+	view := &domain.CustomerListView{OwnerID: ownerID}
+	if err := req.Apply(view); err != nil {
+		response.BadRequest(c, err.Error(), nil)
+		return
+	}
+
+	if err := h.views.Create(c.Request.Context(), view); err != nil {
+		if code, ok := response.CodeOf(err); ok {
+			response.Fail(c, code, err.Error())
+			return
+		}
+		h.logger.Error("Failed to create customer view", zap.Error(err))
+		response.InternalServerError(c, "Failed to save view")
+		return
+	}
+
+	response.Created(c, "View saved", view)
+}
+
+// UpdateCustomerView handles PUT /admin/customers/views/:viewId
+// Replaces the view's name, filters, sort, columns and sharing.
+func (h *AdminCustomerHandler) UpdateCustomerView(c *gin.Context) {
+	view, ok := h.editableView(c)
+	if !ok {
+		return
+	}
+
+	var req domain.SaveCustomerViewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Invalid(c, err)
+		return
+	}
+	if err := req.Apply(view); err != nil {
+		response.BadRequest(c, err.Error(), nil)
+		return
+	}
+
+	if err := h.views.Update(c.Request.Context(), view); err != nil {
+		if code, ok := response.CodeOf(err); ok {
+			response.Fail(c, code, err.Error())
+			return
+		}
+		h.logger.Error("Failed to update customer view", zap.Error(err))
+		response.InternalServerError(c, "Failed to save view")
+		return
+	}
+
+	response.Updated(c, "View updated", view)
+}
+
+// DeleteCustomerView handles DELETE /admin/customers/views/:viewId
+func (h *AdminCustomerHandler) DeleteCustomerView(c *gin.Context) {
+	view, ok := h.editableView(c)
+	if !ok {
+		return
+	}
+
+	if err := h.views.Delete(c.Request.Context(), view.ID); err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		h.logger.Error("Failed to delete customer view", zap.Error(err))
+		response.InternalServerError(c, "Failed to delete view")
+		return
+	}
+
+	response.Deleted(c, "View deleted")
+}
+
+// visibleView loads the :viewId view if the admin owns it or it is shared,
+// writing a 404 otherwise
+func (h *AdminCustomerHandler) visibleView(c *gin.Context) (*domain.CustomerListView, bool) {
+	viewID, err := uuid.Parse(c.Param("viewId"))
+	if err != nil {
+		response.BadRequest(c, "Invalid view ID", nil)
+		return nil, false
+	}
+
+	view, err := h.views.GetVisible(c.Request.Context(), viewID, authctx.UserID(c))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			response.Fail(c, response.CodeCustomerViewNotFound, "View not found")
+			return nil, false
+		}
+		h.logger.Error("Failed to get customer view", zap.Error(err))
+		response.InternalServerError(c, "Failed to retrieve view")
+		return nil, false
+	}
+	return view, true
+}
+
+// editableView is visibleView restricted to the view's owner and admins
+func (h *AdminCustomerHandler) editableView(c *gin.Context) (*domain.CustomerListView, bool) {
+	view, ok := h.visibleView(c)
+	if !ok {
+		return nil, false
+	}
+	if view.OwnerID != authctx.UserID(c) && !authctx.Get(c).HasPermission(domain.PermissionNotesModerate) {
+		response.Forbidden(c, "Only the view's owner or an admin can change it")
+		return nil, false
+	}
+	return view, true
+}
+
+// customerListQuery returns the customer list query parameters. With
+// ?view=<id> the saved view's filters and sort are applied first and any
+// parameter given explicitly overrides them. An error is written and false
+// returned if the view can't be used.
+func (h *AdminCustomerHandler) customerListQuery(c *gin.Context) (url.Values, bool) {
+	query := c.Request.URL.Query()
+	viewParam := query.Get("view")
+	if viewParam == "" || h.views == nil {
+		return query, true
+	}
+
+	viewID, err := uuid.Parse(viewParam)
+	if err != nil {
+		response.BadRequest(c, "Invalid view ID", nil)
+		return nil, false
+	}
+	view, err := h.views.GetVisible(c.Request.Context(), viewID, authctx.UserID(c))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			response.Fail(c, response.CodeCustomerViewNotFound, "View not found")
+			return nil, false
+		}
+		h.logger.Error("Failed to get customer view", zap.Error(err))
+		response.InternalServerError(c, "Failed to retrieve view")
+		return nil, false
+	}
+
+	merged := view.Query()
+	for key, values := range query {
+		if key != "view" {
+			merged[key] = values
+		}
+	}
+	return merged, true
+}
+
+// queryDefault returns the query parameter, or def if it is missing or empty
+func queryDefault(query url.Values, key, def string) string {
+	if v := query.Get(key); v != "" {
+		return v
+	}
+	return def
+}
@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/authctx"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/persistence"
+	"github.com/Ecom-micro-template/service-customer/internal/response"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// AdminDuplicateHandler serves the review queue of probable duplicate
+// customers found by the duplicate_detection job. Duplicates are merged with
+// the merge endpoints, which resolve the queued pair.
+type AdminDuplicateHandler struct {
+	repo   *persistence.DuplicateRepository
+	logger *zap.Logger
+}
+
+// NewAdminDuplicateHandler creates a new duplicate review handler
+func NewAdminDuplicateHandler(db *gorm.DB, logger *zap.Logger) *AdminDuplicateHandler {
+	return &AdminDuplicateHandler{
+		repo:   persistence.NewDuplicateRepository(db),
+		logger: logger,
+	}
+}
+
+// ListDuplicates handles GET /admin/customers/duplicates
+// Query: status (pending, merged or dismissed, default pending), page, limit
+func (h *AdminDuplicateHandler) ListDuplicates(c *gin.Context) {
+	status := c.DefaultQuery("status", domain.DuplicateStatusPending)
+	switch status {
+	case domain.DuplicateStatusPending, domain.DuplicateStatusMerged, domain.DuplicateStatusDismissed:
+	default:
+		response.BadRequest(c, "status must be pending, merged or dismissed", nil)
+		return
+	}
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	candidates, total, err := h.repo.List(c.Request.Context(), status, page, limit)
+	if err != nil {
+		h.logger.Error("Failed to list duplicate customers", zap.Error(err))
+		response.InternalServerError(c, "Failed to retrieve duplicate customers")
+		return
+	}
+
+	response.Paginated(c, candidates, page, limit, total)
+}
+
+// DismissDuplicate handles POST /admin/customers/duplicates/:candidateId/dismiss
+// The pair is kept as dismissed so the job doesn't queue it again.
+func (h *AdminDuplicateHandler) DismissDuplicate(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("candidateId"))
+	if err != nil {
+		response.BadRequest(c, "Invalid duplicate candidate ID", nil)
+		return
+	}
+
+	candidate, err := h.repo.Dismiss(c.Request.Context(), id, authctx.UserID(c), time.Now())
+	if err != nil {
+		response.FromError(c, err, response.CodeDuplicateNotFound, "Failed to dismiss duplicate candidate")
+		return
+	}
+
+	response.OK(c, "Duplicate candidate dismissed", candidate)
+}
@@ -0,0 +1,187 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/authctx"
+	"github.com/Ecom-micro-template/service-customer/internal/response"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/persistence"
+	"github.com/Ecom-micro-template/service-customer/internal/middleware"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// AdminImpersonationHandler issues and audits "view as customer" tokens
+type AdminImpersonationHandler struct {
+	repo      *persistence.ImpersonationRepository
+	customers persistence.CustomerRepository
+	jwtSecret string
+	logger    *zap.Logger
+}
+
+// NewAdminImpersonationHandler creates a new admin impersonation handler.
+// Tokens are signed with the same secret customer tokens are verified with.
+func NewAdminImpersonationHandler(db *gorm.DB, customers persistence.CustomerRepository, jwtSecret string, logger *zap.Logger) *AdminImpersonationHandler {
+	return &AdminImpersonationHandler{
+		repo:      persistence.NewImpersonationRepository(db),
+		customers: customers,
+		jwtSecret: jwtSecret,
+		logger:    logger,
+	}
+}
+
+// ImpersonationStart is the payload of a started impersonation; the token
+// acts as the customer until the session expires or is revoked
+type ImpersonationStart struct {
+	Token   string                       `json:"token"`
+	Session *domain.ImpersonationSession `json:"session"`
+}
+
+// StartImpersonation handles POST /admin/customers/:id/impersonate
+// It returns a short-lived token for calling customer endpoints as the
+// customer. Tokens are read-only unless write access is requested.
+func (h *AdminImpersonationHandler) StartImpersonation(c *gin.Context) {
+	customerID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "Invalid customer ID", nil)
+		return
+	}
+
+	var req domain.StartImpersonationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Invalid(c, err)
+		return
+	}
+
+	adminID := authctx.UserID(c)
+	if adminID == uuid.Nil {
+		response.BadRequest(c, "Admin user ID not found in token", nil)
+		return
+	}
+
+	if _, err := h.customers.GetByID(c.Request.Context(), customerID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			response.Fail(c, response.CodeCustomerNotFound, "Customer not found")
+			return
+		}
+		h.logger.Error("Failed to load customer for impersonation", zap.Error(err))
+		response.InternalServerError(c, "Failed to start impersonation")
+		return
+	}
+
+	now := time.Now()
+	session := &domain.ImpersonationSession{
+		AdminID:    adminID,
+		CustomerID: customerID,
+		Reason:     req.Reason,
+		Scope:      req.Scope(),
+		ExpiresAt:  now.Add(req.TTL()),
+		CreatedAt:  now,
+	}
+	if err := h.repo.Create(c.Request.Context(), session); err != nil {
+		h.logger.Error("Failed to create impersonation session", zap.Error(err))
+		response.InternalServerError(c, "Failed to start impersonation")
+		return
+	}
+
+	token, err := middleware.IssueImpersonationToken(h.jwtSecret, session)
+	if err != nil {
+		h.logger.Error("Failed to sign impersonation token", zap.Error(err))
+		response.InternalServerError(c, "Failed to start impersonation")
+		return
+	}
+
+	h.logger.Info("Admin started customer impersonation",
+		zap.String("session_id", session.ID.String()),
+		zap.String("admin_id", adminID.String()),
+		zap.String("customer_id", customerID.String()),
+		zap.String("scope", session.Scope),
+		zap.String("reason", session.Reason),
+		zap.Time("expires_at", session.ExpiresAt))
+
+	response.Created(c, "Impersonation started", ImpersonationStart{
+		Token:   token,
+		Session: session,
+	})
+}
+
+// ListImpersonations handles GET /admin/customers/:id/impersonations
+func (h *AdminImpersonationHandler) ListImpersonations(c *gin.Context) {
+	customerID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "Invalid customer ID", nil)
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	sessions, total, err := h.repo.ListByCustomer(c.Request.Context(), customerID, page, limit)
+	if err != nil {
+		h.logger.Error("Failed to list impersonation sessions", zap.Error(err))
+		response.InternalServerError(c, "Failed to list impersonation sessions")
+		return
+	}
+
+	response.Paginated(c, sessions, page, limit, total)
+}
+
+// ListImpersonationRequests handles GET /admin/impersonations/:sessionId/requests
+// It returns the audit log of the calls made with the session's token.
+func (h *AdminImpersonationHandler) ListImpersonationRequests(c *gin.Context) {
+	sessionID, err := uuid.Parse(c.Param("sessionId"))
+	if err != nil {
+		response.BadRequest(c, "Invalid session ID", nil)
+		return
+	}
+
+	requests, err := h.repo.ListRequests(c.Request.Context(), sessionID)
+	if err != nil {
+		h.logger.Error("Failed to list impersonated requests", zap.Error(err))
+		response.InternalServerError(c, "Failed to list impersonated requests")
+		return
+	}
+
+	response.OK(c, "Impersonated requests retrieved", requests)
+}
+
+// RevokeImpersonation handles DELETE /admin/impersonations/:sessionId
+// The session's token stops working immediately.
+func (h *AdminImpersonationHandler) RevokeImpersonation(c *gin.Context) {
+	sessionID, err := uuid.Parse(c.Param("sessionId"))
+	if err != nil {
+		response.BadRequest(c, "Invalid session ID", nil)
+		return
+	}
+
+	session, err := h.repo.Revoke(c.Request.Context(), sessionID, authctx.UserID(c))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			response.Fail(c, response.CodeImpersonationNotFound, "Impersonation session not found")
+			return
+		}
+		if errors.Is(err, domain.ErrImpersonationInactive) {
+			response.Fail(c, response.CodeImpersonationInactive, "Impersonation session already expired or revoked")
+			return
+		}
+		h.logger.Error("Failed to revoke impersonation session", zap.Error(err))
+		response.InternalServerError(c, "Failed to revoke impersonation session")
+		return
+	}
+
+	h.logger.Info("Admin revoked customer impersonation",
+		zap.String("session_id", session.ID.String()),
+		zap.String("revoked_by", authctx.UserID(c).String()))
+	response.OK(c, "Impersonation revoked", session)
+}
@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/authctx"
+	"github.com/Ecom-micro-template/service-customer/internal/response"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/persistence"
+	"github.com/Ecom-micro-template/service-customer/internal/middleware"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// AdminMergeHandler lets admins preview and run customer merges
+type AdminMergeHandler struct {
+	repo      *persistence.AccountMergeRepository
+	customers persistence.CustomerRepository
+	logger    *zap.Logger
+}
+
+// NewAdminMergeHandler creates a new admin merge handler
+func NewAdminMergeHandler(db *gorm.DB, customers persistence.CustomerRepository, logger *zap.Logger) *AdminMergeHandler {
+	return &AdminMergeHandler{
+		repo:      persistence.NewAccountMergeRepository(db),
+		customers: customers,
+		logger:    logger,
+	}
+}
+
+// PreviewMerge handles POST /admin/customers/:id/merge/preview
+// It lists the fields where the two customers disagree and which side would win.
+func (h *AdminMergeHandler) PreviewMerge(c *gin.Context) {
+	primaryID, req, ok := h.bindMergeRequest(c)
+	if !ok {
+		return
+	}
+
+	preview, err := h.repo.Preview(c.Request.Context(), primaryID, req.SecondaryID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			response.Fail(c, response.CodeCustomerNotFound, "Customer not found")
+			return
+		}
+		h.logger.Error("Failed to preview customer merge", zap.Error(err))
+		response.InternalServerError(c, "Failed to preview customer merge")
+		return
+	}
+
+	// Show the outcome of any winners already chosen in the request
+	for i, conflict := range preview.Conflicts {
+		if winner, ok := req.Winners[conflict.Field]; ok {
+			preview.Conflicts[i].Winner = winner
+		}
+	}
+
+	response.OK(c, "Merge preview generated", preview)
+}
+
+// MergeCustomer handles POST /admin/customers/:id/merge
+// The secondary (duplicate) customer's data and order totals are moved to the
+// customer in the path, and the duplicate is soft-deleted.
+func (h *AdminMergeHandler) MergeCustomer(c *gin.Context) {
+	primaryID, req, ok := h.bindMergeRequest(c)
+	if !ok {
+		return
+	}
+
+	// Both customers must exist before anything is moved
+	if _, err := h.repo.Preview(c.Request.Context(), primaryID, req.SecondaryID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			response.Fail(c, response.CodeCustomerNotFound, "Customer not found")
+			return
+		}
+		h.logger.Error("Failed to load customers for merge", zap.Error(err))
+		response.InternalServerError(c, "Failed to merge customers")
+		return
+	}
+
+	record, merged, err := h.repo.MergeDuplicate(c.Request.Context(), primaryID, req.SecondaryID, req.Winners,
+		authctx.UserID(c))
+	if err != nil {
+		h.logger.Error("Failed to merge customers",
+			zap.String("primary_id", primaryID.String()),
+			zap.String("secondary_id", req.SecondaryID.String()),
+			zap.Error(err))
+		response.InternalServerError(c, "Failed to merge customers")
+		return
+	}
+	if !merged {
+		response.Fail(c, response.CodeCustomerAlreadyMerged, "Secondary customer has already been merged")
+		return
+	}
+
+	h.logger.Info("Customers merged by admin",
+		zap.String("primary_id", primaryID.String()),
+		zap.String("secondary_id", req.SecondaryID.String()),
+		zap.Any("winners", req.Winners))
+	response.OK(c, "Customers merged successfully", record)
+}
+
+// bindMergeRequest parses the path ID and request body shared by the merge endpoints
+func (h *AdminMergeHandler) bindMergeRequest(c *gin.Context) (uuid.UUID, *domain.MergeCustomerRequest, bool) {
+	primaryID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "Invalid customer ID", nil)
+		return uuid.Nil, nil, false
+	}
+
+	var req domain.MergeCustomerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Invalid(c, err)
+		return uuid.Nil, nil, false
+	}
+	if req.SecondaryID == primaryID {
+		response.BadRequest(c, "A customer cannot be merged into itself", nil)
+		return uuid.Nil, nil, false
+	}
+	if err := domain.ValidateMergeWinners(req.Winners); err != nil {
+		response.BadRequest(c, "Invalid winners", err.Error())
+		return uuid.Nil, nil, false
+	}
+	// The path customer is region-checked by middleware; the duplicate must
+	// be in the agent's regions too
+	if region := middleware.GetRegionScope(c); region != nil {
+		ok, err := h.customers.InRegion(c.Request.Context(), req.SecondaryID, region)
+		if err != nil || !ok {
+			response.Fail(c, response.CodeCustomerNotFound, "Customer not found")
+			return uuid.Nil, nil, false
+		}
+	}
+
+	return primaryID, &req, true
+}
@@ -0,0 +1,236 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/authctx"
+	"github.com/Ecom-micro-template/service-customer/internal/response"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/persistence"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// defaultAttachmentURLTTL is how long attachment download links work when
+// the policy doesn't say
+const defaultAttachmentURLTTL = 15 * time.Minute
+
+// NoteFileStore keeps the files attached to customer notes
+type NoteFileStore interface {
+	Put(ctx context.Context, key, contentType string, body io.Reader, size int64) error
+	Delete(ctx context.Context, key string) error
+	SignedURL(key string, ttl time.Duration) (string, error)
+}
+
+// WithNoteAttachments enables file attachments on customer notes
+func (h *AdminCustomerHandler) WithNoteAttachments(repo *persistence.NoteAttachmentRepository, files NoteFileStore, policy domain.NoteAttachmentPolicy) *AdminCustomerHandler {
+	h.attachments = repo
+	h.files = files
+	h.attachmentPolicy = policy
+	if h.attachmentPolicy.URLTTL <= 0 {
+		h.attachmentPolicy.URLTTL = defaultAttachmentURLTTL
+	}
+	return h
+}
+
+// UploadNoteAttachment handles POST /admin/customers/:id/notes/:noteId/attachments
+// The file is sent as the "file" form field. Its type is detected from its
+// content, not the client's Content-Type. Only the note's author or an admin
+// may attach files.
+func (h *AdminCustomerHandler) UploadNoteAttachment(c *gin.Context) {
+	if h.attachments == nil {
+		response.ServiceUnavailable(c, "Note attachments are not enabled")
+		return
+	}
+	customerID, noteID, ok := parseNoteParams(c)
+	if !ok {
+		return
+	}
+	note, ok := h.editableNote(c, customerID, noteID)
+	if !ok {
+		return
+	}
+
+	if h.attachmentPolicy.MaxSize > 0 {
+		// Room for the multipart envelope around the file
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.attachmentPolicy.MaxSize+1<<20)
+	}
+	header, err := c.FormFile("file")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			response.Fail(c, response.CodeNoteAttachmentTooLarge, domain.ErrNoteAttachmentTooLarge.Error())
+			return
+		}
+		response.BadRequest(c, "A file is required in the \"file\" form field", nil)
+		return
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		response.BadRequest(c, "Failed to read the uploaded file", nil)
+		return
+	}
+	defer file.Close()
+
+	sniff := make([]byte, 512)
+	n, _ := io.ReadFull(file, sniff)
+	contentType := http.DetectContentType(sniff[:n])
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		response.InternalServerError(c, "Failed to read the uploaded file")
+		return
+	}
+
+	switch err := h.attachmentPolicy.Check(header.Size, contentType); {
+	case errors.Is(err, domain.ErrNoteAttachmentTooLarge):
+		response.Fail(c, response.CodeNoteAttachmentTooLarge, err.Error())
+		return
+	case errors.Is(err, domain.ErrNoteAttachmentType):
+		response.Fail(c, response.CodeNoteAttachmentType, err.Error()+": "+contentType)
+		return
+	}
+
+	ctx := c.Request.Context()
+	count, err := h.attachments.CountByNote(ctx, note.ID)
+	if err != nil {
+		h.logger.Error("Failed to count note attachments", zap.Error(err))
+		response.InternalServerError(c, "Failed to attach file")
+		return
+	}
+	if count >= domain.MaxNoteAttachments {
+		response.Fail(c, response.CodeNoteAttachmentLimit, domain.ErrNoteAttachmentLimit.Error())
+		return
+	}
+
+	uploadedBy := authctx.UserID(c)
+	attachment := &domain.NoteAttachment{
+		ID:          uuid.New(),
+		NoteID:      note.ID,
+		CustomerID:  note.CustomerID,
+		FileName:    domain.CleanAttachmentName(header.Filename),
+		ContentType: contentType,
+		Size:        header.Size,
+		UploadedBy:  &uploadedBy,
+	}
+	attachment.StorageKey = domain.NoteAttachmentKey(attachment)
+
+	if err := h.files.Put(ctx, attachment.StorageKey, contentType, file, header.Size); err != nil {
+		h.logger.Error("Failed to store note attachment", zap.String("key", attachment.StorageKey), zap.Error(err))
+		response.InternalServerError(c, "Failed to store file")
+		return
+	}
+	if err := h.attachments.Create(ctx, attachment); err != nil {
+		h.removeAttachmentFiles(ctx, *attachment)
+		if code, ok := response.CodeOf(err); ok {
+			response.Fail(c, code, err.Error())
+			return
+		}
+		h.logger.Error("Failed to save note attachment", zap.Error(err))
+		response.InternalServerError(c, "Failed to attach file")
+		return
+	}
+
+	h.signAttachment(attachment)
+	response.Created(c, "File attached", attachment)
+}
+
+// DeleteNoteAttachment handles DELETE /admin/customers/:id/notes/:noteId/attachments/:attachmentId
+// Only the note's author or an admin may remove files.
+func (h *AdminCustomerHandler) DeleteNoteAttachment(c *gin.Context) {
+	if h.attachments == nil {
+		response.ServiceUnavailable(c, "Note attachments are not enabled")
+		return
+	}
+	customerID, noteID, ok := parseNoteParams(c)
+	if !ok {
+		return
+	}
+	attachmentID, err := uuid.Parse(c.Param("attachmentId"))
+	if err != nil {
+		response.BadRequest(c, "Invalid attachment ID", nil)
+		return
+	}
+	if _, ok := h.editableNote(c, customerID, noteID); !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	attachment, err := h.attachments.Get(ctx, noteID, attachmentID)
+	if err == nil {
+		err = h.attachments.Delete(ctx, attachment.ID)
+	}
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			response.Fail(c, response.CodeNoteAttachmentNotFound, "Attachment not found")
+			return
+		}
+		h.logger.Error("Failed to delete note attachment", zap.Error(err))
+		response.InternalServerError(c, "Failed to delete attachment")
+		return
+	}
+	h.removeAttachmentFiles(ctx, *attachment)
+
+	response.Deleted(c, "Attachment deleted successfully")
+}
+
+// loadNoteAttachments fills in the attachments of notes with fresh download links
+func (h *AdminCustomerHandler) loadNoteAttachments(ctx context.Context, notes []domain.CustomerNote) error {
+	if h.attachments == nil || len(notes) == 0 {
+		return nil
+	}
+
+	noteIDs := make([]uuid.UUID, len(notes))
+	for i := range notes {
+		noteIDs[i] = notes[i].ID
+	}
+	byNote, err := h.attachments.ListByNotes(ctx, noteIDs)
+	if err != nil {
+		return err
+	}
+	for i := range notes {
+		notes[i].Attachments = byNote[notes[i].ID]
+		for j := range notes[i].Attachments {
+			h.signAttachment(&notes[i].Attachments[j])
+		}
+	}
+	return nil
+}
+
+// deleteNoteAttachments removes the attachments of a deleted note. The note
+// is already gone, so failures are only logged.
+func (h *AdminCustomerHandler) deleteNoteAttachments(ctx context.Context, noteID uuid.UUID) {
+	if h.attachments == nil {
+		return
+	}
+	attachments, err := h.attachments.DeleteByNote(ctx, noteID)
+	if err != nil {
+		h.logger.Warn("Failed to delete attachments of deleted note", zap.String("note_id", noteID.String()), zap.Error(err))
+		return
+	}
+	h.removeAttachmentFiles(ctx, attachments...)
+}
+
+// removeAttachmentFiles deletes attachment files from the file store. An
+// orphaned file is harmless, so failures are only logged.
+func (h *AdminCustomerHandler) removeAttachmentFiles(ctx context.Context, attachments ...domain.NoteAttachment) {
+	for _, attachment := range attachments {
+		if err := h.files.Delete(ctx, attachment.StorageKey); err != nil {
+			h.logger.Warn("Failed to delete note attachment file", zap.String("key", attachment.StorageKey), zap.Error(err))
+		}
+	}
+}
+
+func (h *AdminCustomerHandler) signAttachment(attachment *domain.NoteAttachment) {
+	link, err := h.files.SignedURL(attachment.StorageKey, h.attachmentPolicy.URLTTL)
+	if err != nil {
+		h.logger.Warn("Failed to sign note attachment URL", zap.String("key", attachment.StorageKey), zap.Error(err))
+		return
+	}
+	attachment.DownloadURL = link
+}
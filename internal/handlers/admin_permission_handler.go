@@ -0,0 +1,32 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/Ecom-micro-template/service-customer/internal/authctx"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/Ecom-micro-template/service-customer/internal/middleware"
+	"github.com/Ecom-micro-template/service-customer/internal/response"
+)
+
+// AdminPermissionHandler exposes the admin permission policy to the admin UI
+type AdminPermissionHandler struct {
+	policy *middleware.PermissionPolicy
+}
+
+// NewAdminPermissionHandler creates a new admin permission handler
+func NewAdminPermissionHandler(policy *middleware.PermissionPolicy) *AdminPermissionHandler {
+	return &AdminPermissionHandler{policy: policy}
+}
+
+// GetPermissions returns the admin's own permissions, the permissions of
+// every role and the permission every route requires
+// GET /api/v1/admin/permissions
+func (h *AdminPermissionHandler) GetPermissions(c *gin.Context) {
+	role := authctx.Role(c)
+	response.OK(c, "", domain.PermissionPolicyView{
+		Role:        role,
+		Permissions: h.policy.Permissions(role),
+		Roles:       h.policy.Roles(),
+		Routes:      h.policy.Routes(),
+	})
+}
@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/authctx"
+	"github.com/Ecom-micro-template/service-customer/internal/response"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/persistence"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// AdminRegionHandler manages the sales regions assigned to admin users
+type AdminRegionHandler struct {
+	repo   *persistence.AdminRegionRepository
+	logger *zap.Logger
+}
+
+// NewAdminRegionHandler creates a new admin region handler
+func NewAdminRegionHandler(db *gorm.DB, logger *zap.Logger) *AdminRegionHandler {
+	return &AdminRegionHandler{
+		repo:   persistence.NewAdminRegionRepository(db),
+		logger: logger,
+	}
+}
+
+// RegionAssignment is the payload of an admin's region assignment
+type RegionAssignment struct {
+	AdminUserID uuid.UUID `json:"admin_user_id"`
+	States      []string  `json:"states"`
+}
+
+// GetAssignment handles GET /admin/region-assignments/:adminId
+func (h *AdminRegionHandler) GetAssignment(c *gin.Context) {
+	adminID, err := uuid.Parse(c.Param("adminId"))
+	if err != nil {
+		response.BadRequest(c, "Invalid admin user ID", nil)
+		return
+	}
+
+	states, err := h.repo.GetStates(c.Request.Context(), adminID)
+	if err != nil {
+		h.logger.Error("Failed to get region assignment", zap.Error(err))
+		response.InternalServerError(c, "Failed to retrieve region assignment")
+		return
+	}
+
+	response.OK(c, "Region assignment retrieved", RegionAssignment{
+		AdminUserID: adminID,
+		States:      states,
+	})
+}
+
+// SetAssignment handles PUT /admin/region-assignments/:adminId
+func (h *AdminRegionHandler) SetAssignment(c *gin.Context) {
+	adminID, err := uuid.Parse(c.Param("adminId"))
+	if err != nil {
+		response.BadRequest(c, "Invalid admin user ID", nil)
+		return
+	}
+
+	var req domain.SetRegionAssignmentInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Invalid(c, err)
+		return
+	}
+
+	states, err := h.repo.SetStates(c.Request.Context(), adminID, req.States, authctx.UserID(c))
+	if err != nil {
+		h.logger.Error("Failed to set region assignment", zap.Error(err))
+		response.InternalServerError(c, "Failed to update region assignment")
+		return
+	}
+
+	response.Updated(c, "Region assignment updated", RegionAssignment{
+		AdminUserID: adminID,
+		States:      states,
+	})
+}
@@ -0,0 +1,238 @@
+package handlers
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/authctx"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/persistence"
+	"github.com/Ecom-micro-template/service-customer/internal/middleware"
+	"github.com/Ecom-micro-template/service-customer/internal/response"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// ScheduledExportRunner starts a run of a scheduled export outside its schedule
+type ScheduledExportRunner interface {
+	RunNow(ctx context.Context, export *domain.ScheduledExport) (*domain.ScheduledExportRun, error)
+}
+
+// AdminScheduledExportHandler manages recurring customer exports and their
+// run history
+type AdminScheduledExportHandler struct {
+	repo   *persistence.ScheduledExportRepository
+	runner ScheduledExportRunner
+	logger *zap.Logger
+}
+
+// NewAdminScheduledExportHandler creates a new scheduled export handler
+func NewAdminScheduledExportHandler(db *gorm.DB, runner ScheduledExportRunner, logger *zap.Logger) *AdminScheduledExportHandler {
+	return &AdminScheduledExportHandler{
+		repo:   persistence.NewScheduledExportRepository(db),
+		runner: runner,
+		logger: logger,
+	}
+}
+
+// ListExports handles GET /admin/scheduled-exports
+// Query: page, limit, sort_by (see domain.ScheduledExportSort), sort_order
+func (h *AdminScheduledExportHandler) ListExports(c *gin.Context) {
+	sort, ok := listSort(c, domain.ScheduledExportSort, c.Query("sort_by"), c.Query("sort_order"))
+	if !ok {
+		return
+	}
+	page, limit := scheduledExportPagination(c)
+	exports, total, err := h.repo.List(c.Request.Context(), sort, page, limit)
+	if err != nil {
+		h.logger.Error("Failed to list scheduled exports", zap.Error(err))
+		response.InternalServerError(c, "Failed to retrieve scheduled exports")
+		return
+	}
+
+	response.Paginated(c, exports, page, limit, total)
+}
+
+// CreateExport handles POST /admin/scheduled-exports
+// The export only includes customers of the admin's region, with PII masked
+// unless the admin may see it.
+func (h *AdminScheduledExportHandler) CreateExport(c *gin.Context) {
+	var req domain.ScheduledExportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Invalid(c, err)
+		return
+	}
+
+	export := &domain.ScheduledExport{IsActive: true}
+	if !h.apply(c, &req, export) {
+		return
+	}
+	if adminID := authctx.UserID(c); adminID != uuid.Nil {
+		export.CreatedBy = &adminID
+	}
+
+	if err := h.repo.Create(c.Request.Context(), export); err != nil {
+		h.logger.Error("Failed to create scheduled export", zap.Error(err))
+		response.InternalServerError(c, "Failed to create scheduled export")
+		return
+	}
+
+	response.Created(c, "Scheduled export created", export)
+}
+
+// GetExport handles GET /admin/scheduled-exports/:exportId
+func (h *AdminScheduledExportHandler) GetExport(c *gin.Context) {
+	id, ok := parseScheduledExportID(c)
+	if !ok {
+		return
+	}
+
+	export, err := h.repo.Get(c.Request.Context(), id)
+	if err != nil {
+		response.FromError(c, err, response.CodeScheduledExportNotFound, "Failed to retrieve scheduled export")
+		return
+	}
+
+	response.OK(c, "", export)
+}
+
+// UpdateExport handles PUT /admin/scheduled-exports/:exportId
+// The definition is replaced, except for secrets left empty. The next run is
+// rescheduled from now.
+func (h *AdminScheduledExportHandler) UpdateExport(c *gin.Context) {
+	id, ok := parseScheduledExportID(c)
+	if !ok {
+		return
+	}
+	var req domain.ScheduledExportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Invalid(c, err)
+		return
+	}
+
+	export, err := h.repo.Get(c.Request.Context(), id)
+	if err != nil {
+		response.FromError(c, err, response.CodeScheduledExportNotFound, "Failed to update scheduled export")
+		return
+	}
+	if !h.apply(c, &req, export) {
+		return
+	}
+	if err := h.repo.Update(c.Request.Context(), export); err != nil {
+		response.FromError(c, err, response.CodeScheduledExportNotFound, "Failed to update scheduled export")
+		return
+	}
+
+	response.OK(c, "Scheduled export updated", export)
+}
+
+// DeleteExport handles DELETE /admin/scheduled-exports/:exportId
+// Its run history is deleted with it.
+func (h *AdminScheduledExportHandler) DeleteExport(c *gin.Context) {
+	id, ok := parseScheduledExportID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.repo.Delete(c.Request.Context(), id); err != nil {
+		response.FromError(c, err, response.CodeScheduledExportNotFound, "Failed to delete scheduled export")
+		return
+	}
+
+	response.Deleted(c, "Scheduled export deleted")
+}
+
+// ListRuns handles GET /admin/scheduled-exports/:exportId/runs
+// Query: page, limit, sort_by (see domain.ScheduledExportRunSort), sort_order
+func (h *AdminScheduledExportHandler) ListRuns(c *gin.Context) {
+	id, ok := parseScheduledExportID(c)
+	if !ok {
+		return
+	}
+	sort, ok := listSort(c, domain.ScheduledExportRunSort, c.Query("sort_by"), c.Query("sort_order"))
+	if !ok {
+		return
+	}
+	page, limit := scheduledExportPagination(c)
+
+	if _, err := h.repo.Get(c.Request.Context(), id); err != nil {
+		response.FromError(c, err, response.CodeScheduledExportNotFound, "Failed to retrieve runs")
+		return
+	}
+	runs, total, err := h.repo.ListRuns(c.Request.Context(), id, sort, page, limit)
+	if err != nil {
+		h.logger.Error("Failed to list scheduled export runs", zap.Error(err))
+		response.InternalServerError(c, "Failed to retrieve runs")
+		return
+	}
+
+	response.Paginated(c, runs, page, limit, total)
+}
+
+// RunExport handles POST /admin/scheduled-exports/:exportId/run
+// The export runs now, paused or not, and its schedule is left as it is.
+func (h *AdminScheduledExportHandler) RunExport(c *gin.Context) {
+	id, ok := parseScheduledExportID(c)
+	if !ok {
+		return
+	}
+
+	export, err := h.repo.Get(c.Request.Context(), id)
+	if err != nil {
+		response.FromError(c, err, response.CodeScheduledExportNotFound, "Failed to run scheduled export")
+		return
+	}
+	run, err := h.runner.RunNow(c.Request.Context(), export)
+	if err != nil {
+		h.logger.Error("Failed to start scheduled export run", zap.Error(err))
+		response.InternalServerError(c, "Failed to run scheduled export")
+		return
+	}
+
+	response.Queued(c, "Scheduled export started", run)
+}
+
+// apply sets the export from req with the admin's region and PII access and
+// schedules its next run, writing the error response when req is invalid
+func (h *AdminScheduledExportHandler) apply(c *gin.Context, req *domain.ScheduledExportRequest, export *domain.ScheduledExport) bool {
+	if err := req.Apply(export); err != nil {
+		if code, ok := response.CodeOf(err); ok {
+			response.Fail(c, code, err.Error())
+		} else {
+			response.BadRequest(c, err.Error(), nil)
+		}
+		return false
+	}
+	export.Region = middleware.GetRegionScope(c)
+	export.MaskPII = !authctx.Get(c).HasPermission(domain.PermissionCustomersPII)
+	if err := export.ScheduleNext(time.Now()); err != nil {
+		response.FromError(c, err, "", "Failed to schedule export")
+		return false
+	}
+	return true
+}
+
+// scheduledExportPagination reads the page and limit query parameters
+func scheduledExportPagination(c *gin.Context) (int, int) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+	return page, limit
+}
+
+func parseScheduledExportID(c *gin.Context) (uuid.UUID, bool) {
+	id, err := uuid.Parse(c.Param("exportId"))
+	if err != nil {
+		response.BadRequest(c, "Invalid scheduled export ID", nil)
+		return uuid.Nil, false
+	}
+	return id, true
+}
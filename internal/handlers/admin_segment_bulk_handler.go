@@ -0,0 +1,252 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/authctx"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/persistence"
+	"github.com/Ecom-micro-template/service-customer/internal/response"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// maxBulkSegmentFileSize bounds the size of an uploaded bulk segment CSV,
+// comfortably above domain.MaxBulkSegmentCustomers emails
+const maxBulkSegmentFileSize = 2 << 20
+
+// AdminSegmentBulkHandler assigns a segment to, or removes it from, many
+// customers at once
+type AdminSegmentBulkHandler struct {
+	repo      *persistence.SegmentBulkRepository
+	customers persistence.CustomerRepository
+	logger    *zap.Logger
+}
+
+// NewAdminSegmentBulkHandler creates a new bulk segment handler
+func NewAdminSegmentBulkHandler(repo *persistence.SegmentBulkRepository, customers persistence.CustomerRepository, logger *zap.Logger) *AdminSegmentBulkHandler {
+	return &AdminSegmentBulkHandler{repo: repo, customers: customers, logger: logger}
+}
+
+// BulkSegmentRequest is the JSON body of a bulk segment change. Exactly one
+// of customer_ids and filter is given.
+type BulkSegmentRequest struct {
+	Action      string      `json:"action" binding:"required,oneof=assign remove"`
+	CustomerIDs []uuid.UUID `json:"customer_ids,omitempty"`
+	// Filter selects customers with the filters of GET
+	// /admin/customers/export, as a query string, e.g. "status=active&tags=vip"
+	Filter string `json:"filter,omitempty"`
+}
+
+// BulkUpdate handles POST /admin/segments/:id/customers/bulk
+// The customers are given as a JSON BulkSegmentRequest, or as a CSV of emails
+// uploaded in the "file" form field with the action in the "action" field.
+// Up to domain.BulkSegmentInlineLimit customers are changed before the
+// response; larger changes return 202 and run in the background, with their
+// progress at the Location of the job.
+func (h *AdminSegmentBulkHandler) BulkUpdate(c *gin.Context) {
+	segmentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "Invalid segment ID", nil)
+		return
+	}
+	ctx := c.Request.Context()
+	if _, err := h.repo.GetSegment(ctx, segmentID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			response.Fail(c, response.CodeSegmentNotFound, "Segment not found")
+			return
+		}
+		h.logger.Error("Failed to get segment", zap.Error(err))
+		response.InternalServerError(c, "Failed to update segment customers")
+		return
+	}
+
+	job := &domain.SegmentBulkJob{SegmentID: segmentID}
+	if userID := authctx.UserID(c); userID != uuid.Nil {
+		job.CreatedBy = &userID
+	}
+	customerIDs, ok := h.bulkCustomers(c, job)
+	if !ok {
+		return
+	}
+	job.Total = len(customerIDs)
+
+	if err := h.repo.CreateJob(ctx, job); err != nil {
+		h.logger.Error("Failed to create bulk segment job", zap.Error(err))
+		response.InternalServerError(c, "Failed to update segment customers")
+		return
+	}
+	if len(customerIDs) <= domain.BulkSegmentInlineLimit {
+		if err := h.repo.Run(ctx, job, customerIDs); err != nil {
+			h.logger.Error("Failed to update segment customers",
+				zap.String("job_id", job.ID.String()), zap.Error(err))
+			response.FromError(c, err, "", "Failed to update segment customers")
+			return
+		}
+		response.OK(c, "Segment customers updated", job)
+		return
+	}
+
+	queued := *job
+	go h.run(context.WithoutCancel(ctx), job, customerIDs)
+	c.Header("Location", fmt.Sprintf("%s/%s", strings.TrimSuffix(c.Request.URL.Path, "/"), queued.ID))
+	response.Queued(c, "Segment customers update started", &queued)
+}
+
+// run applies a bulk change in the background
+func (h *AdminSegmentBulkHandler) run(ctx context.Context, job *domain.SegmentBulkJob, customerIDs []uuid.UUID) {
+	if err := h.repo.Run(ctx, job, customerIDs); err != nil {
+		h.logger.Error("Bulk segment job failed",
+			zap.String("job_id", job.ID.String()), zap.Int("processed", job.Processed), zap.Error(err))
+		return
+	}
+	h.logger.Info("Bulk segment job completed",
+		zap.String("job_id", job.ID.String()),
+		zap.Int("changed", job.Changed), zap.Int("unchanged", job.Unchanged), zap.Int("not_found", job.NotFound))
+}
+
+// bulkCustomers returns the IDs of the customers to change and sets the
+// action, source and emails not found on job. An error is written and false
+// returned if the customers can't be resolved.
+func (h *AdminSegmentBulkHandler) bulkCustomers(c *gin.Context, job *domain.SegmentBulkJob) ([]uuid.UUID, bool) {
+	if strings.HasPrefix(c.ContentType(), "multipart/") {
+		return h.csvCustomers(c, job)
+	}
+
+	var req BulkSegmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Invalid(c, err)
+		return nil, false
+	}
+	if (len(req.CustomerIDs) == 0) == (req.Filter == "") {
+		response.Fail(c, response.CodeBulkSegmentInvalid, "Give either customer_ids or filter")
+		return nil, false
+	}
+	job.Action = req.Action
+
+	if req.Filter == "" {
+		job.Source = domain.BulkSegmentByIDs
+		ids := uniqueIDs(req.CustomerIDs)
+		if len(ids) > domain.MaxBulkSegmentCustomers {
+			response.Fail(c, response.CodeBulkSegmentInvalid, domain.ErrBulkSegmentTooLarge.Error())
+			return nil, false
+		}
+		return ids, true
+	}
+
+	job.Source = domain.BulkSegmentByFilter
+	query, err := url.ParseQuery(strings.TrimPrefix(req.Filter, "?"))
+	if err != nil {
+		response.Fail(c, response.CodeBulkSegmentInvalid, "Invalid filter")
+		return nil, false
+	}
+	filter, err := customerFilter(c, query)
+	if err != nil {
+		response.Fail(c, response.CodeBulkSegmentInvalid, err.Error())
+		return nil, false
+	}
+	filter.Limit = domain.MaxBulkSegmentCustomers + 1
+	ids, err := h.customers.ListAdminIDs(c.Request.Context(), filter)
+	if err != nil {
+		h.logger.Error("Failed to list customers for a bulk segment change", zap.Error(err))
+		response.FromError(c, err, "", "Failed to update segment customers")
+		return nil, false
+	}
+	switch {
+	case len(ids) == 0:
+		response.Fail(c, response.CodeBulkSegmentInvalid, "No customers match the filter")
+		return nil, false
+	case len(ids) > domain.MaxBulkSegmentCustomers:
+		response.Fail(c, response.CodeBulkSegmentInvalid, domain.ErrBulkSegmentTooLarge.Error())
+		return nil, false
+	}
+	return ids, true
+}
+
+// csvCustomers resolves the emails of an uploaded bulk segment CSV
+func (h *AdminSegmentBulkHandler) csvCustomers(c *gin.Context, job *domain.SegmentBulkJob) ([]uuid.UUID, bool) {
+	job.Source = domain.BulkSegmentByCSV
+	job.Action = c.PostForm("action")
+	if job.Action != domain.BulkSegmentAssign && job.Action != domain.BulkSegmentRemove {
+		response.BadRequest(c, "action must be assign or remove", nil)
+		return nil, false
+	}
+
+	file, err := c.FormFile("file")
+	if err != nil {
+		response.BadRequest(c, "A CSV of emails is required in the file field", nil)
+		return nil, false
+	}
+	if file.Size > maxBulkSegmentFileSize {
+		response.Fail(c, response.CodeBulkSegmentInvalid, fmt.Sprintf("CSV is larger than %d bytes", maxBulkSegmentFileSize))
+		return nil, false
+	}
+	body, err := file.Open()
+	if err != nil {
+		response.BadRequest(c, err.Error(), nil)
+		return nil, false
+	}
+	defer body.Close()
+
+	emails, invalid, err := domain.ParseBulkSegmentEmails(io.LimitReader(body, maxBulkSegmentFileSize))
+	if err != nil {
+		response.Fail(c, response.CodeBulkSegmentInvalid, err.Error())
+		return nil, false
+	}
+	ids, err := h.repo.CustomerIDsByEmail(c.Request.Context(), emails)
+	if err != nil {
+		h.logger.Error("Failed to look up customers by email", zap.Error(err))
+		response.InternalServerError(c, "Failed to update segment customers")
+		return nil, false
+	}
+	ids = uniqueIDs(ids)
+	job.NotFound = len(invalid) + max(len(emails)-len(ids), 0)
+	return ids, true
+}
+
+// GetJob handles GET /admin/segments/:id/customers/bulk/:jobId
+func (h *AdminSegmentBulkHandler) GetJob(c *gin.Context) {
+	segmentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "Invalid segment ID", nil)
+		return
+	}
+	jobID, err := uuid.Parse(c.Param("jobId"))
+	if err != nil {
+		response.BadRequest(c, "Invalid job ID", nil)
+		return
+	}
+
+	job, err := h.repo.GetJob(c.Request.Context(), segmentID, jobID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			response.Fail(c, response.CodeBulkSegmentJobNotFound, "Job not found")
+			return
+		}
+		h.logger.Error("Failed to get bulk segment job", zap.Error(err))
+		response.InternalServerError(c, "Failed to retrieve job")
+		return
+	}
+
+	response.OK(c, "", job)
+}
+
+// uniqueIDs returns ids without duplicates, in their first order
+func uniqueIDs(ids []uuid.UUID) []uuid.UUID {
+	seen := make(map[uuid.UUID]bool, len(ids))
+	unique := make([]uuid.UUID, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	return unique
+}
@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/persistence"
+	"github.com/Ecom-micro-template/service-customer/internal/response"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// AdminSegmentHistoryHandler serves the history of customers entering and
+// leaving a segment
+type AdminSegmentHistoryHandler struct {
+	repo   *persistence.SegmentMembershipRepository
+	logger *zap.Logger
+}
+
+// NewAdminSegmentHistoryHandler creates a new segment history handler
+func NewAdminSegmentHistoryHandler(db *gorm.DB, logger *zap.Logger) *AdminSegmentHistoryHandler {
+	return &AdminSegmentHistoryHandler{
+		repo:   persistence.NewSegmentMembershipRepository(db),
+		logger: logger,
+	}
+}
+
+// ListHistory handles GET /admin/segments/:id/history
+// Query: page, limit, change, source, date_from, date_to
+func (h *AdminSegmentHistoryHandler) ListHistory(c *gin.Context) {
+	segmentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "Invalid segment ID", nil)
+		return
+	}
+	filter, err := parseSegmentHistoryFilter(c)
+	if err != nil {
+		response.BadRequest(c, err.Error(), nil)
+		return
+	}
+
+	ctx := c.Request.Context()
+	exists, err := h.repo.SegmentExists(ctx, segmentID)
+	if err != nil {
+		h.logger.Error("Failed to get segment", zap.Error(err))
+		response.InternalServerError(c, "Failed to retrieve segment history")
+		return
+	}
+	if !exists {
+		response.Fail(c, response.CodeSegmentNotFound, "Segment not found")
+		return
+	}
+
+	events, total, err := h.repo.ListBySegment(ctx, segmentID, filter)
+	if err != nil {
+		h.logger.Error("Failed to list segment history", zap.Error(err))
+		response.InternalServerError(c, "Failed to retrieve segment history")
+		return
+	}
+
+	response.Paginated(c, events, filter.Page, filter.Limit, total)
+}
+
+func parseSegmentHistoryFilter(c *gin.Context) (domain.SegmentHistoryFilter, error) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	filter := domain.SegmentHistoryFilter{
+		Change: c.Query("change"),
+		Source: c.Query("source"),
+		Page:   page,
+		Limit:  limit,
+	}
+	switch filter.Change {
+	case "", domain.SegmentMembershipEntered, domain.SegmentMembershipLeft:
+	default:
+		return filter, errors.New("change must be entered or left")
+	}
+	switch filter.Source {
+	case "", domain.SegmentSourceManual, domain.SegmentSourceRule, domain.SegmentSourceBulk:
+	default:
+		return filter, errors.New("source must be manual, rule or bulk")
+	}
+	if dateFromStr := c.Query("date_from"); dateFromStr != "" {
+		dateFrom, err := time.Parse("2006-01-02", dateFromStr)
+		if err != nil {
+			return filter, errors.New("date_from must be YYYY-MM-DD")
+		}
+		filter.DateFrom = &dateFrom
+	}
+	if dateToStr := c.Query("date_to"); dateToStr != "" {
+		dateTo, err := time.Parse("2006-01-02", dateToStr)
+		if err != nil {
+			return filter, errors.New("date_to must be YYYY-MM-DD")
+		}
+		dateTo = dateTo.Add(24*time.Hour - time.Second)
+		filter.DateTo = &dateTo
+	}
+	return filter, nil
+}
@@ -0,0 +1,223 @@
+package handlers
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/authctx"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/persistence"
+	"github.com/Ecom-micro-template/service-customer/internal/response"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// AdminWebhookHandler manages the webhook subscriptions that customer events
+// are sent to, and their delivery logs
+type AdminWebhookHandler struct {
+	repo   *persistence.WebhookSubscriptionRepository
+	logger *zap.Logger
+}
+
+// NewAdminWebhookHandler creates a new webhook subscription handler
+func NewAdminWebhookHandler(db *gorm.DB, logger *zap.Logger) *AdminWebhookHandler {
+	return &AdminWebhookHandler{
+		repo:   persistence.NewWebhookSubscriptionRepository(db),
+		logger: logger,
+	}
+}
+
+// ListWebhooks handles GET /admin/webhooks
+func (h *AdminWebhookHandler) ListWebhooks(c *gin.Context) {
+	subscriptions, err := h.repo.List(c.Request.Context())
+	if err != nil {
+		h.logger.Error("Failed to list webhook subscriptions", zap.Error(err))
+		response.InternalServerError(c, "Failed to retrieve webhooks")
+		return
+	}
+
+	response.OK(c, "", subscriptions)
+}
+
+// CreateWebhook handles POST /admin/webhooks
+// The signing secret is only returned here.
+func (h *AdminWebhookHandler) CreateWebhook(c *gin.Context) {
+	var req domain.CreateWebhookSubscriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Invalid(c, err)
+		return
+	}
+
+	secret := req.Secret
+	if secret == "" {
+		var err error
+		if secret, err = domain.NewWebhookSecret(); err != nil {
+			h.logger.Error("Failed to generate webhook secret", zap.Error(err))
+			response.InternalServerError(c, "Failed to create webhook")
+			return
+		}
+	}
+	subscription := &domain.WebhookSubscription{
+		Name:       req.Name,
+		URL:        req.URL,
+		Secret:     secret,
+		EventTypes: req.EventTypes,
+		IsActive:   true,
+	}
+	if adminID := authctx.UserID(c); adminID != uuid.Nil {
+		subscription.CreatedBy = &adminID
+	}
+
+	if err := h.repo.Create(c.Request.Context(), subscription); err != nil {
+		h.logger.Error("Failed to create webhook subscription", zap.Error(err))
+		response.InternalServerError(c, "Failed to create webhook")
+		return
+	}
+
+	response.Created(c, "Webhook created", domain.CreatedWebhookSubscription{WebhookSubscription: subscription, Secret: secret})
+}
+
+// GetWebhook handles GET /admin/webhooks/:webhookId
+func (h *AdminWebhookHandler) GetWebhook(c *gin.Context) {
+	id, ok := parseWebhookID(c)
+	if !ok {
+		return
+	}
+
+	subscription, err := h.repo.Get(c.Request.Context(), id)
+	if err != nil {
+		response.FromError(c, err, response.CodeWebhookNotFound, "Failed to retrieve webhook")
+		return
+	}
+
+	response.OK(c, "", subscription)
+}
+
+// UpdateWebhook handles PUT /admin/webhooks/:webhookId
+func (h *AdminWebhookHandler) UpdateWebhook(c *gin.Context) {
+	id, ok := parseWebhookID(c)
+	if !ok {
+		return
+	}
+	var req domain.UpdateWebhookSubscriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Invalid(c, err)
+		return
+	}
+
+	subscription, err := h.repo.Update(c.Request.Context(), id, &req)
+	if err != nil {
+		response.FromError(c, err, response.CodeWebhookNotFound, "Failed to update webhook")
+		return
+	}
+
+	response.OK(c, "Webhook updated", subscription)
+}
+
+// DeleteWebhook handles DELETE /admin/webhooks/:webhookId
+// Queued deliveries and the delivery log are deleted with it.
+func (h *AdminWebhookHandler) DeleteWebhook(c *gin.Context) {
+	id, ok := parseWebhookID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.repo.Delete(c.Request.Context(), id); err != nil {
+		response.FromError(c, err, response.CodeWebhookNotFound, "Failed to delete webhook")
+		return
+	}
+
+	response.Deleted(c, "Webhook deleted")
+}
+
+// ListDeliveries handles GET /admin/webhooks/:webhookId/deliveries
+// Query: status (pending, delivered or failed), page, limit
+func (h *AdminWebhookHandler) ListDeliveries(c *gin.Context) {
+	id, ok := parseWebhookID(c)
+	if !ok {
+		return
+	}
+	status := c.Query("status")
+	switch status {
+	case "", domain.OutboundWebhookPending, domain.OutboundWebhookDelivered, domain.OutboundWebhookFailed:
+	default:
+		response.BadRequest(c, "status must be pending, delivered or failed", nil)
+		return
+	}
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	if _, err := h.repo.Get(c.Request.Context(), id); err != nil {
+		response.FromError(c, err, response.CodeWebhookNotFound, "Failed to retrieve deliveries")
+		return
+	}
+	deliveries, total, err := h.repo.Deliveries(c.Request.Context(), id, status, page, limit)
+	if err != nil {
+		h.logger.Error("Failed to list webhook deliveries", zap.Error(err))
+		response.InternalServerError(c, "Failed to retrieve deliveries")
+		return
+	}
+
+	response.Paginated(c, deliveries, page, limit, total)
+}
+
+// GetDelivery handles GET /admin/webhooks/:webhookId/deliveries/:deliveryId
+// The delivery comes with the log of its attempts.
+func (h *AdminWebhookHandler) GetDelivery(c *gin.Context) {
+	id, ok := parseWebhookID(c)
+	if !ok {
+		return
+	}
+	deliveryID, err := uuid.Parse(c.Param("deliveryId"))
+	if err != nil {
+		response.BadRequest(c, "Invalid delivery ID", nil)
+		return
+	}
+
+	delivery, err := h.repo.Delivery(c.Request.Context(), id, deliveryID)
+	if err != nil {
+		response.FromError(c, err, response.CodeWebhookDeliveryNotFound, "Failed to retrieve delivery")
+		return
+	}
+
+	response.OK(c, "", delivery)
+}
+
+// Redeliver handles POST /admin/webhooks/:webhookId/deliveries/:deliveryId/redeliver
+// The same event is sent again with a fresh set of retries.
+func (h *AdminWebhookHandler) Redeliver(c *gin.Context) {
+	id, ok := parseWebhookID(c)
+	if !ok {
+		return
+	}
+	deliveryID, err := uuid.Parse(c.Param("deliveryId"))
+	if err != nil {
+		response.BadRequest(c, "Invalid delivery ID", nil)
+		return
+	}
+
+	delivery, err := h.repo.Redeliver(c.Request.Context(), id, deliveryID, time.Now())
+	if err != nil {
+		response.FromError(c, err, response.CodeWebhookDeliveryNotFound, "Failed to redeliver")
+		return
+	}
+
+	response.OK(c, "Delivery queued", delivery)
+}
+
+func parseWebhookID(c *gin.Context) (uuid.UUID, bool) {
+	id, err := uuid.Parse(c.Param("webhookId"))
+	if err != nil {
+		response.BadRequest(c, "Invalid webhook ID", nil)
+		return uuid.Nil, false
+	}
+	return id, true
+}
@@ -1,14 +1,20 @@
 package handlers
 
 import (
-	"net/http"
+	"context"
+	"errors"
+	"fmt"
+	"log"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/authctx"
 	"github.com/Ecom-micro-template/service-customer/internal/middleware"
 	"github.com/Ecom-micro-template/service-customer/internal/domain"
 	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/persistence"
+	"github.com/Ecom-micro-template/service-customer/internal/response"
 	"gorm.io/gorm"
 )
 
@@ -26,46 +32,71 @@ func NewBackInStockHandler(db *gorm.DB) *BackInStockHandler {
 	}
 }
 
+// WithSubscriptionTTL sets how long subscriptions stay active before expiring
+func (h *BackInStockHandler) WithSubscriptionTTL(ttl time.Duration) *BackInStockHandler {
+	h.repo.WithSubscriptionTTL(ttl)
+	return h
+}
+
+// BackInStockSubscriptions is the payload of a customer's subscription listing
+type BackInStockSubscriptions struct {
+	Subscriptions []domain.BackInStockSubscription `json:"subscriptions"`
+	Count         int                              `json:"count"`
+}
+
+// SubscriptionStatus represents whether the customer is subscribed to a product
+type SubscriptionStatus struct {
+	Subscribed bool       `json:"subscribed"`
+	ProductID  uuid.UUID  `json:"product_id"`
+	VariantID  *uuid.UUID `json:"variant_id"`
+}
+
+// SubscriptionStatusBatch maps each checked product (ProductRef.Key) to
+// whether the customer is subscribed to it
+type SubscriptionStatusBatch struct {
+	Subscribed map[string]bool `json:"subscribed"`
+}
+
 // Subscribe subscribes a customer to back-in-stock notifications
 // POST /api/v1/customer/back-in-stock
 func (h *BackInStockHandler) Subscribe(c *gin.Context) {
-	userID, ok := middleware.GetUserID(c)
+	userID, ok := authctx.AuthenticatedUserID(c)
 	if !ok {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
 		return
 	}
 
 	var input domain.BackInStockSubscribeInput
 	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		response.Invalid(c, err)
 		return
 	}
 
 	subscription, err := h.repo.Subscribe(c.Request.Context(), userID, input)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to subscribe"})
+		response.InternalServerError(c, "Failed to subscribe")
 		return
 	}
 
-	c.JSON(http.StatusCreated, gin.H{
-		"success": true,
-		"message": "Subscribed to back-in-stock notification",
-		"data":    subscription,
-	})
+	if subscription.ProductName != "" {
+		middleware.SetActivityDetails(c, subscription.ProductName)
+	} else {
+		middleware.SetActivityDetails(c, "product "+subscription.ProductID.String())
+	}
+
+	response.Created(c, "Subscribed to back-in-stock notification", subscription)
 }
 
 // Unsubscribe removes a subscription by product/variant
 // DELETE /api/v1/customer/back-in-stock/:productId
 func (h *BackInStockHandler) Unsubscribe(c *gin.Context) {
-	userID, ok := middleware.GetUserID(c)
+	userID, ok := authctx.AuthenticatedUserID(c)
 	if !ok {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
 		return
 	}
 
 	productID, err := uuid.Parse(c.Param("productId"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		response.BadRequest(c, "Invalid product ID", nil)
 		return
 	}
 
@@ -74,85 +105,73 @@ func (h *BackInStockHandler) Unsubscribe(c *gin.Context) {
 	if variantIDStr := c.Query("variant_id"); variantIDStr != "" {
 		parsed, err := uuid.Parse(variantIDStr)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid variant ID"})
+			response.BadRequest(c, "Invalid variant ID", nil)
 			return
 		}
 		variantID = &parsed
 	}
 
 	if err := h.repo.Unsubscribe(c.Request.Context(), userID, productID, variantID); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unsubscribe"})
+		response.InternalServerError(c, "Failed to unsubscribe")
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"success": true,
-		"message": "Unsubscribed from back-in-stock notification",
-	})
+	response.Deleted(c, "Unsubscribed from back-in-stock notification")
 }
 
 // UnsubscribeByID removes a subscription by ID
 // DELETE /api/v1/customer/back-in-stock/subscriptions/:id
 func (h *BackInStockHandler) UnsubscribeByID(c *gin.Context) {
-	userID, ok := middleware.GetUserID(c)
+	userID, ok := authctx.AuthenticatedUserID(c)
 	if !ok {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
 		return
 	}
 
 	subscriptionID, err := uuid.Parse(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid subscription ID"})
+		response.BadRequest(c, "Invalid subscription ID", nil)
 		return
 	}
 
 	if err := h.repo.UnsubscribeByID(c.Request.Context(), userID, subscriptionID); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unsubscribe"})
+		response.InternalServerError(c, "Failed to unsubscribe")
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"success": true,
-		"message": "Subscription removed",
-	})
+	response.Deleted(c, "Subscription removed")
 }
 
 // GetSubscriptions returns all subscriptions for the current customer
 // GET /api/v1/customer/back-in-stock
 func (h *BackInStockHandler) GetSubscriptions(c *gin.Context) {
-	userID, ok := middleware.GetUserID(c)
+	userID, ok := authctx.AuthenticatedUserID(c)
 	if !ok {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
 		return
 	}
 
 	subscriptions, err := h.repo.GetByCustomer(c.Request.Context(), userID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get subscriptions"})
+		response.InternalServerError(c, "Failed to get subscriptions")
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"success": true,
-		"data": gin.H{
-			"subscriptions": subscriptions,
-			"count":         len(subscriptions),
-		},
+	response.OK(c, "", BackInStockSubscriptions{
+		Subscriptions: subscriptions,
+		Count:         len(subscriptions),
 	})
 }
 
 // IsSubscribed checks if customer is subscribed to a product
 // GET /api/v1/customer/back-in-stock/check/:productId
 func (h *BackInStockHandler) IsSubscribed(c *gin.Context) {
-	userID, ok := middleware.GetUserID(c)
+	userID, ok := authctx.AuthenticatedUserID(c)
 	if !ok {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
 		return
 	}
 
 	productID, err := uuid.Parse(c.Param("productId"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		response.BadRequest(c, "Invalid product ID", nil)
 		return
 	}
 
@@ -161,7 +180,7 @@ func (h *BackInStockHandler) IsSubscribed(c *gin.Context) {
 	if variantIDStr := c.Query("variant_id"); variantIDStr != "" {
 		parsed, err := uuid.Parse(variantIDStr)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid variant ID"})
+			response.BadRequest(c, "Invalid variant ID", nil)
 			return
 		}
 		variantID = &parsed
@@ -169,49 +188,136 @@ func (h *BackInStockHandler) IsSubscribed(c *gin.Context) {
 
 	subscribed, err := h.repo.IsSubscribed(c.Request.Context(), userID, productID, variantID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check subscription"})
+		response.InternalServerError(c, "Failed to check subscription")
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"success":    true,
-		"subscribed": subscribed,
-		"product_id": productID,
-		"variant_id": variantID,
+	response.OK(c, "", SubscriptionStatus{
+		Subscribed: subscribed,
+		ProductID:  productID,
+		VariantID:  variantID,
 	})
 }
 
+// IsSubscribedBatch checks up to 100 products or variants at once, for the
+// product grid
+// POST /api/v1/customer/back-in-stock/check-batch
+func (h *BackInStockHandler) IsSubscribedBatch(c *gin.Context) {
+	userID, ok := authctx.AuthenticatedUserID(c)
+	if !ok {
+		return
+	}
+
+	var req domain.MembershipCheckRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Invalid(c, err)
+		return
+	}
+
+	subscribed, err := h.repo.SubscribedTo(c.Request.Context(), userID, req.Items)
+	if err != nil {
+		response.InternalServerError(c, "Failed to check subscriptions")
+		return
+	}
+
+	response.OK(c, "", SubscriptionStatusBatch{Subscribed: subscribed})
+}
+
 // Admin Handler
 
 // AdminBackInStockHandler handles admin back-in-stock operations
 type AdminBackInStockHandler struct {
-	repo *persistence.BackInStockRepository
+	repo      *persistence.BackInStockRepository
+	guestRepo *persistence.GuestBackInStockRepository
+	retries   *persistence.NotificationRetryRepository
+	sender    BackInStockNotificationSender
+	notifier  BackInStockProductNotifier
+}
+
+// BackInStockNotificationSender sends restock notifications through the notification pipeline
+type BackInStockNotificationSender interface {
+	SendBackInStockNotification(ctx context.Context, notification domain.BackInStockNotification) error
+}
+
+// BackInStockProductNotifier notifies a product's pending subscribers outside
+// a restock event
+type BackInStockProductNotifier interface {
+	NotifyProduct(ctx context.Context, productID uuid.UUID, variantID *uuid.UUID, stockQuantity, limit int, dryRun bool) (*domain.BackInStockNotifyResult, error)
 }
 
 // NewAdminBackInStockHandler creates a new admin handler
 func NewAdminBackInStockHandler(db *gorm.DB) *AdminBackInStockHandler {
 	return &AdminBackInStockHandler{
-		repo: persistence.NewBackInStockRepository(db),
+		repo:      persistence.NewBackInStockRepository(db),
+		guestRepo: persistence.NewGuestBackInStockRepository(db),
+		retries:   persistence.NewNotificationRetryRepository(db),
 	}
 }
 
+// WithNotificationSender sets how test notifications are sent
+func (h *AdminBackInStockHandler) WithNotificationSender(sender BackInStockNotificationSender) *AdminBackInStockHandler {
+	h.sender = sender
+	return h
+}
+
+// WithProductNotifier enables notifying a product's subscribers by hand
+func (h *AdminBackInStockHandler) WithProductNotifier(notifier BackInStockProductNotifier) *AdminBackInStockHandler {
+	h.notifier = notifier
+	return h
+}
+
+// ProductSubscriptions is the payload of a product's subscription listing
+type ProductSubscriptions struct {
+	Subscriptions []domain.BackInStockSubscription `json:"subscriptions"`
+	Count         int                              `json:"count"`
+	ProductID     uuid.UUID                        `json:"product_id"`
+	VariantID     *uuid.UUID                       `json:"variant_id"`
+}
+
+// MarkNotifiedRequest represents the request body for marking subscriptions notified
+type MarkNotifiedRequest struct {
+	SubscriptionIDs []string `json:"subscription_ids" binding:"required"`
+}
+
+// MarkNotifiedResult represents the number of subscriptions marked notified
+type MarkNotifiedResult struct {
+	Count int `json:"count"`
+}
+
+// CleanupResult represents the number of old subscriptions deleted, in total
+// and per batch
+type CleanupResult struct {
+	Deleted int64                      `json:"deleted"`
+	Batches []persistence.CleanupBatch `json:"batches"`
+}
+
+// maxCleanupBatchSize caps the batch_size of a cleanup
+const maxCleanupBatchSize = 10000
+
 // GetStats returns subscription statistics
 // GET /api/v1/admin/back-in-stock/stats
 func (h *AdminBackInStockHandler) GetStats(c *gin.Context) {
 	stats, err := h.repo.GetStats(c.Request.Context())
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get stats"})
+		response.InternalServerError(c, "Failed to get stats")
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"success": true,
-		"data":    stats,
-	})
+	retries, err := h.retries.CountByStatus(c.Request.Context())
+	if err != nil {
+		response.InternalServerError(c, "Failed to get stats")
+		return
+	}
+	stats.RetryingNotifications = retries[domain.NotificationRetryPending]
+	stats.FailedNotifications = retries[domain.NotificationRetryFailed]
+
+	response.OK(c, "", stats)
 }
 
 // ListSubscriptions returns all subscriptions with pagination
 // GET /api/v1/admin/back-in-stock/subscriptions
+// ?cursor= switches to cursor pagination (see cursorQuery); meta then
+// carries next_cursor instead of page counts.
 func (h *AdminBackInStockHandler) ListSubscriptions(c *gin.Context) {
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
@@ -224,26 +330,28 @@ func (h *AdminBackInStockHandler) ListSubscriptions(c *gin.Context) {
 		limit = 20
 	}
 
-	subscriptions, total, err := h.repo.ListAll(c.Request.Context(), page, limit, pendingOnly)
+	after, useCursor, err := cursorQuery(c.Request.URL.Query())
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list subscriptions"})
+		response.Fail(c, response.CodeInvalidCursor, err.Error())
+		return
+	}
+	if useCursor {
+		subscriptions, next, err := h.repo.ListAllAfter(c.Request.Context(), after, limit, pendingOnly)
+		if err != nil {
+			response.InternalServerError(c, "Failed to list subscriptions")
+			return
+		}
+		response.CursorPaginated(c, "", subscriptions, limit, next)
 		return
 	}
 
-	totalPages := (int(total) + limit - 1) / limit
+	subscriptions, total, err := h.repo.ListAll(c.Request.Context(), page, limit, pendingOnly)
+	if err != nil {
+		response.InternalServerError(c, "Failed to list subscriptions")
+		return
+	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"success": true,
-		"data": gin.H{
-			"subscriptions": subscriptions,
-			"pagination": gin.H{
-				"page":        page,
-				"limit":       limit,
-				"total":       total,
-				"total_pages": totalPages,
-			},
-		},
-	})
+	response.Paginated(c, subscriptions, page, limit, total)
 }
 
 // GetByProduct returns subscriptions for a specific product
@@ -251,7 +359,7 @@ func (h *AdminBackInStockHandler) ListSubscriptions(c *gin.Context) {
 func (h *AdminBackInStockHandler) GetByProduct(c *gin.Context) {
 	productID, err := uuid.Parse(c.Param("productId"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		response.BadRequest(c, "Invalid product ID", nil)
 		return
 	}
 
@@ -260,7 +368,7 @@ func (h *AdminBackInStockHandler) GetByProduct(c *gin.Context) {
 	if variantIDStr := c.Query("variant_id"); variantIDStr != "" {
 		parsed, err := uuid.Parse(variantIDStr)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid variant ID"})
+			response.BadRequest(c, "Invalid variant ID", nil)
 			return
 		}
 		variantID = &parsed
@@ -268,30 +376,24 @@ func (h *AdminBackInStockHandler) GetByProduct(c *gin.Context) {
 
 	subscriptions, err := h.repo.GetByProduct(c.Request.Context(), productID, variantID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get subscriptions"})
+		response.InternalServerError(c, "Failed to get subscriptions")
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"success": true,
-		"data": gin.H{
-			"subscriptions": subscriptions,
-			"count":         len(subscriptions),
-			"product_id":    productID,
-			"variant_id":    variantID,
-		},
+	response.OK(c, "", ProductSubscriptions{
+		Subscriptions: subscriptions,
+		Count:         len(subscriptions),
+		ProductID:     productID,
+		VariantID:     variantID,
 	})
 }
 
 // MarkAsNotified marks subscriptions as notified (after sending notifications)
 // POST /api/v1/admin/back-in-stock/mark-notified
 func (h *AdminBackInStockHandler) MarkAsNotified(c *gin.Context) {
-	var req struct {
-		SubscriptionIDs []string `json:"subscription_ids" binding:"required"`
-	}
-
+	var req MarkNotifiedRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		response.Invalid(c, err)
 		return
 	}
 
@@ -299,22 +401,18 @@ func (h *AdminBackInStockHandler) MarkAsNotified(c *gin.Context) {
 	for _, idStr := range req.SubscriptionIDs {
 		id, err := uuid.Parse(idStr)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid subscription ID: " + idStr})
+			response.BadRequest(c, "Invalid subscription ID: "+idStr, nil)
 			return
 		}
 		ids = append(ids, id)
 	}
 
 	if err := h.repo.MarkMultipleAsNotified(c.Request.Context(), ids); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to mark as notified"})
+		response.InternalServerError(c, "Failed to mark as notified")
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"success": true,
-		"message": "Subscriptions marked as notified",
-		"count":   len(ids),
-	})
+	response.OK(c, "Subscriptions marked as notified", MarkNotifiedResult{Count: len(ids)})
 }
 
 // Cleanup deletes old notified subscriptions
@@ -325,15 +423,136 @@ func (h *AdminBackInStockHandler) Cleanup(c *gin.Context) {
 		days = 30
 	}
 
-	deleted, err := h.repo.DeleteOldNotified(c.Request.Context(), days)
+	batchSize, _ := strconv.Atoi(c.DefaultQuery("batch_size", strconv.Itoa(persistence.DefaultCleanupBatchSize)))
+	if batchSize < 1 || batchSize > maxCleanupBatchSize {
+		batchSize = persistence.DefaultCleanupBatchSize
+	}
+
+	result := CleanupResult{Batches: []persistence.CleanupBatch{}}
+	deleted, err := h.repo.DeleteOldNotified(c.Request.Context(), days, batchSize, func(batch persistence.CleanupBatch) {
+		result.Batches = append(result.Batches, batch)
+	})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cleanup"})
+		log.Printf("⚠️  Back-in-stock cleanup stopped after deleting %d subscriptions: %v", deleted, err)
+		response.InternalServerError(c, fmt.Sprintf("Cleanup stopped after deleting %d subscriptions", deleted))
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"success": true,
-		"message": "Cleanup completed",
-		"deleted": deleted,
-	})
+	result.Deleted = deleted
+	response.OK(c, "Cleanup completed", result)
+}
+
+// SendTestNotification composes the notification a subscription would get on
+// restock and sends it, flagged as a test, to an admin's address. The
+// subscription itself is left untouched.
+// POST /api/v1/admin/back-in-stock/test-notification
+func (h *AdminBackInStockHandler) SendTestNotification(c *gin.Context) {
+	var input domain.BackInStockTestNotificationInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		response.Invalid(c, err)
+		return
+	}
+
+	subscriptionID, err := uuid.Parse(input.SubscriptionID)
+	if err != nil {
+		response.BadRequest(c, "Invalid subscription ID", nil)
+		return
+	}
+
+	stockQuantity := input.StockQuantity
+	if stockQuantity < 1 {
+		stockQuantity = 1
+	}
+
+	ctx := c.Request.Context()
+	var notification domain.BackInStockNotification
+	if subscription, err := h.repo.GetByID(ctx, subscriptionID); err == nil {
+		notification = subscription.Notification(stockQuantity)
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		response.InternalServerError(c, "Failed to get subscription")
+		return
+	} else if guest, err := h.guestRepo.GetByID(ctx, subscriptionID); err == nil {
+		notification = guest.Notification(stockQuantity)
+	} else if errors.Is(err, gorm.ErrRecordNotFound) {
+		response.Fail(c, response.CodeSubscriptionNotFound, "Subscription not found")
+		return
+	} else {
+		response.InternalServerError(c, "Failed to get subscription")
+		return
+	}
+
+	notification.CustomerEmail = input.Email
+	notification.IsTest = true
+
+	if h.sender == nil {
+		response.ServiceUnavailable(c, "Notification sending is not configured")
+		return
+	}
+	if err := h.sender.SendBackInStockNotification(ctx, notification); err != nil {
+		log.Printf("⚠️  Failed to send test back-in-stock notification for subscription %s: %v", subscriptionID, err)
+		response.BadGateway(c, "Failed to send test notification")
+		return
+	}
+
+	response.OK(c, "Test notification sent to "+input.Email, notification)
+}
+
+// NotifyProduct notifies a product's pending subscribers by hand, for when the
+// restock event was missed or stock was adjusted manually. Throttling and
+// notification preferences apply as on a restock. With dry_run nothing is
+// sent and the response lists who would be notified.
+// POST /api/v1/admin/back-in-stock/products/:productId/notify
+func (h *AdminBackInStockHandler) NotifyProduct(c *gin.Context) {
+	productID, err := uuid.Parse(c.Param("productId"))
+	if err != nil {
+		response.BadRequest(c, "Invalid product ID", nil)
+		return
+	}
+
+	var input domain.BackInStockNotifyInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		response.Invalid(c, err)
+		return
+	}
+	var variantID *uuid.UUID
+	if input.VariantID != "" {
+		parsed, err := uuid.Parse(input.VariantID)
+		if err != nil {
+			response.BadRequest(c, "Invalid variant ID", nil)
+			return
+		}
+		variantID = &parsed
+	}
+	stockQuantity := input.StockQuantity
+	if stockQuantity < 1 {
+		stockQuantity = 1
+	}
+
+	if h.notifier == nil {
+		response.ServiceUnavailable(c, "Notification sending is not configured")
+		return
+	}
+	result, err := h.notifier.NotifyProduct(c.Request.Context(), productID, variantID, stockQuantity, input.Limit, input.DryRun)
+	if result == nil {
+		log.Printf("⚠️  Failed to notify back-in-stock subscribers of product %s: %v", productID, err)
+		response.InternalServerError(c, "Failed to notify subscribers")
+		return
+	}
+	if err != nil {
+		// Those notified are marked; sending again only retries the rest
+		log.Printf("⚠️  Back-in-stock notifications for product %s partly failed: %v", productID, err)
+		response.BadGateway(c, fmt.Sprintf("Notified %d of %d subscribers; the rest failed", result.Notified, result.Selected))
+		return
+	}
+
+	if !authctx.Get(c).HasPermission(domain.PermissionCustomersPII) {
+		for i := range result.Recipients {
+			result.Recipients[i].Email = domain.MaskEmail(result.Recipients[i].Email)
+		}
+	}
+	message := fmt.Sprintf("Notified %d of %d subscribers", result.Notified, result.Selected)
+	if result.DryRun {
+		message = fmt.Sprintf("Dry run: %d of %d waiting subscribers would be notified", result.Selected, result.Waiting)
+	}
+	response.OK(c, message, result)
 }
@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/authctx"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubProductNotifier returns result and err, recording what it was asked
+type stubProductNotifier struct {
+	result *domain.BackInStockNotifyResult
+	err    error
+
+	productID     uuid.UUID
+	variantID     *uuid.UUID
+	stockQuantity int
+	limit         int
+	dryRun        bool
+}
+
+func (n *stubProductNotifier) NotifyProduct(_ context.Context, productID uuid.UUID, variantID *uuid.UUID, stockQuantity, limit int, dryRun bool) (*domain.BackInStockNotifyResult, error) {
+	n.productID, n.variantID, n.stockQuantity, n.limit, n.dryRun = productID, variantID, stockQuantity, limit, dryRun
+	return n.result, n.err
+}
+
+// notifyProduct posts body to the notify-now endpoint as an admin with
+// permissions
+func notifyProduct(t *testing.T, notifier BackInStockProductNotifier, productID uuid.UUID, body string, permissions ...string) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	handler := NewAdminBackInStockHandler(nil)
+	if notifier != nil {
+		handler.WithProductNotifier(notifier)
+	}
+	router := gin.New()
+	router.POST("/admin/back-in-stock/products/:productId/notify", func(c *gin.Context) {
+		authctx.Set(c, &authctx.Principal{ID: uuid.New(), Role: "MANAGER", Permissions: permissions})
+	}, handler.NotifyProduct)
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/admin/back-in-stock/products/"+productID.String()+"/notify", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func dryRunResult() *domain.BackInStockNotifyResult {
+	customerID := uuid.New()
+	return &domain.BackInStockNotifyResult{
+		Waiting:  3,
+		Selected: 2,
+		DryRun:   true,
+		Recipients: []domain.BackInStockRecipient{
+			{SubscriptionID: uuid.New(), Subscriber: "customer", CustomerID: &customerID, Email: "aisyah.rahman@example.com"},
+			{SubscriptionID: uuid.New(), Subscriber: "guest", Email: "farid.guest@example.com"},
+		},
+	}
+}
+
+func decodeNotifyResult(t *testing.T, w *httptest.ResponseRecorder) domain.BackInStockNotifyResult {
+	t.Helper()
+	var body struct {
+		Data domain.BackInStockNotifyResult `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	return body.Data
+}
+
+func TestAdminBackInStockHandler_NotifyProductDryRun(t *testing.T) {
+	notifier := &stubProductNotifier{result: dryRunResult()}
+	productID, variantID := uuid.New(), uuid.New()
+
+	w := notifyProduct(t, notifier, productID, `{"variant_id": "`+variantID.String()+`", "limit": 2, "dry_run": true}`,
+		domain.PermissionBackInStockManage)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	assert.Equal(t, productID, notifier.productID)
+	require.NotNil(t, notifier.variantID)
+	assert.Equal(t, variantID, *notifier.variantID)
+	assert.Equal(t, 2, notifier.limit)
+	assert.True(t, notifier.dryRun)
+	assert.Equal(t, 1, notifier.stockQuantity, "stock quantity defaults to 1")
+
+	result := decodeNotifyResult(t, w)
+	assert.True(t, result.DryRun)
+	assert.Equal(t, 2, result.Selected)
+	require.Len(t, result.Recipients, 2)
+	assert.Equal(t, domain.MaskEmail("aisyah.rahman@example.com"), result.Recipients[0].Email)
+	assert.Equal(t, domain.MaskEmail("farid.guest@example.com"), result.Recipients[1].Email)
+	assert.NotEqual(t, "aisyah.rahman@example.com", result.Recipients[0].Email)
+}
+
+func TestAdminBackInStockHandler_NotifyProductShowsEmailsWithPIIPermission(t *testing.T) {
+	notifier := &stubProductNotifier{result: dryRunResult()}
+
+	w := notifyProduct(t, notifier, uuid.New(), `{"dry_run": true}`,
+		domain.PermissionBackInStockManage, domain.PermissionCustomersPII)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	result := decodeNotifyResult(t, w)
+	require.Len(t, result.Recipients, 2)
+	assert.Equal(t, "aisyah.rahman@example.com", result.Recipients[0].Email)
+	assert.Equal(t, "farid.guest@example.com", result.Recipients[1].Email)
+}
+
+func TestAdminBackInStockHandler_NotifyProductErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		notifier BackInStockProductNotifier
+		body     string
+		want     int
+	}{
+		{"negative limit", &stubProductNotifier{}, `{"limit": -1}`, http.StatusBadRequest},
+		{"invalid variant", &stubProductNotifier{}, `{"variant_id": "nope"}`, http.StatusBadRequest},
+		{"sending not configured", nil, `{}`, http.StatusServiceUnavailable},
+		{"lookup failed", &stubProductNotifier{err: errors.New("db down")}, `{}`, http.StatusInternalServerError},
+		{
+			"some sends failed",
+			&stubProductNotifier{result: &domain.BackInStockNotifyResult{Waiting: 3, Selected: 3, Notified: 2}, err: errors.New("1 of 3 failed")},
+			`{}`,
+			http.StatusBadGateway,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := notifyProduct(t, tt.notifier, uuid.New(), tt.body, domain.PermissionBackInStockManage)
+			assert.Equal(t, tt.want, w.Code, w.Body.String())
+		})
+	}
+}
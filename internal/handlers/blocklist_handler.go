@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/authctx"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/persistence"
+	"github.com/Ecom-micro-template/service-customer/internal/response"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// AdminBlocklistHandler manages the emails, phone numbers and addresses
+// flagged as fraudulent. Blocking a customer flags theirs automatically.
+type AdminBlocklistHandler struct {
+	repo   *persistence.BlocklistRepository
+	logger *zap.Logger
+}
+
+// NewAdminBlocklistHandler creates a new blocklist handler
+func NewAdminBlocklistHandler(db *gorm.DB, logger *zap.Logger) *AdminBlocklistHandler {
+	return &AdminBlocklistHandler{
+		repo:   persistence.NewBlocklistRepository(db),
+		logger: logger,
+	}
+}
+
+// ListBlocklist handles GET /admin/blocklist
+// Query: kind (email, phone or address), include_expired, page, limit
+func (h *AdminBlocklistHandler) ListBlocklist(c *gin.Context) {
+	filter := domain.BlocklistFilter{Kind: c.Query("kind")}
+	switch filter.Kind {
+	case "", domain.BlocklistKindEmail, domain.BlocklistKindPhone, domain.BlocklistKindAddress:
+	default:
+		response.BadRequest(c, "kind must be email, phone or address", nil)
+		return
+	}
+	filter.IncludeExpired, _ = strconv.ParseBool(c.DefaultQuery("include_expired", "false"))
+	filter.Page, _ = strconv.Atoi(c.DefaultQuery("page", "1"))
+	filter.Limit, _ = strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if filter.Page < 1 {
+		filter.Page = 1
+	}
+	if filter.Limit < 1 || filter.Limit > 100 {
+		filter.Limit = 20
+	}
+
+	entries, total, err := h.repo.List(c.Request.Context(), filter, time.Now())
+	if err != nil {
+		h.logger.Error("Failed to list blocklist", zap.Error(err))
+		response.InternalServerError(c, "Failed to retrieve blocklist")
+		return
+	}
+
+	response.Paginated(c, entries, filter.Page, filter.Limit, total)
+}
+
+// CreateBlocklistEntry handles POST /admin/blocklist
+// Flagging a value that is already on the blocklist replaces its reason and
+// expiry.
+func (h *AdminBlocklistHandler) CreateBlocklistEntry(c *gin.Context) {
+	var req domain.CreateBlocklistEntryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Invalid(c, err)
+		return
+	}
+
+	entry, err := req.Entry(time.Now())
+	if err != nil {
+		response.FromError(c, err, "", "Failed to add blocklist entry")
+		return
+	}
+	adminID := authctx.UserID(c)
+	if adminID != uuid.Nil {
+		entry.CreatedBy = &adminID
+	}
+
+	if err := h.repo.Save(c.Request.Context(), entry); err != nil {
+		h.logger.Error("Failed to add blocklist entry", zap.String("kind", entry.Kind), zap.Error(err))
+		response.InternalServerError(c, "Failed to add blocklist entry")
+		return
+	}
+
+	response.Created(c, "Blocklist entry added", entry)
+}
+
+// DeleteBlocklistEntry handles DELETE /admin/blocklist/:entryId
+func (h *AdminBlocklistHandler) DeleteBlocklistEntry(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("entryId"))
+	if err != nil {
+		response.BadRequest(c, "Invalid blocklist entry ID", nil)
+		return
+	}
+
+	if err := h.repo.Delete(c.Request.Context(), id); err != nil {
+		response.FromError(c, err, response.CodeBlocklistEntryNotFound, "Failed to remove blocklist entry")
+		return
+	}
+
+	response.Deleted(c, "Blocklist entry removed")
+}
+
+// InternalFraudCheckHandler lets the order service check a buyer against
+// the blocklist before accepting an order
+type InternalFraudCheckHandler struct {
+	repo *persistence.BlocklistRepository
+}
+
+// NewInternalFraudCheckHandler creates a new internal fraud check handler
+func NewInternalFraudCheckHandler(db *gorm.DB) *InternalFraudCheckHandler {
+	return &InternalFraudCheckHandler{
+		repo: persistence.NewBlocklistRepository(db),
+	}
+}
+
+// Check reports whether an order's customer, email, phone or addresses are
+// blocklisted
+// POST /internal/v1/customers/fraud-check
+func (h *InternalFraudCheckHandler) Check(c *gin.Context) {
+	var req domain.FraudCheckRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Invalid(c, err)
+		return
+	}
+
+	result, err := h.repo.Check(c.Request.Context(), &req, time.Now())
+	if err != nil {
+		response.InternalServerError(c, "Failed to check blocklist")
+		return
+	}
+
+	response.OK(c, "", result)
+}
@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/persistence"
+	"github.com/Ecom-micro-template/service-customer/internal/response"
+	"gorm.io/gorm"
+)
+
+// InternalCustomerLookupHandler lets other services read customers
+type InternalCustomerLookupHandler struct {
+	repo *persistence.CustomerLookupRepository
+}
+
+// NewInternalCustomerLookupHandler creates a new internal customer lookup handler
+func NewInternalCustomerLookupHandler(db *gorm.DB) *InternalCustomerLookupHandler {
+	return &InternalCustomerLookupHandler{
+		repo: persistence.NewCustomerLookupRepository(db),
+	}
+}
+
+// GetBatch returns up to domain.MaxCustomerBatchSize customers by ID
+// POST /internal/v1/customers/batch
+func (h *InternalCustomerLookupHandler) GetBatch(c *gin.Context) {
+	var req domain.CustomerBatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Invalid(c, err)
+		return
+	}
+
+	result, err := h.repo.GetBatch(c.Request.Context(), req.IDs)
+	if err != nil {
+		response.InternalServerError(c, "Failed to retrieve customers")
+		return
+	}
+
+	response.OK(c, "", result)
+}
+
+// Birthdays returns the customers whose birthday is on ?date= (YYYY-MM-DD,
+// default today in domain.DefaultNotificationTimezone)
+// Query: date, limit, cursor. Pass the returned next_cursor as cursor to get the next page.
+// GET /internal/v1/customers/birthdays
+func (h *InternalCustomerLookupHandler) Birthdays(c *gin.Context) {
+	date := time.Now()
+	if loc, err := time.LoadLocation(domain.DefaultNotificationTimezone); err == nil {
+		date = date.In(loc)
+	}
+	if raw := c.Query("date"); raw != "" {
+		var err error
+		if date, err = time.Parse(time.DateOnly, raw); err != nil {
+			response.BadRequest(c, "date must be formatted as YYYY-MM-DD", nil)
+			return
+		}
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(domain.DefaultBirthdayFeedLimit)))
+	if limit < 1 || limit > domain.MaxBirthdayFeedLimit {
+		limit = domain.DefaultBirthdayFeedLimit
+	}
+	after, _, err := cursorQuery(c.Request.URL.Query())
+	if err != nil {
+		response.Fail(c, response.CodeInvalidCursor, err.Error())
+		return
+	}
+
+	birthdays, next, err := h.repo.Birthdays(c.Request.Context(), date, after, limit)
+	if err != nil {
+		response.InternalServerError(c, "Failed to retrieve birthdays")
+		return
+	}
+
+	response.CursorPaginated(c, "", birthdays, limit, next)
+}
@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/Ecom-micro-template/service-customer/internal/authctx"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	addressdomain "github.com/Ecom-micro-template/service-customer/internal/domain/address"
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/persistence"
+	"github.com/Ecom-micro-template/service-customer/internal/middleware"
+	"github.com/Ecom-micro-template/service-customer/internal/response"
+	"gorm.io/gorm"
+)
+
+// maxDataImportSize bounds the size of an uploaded data export
+const maxDataImportSize = 5 << 20
+
+// DataPortabilityHandler lets customers export their addresses, wishlist and
+// measurements and restore such an export into another account
+type DataPortabilityHandler struct {
+	repo      *persistence.DataPortabilityRepository
+	countries addressdomain.CountryPolicy
+	limits    *persistence.CustomerLimitRepository
+}
+
+// NewDataPortabilityHandler creates a new data portability handler
+func NewDataPortabilityHandler(db *gorm.DB) *DataPortabilityHandler {
+	return &DataPortabilityHandler{
+		repo:   persistence.NewDataPortabilityRepository(db),
+		limits: persistence.NewCustomerLimitRepository(db, domain.DefaultCustomerLimits()),
+	}
+}
+
+// WithMirror mirrors imported addresses, e.g. to the legacy CRM during migration
+func (h *DataPortabilityHandler) WithMirror(mirror persistence.CustomerMirror) *DataPortabilityHandler {
+	h.repo.WithMirror(mirror)
+	return h
+}
+
+// WithWishlistEvents reports imported wishlist items, e.g. for analytics
+func (h *DataPortabilityHandler) WithWishlistEvents(events persistence.WishlistEvents) *DataPortabilityHandler {
+	h.repo.WithWishlistEvents(events)
+	return h
+}
+
+// WithCountryPolicy sets the countries imported addresses may be in
+func (h *DataPortabilityHandler) WithCountryPolicy(policy addressdomain.CountryPolicy) *DataPortabilityHandler {
+	h.countries = policy
+	return h
+}
+
+// WithLimits sets the per-customer caps imports must respect
+func (h *DataPortabilityHandler) WithLimits(limits *persistence.CustomerLimitRepository) *DataPortabilityHandler {
+	h.limits = limits
+	return h
+}
+
+// ExportData downloads the customer's addresses, wishlist and measurements
+// in the format accepted by ImportData
+// GET /api/v1/customer/data-export
+func (h *DataPortabilityHandler) ExportData(c *gin.Context) {
+	userID, ok := authctx.AuthenticatedUserID(c)
+	if !ok {
+		return
+	}
+
+	export, err := h.repo.Export(c.Request.Context(), userID)
+	if err != nil {
+		log.Printf("⚠️  Failed to export data for customer %s: %v", userID, err)
+		response.InternalServerError(c, "Failed to export data")
+		return
+	}
+
+	filename := fmt.Sprintf("customer-data-%s.json", export.ExportedAt.Format("20060102"))
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	c.JSON(http.StatusOK, export)
+}
+
+// ImportData restores a data export into the customer's account. Records
+// the account already has are skipped as duplicates, and invalid records or
+// ones past the account limits are reported rather than failing the import.
+// With dry_run=true nothing is written and the response previews the outcome.
+// POST /api/v1/customer/data-import
+func (h *DataPortabilityHandler) ImportData(c *gin.Context) {
+	userID, ok := authctx.AuthenticatedUserID(c)
+	if !ok {
+		return
+	}
+	dryRun, err := strconv.ParseBool(c.DefaultQuery("dry_run", "false"))
+	if err != nil {
+		response.BadRequest(c, "dry_run must be true or false", nil)
+		return
+	}
+
+	var export domain.CustomerDataExport
+	if err := json.NewDecoder(io.LimitReader(c.Request.Body, maxDataImportSize)).Decode(&export); err != nil {
+		response.BadRequest(c, "Invalid data export: "+err.Error(), nil)
+		return
+	}
+	if err := export.Validate(); err != nil {
+		response.Fail(c, response.CodeUnsupportedDataExport, err.Error())
+		return
+	}
+
+	limits, err := h.limits.For(c.Request.Context(), userID)
+	if err != nil {
+		response.InternalServerError(c, "Failed to import data")
+		return
+	}
+
+	summary, err := h.repo.Import(c.Request.Context(), userID, &export, domain.DataImportOptions{
+		Countries: h.countries,
+		Limits:    limits,
+	}, dryRun)
+	if err != nil {
+		log.Printf("⚠️  Failed to import data for customer %s: %v", userID, err)
+		response.InternalServerError(c, "Failed to import data")
+		return
+	}
+
+	message := "Data imported"
+	if dryRun {
+		message = "Data import preview"
+		middleware.SkipActivity(c)
+	} else {
+		middleware.SetActivityDetails(c, fmt.Sprintf("%d addresses, %d wishlist items, %d measurements (exported %s)",
+			summary.Addresses.Added, summary.Wishlist.Added, summary.Measurements.Added,
+			export.ExportedAt.UTC().Format(time.DateOnly)))
+	}
+	response.OK(c, message, summary)
+}
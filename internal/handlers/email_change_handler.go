@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/url"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/Ecom-micro-template/service-customer/internal/authctx"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/Ecom-micro-template/service-customer/internal/domain/shared"
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/persistence"
+	"github.com/Ecom-micro-template/service-customer/internal/response"
+	"gorm.io/gorm"
+)
+
+// EmailChangeHandler handles changes of a customer's email, which only take
+// effect once the new address is verified
+type EmailChangeHandler struct {
+	repo      *persistence.EmailChangeRepository
+	verifyURL string
+	sender    EmailChangeSender
+}
+
+// EmailChangeSender emails the verification link to the new address and the
+// security notice to the old one
+type EmailChangeSender interface {
+	SendEmailChangeVerification(ctx context.Context, verification domain.EmailChangeVerification) error
+	SendEmailChangeNotice(ctx context.Context, changeID string, notice domain.EmailChangeNotice) error
+}
+
+// NewEmailChangeHandler creates a new email change handler. verifyURL is the
+// storefront page the verification email links to; the token is appended as
+// the "token" query parameter.
+func NewEmailChangeHandler(db *gorm.DB, verifyURL string) *EmailChangeHandler {
+	return &EmailChangeHandler{
+		repo:      persistence.NewEmailChangeRepository(db),
+		verifyURL: verifyURL,
+	}
+}
+
+// WithSender sets how verification emails and notices are sent
+func (h *EmailChangeHandler) WithSender(sender EmailChangeSender) *EmailChangeHandler {
+	h.sender = sender
+	return h
+}
+
+// Request starts a change of email and emails a verification link to the new address
+// POST /api/v1/customer/email-change
+func (h *EmailChangeHandler) Request(c *gin.Context) {
+	userID, ok := authctx.AuthenticatedUserID(c)
+	if !ok {
+		return
+	}
+
+	var input domain.RequestEmailChangeInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		response.Invalid(c, err)
+		return
+	}
+
+	change, token, err := h.repo.Request(c.Request.Context(), userID, input.Email)
+	if err != nil {
+		response.FromError(c, err, response.CodeNotFound, "Failed to request email change")
+		return
+	}
+
+	if err := h.sendVerification(c.Request.Context(), change, token); err != nil {
+		log.Printf("⚠️  Failed to send email change verification for user %s: %v", userID, err)
+		response.InternalServerError(c, "Failed to send verification email")
+		return
+	}
+
+	response.Accepted(c, "Check your new email address to confirm the change")
+}
+
+// Confirm applies the change once the new address is verified and notifies the old one
+// POST /api/v1/customer/email-change/confirm
+func (h *EmailChangeHandler) Confirm(c *gin.Context) {
+	userID, ok := authctx.AuthenticatedUserID(c)
+	if !ok {
+		return
+	}
+
+	var input domain.ConfirmEmailChangeInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		response.Invalid(c, err)
+		return
+	}
+
+	change, profile, err := h.repo.Confirm(c.Request.Context(), userID, input.Token)
+	if err != nil {
+		response.FromError(c, err, "", "Failed to change email")
+		return
+	}
+
+	// The change is applied; a lost notice must not fail the request
+	if change.OldEmail != "" {
+		if err := h.sendNotice(c.Request.Context(), change); err != nil {
+			log.Printf("⚠️  Failed to send email change notice for user %s: %v", userID, err)
+		}
+	}
+
+	setVersionETag(c, profile.Version)
+	response.OK(c, "Email changed successfully", profile)
+}
+
+func (h *EmailChangeHandler) sendVerification(ctx context.Context, change *domain.EmailChange, token string) error {
+	if h.sender == nil {
+		return errors.New("no email change sender configured")
+	}
+
+	link, err := url.Parse(h.verifyURL)
+	if err != nil {
+		return err
+	}
+	query := link.Query()
+	query.Set("token", token)
+	link.RawQuery = query.Encode()
+
+	return h.sender.SendEmailChangeVerification(ctx, domain.EmailChangeVerification{
+		Email:     change.NewEmail,
+		VerifyURL: link.String(),
+		ExpiresAt: change.ExpiresAt,
+	})
+}
+
+func (h *EmailChangeHandler) sendNotice(ctx context.Context, change *domain.EmailChange) error {
+	if h.sender == nil {
+		return errors.New("no email change sender configured")
+	}
+
+	newEmail := change.NewEmail
+	if email, err := shared.NewEmail(change.NewEmail); err == nil {
+		newEmail = email.MaskedEmail()
+	}
+	return h.sender.SendEmailChangeNotice(ctx, change.ID.String(), domain.EmailChangeNotice{
+		Email:     change.OldEmail,
+		NewEmail:  newEmail,
+		ChangedAt: time.Now(),
+	})
+}
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	graphql "github.com/graph-gophers/graphql-go"
+	gqlerrors "github.com/graph-gophers/graphql-go/errors"
+	"github.com/Ecom-micro-template/service-customer/internal/authctx"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/Ecom-micro-template/service-customer/internal/graph"
+	"github.com/Ecom-micro-template/service-customer/internal/middleware"
+	"github.com/Ecom-micro-template/service-customer/internal/response"
+)
+
+// GraphQLHandler serves the customer account graph
+type GraphQLHandler struct {
+	schema *graphql.Schema
+}
+
+// NewGraphQLHandler creates a new GraphQL handler
+func NewGraphQLHandler(schema *graphql.Schema) *GraphQLHandler {
+	return &GraphQLHandler{schema: schema}
+}
+
+// GraphQLRequest is a GraphQL query. On GET, variables is a JSON-encoded
+// query parameter.
+type GraphQLRequest struct {
+	Query         string                 `json:"query" binding:"required"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// GraphQLResponse is the result of a query; data is null when the query
+// itself is invalid
+type GraphQLResponse struct {
+	Data   json.RawMessage         `json:"data,omitempty"`
+	Errors []*gqlerrors.QueryError `json:"errors,omitempty"`
+}
+
+// Query handles GET and POST /api/v1/graphql
+// GET lets read-only impersonation sessions query the graph too. Errors in
+// the query itself are returned in the errors array with status 200, as
+// GraphQL clients expect.
+func (h *GraphQLHandler) Query(c *gin.Context) {
+	userID, ok := authctx.AuthenticatedUserID(c)
+	if !ok {
+		return
+	}
+
+	var req GraphQLRequest
+	if c.Request.Method == http.MethodGet {
+		req.Query = c.Query("query")
+		req.OperationName = c.Query("operationName")
+		if variables := c.Query("variables"); variables != "" {
+			if err := json.Unmarshal([]byte(variables), &req.Variables); err != nil {
+				response.BadRequest(c, "variables must be a JSON object", nil)
+				return
+			}
+		}
+		if req.Query == "" {
+			response.BadRequest(c, "query is required", nil)
+			return
+		}
+	} else if err := c.ShouldBindJSON(&req); err != nil {
+		response.Invalid(c, err)
+		return
+	}
+
+	// Staff see other customers and staff-only fields with their own token
+	// only, never while impersonating a customer
+	_, impersonating := middleware.GetImpersonation(c)
+	viewer := graph.Viewer{
+		CustomerID: userID,
+		Staff:      authctx.Get(c).HasPermission(domain.PermissionCustomersRead) && !impersonating,
+	}
+
+	result := h.schema.Exec(graph.WithViewer(c.Request.Context(), viewer), req.Query, req.OperationName, req.Variables)
+	c.JSON(http.StatusOK, GraphQLResponse{Data: result.Data, Errors: result.Errors})
+}
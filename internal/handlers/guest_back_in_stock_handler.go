@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/url"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/persistence"
+	"github.com/Ecom-micro-template/service-customer/internal/response"
+	"gorm.io/gorm"
+)
+
+// GuestBackInStockHandler handles back-in-stock subscriptions from visitors without an account
+type GuestBackInStockHandler struct {
+	repo       *persistence.GuestBackInStockRepository
+	confirmURL string
+	sender     BackInStockConfirmationSender
+}
+
+// BackInStockConfirmationSender emails guests the link to confirm their subscription
+type BackInStockConfirmationSender interface {
+	SendBackInStockConfirmation(ctx context.Context, confirmation domain.BackInStockConfirmation) error
+}
+
+// NewGuestBackInStockHandler creates a new guest back-in-stock handler.
+// confirmURL is the storefront page the confirmation email links to; the
+// token is appended as the "token" query parameter.
+func NewGuestBackInStockHandler(db *gorm.DB, confirmURL string) *GuestBackInStockHandler {
+	return &GuestBackInStockHandler{
+		repo:       persistence.NewGuestBackInStockRepository(db),
+		confirmURL: confirmURL,
+	}
+}
+
+// WithConfirmationSender sets how confirmation emails are sent
+func (h *GuestBackInStockHandler) WithConfirmationSender(sender BackInStockConfirmationSender) *GuestBackInStockHandler {
+	h.sender = sender
+	return h
+}
+
+// WithSubscriptionTTL sets how long subscriptions stay active before expiring
+func (h *GuestBackInStockHandler) WithSubscriptionTTL(ttl time.Duration) *GuestBackInStockHandler {
+	h.repo.WithSubscriptionTTL(ttl)
+	return h
+}
+
+// Subscribe creates a guest subscription and emails a confirmation link
+// POST /api/v1/public/back-in-stock
+func (h *GuestBackInStockHandler) Subscribe(c *gin.Context) {
+	var input domain.GuestBackInStockSubscribeInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		response.Invalid(c, err)
+		return
+	}
+	if _, err := uuid.Parse(input.ProductID); err != nil {
+		response.BadRequest(c, "Invalid product ID", nil)
+		return
+	}
+	if input.VariantID != "" {
+		if _, err := uuid.Parse(input.VariantID); err != nil {
+			response.BadRequest(c, "Invalid variant ID", nil)
+			return
+		}
+	}
+
+	subscription, token, err := h.repo.Subscribe(c.Request.Context(), input)
+	if err != nil {
+		if errors.Is(err, domain.ErrGuestSubscriptionLimit) {
+			response.Fail(c, response.CodeGuestSubscriptionLimit, "Too many back-in-stock subscriptions for this email")
+			return
+		}
+		response.InternalServerError(c, "Failed to subscribe")
+		return
+	}
+
+	if token != "" {
+		if err := h.sendConfirmation(c.Request.Context(), subscription, token); err != nil {
+			log.Printf("⚠️  Failed to send back-in-stock confirmation for subscription %s: %v", subscription.ID, err)
+			response.InternalServerError(c, "Failed to send confirmation email")
+			return
+		}
+	}
+
+	// Same response whether or not the email was already subscribed
+	response.Accepted(c, "Check your email to confirm the back-in-stock notification")
+}
+
+// Confirm confirms a guest subscription from the emailed link
+// GET /api/v1/public/back-in-stock/confirm?token=...
+func (h *GuestBackInStockHandler) Confirm(c *gin.Context) {
+	token := c.Query("token")
+	if token == "" {
+		response.BadRequest(c, "Confirmation token is required", nil)
+		return
+	}
+
+	subscription, err := h.repo.Confirm(c.Request.Context(), token)
+	if err != nil {
+		if errors.Is(err, domain.ErrGuestTokenInvalid) {
+			response.Fail(c, response.CodeGuestTokenInvalid, "Invalid or expired confirmation link")
+			return
+		}
+		response.InternalServerError(c, "Failed to confirm subscription")
+		return
+	}
+
+	response.OK(c, "You will be notified when the product is back in stock", subscription)
+}
+
+func (h *GuestBackInStockHandler) sendConfirmation(ctx context.Context, subscription *domain.GuestBackInStockSubscription, token string) error {
+	if h.sender == nil {
+		return errors.New("no confirmation sender configured")
+	}
+
+	link, err := url.Parse(h.confirmURL)
+	if err != nil {
+		return err
+	}
+	query := link.Query()
+	query.Set("token", token)
+	link.RawQuery = query.Encode()
+
+	return h.sender.SendBackInStockConfirmation(ctx, domain.BackInStockConfirmation{
+		Email:       subscription.Email,
+		ProductName: subscription.ProductName,
+		VariantName: subscription.VariantName,
+		ConfirmURL:  link.String(),
+		ExpiresAt:   subscription.TokenExpiresAt,
+	})
+}
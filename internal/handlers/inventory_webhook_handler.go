@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"context"
+	"crypto/hmac"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/Ecom-micro-template/service-customer/internal/events"
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/persistence"
+	"github.com/Ecom-micro-template/service-customer/internal/response"
+	"go.uber.org/zap"
+)
+
+// Inventory webhook request headers
+const (
+	HeaderWebhookTimestamp = "X-Webhook-Timestamp" // unix seconds
+	HeaderWebhookSignature = "X-Webhook-Signature" // see domain.WebhookSignature
+)
+
+const (
+	// maxWebhookBody bounds an inventory webhook body
+	maxWebhookBody = 1 << 20
+	// webhookDeliveryRetention is how long event IDs are remembered, so a
+	// sender retrying a delivered event within a day doesn't notify twice
+	webhookDeliveryRetention = 24 * time.Hour
+	// defaultWebhookTolerance is the allowed clock skew when none is configured
+	defaultWebhookTolerance = 5 * time.Minute
+)
+
+// RestockProcessor runs the back-in-stock notifications for a restock
+type RestockProcessor interface {
+	ProcessRestock(ctx context.Context, event events.ProductRestockedEvent) error
+}
+
+// InventoryWebhookHandler receives restock alerts from inventory sources that
+// can't publish to NATS and feeds them into the same back-in-stock path
+type InventoryWebhookHandler struct {
+	deliveries *persistence.WebhookDeliveryRepository
+	restocks   RestockProcessor
+	secret     []byte
+	tolerance  time.Duration
+	logger     *zap.Logger
+}
+
+// NewInventoryWebhookHandler creates a new inventory webhook handler. Requests
+// are rejected until a secret is configured.
+func NewInventoryWebhookHandler(deliveries *persistence.WebhookDeliveryRepository, restocks RestockProcessor, secret string, tolerance time.Duration, logger *zap.Logger) *InventoryWebhookHandler {
+	if tolerance <= 0 {
+		tolerance = defaultWebhookTolerance
+	}
+	return &InventoryWebhookHandler{
+		deliveries: deliveries,
+		restocks:   restocks,
+		secret:     []byte(secret),
+		tolerance:  tolerance,
+		logger:     logger,
+	}
+}
+
+// Receive handles an inventory webhook
+// POST /api/v1/internal/webhooks/inventory
+// The body is signed with the shared secret (see domain.WebhookSignature) and
+// the timestamp must be within the tolerance. Each event_id is processed once;
+// replays are acknowledged without notifying anyone again. Processing errors
+// return 500 so the sender retries.
+func (h *InventoryWebhookHandler) Receive(c *gin.Context) {
+	if len(h.secret) == 0 {
+		response.ServiceUnavailable(c, "Inventory webhook is not configured")
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxWebhookBody))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			response.PayloadTooLarge(c, "Payload too large")
+			return
+		}
+		response.BadRequest(c, "Failed to read payload", nil)
+		return
+	}
+	if !h.verify(c, body) {
+		return
+	}
+
+	var payload domain.InventoryWebhookPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		response.BadRequest(c, "Invalid JSON payload", err.Error())
+		return
+	}
+	if problems := payload.Validate(); len(problems) > 0 {
+		response.Unprocessable(c, "Invalid payload", problems)
+		return
+	}
+	if payload.Event != domain.InventoryEventRestocked {
+		h.logger.Debug("Ignoring inventory webhook event", zap.String("event", payload.Event))
+		response.Done(c, http.StatusOK, "Event ignored")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+	defer cancel()
+
+	claimed, err := h.deliveries.Claim(ctx, domain.WebhookSourceInventory, payload.EventID)
+	if err != nil {
+		h.logger.Error("Failed to record inventory webhook delivery", zap.Error(err))
+		response.InternalServerError(c, "Failed to process event")
+		return
+	}
+	if !claimed {
+		h.logger.Info("Ignoring replayed inventory webhook", zap.String("event_id", payload.EventID))
+		response.Done(c, http.StatusOK, "Event already processed")
+		return
+	}
+
+	err = h.restocks.ProcessRestock(ctx, events.ProductRestockedEvent{
+		ProductID:   payload.ProductID,
+		VariantID:   payload.VariantID,
+		WarehouseID: payload.WarehouseID,
+		Quantity:    payload.Quantity,
+		ProductName: payload.ProductName,
+		ProductSlug: payload.ProductSlug,
+	})
+	if err != nil {
+		h.logger.Error("Failed to process inventory webhook restock",
+			zap.String("event_id", payload.EventID),
+			zap.Error(err))
+		// Not ctx: it may be the one that timed out
+		if err := h.deliveries.Release(context.Background(), domain.WebhookSourceInventory, payload.EventID); err != nil {
+			h.logger.Error("Failed to release inventory webhook delivery", zap.Error(err))
+		}
+		response.InternalServerError(c, "Failed to process event")
+		return
+	}
+
+	if _, err := h.deliveries.PurgeBefore(ctx, time.Now().Add(-webhookDeliveryRetention)); err != nil {
+		h.logger.Warn("Failed to purge old webhook deliveries", zap.Error(err))
+	}
+
+	response.Done(c, http.StatusOK, "Event processed")
+}
+
+// verify checks the timestamp and HMAC signature, writing a 401 if either is bad
+func (h *InventoryWebhookHandler) verify(c *gin.Context, body []byte) bool {
+	timestamp := c.GetHeader(HeaderWebhookTimestamp)
+	sent, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		response.Unauthorized(c, "Missing or invalid "+HeaderWebhookTimestamp)
+		return false
+	}
+	if skew := time.Since(time.Unix(sent, 0)); math.Abs(float64(skew)) > float64(h.tolerance) {
+		response.Unauthorized(c, "Webhook timestamp is outside the allowed window")
+		return false
+	}
+
+	expected := domain.WebhookSignature(h.secret, timestamp, body)
+	if !hmac.Equal([]byte(c.GetHeader(HeaderWebhookSignature)), []byte(expected)) {
+		response.Unauthorized(c, "Invalid webhook signature")
+		return false
+	}
+	return true
+}
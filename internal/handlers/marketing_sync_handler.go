@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"slices"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/Ecom-micro-template/service-customer/internal/authctx"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/persistence"
+	"github.com/Ecom-micro-template/service-customer/internal/response"
+	"go.uber.org/zap"
+)
+
+// MarketingConsentHandler lets customers choose which marketing they receive
+type MarketingConsentHandler struct {
+	repo *persistence.MarketingSyncRepository
+}
+
+// NewMarketingConsentHandler creates a new marketing consent handler
+func NewMarketingConsentHandler(repo *persistence.MarketingSyncRepository) *MarketingConsentHandler {
+	return &MarketingConsentHandler{repo: repo}
+}
+
+// GetConsent handles GET /api/v1/customer/marketing-consent
+func (h *MarketingConsentHandler) GetConsent(c *gin.Context) {
+	userID, ok := authctx.AuthenticatedUserID(c)
+	if !ok {
+		return
+	}
+
+	consent, err := h.repo.Consent(c.Request.Context(), userID)
+	if err != nil {
+		response.InternalServerError(c, "Failed to retrieve marketing consent")
+		return
+	}
+
+	response.OK(c, "", consent)
+}
+
+// UpdateConsent handles PUT /api/v1/customer/marketing-consent
+// The change is pushed to the marketing platforms by the sync job.
+func (h *MarketingConsentHandler) UpdateConsent(c *gin.Context) {
+	userID, ok := authctx.AuthenticatedUserID(c)
+	if !ok {
+		return
+	}
+	var req domain.UpdateMarketingConsentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Invalid(c, err)
+		return
+	}
+
+	consent, err := h.repo.UpdateConsent(c.Request.Context(), userID, &req)
+	if err != nil {
+		response.InternalServerError(c, "Failed to update marketing consent")
+		return
+	}
+
+	response.OK(c, "Marketing consent updated", consent)
+}
+
+// AdminMarketingSyncHandler shows how the marketing platform sync is doing
+// and requeues customers that failed
+type AdminMarketingSyncHandler struct {
+	repo   *persistence.MarketingSyncRepository
+	logger *zap.Logger
+}
+
+// NewAdminMarketingSyncHandler creates a new marketing sync handler
+func NewAdminMarketingSyncHandler(repo *persistence.MarketingSyncRepository, logger *zap.Logger) *AdminMarketingSyncHandler {
+	return &AdminMarketingSyncHandler{repo: repo, logger: logger}
+}
+
+// Status handles GET /admin/marketing-sync
+// Returns pending, synced and failed counts per configured platform.
+func (h *AdminMarketingSyncHandler) Status(c *gin.Context) {
+	statuses, err := h.repo.Status(c.Request.Context())
+	if err != nil {
+		h.logger.Error("Failed to get marketing sync status", zap.Error(err))
+		response.InternalServerError(c, "Failed to retrieve marketing sync status")
+		return
+	}
+
+	response.OK(c, "", statuses)
+}
+
+// ListContacts handles GET /admin/marketing-sync/:provider/contacts
+// Query: status (pending, synced or failed), page, limit
+func (h *AdminMarketingSyncHandler) ListContacts(c *gin.Context) {
+	provider, ok := h.provider(c)
+	if !ok {
+		return
+	}
+	status := c.Query("status")
+	switch status {
+	case "", domain.MarketingSyncPending, domain.MarketingSyncSynced, domain.MarketingSyncFailed:
+	default:
+		response.BadRequest(c, "status must be pending, synced or failed", nil)
+		return
+	}
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	states, total, err := h.repo.States(c.Request.Context(), provider, status, page, limit)
+	if err != nil {
+		h.logger.Error("Failed to list marketing sync contacts", zap.Error(err))
+		response.InternalServerError(c, "Failed to retrieve contacts")
+		return
+	}
+
+	response.Paginated(c, states, page, limit, total)
+}
+
+// Resync handles POST /admin/marketing-sync/:provider/resync
+// Queues the given customers, or every failed one, to be pushed again.
+func (h *AdminMarketingSyncHandler) Resync(c *gin.Context) {
+	provider, ok := h.provider(c)
+	if !ok {
+		return
+	}
+	var req domain.ResyncMarketingRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.Invalid(c, err)
+			return
+		}
+	}
+
+	queued, err := h.repo.Resync(c.Request.Context(), provider, req.CustomerIDs)
+	if err != nil {
+		h.logger.Error("Failed to queue marketing resync", zap.String("provider", provider), zap.Error(err))
+		response.InternalServerError(c, "Failed to queue resync")
+		return
+	}
+
+	response.OK(c, "Resync queued", MarketingResyncResult{Queued: queued})
+}
+
+// MarketingResyncResult is the number of customers queued by a resync
+type MarketingResyncResult struct {
+	Queued int64 `json:"queued"`
+}
+
+func (h *AdminMarketingSyncHandler) provider(c *gin.Context) (string, bool) {
+	provider := c.Param("provider")
+	if !slices.Contains(h.repo.Providers(), provider) {
+		response.Fail(c, response.CodeMarketingSyncDisabled, "Marketing sync is not configured for "+provider)
+		return "", false
+	}
+	return provider, true
+}
@@ -1,30 +1,57 @@
 package handlers
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/authctx"
 	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/Ecom-micro-template/service-customer/internal/domain/shared"
 	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/persistence"
+	"github.com/Ecom-micro-template/service-customer/internal/response"
 	"gorm.io/gorm"
 )
 
 // MeasurementHandler handles customer measurement-related requests
 type MeasurementHandler struct {
-	repo *persistence.MeasurementRepository
+	repo              *persistence.MeasurementRepository
+	sizeDriftNotifier SizeDriftNotifier
+}
+
+// SizeDriftNotifier tells a customer that their derived standard size changed,
+// so previously recommended sizes may no longer fit
+type SizeDriftNotifier interface {
+	NotifySizeChanged(ctx context.Context, measurement *domain.CustomerMeasurement, previousSize string) error
 }
 
 // NewMeasurementHandler creates a new measurement handler
 func NewMeasurementHandler(db *gorm.DB) *MeasurementHandler {
 	return &MeasurementHandler{
-		repo: persistence.NewMeasurementRepository(db),
+		repo: persistence.NewMeasurementRepository(db).WithLimits(persistence.NewCustomerLimitRepository(db, domain.DefaultCustomerLimits())),
 	}
 }
 
+// WithLimits sets the per-customer caps, including how many measurements a customer can store
+func (h *MeasurementHandler) WithLimits(limits *persistence.CustomerLimitRepository) *MeasurementHandler {
+	h.repo.WithLimits(limits)
+	return h
+}
+
+// WithSizeDriftNotifier enables customer notifications when a derived size changes
+func (h *MeasurementHandler) WithSizeDriftNotifier(notifier SizeDriftNotifier) *MeasurementHandler {
+	h.sizeDriftNotifier = notifier
+	return h
+}
+
 // CreateMeasurementRequest represents the request body
 type CreateMeasurementRequest struct {
 	Name          *string  `json:"name"`
+	ProfilePerson *string  `json:"profile_person" binding:"omitempty,max=50"` // "self" (default) or a family member label
 	Gender        string   `json:"gender" binding:"required,oneof=men women"`
 	Bust          *float64 `json:"bust"`
 	Chest         *float64 `json:"chest"`
@@ -39,28 +66,46 @@ type CreateMeasurementRequest struct {
 	Wrist         *float64 `json:"wrist"`
 	Height        *float64 `json:"height"`
 	Weight        *float64 `json:"weight"`
+	Unit          string   `json:"unit" binding:"omitempty,oneof=cm inch"` // unit of the lengths above, defaults to cm
 	Notes         *string  `json:"notes"`
 	IsDefault     *bool    `json:"is_default"`
 }
 
+// UpdateMeasurementRequest represents the request body for updating a
+// measurement
+type UpdateMeasurementRequest struct {
+	CreateMeasurementRequest
+	Version *int64 `json:"version"` // optimistic locking; If-Match takes precedence
+}
+
+// MeasurementList is the payload of a measurement listing
+type MeasurementList struct {
+	Measurements []domain.CustomerMeasurement `json:"measurements"`
+	Count        int                          `json:"count"`
+}
+
+// MeasurementProfiles is the payload of the listing of the people measured,
+// with the most a customer may keep
+type MeasurementProfiles struct {
+	Profiles []string `json:"profiles"`
+	Count    int      `json:"count"`
+	Limit    int      `json:"limit"`
+}
+
 // Create handles measurement creation
 func (h *MeasurementHandler) Create(c *gin.Context) {
-	// TODO: Get user ID from auth context
-	userIDStr := c.GetHeader("X-User-ID")
-	if userIDStr == "" {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
-		return
-	}
-
-	userID, err := uuid.Parse(userIDStr)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+	userID, ok := authctx.AuthenticatedUserID(c)
+	if !ok {
 		return
 	}
 
 	var req CreateMeasurementRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		response.Invalid(c, err)
+		return
+	}
+	displayUnit, ok := requestedUnit(c)
+	if !ok {
 		return
 	}
 
@@ -69,10 +114,19 @@ func (h *MeasurementHandler) Create(c *gin.Context) {
 		isDefault = *req.IsDefault
 	}
 
+	person := domain.MeasurementProfileSelf
+	if req.ProfilePerson != nil {
+		person = domain.NormalizeProfilePerson(*req.ProfilePerson)
+	}
+	if !h.checkProfileLimit(c, userID, person) {
+		return
+	}
+
 	measurement := &domain.CustomerMeasurement{
 		UserID:        userID,
 		Name:          req.Name,
-		Gender:        req.Gender,
+		ProfilePerson: person,
+		Gender:        shared.Gender(req.Gender),
 		Bust:          req.Bust,
 		Chest:         req.Chest,
 		Waist:         req.Waist,
@@ -89,42 +143,38 @@ func (h *MeasurementHandler) Create(c *gin.Context) {
 		Notes:         req.Notes,
 		IsDefault:     isDefault,
 	}
+	unit := req.Unit
+	if unit == "" {
+		unit = domain.MeasurementUnitCM
+	}
+	measurement.NormalizeLengths(unit)
+	measurement.DeriveStandardSize()
 
 	if err := h.repo.Create(c.Request.Context(), measurement); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create measurement"})
+		response.FromError(c, err, "", "Failed to create measurement")
 		return
 	}
 
-	// If set as default, update other measurements
-	if isDefault {
-		h.repo.SetDefault(c.Request.Context(), userID, measurement.ID)
-	}
-
-	c.JSON(http.StatusCreated, gin.H{
-		"message":     "Measurement created successfully",
-		"measurement": measurement,
-	})
+	setVersionETag(c, measurement.Version)
+	response.Created(c, "Measurement created successfully", inDisplayUnit(*measurement, displayUnit))
 }
 
 // GetByID retrieves a measurement by ID (with IDOR protection)
 func (h *MeasurementHandler) GetByID(c *gin.Context) {
-	// Get user ID from auth context for ownership check
-	userIDStr := c.GetHeader("X-User-ID")
-	if userIDStr == "" {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+	userID, ok := authctx.AuthenticatedUserID(c)
+	if !ok {
 		return
 	}
 
-	userID, err := uuid.Parse(userIDStr)
+	idStr := c.Param("id")
+	id, err := uuid.Parse(idStr)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		response.BadRequest(c, "Invalid measurement ID", nil)
 		return
 	}
 
-	idStr := c.Param("id")
-	id, err := uuid.Parse(idStr)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid measurement ID"})
+	displayUnit, ok := requestedUnit(c)
+	if !ok {
 		return
 	}
 
@@ -132,67 +182,94 @@ func (h *MeasurementHandler) GetByID(c *gin.Context) {
 	measurement, err := h.repo.GetByID(c.Request.Context(), id, userID)
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Measurement not found"})
+			response.Fail(c, response.CodeMeasurementNotFound, "Measurement not found")
 			return
 		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve measurement"})
+		response.InternalServerError(c, "Failed to retrieve measurement")
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"measurement": measurement})
+	setVersionETag(c, measurement.Version)
+	response.OK(c, "", inDisplayUnit(*measurement, displayUnit))
 }
 
-// List retrieves all measurements for the authenticated user
+// List retrieves all measurements for the authenticated user.
+// Use ?person= to only return one profile person's measurements.
+// Lengths are returned in each measurement's entry unit unless ?unit=cm|inch is given.
 func (h *MeasurementHandler) List(c *gin.Context) {
-	userIDStr := c.GetHeader("X-User-ID")
-	if userIDStr == "" {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+	userID, ok := authctx.AuthenticatedUserID(c)
+	if !ok {
 		return
 	}
 
-	userID, err := uuid.Parse(userIDStr)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+	displayUnit, ok := requestedUnit(c)
+	if !ok {
 		return
 	}
 
-	measurements, err := h.repo.GetByUserID(c.Request.Context(), userID)
+	person := ""
+	if c.Query("person") != "" {
+		person = domain.NormalizeProfilePerson(c.Query("person"))
+	}
+
+	measurements, err := h.repo.GetByUserID(c.Request.Context(), userID, person)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve measurements"})
+		response.InternalServerError(c, "Failed to retrieve measurements")
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"measurements": measurements,
-		"count":        len(measurements),
+	for i := range measurements {
+		measurements[i] = inDisplayUnit(measurements[i], displayUnit)
+	}
+
+	response.OK(c, "", MeasurementList{
+		Measurements: measurements,
+		Count:        len(measurements),
 	})
 }
 
-// Update updates a measurement (with IDOR protection)
-func (h *MeasurementHandler) Update(c *gin.Context) {
-	// Get user ID from auth context for ownership check
-	userIDStr := c.GetHeader("X-User-ID")
-	if userIDStr == "" {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+// ListProfiles handles GET /api/v1/customer/measurements/profiles
+// It returns the people the customer keeps measurements for.
+func (h *MeasurementHandler) ListProfiles(c *gin.Context) {
+	userID, ok := authctx.AuthenticatedUserID(c)
+	if !ok {
 		return
 	}
 
-	userID, err := uuid.Parse(userIDStr)
+	people, err := h.repo.ListProfiles(c.Request.Context(), userID)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		response.InternalServerError(c, "Failed to retrieve measurement profiles")
+		return
+	}
+
+	response.OK(c, "", MeasurementProfiles{
+		Profiles: people,
+		Count:    len(people),
+		Limit:    domain.MaxMeasurementProfiles,
+	})
+}
+
+// Update updates a measurement (with IDOR protection)
+func (h *MeasurementHandler) Update(c *gin.Context) {
+	userID, ok := authctx.AuthenticatedUserID(c)
+	if !ok {
 		return
 	}
 
 	idStr := c.Param("id")
 	id, err := uuid.Parse(idStr)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid measurement ID"})
+		response.BadRequest(c, "Invalid measurement ID", nil)
 		return
 	}
 
-	var req CreateMeasurementRequest
+	var req UpdateMeasurementRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		response.Invalid(c, err)
+		return
+	}
+	displayUnit, ok := requestedUnit(c)
+	if !ok {
 		return
 	}
 
@@ -200,10 +277,13 @@ func (h *MeasurementHandler) Update(c *gin.Context) {
 	measurement, err := h.repo.GetByID(c.Request.Context(), id, userID)
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Measurement not found"})
+			response.Fail(c, response.CodeMeasurementNotFound, "Measurement not found")
 			return
 		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve measurement"})
+		response.InternalServerError(c, "Failed to retrieve measurement")
+		return
+	}
+	if !checkVersion(c, req.Version, measurement.Version) {
 		return
 	}
 
@@ -211,8 +291,17 @@ func (h *MeasurementHandler) Update(c *gin.Context) {
 	if req.Name != nil {
 		measurement.Name = req.Name
 	}
+	if req.ProfilePerson != nil {
+		person := domain.NormalizeProfilePerson(*req.ProfilePerson)
+		if person != measurement.ProfilePerson {
+			if !h.checkProfileLimit(c, userID, person) {
+				return
+			}
+			measurement.ProfilePerson = person
+		}
+	}
 	if req.Gender != "" {
-		measurement.Gender = req.Gender
+		measurement.Gender = shared.Gender(req.Gender)
 	}
 	measurement.Bust = req.Bust
 	measurement.Chest = req.Chest
@@ -228,87 +317,132 @@ func (h *MeasurementHandler) Update(c *gin.Context) {
 	measurement.Height = req.Height
 	measurement.Weight = req.Weight
 	measurement.Notes = req.Notes
-	
+
+	// Lengths sent without a unit are cm; the stored display unit only changes when one is given
+	if req.Unit != "" {
+		measurement.NormalizeLengths(req.Unit)
+	}
+
 	if req.IsDefault != nil {
 		measurement.IsDefault = *req.IsDefault
 	}
+	previousSize := measurement.DeriveStandardSize()
 
 	if err := h.repo.Update(c.Request.Context(), measurement); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update measurement"})
+		response.FromError(c, err, response.CodeMeasurementNotFound, "Failed to update measurement")
 		return
 	}
 
-	// If set as default, update other measurements
-	if measurement.IsDefault {
-		h.repo.SetDefault(c.Request.Context(), measurement.UserID, measurement.ID)
+	if measurement.StandardSizeChanged(previousSize) {
+		h.handleSizeDrift(c.Request.Context(), measurement, *previousSize)
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"message":     "Measurement updated successfully",
-		"measurement": measurement,
-	})
+	setVersionETag(c, measurement.Version)
+	response.OK(c, "Measurement updated successfully", inDisplayUnit(*measurement, displayUnit))
 }
 
 // Delete deletes a measurement (with IDOR protection)
 func (h *MeasurementHandler) Delete(c *gin.Context) {
-	// Get user ID from auth context for ownership check
-	userIDStr := c.GetHeader("X-User-ID")
-	if userIDStr == "" {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
-		return
-	}
-
-	userID, err := uuid.Parse(userIDStr)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+	userID, ok := authctx.AuthenticatedUserID(c)
+	if !ok {
 		return
 	}
 
 	idStr := c.Param("id")
 	id, err := uuid.Parse(idStr)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid measurement ID"})
+		response.BadRequest(c, "Invalid measurement ID", nil)
 		return
 	}
 
 	// IDOR protection: only delete if owned by user
 	if err := h.repo.Delete(c.Request.Context(), id, userID); err != nil {
 		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Measurement not found"})
+			response.Fail(c, response.CodeMeasurementNotFound, "Measurement not found")
 			return
 		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete measurement"})
+		response.InternalServerError(c, "Failed to delete measurement")
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Measurement deleted successfully"})
+	response.Deleted(c, "Measurement deleted successfully")
 }
 
 // SetDefault sets a measurement as default
 func (h *MeasurementHandler) SetDefault(c *gin.Context) {
-	userIDStr := c.GetHeader("X-User-ID")
-	if userIDStr == "" {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
-		return
-	}
-
-	userID, err := uuid.Parse(userIDStr)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+	userID, ok := authctx.AuthenticatedUserID(c)
+	if !ok {
 		return
 	}
 
 	idStr := c.Param("id")
 	id, err := uuid.Parse(idStr)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid measurement ID"})
+		response.BadRequest(c, "Invalid measurement ID", nil)
 		return
 	}
 
 	if err := h.repo.SetDefault(c.Request.Context(), userID, id); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to set default measurement"})
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			response.Fail(c, response.CodeMeasurementNotFound, "Measurement not found")
+			return
+		}
+		response.InternalServerError(c, "Failed to set default measurement")
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Default measurement set successfully"})
+	response.Done(c, http.StatusOK, "Default measurement set successfully")
+}
+
+// requestedUnit reads the optional ?unit= display unit. It writes an error
+// response and returns false if the unit is not supported.
+func requestedUnit(c *gin.Context) (string, bool) {
+	unit := c.Query("unit")
+	if unit != "" && !domain.IsValidMeasurementUnit(unit) {
+		response.BadRequest(c, "unit must be cm or inch", nil)
+		return "", false
+	}
+	return unit, true
+}
+
+// inDisplayUnit returns the measurement in the requested unit, falling back
+// to the unit the customer entered it in
+func inDisplayUnit(measurement domain.CustomerMeasurement, requested string) domain.CustomerMeasurement {
+	if requested == "" {
+		requested = measurement.Unit
+	}
+	if requested == "" {
+		requested = domain.MeasurementUnitCM
+	}
+	return measurement.InUnit(requested)
+}
+
+// checkProfileLimit writes an error response and returns false if the user
+// cannot store measurements for person without exceeding the profile cap
+func (h *MeasurementHandler) checkProfileLimit(c *gin.Context, userID uuid.UUID, person string) bool {
+	err := h.repo.CheckProfileLimit(c.Request.Context(), userID, person)
+	if errors.Is(err, domain.ErrMeasurementProfileLimit) {
+		response.Fail(c, response.CodeMeasurementProfileLimit, fmt.Sprintf("Measurements can be stored for at most %d people", domain.MaxMeasurementProfiles))
+		return false
+	}
+	if err != nil {
+		response.InternalServerError(c, "Failed to check measurement profiles")
+		return false
+	}
+	return true
+}
+
+// handleSizeDrift records the size change and, if configured, notifies the customer.
+// Failures are logged only; the measurement update itself has already succeeded.
+func (h *MeasurementHandler) handleSizeDrift(ctx context.Context, measurement *domain.CustomerMeasurement, previousSize string) {
+	if err := h.repo.RecordSizeChange(ctx, measurement, previousSize); err != nil {
+		log.Printf("⚠️  Failed to record size change for measurement %s: %v", measurement.ID, err)
+	}
+
+	if h.sizeDriftNotifier == nil {
+		return
+	}
+	if err := h.sizeDriftNotifier.NotifySizeChanged(ctx, measurement, previousSize); err != nil {
+		log.Printf("⚠️  Failed to send size change notification for measurement %s: %v", measurement.ID, err)
+	}
 }
@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/authctx"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/Ecom-micro-template/service-customer/internal/domain/shared"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// openTestDB opens an in-memory SQLite database for the models, with the
+// same adjustments as the persistence tests: no schemas in table names and
+// no Postgres defaults
+func openTestDB(t *testing.T, models ...interface{}) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+
+	for _, model := range models {
+		stmt := &gorm.Statement{DB: db}
+		require.NoError(t, stmt.Parse(model))
+		stmt.Schema.Table = strings.ReplaceAll(stmt.Schema.Table, ".", "_")
+		for _, field := range stmt.Schema.Fields {
+			if strings.HasSuffix(field.DefaultValue, "()") {
+				field.DefaultValue = ""
+				field.HasDefaultValue = false
+				field.DefaultValueInterface = nil
+			}
+		}
+	}
+	require.NoError(t, db.AutoMigrate(models...))
+	return db
+}
+
+// recordingSizeDriftNotifier records the previous sizes it was told about
+type recordingSizeDriftNotifier struct {
+	previous []string
+}
+
+func (n *recordingSizeDriftNotifier) NotifySizeChanged(_ context.Context, _ *domain.CustomerMeasurement, previousSize string) error {
+	n.previous = append(n.previous, previousSize)
+	return nil
+}
+
+func TestMeasurementHandler_UpdateAlertsOncePerSizeDrift(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := openTestDB(t, &domain.CustomerMeasurement{}, &domain.CustomerActivity{})
+	userID := uuid.New()
+	bust := 85.0
+	measurement := &domain.CustomerMeasurement{UserID: userID, Gender: shared.GenderWomen, Bust: &bust}
+	measurement.DeriveStandardSize()
+	require.NoError(t, db.Create(measurement).Error)
+	require.Equal(t, "S", *measurement.StandardSize)
+
+	notifier := &recordingSizeDriftNotifier{}
+	handler := NewMeasurementHandler(db).WithSizeDriftNotifier(notifier)
+	router := gin.New()
+	router.PUT("/measurements/:id", func(c *gin.Context) {
+		authctx.Set(c, &authctx.Principal{ID: userID, Role: "CUSTOMER"})
+	}, handler.Update)
+	update := func(body string) {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPut, "/measurements/"+measurement.ID.String(), bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	}
+
+	update(`{"gender": "women", "bust": 95}`)                   // S -> L
+	update(`{"gender": "women", "bust": 96}`)                   // still L
+	update(`{"gender": "women", "bust": 37.5, "unit": "inch"}`) // 95.3 cm, still L
+
+	assert.Equal(t, []string{"S"}, notifier.previous)
+	var changes []domain.CustomerActivity
+	require.NoError(t, db.Where("type = ?", "measurement_size_changed").Find(&changes).Error)
+	require.Len(t, changes, 1)
+	assert.Equal(t, "measurement: S -> L", changes[0].Details)
+}
@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"context"
+	"slices"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/authctx"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/persistence"
+	"github.com/Ecom-micro-template/service-customer/internal/response"
+	"gorm.io/gorm"
+)
+
+// NotificationPreferenceHandler lets customers schedule the notifications
+// they receive
+type NotificationPreferenceHandler struct {
+	repo *persistence.NotificationPreferenceRepository
+}
+
+// NewNotificationPreferenceHandler creates a new notification preference handler
+func NewNotificationPreferenceHandler(db *gorm.DB) *NotificationPreferenceHandler {
+	return &NotificationPreferenceHandler{
+		repo: persistence.NewNotificationPreferenceRepository(db),
+	}
+}
+
+// GetPreferences handles GET /api/v1/customer/notification-preferences
+func (h *NotificationPreferenceHandler) GetPreferences(c *gin.Context) {
+	userID, ok := authctx.AuthenticatedUserID(c)
+	if !ok {
+		return
+	}
+
+	prefs, err := h.repo.Get(c.Request.Context(), userID)
+	if err != nil {
+		response.InternalServerError(c, "Failed to retrieve notification preferences")
+		return
+	}
+
+	response.OK(c, "", prefs)
+}
+
+// UpdatePreferences handles PUT /api/v1/customer/notification-preferences
+// Omitted fields are kept.
+func (h *NotificationPreferenceHandler) UpdatePreferences(c *gin.Context) {
+	userID, ok := authctx.AuthenticatedUserID(c)
+	if !ok {
+		return
+	}
+	var req domain.UpdateNotificationPreferencesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Invalid(c, err)
+		return
+	}
+
+	prefs, err := h.repo.Get(c.Request.Context(), userID)
+	if err != nil {
+		response.InternalServerError(c, "Failed to retrieve notification preferences")
+		return
+	}
+	if err := req.Apply(prefs); err != nil {
+		response.BadRequest(c, err.Error(), nil)
+		return
+	}
+	if err := h.repo.Save(c.Request.Context(), prefs); err != nil {
+		response.InternalServerError(c, "Failed to update notification preferences")
+		return
+	}
+
+	response.OK(c, "Notification preferences updated", prefs)
+}
+
+// NotificationPolicy decides whether a customer gets a notification now
+type NotificationPolicy interface {
+	ShouldNotify(ctx context.Context, customerID uuid.UUID, notificationType string) (domain.NotificationDecision, error)
+	Sent(ctx context.Context, customerID uuid.UUID, notificationType, channel string)
+}
+
+// NotificationCheckRequest asks whether a customer may be sent a notification
+type NotificationCheckRequest struct {
+	CustomerID uuid.UUID `json:"customer_id" binding:"required"`
+	Type       string    `json:"type" binding:"required"`
+}
+
+// InternalNotificationCheckHandler lets other services, such as the one
+// sending product review reminders, apply customers' notification preferences
+type InternalNotificationCheckHandler struct {
+	policy NotificationPolicy
+}
+
+// NewInternalNotificationCheckHandler creates a new internal notification check handler
+func NewInternalNotificationCheckHandler(policy NotificationPolicy) *InternalNotificationCheckHandler {
+	return &InternalNotificationCheckHandler{policy: policy}
+}
+
+// Check reports whether the customer may be sent the notification now and on
+// which channel. An allowed check counts toward the customer's weekly cap, so
+// callers should only check when about to send.
+// POST /internal/v1/customers/notification-check
+func (h *InternalNotificationCheckHandler) Check(c *gin.Context) {
+	var req NotificationCheckRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Invalid(c, err)
+		return
+	}
+	if !slices.Contains(domain.NotificationTypes, req.Type) {
+		response.BadRequest(c, domain.ErrInvalidNotificationType.Error(), nil)
+		return
+	}
+
+	decision, err := h.policy.ShouldNotify(c.Request.Context(), req.CustomerID, req.Type)
+	if err != nil {
+		response.InternalServerError(c, "Failed to check notification preferences")
+		return
+	}
+	if decision.Allowed {
+		h.policy.Sent(c.Request.Context(), req.CustomerID, req.Type, decision.Channel)
+	}
+
+	response.OK(c, "", decision)
+}
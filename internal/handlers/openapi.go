@@ -0,0 +1,1210 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/rand"
+	_ "embed"
+	"encoding/base64"
+	"fmt"
+	"html/template"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/Ecom-micro-template/service-customer/internal/domain/shared"
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/addressvalidation"
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/persistence"
+	"github.com/Ecom-micro-template/service-customer/internal/middleware"
+	"github.com/Ecom-micro-template/service-customer/internal/openapi"
+	"github.com/Ecom-micro-template/service-customer/internal/response"
+)
+
+// swaggerUIVersion is the swagger-ui-dist release the docs page loads
+const swaggerUIVersion = "5.17.14"
+
+//go:embed swagger_ui.html
+var swaggerUIPage string
+
+var swaggerUITemplate = template.Must(template.New("swagger-ui").Parse(swaggerUIPage))
+
+// OpenAPIHandler serves the OpenAPI document generated by APISpec and a
+// Swagger UI page for browsing it
+type OpenAPIHandler struct {
+	spec    []byte
+	specURL string
+}
+
+// NewOpenAPIHandler renders the spec once; specURL is where Spec is mounted,
+// for the UI to load it from
+func NewOpenAPIHandler(specURL string) (*OpenAPIHandler, error) {
+	spec, err := APISpec().JSON()
+	if err != nil {
+		return nil, fmt.Errorf("render OpenAPI spec: %w", err)
+	}
+	return &OpenAPIHandler{spec: spec, specURL: specURL}, nil
+}
+
+// Spec serves the OpenAPI document
+// GET /api/v1/openapi.json
+func (h *OpenAPIHandler) Spec(c *gin.Context) {
+	c.Data(http.StatusOK, "application/json; charset=utf-8", h.spec)
+}
+
+// UI serves Swagger UI for the spec. The UI's assets come from a CDN; the
+// page's own script only runs with the per-request nonce.
+// GET /api/v1/docs
+func (h *OpenAPIHandler) UI(c *gin.Context) {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		response.InternalServerError(c, "Failed to render API docs")
+		return
+	}
+	data := struct{ Version, SpecURL, Nonce string }{
+		Version: swaggerUIVersion,
+		SpecURL: h.specURL,
+		Nonce:   base64.StdEncoding.EncodeToString(nonce),
+	}
+	var page bytes.Buffer
+	if err := swaggerUITemplate.Execute(&page, data); err != nil {
+		response.InternalServerError(c, "Failed to render API docs")
+		return
+	}
+
+	c.Header("Content-Security-Policy", fmt.Sprintf(
+		"default-src 'none'; script-src 'nonce-%s' https://unpkg.com; style-src 'unsafe-inline' https://unpkg.com; "+
+			"img-src 'self' data: https://unpkg.com; connect-src 'self'", data.Nonce))
+	c.Data(http.StatusOK, "text/html; charset=utf-8", page.Bytes())
+}
+
+// Security schemes of the spec
+const (
+	bearerAuth     = "bearerAuth"
+	serviceToken   = "serviceToken"
+	internalAPIKey = "internalApiKey" // deprecated, see middleware.ServiceAuthMiddleware
+)
+
+// Descriptions of the headers making retries and concurrent edits safe
+const (
+	versionedUpdate = "Send the ETag of the record as If-Match, or its version in the body, to get a 409 VERSION_CONFLICT instead of overwriting a concurrent edit. Without either the update applies unconditionally."
+	ifMatchHeader   = "ETag of the version being edited; 409 if the record changed since"
+	idempotencyKey  = "Unique per create (e.g. a UUID); retrying with the same key and body replays the first response for 24 hours instead of creating a duplicate"
+)
+
+// APISpec describes the routes under /api/v1 and /internal/v1. Each route
+// registered in cmd/server must be documented here, with the request and
+// response types its handler binds and renders.
+func APISpec() *openapi.Document {
+	doc := openapi.New(openapi.Info{
+		Title:   "Customer Service API",
+		Version: "1.0.0",
+		Description: "Profiles, addresses, wishlists, measurements, back-in-stock alerts and store credit for customers, " +
+			"customer management for admins, and store credit reservations for other services.",
+	}).
+		SecurityScheme(bearerAuth, openapi.BearerAuth(), true).
+		SecurityScheme(serviceToken, openapi.APIKeyHeader(middleware.ServiceTokenHeader), false).
+		SecurityScheme(internalAPIKey, openapi.APIKeyHeader(middleware.InternalAPIKeyHeader), false).
+		ErrorBody(response.Problem{}).
+		ErrorMediaType(response.ContentType).
+		Enum(shared.CustomerStatus(""), shared.EnumValues(shared.AllCustomerStatuses())).
+		Enum(shared.AddressLabel(""), shared.EnumValues(shared.AllAddressLabels())).
+		Enum(shared.Gender(""), shared.EnumValues(shared.AllGenders())).
+		Enum(shared.ProfileGender(""), shared.EnumValues(shared.AllProfileGenders()))
+
+	documentPublicRoutes(doc)
+	documentCustomerRoutes(doc)
+	documentAdminRoutes(doc)
+	documentInternalRoutes(doc)
+	return doc
+}
+
+func documentPublicRoutes(doc *openapi.Document) {
+	problems := doc.Group("/api/v1/problems", "Problems")
+	problems.GET("", "List the error codes of problem responses").
+		ID("listProblems").Public().
+		Returns(http.StatusOK, "Error catalog", response.Data[[]response.Entry]{})
+	problems.GET("/:type", "Describe the error code of a problem type").
+		ID("getProblem").Public().
+		Returns(http.StatusOK, "Error code", response.Data[response.Entry]{}).
+		Errors(http.StatusNotFound)
+
+	public := doc.Group("/api/v1/public", "Public")
+	public.POST("/back-in-stock", "Subscribe to a back-in-stock notification without an account").
+		ID("guestSubscribeBackInStock").Public().
+		Description("Sends a confirmation email; the subscription is active once confirmed. The response is the same whether or not the email was already subscribed.").
+		Body(domain.GuestBackInStockSubscribeInput{}).
+		Returns(http.StatusAccepted, "Confirmation email sent", response.Message{}).
+		Errors(http.StatusBadRequest, http.StatusTooManyRequests, http.StatusInternalServerError)
+	public.GET("/back-in-stock/confirm", "Confirm a guest back-in-stock subscription").
+		ID("guestConfirmBackInStock").Public().
+		Query("token", "Token from the confirmation email", "").
+		Returns(http.StatusOK, "Subscription confirmed", response.Data[*domain.GuestBackInStockSubscription]{}).
+		Errors(http.StatusBadRequest, http.StatusInternalServerError)
+
+	webhooks := doc.Group("/api/v1/internal/webhooks", "Webhooks")
+	webhooks.POST("/inventory", "Receive an inventory event").
+		ID("receiveInventoryWebhook").Public().
+		Description(fmt.Sprintf("Signed with the shared secret in %s over the %s timestamp and body. Each event_id is processed once.",
+			HeaderWebhookSignature, HeaderWebhookTimestamp)).
+		Body(domain.InventoryWebhookPayload{}).
+		Returns(http.StatusOK, "Event processed, ignored or already processed", response.Message{}).
+		Errors(http.StatusBadRequest, http.StatusUnauthorized, http.StatusRequestEntityTooLarge,
+			http.StatusUnprocessableEntity, http.StatusInternalServerError, http.StatusServiceUnavailable)
+}
+
+func documentCustomerRoutes(doc *openapi.Document) {
+	profile := doc.Group("/api/v1/customer", "Profile")
+	profile.GET("/profile", "Get the customer's profile").
+		ID("getProfile").
+		Returns(http.StatusOK, "Profile", response.Data[*domain.Profile]{}).
+		Errors(http.StatusUnauthorized, http.StatusInternalServerError)
+	profile.PUT("/profile", "Create or update the customer's profile").
+		ID("updateProfile").
+		Description(versionedUpdate).
+		Header("If-Match", ifMatchHeader).
+		Body(UpdateProfileRequest{}).
+		Returns(http.StatusOK, "Profile updated", response.Data[*domain.Profile]{}).
+		Fails(http.StatusBadRequest, "Invalid profile, or the email differs from the current one (EMAIL_CHANGE_REQUIRED)").
+		Fails(http.StatusConflict, "Profile modified by another request").
+		Errors(http.StatusUnauthorized, http.StatusInternalServerError)
+	profile.POST("/profile/avatar", "Upload an avatar").
+		ID("uploadAvatar").
+		Description("Crops the JPEG, PNG, GIF or WebP image to a square and stores it as 64, 256 and 512 pixel JPEGs, returned in avatar_urls; profile_picture is the 256 pixel one. The previous avatar is deleted.").
+		Upload("file").
+		Returns(http.StatusOK, "Avatar updated", response.Data[*domain.Profile]{}).
+		Fails(http.StatusRequestEntityTooLarge, "Image file or dimensions too large").
+		Fails(http.StatusUnsupportedMediaType, "Not a supported image").
+		Errors(http.StatusBadRequest, http.StatusUnauthorized, http.StatusInternalServerError, http.StatusServiceUnavailable)
+	profile.POST("/email-change", "Request a change of email").
+		ID("requestEmailChange").
+		Description("Emails a verification link to the new address; the profile keeps the current email until it is confirmed. Requesting again replaces the pending change.").
+		Body(domain.RequestEmailChangeInput{}).
+		Returns(http.StatusAccepted, "Verification email sent", response.Message{}).
+		Fails(http.StatusBadRequest, "Invalid email, or it is the current one").
+		Fails(http.StatusNotFound, "Customer has no profile").
+		Fails(http.StatusConflict, "Email already used by another account").
+		Errors(http.StatusUnauthorized, http.StatusTooManyRequests, http.StatusInternalServerError)
+	profile.POST("/email-change/confirm", "Confirm a change of email").
+		ID("confirmEmailChange").
+		Description("Applies the pending change with the token from the verification email and sends a security notice to the old address.").
+		Body(domain.ConfirmEmailChangeInput{}).
+		Returns(http.StatusOK, "Email changed", response.Data[*domain.Profile]{}).
+		Fails(http.StatusBadRequest, "Invalid or expired verification token").
+		Fails(http.StatusConflict, "Email taken by another account since the change was requested").
+		Errors(http.StatusUnauthorized, http.StatusInternalServerError)
+
+	profile.GET("/marketing-consent", "Get the customer's marketing consent").
+		ID("getMarketingConsent").
+		Description("Customers who never chose have opted out of both.").
+		Returns(http.StatusOK, "Marketing consent", response.Data[*domain.MarketingConsent]{}).
+		Errors(http.StatusUnauthorized, http.StatusInternalServerError)
+	profile.PUT("/marketing-consent", "Opt in to or out of marketing email and SMS").
+		ID("updateMarketingConsent").
+		Description("Omitted fields are left as they are. The change is pushed to the configured marketing platforms.").
+		Body(domain.UpdateMarketingConsentRequest{}).
+		Returns(http.StatusOK, "Marketing consent updated", response.Data[*domain.MarketingConsent]{}).
+		Errors(http.StatusBadRequest, http.StatusUnauthorized, http.StatusInternalServerError)
+	profile.GET("/notification-preferences", "Get the customer's notification schedule").
+		ID("getNotificationPreferences").
+		Description("Customers who never changed it get every notification by email, at any hour and without a weekly cap.").
+		Returns(http.StatusOK, "Notification preferences", response.Data[*domain.NotificationPreferences]{}).
+		Errors(http.StatusUnauthorized, http.StatusInternalServerError)
+	profile.PUT("/notification-preferences", "Set the preferred channel, quiet hours, weekly cap and opt-outs").
+		ID("updateNotificationPreferences").
+		Description("Omitted fields are left as they are. Quiet hours are HH:MM in the customer's timezone and may span midnight; empty quiet hours turn them off. A max_per_week of 0 removes the cap. opted_out lists the notification types not to send: back_in_stock, price_drop, saved_search and review_reminder. Notifications held back by quiet hours or the cap stay pending, for example a back-in-stock alert goes out on a later restock.").
+		Body(domain.UpdateNotificationPreferencesRequest{}).
+		Returns(http.StatusOK, "Notification preferences updated", response.Data[*domain.NotificationPreferences]{}).
+		Errors(http.StatusBadRequest, http.StatusUnauthorized, http.StatusInternalServerError)
+
+	activity := doc.Group("/api/v1/customer/activity", "Activity")
+	activity.GET("", "List my recent security activity").
+		ID("listMyActivity").
+		Description("Profile and address changes and sign-ins from the last 90 days, newest first, with the IP address and device they came from. by_support marks changes made by customer support on the customer's behalf.").
+		Query("limit", "Events per page, at most 100", 0).
+		Query("cursor", "next_cursor of the previous page", "").
+		Returns(http.StatusOK, "Activity", response.CursorPage[[]domain.SecurityEvent]{}).
+		Fails(http.StatusBadRequest, "Invalid cursor").
+		Errors(http.StatusUnauthorized, http.StatusInternalServerError)
+
+	addresses := doc.Group("/api/v1/customer/addresses", "Addresses")
+	addresses.GET("", "List saved addresses").
+		ID("listAddresses").
+		Returns(http.StatusOK, "Addresses", response.Data[AddressList]{}).
+		Errors(http.StatusUnauthorized, http.StatusInternalServerError)
+	addresses.POST("", "Add an address").
+		ID("createAddress").
+		Header(middleware.IdempotencyKeyHeader, idempotencyKey).
+		Body(CreateAddressRequest{}).
+		Returns(http.StatusCreated, "Address created", response.Data[*domain.Address]{}).
+		Fails(http.StatusConflict, "A request with the same Idempotency-Key is in progress").
+		Fails(http.StatusUnprocessableEntity, "Address rejected by the country rules or the validation provider, the address limit is reached, or the Idempotency-Key was used for a different request").
+		Errors(http.StatusBadRequest, http.StatusUnauthorized, http.StatusInternalServerError)
+	addresses.POST("/validate", "Validate and normalize an address without saving it").
+		ID("validateAddress").
+		Body(addressvalidation.Input{}).
+		Returns(http.StatusOK, "Validation result", response.Data[*addressvalidation.Result]{}).
+		Fails(http.StatusUnprocessableEntity, "Address breaks the country rules").
+		Errors(http.StatusBadRequest, http.StatusServiceUnavailable)
+	addresses.POST("/import-from-order/:orderId", "Save the shipping address of an order").
+		ID("importAddressFromOrder").
+		Body(ImportAddressRequest{}).
+		Returns(http.StatusCreated, "Address imported", response.Data[ImportedAddress]{}).
+		Returns(http.StatusOK, "An equivalent address was already saved", response.Data[ImportedAddress]{}).
+		Fails(http.StatusUnprocessableEntity, "Address rejected by the validation provider, or the address limit is reached").
+		Errors(http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound, http.StatusInternalServerError, http.StatusServiceUnavailable)
+	addresses.PUT("/:id", "Update an address").
+		ID("updateAddress").
+		Description(versionedUpdate).
+		Header("If-Match", ifMatchHeader).
+		Body(UpdateAddressRequest{}).
+		Returns(http.StatusOK, "Address updated", response.Data[*domain.Address]{}).
+		Fails(http.StatusConflict, "Address modified by another request").
+		Fails(http.StatusUnprocessableEntity, "Address rejected by the country rules or the validation provider").
+		Errors(http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound, http.StatusInternalServerError)
+	addresses.DELETE("/:id", "Delete an address (restorable for 30 days)").
+		ID("deleteAddress").
+		Returns(http.StatusOK, "Address deleted", response.Message{}).
+		Errors(http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound, http.StatusInternalServerError)
+	addresses.PUT("/:id/default", "Make an address the default").
+		ID("setDefaultAddress").
+		Returns(http.StatusOK, "Default address set", response.Message{}).
+		Errors(http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound, http.StatusInternalServerError)
+	addresses.POST("/:id/restore", "Restore a deleted address").
+		ID("restoreAddress").
+		Returns(http.StatusOK, "Address restored", response.Data[*domain.Address]{}).
+		Fails(http.StatusUnprocessableEntity, "Address limit reached").
+		Errors(http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound, http.StatusGone, http.StatusInternalServerError)
+
+	wishlist := doc.Group("/api/v1/customer/wishlist", "Wishlist")
+	wishlist.GET("", "List wishlist items").
+		ID("getWishlist").
+		Returns(http.StatusOK, "Wishlist items", response.Data[WishlistItems]{}).
+		Errors(http.StatusUnauthorized, http.StatusInternalServerError)
+	wishlist.GET("/count", "Count wishlist items").
+		ID("getWishlistCount").
+		Returns(http.StatusOK, "Item count", response.Data[WishlistCount]{}).
+		Errors(http.StatusUnauthorized, http.StatusInternalServerError)
+	wishlist.GET("/stock-status", "Live stock badges for wishlist items").
+		ID("getWishlistStockStatus").
+		Returns(http.StatusOK, "Stock status per item", response.Data[WishlistStockStatuses]{}).
+		Errors(http.StatusUnauthorized, http.StatusInternalServerError, http.StatusServiceUnavailable)
+	wishlist.GET("/check/:productId", "Check whether a product or variant is in the wishlist").
+		ID("checkWishlist").
+		Query("variant_id", "Variant to check instead of any variant of the product", "").
+		Returns(http.StatusOK, "Whether the product is in the wishlist", response.Data[WishlistCheck]{}).
+		Errors(http.StatusBadRequest, http.StatusUnauthorized, http.StatusInternalServerError)
+	wishlist.POST("/check-batch", "Check up to 100 products or variants against the wishlist").
+		ID("checkWishlistBatch").
+		Description("Keyed by product_id, or product_id:variant_id for items with a variant. An item without a variant is in the wishlist when any of its variants is.").
+		Body(domain.MembershipCheckRequest{}).
+		Returns(http.StatusOK, "Wishlist membership per item", response.Data[WishlistCheckBatch]{}).
+		Errors(http.StatusBadRequest, http.StatusUnauthorized, http.StatusInternalServerError)
+	wishlist.POST("", "Add a product or variant to the wishlist").
+		ID("addToWishlist").
+		Header(middleware.IdempotencyKeyHeader, idempotencyKey).
+		Body(AddToWishlistRequest{}).
+		Returns(http.StatusCreated, "Added to wishlist", response.Data[WishlistProduct]{}).
+		Fails(http.StatusConflict, "A request with the same Idempotency-Key is in progress").
+		Fails(http.StatusUnprocessableEntity, "Wishlist is full, or the Idempotency-Key was used for a different request").
+		Errors(http.StatusBadRequest, http.StatusUnauthorized, http.StatusInternalServerError)
+	wishlist.POST("/import", "Import products from a CSV of SKUs or product URLs").
+		ID("importWishlist").
+		Description("The CSV is sent as the \"file\" form field or as the raw request body.").
+		Upload("file").
+		Returns(http.StatusOK, "Result per row", response.Data[domain.WishlistImportSummary]{}).
+		Errors(http.StatusBadRequest, http.StatusUnauthorized, http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable)
+	wishlist.POST("/confirm", "Keep the items of a \"still interested?\" nudge").
+		ID("confirmWishlist").
+		Description("Items with nudged_at set were saved long ago and are archived unless confirmed; confirming restarts their retention period.").
+		Returns(http.StatusOK, "Number of items kept", response.Data[WishlistConfirmation]{}).
+		Errors(http.StatusUnauthorized, http.StatusInternalServerError)
+	wishlist.DELETE("/:productId", "Remove a product (all variants) from the wishlist").
+		ID("removeFromWishlist").
+		Returns(http.StatusOK, "Removed from wishlist", response.Message{}).
+		Errors(http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound, http.StatusInternalServerError)
+	wishlist.DELETE("/items/:itemId", "Remove a wishlist item").
+		ID("removeWishlistItem").
+		Returns(http.StatusOK, "Item removed", response.Message{}).
+		Errors(http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound, http.StatusInternalServerError)
+	wishlist.PATCH("/items/:itemId", "Update a wishlist item").
+		ID("updateWishlistItem").
+		Body(UpdateWishlistItemRequest{}).
+		Returns(http.StatusOK, "Item updated", response.Message{}).
+		Errors(http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound, http.StatusInternalServerError)
+
+	recentlyViewed := doc.Group("/api/v1/customer/recently-viewed", "Recently Viewed")
+	recentlyViewed.GET("", "List recently viewed products").
+		ID("listRecentlyViewed").
+		Description("Most recent first, with name, slug and image from the catalog. Products no longer in the catalog are left out; partial is set when the catalog could not be reached and the details are missing.").
+		Query("limit", "Products to return, at most the number kept per customer (50 by default)", 0).
+		Returns(http.StatusOK, "Recently viewed products", response.Data[RecentlyViewedItems]{}).
+		Errors(http.StatusUnauthorized, http.StatusInternalServerError)
+	recentlyViewed.POST("", "Record a product view").
+		ID("recordRecentlyViewed").
+		Description("Called by the storefront product page. Viewing a product again moves it to the front; only the most recent views are kept.").
+		Body(domain.RecordViewInput{}).
+		Returns(http.StatusCreated, "View recorded", response.Data[domain.RecordViewInput]{}).
+		Errors(http.StatusBadRequest, http.StatusUnauthorized, http.StatusInternalServerError)
+	recentlyViewed.DELETE("", "Clear recently viewed products").
+		ID("clearRecentlyViewed").
+		Returns(http.StatusOK, "History cleared", response.Message{}).
+		Errors(http.StatusUnauthorized, http.StatusInternalServerError)
+
+	savedSearches := doc.Group("/api/v1/customer/saved-searches", "Saved Searches")
+	savedSearches.GET("", "List saved searches").
+		ID("listSavedSearches").
+		Returns(http.StatusOK, "Saved searches, newest first", response.Data[[]domain.SavedSearch]{}).
+		Errors(http.StatusUnauthorized, http.StatusInternalServerError)
+	savedSearches.POST("", "Save a catalog search").
+		ID("saveSearch").
+		Description("Needs a query or at least one filter. While alerts are enabled, the customer is notified of newly published products matching every query term and filter, at most once a day per search. A customer can save up to 20 searches.").
+		Body(domain.SaveSearchRequest{}).
+		Returns(http.StatusCreated, "Search saved", response.Data[domain.SavedSearch]{}).
+		Fails(http.StatusUnprocessableEntity, "Saved search limit reached").
+		Errors(http.StatusBadRequest, http.StatusUnauthorized, http.StatusInternalServerError)
+	savedSearches.PUT("/:id", "Update a saved search").
+		ID("updateSavedSearch").
+		Description("Replaces the name, query and filters. Alerts are left as they are unless alerts_enabled is given.").
+		Body(domain.SaveSearchRequest{}).
+		Returns(http.StatusOK, "Saved search updated", response.Data[domain.SavedSearch]{}).
+		Errors(http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound, http.StatusInternalServerError)
+	savedSearches.DELETE("/:id", "Delete a saved search").
+		ID("deleteSavedSearch").
+		Returns(http.StatusOK, "Saved search deleted", response.Message{}).
+		Errors(http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound, http.StatusInternalServerError)
+
+	data := doc.Group("/api/v1/customer", "Data Portability")
+	data.GET("/data-export", "Download addresses, wishlist and measurements as a data export").
+		ID("exportCustomerData").
+		Returns(http.StatusOK, "Data export, sent as an attachment", domain.CustomerDataExport{}).
+		Errors(http.StatusUnauthorized, http.StatusInternalServerError)
+	data.POST("/data-import", "Restore a data export into this account").
+		ID("importCustomerData").
+		Query("dry_run", "Preview the outcome without writing anything", false).
+		Body(domain.CustomerDataExport{}).
+		Returns(http.StatusOK, "Import summary", response.Data[*domain.DataImportSummary]{}).
+		Errors(http.StatusBadRequest, http.StatusUnauthorized, http.StatusInternalServerError)
+
+	orders := doc.Group("/api/v1/customer", "Orders")
+	orders.GET("/orders", "List the customer's orders").
+		ID("getOrderHistory").
+		Query("page", "Page number", 0).
+		Query("limit", "Orders per page", 0).
+		Returns(http.StatusOK, "Orders", response.Page[[]Order]{}).
+		Errors(http.StatusUnauthorized, http.StatusInternalServerError, http.StatusServiceUnavailable)
+
+	measurements := doc.Group("/api/v1/customer/measurements", "Measurements")
+	measurements.GET("", "List body measurements").
+		ID("listMeasurements").
+		Query("person", "Only this profile person's measurements", "").
+		Query("unit", "Convert lengths to cm or inch", "").
+		Returns(http.StatusOK, "Measurements", response.Data[MeasurementList]{}).
+		Errors(http.StatusBadRequest, http.StatusUnauthorized, http.StatusInternalServerError)
+	measurements.POST("", "Save body measurements").
+		ID("createMeasurement").
+		Query("unit", "Convert lengths in the response to cm or inch", "").
+		Body(CreateMeasurementRequest{}).
+		Returns(http.StatusCreated, "Measurement created", response.Data[domain.CustomerMeasurement]{}).
+		Fails(http.StatusUnprocessableEntity, "Measurement or profile person limit reached").
+		Errors(http.StatusBadRequest, http.StatusUnauthorized, http.StatusInternalServerError)
+	measurements.GET("/profiles", "List the people the customer keeps measurements for").
+		ID("listMeasurementProfiles").
+		Returns(http.StatusOK, "Profile people", response.Data[MeasurementProfiles]{}).
+		Errors(http.StatusUnauthorized, http.StatusInternalServerError)
+	measurements.GET("/:id", "Get one measurement").
+		ID("getMeasurement").
+		Query("unit", "Convert lengths to cm or inch", "").
+		Returns(http.StatusOK, "Measurement", response.Data[domain.CustomerMeasurement]{}).
+		Errors(http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound, http.StatusInternalServerError)
+	measurements.PUT("/:id", "Update a measurement").
+		ID("updateMeasurement").
+		Description(versionedUpdate).
+		Header("If-Match", ifMatchHeader).
+		Query("unit", "Convert lengths in the response to cm or inch", "").
+		Body(UpdateMeasurementRequest{}).
+		Returns(http.StatusOK, "Measurement updated", response.Data[domain.CustomerMeasurement]{}).
+		Fails(http.StatusConflict, "Measurement modified by another request").
+		Errors(http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound, http.StatusUnprocessableEntity, http.StatusInternalServerError)
+	measurements.DELETE("/:id", "Delete a measurement").
+		ID("deleteMeasurement").
+		Returns(http.StatusOK, "Measurement deleted", response.Message{}).
+		Errors(http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound, http.StatusInternalServerError)
+	measurements.PUT("/:id/set-default", "Make a measurement the default").
+		ID("setDefaultMeasurement").
+		Returns(http.StatusOK, "Default measurement set", response.Message{}).
+		Errors(http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound, http.StatusInternalServerError)
+
+	backInStock := doc.Group("/api/v1/customer/back-in-stock", "Back in stock")
+	backInStock.GET("", "List back-in-stock subscriptions").
+		ID("listBackInStock").
+		Returns(http.StatusOK, "Subscriptions", response.Data[BackInStockSubscriptions]{}).
+		Errors(http.StatusUnauthorized, http.StatusInternalServerError)
+	backInStock.POST("", "Subscribe to a back-in-stock notification").
+		ID("subscribeBackInStock").
+		Header(middleware.IdempotencyKeyHeader, idempotencyKey).
+		Body(domain.BackInStockSubscribeInput{}).
+		Returns(http.StatusCreated, "Subscribed", response.Data[*domain.BackInStockSubscription]{}).
+		Fails(http.StatusConflict, "A request with the same Idempotency-Key is in progress").
+		Fails(http.StatusUnprocessableEntity, "The Idempotency-Key was used for a different request").
+		Errors(http.StatusBadRequest, http.StatusUnauthorized, http.StatusInternalServerError)
+	backInStock.GET("/check/:productId", "Check whether the customer is subscribed to a product").
+		ID("checkBackInStock").
+		Query("variant_id", "Variant to check", "").
+		Returns(http.StatusOK, "Subscription status", response.Data[SubscriptionStatus]{}).
+		Errors(http.StatusBadRequest, http.StatusUnauthorized, http.StatusInternalServerError)
+	backInStock.POST("/check-batch", "Check up to 100 products or variants for subscriptions").
+		ID("checkBackInStockBatch").
+		Description("Keyed by product_id, or product_id:variant_id for items with a variant. An item without a variant only matches a subscription to the product as a whole.").
+		Body(domain.MembershipCheckRequest{}).
+		Returns(http.StatusOK, "Subscription status per item", response.Data[SubscriptionStatusBatch]{}).
+		Errors(http.StatusBadRequest, http.StatusUnauthorized, http.StatusInternalServerError)
+	backInStock.DELETE("/:productId", "Unsubscribe from a product").
+		ID("unsubscribeBackInStock").
+		Query("variant_id", "Variant to unsubscribe from", "").
+		Returns(http.StatusOK, "Unsubscribed", response.Message{}).
+		Errors(http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound, http.StatusInternalServerError)
+	backInStock.DELETE("/subscriptions/:id", "Remove a subscription").
+		ID("deleteBackInStockSubscription").
+		Returns(http.StatusOK, "Subscription removed", response.Message{}).
+		Errors(http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound, http.StatusInternalServerError)
+
+	wallet := doc.Group("/api/v1/customer/wallet", "Wallet")
+	wallet.GET("", "Get the store credit balance").
+		ID("getWallet").
+		Returns(http.StatusOK, "Wallet", response.Data[WalletBalance]{}).
+		Errors(http.StatusUnauthorized, http.StatusInternalServerError)
+	wallet.GET("/transactions", "List store credit transactions").
+		ID("getWalletTransactions").
+		Query("page", "Page number", 0).
+		Query("limit", "Transactions per page, at most 100", 0).
+		Returns(http.StatusOK, "Transactions", response.Page[[]domain.WalletTransaction]{}).
+		Errors(http.StatusUnauthorized, http.StatusInternalServerError)
+
+	const graphQLDescription = "Query the account graph: me, or account(customerId) for staff, with profile, addresses, defaultAddress, wishlist, measurements(person, unit), backInStockSubscriptions and the staff-only insights. Fields the caller may not read resolve to null with a FORBIDDEN error; query errors are returned in errors with status 200. The schema is available by introspection outside production."
+	graphQL := doc.Group("/api/v1/graphql", "GraphQL")
+	graphQL.POST("", "Query the customer account graph").
+		ID("queryGraphQL").
+		Description(graphQLDescription).
+		Body(GraphQLRequest{}).
+		Returns(http.StatusOK, "Query result", GraphQLResponse{}).
+		Errors(http.StatusBadRequest, http.StatusUnauthorized, http.StatusTooManyRequests)
+	graphQL.GET("", "Query the customer account graph").
+		ID("queryGraphQLGet").
+		Description(graphQLDescription+" Read-only impersonation sessions must use GET.").
+		Query("query", "GraphQL query", "").
+		Query("operationName", "", "").
+		Query("variables", "JSON object", "").
+		Returns(http.StatusOK, "Query result", GraphQLResponse{}).
+		Errors(http.StatusBadRequest, http.StatusUnauthorized, http.StatusTooManyRequests)
+}
+
+func documentAdminRoutes(doc *openapi.Document) {
+	permissions := doc.Group("/api/v1/admin", "Admin: Permissions")
+	permissions.GET("/permissions", "Get my permissions and the permission policy").
+		ID("getAdminPermissions").
+		Description("Every admin route requires the permission listed in routes (none when empty). For the admin UI to hide actions the admin can't take.").
+		Returns(http.StatusOK, "Permissions", response.Data[domain.PermissionPolicyView]{}).
+		Errors(http.StatusUnauthorized, http.StatusForbidden)
+
+	audit := doc.Group("/api/v1/admin", "Admin: Audit")
+	audit.GET("/audit-logs", "List admin changes").
+		ID("listAuditLogs").
+		Query("entity_type", "", "").
+		Query("entity_id", "", "").
+		Query("action", "", "").
+		Query("actor_id", "", "").
+		Query("date_from", "YYYY-MM-DD", "").
+		Query("date_to", "YYYY-MM-DD", "").
+		Query("page", "", 0).
+		Query("limit", "", 0).
+		Returns(http.StatusOK, "Audit log entries", response.Page[[]domain.AuditLog]{}).
+		Errors(http.StatusBadRequest, http.StatusInternalServerError)
+
+	customers := doc.Group("/api/v1/admin/customers", "Admin: Customers")
+	customerQuery := func(op *openapi.Operation) *openapi.Operation {
+		return op.
+			Query("view", "Saved view whose filters apply unless given explicitly", "").
+			Query("status", "", "").
+			Query("segment", "", "").
+			Query("search", "", "").
+			Query("tags", "Comma-separated tags, all of which must match", "").
+			Query("rfm_segment", "Comma-separated RFM segments, any of which must match: "+strings.Join(domain.RFMSegments, ", "), "").
+			Query("clv_min", "Minimum predicted lifetime value", 0.0).
+			Query("clv_max", "Maximum predicted lifetime value", 0.0).
+			Query("churn_risk", "One of "+strings.Join(domain.ChurnRiskLevels, ", "), "").
+			Query("sort_by", "One of "+strings.Join(domain.CustomerSort.Fields(), ", ")+" (default created_at)", "").
+			Query("sort_order", "asc or desc (default desc)", "")
+	}
+	customerQuery(customers.GET("", "List customers")).
+		ID("listCustomers").
+		Description("Passing cursor (empty for the first page) switches to cursor pagination; meta then carries next_cursor instead of page counts.").
+		Query("date_from", "YYYY-MM-DD", "").
+		Query("date_to", "YYYY-MM-DD", "").
+		Query("orders_min", "", 0).
+		Query("orders_max", "", 0).
+		Query("spent_min", "", 0.0).
+		Query("spent_max", "", 0.0).
+		Query("page", "", 0).
+		Query("limit", "", 0).
+		Query("cursor", "", "").
+		Returns(http.StatusOK, "Customers", response.Page[[]domain.Customer]{}).
+		Errors(http.StatusBadRequest, http.StatusInternalServerError)
+	customers.GET("/stats", "Customer statistics").
+		ID("getCustomerStats").
+		Description("Customer totals, and signups and first purchases in the selected period compared with the period of the same length before it, with a per-day series. Periods are whole days in timezone ending with today.").
+		Query("period", "7d, 30d (default) or 90d", "").
+		Query("timezone", "IANA time zone (default UTC)", "").
+		Returns(http.StatusOK, "Statistics", response.Data[*persistence.CustomerStats]{}).
+		Errors(http.StatusBadRequest, http.StatusInternalServerError)
+	customers.GET("/analytics/cohorts", "Customer signup cohorts").
+		ID("getCustomerCohorts").
+		Description("Monthly signup cohorts, newest first, with the share of each cohort that completed an order in each month since signing up. Refreshed by the cohort_refresh job.").
+		Query("months", "Signup months to include, 1-36 (default 12)", 0).
+		Returns(http.StatusOK, "Cohorts", response.Data[[]domain.Cohort]{}).
+		Errors(http.StatusBadRequest, http.StatusInternalServerError)
+	customers.GET("/analytics/growth", "Customer growth").
+		ID("getCustomerGrowth").
+		Description("Signups, first purchases and the running customer total per day or week (weeks start on Monday), in UTC.").
+		Query("interval", "day (default) or week", "").
+		Query("from", "YYYY-MM-DD (default 29 days before to)", "").
+		Query("to", "YYYY-MM-DD (default today)", "").
+		Returns(http.StatusOK, "Growth series", response.Data[domain.GrowthSeries]{}).
+		Errors(http.StatusBadRequest, http.StatusInternalServerError)
+	customerQuery(customers.GET("/export", "Export customers")).
+		ID("exportCustomers").
+		Description(fmt.Sprintf("Up to %d customers. Use POST /api/v1/admin/customers/exports instead.", domain.MaxCustomerExportRows)).
+		Deprecated().
+		Query("format", "csv (default) or json", "").
+		Query("columns", "Comma-separated columns; defaults to the admin's saved columns", "").
+		Returns(http.StatusOK, "JSON export", response.Data[domain.CustomerExport]{}).
+		ReturnsFile(http.StatusOK, "CSV export", "text/csv").
+		Errors(http.StatusBadRequest, http.StatusInternalServerError)
+	customerQuery(customers.POST("/exports", "Start a background customer export")).
+		ID("createCustomerExport").
+		Description("Takes the query parameters of GET /export, without its row cap; customers are exported oldest first, "+
+			"so sort_by and sort_order are ignored. The export runs in the background; its progress, and a download "+
+			"link once it has completed, are at the Location header.").
+		Query("format", "csv (default) or json", "").
+		Query("columns", "Comma-separated columns; defaults to the admin's saved columns", "").
+		Returns(http.StatusAccepted, "Export queued", response.Data[CustomerExportJobResponse]{}).
+		Errors(http.StatusBadRequest, http.StatusInternalServerError, http.StatusServiceUnavailable)
+	customers.GET("/exports/:exportId", "Get the progress of a background customer export").
+		ID("getCustomerExport").
+		Description("Admins only see their own exports. download_url is set once the export has completed.").
+		Returns(http.StatusOK, "Export", response.Data[CustomerExportJobResponse]{}).
+		Errors(http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError, http.StatusServiceUnavailable)
+	customers.GET("/lookup", "Find the customer who placed an order").
+		ID("lookupCustomer").
+		Query("order_number", "", "").
+		Returns(http.StatusOK, "Customer", response.Data[CustomerLookup]{}).
+		Errors(http.StatusBadRequest, http.StatusNotFound, http.StatusServiceUnavailable)
+	customers.GET("/tags", "Suggest tags").
+		ID("suggestTags").
+		Query("q", "Tag prefix", "").
+		Query("limit", "", 0).
+		Returns(http.StatusOK, "Suggestions", response.Data[[]domain.TagSuggestion]{}).
+		Errors(http.StatusInternalServerError, http.StatusServiceUnavailable)
+	customers.GET("/columns", "Get the admin's customer list columns").
+		ID("getCustomerColumns").
+		Returns(http.StatusOK, "Columns", response.Data[CustomerColumnsResponse]{}).
+		Errors(http.StatusInternalServerError)
+	customers.PUT("/columns", "Save the admin's customer list columns").
+		ID("updateCustomerColumns").
+		Body(CustomerColumnsRequest{}).
+		Returns(http.StatusOK, "Columns saved", response.Data[CustomerColumnsResponse]{}).
+		Errors(http.StatusBadRequest, http.StatusInternalServerError)
+	customers.DELETE("/columns", "Reset the admin's customer list columns").
+		ID("resetCustomerColumns").
+		Returns(http.StatusOK, "Columns reset", response.Data[CustomerColumnsResponse]{}).
+		Errors(http.StatusInternalServerError)
+	customers.GET("/views", "List saved customer views").
+		ID("listCustomerViews").
+		Returns(http.StatusOK, "Views", response.Data[[]domain.CustomerListView]{}).
+		Errors(http.StatusInternalServerError)
+	customers.POST("/views", "Save a customer view").
+		ID("createCustomerView").
+		Body(domain.SaveCustomerViewRequest{}).
+		Returns(http.StatusCreated, "View saved", response.Data[*domain.CustomerListView]{}).
+		Errors(http.StatusBadRequest, http.StatusConflict, http.StatusInternalServerError)
+	customers.GET("/views/:viewId", "Get a saved customer view").
+		ID("getCustomerView").
+		Returns(http.StatusOK, "View", response.Data[*domain.CustomerListView]{}).
+		Errors(http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError)
+	customers.PUT("/views/:viewId", "Update a saved customer view").
+		ID("updateCustomerView").
+		Body(domain.SaveCustomerViewRequest{}).
+		Returns(http.StatusOK, "View updated", response.Data[*domain.CustomerListView]{}).
+		Errors(http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound, http.StatusConflict, http.StatusInternalServerError)
+	customers.DELETE("/views/:viewId", "Delete a saved customer view").
+		ID("deleteCustomerView").
+		Returns(http.StatusOK, "View deleted", response.Message{}).
+		Errors(http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound, http.StatusInternalServerError)
+	customers.POST("", "Create a customer").
+		ID("createCustomer").
+		Header(middleware.IdempotencyKeyHeader, idempotencyKey).
+		Body(domain.CreateCustomerRequest{}).
+		Returns(http.StatusCreated, "Customer created", response.Data[*domain.Customer]{}).
+		Fails(http.StatusConflict, "Email already registered, or a request with the same Idempotency-Key is in progress").
+		Fails(http.StatusUnprocessableEntity, "The Idempotency-Key was used for a different request").
+		Errors(http.StatusBadRequest, http.StatusInternalServerError)
+	customers.GET("/:id", "Get a customer").
+		ID("getCustomer").
+		Description("Email and phone are masked, with pii_masked set, for admins without customers:pii. The same applies to the customer list, lookup, export, create and update responses.").
+		Returns(http.StatusOK, "Customer", response.Data[*domain.Customer]{}).
+		Errors(http.StatusBadRequest, http.StatusNotFound)
+	customers.POST("/:id/reveal", "Reveal a customer's email and phone").
+		ID("revealCustomerPII").
+		Description("For admins who see contact details masked. Each reveal is recorded in the audit log with the reason given.").
+		Body(domain.RevealPIIRequest{}).
+		Returns(http.StatusOK, "Contact details", response.Data[domain.CustomerPII]{}).
+		Errors(http.StatusBadRequest, http.StatusNotFound)
+	customers.PUT("/:id", "Update a customer").
+		ID("updateCustomer").
+		Body(domain.UpdateCustomerRequest{}).
+		Returns(http.StatusOK, "Customer updated", response.Data[*domain.Customer]{}).
+		Errors(http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError)
+	customers.DELETE("/:id", "Delete a customer").
+		ID("deleteCustomer").
+		Returns(http.StatusOK, "Customer deleted", response.Message{}).
+		Errors(http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError)
+	customers.GET("/:id/orders", "List a customer's orders").
+		ID("getCustomerOrders").
+		Query("page", "", 0).
+		Query("limit", "", 0).
+		Description("Read from service-order. A page cached in the last 15 minutes is served with a Warning header if service-order is down.").
+		Returns(http.StatusOK, "Orders", response.Page[[]domain.CustomerOrderSummary]{}).
+		Fails(http.StatusForbidden, "service-order rejected the admin's credentials").
+		Errors(http.StatusBadRequest, http.StatusServiceUnavailable)
+
+	noteQuery := func(op *openapi.Operation) *openapi.Operation {
+		return op.
+			Query("category", "", "").
+			Query("is_private", "", false).
+			Query("created_by", "", "").
+			Query("date_from", "YYYY-MM-DD", "").
+			Query("date_to", "YYYY-MM-DD", "").
+			Query("sort_order", "asc (oldest first) or desc (default)", "")
+	}
+	noteQuery(customers.GET("/:id/notes", "List a customer's notes")).
+		ID("getCustomerNotes").
+		Query("page", "", 0).
+		Query("limit", "", 0).
+		Returns(http.StatusOK, "Notes", response.Page[[]domain.CustomerNote]{}).
+		Errors(http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError)
+	noteQuery(customers.GET("/:id/notes/count", "Count a customer's notes by category")).
+		ID("countCustomerNotes").
+		Returns(http.StatusOK, "Counts", response.Data[*domain.CustomerNoteCounts]{}).
+		Errors(http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError)
+	customers.POST("/:id/notes", "Add a note").
+		ID("addCustomerNote").
+		Body(AddCustomerNoteRequest{}).
+		Returns(http.StatusCreated, "Note added", response.Data[*domain.CustomerNote]{}).
+		Errors(http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError)
+	customers.PUT("/:id/notes/:noteId", "Edit a note").
+		ID("updateCustomerNote").
+		Body(domain.UpdateCustomerNoteRequest{}).
+		Returns(http.StatusOK, "Note updated", response.Data[*domain.CustomerNote]{}).
+		Errors(http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound, http.StatusInternalServerError)
+	customers.DELETE("/:id/notes/:noteId", "Delete a note").
+		ID("deleteCustomerNote").
+		Returns(http.StatusOK, "Note deleted", response.Message{}).
+		Errors(http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound, http.StatusInternalServerError)
+	customers.POST("/:id/notes/:noteId/pin", "Pin a note").
+		ID("pinCustomerNote").
+		Returns(http.StatusOK, "Note pinned", response.Data[*domain.CustomerNote]{}).
+		Errors(http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError)
+	customers.DELETE("/:id/notes/:noteId/pin", "Unpin a note").
+		ID("unpinCustomerNote").
+		Returns(http.StatusOK, "Note unpinned", response.Data[*domain.CustomerNote]{}).
+		Errors(http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError)
+	customers.POST("/:id/notes/:noteId/attachments", "Attach a file to a note").
+		ID("uploadNoteAttachment").
+		Upload("file").
+		Returns(http.StatusCreated, "File attached", response.Data[*domain.NoteAttachment]{}).
+		Errors(http.StatusBadRequest, http.StatusNotFound, http.StatusConflict, http.StatusRequestEntityTooLarge,
+			http.StatusUnsupportedMediaType, http.StatusInternalServerError, http.StatusServiceUnavailable)
+	customers.DELETE("/:id/notes/:noteId/attachments/:attachmentId", "Delete a note attachment").
+		ID("deleteNoteAttachment").
+		Returns(http.StatusOK, "Attachment deleted", response.Message{}).
+		Errors(http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError, http.StatusServiceUnavailable)
+	customers.GET("/:id/tags", "List a customer's tags").
+		ID("getCustomerTags").
+		Returns(http.StatusOK, "Tags", response.Data[[]domain.CustomerTag]{}).
+		Errors(http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError, http.StatusServiceUnavailable)
+	customers.POST("/:id/tags", "Tag a customer").
+		ID("addCustomerTags").
+		Body(CustomerTagsRequest{}).
+		Returns(http.StatusOK, "Tags after adding", response.Data[[]domain.CustomerTag]{}).
+		Errors(http.StatusBadRequest, http.StatusNotFound, http.StatusConflict, http.StatusInternalServerError, http.StatusServiceUnavailable)
+	customers.DELETE("/:id/tags/:tag", "Remove a tag from a customer").
+		ID("removeCustomerTag").
+		Returns(http.StatusOK, "Tag removed", response.Message{}).
+		Errors(http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError, http.StatusServiceUnavailable)
+	customers.GET("/:id/activity", "List a customer's activity").
+		ID("getCustomerActivity").
+		Description("Passing cursor (empty for the first page) switches to cursor pagination; meta then carries next_cursor instead of page counts.").
+		Query("page", "", 0).
+		Query("limit", "", 0).
+		Query("cursor", "", "").
+		Returns(http.StatusOK, "Activity", response.Page[[]domain.CustomerActivity]{}).
+		Errors(http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError)
+	customers.GET("/:id/timeline", "A customer's notes, activity and orders in one timeline").
+		ID("getCustomerTimeline").
+		Query("types", "Comma-separated entry types", "").
+		Query("page", "", 0).
+		Query("limit", "", 0).
+		Returns(http.StatusOK, "Timeline", response.Data[domain.Timeline]{}).
+		Errors(http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError)
+	customers.GET("/:id/activity/pinned", "List a customer's pinned activity").
+		ID("getPinnedActivity").
+		Returns(http.StatusOK, "Pinned activity", response.Data[PinnedActivities]{}).
+		Errors(http.StatusBadRequest, http.StatusInternalServerError)
+	customers.POST("/:id/activity/:activityId/pin", "Pin an activity").
+		ID("pinActivity").
+		Returns(http.StatusOK, "Activity pinned", response.Data[*domain.CustomerActivity]{}).
+		Errors(http.StatusBadRequest, http.StatusNotFound, http.StatusConflict, http.StatusInternalServerError)
+	customers.DELETE("/:id/activity/:activityId/pin", "Unpin an activity").
+		ID("unpinActivity").
+		Returns(http.StatusOK, "Activity unpinned", response.Data[*domain.CustomerActivity]{}).
+		Errors(http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError)
+	customers.POST("/:id/segments", "Assign segments to a customer").
+		ID("assignSegments").
+		Body(AssignSegmentsRequest{}).
+		Returns(http.StatusOK, "Segments assigned", response.Data[any]{}).
+		Errors(http.StatusBadRequest, http.StatusInternalServerError)
+	customers.POST("/:id/merge/preview", "Preview merging another customer into this one").
+		ID("previewMerge").
+		Body(domain.MergeCustomerRequest{}).
+		Returns(http.StatusOK, "Merge preview", response.Data[*domain.MergePreview]{}).
+		Errors(http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError)
+	customers.POST("/:id/merge", "Merge another customer into this one").
+		ID("mergeCustomer").
+		Body(domain.MergeCustomerRequest{}).
+		Returns(http.StatusOK, "Customers merged", response.Data[*domain.AccountMerge]{}).
+		Errors(http.StatusBadRequest, http.StatusNotFound, http.StatusConflict, http.StatusInternalServerError)
+	customers.GET("/duplicates", "Probable duplicate customers").
+		ID("listDuplicateCustomers").
+		Description("Pairs of customers found by the duplicate_detection job sharing a phone number, an email up to +tags, Gmail dots and aliases, or a name and address. The suggested primary comes first in customers; merge the other into it with mergeCustomer, which marks the pair merged.").
+		Query("status", "pending (default), merged or dismissed", "").
+		Query("page", "", 0).
+		Query("limit", "", 0).
+		Returns(http.StatusOK, "Duplicate candidates", response.Page[[]domain.DuplicateCandidate]{}).
+		Errors(http.StatusBadRequest, http.StatusForbidden, http.StatusInternalServerError)
+	customers.POST("/duplicates/:candidateId/dismiss", "Dismiss a probable duplicate").
+		ID("dismissDuplicateCustomer").
+		Returns(http.StatusOK, "Duplicate candidate dismissed", response.Data[*domain.DuplicateCandidate]{}).
+		Errors(http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound, http.StatusConflict, http.StatusInternalServerError)
+	customers.POST("/:id/impersonate", "Start acting as a customer").
+		ID("startImpersonation").
+		Body(domain.StartImpersonationRequest{}).
+		Returns(http.StatusCreated, "Impersonation started", response.Data[ImpersonationStart]{}).
+		Errors(http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound, http.StatusInternalServerError)
+	customers.GET("/:id/impersonations", "List impersonations of a customer").
+		ID("listImpersonations").
+		Query("page", "", 0).
+		Query("limit", "", 0).
+		Returns(http.StatusOK, "Impersonation sessions", response.Page[[]domain.ImpersonationSession]{}).
+		Errors(http.StatusBadRequest, http.StatusInternalServerError)
+	customers.GET("/:id/addresses/deleted", "List a customer's deleted addresses").
+		ID("listDeletedAddresses").
+		Returns(http.StatusOK, "Deleted addresses", response.Data[[]DeletedAddress]{}).
+		Errors(http.StatusBadRequest, http.StatusInternalServerError)
+	customers.GET("/:id/wallet", "Get a customer's wallet and ledger").
+		ID("getCustomerWallet").
+		Query("page", "", 0).
+		Query("limit", "", 0).
+		Returns(http.StatusOK, "Wallet", response.Page[AdminWallet]{}).
+		Errors(http.StatusBadRequest, http.StatusInternalServerError)
+	customers.POST("/:id/wallet/credit", "Grant store credit").
+		ID("creditWallet").
+		Body(domain.WalletAdjustmentRequest{}).
+		Returns(http.StatusCreated, "Wallet updated", response.Data[*domain.WalletTransaction]{}).
+		Errors(http.StatusBadRequest, http.StatusInternalServerError)
+	customers.POST("/:id/wallet/debit", "Deduct store credit").
+		ID("debitWallet").
+		Body(domain.WalletAdjustmentRequest{}).
+		Returns(http.StatusCreated, "Wallet updated", response.Data[*domain.WalletTransaction]{}).
+		Errors(http.StatusBadRequest, http.StatusConflict, http.StatusInternalServerError)
+	customers.GET("/:id/limits", "Get a customer's address, wishlist and measurement limits").
+		ID("getCustomerLimits").
+		Returns(http.StatusOK, "Limits in effect, defaults, usage and override", response.Data[*CustomerLimitStatus]{}).
+		Errors(http.StatusBadRequest, http.StatusInternalServerError)
+	customers.PUT("/:id/limits", "Override a customer's limits").
+		ID("setCustomerLimits").
+		Description("Omitted limits keep the default; 0 removes the limit.").
+		Body(domain.CustomerLimitOverrideRequest{}).
+		Returns(http.StatusOK, "Limits updated", response.Data[*CustomerLimitStatus]{}).
+		Errors(http.StatusBadRequest, http.StatusInternalServerError)
+	customers.DELETE("/:id/limits", "Reset a customer's limits to the defaults").
+		ID("resetCustomerLimits").
+		Returns(http.StatusOK, "Limits reset", response.Message{}).
+		Errors(http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError)
+
+	activityQuery := func(op *openapi.Operation) *openapi.Operation {
+		return op.
+			Query("types", "Comma-separated activity types", "").
+			Query("customer_id", "", "").
+			Query("actor_id", "", "").
+			Query("date_from", "YYYY-MM-DD", "").
+			Query("date_to", "YYYY-MM-DD", "").
+			Query("sort_order", "asc (oldest first) or desc (default)", "")
+	}
+	activity := doc.Group("/api/v1/admin/activity", "Admin: Activity")
+	activityQuery(activity.GET("", "Activity across all customers")).
+		ID("listActivity").
+		Query("limit", "", 0).
+		Query("cursor", "next_cursor of the previous page", "").
+		Returns(http.StatusOK, "Activity page", response.Data[*domain.ActivityFeedPage]{}).
+		Errors(http.StatusBadRequest, http.StatusInternalServerError)
+	activityQuery(activity.GET("/export", "Export activity as CSV")).
+		ID("exportActivity").
+		ReturnsFile(http.StatusOK, "CSV export", "text/csv").
+		Errors(http.StatusBadRequest, http.StatusInternalServerError)
+
+	segments := doc.Group("/api/v1/admin/segments", "Admin: Segments")
+	segments.GET("", "List segments").
+		ID("listSegments").
+		Description(fmt.Sprintf("member_count is cached for %s.", domain.SegmentCountTTL)).
+		Returns(http.StatusOK, "Segments", response.Data[[]domain.SegmentWithCount]{}).
+		Errors(http.StatusInternalServerError)
+	segments.POST("/preview", "Preview the customers matching segment conditions").
+		ID("previewSegment").
+		Description(fmt.Sprintf("Counts the customers in the admin's region matching the conditions and returns %d of them. Nothing is saved.",
+			domain.SegmentPreviewSampleSize)).
+		Body(PreviewSegmentRequest{}).
+		Returns(http.StatusOK, "Preview", response.Data[domain.SegmentPreview]{}).
+		Errors(http.StatusBadRequest, http.StatusInternalServerError, http.StatusServiceUnavailable)
+	segments.POST("", "Create a segment").
+		ID("createSegment").
+		Body(CreateSegmentRequest{}).
+		Returns(http.StatusCreated, "Segment created", response.Data[*domain.CustomerSegment]{}).
+		Errors(http.StatusBadRequest, http.StatusInternalServerError)
+	segments.PUT("/:id", "Update a segment").
+		ID("updateSegment").
+		Body(UpdateSegmentRequest{}).
+		Returns(http.StatusOK, "Segment updated", response.Data[*domain.CustomerSegment]{}).
+		Errors(http.StatusBadRequest, http.StatusInternalServerError)
+	segments.DELETE("/:id", "Delete a segment").
+		ID("deleteSegment").
+		Returns(http.StatusOK, "Segment deleted", response.Message{}).
+		Errors(http.StatusBadRequest, http.StatusInternalServerError)
+	segments.POST("/:id/customers/bulk", "Assign or remove a segment for many customers").
+		ID("bulkUpdateSegmentCustomers").
+		Description(fmt.Sprintf("Up to %d customers, by ID or by filter, or as a CSV of emails uploaded in the \"file\" form field "+
+			"with the action in the \"action\" field. Up to %d customers are changed before the response; "+
+			"larger changes return 202 and run in the background, with their progress at the Location header.",
+			domain.MaxBulkSegmentCustomers, domain.BulkSegmentInlineLimit)).
+		Body(BulkSegmentRequest{}).
+		Returns(http.StatusOK, "Completed job", response.Data[*domain.SegmentBulkJob]{}).
+		Returns(http.StatusAccepted, "Started job", response.Data[*domain.SegmentBulkJob]{}).
+		Errors(http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError, http.StatusServiceUnavailable)
+	segments.GET("/:id/customers/bulk/:jobId", "Get the progress of a bulk segment change").
+		ID("getSegmentBulkJob").
+		Returns(http.StatusOK, "Job", response.Data[*domain.SegmentBulkJob]{}).
+		Errors(http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError)
+	segments.GET("/:id/history", "List the customers entering and leaving a segment").
+		ID("listSegmentHistory").
+		Description("Newest first. Each event records whether it was a manual, rule or bulk change, "+
+			"with the rule, bulk job or admin that made it.").
+		Query("change", "entered or left", "").
+		Query("source", "manual, rule or bulk", "").
+		Query("date_from", "YYYY-MM-DD", "").
+		Query("date_to", "YYYY-MM-DD, inclusive", "").
+		Query("page", "", 0).
+		Query("limit", "", 0).
+		Returns(http.StatusOK, "Membership events", response.Page[[]domain.SegmentMembershipEvent]{}).
+		Errors(http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError)
+	segments.GET("/rules", "List segment rules").
+		ID("listSegmentRules").
+		Returns(http.StatusOK, "Rules", response.Data[[]domain.SegmentRule]{}).
+		Errors(http.StatusInternalServerError)
+	segments.POST("/rules", "Create a segment rule").
+		ID("createSegmentRule").
+		Body(domain.CreateSegmentRuleRequest{}).
+		Returns(http.StatusCreated, "Rule created", response.Data[*domain.SegmentRule]{}).
+		Errors(http.StatusBadRequest, http.StatusInternalServerError)
+	segments.POST("/rules/simulate", "Show which rules would fire for a customer").
+		ID("simulateSegmentRules").
+		Body(SimulateRulesRequest{}).
+		Returns(http.StatusOK, "Evaluation", response.Data[*domain.SegmentEvaluation]{}).
+		Errors(http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError)
+	segments.DELETE("/rules/:ruleId", "Delete a segment rule").
+		ID("deleteSegmentRule").
+		Returns(http.StatusOK, "Rule deleted", response.Message{}).
+		Errors(http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError)
+
+	regions := doc.Group("/api/v1/admin/region-assignments", "Admin: Regions")
+	regions.GET("/:adminId", "Get the states an admin may see").
+		ID("getRegionAssignment").
+		Returns(http.StatusOK, "Assignment", response.Data[RegionAssignment]{}).
+		Errors(http.StatusBadRequest, http.StatusInternalServerError)
+	regions.PUT("/:adminId", "Set the states an admin may see").
+		ID("setRegionAssignment").
+		Body(domain.SetRegionAssignmentInput{}).
+		Returns(http.StatusOK, "Assignment updated", response.Data[RegionAssignment]{}).
+		Errors(http.StatusBadRequest, http.StatusInternalServerError)
+
+	impersonations := doc.Group("/api/v1/admin/impersonations", "Admin: Customers")
+	impersonations.GET("/:sessionId/requests", "List the requests made during an impersonation").
+		ID("listImpersonationRequests").
+		Returns(http.StatusOK, "Requests", response.Data[[]domain.ImpersonationRequest]{}).
+		Errors(http.StatusBadRequest, http.StatusInternalServerError)
+	impersonations.DELETE("/:sessionId", "End an impersonation").
+		ID("revokeImpersonation").
+		Returns(http.StatusOK, "Impersonation revoked", response.Data[*domain.ImpersonationSession]{}).
+		Errors(http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError)
+
+	blocklist := doc.Group("/api/v1/admin/blocklist", "Admin: Blocklist")
+	blocklist.GET("", "List blocklisted emails, phones and addresses").
+		ID("listBlocklist").
+		Query("kind", "email, phone or address", "").
+		Query("include_expired", "", false).
+		Query("page", "", 0).
+		Query("limit", "", 0).
+		Returns(http.StatusOK, "Blocklist entries", response.Page[[]domain.BlocklistEntry]{}).
+		Errors(http.StatusBadRequest, http.StatusForbidden, http.StatusInternalServerError)
+	blocklist.POST("", "Blocklist an email, phone or address").
+		ID("createBlocklistEntry").
+		Description("Email and phone entries take value, address entries take address. Values are normalized, so +tags, Gmail dots and phone formatting don't get around an entry. Flagging a value already on the blocklist replaces its reason and expiry.").
+		Body(domain.CreateBlocklistEntryRequest{}).
+		Returns(http.StatusCreated, "Blocklist entry added", response.Data[*domain.BlocklistEntry]{}).
+		Errors(http.StatusBadRequest, http.StatusForbidden, http.StatusInternalServerError)
+	blocklist.DELETE("/:entryId", "Remove a blocklist entry").
+		ID("deleteBlocklistEntry").
+		Returns(http.StatusOK, "Blocklist entry removed", response.Message{}).
+		Errors(http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound, http.StatusInternalServerError)
+
+	webhooks := doc.Group("/api/v1/admin/webhooks", "Admin: Webhooks")
+	webhooks.GET("", "List webhook subscriptions").
+		ID("listWebhooks").
+		Returns(http.StatusOK, "Webhooks", response.Data[[]domain.WebhookSubscription]{}).
+		Errors(http.StatusForbidden, http.StatusInternalServerError)
+	webhooks.POST("", "Subscribe a URL to customer events").
+		ID("createWebhook").
+		Description("Events are customer.created, customer.updated and customer.segment_changed. Each delivery is POSTed with X-Webhook-Event, X-Webhook-Delivery, X-Webhook-Timestamp and X-Webhook-Signature: sha256=HMAC-SHA256(secret, \"<timestamp>.<body>\"). Non-2xx responses are retried with exponential backoff. The secret is generated unless given and only returned here.").
+		Body(domain.CreateWebhookSubscriptionRequest{}).
+		Returns(http.StatusCreated, "Webhook created", response.Data[domain.CreatedWebhookSubscription]{}).
+		Errors(http.StatusBadRequest, http.StatusForbidden, http.StatusInternalServerError)
+	webhooks.GET("/:webhookId", "Get a webhook subscription").
+		ID("getWebhook").
+		Returns(http.StatusOK, "Webhook", response.Data[*domain.WebhookSubscription]{}).
+		Errors(http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound, http.StatusInternalServerError)
+	webhooks.PUT("/:webhookId", "Update a webhook subscription").
+		ID("updateWebhook").
+		Description("Deliveries to an inactive webhook are held and sent once it is active again.").
+		Body(domain.UpdateWebhookSubscriptionRequest{}).
+		Returns(http.StatusOK, "Webhook updated", response.Data[*domain.WebhookSubscription]{}).
+		Errors(http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound, http.StatusInternalServerError)
+	webhooks.DELETE("/:webhookId", "Delete a webhook subscription and its delivery log").
+		ID("deleteWebhook").
+		Returns(http.StatusOK, "Webhook deleted", response.Message{}).
+		Errors(http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound, http.StatusInternalServerError)
+	webhooks.GET("/:webhookId/deliveries", "List a webhook's deliveries").
+		ID("listWebhookDeliveries").
+		Query("status", "pending, delivered or failed", "").
+		Query("page", "", 0).
+		Query("limit", "", 0).
+		Returns(http.StatusOK, "Deliveries", response.Page[[]domain.OutboundWebhookDelivery]{}).
+		Errors(http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound, http.StatusInternalServerError)
+	webhooks.GET("/:webhookId/deliveries/:deliveryId", "Get a delivery with its attempt log").
+		ID("getWebhookDelivery").
+		Returns(http.StatusOK, "Delivery", response.Data[*domain.OutboundWebhookDeliveryDetail]{}).
+		Errors(http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound, http.StatusInternalServerError)
+	webhooks.POST("/:webhookId/deliveries/:deliveryId/redeliver", "Send a delivery again").
+		ID("redeliverWebhook").
+		Description("Queues the same event, with the same id, for immediate delivery with a fresh set of retries.").
+		Returns(http.StatusOK, "Delivery queued", response.Data[*domain.OutboundWebhookDelivery]{}).
+		Errors(http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound, http.StatusInternalServerError)
+
+	scheduledExports := doc.Group("/api/v1/admin/scheduled-exports", "Admin: Scheduled Exports")
+	scheduledExports.GET("", "List scheduled customer exports").
+		ID("listScheduledExports").
+		Query("page", "", 0).
+		Query("limit", "", 0).
+		Query("sort_by", "One of "+strings.Join(domain.ScheduledExportSort.Fields(), ", ")+" (default created_at)", "").
+		Query("sort_order", "asc or desc (default asc)", "").
+		Returns(http.StatusOK, "Scheduled exports", response.Page[[]domain.ScheduledExport]{}).
+		Errors(http.StatusBadRequest, http.StatusForbidden, http.StatusInternalServerError)
+	scheduledExports.POST("", "Schedule a recurring customer export").
+		ID("createScheduledExport").
+		Description("filter takes the query string of GET /admin/customers/export (status, segment, search, tags and the score filters). "+
+			"schedule is a cron expression or descriptor such as @weekly, optionally prefixed with CRON_TZ=Asia/Kuala_Lumpur. "+
+			"target.type is email (a download link is emailed to recipients), s3 (bucket, region or endpoint, access_key, secret) "+
+			"or sftp (host as host:port, username, host_key as the server key's SHA256 fingerprint, secret as password or private_key). "+
+			"Secrets are never returned. The export keeps the creating admin's region and PII masking, and alert_emails are told of failed runs.").
+		Body(domain.ScheduledExportRequest{}).
+		Returns(http.StatusCreated, "Scheduled export created", response.Data[*domain.ScheduledExport]{}).
+		Errors(http.StatusBadRequest, http.StatusForbidden, http.StatusInternalServerError)
+	scheduledExports.GET("/:exportId", "Get a scheduled export").
+		ID("getScheduledExport").
+		Returns(http.StatusOK, "Scheduled export", response.Data[*domain.ScheduledExport]{}).
+		Errors(http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound, http.StatusInternalServerError)
+	scheduledExports.PUT("/:exportId", "Replace a scheduled export").
+		ID("updateScheduledExport").
+		Description("Secrets left empty keep the saved ones while the target type is unchanged. The next run is rescheduled from now.").
+		Body(domain.ScheduledExportRequest{}).
+		Returns(http.StatusOK, "Scheduled export updated", response.Data[*domain.ScheduledExport]{}).
+		Errors(http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound, http.StatusInternalServerError)
+	scheduledExports.DELETE("/:exportId", "Delete a scheduled export and its run history").
+		ID("deleteScheduledExport").
+		Returns(http.StatusOK, "Scheduled export deleted", response.Message{}).
+		Errors(http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound, http.StatusInternalServerError)
+	scheduledExports.GET("/:exportId/runs", "List a scheduled export's runs").
+		ID("listScheduledExportRuns").
+		Query("page", "", 0).
+		Query("limit", "", 0).
+		Query("sort_by", "One of "+strings.Join(domain.ScheduledExportRunSort.Fields(), ", ")+" (default started_at)", "").
+		Query("sort_order", "asc or desc (default desc)", "").
+		Returns(http.StatusOK, "Runs", response.Page[[]domain.ScheduledExportRun]{}).
+		Errors(http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound, http.StatusInternalServerError)
+	scheduledExports.POST("/:exportId/run", "Run a scheduled export now").
+		ID("runScheduledExport").
+		Description("Runs the export in the background, even when paused; its schedule is unchanged. Follow the run in the run history.").
+		Returns(http.StatusAccepted, "Run started", response.Data[*domain.ScheduledExportRun]{}).
+		Errors(http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound, http.StatusInternalServerError)
+
+	marketing := doc.Group("/api/v1/admin/marketing-sync", "Admin: Marketing Sync")
+	marketing.GET("", "Sync status per marketing platform").
+		ID("getMarketingSyncStatus").
+		Description("Pending, synced and failed customers for each configured platform (mailchimp or klaviyo). Only customers who opted in to marketing are synced.").
+		Returns(http.StatusOK, "Sync status", response.Data[[]domain.MarketingSyncStatus]{}).
+		Errors(http.StatusForbidden, http.StatusInternalServerError)
+	marketing.GET("/:provider/contacts", "List customers' sync state on a platform").
+		ID("listMarketingSyncContacts").
+		Query("status", "pending, synced or failed", "").
+		Query("page", "", 0).
+		Query("limit", "", 0).
+		Returns(http.StatusOK, "Sync states", response.Page[[]domain.MarketingSyncState]{}).
+		Fails(http.StatusNotFound, "Platform not configured (MARKETING_SYNC_DISABLED)").
+		Errors(http.StatusBadRequest, http.StatusForbidden, http.StatusInternalServerError)
+	marketing.POST("/:provider/resync", "Push customers to a platform again").
+		ID("resyncMarketing").
+		Description("Queues the given customers, or every failed one when customer_ids is omitted. Customers without marketing consent are skipped.").
+		Body(domain.ResyncMarketingRequest{}).
+		Returns(http.StatusOK, "Resync queued", response.Data[MarketingResyncResult]{}).
+		Fails(http.StatusNotFound, "Platform not configured (MARKETING_SYNC_DISABLED)").
+		Errors(http.StatusBadRequest, http.StatusForbidden, http.StatusInternalServerError)
+
+	system := doc.Group("/api/v1/admin/system", "Admin: System")
+	system.GET("/slo", "SLO compliance and error budget burn per endpoint").
+		ID("getSLO").
+		Returns(http.StatusOK, "SLO report", response.Data[SLOReport]{})
+	system.GET("/notifications", "Notification delivery metrics").
+		ID("getNotificationMetrics").
+		Returns(http.StatusOK, "Metrics", response.Data[any]{}).
+		Errors(http.StatusServiceUnavailable)
+	system.GET("/legacy-crm/drift", "Rows that differ from the legacy CRM").
+		ID("getLegacyCRMDrift").
+		Query("limit", "Rows per table to compare, at most 5000", 0).
+		Returns(http.StatusOK, "Drift report", response.Data[LegacyCRMDrift]{}).
+		Errors(http.StatusBadRequest, http.StatusInternalServerError, http.StatusServiceUnavailable)
+
+	backInStock := doc.Group("/api/v1/admin/back-in-stock", "Admin: Back in stock")
+	backInStock.GET("/stats", "Subscription statistics").
+		ID("getBackInStockStats").
+		Returns(http.StatusOK, "Statistics", response.Data[*domain.BackInStockStats]{}).
+		Errors(http.StatusInternalServerError)
+	backInStock.GET("/subscriptions", "List subscriptions").
+		ID("listBackInStockSubscriptions").
+		Description("Passing cursor (empty for the first page) switches to cursor pagination; meta then carries next_cursor instead of page counts.").
+		Query("pending_only", "", false).
+		Query("page", "", 0).
+		Query("limit", "", 0).
+		Query("cursor", "", "").
+		Returns(http.StatusOK, "Subscriptions", response.Page[[]domain.BackInStockSubscription]{}).
+		Errors(http.StatusBadRequest, http.StatusInternalServerError)
+	backInStock.GET("/export", "Export pending subscriptions as CSV").
+		ID("exportBackInStockSubscriptions").
+		Description("Unexpired subscriptions of customers and confirmed guests that haven't been notified, longest waiting first. "+
+			"Emails are masked unless the admin has customers:pii.").
+		ReturnsFile(http.StatusOK, "CSV export", "text/csv").
+		Errors(http.StatusInternalServerError)
+	backInStock.GET("/demand", "Rank products by restock demand").
+		ID("getBackInStockDemand").
+		Description("Counts the customers and confirmed guests waiting for each product or variant, with their average wait.").
+		Query("sort", "waiting (default): most waiting first; wait: longest average wait first", "").
+		Query("sort_order", "desc (default) or asc to reverse the ranking", "").
+		Query("limit", fmt.Sprintf("Defaults to %d, at most %d", domain.DefaultBackInStockDemandLimit, domain.MaxBackInStockDemandLimit), 0).
+		Returns(http.StatusOK, "Demand report", response.Data[BackInStockDemandReport]{}).
+		Errors(http.StatusBadRequest, http.StatusInternalServerError)
+	backInStock.GET("/products/:productId/subscriptions", "List a product's subscriptions").
+		ID("getProductBackInStockSubscriptions").
+		Query("variant_id", "", "").
+		Returns(http.StatusOK, "Subscriptions", response.Data[ProductSubscriptions]{}).
+		Errors(http.StatusBadRequest, http.StatusInternalServerError)
+	backInStock.POST("/products/:productId/notify", "Notify a product's subscribers now").
+		ID("notifyBackInStockProduct").
+		Description("For restocks whose inventory event was missed or stock adjusted by hand. Notifies up to limit subscribers (0 for all), "+
+			"longest waiting first; throttling and notification preferences apply. dry_run lists who would be notified without sending.").
+		Body(domain.BackInStockNotifyInput{}).
+		Returns(http.StatusOK, "Subscribers notified", response.Data[domain.BackInStockNotifyResult]{}).
+		Errors(http.StatusBadRequest, http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable)
+	backInStock.POST("/mark-notified", "Mark subscriptions as notified").
+		ID("markBackInStockNotified").
+		Body(MarkNotifiedRequest{}).
+		Returns(http.StatusOK, "Subscriptions marked", response.Data[MarkNotifiedResult]{}).
+		Errors(http.StatusBadRequest, http.StatusInternalServerError)
+	backInStock.POST("/test-notification", "Send a subscription's restock notification to an admin").
+		ID("sendBackInStockTestNotification").
+		Body(domain.BackInStockTestNotificationInput{}).
+		Returns(http.StatusOK, "Test notification sent", response.Data[domain.BackInStockNotification]{}).
+		Errors(http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable)
+	backInStock.DELETE("/cleanup", "Delete old notified subscriptions").
+		ID("cleanupBackInStock").
+		Description("Deletes in batches of batch_size rows so no statement locks more than a batch; batches lists how many each deleted.").
+		Query("older_than_days", "Defaults to 30", 0).
+		Query("batch_size", fmt.Sprintf("Defaults to %d, at most %d", persistence.DefaultCleanupBatchSize, maxCleanupBatchSize), 0).
+		Returns(http.StatusOK, "Cleanup completed", response.Data[CleanupResult]{}).
+		Errors(http.StatusInternalServerError)
+}
+
+func documentInternalRoutes(doc *openapi.Document) {
+	wallet := doc.Group("/internal/v1/wallet", "Internal: Wallet").Security(serviceToken, internalAPIKey)
+	wallet.POST("/reservations", "Hold store credit for an order").
+		ID("reserveStoreCredit").
+		Description("amount is in whole cents. Reserving again with the same reference returns the existing reservation, whatever its status, so retries never hold credit twice.").
+		Body(domain.WalletReserveRequest{}).
+		Returns(http.StatusCreated, "Credit reserved", response.Data[*domain.WalletReservation]{}).
+		Errors(http.StatusBadRequest, http.StatusUnauthorized, http.StatusConflict, http.StatusInternalServerError)
+	wallet.POST("/reservations/:id/capture", "Spend a reservation once the order is confirmed").
+		ID("captureStoreCredit").
+		Returns(http.StatusOK, "Reservation captured", response.Data[*domain.WalletReservation]{}).
+		Errors(http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound, http.StatusConflict, http.StatusInternalServerError)
+	wallet.POST("/reservations/:id/release", "Return a reservation when the order is cancelled").
+		ID("releaseStoreCredit").
+		Returns(http.StatusOK, "Reservation released", response.Data[*domain.WalletReservation]{}).
+		Errors(http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound, http.StatusConflict, http.StatusInternalServerError)
+
+	fraud := doc.Group("/internal/v1/customers", "Internal: Fraud").Security(serviceToken, internalAPIKey)
+	fraud.POST("/fraud-check", "Check an order's buyer against the blocklist").
+		ID("checkCustomerFraud").
+		Description("Every field is optional. The order is flagged when the customer is blocked or the email, phone, shipping or billing address matches an unexpired blocklist entry.").
+		Body(domain.FraudCheckRequest{}).
+		Returns(http.StatusOK, "Check result", response.Data[*domain.FraudCheckResult]{}).
+		Errors(http.StatusBadRequest, http.StatusUnauthorized, http.StatusInternalServerError)
+
+	notifications := doc.Group("/internal/v1/customers", "Internal: Notifications").Security(serviceToken, internalAPIKey)
+	notifications.POST("/notification-check", "Check a notification against the customer's preferences").
+		ID("checkCustomerNotification").
+		Description("For notifications sent by other services, such as product review reminders. Returns whether to send now and on which channel; reason is opted_out, quiet_hours (retry_at is when they end) or weekly_limit. An allowed check counts toward the weekly cap, so only check when about to send.").
+		Body(NotificationCheckRequest{}).
+		Returns(http.StatusOK, "Decision", response.Data[domain.NotificationDecision]{}).
+		Errors(http.StatusBadRequest, http.StatusUnauthorized, http.StatusInternalServerError)
+
+	customers := doc.Group("/internal/v1/customers", "Internal: Customers").Security(serviceToken, internalAPIKey)
+	customers.POST("/batch", "Look up customers by ID").
+		ID("getCustomersBatch").
+		Description("Up to 100 IDs. Customers come back in the order asked for; missing lists the IDs of customers that don't exist or were deleted.").
+		Body(domain.CustomerBatchRequest{}).
+		Returns(http.StatusOK, "Customers", response.Data[*domain.CustomerBatchResult]{}).
+		Errors(http.StatusBadRequest, http.StatusUnauthorized, http.StatusInternalServerError)
+	customers.GET("/birthdays", "List the customers whose birthday is on a date").
+		ID("listCustomerBirthdays").
+		Description("For birthday greetings and rewards. Customers born on 29 February are included on 28 February outside leap years.").
+		Query("date", "YYYY-MM-DD, default today in Asia/Kuala_Lumpur", "").
+		Query("limit", "Customers per page, at most 500", 0).
+		Query("cursor", "next_cursor of the previous page", "").
+		Returns(http.StatusOK, "Customers", response.CursorPage[[]domain.BirthdayCustomer]{}).
+		Fails(http.StatusBadRequest, "Invalid date or cursor").
+		Errors(http.StatusUnauthorized, http.StatusInternalServerError)
+}
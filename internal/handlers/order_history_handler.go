@@ -9,7 +9,9 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/Ecom-micro-template/service-customer/internal/middleware"
+	"github.com/Ecom-micro-template/service-customer/internal/authctx"
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/tracing"
+	"github.com/Ecom-micro-template/service-customer/internal/response"
 )
 
 // OrderHistoryHandler handles order history requests
@@ -28,7 +30,8 @@ func NewOrderHistoryHandler() *OrderHistoryHandler {
 	return &OrderHistoryHandler{
 		orderServiceURL: orderURL,
 		httpClient: &http.Client{
-			Timeout: 10 * time.Second,
+			Transport: tracing.Transport(nil),
+			Timeout:   10 * time.Second,
 		},
 	}
 }
@@ -62,9 +65,8 @@ type Order struct {
 // GetOrderHistory retrieves the customer's order history
 // GET /api/v1/customer/orders
 func (h *OrderHistoryHandler) GetOrderHistory(c *gin.Context) {
-	userID, ok := middleware.GetUserID(c)
+	userID, ok := authctx.AuthenticatedUserID(c)
 	if !ok {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
 		return
 	}
 
@@ -76,9 +78,9 @@ func (h *OrderHistoryHandler) GetOrderHistory(c *gin.Context) {
 	url := fmt.Sprintf("%s/api/v1/orders?page=%d&limit=%d", h.orderServiceURL, page, limit)
 
 	// Create request
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := http.NewRequestWithContext(c.Request.Context(), http.MethodGet, url, nil)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create request"})
+		response.InternalServerError(c, "Failed to create request")
 		return
 	}
 
@@ -89,12 +91,7 @@ func (h *OrderHistoryHandler) GetOrderHistory(c *gin.Context) {
 	// Make request to service-order
 	resp, err := h.httpClient.Do(req)
 	if err != nil {
-		c.JSON(http.StatusServiceUnavailable, gin.H{
-			"error":   "Order service unavailable",
-			"orders":  []gin.H{},
-			"total":   0,
-			"user_id": userID.String(),
-		})
+		response.ServiceUnavailable(c, "Order service unavailable")
 		return
 	}
 	defer resp.Body.Close()
@@ -102,43 +99,29 @@ func (h *OrderHistoryHandler) GetOrderHistory(c *gin.Context) {
 	// Parse response
 	var orderResp OrderResponse
 	if err := json.NewDecoder(resp.Body).Decode(&orderResp); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to parse order response"})
+		response.InternalServerError(c, "Failed to parse order response")
 		return
 	}
 
 	if !orderResp.Success {
-		c.JSON(http.StatusOK, gin.H{
-			"orders":  []gin.H{},
-			"total":   0,
-			"page":    page,
-			"limit":   limit,
-			"user_id": userID.String(),
-		})
+		response.Paginated(c, []Order{}, page, limit, 0)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"success": true,
-		"orders":  orderResp.Data.Orders,
-		"total":   orderResp.Data.Total,
-		"page":    orderResp.Data.Page,
-		"limit":   orderResp.Data.Limit,
-		"user_id": userID.String(),
-	})
+	response.Paginated(c, orderResp.Data.Orders, orderResp.Data.Page, orderResp.Data.Limit, orderResp.Data.Total)
 }
 
 // GetOrder retrieves a single order by ID
 // GET /api/v1/customer/orders/:id
 func (h *OrderHistoryHandler) GetOrder(c *gin.Context) {
-	userID, ok := middleware.GetUserID(c)
+	userID, ok := authctx.AuthenticatedUserID(c)
 	if !ok {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
 		return
 	}
 
 	orderID := c.Param("id")
 	if orderID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Order ID required"})
+		response.BadRequest(c, "Order ID required", nil)
 		return
 	}
 
@@ -146,9 +129,9 @@ func (h *OrderHistoryHandler) GetOrder(c *gin.Context) {
 	url := fmt.Sprintf("%s/api/v1/orders/%s", h.orderServiceURL, orderID)
 
 	// Create request
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := http.NewRequestWithContext(c.Request.Context(), http.MethodGet, url, nil)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create request"})
+		response.InternalServerError(c, "Failed to create request")
 		return
 	}
 
@@ -159,7 +142,7 @@ func (h *OrderHistoryHandler) GetOrder(c *gin.Context) {
 	// Make request to service-order
 	resp, err := h.httpClient.Do(req)
 	if err != nil {
-		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Order service unavailable"})
+		response.ServiceUnavailable(c, "Order service unavailable")
 		return
 	}
 	defer resp.Body.Close()
@@ -167,7 +150,7 @@ func (h *OrderHistoryHandler) GetOrder(c *gin.Context) {
 	// Parse response
 	var result map[string]interface{}
 	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to parse order response"})
+		response.InternalServerError(c, "Failed to parse order response")
 		return
 	}
 
@@ -0,0 +1,23 @@
+package handlers
+
+import (
+	"net/url"
+
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+)
+
+// cursorQuery reports whether a listing should use cursor pagination and
+// decodes its cursor. Passing ?cursor= (even empty, for the first page)
+// switches from page-based pagination; the page's next_cursor is then passed
+// back as ?cursor= until it comes back empty.
+func cursorQuery(query url.Values) (after *domain.Cursor, ok bool, err error) {
+	if _, ok = query["cursor"]; !ok {
+		return nil, false, nil
+	}
+	if raw := query.Get("cursor"); raw != "" {
+		if after, err = domain.DecodeCursor(raw); err != nil {
+			return nil, true, err
+		}
+	}
+	return after, true, nil
+}
@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/Ecom-micro-template/service-customer/internal/response"
+)
+
+// VersionConflict is the details of a VERSION_CONFLICT problem: the version
+// the record is at, so the client can reload it before retrying
+type VersionConflict struct {
+	CurrentVersion int64 `json:"current_version"`
+}
+
+// versionETag is the entity tag of a record version, as returned in ETag
+// and expected back in If-Match
+func versionETag(version int64) string {
+	return strconv.Quote(strconv.FormatInt(version, 10))
+}
+
+// setVersionETag sets the ETag of a versioned record
+func setVersionETag(c *gin.Context, version int64) {
+	c.Header("ETag", versionETag(version))
+}
+
+// checkVersion enforces the precondition of an optimistically locked update:
+// the If-Match header, or else the version field of the body. Without either
+// the update applies unconditionally. Writes a 409 and returns false if the
+// record is no longer at the version the client edited.
+func checkVersion(c *gin.Context, bodyVersion *int64, current int64) bool {
+	if ifMatch := c.GetHeader("If-Match"); ifMatch != "" {
+		for _, tag := range strings.Split(ifMatch, ",") {
+			if tag = strings.TrimSpace(tag); tag == "*" || tag == versionETag(current) {
+				return true
+			}
+		}
+	} else if bodyVersion == nil || *bodyVersion == current {
+		return true
+	}
+
+	setVersionETag(c, current)
+	response.FailWith(c, response.CodeVersionConflict, "Modified by another request since it was loaded; reload and retry", VersionConflict{CurrentVersion: current})
+	return false
+}
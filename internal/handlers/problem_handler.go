@@ -0,0 +1,32 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/Ecom-micro-template/service-customer/internal/response"
+)
+
+// ProblemHandler documents the error codes failed requests are reported
+// with; the type URI of every problem points here
+type ProblemHandler struct{}
+
+// NewProblemHandler creates a new problem handler
+func NewProblemHandler() *ProblemHandler {
+	return &ProblemHandler{}
+}
+
+// ListProblems lists every error code with its status and title
+// GET /api/v1/problems
+func (h *ProblemHandler) ListProblems(c *gin.Context) {
+	response.OK(c, "", response.Catalog())
+}
+
+// GetProblem describes the error code of a problem type
+// GET /api/v1/problems/:type
+func (h *ProblemHandler) GetProblem(c *gin.Context) {
+	entry, ok := response.LookupSlug(c.Param("type"))
+	if !ok {
+		response.NotFound(c, "Unknown problem type")
+		return
+	}
+	response.OK(c, "", entry)
+}
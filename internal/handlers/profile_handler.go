@@ -1,19 +1,39 @@
 package handlers
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/Ecom-micro-template/service-customer/internal/middleware"
+	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/authctx"
 	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/Ecom-micro-template/service-customer/internal/domain/shared"
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/imaging"
 	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/persistence"
+	"github.com/Ecom-micro-template/service-customer/internal/response"
 	"gorm.io/gorm"
 )
 
 // ProfileHandler handles profile-related requests
 type ProfileHandler struct {
 	repo *persistence.ProfileRepository
+
+	avatars       AvatarStore
+	avatarBaseURL string
+	avatarMaxSize int64
+}
+
+// AvatarStore keeps the resized avatar files, served publicly from the avatar base URL
+type AvatarStore interface {
+	Put(ctx context.Context, key, contentType string, body io.Reader, size int64) error
+	Delete(ctx context.Context, key string) error
 }
 
 // NewProfileHandler creates a new profile handler
@@ -23,22 +43,34 @@ func NewProfileHandler(db *gorm.DB) *ProfileHandler {
 	}
 }
 
+// WithAvatars enables avatar uploads. A file stored under key is served at
+// baseURL + "/" + key; maxSize bounds an upload in bytes.
+func (h *ProfileHandler) WithAvatars(store AvatarStore, baseURL string, maxSize int64) *ProfileHandler {
+	h.avatars = store
+	h.avatarBaseURL = strings.TrimRight(baseURL, "/")
+	h.avatarMaxSize = maxSize
+	if h.avatarMaxSize <= 0 {
+		h.avatarMaxSize = domain.DefaultAvatarMaxSize
+	}
+	return h
+}
+
 // UpdateProfileRequest represents the request body for updating profile
 type UpdateProfileRequest struct {
 	FullName       string     `json:"full_name"`
-	Email          string     `json:"email"`
+	Email          string     `json:"email"` // only sets a first email; see EmailChangeHandler
 	Phone          string     `json:"phone"`
 	DateOfBirth    *time.Time `json:"date_of_birth"`
 	Gender         string     `json:"gender"`
 	ProfilePicture string     `json:"profile_picture"`
+	Version        *int64     `json:"version"` // optimistic locking; If-Match takes precedence
 }
 
 // GetProfile retrieves the customer's profile
 // GET /api/v1/customer/profile
 func (h *ProfileHandler) GetProfile(c *gin.Context) {
-	userID, ok := middleware.GetUserID(c)
+	userID, ok := authctx.AuthenticatedUserID(c)
 	if !ok {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
 		return
 	}
 
@@ -46,57 +78,60 @@ func (h *ProfileHandler) GetProfile(c *gin.Context) {
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			// Return empty profile if not found
-			c.JSON(http.StatusOK, gin.H{
-				"profile": gin.H{
-					"id":    userID,
-					"email": "",
-				},
-				"message": "Profile not found, please update your profile",
-			})
+			response.OK(c, "Profile not found, please update your profile", &domain.Profile{ID: userID})
 			return
 		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve profile"})
+		response.InternalServerError(c, "Failed to retrieve profile")
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"profile": profile})
+	setVersionETag(c, profile.Version)
+	response.OK(c, "", profile)
 }
 
 // UpdateProfile creates or updates the customer's profile
 // PUT /api/v1/customer/profile
 func (h *ProfileHandler) UpdateProfile(c *gin.Context) {
-	userID, ok := middleware.GetUserID(c)
+	userID, ok := authctx.AuthenticatedUserID(c)
 	if !ok {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
 		return
 	}
 
 	var req UpdateProfileRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		response.Invalid(c, err)
 		return
 	}
 
 	// Get existing profile or create new one
 	profile, err := h.repo.GetByUserID(c.Request.Context(), userID)
 	if err != nil && err != gorm.ErrRecordNotFound {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve profile"})
+		response.InternalServerError(c, "Failed to retrieve profile")
 		return
 	}
 
 	// Create new profile if doesn't exist
-	if profile == nil {
+	exists := profile != nil
+	if !exists {
 		profile = &domain.Profile{
 			ID: userID,
 		}
 	}
+	if !checkVersion(c, req.Version, profile.Version) {
+		return
+	}
 
 	// Update fields
 	if req.FullName != "" {
 		profile.FullName = req.FullName
 	}
-	if req.Email != "" {
-		profile.Email = req.Email
+	if req.Email != "" && !strings.EqualFold(strings.TrimSpace(req.Email), profile.Email) {
+		// Only a first email is set directly; changing it needs verification
+		if profile.Email != "" {
+			response.FromError(c, domain.ErrEmailChangeRequired, "", "Failed to update profile")
+			return
+		}
+		profile.Email = strings.TrimSpace(req.Email)
 	}
 	if req.Phone != "" {
 		profile.Phone = req.Phone
@@ -105,20 +140,115 @@ func (h *ProfileHandler) UpdateProfile(c *gin.Context) {
 		profile.DateOfBirth = req.DateOfBirth
 	}
 	if req.Gender != "" {
-		profile.Gender = req.Gender
+		gender, err := shared.ParseProfileGender(req.Gender)
+		if err != nil {
+			response.BadRequest(c, err.Error(), nil)
+			return
+		}
+		profile.Gender = gender
 	}
 	if req.ProfilePicture != "" {
 		profile.ProfilePicture = req.ProfilePicture
 	}
 
-	// Upsert profile
-	if err := h.repo.Upsert(c.Request.Context(), profile); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update profile"})
+	save := h.repo.Create
+	if exists {
+		save = h.repo.Update
+	}
+	if err := save(c.Request.Context(), profile); err != nil {
+		response.FromError(c, err, "", "Failed to update profile")
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"message": "Profile updated successfully",
-		"profile": profile,
-	})
+	setVersionETag(c, profile.Version)
+	response.OK(c, "Profile updated successfully", profile)
+}
+
+// UploadAvatar replaces the customer's avatar with an uploaded image, sent as
+// the "file" form field. It is cropped to a square and stored in every
+// AvatarSize; the files of the previous avatar are deleted.
+// POST /api/v1/customer/profile/avatar
+func (h *ProfileHandler) UploadAvatar(c *gin.Context) {
+	if h.avatars == nil {
+		response.ServiceUnavailable(c, "Avatar uploads are not enabled")
+		return
+	}
+	userID, ok := authctx.AuthenticatedUserID(c)
+	if !ok {
+		return
+	}
+
+	// Room for the multipart envelope around the file
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.avatarMaxSize+1<<20)
+	header, err := c.FormFile("file")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			response.Fail(c, response.CodeAvatarTooLarge, domain.ErrAvatarTooLarge.Error())
+			return
+		}
+		response.BadRequest(c, "An image is required in the \"file\" form field", nil)
+		return
+	}
+	if header.Size > h.avatarMaxSize {
+		response.Fail(c, response.CodeAvatarTooLarge, domain.ErrAvatarTooLarge.Error())
+		return
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		response.BadRequest(c, "Failed to read the uploaded file", nil)
+		return
+	}
+	defer file.Close()
+	data, err := io.ReadAll(file)
+	if err != nil {
+		response.BadRequest(c, "Failed to read the uploaded file", nil)
+		return
+	}
+
+	images, err := imaging.Avatars(data, domain.AvatarSizes)
+	if err != nil {
+		response.FromError(c, err, "", "Failed to process avatar")
+		return
+	}
+
+	ctx := c.Request.Context()
+	avatarID := uuid.New()
+	urls := make(map[string]string, len(images))
+	for _, size := range domain.AvatarSizes {
+		key := domain.AvatarKey(userID, avatarID, size.Name)
+		image := images[size.Name]
+		if err := h.avatars.Put(ctx, key, "image/jpeg", bytes.NewReader(image), int64(len(image))); err != nil {
+			log.Printf("⚠️  Failed to store avatar %s: %v", key, err)
+			h.deleteAvatar(ctx, userID, avatarID)
+			response.InternalServerError(c, "Failed to store avatar")
+			return
+		}
+		urls[size.Name] = h.avatarBaseURL + "/" + key
+	}
+
+	profile, previous, err := h.repo.SetAvatar(ctx, userID, avatarID, urls)
+	if err != nil {
+		h.deleteAvatar(ctx, userID, avatarID)
+		response.FromError(c, err, "", "Failed to update avatar")
+		return
+	}
+	if previous != nil {
+		h.deleteAvatar(ctx, userID, *previous)
+	}
+
+	setVersionETag(c, profile.Version)
+	response.OK(c, "Avatar updated successfully", profile)
+}
+
+// deleteAvatar removes the files of an avatar upload. Failures are only
+// logged; they leave unused files behind but don't affect the customer.
+func (h *ProfileHandler) deleteAvatar(ctx context.Context, userID, avatarID uuid.UUID) {
+	for _, size := range domain.AvatarSizes {
+		key := domain.AvatarKey(userID, avatarID, size.Name)
+		if err := h.avatars.Delete(ctx, key); err != nil {
+			log.Printf("⚠️  Failed to delete avatar %s: %v", key, err)
+		}
+	}
 }
@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/authctx"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/catalogclient"
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/persistence"
+	"github.com/Ecom-micro-template/service-customer/internal/response"
+	"gorm.io/gorm"
+)
+
+// RecentlyViewedHandler handles the products customers viewed recently
+type RecentlyViewedHandler struct {
+	repo    *persistence.RecentlyViewedRepository
+	catalog RecentlyViewedCatalog
+}
+
+// RecentlyViewedCatalog looks up the products to show for recent views
+type RecentlyViewedCatalog interface {
+	GetProducts(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]catalogclient.Product, error)
+}
+
+// NewRecentlyViewedHandler creates a new recently viewed handler
+func NewRecentlyViewedHandler(db *gorm.DB) *RecentlyViewedHandler {
+	return &RecentlyViewedHandler{
+		repo: persistence.NewRecentlyViewedRepository(db),
+	}
+}
+
+// WithLimit sets how many products are kept per customer
+func (h *RecentlyViewedHandler) WithLimit(limit int) *RecentlyViewedHandler {
+	h.repo.WithLimit(limit)
+	return h
+}
+
+// WithCatalog sets the catalog used to add product details to the listing
+func (h *RecentlyViewedHandler) WithCatalog(catalog RecentlyViewedCatalog) *RecentlyViewedHandler {
+	h.catalog = catalog
+	return h
+}
+
+// RecentlyViewedItem is a recently viewed product with its catalog details
+type RecentlyViewedItem struct {
+	ProductID uuid.UUID  `json:"product_id"`
+	VariantID *uuid.UUID `json:"variant_id,omitempty"`
+	ViewedAt  time.Time  `json:"viewed_at"`
+	Name      string     `json:"name,omitempty"`
+	Slug      string     `json:"slug,omitempty"`
+	Image     string     `json:"image,omitempty"`
+}
+
+// RecentlyViewedItems is the payload of the recently viewed listing; partial
+// is set when product details could not be looked up
+type RecentlyViewedItems struct {
+	Items   []RecentlyViewedItem `json:"items"`
+	Partial bool                 `json:"partial"`
+}
+
+// RecordView records that the customer viewed a product, for the storefront
+// product page
+// POST /api/v1/customer/recently-viewed
+func (h *RecentlyViewedHandler) RecordView(c *gin.Context) {
+	userID, ok := authctx.AuthenticatedUserID(c)
+	if !ok {
+		return
+	}
+
+	var input domain.RecordViewInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		response.Invalid(c, err)
+		return
+	}
+
+	if err := h.repo.Record(c.Request.Context(), userID, input, time.Now()); err != nil {
+		response.InternalServerError(c, "Failed to record view")
+		return
+	}
+
+	response.Created(c, "View recorded", input)
+}
+
+// GetRecentlyViewed returns the customer's recently viewed products, most
+// recent first, with their name, slug and image from the catalog. Products
+// removed from the catalog are left out; if the catalog can't be reached
+// the products are returned without details and the response is marked
+// partial.
+// GET /api/v1/customer/recently-viewed
+func (h *RecentlyViewedHandler) GetRecentlyViewed(c *gin.Context) {
+	userID, ok := authctx.AuthenticatedUserID(c)
+	if !ok {
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(h.repo.Limit())))
+	if limit < 1 || limit > h.repo.Limit() {
+		limit = h.repo.Limit()
+	}
+
+	views, err := h.repo.List(c.Request.Context(), userID, limit)
+	if err != nil {
+		response.InternalServerError(c, "Failed to retrieve recently viewed products")
+		return
+	}
+
+	var products map[uuid.UUID]catalogclient.Product
+	partial := h.catalog == nil
+	if h.catalog != nil && len(views) > 0 {
+		ids := make([]uuid.UUID, len(views))
+		for i, view := range views {
+			ids[i] = view.ProductID
+		}
+		products, err = h.catalog.GetProducts(c.Request.Context(), ids)
+		if err != nil {
+			log.Printf("⚠️  Recently viewed products without catalog details for user %s: %v", userID, err)
+			partial = true
+		}
+	}
+
+	items := make([]RecentlyViewedItem, 0, len(views))
+	for _, view := range views {
+		item := RecentlyViewedItem{
+			ProductID: view.ProductID,
+			VariantID: view.VariantID,
+			ViewedAt:  view.ViewedAt,
+		}
+		if !partial {
+			product, found := products[view.ProductID]
+			if !found {
+				continue
+			}
+			item.Name, item.Slug, item.Image = product.Name, product.Slug, product.Image
+		}
+		items = append(items, item)
+	}
+
+	response.OK(c, "", RecentlyViewedItems{
+		Items:   items,
+		Partial: partial,
+	})
+}
+
+// ClearRecentlyViewed forgets the customer's recently viewed products
+// DELETE /api/v1/customer/recently-viewed
+func (h *RecentlyViewedHandler) ClearRecentlyViewed(c *gin.Context) {
+	userID, ok := authctx.AuthenticatedUserID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.repo.Clear(c.Request.Context(), userID); err != nil {
+		response.InternalServerError(c, "Failed to clear recently viewed products")
+		return
+	}
+
+	response.Deleted(c, "Recently viewed products cleared")
+}
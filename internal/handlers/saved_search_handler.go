@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/authctx"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/persistence"
+	"github.com/Ecom-micro-template/service-customer/internal/response"
+	"gorm.io/gorm"
+)
+
+// SavedSearchHandler handles customers' saved catalog searches
+type SavedSearchHandler struct {
+	repo *persistence.SavedSearchRepository
+}
+
+// NewSavedSearchHandler creates a new saved search handler
+func NewSavedSearchHandler(db *gorm.DB) *SavedSearchHandler {
+	return &SavedSearchHandler{
+		repo: persistence.NewSavedSearchRepository(db),
+	}
+}
+
+// ListSavedSearches returns the customer's saved searches, newest first
+// GET /api/v1/customer/saved-searches
+func (h *SavedSearchHandler) ListSavedSearches(c *gin.Context) {
+	userID, ok := authctx.AuthenticatedUserID(c)
+	if !ok {
+		return
+	}
+
+	searches, err := h.repo.List(c.Request.Context(), userID)
+	if err != nil {
+		response.InternalServerError(c, "Failed to retrieve saved searches")
+		return
+	}
+
+	response.OK(c, "", searches)
+}
+
+// SaveSearch saves a catalog search. Alerts for new products matching it are
+// on unless alerts_enabled is false.
+// POST /api/v1/customer/saved-searches
+func (h *SavedSearchHandler) SaveSearch(c *gin.Context) {
+	userID, ok := authctx.AuthenticatedUserID(c)
+	if !ok {
+		return
+	}
+
+	var req domain.SaveSearchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Invalid(c, err)
+		return
+	}
+	search := &domain.SavedSearch{UserID: userID}
+	if err := req.Apply(search); err != nil {
+		response.BadRequest(c, err.Error(), nil)
+		return
+	}
+
+	if err := h.repo.Create(c.Request.Context(), search); err != nil {
+		response.FromError(c, err, "", "Failed to save search")
+		return
+	}
+
+	response.Created(c, "Search saved", search)
+}
+
+// UpdateSavedSearch replaces a saved search's name, query, filters and alert
+// setting
+// PUT /api/v1/customer/saved-searches/:id
+func (h *SavedSearchHandler) UpdateSavedSearch(c *gin.Context) {
+	search, ok := h.ownSearch(c)
+	if !ok {
+		return
+	}
+
+	var req domain.SaveSearchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Invalid(c, err)
+		return
+	}
+	if err := req.Apply(search); err != nil {
+		response.BadRequest(c, err.Error(), nil)
+		return
+	}
+
+	if err := h.repo.Update(c.Request.Context(), search); err != nil {
+		response.InternalServerError(c, "Failed to update saved search")
+		return
+	}
+
+	response.Updated(c, "Saved search updated", search)
+}
+
+// DeleteSavedSearch removes a saved search and its alerts
+// DELETE /api/v1/customer/saved-searches/:id
+func (h *SavedSearchHandler) DeleteSavedSearch(c *gin.Context) {
+	userID, ok := authctx.AuthenticatedUserID(c)
+	if !ok {
+		return
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "Invalid saved search ID", nil)
+		return
+	}
+
+	if err := h.repo.Delete(c.Request.Context(), id, userID); err != nil {
+		response.FromError(c, err, response.CodeSavedSearchNotFound, "Failed to delete saved search")
+		return
+	}
+
+	response.Deleted(c, "Saved search deleted")
+}
+
+// ownSearch loads the customer's :id saved search, writing the error response
+// if there is none
+func (h *SavedSearchHandler) ownSearch(c *gin.Context) (*domain.SavedSearch, bool) {
+	userID, ok := authctx.AuthenticatedUserID(c)
+	if !ok {
+		return nil, false
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "Invalid saved search ID", nil)
+		return nil, false
+	}
+
+	search, err := h.repo.Get(c.Request.Context(), id, userID)
+	if err != nil {
+		response.FromError(c, err, response.CodeSavedSearchNotFound, "Failed to retrieve saved search")
+		return nil, false
+	}
+	return search, true
+}
@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/response"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/Ecom-micro-template/service-customer/internal/domain/shared"
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/persistence"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// AdminSegmentRuleHandler manages automatic segment assignment rules
+type AdminSegmentRuleHandler struct {
+	repo   *persistence.SegmentRuleRepository
+	logger *zap.Logger
+}
+
+// NewAdminSegmentRuleHandler creates a new segment rule handler
+func NewAdminSegmentRuleHandler(db *gorm.DB, logger *zap.Logger) *AdminSegmentRuleHandler {
+	return &AdminSegmentRuleHandler{
+		repo:   persistence.NewSegmentRuleRepository(db),
+		logger: logger,
+	}
+}
+
+// ListRules handles GET /admin/segments/rules
+func (h *AdminSegmentRuleHandler) ListRules(c *gin.Context) {
+	rules, err := h.repo.List(c.Request.Context())
+	if err != nil {
+		h.logger.Error("Failed to list segment rules", zap.Error(err))
+		response.InternalServerError(c, "Failed to retrieve segment rules")
+		return
+	}
+
+	response.OK(c, "Segment rules retrieved", rules)
+}
+
+// CreateRule handles POST /admin/segments/rules
+func (h *AdminSegmentRuleHandler) CreateRule(c *gin.Context) {
+	var req domain.CreateSegmentRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Invalid(c, err)
+		return
+	}
+	if req.Action != domain.SegmentActionUnassignMarketing && req.SegmentID == nil {
+		response.BadRequest(c, "segment_id is required for assign and unassign rules", nil)
+		return
+	}
+	if req.Status != nil && !req.Status.IsValid() {
+		response.BadRequest(c, "Invalid request", shared.ErrInvalidCustomerStatus.Error())
+		return
+	}
+
+	rule := &domain.SegmentRule{
+		Name:          req.Name,
+		Trigger:       req.Trigger,
+		Action:        req.Action,
+		SegmentID:     req.SegmentID,
+		Priority:      req.Priority,
+		IsActive:      true,
+		MinOrders:     req.MinOrders,
+		MaxOrders:     req.MaxOrders,
+		MinTotalSpent: req.MinTotalSpent,
+		Status:        req.Status,
+		RFMSegment:    req.RFMSegment,
+		MinRecency:    req.MinRecency,
+		MinFrequency:  req.MinFrequency,
+		MinMonetary:   req.MinMonetary,
+		MinCLV:        req.MinCLV,
+	}
+	if err := h.repo.Create(c.Request.Context(), rule); err != nil {
+		h.logger.Error("Failed to create segment rule", zap.Error(err))
+		response.InternalServerError(c, "Failed to create segment rule")
+		return
+	}
+
+	response.Created(c, "Segment rule created successfully", rule)
+}
+
+// DeleteRule handles DELETE /admin/segments/rules/:ruleId
+func (h *AdminSegmentRuleHandler) DeleteRule(c *gin.Context) {
+	ruleID, err := uuid.Parse(c.Param("ruleId"))
+	if err != nil {
+		response.BadRequest(c, "Invalid rule ID", nil)
+		return
+	}
+
+	if err := h.repo.Delete(c.Request.Context(), ruleID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			response.Fail(c, response.CodeSegmentRuleNotFound, "Segment rule not found")
+			return
+		}
+		h.logger.Error("Failed to delete segment rule", zap.Error(err))
+		response.InternalServerError(c, "Failed to delete segment rule")
+		return
+	}
+
+	response.Deleted(c, "Segment rule deleted successfully")
+}
+
+// SimulateRulesRequest represents the request body for simulating segment rules
+type SimulateRulesRequest struct {
+	CustomerID uuid.UUID `json:"customer_id" binding:"required"`
+	Trigger    string    `json:"trigger" binding:"required,oneof=order_completed status_changed"`
+}
+
+// SimulateRules handles POST /admin/segments/rules/simulate
+// It shows which rules would fire for a customer without changing any assignments.
+func (h *AdminSegmentRuleHandler) SimulateRules(c *gin.Context) {
+	var req SimulateRulesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Invalid(c, err)
+		return
+	}
+
+	evaluation, err := h.repo.Evaluate(c.Request.Context(), req.CustomerID, req.Trigger)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			response.Fail(c, response.CodeCustomerNotFound, "Customer not found")
+			return
+		}
+		h.logger.Error("Failed to simulate segment rules", zap.Error(err))
+		response.InternalServerError(c, "Failed to simulate segment rules")
+		return
+	}
+
+	response.OK(c, "Segment rules simulated", evaluation)
+}
@@ -0,0 +1,258 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nats-io/nats.go"
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/legacycrm"
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/metrics"
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/notificationclient"
+	"github.com/Ecom-micro-template/service-customer/internal/warmup"
+	"github.com/Ecom-micro-template/service-customer/internal/response"
+	"gorm.io/gorm"
+)
+
+// AdminSystemHandler exposes operational status to admins and on-call
+type AdminSystemHandler struct {
+	slo           *metrics.SLOTracker
+	notifications *notificationclient.Client
+	legacyCRM     *legacycrm.Bridge
+}
+
+// maxDriftLimit caps how many rows per table one drift report compares
+const maxDriftLimit = 5000
+
+// NewAdminSystemHandler creates a new admin system handler
+func NewAdminSystemHandler(slo *metrics.SLOTracker) *AdminSystemHandler {
+	return &AdminSystemHandler{slo: slo}
+}
+
+// WithNotificationClient reports the notification client's delivery metrics
+func (h *AdminSystemHandler) WithNotificationClient(client *notificationclient.Client) *AdminSystemHandler {
+	h.notifications = client
+	return h
+}
+
+// WithLegacyCRM reports drift between this service and the legacy CRM
+func (h *AdminSystemHandler) WithLegacyCRM(bridge *legacycrm.Bridge) *AdminSystemHandler {
+	h.legacyCRM = bridge
+	return h
+}
+
+// SLOReport is the payload of the SLO report; alerting counts the endpoints
+// burning their error budget too fast
+type SLOReport struct {
+	GeneratedAt time.Time                 `json:"generated_at"`
+	Alerting    int                       `json:"alerting"`
+	Endpoints   []metrics.EndpointSummary `json:"endpoints"`
+}
+
+// LegacyCRMDrift is the payload of the legacy CRM drift report
+type LegacyCRMDrift struct {
+	*legacycrm.DriftReport
+	InSync bool `json:"in_sync"`
+}
+
+// GetSLO returns per-endpoint SLO compliance and error budget burn rates
+// GET /api/v1/admin/system/slo
+func (h *AdminSystemHandler) GetSLO(c *gin.Context) {
+	endpoints := h.slo.Summary()
+
+	alerting := 0
+	for _, endpoint := range endpoints {
+		if endpoint.Alert != "none" {
+			alerting++
+		}
+	}
+
+	response.OK(c, "", SLOReport{
+		GeneratedAt: time.Now().UTC(),
+		Alerting:    alerting,
+		Endpoints:   endpoints,
+	})
+}
+
+// GetNotificationMetrics returns notification delivery counters, errors by
+// kind and the circuit breaker state
+// GET /api/v1/admin/system/notifications
+func (h *AdminSystemHandler) GetNotificationMetrics(c *gin.Context) {
+	if h.notifications == nil {
+		response.ServiceUnavailable(c, "Notification client not configured")
+		return
+	}
+
+	response.OK(c, "", h.notifications.Metrics())
+}
+
+// GetLegacyCRMDrift compares the most recently changed customers and
+// addresses with the legacy CRM and reports missing, stale and mismatched rows
+// GET /api/v1/admin/system/legacy-crm/drift
+func (h *AdminSystemHandler) GetLegacyCRMDrift(c *gin.Context) {
+	if h.legacyCRM == nil {
+		response.ServiceUnavailable(c, "Legacy CRM bridge not configured")
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "500"))
+	if limit < 1 || limit > maxDriftLimit {
+		response.BadRequest(c, "limit must be between 1 and 5000", nil)
+		return
+	}
+
+	report, err := h.legacyCRM.Drift(c.Request.Context(), limit)
+	if err != nil {
+		response.InternalServerError(c, "Failed to check legacy CRM drift")
+		return
+	}
+
+	response.OK(c, "", LegacyCRMDrift{DriftReport: report, InSync: report.InSync()})
+}
+
+// readinessTimeout bounds the dependency checks so a hung database fails the
+// probe instead of hanging it
+const readinessTimeout = 2 * time.Second
+
+// ReadinessHandler reports whether the service is alive and whether it should
+// receive traffic
+type ReadinessHandler struct {
+	db      *gorm.DB
+	replica *sql.DB
+	nc      *nats.Conn
+	natsOn  bool
+	warmer  *warmup.Warmer
+}
+
+// NewReadinessHandler creates a new readiness handler
+func NewReadinessHandler(db *gorm.DB) *ReadinessHandler {
+	return &ReadinessHandler{db: db}
+}
+
+// WithWarmer keeps the service unready until startup warm-up has finished
+func (h *ReadinessHandler) WithWarmer(warmer *warmup.Warmer) *ReadinessHandler {
+	h.warmer = warmer
+	return h
+}
+
+// WithReplica reports the read replica. replica is nil when none is
+// configured. Only admin reports read from it, so an unreachable replica
+// degrades the service but keeps it ready.
+func (h *ReadinessHandler) WithReplica(replica *sql.DB) *ReadinessHandler {
+	h.replica = replica
+	return h
+}
+
+// WithNATS reports the NATS connection. nc is nil when the service started
+// without NATS. Events only drive background work, so a lost connection
+// degrades the service but keeps it ready.
+func (h *ReadinessHandler) WithNATS(nc *nats.Conn) *ReadinessHandler {
+	h.nc = nc
+	h.natsOn = true
+	return h
+}
+
+// replicationLagQuery returns how far, in seconds, the replica is behind the
+// primary; 0 on a server that isn't replaying
+const replicationLagQuery = `SELECT COALESCE(EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp()), 0)`
+
+// poolStats summarises a connection pool for the readiness probe
+func poolStats(stats sql.DBStats) gin.H {
+	return gin.H{
+		"open":          stats.OpenConnections,
+		"in_use":        stats.InUse,
+		"idle":          stats.Idle,
+		"max_open":      stats.MaxOpenConnections,
+		"wait_count":    stats.WaitCount,
+		"wait_duration": stats.WaitDuration.String(),
+	}
+}
+
+// Live is the liveness probe: the process is up and serving requests. It
+// checks no dependencies so an outage elsewhere doesn't get pods restarted.
+// GET /health/live
+func (h *ReadinessHandler) Live(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "healthy",
+		"service": "customer",
+		"time":    time.Now().UTC(),
+	})
+}
+
+// Ready is the readiness probe. It fails while the database pool can't reach
+// the database or warm-up is still running, and reports "degraded" while NATS
+// is disconnected or the read replica is unreachable.
+// GET /health/ready
+func (h *ReadinessHandler) Ready(c *gin.Context) {
+	body := gin.H{
+		"service": "customer",
+		"time":    time.Now().UTC(),
+	}
+	ready, degraded := true, false
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), readinessTimeout)
+	defer cancel()
+	sqlDB, err := h.db.DB()
+	if err == nil {
+		err = sqlDB.PingContext(ctx)
+	}
+	if err != nil {
+		ready = false
+		body["database"] = "unreachable"
+	} else {
+		body["database"] = "ok"
+		body["database_pool"] = poolStats(sqlDB.Stats())
+	}
+
+	if h.replica != nil {
+		var lag float64
+		err := h.replica.PingContext(ctx)
+		if err == nil {
+			err = h.replica.QueryRowContext(ctx, replicationLagQuery).Scan(&lag)
+		}
+		if err != nil {
+			degraded = true
+			body["database_replica"] = "unreachable"
+		} else {
+			body["database_replica"] = "ok"
+			body["database_replica_pool"] = poolStats(h.replica.Stats())
+			body["database_replica_lag_seconds"] = lag
+		}
+	}
+
+	if h.natsOn {
+		switch {
+		case h.nc == nil:
+			degraded = true
+			body["nats"] = "not_connected"
+		case h.nc.Status() == nats.CONNECTED:
+			body["nats"] = "ok"
+		default:
+			degraded = true
+			body["nats"] = strings.ToLower(h.nc.Status().String())
+		}
+	}
+
+	if h.warmer != nil {
+		body["warmup"] = h.warmer.Status()
+		if !h.warmer.Ready() {
+			ready = false
+		}
+	}
+
+	switch {
+	case !ready:
+		body["status"] = "not_ready"
+		c.JSON(http.StatusServiceUnavailable, body)
+	case degraded:
+		body["status"] = "degraded"
+		c.JSON(http.StatusOK, body)
+	default:
+		body["status"] = "ready"
+		c.JSON(http.StatusOK, body)
+	}
+}
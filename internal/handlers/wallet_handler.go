@@ -0,0 +1,253 @@
+package handlers
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/authctx"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/persistence"
+	"github.com/Ecom-micro-template/service-customer/internal/response"
+	"gorm.io/gorm"
+)
+
+// WalletHandler handles customer store credit requests
+type WalletHandler struct {
+	repo *persistence.WalletRepository
+}
+
+// NewWalletHandler creates a new wallet handler
+func NewWalletHandler(db *gorm.DB) *WalletHandler {
+	return &WalletHandler{
+		repo: persistence.NewWalletRepository(db),
+	}
+}
+
+// WalletBalance is the payload of a wallet lookup; available excludes the
+// credit held by pending reservations
+type WalletBalance struct {
+	Wallet    *domain.CustomerWallet `json:"wallet"`
+	Available float64                `json:"available"`
+}
+
+// GetWallet returns the customer's store credit balance
+// GET /api/v1/customer/wallet
+func (h *WalletHandler) GetWallet(c *gin.Context) {
+	userID, ok := authctx.AuthenticatedUserID(c)
+	if !ok {
+		return
+	}
+
+	wallet, err := h.repo.GetOrCreate(c.Request.Context(), userID)
+	if err != nil {
+		response.InternalServerError(c, "Failed to retrieve wallet")
+		return
+	}
+
+	response.OK(c, "", WalletBalance{
+		Wallet:    wallet,
+		Available: wallet.Available(),
+	})
+}
+
+// GetTransactions returns the customer's store credit ledger
+// GET /api/v1/customer/wallet/transactions
+func (h *WalletHandler) GetTransactions(c *gin.Context) {
+	userID, ok := authctx.AuthenticatedUserID(c)
+	if !ok {
+		return
+	}
+
+	page, limit := walletPagination(c)
+
+	transactions, total, err := h.repo.ListTransactions(c.Request.Context(), userID, page, limit)
+	if err != nil {
+		response.InternalServerError(c, "Failed to retrieve transactions")
+		return
+	}
+
+	response.Paginated(c, transactions, page, limit, total)
+}
+
+// Admin Handler
+
+// AdminWalletHandler handles admin store credit operations
+type AdminWalletHandler struct {
+	repo *persistence.WalletRepository
+}
+
+// NewAdminWalletHandler creates a new admin wallet handler
+func NewAdminWalletHandler(db *gorm.DB) *AdminWalletHandler {
+	return &AdminWalletHandler{
+		repo: persistence.NewWalletRepository(db),
+	}
+}
+
+// AdminWallet is the payload of the admin wallet lookup; meta paginates
+// the transactions
+type AdminWallet struct {
+	WalletBalance
+	Transactions []domain.WalletTransaction `json:"transactions"`
+}
+
+// GetWallet returns a customer's wallet and recent ledger
+// GET /api/v1/admin/customers/:id/wallet
+func (h *AdminWalletHandler) GetWallet(c *gin.Context) {
+	customerID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "Invalid customer ID", nil)
+		return
+	}
+
+	page, limit := walletPagination(c)
+
+	wallet, err := h.repo.GetOrCreate(c.Request.Context(), customerID)
+	if err != nil {
+		response.InternalServerError(c, "Failed to retrieve wallet")
+		return
+	}
+
+	transactions, total, err := h.repo.ListTransactions(c.Request.Context(), customerID, page, limit)
+	if err != nil {
+		response.InternalServerError(c, "Failed to retrieve transactions")
+		return
+	}
+
+	response.Paginated(c, AdminWallet{
+		WalletBalance: WalletBalance{
+			Wallet:    wallet,
+			Available: wallet.Available(),
+		},
+		Transactions: transactions,
+	}, page, limit, total)
+}
+
+// Grant adds store credit to a customer's wallet
+// POST /api/v1/admin/customers/:id/wallet/credit
+func (h *AdminWalletHandler) Grant(c *gin.Context) {
+	h.adjust(c, domain.WalletTxnCredit)
+}
+
+// Deduct removes store credit from a customer's wallet
+// POST /api/v1/admin/customers/:id/wallet/debit
+func (h *AdminWalletHandler) Deduct(c *gin.Context) {
+	h.adjust(c, domain.WalletTxnDebit)
+}
+
+func (h *AdminWalletHandler) adjust(c *gin.Context, txnType string) {
+	customerID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "Invalid customer ID", nil)
+		return
+	}
+
+	var req domain.WalletAdjustmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Invalid(c, err)
+		return
+	}
+
+	var createdBy *uuid.UUID
+	if adminID := authctx.UserID(c); adminID != uuid.Nil {
+		createdBy = &adminID
+	}
+
+	var txn *domain.WalletTransaction
+	if txnType == domain.WalletTxnCredit {
+		txn, err = h.repo.Credit(c.Request.Context(), customerID, req.Amount, req.Reason, createdBy)
+	} else {
+		txn, err = h.repo.Debit(c.Request.Context(), customerID, req.Amount, req.Reason, createdBy)
+	}
+	if err != nil {
+		writeWalletError(c, err)
+		return
+	}
+
+	response.Created(c, "Wallet updated", txn)
+}
+
+// Internal Handler
+
+// InternalWalletHandler exposes reserve/capture/release for the order service
+type InternalWalletHandler struct {
+	repo *persistence.WalletRepository
+}
+
+// NewInternalWalletHandler creates a new internal wallet handler
+func NewInternalWalletHandler(db *gorm.DB) *InternalWalletHandler {
+	return &InternalWalletHandler{
+		repo: persistence.NewWalletRepository(db),
+	}
+}
+
+// Reserve holds store credit for an order
+// POST /internal/v1/wallet/reservations
+func (h *InternalWalletHandler) Reserve(c *gin.Context) {
+	var req domain.WalletReserveRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Invalid(c, err)
+		return
+	}
+
+	reservation, err := h.repo.Reserve(c.Request.Context(), req.CustomerID, req.Amount, req.Reference)
+	if err != nil {
+		writeWalletError(c, err)
+		return
+	}
+
+	response.Created(c, "", reservation)
+}
+
+// Capture spends a reservation once the order is confirmed
+// POST /internal/v1/wallet/reservations/:id/capture
+func (h *InternalWalletHandler) Capture(c *gin.Context) {
+	reservationID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "Invalid reservation ID", nil)
+		return
+	}
+
+	reservation, err := h.repo.Capture(c.Request.Context(), reservationID)
+	if err != nil {
+		writeWalletError(c, err)
+		return
+	}
+
+	response.OK(c, "", reservation)
+}
+
+// Release returns a reservation to the customer when the order is cancelled
+// POST /internal/v1/wallet/reservations/:id/release
+func (h *InternalWalletHandler) Release(c *gin.Context) {
+	reservationID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "Invalid reservation ID", nil)
+		return
+	}
+
+	reservation, err := h.repo.Release(c.Request.Context(), reservationID)
+	if err != nil {
+		writeWalletError(c, err)
+		return
+	}
+
+	response.OK(c, "", reservation)
+}
+
+// writeWalletError maps wallet domain errors to their problems
+func writeWalletError(c *gin.Context, err error) {
+	response.FromError(c, err, "", "Failed to update wallet")
+}
+
+func walletPagination(c *gin.Context) (int, int) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+	return page, limit
+}
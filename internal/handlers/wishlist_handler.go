@@ -1,27 +1,88 @@
 package handlers
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/authctx"
 	"github.com/Ecom-micro-template/service-customer/internal/middleware"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/catalogclient"
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/inventoryclient"
 	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/persistence"
+	"github.com/Ecom-micro-template/service-customer/internal/response"
 	"gorm.io/gorm"
 )
 
+// maxWishlistImportSize bounds the size of an uploaded wishlist import file
+const maxWishlistImportSize = 1 << 20
+
 // WishlistHandler handles wishlist-related requests
 type WishlistHandler struct {
-	repo *persistence.WishlistRepository
+	repo      *persistence.WishlistRepository
+	catalog   WishlistCatalog
+	inventory WishlistInventory
+}
+
+// WishlistCatalog resolves imported SKUs and product URLs to catalog products
+type WishlistCatalog interface {
+	Resolve(ctx context.Context, skus, slugs []string) (*catalogclient.Matches, error)
+	GetProducts(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]catalogclient.Product, error)
+}
+
+// WishlistInventory looks up live stock for wishlist items. It returns
+// whatever it could look up alongside an error for the rest.
+type WishlistInventory interface {
+	GetStock(ctx context.Context, items []inventoryclient.Item) (map[string]inventoryclient.Stock, error)
+}
+
+// WishlistStockStatus is the stock badge of one wishlist item
+type WishlistStockStatus struct {
+	ItemID    uuid.UUID  `json:"item_id"`
+	ProductID uuid.UUID  `json:"product_id"`
+	VariantID *uuid.UUID `json:"variant_id,omitempty"`
+	inventoryclient.Stock
 }
 
 // NewWishlistHandler creates a new wishlist handler
 func NewWishlistHandler(db *gorm.DB) *WishlistHandler {
 	return &WishlistHandler{
-		repo: persistence.NewWishlistRepository(db),
+		repo: persistence.NewWishlistRepository(db).WithLimits(persistence.NewCustomerLimitRepository(db, domain.DefaultCustomerLimits())),
 	}
 }
 
+// WithCatalog sets the catalog used to resolve wishlist imports
+func (h *WishlistHandler) WithCatalog(catalog WishlistCatalog) *WishlistHandler {
+	h.catalog = catalog
+	return h
+}
+
+// WithInventory sets the inventory used for wishlist stock badges
+func (h *WishlistHandler) WithInventory(inventory WishlistInventory) *WishlistHandler {
+	h.inventory = inventory
+	return h
+}
+
+// WithLimits sets the per-customer caps, including how many items one
+// customer's wishlist may hold
+func (h *WishlistHandler) WithLimits(limits *persistence.CustomerLimitRepository) *WishlistHandler {
+	h.repo.WithLimits(limits)
+	return h
+}
+
+// WithAnalytics reports items added to and removed from wishlists to events
+func (h *WishlistHandler) WithAnalytics(events persistence.WishlistEvents) *WishlistHandler {
+	h.repo.WithEvents(events)
+	return h
+}
+
 // AddToWishlistRequest represents the request body for adding to wishlist
 type AddToWishlistRequest struct {
 	ProductID    uuid.UUID  `json:"product_id" binding:"required"`
@@ -30,52 +91,93 @@ type AddToWishlistRequest struct {
 	VariantName  *string    `json:"variant_name,omitempty"` // e.g., "Red / Large"
 	PriceAtAdd   float64    `json:"price_at_add,omitempty"`
 	NotifyOnSale *bool      `json:"notify_on_sale,omitempty"`
-	ProductName  *string    `json:"product_name,omitempty"`
-	ProductSlug  *string    `json:"product_slug,omitempty"`
-	ProductImage *string    `json:"product_image,omitempty"`
+	// Subscribe to back-in-stock when the product goes out of stock
+	NotifyRestock *bool   `json:"notify_restock,omitempty"`
+	ProductName   *string `json:"product_name,omitempty"`
+	ProductSlug   *string `json:"product_slug,omitempty"`
+	ProductImage  *string `json:"product_image,omitempty"`
 }
 
 // UpdateWishlistItemRequest represents the request body for updating a wishlist item
 type UpdateWishlistItemRequest struct {
-	NotifyOnSale *bool `json:"notify_on_sale"`
+	NotifyOnSale  *bool `json:"notify_on_sale"`
+	NotifyRestock *bool `json:"notify_restock"`
+}
+
+// WishlistItems is the payload of a wishlist listing
+type WishlistItems struct {
+	Items []domain.WishlistItem `json:"items"`
+	Count int                   `json:"count"`
+}
+
+// WishlistProduct identifies the product (and variant) added to the wishlist
+type WishlistProduct struct {
+	ProductID uuid.UUID  `json:"product_id"`
+	VariantID *uuid.UUID `json:"variant_id"`
+}
+
+// WishlistCheck represents whether a product/variant is in the wishlist
+type WishlistCheck struct {
+	InWishlist bool       `json:"in_wishlist"`
+	ProductID  uuid.UUID  `json:"product_id"`
+	VariantID  *uuid.UUID `json:"variant_id"`
+}
+
+// WishlistCheckBatch maps each checked product (ProductRef.Key) to whether
+// it is in the wishlist
+type WishlistCheckBatch struct {
+	InWishlist map[string]bool `json:"in_wishlist"`
+}
+
+// WishlistConfirmation is the number of items kept after a "still
+// interested?" nudge
+type WishlistConfirmation struct {
+	Confirmed int64 `json:"confirmed"`
+}
+
+// WishlistCount represents the number of wishlist items
+type WishlistCount struct {
+	Count int64 `json:"count"`
+}
+
+// WishlistStockStatuses is the payload of the wishlist stock status; partial
+// is set when some items' stock could not be looked up live
+type WishlistStockStatuses struct {
+	Items   []WishlistStockStatus `json:"items"`
+	Partial bool                  `json:"partial"`
 }
 
 // GetWishlist retrieves the customer's wishlist
 // GET /api/v1/customer/wishlist
 func (h *WishlistHandler) GetWishlist(c *gin.Context) {
-	userID, ok := middleware.GetUserID(c)
+	userID, ok := authctx.AuthenticatedUserID(c)
 	if !ok {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
 		return
 	}
 
 	items, err := h.repo.ListByUserID(c.Request.Context(), userID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve wishlist"})
+		response.InternalServerError(c, "Failed to retrieve wishlist")
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"success": true,
-		"data": gin.H{
-			"items": items,
-			"count": len(items),
-		},
+	response.OK(c, "", WishlistItems{
+		Items: items,
+		Count: len(items),
 	})
 }
 
 // AddToWishlist adds a product/variant to the wishlist
 // POST /api/v1/customer/wishlist
 func (h *WishlistHandler) AddToWishlist(c *gin.Context) {
-	userID, ok := middleware.GetUserID(c)
+	userID, ok := authctx.AuthenticatedUserID(c)
 	if !ok {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
 		return
 	}
 
 	var req AddToWishlistRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		response.Invalid(c, err)
 		return
 	}
 
@@ -85,42 +187,46 @@ func (h *WishlistHandler) AddToWishlist(c *gin.Context) {
 	}
 
 	input := persistence.AddWishlistItemInput{
-		ProductID:    req.ProductID,
-		VariantID:    req.VariantID,
-		VariantSKU:   req.VariantSKU,
-		VariantName:  req.VariantName,
-		PriceAtAdd:   req.PriceAtAdd,
-		NotifyOnSale: notifyOnSale,
-		ProductName:  req.ProductName,
-		ProductSlug:  req.ProductSlug,
-		ProductImage: req.ProductImage,
+		ProductID:     req.ProductID,
+		VariantID:     req.VariantID,
+		VariantSKU:    req.VariantSKU,
+		VariantName:   req.VariantName,
+		PriceAtAdd:    req.PriceAtAdd,
+		NotifyOnSale:  notifyOnSale,
+		NotifyRestock: req.NotifyRestock != nil && *req.NotifyRestock,
+		ProductName:   req.ProductName,
+		ProductSlug:   req.ProductSlug,
+		ProductImage:  req.ProductImage,
 	}
 
 	if err := h.repo.AddWithVariant(c.Request.Context(), userID, input); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add to wishlist"})
+		response.FromError(c, err, "", "Failed to add to wishlist")
 		return
 	}
 
-	c.JSON(http.StatusCreated, gin.H{
-		"success":    true,
-		"message":    "Added to wishlist",
-		"product_id": req.ProductID,
-		"variant_id": req.VariantID,
+	if req.ProductName != nil && *req.ProductName != "" {
+		middleware.SetActivityDetails(c, *req.ProductName)
+	} else {
+		middleware.SetActivityDetails(c, "product "+req.ProductID.String())
+	}
+
+	response.Created(c, "Added to wishlist", WishlistProduct{
+		ProductID: req.ProductID,
+		VariantID: req.VariantID,
 	})
 }
 
 // RemoveFromWishlist removes a product from the wishlist (all variants)
 // DELETE /api/v1/customer/wishlist/:productId
 func (h *WishlistHandler) RemoveFromWishlist(c *gin.Context) {
-	userID, ok := middleware.GetUserID(c)
+	userID, ok := authctx.AuthenticatedUserID(c)
 	if !ok {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
 		return
 	}
 
 	productID, err := uuid.Parse(c.Param("productId"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		response.BadRequest(c, "Invalid product ID", nil)
 		return
 	}
 
@@ -130,7 +236,7 @@ func (h *WishlistHandler) RemoveFromWishlist(c *gin.Context) {
 	if variantIDStr != "" {
 		parsed, err := uuid.Parse(variantIDStr)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid variant ID"})
+			response.BadRequest(c, "Invalid variant ID", nil)
 			return
 		}
 		variantID = &parsed
@@ -145,99 +251,97 @@ func (h *WishlistHandler) RemoveFromWishlist(c *gin.Context) {
 
 	if removeErr != nil {
 		if removeErr == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Item not in wishlist"})
+			response.Fail(c, response.CodeWishlistItemNotFound, "Item not in wishlist")
 			return
 		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove from wishlist"})
+		response.InternalServerError(c, "Failed to remove from wishlist")
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"success": true,
-		"message": "Removed from wishlist",
-	})
+	response.Deleted(c, "Removed from wishlist")
 }
 
 // RemoveWishlistItem removes a wishlist item by ID
 // DELETE /api/v1/customer/wishlist/items/:itemId
 func (h *WishlistHandler) RemoveWishlistItem(c *gin.Context) {
-	userID, ok := middleware.GetUserID(c)
+	userID, ok := authctx.AuthenticatedUserID(c)
 	if !ok {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
 		return
 	}
 
 	itemID, err := uuid.Parse(c.Param("itemId"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid item ID"})
+		response.BadRequest(c, "Invalid item ID", nil)
 		return
 	}
 
 	if err := h.repo.RemoveByID(c.Request.Context(), userID, itemID); err != nil {
 		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Item not found"})
+			response.Fail(c, response.CodeWishlistItemNotFound, "Item not found")
 			return
 		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove item"})
+		response.InternalServerError(c, "Failed to remove item")
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"success": true,
-		"message": "Item removed from wishlist",
-	})
+	response.Deleted(c, "Item removed from wishlist")
 }
 
-// UpdateWishlistItem updates a wishlist item (e.g., notify_on_sale)
+// UpdateWishlistItem updates a wishlist item (e.g., notify_on_sale, notify_restock)
 // PATCH /api/v1/customer/wishlist/items/:itemId
 func (h *WishlistHandler) UpdateWishlistItem(c *gin.Context) {
-	userID, ok := middleware.GetUserID(c)
+	userID, ok := authctx.AuthenticatedUserID(c)
 	if !ok {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
 		return
 	}
 
 	itemID, err := uuid.Parse(c.Param("itemId"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid item ID"})
+		response.BadRequest(c, "Invalid item ID", nil)
 		return
 	}
 
 	var req UpdateWishlistItemRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		response.Invalid(c, err)
 		return
 	}
 
 	if req.NotifyOnSale != nil {
 		if err := h.repo.UpdateNotifyOnSale(c.Request.Context(), userID, itemID, *req.NotifyOnSale); err != nil {
 			if err == gorm.ErrRecordNotFound {
-				c.JSON(http.StatusNotFound, gin.H{"error": "Item not found"})
+				response.Fail(c, response.CodeWishlistItemNotFound, "Item not found")
 				return
 			}
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update item"})
+			response.InternalServerError(c, "Failed to update item")
+			return
+		}
+	}
+	if req.NotifyRestock != nil {
+		if err := h.repo.UpdateNotifyRestock(c.Request.Context(), userID, itemID, *req.NotifyRestock); err != nil {
+			if err == gorm.ErrRecordNotFound {
+				response.Fail(c, response.CodeWishlistItemNotFound, "Item not found")
+				return
+			}
+			response.InternalServerError(c, "Failed to update item")
 			return
 		}
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"success": true,
-		"message": "Wishlist item updated",
-	})
+	response.Done(c, http.StatusOK, "Wishlist item updated")
 }
 
 // CheckWishlist checks if a product/variant is in the wishlist
 // GET /api/v1/customer/wishlist/check/:productId
 func (h *WishlistHandler) CheckWishlist(c *gin.Context) {
-	userID, ok := middleware.GetUserID(c)
+	userID, ok := authctx.AuthenticatedUserID(c)
 	if !ok {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
 		return
 	}
 
 	productID, err := uuid.Parse(c.Param("productId"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		response.BadRequest(c, "Invalid product ID", nil)
 		return
 	}
 
@@ -247,7 +351,7 @@ func (h *WishlistHandler) CheckWishlist(c *gin.Context) {
 	if variantIDStr != "" {
 		parsed, err := uuid.Parse(variantIDStr)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid variant ID"})
+			response.BadRequest(c, "Invalid variant ID", nil)
 			return
 		}
 		variantID = &parsed
@@ -262,35 +366,321 @@ func (h *WishlistHandler) CheckWishlist(c *gin.Context) {
 	}
 
 	if checkErr != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check wishlist"})
+		response.InternalServerError(c, "Failed to check wishlist")
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"success":    true,
-		"in_wishlist": exists,
-		"product_id": productID,
-		"variant_id": variantID,
+	response.OK(c, "", WishlistCheck{
+		InWishlist: exists,
+		ProductID:  productID,
+		VariantID:  variantID,
 	})
 }
 
+// CheckWishlistBatch checks up to 100 products or variants at once, for the
+// product grid
+// POST /api/v1/customer/wishlist/check-batch
+func (h *WishlistHandler) CheckWishlistBatch(c *gin.Context) {
+	userID, ok := authctx.AuthenticatedUserID(c)
+	if !ok {
+		return
+	}
+
+	var req domain.MembershipCheckRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Invalid(c, err)
+		return
+	}
+
+	memberships, err := h.repo.InWishlist(c.Request.Context(), userID, req.Items)
+	if err != nil {
+		response.InternalServerError(c, "Failed to check wishlist")
+		return
+	}
+
+	response.OK(c, "", WishlistCheckBatch{InWishlist: memberships})
+}
+
+// ConfirmWishlist keeps the items the customer was asked about in a "still
+// interested?" nudge, so they aren't archived
+// POST /api/v1/customer/wishlist/confirm
+func (h *WishlistHandler) ConfirmWishlist(c *gin.Context) {
+	userID, ok := authctx.AuthenticatedUserID(c)
+	if !ok {
+		return
+	}
+
+	confirmed, err := h.repo.ConfirmNudged(c.Request.Context(), userID, time.Now())
+	if err != nil {
+		response.InternalServerError(c, "Failed to confirm wishlist")
+		return
+	}
+
+	response.OK(c, "Wishlist confirmed", WishlistConfirmation{Confirmed: confirmed})
+}
+
 // GetWishlistCount returns the count of items in the wishlist
 // GET /api/v1/customer/wishlist/count
 func (h *WishlistHandler) GetWishlistCount(c *gin.Context) {
-	userID, ok := middleware.GetUserID(c)
+	userID, ok := authctx.AuthenticatedUserID(c)
 	if !ok {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
 		return
 	}
 
 	count, err := h.repo.CountByUserID(c.Request.Context(), userID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get count"})
+		response.InternalServerError(c, "Failed to get count")
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"success": true,
-		"count":   count,
+	response.OK(c, "", WishlistCount{Count: count})
+}
+
+// GetStockStatus returns the live stock state of every wishlist item, so the
+// wishlist page can show availability badges. Items the inventory service
+// could not be asked about are served from cache or reported as unknown, and
+// the response is marked partial.
+// GET /api/v1/customer/wishlist/stock-status
+func (h *WishlistHandler) GetStockStatus(c *gin.Context) {
+	userID, ok := authctx.AuthenticatedUserID(c)
+	if !ok {
+		return
+	}
+	if h.inventory == nil {
+		response.ServiceUnavailable(c, "Stock status is not available")
+		return
+	}
+
+	items, err := h.repo.ListByUserID(c.Request.Context(), userID)
+	if err != nil {
+		response.InternalServerError(c, "Failed to retrieve wishlist")
+		return
+	}
+
+	lookups := make([]inventoryclient.Item, len(items))
+	for i, item := range items {
+		lookups[i] = inventoryclient.Item{ProductID: item.ProductID, VariantID: item.VariantID}
+	}
+	stock, err := h.inventory.GetStock(c.Request.Context(), lookups)
+	partial := err != nil
+	if partial {
+		log.Printf("⚠️  Partial wishlist stock status for user %s: %v", userID, err)
+	}
+
+	statuses := make([]WishlistStockStatus, len(items))
+	for i, item := range items {
+		statuses[i] = WishlistStockStatus{
+			ItemID:    item.ID,
+			ProductID: item.ProductID,
+			VariantID: item.VariantID,
+			Stock:     stock[lookups[i].Key()],
+		}
+		if statuses[i].Status == "" {
+			statuses[i].Status = inventoryclient.StockUnknown
+		}
+	}
+
+	response.OK(c, "", WishlistStockStatuses{
+		Items:   statuses,
+		Partial: partial,
 	})
 }
+
+// ImportWishlist adds products from a CSV of SKUs or product URLs, sent as
+// the "file" form field or as the raw request body. Each row gets its own
+// result; rows past the wishlist quota are reported rather than failing.
+// POST /api/v1/customer/wishlist/import
+func (h *WishlistHandler) ImportWishlist(c *gin.Context) {
+	userID, ok := authctx.AuthenticatedUserID(c)
+	if !ok {
+		return
+	}
+	if h.catalog == nil {
+		response.ServiceUnavailable(c, "Wishlist import is not available")
+		return
+	}
+
+	body, err := wishlistImportBody(c)
+	if err != nil {
+		response.BadRequest(c, err.Error(), nil)
+		return
+	}
+	defer body.Close()
+
+	entries, err := domain.ParseWishlistImport(io.LimitReader(body, maxWishlistImportSize))
+	if err != nil {
+		response.Fail(c, response.CodeWishlistImportInvalid, err.Error())
+		return
+	}
+
+	ctx := c.Request.Context()
+	matches, err := h.resolveImport(ctx, entries)
+	if err != nil {
+		log.Printf("⚠️  Failed to resolve wishlist import: %v", err)
+		response.BadGateway(c, "Failed to look up products")
+		return
+	}
+
+	items, err := h.repo.ListByUserID(ctx, userID)
+	if err != nil {
+		response.InternalServerError(c, "Failed to import wishlist")
+		return
+	}
+	inWishlist := make(map[string]bool, len(items))
+	for i := range items {
+		inWishlist[items[i].GetUniqueKey()] = true
+	}
+
+	summary := domain.WishlistImportSummary{Results: make([]domain.WishlistImportResult, 0, len(entries))}
+	for _, entry := range entries {
+		result := domain.WishlistImportResult{Row: entry.Row, Value: entry.Value}
+		if entry.Kind == "" {
+			result.Status = domain.WishlistImportInvalid
+			result.Message = "not a SKU or product URL"
+			summary.Add(result)
+			continue
+		}
+
+		match, found := matches.lookup(entry)
+		if !found {
+			result.Status = domain.WishlistImportNotFound
+			summary.Add(result)
+			continue
+		}
+		result.ProductID = &match.ID
+		result.VariantID = match.VariantID
+		result.ProductName = match.Name
+
+		item := domain.WishlistItem{ProductID: match.ID, VariantID: match.VariantID}
+		if inWishlist[item.GetUniqueKey()] {
+			result.Status = domain.WishlistImportDuplicate
+			summary.Add(result)
+			continue
+		}
+		err := h.repo.AddWithVariant(ctx, userID, importedWishlistItem(match))
+		switch {
+		case errors.Is(err, domain.ErrWishlistFull):
+			result.Status = domain.WishlistImportQuotaExceeded
+			result.Message = err.Error()
+		case err != nil:
+			response.InternalServerError(c, "Failed to import wishlist")
+			return
+		default:
+			inWishlist[item.GetUniqueKey()] = true
+			result.Status = domain.WishlistImportAdded
+		}
+		summary.Add(result)
+	}
+
+	middleware.SetActivityDetails(c, fmt.Sprintf("%d of %d products imported", summary.Added, len(entries)))
+
+	response.OK(c, "", summary)
+}
+
+// wishlistImportBody returns the uploaded import file, falling back to the
+// raw request body when the request is not a multipart upload
+func wishlistImportBody(c *gin.Context) (io.ReadCloser, error) {
+	file, err := c.FormFile("file")
+	if errors.Is(err, http.ErrNotMultipart) || errors.Is(err, http.ErrMissingFile) {
+		return c.Request.Body, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if file.Size > maxWishlistImportSize {
+		return nil, fmt.Errorf("import file is larger than %d bytes", maxWishlistImportSize)
+	}
+	return file.Open()
+}
+
+// importMatches holds the catalog products found for a wishlist import
+type importMatches struct {
+	*catalogclient.Matches
+	byID map[uuid.UUID]catalogclient.Product
+}
+
+func (m importMatches) lookup(entry domain.WishlistImportEntry) (catalogclient.ProductMatch, bool) {
+	switch entry.Kind {
+	case domain.WishlistImportBySKU:
+		match, ok := m.BySKU[entry.Key]
+		return match, ok
+	case domain.WishlistImportBySlug:
+		match, ok := m.BySlug[entry.Key]
+		return match, ok
+	case domain.WishlistImportByProductID:
+		product, ok := m.byID[entry.ProductID]
+		return catalogclient.ProductMatch{Product: product}, ok
+	}
+	return catalogclient.ProductMatch{}, false
+}
+
+// resolveImport looks up every SKU, slug and product ID of the import
+func (h *WishlistHandler) resolveImport(ctx context.Context, entries []domain.WishlistImportEntry) (importMatches, error) {
+	var skus, slugs []string
+	var ids []uuid.UUID
+	seen := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		key := entry.Kind + ":" + entry.Key + entry.ProductID.String()
+		if entry.Kind == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		switch entry.Kind {
+		case domain.WishlistImportBySKU:
+			skus = append(skus, entry.Key)
+		case domain.WishlistImportBySlug:
+			slugs = append(slugs, entry.Key)
+		case domain.WishlistImportByProductID:
+			ids = append(ids, entry.ProductID)
+		}
+	}
+
+	matches := importMatches{
+		Matches: &catalogclient.Matches{},
+		byID:    make(map[uuid.UUID]catalogclient.Product, len(ids)),
+	}
+	if len(skus) > 0 || len(slugs) > 0 {
+		resolved, err := h.catalog.Resolve(ctx, skus, slugs)
+		if err != nil {
+			return matches, err
+		}
+		matches.Matches = resolved
+	}
+	for len(ids) > 0 {
+		batch := ids[:min(len(ids), catalogclient.MaxBatchSize)]
+		ids = ids[len(batch):]
+		products, err := h.catalog.GetProducts(ctx, batch)
+		if err != nil {
+			return matches, err
+		}
+		for id, product := range products {
+			matches.byID[id] = product
+		}
+	}
+	return matches, nil
+}
+
+// importedWishlistItem builds the wishlist item for a matched product
+func importedWishlistItem(match catalogclient.ProductMatch) persistence.AddWishlistItemInput {
+	input := persistence.AddWishlistItemInput{
+		ProductID:  match.ID,
+		VariantID:  match.VariantID,
+		PriceAtAdd: match.Price,
+	}
+	if match.VariantSKU != "" {
+		input.VariantSKU = &match.VariantSKU
+	}
+	if match.VariantName != "" {
+		input.VariantName = &match.VariantName
+	}
+	if match.Name != "" {
+		input.ProductName = &match.Name
+	}
+	if match.Slug != "" {
+		input.ProductSlug = &match.Slug
+	}
+	if match.Image != "" {
+		input.ProductImage = &match.Image
+	}
+	return input
+}
@@ -0,0 +1,152 @@
+package addressvalidation
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const googleValidateURL = "https://addressvalidation.googleapis.com/v1:validateAddress"
+
+// GoogleValidator validates addresses with the Google Address Validation API
+type GoogleValidator struct {
+	apiKey     string
+	endpoint   string
+	httpClient *http.Client
+}
+
+// NewGoogleValidator creates a Google Address Validation provider
+func NewGoogleValidator(apiKey string) *GoogleValidator {
+	return &GoogleValidator{
+		apiKey:   apiKey,
+		endpoint: googleValidateURL,
+		httpClient: &http.Client{
+			Timeout: 5 * time.Second,
+		},
+	}
+}
+
+// Name returns the provider name
+func (v *GoogleValidator) Name() string {
+	return "google"
+}
+
+type googlePostalAddress struct {
+	RegionCode         string   `json:"regionCode,omitempty"`
+	PostalCode         string   `json:"postalCode,omitempty"`
+	AdministrativeArea string   `json:"administrativeArea,omitempty"`
+	Locality           string   `json:"locality,omitempty"`
+	AddressLines       []string `json:"addressLines,omitempty"`
+}
+
+type googleValidateRequest struct {
+	Address googlePostalAddress `json:"address"`
+}
+
+type googleValidateResponse struct {
+	Result struct {
+		Verdict struct {
+			AddressComplete          bool   `json:"addressComplete"`
+			HasUnconfirmedComponents bool   `json:"hasUnconfirmedComponents"`
+			ValidationGranularity    string `json:"validationGranularity"`
+		} `json:"verdict"`
+		Address struct {
+			PostalAddress         googlePostalAddress `json:"postalAddress"`
+			MissingComponentTypes []string            `json:"missingComponentTypes"`
+			UnresolvedTokens      []string            `json:"unresolvedTokens"`
+		} `json:"address"`
+		Geocode struct {
+			Location struct {
+				Latitude  float64 `json:"latitude"`
+				Longitude float64 `json:"longitude"`
+			} `json:"location"`
+		} `json:"geocode"`
+	} `json:"result"`
+}
+
+// Validate sends the address to Google and maps the verdict back to a Result
+func (v *GoogleValidator) Validate(ctx context.Context, input Input) (*Result, error) {
+	input = input.Trimmed()
+
+	lines := []string{input.AddressLine1}
+	if input.AddressLine2 != "" {
+		lines = append(lines, input.AddressLine2)
+	}
+
+	body, err := json.Marshal(googleValidateRequest{
+		Address: googlePostalAddress{
+			RegionCode:         RegionCode(input.Country),
+			PostalCode:         input.Postcode,
+			AdministrativeArea: input.State,
+			Locality:           input.City,
+			AddressLines:       lines,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// The key goes in a header rather than the query string, so it never
+	// shows up in a *url.Error logged by the caller
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, v.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Goog-Api-Key", v.apiKey)
+
+	resp, err := v.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("google address validation returned status %d", resp.StatusCode)
+	}
+
+	var parsed googleValidateResponse
+	if err := json.NewDecoder(resp.Body).Decode(&parsed); err != nil {
+		return nil, err
+	}
+
+	result := &Result{
+		Valid:      parsed.Result.Verdict.AddressComplete,
+		Normalized: input,
+		Provider:   v.Name(),
+	}
+
+	postal := parsed.Result.Address.PostalAddress
+	if len(postal.AddressLines) > 0 {
+		result.Normalized.AddressLine1 = postal.AddressLines[0]
+		result.Normalized.AddressLine2 = strings.Join(postal.AddressLines[1:], ", ")
+	}
+	if postal.Locality != "" {
+		result.Normalized.City = postal.Locality
+	}
+	if postal.AdministrativeArea != "" {
+		result.Normalized.State = postal.AdministrativeArea
+	}
+	if postal.PostalCode != "" {
+		result.Normalized.Postcode = postal.PostalCode
+	}
+
+	if loc := parsed.Result.Geocode.Location; loc.Latitude != 0 || loc.Longitude != 0 {
+		lat, lng := loc.Latitude, loc.Longitude
+		result.Latitude = &lat
+		result.Longitude = &lng
+	}
+
+	for _, component := range parsed.Result.Address.MissingComponentTypes {
+		result.Messages = append(result.Messages, "missing "+component)
+	}
+	for _, token := range parsed.Result.Address.UnresolvedTokens {
+		result.Messages = append(result.Messages, "unrecognized \""+token+"\"")
+	}
+
+	return result, nil
+}
@@ -0,0 +1,78 @@
+package addressvalidation
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGoogleValidator_Validate(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req googleValidateRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, "MY", req.Address.RegionCode)
+		assert.Equal(t, "test-key", r.Header.Get("X-Goog-Api-Key"))
+		assert.Empty(t, r.URL.RawQuery, "the key stays out of the URL")
+
+		w.Write([]byte(`{
+			"result": {
+				"verdict": {"addressComplete": true},
+				"address": {"postalAddress": {
+					"postalCode": "50450",
+					"administrativeArea": "Wilayah Persekutuan Kuala Lumpur",
+					"locality": "Kuala Lumpur",
+					"addressLines": ["1 Jalan Ampang"]
+				}},
+				"geocode": {"location": {"latitude": 3.1579, "longitude": 101.7116}}
+			}
+		}`))
+	}))
+	defer server.Close()
+
+	validator := NewGoogleValidator("test-key")
+	validator.endpoint = server.URL
+
+	result, err := validator.Validate(context.Background(), Input{
+		AddressLine1: " 1 jalan ampang ",
+		City:         "KL",
+		State:        "WP",
+		Postcode:     "50450",
+		Country:      "Malaysia",
+	})
+	require.NoError(t, err)
+	assert.True(t, result.Valid)
+	assert.Equal(t, "1 Jalan Ampang", result.Normalized.AddressLine1)
+	assert.Equal(t, "Kuala Lumpur", result.Normalized.City)
+	require.NotNil(t, result.Latitude)
+	assert.InDelta(t, 3.1579, *result.Latitude, 0.0001)
+}
+
+func TestGoogleValidator_ErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	validator := NewGoogleValidator("bad-key")
+	validator.endpoint = server.URL
+
+	_, err := validator.Validate(context.Background(), Input{AddressLine1: "x", Country: "MY"})
+	assert.Error(t, err)
+}
+
+func TestGoogleValidator_TransportErrorHidesKey(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.Close()
+
+	validator := NewGoogleValidator("secret-key")
+	validator.endpoint = server.URL
+
+	_, err := validator.Validate(context.Background(), Input{AddressLine1: "x", Country: "MY"})
+	require.Error(t, err)
+	assert.NotContains(t, err.Error(), "secret-key")
+}
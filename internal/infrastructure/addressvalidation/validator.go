@@ -0,0 +1,99 @@
+// Package addressvalidation provides pluggable address validation and geocoding providers.
+package addressvalidation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Ecom-micro-template/service-customer/internal/domain/address"
+)
+
+// Input is the address to validate
+type Input struct {
+	AddressLine1 string `json:"address_line1" binding:"required"`
+	AddressLine2 string `json:"address_line2,omitempty"`
+	City         string `json:"city"`
+	State        string `json:"state"`
+	Postcode     string `json:"postcode"`
+	Country      string `json:"country" binding:"required"`
+}
+
+// Result is the outcome of validating an address.
+// Normalized contains the provider's corrected address fields; callers should
+// store these instead of the raw input when the address is valid.
+type Result struct {
+	Valid      bool     `json:"valid"`
+	Normalized Input    `json:"normalized"`
+	Latitude   *float64 `json:"latitude,omitempty"`
+	Longitude  *float64 `json:"longitude,omitempty"`
+	Messages   []string `json:"messages,omitempty"`
+	Provider   string   `json:"provider"`
+}
+
+// Validator validates, normalizes and geocodes addresses
+type Validator interface {
+	Validate(ctx context.Context, input Input) (*Result, error)
+	Name() string
+}
+
+// NoopValidator accepts every address and only trims whitespace.
+// It is used when no external provider is configured.
+type NoopValidator struct{}
+
+// NewNoopValidator creates a validator that performs no external lookups
+func NewNoopValidator() *NoopValidator {
+	return &NoopValidator{}
+}
+
+// Name returns the provider name
+func (v *NoopValidator) Name() string {
+	return "none"
+}
+
+// Validate returns the trimmed input as a valid address
+func (v *NoopValidator) Validate(ctx context.Context, input Input) (*Result, error) {
+	return &Result{
+		Valid:      true,
+		Normalized: input.Trimmed(),
+		Provider:   v.Name(),
+	}, nil
+}
+
+// Trimmed returns a copy of the input with surrounding whitespace removed
+// and the postcode upper-cased
+func (in Input) Trimmed() Input {
+	return Input{
+		AddressLine1: strings.TrimSpace(in.AddressLine1),
+		AddressLine2: strings.TrimSpace(in.AddressLine2),
+		City:         strings.TrimSpace(in.City),
+		State:        strings.TrimSpace(in.State),
+		Postcode:     strings.ToUpper(strings.TrimSpace(in.Postcode)),
+		Country:      strings.TrimSpace(in.Country),
+	}
+}
+
+// New returns the validator for the configured provider name: "google", or
+// "none" (or empty) for the no-op validator. An unknown provider, or one
+// missing its API key, is an error so a typo fails at startup instead of
+// silently skipping validation.
+func New(provider, apiKey string) (Validator, error) {
+	switch strings.ToLower(strings.TrimSpace(provider)) {
+	case "", "none":
+		return NewNoopValidator(), nil
+	case "google":
+		if apiKey == "" {
+			return nil, errors.New("address validation provider google needs ADDRESS_VALIDATION_API_KEY")
+		}
+		return NewGoogleValidator(apiKey), nil
+	default:
+		return nil, fmt.Errorf("unknown address validation provider %q (want google or none)", provider)
+	}
+}
+
+// RegionCode returns the ISO 3166-1 alpha-2 code for a country name or code.
+// Returns an empty string if the country is unknown.
+func RegionCode(country string) string {
+	return address.CountryCode(country)
+}
@@ -0,0 +1,33 @@
+package addressvalidation
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		provider string
+		apiKey   string
+		want     string // provider name, or "" for an error
+	}{
+		{"", "", "none"},
+		{"none", "", "none"},
+		{"Google", "key", "google"},
+		{"google", "", ""},
+		{"here", "key", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.provider, func(t *testing.T) {
+			validator, err := New(tt.provider, tt.apiKey)
+			if tt.want == "" {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, validator.Name())
+		})
+	}
+}
@@ -0,0 +1,169 @@
+// Package catalogclient provides a client for reading product data from the catalog service.
+package catalogclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/tracing"
+)
+
+// MaxBatchSize is the largest number of products fetched in one request
+const MaxBatchSize = 100
+
+// Product is the subset of catalog product data denormalized into this service
+type Product struct {
+	ID    uuid.UUID `json:"id"`
+	Name  string    `json:"name"`
+	Slug  string    `json:"slug"`
+	Image string    `json:"image"`
+}
+
+type productsResponse struct {
+	Success bool      `json:"success"`
+	Data    []Product `json:"data"`
+}
+
+// Client calls the catalog service
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+// NewClient creates a catalog service client
+func NewClient(baseURL string) *Client {
+	return &Client{
+		baseURL: baseURL,
+		httpClient: &http.Client{
+			Transport: tracing.Transport(nil),
+			Timeout:   15 * time.Second,
+		},
+	}
+}
+
+// GetProducts fetches up to MaxBatchSize products by ID. Products that no
+// longer exist are simply missing from the result.
+func (c *Client) GetProducts(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]Product, error) {
+	if len(ids) > MaxBatchSize {
+		return nil, fmt.Errorf("at most %d products can be fetched at once", MaxBatchSize)
+	}
+
+	idStrings := make([]string, len(ids))
+	for i, id := range ids {
+		idStrings[i] = id.String()
+	}
+	endpoint := fmt.Sprintf("%s/api/v1/products/batch?ids=%s", c.baseURL, url.QueryEscape(strings.Join(idStrings, ",")))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("catalog service returned status %d", resp.StatusCode)
+	}
+
+	var parsed productsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&parsed); err != nil {
+		return nil, err
+	}
+
+	products := make(map[uuid.UUID]Product, len(parsed.Data))
+	for _, product := range parsed.Data {
+		products[product.ID] = product
+	}
+	return products, nil
+}
+
+// ProductMatch is a product matched by SKU or slug. A variant SKU matches
+// the variant, which is then set.
+type ProductMatch struct {
+	Product
+	VariantID   *uuid.UUID `json:"variant_id,omitempty"`
+	VariantSKU  string     `json:"variant_sku,omitempty"`
+	VariantName string     `json:"variant_name,omitempty"`
+	Price       float64    `json:"price"`
+}
+
+// Matches holds resolved products keyed by the SKU or slug they were found by
+type Matches struct {
+	BySKU  map[string]ProductMatch `json:"skus"`
+	BySlug map[string]ProductMatch `json:"slugs"`
+}
+
+type resolveResponse struct {
+	Success bool    `json:"success"`
+	Data    Matches `json:"data"`
+}
+
+// Resolve looks up products by SKU and by slug, MaxBatchSize keys per request.
+// Unknown SKUs and slugs are simply missing from the result.
+func (c *Client) Resolve(ctx context.Context, skus, slugs []string) (*Matches, error) {
+	matches := &Matches{
+		BySKU:  make(map[string]ProductMatch, len(skus)),
+		BySlug: make(map[string]ProductMatch, len(slugs)),
+	}
+
+	for len(skus) > 0 || len(slugs) > 0 {
+		skuBatch := skus[:min(len(skus), MaxBatchSize)]
+		skus = skus[len(skuBatch):]
+		slugBatch := slugs[:min(len(slugs), MaxBatchSize-len(skuBatch))]
+		slugs = slugs[len(slugBatch):]
+
+		batch, err := c.resolveBatch(ctx, skuBatch, slugBatch)
+		if err != nil {
+			return nil, err
+		}
+		for sku, match := range batch.BySKU {
+			matches.BySKU[sku] = match
+		}
+		for slug, match := range batch.BySlug {
+			matches.BySlug[slug] = match
+		}
+	}
+	return matches, nil
+}
+
+func (c *Client) resolveBatch(ctx context.Context, skus, slugs []string) (*Matches, error) {
+	query := url.Values{}
+	if len(skus) > 0 {
+		query.Set("skus", strings.Join(skus, ","))
+	}
+	if len(slugs) > 0 {
+		query.Set("slugs", strings.Join(slugs, ","))
+	}
+	endpoint := fmt.Sprintf("%s/api/v1/products/resolve?%s", c.baseURL, query.Encode())
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("catalog service returned status %d", resp.StatusCode)
+	}
+
+	var parsed resolveResponse
+	if err := json.NewDecoder(resp.Body).Decode(&parsed); err != nil {
+		return nil, err
+	}
+	return &parsed.Data, nil
+}
@@ -0,0 +1,90 @@
+package catalogclient
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_GetProducts(t *testing.T) {
+	first, second := uuid.New(), uuid.New()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v1/products/batch", r.URL.Path)
+		assert.Equal(t, first.String()+","+second.String(), r.URL.Query().Get("ids"))
+
+		fmt.Fprintf(w, `{"success": true, "data": [{"id": %q, "name": "Baju Kurung", "slug": "baju-kurung", "image": "https://cdn.example.com/bk.jpg"}]}`, first)
+	}))
+	defer server.Close()
+
+	products, err := NewClient(server.URL).GetProducts(context.Background(), []uuid.UUID{first, second})
+	require.NoError(t, err)
+	require.Len(t, products, 1)
+	assert.Equal(t, "baju-kurung", products[first].Slug)
+	_, found := products[second]
+	assert.False(t, found)
+}
+
+func TestClient_GetProducts_Error(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	_, err := NewClient(server.URL).GetProducts(context.Background(), []uuid.UUID{uuid.New()})
+	assert.Error(t, err)
+}
+
+func TestClient_Resolve(t *testing.T) {
+	productID, variantID := uuid.New(), uuid.New()
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		assert.Equal(t, "/api/v1/products/resolve", r.URL.Path)
+		assert.Equal(t, "BK-RED-M,BK-BLUE-S", r.URL.Query().Get("skus"))
+		assert.Equal(t, "baju-kurung", r.URL.Query().Get("slugs"))
+
+		fmt.Fprintf(w, `{"success": true, "data": {
+			"skus": {"BK-RED-M": {"id": %q, "name": "Baju Kurung", "variant_id": %q, "variant_sku": "BK-RED-M", "price": 129.9}},
+			"slugs": {"baju-kurung": {"id": %q, "name": "Baju Kurung", "slug": "baju-kurung"}}
+		}}`, productID, variantID, productID)
+	}))
+	defer server.Close()
+
+	matches, err := NewClient(server.URL).Resolve(context.Background(), []string{"BK-RED-M", "BK-BLUE-S"}, []string{"baju-kurung"})
+	require.NoError(t, err)
+	assert.Equal(t, 1, requests)
+
+	match, found := matches.BySKU["BK-RED-M"]
+	require.True(t, found)
+	assert.Equal(t, productID, match.ID)
+	require.NotNil(t, match.VariantID)
+	assert.Equal(t, variantID, *match.VariantID)
+	assert.Equal(t, 129.9, match.Price)
+
+	_, found = matches.BySKU["BK-BLUE-S"]
+	assert.False(t, found)
+	assert.Equal(t, productID, matches.BySlug["baju-kurung"].ID)
+}
+
+func TestClient_Resolve_Batches(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		fmt.Fprint(w, `{"success": true, "data": {"skus": {}, "slugs": {}}}`)
+	}))
+	defer server.Close()
+
+	skus := make([]string, MaxBatchSize+1)
+	for i := range skus {
+		skus[i] = fmt.Sprintf("SKU-%d", i)
+	}
+	_, err := NewClient(server.URL).Resolve(context.Background(), skus, []string{"baju-kurung"})
+	require.NoError(t, err)
+	assert.Equal(t, 2, requests)
+}
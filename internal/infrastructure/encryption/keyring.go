@@ -0,0 +1,196 @@
+// Package encryption encrypts sensitive column values with AES-256-GCM under
+// a set of versioned keys, so keys can be rotated without losing access to
+// values encrypted with the previous ones.
+package encryption
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Prefix starts every encrypted value: "enc:<key ID>:<base64 nonce and
+// ciphertext>". Values without it were stored before encryption was enabled.
+const Prefix = "enc:"
+
+// KeySize is the length of an AES-256 key
+const KeySize = 32
+
+var (
+	// ErrUnknownKey is returned for a value encrypted with a key the keyring
+	// doesn't have, e.g. one retired before re-encryption finished
+	ErrUnknownKey = errors.New("value encrypted with an unknown key")
+
+	// ErrMalformed is returned for a value with the prefix that can't be
+	// decrypted
+	ErrMalformed = errors.New("malformed encrypted value")
+)
+
+// Keyring encrypts with its primary key and decrypts with any of its keys.
+// A keyring without keys leaves values in plaintext.
+type Keyring struct {
+	primary  string
+	keys     map[string]cipher.AEAD
+	indexKey []byte
+}
+
+// NewKeyring creates a keyring of 32-byte keys by ID, encrypting with the
+// primary key. The primary may be empty when there is a single key.
+func NewKeyring(keys map[string][]byte, primary string) (*Keyring, error) {
+	k := &Keyring{keys: make(map[string]cipher.AEAD, len(keys))}
+	for id, key := range keys {
+		if id == "" || strings.Contains(id, ":") {
+			return nil, fmt.Errorf("key ID %q must be non-empty and not contain ':'", id)
+		}
+		if len(key) != KeySize {
+			return nil, fmt.Errorf("key %s is %d bytes; it must be %d", id, len(key), KeySize)
+		}
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		if k.keys[id], err = cipher.NewGCM(block); err != nil {
+			return nil, err
+		}
+		if primary == "" && len(keys) == 1 {
+			primary = id
+		}
+	}
+	if len(keys) > 0 {
+		if _, ok := k.keys[primary]; !ok {
+			return nil, fmt.Errorf("primary key %q is not one of the keys", primary)
+		}
+	}
+	k.primary = primary
+	return k, nil
+}
+
+// WithIndexKey sets the 32-byte HMAC key of BlindIndex. Unlike the
+// encryption keys it can't be rotated without recomputing every index.
+func (k *Keyring) WithIndexKey(key []byte) (*Keyring, error) {
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("blind index key is %d bytes; it must be %d", len(key), KeySize)
+	}
+	k.indexKey = key
+	return k, nil
+}
+
+// ParseKeys parses keys formatted as "id=base64key,id=base64key"; newlines
+// may separate them too, as in a key file
+func ParseKeys(value string) (map[string][]byte, error) {
+	keys := make(map[string][]byte)
+	for _, entry := range strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == '\n' }) {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+		id, encoded, found := strings.Cut(entry, "=")
+		id, encoded = strings.TrimSpace(id), strings.TrimSpace(encoded)
+		if !found || id == "" || encoded == "" {
+			return nil, fmt.Errorf("key entry %q must be formatted as id=base64key", id)
+		}
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("key %s is not valid base64: %w", id, err)
+		}
+		keys[id] = key
+	}
+	return keys, nil
+}
+
+// Enabled reports whether the keyring has keys to encrypt with
+func (k *Keyring) Enabled() bool {
+	return k != nil && k.primary != ""
+}
+
+// PrimaryKeyID returns the ID of the key new values are encrypted with
+func (k *Keyring) PrimaryKeyID() string {
+	if k == nil {
+		return ""
+	}
+	return k.primary
+}
+
+// Encrypt encrypts plaintext with the primary key, binding it to the
+// additional data, e.g. the table, column and row of the value, so a value
+// copied anywhere else fails to decrypt. Without keys the plaintext is
+// returned as it is.
+func (k *Keyring) Encrypt(plaintext, binding string) (string, error) {
+	if !k.Enabled() {
+		return plaintext, nil
+	}
+	aead := k.keys[k.primary]
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(plaintext), []byte(binding))
+	return Prefix + k.primary + ":" + base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt returns the plaintext of an encrypted value, which must have been
+// encrypted with the same binding, or the value as it is when it was stored
+// in plaintext
+func (k *Keyring) Decrypt(value, binding string) (string, error) {
+	if !IsEncrypted(value) {
+		return value, nil
+	}
+	id, encoded, found := strings.Cut(strings.TrimPrefix(value, Prefix), ":")
+	if !found {
+		return "", ErrMalformed
+	}
+	var aead cipher.AEAD
+	if k != nil {
+		aead = k.keys[id]
+	}
+	if aead == nil {
+		return "", fmt.Errorf("%w %q", ErrUnknownKey, id)
+	}
+	sealed, err := base64.RawStdEncoding.DecodeString(encoded)
+	if err != nil || len(sealed) < aead.NonceSize() {
+		return "", ErrMalformed
+	}
+	plaintext, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], []byte(binding))
+	if err != nil {
+		return "", ErrMalformed
+	}
+	return string(plaintext), nil
+}
+
+// BlindIndex returns a keyed hash of value for equality lookups on an
+// encrypted column, scoped to column so equal values in different columns
+// don't match. Without an index key the value is returned as it is, like
+// Encrypt without keys.
+func (k *Keyring) BlindIndex(value, column string) string {
+	if value == "" || k == nil || k.indexKey == nil {
+		return value
+	}
+	mac := hmac.New(sha256.New, k.indexKey)
+	mac.Write([]byte(column))
+	mac.Write([]byte{0})
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// IsEncrypted reports whether a stored value is encrypted
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, Prefix)
+}
+
+// IsCurrent reports whether a stored value needs no re-encryption: it is
+// empty or encrypted with the primary key
+func (k *Keyring) IsCurrent(value string) bool {
+	if value == "" {
+		return true
+	}
+	if !k.Enabled() {
+		return !IsEncrypted(value)
+	}
+	return strings.HasPrefix(value, Prefix+k.primary+":")
+}
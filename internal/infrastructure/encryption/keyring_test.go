@@ -0,0 +1,132 @@
+package encryption
+
+import (
+	"bytes"
+	"encoding/base64"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func key(b byte) []byte {
+	return bytes.Repeat([]byte{b}, KeySize)
+}
+
+func TestKeyring_RoundTrip(t *testing.T) {
+	keys, err := NewKeyring(map[string][]byte{"k1": key(1)}, "")
+	require.NoError(t, err)
+	assert.Equal(t, "k1", keys.PrimaryKeyID())
+
+	encrypted, err := keys.Encrypt("+60123456789", "phone")
+	require.NoError(t, err)
+	assert.True(t, IsEncrypted(encrypted))
+	assert.NotContains(t, encrypted, "60123456789")
+
+	plaintext, err := keys.Decrypt(encrypted, "phone")
+	require.NoError(t, err)
+	assert.Equal(t, "+60123456789", plaintext)
+
+	_, err = keys.Decrypt(encrypted, "notes")
+	assert.ErrorIs(t, err, ErrMalformed, "a value is bound to its column")
+
+	plaintext, err = keys.Decrypt("+60123456789", "phone")
+	require.NoError(t, err)
+	assert.Equal(t, "+60123456789", plaintext, "plaintext from before encryption is returned as is")
+}
+
+func TestKeyring_Rotation(t *testing.T) {
+	old, err := NewKeyring(map[string][]byte{"k1": key(1)}, "")
+	require.NoError(t, err)
+	encrypted, err := old.Encrypt("secret", "notes")
+	require.NoError(t, err)
+
+	rotated, err := NewKeyring(map[string][]byte{"k1": key(1), "k2": key(2)}, "k2")
+	require.NoError(t, err)
+	assert.False(t, rotated.IsCurrent(encrypted))
+
+	plaintext, err := rotated.Decrypt(encrypted, "notes")
+	require.NoError(t, err)
+	assert.Equal(t, "secret", plaintext)
+
+	reencrypted, err := rotated.Encrypt(plaintext, "notes")
+	require.NoError(t, err)
+	assert.True(t, rotated.IsCurrent(reencrypted))
+
+	retired, err := NewKeyring(map[string][]byte{"k2": key(2)}, "")
+	require.NoError(t, err)
+	_, err = retired.Decrypt(encrypted, "notes")
+	assert.ErrorIs(t, err, ErrUnknownKey)
+}
+
+func TestKeyring_Disabled(t *testing.T) {
+	keys, err := NewKeyring(nil, "")
+	require.NoError(t, err)
+	assert.False(t, keys.Enabled())
+
+	value, err := keys.Encrypt("secret", "notes")
+	require.NoError(t, err)
+	assert.Equal(t, "secret", value)
+	assert.True(t, keys.IsCurrent(value))
+}
+
+func TestNewKeyring_Invalid(t *testing.T) {
+	_, err := NewKeyring(map[string][]byte{"k1": key(1)[:16]}, "")
+	assert.Error(t, err, "short key")
+
+	_, err = NewKeyring(map[string][]byte{"k1": key(1), "k2": key(2)}, "")
+	assert.Error(t, err, "primary required with several keys")
+
+	_, err = NewKeyring(map[string][]byte{"k:1": key(1)}, "")
+	assert.Error(t, err, "key ID with a colon")
+}
+
+func TestParseKeys(t *testing.T) {
+	encoded := base64.StdEncoding.EncodeToString(key(1))
+	keys, err := ParseKeys("k1=" + encoded + ",\nk2=" + base64.StdEncoding.EncodeToString(key(2)) + "\n")
+	require.NoError(t, err)
+	assert.Equal(t, key(1), keys["k1"])
+	assert.Equal(t, key(2), keys["k2"])
+
+	_, err = ParseKeys("k1")
+	assert.Error(t, err)
+	_, err = ParseKeys("k1=not base64!")
+	assert.Error(t, err)
+}
+
+func TestKeyring_BindsToRow(t *testing.T) {
+	keys, err := NewKeyring(map[string][]byte{"k1": key(1)}, "")
+	require.NoError(t, err)
+
+	encrypted, err := keys.Encrypt("+60123456789", "public.customers.phone#a")
+	require.NoError(t, err)
+	_, err = keys.Decrypt(encrypted, "public.customers.phone#b")
+	assert.ErrorIs(t, err, ErrMalformed, "a value copied to another row")
+	_, err = keys.Decrypt(encrypted, "customer.profiles.phone#a")
+	assert.ErrorIs(t, err, ErrMalformed, "a value copied to another table")
+}
+
+func TestKeyring_BlindIndex(t *testing.T) {
+	keys, err := NewKeyring(map[string][]byte{"k1": key(1)}, "")
+	require.NoError(t, err)
+	assert.Equal(t, "60123456789", keys.BlindIndex("60123456789", "customers.phone"), "no index key")
+
+	_, err = keys.WithIndexKey(key(9)[:16])
+	assert.Error(t, err, "short index key")
+	keys, err = keys.WithIndexKey(key(9))
+	require.NoError(t, err)
+
+	index := keys.BlindIndex("60123456789", "customers.phone")
+	assert.Len(t, index, 64)
+	assert.NotContains(t, index, "60123456789")
+	assert.Equal(t, index, keys.BlindIndex("60123456789", "customers.phone"), "deterministic")
+	assert.NotEqual(t, index, keys.BlindIndex("60123456780", "customers.phone"))
+	assert.NotEqual(t, index, keys.BlindIndex("60123456789", "profiles.phone"), "scoped to the column")
+	assert.Empty(t, keys.BlindIndex("", "customers.phone"))
+
+	other, err := NewKeyring(map[string][]byte{"k2": key(2)}, "")
+	require.NoError(t, err)
+	other, err = other.WithIndexKey(key(8))
+	require.NoError(t, err)
+	assert.NotEqual(t, index, other.BlindIndex("60123456789", "customers.phone"), "keyed")
+}
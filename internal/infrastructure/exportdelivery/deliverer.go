@@ -0,0 +1,117 @@
+// Package exportdelivery delivers the files of scheduled customer exports to
+// their destinations: an emailed download link, an S3 bucket or an SFTP
+// server.
+package exportdelivery
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/filestore"
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/sftpclient"
+)
+
+// ErrNoFileStore is returned for email exports when file storage isn't
+// configured, as the link needs somewhere to download the file from
+var ErrNoFileStore = errors.New("file storage is not configured for emailed exports")
+
+// LinkSender emails the download link of an export run
+type LinkSender interface {
+	SendScheduledExportLink(ctx context.Context, runID string, link domain.ScheduledExportLink) error
+}
+
+// Deliverer sends export files to the destination of their export
+type Deliverer struct {
+	files       filestore.Store // nil disables email exports
+	links       LinkSender
+	linkTTL     time.Duration
+	sftpTimeout time.Duration
+	now         func() time.Time
+}
+
+// New creates a deliverer. Emailed files are kept in files and linked for
+// linkTTL; files may be nil when storage isn't configured.
+func New(files filestore.Store, links LinkSender, linkTTL time.Duration) *Deliverer {
+	if linkTTL <= 0 {
+		linkTTL = domain.DefaultExportLinkTTL
+	}
+	return &Deliverer{
+		files:       files,
+		links:       links,
+		linkTTL:     linkTTL,
+		sftpTimeout: 30 * time.Second,
+		now:         time.Now,
+	}
+}
+
+// Deliver sends file to the export's destination and returns where it went:
+// the object key for email and S3 exports, the remote path for SFTP
+func (d *Deliverer) Deliver(ctx context.Context, export *domain.ScheduledExport, run *domain.ScheduledExportRun, file domain.ExportFile) (string, error) {
+	target := export.Target
+	switch target.Type {
+	case domain.ExportTargetEmail:
+		return d.email(ctx, export, run, file)
+	case domain.ExportTargetS3:
+		key := strings.TrimPrefix(target.FilePath(file.Name), "/")
+		store, err := filestore.NewS3Store(target.Endpoint, target.Region, target.Bucket, target.AccessKey, target.Secret, target.Endpoint != "")
+		if err != nil {
+			return "", err
+		}
+		if err := store.Put(ctx, key, file.ContentType, bytes.NewReader(file.Body), int64(len(file.Body))); err != nil {
+			return "", fmt.Errorf("upload to s3://%s/%s: %w", target.Bucket, key, err)
+		}
+		return key, nil
+	case domain.ExportTargetSFTP:
+		remotePath := target.FilePath(file.Name)
+		client, err := sftpclient.New(sftpclient.Config{
+			Addr:       target.Host,
+			User:       target.Username,
+			Password:   target.Secret,
+			PrivateKey: target.PrivateKey,
+			HostKey:    target.HostKey,
+			Timeout:    d.sftpTimeout,
+		})
+		if err != nil {
+			return "", err
+		}
+		if _, err := client.Upload(ctx, remotePath, bytes.NewReader(file.Body)); err != nil {
+			return "", fmt.Errorf("upload to sftp://%s%s: %w", target.Host, remotePath, err)
+		}
+		return remotePath, nil
+	default:
+		return "", domain.ErrInvalidExportTarget
+	}
+}
+
+// email keeps the file in storage and emails the recipients a link to it
+func (d *Deliverer) email(ctx context.Context, export *domain.ScheduledExport, run *domain.ScheduledExportRun, file domain.ExportFile) (string, error) {
+	if d.files == nil {
+		return "", ErrNoFileStore
+	}
+	key := path.Join("exports", export.ID.String(), file.Name)
+	if err := d.files.Put(ctx, key, file.ContentType, bytes.NewReader(file.Body), int64(len(file.Body))); err != nil {
+		return "", fmt.Errorf("store export file: %w", err)
+	}
+	url, err := d.files.SignedURL(key, d.linkTTL)
+	if err != nil {
+		return "", err
+	}
+
+	if err := d.links.SendScheduledExportLink(ctx, run.ID.String(), domain.ScheduledExportLink{
+		Recipients: export.Target.Recipients,
+		ExportName: export.Name,
+		Filename:   file.Name,
+		Rows:       file.Rows,
+		URL:        url,
+		ExpiresAt:  d.now().Add(d.linkTTL).UTC(),
+	}); err != nil {
+		return key, fmt.Errorf("email export link: %w", err)
+	}
+	return key, nil
+}
@@ -0,0 +1,86 @@
+package exportdelivery
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/filestore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type sentLinks struct {
+	runIDs []string
+	links  []domain.ScheduledExportLink
+}
+
+func (s *sentLinks) SendScheduledExportLink(_ context.Context, runID string, link domain.ScheduledExportLink) error {
+	s.runIDs = append(s.runIDs, runID)
+	s.links = append(s.links, link)
+	return nil
+}
+
+func testFile() domain.ExportFile {
+	return domain.ExportFile{
+		Name:        "customers-20250602-080000.csv",
+		ContentType: "text/csv; charset=utf-8",
+		Body:        []byte("id,email\n1,aisyah@example.com\n"),
+		Rows:        1,
+	}
+}
+
+func TestDeliverer_Email(t *testing.T) {
+	store, err := filestore.NewLocalStore(t.TempDir(), "http://files.test/api/v1/files", "secret")
+	require.NoError(t, err)
+	links := &sentLinks{}
+	deliverer := New(store, links, 24*time.Hour)
+	now := time.Date(2025, 6, 2, 8, 0, 0, 0, time.UTC)
+	deliverer.now = func() time.Time { return now }
+
+	export := &domain.ScheduledExport{ID: uuid.New(), Name: "Weekly VIPs",
+		Target: domain.ExportTarget{Type: domain.ExportTargetEmail, Recipients: []string{"ops@example.com"}}}
+	run := &domain.ScheduledExportRun{ID: uuid.New()}
+
+	location, err := deliverer.Deliver(context.Background(), export, run, testFile())
+	require.NoError(t, err)
+	assert.Equal(t, "exports/"+export.ID.String()+"/customers-20250602-080000.csv", location)
+
+	require.Len(t, links.links, 1)
+	assert.Equal(t, run.ID.String(), links.runIDs[0])
+	link := links.links[0]
+	assert.Equal(t, []string{"ops@example.com"}, link.Recipients)
+	assert.Equal(t, 1, link.Rows)
+	assert.True(t, strings.HasPrefix(link.URL, "http://files.test/api/v1/files/"+location+"?"))
+	assert.Equal(t, now.Add(24*time.Hour), link.ExpiresAt)
+
+	_, err = New(nil, links, 0).Deliver(context.Background(), export, run, testFile())
+	assert.ErrorIs(t, err, ErrNoFileStore)
+}
+
+func TestDeliverer_S3(t *testing.T) {
+	var gotPath, gotBody, gotType string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		gotPath, gotBody, gotType = r.URL.Path, string(body), r.Header.Get("Content-Type")
+		assert.Contains(t, r.Header.Get("Authorization"), "Credential=AKIAEXPORT/")
+	}))
+	defer srv.Close()
+
+	export := &domain.ScheduledExport{ID: uuid.New(), Name: "CRM feed", Target: domain.ExportTarget{
+		Type: domain.ExportTargetS3, Endpoint: srv.URL, Region: "ap-southeast-1", Bucket: "crm-drop",
+		AccessKey: "AKIAEXPORT", Secret: "s3cret", Path: "/incoming/customers"}}
+
+	location, err := New(nil, &sentLinks{}, 0).Deliver(context.Background(), export, &domain.ScheduledExportRun{ID: uuid.New()}, testFile())
+	require.NoError(t, err)
+	assert.Equal(t, "incoming/customers/customers-20250602-080000.csv", location)
+	assert.Equal(t, "/crm-drop/incoming/customers/customers-20250602-080000.csv", gotPath)
+	assert.Equal(t, string(testFile().Body), gotBody)
+	assert.Equal(t, "text/csv; charset=utf-8", gotType)
+}
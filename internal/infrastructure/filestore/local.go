@@ -0,0 +1,150 @@
+package filestore
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LocalStore keeps files on local disk. Its download URLs carry an expiry and
+// an HMAC signature that ServeHTTP checks before serving the file.
+type LocalStore struct {
+	dir          string
+	publicURL    string
+	secret       []byte
+	publicPrefix string
+}
+
+// NewLocalStore creates a store in dir whose files are downloaded from
+// publicURL (e.g. "http://localhost:8004/api/v1/files")
+func NewLocalStore(dir, publicURL, secret string) (*LocalStore, error) {
+	if dir == "" || publicURL == "" {
+		return nil, errors.New("local storage needs a directory and a public URL")
+	}
+	if secret == "" {
+		return nil, errors.New("local storage needs a signing secret")
+	}
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, err
+	}
+	return &LocalStore{
+		dir:       dir,
+		publicURL: strings.TrimRight(publicURL, "/"),
+		secret:    []byte(secret),
+	}, nil
+}
+
+// WithPublicPrefix serves the keys under prefix, such as avatars, without a
+// signature and lets browsers and CDNs cache them. Files under it must never
+// change; write a new version under a new key instead.
+func (s *LocalStore) WithPublicPrefix(prefix string) *LocalStore {
+	s.publicPrefix = prefix
+	return s
+}
+
+// Put writes the file to a temporary file first so a failed upload never
+// leaves a partial file under key
+func (s *LocalStore) Put(ctx context.Context, key, contentType string, body io.Reader, size int64) error {
+	if !validKey(key) {
+		return ErrInvalidKey
+	}
+	path := filepath.Join(s.dir, filepath.FromSlash(key))
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	written, err := io.Copy(tmp, io.LimitReader(body, size))
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	if written != size {
+		return fmt.Errorf("short upload: got %d of %d bytes", written, size)
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func (s *LocalStore) Delete(ctx context.Context, key string) error {
+	if !validKey(key) {
+		return ErrInvalidKey
+	}
+	err := os.Remove(filepath.Join(s.dir, filepath.FromSlash(key)))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+func (s *LocalStore) SignedURL(key string, ttl time.Duration) (string, error) {
+	if !validKey(key) {
+		return "", ErrInvalidKey
+	}
+	expires := time.Now().Add(ttl).Unix()
+	query := url.Values{}
+	query.Set("expires", strconv.FormatInt(expires, 10))
+	query.Set("signature", s.sign(key, expires))
+	return s.publicURL + "/" + key + "?" + query.Encode(), nil
+}
+
+// ServeHTTP serves a file requested through a signed URL, or any file under
+// the public prefix. The request path is the key, so mount the store with
+// http.StripPrefix for PublicURL's path.
+func (s *LocalStore) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	key := strings.TrimPrefix(r.URL.Path, "/")
+	public := s.publicPrefix != "" && strings.HasPrefix(key, s.publicPrefix)
+	if !public {
+		expires, err := strconv.ParseInt(r.URL.Query().Get("expires"), 10, 64)
+		if err != nil || !validKey(key) || time.Now().Unix() > expires ||
+			!hmac.Equal([]byte(r.URL.Query().Get("signature")), []byte(s.sign(key, expires))) {
+			http.Error(w, "invalid or expired link", http.StatusForbidden)
+			return
+		}
+	} else if !validKey(key) {
+		http.NotFound(w, r)
+		return
+	}
+
+	file, err := os.Open(filepath.Join(s.dir, filepath.FromSlash(key)))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		http.Error(w, "failed to read file", http.StatusInternalServerError)
+		return
+	}
+	if public {
+		w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	} else {
+		w.Header().Set("Cache-Control", "private, no-store")
+	}
+	http.ServeContent(w, r, filepath.Base(key), info.ModTime(), file)
+}
+
+func (s *LocalStore) sign(key string, expires int64) string {
+	mac := hmac.New(sha256.New, s.secret)
+	fmt.Fprintf(mac, "%s\n%d", key, expires)
+	return hex.EncodeToString(mac.Sum(nil))
+}
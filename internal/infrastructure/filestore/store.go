@@ -0,0 +1,81 @@
+// Package filestore stores uploaded files, such as customer note attachments,
+// on local disk or in an S3-compatible bucket (AWS S3, MinIO) and hands out
+// time-limited download URLs for them.
+package filestore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Storage providers
+const (
+	ProviderLocal = "local"
+	ProviderS3    = "s3"
+	ProviderMinIO = "minio"
+)
+
+// ErrInvalidKey is returned for an object key that is empty or could escape
+// the store, e.g. one containing ".."
+var ErrInvalidKey = errors.New("invalid file key")
+
+// Store keeps files under slash-separated keys
+type Store interface {
+	// Put stores size bytes of body under key, replacing any existing file
+	Put(ctx context.Context, key, contentType string, body io.Reader, size int64) error
+	// Delete removes the file; deleting a missing file is not an error
+	Delete(ctx context.Context, key string) error
+	// SignedURL returns a URL that downloads the file until ttl has passed
+	SignedURL(key string, ttl time.Duration) (string, error)
+}
+
+// Config selects and configures a storage provider
+type Config struct {
+	Provider string // "local", "s3" or "minio"
+
+	// Local provider: files are kept in LocalDir and downloaded from
+	// PublicURL, which must route to LocalStore.ServeHTTP
+	LocalDir      string
+	PublicURL     string
+	SigningSecret string
+
+	// S3 and MinIO providers. Endpoint defaults to AWS S3 in Region.
+	Endpoint  string
+	Region    string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+	PathStyle bool // bucket in the path rather than the host name; MinIO needs this
+}
+
+// New creates the store for cfg.Provider
+func New(cfg Config) (Store, error) {
+	switch strings.ToLower(cfg.Provider) {
+	case ProviderLocal, "":
+		return NewLocalStore(cfg.LocalDir, cfg.PublicURL, cfg.SigningSecret)
+	case ProviderS3:
+		return NewS3Store(cfg.Endpoint, cfg.Region, cfg.Bucket, cfg.AccessKey, cfg.SecretKey, cfg.PathStyle)
+	case ProviderMinIO:
+		return NewS3Store(cfg.Endpoint, cfg.Region, cfg.Bucket, cfg.AccessKey, cfg.SecretKey, true)
+	default:
+		return nil, fmt.Errorf("unknown storage provider %q", cfg.Provider)
+	}
+}
+
+// validKey reports whether key is a relative slash-separated path that
+// stays inside the store
+func validKey(key string) bool {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, `\`) {
+		return false
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,64 @@
+// Package imaging crops and resizes uploaded images, such as customer
+// avatars, to the standard sizes they are served in.
+package imaging
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	_ "image/gif" // registers the GIF decoder
+	"image/jpeg"
+	_ "image/png" // registers the PNG decoder
+
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp" // registers the WebP decoder
+)
+
+// MaxSourcePixels bounds the dimensions of a decoded image, so a small but
+// highly compressed file can't exhaust memory
+const MaxSourcePixels = 40_000_000
+
+// jpegQuality is the quality avatars are encoded with
+const jpegQuality = 85
+
+// Avatars crops an image to a centered square and returns it as a JPEG in
+// each size, keyed by size name. Transparent areas become white. Returns
+// domain.ErrAvatarInvalid if data isn't a supported image and
+// domain.ErrAvatarTooLarge if its dimensions exceed MaxSourcePixels.
+func Avatars(data []byte, sizes []domain.AvatarSize) (map[string][]byte, error) {
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || config.Width <= 0 || config.Height <= 0 {
+		return nil, domain.ErrAvatarInvalid
+	}
+	if config.Width*config.Height > MaxSourcePixels {
+		return nil, domain.ErrAvatarTooLarge
+	}
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, domain.ErrAvatarInvalid
+	}
+
+	square := centerSquare(src.Bounds())
+	encoded := make(map[string][]byte, len(sizes))
+	for _, size := range sizes {
+		dst := image.NewRGBA(image.Rect(0, 0, size.Pixels, size.Pixels))
+		draw.Draw(dst, dst.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+		draw.CatmullRom.Scale(dst, dst.Bounds(), src, square, draw.Over, nil)
+
+		var buf bytes.Buffer
+		if err := jpeg.Encode(&buf, dst, &jpeg.Options{Quality: jpegQuality}); err != nil {
+			return nil, err
+		}
+		encoded[size.Name] = buf.Bytes()
+	}
+	return encoded, nil
+}
+
+// centerSquare returns the largest square centered in bounds
+func centerSquare(bounds image.Rectangle) image.Rectangle {
+	side := min(bounds.Dx(), bounds.Dy())
+	x := bounds.Min.X + (bounds.Dx()-side)/2
+	y := bounds.Min.Y + (bounds.Dy()-side)/2
+	return image.Rect(x, y, x+side, y+side)
+}
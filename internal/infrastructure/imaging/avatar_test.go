@@ -0,0 +1,63 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAvatars_CropsAndResizes(t *testing.T) {
+	// A 300x100 banner: red in the middle third, blue on either side
+	src := image.NewRGBA(image.Rect(0, 0, 300, 100))
+	for x := 0; x < 300; x++ {
+		for y := 0; y < 100; y++ {
+			c := color.RGBA{B: 255, A: 255}
+			if x >= 100 && x < 200 {
+				c = color.RGBA{R: 255, A: 255}
+			}
+			src.Set(x, y, c)
+		}
+	}
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, src))
+
+	avatars, err := Avatars(buf.Bytes(), domain.AvatarSizes)
+	require.NoError(t, err)
+	require.Len(t, avatars, len(domain.AvatarSizes))
+
+	for _, size := range domain.AvatarSizes {
+		img, err := jpeg.Decode(bytes.NewReader(avatars[size.Name]))
+		require.NoError(t, err, size.Name)
+		assert.Equal(t, image.Rect(0, 0, size.Pixels, size.Pixels), img.Bounds(), size.Name)
+
+		// Only the centered red square is kept
+		r, g, b, _ := img.At(size.Pixels/2, size.Pixels/2).RGBA()
+		assert.Greater(t, r>>8, uint32(200), size.Name)
+		assert.Less(t, g>>8, uint32(60), size.Name)
+		assert.Less(t, b>>8, uint32(60), size.Name)
+	}
+}
+
+func TestAvatars_RejectsInvalidImages(t *testing.T) {
+	_, err := Avatars([]byte("%PDF-1.4 not an image"), domain.AvatarSizes)
+	assert.ErrorIs(t, err, domain.ErrAvatarInvalid)
+
+	// A PNG claiming to be 10000x10000 is rejected before its pixels are decoded
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, image.NewGray(image.Rect(0, 0, 1, 1))))
+	data := buf.Bytes()
+	binary.BigEndian.PutUint32(data[16:], 10000) // IHDR width
+	binary.BigEndian.PutUint32(data[20:], 10000) // IHDR height
+	binary.BigEndian.PutUint32(data[29:], crc32.ChecksumIEEE(data[12:29]))
+	_, err = Avatars(data, domain.AvatarSizes)
+	assert.ErrorIs(t, err, domain.ErrAvatarTooLarge)
+}
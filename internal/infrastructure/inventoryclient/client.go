@@ -0,0 +1,232 @@
+// Package inventoryclient provides a client for reading stock levels from the inventory service.
+package inventoryclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/metrics"
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/tracing"
+)
+
+// MaxBatchSize is the largest number of items looked up in one request
+const MaxBatchSize = 100
+
+// Stock states
+const (
+	StockInStock    = "in_stock"
+	StockLowStock   = "low_stock"
+	StockOutOfStock = "out_of_stock"
+	StockUnknown    = "unknown" // the inventory service could not be reached
+)
+
+// LowStockThreshold is the available quantity at or below which stock is low
+const LowStockThreshold = 5
+
+// Cached stock levels are fresh for the TTL and may be served as stale for
+// staleFor more when the inventory service fails
+const (
+	defaultCacheTTL = 30 * time.Second
+	staleFor        = 5 * time.Minute
+	cacheMaxItems   = 50000
+)
+
+// Item identifies a product, or one of its variants, to look up
+type Item struct {
+	ProductID uuid.UUID  `json:"product_id"`
+	VariantID *uuid.UUID `json:"variant_id,omitempty"`
+}
+
+// Key identifies the item in GetStock results
+func (i Item) Key() string {
+	if i.VariantID != nil {
+		return i.ProductID.String() + "-" + i.VariantID.String()
+	}
+	return i.ProductID.String() + "-nil"
+}
+
+// Stock is the stock level of one item
+type Stock struct {
+	Available int       `json:"available"`
+	Status    string    `json:"status"`
+	CheckedAt time.Time `json:"checked_at"`
+	Stale     bool      `json:"stale,omitempty"` // served from cache after a failed lookup
+}
+
+type stockLevel struct {
+	ProductID uuid.UUID  `json:"product_id"`
+	VariantID *uuid.UUID `json:"variant_id,omitempty"`
+	Available int        `json:"available"`
+}
+
+type stockResponse struct {
+	Success bool         `json:"success"`
+	Data    []stockLevel `json:"data"`
+}
+
+type cachedStock struct {
+	stock     Stock
+	expiresAt time.Time
+}
+
+// Client calls the inventory service. Lookups are cached briefly: stock
+// shown as a badge may lag the warehouse by the cache TTL.
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+	ttl        time.Duration
+
+	mu    sync.Mutex
+	cache map[string]cachedStock
+}
+
+// NewClient creates an inventory service client caching stock levels for
+// ttl (30s if zero)
+func NewClient(baseURL string, ttl time.Duration) *Client {
+	if ttl <= 0 {
+		ttl = defaultCacheTTL
+	}
+	return &Client{
+		baseURL: baseURL,
+		httpClient: &http.Client{
+			Transport: tracing.Transport(nil),
+			Timeout:   3 * time.Second,
+		},
+		ttl:   ttl,
+		cache: make(map[string]cachedStock),
+	}
+}
+
+// GetStock returns the stock of each item, keyed by Item.Key. Items are
+// looked up MaxBatchSize at a time; when a batch fails its items fall back
+// to stale cached stock or StockUnknown, and the error is returned alongside
+// the partial result.
+func (c *Client) GetStock(ctx context.Context, items []Item) (map[string]Stock, error) {
+	now := time.Now()
+	result := make(map[string]Stock, len(items))
+
+	var missing []Item
+	c.mu.Lock()
+	for _, item := range items {
+		key := item.Key()
+		if _, seen := result[key]; seen {
+			continue
+		}
+		cached, ok := c.cache[key]
+		hit := ok && now.Before(cached.expiresAt)
+		metrics.RecordCacheLookup("inventory_stock", hit)
+		if hit {
+			result[key] = cached.stock
+		} else {
+			result[key] = Stock{Status: StockUnknown}
+			missing = append(missing, item)
+		}
+	}
+	c.mu.Unlock()
+
+	var errs []error
+	for len(missing) > 0 {
+		batch := missing[:min(len(missing), MaxBatchSize)]
+		missing = missing[len(batch):]
+
+		levels, err := c.fetch(ctx, batch)
+		if err != nil {
+			errs = append(errs, err)
+			c.fallback(batch, result, now)
+			continue
+		}
+		c.store(batch, levels, result, now)
+	}
+	return result, errors.Join(errs...)
+}
+
+func (c *Client) fetch(ctx context.Context, items []Item) ([]stockLevel, error) {
+	body, err := json.Marshal(map[string][]Item{"items": items})
+	if err != nil {
+		return nil, err
+	}
+
+	endpoint := fmt.Sprintf("%s/api/v1/inventory/stock/batch", c.baseURL)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("inventory service returned status %d", resp.StatusCode)
+	}
+
+	var parsed stockResponse
+	if err := json.NewDecoder(resp.Body).Decode(&parsed); err != nil {
+		return nil, err
+	}
+	return parsed.Data, nil
+}
+
+// store caches a fetched batch. Items the inventory service doesn't track
+// are out of stock.
+func (c *Client) store(batch []Item, levels []stockLevel, result map[string]Stock, now time.Time) {
+	available := make(map[string]int, len(levels))
+	for _, level := range levels {
+		available[Item{ProductID: level.ProductID, VariantID: level.VariantID}.Key()] = level.Available
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.cache)+len(batch) > cacheMaxItems {
+		for key, entry := range c.cache {
+			if now.After(entry.expiresAt.Add(staleFor)) {
+				delete(c.cache, key)
+			}
+		}
+		if len(c.cache)+len(batch) > cacheMaxItems {
+			c.cache = make(map[string]cachedStock)
+		}
+	}
+
+	for _, item := range batch {
+		quantity := available[item.Key()]
+		stock := Stock{Available: quantity, Status: stockStatus(quantity), CheckedAt: now}
+		c.cache[item.Key()] = cachedStock{stock: stock, expiresAt: now.Add(c.ttl)}
+		result[item.Key()] = stock
+	}
+}
+
+// fallback serves stale cached stock for a failed batch, or StockUnknown
+func (c *Client) fallback(batch []Item, result map[string]Stock, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, item := range batch {
+		if cached, ok := c.cache[item.Key()]; ok && now.Before(cached.expiresAt.Add(staleFor)) {
+			stock := cached.stock
+			stock.Stale = true
+			result[item.Key()] = stock
+		} else {
+			result[item.Key()] = Stock{Status: StockUnknown}
+		}
+	}
+}
+
+func stockStatus(available int) string {
+	switch {
+	case available <= 0:
+		return StockOutOfStock
+	case available <= LowStockThreshold:
+		return StockLowStock
+	}
+	return StockInStock
+}
@@ -0,0 +1,72 @@
+package inventoryclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_GetStock(t *testing.T) {
+	productID, variantID, untracked := uuid.New(), uuid.New(), uuid.New()
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		assert.Equal(t, "/api/v1/inventory/stock/batch", r.URL.Path)
+
+		var body struct{ Items []Item }
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Len(t, body.Items, 3)
+
+		fmt.Fprintf(w, `{"success": true, "data": [
+			{"product_id": %q, "available": 40},
+			{"product_id": %q, "variant_id": %q, "available": 2}
+		]}`, productID, productID, variantID)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, time.Minute)
+	items := []Item{{ProductID: productID}, {ProductID: productID, VariantID: &variantID}, {ProductID: untracked}}
+	for i := 0; i < 2; i++ {
+		stock, err := client.GetStock(context.Background(), items)
+		require.NoError(t, err)
+		assert.Equal(t, StockInStock, stock[items[0].Key()].Status)
+		assert.Equal(t, StockLowStock, stock[items[1].Key()].Status)
+		assert.Equal(t, 2, stock[items[1].Key()].Available)
+		assert.Equal(t, StockOutOfStock, stock[items[2].Key()].Status)
+	}
+	assert.Equal(t, 1, calls, "second lookup should be served from cache")
+}
+
+func TestClient_GetStock_Partial(t *testing.T) {
+	productID := uuid.New()
+	failing := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failing {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprintf(w, `{"success": true, "data": [{"product_id": %q, "available": 12}]}`, productID)
+	}))
+	defer server.Close()
+
+	// A nanosecond TTL expires the cached level right away, leaving it stale
+	client := NewClient(server.URL, time.Nanosecond)
+	known, unknown := Item{ProductID: productID}, Item{ProductID: uuid.New()}
+	_, err := client.GetStock(context.Background(), []Item{known})
+	require.NoError(t, err)
+
+	failing = true
+	stock, err := client.GetStock(context.Background(), []Item{known, unknown})
+	assert.Error(t, err)
+	assert.Equal(t, StockInStock, stock[known.Key()].Status)
+	assert.True(t, stock[known.Key()].Stale)
+	assert.Equal(t, StockUnknown, stock[unknown.Key()].Status)
+}
@@ -0,0 +1,213 @@
+// Package jwks fetches and caches the token signing keys an identity provider
+// publishes as a JSON Web Key Set.
+package jwks
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/metrics"
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/tracing"
+	"go.uber.org/zap"
+	"golang.org/x/sync/singleflight"
+)
+
+// ErrKeyNotFound is returned for a key ID the identity provider doesn't publish
+var ErrKeyNotFound = errors.New("signing key not found")
+
+const (
+	defaultCacheTTL = time.Hour
+	// defaultRefreshInterval limits refetches for unknown key IDs, so
+	// tokens with made-up IDs can't hammer the identity provider
+	defaultRefreshInterval = 30 * time.Second
+	// minRSAKeyBits is the smallest RSA modulus accepted for verifying tokens
+	minRSAKeyBits = 2048
+)
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// KeySet is the identity provider's RSA signing keys, by key ID. Keys are
+// cached for the TTL; a token signed with a key ID not in the cache triggers
+// a refetch, which is how rotated keys are picked up without a redeploy.
+type KeySet struct {
+	url        string
+	httpClient *http.Client
+	ttl        time.Duration
+	refresh    time.Duration
+	logger     *zap.Logger
+
+	// refetches collapses concurrent refetches into one request, made
+	// without holding mu so cached keys keep being served meanwhile
+	refetches singleflight.Group
+
+	mu          sync.Mutex
+	keys        map[string]*rsa.PublicKey
+	fetchedAt   time.Time
+	attemptedAt time.Time
+}
+
+// NewKeySet creates a key set fetched from the JWKS URL and cached for ttl
+// (an hour if zero)
+func NewKeySet(url string, ttl time.Duration, logger *zap.Logger) *KeySet {
+	if ttl <= 0 {
+		ttl = defaultCacheTTL
+	}
+	return &KeySet{
+		url: url,
+		httpClient: &http.Client{
+			Transport: tracing.Transport(nil),
+			Timeout:   5 * time.Second,
+		},
+		ttl:     ttl,
+		refresh: defaultRefreshInterval,
+		logger:  logger,
+	}
+}
+
+// WithRefreshInterval sets how often tokens with a key ID that isn't cached
+// may trigger a refetch, 30s by default
+func (s *KeySet) WithRefreshInterval(interval time.Duration) *KeySet {
+	s.refresh = interval
+	return s
+}
+
+// PublicKey returns the key with the given ID. A token without a key ID can
+// only be verified while the provider publishes a single key. When the key
+// set can't be refetched, cached keys keep being served.
+func (s *KeySet) PublicKey(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	s.mu.Lock()
+	key, ok := s.lookup(kid)
+	fresh := time.Since(s.fetchedAt) < s.ttl
+	s.mu.Unlock()
+	metrics.RecordCacheLookup("jwks", ok && fresh)
+	if ok && fresh {
+		return key, nil
+	}
+
+	// The shared refetch outlives a caller that gives up on it
+	_, err, _ := s.refetches.Do(s.url, func() (interface{}, error) {
+		return nil, s.refetch(context.WithoutCancel(ctx))
+	})
+
+	s.mu.Lock()
+	key, ok = s.lookup(kid)
+	s.mu.Unlock()
+	if ok {
+		return key, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return nil, fmt.Errorf("%w: %q", ErrKeyNotFound, kid)
+}
+
+// refetch fetches the key set, unless that was tried within the refresh
+// interval, and swaps in the fetched keys
+func (s *KeySet) refetch(ctx context.Context) error {
+	s.mu.Lock()
+	now := time.Now()
+	if now.Sub(s.attemptedAt) < s.refresh {
+		s.mu.Unlock()
+		return nil
+	}
+	s.attemptedAt = now
+	s.mu.Unlock()
+
+	keys, err := s.fetch(ctx)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.keys, s.fetchedAt = keys, now
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *KeySet) lookup(kid string) (*rsa.PublicKey, bool) {
+	if kid == "" && len(s.keys) == 1 {
+		for _, key := range s.keys {
+			return key, true
+		}
+	}
+	key, ok := s.keys[kid]
+	return key, ok
+}
+
+func (s *KeySet) fetch(ctx context.Context) (map[string]*rsa.PublicKey, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetch JWKS: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("JWKS endpoint returned status %d", resp.StatusCode)
+	}
+
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return nil, fmt.Errorf("decode JWKS: %w", err)
+	}
+
+	// One bad key mustn't lock out tokens signed with the others
+	keys := make(map[string]*rsa.PublicKey, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Kty != "RSA" || (jwk.Use != "" && jwk.Use != "sig") {
+			s.logger.Warn("Skipping unsupported JWKS key",
+				zap.String("kid", jwk.Kid), zap.String("kty", jwk.Kty), zap.String("use", jwk.Use))
+			continue
+		}
+		key, err := rsaPublicKey(jwk)
+		if err != nil {
+			s.logger.Warn("Skipping invalid JWKS key", zap.String("kid", jwk.Kid), zap.Error(err))
+			continue
+		}
+		keys[jwk.Kid] = key
+	}
+	return keys, nil
+}
+
+// rsaPublicKey decodes the base64url modulus and exponent of an RSA JWK,
+// rejecting moduli under minRSAKeyBits
+func rsaPublicKey(jwk jsonWebKey) (*rsa.PublicKey, error) {
+	n, err := base64.RawURLEncoding.DecodeString(jwk.N)
+	if err != nil {
+		return nil, fmt.Errorf("invalid modulus: %w", err)
+	}
+	e, err := base64.RawURLEncoding.DecodeString(jwk.E)
+	if err != nil {
+		return nil, fmt.Errorf("invalid exponent: %w", err)
+	}
+	exponent := new(big.Int).SetBytes(e)
+	if len(n) == 0 || !exponent.IsInt64() || exponent.Int64() < 3 {
+		return nil, errors.New("invalid RSA key")
+	}
+	modulus := new(big.Int).SetBytes(n)
+	if modulus.BitLen() < minRSAKeyBits {
+		return nil, fmt.Errorf("RSA key of %d bits is under %d", modulus.BitLen(), minRSAKeyBits)
+	}
+	return &rsa.PublicKey{N: modulus, E: int(exponent.Int64())}, nil
+}
@@ -0,0 +1,179 @@
+package jwks
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func newKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	return key
+}
+
+func jwk(kid string, key *rsa.PrivateKey) map[string]string {
+	return map[string]string{
+		"kty": "RSA",
+		"kid": kid,
+		"use": "sig",
+		"alg": "RS256",
+		"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}
+}
+
+func TestKeySet_PublicKey(t *testing.T) {
+	current := newKey(t)
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		json.NewEncoder(w).Encode(map[string]any{"keys": []any{
+			jwk("current", current),
+			map[string]string{"kty": "EC", "kid": "ec-key"},
+		}})
+	}))
+	defer server.Close()
+
+	keys := NewKeySet(server.URL, time.Hour, zap.NewNop())
+	for i := 0; i < 2; i++ {
+		key, err := keys.PublicKey(context.Background(), "current")
+		require.NoError(t, err)
+		assert.True(t, current.PublicKey.Equal(key))
+	}
+	assert.Equal(t, 1, calls, "second lookup should be served from cache")
+
+	key, err := keys.PublicKey(context.Background(), "")
+	require.NoError(t, err, "a token without a key ID uses the only key")
+	assert.True(t, current.PublicKey.Equal(key))
+
+	_, err = keys.PublicKey(context.Background(), "ec-key")
+	assert.ErrorIs(t, err, ErrKeyNotFound, "only RSA keys are used")
+}
+
+func TestKeySet_Rotation(t *testing.T) {
+	old, rotated := newKey(t), newKey(t)
+	published := []any{jwk("old", old)}
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		json.NewEncoder(w).Encode(map[string]any{"keys": published})
+	}))
+	defer server.Close()
+
+	keys := NewKeySet(server.URL, time.Hour, zap.NewNop())
+	_, err := keys.PublicKey(context.Background(), "old")
+	require.NoError(t, err)
+
+	// The provider rotates; a token signed with the new key triggers a refetch
+	published = []any{jwk("old", old), jwk("new", rotated)}
+	keys.attemptedAt = time.Time{}
+	key, err := keys.PublicKey(context.Background(), "new")
+	require.NoError(t, err)
+	assert.True(t, rotated.PublicKey.Equal(key))
+	assert.Equal(t, 2, calls)
+
+	_, err = keys.PublicKey(context.Background(), "made-up")
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+	assert.Equal(t, 2, calls, "unknown key IDs refetch at most every refresh interval")
+}
+
+func TestKeySet_ServesCachedKeysWhenProviderFails(t *testing.T) {
+	current := newKey(t)
+	failing := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failing {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{"keys": []any{jwk("current", current)}})
+	}))
+	defer server.Close()
+
+	// A nanosecond TTL expires the cached keys right away
+	keys := NewKeySet(server.URL, time.Nanosecond, zap.NewNop())
+	_, err := keys.PublicKey(context.Background(), "current")
+	require.NoError(t, err)
+
+	failing = true
+	keys.attemptedAt = time.Time{}
+	key, err := keys.PublicKey(context.Background(), "current")
+	require.NoError(t, err)
+	assert.True(t, current.PublicKey.Equal(key))
+
+	keys.attemptedAt = time.Time{}
+	_, err = keys.PublicKey(context.Background(), "other")
+	assert.Error(t, err)
+}
+
+func TestKeySet_SkipsInvalidKeys(t *testing.T) {
+	current := newKey(t)
+	weak, err := rsa.GenerateKey(rand.Reader, 1024)
+	require.NoError(t, err)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{"keys": []any{
+			map[string]string{"kty": "RSA", "kid": "malformed", "n": "not base64!", "e": "AQAB"},
+			jwk("weak", weak),
+			jwk("current", current),
+		}})
+	}))
+	defer server.Close()
+
+	keys := NewKeySet(server.URL, time.Hour, zap.NewNop())
+	key, err := keys.PublicKey(context.Background(), "current")
+	require.NoError(t, err, "a malformed key doesn't fail the whole set")
+	assert.True(t, current.PublicKey.Equal(key))
+
+	_, err = keys.PublicKey(context.Background(), "malformed")
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+	_, err = keys.PublicKey(context.Background(), "weak")
+	assert.ErrorIs(t, err, ErrKeyNotFound, "RSA keys under 2048 bits are rejected")
+}
+
+func TestKeySet_ConcurrentLookupsShareOneFetch(t *testing.T) {
+	current := newKey(t)
+	var calls atomic.Int32
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		<-release
+		json.NewEncoder(w).Encode(map[string]any{"keys": []any{jwk("current", current)}})
+	}))
+	defer server.Close()
+
+	keys := NewKeySet(server.URL, time.Hour, zap.NewNop())
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := keys.PublicKey(context.Background(), "current")
+			errs <- err
+		}()
+	}
+	// Let the lookups pile up behind the first fetch
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, int32(1), calls.Load())
+}
@@ -0,0 +1,148 @@
+// Package legacycrm mirrors customer and address writes to the legacy CRM
+// database while customers are migrated off it, and reports where the two
+// databases have drifted apart.
+package legacycrm
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Config configures the legacy CRM bridge
+type Config struct {
+	// DualWrite mirrors writes to the legacy CRM. Turn it off once the
+	// migration is complete; drift reports keep working.
+	DualWrite      bool
+	CustomersTable string
+	AddressesTable string
+	Timeout        time.Duration // per mirrored write
+}
+
+// Bridge keeps the legacy CRM database in step with this service's customers
+// and addresses. Mirrored writes are best effort: failures are logged and
+// counted, and show up in the drift report.
+type Bridge struct {
+	primary  *gorm.DB
+	legacy   *gorm.DB
+	cfg      Config
+	logger   *zap.Logger
+	mirrored atomic.Int64
+	failed   atomic.Int64
+}
+
+// New creates a bridge from the primary database to the legacy CRM database
+func New(primary, legacy *gorm.DB, cfg Config, logger *zap.Logger) *Bridge {
+	if cfg.CustomersTable == "" {
+		cfg.CustomersTable = "customers"
+	}
+	if cfg.AddressesTable == "" {
+		cfg.AddressesTable = "customer_addresses"
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 5 * time.Second
+	}
+	return &Bridge{primary: primary, legacy: legacy, cfg: cfg, logger: logger}
+}
+
+// DualWrite reports whether writes are mirrored to the legacy CRM
+func (b *Bridge) DualWrite() bool {
+	return b.cfg.DualWrite
+}
+
+// MirrorCustomer copies the customer to the legacy CRM, or removes it there
+// if it was deleted here
+func (b *Bridge) MirrorCustomer(ctx context.Context, customerID uuid.UUID) {
+	if !b.cfg.DualWrite {
+		return
+	}
+	ctx, cancel := b.mirrorContext(ctx)
+	defer cancel()
+
+	var customer domain.Customer
+	err := b.primary.WithContext(ctx).Unscoped().Where("id = ?", customerID).First(&customer).Error
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		err = b.deleteRows(ctx, b.cfg.CustomersTable, customerID)
+	case err != nil:
+	case customer.DeletedAt.Valid:
+		err = b.deleteRows(ctx, b.cfg.CustomersTable, customerID)
+	default:
+		err = b.upsert(ctx, b.cfg.CustomersTable, newCustomerRow(&customer))
+	}
+	b.record(err, zap.String("customer_id", customerID.String()))
+}
+
+// MirrorAddresses copies all of the user's addresses to the legacy CRM.
+// Whole address books are mirrored because changing one address can clear
+// the default flag on the others. Deleted addresses are removed there.
+func (b *Bridge) MirrorAddresses(ctx context.Context, userID uuid.UUID) {
+	if !b.cfg.DualWrite {
+		return
+	}
+	ctx, cancel := b.mirrorContext(ctx)
+	defer cancel()
+
+	err := b.mirrorAddresses(ctx, userID)
+	b.record(err, zap.String("user_id", userID.String()))
+}
+
+func (b *Bridge) mirrorAddresses(ctx context.Context, userID uuid.UUID) error {
+	var addresses []domain.Address
+	if err := b.primary.WithContext(ctx).Unscoped().Where("user_id = ?", userID).Find(&addresses).Error; err != nil {
+		return err
+	}
+
+	var deleted []uuid.UUID
+	for i := range addresses {
+		if addresses[i].DeletedAt.Valid {
+			deleted = append(deleted, addresses[i].ID)
+			continue
+		}
+		if err := b.upsert(ctx, b.cfg.AddressesTable, newAddressRow(&addresses[i])); err != nil {
+			return err
+		}
+	}
+	return b.deleteRows(ctx, b.cfg.AddressesTable, deleted...)
+}
+
+// Stats returns how many mirrored writes succeeded and failed since startup
+func (b *Bridge) Stats() (mirrored, failed int64) {
+	return b.mirrored.Load(), b.failed.Load()
+}
+
+// mirrorContext detaches the mirrored write from the request, which may
+// already be finished, and bounds it by the configured timeout
+func (b *Bridge) mirrorContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.WithoutCancel(ctx), b.cfg.Timeout)
+}
+
+func (b *Bridge) upsert(ctx context.Context, table string, row interface{}) error {
+	return b.legacy.WithContext(ctx).
+		Table(table).
+		Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "id"}}, UpdateAll: true}).
+		Create(row).Error
+}
+
+func (b *Bridge) deleteRows(ctx context.Context, table string, ids ...uuid.UUID) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return b.legacy.WithContext(ctx).Table(table).Where("id IN ?", ids).Delete(map[string]interface{}{}).Error
+}
+
+func (b *Bridge) record(err error, fields ...zap.Field) {
+	if err != nil {
+		b.failed.Add(1)
+		b.logger.Warn("Failed to mirror write to legacy CRM", append(fields, zap.Error(err))...)
+		return
+	}
+	b.mirrored.Add(1)
+}
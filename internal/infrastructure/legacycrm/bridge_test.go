@@ -0,0 +1,116 @@
+package legacycrm
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// openPrimary opens an in-memory database with the public and customer
+// schemas attached, so the models' schema-qualified tables resolve
+func openPrimary(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1)
+
+	require.NoError(t, db.Exec("ATTACH DATABASE ':memory:' AS public").Error)
+	require.NoError(t, db.Exec("ATTACH DATABASE ':memory:' AS customer").Error)
+	require.NoError(t, db.Exec(`CREATE TABLE public.customers (
+		id TEXT PRIMARY KEY, email TEXT, first_name TEXT, last_name TEXT, phone TEXT, avatar_url TEXT,
+		status TEXT DEFAULT 'active', total_orders INTEGER DEFAULT 0, total_spent REAL DEFAULT 0,
+		first_order_at DATETIME, last_order_at DATETIME, recency_score INTEGER DEFAULT 0, frequency_score INTEGER DEFAULT 0,
+		monetary_score INTEGER DEFAULT 0, rfm_segment TEXT, lifetime_value REAL DEFAULT 0, scored_at DATETIME,
+		churn_risk TEXT, churn_flagged_at DATETIME, phone_index TEXT,
+		version INTEGER DEFAULT 1, created_at DATETIME, updated_at DATETIME, deleted_at DATETIME)`).Error)
+	require.NoError(t, db.Exec(`CREATE TABLE customer.addresses (
+		id TEXT PRIMARY KEY, user_id TEXT, label TEXT, recipient_name TEXT, phone TEXT,
+		address_line1 TEXT, address_line2 TEXT, city TEXT, state TEXT, postcode TEXT, country TEXT,
+		is_default BOOLEAN DEFAULT false, latitude REAL, longitude REAL, validated_at DATETIME, version INTEGER DEFAULT 1,
+		created_at DATETIME, updated_at DATETIME, deleted_at DATETIME)`).Error)
+	return db
+}
+
+func openLegacy(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1)
+
+	require.NoError(t, db.Table("customers").AutoMigrate(&customerRow{}))
+	require.NoError(t, db.Table("customer_addresses").AutoMigrate(&addressRow{}))
+	return db
+}
+
+func TestBridge_MirrorAndDrift(t *testing.T) {
+	primary, legacy := openPrimary(t), openLegacy(t)
+	bridge := New(primary, legacy, Config{DualWrite: true}, zap.NewNop())
+	ctx := context.Background()
+
+	customer := &domain.Customer{ID: uuid.New(), Email: "aisyah@example.com", FirstName: "Aisyah", Status: "active", Version: 1}
+	require.NoError(t, primary.Create(customer).Error)
+	home := &domain.Address{ID: uuid.New(), UserID: customer.ID, Label: "Home", RecipientName: "Aisyah", Phone: "0123456789",
+		AddressLine1: "1 Jalan Ampang", City: "Kuala Lumpur", State: "WP", Postcode: "50450", Country: "Malaysia", IsDefault: true}
+	require.NoError(t, primary.Create(home).Error)
+
+	bridge.MirrorCustomer(ctx, customer.ID)
+	bridge.MirrorAddresses(ctx, customer.ID)
+
+	report, err := bridge.Drift(ctx, 100)
+	require.NoError(t, err)
+	assert.True(t, report.InSync(), "%+v", report)
+	assert.Equal(t, int64(2), report.Mirrored)
+	assert.Zero(t, report.FailedWrites)
+
+	// A write that bypassed the bridge shows up as a mismatch
+	require.NoError(t, primary.Model(&domain.Customer{}).Where("id = ?", customer.ID).UpdateColumn("phone", "0198765432").Error)
+	// A deleted address that was never mirrored is stale in the legacy CRM
+	require.NoError(t, primary.Delete(home).Error)
+	office := &domain.Address{ID: uuid.New(), UserID: customer.ID, Label: "Office", RecipientName: "Aisyah", Phone: "0123456789",
+		AddressLine1: "88 Jalan Sultan Ismail", City: "Kuala Lumpur", State: "WP", Postcode: "50250", Country: "Malaysia"}
+	require.NoError(t, primary.Create(office).Error)
+
+	report, err = bridge.Drift(ctx, 100)
+	require.NoError(t, err)
+	assert.False(t, report.InSync())
+	require.Len(t, report.Customers.Mismatched, 1)
+	assert.Equal(t, []string{"phone"}, report.Customers.Mismatched[0].Columns)
+	assert.Equal(t, []uuid.UUID{office.ID}, report.Addresses.Missing)
+	assert.Equal(t, []uuid.UUID{home.ID}, report.Addresses.Stale)
+
+	// Mirroring again repairs the drift
+	bridge.MirrorCustomer(ctx, customer.ID)
+	bridge.MirrorAddresses(ctx, customer.ID)
+	report, err = bridge.Drift(ctx, 100)
+	require.NoError(t, err)
+	assert.True(t, report.InSync(), "%+v", report)
+}
+
+func TestBridge_DualWriteOff(t *testing.T) {
+	primary, legacy := openPrimary(t), openLegacy(t)
+	bridge := New(primary, legacy, Config{DualWrite: false}, zap.NewNop())
+	ctx := context.Background()
+
+	customer := &domain.Customer{ID: uuid.New(), Email: "aisyah@example.com", Status: "active", Version: 1}
+	require.NoError(t, primary.Create(customer).Error)
+	bridge.MirrorCustomer(ctx, customer.ID)
+
+	var count int64
+	require.NoError(t, legacy.Table("customers").Count(&count).Error)
+	assert.Zero(t, count)
+
+	// Drift reports still work with dual writes off
+	report, err := bridge.Drift(ctx, 100)
+	require.NoError(t, err)
+	assert.False(t, report.DualWrite)
+	assert.Equal(t, []uuid.UUID{customer.ID}, report.Customers.Missing)
+}
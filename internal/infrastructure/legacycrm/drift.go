@@ -0,0 +1,179 @@
+package legacycrm
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"gorm.io/gorm"
+)
+
+// DriftReport compares the most recently changed customers and addresses
+// with their copies in the legacy CRM
+type DriftReport struct {
+	GeneratedAt  time.Time  `json:"generated_at"`
+	DualWrite    bool       `json:"dual_write"`
+	Mirrored     int64      `json:"mirrored_writes"`
+	FailedWrites int64      `json:"failed_writes"`
+	Customers    TableDrift `json:"customers"`
+	Addresses    TableDrift `json:"addresses"`
+}
+
+// InSync reports whether no drift was found
+func (r *DriftReport) InSync() bool {
+	return r.Customers.InSync() && r.Addresses.InSync()
+}
+
+// TableDrift is the drift found in one table
+type TableDrift struct {
+	PrimaryCount int64 `json:"primary_count"`
+	LegacyCount  int64 `json:"legacy_count"`
+	Checked      int   `json:"checked"`
+	// Missing rows exist here but not in the legacy CRM
+	Missing []uuid.UUID `json:"missing"`
+	// Stale rows were deleted here but still exist in the legacy CRM
+	Stale      []uuid.UUID `json:"stale"`
+	Mismatched []Mismatch  `json:"mismatched"`
+}
+
+// InSync reports whether the checked rows and the row counts match
+func (d TableDrift) InSync() bool {
+	return d.PrimaryCount == d.LegacyCount && len(d.Missing) == 0 && len(d.Stale) == 0 && len(d.Mismatched) == 0
+}
+
+// Mismatch is a row whose columns differ between the two databases
+type Mismatch struct {
+	ID      uuid.UUID `json:"id"`
+	Columns []string  `json:"columns"`
+}
+
+// recentFirst orders rows by their last change, deletions included
+const recentFirst = "COALESCE(deleted_at, updated_at) DESC"
+
+// Drift checks up to limit of the most recently changed customers and
+// addresses against the legacy CRM. Recent changes are where failed or
+// bypassed dual writes show up first; the row counts cover the rest.
+func (b *Bridge) Drift(ctx context.Context, limit int) (*DriftReport, error) {
+	mirrored, failed := b.Stats()
+	report := &DriftReport{
+		GeneratedAt:  time.Now().UTC(),
+		DualWrite:    b.cfg.DualWrite,
+		Mirrored:     mirrored,
+		FailedWrites: failed,
+	}
+
+	var err error
+	if report.Customers, err = b.customerDrift(ctx, limit); err != nil {
+		return nil, err
+	}
+	if report.Addresses, err = b.addressDrift(ctx, limit); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+func (b *Bridge) customerDrift(ctx context.Context, limit int) (TableDrift, error) {
+	var customers []domain.Customer
+	if err := b.primary.WithContext(ctx).Unscoped().Order(recentFirst).Limit(limit).Find(&customers).Error; err != nil {
+		return TableDrift{}, err
+	}
+
+	ids := make([]uuid.UUID, len(customers))
+	expected := make(map[uuid.UUID]map[string]interface{}, len(customers))
+	for i := range customers {
+		ids[i] = customers[i].ID
+		if !customers[i].DeletedAt.Valid {
+			expected[ids[i]] = newCustomerRow(&customers[i]).compared()
+		}
+	}
+
+	var rows []customerRow
+	if len(ids) > 0 {
+		if err := b.legacy.WithContext(ctx).Table(b.cfg.CustomersTable).Where("id IN ?", ids).Find(&rows).Error; err != nil {
+			return TableDrift{}, err
+		}
+	}
+	actual := make(map[uuid.UUID]map[string]interface{}, len(rows))
+	for i := range rows {
+		actual[rows[i].ID] = rows[i].compared()
+	}
+
+	drift := compareRows(ids, expected, actual)
+	err := b.countRows(ctx, &drift, b.primary.Model(&domain.Customer{}), b.cfg.CustomersTable)
+	return drift, err
+}
+
+func (b *Bridge) addressDrift(ctx context.Context, limit int) (TableDrift, error) {
+	var addresses []domain.Address
+	if err := b.primary.WithContext(ctx).Unscoped().Order(recentFirst).Limit(limit).Find(&addresses).Error; err != nil {
+		return TableDrift{}, err
+	}
+
+	ids := make([]uuid.UUID, len(addresses))
+	expected := make(map[uuid.UUID]map[string]interface{}, len(addresses))
+	for i := range addresses {
+		ids[i] = addresses[i].ID
+		if !addresses[i].DeletedAt.Valid {
+			expected[ids[i]] = newAddressRow(&addresses[i]).compared()
+		}
+	}
+
+	var rows []addressRow
+	if len(ids) > 0 {
+		if err := b.legacy.WithContext(ctx).Table(b.cfg.AddressesTable).Where("id IN ?", ids).Find(&rows).Error; err != nil {
+			return TableDrift{}, err
+		}
+	}
+	actual := make(map[uuid.UUID]map[string]interface{}, len(rows))
+	for i := range rows {
+		actual[rows[i].ID] = rows[i].compared()
+	}
+
+	drift := compareRows(ids, expected, actual)
+	err := b.countRows(ctx, &drift, b.primary.Model(&domain.Address{}), b.cfg.AddressesTable)
+	return drift, err
+}
+
+// countRows fills in the live row count here and the row count in the legacy table
+func (b *Bridge) countRows(ctx context.Context, drift *TableDrift, primary *gorm.DB, legacyTable string) error {
+	if err := primary.WithContext(ctx).Count(&drift.PrimaryCount).Error; err != nil {
+		return err
+	}
+	return b.legacy.WithContext(ctx).Table(legacyTable).Count(&drift.LegacyCount).Error
+}
+
+// compareRows compares the checked rows. expected holds the columns of live
+// rows here; ids without an entry were deleted here.
+func compareRows(ids []uuid.UUID, expected, actual map[uuid.UUID]map[string]interface{}) TableDrift {
+	drift := TableDrift{
+		Checked:    len(ids),
+		Missing:    []uuid.UUID{},
+		Stale:      []uuid.UUID{},
+		Mismatched: []Mismatch{},
+	}
+
+	for _, id := range ids {
+		want, live := expected[id]
+		got, mirrored := actual[id]
+		switch {
+		case live && !mirrored:
+			drift.Missing = append(drift.Missing, id)
+		case !live && mirrored:
+			drift.Stale = append(drift.Stale, id)
+		case live:
+			var columns []string
+			for column, value := range want {
+				if got[column] != value {
+					columns = append(columns, column)
+				}
+			}
+			if len(columns) > 0 {
+				sort.Strings(columns)
+				drift.Mismatched = append(drift.Mismatched, Mismatch{ID: id, Columns: columns})
+			}
+		}
+	}
+	return drift
+}
@@ -0,0 +1,104 @@
+package legacycrm
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+)
+
+// customerRow is a customer as stored in the legacy CRM
+type customerRow struct {
+	ID          uuid.UUID `gorm:"type:uuid;primaryKey"`
+	Email       string
+	FirstName   string
+	LastName    string
+	Phone       string
+	Status      string
+	TotalOrders int
+	TotalSpent  float64
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+func newCustomerRow(c *domain.Customer) *customerRow {
+	return &customerRow{
+		ID:          c.ID,
+		Email:       c.Email,
+		FirstName:   c.FirstName,
+		LastName:    c.LastName,
+		Phone:       c.Phone,
+		Status:      string(c.Status),
+		TotalOrders: c.TotalOrders,
+		TotalSpent:  c.TotalSpent,
+		CreatedAt:   c.CreatedAt,
+		UpdatedAt:   c.UpdatedAt,
+	}
+}
+
+// compared returns the columns checked for drift. Timestamps are left out
+// since the two databases store them with different precision.
+func (r *customerRow) compared() map[string]interface{} {
+	return map[string]interface{}{
+		"email":        r.Email,
+		"first_name":   r.FirstName,
+		"last_name":    r.LastName,
+		"phone":        r.Phone,
+		"status":       r.Status,
+		"total_orders": r.TotalOrders,
+		"total_spent":  r.TotalSpent,
+	}
+}
+
+// addressRow is a customer address as stored in the legacy CRM
+type addressRow struct {
+	ID            uuid.UUID `gorm:"type:uuid;primaryKey"`
+	CustomerID    uuid.UUID `gorm:"type:uuid"`
+	Label         string
+	RecipientName string
+	Phone         string
+	AddressLine1  string `gorm:"column:address_line1"`
+	AddressLine2  string `gorm:"column:address_line2"`
+	City          string
+	State         string
+	Postcode      string
+	Country       string
+	IsDefault     bool
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+func newAddressRow(a *domain.Address) *addressRow {
+	return &addressRow{
+		ID:            a.ID,
+		CustomerID:    a.UserID,
+		Label:         string(a.Label),
+		RecipientName: a.RecipientName,
+		Phone:         a.Phone,
+		AddressLine1:  a.AddressLine1,
+		AddressLine2:  a.AddressLine2,
+		City:          a.City,
+		State:         a.State,
+		Postcode:      a.Postcode,
+		Country:       a.Country,
+		IsDefault:     a.IsDefault,
+		CreatedAt:     a.CreatedAt,
+		UpdatedAt:     a.UpdatedAt,
+	}
+}
+
+func (r *addressRow) compared() map[string]interface{} {
+	return map[string]interface{}{
+		"customer_id":    r.CustomerID,
+		"label":          r.Label,
+		"recipient_name": r.RecipientName,
+		"phone":          r.Phone,
+		"address_line1":  r.AddressLine1,
+		"address_line2":  r.AddressLine2,
+		"city":           r.City,
+		"state":          r.State,
+		"postcode":       r.Postcode,
+		"country":        r.Country,
+		"is_default":     r.IsDefault,
+	}
+}
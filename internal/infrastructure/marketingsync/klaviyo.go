@@ -0,0 +1,214 @@
+package marketingsync
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+)
+
+const (
+	klaviyoEndpoint = "https://a.klaviyo.com/api"
+	klaviyoRevision = "2024-10-15"
+)
+
+// klaviyoBatchSize keeps every bulk job of a batch well under Klaviyo's
+// per-job profile limits
+const klaviyoBatchSize = 500
+
+// Klaviyo pushes contacts with Klaviyo's bulk profile import. Mapped fields
+// become custom profile properties. With a list configured, consent
+// subscribes or unsubscribes the profile's email and SMS on that list.
+//
+// Bulk jobs are processed asynchronously, so profiles Klaviyo rejects later
+// (e.g. an invalid email) only show in Klaviyo's job errors.
+type Klaviyo struct {
+	apiKey     string
+	listID     string
+	endpoint   string
+	fields     domain.MarketingFieldMap
+	httpClient *http.Client
+}
+
+// NewKlaviyo creates a Klaviyo provider with a private API key
+func NewKlaviyo(cfg ProviderConfig, httpClient *http.Client) (*Klaviyo, error) {
+	if cfg.APIKey == "" {
+		return nil, errors.New("klaviyo: private API key is required")
+	}
+	fields, err := domain.ParseMarketingFieldMap(cfg.FieldMap)
+	if err != nil {
+		return nil, fmt.Errorf("klaviyo: %w", err)
+	}
+
+	return &Klaviyo{
+		apiKey:     cfg.APIKey,
+		listID:     cfg.ListID,
+		endpoint:   klaviyoEndpoint,
+		fields:     fields,
+		httpClient: httpClient,
+	}, nil
+}
+
+// Name returns the provider name
+func (k *Klaviyo) Name() string {
+	return ProviderKlaviyo
+}
+
+// BatchSize returns the most contacts one Push sends
+func (k *Klaviyo) BatchSize() int {
+	return klaviyoBatchSize
+}
+
+type klaviyoResource struct {
+	Type          string                 `json:"type"`
+	ID            string                 `json:"id,omitempty"`
+	Attributes    map[string]interface{} `json:"attributes,omitempty"`
+	Relationships map[string]interface{} `json:"relationships,omitempty"`
+}
+
+type klaviyoDocument struct {
+	Data klaviyoResource `json:"data"`
+}
+
+type klaviyoErrors struct {
+	Errors []struct {
+		Detail string `json:"detail"`
+	} `json:"errors"`
+}
+
+// Push imports the contacts' profiles and then updates their subscriptions
+func (k *Klaviyo) Push(ctx context.Context, contacts []domain.MarketingContact) (map[uuid.UUID]string, error) {
+	profiles := make([]klaviyoResource, 0, len(contacts))
+	for _, contact := range contacts {
+		attributes := map[string]interface{}{
+			"email":       contact.Email,
+			"external_id": contact.CustomerID.String(),
+			"first_name":  contact.FirstName,
+			"last_name":   contact.LastName,
+			"properties":  k.fields.Values(contact),
+		}
+		if phone := klaviyoPhone(contact.Phone); phone != "" {
+			attributes["phone_number"] = phone
+		}
+		profiles = append(profiles, klaviyoResource{Type: "profile", Attributes: attributes})
+	}
+	if err := k.bulkJob(ctx, "profile-bulk-import-job", profiles, nil); err != nil {
+		return nil, err
+	}
+	if k.listID == "" {
+		return nil, nil
+	}
+
+	subscribe, unsubscribe := k.subscriptions(contacts)
+	list := map[string]interface{}{
+		"list": klaviyoDocument{Data: klaviyoResource{Type: "list", ID: k.listID}},
+	}
+	if len(subscribe) > 0 {
+		if err := k.bulkJob(ctx, "profile-subscription-bulk-create-job", subscribe, list); err != nil {
+			return nil, err
+		}
+	}
+	if len(unsubscribe) > 0 {
+		if err := k.bulkJob(ctx, "profile-subscription-bulk-delete-job", unsubscribe, list); err != nil {
+			return nil, err
+		}
+	}
+	return nil, nil
+}
+
+// subscriptions splits the contacts' channels into those to subscribe and
+// those to unsubscribe. SMS needs a phone number in E.164 format.
+func (k *Klaviyo) subscriptions(contacts []domain.MarketingContact) (subscribe, unsubscribe []klaviyoResource) {
+	for _, contact := range contacts {
+		phone := klaviyoPhone(contact.Phone)
+		in, out := map[string]interface{}{}, map[string]interface{}{}
+		channel := func(optIn bool, name string) {
+			if optIn {
+				in[name] = map[string]interface{}{"marketing": map[string]string{"consent": "SUBSCRIBED"}}
+			} else {
+				out[name] = map[string]interface{}{"marketing": map[string]string{"consent": "UNSUBSCRIBED"}}
+			}
+		}
+		channel(contact.EmailOptIn, "email")
+		if phone != "" {
+			channel(contact.SMSOptIn, "sms")
+		}
+
+		profile := func(subscriptions map[string]interface{}) klaviyoResource {
+			attributes := map[string]interface{}{"email": contact.Email, "subscriptions": subscriptions}
+			if _, ok := subscriptions["sms"]; ok {
+				attributes["phone_number"] = phone
+			}
+			return klaviyoResource{Type: "profile", Attributes: attributes}
+		}
+		if len(in) > 0 {
+			subscribe = append(subscribe, profile(in))
+		}
+		if len(out) > 0 {
+			unsubscribe = append(unsubscribe, profile(out))
+		}
+	}
+	return subscribe, unsubscribe
+}
+
+// bulkJob starts a bulk job of jobType over profiles
+func (k *Klaviyo) bulkJob(ctx context.Context, jobType string, profiles []klaviyoResource, relationships map[string]interface{}) error {
+	body, err := json.Marshal(klaviyoDocument{Data: klaviyoResource{
+		Type: jobType,
+		Attributes: map[string]interface{}{
+			"profiles": map[string]interface{}{"data": profiles},
+		},
+		Relationships: relationships,
+	}})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, k.endpoint+"/"+jobType+"s/", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Klaviyo-API-Key "+k.apiKey)
+	req.Header.Set("Revision", klaviyoRevision)
+	req.Header.Set("Content-Type", "application/vnd.api+json")
+	req.Header.Set("Accept", "application/vnd.api+json")
+
+	resp, err := k.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("klaviyo: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return &RateLimitError{Provider: ProviderKlaviyo, RetryAfter: retryAfter(resp)}
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		var problem klaviyoErrors
+		_ = json.NewDecoder(resp.Body).Decode(&problem)
+		details := make([]string, 0, len(problem.Errors))
+		for _, e := range problem.Errors {
+			details = append(details, e.Detail)
+		}
+		return fmt.Errorf("klaviyo: %s: status %d: %s", jobType, resp.StatusCode, strings.Join(details, "; "))
+	}
+	return nil
+}
+
+// klaviyoPhone returns phone if it is in the E.164 format Klaviyo requires
+func klaviyoPhone(phone string) string {
+	phone = strings.NewReplacer(" ", "", "-", "").Replace(phone)
+	if len(phone) < 8 || phone[0] != '+' {
+		return ""
+	}
+	for _, r := range phone[1:] {
+		if r < '0' || r > '9' {
+			return ""
+		}
+	}
+	return phone
+}
@@ -0,0 +1,168 @@
+package marketingsync
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+)
+
+// mailchimpBatchSize is the most members Mailchimp accepts in one batch
+// subscribe call
+const mailchimpBatchSize = 500
+
+// Mailchimp member statuses
+const (
+	mailchimpSubscribed   = "subscribed"
+	mailchimpUnsubscribed = "unsubscribed"
+)
+
+// Mailchimp pushes contacts to a Mailchimp audience with the batch subscribe
+// endpoint. Mapped fields become merge fields; only email consent is synced,
+// as the member's status.
+type Mailchimp struct {
+	apiKey     string
+	listID     string
+	endpoint   string
+	fields     domain.MarketingFieldMap
+	httpClient *http.Client
+}
+
+// NewMailchimp creates a Mailchimp provider. The API key ends in the data
+// center it belongs to, e.g. "...-us21".
+func NewMailchimp(cfg ProviderConfig, httpClient *http.Client) (*Mailchimp, error) {
+	dc := cfg.APIKey[strings.LastIndex(cfg.APIKey, "-")+1:]
+	if dc == "" || dc == cfg.APIKey {
+		return nil, errors.New("mailchimp: API key must end in its data center, e.g. -us21")
+	}
+	if cfg.ListID == "" {
+		return nil, errors.New("mailchimp: audience (list) ID is required")
+	}
+	fields, err := domain.ParseMarketingFieldMap(cfg.FieldMap)
+	if err != nil {
+		return nil, fmt.Errorf("mailchimp: %w", err)
+	}
+
+	return &Mailchimp{
+		apiKey:     cfg.APIKey,
+		listID:     cfg.ListID,
+		endpoint:   "https://" + dc + ".api.mailchimp.com/3.0",
+		fields:     fields,
+		httpClient: httpClient,
+	}, nil
+}
+
+// Name returns the provider name
+func (m *Mailchimp) Name() string {
+	return ProviderMailchimp
+}
+
+// BatchSize returns the most contacts one Push sends
+func (m *Mailchimp) BatchSize() int {
+	return mailchimpBatchSize
+}
+
+type mailchimpMember struct {
+	EmailAddress string                 `json:"email_address"`
+	Status       string                 `json:"status"`
+	StatusIfNew  string                 `json:"status_if_new"`
+	MergeFields  map[string]interface{} `json:"merge_fields,omitempty"`
+}
+
+type mailchimpBatchRequest struct {
+	Members        []mailchimpMember `json:"members"`
+	UpdateExisting bool              `json:"update_existing"`
+}
+
+type mailchimpBatchResponse struct {
+	Errors []struct {
+		EmailAddress string `json:"email_address"`
+		Error        string `json:"error"`
+	} `json:"errors"`
+}
+
+type mailchimpProblem struct {
+	Title  string `json:"title"`
+	Detail string `json:"detail"`
+}
+
+// Push creates or updates the contacts as audience members
+func (m *Mailchimp) Push(ctx context.Context, contacts []domain.MarketingContact) (map[uuid.UUID]string, error) {
+	request := mailchimpBatchRequest{
+		Members:        make([]mailchimpMember, 0, len(contacts)),
+		UpdateExisting: true,
+	}
+	byEmail := make(map[string]uuid.UUID, len(contacts))
+	for _, contact := range contacts {
+		status := mailchimpUnsubscribed
+		if contact.EmailOptIn {
+			status = mailchimpSubscribed
+		}
+		request.Members = append(request.Members, mailchimpMember{
+			EmailAddress: contact.Email,
+			Status:       status,
+			StatusIfNew:  status,
+			MergeFields:  mailchimpMergeFields(m.fields.Values(contact)),
+		})
+		byEmail[strings.ToLower(contact.Email)] = contact.CustomerID
+	}
+
+	body, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, m.endpoint+"/lists/"+m.listID, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.SetBasicAuth("anystring", m.apiKey)
+
+	resp, err := m.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("mailchimp: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return nil, &RateLimitError{Provider: ProviderMailchimp, RetryAfter: retryAfter(resp)}
+	}
+	if resp.StatusCode != http.StatusOK {
+		var problem mailchimpProblem
+		_ = json.NewDecoder(resp.Body).Decode(&problem)
+		return nil, fmt.Errorf("mailchimp: status %d: %s %s", resp.StatusCode, problem.Title, problem.Detail)
+	}
+
+	var result mailchimpBatchResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("mailchimp: decode response: %w", err)
+	}
+	rejected := make(map[uuid.UUID]string)
+	for _, e := range result.Errors {
+		if customerID, ok := byEmail[strings.ToLower(e.EmailAddress)]; ok {
+			rejected[customerID] = e.Error
+		}
+	}
+	return rejected, nil
+}
+
+// mailchimpMergeFields converts values to what merge fields hold: lists are
+// joined and booleans spelled out
+func mailchimpMergeFields(values map[string]interface{}) map[string]interface{} {
+	for field, value := range values {
+		switch v := value.(type) {
+		case []string:
+			values[field] = strings.Join(v, ", ")
+		case bool:
+			values[field] = strconv.FormatBool(v)
+		}
+	}
+	return values
+}
@@ -0,0 +1,99 @@
+// Package marketingsync pushes customer profiles, marketing segments and
+// consent to email marketing platforms (Mailchimp, Klaviyo).
+package marketingsync
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/tracing"
+)
+
+// Marketing platforms
+const (
+	ProviderMailchimp = "mailchimp"
+	ProviderKlaviyo   = "klaviyo"
+)
+
+// defaultRetryAfter is how long to back off from a rate-limited platform that
+// doesn't say when to come back
+const defaultRetryAfter = time.Minute
+
+// Provider pushes contacts to one marketing platform
+type Provider interface {
+	Name() string
+	// BatchSize is the most contacts one Push sends
+	BatchSize() int
+	// Push creates or updates contacts. Contacts the platform rejected are
+	// returned with the reason; an error means none were pushed.
+	Push(ctx context.Context, contacts []domain.MarketingContact) (map[uuid.UUID]string, error)
+}
+
+// RateLimitError is returned by Push when the platform's rate limit is hit.
+// Nothing was pushed; try again after RetryAfter.
+type RateLimitError struct {
+	Provider   string
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("%s rate limit exceeded, retry after %s", e.Provider, e.RetryAfter)
+}
+
+// ProviderConfig configures one marketing platform
+type ProviderConfig struct {
+	APIKey   string
+	ListID   string // Mailchimp audience, or Klaviyo list that consent subscribes to
+	FieldMap string // platform field=customer attribute pairs, see domain.ParseMarketingFieldMap
+}
+
+// Config selects and configures the marketing platforms
+type Config struct {
+	Providers []string // "mailchimp" and/or "klaviyo"
+	Mailchimp ProviderConfig
+	Klaviyo   ProviderConfig
+	Timeout   time.Duration // per request
+}
+
+// New creates the configured providers
+func New(cfg Config) ([]Provider, error) {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 30 * time.Second
+	}
+	httpClient := &http.Client{Transport: tracing.Transport(nil), Timeout: cfg.Timeout}
+
+	providers := make([]Provider, 0, len(cfg.Providers))
+	for _, name := range cfg.Providers {
+		var (
+			provider Provider
+			err      error
+		)
+		switch strings.ToLower(name) {
+		case ProviderMailchimp:
+			provider, err = NewMailchimp(cfg.Mailchimp, httpClient)
+		case ProviderKlaviyo:
+			provider, err = NewKlaviyo(cfg.Klaviyo, httpClient)
+		default:
+			err = fmt.Errorf("unknown marketing platform %q", name)
+		}
+		if err != nil {
+			return nil, err
+		}
+		providers = append(providers, provider)
+	}
+	return providers, nil
+}
+
+// retryAfter reads a rate-limited response's Retry-After seconds
+func retryAfter(resp *http.Response) time.Duration {
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	return defaultRetryAfter
+}
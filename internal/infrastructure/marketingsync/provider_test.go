@@ -0,0 +1,132 @@
+package marketingsync
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testContacts() []domain.MarketingContact {
+	subscribed := domain.NewMarketingContact(
+		&domain.Customer{ID: uuid.New(), Email: "aisyah@example.com", FirstName: "Aisyah", LastName: "Rahman", Phone: "+60 12-345 6789"},
+		&domain.MarketingConsent{EmailOptIn: true, SMSOptIn: true},
+		[]string{"VIP", "Wholesale"})
+	unsubscribed := domain.NewMarketingContact(
+		&domain.Customer{ID: uuid.New(), Email: "farid@example.com", FirstName: "Farid"},
+		&domain.MarketingConsent{},
+		nil)
+	return []domain.MarketingContact{subscribed, unsubscribed}
+}
+
+func TestNew(t *testing.T) {
+	providers, err := New(Config{
+		Providers: []string{"mailchimp", "Klaviyo"},
+		Mailchimp: ProviderConfig{APIKey: "key-us21", ListID: "list", FieldMap: "FNAME=first_name"},
+		Klaviyo:   ProviderConfig{APIKey: "pk_test"},
+	})
+	require.NoError(t, err)
+	require.Len(t, providers, 2)
+	assert.Equal(t, ProviderMailchimp, providers[0].Name())
+	assert.Equal(t, ProviderKlaviyo, providers[1].Name())
+
+	_, err = New(Config{Providers: []string{"mailchimp"}, Mailchimp: ProviderConfig{APIKey: "no-data-center-", ListID: "list"}})
+	assert.Error(t, err)
+	_, err = New(Config{Providers: []string{"hubspot"}})
+	assert.Error(t, err)
+}
+
+func TestMailchimp_Push(t *testing.T) {
+	contacts := testContacts()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/lists/list123", r.URL.Path)
+		_, password, ok := r.BasicAuth()
+		assert.True(t, ok)
+		assert.Equal(t, "key-us21", password)
+
+		var req mailchimpBatchRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.True(t, req.UpdateExisting)
+		require.Len(t, req.Members, 2)
+		assert.Equal(t, mailchimpSubscribed, req.Members[0].Status)
+		assert.Equal(t, map[string]interface{}{"FNAME": "Aisyah", "SEGMENTS": "VIP, Wholesale", "SMS": "true"}, req.Members[0].MergeFields)
+		assert.Equal(t, mailchimpUnsubscribed, req.Members[1].StatusIfNew)
+
+		w.Write([]byte(`{"new_members": [{}], "errors": [{"email_address": "Farid@example.com", "error": "Farid@example.com looks fake or invalid"}]}`))
+	}))
+	defer server.Close()
+
+	mailchimp, err := NewMailchimp(ProviderConfig{APIKey: "key-us21", ListID: "list123", FieldMap: "FNAME=first_name,SEGMENTS=segments,SMS=sms_opt_in"}, server.Client())
+	require.NoError(t, err)
+	mailchimp.endpoint = server.URL
+
+	rejected, err := mailchimp.Push(context.Background(), contacts)
+	require.NoError(t, err)
+	assert.Equal(t, map[uuid.UUID]string{contacts[1].CustomerID: "Farid@example.com looks fake or invalid"}, rejected)
+}
+
+func TestMailchimp_RateLimited(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "30")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	mailchimp, err := NewMailchimp(ProviderConfig{APIKey: "key-us21", ListID: "list123"}, server.Client())
+	require.NoError(t, err)
+	mailchimp.endpoint = server.URL
+
+	_, err = mailchimp.Push(context.Background(), testContacts())
+	var rateLimited *RateLimitError
+	require.True(t, errors.As(err, &rateLimited))
+	assert.Equal(t, 30*time.Second, rateLimited.RetryAfter)
+}
+
+func TestKlaviyo_Push(t *testing.T) {
+	contacts := testContacts()
+	jobs := make(map[string]klaviyoDocument)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Klaviyo-API-Key pk_test", r.Header.Get("Authorization"))
+		assert.Equal(t, klaviyoRevision, r.Header.Get("Revision"))
+
+		var doc klaviyoDocument
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&doc))
+		jobs[r.URL.Path] = doc
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	klaviyo, err := NewKlaviyo(ProviderConfig{APIKey: "pk_test", ListID: "Xy12", FieldMap: "segments=segments"}, server.Client())
+	require.NoError(t, err)
+	klaviyo.endpoint = server.URL
+
+	rejected, err := klaviyo.Push(context.Background(), contacts)
+	require.NoError(t, err)
+	assert.Empty(t, rejected)
+	require.Len(t, jobs, 3)
+
+	profiles := jobs["/profile-bulk-import-jobs/"].Data.Attributes["profiles"].(map[string]interface{})["data"].([]interface{})
+	require.Len(t, profiles, 2)
+	first := profiles[0].(map[string]interface{})["attributes"].(map[string]interface{})
+	assert.Equal(t, "+60123456789", first["phone_number"])
+	assert.Equal(t, contacts[0].CustomerID.String(), first["external_id"])
+	assert.Equal(t, map[string]interface{}{"segments": []interface{}{"VIP", "Wholesale"}}, first["properties"])
+
+	subscribe := jobs["/profile-subscription-bulk-create-jobs/"]
+	assert.Equal(t, "Xy12", subscribe.Data.Relationships["list"].(map[string]interface{})["data"].(map[string]interface{})["id"])
+	subscribed := subscribe.Data.Attributes["profiles"].(map[string]interface{})["data"].([]interface{})
+	require.Len(t, subscribed, 1)
+	assert.Contains(t, subscribed[0].(map[string]interface{})["attributes"].(map[string]interface{})["subscriptions"], "sms")
+
+	unsubscribed := jobs["/profile-subscription-bulk-delete-jobs/"].Data.Attributes["profiles"].(map[string]interface{})["data"].([]interface{})
+	require.Len(t, unsubscribed, 1)
+	assert.Equal(t, "farid@example.com", unsubscribed[0].(map[string]interface{})["attributes"].(map[string]interface{})["email"])
+}
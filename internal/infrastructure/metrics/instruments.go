@@ -0,0 +1,214 @@
+package metrics
+
+import (
+	"database/sql"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Default is the registry served on /metrics
+var Default = NewRegistry()
+
+// Service instruments, registered on Default
+var (
+	HTTPRequestDuration = Default.NewHistogramVec(
+		"http_request_duration_seconds",
+		"HTTP request latency by method, route template and status code.",
+		nil, "method", "route", "status")
+
+	DBQueryDuration = Default.NewHistogramVec(
+		"db_query_duration_seconds",
+		"Database query latency by operation and table.",
+		[]float64{0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 5},
+		"operation", "table")
+
+	DBQueryErrors = Default.NewCounterVec(
+		"db_query_errors_total",
+		"Database queries that failed, excluding record not found.",
+		"operation", "table")
+
+	NATSMessages = Default.NewCounterVec(
+		"nats_messages_total",
+		"NATS messages handled by subject and result (processed or failed).",
+		"subject", "result")
+
+	EventsDropped = Default.NewCounterVec(
+		"events_dropped_total",
+		"Events dropped by subject because the publish queue was full.",
+		"subject")
+
+	BackInStockNotifications = Default.NewCounterVec(
+		"back_in_stock_notifications_total",
+		"Back-in-stock notifications by audience (customer or guest) and result (sent, failed, throttled, deferred or waitlisted).",
+		"audience", "result")
+
+	BackInStockPartialRestocks = Default.NewCounterVec(
+		"back_in_stock_partial_restocks_total",
+		"Restocks smaller than their waiting list, of which only the longest-waiting subscribers were notified.")
+
+	CacheRequests = Default.NewCounterVec(
+		"cache_requests_total",
+		"Cache lookups by cache and result (hit or miss).",
+		"cache", "result")
+
+	ScheduledJobRuns = Default.NewCounterVec(
+		"scheduled_job_runs_total",
+		"Scheduled maintenance job runs by job and result (succeeded or failed).",
+		"job", "result")
+
+	ScheduledJobDuration = Default.NewHistogramVec(
+		"scheduled_job_duration_seconds",
+		"Scheduled maintenance job run time by job.",
+		[]float64{0.1, 0.5, 1, 5, 15, 30, 60, 300, 900, 1800, 3600},
+		"job")
+)
+
+// NATS message results
+const (
+	ResultProcessed = "processed"
+	ResultFailed    = "failed"
+)
+
+// Back-in-stock notification results
+const (
+	NotificationSent       = "sent"
+	NotificationFailed     = "failed"
+	NotificationThrottled  = "throttled"
+	NotificationDeferred   = "deferred"   // held back by the customer's notification preferences
+	NotificationWaitlisted = "waitlisted" // kept pending because the restock was too small for everyone waiting
+)
+
+// RecordMessage counts a handled NATS message as processed, or failed if err
+// is not nil
+func RecordMessage(subject string, err error) {
+	result := ResultProcessed
+	if err != nil {
+		result = ResultFailed
+	}
+	NATSMessages.Inc(subject, result)
+}
+
+// RecordCacheLookup counts a cache hit or miss
+func RecordCacheLookup(cache string, hit bool) {
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	CacheRequests.Inc(cache, result)
+}
+
+// RecordNotification counts a back-in-stock notification outcome for a
+// customer or guest subscription
+func RecordNotification(guest bool, result string) {
+	audience := "customer"
+	if guest {
+		audience = "guest"
+	}
+	BackInStockNotifications.Inc(audience, result)
+}
+
+// RecordJobRun records the run time and outcome of a scheduled job run
+func RecordJobRun(job string, elapsed time.Duration, err error) {
+	result := "succeeded"
+	if err != nil {
+		result = "failed"
+	}
+	ScheduledJobRuns.Inc(job, result)
+	ScheduledJobDuration.ObserveDuration(elapsed, job)
+}
+
+// HTTPMiddleware records the latency and status of every request. Unmatched
+// routes share the route label "unmatched" so scanners can't blow up the
+// number of series.
+func HTTPMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		HTTPRequestDuration.ObserveDuration(time.Since(start), c.Request.Method, route, strconv.Itoa(c.Writer.Status()))
+	}
+}
+
+// RegisterDBPool reports the connection pool of a database on r, under
+// metric names starting with prefix: "db" for the primary, "db_replica" for
+// the read replica
+func RegisterDBPool(r *Registry, prefix string, pool *sql.DB) {
+	stat := func(fn func(sql.DBStats) float64) func() float64 {
+		return func() float64 { return fn(pool.Stats()) }
+	}
+	r.NewGaugeFunc(prefix+"_open_connections", "Open database connections, in use or idle.",
+		stat(func(s sql.DBStats) float64 { return float64(s.OpenConnections) }))
+	r.NewGaugeFunc(prefix+"_in_use_connections", "Database connections in use.",
+		stat(func(s sql.DBStats) float64 { return float64(s.InUse) }))
+	r.NewGaugeFunc(prefix+"_idle_connections", "Idle database connections.",
+		stat(func(s sql.DBStats) float64 { return float64(s.Idle) }))
+	r.NewGaugeFunc(prefix+"_max_open_connections", "Maximum open database connections.",
+		stat(func(s sql.DBStats) float64 { return float64(s.MaxOpenConnections) }))
+	r.NewCounterFunc(prefix+"_wait_count_total", "Waits for a free database connection.",
+		stat(func(s sql.DBStats) float64 { return float64(s.WaitCount) }))
+	r.NewCounterFunc(prefix+"_wait_duration_seconds_total", "Time spent waiting for a free database connection.",
+		stat(func(s sql.DBStats) float64 { return s.WaitDuration.Seconds() }))
+}
+
+// GORMPlugin times every query through GORM's callbacks. Register it with
+// db.Use(metrics.GORMPlugin{}).
+type GORMPlugin struct{}
+
+const startKey = "metrics:start"
+
+// Name implements gorm.Plugin
+func (GORMPlugin) Name() string {
+	return "metrics"
+}
+
+// Initialize implements gorm.Plugin
+func (GORMPlugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	return errors.Join(
+		cb.Create().Before("gorm:create").Register("metrics:before_create", before),
+		cb.Create().After("gorm:create").Register("metrics:after_create", after("create")),
+		cb.Query().Before("gorm:query").Register("metrics:before_query", before),
+		cb.Query().After("gorm:query").Register("metrics:after_query", after("query")),
+		cb.Update().Before("gorm:update").Register("metrics:before_update", before),
+		cb.Update().After("gorm:update").Register("metrics:after_update", after("update")),
+		cb.Delete().Before("gorm:delete").Register("metrics:before_delete", before),
+		cb.Delete().After("gorm:delete").Register("metrics:after_delete", after("delete")),
+		cb.Row().Before("gorm:row").Register("metrics:before_row", before),
+		cb.Row().After("gorm:row").Register("metrics:after_row", after("row")),
+		cb.Raw().Before("gorm:raw").Register("metrics:before_raw", before),
+		cb.Raw().After("gorm:raw").Register("metrics:after_raw", after("raw")),
+	)
+}
+
+func before(db *gorm.DB) {
+	db.InstanceSet(startKey, time.Now())
+}
+
+func after(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		value, ok := db.InstanceGet(startKey)
+		if !ok {
+			return
+		}
+		start, ok := value.(time.Time)
+		if !ok {
+			return
+		}
+		table := db.Statement.Table
+		if table == "" {
+			table = "unknown"
+		}
+		DBQueryDuration.ObserveDuration(time.Since(start), operation, table)
+		if db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound) {
+			DBQueryErrors.Inc(operation, table)
+		}
+	}
+}
@@ -0,0 +1,312 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultBuckets are latency histogram buckets in seconds, from 5ms to 10s
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Registry holds metrics and writes them in the Prometheus text exposition
+// format
+type Registry struct {
+	mu      sync.Mutex
+	metrics []metric
+}
+
+type metric interface {
+	name() string
+	write(w io.Writer)
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+func (r *Registry) register(m metric) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, existing := range r.metrics {
+		if existing.name() == m.name() {
+			panic("metrics: duplicate metric " + m.name())
+		}
+	}
+	r.metrics = append(r.metrics, m)
+}
+
+// Write writes every metric, sorted by name
+func (r *Registry) Write(w io.Writer) {
+	r.mu.Lock()
+	metrics := append([]metric(nil), r.metrics...)
+	r.mu.Unlock()
+
+	sort.Slice(metrics, func(i, j int) bool { return metrics[i].name() < metrics[j].name() })
+	for _, m := range metrics {
+		m.write(w)
+	}
+}
+
+// Handler serves the registry for Prometheus to scrape
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		r.Write(w)
+	})
+}
+
+// vec holds one series per combination of label values
+type vec[T any] struct {
+	metricName string
+	help       string
+	labels     []string
+
+	mu     sync.Mutex
+	series map[string]*T
+	values map[string][]string
+	newT   func() *T
+}
+
+func (v *vec[T]) name() string { return v.metricName }
+
+func (v *vec[T]) get(values []string) *T {
+	if len(values) != len(v.labels) {
+		panic(fmt.Sprintf("metrics: %s takes %d label values, got %d", v.metricName, len(v.labels), len(values)))
+	}
+	key := strings.Join(values, "\xff")
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	s, ok := v.series[key]
+	if !ok {
+		s = v.newT()
+		v.series[key] = s
+		v.values[key] = append([]string(nil), values...)
+	}
+	return s
+}
+
+// each calls fn for every series in label order
+func (v *vec[T]) each(fn func(labels string, s *T)) {
+	v.mu.Lock()
+	keys := make([]string, 0, len(v.series))
+	for key := range v.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	series := make([]*T, len(keys))
+	labels := make([]string, len(keys))
+	for i, key := range keys {
+		series[i] = v.series[key]
+		labels[i] = formatLabels(v.labels, v.values[key])
+	}
+	v.mu.Unlock()
+
+	for i := range keys {
+		fn(labels[i], series[i])
+	}
+}
+
+func (v *vec[T]) header(w io.Writer, kind string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", v.metricName, escapeHelp(v.help), v.metricName, kind)
+}
+
+// CounterVec is a counter partitioned by labels
+type CounterVec struct {
+	vec[counter]
+}
+
+type counter struct {
+	mu    sync.Mutex
+	value float64
+}
+
+// NewCounterVec creates and registers a counter. Counter names should end
+// in _total.
+func (r *Registry) NewCounterVec(name, help string, labels ...string) *CounterVec {
+	c := &CounterVec{vec[counter]{
+		metricName: name,
+		help:       help,
+		labels:     labels,
+		series:     make(map[string]*counter),
+		values:     make(map[string][]string),
+		newT:       func() *counter { return &counter{} },
+	}}
+	r.register(c)
+	return c
+}
+
+// Inc adds one to the series with the given label values
+func (c *CounterVec) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add adds delta, which must not be negative, to the series with the given
+// label values
+func (c *CounterVec) Add(delta float64, labelValues ...string) {
+	if delta < 0 {
+		panic("metrics: counters cannot decrease")
+	}
+	s := c.get(labelValues)
+	s.mu.Lock()
+	s.value += delta
+	s.mu.Unlock()
+}
+
+func (c *CounterVec) write(w io.Writer) {
+	c.header(w, "counter")
+	c.each(func(labels string, s *counter) {
+		s.mu.Lock()
+		value := s.value
+		s.mu.Unlock()
+		fmt.Fprintf(w, "%s%s %s\n", c.metricName, braces(labels), formatFloat(value))
+	})
+}
+
+// HistogramVec is a histogram partitioned by labels
+type HistogramVec struct {
+	vec[histogram]
+	buckets []float64
+}
+
+type histogram struct {
+	mu     sync.Mutex
+	counts []uint64 // per bucket, not cumulative; the last is +Inf
+	sum    float64
+	count  uint64
+}
+
+// NewHistogramVec creates and registers a histogram with the given upper
+// bucket bounds, DefaultBuckets if nil
+func (r *Registry) NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	if buckets == nil {
+		buckets = DefaultBuckets
+	}
+	buckets = append([]float64(nil), buckets...)
+	sort.Float64s(buckets)
+
+	h := &HistogramVec{buckets: buckets}
+	h.vec = vec[histogram]{
+		metricName: name,
+		help:       help,
+		labels:     labels,
+		series:     make(map[string]*histogram),
+		values:     make(map[string][]string),
+		newT:       func() *histogram { return &histogram{counts: make([]uint64, len(buckets)+1)} },
+	}
+	r.register(h)
+	return h
+}
+
+// Observe records a value in the series with the given label values
+func (h *HistogramVec) Observe(value float64, labelValues ...string) {
+	s := h.get(labelValues)
+	i := sort.SearchFloat64s(h.buckets, value)
+
+	s.mu.Lock()
+	s.counts[i]++
+	s.sum += value
+	s.count++
+	s.mu.Unlock()
+}
+
+// ObserveDuration records a duration in seconds
+func (h *HistogramVec) ObserveDuration(d time.Duration, labelValues ...string) {
+	h.Observe(d.Seconds(), labelValues...)
+}
+
+func (h *HistogramVec) write(w io.Writer) {
+	h.header(w, "histogram")
+	h.each(func(labels string, s *histogram) {
+		s.mu.Lock()
+		counts := append([]uint64(nil), s.counts...)
+		sum, count := s.sum, s.count
+		s.mu.Unlock()
+
+		sep := ""
+		if labels != "" {
+			sep = ","
+		}
+		var cumulative uint64
+		for i, bound := range h.buckets {
+			cumulative += counts[i]
+			fmt.Fprintf(w, "%s_bucket{%s%sle=\"%s\"} %d\n", h.metricName, labels, sep, formatFloat(bound), cumulative)
+		}
+		fmt.Fprintf(w, "%s_bucket{%s%sle=\"+Inf\"} %d\n", h.metricName, labels, sep, count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", h.metricName, braces(labels), formatFloat(sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", h.metricName, braces(labels), count)
+	})
+}
+
+// GaugeFunc is a gauge whose value is read when the registry is scraped
+type GaugeFunc struct {
+	metricName string
+	help       string
+	kind       string
+	fn         func() float64
+}
+
+// NewGaugeFunc creates and registers a gauge reporting fn
+func (r *Registry) NewGaugeFunc(name, help string, fn func() float64) *GaugeFunc {
+	g := &GaugeFunc{metricName: name, help: help, kind: "gauge", fn: fn}
+	r.register(g)
+	return g
+}
+
+// NewCounterFunc creates and registers a counter reporting fn, for totals
+// kept elsewhere, like those of a connection pool. fn must never decrease.
+func (r *Registry) NewCounterFunc(name, help string, fn func() float64) *GaugeFunc {
+	g := &GaugeFunc{metricName: name, help: help, kind: "counter", fn: fn}
+	r.register(g)
+	return g
+}
+
+func (g *GaugeFunc) name() string { return g.metricName }
+
+func (g *GaugeFunc) write(w io.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n%s %s\n",
+		g.metricName, escapeHelp(g.help), g.metricName, g.kind, g.metricName, formatFloat(g.fn()))
+}
+
+func formatLabels(names, values []string) string {
+	pairs := make([]string, len(names))
+	for i, name := range names {
+		pairs[i] = name + `="` + escapeLabel(values[i]) + `"`
+	}
+	return strings.Join(pairs, ",")
+}
+
+func braces(labels string) string {
+	if labels == "" {
+		return ""
+	}
+	return "{" + labels + "}"
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(s string) string  { return helpEscaper.Replace(s) }
+func escapeLabel(s string) string { return labelEscaper.Replace(s) }
@@ -0,0 +1,160 @@
+package metrics
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func scrape(t *testing.T, r *Registry) string {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	r.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Header().Get("Content-Type"), "text/plain; version=0.0.4")
+	return rec.Body.String()
+}
+
+func TestRegistry_Exposition(t *testing.T) {
+	r := NewRegistry()
+	messages := r.NewCounterVec("nats_messages_total", "NATS messages.", "subject", "result")
+	latency := r.NewHistogramVec("request_seconds", "Latency.", []float64{0.1, 0.5}, "route")
+	r.NewGaugeFunc("open_connections", "Open connections.", func() float64 { return 7 })
+
+	messages.Inc("order.completed", "processed")
+	messages.Inc("order.completed", "processed")
+	messages.Inc("order.completed", "failed")
+	latency.Observe(0.05, "/a")
+	latency.Observe(0.1, "/a") // bounds are inclusive
+	latency.Observe(0.3, "/a")
+	latency.Observe(2, "/a")
+
+	assert.Equal(t, `# HELP nats_messages_total NATS messages.
+# TYPE nats_messages_total counter
+nats_messages_total{subject="order.completed",result="failed"} 1
+nats_messages_total{subject="order.completed",result="processed"} 2
+# HELP open_connections Open connections.
+# TYPE open_connections gauge
+open_connections 7
+# HELP request_seconds Latency.
+# TYPE request_seconds histogram
+request_seconds_bucket{route="/a",le="0.1"} 2
+request_seconds_bucket{route="/a",le="0.5"} 3
+request_seconds_bucket{route="/a",le="+Inf"} 4
+request_seconds_sum{route="/a"} 2.45
+request_seconds_count{route="/a"} 4
+`, scrape(t, r))
+}
+
+func TestRegistry_EscapesLabelValues(t *testing.T) {
+	r := NewRegistry()
+	r.NewCounterVec("errors_total", "Errors.", "message").Inc("bad \"quote\"\nand \\ slash")
+
+	assert.Contains(t, scrape(t, r), `errors_total{message="bad \"quote\"\nand \\ slash"} 1`)
+}
+
+func TestRegistry_RejectsMisuse(t *testing.T) {
+	r := NewRegistry()
+	counter := r.NewCounterVec("things_total", "Things.", "kind")
+
+	assert.Panics(t, func() { r.NewCounterVec("things_total", "Again.") })
+	assert.Panics(t, func() { counter.Inc() })
+	assert.Panics(t, func() { counter.Add(-1, "a") })
+}
+
+func TestHTTPMiddleware_RecordsRouteTemplate(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(HTTPMiddleware())
+	router.GET("/customers/:id", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+
+	for _, path := range []string{"/customers/1", "/customers/2", "/nowhere"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	out := scrape(t, Default)
+	assert.Contains(t, out, `http_request_duration_seconds_count{method="GET",route="/customers/:id",status="204"} 2`)
+	assert.Contains(t, out, `http_request_duration_seconds_count{method="GET",route="unmatched",status="404"} 1`)
+}
+
+func TestGORMPlugin_TimesQueries(t *testing.T) {
+	type metricsWidget struct {
+		ID   uint
+		Name string
+	}
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.Use(GORMPlugin{}))
+	require.NoError(t, db.AutoMigrate(&metricsWidget{}))
+
+	require.NoError(t, db.Create(&metricsWidget{Name: "a"}).Error)
+	var found metricsWidget
+	require.NoError(t, db.First(&found).Error)
+	assert.ErrorIs(t, db.First(&found, 99).Error, gorm.ErrRecordNotFound)
+	assert.Error(t, db.Exec("SELECT * FROM missing_table").Error)
+
+	out := scrape(t, Default)
+	assert.Contains(t, out, `db_query_duration_seconds_count{operation="create",table="metrics_widgets"} 1`)
+	assert.Contains(t, out, `db_query_duration_seconds_count{operation="query",table="metrics_widgets"} 2`)
+	// Not found is not an error
+	assert.NotContains(t, out, `db_query_errors_total{operation="query"`)
+	assert.Contains(t, out, `db_query_errors_total{operation="raw",table="unknown"} 1`)
+}
+
+func TestRegisterDBPool(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	pool, err := db.DB()
+	require.NoError(t, err)
+	pool.SetMaxOpenConns(5)
+	require.NoError(t, pool.Ping())
+
+	r := NewRegistry()
+	RegisterDBPool(r, "db", pool)
+
+	out := scrape(t, r)
+	for _, line := range []string{
+		"db_open_connections 1",
+		"db_idle_connections 1",
+		"db_in_use_connections 0",
+		"db_max_open_connections 5",
+		"# TYPE db_wait_count_total counter",
+		"db_wait_count_total 0",
+		"db_wait_duration_seconds_total 0",
+	} {
+		assert.Contains(t, out, line)
+	}
+}
+
+func TestRecordHelpers(t *testing.T) {
+	RecordMessage("test.subject", nil)
+	RecordMessage("test.subject", errors.New("boom"))
+	RecordCacheLookup("test_cache", true)
+	RecordCacheLookup("test_cache", false)
+	RecordCacheLookup("test_cache", true)
+	RecordNotification(true, NotificationThrottled)
+	RecordJobRun("test_job", 2*time.Second, nil)
+	RecordJobRun("test_job", time.Second, errors.New("boom"))
+
+	out := scrape(t, Default)
+	for _, line := range []string{
+		`nats_messages_total{subject="test.subject",result="processed"} 1`,
+		`nats_messages_total{subject="test.subject",result="failed"} 1`,
+		`cache_requests_total{cache="test_cache",result="hit"} 2`,
+		`cache_requests_total{cache="test_cache",result="miss"} 1`,
+		`back_in_stock_notifications_total{audience="guest",result="throttled"} 1`,
+		`scheduled_job_runs_total{job="test_job",result="succeeded"} 1`,
+		`scheduled_job_runs_total{job="test_job",result="failed"} 1`,
+		`scheduled_job_duration_seconds_sum{job="test_job"} 3`,
+	} {
+		assert.Contains(t, out, line)
+	}
+}
@@ -0,0 +1,207 @@
+// Package metrics tracks request SLOs and error budget burn rates in memory
+// and exposes service metrics for Prometheus.
+package metrics
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Objective is the availability and latency target of an endpoint.
+// Availability and LatencyTarget are fractions, e.g. 0.999 for 99.9%.
+type Objective struct {
+	Availability     float64
+	LatencyThreshold time.Duration
+	LatencyTarget    float64
+}
+
+// Burn rate windows, following the multi-window alerting approach from the
+// Google SRE workbook: a page fires when both the long and short window burn
+// fast, a ticket when the 6h window burns steadily.
+var windows = []struct {
+	name     string
+	duration time.Duration
+}{
+	{"5m", 5 * time.Minute},
+	{"1h", time.Hour},
+	{"6h", 6 * time.Hour},
+}
+
+const (
+	pageBurnRate   = 14.4 // 2% of a 30-day budget in 1h
+	ticketBurnRate = 6.0  // 5% of a 30-day budget in 6h
+	bucketCount    = 360  // one bucket per minute over the longest window
+)
+
+type bucket struct {
+	minute int64
+	total  int64
+	errors int64
+	slow   int64
+}
+
+type series struct {
+	objective Objective
+	buckets   [bucketCount]bucket
+}
+
+// SLOTracker records request outcomes per route and computes burn rates
+type SLOTracker struct {
+	mu         sync.Mutex
+	defaults   Objective
+	objectives map[string]Objective
+	series     map[string]*series
+	now        func() time.Time
+}
+
+// NewSLOTracker creates a tracker. Routes without their own objective use defaults.
+// Objectives are keyed by "METHOD /route/template", e.g. "GET /api/v1/customer/profile".
+func NewSLOTracker(defaults Objective, objectives map[string]Objective) *SLOTracker {
+	if objectives == nil {
+		objectives = make(map[string]Objective)
+	}
+	return &SLOTracker{
+		defaults:   defaults,
+		objectives: objectives,
+		series:     make(map[string]*series),
+		now:        time.Now,
+	}
+}
+
+// Middleware records the outcome and latency of every routed request
+func (t *SLOTracker) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		if c.FullPath() == "" {
+			return // unmatched routes are not part of any SLO
+		}
+		t.Record(c.Request.Method+" "+c.FullPath(), c.Writer.Status(), time.Since(start))
+	}
+}
+
+// Record adds one request to the endpoint's current minute bucket.
+// 5xx responses count against availability; 503s from load shedding included.
+func (t *SLOTracker) Record(endpoint string, status int, latency time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	s, ok := t.series[endpoint]
+	if !ok {
+		objective, found := t.objectives[endpoint]
+		if !found {
+			objective = t.defaults
+		}
+		s = &series{objective: objective}
+		t.series[endpoint] = s
+	}
+
+	minute := t.now().Unix() / 60
+	b := &s.buckets[minute%bucketCount]
+	if b.minute != minute {
+		*b = bucket{minute: minute}
+	}
+	b.total++
+	if status >= 500 {
+		b.errors++
+	}
+	if latency > s.objective.LatencyThreshold {
+		b.slow++
+	}
+}
+
+// WindowBurn is the error budget burn of one time window
+type WindowBurn struct {
+	Window            string  `json:"window"`
+	Requests          int64   `json:"requests"`
+	Availability      float64 `json:"availability"`
+	AvailabilityBurn  float64 `json:"availability_burn_rate"`
+	LatencyCompliance float64 `json:"latency_compliance"`
+	LatencyBurn       float64 `json:"latency_burn_rate"`
+}
+
+// EndpointSummary is the SLO status of one endpoint
+type EndpointSummary struct {
+	Endpoint           string       `json:"endpoint"`
+	AvailabilityTarget float64      `json:"availability_target"`
+	LatencyThresholdMs int64        `json:"latency_threshold_ms"`
+	LatencyTarget      float64      `json:"latency_target"`
+	Windows            []WindowBurn `json:"windows"`
+	Alert              string       `json:"alert"` // "page", "ticket" or "none"
+}
+
+// Summary returns the SLO status of every endpoint seen so far, endpoints
+// with an active alert first
+func (t *SLOTracker) Summary() []EndpointSummary {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	currentMinute := t.now().Unix() / 60
+	summaries := make([]EndpointSummary, 0, len(t.series))
+	for endpoint, s := range t.series {
+		summary := EndpointSummary{
+			Endpoint:           endpoint,
+			AvailabilityTarget: s.objective.Availability,
+			LatencyThresholdMs: s.objective.LatencyThreshold.Milliseconds(),
+			LatencyTarget:      s.objective.LatencyTarget,
+			Alert:              "none",
+		}
+
+		burns := make(map[string]float64, len(windows))
+		for _, w := range windows {
+			var total, errs, slow int64
+			minutes := int64(w.duration / time.Minute)
+			for _, b := range s.buckets {
+				if b.total > 0 && currentMinute-b.minute < minutes {
+					total += b.total
+					errs += b.errors
+					slow += b.slow
+				}
+			}
+
+			burn := WindowBurn{Window: w.name, Requests: total, Availability: 1, LatencyCompliance: 1}
+			if total > 0 {
+				burn.Availability = 1 - float64(errs)/float64(total)
+				burn.LatencyCompliance = 1 - float64(slow)/float64(total)
+				burn.AvailabilityBurn = burnRate(burn.Availability, s.objective.Availability)
+				burn.LatencyBurn = burnRate(burn.LatencyCompliance, s.objective.LatencyTarget)
+			}
+			summary.Windows = append(summary.Windows, burn)
+
+			burns[w.name] = burn.AvailabilityBurn
+			if burn.LatencyBurn > burns[w.name] {
+				burns[w.name] = burn.LatencyBurn
+			}
+		}
+
+		switch {
+		case burns["1h"] >= pageBurnRate && burns["5m"] >= pageBurnRate:
+			summary.Alert = "page"
+		case burns["6h"] >= ticketBurnRate:
+			summary.Alert = "ticket"
+		}
+		summaries = append(summaries, summary)
+	}
+
+	rank := map[string]int{"page": 0, "ticket": 1, "none": 2}
+	sort.Slice(summaries, func(i, j int) bool {
+		if rank[summaries[i].Alert] != rank[summaries[j].Alert] {
+			return rank[summaries[i].Alert] < rank[summaries[j].Alert]
+		}
+		return summaries[i].Endpoint < summaries[j].Endpoint
+	})
+	return summaries
+}
+
+// burnRate is how many times faster than allowed the error budget is spent
+func burnRate(good, target float64) float64 {
+	budget := 1 - target
+	if budget <= 0 {
+		return 0
+	}
+	return (1 - good) / budget
+}
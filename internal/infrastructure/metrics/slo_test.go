@@ -0,0 +1,54 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSLOTracker_BurnRate(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	tracker := NewSLOTracker(
+		Objective{Availability: 0.99, LatencyThreshold: 300 * time.Millisecond, LatencyTarget: 0.95},
+		map[string]Objective{
+			"POST /internal/v1/wallet/reservations": {Availability: 0.999, LatencyThreshold: 200 * time.Millisecond, LatencyTarget: 0.99},
+		},
+	)
+	tracker.now = func() time.Time { return now }
+
+	// 2% errors against a 99.9% objective burns 20x
+	for i := 0; i < 100; i++ {
+		status := 200
+		if i < 2 {
+			status = 500
+		}
+		tracker.Record("POST /internal/v1/wallet/reservations", status, 50*time.Millisecond)
+	}
+	// Healthy endpoint on the default objective
+	for i := 0; i < 10; i++ {
+		tracker.Record("GET /api/v1/customer/profile", 200, 10*time.Millisecond)
+	}
+
+	summary := tracker.Summary()
+	require.Len(t, summary, 2)
+
+	wallet := summary[0]
+	assert.Equal(t, "POST /internal/v1/wallet/reservations", wallet.Endpoint)
+	assert.Equal(t, "page", wallet.Alert)
+	assert.InDelta(t, 0.98, wallet.Windows[0].Availability, 0.0001)
+	assert.InDelta(t, 20.0, wallet.Windows[0].AvailabilityBurn, 0.0001)
+
+	profile := summary[1]
+	assert.Equal(t, "none", profile.Alert)
+	assert.Equal(t, 0.99, profile.AvailabilityTarget)
+	assert.Equal(t, 0.0, profile.Windows[2].AvailabilityBurn)
+
+	// Two hours later only the 6h window still sees the errors
+	now = now.Add(2 * time.Hour)
+	summary = tracker.Summary()
+	assert.Equal(t, int64(0), summary[0].Windows[0].Requests)
+	assert.Equal(t, int64(100), summary[0].Windows[2].Requests)
+	assert.Equal(t, "ticket", summary[0].Alert)
+}
@@ -0,0 +1,339 @@
+// Package notificationclient sends customer notifications through the
+// notification service.
+package notificationclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/tracing"
+	"go.uber.org/zap"
+)
+
+// ErrCircuitOpen is returned without calling the notification service while
+// the circuit breaker is open
+var ErrCircuitOpen = errors.New("notification service circuit breaker is open")
+
+// Config configures the notification service client
+type Config struct {
+	BaseURL          string
+	Timeout          time.Duration // per attempt
+	MaxRetries       int           // retries after the first attempt
+	RetryBackoff     time.Duration // doubled after every retry
+	MaxRetryBackoff  time.Duration
+	BreakerThreshold int // consecutive failed sends that open the breaker; 0 disables it
+	BreakerCooldown  time.Duration
+}
+
+// Error kinds counted in Metrics
+const (
+	ErrorKindTimeout     = "timeout"
+	ErrorKindNetwork     = "network"
+	ErrorKindServer      = "status_5xx"
+	ErrorKindRateLimited = "status_429"
+	ErrorKindRejected    = "status_4xx"
+	ErrorKindCircuitOpen = "circuit_open"
+	ErrorKindEncode      = "encode"
+)
+
+// Metrics is a snapshot of the client's delivery counters
+type Metrics struct {
+	Sent         int64            `json:"sent"`
+	Failed       int64            `json:"failed"`
+	Retries      int64            `json:"retries"`
+	Errors       map[string]int64 `json:"errors"` // by error kind, counted per attempt
+	CircuitState string           `json:"circuit_state"`
+	OpenedAt     *time.Time       `json:"opened_at,omitempty"`
+}
+
+// Circuit breaker states
+const (
+	circuitClosed   = "closed"
+	circuitOpen     = "open"
+	circuitHalfOpen = "half_open"
+)
+
+// Client posts notifications to the notification service with retries and a
+// circuit breaker. It implements the back-in-stock notification and
+// confirmation sender interfaces.
+type Client struct {
+	cfg        Config
+	httpClient *http.Client
+	logger     *zap.Logger
+	sleep      func(ctx context.Context, d time.Duration) error
+
+	mu        sync.Mutex
+	state     string
+	failures  int
+	openedAt  time.Time
+	probing   bool
+	sent      int64
+	failed    int64
+	retries   int64
+	errCounts map[string]int64
+}
+
+// New creates a notification service client
+func New(cfg Config, logger *zap.Logger) *Client {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 5 * time.Second
+	}
+	if cfg.RetryBackoff <= 0 {
+		cfg.RetryBackoff = 200 * time.Millisecond
+	}
+	if cfg.MaxRetryBackoff <= 0 {
+		cfg.MaxRetryBackoff = 5 * time.Second
+	}
+	if cfg.BreakerCooldown <= 0 {
+		cfg.BreakerCooldown = 30 * time.Second
+	}
+
+	return &Client{
+		cfg:        cfg,
+		httpClient: &http.Client{Transport: tracing.Transport(nil)},
+		logger:     logger,
+		sleep:      sleepContext,
+		state:      circuitClosed,
+		errCounts:  make(map[string]int64),
+	}
+}
+
+// SendBackInStockNotification sends a back-in-stock notification. Real sends
+// carry the subscription ID as idempotency key so a retried request never
+// emails the customer twice.
+func (c *Client) SendBackInStockNotification(ctx context.Context, notification domain.BackInStockNotification) error {
+	idempotencyKey := ""
+	if !notification.IsTest {
+		idempotencyKey = "back-in-stock:" + notification.SubscriptionID
+	}
+	return c.post(ctx, "/api/v1/notifications/back-in-stock", notification, idempotencyKey)
+}
+
+// SendBackInStockConfirmation sends the confirm-email message for a guest subscription
+func (c *Client) SendBackInStockConfirmation(ctx context.Context, confirmation domain.BackInStockConfirmation) error {
+	return c.post(ctx, "/api/v1/notifications/back-in-stock/confirm", confirmation, "")
+}
+
+// SendEmailChangeVerification sends the verify-email message for a new email address
+func (c *Client) SendEmailChangeVerification(ctx context.Context, verification domain.EmailChangeVerification) error {
+	return c.post(ctx, "/api/v1/notifications/email-change/verify", verification, "")
+}
+
+// SendEmailChangeNotice tells the old email address that the account's email
+// was changed. changeID is the idempotency key, so a retry never sends the
+// notice twice.
+func (c *Client) SendEmailChangeNotice(ctx context.Context, changeID string, notice domain.EmailChangeNotice) error {
+	return c.post(ctx, "/api/v1/notifications/email-change/notice", notice, "email-change:"+changeID)
+}
+
+// SendScheduledExportLink emails the download link of a scheduled export
+// run. runID is the idempotency key.
+func (c *Client) SendScheduledExportLink(ctx context.Context, runID string, link domain.ScheduledExportLink) error {
+	return c.post(ctx, "/api/v1/notifications/scheduled-export/ready", link, "scheduled-export:"+runID)
+}
+
+// SendScheduledExportFailure alerts that a scheduled export run failed.
+// runID is the idempotency key.
+func (c *Client) SendScheduledExportFailure(ctx context.Context, runID string, failure domain.ScheduledExportFailure) error {
+	return c.post(ctx, "/api/v1/notifications/scheduled-export/failed", failure, "scheduled-export-failed:"+runID)
+}
+
+// Metrics returns a snapshot of the delivery counters and breaker state
+func (c *Client) Metrics() Metrics {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	errs := make(map[string]int64, len(c.errCounts))
+	for kind, count := range c.errCounts {
+		errs[kind] = count
+	}
+	metrics := Metrics{
+		Sent:         c.sent,
+		Failed:       c.failed,
+		Retries:      c.retries,
+		Errors:       errs,
+		CircuitState: c.state,
+	}
+	if c.state != circuitClosed {
+		openedAt := c.openedAt
+		metrics.OpenedAt = &openedAt
+	}
+	return metrics
+}
+
+// post sends payload, retrying timeouts, network errors, 429 and 5xx responses
+// with exponential backoff. ctx carries the trace; its cancellation is ignored
+// so retries aren't cut short by the caller's deadline.
+func (c *Client) post(ctx context.Context, path string, payload interface{}, idempotencyKey string) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		c.recordError(ErrorKindEncode)
+		c.finish(path, err, false)
+		return err
+	}
+
+	if !c.allow() {
+		c.recordError(ErrorKindCircuitOpen)
+		c.finish(path, ErrCircuitOpen, false)
+		return ErrCircuitOpen
+	}
+
+	ctx = context.WithoutCancel(ctx)
+	backoff := c.cfg.RetryBackoff
+	for attempt := 0; ; attempt++ {
+		kind, err := c.attempt(ctx, path, body, idempotencyKey)
+		if err == nil {
+			c.finish(path, nil, true)
+			return nil
+		}
+		c.recordError(kind)
+
+		retryable := kind != ErrorKindRejected
+		if !retryable || attempt >= c.cfg.MaxRetries {
+			// A rejected request says nothing about the service's health
+			c.finish(path, err, kind == ErrorKindRejected)
+			return err
+		}
+
+		c.logger.Warn("Retrying notification request",
+			zap.String("path", path),
+			zap.Int("attempt", attempt+1),
+			zap.String("error_kind", kind),
+			zap.Duration("backoff", backoff),
+			zap.Error(err))
+		c.mu.Lock()
+		c.retries++
+		c.mu.Unlock()
+
+		if err := c.sleep(ctx, backoff); err != nil {
+			c.finish(path, err, false)
+			return err
+		}
+		backoff *= 2
+		if backoff > c.cfg.MaxRetryBackoff {
+			backoff = c.cfg.MaxRetryBackoff
+		}
+	}
+}
+
+// attempt makes one request and classifies a failure
+func (c *Client) attempt(ctx context.Context, path string, body []byte, idempotencyKey string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.cfg.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.cfg.BaseURL+path, bytes.NewReader(body))
+	if err != nil {
+		return ErrorKindRejected, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if idempotencyKey != "" {
+		req.Header.Set("Idempotency-Key", idempotencyKey)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return ErrorKindTimeout, err
+		}
+		return ErrorKindNetwork, err
+	}
+	resp.Body.Close()
+
+	switch {
+	case resp.StatusCode < 300:
+		return "", nil
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return ErrorKindRateLimited, fmt.Errorf("notification service returned status %d", resp.StatusCode)
+	case resp.StatusCode >= 500:
+		return ErrorKindServer, fmt.Errorf("notification service returned status %d", resp.StatusCode)
+	default:
+		return ErrorKindRejected, fmt.Errorf("notification service rejected request with status %d", resp.StatusCode)
+	}
+}
+
+// allow reports whether a request may be sent. Once the cooldown has passed
+// an open breaker lets a single probe through.
+func (c *Client) allow() bool {
+	if c.cfg.BreakerThreshold <= 0 {
+		return true
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	switch c.state {
+	case circuitOpen:
+		if time.Since(c.openedAt) < c.cfg.BreakerCooldown {
+			return false
+		}
+		c.state = circuitHalfOpen
+		c.probing = true
+		return true
+	case circuitHalfOpen:
+		if c.probing {
+			return false
+		}
+		c.probing = true
+		return true
+	default:
+		return true
+	}
+}
+
+// finish records the outcome of a send. healthy is true when the service
+// answered, even if it rejected the request.
+func (c *Client) finish(path string, err error, healthy bool) {
+	c.mu.Lock()
+	if err == nil {
+		c.sent++
+	} else {
+		c.failed++
+	}
+
+	if c.cfg.BreakerThreshold > 0 && !errors.Is(err, ErrCircuitOpen) {
+		c.probing = false
+		if healthy {
+			c.failures = 0
+			c.state = circuitClosed
+		} else {
+			c.failures++
+			if c.state == circuitHalfOpen || c.failures >= c.cfg.BreakerThreshold {
+				if c.state != circuitOpen {
+					c.logger.Error("Notification service circuit breaker opened",
+						zap.Int("consecutive_failures", c.failures))
+				}
+				c.state = circuitOpen
+				c.openedAt = time.Now()
+			}
+		}
+	}
+	c.mu.Unlock()
+
+	if err != nil {
+		c.logger.Error("Failed to send notification", zap.String("path", path), zap.Error(err))
+	}
+}
+
+func (c *Client) recordError(kind string) {
+	c.mu.Lock()
+	c.errCounts[kind]++
+	c.mu.Unlock()
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,203 @@
+package notificationclient
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func newTestClient(url string, cfg Config) *Client {
+	cfg.BaseURL = url
+	client := New(cfg, zap.NewNop())
+	client.sleep = func(ctx context.Context, d time.Duration) error { return nil }
+	return client
+}
+
+func TestClient_SendBackInStockNotification(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "/api/v1/notifications/back-in-stock", r.URL.Path)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		assert.Equal(t, "back-in-stock:sub-1", r.Header.Get("Idempotency-Key"))
+
+		var notification domain.BackInStockNotification
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&notification))
+		assert.Equal(t, "aisyah@example.com", notification.CustomerEmail)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	client := newTestClient(server.URL, Config{})
+	err := client.SendBackInStockNotification(context.Background(), domain.BackInStockNotification{
+		SubscriptionID: "sub-1",
+		CustomerEmail:  "aisyah@example.com",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), client.Metrics().Sent)
+}
+
+func TestClient_TestNotificationHasNoIdempotencyKey(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Empty(t, r.Header.Get("Idempotency-Key"))
+	}))
+	defer server.Close()
+
+	err := newTestClient(server.URL, Config{}).SendBackInStockNotification(context.Background(), domain.BackInStockNotification{
+		SubscriptionID: "sub-1",
+		IsTest:         true,
+	})
+	require.NoError(t, err)
+}
+
+func TestClient_SendEmailChangeNotice(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v1/notifications/email-change/notice", r.URL.Path)
+		assert.Equal(t, "email-change:change-1", r.Header.Get("Idempotency-Key"))
+
+		var notice domain.EmailChangeNotice
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&notice))
+		assert.Equal(t, "aisyah@example.com", notice.Email)
+		assert.Equal(t, "a***h@example.com", notice.NewEmail)
+	}))
+	defer server.Close()
+
+	err := newTestClient(server.URL, Config{}).SendEmailChangeNotice(context.Background(), "change-1", domain.EmailChangeNotice{
+		Email:    "aisyah@example.com",
+		NewEmail: "a***h@example.com",
+	})
+	require.NoError(t, err)
+}
+
+func TestClient_RetriesServerErrors(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		assert.Equal(t, "/api/v1/notifications/back-in-stock/confirm", r.URL.Path)
+		switch calls {
+		case 1:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case 2:
+			w.WriteHeader(http.StatusTooManyRequests)
+		default:
+			w.WriteHeader(http.StatusOK)
+		}
+	}))
+	defer server.Close()
+
+	client := newTestClient(server.URL, Config{MaxRetries: 3})
+	require.NoError(t, client.SendBackInStockConfirmation(context.Background(), domain.BackInStockConfirmation{Email: "guest@example.com"}))
+	assert.Equal(t, 3, calls)
+
+	metrics := client.Metrics()
+	assert.Equal(t, int64(1), metrics.Sent)
+	assert.Equal(t, int64(2), metrics.Retries)
+	assert.Equal(t, int64(1), metrics.Errors[ErrorKindServer])
+	assert.Equal(t, int64(1), metrics.Errors[ErrorKindRateLimited])
+}
+
+func TestClient_GivesUpAfterMaxRetries(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	client := newTestClient(server.URL, Config{MaxRetries: 2})
+	assert.Error(t, client.SendBackInStockNotification(context.Background(), domain.BackInStockNotification{SubscriptionID: "sub-1"}))
+	assert.Equal(t, 3, calls)
+	assert.Equal(t, int64(1), client.Metrics().Failed)
+}
+
+func TestClient_DoesNotRetryRejectedRequests(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusUnprocessableEntity)
+	}))
+	defer server.Close()
+
+	client := newTestClient(server.URL, Config{MaxRetries: 3, BreakerThreshold: 1})
+	assert.Error(t, client.SendBackInStockNotification(context.Background(), domain.BackInStockNotification{SubscriptionID: "sub-1"}))
+	assert.Equal(t, 1, calls)
+
+	// The service answered, so the breaker stays closed
+	metrics := client.Metrics()
+	assert.Equal(t, int64(1), metrics.Errors[ErrorKindRejected])
+	assert.Equal(t, circuitClosed, metrics.CircuitState)
+}
+
+func TestClient_Timeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	client := newTestClient(server.URL, Config{Timeout: 20 * time.Millisecond})
+	assert.Error(t, client.SendBackInStockNotification(context.Background(), domain.BackInStockNotification{SubscriptionID: "sub-1"}))
+	assert.Equal(t, int64(1), client.Metrics().Errors[ErrorKindTimeout])
+}
+
+func TestClient_CircuitBreaker(t *testing.T) {
+	calls := 0
+	healthy := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if !healthy {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	client := newTestClient(server.URL, Config{BreakerThreshold: 2, BreakerCooldown: time.Hour})
+	notification := domain.BackInStockNotification{SubscriptionID: "sub-1"}
+
+	assert.Error(t, client.SendBackInStockNotification(context.Background(), notification))
+	assert.Error(t, client.SendBackInStockNotification(context.Background(), notification))
+	assert.Equal(t, circuitOpen, client.Metrics().CircuitState)
+
+	// While open, sends fail fast without calling the service
+	assert.ErrorIs(t, client.SendBackInStockNotification(context.Background(), notification), ErrCircuitOpen)
+	assert.Equal(t, 2, calls)
+	assert.Equal(t, int64(1), client.Metrics().Errors[ErrorKindCircuitOpen])
+
+	// After the cooldown a successful probe closes the breaker
+	healthy = true
+	client.mu.Lock()
+	client.openedAt = time.Now().Add(-2 * time.Hour)
+	client.mu.Unlock()
+
+	require.NoError(t, client.SendBackInStockNotification(context.Background(), notification))
+	assert.Equal(t, 3, calls)
+	assert.Equal(t, circuitClosed, client.Metrics().CircuitState)
+}
+
+func TestClient_FailedProbeReopensBreaker(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	client := newTestClient(server.URL, Config{BreakerThreshold: 1, BreakerCooldown: time.Hour})
+	notification := domain.BackInStockNotification{SubscriptionID: "sub-1"}
+
+	assert.Error(t, client.SendBackInStockNotification(context.Background(), notification))
+	client.mu.Lock()
+	client.openedAt = time.Now().Add(-2 * time.Hour)
+	client.mu.Unlock()
+
+	assert.Error(t, client.SendBackInStockNotification(context.Background(), notification))
+	metrics := client.Metrics()
+	assert.Equal(t, circuitOpen, metrics.CircuitState)
+	assert.WithinDuration(t, time.Now(), *metrics.OpenedAt, time.Minute)
+}
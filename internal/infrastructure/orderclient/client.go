@@ -0,0 +1,413 @@
+// Package orderclient provides a client for reading orders from service-order.
+package orderclient
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/metrics"
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/tracing"
+)
+
+// ErrOrderNotFound is returned when the order does not exist or belongs to another customer
+var ErrOrderNotFound = errors.New("order not found")
+
+// ErrOrderAccessDenied is returned when service-order rejects the caller's credentials
+var ErrOrderAccessDenied = errors.New("order access denied")
+
+// ShippingAddress is the shipping address captured on an order at checkout
+type ShippingAddress struct {
+	Name         string `json:"name"`
+	Phone        string `json:"phone"`
+	Address      string `json:"address"`
+	AddressLine2 string `json:"address2"`
+	City         string `json:"city"`
+	State        string `json:"state"`
+	Postcode     string `json:"postcode"`
+	Country      string `json:"country"`
+}
+
+// Order is the subset of a service-order order used by this service
+type Order struct {
+	ID              string          `json:"id"`
+	OrderNumber     string          `json:"orderNumber"`
+	CustomerID      string          `json:"customerId"`
+	ShippingAddress ShippingAddress `json:"shippingAddress"`
+}
+
+type orderResponse struct {
+	Success bool  `json:"success"`
+	Data    Order `json:"data"`
+}
+
+// orderOwnerTTL is how long order number to customer lookups are cached.
+// An order never changes owner, so this only bounds memory and staleness after
+// account merges. Like order pages they are cached per caller, since
+// service-order decides what each caller may see.
+const (
+	orderOwnerTTL      = 10 * time.Minute
+	orderOwnerMaxItems = 10000
+)
+
+// Pages of a customer's orders are fresh for orderListTTL and may be served
+// as stale for orderListStaleFor more when service-order fails
+const (
+	orderListTTL        = time.Minute
+	orderListStaleFor   = 15 * time.Minute
+	orderListMaxItems   = 5000
+	orderTotalsPageSize = 100
+)
+
+type cachedOwner struct {
+	customerID string
+	expiresAt  time.Time
+}
+
+type cachedOrderPage struct {
+	page      *domain.CustomerOrderPage
+	expiresAt time.Time
+}
+
+// Client calls service-order on behalf of a customer or admin
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+
+	mu         sync.Mutex
+	owners     map[string]cachedOwner
+	orderPages map[string]cachedOrderPage
+}
+
+// NewClient creates a service-order client
+func NewClient(baseURL string) *Client {
+	return &Client{
+		baseURL: baseURL,
+		httpClient: &http.Client{
+			Transport: tracing.Transport(nil),
+			Timeout:   10 * time.Second,
+		},
+		owners:     make(map[string]cachedOwner),
+		orderPages: make(map[string]cachedOrderPage),
+	}
+}
+
+// GetOrder fetches a customer's order. The caller's Authorization header is
+// forwarded so service-order enforces ownership.
+func (c *Client) GetOrder(ctx context.Context, orderID, userID, authorization string) (*Order, error) {
+	endpoint := fmt.Sprintf("%s/api/v1/orders/%s", c.baseURL, url.PathEscape(orderID))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", authorization)
+	req.Header.Set("X-User-ID", userID)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound, http.StatusForbidden:
+		return nil, ErrOrderNotFound
+	default:
+		return nil, fmt.Errorf("order service returned status %d", resp.StatusCode)
+	}
+
+	var parsed orderResponse
+	if err := json.NewDecoder(resp.Body).Decode(&parsed); err != nil {
+		return nil, err
+	}
+	if !parsed.Success {
+		return nil, ErrOrderNotFound
+	}
+
+	// Never trust an order that belongs to someone else
+	if parsed.Data.CustomerID != "" && parsed.Data.CustomerID != userID {
+		return nil, ErrOrderNotFound
+	}
+	return &parsed.Data, nil
+}
+
+// CustomerIDByOrderNumber resolves an order number to the ID of the customer
+// who placed it. The admin's Authorization header is forwarded so
+// service-order applies its own permission checks. Results are cached per
+// caller, so one admin's lookup never answers another's.
+func (c *Client) CustomerIDByOrderNumber(ctx context.Context, orderNumber, authorization string) (string, error) {
+	key := callerKey(authorization) + "|" + orderNumber
+	now := time.Now()
+
+	c.mu.Lock()
+	cached, ok := c.owners[key]
+	c.mu.Unlock()
+	hit := ok && now.Before(cached.expiresAt)
+	metrics.RecordCacheLookup("order_owner", hit)
+	if hit {
+		return cached.customerID, nil
+	}
+
+	endpoint := fmt.Sprintf("%s/api/v1/admin/orders/by-number/%s", c.baseURL, url.PathEscape(orderNumber))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", authorization)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return "", ErrOrderNotFound
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return "", ErrOrderAccessDenied
+	default:
+		return "", fmt.Errorf("order service returned status %d", resp.StatusCode)
+	}
+
+	var parsed orderResponse
+	if err := json.NewDecoder(resp.Body).Decode(&parsed); err != nil {
+		return "", err
+	}
+	if !parsed.Success || parsed.Data.CustomerID == "" {
+		return "", ErrOrderNotFound
+	}
+
+	c.mu.Lock()
+	if len(c.owners) >= orderOwnerMaxItems {
+		for key, entry := range c.owners {
+			if now.After(entry.expiresAt) {
+				delete(c.owners, key)
+			}
+		}
+		if len(c.owners) >= orderOwnerMaxItems {
+			c.owners = make(map[string]cachedOwner)
+		}
+	}
+	c.owners[key] = cachedOwner{customerID: parsed.Data.CustomerID, expiresAt: now.Add(orderOwnerTTL)}
+	c.mu.Unlock()
+
+	return parsed.Data.CustomerID, nil
+}
+
+// OrderSummary is an order as listed by service-order
+type OrderSummary struct {
+	ID            string      `json:"id"`
+	OrderNumber   string      `json:"orderNumber"`
+	Status        string      `json:"status"`
+	PaymentStatus string      `json:"paymentStatus,omitempty"`
+	Subtotal      float64     `json:"subtotal"`
+	Total         float64     `json:"total"`
+	Items         []OrderItem `json:"items,omitempty"`
+	CreatedAt     time.Time   `json:"createdAt"`
+}
+
+// OrderItem is an item of an order as listed by service-order
+type OrderItem struct {
+	ID          string  `json:"id"`
+	ProductID   string  `json:"productId"`
+	ProductName string  `json:"productName"`
+	SKU         string  `json:"sku"`
+	Quantity    int     `json:"quantity"`
+	UnitPrice   float64 `json:"unitPrice"`
+	ImageURL    string  `json:"imageUrl"`
+}
+
+type orderListResponse struct {
+	Success    bool           `json:"success"`
+	Data       []OrderSummary `json:"data"`
+	Total      int64          `json:"total"`
+	Pagination struct {
+		Total int64 `json:"total"`
+	} `json:"pagination"`
+}
+
+// ListCustomerOrders returns a page of a customer's orders, newest first,
+// and the customer's total order count. The admin's Authorization header is
+// forwarded so service-order applies its own permission checks.
+//
+// Pages are cached per caller for orderListTTL. When service-order can't be
+// reached or fails, a page the caller was served in the last
+// orderListStaleFor is served instead, marked Stale.
+func (c *Client) ListCustomerOrders(ctx context.Context, customerID, authorization string, page, limit int) (*domain.CustomerOrderPage, error) {
+	key := fmt.Sprintf("%s|%s|%d|%d", callerKey(authorization), customerID, page, limit)
+	now := time.Now()
+
+	c.mu.Lock()
+	cached, ok := c.orderPages[key]
+	c.mu.Unlock()
+	hit := ok && now.Before(cached.expiresAt)
+	metrics.RecordCacheLookup("customer_orders", hit)
+	if hit {
+		return cached.page, nil
+	}
+
+	query := url.Values{}
+	query.Set("customer_id", customerID)
+	query.Set("page", strconv.Itoa(page))
+	query.Set("limit", strconv.Itoa(limit))
+	query.Set("sort", "created_at:desc")
+	orders, total, err := c.listOrders(ctx, query, authorization)
+	if errors.Is(err, ErrOrderAccessDenied) {
+		return nil, err
+	}
+	if err != nil {
+		if ok && now.Before(cached.expiresAt.Add(orderListStaleFor)) {
+			stale := *cached.page
+			stale.Stale = true
+			return &stale, nil
+		}
+		return nil, err
+	}
+
+	result := &domain.CustomerOrderPage{Orders: make([]domain.CustomerOrderSummary, 0, len(orders)), Total: total}
+	for _, order := range orders {
+		result.Orders = append(result.Orders, order.summary())
+	}
+
+	c.mu.Lock()
+	if len(c.orderPages) >= orderListMaxItems {
+		for key, entry := range c.orderPages {
+			if now.After(entry.expiresAt.Add(orderListStaleFor)) {
+				delete(c.orderPages, key)
+			}
+		}
+		if len(c.orderPages) >= orderListMaxItems {
+			c.orderPages = make(map[string]cachedOrderPage)
+		}
+	}
+	c.orderPages[key] = cachedOrderPage{page: result, expiresAt: now.Add(orderListTTL)}
+	c.mu.Unlock()
+
+	return result, nil
+}
+
+// CustomerOrderTotals counts a customer's paid orders, adds up their totals
+// and finds the first and last, reading every page of the customer's orders
+// uncached
+func (c *Client) CustomerOrderTotals(ctx context.Context, customerID, authorization string) (domain.OrderTotals, error) {
+	var (
+		totals domain.OrderTotals
+		seen   int64
+	)
+	for page := 1; ; page++ {
+		query := url.Values{}
+		query.Set("customer_id", customerID)
+		query.Set("payment_status", domain.OrderPaymentStatusPaid)
+		query.Set("page", strconv.Itoa(page))
+		query.Set("limit", strconv.Itoa(orderTotalsPageSize))
+		query.Set("sort", "created_at:asc")
+		orders, total, err := c.listOrders(ctx, query, authorization)
+		if err != nil {
+			return domain.OrderTotals{}, err
+		}
+		for _, order := range orders {
+			if order.PaymentStatus != domain.OrderPaymentStatusPaid {
+				continue
+			}
+			totals.Orders++
+			totals.Spent += order.Total
+			placed := order.CreatedAt
+			if totals.FirstOrderAt == nil || placed.Before(*totals.FirstOrderAt) {
+				totals.FirstOrderAt = &placed
+			}
+			if totals.LastOrderAt == nil || placed.After(*totals.LastOrderAt) {
+				totals.LastOrderAt = &placed
+			}
+		}
+		seen += int64(len(orders))
+		if len(orders) < orderTotalsPageSize || seen >= total {
+			return totals, nil
+		}
+	}
+}
+
+// listOrders calls the admin order listing of service-order
+func (c *Client) listOrders(ctx context.Context, query url.Values, authorization string) ([]OrderSummary, int64, error) {
+	endpoint := fmt.Sprintf("%s/api/v1/admin/orders?%s", c.baseURL, query.Encode())
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	req.Header.Set("Authorization", authorization)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return nil, 0, ErrOrderAccessDenied
+	default:
+		return nil, 0, fmt.Errorf("order service returned status %d", resp.StatusCode)
+	}
+
+	var parsed orderListResponse
+	if err := json.NewDecoder(resp.Body).Decode(&parsed); err != nil {
+		return nil, 0, err
+	}
+
+	total := parsed.Total
+	if total == 0 {
+		total = parsed.Pagination.Total
+	}
+	if total < int64(len(parsed.Data)) {
+		total = int64(len(parsed.Data))
+	}
+	return parsed.Data, total, nil
+}
+
+// callerKey identifies the caller of a cached lookup by a hash of their
+// Authorization header, so tokens aren't kept in memory
+func callerKey(authorization string) string {
+	sum := sha256.Sum256([]byte(authorization))
+	return hex.EncodeToString(sum[:])
+}
+
+// summary converts the order to this service's representation
+func (o OrderSummary) summary() domain.CustomerOrderSummary {
+	items := make([]domain.CustomerOrderItem, 0, len(o.Items))
+	for _, item := range o.Items {
+		items = append(items, domain.CustomerOrderItem{
+			ID:          item.ID,
+			ProductID:   item.ProductID,
+			ProductName: item.ProductName,
+			SKU:         item.SKU,
+			Quantity:    item.Quantity,
+			UnitPrice:   item.UnitPrice,
+			Total:       float64(item.Quantity) * item.UnitPrice,
+			ImageURL:    item.ImageURL,
+		})
+	}
+	return domain.CustomerOrderSummary{
+		ID:            o.ID,
+		OrderNum:      o.OrderNumber,
+		Total:         o.Total,
+		Subtotal:      o.Subtotal,
+		Status:        o.Status,
+		PaymentStatus: o.PaymentStatus,
+		Items:         items,
+		CreatedAt:     o.CreatedAt,
+	}
+}
@@ -0,0 +1,226 @@
+package orderclient
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_GetOrder(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v1/orders/order-1", r.URL.Path)
+		assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+		assert.Equal(t, "user-1", r.Header.Get("X-User-ID"))
+
+		w.Write([]byte(`{
+			"success": true,
+			"data": {
+				"id": "order-1",
+				"customerId": "user-1",
+				"shippingAddress": {"name": "Aisyah", "address": "1 Jalan Ampang", "city": "Kuala Lumpur", "postcode": "50450"}
+			}
+		}`))
+	}))
+	defer server.Close()
+
+	order, err := NewClient(server.URL).GetOrder(context.Background(), "order-1", "user-1", "Bearer token")
+	require.NoError(t, err)
+	assert.Equal(t, "1 Jalan Ampang", order.ShippingAddress.Address)
+	assert.Equal(t, "50450", order.ShippingAddress.Postcode)
+}
+
+func TestClient_GetOrder_OtherCustomer(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"success": true, "data": {"id": "order-1", "customerId": "someone-else"}}`))
+	}))
+	defer server.Close()
+
+	_, err := NewClient(server.URL).GetOrder(context.Background(), "order-1", "user-1", "")
+	assert.ErrorIs(t, err, ErrOrderNotFound)
+}
+
+func TestClient_GetOrder_NotFound(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	_, err := NewClient(server.URL).GetOrder(context.Background(), "missing", "user-1", "")
+	assert.ErrorIs(t, err, ErrOrderNotFound)
+}
+
+func TestClient_CustomerIDByOrderNumber_Cached(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		assert.Equal(t, "/api/v1/admin/orders/by-number/ORD-1001", r.URL.Path)
+		w.Write([]byte(`{"success": true, "data": {"id": "order-1", "orderNumber": "ORD-1001", "customerId": "user-1"}}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	for i := 0; i < 2; i++ {
+		customerID, err := client.CustomerIDByOrderNumber(context.Background(), "ORD-1001", "Bearer admin")
+		require.NoError(t, err)
+		assert.Equal(t, "user-1", customerID)
+	}
+	assert.Equal(t, 1, calls)
+}
+
+func TestClient_CustomerIDByOrderNumber_CachedPerCaller(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if r.Header.Get("Authorization") != "Bearer admin" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.Write([]byte(`{"success": true, "data": {"id": "order-1", "orderNumber": "ORD-1001", "customerId": "user-1"}}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	customerID, err := client.CustomerIDByOrderNumber(context.Background(), "ORD-1001", "Bearer admin")
+	require.NoError(t, err)
+	assert.Equal(t, "user-1", customerID)
+
+	// Another caller is checked by service-order rather than served the
+	// first caller's answer
+	_, err = client.CustomerIDByOrderNumber(context.Background(), "ORD-1001", "Bearer other-admin")
+	assert.ErrorIs(t, err, ErrOrderAccessDenied)
+	assert.Equal(t, 2, calls)
+}
+
+func TestClient_ListCustomerOrders_CachedPerCaller(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer admin" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.Write([]byte(`{"success": true, "data": [{"id": "order-1", "orderNumber": "ORD-1001", "total": 50}], "total": 1}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	_, err := client.ListCustomerOrders(context.Background(), "user-1", "Bearer admin", 1, 20)
+	require.NoError(t, err)
+	_, err = client.ListCustomerOrders(context.Background(), "user-1", "Bearer other-admin", 1, 20)
+	assert.ErrorIs(t, err, ErrOrderAccessDenied)
+}
+
+func TestClient_ListCustomerOrders(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v1/admin/orders", r.URL.Path)
+		assert.Equal(t, "user-1", r.URL.Query().Get("customer_id"))
+		assert.Equal(t, "2", r.URL.Query().Get("page"))
+		assert.Equal(t, "10", r.URL.Query().Get("limit"))
+		assert.Equal(t, "Bearer admin", r.Header.Get("Authorization"))
+
+		w.Write([]byte(`{
+			"success": true,
+			"data": [{"id": "order-2", "orderNumber": "ORD-1002", "status": "shipped", "paymentStatus": "paid", "total": 120.5,
+				"items": [{"id": "item-1", "productName": "Baju Kurung", "quantity": 2, "unitPrice": 60.25}],
+				"createdAt": "2026-03-02T10:00:00Z"}],
+			"pagination": {"total": 12}
+		}`))
+	}))
+	defer server.Close()
+
+	page, err := NewClient(server.URL).ListCustomerOrders(context.Background(), "user-1", "Bearer admin", 2, 10)
+	require.NoError(t, err)
+	assert.Equal(t, int64(12), page.Total)
+	assert.False(t, page.Stale)
+	require.Len(t, page.Orders, 1)
+	assert.Equal(t, "ORD-1002", page.Orders[0].OrderNum)
+	assert.Equal(t, 2026, page.Orders[0].CreatedAt.Year())
+	require.Len(t, page.Orders[0].Items, 1)
+	assert.Equal(t, 120.5, page.Orders[0].Items[0].Total)
+}
+
+func TestClient_ListCustomerOrders_ServesStaleCache(t *testing.T) {
+	calls := 0
+	down := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if down {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.Write([]byte(`{"success": true, "data": [{"id": "order-1", "orderNumber": "ORD-1001", "total": 50}], "total": 1}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	_, err := client.ListCustomerOrders(context.Background(), "user-1", "Bearer admin", 1, 20)
+	require.NoError(t, err)
+
+	// Fresh pages are served from cache
+	page, err := client.ListCustomerOrders(context.Background(), "user-1", "Bearer admin", 1, 20)
+	require.NoError(t, err)
+	assert.False(t, page.Stale)
+	assert.Equal(t, 1, calls)
+
+	// Once expired, a failing service-order falls back to the cached page
+	down = true
+	client.mu.Lock()
+	for key, entry := range client.orderPages {
+		entry.expiresAt = time.Now().Add(-time.Minute)
+		client.orderPages[key] = entry
+	}
+	client.mu.Unlock()
+	page, err = client.ListCustomerOrders(context.Background(), "user-1", "Bearer admin", 1, 20)
+	require.NoError(t, err)
+	assert.True(t, page.Stale)
+	assert.Equal(t, "ORD-1001", page.Orders[0].OrderNum)
+	assert.Equal(t, 2, calls)
+
+	// Pages never fetched have nothing to fall back to
+	_, err = client.ListCustomerOrders(context.Background(), "user-1", "Bearer admin", 2, 20)
+	assert.Error(t, err)
+}
+
+func TestClient_ListCustomerOrders_Forbidden(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	_, err := NewClient(server.URL).ListCustomerOrders(context.Background(), "user-1", "", 1, 10)
+	assert.ErrorIs(t, err, ErrOrderAccessDenied)
+}
+
+func TestClient_CustomerOrderTotals(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "paid", r.URL.Query().Get("payment_status"))
+		if r.URL.Query().Get("page") == "1" {
+			orders := make([]string, orderTotalsPageSize)
+			for i := range orders {
+				orders[i] = `{"id": "order", "paymentStatus": "paid", "total": 10, "createdAt": "2025-01-10T08:00:00Z"}`
+			}
+			fmt.Fprintf(w, `{"success": true, "data": [%s], "total": %d}`, strings.Join(orders, ","), orderTotalsPageSize+2)
+			return
+		}
+		// An order that isn't paid is skipped even if the filter is ignored
+		w.Write([]byte(`{"success": true, "data": [
+			{"id": "order", "paymentStatus": "paid", "total": 5.5, "createdAt": "2025-03-02T12:30:00Z"},
+			{"id": "order", "paymentStatus": "refunded", "total": 99, "createdAt": "2025-04-01T00:00:00Z"}
+		], "total": 102}`))
+	}))
+	defer server.Close()
+
+	totals, err := NewClient(server.URL).CustomerOrderTotals(context.Background(), "user-1", "Bearer service")
+	require.NoError(t, err)
+	assert.Equal(t, orderTotalsPageSize+1, totals.Orders)
+	assert.InDelta(t, float64(orderTotalsPageSize)*10+5.5, totals.Spent, 0.001)
+	require.NotNil(t, totals.FirstOrderAt)
+	require.NotNil(t, totals.LastOrderAt)
+	assert.Equal(t, time.Date(2025, 1, 10, 8, 0, 0, 0, time.UTC), totals.FirstOrderAt.UTC())
+	assert.Equal(t, time.Date(2025, 3, 2, 12, 30, 0, 0, time.UTC), totals.LastOrderAt.UTC())
+}
@@ -0,0 +1,419 @@
+package persistence
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// AccountMergeRepository moves customer-side data between identities when
+// the auth service merges two accounts or an admin merges a duplicate customer
+type AccountMergeRepository struct {
+	db *gorm.DB
+}
+
+// NewAccountMergeRepository creates a new account merge repository
+func NewAccountMergeRepository(db *gorm.DB) *AccountMergeRepository {
+	return &AccountMergeRepository{db: db}
+}
+
+// Merge remaps wishlist items, back-in-stock subscriptions, addresses,
+// measurements, notes and store credit from the secondary to the primary user
+// in one transaction. Entries the primary user already has (same product and
+// variant) are dropped instead of duplicated, and the primary user's defaults win.
+// If the secondary user was already merged, the existing record is returned
+// with merged=false.
+func (r *AccountMergeRepository) Merge(ctx context.Context, primaryID, secondaryID uuid.UUID) (record *domain.AccountMerge, merged bool, err error) {
+	return r.MergeWithWinners(ctx, primaryID, secondaryID, nil)
+}
+
+// MergeWithWinners merges like Merge, but first applies the admin's conflict
+// resolutions: profile fields won by the secondary account are copied onto
+// the primary customer, and a secondary default address win makes the
+// secondary's default address the merged default.
+func (r *AccountMergeRepository) MergeWithWinners(ctx context.Context, primaryID, secondaryID uuid.UUID, winners map[string]string) (record *domain.AccountMerge, merged bool, err error) {
+	return r.merge(ctx, &domain.AccountMerge{
+		PrimaryUserID:   primaryID,
+		SecondaryUserID: secondaryID,
+		Source:          domain.MergeSourceAuth,
+	}, winners, nil)
+}
+
+// MergeDuplicate merges a duplicate customer into the primary one on behalf
+// of an admin. On top of MergeWithWinners it adds the duplicate's order totals
+// to the primary customer and soft-deletes the duplicate, all in the same
+// transaction. The merge record names the admin for the audit trail.
+func (r *AccountMergeRepository) MergeDuplicate(ctx context.Context, primaryID, duplicateID uuid.UUID, winners map[string]string, mergedBy uuid.UUID) (record *domain.AccountMerge, merged bool, err error) {
+	merge := &domain.AccountMerge{
+		PrimaryUserID:   primaryID,
+		SecondaryUserID: duplicateID,
+		Source:          domain.MergeSourceAdmin,
+	}
+	if mergedBy != uuid.Nil {
+		merge.MergedBy = &mergedBy
+	}
+	return r.merge(ctx, merge, winners, retireDuplicate)
+}
+
+// merge moves the secondary's data to the primary and stores merge as the
+// record. finish, if set, runs in the transaction after the data is moved.
+func (r *AccountMergeRepository) merge(ctx context.Context, merge *domain.AccountMerge, winners map[string]string, finish func(tx *gorm.DB, merge *domain.AccountMerge) error) (record *domain.AccountMerge, merged bool, err error) {
+	primaryID, secondaryID := merge.PrimaryUserID, merge.SecondaryUserID
+	if primaryID == secondaryID {
+		return nil, false, errors.New("primary and secondary accounts must differ")
+	}
+
+	err = r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var existing domain.AccountMerge
+		err := tx.Where("secondary_user_id = ?", secondaryID).First(&existing).Error
+		if err == nil {
+			record = &existing
+			return nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		if err := applyMergeWinners(tx, primaryID, secondaryID, winners); err != nil {
+			return err
+		}
+
+		merge.MergedAt = time.Now()
+
+		if merge.WishlistItems, err = mergeWishlist(tx, primaryID, secondaryID); err != nil {
+			return err
+		}
+		if merge.Subscriptions, err = mergeBackInStock(tx, primaryID, secondaryID); err != nil {
+			return err
+		}
+		if merge.Addresses, err = mergeDefaultable(tx, &domain.Address{}, primaryID, secondaryID); err != nil {
+			return err
+		}
+		if merge.Measurements, err = mergeMeasurements(tx, primaryID, secondaryID); err != nil {
+			return err
+		}
+
+		result := tx.Model(&domain.CustomerNote{}).
+			Where("customer_id = ?", secondaryID).
+			Update("customer_id", primaryID)
+		if result.Error != nil {
+			return result.Error
+		}
+		merge.Notes = int(result.RowsAffected)
+
+		if err := mergeWallet(tx, primaryID, secondaryID); err != nil {
+			return err
+		}
+		if finish != nil {
+			if err := finish(tx, merge); err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Create(merge).Error; err != nil {
+			return err
+		}
+		record = merge
+		merged = true
+		return nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return record, merged, nil
+}
+
+// Preview compares the two customers without changing anything
+func (r *AccountMergeRepository) Preview(ctx context.Context, primaryID, secondaryID uuid.UUID) (*domain.MergePreview, error) {
+	db := r.db.WithContext(ctx)
+
+	var primary, secondary domain.Customer
+	if err := db.Where("id = ?", primaryID).First(&primary).Error; err != nil {
+		return nil, err
+	}
+	if err := db.Where("id = ?", secondaryID).First(&secondary).Error; err != nil {
+		return nil, err
+	}
+
+	primaryAddress, err := findDefaultAddress(db, primaryID)
+	if err != nil {
+		return nil, err
+	}
+	secondaryAddress, err := findDefaultAddress(db, secondaryID)
+	if err != nil {
+		return nil, err
+	}
+
+	var merges int64
+	if err := db.Model(&domain.AccountMerge{}).
+		Where("secondary_user_id = ?", secondaryID).
+		Count(&merges).Error; err != nil {
+		return nil, err
+	}
+
+	return &domain.MergePreview{
+		PrimaryID:     primaryID,
+		SecondaryID:   secondaryID,
+		AlreadyMerged: merges > 0,
+		Conflicts:     domain.DetectMergeConflicts(&primary, &secondary, primaryAddress, secondaryAddress),
+	}, nil
+}
+
+// retireDuplicate adds the duplicate customer's order totals to the primary
+// customer, resolves the pair in the duplicate review queue and soft-deletes
+// the duplicate
+func retireDuplicate(tx *gorm.DB, merge *domain.AccountMerge) error {
+	var duplicate domain.Customer
+	if err := tx.Where("id = ?", merge.SecondaryUserID).First(&duplicate).Error; err != nil {
+		return err
+	}
+	merge.Orders = duplicate.TotalOrders
+	merge.Spent = duplicate.TotalSpent
+
+	// UpdateColumns skips the optimistic locking hook, so bump the version here
+	if err := tx.Model(&domain.Customer{}).
+		Where("id = ?", merge.PrimaryUserID).
+		UpdateColumns(map[string]interface{}{
+			"total_orders": gorm.Expr("total_orders + ?", duplicate.TotalOrders),
+			"total_spent":  gorm.Expr("total_spent + ?", duplicate.TotalSpent),
+			"version":      gorm.Expr("version + 1"),
+		}).Error; err != nil {
+		return err
+	}
+
+	if err := resolveDuplicateCandidate(tx, merge); err != nil {
+		return err
+	}
+	return tx.Delete(&duplicate).Error
+}
+
+// findDefaultAddress returns the user's default address, or nil if there is none
+func findDefaultAddress(db *gorm.DB, userID uuid.UUID) (*domain.Address, error) {
+	var address domain.Address
+	err := db.Where("user_id = ? AND is_default = ?", userID, true).First(&address).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &address, nil
+}
+
+// applyMergeWinners applies the fields won by the secondary account before
+// its data is moved. Primary wins need no action since the merge keeps the
+// primary's values by default.
+func applyMergeWinners(tx *gorm.DB, primaryID, secondaryID uuid.UUID, winners map[string]string) error {
+	var profileFields []string
+	for field, winner := range winners {
+		if winner != domain.MergeWinnerSecondary {
+			continue
+		}
+		if field == domain.MergeFieldDefaultAddress {
+			// With no primary default left, mergeDefaultable keeps the secondary's
+			if err := tx.Model(&domain.Address{}).
+				Where("user_id = ? AND is_default = ?", primaryID, true).
+				Updates(map[string]interface{}{"is_default": false, "version": bumpVersion}).Error; err != nil {
+				return err
+			}
+			continue
+		}
+		profileFields = append(profileFields, field)
+	}
+	if len(profileFields) == 0 {
+		return nil
+	}
+
+	var secondary domain.Customer
+	if err := tx.Where("id = ?", secondaryID).First(&secondary).Error; err != nil {
+		return err
+	}
+
+	updates := map[string]interface{}{"version": gorm.Expr("version + 1")}
+	for _, field := range profileFields {
+		if value := domain.MergeProfileFieldValue(&secondary, field); value != nil {
+			updates[field] = *value
+		}
+	}
+
+	// UpdateColumns skips the optimistic locking hook, so bump the version here
+	return tx.Model(&domain.Customer{}).
+		Where("id = ?", primaryID).
+		UpdateColumns(updates).Error
+}
+
+// mergeWishlist moves wishlist items, dropping ones the primary user already saved
+func mergeWishlist(tx *gorm.DB, primaryID, secondaryID uuid.UUID) (int, error) {
+	var primaryItems, secondaryItems []domain.WishlistItem
+	if err := tx.Where("user_id = ?", primaryID).Find(&primaryItems).Error; err != nil {
+		return 0, err
+	}
+	if err := tx.Where("user_id = ?", secondaryID).Find(&secondaryItems).Error; err != nil {
+		return 0, err
+	}
+
+	existing := make(map[string]bool, len(primaryItems))
+	for _, item := range primaryItems {
+		existing[item.GetUniqueKey()] = true
+	}
+
+	var duplicates []uuid.UUID
+	for _, item := range secondaryItems {
+		if existing[item.GetUniqueKey()] {
+			duplicates = append(duplicates, item.ID)
+		}
+	}
+	if len(duplicates) > 0 {
+		if err := tx.Where("id IN ?", duplicates).Delete(&domain.WishlistItem{}).Error; err != nil {
+			return 0, err
+		}
+	}
+
+	result := tx.Model(&domain.WishlistItem{}).
+		Where("user_id = ?", secondaryID).
+		Update("user_id", primaryID)
+	return int(result.RowsAffected), result.Error
+}
+
+// mergeBackInStock moves subscriptions, dropping ones the primary user already has
+func mergeBackInStock(tx *gorm.DB, primaryID, secondaryID uuid.UUID) (int, error) {
+	var primarySubs, secondarySubs []domain.BackInStockSubscription
+	if err := tx.Where("customer_id = ?", primaryID).Find(&primarySubs).Error; err != nil {
+		return 0, err
+	}
+	if err := tx.Where("customer_id = ?", secondaryID).Find(&secondarySubs).Error; err != nil {
+		return 0, err
+	}
+
+	key := func(sub domain.BackInStockSubscription) string {
+		if sub.VariantID != nil {
+			return sub.ProductID.String() + "-" + sub.VariantID.String()
+		}
+		return sub.ProductID.String() + "-nil"
+	}
+
+	existing := make(map[string]bool, len(primarySubs))
+	for _, sub := range primarySubs {
+		existing[key(sub)] = true
+	}
+
+	var duplicates []uuid.UUID
+	for _, sub := range secondarySubs {
+		if existing[key(sub)] {
+			duplicates = append(duplicates, sub.ID)
+		}
+	}
+	if len(duplicates) > 0 {
+		if err := tx.Where("id IN ?", duplicates).Delete(&domain.BackInStockSubscription{}).Error; err != nil {
+			return 0, err
+		}
+	}
+
+	result := tx.Model(&domain.BackInStockSubscription{}).
+		Where("customer_id = ?", secondaryID).
+		Update("customer_id", primaryID)
+	return int(result.RowsAffected), result.Error
+}
+
+// mergeDefaultable moves user_id-owned rows with is_default and version
+// columns. If the primary user already has a default, the secondary's
+// default is cleared.
+func mergeDefaultable(tx *gorm.DB, model interface{}, primaryID, secondaryID uuid.UUID) (int, error) {
+	var primaryDefaults int64
+	if err := tx.Model(model).
+		Where("user_id = ? AND is_default = ?", primaryID, true).
+		Count(&primaryDefaults).Error; err != nil {
+		return 0, err
+	}
+
+	if primaryDefaults > 0 {
+		if err := tx.Model(model).
+			Where("user_id = ? AND is_default = ?", secondaryID, true).
+			Updates(map[string]interface{}{"is_default": false, "version": bumpVersion}).Error; err != nil {
+			return 0, err
+		}
+	}
+
+	// Unscoped so soft-deleted rows follow the account and stay restorable
+	result := tx.Unscoped().Model(model).
+		Where("user_id = ?", secondaryID).
+		Update("user_id", primaryID)
+	return int(result.RowsAffected), result.Error
+}
+
+// mergeMeasurements moves measurements like mergeDefaultable, but defaults
+// are per profile person: a secondary default is only cleared when the
+// primary user already has a default for the same person.
+func mergeMeasurements(tx *gorm.DB, primaryID, secondaryID uuid.UUID) (int, error) {
+	primaryDefaults := tx.Model(&domain.CustomerMeasurement{}).
+		Select("profile_person").
+		Where("user_id = ? AND is_default = ?", primaryID, true)
+	if err := tx.Model(&domain.CustomerMeasurement{}).
+		Where("user_id = ? AND is_default = ? AND profile_person IN (?)", secondaryID, true, primaryDefaults).
+		Updates(map[string]interface{}{"is_default": false, "version": bumpVersion}).Error; err != nil {
+		return 0, err
+	}
+
+	result := tx.Model(&domain.CustomerMeasurement{}).
+		Where("user_id = ?", secondaryID).
+		Update("user_id", primaryID)
+	return int(result.RowsAffected), result.Error
+}
+
+// mergeWallet moves the secondary user's store credit to the primary user.
+// If both have wallets, balances are added up and the secondary ledger and
+// reservations are re-pointed at the primary wallet. Both wallets are
+// locked, in ID order so a concurrent merge or wallet write can't deadlock
+// with it, before either balance is read.
+func mergeWallet(tx *gorm.DB, primaryID, secondaryID uuid.UUID) error {
+	var wallets []domain.CustomerWallet
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("customer_id IN ?", []uuid.UUID{primaryID, secondaryID}).
+		Order("id").
+		Find(&wallets).Error; err != nil {
+		return err
+	}
+	var primary, secondary *domain.CustomerWallet
+	for i := range wallets {
+		switch wallets[i].CustomerID {
+		case primaryID:
+			primary = &wallets[i]
+		case secondaryID:
+			secondary = &wallets[i]
+		}
+	}
+	if secondary == nil {
+		return nil
+	}
+
+	if primary == nil {
+		// Primary has no wallet yet: hand the secondary wallet over as-is
+		if err := tx.Model(secondary).Update("customer_id", primaryID).Error; err != nil {
+			return err
+		}
+		primary = secondary
+	} else {
+		primary.Balance += secondary.Balance
+		primary.Reserved += secondary.Reserved
+		if err := tx.Save(primary).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(secondary).Error; err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Model(&domain.WalletTransaction{}).
+		Where("customer_id = ?", secondaryID).
+		Updates(map[string]interface{}{"customer_id": primaryID, "wallet_id": primary.ID}).Error; err != nil {
+		return err
+	}
+	return tx.Model(&domain.WalletReservation{}).
+		Where("customer_id = ?", secondaryID).
+		Updates(map[string]interface{}{"customer_id": primaryID, "wallet_id": primary.ID}).Error
+}
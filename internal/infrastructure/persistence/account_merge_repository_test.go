@@ -0,0 +1,179 @@
+package persistence
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func setupAccountMergeTestDB(t *testing.T) *gorm.DB {
+	return openTestDB(t,
+		&domain.AccountMerge{},
+		&domain.WishlistItem{},
+		&domain.Customer{},
+		&domain.BackInStockSubscription{},
+		&domain.Address{},
+		&domain.CustomerMeasurement{},
+		&domain.CustomerNote{},
+		&domain.CustomerWallet{},
+		&domain.WalletTransaction{},
+		&domain.WalletReservation{},
+		&domain.DuplicateCandidate{},
+	)
+}
+
+func TestAccountMergeRepository_Merge(t *testing.T) {
+	db := setupAccountMergeTestDB(t)
+	repo := NewAccountMergeRepository(db)
+	ctx := context.Background()
+
+	primaryID := uuid.New()
+	secondaryID := uuid.New()
+	sharedProduct := uuid.New()
+
+	// Wishlist: one shared product, one only on the secondary account
+	require.NoError(t, db.Create(&domain.WishlistItem{UserID: primaryID, ProductID: sharedProduct}).Error)
+	require.NoError(t, db.Create(&domain.WishlistItem{UserID: secondaryID, ProductID: sharedProduct}).Error)
+	require.NoError(t, db.Create(&domain.WishlistItem{UserID: secondaryID, ProductID: uuid.New()}).Error)
+
+	require.NoError(t, db.Create(&domain.BackInStockSubscription{ID: uuid.New(), CustomerID: secondaryID, ProductID: uuid.New()}).Error)
+
+	// Both accounts have a default address; the primary one must stay default
+	newAddress := func(userID uuid.UUID) *domain.Address {
+		return &domain.Address{
+			UserID: userID, RecipientName: "Aisyah", Phone: "0123456789",
+			AddressLine1: "1 Jalan Ampang", City: "Kuala Lumpur", State: "WP", Postcode: "50450",
+			Country: "Malaysia", IsDefault: true,
+		}
+	}
+	require.NoError(t, db.Create(newAddress(primaryID)).Error)
+	require.NoError(t, db.Create(newAddress(secondaryID)).Error)
+
+	require.NoError(t, db.Create(&domain.CustomerNote{CustomerID: secondaryID, Note: "VIP"}).Error)
+
+	wallets := NewWalletRepository(db)
+	_, err := wallets.Credit(ctx, primaryID, 10, "Promo", nil)
+	require.NoError(t, err)
+	_, err = wallets.Credit(ctx, secondaryID, 15, "Refund", nil)
+	require.NoError(t, err)
+
+	record, merged, err := repo.Merge(ctx, primaryID, secondaryID)
+	require.NoError(t, err)
+	assert.True(t, merged)
+	assert.Equal(t, 1, record.WishlistItems)
+	assert.Equal(t, 1, record.Subscriptions)
+	assert.Equal(t, 1, record.Addresses)
+	assert.Equal(t, 1, record.Notes)
+
+	var wishlistCount int64
+	db.Model(&domain.WishlistItem{}).Where("user_id = ?", primaryID).Count(&wishlistCount)
+	assert.Equal(t, int64(2), wishlistCount)
+
+	var defaults int64
+	db.Model(&domain.Address{}).Where("user_id = ? AND is_default = ?", primaryID, true).Count(&defaults)
+	assert.Equal(t, int64(1), defaults)
+
+	wallet, err := wallets.GetOrCreate(ctx, primaryID)
+	require.NoError(t, err)
+	assert.Equal(t, 25.0, wallet.Balance)
+
+	_, total, err := wallets.ListTransactions(ctx, primaryID, 1, 10)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), total)
+
+	// Redelivered event is a no-op
+	again, merged, err := repo.Merge(ctx, primaryID, secondaryID)
+	require.NoError(t, err)
+	assert.False(t, merged)
+	assert.Equal(t, record.ID, again.ID)
+}
+
+func TestAccountMergeRepository_PreviewAndMergeWithWinners(t *testing.T) {
+	db := setupAccountMergeTestDB(t)
+	repo := NewAccountMergeRepository(db)
+	ctx := context.Background()
+
+	primary := &domain.Customer{Email: "aisyah@example.com", FirstName: "Aisyah", Phone: "0123456789"}
+	secondary := &domain.Customer{Email: "aisyah.work@example.com", FirstName: "Aisyah", Phone: "0198765432", AvatarURL: "https://cdn.example.com/a.png"}
+	require.NoError(t, db.Create(primary).Error)
+	require.NoError(t, db.Create(secondary).Error)
+
+	home := &domain.Address{UserID: primary.ID, RecipientName: "Aisyah", Phone: "0123456789",
+		AddressLine1: "1 Jalan Ampang", City: "Kuala Lumpur", State: "WP", Postcode: "50450", Country: "Malaysia", IsDefault: true}
+	office := &domain.Address{UserID: secondary.ID, RecipientName: "Aisyah", Phone: "0198765432",
+		AddressLine1: "88 Jalan Sultan Ismail", City: "Kuala Lumpur", State: "WP", Postcode: "50250", Country: "Malaysia", IsDefault: true}
+	require.NoError(t, db.Create(home).Error)
+	require.NoError(t, db.Create(office).Error)
+
+	preview, err := repo.Preview(ctx, primary.ID, secondary.ID)
+	require.NoError(t, err)
+	assert.False(t, preview.AlreadyMerged)
+
+	// Same first name and an avatar only on one side are not conflicts
+	fields := make([]string, len(preview.Conflicts))
+	for i, conflict := range preview.Conflicts {
+		fields[i] = conflict.Field
+		assert.Equal(t, domain.MergeWinnerPrimary, conflict.Winner)
+	}
+	assert.Equal(t, []string{domain.MergeFieldPhone, domain.MergeFieldDefaultAddress}, fields)
+
+	_, merged, err := repo.MergeWithWinners(ctx, primary.ID, secondary.ID, map[string]string{
+		domain.MergeFieldPhone:          domain.MergeWinnerSecondary,
+		domain.MergeFieldDefaultAddress: domain.MergeWinnerSecondary,
+	})
+	require.NoError(t, err)
+	assert.True(t, merged)
+
+	var updated domain.Customer
+	require.NoError(t, db.Where("id = ?", primary.ID).First(&updated).Error)
+	assert.Equal(t, "0198765432", updated.Phone)
+	assert.Equal(t, primary.Version+1, updated.Version)
+
+	var defaultAddress domain.Address
+	require.NoError(t, db.Where("user_id = ? AND is_default = ?", primary.ID, true).First(&defaultAddress).Error)
+	assert.Equal(t, office.ID, defaultAddress.ID)
+
+	preview, err = repo.Preview(ctx, primary.ID, secondary.ID)
+	require.NoError(t, err)
+	assert.True(t, preview.AlreadyMerged)
+}
+
+func TestAccountMergeRepository_MergeDuplicate(t *testing.T) {
+	db := setupAccountMergeTestDB(t)
+	repo := NewAccountMergeRepository(db)
+	ctx := context.Background()
+	adminID := uuid.New()
+
+	primary := &domain.Customer{Email: "aisyah@example.com", FirstName: "Aisyah", TotalOrders: 3, TotalSpent: 150}
+	duplicate := &domain.Customer{Email: "aisyah+guest@example.com", FirstName: "Aisyah", TotalOrders: 2, TotalSpent: 80.5}
+	require.NoError(t, db.Create(primary).Error)
+	require.NoError(t, db.Create(duplicate).Error)
+	require.NoError(t, db.Create(&domain.CustomerNote{CustomerID: duplicate.ID, Note: "Guest checkout"}).Error)
+
+	record, merged, err := repo.MergeDuplicate(ctx, primary.ID, duplicate.ID, nil, adminID)
+	require.NoError(t, err)
+	assert.True(t, merged)
+	assert.Equal(t, domain.MergeSourceAdmin, record.Source)
+	require.NotNil(t, record.MergedBy)
+	assert.Equal(t, adminID, *record.MergedBy)
+	assert.Equal(t, 2, record.Orders)
+	assert.Equal(t, 80.5, record.Spent)
+	assert.Equal(t, 1, record.Notes)
+
+	var updated domain.Customer
+	require.NoError(t, db.Where("id = ?", primary.ID).First(&updated).Error)
+	assert.Equal(t, 5, updated.TotalOrders)
+	assert.Equal(t, 230.5, updated.TotalSpent)
+
+	// The duplicate is soft-deleted, not removed
+	err = db.Where("id = ?", duplicate.ID).First(&domain.Customer{}).Error
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	var retired domain.Customer
+	require.NoError(t, db.Unscoped().Where("id = ?", duplicate.ID).First(&retired).Error)
+	assert.True(t, retired.DeletedAt.Valid)
+}
@@ -0,0 +1,85 @@
+package persistence
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"gorm.io/gorm"
+)
+
+// ActivityRepository writes customer activity timeline entries
+type ActivityRepository struct {
+	db *gorm.DB
+}
+
+// NewActivityRepository creates a new activity repository
+func NewActivityRepository(db *gorm.DB) *ActivityRepository {
+	return &ActivityRepository{db: db}
+}
+
+// Record adds an activity to the customer's timeline
+func (r *ActivityRepository) Record(ctx context.Context, activity *domain.CustomerActivity) error {
+	return r.db.WithContext(ctx).Create(activity).Error
+}
+
+// Feed returns a page of activities across all customers, newest first
+// unless filter.Sort asks for oldest first.
+// Pages are keyset-paginated on (created_at, id) so they stay stable while
+// new activities arrive and deep pages stay cheap.
+func (r *ActivityRepository) Feed(ctx context.Context, filter domain.ActivityFeedFilter) (*domain.ActivityFeedPage, error) {
+	query := r.db.WithContext(ctx).Model(&domain.CustomerActivity{})
+	if len(filter.Types) > 0 {
+		query = query.Where("type IN ?", filter.Types)
+	}
+	if filter.CustomerID != nil {
+		query = query.Where("customer_id = ?", *filter.CustomerID)
+	}
+	if filter.ActorID != nil {
+		query = query.Where("actor_id = ?", *filter.ActorID)
+	}
+	if filter.DateFrom != nil {
+		query = query.Where("created_at >= ?", *filter.DateFrom)
+	}
+	if filter.DateTo != nil {
+		query = query.Where("created_at <= ?", *filter.DateTo)
+	}
+	if filter.Region != nil {
+		defaultAddresses := r.db.Model(&domain.Address{}).
+			Select("user_id").
+			Where("is_default = ? AND LOWER(state) IN ?", true, filter.Region.NormalizedStates())
+		query = query.Where("customer_id IN (?)", defaultAddresses)
+	}
+
+	_, desc, err := domain.ActivityFeedSort.Resolve(filter.Sort)
+	if err != nil {
+		return nil, err
+	}
+
+	var activities []domain.CustomerActivity
+	if err := keysetOrder(query, filter.After, desc, filter.Limit).Find(&activities).Error; err != nil {
+		return nil, err
+	}
+
+	page := &domain.ActivityFeedPage{}
+	page.Items, page.NextCursor = cursorPage(activities, filter.Limit, activityCursor)
+	return page, nil
+}
+
+// SecurityLog returns a page of a customer's own security-relevant
+// activities from the last domain.SecurityActivityWindow, newest first
+func (r *ActivityRepository) SecurityLog(ctx context.Context, customerID uuid.UUID, after *domain.Cursor, limit int) (*domain.ActivityFeedPage, error) {
+	since := time.Now().Add(-domain.SecurityActivityWindow)
+	return r.Feed(ctx, domain.ActivityFeedFilter{
+		Types:      domain.SecurityActivityTypes,
+		CustomerID: &customerID,
+		DateFrom:   &since,
+		After:      after,
+		Limit:      limit,
+	})
+}
+
+func activityCursor(a *domain.CustomerActivity) domain.Cursor {
+	return domain.Cursor{CreatedAt: a.CreatedAt, ID: a.ID}
+}
@@ -0,0 +1,140 @@
+package persistence
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestActivityRepository_RecordShowsOnTimeline(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t, &domain.CustomerActivity{})
+	customerID := uuid.New()
+
+	err := NewActivityRepository(db).Record(context.Background(), &domain.CustomerActivity{
+		CustomerID: customerID,
+		Type:       domain.ActivityTypeAddress,
+		Title:      "Address updated",
+		Details:    "id=42",
+		IPAddress:  "203.0.113.7",
+		UserAgent:  "Mozilla/5.0",
+		RequestID:  "req-1",
+	})
+	require.NoError(t, err)
+
+	activities, total, err := NewCustomerRepository(db, DefaultQueryTimeouts()).GetActivity(ctx, customerID, 1, 20)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), total)
+	assert.NotEqual(t, uuid.Nil, activities[0].ID)
+	assert.Equal(t, "Address updated", activities[0].Title)
+	assert.Equal(t, "203.0.113.7", activities[0].IPAddress)
+	assert.Equal(t, "req-1", activities[0].RequestID)
+}
+
+func TestActivityRepository_FeedKeysetPagination(t *testing.T) {
+	db := openTestDB(t, &domain.CustomerActivity{})
+	repo := NewActivityRepository(db)
+	ctx := context.Background()
+	adminID := uuid.New()
+
+	// Five activities across two customers, two sharing a timestamp so the
+	// ID tie-break is exercised
+	base := time.Now().UTC().Truncate(time.Second)
+	var created []domain.CustomerActivity
+	for i, at := range []time.Time{base, base.Add(time.Minute), base.Add(time.Minute), base.Add(2 * time.Minute), base.Add(3 * time.Minute)} {
+		activity := domain.CustomerActivity{
+			CustomerID: uuid.New(),
+			Type:       domain.ActivityTypeAddress,
+			Title:      "Address updated",
+			CreatedAt:  at,
+		}
+		if i%2 == 0 {
+			activity.Type = domain.ActivityTypeStatusChange
+			activity.ActorID = &adminID
+		}
+		require.NoError(t, repo.Record(ctx, &activity))
+		created = append(created, activity)
+	}
+
+	seen := map[uuid.UUID]bool{}
+	filter := domain.ActivityFeedFilter{Limit: 2}
+	for pages := 0; ; pages++ {
+		require.Less(t, pages, 3)
+		page, err := repo.Feed(ctx, filter)
+		require.NoError(t, err)
+		for _, activity := range page.Items {
+			assert.False(t, seen[activity.ID], "activity listed twice")
+			seen[activity.ID] = true
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		filter.After, err = domain.DecodeCursor(page.NextCursor)
+		require.NoError(t, err)
+	}
+	assert.Len(t, seen, len(created))
+
+	page, err := repo.Feed(ctx, domain.ActivityFeedFilter{Types: []string{domain.ActivityTypeStatusChange}, ActorID: &adminID, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, page.Items, 3)
+	assert.Equal(t, created[4].ID, page.Items[0].ID)
+	assert.Empty(t, page.NextCursor)
+
+	// Oldest first, paged the other way
+	var oldestFirst []uuid.UUID
+	filter = domain.ActivityFeedFilter{Sort: domain.Sort{Field: "created_at", Direction: domain.SortAsc}, Limit: 2}
+	for {
+		page, err := repo.Feed(ctx, filter)
+		require.NoError(t, err)
+		for _, activity := range page.Items {
+			oldestFirst = append(oldestFirst, activity.ID)
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		filter.After, err = domain.DecodeCursor(page.NextCursor)
+		require.NoError(t, err)
+	}
+	require.Len(t, oldestFirst, len(created))
+	assert.Equal(t, created[0].ID, oldestFirst[0])
+	assert.Equal(t, created[4].ID, oldestFirst[4])
+
+	_, err = repo.Feed(ctx, domain.ActivityFeedFilter{Sort: domain.Sort{Field: "title", Direction: domain.SortAsc}, Limit: 2})
+	assert.ErrorIs(t, err, domain.ErrInvalidSort)
+
+	_, err = domain.DecodeCursor("not-a-cursor")
+	assert.ErrorIs(t, err, domain.ErrInvalidCursor)
+}
+
+func TestActivityRepository_SecurityLog(t *testing.T) {
+	db := openTestDB(t, &domain.CustomerActivity{})
+	repo := NewActivityRepository(db)
+	ctx := context.Background()
+	customerID := uuid.New()
+
+	record := func(customerID uuid.UUID, activityType string, age time.Duration) {
+		require.NoError(t, repo.Record(ctx, &domain.CustomerActivity{
+			CustomerID: customerID,
+			Type:       activityType,
+			Title:      activityType,
+			CreatedAt:  time.Now().Add(-age),
+		}))
+	}
+	record(customerID, domain.ActivityTypeLogin, time.Minute)
+	record(customerID, domain.ActivityTypeAddress, time.Hour)
+	record(customerID, domain.ActivityTypeWishlist, time.Hour)                              // not security-relevant
+	record(customerID, domain.ActivityTypeProfile, domain.SecurityActivityWindow+time.Hour) // too old
+	record(uuid.New(), domain.ActivityTypeLogin, time.Minute)                               // someone else
+
+	page, err := repo.SecurityLog(ctx, customerID, nil, 10)
+	require.NoError(t, err)
+	require.Len(t, page.Items, 2)
+	assert.Equal(t, domain.ActivityTypeLogin, page.Items[0].Type)
+	assert.Equal(t, domain.ActivityTypeAddress, page.Items[1].Type)
+	assert.Empty(t, page.NextCursor)
+}
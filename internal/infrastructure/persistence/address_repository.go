@@ -2,15 +2,19 @@ package persistence
 
 import (
 	"context"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/Ecom-micro-template/service-customer/internal/domain/address"
 	"gorm.io/gorm"
 )
 
 // AddressRepository handles address data operations
 type AddressRepository struct {
-	db *gorm.DB
+	db     *gorm.DB
+	mirror CustomerMirror
+	limits *CustomerLimitRepository
 }
 
 // NewAddressRepository creates a new address repository
@@ -18,6 +22,31 @@ func NewAddressRepository(db *gorm.DB) *AddressRepository {
 	return &AddressRepository{db: db}
 }
 
+// WithMirror mirrors the user's addresses to mirror after every successful write
+func (r *AddressRepository) WithMirror(mirror CustomerMirror) *AddressRepository {
+	r.mirror = mirror
+	return r
+}
+
+// WithLimits caps how many addresses each customer can keep
+func (r *AddressRepository) WithLimits(limits *CustomerLimitRepository) *AddressRepository {
+	r.limits = limits
+	return r
+}
+
+// checkLimit returns address.ErrMaxAddresses if the user can't keep another address
+func (r *AddressRepository) checkLimit(tx *gorm.DB, userID uuid.UUID) error {
+	return checkCustomerLimit(tx, r.limits, userID, &domain.Address{}, func(l domain.CustomerLimits) int { return l.Addresses }, address.ErrMaxAddresses)
+}
+
+// mirrored mirrors the user's addresses if the write succeeded and returns its error
+func (r *AddressRepository) mirrored(ctx context.Context, userID uuid.UUID, err error) error {
+	if err == nil && r.mirror != nil {
+		r.mirror.MirrorAddresses(ctx, userID)
+	}
+	return err
+}
+
 // ListByUserID retrieves all addresses for a user
 func (r *AddressRepository) ListByUserID(ctx context.Context, userID uuid.UUID) ([]domain.Address, error) {
 	var addresses []domain.Address
@@ -40,54 +69,111 @@ func (r *AddressRepository) GetByID(ctx context.Context, id, userID uuid.UUID) (
 	return &address, nil
 }
 
-// Create creates a new address
+// Create creates a new address. Returns address.ErrMaxAddresses if the user
+// already keeps as many addresses as their limit allows.
 func (r *AddressRepository) Create(ctx context.Context, address *domain.Address) error {
-	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := r.checkLimit(tx, address.UserID); err != nil {
+			return err
+		}
 		// If this address is set as default, clear other defaults first
 		if address.IsDefault {
 			if err := tx.Model(&domain.Address{}).
 				Where("user_id = ? AND is_default = ?", address.UserID, true).
-				Update("is_default", false).Error; err != nil {
+				Updates(map[string]interface{}{"is_default": false, "version": bumpVersion}).Error; err != nil {
 				return err
 			}
 		}
 		return tx.Create(address).Error
 	})
+	return r.mirrored(ctx, address.UserID, err)
 }
 
-// Update updates an existing address
+// Update updates an existing address if it is still at address.Version.
+// Returns domain.ErrVersionConflict if it changed meanwhile.
 func (r *AddressRepository) Update(ctx context.Context, address *domain.Address) error {
-	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
-		// If this address is set as default, clear other defaults first
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := updateVersioned(tx, address, &address.Version); err != nil {
+			return err
+		}
+		// If this address is set as default, clear other defaults
 		if address.IsDefault {
-			if err := tx.Model(&domain.Address{}).
+			return tx.Model(&domain.Address{}).
 				Where("user_id = ? AND id != ? AND is_default = ?", address.UserID, address.ID, true).
-				Update("is_default", false).Error; err != nil {
-				return err
-			}
+				Updates(map[string]interface{}{"is_default": false, "version": bumpVersion}).Error
 		}
-		return tx.Save(address).Error
+		return nil
 	})
+	return r.mirrored(ctx, address.UserID, err)
 }
 
-// Delete deletes an address with ownership check
+// Delete soft-deletes an address with ownership check. The default flag is
+// cleared so a restored address does not silently become the default again.
 func (r *AddressRepository) Delete(ctx context.Context, id, userID uuid.UUID) error {
-	result := r.db.WithContext(ctx).
-		Where("id = ? AND user_id = ?", id, userID).
-		Delete(&domain.Address{})
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&domain.Address{}).
+			Where("id = ? AND user_id = ?", id, userID).
+			Update("is_default", false).Error; err != nil {
+			return err
+		}
 
-	if result.Error != nil {
-		return result.Error
+		result := tx.Where("id = ? AND user_id = ?", id, userID).Delete(&domain.Address{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+	return r.mirrored(ctx, userID, err)
+}
+
+// Restore undeletes an address deleted within AddressRestoreWindow. A
+// restored address counts towards the user's address limit.
+func (r *AddressRepository) Restore(ctx context.Context, id, userID uuid.UUID) (*domain.Address, error) {
+	var address domain.Address
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().
+			Where("id = ? AND user_id = ? AND deleted_at IS NOT NULL", id, userID).
+			First(&address).Error; err != nil {
+			return err
+		}
+
+		if time.Since(address.DeletedAt.Time) > domain.AddressRestoreWindow {
+			return domain.ErrAddressRestoreExpired
+		}
+		if err := r.checkLimit(tx, userID); err != nil {
+			return err
+		}
+
+		return tx.Unscoped().
+			Model(&address).
+			Update("deleted_at", nil).Error
+	})
+	if err != nil {
+		return nil, err
 	}
-	if result.RowsAffected == 0 {
-		return gorm.ErrRecordNotFound
+	address.DeletedAt = gorm.DeletedAt{}
+	if r.mirror != nil {
+		r.mirror.MirrorAddresses(ctx, userID)
 	}
-	return nil
+	return &address, nil
+}
+
+// ListDeleted retrieves a user's soft-deleted addresses, most recently deleted first
+func (r *AddressRepository) ListDeleted(ctx context.Context, userID uuid.UUID) ([]domain.Address, error) {
+	var addresses []domain.Address
+	err := r.db.WithContext(ctx).Unscoped().
+		Where("user_id = ? AND deleted_at IS NOT NULL", userID).
+		Order("deleted_at DESC").
+		Find(&addresses).Error
+	return addresses, err
 }
 
 // SetDefault sets an address as the default address
 func (r *AddressRepository) SetDefault(ctx context.Context, id, userID uuid.UUID) error {
-	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
 		// Verify address exists and belongs to user
 		var address domain.Address
 		if err := tx.Where("id = ? AND user_id = ?", id, userID).First(&address).Error; err != nil {
@@ -97,13 +183,14 @@ func (r *AddressRepository) SetDefault(ctx context.Context, id, userID uuid.UUID
 		// Clear all other defaults for this user
 		if err := tx.Model(&domain.Address{}).
 			Where("user_id = ? AND is_default = ?", userID, true).
-			Update("is_default", false).Error; err != nil {
+			Updates(map[string]interface{}{"is_default": false, "version": bumpVersion}).Error; err != nil {
 			return err
 		}
 
 		// Set this address as default
 		return tx.Model(&domain.Address{}).
 			Where("id = ?", id).
-			Update("is_default", true).Error
+			Updates(map[string]interface{}{"is_default": true, "version": bumpVersion}).Error
 	})
+	return r.mirrored(ctx, userID, err)
 }
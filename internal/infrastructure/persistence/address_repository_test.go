@@ -3,23 +3,18 @@ package persistence
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/Ecom-micro-template/service-customer/internal/domain/shared"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
-	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 )
 
 func setupAddressTestDB(t *testing.T) *gorm.DB {
-	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
-	require.NoError(t, err)
-
-	err = db.AutoMigrate(&domain.Address{})
-	require.NoError(t, err)
-
-	return db
+	return openTestDB(t, &domain.Address{})
 }
 
 func TestAddressRepository_Create(t *testing.T) {
@@ -181,6 +176,72 @@ func TestAddressRepository_Delete(t *testing.T) {
 	assert.Equal(t, gorm.ErrRecordNotFound, err)
 }
 
+func TestAddressRepository_Restore(t *testing.T) {
+	db := setupAddressTestDB(t)
+	repo := NewAddressRepository(db)
+	ctx := context.Background()
+
+	userID := uuid.New()
+	address := &domain.Address{
+		UserID:        userID,
+		Label:         "Home",
+		RecipientName: "John Doe",
+		Phone:         "+1234567890",
+		AddressLine1:  "123 Main St",
+		City:          "New York",
+		State:         "NY",
+		Postcode:      "10001",
+		Country:       "USA",
+		IsDefault:     true,
+	}
+	require.NoError(t, repo.Create(ctx, address))
+	require.NoError(t, repo.Delete(ctx, address.ID, userID))
+
+	deleted, err := repo.ListDeleted(ctx, userID)
+	require.NoError(t, err)
+	require.Len(t, deleted, 1)
+	assert.True(t, deleted[0].DeletedAt.Valid)
+
+	restored, err := repo.Restore(ctx, address.ID, userID)
+	require.NoError(t, err)
+	assert.False(t, restored.IsDefault)
+
+	found, err := repo.GetByID(ctx, address.ID, userID)
+	require.NoError(t, err)
+	assert.Equal(t, "123 Main St", found.AddressLine1)
+
+	// Restoring an address that is not deleted
+	_, err = repo.Restore(ctx, address.ID, userID)
+	assert.Equal(t, gorm.ErrRecordNotFound, err)
+}
+
+func TestAddressRepository_RestoreExpired(t *testing.T) {
+	db := setupAddressTestDB(t)
+	repo := NewAddressRepository(db)
+	ctx := context.Background()
+
+	userID := uuid.New()
+	address := &domain.Address{
+		UserID:        userID,
+		RecipientName: "John Doe",
+		Phone:         "+1234567890",
+		AddressLine1:  "123 Main St",
+		City:          "New York",
+		State:         "NY",
+		Postcode:      "10001",
+		Country:       "USA",
+	}
+	require.NoError(t, repo.Create(ctx, address))
+	require.NoError(t, repo.Delete(ctx, address.ID, userID))
+
+	// Pretend it was deleted 31 days ago
+	require.NoError(t, db.Unscoped().Model(address).
+		Update("deleted_at", time.Now().Add(-31*24*time.Hour)).Error)
+
+	_, err := repo.Restore(ctx, address.ID, userID)
+	assert.ErrorIs(t, err, domain.ErrAddressRestoreExpired)
+}
+
 func TestAddressRepository_Update(t *testing.T) {
 	db := setupAddressTestDB(t)
 	repo := NewAddressRepository(db)
@@ -203,7 +264,7 @@ func TestAddressRepository_Update(t *testing.T) {
 	require.NoError(t, err)
 
 	// Update address
-	address.Label = "Home Sweet Home"
+	address.Label = shared.AddressLabelOffice
 	address.City = "Los Angeles"
 	err = repo.Update(ctx, address)
 	assert.NoError(t, err)
@@ -211,6 +272,52 @@ func TestAddressRepository_Update(t *testing.T) {
 	// Verify update
 	retrieved, err := repo.GetByID(ctx, address.ID, userID)
 	assert.NoError(t, err)
-	assert.Equal(t, "Home Sweet Home", retrieved.Label)
+	assert.Equal(t, shared.AddressLabelOffice, retrieved.Label)
 	assert.Equal(t, "Los Angeles", retrieved.City)
 }
+
+func TestAddressRepository_UpdateVersionConflict(t *testing.T) {
+	db := setupAddressTestDB(t)
+	repo := NewAddressRepository(db)
+	ctx := context.Background()
+
+	userID := uuid.New()
+	home := limitTestAddress(userID, "1 Jalan Ampang")
+	office := limitTestAddress(userID, "2 Jalan Ampang")
+	office.IsDefault = true
+	require.NoError(t, repo.Create(ctx, home))
+	require.NoError(t, repo.Create(ctx, office))
+	assert.Equal(t, int64(1), home.Version)
+
+	phone, err := repo.GetByID(ctx, home.ID, userID)
+	require.NoError(t, err)
+	laptop, err := repo.GetByID(ctx, home.ID, userID)
+	require.NoError(t, err)
+
+	phone.Postcode = "50450"
+	phone.IsDefault = true
+	require.NoError(t, repo.Update(ctx, phone))
+	assert.Equal(t, int64(2), phone.Version)
+
+	// The laptop edited version 1; its update is rejected instead of
+	// silently overwriting the phone's postcode
+	laptop.City = "Petaling Jaya"
+	assert.ErrorIs(t, repo.Update(ctx, laptop), domain.ErrVersionConflict)
+
+	retrieved, err := repo.GetByID(ctx, home.ID, userID)
+	require.NoError(t, err)
+	assert.Equal(t, "50450", retrieved.Postcode)
+	assert.Equal(t, "Kuala Lumpur", retrieved.City)
+
+	// Clearing the office's default flag changed it too
+	cleared, err := repo.GetByID(ctx, office.ID, userID)
+	require.NoError(t, err)
+	assert.False(t, cleared.IsDefault)
+	assert.Equal(t, int64(2), cleared.Version)
+
+	// A deleted address can't be updated back into existence
+	require.NoError(t, repo.Delete(ctx, home.ID, userID))
+	assert.ErrorIs(t, repo.Update(ctx, phone), domain.ErrVersionConflict)
+	_, err = repo.GetByID(ctx, home.ID, userID)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
@@ -0,0 +1,65 @@
+package persistence
+
+import (
+	"context"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"gorm.io/gorm"
+)
+
+// AdminRegionRepository manages the states assigned to admin users
+type AdminRegionRepository struct {
+	db *gorm.DB
+}
+
+// NewAdminRegionRepository creates a new admin region repository
+func NewAdminRegionRepository(db *gorm.DB) *AdminRegionRepository {
+	return &AdminRegionRepository{db: db}
+}
+
+// GetStates returns the states assigned to an admin user
+func (r *AdminRegionRepository) GetStates(ctx context.Context, adminUserID uuid.UUID) ([]string, error) {
+	states := []string{}
+	err := r.db.WithContext(ctx).
+		Model(&domain.AdminRegionAssignment{}).
+		Where("admin_user_id = ?", adminUserID).
+		Order("state ASC").
+		Pluck("state", &states).Error
+	return states, err
+}
+
+// SetStates replaces the states assigned to an admin user. Duplicate and
+// blank states are dropped; an empty list removes every assignment.
+func (r *AdminRegionRepository) SetStates(ctx context.Context, adminUserID uuid.UUID, states []string, assignedBy uuid.UUID) ([]string, error) {
+	seen := make(map[string]bool, len(states))
+	assignments := make([]domain.AdminRegionAssignment, 0, len(states))
+	for _, state := range states {
+		state = strings.TrimSpace(state)
+		key := strings.ToLower(state)
+		if state == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		assignments = append(assignments, domain.AdminRegionAssignment{
+			AdminUserID: adminUserID,
+			State:       state,
+			AssignedBy:  &assignedBy,
+		})
+	}
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("admin_user_id = ?", adminUserID).Delete(&domain.AdminRegionAssignment{}).Error; err != nil {
+			return err
+		}
+		if len(assignments) == 0 {
+			return nil
+		}
+		return tx.Create(&assignments).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r.GetStates(ctx, adminUserID)
+}
@@ -0,0 +1,35 @@
+package persistence
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdminRegionRepository_SetStates(t *testing.T) {
+	db := openTestDB(t, &domain.AdminRegionAssignment{})
+	repo := NewAdminRegionRepository(db)
+	ctx := context.Background()
+	agent, manager := uuid.New(), uuid.New()
+
+	states, err := repo.GetStates(ctx, agent)
+	require.NoError(t, err)
+	assert.Empty(t, states)
+
+	states, err = repo.SetStates(ctx, agent, []string{"Selangor", "Johor", " selangor", ""}, manager)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Johor", "Selangor"}, states)
+
+	// Replaces rather than adds
+	states, err = repo.SetStates(ctx, agent, []string{"Penang"}, manager)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Penang"}, states)
+
+	states, err = repo.SetStates(ctx, agent, nil, manager)
+	require.NoError(t, err)
+	assert.Empty(t, states)
+}
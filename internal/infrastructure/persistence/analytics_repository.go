@@ -0,0 +1,143 @@
+package persistence
+
+import (
+	"context"
+	"time"
+
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"gorm.io/gorm"
+)
+
+// cohortViews create the materialized views behind the cohort analytics.
+// Cohorts are signup months in UTC; activity counts completed orders from
+// customer.order_stats_entries, so orders from before the order event
+// subscriber aren't included. The unique indexes allow REFRESH ...
+// CONCURRENTLY.
+var cohortViews = []string{
+	`CREATE MATERIALIZED VIEW IF NOT EXISTS customer.customer_cohort_sizes AS
+	SELECT date_trunc('month', created_at AT TIME ZONE 'UTC')::date AS cohort,
+		COUNT(*) AS customers,
+		COUNT(*) FILTER (WHERE total_orders >= 1) AS buyers,
+		COUNT(*) FILTER (WHERE total_orders >= 2) AS repeat_buyers
+	FROM public.customers
+	WHERE deleted_at IS NULL
+	GROUP BY 1`,
+	`CREATE UNIQUE INDEX IF NOT EXISTS idx_customer_cohort_sizes_cohort
+	ON customer.customer_cohort_sizes (cohort)`,
+	`CREATE MATERIALIZED VIEW IF NOT EXISTS customer.customer_cohort_activity AS
+	SELECT date_trunc('month', c.created_at AT TIME ZONE 'UTC')::date AS cohort,
+		((EXTRACT(YEAR FROM e.created_at AT TIME ZONE 'UTC') - EXTRACT(YEAR FROM c.created_at AT TIME ZONE 'UTC')) * 12
+			+ EXTRACT(MONTH FROM e.created_at AT TIME ZONE 'UTC') - EXTRACT(MONTH FROM c.created_at AT TIME ZONE 'UTC'))::int AS month_offset,
+		COUNT(DISTINCT c.id) AS active_customers
+	FROM public.customers c
+	JOIN customer.order_stats_entries e ON e.customer_id = c.id AND e.kind = 'completed'
+	WHERE c.deleted_at IS NULL
+		AND e.created_at >= date_trunc('month', c.created_at AT TIME ZONE 'UTC')
+	GROUP BY 1, 2`,
+	`CREATE UNIQUE INDEX IF NOT EXISTS idx_customer_cohort_activity_cohort_offset
+	ON customer.customer_cohort_activity (cohort, month_offset)`,
+}
+
+// CreateCohortViews creates the cohort materialized views if they don't
+// exist. PostgreSQL only; public.customers and customer.order_stats_entries
+// must exist.
+func CreateCohortViews(db *gorm.DB) error {
+	for _, statement := range cohortViews {
+		if err := db.Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AnalyticsRepository computes customer cohort and growth analytics
+type AnalyticsRepository struct {
+	db *gorm.DB
+}
+
+// NewAnalyticsRepository creates a new analytics repository
+func NewAnalyticsRepository(db *gorm.DB) *AnalyticsRepository {
+	return &AnalyticsRepository{db: db}
+}
+
+// RefreshCohorts recomputes the cohort materialized views without blocking
+// readers
+func (r *AnalyticsRepository) RefreshCohorts(ctx context.Context) error {
+	db := r.db.WithContext(ctx)
+	for _, view := range []string{domain.CohortSize{}.TableName(), domain.CohortActivity{}.TableName()} {
+		if err := db.Exec("REFRESH MATERIALIZED VIEW CONCURRENTLY " + view).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Cohorts returns the cohorts of the last months signup months up to now,
+// newest first, as of the last refresh
+func (r *AnalyticsRepository) Cohorts(ctx context.Context, months int, now time.Time) ([]domain.Cohort, error) {
+	db := Replica(r.db.WithContext(ctx))
+	now = now.UTC()
+	from := time.Date(now.Year(), now.Month()-time.Month(months-1), 1, 0, 0, 0, 0, time.UTC)
+
+	var sizes []domain.CohortSize
+	if err := db.Where("cohort >= ?", from).Order("cohort DESC").Find(&sizes).Error; err != nil {
+		return nil, err
+	}
+	var activity []domain.CohortActivity
+	if err := db.Where("cohort >= ?", from).Find(&activity).Error; err != nil {
+		return nil, err
+	}
+
+	byCohort := make(map[string][]domain.CohortActivity)
+	for _, a := range activity {
+		key := a.Cohort.Format("2006-01")
+		byCohort[key] = append(byCohort[key], a)
+	}
+	cohorts := make([]domain.Cohort, 0, len(sizes))
+	for _, size := range sizes {
+		cohorts = append(cohorts, domain.NewCohort(size, byCohort[size.Cohort.Format("2006-01")], now))
+	}
+	return cohorts, nil
+}
+
+// Growth returns the signups and first purchases per interval from the
+// interval containing from to the one containing to, in UTC. PostgreSQL only.
+func (r *AnalyticsRepository) Growth(ctx context.Context, interval string, from, to time.Time) (domain.GrowthSeries, error) {
+	from, to, err := domain.GrowthRange(interval, from.UTC(), to.UTC())
+	if err != nil {
+		return domain.GrowthSeries{}, err
+	}
+	end := domain.GrowthIntervalEnd(interval, to)
+	db := Replica(r.db.WithContext(ctx))
+
+	var before int64
+	if err := db.Model(&domain.Customer{}).Where("created_at < ?", from).Count(&before).Error; err != nil {
+		return domain.GrowthSeries{}, err
+	}
+
+	count := func(column string) (map[string]int64, error) {
+		var rows []struct {
+			Start time.Time
+			Count int64
+		}
+		err := db.Model(&domain.Customer{}).
+			Select("date_trunc(?, "+column+" AT TIME ZONE 'UTC') AS start, COUNT(*) AS count", interval).
+			Where(column+" >= ? AND "+column+" < ?", from, end).
+			Group("1").
+			Scan(&rows).Error
+		counts := make(map[string]int64, len(rows))
+		for _, row := range rows {
+			counts[row.Start.Format("2006-01-02")] = row.Count
+		}
+		return counts, err
+	}
+	signups, err := count("created_at")
+	if err != nil {
+		return domain.GrowthSeries{}, err
+	}
+	firstPurchases, err := count("first_order_at")
+	if err != nil {
+		return domain.GrowthSeries{}, err
+	}
+	return domain.NewGrowthSeries(interval, from, to, before, signups, firstPurchases), nil
+}
@@ -0,0 +1,75 @@
+package persistence
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnalyticsRepository_Cohorts(t *testing.T) {
+	// The materialized views are stood in for by tables
+	db := openTestDB(t, &domain.CohortSize{}, &domain.CohortActivity{})
+	repo := NewAnalyticsRepository(db)
+
+	month := func(m time.Month) time.Time { return time.Date(2025, m, 1, 0, 0, 0, 0, time.UTC) }
+	require.NoError(t, db.Create([]domain.CohortSize{
+		{Cohort: month(time.March), Customers: 50, Buyers: 10, RepeatBuyers: 5},
+		{Cohort: month(time.April), Customers: 100, Buyers: 40, RepeatBuyers: 10},
+		{Cohort: month(time.June), Customers: 20, Buyers: 5},
+	}).Error)
+	require.NoError(t, db.Create([]domain.CohortActivity{
+		{Cohort: month(time.March), MonthOffset: 0, ActiveCustomers: 10},
+		{Cohort: month(time.April), MonthOffset: 0, ActiveCustomers: 30},
+		{Cohort: month(time.April), MonthOffset: 2, ActiveCustomers: 12},
+		{Cohort: month(time.June), MonthOffset: 0, ActiveCustomers: 5},
+	}).Error)
+
+	now := time.Date(2025, 6, 15, 8, 0, 0, 0, time.UTC)
+	cohorts, err := repo.Cohorts(context.Background(), 3, now)
+	require.NoError(t, err)
+	require.Len(t, cohorts, 2)
+
+	june, april := cohorts[0], cohorts[1]
+	assert.Equal(t, "2025-06", june.Month)
+	assert.Equal(t, []domain.CohortRetention{{MonthOffset: 0, ActiveCustomers: 5, Rate: 0.25}}, june.Retention)
+	assert.Zero(t, june.RepeatPurchaseRate)
+
+	assert.Equal(t, "2025-04", april.Month)
+	assert.Equal(t, 0.25, april.RepeatPurchaseRate)
+	assert.Equal(t, []domain.CohortRetention{
+		{MonthOffset: 0, ActiveCustomers: 30, Rate: 0.3},
+		{MonthOffset: 1, ActiveCustomers: 0, Rate: 0},
+		{MonthOffset: 2, ActiveCustomers: 12, Rate: 0.12},
+	}, april.Retention)
+}
+
+func TestGrowthSeries(t *testing.T) {
+	// Wednesday to the Tuesday 13 days later covers three weeks
+	from := time.Date(2025, 6, 4, 15, 0, 0, 0, time.UTC)
+	to := time.Date(2025, 6, 17, 9, 0, 0, 0, time.UTC)
+	start, end, err := domain.GrowthRange(domain.GrowthIntervalWeek, from, to)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2025, 6, 2, 0, 0, 0, 0, time.UTC), start)
+	assert.Equal(t, time.Date(2025, 6, 16, 0, 0, 0, 0, time.UTC), end)
+
+	series := domain.NewGrowthSeries(domain.GrowthIntervalWeek, start, end, 100,
+		map[string]int64{"2025-06-02": 5, "2025-06-16": 2},
+		map[string]int64{"2025-06-09": 3})
+	require.Len(t, series.Points, 3)
+	assert.Equal(t, domain.GrowthPoint{Start: start, Signups: 5, TotalCustomers: 105}, series.Points[0])
+	assert.Equal(t, domain.GrowthPoint{Start: start.AddDate(0, 0, 7), FirstPurchases: 3, TotalCustomers: 105}, series.Points[1])
+	assert.Equal(t, int64(107), series.Points[2].TotalCustomers)
+
+	_, _, err = domain.GrowthRange("month", from, to)
+	assert.ErrorIs(t, err, domain.ErrInvalidGrowthInterval)
+	_, _, err = domain.GrowthRange(domain.GrowthIntervalDay, to, from)
+	assert.ErrorIs(t, err, domain.ErrInvalidGrowthRange)
+	_, _, err = domain.GrowthRange(domain.GrowthIntervalDay, from, from.AddDate(1, 1, 0))
+	assert.ErrorIs(t, err, domain.ErrGrowthRangeTooLong)
+	_, _, err = domain.GrowthRange(domain.GrowthIntervalWeek, from, from.AddDate(1, 1, 0))
+	assert.NoError(t, err)
+}
@@ -0,0 +1,83 @@
+package persistence
+
+import (
+	"context"
+	"errors"
+	"reflect"
+
+	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"gorm.io/gorm"
+)
+
+// AuditLogRepository stores the admin audit log
+type AuditLogRepository struct {
+	db *gorm.DB
+}
+
+// NewAuditLogRepository creates a new audit log repository
+func NewAuditLogRepository(db *gorm.DB) *AuditLogRepository {
+	return &AuditLogRepository{db: db}
+}
+
+// Record stores an audit log entry
+func (r *AuditLogRepository) Record(ctx context.Context, entry *domain.AuditLog) error {
+	return r.db.WithContext(ctx).Create(entry).Error
+}
+
+// List returns audit log entries matching the filter, newest first
+func (r *AuditLogRepository) List(ctx context.Context, filter domain.AuditLogFilter) ([]domain.AuditLog, int64, error) {
+	var entries []domain.AuditLog
+	var total int64
+
+	query := r.db.WithContext(ctx).Model(&domain.AuditLog{})
+	if filter.EntityType != "" {
+		query = query.Where("entity_type = ?", filter.EntityType)
+	}
+	if filter.EntityID != nil {
+		query = query.Where("entity_id = ?", *filter.EntityID)
+	}
+	if filter.ActorID != nil {
+		query = query.Where("actor_id = ?", *filter.ActorID)
+	}
+	if filter.Action != "" {
+		query = query.Where("action = ?", filter.Action)
+	}
+	if filter.DateFrom != nil {
+		query = query.Where("created_at >= ?", *filter.DateFrom)
+	}
+	if filter.DateTo != nil {
+		query = query.Where("created_at <= ?", *filter.DateTo)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err := query.Order("created_at DESC").
+		Offset((filter.Page - 1) * filter.Limit).
+		Limit(filter.Limit).
+		Find(&entries).Error
+	return entries, total, err
+}
+
+// Snapshot returns a loader for audit snapshots of a model, looked up by the
+// given column (usually "id"). A missing row loads as nil, e.g. after a delete.
+func (r *AuditLogRepository) Snapshot(model interface{}, column string) func(ctx context.Context, id uuid.UUID) (interface{}, error) {
+	modelType := reflect.TypeOf(model)
+	if modelType.Kind() == reflect.Ptr {
+		modelType = modelType.Elem()
+	}
+
+	return func(ctx context.Context, id uuid.UUID) (interface{}, error) {
+		row := reflect.New(modelType).Interface()
+		err := r.db.WithContext(ctx).Where(column+" = ?", id).First(row).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		return row, nil
+	}
+}
@@ -0,0 +1,79 @@
+package persistence
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuditLogRepository_List(t *testing.T) {
+	db := openTestDB(t, &domain.AuditLog{})
+	repo := NewAuditLogRepository(db)
+	ctx := context.Background()
+	adminID, otherAdminID, customerID := uuid.New(), uuid.New(), uuid.New()
+
+	entries := []*domain.AuditLog{
+		{ActorID: adminID, Action: domain.AuditActionUpdate, EntityType: domain.AuditEntityCustomer, EntityID: &customerID,
+			Method: "PUT", Path: "/api/v1/admin/customers/" + customerID.String(), Status: 200,
+			Changes: map[string]domain.AuditChange{"status": {From: "active", To: "blocked"}}},
+		{ActorID: otherAdminID, Action: domain.AuditActionCreate, EntityType: domain.AuditEntitySegment,
+			Method: "POST", Path: "/api/v1/admin/segments", Status: 201},
+		{ActorID: adminID, Action: domain.AuditActionBulk, EntityType: domain.AuditEntityBackInStock,
+			Method: "POST", Path: "/api/v1/admin/back-in-stock/mark-notified", Status: 200,
+			CreatedAt: time.Now().Add(-48 * time.Hour)},
+	}
+	for _, entry := range entries {
+		require.NoError(t, repo.Record(ctx, entry))
+	}
+
+	got, total, err := repo.List(ctx, domain.AuditLogFilter{EntityType: domain.AuditEntityCustomer, EntityID: &customerID, Page: 1, Limit: 20})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+	require.Len(t, got, 1)
+	assert.Equal(t, domain.AuditChange{From: "active", To: "blocked"}, got[0].Changes["status"])
+
+	_, total, err = repo.List(ctx, domain.AuditLogFilter{ActorID: &adminID, Page: 1, Limit: 20})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), total)
+
+	from := time.Now().Add(-24 * time.Hour)
+	_, total, err = repo.List(ctx, domain.AuditLogFilter{DateFrom: &from, Page: 1, Limit: 20})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), total)
+}
+
+func TestAuditLogRepository_Snapshot(t *testing.T) {
+	db := openTestDB(t, &domain.SegmentRule{})
+	repo := NewAuditLogRepository(db)
+	ctx := context.Background()
+
+	rule := &domain.SegmentRule{Name: "VIP", Trigger: domain.SegmentTriggerStatusChanged, Action: domain.SegmentActionAssign}
+	require.NoError(t, db.Create(rule).Error)
+
+	snapshot := repo.Snapshot(&domain.SegmentRule{}, "id")
+	got, err := snapshot(ctx, rule.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "VIP", got.(*domain.SegmentRule).Name)
+
+	got, err = snapshot(ctx, uuid.New())
+	require.NoError(t, err)
+	assert.Nil(t, got)
+}
+
+func TestDiffAuditSnapshots(t *testing.T) {
+	before := map[string]any{"first_name": "Aisyah", "status": "active", "updated_at": "2026-01-01T00:00:00Z"}
+	after := map[string]any{"first_name": "Aisyah", "status": "blocked", "updated_at": "2026-01-02T00:00:00Z"}
+
+	assert.Equal(t, map[string]domain.AuditChange{"status": {From: "active", To: "blocked"}},
+		domain.DiffAuditSnapshots(before, after))
+	assert.Nil(t, domain.DiffAuditSnapshots(before, before))
+
+	// Creates and deletes diff against nothing
+	assert.Len(t, domain.DiffAuditSnapshots(nil, after), 2)
+	assert.Equal(t, domain.AuditChange{From: "Aisyah", To: nil}, domain.DiffAuditSnapshots(before, nil)["first_name"])
+}
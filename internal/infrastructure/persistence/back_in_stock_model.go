@@ -25,6 +25,9 @@ type BackInStockSubscriptionModel struct {
 	IsNotified         bool       `gorm:"default:false" json:"isNotified"`
 	NotificationSentAt *time.Time `json:"notificationSentAt,omitempty"`
 
+	// Expiry of pending subscriptions
+	ExpiresAt *time.Time `gorm:"index:idx_bis_expires" json:"expiresAt,omitempty"`
+
 	// Timestamps
 	CreatedAt time.Time      `json:"createdAt"`
 	UpdatedAt time.Time      `json:"updatedAt"`
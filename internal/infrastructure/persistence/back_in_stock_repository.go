@@ -3,6 +3,8 @@ package persistence
 import (
 	"context"
 	"errors"
+	"math"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/Ecom-micro-template/service-customer/internal/domain"
@@ -13,12 +15,21 @@ import (
 
 // BackInStockRepository handles back-in-stock subscription database operations
 type BackInStockRepository struct {
-	db *gorm.DB
+	db  *gorm.DB
+	ttl time.Duration
 }
 
 // NewBackInStockRepository creates a new repository
 func NewBackInStockRepository(db *gorm.DB) *BackInStockRepository {
-	return &BackInStockRepository{db: db}
+	return &BackInStockRepository{db: db, ttl: domain.DefaultBackInStockSubscriptionTTL}
+}
+
+// WithSubscriptionTTL sets how long new and renewed subscriptions stay active
+func (r *BackInStockRepository) WithSubscriptionTTL(ttl time.Duration) *BackInStockRepository {
+	if ttl > 0 {
+		r.ttl = ttl
+	}
+	return r
 }
 
 // Subscribe creates a new subscription or returns existing one
@@ -46,8 +57,15 @@ func (r *BackInStockRepository) Subscribe(ctx context.Context, customerID uuid.U
 		query = query.Where("variant_id IS NULL")
 	}
 
+	expiresAt := time.Now().Add(r.ttl)
 	if err := query.First(&existing).Error; err == nil {
-		// Already subscribed, just return it
+		// Already subscribed; a pending subscription gets a fresh expiry
+		if !existing.IsNotified {
+			if err := r.db.WithContext(ctx).Model(&existing).Update("expires_at", expiresAt).Error; err != nil {
+				return nil, err
+			}
+			existing.ExpiresAt = &expiresAt
+		}
 		return &existing, nil
 	}
 
@@ -62,6 +80,7 @@ func (r *BackInStockRepository) Subscribe(ctx context.Context, customerID uuid.U
 		VariantSKU:   input.VariantSKU,
 		VariantName:  input.VariantName,
 		IsNotified:   false,
+		ExpiresAt:    &expiresAt,
 	}
 
 	if err := r.db.WithContext(ctx).Create(&subscription).Error; err != nil {
@@ -92,6 +111,15 @@ func (r *BackInStockRepository) UnsubscribeByID(ctx context.Context, customerID,
 		Delete(&domain.BackInStockSubscription{}).Error
 }
 
+// GetByID returns a subscription with its customer
+func (r *BackInStockRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.BackInStockSubscription, error) {
+	var subscription domain.BackInStockSubscription
+	if err := r.db.WithContext(ctx).Preload("Customer").First(&subscription, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &subscription, nil
+}
+
 // GetByCustomer returns all subscriptions for a customer
 func (r *BackInStockRepository) GetByCustomer(ctx context.Context, customerID uuid.UUID) ([]domain.BackInStockSubscription, error) {
 	var subscriptions []domain.BackInStockSubscription
@@ -102,18 +130,20 @@ func (r *BackInStockRepository) GetByCustomer(ctx context.Context, customerID uu
 	return subscriptions, err
 }
 
-// GetByProduct returns all pending subscriptions for a product
+// GetByProduct returns all pending, unexpired subscriptions for a product,
+// oldest first
 func (r *BackInStockRepository) GetByProduct(ctx context.Context, productID uuid.UUID, variantID *uuid.UUID) ([]domain.BackInStockSubscription, error) {
 	var subscriptions []domain.BackInStockSubscription
 	query := r.db.WithContext(ctx).
 		Preload("Customer").
-		Where("product_id = ? AND is_notified = false", productID)
+		Where("product_id = ? AND is_notified = false", productID).
+		Where("expires_at IS NULL OR expires_at > ?", time.Now())
 
 	if variantID != nil {
 		query = query.Where("variant_id = ?", variantID)
 	}
 
-	err := query.Find(&subscriptions).Error
+	err := query.Order("created_at ASC, id ASC").Find(&subscriptions).Error
 	return subscriptions, err
 }
 
@@ -135,7 +165,7 @@ func (r *BackInStockRepository) MarkAsNotified(ctx context.Context, subscription
 		Where("id = ?", subscriptionID).
 		Updates(map[string]interface{}{
 			"is_notified":          true,
-			"notification_sent_at": gorm.Expr("NOW()"),
+			"notification_sent_at": time.Now(),
 		}).Error
 }
 
@@ -146,10 +176,22 @@ func (r *BackInStockRepository) MarkMultipleAsNotified(ctx context.Context, subs
 		Where("id IN ?", subscriptionIDs).
 		Updates(map[string]interface{}{
 			"is_notified":          true,
-			"notification_sent_at": gorm.Expr("NOW()"),
+			"notification_sent_at": time.Now(),
 		}).Error
 }
 
+// CountNotifiedSince returns how many back-in-stock notifications a customer
+// has been sent since the given time
+func (r *BackInStockRepository) CountNotifiedSince(ctx context.Context, customerID uuid.UUID, since time.Time) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).
+		Model(&domain.BackInStockSubscription{}).
+		Unscoped().
+		Where("customer_id = ? AND is_notified = ? AND notification_sent_at >= ?", customerID, true, since).
+		Count(&count).Error
+	return count, err
+}
+
 // IsSubscribed checks if a customer is subscribed to a product
 func (r *BackInStockRepository) IsSubscribed(ctx context.Context, customerID, productID uuid.UUID, variantID *uuid.UUID) (bool, error) {
 	var count int64
@@ -167,6 +209,31 @@ func (r *BackInStockRepository) IsSubscribed(ctx context.Context, customerID, pr
 	return count > 0, err
 }
 
+// SubscribedTo checks which of the products the customer is subscribed to
+// with a single query, keyed by ProductRef.Key. Like IsSubscribed, a product
+// without a variant only matches a subscription to the whole product.
+func (r *BackInStockRepository) SubscribedTo(ctx context.Context, customerID uuid.UUID, refs []domain.ProductRef) (map[string]bool, error) {
+	var rows []domain.ProductRef
+	err := r.db.WithContext(ctx).
+		Model(&domain.BackInStockSubscription{}).
+		Select("product_id, variant_id").
+		Where("customer_id = ? AND product_id IN ?", customerID, domain.ProductIDs(refs)).
+		Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	found := make(map[string]bool, len(rows))
+	for _, row := range rows {
+		found[row.Key()] = true
+	}
+	subscribed := make(map[string]bool, len(refs))
+	for _, ref := range refs {
+		subscribed[ref.Key()] = found[ref.Key()]
+	}
+	return subscribed, nil
+}
+
 // GetStats returns statistics about subscriptions
 func (r *BackInStockRepository) GetStats(ctx context.Context) (*domain.BackInStockStats, error) {
 	var stats domain.BackInStockStats
@@ -221,10 +288,179 @@ func (r *BackInStockRepository) ListAll(ctx context.Context, page, limit int, pe
 	return subscriptions, total, err
 }
 
-// DeleteOldNotified deletes old notified subscriptions (cleanup)
-func (r *BackInStockRepository) DeleteOldNotified(ctx context.Context, olderThanDays int) (int64, error) {
+// ListAllAfter is the keyset-paginated ListAll: it returns the subscriptions
+// after the cursor (nil for the first page), newest first, and the next
+// page's cursor
+func (r *BackInStockRepository) ListAllAfter(ctx context.Context, after *domain.Cursor, limit int, pendingOnly bool) ([]domain.BackInStockSubscription, string, error) {
+	var subscriptions []domain.BackInStockSubscription
+
+	query := r.db.WithContext(ctx).Model(&domain.BackInStockSubscription{})
+	if pendingOnly {
+		query = query.Where("is_notified = false")
+	}
+
+	if err := keysetOrder(query, after, true, limit).Preload("Customer").Find(&subscriptions).Error; err != nil {
+		return nil, "", err
+	}
+	subscriptions, next := cursorPage(subscriptions, limit, func(s *domain.BackInStockSubscription) domain.Cursor {
+		return domain.Cursor{CreatedAt: s.CreatedAt, ID: s.ID}
+	})
+	return subscriptions, next, nil
+}
+
+// PendingAfter returns pending, unexpired subscriptions after the cursor
+// (nil for the first page), longest waiting first, and the next page's cursor
+func (r *BackInStockRepository) PendingAfter(ctx context.Context, after *domain.Cursor, limit int, now time.Time) ([]domain.BackInStockSubscription, string, error) {
+	var subscriptions []domain.BackInStockSubscription
+
+	query := r.db.WithContext(ctx).Model(&domain.BackInStockSubscription{}).
+		Where("is_notified = ?", false).
+		Where("expires_at IS NULL OR expires_at > ?", now)
+	if err := keysetOrder(query, after, false, limit).Preload("Customer").Find(&subscriptions).Error; err != nil {
+		return nil, "", err
+	}
+	subscriptions, next := cursorPage(subscriptions, limit, func(s *domain.BackInStockSubscription) domain.Cursor {
+		return domain.Cursor{CreatedAt: s.CreatedAt, ID: s.ID}
+	})
+	return subscriptions, next, nil
+}
+
+// Demand adds up the pending, unexpired subscriptions of customers and
+// confirmed guests per product and variant, with waits measured up to now,
+// and returns up to limit of them ranked by sort. The grouping, ranking and
+// limit run in the database, on the read replica when there is one.
+func (r *BackInStockRepository) Demand(ctx context.Context, sort domain.Sort, limit int, now time.Time) ([]domain.BackInStockDemand, error) {
+	column, desc, err := domain.BackInStockDemandSort.Resolve(sort)
+	if err != nil {
+		return nil, err
+	}
+	db := Replica(r.db.WithContext(ctx))
+
+	waiting := func(model interface{}, guest int) *gorm.DB {
+		return db.Model(model).
+			Select("product_id, variant_id, product_name, variant_sku, variant_name, "+
+				epochSeconds(db, "created_at")+" AS subscribed_at, ? AS guest", guest).
+			Where("is_notified = ?", false).
+			Where("expires_at IS NULL OR expires_at > ?", now)
+	}
+	subscriptions := db.Raw("? UNION ALL ?",
+		waiting(&domain.BackInStockSubscription{}, 0),
+		waiting(&domain.GuestBackInStockSubscription{}, 1).Where("confirmed_at IS NOT NULL"),
+	)
+
+	// Names are denormalized at subscription time and only blank for old
+	// subscriptions, so any non-blank one will do
+	var rows []struct {
+		ProductID   uuid.UUID
+		VariantID   *uuid.UUID
+		ProductName string
+		VariantSKU  string
+		VariantName string
+		Waiting     int
+		Guests      int
+		Oldest      float64
+		AverageWait float64
+	}
+	order := " ASC"
+	if desc {
+		order = " DESC"
+	}
+	err = db.Table("(?) AS waiting", subscriptions).
+		Select("product_id, variant_id, MAX(product_name) AS product_name, MAX(variant_sku) AS variant_sku, "+
+			"MAX(variant_name) AS variant_name, COUNT(*) AS waiting, SUM(guest) AS guests, "+
+			"MIN(subscribed_at) AS oldest, ? - AVG(subscribed_at) AS average_wait", now.Unix()).
+		Group("product_id, variant_id").
+		Order(column + order).
+		Order("waiting DESC").
+		Order("average_wait DESC").
+		Order("product_name").
+		Limit(limit).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	demand := make([]domain.BackInStockDemand, len(rows))
+	for i, row := range rows {
+		demand[i] = domain.BackInStockDemand{
+			ProductID:          row.ProductID,
+			VariantID:          row.VariantID,
+			ProductName:        row.ProductName,
+			VariantSKU:         row.VariantSKU,
+			VariantName:        row.VariantName,
+			Waiting:            row.Waiting,
+			Customers:          row.Waiting - row.Guests,
+			Guests:             row.Guests,
+			AverageWaitDays:    math.Round(row.AverageWait/(24*60*60)*10) / 10,
+			OldestSubscribedAt: time.Unix(int64(row.Oldest), 0),
+		}
+	}
+	return demand, nil
+}
+
+// epochSeconds is the SQL for column as seconds since the Unix epoch, for
+// PostgreSQL or, in tests, SQLite
+func epochSeconds(db *gorm.DB, column string) string {
+	if db.Dialector.Name() == "sqlite" {
+		return "CAST(strftime('%s', " + column + ") AS REAL)"
+	}
+	return "EXTRACT(EPOCH FROM " + column + ")"
+}
+
+// DefaultCleanupBatchSize is how many rows a batched cleanup deletes per
+// statement when not configured
+const DefaultCleanupBatchSize = 1000
+
+// CleanupBatch reports one batch of a batched cleanup
+type CleanupBatch struct {
+	Batch   int   `json:"batch"`
+	Deleted int64 `json:"deleted"`
+}
+
+// DeleteOldNotified deletes subscriptions notified more than olderThanDays
+// ago (cleanup), batchSize rows per statement so that no statement holds
+// locks on more than a batch. onBatch, if not nil, is called after each
+// batch. The rows deleted so far are returned with an error.
+func (r *BackInStockRepository) DeleteOldNotified(ctx context.Context, olderThanDays, batchSize int, onBatch func(CleanupBatch)) (int64, error) {
+	if batchSize <= 0 {
+		batchSize = DefaultCleanupBatchSize
+	}
+	db := r.db.WithContext(ctx)
+	cutoff := time.Now().AddDate(0, 0, -olderThanDays)
+
+	var deleted int64
+	for batch := 1; ; batch++ {
+		var ids []uuid.UUID
+		err := db.Model(&domain.BackInStockSubscription{}).
+			Where("is_notified = ? AND notification_sent_at < ?", true, cutoff).
+			Order("id").
+			Limit(batchSize).
+			Pluck("id", &ids).Error
+		if err != nil || len(ids) == 0 {
+			return deleted, err
+		}
+
+		result := db.Where("id IN ?", ids).Delete(&domain.BackInStockSubscription{})
+		if result.Error != nil {
+			return deleted, result.Error
+		}
+		deleted += result.RowsAffected
+		if onBatch != nil {
+			onBatch(CleanupBatch{Batch: batch, Deleted: result.RowsAffected})
+		}
+		if err := ctx.Err(); err != nil {
+			return deleted, err
+		}
+	}
+}
+
+// ExpirePending removes pending subscriptions whose expiry has passed.
+// Subscriptions created before expiry was tracked expire once they are older
+// than the repository's TTL.
+func (r *BackInStockRepository) ExpirePending(ctx context.Context, now time.Time) (int64, error) {
 	result := r.db.WithContext(ctx).
-		Where("is_notified = true AND notification_sent_at < NOW() - INTERVAL '? days'", olderThanDays).
+		Where("is_notified = ?", false).
+		Where("expires_at <= ? OR (expires_at IS NULL AND created_at <= ?)", now, now.Add(-r.ttl)).
 		Delete(&domain.BackInStockSubscription{})
 	return result.RowsAffected, result.Error
 }
@@ -0,0 +1,261 @@
+package persistence
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestBackInStockRepository_SubscribeSetsAndRenewsExpiry(t *testing.T) {
+	db := openTestDB(t, &domain.Customer{}, &domain.BackInStockSubscription{})
+	repo := NewBackInStockRepository(db).WithSubscriptionTTL(24 * time.Hour)
+	ctx := context.Background()
+	customerID, productID := uuid.New(), uuid.New()
+
+	subscription, err := repo.Subscribe(ctx, customerID, domain.BackInStockSubscribeInput{ProductID: productID.String()})
+	require.NoError(t, err)
+	require.NotNil(t, subscription.ExpiresAt)
+	assert.WithinDuration(t, time.Now().Add(24*time.Hour), *subscription.ExpiresAt, time.Minute)
+
+	require.NoError(t, db.Model(subscription).Update("expires_at", time.Now().Add(time.Hour)).Error)
+
+	renewed, err := repo.Subscribe(ctx, customerID, domain.BackInStockSubscribeInput{ProductID: productID.String()})
+	require.NoError(t, err)
+	assert.Equal(t, subscription.ID, renewed.ID)
+	assert.WithinDuration(t, time.Now().Add(24*time.Hour), *renewed.ExpiresAt, time.Minute)
+}
+
+func TestBackInStockRepository_ExpirePending(t *testing.T) {
+	db := openTestDB(t, &domain.Customer{}, &domain.BackInStockSubscription{})
+	repo := NewBackInStockRepository(db).WithSubscriptionTTL(24 * time.Hour)
+	ctx := context.Background()
+	productID := uuid.New()
+	now := time.Now()
+	past, future := now.Add(-time.Hour), now.Add(time.Hour)
+	sentAt := now.Add(-48 * time.Hour)
+
+	subscriptions := []domain.BackInStockSubscription{
+		{CustomerID: uuid.New(), ProductID: productID, ExpiresAt: &past},
+		{CustomerID: uuid.New(), ProductID: productID, ExpiresAt: &future},
+		{CustomerID: uuid.New(), ProductID: productID, IsNotified: true, NotificationSentAt: &sentAt, ExpiresAt: &past},
+		// Created before expiry was tracked
+		{CustomerID: uuid.New(), ProductID: productID, CreatedAt: now.Add(-48 * time.Hour)},
+		{CustomerID: uuid.New(), ProductID: productID, CreatedAt: now.Add(-time.Hour)},
+	}
+	require.NoError(t, db.Create(&subscriptions).Error)
+
+	// Expired subscriptions are skipped even before the job runs
+	pending, err := repo.GetByProduct(ctx, productID, nil)
+	require.NoError(t, err)
+	assert.Len(t, pending, 3)
+
+	expired, err := repo.ExpirePending(ctx, now)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), expired)
+
+	var remaining []uuid.UUID
+	require.NoError(t, db.Model(&domain.BackInStockSubscription{}).Pluck("id", &remaining).Error)
+	assert.ElementsMatch(t, []uuid.UUID{subscriptions[1].ID, subscriptions[2].ID, subscriptions[4].ID}, remaining)
+}
+
+func TestBackInStockRepository_DeleteOldNotified(t *testing.T) {
+	db := openTestDB(t, &domain.Customer{}, &domain.BackInStockSubscription{})
+	repo := NewBackInStockRepository(db)
+	ctx := context.Background()
+	old, recent := time.Now().AddDate(0, 0, -40), time.Now().AddDate(0, 0, -5)
+
+	subscriptions := []domain.BackInStockSubscription{
+		{CustomerID: uuid.New(), ProductID: uuid.New(), IsNotified: true, NotificationSentAt: &old},
+		{CustomerID: uuid.New(), ProductID: uuid.New(), IsNotified: true, NotificationSentAt: &recent},
+		{CustomerID: uuid.New(), ProductID: uuid.New(), CreatedAt: old},
+	}
+	require.NoError(t, db.Create(&subscriptions).Error)
+
+	deleted, err := repo.DeleteOldNotified(ctx, 30, 0, nil)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), deleted)
+
+	var remaining []uuid.UUID
+	require.NoError(t, db.Model(&domain.BackInStockSubscription{}).Pluck("id", &remaining).Error)
+	assert.ElementsMatch(t, []uuid.UUID{subscriptions[1].ID, subscriptions[2].ID}, remaining)
+}
+
+func TestBackInStockRepository_DeleteOldNotifiedInBatches(t *testing.T) {
+	db := openTestDB(t, &domain.Customer{}, &domain.BackInStockSubscription{})
+	repo := NewBackInStockRepository(db)
+	old := time.Now().AddDate(0, 0, -40)
+
+	subscriptions := make([]domain.BackInStockSubscription, 5)
+	for i := range subscriptions {
+		subscriptions[i] = domain.BackInStockSubscription{CustomerID: uuid.New(), ProductID: uuid.New(), IsNotified: true, NotificationSentAt: &old}
+	}
+	require.NoError(t, db.Create(&subscriptions).Error)
+
+	var batches []CleanupBatch
+	deleted, err := repo.DeleteOldNotified(context.Background(), 30, 2, func(b CleanupBatch) {
+		batches = append(batches, b)
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), deleted)
+	assert.Equal(t, []CleanupBatch{{Batch: 1, Deleted: 2}, {Batch: 2, Deleted: 2}, {Batch: 3, Deleted: 1}}, batches)
+}
+
+func TestBackInStockRepository_CountNotifiedSince(t *testing.T) {
+	db := openTestDB(t, &domain.Customer{}, &domain.BackInStockSubscription{})
+	repo := NewBackInStockRepository(db)
+	ctx := context.Background()
+	customerID := uuid.New()
+	recent, old := time.Now().Add(-10*time.Minute), time.Now().Add(-2*time.Hour)
+
+	require.NoError(t, db.Create(&[]domain.BackInStockSubscription{
+		{CustomerID: customerID, ProductID: uuid.New(), IsNotified: true, NotificationSentAt: &recent},
+		{CustomerID: customerID, ProductID: uuid.New(), IsNotified: true, NotificationSentAt: &old},
+		{CustomerID: customerID, ProductID: uuid.New()},
+		{CustomerID: uuid.New(), ProductID: uuid.New(), IsNotified: true, NotificationSentAt: &recent},
+	}).Error)
+
+	count, err := repo.CountNotifiedSince(ctx, customerID, time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+}
+
+func TestBackInStockRepository_GetByIDNotification(t *testing.T) {
+	db := openTestDB(t, &domain.Customer{}, &domain.BackInStockSubscription{})
+	repo := NewBackInStockRepository(db)
+	ctx := context.Background()
+
+	customer := &domain.Customer{Email: "aisyah@example.com", FirstName: "Aisyah", LastName: "Rahman"}
+	require.NoError(t, db.Create(customer).Error)
+	variantID := uuid.New()
+	subscription := &domain.BackInStockSubscription{
+		CustomerID:  customer.ID,
+		ProductID:   uuid.New(),
+		VariantID:   &variantID,
+		ProductName: "Kebaya Nyonya",
+		VariantName: "M / Merah",
+	}
+	require.NoError(t, db.Create(subscription).Error)
+
+	found, err := repo.GetByID(ctx, subscription.ID)
+	require.NoError(t, err)
+
+	notification := found.Notification(4)
+	assert.Equal(t, "aisyah@example.com", notification.CustomerEmail)
+	assert.Equal(t, "Aisyah Rahman", notification.CustomerName)
+	assert.Equal(t, variantID.String(), notification.VariantID)
+	assert.Equal(t, 4, notification.StockQuantity)
+	assert.False(t, notification.IsTest)
+
+	_, err = repo.GetByID(ctx, uuid.New())
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
+
+func TestBackInStockRepository_ListAllAfter(t *testing.T) {
+	db := openTestDB(t, &domain.Customer{}, &domain.BackInStockSubscription{})
+	repo := NewBackInStockRepository(db)
+	ctx := context.Background()
+	productID := uuid.New()
+
+	base := time.Now().Add(-time.Hour)
+	var ids []uuid.UUID
+	for i := 0; i < 5; i++ {
+		subscription := domain.BackInStockSubscription{
+			CustomerID: uuid.New(),
+			ProductID:  productID,
+			IsNotified: i == 2,
+			CreatedAt:  base.Add(time.Duration(i) * time.Minute),
+		}
+		require.NoError(t, db.Create(&subscription).Error)
+		ids = append(ids, subscription.ID)
+	}
+
+	page, next, err := repo.ListAllAfter(ctx, nil, 2, false)
+	require.NoError(t, err)
+	require.Len(t, page, 2)
+	assert.Equal(t, []uuid.UUID{ids[4], ids[3]}, []uuid.UUID{page[0].ID, page[1].ID})
+
+	after, err := domain.DecodeCursor(next)
+	require.NoError(t, err)
+	page, next, err = repo.ListAllAfter(ctx, after, 2, true)
+	require.NoError(t, err)
+	require.Len(t, page, 2, "the notified subscription is skipped")
+	assert.Equal(t, []uuid.UUID{ids[1], ids[0]}, []uuid.UUID{page[0].ID, page[1].ID})
+	assert.Empty(t, next)
+}
+
+func TestBackInStockRepository_SubscribedTo(t *testing.T) {
+	db := openTestDB(t, &domain.Customer{}, &domain.BackInStockSubscription{})
+	repo := NewBackInStockRepository(db)
+	ctx := context.Background()
+
+	customerID := uuid.New()
+	productID, variantProductID, otherID := uuid.New(), uuid.New(), uuid.New()
+	variantID := uuid.New()
+	require.NoError(t, db.Create(&domain.BackInStockSubscription{CustomerID: customerID, ProductID: productID}).Error)
+	require.NoError(t, db.Create(&domain.BackInStockSubscription{CustomerID: customerID, ProductID: variantProductID, VariantID: &variantID}).Error)
+	require.NoError(t, db.Create(&domain.BackInStockSubscription{CustomerID: uuid.New(), ProductID: otherID}).Error)
+
+	subscribed, err := repo.SubscribedTo(ctx, customerID, []domain.ProductRef{
+		{ProductID: productID},
+		{ProductID: variantProductID},
+		{ProductID: variantProductID, VariantID: &variantID},
+		{ProductID: otherID},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]bool{
+		productID.String():                                   true,
+		variantProductID.String():                            false,
+		variantProductID.String() + ":" + variantID.String(): true,
+		otherID.String():                                     false,
+	}, subscribed)
+}
+
+func TestBackInStockRepository_Demand(t *testing.T) {
+	db := openTestDB(t, &domain.Customer{}, &domain.BackInStockSubscription{}, &domain.GuestBackInStockSubscription{})
+	repo := NewBackInStockRepository(db)
+	ctx := context.Background()
+	now := time.Now()
+	days := func(n int) time.Time { return now.Add(-time.Duration(n) * 24 * time.Hour) }
+	past := now.Add(-time.Hour)
+
+	popular, patient := uuid.New(), uuid.New()
+	require.NoError(t, db.Create(&[]domain.BackInStockSubscription{
+		{CustomerID: uuid.New(), ProductID: popular, ProductName: "Baju Kurung", CreatedAt: days(2)},
+		{CustomerID: uuid.New(), ProductID: popular, ProductName: "Baju Kurung", CreatedAt: days(4)},
+		{CustomerID: uuid.New(), ProductID: patient, ProductName: "Songket", CreatedAt: days(30)},
+		// Not waiting any more
+		{CustomerID: uuid.New(), ProductID: patient, ProductName: "Songket", CreatedAt: days(1), IsNotified: true},
+		{CustomerID: uuid.New(), ProductID: patient, ProductName: "Songket", CreatedAt: days(1), ExpiresAt: &past},
+	}).Error)
+	require.NoError(t, db.Create(&[]domain.GuestBackInStockSubscription{
+		{Email: "a@example.com", TokenHash: "a", ProductID: popular, ProductName: "Baju Kurung", CreatedAt: days(6), ConfirmedAt: &now},
+		{Email: "b@example.com", TokenHash: "b", ProductID: popular, ProductName: "Baju Kurung", CreatedAt: days(1)},
+	}).Error)
+
+	ranked, err := repo.Demand(ctx, domain.Sort{}, 10, now)
+	require.NoError(t, err)
+	require.Len(t, ranked, 2)
+	assert.Equal(t, popular, ranked[0].ProductID)
+	assert.Equal(t, "Baju Kurung", ranked[0].ProductName)
+	assert.Equal(t, 3, ranked[0].Waiting, "the unconfirmed guest isn't counted")
+	assert.Equal(t, 2, ranked[0].Customers)
+	assert.Equal(t, 1, ranked[0].Guests)
+	assert.Equal(t, 4.0, ranked[0].AverageWaitDays)
+	assert.WithinDuration(t, days(6), ranked[0].OldestSubscribedAt, time.Second)
+	assert.Equal(t, 1, ranked[1].Waiting)
+
+	ranked, err = repo.Demand(ctx, domain.Sort{Field: domain.BackInStockDemandByWait, Direction: domain.SortDesc}, 1, now)
+	require.NoError(t, err)
+	require.Len(t, ranked, 1)
+	assert.Equal(t, patient, ranked[0].ProductID)
+	assert.Equal(t, 30.0, ranked[0].AverageWaitDays)
+
+	_, err = repo.Demand(ctx, domain.Sort{Field: "product_name", Direction: domain.SortAsc}, 10, now)
+	assert.ErrorIs(t, err, domain.ErrInvalidSort)
+}
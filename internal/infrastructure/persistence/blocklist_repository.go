@@ -0,0 +1,182 @@
+package persistence
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/Ecom-micro-template/service-customer/internal/domain/shared"
+	"gorm.io/gorm"
+)
+
+// BlocklistRepository stores flagged emails, phone numbers and addresses
+// and checks orders against them
+type BlocklistRepository struct {
+	db *gorm.DB
+}
+
+// NewBlocklistRepository creates a new blocklist repository
+func NewBlocklistRepository(db *gorm.DB) *BlocklistRepository {
+	return &BlocklistRepository{db: db}
+}
+
+// List returns a page of entries, newest first. Expired entries are left
+// out unless the filter includes them.
+func (r *BlocklistRepository) List(ctx context.Context, filter domain.BlocklistFilter, now time.Time) ([]domain.BlocklistEntry, int64, error) {
+	var entries []domain.BlocklistEntry
+	var total int64
+
+	query := r.db.WithContext(ctx).Model(&domain.BlocklistEntry{})
+	if filter.Kind != "" {
+		query = query.Where("kind = ?", filter.Kind)
+	}
+	if !filter.IncludeExpired {
+		query = query.Where("expires_at IS NULL OR expires_at > ?", now)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err := query.Order("created_at DESC").
+		Offset((filter.Page - 1) * filter.Limit).
+		Limit(filter.Limit).
+		Find(&entries).Error
+	return entries, total, err
+}
+
+// Save flags entry's value. Flagging a value that is already on the
+// blocklist replaces that entry, so the admin's reason and expiry apply and
+// unblocking the customer it came from no longer removes it.
+func (r *BlocklistRepository) Save(ctx context.Context, entry *domain.BlocklistEntry) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var existing domain.BlocklistEntry
+		err := tx.Where("kind = ? AND value = ?", entry.Kind, entry.Value).First(&existing).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return tx.Create(entry).Error
+		}
+		if err != nil {
+			return err
+		}
+
+		entry.ID = existing.ID
+		entry.CreatedAt = existing.CreatedAt
+		return tx.Select("*").Omit("created_at").Save(entry).Error
+	})
+}
+
+// Delete removes an entry
+func (r *BlocklistRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	result := r.db.WithContext(ctx).Delete(&domain.BlocklistEntry{}, "id = ?", id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// Check matches an order's customer, email, phone and addresses against
+// the blocklist at now
+func (r *BlocklistRepository) Check(ctx context.Context, req *domain.FraudCheckRequest, now time.Time) (*domain.FraudCheckResult, error) {
+	db := r.db.WithContext(ctx)
+	result := &domain.FraudCheckResult{Matches: []domain.FraudCheckMatch{}}
+
+	if req.CustomerID != nil {
+		var customer domain.Customer
+		err := db.Select("id", "status").Where("id = ?", *req.CustomerID).First(&customer).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+		if err == nil && customer.Status == shared.StatusBlocked {
+			result.Matches = append(result.Matches, domain.FraudCheckMatch{
+				Kind:   domain.FraudCheckKindCustomer,
+				Field:  "customer_id",
+				Reason: "Customer is blocked",
+			})
+		}
+	}
+
+	// The request fields by the kind and value they're looked up with
+	type lookup struct{ kind, value, field string }
+	var lookups []lookup
+	if email := domain.NormalizeDuplicateEmail(req.Email); email != "" {
+		lookups = append(lookups, lookup{domain.BlocklistKindEmail, email, "email"})
+	}
+	if phone := domain.PhoneBlindIndex(req.Phone); phone != "" {
+		lookups = append(lookups, lookup{domain.BlocklistKindPhone, phone, "phone"})
+	}
+	if req.ShippingAddress != nil {
+		lookups = append(lookups, lookup{domain.BlocklistKindAddress, domain.AddressFingerprint(*req.ShippingAddress), "shipping_address"})
+	}
+	if req.BillingAddress != nil {
+		lookups = append(lookups, lookup{domain.BlocklistKindAddress, domain.AddressFingerprint(*req.BillingAddress), "billing_address"})
+	}
+
+	for _, l := range lookups {
+		var entry domain.BlocklistEntry
+		err := db.Where("kind = ? AND value = ?", l.kind, l.value).
+			Where("expires_at IS NULL OR expires_at > ?", now).
+			First(&entry).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		entryID := entry.ID
+		result.Matches = append(result.Matches, domain.FraudCheckMatch{
+			Kind:      entry.Kind,
+			Field:     l.field,
+			Reason:    entry.Reason,
+			EntryID:   &entryID,
+			ExpiresAt: entry.ExpiresAt,
+		})
+	}
+
+	result.Flagged = len(result.Matches) > 0
+	return result, nil
+}
+
+// blockCustomerIdentifiers flags a customer's email, phone and addresses
+// when the customer is blocked. Values already flagged keep their entry
+// unless it has expired.
+func blockCustomerIdentifiers(tx *gorm.DB, customer *domain.Customer, blockedBy *uuid.UUID, now time.Time) error {
+	var addresses []domain.Address
+	if err := tx.Where("user_id = ?", customer.ID).Find(&addresses).Error; err != nil {
+		return err
+	}
+
+	for _, entry := range domain.BlocklistEntriesFor(customer, addresses, "Customer blocked", blockedBy) {
+		var existing domain.BlocklistEntry
+		err := tx.Where("kind = ? AND value = ?", entry.Kind, entry.Value).First(&existing).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			if err := tx.Create(&entry).Error; err != nil {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if existing.Active(now) {
+			continue
+		}
+		entry.ID = existing.ID
+		entry.CreatedAt = existing.CreatedAt
+		if err := tx.Select("*").Omit("created_at").Save(&entry).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// unblockCustomerIdentifiers removes the entries added when the customer
+// was blocked. Entries an admin added or took over stay.
+func unblockCustomerIdentifiers(tx *gorm.DB, customerID uuid.UUID) error {
+	return tx.Where("source = ? AND customer_id = ?", domain.BlocklistSourceCustomerBlocked, customerID).
+		Delete(&domain.BlocklistEntry{}).Error
+}
@@ -0,0 +1,133 @@
+package persistence
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/Ecom-micro-template/service-customer/internal/domain/shared"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestBlocklistRepository_Check(t *testing.T) {
+	db := openTestDB(t, &domain.Customer{}, &domain.BlocklistEntry{})
+	repo := NewBlocklistRepository(db)
+	ctx := context.Background()
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	add := func(req domain.CreateBlocklistEntryRequest) *domain.BlocklistEntry {
+		t.Helper()
+		entry, err := req.Entry(now)
+		require.NoError(t, err)
+		require.NoError(t, repo.Save(ctx, entry))
+		return entry
+	}
+	tomorrow := now.Add(24 * time.Hour)
+	email := add(domain.CreateBlocklistEntryRequest{Kind: domain.BlocklistKindEmail, Value: "Jo.Tan@gmail.com", Reason: "Chargebacks"})
+	add(domain.CreateBlocklistEntryRequest{Kind: domain.BlocklistKindPhone, Value: "012-345 6789", Reason: "Fake orders", ExpiresAt: &tomorrow})
+	address := add(domain.CreateBlocklistEntryRequest{Kind: domain.BlocklistKindAddress, Reason: "Drop address", Address: &domain.BlocklistAddress{
+		AddressLine1: "12, Jalan Ampang", City: "Kuala Lumpur", Postcode: "50450", Country: "Malaysia",
+	}})
+	assert.Equal(t, "12, Jalan Ampang, Kuala Lumpur, 50450, Malaysia", address.Label)
+
+	// Flagging a value again replaces the entry
+	again := add(domain.CreateBlocklistEntryRequest{Kind: domain.BlocklistKindEmail, Value: "jotan+shop@googlemail.com", Reason: "Stolen cards"})
+	assert.Equal(t, email.ID, again.ID)
+
+	blocked := &domain.Customer{Email: "blocked@example.com", Status: shared.StatusBlocked}
+	require.NoError(t, db.Create(blocked).Error)
+
+	result, err := repo.Check(ctx, &domain.FraudCheckRequest{
+		CustomerID: &blocked.ID,
+		Email:      "jo.tan+x@gmail.com",
+		Phone:      "+60 12 345 6789",
+		ShippingAddress: &domain.BlocklistAddress{
+			AddressLine1: "12 JALAN AMPANG", City: "kuala lumpur", Postcode: "50450", Country: "MY",
+		},
+	}, now)
+	require.NoError(t, err)
+	assert.True(t, result.Flagged)
+	fields := make(map[string]domain.FraudCheckMatch)
+	for _, m := range result.Matches {
+		fields[m.Field] = m
+	}
+	assert.Len(t, fields, 4)
+	assert.Equal(t, domain.FraudCheckKindCustomer, fields["customer_id"].Kind)
+	assert.Equal(t, "Stolen cards", fields["email"].Reason)
+	assert.Equal(t, address.ID, *fields["shipping_address"].EntryID)
+
+	// Expired entries stop matching and leave the default list
+	result, err = repo.Check(ctx, &domain.FraudCheckRequest{Phone: "0123456789", Email: "someone@example.com"}, tomorrow)
+	require.NoError(t, err)
+	assert.False(t, result.Flagged)
+	assert.Empty(t, result.Matches)
+
+	entries, total, err := repo.List(ctx, domain.BlocklistFilter{Page: 1, Limit: 10}, tomorrow)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), total)
+	assert.Len(t, entries, 2)
+	_, total, err = repo.List(ctx, domain.BlocklistFilter{Kind: domain.BlocklistKindPhone, IncludeExpired: true, Page: 1, Limit: 10}, tomorrow)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+
+	require.NoError(t, repo.Delete(ctx, email.ID))
+	assert.ErrorIs(t, repo.Delete(ctx, email.ID), gorm.ErrRecordNotFound)
+}
+
+func TestCreateBlocklistEntryRequest_Entry(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	past := now.Add(-time.Hour)
+
+	for _, tc := range []struct {
+		req  domain.CreateBlocklistEntryRequest
+		want error
+	}{
+		{domain.CreateBlocklistEntryRequest{Kind: domain.BlocklistKindEmail, Value: "not-an-email"}, domain.ErrInvalidBlocklistEmail},
+		{domain.CreateBlocklistEntryRequest{Kind: domain.BlocklistKindPhone, Value: "123"}, domain.ErrInvalidBlocklistPhone},
+		{domain.CreateBlocklistEntryRequest{Kind: domain.BlocklistKindAddress}, domain.ErrBlocklistAddressMissing},
+		{domain.CreateBlocklistEntryRequest{Kind: domain.BlocklistKindEmail, Value: "a@example.com", ExpiresAt: &past}, domain.ErrBlocklistExpiryPast},
+	} {
+		_, err := tc.req.Entry(now)
+		assert.ErrorIs(t, err, tc.want, tc.req.Kind)
+	}
+}
+
+func TestCustomerRepository_UpdateBlocklistsBlockedCustomer(t *testing.T) {
+	db := openTestDB(t, &domain.Customer{}, &domain.CustomerActivity{}, &domain.Address{}, &domain.BlocklistEntry{})
+	repo := NewCustomerRepository(db, DefaultQueryTimeouts())
+	blocklist := NewBlocklistRepository(db)
+	ctx := context.Background()
+
+	customer := &domain.Customer{Email: "fraud@example.com", Phone: "012-999 8888", Status: shared.StatusActive}
+	require.NoError(t, db.Create(customer).Error)
+	require.NoError(t, db.Create(&domain.Address{UserID: customer.ID, RecipientName: "Fraud", Phone: "0129998888",
+		AddressLine1: "1 Jalan Tun Razak", City: "Kuala Lumpur", State: "WP", Postcode: "50400", Country: "Malaysia"}).Error)
+
+	// An admin already flagged the email; that entry is kept as it is
+	entry, err := (&domain.CreateBlocklistEntryRequest{Kind: domain.BlocklistKindEmail, Value: "fraud@example.com", Reason: "Chargebacks"}).Entry(time.Now())
+	require.NoError(t, err)
+	require.NoError(t, blocklist.Save(ctx, entry))
+
+	setStatus := func(status shared.CustomerStatus) {
+		t.Helper()
+		_, err := repo.Update(ctx, customer.ID, &domain.UpdateCustomerRequest{Status: &status})
+		require.NoError(t, err)
+	}
+
+	setStatus(shared.StatusBlocked)
+	var entries []domain.BlocklistEntry
+	require.NoError(t, db.Order("kind").Find(&entries).Error)
+	require.Len(t, entries, 3)
+	assert.Equal(t, domain.BlocklistKindAddress, entries[0].Kind)
+	assert.Equal(t, domain.BlocklistSourceCustomerBlocked, entries[0].Source)
+	assert.Equal(t, domain.BlocklistSourceAdmin, entries[1].Source)
+	assert.Equal(t, "60129998888", entries[2].Value)
+
+	setStatus(shared.StatusActive)
+	require.NoError(t, db.Find(&entries).Error)
+	require.Len(t, entries, 1)
+	assert.Equal(t, entry.ID, entries[0].ID)
+}
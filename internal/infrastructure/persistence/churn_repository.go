@@ -0,0 +1,77 @@
+package persistence
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"gorm.io/gorm"
+)
+
+// ChurnRepository stores customers' churn risk
+type ChurnRepository struct {
+	db *gorm.DB
+}
+
+// NewChurnRepository creates a new churn repository
+func NewChurnRepository(db *gorm.DB) *ChurnRepository {
+	return &ChurnRepository{db: db}
+}
+
+// Flag updates the churn risk of up to limit customers after the given
+// customer ID at now, and returns the last customer ID handled, or uuid.Nil
+// once every customer has been handled. Customers who become high risk get
+// an activity on their timeline in the same transaction.
+func (r *ChurnRepository) Flag(ctx context.Context, after uuid.UUID, limit int, policy domain.ChurnPolicy, now time.Time) (uuid.UUID, domain.ChurnRiskResult, error) {
+	var result domain.ChurnRiskResult
+	db := r.db.WithContext(ctx)
+
+	var customers []domain.Customer
+	if err := db.Select("id", "total_orders", "last_order_at", "churn_risk").
+		Where("id > ?", after).
+		Order("id").
+		Limit(limit).
+		Find(&customers).Error; err != nil {
+		return uuid.Nil, result, err
+	}
+	if len(customers) == 0 {
+		return uuid.Nil, result, nil
+	}
+
+	for i := range customers {
+		c := &customers[i]
+		result.Customers++
+		risk := policy.Risk(c, now)
+		if risk == c.ChurnRisk {
+			continue
+		}
+
+		// Derived like the order totals' RFM scores, so the version isn't bumped
+		updates := map[string]interface{}{"churn_risk": risk}
+		flagged := risk == domain.ChurnRiskHigh
+		if flagged {
+			updates["churn_flagged_at"] = now
+			result.Flagged++
+		} else if c.ChurnRisk == domain.ChurnRiskHigh {
+			result.Cleared++
+		}
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Model(&domain.Customer{}).Where("id = ?", c.ID).UpdateColumns(updates).Error; err != nil {
+				return err
+			}
+			if !flagged {
+				return nil
+			}
+			return tx.Create(&domain.CustomerActivity{
+				CustomerID: c.ID,
+				Type:       domain.ActivityTypeChurnRisk,
+				Title:      policy.ChurnActivityTitle(c),
+			}).Error
+		})
+		if err != nil {
+			return uuid.Nil, result, err
+		}
+	}
+	return customers[len(customers)-1].ID, result, nil
+}
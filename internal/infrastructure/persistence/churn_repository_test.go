@@ -0,0 +1,82 @@
+package persistence
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChurnRepository_Flag(t *testing.T) {
+	db := openTestDB(t, &domain.Customer{}, &domain.CustomerActivity{})
+	repo := NewChurnRepository(db)
+	ctx := context.Background()
+
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	daysAgo := func(days int) *time.Time {
+		at := now.AddDate(0, 0, -days)
+		return &at
+	}
+	lapsed := domain.Customer{Email: "lapsed@example.com", TotalOrders: 5, LastOrderAt: daysAgo(120), Version: 1}
+	quiet := domain.Customer{Email: "quiet@example.com", TotalOrders: 3, LastOrderAt: daysAgo(50), Version: 1}
+	regular := domain.Customer{Email: "regular@example.com", TotalOrders: 8, LastOrderAt: daysAgo(5), Version: 1}
+	oneOff := domain.Customer{Email: "oneoff@example.com", TotalOrders: 1, LastOrderAt: daysAgo(300), Version: 1}
+	for _, c := range []*domain.Customer{&lapsed, &quiet, &regular, &oneOff} {
+		require.NoError(t, db.Create(c).Error)
+	}
+
+	flag := func(at time.Time) domain.ChurnRiskResult {
+		t.Helper()
+		var total domain.ChurnRiskResult
+		after := uuid.Nil
+		for pages := 0; ; pages++ {
+			require.Less(t, pages, 4)
+			next, page, err := repo.Flag(ctx, after, 3, domain.DefaultChurnPolicy, at)
+			require.NoError(t, err)
+			total.Customers += page.Customers
+			total.Flagged += page.Flagged
+			total.Cleared += page.Cleared
+			if next == uuid.Nil {
+				return total
+			}
+			after = next
+		}
+	}
+	assert.Equal(t, domain.ChurnRiskResult{Customers: 4, Flagged: 1}, flag(now))
+
+	risk := func(id uuid.UUID) domain.Customer {
+		var customer domain.Customer
+		require.NoError(t, db.First(&customer, "id = ?", id).Error)
+		return customer
+	}
+	c := risk(lapsed.ID)
+	assert.Equal(t, domain.ChurnRiskHigh, c.ChurnRisk)
+	require.NotNil(t, c.ChurnFlaggedAt)
+	assert.Equal(t, int64(1), c.Version)
+	assert.Equal(t, domain.ChurnRiskMedium, risk(quiet.ID).ChurnRisk)
+	assert.Equal(t, domain.ChurnRiskLow, risk(regular.ID).ChurnRisk)
+	assert.Empty(t, risk(oneOff.ID).ChurnRisk)
+
+	var activities []domain.CustomerActivity
+	require.NoError(t, db.Where("type = ?", domain.ActivityTypeChurnRisk).Find(&activities).Error)
+	require.Len(t, activities, 1)
+	assert.Equal(t, lapsed.ID, activities[0].CustomerID)
+	assert.Equal(t, "Flagged as churn risk: 5 orders, none in 90 days", activities[0].Title)
+
+	// Flagged customers are flagged once; an order clears the flag
+	require.NoError(t, db.Model(&domain.Customer{}).Where("id = ?", lapsed.ID).
+		UpdateColumns(map[string]interface{}{"total_orders": 6, "last_order_at": now}).Error)
+	assert.Equal(t, domain.ChurnRiskResult{Customers: 4, Cleared: 1}, flag(now))
+	assert.Equal(t, domain.ChurnRiskLow, risk(lapsed.ID).ChurnRisk)
+
+	// Customers can be listed by churn risk
+	customers := NewCustomerRepository(db, DefaultQueryTimeouts())
+	list, _, err := customers.ListAdmin(ctx, domain.CustomerListFilter{Page: 1, Limit: 10, ChurnRisk: domain.ChurnRiskMedium})
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	assert.Equal(t, "quiet@example.com", list[0].Email)
+}
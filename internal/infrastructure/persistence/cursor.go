@@ -0,0 +1,31 @@
+package persistence
+
+import (
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"gorm.io/gorm"
+)
+
+// keysetOrder orders a cursor-paginated query by (created_at, id) and, given a
+// cursor, keeps only the rows after it. It fetches one row more than limit so
+// cursorPage can tell whether there is a next page.
+func keysetOrder(query *gorm.DB, after *domain.Cursor, desc bool, limit int) *gorm.DB {
+	op, dir := ">", "ASC"
+	if desc {
+		op, dir = "<", "DESC"
+	}
+	if after != nil {
+		query = query.Where("created_at "+op+" ? OR (created_at = ? AND id "+op+" ?)",
+			after.CreatedAt, after.CreatedAt, after.ID)
+	}
+	return query.Order("created_at " + dir).Order("id " + dir).Limit(limit + 1)
+}
+
+// cursorPage trims a keysetOrder result to limit rows and returns the cursor
+// of the next page, or "" on the last page
+func cursorPage[T any](rows []T, limit int, key func(*T) domain.Cursor) ([]T, string) {
+	if len(rows) <= limit {
+		return rows, ""
+	}
+	rows = rows[:limit]
+	return rows, key(&rows[limit-1]).Encode()
+}
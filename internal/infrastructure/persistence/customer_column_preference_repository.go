@@ -0,0 +1,52 @@
+package persistence
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CustomerColumnPreferenceRepository stores each admin's customer list columns
+type CustomerColumnPreferenceRepository struct {
+	db *gorm.DB
+}
+
+// NewCustomerColumnPreferenceRepository creates a new column preference repository
+func NewCustomerColumnPreferenceRepository(db *gorm.DB) *CustomerColumnPreferenceRepository {
+	return &CustomerColumnPreferenceRepository{db: db}
+}
+
+// Columns returns the admin's saved columns, or nil if they haven't chosen any
+func (r *CustomerColumnPreferenceRepository) Columns(ctx context.Context, adminID uuid.UUID) ([]string, error) {
+	var pref domain.CustomerColumnPreference
+	err := r.db.WithContext(ctx).Where("admin_id = ?", adminID).First(&pref).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return pref.Columns, nil
+}
+
+// Save replaces the admin's saved columns
+func (r *CustomerColumnPreferenceRepository) Save(ctx context.Context, adminID uuid.UUID, columns []string) error {
+	return r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "admin_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"columns", "updated_at"}),
+		}).
+		Create(&domain.CustomerColumnPreference{AdminID: adminID, Columns: columns, UpdatedAt: time.Now()}).Error
+}
+
+// Reset forgets the admin's saved columns so the defaults apply again
+func (r *CustomerColumnPreferenceRepository) Reset(ctx context.Context, adminID uuid.UUID) error {
+	return r.db.WithContext(ctx).
+		Where("admin_id = ?", adminID).
+		Delete(&domain.CustomerColumnPreference{}).Error
+}
@@ -0,0 +1,79 @@
+package persistence
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/Ecom-micro-template/service-customer/internal/domain/shared"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCustomerColumnPreferenceRepository(t *testing.T) {
+	db := openTestDB(t, &domain.CustomerColumnPreference{})
+	repo := NewCustomerColumnPreferenceRepository(db)
+	ctx := context.Background()
+	adminID := uuid.New()
+
+	columns, err := repo.Columns(ctx, adminID)
+	require.NoError(t, err)
+	assert.Nil(t, columns, "no preference until one is saved")
+
+	require.NoError(t, repo.Save(ctx, adminID, []string{"email", "tags"}))
+	require.NoError(t, repo.Save(ctx, adminID, []string{"tags", "email", "phone"}))
+	columns, err = repo.Columns(ctx, adminID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"tags", "email", "phone"}, columns)
+
+	other, err := repo.Columns(ctx, uuid.New())
+	require.NoError(t, err)
+	assert.Nil(t, other)
+
+	require.NoError(t, repo.Reset(ctx, adminID))
+	columns, err = repo.Columns(ctx, adminID)
+	require.NoError(t, err)
+	assert.Nil(t, columns)
+}
+
+func TestParseCustomerColumns(t *testing.T) {
+	columns, err := domain.ParseCustomerColumns([]string{" tags", "email", "tags", ""})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"tags", "email"}, columns)
+
+	_, err = domain.ParseCustomerColumns([]string{"email", "password"})
+	assert.ErrorIs(t, err, domain.ErrUnknownCustomerColumn)
+
+	_, err = domain.ParseCustomerColumns([]string{" "})
+	assert.ErrorIs(t, err, domain.ErrNoCustomerColumns)
+
+	for _, key := range append(domain.DefaultCustomerColumns, domain.CustomerSortFields...) {
+		_, ok := domain.LookupCustomerColumn(key)
+		assert.True(t, ok, key)
+	}
+}
+
+func TestCustomerColumn_Value(t *testing.T) {
+	created := time.Date(2026, 3, 4, 5, 6, 7, 0, time.UTC)
+	customer := domain.Customer{
+		Email:      "jo@example.com",
+		Status:     shared.StatusActive,
+		TotalSpent: 12.5,
+		Tags:       []string{"vip", "wholesale"},
+		CreatedAt:  created,
+	}
+	want := map[string]string{
+		"email":       "jo@example.com",
+		"status":      "active",
+		"total_spent": "12.50",
+		"tags":        "vip, wholesale",
+		"created_at":  "2026-03-04T05:06:07Z",
+	}
+	for key, value := range want {
+		col, ok := domain.LookupCustomerColumn(key)
+		require.True(t, ok, key)
+		assert.Equal(t, value, col.Value(&customer), key)
+	}
+}
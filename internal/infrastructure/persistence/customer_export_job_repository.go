@@ -0,0 +1,107 @@
+package persistence
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"gorm.io/gorm"
+)
+
+// CustomerExportJobRepository stores background customer export jobs
+type CustomerExportJobRepository struct {
+	db *gorm.DB
+}
+
+// NewCustomerExportJobRepository creates a new customer export job repository
+func NewCustomerExportJobRepository(db *gorm.DB) *CustomerExportJobRepository {
+	return &CustomerExportJobRepository{db: db}
+}
+
+// Create queues a job
+func (r *CustomerExportJobRepository) Create(ctx context.Context, job *domain.CustomerExportJob) error {
+	job.Status = domain.CustomerExportPending
+	return r.db.WithContext(ctx).Create(job).Error
+}
+
+// Get retrieves a job
+func (r *CustomerExportJobRepository) Get(ctx context.Context, id uuid.UUID) (*domain.CustomerExportJob, error) {
+	var job domain.CustomerExportJob
+	if err := r.db.WithContext(ctx).Where("id = ?", id).First(&job).Error; err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// Runnable returns the queued jobs, and running jobs that haven't reported
+// progress since staleBefore because their worker went away, oldest first
+func (r *CustomerExportJobRepository) Runnable(ctx context.Context, staleBefore time.Time, limit int) ([]domain.CustomerExportJob, error) {
+	var jobs []domain.CustomerExportJob
+	err := r.db.WithContext(ctx).
+		Where("status = ? OR (status = ? AND updated_at < ?)",
+			domain.CustomerExportPending, domain.CustomerExportRunning, staleBefore).
+		Order("created_at ASC").
+		Limit(limit).
+		Find(&jobs).Error
+	return jobs, err
+}
+
+// Claim starts a runnable job from the beginning. It reports false when
+// another worker claimed it first.
+func (r *CustomerExportJobRepository) Claim(ctx context.Context, job *domain.CustomerExportJob, now time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Model(&domain.CustomerExportJob{}).
+		Where("id = ? AND status = ? AND updated_at = ?", job.ID, job.Status, job.UpdatedAt).
+		Updates(map[string]interface{}{
+			"status":     domain.CustomerExportRunning,
+			"processed":  0,
+			"attempts":   gorm.Expr("attempts + 1"),
+			"started_at": now,
+			"updated_at": now,
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected != 1 {
+		return false, nil
+	}
+	job.Status, job.Processed, job.StartedAt, job.UpdatedAt = domain.CustomerExportRunning, 0, &now, now
+	job.Attempts++
+	return true, nil
+}
+
+// Progress records the total and the customers written so far, which also
+// tells other workers the job is still being run
+func (r *CustomerExportJobRepository) Progress(ctx context.Context, job *domain.CustomerExportJob) error {
+	return r.db.WithContext(ctx).
+		Model(job).
+		Select("total", "processed", "updated_at").
+		Updates(job).Error
+}
+
+// Finish saves the outcome of a job
+func (r *CustomerExportJobRepository) Finish(ctx context.Context, job *domain.CustomerExportJob) error {
+	return r.db.WithContext(ctx).
+		Model(job).
+		Select("status", "total", "processed", "file_key", "bytes", "error", "completed_at", "updated_at").
+		Updates(job).Error
+}
+
+// Expired returns up to limit jobs that finished before the given time,
+// oldest first
+func (r *CustomerExportJobRepository) Expired(ctx context.Context, before time.Time, limit int) ([]domain.CustomerExportJob, error) {
+	var jobs []domain.CustomerExportJob
+	err := r.db.WithContext(ctx).
+		Where("status IN ? AND completed_at < ?",
+			[]string{domain.CustomerExportCompleted, domain.CustomerExportFailed}, before).
+		Order("completed_at ASC").
+		Limit(limit).
+		Find(&jobs).Error
+	return jobs, err
+}
+
+// Delete removes a job
+func (r *CustomerExportJobRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	return r.db.WithContext(ctx).Delete(&domain.CustomerExportJob{}, "id = ?", id).Error
+}
@@ -0,0 +1,63 @@
+package persistence
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCustomerExportJobRepository_ClaimAndFinish(t *testing.T) {
+	db := openTestDB(t, &domain.CustomerExportJob{})
+	repo := NewCustomerExportJobRepository(db)
+	ctx := context.Background()
+	now := time.Now()
+
+	job := &domain.CustomerExportJob{Format: "csv", Columns: []string{"id", "email"}, Filter: "status=active"}
+	require.NoError(t, repo.Create(ctx, job))
+	assert.Equal(t, domain.CustomerExportPending, job.Status)
+
+	runnable, err := repo.Runnable(ctx, now.Add(-time.Minute), 10)
+	require.NoError(t, err)
+	require.Len(t, runnable, 1)
+
+	stale := runnable[0]
+	claimed, err := repo.Claim(ctx, &runnable[0], now)
+	require.NoError(t, err)
+	assert.True(t, claimed)
+	claimed, err = repo.Claim(ctx, &stale, now)
+	require.NoError(t, err)
+	assert.False(t, claimed, "a second worker must not claim the same job")
+
+	running := &runnable[0]
+	running.Total, running.Processed = 2500, 1000
+	require.NoError(t, repo.Progress(ctx, running))
+
+	runnable, err = repo.Runnable(ctx, now.Add(-time.Minute), 10)
+	require.NoError(t, err)
+	assert.Empty(t, runnable, "a job reporting progress is left to its worker")
+	runnable, err = repo.Runnable(ctx, time.Now().Add(time.Minute), 10)
+	require.NoError(t, err)
+	assert.Len(t, runnable, 1, "a job that stopped reporting progress is run again")
+
+	completed := time.Now()
+	running.Status, running.Processed = domain.CustomerExportCompleted, 2500
+	running.FileKey, running.Bytes, running.CompletedAt = running.Key(), 4096, &completed
+	require.NoError(t, repo.Finish(ctx, running))
+
+	got, err := repo.Get(ctx, job.ID)
+	require.NoError(t, err)
+	assert.Equal(t, domain.CustomerExportCompleted, got.Status)
+	assert.Equal(t, int64(2500), got.Processed)
+	assert.Equal(t, int64(4096), got.Bytes)
+	assert.Equal(t, 1, got.Attempts)
+	assert.Equal(t, 100, got.Progress())
+	assert.Contains(t, got.FileKey, domain.CustomerExportKeyPrefix+job.ID.String()+"/customers-")
+
+	runnable, err = repo.Runnable(ctx, time.Now().Add(time.Minute), 10)
+	require.NoError(t, err)
+	assert.Empty(t, runnable)
+}
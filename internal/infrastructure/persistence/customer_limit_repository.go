@@ -0,0 +1,124 @@
+package persistence
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CustomerLimitRepository resolves the per-customer caps on addresses,
+// wishlist items and measurements: the configured defaults, replaced by an
+// admin override where one exists
+type CustomerLimitRepository struct {
+	db       *gorm.DB
+	defaults domain.CustomerLimits
+}
+
+// NewCustomerLimitRepository creates a new customer limit repository
+func NewCustomerLimitRepository(db *gorm.DB, defaults domain.CustomerLimits) *CustomerLimitRepository {
+	return &CustomerLimitRepository{db: db, defaults: defaults}
+}
+
+// Defaults returns the caps of customers without an override
+func (r *CustomerLimitRepository) Defaults() domain.CustomerLimits {
+	return r.defaults
+}
+
+// For returns the caps that apply to a customer
+func (r *CustomerLimitRepository) For(ctx context.Context, customerID uuid.UUID) (domain.CustomerLimits, error) {
+	return r.limits(r.db.WithContext(ctx), customerID)
+}
+
+// limits resolves a customer's caps within db, so repositories can check
+// them in the transaction that adds the record
+func (r *CustomerLimitRepository) limits(db *gorm.DB, customerID uuid.UUID) (domain.CustomerLimits, error) {
+	var overrides []domain.CustomerLimitOverride
+	if err := db.Where("customer_id = ?", customerID).Limit(1).Find(&overrides).Error; err != nil {
+		return domain.CustomerLimits{}, err
+	}
+	if len(overrides) == 0 {
+		return r.defaults, nil
+	}
+	return overrides[0].Apply(r.defaults), nil
+}
+
+// GetOverride returns a customer's override, or gorm.ErrRecordNotFound
+func (r *CustomerLimitRepository) GetOverride(ctx context.Context, customerID uuid.UUID) (*domain.CustomerLimitOverride, error) {
+	var override domain.CustomerLimitOverride
+	if err := r.db.WithContext(ctx).Where("customer_id = ?", customerID).First(&override).Error; err != nil {
+		return nil, err
+	}
+	return &override, nil
+}
+
+// SaveOverride creates or replaces a customer's override
+func (r *CustomerLimitRepository) SaveOverride(ctx context.Context, override *domain.CustomerLimitOverride) error {
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "customer_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"addresses", "wishlist_items", "measurements", "reason", "updated_by", "updated_at"}),
+	}).Create(override).Error
+}
+
+// DeleteOverride restores a customer's default caps. Returns
+// gorm.ErrRecordNotFound if the customer has no override.
+func (r *CustomerLimitRepository) DeleteOverride(ctx context.Context, customerID uuid.UUID) error {
+	result := r.db.WithContext(ctx).
+		Where("customer_id = ?", customerID).
+		Delete(&domain.CustomerLimitOverride{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// Usage counts the records a customer currently stores against each cap
+func (r *CustomerLimitRepository) Usage(ctx context.Context, customerID uuid.UUID) (domain.CustomerLimits, error) {
+	db := r.db.WithContext(ctx)
+	var addresses, wishlist, measurements int64
+	if err := db.Model(&domain.Address{}).Where("user_id = ?", customerID).Count(&addresses).Error; err != nil {
+		return domain.CustomerLimits{}, err
+	}
+	if err := db.Model(&domain.WishlistItem{}).Where("user_id = ?", customerID).Count(&wishlist).Error; err != nil {
+		return domain.CustomerLimits{}, err
+	}
+	if err := db.Model(&domain.CustomerMeasurement{}).Where("user_id = ?", customerID).Count(&measurements).Error; err != nil {
+		return domain.CustomerLimits{}, err
+	}
+	return domain.CustomerLimits{
+		Addresses:     int(addresses),
+		WishlistItems: int(wishlist),
+		Measurements:  int(measurements),
+	}, nil
+}
+
+// checkCustomerLimit returns limitErr if userID already stores as many
+// model rows as the cap picked from their limits allows. A nil limits
+// repository means no caps.
+func checkCustomerLimit(tx *gorm.DB, limits *CustomerLimitRepository, userID uuid.UUID, model interface{}, capOf func(domain.CustomerLimits) int, limitErr error) error {
+	if limits == nil {
+		return nil
+	}
+	resolved, err := limits.limits(tx, userID)
+	if err != nil {
+		return err
+	}
+	limit := capOf(resolved)
+	if limit <= 0 {
+		return nil
+	}
+	var count int64
+	if err := tx.Model(model).Where("user_id = ?", userID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count >= int64(limit) {
+		return fmt.Errorf("%w (at most %d)", limitErr, limit)
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package persistence
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/Ecom-micro-template/service-customer/internal/domain/address"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func setupCustomerLimitTestDB(t *testing.T) *gorm.DB {
+	return openTestDB(t, &domain.CustomerLimitOverride{}, &domain.Address{}, &domain.WishlistItem{}, &domain.CustomerMeasurement{})
+}
+
+func limitTestAddress(userID uuid.UUID, line string) *domain.Address {
+	return &domain.Address{
+		UserID:        userID,
+		Label:         "Home",
+		RecipientName: "Nur Aisyah",
+		Phone:         "+60123456789",
+		AddressLine1:  line,
+		City:          "Kuala Lumpur",
+		State:         "Wilayah Persekutuan",
+		Postcode:      "50250",
+		Country:       "Malaysia",
+	}
+}
+
+func TestCustomerLimitRepository_Override(t *testing.T) {
+	db := setupCustomerLimitTestDB(t)
+	repo := NewCustomerLimitRepository(db, domain.CustomerLimits{Addresses: 2, WishlistItems: 3, Measurements: 1})
+	ctx := context.Background()
+	customerID := uuid.New()
+
+	limits, err := repo.For(ctx, customerID)
+	require.NoError(t, err)
+	assert.Equal(t, repo.Defaults(), limits)
+
+	addresses, adminID := 50, uuid.New()
+	require.NoError(t, repo.SaveOverride(ctx, &domain.CustomerLimitOverride{CustomerID: customerID, Addresses: &addresses, Reason: "reseller", UpdatedBy: &adminID}))
+	limits, err = repo.For(ctx, customerID)
+	require.NoError(t, err)
+	assert.Equal(t, domain.CustomerLimits{Addresses: 50, WishlistItems: 3, Measurements: 1}, limits)
+
+	// Saving again replaces the override
+	unlimited := 0
+	require.NoError(t, repo.SaveOverride(ctx, &domain.CustomerLimitOverride{CustomerID: customerID, Measurements: &unlimited, Reason: "tailor"}))
+	override, err := repo.GetOverride(ctx, customerID)
+	require.NoError(t, err)
+	assert.Nil(t, override.Addresses)
+	assert.Equal(t, "tailor", override.Reason)
+	limits, err = repo.For(ctx, customerID)
+	require.NoError(t, err)
+	assert.Equal(t, domain.CustomerLimits{Addresses: 2, WishlistItems: 3, Measurements: 0}, limits)
+
+	// Other customers keep the defaults
+	limits, err = repo.For(ctx, uuid.New())
+	require.NoError(t, err)
+	assert.Equal(t, repo.Defaults(), limits)
+
+	require.NoError(t, repo.DeleteOverride(ctx, customerID))
+	assert.ErrorIs(t, repo.DeleteOverride(ctx, customerID), gorm.ErrRecordNotFound)
+	_, err = repo.GetOverride(ctx, customerID)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
+
+func TestCustomerLimitRepository_EnforcedByRepositories(t *testing.T) {
+	db := setupCustomerLimitTestDB(t)
+	limits := NewCustomerLimitRepository(db, domain.CustomerLimits{Addresses: 2, WishlistItems: 2, Measurements: 1})
+	addresses := NewAddressRepository(db).WithLimits(limits)
+	wishlist := NewWishlistRepository(db).WithLimits(limits)
+	measurements := NewMeasurementRepository(db).WithLimits(limits)
+	ctx := context.Background()
+	userID := uuid.New()
+
+	// Addresses
+	first := limitTestAddress(userID, "1 Jalan Ampang")
+	require.NoError(t, addresses.Create(ctx, first))
+	require.NoError(t, addresses.Create(ctx, limitTestAddress(userID, "2 Jalan Ampang")))
+	assert.ErrorIs(t, addresses.Create(ctx, limitTestAddress(userID, "3 Jalan Ampang")), address.ErrMaxAddresses)
+
+	// A deleted address frees a slot, and restoring it needs one
+	require.NoError(t, addresses.Delete(ctx, first.ID, userID))
+	require.NoError(t, addresses.Create(ctx, limitTestAddress(userID, "3 Jalan Ampang")))
+	_, err := addresses.Restore(ctx, first.ID, userID)
+	assert.ErrorIs(t, err, address.ErrMaxAddresses)
+
+	// Wishlist; re-adding an item already in the wishlist is not blocked
+	product := uuid.New()
+	require.NoError(t, wishlist.Add(ctx, userID, product))
+	require.NoError(t, wishlist.Add(ctx, userID, uuid.New()))
+	assert.ErrorIs(t, wishlist.Add(ctx, userID, uuid.New()), domain.ErrWishlistFull)
+	require.NoError(t, wishlist.Add(ctx, userID, product))
+
+	// Measurements
+	require.NoError(t, measurements.Create(ctx, &domain.CustomerMeasurement{UserID: userID, Gender: "women"}))
+	assert.ErrorIs(t, measurements.Create(ctx, &domain.CustomerMeasurement{UserID: userID, Gender: "women"}), domain.ErrMeasurementLimit)
+
+	usage, err := limits.Usage(ctx, userID)
+	require.NoError(t, err)
+	assert.Equal(t, domain.CustomerLimits{Addresses: 2, WishlistItems: 2, Measurements: 1}, usage)
+
+	// An override lifts the caps for this customer only
+	more := 3
+	require.NoError(t, limits.SaveOverride(ctx, &domain.CustomerLimitOverride{CustomerID: userID, Addresses: &more, WishlistItems: &more, Reason: "wholesale buyer"}))
+	restored, err := addresses.Restore(ctx, first.ID, userID)
+	require.NoError(t, err)
+	assert.Equal(t, first.ID, restored.ID)
+	require.NoError(t, wishlist.Add(ctx, userID, uuid.New()))
+	assert.ErrorIs(t, measurements.Create(ctx, &domain.CustomerMeasurement{UserID: userID, Gender: "women"}), domain.ErrMeasurementLimit)
+
+	other := uuid.New()
+	require.NoError(t, addresses.Create(ctx, limitTestAddress(other, "1 Jalan Tun Razak")))
+	require.NoError(t, addresses.Create(ctx, limitTestAddress(other, "2 Jalan Tun Razak")))
+	assert.ErrorIs(t, addresses.Create(ctx, limitTestAddress(other, "3 Jalan Tun Razak")), address.ErrMaxAddresses)
+}
@@ -0,0 +1,78 @@
+package persistence
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"gorm.io/gorm"
+)
+
+// CustomerLookupRepository reads customers for other services
+type CustomerLookupRepository struct {
+	db *gorm.DB
+}
+
+// NewCustomerLookupRepository creates a new customer lookup repository
+func NewCustomerLookupRepository(db *gorm.DB) *CustomerLookupRepository {
+	return &CustomerLookupRepository{db: db}
+}
+
+// GetBatch returns the customers with the given IDs in the order asked for,
+// and the IDs not found. Duplicate IDs are returned once.
+func (r *CustomerLookupRepository) GetBatch(ctx context.Context, ids []uuid.UUID) (*domain.CustomerBatchResult, error) {
+	var customers []domain.Customer
+	if err := r.db.WithContext(ctx).Where("id IN ?", ids).Find(&customers).Error; err != nil {
+		return nil, err
+	}
+
+	byID := make(map[uuid.UUID]domain.Customer, len(customers))
+	for _, customer := range customers {
+		byID[customer.ID] = customer
+	}
+
+	result := &domain.CustomerBatchResult{
+		Customers: make([]domain.Customer, 0, len(customers)),
+		Missing:   []uuid.UUID{},
+	}
+	seen := make(map[uuid.UUID]bool, len(ids))
+	for _, id := range ids {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		if customer, ok := byID[id]; ok {
+			result.Customers = append(result.Customers, customer)
+		} else {
+			result.Missing = append(result.Missing, id)
+		}
+	}
+	return result, nil
+}
+
+// Birthdays returns a page of the customers whose birthday is on the date,
+// keyset-paginated on the profile's (created_at, id)
+func (r *CustomerLookupRepository) Birthdays(ctx context.Context, date time.Time, after *domain.Cursor, limit int) ([]domain.BirthdayCustomer, string, error) {
+	query := r.db.WithContext(ctx).Model(&domain.Profile{}).
+		Where("birthday IN ?", domain.Birthdays(date))
+
+	var profiles []domain.Profile
+	if err := keysetOrder(query, after, false, limit).Find(&profiles).Error; err != nil {
+		return nil, "", err
+	}
+	profiles, next := cursorPage(profiles, limit, func(p *domain.Profile) domain.Cursor {
+		return domain.Cursor{CreatedAt: p.CreatedAt, ID: p.ID}
+	})
+
+	birthdays := make([]domain.BirthdayCustomer, len(profiles))
+	for i, profile := range profiles {
+		birthdays[i] = domain.BirthdayCustomer{
+			CustomerID:  profile.ID,
+			FullName:    profile.FullName,
+			Email:       profile.Email,
+			DateOfBirth: *profile.DateOfBirth,
+		}
+	}
+	return birthdays, next, nil
+}
@@ -0,0 +1,32 @@
+package persistence
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCustomerLookupRepository_GetBatch(t *testing.T) {
+	db := openTestDB(t, &domain.Customer{})
+	repo := NewCustomerLookupRepository(db)
+
+	alice := &domain.Customer{Email: "alice@example.com", FirstName: "Alice"}
+	bob := &domain.Customer{Email: "bob@example.com", FirstName: "Bob"}
+	deleted := &domain.Customer{Email: "carol@example.com"}
+	for _, customer := range []*domain.Customer{alice, bob, deleted} {
+		require.NoError(t, db.Create(customer).Error)
+	}
+	require.NoError(t, db.Delete(deleted).Error)
+	unknown := uuid.New()
+
+	result, err := repo.GetBatch(context.Background(), []uuid.UUID{bob.ID, unknown, alice.ID, bob.ID, deleted.ID})
+	require.NoError(t, err)
+	require.Len(t, result.Customers, 2)
+	assert.Equal(t, bob.ID, result.Customers[0].ID, "customers come back in the order asked for")
+	assert.Equal(t, alice.ID, result.Customers[1].ID)
+	assert.Equal(t, []uuid.UUID{unknown, deleted.ID}, result.Missing)
+}
@@ -0,0 +1,49 @@
+package persistence
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"go.uber.org/zap"
+)
+
+// marketingCustomerRepository queues customers for the marketing platforms
+// after their profile or segments change. Like mirroring, queueing never
+// fails the write.
+type marketingCustomerRepository struct {
+	CustomerRepository
+	sync   *MarketingSyncRepository
+	logger *zap.Logger
+}
+
+// NewMarketingCustomerRepository wraps repo so updated customers and segment
+// assignments are pushed to the marketing platforms. New customers have no
+// consent yet, so they are queued once they give it.
+func NewMarketingCustomerRepository(repo CustomerRepository, sync *MarketingSyncRepository, logger *zap.Logger) CustomerRepository {
+	return &marketingCustomerRepository{CustomerRepository: repo, sync: sync, logger: logger}
+}
+
+func (r *marketingCustomerRepository) Update(ctx context.Context, id uuid.UUID, req *domain.UpdateCustomerRequest) (*domain.Customer, error) {
+	customer, err := r.CustomerRepository.Update(ctx, id, req)
+	if err == nil {
+		r.markChanged(ctx, id)
+	}
+	return customer, err
+}
+
+func (r *marketingCustomerRepository) AssignSegments(ctx context.Context, customerID uuid.UUID, segmentIDs []uuid.UUID) error {
+	err := r.CustomerRepository.AssignSegments(ctx, customerID, segmentIDs)
+	if err == nil {
+		r.markChanged(ctx, customerID)
+	}
+	return err
+}
+
+func (r *marketingCustomerRepository) markChanged(ctx context.Context, customerID uuid.UUID) {
+	if err := r.sync.MarkChanged(context.WithoutCancel(ctx), customerID); err != nil {
+		r.logger.Error("Failed to queue customer for marketing sync",
+			zap.String("customer_id", customerID.String()),
+			zap.Error(err))
+	}
+}
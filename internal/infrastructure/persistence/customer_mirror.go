@@ -0,0 +1,52 @@
+package persistence
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+)
+
+// CustomerMirror copies customer and address writes to another store, such as
+// the legacy CRM during migration. Mirroring is best effort: it runs after the
+// write is committed and never fails it.
+type CustomerMirror interface {
+	MirrorCustomer(ctx context.Context, customerID uuid.UUID)
+	MirrorAddresses(ctx context.Context, userID uuid.UUID)
+}
+
+// mirroredCustomerRepository mirrors customer writes after they succeed
+type mirroredCustomerRepository struct {
+	CustomerRepository
+	mirror CustomerMirror
+}
+
+// NewMirroredCustomerRepository wraps repo so created, updated and deleted
+// customers are also written to mirror
+func NewMirroredCustomerRepository(repo CustomerRepository, mirror CustomerMirror) CustomerRepository {
+	return &mirroredCustomerRepository{CustomerRepository: repo, mirror: mirror}
+}
+
+func (r *mirroredCustomerRepository) Create(ctx context.Context, req *domain.CreateCustomerRequest, createdBy *uuid.UUID) (*domain.Customer, error) {
+	customer, err := r.CustomerRepository.Create(ctx, req, createdBy)
+	if err == nil {
+		r.mirror.MirrorCustomer(context.WithoutCancel(ctx), customer.ID)
+	}
+	return customer, err
+}
+
+func (r *mirroredCustomerRepository) Update(ctx context.Context, id uuid.UUID, req *domain.UpdateCustomerRequest) (*domain.Customer, error) {
+	customer, err := r.CustomerRepository.Update(ctx, id, req)
+	if err == nil {
+		r.mirror.MirrorCustomer(context.WithoutCancel(ctx), id)
+	}
+	return customer, err
+}
+
+func (r *mirroredCustomerRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	err := r.CustomerRepository.Delete(ctx, id)
+	if err == nil {
+		r.mirror.MirrorCustomer(context.WithoutCancel(ctx), id)
+	}
+	return err
+}
@@ -4,24 +4,25 @@ package persistence
 import (
 	"time"
 
+	"github.com/Ecom-micro-template/service-customer/internal/domain/shared"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
 // CustomerModel is the GORM persistence model for Customer.
 type CustomerModel struct {
-	ID          uuid.UUID      `gorm:"type:uuid;primary_key" json:"id"`
-	Email       string         `gorm:"uniqueIndex;not null" json:"email"`
-	FirstName   string         `gorm:"type:varchar(100)" json:"first_name"`
-	LastName    string         `gorm:"type:varchar(100)" json:"last_name"`
-	Phone       string         `gorm:"type:varchar(20)" json:"phone,omitempty"`
-	AvatarURL   string         `gorm:"type:varchar(500)" json:"avatar_url,omitempty"`
-	Status      string         `gorm:"type:varchar(20);default:'active'" json:"status"`
-	TotalOrders int            `gorm:"default:0" json:"total_orders"`
-	TotalSpent  float64        `gorm:"type:decimal(12,2);default:0" json:"total_spent"`
-	CreatedAt   time.Time      `json:"created_at"`
-	UpdatedAt   time.Time      `json:"updated_at"`
-	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
+	ID          uuid.UUID             `gorm:"type:uuid;primary_key" json:"id"`
+	Email       string                `gorm:"uniqueIndex;not null" json:"email"`
+	FirstName   string                `gorm:"type:varchar(100)" json:"first_name"`
+	LastName    string                `gorm:"type:varchar(100)" json:"last_name"`
+	Phone       string                `gorm:"type:varchar(20)" json:"phone,omitempty"`
+	AvatarURL   string                `gorm:"type:varchar(500)" json:"avatar_url,omitempty"`
+	Status      shared.CustomerStatus `gorm:"type:varchar(20);default:'active'" json:"status"`
+	TotalOrders int                   `gorm:"default:0" json:"total_orders"`
+	TotalSpent  float64               `gorm:"type:decimal(12,2);default:0" json:"total_spent"`
+	CreatedAt   time.Time             `json:"created_at"`
+	UpdatedAt   time.Time             `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt        `gorm:"index" json:"-"`
 }
 
 // TableName specifies the table name.
@@ -42,6 +43,7 @@ type CustomerNoteModel struct {
 	ID         uuid.UUID  `gorm:"type:uuid;primary_key" json:"id"`
 	CustomerID uuid.UUID  `gorm:"type:uuid;index" json:"customer_id"`
 	Note       string     `gorm:"type:text" json:"note"`
+	Category   string     `gorm:"type:varchar(50);default:'general';index" json:"category"`
 	IsPrivate  bool       `gorm:"default:false" json:"is_private"`
 	CreatedBy  *uuid.UUID `gorm:"type:uuid" json:"created_by,omitempty"`
 	CreatedAt  time.Time  `json:"created_at"`
@@ -68,6 +70,13 @@ type CustomerActivityModel struct {
 	Title      string    `gorm:"type:varchar(255)" json:"title"`
 	Details    string    `gorm:"type:text" json:"details,omitempty"`
 	CreatedAt  time.Time `json:"created_at"`
+
+	IPAddress string `gorm:"type:varchar(45)" json:"ip_address,omitempty"`
+	UserAgent string `gorm:"type:varchar(500)" json:"user_agent,omitempty"`
+	RequestID string `gorm:"type:varchar(100)" json:"request_id,omitempty"`
+
+	PinnedAt *time.Time `gorm:"index" json:"pinned_at,omitempty"`
+	PinnedBy *uuid.UUID `gorm:"type:uuid" json:"pinned_by,omitempty"`
 }
 
 // TableName specifies the table name.
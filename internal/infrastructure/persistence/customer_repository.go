@@ -1,64 +1,57 @@
 package persistence
 
 import (
+	"context"
+	"fmt"
+	"time"
+
 	"github.com/google/uuid"
 	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/Ecom-micro-template/service-customer/internal/domain/shared"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // CustomerRepository defines the interface for customer data operations
 type CustomerRepository interface {
 	// CRUD operations
-	ListAdmin(filter domain.CustomerListFilter) ([]domain.Customer, int64, error)
-	GetByID(id uuid.UUID) (*domain.Customer, error)
-	Create(req *domain.CreateCustomerRequest, createdBy *uuid.UUID) (*domain.Customer, error)
-	Update(id uuid.UUID, req *domain.UpdateCustomerRequest) (*domain.Customer, error)
-	Delete(id uuid.UUID) error
-
-	// Order-related
-	GetCustomerOrders(customerID uuid.UUID, page, limit int) ([]CustomerOrderSummary, int64, error)
+	ListAdmin(ctx context.Context, filter domain.CustomerListFilter) ([]domain.Customer, int64, error)
+	ListAdminAfter(ctx context.Context, filter domain.CustomerListFilter, after *domain.Cursor) ([]domain.Customer, string, error)
+	ListAdminIDs(ctx context.Context, filter domain.CustomerListFilter) ([]uuid.UUID, error)
+	GetByID(ctx context.Context, id uuid.UUID) (*domain.Customer, error)
+	InRegion(ctx context.Context, id uuid.UUID, region *domain.RegionScope) (bool, error)
+	Create(ctx context.Context, req *domain.CreateCustomerRequest, createdBy *uuid.UUID) (*domain.Customer, error)
+	Update(ctx context.Context, id uuid.UUID, req *domain.UpdateCustomerRequest) (*domain.Customer, error)
+	Delete(ctx context.Context, id uuid.UUID) error
 
 	// Notes
-	AddNote(customerID uuid.UUID, note string, isPrivate bool, createdBy uuid.UUID) (*domain.CustomerNote, error)
-	GetNotes(customerID uuid.UUID) ([]domain.CustomerNote, error)
+	AddNote(ctx context.Context, customerID uuid.UUID, note, category string, isPrivate bool, createdBy uuid.UUID) (*domain.CustomerNote, error)
+	GetNotes(ctx context.Context, customerID uuid.UUID, filter domain.CustomerNoteFilter) ([]domain.CustomerNote, int64, error)
+	CountNotes(ctx context.Context, customerID uuid.UUID, filter domain.CustomerNoteFilter) (*domain.CustomerNoteCounts, error)
+	GetNote(ctx context.Context, customerID, noteID uuid.UUID) (*domain.CustomerNote, error)
+	UpdateNote(ctx context.Context, customerID, noteID uuid.UUID, req *domain.UpdateCustomerNoteRequest, editedBy uuid.UUID) (*domain.CustomerNote, error)
+	DeleteNote(ctx context.Context, customerID, noteID uuid.UUID) error
+	PinNote(ctx context.Context, customerID, noteID, pinnedBy uuid.UUID) (*domain.CustomerNote, error)
+	UnpinNote(ctx context.Context, customerID, noteID uuid.UUID) (*domain.CustomerNote, error)
 
 	// Activity
-	GetActivity(customerID uuid.UUID, page, limit int) ([]domain.CustomerActivity, int64, error)
+	GetActivity(ctx context.Context, customerID uuid.UUID, page, limit int) ([]domain.CustomerActivity, int64, error)
+	GetActivityAfter(ctx context.Context, customerID uuid.UUID, after *domain.Cursor, limit int) ([]domain.CustomerActivity, string, error)
+	GetPinnedActivities(ctx context.Context, customerID uuid.UUID) ([]domain.CustomerActivity, error)
+	PinActivity(ctx context.Context, customerID, activityID, pinnedBy uuid.UUID) (*domain.CustomerActivity, error)
+	UnpinActivity(ctx context.Context, customerID, activityID uuid.UUID) (*domain.CustomerActivity, error)
+	GetTimeline(ctx context.Context, customerID uuid.UUID, filter domain.TimelineFilter) ([]domain.TimelineEntry, int64, error)
 
 	// Segments
-	GetSegments() ([]domain.CustomerSegment, error)
-	CreateSegment(name, description string, conditions interface{}, color string) (*domain.CustomerSegment, error)
-	UpdateSegment(id uuid.UUID, name, description *string, conditions interface{}, color *string) (*domain.CustomerSegment, error)
-	DeleteSegment(id uuid.UUID) error
-	AssignSegments(customerID uuid.UUID, segmentIDs []uuid.UUID) error
+	GetSegments(ctx context.Context) ([]domain.CustomerSegment, error)
+	CreateSegment(ctx context.Context, name, description string, conditions interface{}, color string) (*domain.CustomerSegment, error)
+	UpdateSegment(ctx context.Context, id uuid.UUID, name, description *string, conditions interface{}, color *string) (*domain.CustomerSegment, error)
+	DeleteSegment(ctx context.Context, id uuid.UUID) error
+	AssignSegments(ctx context.Context, customerID uuid.UUID, segmentIDs []uuid.UUID) error
 
 	// Export and stats
-	Export(filter domain.CustomerListFilter, format string) (interface{}, error)
-	GetStats() (*CustomerStats, error)
-}
-
-// CustomerOrderItem represents an item in a customer order
-type CustomerOrderItem struct {
-	ID          uuid.UUID `json:"id"`
-	ProductID   uuid.UUID `json:"product_id"`
-	ProductName string    `json:"product_name"`
-	SKU         string    `json:"sku"`
-	Quantity    int       `json:"quantity"`
-	UnitPrice   float64   `json:"unit_price"`
-	Total       float64   `json:"total"`
-	ImageURL    string    `json:"image_url"`
-}
-
-// CustomerOrderSummary represents a summarized order for a customer
-type CustomerOrderSummary struct {
-	ID            uuid.UUID           `json:"id"`
-	OrderNum      string              `json:"order_number"`
-	Total         float64             `json:"total"`
-	Subtotal      float64             `json:"subtotal"`
-	Status        string              `json:"status"`
-	PaymentStatus string              `json:"payment_status"`
-	Items         []CustomerOrderItem `json:"items"`
-	CreatedAt     string              `json:"created_at"`
+	Export(ctx context.Context, filter domain.CustomerListFilter, format string) (interface{}, error)
+	GetStats(ctx context.Context, window domain.StatsWindow) (*CustomerStats, error)
 }
 
 // CustomerStats represents customer statistics
@@ -69,36 +62,60 @@ type CustomerStats struct {
 	NewCustomersMonth int64   `json:"new_customers_month"`
 	TotalRevenue      float64 `json:"total_revenue"`
 	AverageOrderValue float64 `json:"average_order_value"`
+
+	// Signups and first purchases in the selected period, compared with the
+	// period before it, and per day of the period
+	Period         string              `json:"period"`
+	Timezone       string              `json:"timezone"`
+	From           time.Time           `json:"from"`
+	To             time.Time           `json:"to"` // exclusive
+	NewCustomers   domain.StatsDelta   `json:"new_customers"`
+	FirstPurchases domain.StatsDelta   `json:"first_purchases"`
+	Series         []domain.StatsPoint `json:"series"`
+
+	// Customers per churn risk level, for the churn risk widget
+	ChurnRisk map[string]int64 `json:"churn_risk"`
 }
 
 // customerRepository is the concrete implementation
 type customerRepository struct {
-	db *gorm.DB
+	db       *gorm.DB
+	timeouts QueryTimeouts
+}
+
+// NewCustomerRepository creates a new customer repository whose queries are
+// bounded by timeouts
+func NewCustomerRepository(db *gorm.DB, timeouts QueryTimeouts) CustomerRepository {
+	return &customerRepository{db: db, timeouts: timeouts}
 }
 
-// NewCustomerRepository creates a new customer repository
-func NewCustomerRepository(db *gorm.DB) CustomerRepository {
-	return &customerRepository{db: db}
+// session returns the database bound to ctx and the deadline of the query
+// class; cancel must be called once the query is done
+func (r *customerRepository) session(ctx context.Context, class QueryClass) (*gorm.DB, context.CancelFunc) {
+	ctx, cancel := r.timeouts.Bound(ctx, class)
+	return r.db.WithContext(ctx), cancel
 }
 
-func (r *customerRepository) ListAdmin(filter domain.CustomerListFilter) ([]domain.Customer, int64, error) {
+func (r *customerRepository) ListAdmin(ctx context.Context, filter domain.CustomerListFilter) ([]domain.Customer, int64, error) {
+	db, cancel := r.session(ctx, QueryList)
+	defer cancel()
+	db = Replica(db)
+
 	var customers []domain.Customer
 	var total int64
 
-	query := r.db.Model(&domain.Customer{})
-
-	if filter.Status != "" {
-		query = query.Where("status = ?", filter.Status)
-	}
-	if filter.Search != "" {
-		search := "%" + filter.Search + "%"
-		query = query.Where("first_name ILIKE ? OR last_name ILIKE ? OR email ILIKE ?", search, search, search)
+	column, desc, err := domain.CustomerSort.Resolve(filter.Sort)
+	if err != nil {
+		return nil, 0, err
 	}
 
-	query.Count(&total)
+	query := r.adminListQuery(db, filter)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
 
 	offset := (filter.Page - 1) * filter.Limit
-	query = query.Order(filter.SortBy + " " + filter.SortOrder).Offset(offset).Limit(filter.Limit)
+	query = query.Order(clause.OrderByColumn{Column: clause.Column{Name: column}, Desc: desc}).Offset(offset).Limit(filter.Limit)
 
 	if err := query.Find(&customers).Error; err != nil {
 		return nil, 0, err
@@ -106,15 +123,140 @@ func (r *customerRepository) ListAdmin(filter domain.CustomerListFilter) ([]doma
 	return customers, total, nil
 }
 
-func (r *customerRepository) GetByID(id uuid.UUID) (*domain.Customer, error) {
+// ListAdminIDs returns the IDs of up to filter.Limit customers matching
+// filter, oldest first, for bulk changes. It reads from the primary under the
+// export timeout; filter.Page and filter.Sort are ignored.
+func (r *customerRepository) ListAdminIDs(ctx context.Context, filter domain.CustomerListFilter) ([]uuid.UUID, error) {
+	db, cancel := r.session(ctx, QueryExport)
+	defer cancel()
+
+	var ids []uuid.UUID
+	err := r.adminListQuery(db, filter).
+		Order("created_at ASC, id ASC").
+		Limit(filter.Limit).
+		Pluck("id", &ids).Error
+	return ids, err
+}
+
+// ListAdminAfter is the keyset-paginated ListAdmin: it returns the customers
+// after the cursor (nil for the first page) in created_at order, ascending or
+// descending per filter.Sort, and the next page's cursor. filter.Sort must be
+// on created_at; filter.Page is ignored and no total is counted.
+func (r *customerRepository) ListAdminAfter(ctx context.Context, filter domain.CustomerListFilter, after *domain.Cursor) ([]domain.Customer, string, error) {
+	db, cancel := r.session(ctx, QueryList)
+	defer cancel()
+	db = Replica(db)
+
+	column, desc, err := domain.CustomerSort.Resolve(filter.Sort)
+	if err != nil {
+		return nil, "", err
+	}
+	if column != "created_at" {
+		return nil, "", fmt.Errorf("%w: keyset pages are ordered by created_at", domain.ErrInvalidSort)
+	}
+
+	var customers []domain.Customer
+	query := keysetOrder(r.adminListQuery(db, filter), after, desc, filter.Limit)
+	if err := query.Find(&customers).Error; err != nil {
+		return nil, "", err
+	}
+	customers, next := cursorPage(customers, filter.Limit, func(c *domain.Customer) domain.Cursor {
+		return domain.Cursor{CreatedAt: c.CreatedAt, ID: c.ID}
+	})
+	return customers, next, nil
+}
+
+// adminListQuery applies the admin customer list filters
+func (r *customerRepository) adminListQuery(db *gorm.DB, filter domain.CustomerListFilter) *gorm.DB {
+	query := regionScope(db, db.Model(&domain.Customer{}), filter.Region)
+
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.OrdersMin != nil {
+		query = query.Where("total_orders >= ?", *filter.OrdersMin)
+	}
+	if filter.OrdersMax != nil {
+		query = query.Where("total_orders <= ?", *filter.OrdersMax)
+	}
+	if filter.SpentMin != nil {
+		query = query.Where("total_spent >= ?", *filter.SpentMin)
+	}
+	if filter.SpentMax != nil {
+		query = query.Where("total_spent <= ?", *filter.SpentMax)
+	}
+	if filter.CLVMin != nil {
+		query = query.Where("lifetime_value >= ?", *filter.CLVMin)
+	}
+	if filter.CLVMax != nil {
+		query = query.Where("lifetime_value <= ?", *filter.CLVMax)
+	}
+	if filter.ChurnRisk != "" {
+		query = query.Where("churn_risk = ?", filter.ChurnRisk)
+	}
+	if len(filter.RFMSegments) > 0 {
+		query = query.Where("rfm_segment IN ?", filter.RFMSegments)
+	}
+	if filter.Search != "" {
+		search := "%" + filter.Search + "%"
+		query = query.Where("first_name ILIKE ? OR last_name ILIKE ? OR email ILIKE ?", search, search, search)
+	}
+	if len(filter.Tags) > 0 {
+		tagIDs := db.Model(&domain.CustomerTag{}).Select("id").Where("name IN ?", filter.Tags)
+		tagged := db.Model(&domain.CustomerTagAssignment{}).
+			Select("customer_id").
+			Where("tag_id IN (?)", tagIDs).
+			Group("customer_id").
+			Having("COUNT(*) = ?", len(filter.Tags))
+		query = query.Where("id IN (?)", tagged)
+	}
+	return query
+}
+
+func (r *customerRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.Customer, error) {
+	db, cancel := r.session(ctx, QueryLookup)
+	defer cancel()
+
 	var customer domain.Customer
-	if err := r.db.First(&customer, "id = ?", id).Error; err != nil {
+	if err := db.First(&customer, "id = ?", id).Error; err != nil {
 		return nil, err
 	}
 	return &customer, nil
 }
 
-func (r *customerRepository) Create(req *domain.CreateCustomerRequest, createdBy *uuid.UUID) (*domain.Customer, error) {
+// InRegion reports whether the customer is visible within the region scope;
+// a nil scope sees every customer
+func (r *customerRepository) InRegion(ctx context.Context, id uuid.UUID, region *domain.RegionScope) (bool, error) {
+	if region == nil {
+		return true, nil
+	}
+	db, cancel := r.session(ctx, QueryLookup)
+	defer cancel()
+
+	var count int64
+	err := regionScope(db, db.Model(&domain.Customer{}), region).
+		Where("id = ?", id).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// regionScope limits query to customers whose default address is in one of
+// the scope's states
+func regionScope(db *gorm.DB, query *gorm.DB, region *domain.RegionScope) *gorm.DB {
+	if region == nil {
+		return query
+	}
+
+	defaultAddresses := db.Model(&domain.Address{}).
+		Select("user_id").
+		Where("is_default = ? AND LOWER(state) IN ?", true, region.NormalizedStates())
+	return query.Where("id IN (?)", defaultAddresses)
+}
+
+func (r *customerRepository) Create(ctx context.Context, req *domain.CreateCustomerRequest, createdBy *uuid.UUID) (*domain.Customer, error) {
+	db, cancel := r.session(ctx, QueryLookup)
+	defer cancel()
+
 	customer := &domain.Customer{
 		Email:     req.Email,
 		FirstName: req.FirstName,
@@ -122,163 +264,534 @@ func (r *customerRepository) Create(req *domain.CreateCustomerRequest, createdBy
 		Phone:     req.Phone,
 		Status:    "active",
 	}
-	if err := r.db.Create(customer).Error; err != nil {
+	if err := db.Create(customer).Error; err != nil {
 		return nil, err
 	}
 	return customer, nil
 }
 
-func (r *customerRepository) Update(id uuid.UUID, req *domain.UpdateCustomerRequest) (*domain.Customer, error) {
+func (r *customerRepository) Update(ctx context.Context, id uuid.UUID, req *domain.UpdateCustomerRequest) (*domain.Customer, error) {
+	db, cancel := r.session(ctx, QueryLookup)
+	defer cancel()
+
 	var customer domain.Customer
-	if err := r.db.First(&customer, "id = ?", id).Error; err != nil {
+	if err := db.First(&customer, "id = ?", id).Error; err != nil {
 		return nil, err
 	}
 
-	updates := make(map[string]interface{})
+	// Updated from the struct rather than a map, so the phone goes through
+	// its serializer and BeforeSave keeps its blind index in step
+	previousStatus := customer.Status
+	columns := []string{"version", "updated_at"}
 	if req.FirstName != nil {
-		updates["first_name"] = *req.FirstName
+		customer.FirstName = *req.FirstName
+		columns = append(columns, "first_name")
 	}
 	if req.LastName != nil {
-		updates["last_name"] = *req.LastName
+		customer.LastName = *req.LastName
+		columns = append(columns, "last_name")
 	}
 	if req.Phone != nil {
-		updates["phone"] = *req.Phone
+		customer.Phone = *req.Phone
+		columns = append(columns, "phone", "phone_index")
 	}
 	if req.Status != nil {
-		updates["status"] = *req.Status
+		customer.Status = *req.Status
+		columns = append(columns, "status")
 	}
 
-	if err := r.db.Model(&customer).Updates(updates).Error; err != nil {
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&customer).Select(columns).Updates(&customer).Error; err != nil {
+			return err
+		}
+		if req.Status == nil || *req.Status == previousStatus {
+			return nil
+		}
+		// Keep a status history on the activity timeline
+		if err := tx.Create(&domain.CustomerActivity{
+			CustomerID: customer.ID,
+			Type:       domain.ActivityTypeStatusChange,
+			Title:      "Status changed",
+			Details:    fmt.Sprintf("%s -> %s", previousStatus, *req.Status),
+			ActorID:    req.UpdatedBy,
+		}).Error; err != nil {
+			return err
+		}
+
+		// Blocking a customer blocklists their email, phone and addresses
+		// for as long as they stay blocked
+		switch {
+		case *req.Status == shared.StatusBlocked:
+			return blockCustomerIdentifiers(tx, &customer, req.UpdatedBy, time.Now())
+		case previousStatus == shared.StatusBlocked:
+			return unblockCustomerIdentifiers(tx, customer.ID)
+		}
+		return nil
+	})
+	if err != nil {
 		return nil, err
 	}
 	return &customer, nil
 }
 
-func (r *customerRepository) Delete(id uuid.UUID) error {
-	return r.db.Delete(&domain.Customer{}, "id = ?", id).Error
+func (r *customerRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	db, cancel := r.session(ctx, QueryLookup)
+	defer cancel()
+
+	return db.Delete(&domain.Customer{}, "id = ?", id).Error
 }
 
-func (r *customerRepository) GetCustomerOrders(customerID uuid.UUID, page, limit int) ([]CustomerOrderSummary, int64, error) {
+func (r *customerRepository) AddNote(ctx context.Context, customerID uuid.UUID, note, category string, isPrivate bool, createdBy uuid.UUID) (*domain.CustomerNote, error) {
+	db, cancel := r.session(ctx, QueryLookup)
+	defer cancel()
+
+	if category == "" {
+		category = domain.NoteCategoryGeneral
+	}
+	n := &domain.CustomerNote{
+		CustomerID: customerID,
+		Note:       note,
+		Category:   category,
+		IsPrivate:  isPrivate,
+		CreatedBy:  &createdBy,
+	}
+	if err := db.Create(n).Error; err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+func (r *customerRepository) GetNotes(ctx context.Context, customerID uuid.UUID, filter domain.CustomerNoteFilter) ([]domain.CustomerNote, int64, error) {
+	db, cancel := r.session(ctx, QueryList)
+	defer cancel()
+
+	var notes []domain.CustomerNote
 	var total int64
 
-	offset := (page - 1) * limit
+	query := notesQuery(db, customerID, filter)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
 
-	// Count total orders
-	if err := r.db.Table("public.orders").
-		Where("customer_id = ? AND deleted_at IS NULL", customerID).
-		Count(&total).Error; err != nil {
+	// Pinned notes first, most recently pinned at the top
+	offset := (filter.Page - 1) * filter.Limit
+	if err := query.Order("pinned_at IS NULL").Order("pinned_at DESC").Order("created_at DESC").
+		Offset(offset).Limit(filter.Limit).Find(&notes).Error; err != nil {
 		return nil, 0, err
 	}
+	return notes, total, nil
+}
 
-	// Struct for raw order data
-	type rawOrder struct {
-		ID            uuid.UUID `gorm:"column:id"`
-		OrderNumber   string    `gorm:"column:order_number"`
-		Total         float64   `gorm:"column:total"`
-		Subtotal      float64   `gorm:"column:subtotal"`
-		Status        string    `gorm:"column:status"`
-		PaymentStatus string    `gorm:"column:payment_status"`
-		CreatedAt     string    `gorm:"column:created_at"`
+func (r *customerRepository) GetNote(ctx context.Context, customerID, noteID uuid.UUID) (*domain.CustomerNote, error) {
+	db, cancel := r.session(ctx, QueryLookup)
+	defer cancel()
+
+	var note domain.CustomerNote
+	if err := db.Where("id = ? AND customer_id = ?", noteID, customerID).First(&note).Error; err != nil {
+		return nil, err
 	}
+	return &note, nil
+}
 
-	var rawOrders []rawOrder
+// UpdateNote applies the set fields of req and marks the note as edited
+func (r *customerRepository) UpdateNote(ctx context.Context, customerID, noteID uuid.UUID, req *domain.UpdateCustomerNoteRequest, editedBy uuid.UUID) (*domain.CustomerNote, error) {
+	db, cancel := r.session(ctx, QueryLookup)
+	defer cancel()
 
-	// Fetch orders
-	if err := r.db.Table("public.orders").
-		Select("id, order_number, total, subtotal, status, payment_status, created_at").
-		Where("customer_id = ? AND deleted_at IS NULL", customerID).
-		Order("created_at DESC").
-		Offset(offset).
-		Limit(limit).
-		Scan(&rawOrders).Error; err != nil {
-		return nil, 0, err
+	note, err := r.GetNote(ctx, customerID, noteID)
+	if err != nil {
+		return nil, err
 	}
 
-	// Convert to CustomerOrderSummary and fetch items for each order
-	orders := make([]CustomerOrderSummary, len(rawOrders))
-	for i, ro := range rawOrders {
-		orders[i] = CustomerOrderSummary{
-			ID:            ro.ID,
-			OrderNum:      ro.OrderNumber,
-			Total:         ro.Total,
-			Subtotal:      ro.Subtotal,
-			Status:        ro.Status,
-			PaymentStatus: ro.PaymentStatus,
-			CreatedAt:     ro.CreatedAt,
-			Items:         []CustomerOrderItem{},
-		}
+	now := time.Now()
+	updates := map[string]interface{}{
+		"edited_at": now,
+		"edited_by": editedBy,
+	}
+	if req.Note != nil {
+		updates["note"] = *req.Note
+	}
+	if req.Category != nil {
+		updates["category"] = *req.Category
+	}
+	if req.IsPrivate != nil {
+		updates["is_private"] = *req.IsPrivate
+	}
+	if err := db.Model(note).Updates(updates).Error; err != nil {
+		return nil, err
+	}
+	return r.GetNote(ctx, customerID, noteID)
+}
 
-		// Fetch order items
-		var items []CustomerOrderItem
-		if err := r.db.Table("public.order_items").
-			Select("id, product_id, product_name, sku, quantity, unit_price, (quantity * unit_price) as total, image_url").
-			Where("order_id = ?", ro.ID).
-			Scan(&items).Error; err == nil {
-			orders[i].Items = items
-		}
+func (r *customerRepository) DeleteNote(ctx context.Context, customerID, noteID uuid.UUID) error {
+	db, cancel := r.session(ctx, QueryLookup)
+	defer cancel()
+
+	result := db.Where("id = ? AND customer_id = ?", noteID, customerID).Delete(&domain.CustomerNote{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// PinNote pins a note to the top of the customer's notes. Pinning an already
+// pinned note keeps its original pin.
+func (r *customerRepository) PinNote(ctx context.Context, customerID, noteID, pinnedBy uuid.UUID) (*domain.CustomerNote, error) {
+	db, cancel := r.session(ctx, QueryLookup)
+	defer cancel()
+
+	note, err := r.GetNote(ctx, customerID, noteID)
+	if err != nil {
+		return nil, err
+	}
+	if note.IsPinned() {
+		return note, nil
 	}
 
-	return orders, total, nil
+	now := time.Now()
+	if err := db.Model(note).Updates(map[string]interface{}{
+		"pinned_at": now,
+		"pinned_by": pinnedBy,
+	}).Error; err != nil {
+		return nil, err
+	}
+	note.PinnedAt = &now
+	note.PinnedBy = &pinnedBy
+	return note, nil
 }
 
-func (r *customerRepository) AddNote(customerID uuid.UUID, note string, isPrivate bool, createdBy uuid.UUID) (*domain.CustomerNote, error) {
-	n := &domain.CustomerNote{
-		CustomerID: customerID,
-		Note:       note,
-		IsPrivate:  isPrivate,
-		CreatedBy:  &createdBy,
+func (r *customerRepository) UnpinNote(ctx context.Context, customerID, noteID uuid.UUID) (*domain.CustomerNote, error) {
+	db, cancel := r.session(ctx, QueryLookup)
+	defer cancel()
+
+	note, err := r.GetNote(ctx, customerID, noteID)
+	if err != nil {
+		return nil, err
 	}
-	if err := r.db.Create(n).Error; err != nil {
+
+	if err := db.Model(note).Updates(map[string]interface{}{
+		"pinned_at": nil,
+		"pinned_by": nil,
+	}).Error; err != nil {
 		return nil, err
 	}
-	return n, nil
+	note.PinnedAt = nil
+	note.PinnedBy = nil
+	return note, nil
 }
 
-func (r *customerRepository) GetNotes(customerID uuid.UUID) ([]domain.CustomerNote, error) {
-	var notes []domain.CustomerNote
-	if err := r.db.Where("customer_id = ?", customerID).Order("created_at DESC").Find(&notes).Error; err != nil {
+func (r *customerRepository) CountNotes(ctx context.Context, customerID uuid.UUID, filter domain.CustomerNoteFilter) (*domain.CustomerNoteCounts, error) {
+	db, cancel := r.session(ctx, QueryList)
+	defer cancel()
+
+	var rows []struct {
+		Category  string
+		IsPrivate bool
+		Count     int64
+	}
+	if err := notesQuery(db, customerID, filter).
+		Select("category, is_private, COUNT(*) AS count").
+		Group("category, is_private").
+		Scan(&rows).Error; err != nil {
 		return nil, err
 	}
-	return notes, nil
+
+	counts := &domain.CustomerNoteCounts{ByCategory: map[string]int64{}}
+	for _, row := range rows {
+		counts.Total += row.Count
+		counts.ByCategory[row.Category] += row.Count
+		if row.IsPrivate {
+			counts.Private += row.Count
+		}
+	}
+	return counts, nil
+}
+
+// notesQuery scopes a query to a customer's notes matching the filter
+func notesQuery(db *gorm.DB, customerID uuid.UUID, filter domain.CustomerNoteFilter) *gorm.DB {
+	query := db.Model(&domain.CustomerNote{}).Where("customer_id = ?", customerID)
+
+	if filter.Category != "" {
+		query = query.Where("category = ?", filter.Category)
+	}
+	if filter.IsPrivate != nil {
+		query = query.Where("is_private = ?", *filter.IsPrivate)
+	}
+	if filter.CreatedBy != nil {
+		query = query.Where("created_by = ?", *filter.CreatedBy)
+	}
+	if filter.DateFrom != nil {
+		query = query.Where("created_at >= ?", *filter.DateFrom)
+	}
+	if filter.DateTo != nil {
+		query = query.Where("created_at <= ?", *filter.DateTo)
+	}
+	return query
 }
 
-func (r *customerRepository) GetActivity(customerID uuid.UUID, page, limit int) ([]domain.CustomerActivity, int64, error) {
+func (r *customerRepository) GetActivity(ctx context.Context, customerID uuid.UUID, page, limit int) ([]domain.CustomerActivity, int64, error) {
+	db, cancel := r.session(ctx, QueryList)
+	defer cancel()
+
 	var activities []domain.CustomerActivity
 	var total int64
 
-	query := r.db.Model(&domain.CustomerActivity{}).Where("customer_id = ?", customerID)
-	query.Count(&total)
+	query := db.Model(&domain.CustomerActivity{}).Where("customer_id = ?", customerID)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
 
+	// Pinned activities first, most recently pinned on top
 	offset := (page - 1) * limit
-	if err := query.Order("created_at DESC").Offset(offset).Limit(limit).Find(&activities).Error; err != nil {
+	if err := query.Order("pinned_at IS NULL, pinned_at DESC, created_at DESC").Offset(offset).Limit(limit).Find(&activities).Error; err != nil {
 		return nil, 0, err
 	}
 	return activities, total, nil
 }
 
-func (r *customerRepository) GetSegments() ([]domain.CustomerSegment, error) {
+// GetActivityAfter is the keyset-paginated GetActivity. Activities come
+// newest first without pinned ones being lifted to the top; the pinned list is
+// served separately by GetPinnedActivities.
+func (r *customerRepository) GetActivityAfter(ctx context.Context, customerID uuid.UUID, after *domain.Cursor, limit int) ([]domain.CustomerActivity, string, error) {
+	db, cancel := r.session(ctx, QueryList)
+	defer cancel()
+
+	var activities []domain.CustomerActivity
+	query := db.Model(&domain.CustomerActivity{}).Where("customer_id = ?", customerID)
+	if err := keysetOrder(query, after, true, limit).Find(&activities).Error; err != nil {
+		return nil, "", err
+	}
+	activities, next := cursorPage(activities, limit, activityCursor)
+	return activities, next, nil
+}
+
+func (r *customerRepository) GetPinnedActivities(ctx context.Context, customerID uuid.UUID) ([]domain.CustomerActivity, error) {
+	db, cancel := r.session(ctx, QueryList)
+	defer cancel()
+
+	var activities []domain.CustomerActivity
+	if err := db.Where("customer_id = ? AND pinned_at IS NOT NULL", customerID).
+		Order("pinned_at DESC").
+		Find(&activities).Error; err != nil {
+		return nil, err
+	}
+	return activities, nil
+}
+
+// PinActivity pins an activity to the top of the customer's timeline. Pinning
+// an already pinned activity keeps its original pin.
+func (r *customerRepository) PinActivity(ctx context.Context, customerID, activityID, pinnedBy uuid.UUID) (*domain.CustomerActivity, error) {
+	db, cancel := r.session(ctx, QueryLookup)
+	defer cancel()
+
+	var activity domain.CustomerActivity
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("id = ? AND customer_id = ?", activityID, customerID).First(&activity).Error; err != nil {
+			return err
+		}
+		if activity.IsPinned() {
+			return nil
+		}
+
+		var pinned int64
+		if err := tx.Model(&domain.CustomerActivity{}).
+			Where("customer_id = ? AND pinned_at IS NOT NULL", customerID).
+			Count(&pinned).Error; err != nil {
+			return err
+		}
+		if pinned >= domain.MaxPinnedActivities {
+			return domain.ErrPinnedActivityLimit
+		}
+
+		now := time.Now()
+		if err := tx.Model(&activity).Updates(map[string]interface{}{
+			"pinned_at": now,
+			"pinned_by": pinnedBy,
+		}).Error; err != nil {
+			return err
+		}
+		activity.PinnedAt = &now
+		activity.PinnedBy = &pinnedBy
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &activity, nil
+}
+
+func (r *customerRepository) UnpinActivity(ctx context.Context, customerID, activityID uuid.UUID) (*domain.CustomerActivity, error) {
+	db, cancel := r.session(ctx, QueryLookup)
+	defer cancel()
+
+	var activity domain.CustomerActivity
+	if err := db.Where("id = ? AND customer_id = ?", activityID, customerID).First(&activity).Error; err != nil {
+		return nil, err
+	}
+
+	if err := db.Model(&activity).Updates(map[string]interface{}{
+		"pinned_at": nil,
+		"pinned_by": nil,
+	}).Error; err != nil {
+		return nil, err
+	}
+	activity.PinnedAt = nil
+	activity.PinnedBy = nil
+	return &activity, nil
+}
+
+// GetTimeline returns the customer's most recent activities, notes, status
+// changes and segment assignments, up to filter.Depth() of each requested
+// type, newest first. The total counts every matching entry.
+func (r *customerRepository) GetTimeline(ctx context.Context, customerID uuid.UUID, filter domain.TimelineFilter) ([]domain.TimelineEntry, int64, error) {
+	db, cancel := r.session(ctx, QueryReport)
+	defer cancel()
+
+	var entries []domain.TimelineEntry
+	var total int64
+	depth := filter.Depth()
+
+	// Status changes are stored as activities
+	activityTypes := db.Model(&domain.CustomerActivity{}).Where("customer_id = ?", customerID)
+	switch {
+	case filter.Includes(domain.TimelineTypeActivity) && filter.Includes(domain.TimelineTypeStatusChange):
+	case filter.Includes(domain.TimelineTypeActivity):
+		activityTypes = activityTypes.Where("type <> ?", domain.ActivityTypeStatusChange)
+	case filter.Includes(domain.TimelineTypeStatusChange):
+		activityTypes = activityTypes.Where("type = ?", domain.ActivityTypeStatusChange)
+	default:
+		activityTypes = nil
+	}
+	if activityTypes != nil {
+		var activities []domain.CustomerActivity
+		var count int64
+		if err := activityTypes.Session(&gorm.Session{}).Count(&count).Error; err != nil {
+			return nil, 0, err
+		}
+		if err := activityTypes.Order("created_at DESC").Limit(depth).Find(&activities).Error; err != nil {
+			return nil, 0, err
+		}
+		total += count
+		for _, activity := range activities {
+			entryType := domain.TimelineTypeActivity
+			if activity.Type == domain.ActivityTypeStatusChange {
+				entryType = domain.TimelineTypeStatusChange
+			}
+			entries = append(entries, domain.TimelineEntry{
+				ID:         activity.ID.String(),
+				Type:       entryType,
+				Title:      activity.Title,
+				Details:    activity.Details,
+				OccurredAt: activity.CreatedAt,
+				Data:       activity,
+			})
+		}
+	}
+
+	if filter.Includes(domain.TimelineTypeNote) {
+		var notes []domain.CustomerNote
+		var count int64
+		query := db.Model(&domain.CustomerNote{}).Where("customer_id = ?", customerID)
+		if err := query.Session(&gorm.Session{}).Count(&count).Error; err != nil {
+			return nil, 0, err
+		}
+		if err := query.Order("created_at DESC").Limit(depth).Find(&notes).Error; err != nil {
+			return nil, 0, err
+		}
+		total += count
+		for _, note := range notes {
+			entries = append(entries, domain.TimelineEntry{
+				ID:         note.ID.String(),
+				Type:       domain.TimelineTypeNote,
+				Title:      "Note added",
+				Details:    note.Note,
+				OccurredAt: note.CreatedAt,
+				Data:       note,
+			})
+		}
+	}
+
+	if filter.Includes(domain.TimelineTypeSegment) {
+		var events []domain.SegmentMembershipEvent
+		var count int64
+		query := db.Model(&domain.SegmentMembershipEvent{}).Where("customer_id = ?", customerID)
+		if err := query.Session(&gorm.Session{}).Count(&count).Error; err != nil {
+			return nil, 0, err
+		}
+		if err := query.Order("occurred_at DESC").Limit(depth).Find(&events).Error; err != nil {
+			return nil, 0, err
+		}
+		total += count
+
+		segmentIDs := make([]uuid.UUID, 0, len(events))
+		for _, event := range events {
+			segmentIDs = append(segmentIDs, event.SegmentID)
+		}
+		var segments []domain.CustomerSegment
+		if len(segmentIDs) > 0 {
+			if err := db.Where("id IN ?", segmentIDs).Find(&segments).Error; err != nil {
+				return nil, 0, err
+			}
+		}
+		segmentsByID := make(map[uuid.UUID]domain.CustomerSegment, len(segments))
+		for _, segment := range segments {
+			segmentsByID[segment.ID] = segment
+		}
+
+		for _, event := range events {
+			entry := domain.TimelineEntry{
+				ID:         event.ID.String(),
+				Type:       domain.TimelineTypeSegment,
+				Title:      event.TimelineTitle(),
+				OccurredAt: event.OccurredAt,
+				Data:       event,
+			}
+			if segment, ok := segmentsByID[event.SegmentID]; ok {
+				entry.Details = segment.Name
+			}
+			entries = append(entries, entry)
+		}
+	}
+
+	domain.SortTimeline(entries)
+	return entries, total, nil
+}
+
+func (r *customerRepository) GetSegments(ctx context.Context) ([]domain.CustomerSegment, error) {
+	db, cancel := r.session(ctx, QueryList)
+	defer cancel()
+
 	var segments []domain.CustomerSegment
-	if err := r.db.Find(&segments).Error; err != nil {
+	if err := db.Find(&segments).Error; err != nil {
 		return nil, err
 	}
 	return segments, nil
 }
 
-func (r *customerRepository) CreateSegment(name, description string, conditions interface{}, color string) (*domain.CustomerSegment, error) {
+func (r *customerRepository) CreateSegment(ctx context.Context, name, description string, conditions interface{}, color string) (*domain.CustomerSegment, error) {
+	db, cancel := r.session(ctx, QueryLookup)
+	defer cancel()
+
 	segment := &domain.CustomerSegment{
 		Name:        name,
 		Description: description,
 		Color:       color,
 	}
-	if err := r.db.Create(segment).Error; err != nil {
+	if err := db.Create(segment).Error; err != nil {
 		return nil, err
 	}
 	return segment, nil
 }
 
-func (r *customerRepository) UpdateSegment(id uuid.UUID, name, description *string, conditions interface{}, color *string) (*domain.CustomerSegment, error) {
+func (r *customerRepository) UpdateSegment(ctx context.Context, id uuid.UUID, name, description *string, conditions interface{}, color *string) (*domain.CustomerSegment, error) {
+	db, cancel := r.session(ctx, QueryLookup)
+	defer cancel()
+
 	var segment domain.CustomerSegment
-	if err := r.db.First(&segment, "id = ?", id).Error; err != nil {
+	if err := db.First(&segment, "id = ?", id).Error; err != nil {
 		return nil, err
 	}
 
@@ -293,48 +806,172 @@ func (r *customerRepository) UpdateSegment(id uuid.UUID, name, description *stri
 		updates["color"] = *color
 	}
 
-	if err := r.db.Model(&segment).Updates(updates).Error; err != nil {
+	if err := db.Model(&segment).Updates(updates).Error; err != nil {
 		return nil, err
 	}
 	return &segment, nil
 }
 
-func (r *customerRepository) DeleteSegment(id uuid.UUID) error {
-	return r.db.Delete(&domain.CustomerSegment{}, "id = ?", id).Error
+func (r *customerRepository) DeleteSegment(ctx context.Context, id uuid.UUID) error {
+	db, cancel := r.session(ctx, QueryLookup)
+	defer cancel()
+
+	return db.Delete(&domain.CustomerSegment{}, "id = ?", id).Error
 }
 
-func (r *customerRepository) AssignSegments(customerID uuid.UUID, segmentIDs []uuid.UUID) error {
-	// Clear existing assignments
-	r.db.Where("customer_id = ?", customerID).Delete(&domain.CustomerSegmentAssignment{})
+// AssignSegments sets the customer's segments, recording the segments entered
+// and left as manual membership events by the user ctx belongs to
+func (r *customerRepository) AssignSegments(ctx context.Context, customerID uuid.UUID, segmentIDs []uuid.UUID) error {
+	db, cancel := r.session(ctx, QueryLookup)
+	defer cancel()
 
-	// Create new assignments
-	for _, segmentID := range segmentIDs {
-		assignment := &domain.CustomerSegmentAssignment{
-			CustomerID: customerID,
-			SegmentID:  segmentID,
-		}
-		if err := r.db.Create(assignment).Error; err != nil {
+	return db.Transaction(func(tx *gorm.DB) error {
+		current, err := customerSegmentIDs(tx, customerID)
+		if err != nil {
 			return err
 		}
-	}
-	return nil
+		keep := make(map[uuid.UUID]bool, len(segmentIDs))
+		for _, segmentID := range segmentIDs {
+			keep[segmentID] = true
+		}
+
+		now := time.Now()
+		actor := segmentActor(ctx)
+		var events []domain.SegmentMembershipEvent
+		var left []uuid.UUID
+		for _, segmentID := range current {
+			if keep[segmentID] {
+				delete(keep, segmentID)
+				continue
+			}
+			left = append(left, segmentID)
+			events = append(events, domain.SegmentMembershipEvent{
+				CustomerID: customerID, SegmentID: segmentID,
+				Change: domain.SegmentMembershipLeft, Source: domain.SegmentSourceManual,
+				ActorID: actor, OccurredAt: now,
+			})
+		}
+		if len(left) > 0 {
+			if err := tx.Where("customer_id = ? AND segment_id IN ?", customerID, left).
+				Delete(&domain.CustomerSegmentAssignment{}).Error; err != nil {
+				return err
+			}
+		}
+
+		// Segments not already assigned, in the order given
+		for _, segmentID := range segmentIDs {
+			if !keep[segmentID] {
+				continue
+			}
+			delete(keep, segmentID)
+			if err := tx.Create(&domain.CustomerSegmentAssignment{
+				CustomerID: customerID,
+				SegmentID:  segmentID,
+			}).Error; err != nil {
+				return err
+			}
+			events = append(events, domain.SegmentMembershipEvent{
+				CustomerID: customerID, SegmentID: segmentID,
+				Change: domain.SegmentMembershipEntered, Source: domain.SegmentSourceManual,
+				ActorID: actor, OccurredAt: now,
+			})
+		}
+		return recordSegmentMembership(tx, events)
+	})
 }
 
-func (r *customerRepository) Export(filter domain.CustomerListFilter, format string) (interface{}, error) {
-	customers, _, err := r.ListAdmin(filter)
+// Export returns the customers of filter's page without counting the total,
+// under the export timeout rather than the list one. The query is cancelled
+// with ctx, so an export is abandoned when the client disconnects.
+func (r *customerRepository) Export(ctx context.Context, filter domain.CustomerListFilter, format string) (interface{}, error) {
+	db, cancel := r.session(ctx, QueryExport)
+	defer cancel()
+	db = Replica(db)
+
+	column, desc, err := domain.CustomerSort.Resolve(filter.Sort)
 	if err != nil {
 		return nil, err
 	}
+
+	var customers []domain.Customer
+	offset := (filter.Page - 1) * filter.Limit
+	if err := r.adminListQuery(db, filter).
+		Order(clause.OrderByColumn{Column: clause.Column{Name: column}, Desc: desc}).
+		Offset(offset).Limit(filter.Limit).
+		Find(&customers).Error; err != nil {
+		return nil, err
+	}
 	return customers, nil
 }
 
-func (r *customerRepository) GetStats() (*CustomerStats, error) {
-	stats := &CustomerStats{}
+// GetStats returns the customer totals and the signups and first purchases
+// of the window's period. Today and this month are in the window's
+// timezone. The per-day series is counted here rather than with date_trunc
+// so days follow the timezone's DST changes; a period has at most 90 days.
+func (r *customerRepository) GetStats(ctx context.Context, window domain.StatsWindow) (*CustomerStats, error) {
+	db, cancel := r.session(ctx, QueryReport)
+	defer cancel()
+	db = Replica(db)
+
+	stats := &CustomerStats{
+		Period:   window.Period,
+		Timezone: window.Location.String(),
+		From:     window.Start,
+		To:       window.End,
+	}
+	customers := func() *gorm.DB { return db.Model(&domain.Customer{}) }
+	start, end, previousStart := window.Start.UTC(), window.End.UTC(), window.PreviousStart.UTC()
+
+	if err := customers().Count(&stats.TotalCustomers).Error; err != nil {
+		return nil, err
+	}
+	if err := customers().Where("status = ?", "active").Count(&stats.ActiveCustomers).Error; err != nil {
+		return nil, err
+	}
+	if err := customers().Where("created_at >= ? AND created_at < ?", window.Today.UTC(), end).Count(&stats.NewCustomersToday).Error; err != nil {
+		return nil, err
+	}
+	if err := customers().Where("created_at >= ? AND created_at < ?", window.Month.UTC(), end).Count(&stats.NewCustomersMonth).Error; err != nil {
+		return nil, err
+	}
+
+	var signups, firstPurchases []time.Time
+	if err := customers().Where("created_at >= ? AND created_at < ?", start, end).Pluck("created_at", &signups).Error; err != nil {
+		return nil, err
+	}
+	if err := customers().Where("first_order_at >= ? AND first_order_at < ?", start, end).Pluck("first_order_at", &firstPurchases).Error; err != nil {
+		return nil, err
+	}
+	var previousSignups, previousFirstPurchases int64
+	if err := customers().Where("created_at >= ? AND created_at < ?", previousStart, start).Count(&previousSignups).Error; err != nil {
+		return nil, err
+	}
+	if err := customers().Where("first_order_at >= ? AND first_order_at < ?", previousStart, start).Count(&previousFirstPurchases).Error; err != nil {
+		return nil, err
+	}
+	stats.NewCustomers = domain.NewStatsDelta(int64(len(signups)), previousSignups)
+	stats.FirstPurchases = domain.NewStatsDelta(int64(len(firstPurchases)), previousFirstPurchases)
+	stats.Series = domain.NewStatsSeries(window, signups, firstPurchases)
 
-	r.db.Model(&domain.Customer{}).Count(&stats.TotalCustomers)
-	r.db.Model(&domain.Customer{}).Where("status = ?", "active").Count(&stats.ActiveCustomers)
-	r.db.Model(&domain.Customer{}).Where("created_at >= CURRENT_DATE").Count(&stats.NewCustomersToday)
-	r.db.Model(&domain.Customer{}).Where("created_at >= date_trunc('month', CURRENT_DATE)").Count(&stats.NewCustomersMonth)
+	stats.ChurnRisk = make(map[string]int64, len(domain.ChurnRiskLevels))
+	for _, level := range domain.ChurnRiskLevels {
+		stats.ChurnRisk[level] = 0
+	}
+	var levels []struct {
+		ChurnRisk string
+		Count     int64
+	}
+	err := customers().
+		Select("churn_risk, COUNT(*) AS count").
+		Where("churn_risk IN ?", domain.ChurnRiskLevels).
+		Group("churn_risk").
+		Scan(&levels).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, level := range levels {
+		stats.ChurnRisk[level.ChurnRisk] = level.Count
+	}
 
 	return stats, nil
 }
@@ -0,0 +1,548 @@
+package persistence
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/Ecom-micro-template/service-customer/internal/domain/shared"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestCustomerRepository_GetNotesFiltersAndPaginates(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t, &domain.CustomerNote{})
+	repo := NewCustomerRepository(db, DefaultQueryTimeouts())
+	customerID, admin, otherAdmin := uuid.New(), uuid.New(), uuid.New()
+
+	for i := 0; i < 5; i++ {
+		_, err := repo.AddNote(ctx, customerID, "Prefers courier delivery", domain.NoteCategoryShipping, false, admin)
+		require.NoError(t, err)
+	}
+	_, err := repo.AddNote(ctx, customerID, "Chargeback on last order", domain.NoteCategoryFraud, true, otherAdmin)
+	require.NoError(t, err)
+	general, err := repo.AddNote(ctx, customerID, "Called about sizing", "", false, admin)
+	require.NoError(t, err)
+	assert.Equal(t, domain.NoteCategoryGeneral, general.Category)
+	_, err = repo.AddNote(ctx, uuid.New(), "Another customer", domain.NoteCategoryShipping, false, admin)
+	require.NoError(t, err)
+
+	notes, total, err := repo.GetNotes(ctx, customerID, domain.CustomerNoteFilter{Page: 2, Limit: 3})
+	require.NoError(t, err)
+	assert.Equal(t, int64(7), total)
+	assert.Len(t, notes, 3)
+
+	notes, total, err = repo.GetNotes(ctx, customerID, domain.CustomerNoteFilter{Category: domain.NoteCategoryShipping, Page: 1, Limit: 20})
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), total)
+	assert.Len(t, notes, 5)
+
+	private := true
+	notes, _, err = repo.GetNotes(ctx, customerID, domain.CustomerNoteFilter{IsPrivate: &private, Page: 1, Limit: 20})
+	require.NoError(t, err)
+	require.Len(t, notes, 1)
+	assert.Equal(t, domain.NoteCategoryFraud, notes[0].Category)
+
+	_, total, err = repo.GetNotes(ctx, customerID, domain.CustomerNoteFilter{CreatedBy: &otherAdmin, Page: 1, Limit: 20})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+
+	tomorrow := time.Now().Add(24 * time.Hour)
+	_, total, err = repo.GetNotes(ctx, customerID, domain.CustomerNoteFilter{DateFrom: &tomorrow, Page: 1, Limit: 20})
+	require.NoError(t, err)
+	assert.Zero(t, total)
+}
+
+func TestCustomerRepository_CountNotes(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t, &domain.CustomerNote{})
+	repo := NewCustomerRepository(db, DefaultQueryTimeouts())
+	customerID, admin := uuid.New(), uuid.New()
+
+	for _, category := range []string{domain.NoteCategoryVIP, domain.NoteCategoryVIP, domain.NoteCategorySupport} {
+		_, err := repo.AddNote(ctx, customerID, "note", category, false, admin)
+		require.NoError(t, err)
+	}
+	_, err := repo.AddNote(ctx, customerID, "note", domain.NoteCategorySupport, true, admin)
+	require.NoError(t, err)
+
+	counts, err := repo.CountNotes(ctx, customerID, domain.CustomerNoteFilter{})
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), counts.Total)
+	assert.Equal(t, int64(1), counts.Private)
+	assert.Equal(t, map[string]int64{domain.NoteCategoryVIP: 2, domain.NoteCategorySupport: 2}, counts.ByCategory)
+
+	counts, err = repo.CountNotes(ctx, customerID, domain.CustomerNoteFilter{Category: domain.NoteCategorySupport})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), counts.Total)
+}
+
+func TestCustomerRepository_PinActivity(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t, &domain.CustomerActivity{})
+	repo := NewCustomerRepository(db, DefaultQueryTimeouts())
+	customerID, admin := uuid.New(), uuid.New()
+
+	var activities []domain.CustomerActivity
+	for i := 0; i < domain.MaxPinnedActivities+2; i++ {
+		activity := domain.CustomerActivity{
+			CustomerID: customerID,
+			Type:       "order",
+			Title:      "Order placed",
+			CreatedAt:  time.Now().Add(time.Duration(i) * time.Minute),
+		}
+		require.NoError(t, db.Create(&activity).Error)
+		activities = append(activities, activity)
+	}
+
+	// The oldest activity moves to the top of the timeline once pinned
+	pinned, err := repo.PinActivity(ctx, customerID, activities[0].ID, admin)
+	require.NoError(t, err)
+	require.True(t, pinned.IsPinned())
+	assert.Equal(t, admin, *pinned.PinnedBy)
+
+	timeline, _, err := repo.GetActivity(ctx, customerID, 1, 3)
+	require.NoError(t, err)
+	assert.Equal(t, activities[0].ID, timeline[0].ID)
+	assert.Equal(t, activities[len(activities)-1].ID, timeline[1].ID)
+
+	// Pinning again is a no-op
+	again, err := repo.PinActivity(ctx, customerID, activities[0].ID, uuid.New())
+	require.NoError(t, err)
+	assert.Equal(t, admin, *again.PinnedBy)
+
+	for _, activity := range activities[1:domain.MaxPinnedActivities] {
+		_, err := repo.PinActivity(ctx, customerID, activity.ID, admin)
+		require.NoError(t, err)
+	}
+	_, err = repo.PinActivity(ctx, customerID, activities[domain.MaxPinnedActivities].ID, admin)
+	assert.ErrorIs(t, err, domain.ErrPinnedActivityLimit)
+
+	unpinned, err := repo.UnpinActivity(ctx, customerID, activities[0].ID)
+	require.NoError(t, err)
+	assert.False(t, unpinned.IsPinned())
+
+	_, err = repo.PinActivity(ctx, customerID, activities[domain.MaxPinnedActivities].ID, admin)
+	require.NoError(t, err)
+
+	pinnedList, err := repo.GetPinnedActivities(ctx, customerID)
+	require.NoError(t, err)
+	assert.Len(t, pinnedList, domain.MaxPinnedActivities)
+
+	// Activities of other customers can't be pinned through this customer
+	_, err = repo.PinActivity(ctx, uuid.New(), activities[0].ID, admin)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
+
+func TestCustomerRepository_EditAndPinNotes(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t, &domain.CustomerNote{})
+	repo := NewCustomerRepository(db, DefaultQueryTimeouts())
+	customerID, author, editor := uuid.New(), uuid.New(), uuid.New()
+
+	older, err := repo.AddNote(ctx, customerID, "Asked about bulk pricing", domain.NoteCategoryGeneral, false, author)
+	require.NoError(t, err)
+	require.NoError(t, db.Model(older).Update("created_at", time.Now().Add(-time.Hour)).Error)
+	newer, err := repo.AddNote(ctx, customerID, "Refund processed", domain.NoteCategoryBilling, false, author)
+	require.NoError(t, err)
+
+	text, private := "Asked about bulk pricing for raya, cc @farid", true
+	updated, err := repo.UpdateNote(ctx, customerID, older.ID, &domain.UpdateCustomerNoteRequest{Note: &text, IsPrivate: &private}, editor)
+	require.NoError(t, err)
+	assert.Equal(t, text, updated.Note)
+	assert.True(t, updated.IsPrivate)
+	assert.Equal(t, domain.NoteCategoryGeneral, updated.Category)
+	require.NotNil(t, updated.EditedBy)
+	assert.Equal(t, editor, *updated.EditedBy)
+
+	// The older note floats above the newer one once pinned
+	_, err = repo.PinNote(ctx, customerID, older.ID, editor)
+	require.NoError(t, err)
+	notes, _, err := repo.GetNotes(ctx, customerID, domain.CustomerNoteFilter{Page: 1, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, notes, 2)
+	assert.Equal(t, older.ID, notes[0].ID)
+
+	unpinned, err := repo.UnpinNote(ctx, customerID, older.ID)
+	require.NoError(t, err)
+	assert.False(t, unpinned.IsPinned())
+	notes, _, err = repo.GetNotes(ctx, customerID, domain.CustomerNoteFilter{Page: 1, Limit: 10})
+	require.NoError(t, err)
+	assert.Equal(t, newer.ID, notes[0].ID)
+
+	// Notes of other customers can't be reached through this customer
+	assert.ErrorIs(t, repo.DeleteNote(ctx, uuid.New(), newer.ID), gorm.ErrRecordNotFound)
+	require.NoError(t, repo.DeleteNote(ctx, customerID, newer.ID))
+	_, err = repo.GetNote(ctx, customerID, newer.ID)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
+
+func TestParseMentions(t *testing.T) {
+	mentions := domain.ParseMentions("@Farid please call back; cc @siti.rahman and @farid. Email sent to ops@example.com")
+	assert.Equal(t, []string{"farid", "siti.rahman"}, mentions)
+	assert.Empty(t, domain.ParseMentions("No mentions here"))
+}
+
+func TestCustomerRepository_ListAdminSort(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t, &domain.Customer{})
+	repo := NewCustomerRepository(db, DefaultQueryTimeouts())
+	for i, email := range []string{"b@example.com", "c@example.com", "a@example.com"} {
+		require.NoError(t, db.Create(&domain.Customer{Email: email, TotalSpent: float64(i * 100)}).Error)
+	}
+	emails := func(filter domain.CustomerListFilter) []string {
+		t.Helper()
+		customers, _, err := repo.ListAdmin(ctx, filter)
+		require.NoError(t, err)
+		out := make([]string, len(customers))
+		for i := range customers {
+			out[i] = customers[i].Email
+		}
+		return out
+	}
+
+	sort, err := domain.CustomerSort.Parse("email", "ASC")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a@example.com", "b@example.com", "c@example.com"}, emails(domain.CustomerListFilter{Page: 1, Limit: 10, Sort: sort}))
+	sort, err = domain.CustomerSort.Parse("total_spent", "")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a@example.com", "c@example.com", "b@example.com"}, emails(domain.CustomerListFilter{Page: 1, Limit: 10, Sort: sort}))
+
+	for _, input := range [][2]string{{"email; DROP TABLE customers", "asc"}, {"password", ""}, {"email", "asc, id"}} {
+		_, err := domain.CustomerSort.Parse(input[0], input[1])
+		assert.ErrorIs(t, err, domain.ErrInvalidSort, input)
+	}
+
+	// Sorts that bypass Parse are still checked before reaching ORDER BY
+	_, _, err = repo.ListAdmin(ctx, domain.CustomerListFilter{Page: 1, Limit: 10, Sort: domain.Sort{Field: "email desc; --", Direction: domain.SortAsc}})
+	assert.ErrorIs(t, err, domain.ErrInvalidSort)
+	_, _, err = repo.ListAdmin(ctx, domain.CustomerListFilter{Page: 1, Limit: 10, Sort: domain.Sort{Field: "email", Direction: "asc nulls first"}})
+	assert.ErrorIs(t, err, domain.ErrInvalidSort)
+	_, _, err = repo.ListAdminAfter(ctx, domain.CustomerListFilter{Limit: 10, Sort: domain.Sort{Field: "email", Direction: domain.SortAsc}}, nil)
+	assert.ErrorIs(t, err, domain.ErrInvalidSort)
+}
+
+func TestCustomerRepository_QueryTimeouts(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t, &domain.Customer{})
+	customer := &domain.Customer{Email: "a@example.com"}
+	require.NoError(t, db.Create(customer).Error)
+	filter := domain.CustomerListFilter{Page: 1, Limit: 10, Sort: domain.Sort{Field: "created_at", Direction: domain.SortDesc}}
+
+	// Only the list class is given a deadline it can't meet
+	repo := NewCustomerRepository(db, QueryTimeouts{List: time.Nanosecond})
+	_, _, err := repo.ListAdmin(ctx, filter)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	_, err = repo.GetByID(ctx, customer.ID)
+	assert.NoError(t, err)
+	exported, err := repo.Export(ctx, filter, "csv")
+	require.NoError(t, err)
+	assert.Len(t, exported, 1)
+
+	// An export is abandoned once its request is cancelled
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = NewCustomerRepository(db, DefaultQueryTimeouts()).Export(cancelled, filter, "csv")
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestQueryTimeouts_Bound(t *testing.T) {
+	timeouts := QueryTimeouts{Lookup: time.Second, Report: time.Hour}
+
+	ctx, cancel := timeouts.Bound(context.Background(), QueryLookup)
+	defer cancel()
+	deadline, ok := ctx.Deadline()
+	require.True(t, ok)
+	assert.WithinDuration(t, time.Now().Add(time.Second), deadline, 100*time.Millisecond)
+
+	// A caller's earlier deadline is kept
+	ctx, cancel = timeouts.Bound(ctx, QueryReport)
+	defer cancel()
+	bounded, _ := ctx.Deadline()
+	assert.Equal(t, deadline, bounded)
+
+	// Zero leaves the class unbounded
+	ctx, cancel = timeouts.Bound(context.Background(), QueryExport)
+	defer cancel()
+	_, ok = ctx.Deadline()
+	assert.False(t, ok)
+}
+
+func TestCustomerRepository_RegionScope(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t, &domain.Customer{}, &domain.Address{})
+	repo := NewCustomerRepository(db, DefaultQueryTimeouts())
+
+	newCustomer := func(email, state string, isDefault bool) uuid.UUID {
+		customer := &domain.Customer{Email: email}
+		require.NoError(t, db.Create(customer).Error)
+		require.NoError(t, db.Create(&domain.Address{
+			UserID: customer.ID, RecipientName: "Test", Phone: "0123", AddressLine1: "1 Jalan",
+			City: "City", State: state, Postcode: "10000", Country: "MY", IsDefault: isDefault,
+		}).Error)
+		return customer.ID
+	}
+	selangor := newCustomer("selangor@example.com", "Selangor", true)
+	johor := newCustomer("johor@example.com", "Johor", true)
+	nonDefault := newCustomer("other@example.com", "Selangor", false)
+
+	// A soft-deleted default address no longer places the customer in the region
+	deleted := newCustomer("deleted@example.com", "Selangor", true)
+	require.NoError(t, db.Where("user_id = ?", deleted).Delete(&domain.Address{}).Error)
+
+	filter := domain.CustomerListFilter{Page: 1, Limit: 20, Sort: domain.Sort{Field: "created_at", Direction: domain.SortAsc}}
+	_, total, err := repo.ListAdmin(ctx, filter)
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), total)
+
+	filter.Region = &domain.RegionScope{States: []string{" selangor "}}
+	customers, total, err := repo.ListAdmin(ctx, filter)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+	require.Len(t, customers, 1)
+	assert.Equal(t, selangor, customers[0].ID)
+
+	filter.Region = &domain.RegionScope{}
+	_, total, err = repo.ListAdmin(ctx, filter)
+	require.NoError(t, err)
+	assert.Zero(t, total)
+
+	region := &domain.RegionScope{States: []string{"Johor"}}
+	for id, want := range map[uuid.UUID]bool{selangor: false, johor: true, nonDefault: false, deleted: false} {
+		ok, err := repo.InRegion(ctx, id, region)
+		require.NoError(t, err)
+		assert.Equal(t, want, ok)
+	}
+	ok, err := repo.InRegion(ctx, selangor, nil)
+	require.NoError(t, err)
+	assert.True(t, ok)
+}
+
+func TestCustomerRepository_GetTimeline(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t, &domain.Customer{}, &domain.CustomerActivity{}, &domain.CustomerNote{},
+		&domain.CustomerSegment{}, &domain.SegmentMembershipEvent{}, &domain.Address{}, &domain.BlocklistEntry{})
+	repo := NewCustomerRepository(db, DefaultQueryTimeouts())
+
+	customer := &domain.Customer{Email: "timeline@example.com", Status: "active"}
+	require.NoError(t, db.Create(customer).Error)
+	base := time.Now().Add(-time.Hour)
+
+	require.NoError(t, db.Create(&domain.CustomerActivity{CustomerID: customer.ID, Type: domain.ActivityTypeProfile, Title: "Profile updated", CreatedAt: base}).Error)
+	require.NoError(t, db.Create(&domain.CustomerNote{CustomerID: customer.ID, Note: "Asked about returns", Category: domain.NoteCategorySupport, CreatedAt: base.Add(time.Minute)}).Error)
+	segment := &domain.CustomerSegment{Name: "VIP"}
+	require.NoError(t, db.Create(segment).Error)
+	require.NoError(t, db.Create(&domain.SegmentMembershipEvent{CustomerID: customer.ID, SegmentID: segment.ID,
+		Change: domain.SegmentMembershipEntered, Source: domain.SegmentSourceManual, OccurredAt: base.Add(2 * time.Minute)}).Error)
+
+	// Changing the status records a status change; an unchanged status doesn't
+	blocked, active := shared.StatusBlocked, shared.StatusActive
+	_, err := repo.Update(ctx, customer.ID, &domain.UpdateCustomerRequest{Status: &active})
+	require.NoError(t, err)
+	_, err = repo.Update(ctx, customer.ID, &domain.UpdateCustomerRequest{Status: &blocked})
+	require.NoError(t, err)
+
+	entries, total, err := repo.GetTimeline(ctx, customer.ID, domain.TimelineFilter{Page: 1, Limit: 20})
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), total)
+	require.Len(t, entries, 4)
+	assert.Equal(t, domain.TimelineTypeStatusChange, entries[0].Type)
+	assert.Equal(t, "active -> blocked", entries[0].Details)
+	assert.Equal(t, domain.TimelineTypeSegment, entries[1].Type)
+	assert.Equal(t, "Added to segment", entries[1].Title)
+	assert.Equal(t, "VIP", entries[1].Details)
+	assert.Equal(t, domain.TimelineTypeNote, entries[2].Type)
+	assert.Equal(t, domain.TimelineTypeActivity, entries[3].Type)
+
+	entries, total, err = repo.GetTimeline(ctx, customer.ID, domain.TimelineFilter{Types: []string{domain.TimelineTypeActivity, domain.TimelineTypeNote}, Page: 1, Limit: 20})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), total)
+	assert.Len(t, entries, 2)
+
+	// Each source is read only as deep as the requested page
+	entries, total, err = repo.GetTimeline(ctx, customer.ID, domain.TimelineFilter{Page: 1, Limit: 1})
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), total)
+	assert.Len(t, domain.TimelinePage(entries, domain.TimelineFilter{Page: 1, Limit: 1}), 1)
+}
+
+func TestCustomerRepository_ListAdminAfter(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t, &domain.Customer{}, &domain.Address{}, &domain.CustomerActivity{})
+	repo := NewCustomerRepository(db, DefaultQueryTimeouts())
+
+	// Two customers share a created_at so the id tie-breaker is exercised
+	base := time.Now().Add(-time.Hour).Truncate(time.Second)
+	var ids []uuid.UUID
+	for i, offset := range []time.Duration{0, time.Minute, time.Minute, 2 * time.Minute, 3 * time.Minute} {
+		customer := &domain.Customer{Email: uuid.NewString() + "@example.com", CreatedAt: base.Add(offset)}
+		if i == 4 {
+			customer.Status = shared.StatusBlocked
+		}
+		require.NoError(t, db.Create(customer).Error)
+		ids = append(ids, customer.ID)
+	}
+
+	collect := func(filter domain.CustomerListFilter) []uuid.UUID {
+		var seen []uuid.UUID
+		var after *domain.Cursor
+		for pages := 0; pages < 10; pages++ {
+			customers, next, err := repo.ListAdminAfter(ctx, filter, after)
+			require.NoError(t, err)
+			assert.LessOrEqual(t, len(customers), filter.Limit)
+			for _, c := range customers {
+				seen = append(seen, c.ID)
+			}
+			if next == "" {
+				return seen
+			}
+			after, err = domain.DecodeCursor(next)
+			require.NoError(t, err)
+		}
+		t.Fatal("cursor never ran out")
+		return nil
+	}
+
+	desc := collect(domain.CustomerListFilter{Limit: 2, Sort: domain.Sort{Field: "created_at", Direction: domain.SortDesc}})
+	require.Len(t, desc, 5)
+	assert.Equal(t, ids[4], desc[0])
+	assert.Equal(t, ids[0], desc[4])
+	assert.ElementsMatch(t, ids, desc, "no customer is skipped or repeated")
+
+	asc := collect(domain.CustomerListFilter{Limit: 2, Sort: domain.Sort{Field: "created_at", Direction: domain.SortAsc}})
+	require.Len(t, asc, 5)
+	for i := range asc {
+		assert.Equal(t, desc[len(desc)-1-i], asc[i])
+	}
+
+	active := collect(domain.CustomerListFilter{Limit: 3, Sort: domain.Sort{Field: "created_at", Direction: domain.SortDesc}, Status: string(shared.StatusActive)})
+	assert.NotContains(t, active, ids[4], "filters still apply")
+	assert.Len(t, active, 4)
+
+	// A customer created mid-way through paging doesn't shift later pages
+	first, next, err := repo.ListAdminAfter(ctx, domain.CustomerListFilter{Limit: 2}, nil)
+	require.NoError(t, err)
+	require.NoError(t, db.Create(&domain.Customer{Email: "late@example.com"}).Error)
+	after, err := domain.DecodeCursor(next)
+	require.NoError(t, err)
+	second, _, err := repo.ListAdminAfter(ctx, domain.CustomerListFilter{Limit: 2}, after)
+	require.NoError(t, err)
+	assert.Equal(t, desc[:4], []uuid.UUID{first[0].ID, first[1].ID, second[0].ID, second[1].ID})
+}
+
+func TestCustomerRepository_GetActivityAfter(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t, &domain.CustomerActivity{})
+	repo := NewCustomerRepository(db, DefaultQueryTimeouts())
+	customerID := uuid.New()
+
+	base := time.Now().Add(-time.Hour)
+	pinnedAt := time.Now()
+	for i := 0; i < 5; i++ {
+		activity := &domain.CustomerActivity{CustomerID: customerID, Type: "login", Title: "Logged in", CreatedAt: base.Add(time.Duration(i) * time.Minute)}
+		if i == 0 {
+			activity.PinnedAt = &pinnedAt
+		}
+		require.NoError(t, db.Create(activity).Error)
+	}
+	require.NoError(t, db.Create(&domain.CustomerActivity{CustomerID: uuid.New(), Type: "login", Title: "Other"}).Error)
+
+	page, next, err := repo.GetActivityAfter(ctx, customerID, nil, 3)
+	require.NoError(t, err)
+	require.Len(t, page, 3)
+	assert.NotEmpty(t, next)
+	assert.Nil(t, page[0].PinnedAt, "pinned activities aren't lifted in cursor mode")
+
+	after, err := domain.DecodeCursor(next)
+	require.NoError(t, err)
+	rest, next, err := repo.GetActivityAfter(ctx, customerID, after, 3)
+	require.NoError(t, err)
+	assert.Len(t, rest, 2)
+	assert.Empty(t, next)
+	assert.NotNil(t, rest[1].PinnedAt, "the pinned activity is the oldest")
+}
+
+func TestCustomerRepository_GetStats(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t, &domain.Customer{})
+	repo := NewCustomerRepository(db, DefaultQueryTimeouts())
+	kl, err := domain.LoadStatsTimezone("Asia/Kuala_Lumpur")
+	require.NoError(t, err)
+
+	// 01:30 on 2 March in Kuala Lumpur is still 1 March in UTC
+	now := time.Date(2025, 3, 1, 17, 30, 0, 0, time.UTC)
+	window, err := domain.NewStatsWindow(domain.StatsPeriod7Days, kl, now)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2025, 2, 24, 0, 0, 0, 0, kl), window.Start)
+	assert.Equal(t, time.Date(2025, 3, 3, 0, 0, 0, 0, kl), window.End)
+	assert.Equal(t, time.Date(2025, 2, 17, 0, 0, 0, 0, kl), window.PreviousStart)
+	assert.Equal(t, time.Date(2025, 3, 2, 0, 0, 0, 0, kl), window.Today)
+	assert.Equal(t, time.Date(2025, 3, 1, 0, 0, 0, 0, kl), window.Month)
+
+	create := func(email string, createdAt time.Time, firstOrderAt *time.Time) {
+		t.Helper()
+		require.NoError(t, db.Create(&domain.Customer{Email: email, CreatedAt: createdAt.UTC(), FirstOrderAt: firstOrderAt}).Error)
+	}
+	firstOrder := window.Today.Add(time.Hour)
+	create("first-day@example.com", window.Start, &firstOrder)
+	create("today@example.com", now, nil)
+	create("month@example.com", window.Month, nil)
+	create("previous-last@example.com", window.Start.Add(-time.Second), nil)
+	create("previous-first@example.com", window.PreviousStart, nil)
+	create("older@example.com", window.PreviousStart.Add(-time.Second), nil)
+
+	stats, err := repo.GetStats(ctx, window)
+	require.NoError(t, err)
+	assert.Equal(t, int64(6), stats.TotalCustomers)
+	assert.Equal(t, int64(1), stats.NewCustomersToday)
+	assert.Equal(t, int64(2), stats.NewCustomersMonth)
+	assert.Equal(t, "Asia/Kuala_Lumpur", stats.Timezone)
+
+	assert.Equal(t, int64(3), stats.NewCustomers.Current)
+	assert.Equal(t, int64(2), stats.NewCustomers.Previous)
+	require.NotNil(t, stats.NewCustomers.Change)
+	assert.Equal(t, 0.5, *stats.NewCustomers.Change)
+	assert.Equal(t, domain.StatsDelta{Current: 1}, stats.FirstPurchases, "no change from an empty previous period")
+
+	require.Len(t, stats.Series, 7)
+	assert.Equal(t, domain.StatsPoint{Date: "2025-02-24", NewCustomers: 1}, stats.Series[0])
+	assert.Equal(t, domain.StatsPoint{Date: "2025-03-01", NewCustomers: 1}, stats.Series[5])
+	assert.Equal(t, domain.StatsPoint{Date: "2025-03-02", NewCustomers: 1, FirstPurchases: 1}, stats.Series[6])
+}
+
+func TestNewStatsWindow(t *testing.T) {
+	newYork, err := domain.LoadStatsTimezone("America/New_York")
+	require.NoError(t, err)
+
+	// The week ending 10 March 2025 includes the 23-hour day clocks went
+	// forward
+	window, err := domain.NewStatsWindow(domain.StatsPeriod7Days, newYork, time.Date(2025, 3, 10, 12, 0, 0, 0, newYork))
+	require.NoError(t, err)
+	assert.Equal(t, 7*24*time.Hour-time.Hour, window.End.Sub(window.Start))
+	series := domain.NewStatsSeries(window, nil, nil)
+	require.Len(t, series, 7)
+	assert.Equal(t, "2025-03-04", series[0].Date)
+	assert.Equal(t, "2025-03-10", series[6].Date)
+
+	// 30 days ending 1 March 2024 are all of leap-year February and 1 March;
+	// the previous period is the 30 days before that
+	window, err = domain.NewStatsWindow(domain.StatsPeriod30Days, time.UTC, time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC), window.Start)
+	assert.Equal(t, time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC), window.PreviousStart)
+	assert.Len(t, domain.NewStatsSeries(window, nil, nil), 30)
+
+	_, err = domain.NewStatsWindow("1y", time.UTC, time.Now())
+	assert.ErrorIs(t, err, domain.ErrInvalidStatsPeriod)
+	for _, name := range []string{"Mars/Olympus_Mons", "Local"} {
+		_, err = domain.LoadStatsTimezone(name)
+		assert.ErrorIs(t, err, domain.ErrInvalidTimezone, name)
+	}
+	loc, err := domain.LoadStatsTimezone("")
+	require.NoError(t, err)
+	assert.Equal(t, time.UTC, loc)
+}
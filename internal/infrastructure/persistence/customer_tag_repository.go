@@ -0,0 +1,213 @@
+package persistence
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CustomerTagRepository handles free-form customer tags and their assignments
+type CustomerTagRepository struct {
+	db *gorm.DB
+}
+
+// NewCustomerTagRepository creates a new customer tag repository
+func NewCustomerTagRepository(db *gorm.DB) *CustomerTagRepository {
+	return &CustomerTagRepository{db: db}
+}
+
+// ListForCustomer returns the tags on a customer in name order
+func (r *CustomerTagRepository) ListForCustomer(ctx context.Context, customerID uuid.UUID) ([]domain.CustomerTag, error) {
+	var tags []domain.CustomerTag
+	err := r.db.WithContext(ctx).
+		Where("id IN (?)", r.db.Model(&domain.CustomerTagAssignment{}).
+			Select("tag_id").
+			Where("customer_id = ?", customerID)).
+		Order("name ASC").
+		Find(&tags).Error
+	return tags, err
+}
+
+// NamesByCustomers returns the tag names of the given customers keyed by
+// customer ID
+func (r *CustomerTagRepository) NamesByCustomers(ctx context.Context, customerIDs []uuid.UUID) (map[uuid.UUID][]string, error) {
+	byCustomer := make(map[uuid.UUID][]string)
+	if len(customerIDs) == 0 {
+		return byCustomer, nil
+	}
+
+	var assignments []domain.CustomerTagAssignment
+	if err := r.db.WithContext(ctx).
+		Where("customer_id IN ?", customerIDs).
+		Find(&assignments).Error; err != nil {
+		return nil, err
+	}
+	if len(assignments) == 0 {
+		return byCustomer, nil
+	}
+
+	tagIDs := make([]uuid.UUID, 0, len(assignments))
+	for _, a := range assignments {
+		tagIDs = append(tagIDs, a.TagID)
+	}
+	var tags []domain.CustomerTag
+	if err := r.db.WithContext(ctx).
+		Where("id IN ?", tagIDs).
+		Find(&tags).Error; err != nil {
+		return nil, err
+	}
+	names := make(map[uuid.UUID]string, len(tags))
+	for _, tag := range tags {
+		names[tag.ID] = tag.Name
+	}
+
+	for _, a := range assignments {
+		if name, ok := names[a.TagID]; ok {
+			byCustomer[a.CustomerID] = append(byCustomer[a.CustomerID], name)
+		}
+	}
+	for _, list := range byCustomer {
+		sort.Strings(list)
+	}
+	return byCustomer, nil
+}
+
+// AddToCustomer puts tags on a customer, creating tags that don't exist yet.
+// Tags the customer already has are left alone. Names must already be
+// normalized. Returns domain.ErrCustomerTagLimit if the customer would end up
+// with more than domain.MaxCustomerTags.
+func (r *CustomerTagRepository) AddToCustomer(ctx context.Context, customerID uuid.UUID, names []string, assignedBy *uuid.UUID) error {
+	if len(names) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Another admin may create the same tag concurrently; the unique
+		// name makes the loser a no-op and the lookup below finds the winner
+		for _, name := range names {
+			tag := domain.CustomerTag{Name: name, CreatedBy: assignedBy}
+			if err := tx.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "name"}},
+				DoNothing: true,
+			}).Create(&tag).Error; err != nil {
+				return err
+			}
+		}
+
+		var tags []domain.CustomerTag
+		if err := tx.Where("name IN ?", names).Find(&tags).Error; err != nil {
+			return err
+		}
+		tagIDs := make([]uuid.UUID, len(tags))
+		for i, tag := range tags {
+			tagIDs[i] = tag.ID
+		}
+
+		var current []uuid.UUID
+		if err := tx.Model(&domain.CustomerTagAssignment{}).
+			Where("customer_id = ?", customerID).
+			Pluck("tag_id", &current).Error; err != nil {
+			return err
+		}
+		has := make(map[uuid.UUID]bool, len(current))
+		for _, id := range current {
+			has[id] = true
+		}
+
+		var assignments []domain.CustomerTagAssignment
+		for _, id := range tagIDs {
+			if !has[id] {
+				assignments = append(assignments, domain.CustomerTagAssignment{
+					CustomerID: customerID,
+					TagID:      id,
+					AssignedBy: assignedBy,
+				})
+			}
+		}
+		if len(assignments) == 0 {
+			return nil
+		}
+		if len(current)+len(assignments) > domain.MaxCustomerTags {
+			return domain.ErrCustomerTagLimit
+		}
+		return tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "customer_id"}, {Name: "tag_id"}},
+			DoNothing: true,
+		}).Create(&assignments).Error
+	})
+}
+
+// RemoveFromCustomer takes a tag off a customer. The tag itself is kept for
+// autocomplete. Returns gorm.ErrRecordNotFound if the customer doesn't have it.
+func (r *CustomerTagRepository) RemoveFromCustomer(ctx context.Context, customerID uuid.UUID, name string) error {
+	result := r.db.WithContext(ctx).
+		Where("customer_id = ?", customerID).
+		Where("tag_id IN (?)", r.db.Model(&domain.CustomerTag{}).Select("id").Where("name = ?", name)).
+		Delete(&domain.CustomerTagAssignment{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// Suggest returns up to limit tags starting with prefix, most used first
+func (r *CustomerTagRepository) Suggest(ctx context.Context, prefix string, limit int) ([]domain.TagSuggestion, error) {
+	var tags []domain.CustomerTag
+	query := r.db.WithContext(ctx)
+	if prefix != "" {
+		query = query.Where("name LIKE ? ESCAPE '\\'", escapeLike(prefix)+"%")
+	}
+	if err := query.Find(&tags).Error; err != nil {
+		return nil, err
+	}
+	if len(tags) == 0 {
+		return []domain.TagSuggestion{}, nil
+	}
+
+	tagIDs := make([]uuid.UUID, len(tags))
+	for i, tag := range tags {
+		tagIDs[i] = tag.ID
+	}
+	var counts []struct {
+		TagID uuid.UUID
+		Count int64
+	}
+	if err := r.db.WithContext(ctx).Model(&domain.CustomerTagAssignment{}).
+		Select("tag_id, COUNT(*) AS count").
+		Where("tag_id IN ?", tagIDs).
+		Group("tag_id").
+		Scan(&counts).Error; err != nil {
+		return nil, err
+	}
+	usage := make(map[uuid.UUID]int64, len(counts))
+	for _, c := range counts {
+		usage[c.TagID] = c.Count
+	}
+
+	suggestions := make([]domain.TagSuggestion, len(tags))
+	for i, tag := range tags {
+		suggestions[i] = domain.TagSuggestion{Name: tag.Name, Customers: usage[tag.ID]}
+	}
+	sort.Slice(suggestions, func(i, j int) bool {
+		if suggestions[i].Customers != suggestions[j].Customers {
+			return suggestions[i].Customers > suggestions[j].Customers
+		}
+		return suggestions[i].Name < suggestions[j].Name
+	})
+	if limit > 0 && len(suggestions) > limit {
+		suggestions = suggestions[:limit]
+	}
+	return suggestions, nil
+}
+
+// escapeLike escapes LIKE wildcards so s matches literally
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
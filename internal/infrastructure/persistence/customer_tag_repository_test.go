@@ -0,0 +1,95 @@
+package persistence
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestCustomerTagRepository(t *testing.T) {
+	db := openTestDB(t, &domain.Customer{}, &domain.CustomerTag{}, &domain.CustomerTagAssignment{})
+	repo := NewCustomerTagRepository(db)
+	customers := NewCustomerRepository(db, DefaultQueryTimeouts())
+	ctx := context.Background()
+
+	newCustomer := func(email string) uuid.UUID {
+		c := &domain.Customer{Email: email, Status: "active"}
+		require.NoError(t, db.Create(c).Error)
+		return c.ID
+	}
+	alice, bob, carol := newCustomer("alice@example.com"), newCustomer("bob@example.com"), newCustomer("carol@example.com")
+	admin := uuid.New()
+
+	require.NoError(t, repo.AddToCustomer(ctx, alice, []string{"wholesale-inquiry", "vip"}, &admin))
+	require.NoError(t, repo.AddToCustomer(ctx, bob, []string{"wholesale-inquiry"}, &admin))
+	// Re-adding a tag the customer has is a no-op
+	require.NoError(t, repo.AddToCustomer(ctx, bob, []string{"wholesale-inquiry", "wholesale"}, &admin))
+
+	tags, err := repo.ListForCustomer(ctx, bob)
+	require.NoError(t, err)
+	require.Len(t, tags, 2)
+	assert.Equal(t, "wholesale", tags[0].Name)
+	assert.Equal(t, "wholesale-inquiry", tags[1].Name)
+
+	names, err := repo.NamesByCustomers(ctx, []uuid.UUID{alice, bob, carol})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"vip", "wholesale-inquiry"}, names[alice])
+	assert.Empty(t, names[carol])
+
+	list := func(tags ...string) []uuid.UUID {
+		found, _, err := customers.ListAdmin(ctx, domain.CustomerListFilter{
+			Tags: tags, Page: 1, Limit: 10, Sort: domain.Sort{Field: "email", Direction: domain.SortAsc},
+		})
+		require.NoError(t, err)
+		ids := make([]uuid.UUID, len(found))
+		for i, c := range found {
+			ids[i] = c.ID
+		}
+		return ids
+	}
+	assert.Equal(t, []uuid.UUID{alice, bob}, list("wholesale-inquiry"))
+	assert.Equal(t, []uuid.UUID{alice}, list("wholesale-inquiry", "vip"), "customers must carry every tag")
+	assert.Empty(t, list("no-such-tag"))
+	assert.Len(t, list(), 3)
+
+	suggestions, err := repo.Suggest(ctx, "whole", 10)
+	require.NoError(t, err)
+	assert.Equal(t, []domain.TagSuggestion{
+		{Name: "wholesale-inquiry", Customers: 2},
+		{Name: "wholesale", Customers: 1},
+	}, suggestions)
+
+	require.NoError(t, repo.RemoveFromCustomer(ctx, bob, "wholesale-inquiry"))
+	assert.ErrorIs(t, repo.RemoveFromCustomer(ctx, bob, "wholesale-inquiry"), gorm.ErrRecordNotFound)
+	suggestions, err = repo.Suggest(ctx, "wholesale_", 10)
+	require.NoError(t, err)
+	assert.Empty(t, suggestions, "underscore must not act as a wildcard")
+
+	many := make([]string, domain.MaxCustomerTags)
+	for i := range many {
+		many[i] = fmt.Sprintf("tag-%d", i)
+	}
+	assert.ErrorIs(t, repo.AddToCustomer(ctx, alice, many, &admin), domain.ErrCustomerTagLimit)
+}
+
+func TestNormalizeTagName(t *testing.T) {
+	name, err := domain.NormalizeTagName("  Wholesale   Inquiry ")
+	require.NoError(t, err)
+	assert.Equal(t, "wholesale-inquiry", name)
+
+	for _, bad := range []string{"", "-vip", "vip!", "a/b", strings.Repeat("a", 51)} {
+		_, err := domain.NormalizeTagName(bad)
+		assert.ErrorIs(t, err, domain.ErrInvalidTagName, bad)
+	}
+
+	names, err := domain.NormalizeTagNames([]string{"VIP", "vip", "b2b:lead"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"vip", "b2b:lead"}, names)
+}
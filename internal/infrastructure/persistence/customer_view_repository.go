@@ -0,0 +1,106 @@
+package persistence
+
+import (
+	"context"
+	"sort"
+
+	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"gorm.io/gorm"
+)
+
+// CustomerViewRepository handles saved admin customer list views
+type CustomerViewRepository struct {
+	db *gorm.DB
+}
+
+// NewCustomerViewRepository creates a new customer view repository
+func NewCustomerViewRepository(db *gorm.DB) *CustomerViewRepository {
+	return &CustomerViewRepository{db: db}
+}
+
+// ListVisible returns the admin's own views followed by views other admins
+// have shared, each in name order
+func (r *CustomerViewRepository) ListVisible(ctx context.Context, adminID uuid.UUID) ([]domain.CustomerListView, error) {
+	var views []domain.CustomerListView
+	err := r.db.WithContext(ctx).
+		Where("owner_id = ? OR is_shared = ?", adminID, true).
+		Order("name ASC").
+		Find(&views).Error
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(views, func(i, j int) bool {
+		return views[i].OwnerID == adminID && views[j].OwnerID != adminID
+	})
+	return views, nil
+}
+
+// GetVisible returns a view the admin owns or that has been shared
+func (r *CustomerViewRepository) GetVisible(ctx context.Context, id, adminID uuid.UUID) (*domain.CustomerListView, error) {
+	var view domain.CustomerListView
+	err := r.db.WithContext(ctx).
+		Where("id = ? AND (owner_id = ? OR is_shared = ?)", id, adminID, true).
+		First(&view).Error
+	if err != nil {
+		return nil, err
+	}
+	return &view, nil
+}
+
+// Create saves a new view, enforcing unique names per owner and the per-owner
+// limit
+func (r *CustomerViewRepository) Create(ctx context.Context, view *domain.CustomerListView) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var owned int64
+		if err := tx.Model(&domain.CustomerListView{}).
+			Where("owner_id = ?", view.OwnerID).
+			Count(&owned).Error; err != nil {
+			return err
+		}
+		if owned >= domain.MaxCustomerViews {
+			return domain.ErrCustomerViewLimit
+		}
+		if err := r.checkName(tx, view); err != nil {
+			return err
+		}
+		return tx.Create(view).Error
+	})
+}
+
+// Update saves changes to an existing view
+func (r *CustomerViewRepository) Update(ctx context.Context, view *domain.CustomerListView) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := r.checkName(tx, view); err != nil {
+			return err
+		}
+		return tx.Save(view).Error
+	})
+}
+
+// Delete removes a view
+func (r *CustomerViewRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	result := r.db.WithContext(ctx).Delete(&domain.CustomerListView{}, "id = ?", id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// checkName returns ErrCustomerViewNameTaken if the owner has another view
+// with the same name. The unique index still guards against races.
+func (r *CustomerViewRepository) checkName(tx *gorm.DB, view *domain.CustomerListView) error {
+	var taken int64
+	if err := tx.Model(&domain.CustomerListView{}).
+		Where("owner_id = ? AND name = ? AND id <> ?", view.OwnerID, view.Name, view.ID).
+		Count(&taken).Error; err != nil {
+		return err
+	}
+	if taken > 0 {
+		return domain.ErrCustomerViewNameTaken
+	}
+	return nil
+}
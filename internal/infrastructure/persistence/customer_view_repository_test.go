@@ -0,0 +1,109 @@
+package persistence
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestCustomerViewRepository(t *testing.T) {
+	db := openTestDB(t, &domain.CustomerListView{})
+	repo := NewCustomerViewRepository(db)
+	ctx := context.Background()
+	alice, bob := uuid.New(), uuid.New()
+
+	minOrders := 5
+	mine := &domain.CustomerListView{
+		OwnerID: alice,
+		Name:    "VIPs",
+		Filters: domain.CustomerViewFilters{Status: "active", OrdersMin: &minOrders, Tags: []string{"vip"}},
+		Columns: []string{"email", "total_spent"},
+	}
+	require.NoError(t, repo.Create(ctx, mine))
+	shared := &domain.CustomerListView{OwnerID: bob, Name: "Churn risk", IsShared: true}
+	require.NoError(t, repo.Create(ctx, shared))
+	private := &domain.CustomerListView{OwnerID: bob, Name: "Bob's scratch"}
+	require.NoError(t, repo.Create(ctx, private))
+
+	err := repo.Create(ctx, &domain.CustomerListView{OwnerID: alice, Name: "VIPs"})
+	assert.ErrorIs(t, err, domain.ErrCustomerViewNameTaken)
+	require.NoError(t, repo.Create(ctx, &domain.CustomerListView{OwnerID: bob, Name: "VIPs"}),
+		"names are only unique per owner")
+
+	views, err := repo.ListVisible(ctx, alice)
+	require.NoError(t, err)
+	require.Len(t, views, 2)
+	assert.Equal(t, mine.ID, views[0].ID, "own views come first")
+	assert.Equal(t, shared.ID, views[1].ID)
+	assert.Equal(t, 5, *views[0].Filters.OrdersMin)
+	assert.Equal(t, []string{"email", "total_spent"}, views[0].Columns)
+
+	_, err = repo.GetVisible(ctx, private.ID, alice)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	got, err := repo.GetVisible(ctx, shared.ID, alice)
+	require.NoError(t, err)
+	assert.Equal(t, "Churn risk", got.Name)
+
+	private.Name = "Churn risk"
+	assert.ErrorIs(t, repo.Update(ctx, private), domain.ErrCustomerViewNameTaken)
+	private.Name = "Renamed"
+	private.IsShared = true
+	require.NoError(t, repo.Update(ctx, private))
+	_, err = repo.GetVisible(ctx, private.ID, alice)
+	assert.NoError(t, err, "a view is visible to others once shared")
+
+	require.NoError(t, repo.Delete(ctx, mine.ID))
+	assert.ErrorIs(t, repo.Delete(ctx, mine.ID), gorm.ErrRecordNotFound)
+}
+
+func TestSaveCustomerViewRequest_Apply(t *testing.T) {
+	minSpent, maxSpent := 100.0, 50.0
+	cases := []struct {
+		name string
+		req  domain.SaveCustomerViewRequest
+		ok   bool
+	}{
+		{"valid", domain.SaveCustomerViewRequest{Name: " New ", Filters: domain.CustomerViewFilters{Status: "active", DateFrom: "2026-01-01"}, SortBy: "total_spent", SortOrder: "DESC", Columns: []string{"email"}}, true},
+		{"blank name", domain.SaveCustomerViewRequest{Name: "  "}, false},
+		{"bad status", domain.SaveCustomerViewRequest{Name: "x", Filters: domain.CustomerViewFilters{Status: "gone"}}, false},
+		{"bad date", domain.SaveCustomerViewRequest{Name: "x", Filters: domain.CustomerViewFilters{DateTo: "01/02/2026"}}, false},
+		{"inverted range", domain.SaveCustomerViewRequest{Name: "x", Filters: domain.CustomerViewFilters{SpentMin: &minSpent, SpentMax: &maxSpent}}, false},
+		{"unsortable column", domain.SaveCustomerViewRequest{Name: "x", SortBy: "password; drop table"}, false},
+		{"bad sort order", domain.SaveCustomerViewRequest{Name: "x", SortOrder: "up"}, false},
+		{"unknown column", domain.SaveCustomerViewRequest{Name: "x", Columns: []string{"ssn"}}, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var view domain.CustomerListView
+			err := tc.req.Apply(&view)
+			if !tc.ok {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, "New", view.Name)
+			assert.Equal(t, "desc", view.SortOrder)
+		})
+	}
+}
+
+func TestCustomerListView_Query(t *testing.T) {
+	minOrders, maxSpent := 2, 99.5
+	view := domain.CustomerListView{
+		Filters:   domain.CustomerViewFilters{Status: "active", OrdersMin: &minOrders, SpentMax: &maxSpent, Tags: []string{"vip", "wholesale"}},
+		SortBy:    "email",
+		SortOrder: "asc",
+	}
+	q := view.Query()
+	assert.Equal(t, "active", q.Get("status"))
+	assert.Equal(t, "2", q.Get("orders_min"))
+	assert.Equal(t, "99.5", q.Get("spent_max"))
+	assert.Equal(t, "vip,wholesale", q.Get("tags"))
+	assert.Equal(t, "email", q.Get("sort_by"))
+	assert.False(t, q.Has("search"))
+}
@@ -0,0 +1,62 @@
+package persistence
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"go.uber.org/zap"
+)
+
+// webhookCustomerRepository queues webhook events for customer writes after
+// they succeed. Like mirroring, queueing never fails the write.
+type webhookCustomerRepository struct {
+	CustomerRepository
+	webhooks *WebhookSubscriptionRepository
+	logger   *zap.Logger
+}
+
+// NewWebhookCustomerRepository wraps repo so created and updated customers
+// and segment assignments are sent to webhook subscriptions
+func NewWebhookCustomerRepository(repo CustomerRepository, webhooks *WebhookSubscriptionRepository, logger *zap.Logger) CustomerRepository {
+	return &webhookCustomerRepository{CustomerRepository: repo, webhooks: webhooks, logger: logger}
+}
+
+func (r *webhookCustomerRepository) Create(ctx context.Context, req *domain.CreateCustomerRequest, createdBy *uuid.UUID) (*domain.Customer, error) {
+	customer, err := r.CustomerRepository.Create(ctx, req, createdBy)
+	if err == nil {
+		r.enqueue(ctx, domain.WebhookEventCustomerCreated, customer.ID, customer)
+	}
+	return customer, err
+}
+
+func (r *webhookCustomerRepository) Update(ctx context.Context, id uuid.UUID, req *domain.UpdateCustomerRequest) (*domain.Customer, error) {
+	customer, err := r.CustomerRepository.Update(ctx, id, req)
+	if err == nil {
+		r.enqueue(ctx, domain.WebhookEventCustomerUpdated, id, customer)
+	}
+	return customer, err
+}
+
+func (r *webhookCustomerRepository) AssignSegments(ctx context.Context, customerID uuid.UUID, segmentIDs []uuid.UUID) error {
+	err := r.CustomerRepository.AssignSegments(ctx, customerID, segmentIDs)
+	if err == nil {
+		if segmentIDs == nil {
+			segmentIDs = []uuid.UUID{}
+		}
+		r.enqueue(ctx, domain.WebhookEventCustomerSegmentChanged, customerID, domain.CustomerSegmentsChanged{
+			CustomerID: customerID,
+			SegmentIDs: segmentIDs,
+		})
+	}
+	return err
+}
+
+func (r *webhookCustomerRepository) enqueue(ctx context.Context, eventType string, customerID uuid.UUID, data interface{}) {
+	if err := r.webhooks.Enqueue(context.WithoutCancel(ctx), eventType, data); err != nil {
+		r.logger.Error("Failed to queue customer webhook event",
+			zap.String("event_type", eventType),
+			zap.String("customer_id", customerID.String()),
+			zap.Error(err))
+	}
+}
@@ -0,0 +1,104 @@
+package persistence
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"gorm.io/gorm"
+)
+
+// DataPortabilityRepository exports a customer's addresses, wishlist and
+// measurements and imports such an export into an account
+type DataPortabilityRepository struct {
+	db     *gorm.DB
+	mirror CustomerMirror
+	events WishlistEvents
+}
+
+// NewDataPortabilityRepository creates a new data portability repository
+func NewDataPortabilityRepository(db *gorm.DB) *DataPortabilityRepository {
+	return &DataPortabilityRepository{db: db}
+}
+
+// WithMirror mirrors the user's addresses to mirror after an import adds any
+func (r *DataPortabilityRepository) WithMirror(mirror CustomerMirror) *DataPortabilityRepository {
+	r.mirror = mirror
+	return r
+}
+
+// WithWishlistEvents reports the wishlist items an import adds to events
+func (r *DataPortabilityRepository) WithWishlistEvents(events WishlistEvents) *DataPortabilityRepository {
+	r.events = events
+	return r
+}
+
+// Export returns the user's data in the portable export format
+func (r *DataPortabilityRepository) Export(ctx context.Context, userID uuid.UUID) (*domain.CustomerDataExport, error) {
+	addresses, wishlist, measurements, err := loadPortableData(r.db.WithContext(ctx), userID)
+	if err != nil {
+		return nil, err
+	}
+	return domain.NewCustomerDataExport(addresses, wishlist, measurements), nil
+}
+
+// Import adds the records of export the account doesn't hold yet and returns
+// the outcome of every record. A dry run only plans the import.
+func (r *DataPortabilityRepository) Import(ctx context.Context, userID uuid.UUID, export *domain.CustomerDataExport, opts domain.DataImportOptions, dryRun bool) (*domain.DataImportSummary, error) {
+	var plan *domain.DataImportPlan
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		addresses, wishlist, measurements, err := loadPortableData(tx, userID)
+		if err != nil {
+			return err
+		}
+		plan = domain.PlanDataImport(userID, export, addresses, wishlist, measurements, opts)
+		if dryRun {
+			return nil
+		}
+
+		if len(plan.Addresses) > 0 {
+			if err := tx.Create(&plan.Addresses).Error; err != nil {
+				return err
+			}
+		}
+		if len(plan.Wishlist) > 0 {
+			if err := tx.Create(&plan.Wishlist).Error; err != nil {
+				return err
+			}
+		}
+		if len(plan.Measurements) > 0 {
+			if err := tx.Create(&plan.Measurements).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if !dryRun && len(plan.Addresses) > 0 && r.mirror != nil {
+		r.mirror.MirrorAddresses(ctx, userID)
+	}
+	if !dryRun && r.events != nil {
+		for i := range plan.Wishlist {
+			r.events.ItemAdded(ctx, plan.Wishlist[i])
+		}
+	}
+	plan.Summary.DryRun = dryRun
+	return &plan.Summary, nil
+}
+
+// loadPortableData loads the exportable records of a user, oldest first
+func loadPortableData(db *gorm.DB, userID uuid.UUID) (addresses []domain.Address, wishlist []domain.WishlistItem, measurements []domain.CustomerMeasurement, err error) {
+	if err = db.Where("user_id = ?", userID).Order("created_at").Find(&addresses).Error; err != nil {
+		return nil, nil, nil, err
+	}
+	if err = db.Where("user_id = ?", userID).Order("created_at").Find(&wishlist).Error; err != nil {
+		return nil, nil, nil, err
+	}
+	if err = db.Where("user_id = ?", userID).Order("created_at").Find(&measurements).Error; err != nil {
+		return nil, nil, nil, err
+	}
+	return addresses, wishlist, measurements, nil
+}
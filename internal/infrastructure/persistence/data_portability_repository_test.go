@@ -0,0 +1,102 @@
+package persistence
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDataPortabilityRepository_ExportImport(t *testing.T) {
+	db := openTestDB(t, &domain.Address{}, &domain.WishlistItem{}, &domain.CustomerMeasurement{})
+	events := &recordedWishlistEvents{}
+	repo := NewDataPortabilityRepository(db).WithWishlistEvents(events)
+	ctx := context.Background()
+
+	sourceID, targetID := uuid.New(), uuid.New()
+	productA, productB := uuid.New(), uuid.New()
+	waist := 80.0
+
+	require.NoError(t, db.Create(&domain.Address{
+		UserID: sourceID, Label: "Home", RecipientName: "Aisyah", Phone: "0123456789",
+		AddressLine1: "1 Jalan Ampang", City: "Kuala Lumpur", State: "WP", Postcode: "50450",
+		Country: "Malaysia", IsDefault: true,
+	}).Error)
+	require.NoError(t, db.Create(&domain.WishlistItem{UserID: sourceID, ProductID: productA}).Error)
+	require.NoError(t, db.Create(&domain.WishlistItem{UserID: sourceID, ProductID: productB}).Error)
+	require.NoError(t, db.Create(&domain.CustomerMeasurement{UserID: sourceID, Gender: "women", Waist: &waist, IsDefault: true}).Error)
+
+	export, err := repo.Export(ctx, sourceID)
+	require.NoError(t, err)
+	assert.Equal(t, domain.DataExportFormat, export.Format)
+	require.NoError(t, export.Validate())
+	require.Len(t, export.Addresses, 1)
+	require.Len(t, export.Wishlist, 2)
+	require.Len(t, export.Measurements, 1)
+
+	// The target already saved product A and has a default address elsewhere;
+	// the document also repeats the address and carries an invalid measurement
+	require.NoError(t, db.Create(&domain.WishlistItem{UserID: targetID, ProductID: productA}).Error)
+	require.NoError(t, db.Create(&domain.Address{
+		UserID: targetID, RecipientName: "Aisyah", Phone: "0123456789",
+		AddressLine1: "9 Orchard Road", City: "Singapore", State: "SG", Postcode: "238823",
+		Country: "Singapore", IsDefault: true,
+	}).Error)
+	export.Addresses = append(export.Addresses, export.Addresses[0])
+	export.Measurements = append(export.Measurements, domain.PortableMeasurement{Gender: "other"})
+
+	opts := domain.DataImportOptions{Limits: domain.CustomerLimits{WishlistItems: 2}}
+	preview, err := repo.Import(ctx, targetID, export, opts, true)
+	require.NoError(t, err)
+	assert.True(t, preview.DryRun)
+	assert.Equal(t, 1, preview.Addresses.Added)
+	assert.Equal(t, 1, preview.Addresses.Duplicate)
+	assert.Equal(t, 1, preview.Wishlist.Added)
+	assert.Equal(t, 1, preview.Wishlist.Duplicate)
+	assert.Equal(t, 1, preview.Measurements.Added)
+	assert.Equal(t, 1, preview.Measurements.Invalid)
+
+	var count int64
+	db.Model(&domain.Address{}).Where("user_id = ?", targetID).Count(&count)
+	assert.Equal(t, int64(1), count, "dry run must not write")
+	assert.Empty(t, events.added, "dry run must not report wishlist items")
+
+	summary, err := repo.Import(ctx, targetID, export, opts, false)
+	require.NoError(t, err)
+	assert.False(t, summary.DryRun)
+	assert.Equal(t, preview.Addresses.Results, summary.Addresses.Results)
+	require.Len(t, events.added, 1, "imported wishlist items are reported")
+	assert.Equal(t, productB, events.added[0].ProductID)
+
+	var addresses []domain.Address
+	require.NoError(t, db.Where("user_id = ?", targetID).Order("created_at").Find(&addresses).Error)
+	require.Len(t, addresses, 2)
+	assert.False(t, addresses[1].IsDefault, "imported address must not replace the existing default")
+
+	var measurements []domain.CustomerMeasurement
+	require.NoError(t, db.Where("user_id = ?", targetID).Find(&measurements).Error)
+	require.Len(t, measurements, 1)
+	assert.True(t, measurements[0].IsDefault)
+
+	// Importing again adds nothing; the wishlist is now full
+	again, err := repo.Import(ctx, targetID, export, domain.DataImportOptions{Limits: domain.CustomerLimits{WishlistItems: 2}}, false)
+	require.NoError(t, err)
+	assert.Equal(t, 0, again.Addresses.Added)
+	assert.Equal(t, 2, again.Wishlist.Duplicate)
+	assert.Equal(t, 1, again.Measurements.Duplicate)
+}
+
+func TestCustomerDataExport_Validate(t *testing.T) {
+	export := &domain.CustomerDataExport{Format: domain.DataExportFormat, Version: domain.DataExportVersion}
+	assert.NoError(t, export.Validate())
+
+	export.Version = domain.DataExportVersion + 1
+	assert.ErrorIs(t, export.Validate(), domain.ErrUnsupportedDataExport)
+
+	export.Version = domain.DataExportVersion
+	export.Wishlist = make([]domain.PortableWishlistItem, domain.MaxDataImportRecords+1)
+	assert.Error(t, export.Validate())
+}
@@ -0,0 +1,258 @@
+package persistence
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// duplicateKeyChunk is how many shared keys are looked up per query
+const duplicateKeyChunk = 500
+
+// DuplicateRepository finds probable duplicate customers and keeps the
+// queue of them for admins to review
+type DuplicateRepository struct {
+	db *gorm.DB
+}
+
+// NewDuplicateRepository creates a new duplicate repository
+func NewDuplicateRepository(db *gorm.DB) *DuplicateRepository {
+	return &DuplicateRepository{db: db}
+}
+
+// RefreshKeys recomputes the match keys of up to limit customers after the
+// given customer ID. It returns the last customer ID handled, or uuid.Nil
+// once every customer has been handled, and the number of customers handled.
+func (r *DuplicateRepository) RefreshKeys(ctx context.Context, after uuid.UUID, limit int) (uuid.UUID, int, error) {
+	db := r.db.WithContext(ctx)
+
+	// The phone is only used for customers saved before its blind index
+	// was added; see Customer.PhoneLookup
+	var customers []domain.Customer
+	if err := db.Select("id", "email", "phone_index", "phone", "first_name", "last_name").
+		Where("id > ?", after).
+		Order("id").
+		Limit(limit).
+		Find(&customers).Error; err != nil {
+		return uuid.Nil, 0, err
+	}
+	if len(customers) == 0 {
+		return uuid.Nil, 0, nil
+	}
+
+	ids := make([]uuid.UUID, len(customers))
+	for i := range customers {
+		ids[i] = customers[i].ID
+	}
+	var addresses []domain.Address
+	if err := db.Where("user_id IN ?", ids).Find(&addresses).Error; err != nil {
+		return uuid.Nil, 0, err
+	}
+	byCustomer := make(map[uuid.UUID][]domain.Address)
+	for _, a := range addresses {
+		byCustomer[a.UserID] = append(byCustomer[a.UserID], a)
+	}
+
+	var keys []domain.DuplicateMatchKey
+	for i := range customers {
+		keys = append(keys, domain.DuplicateMatchKeys(&customers[i], byCustomer[customers[i].ID])...)
+	}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("customer_id IN ?", ids).Delete(&domain.DuplicateMatchKey{}).Error; err != nil {
+			return err
+		}
+		if len(keys) == 0 {
+			return nil
+		}
+		return tx.Create(&keys).Error
+	})
+	if err != nil {
+		return uuid.Nil, 0, err
+	}
+	return customers[len(customers)-1].ID, len(customers), nil
+}
+
+// PruneKeys deletes the match keys of customers that have been deleted or
+// merged away
+func (r *DuplicateRepository) PruneKeys(ctx context.Context) error {
+	db := r.db.WithContext(ctx)
+	return db.Where("customer_id NOT IN (?)", db.Model(&domain.Customer{}).Select("id")).
+		Delete(&domain.DuplicateMatchKey{}).Error
+}
+
+// Detect pairs the customers who share a match key and queues the pairs not
+// yet queued. Pending candidates that no longer match are dropped; merged
+// and dismissed ones are kept so they aren't queued again. It returns the
+// number of pairs found and newly queued.
+func (r *DuplicateRepository) Detect(ctx context.Context, now time.Time) (pairs int, queued int, err error) {
+	db := r.db.WithContext(ctx)
+
+	var shared []domain.DuplicateMatchKey
+	if err := db.Model(&domain.DuplicateMatchKey{}).
+		Select("kind, key").
+		Group("kind, key").
+		Having("COUNT(*) BETWEEN 2 AND ?", domain.MaxDuplicateGroup).
+		Find(&shared).Error; err != nil {
+		return 0, 0, err
+	}
+	sharedByKind := make(map[string][]string)
+	for _, key := range shared {
+		sharedByKind[key.Kind] = append(sharedByKind[key.Kind], key.Key)
+	}
+
+	groups := make(map[domain.DuplicateMatchKey][]uuid.UUID)
+	for kind, values := range sharedByKind {
+		for start := 0; start < len(values); start += duplicateKeyChunk {
+			end := min(start+duplicateKeyChunk, len(values))
+			var keys []domain.DuplicateMatchKey
+			if err := db.Where("kind = ? AND key IN ?", kind, values[start:end]).Find(&keys).Error; err != nil {
+				return 0, 0, err
+			}
+			for _, key := range keys {
+				group := domain.DuplicateMatchKey{Kind: key.Kind, Key: key.Key}
+				groups[group] = append(groups[group], key.CustomerID)
+			}
+		}
+	}
+
+	reasons := make(map[[2]uuid.UUID][]string)
+	for group, customerIDs := range groups {
+		for i := range customerIDs {
+			for _, other := range customerIDs[i+1:] {
+				customerID, duplicateID := domain.DuplicatePair(customerIDs[i], other)
+				pair := [2]uuid.UUID{customerID, duplicateID}
+				if !slices.Contains(reasons[pair], group.Kind) {
+					reasons[pair] = append(reasons[pair], group.Kind)
+				}
+			}
+		}
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		for pair, kinds := range reasons {
+			sort.Strings(kinds)
+			candidate := domain.DuplicateCandidate{
+				CustomerID:  pair[0],
+				DuplicateID: pair[1],
+				Reasons:     kinds,
+				Status:      domain.DuplicateStatusPending,
+				DetectedAt:  now,
+			}
+			created := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&candidate)
+			if created.Error != nil {
+				return created.Error
+			}
+			if created.RowsAffected > 0 {
+				queued++
+				continue
+			}
+			if err := tx.Model(&domain.DuplicateCandidate{}).
+				Where("customer_id = ? AND duplicate_id = ? AND status = ?", pair[0], pair[1], domain.DuplicateStatusPending).
+				Updates(&domain.DuplicateCandidate{Reasons: candidate.Reasons, DetectedAt: now}).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Where("status = ? AND detected_at < ?", domain.DuplicateStatusPending, now).
+			Delete(&domain.DuplicateCandidate{}).Error
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+	return len(reasons), queued, nil
+}
+
+// List returns a page of the candidates with the given status, most recently
+// detected first, with both customers
+func (r *DuplicateRepository) List(ctx context.Context, status string, page, limit int) ([]domain.DuplicateCandidate, int64, error) {
+	db := r.db.WithContext(ctx)
+
+	query := db.Model(&domain.DuplicateCandidate{}).Where("status = ?", status)
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var candidates []domain.DuplicateCandidate
+	if err := query.Order("detected_at DESC, id").
+		Offset((page - 1) * limit).
+		Limit(limit).
+		Find(&candidates).Error; err != nil {
+		return nil, 0, err
+	}
+	if len(candidates) == 0 {
+		return candidates, total, nil
+	}
+
+	var ids []uuid.UUID
+	for _, c := range candidates {
+		ids = append(ids, c.CustomerID, c.DuplicateID)
+	}
+	// Merged duplicates are soft-deleted but still shown in the history
+	var customers []domain.Customer
+	if err := db.Unscoped().Where("id IN ?", ids).Find(&customers).Error; err != nil {
+		return nil, 0, err
+	}
+	byID := make(map[uuid.UUID]domain.Customer, len(customers))
+	for _, c := range customers {
+		byID[c.ID] = c
+	}
+	for i := range candidates {
+		c := &candidates[i]
+		for _, id := range []uuid.UUID{c.CustomerID, c.DuplicateID} {
+			if customer, ok := byID[id]; ok {
+				c.Customers = append(c.Customers, customer)
+			}
+		}
+		domain.SortDuplicates(c.Customers)
+	}
+	return candidates, total, nil
+}
+
+// Dismiss marks a pending candidate as not a duplicate, so it isn't queued
+// again
+func (r *DuplicateRepository) Dismiss(ctx context.Context, id, adminID uuid.UUID, now time.Time) (*domain.DuplicateCandidate, error) {
+	db := r.db.WithContext(ctx)
+
+	var candidate domain.DuplicateCandidate
+	if err := db.First(&candidate, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	if candidate.Status != domain.DuplicateStatusPending {
+		return nil, domain.ErrDuplicateCandidateReviewed
+	}
+	result := db.Model(&domain.DuplicateCandidate{}).
+		Where("id = ? AND status = ?", id, domain.DuplicateStatusPending).
+		Updates(map[string]interface{}{
+			"status":      domain.DuplicateStatusDismissed,
+			"reviewed_by": adminID,
+			"reviewed_at": now,
+		})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, domain.ErrDuplicateCandidateReviewed
+	}
+	candidate.Status = domain.DuplicateStatusDismissed
+	candidate.ReviewedBy = &adminID
+	candidate.ReviewedAt = &now
+	return &candidate, nil
+}
+
+// resolveDuplicateCandidate marks the pending candidate for a merged pair of
+// customers as merged
+func resolveDuplicateCandidate(tx *gorm.DB, merge *domain.AccountMerge) error {
+	customerID, duplicateID := domain.DuplicatePair(merge.PrimaryUserID, merge.SecondaryUserID)
+	return tx.Model(&domain.DuplicateCandidate{}).
+		Where("customer_id = ? AND duplicate_id = ? AND status = ?", customerID, duplicateID, domain.DuplicateStatusPending).
+		Updates(map[string]interface{}{
+			"status":      domain.DuplicateStatusMerged,
+			"reviewed_by": merge.MergedBy,
+			"reviewed_at": merge.MergedAt,
+		}).Error
+}
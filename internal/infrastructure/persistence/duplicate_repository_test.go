@@ -0,0 +1,126 @@
+package persistence
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDuplicateRepository_Detect(t *testing.T) {
+	db := openTestDB(t, &domain.Customer{}, &domain.Address{}, &domain.DuplicateMatchKey{}, &domain.DuplicateCandidate{})
+	repo := NewDuplicateRepository(db)
+	ctx := context.Background()
+
+	create := func(c domain.Customer) uuid.UUID {
+		t.Helper()
+		require.NoError(t, db.Create(&c).Error)
+		return c.ID
+	}
+	phoneA := create(domain.Customer{Email: "aisyah@example.com", Phone: "+60 12-345 6789", TotalOrders: 1})
+	phoneB := create(domain.Customer{Email: "aisyah.work@example.com", Phone: "012 345 6789", TotalOrders: 4})
+	gmailA := create(domain.Customer{Email: "Jo.Tan@gmail.com"})
+	gmailB := create(domain.Customer{Email: "jotan+shop@googlemail.com"})
+	nameA := create(domain.Customer{Email: "lim1@example.com", FirstName: "Lim", LastName: "Wei"})
+	nameB := create(domain.Customer{Email: "lim2@example.com", FirstName: "LIM", LastName: "wei"})
+	create(domain.Customer{Email: "other@example.com", FirstName: "Lim", LastName: "Wei"})
+	for _, id := range []uuid.UUID{nameA, nameB} {
+		require.NoError(t, db.Create(&domain.Address{UserID: id, RecipientName: "Lim Wei", Phone: "0123456789",
+			AddressLine1: "12, Jalan Ampang", City: "Kuala Lumpur", State: "WP", Postcode: "50450", Country: "Malaysia"}).Error)
+	}
+	// A store phone number on many accounts is not a duplicate
+	for i := 0; i < domain.MaxDuplicateGroup+1; i++ {
+		create(domain.Customer{Email: uuid.NewString() + "@example.com", Phone: "03-2222 0000"})
+	}
+
+	detect := func(now time.Time) (int, int) {
+		t.Helper()
+		after := uuid.Nil
+		for {
+			next, _, err := repo.RefreshKeys(ctx, after, 4)
+			require.NoError(t, err)
+			if next == uuid.Nil {
+				break
+			}
+			after = next
+		}
+		require.NoError(t, repo.PruneKeys(ctx))
+		pairs, queued, err := repo.Detect(ctx, now)
+		require.NoError(t, err)
+		return pairs, queued
+	}
+	now := time.Date(2025, 6, 1, 3, 45, 0, 0, time.UTC)
+	pairs, queued := detect(now)
+	assert.Equal(t, 3, pairs)
+	assert.Equal(t, 3, queued)
+
+	candidates, total, err := repo.List(ctx, domain.DuplicateStatusPending, 1, 10)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), total)
+	reasons := make(map[string]domain.DuplicateCandidate)
+	for _, c := range candidates {
+		require.Len(t, c.Reasons, 1)
+		require.Len(t, c.Customers, 2)
+		reasons[c.Reasons[0]] = c
+	}
+	assert.Equal(t, phoneB, reasons[domain.DuplicateReasonPhone].Customers[0].ID, "the customer with more orders is the suggested primary")
+	assert.ElementsMatch(t, []uuid.UUID{phoneA, phoneB}, []uuid.UUID{reasons[domain.DuplicateReasonPhone].CustomerID, reasons[domain.DuplicateReasonPhone].DuplicateID})
+	assert.ElementsMatch(t, []uuid.UUID{gmailA, gmailB}, []uuid.UUID{reasons[domain.DuplicateReasonEmail].CustomerID, reasons[domain.DuplicateReasonEmail].DuplicateID})
+	assert.ElementsMatch(t, []uuid.UUID{nameA, nameB}, []uuid.UUID{reasons[domain.DuplicateReasonNameAddress].CustomerID, reasons[domain.DuplicateReasonNameAddress].DuplicateID})
+
+	// Dismissed pairs stay dismissed; pairs that no longer match leave the queue
+	_, err = repo.Dismiss(ctx, reasons[domain.DuplicateReasonEmail].ID, uuid.New(), now)
+	require.NoError(t, err)
+	_, err = repo.Dismiss(ctx, reasons[domain.DuplicateReasonEmail].ID, uuid.New(), now)
+	assert.ErrorIs(t, err, domain.ErrDuplicateCandidateReviewed)
+	require.NoError(t, db.Model(&domain.Customer{}).Where("id = ?", nameB).UpdateColumn("last_name", "Wong").Error)
+
+	pairs, queued = detect(now.Add(24 * time.Hour))
+	assert.Equal(t, 2, pairs)
+	assert.Zero(t, queued)
+	candidates, _, err = repo.List(ctx, domain.DuplicateStatusPending, 1, 10)
+	require.NoError(t, err)
+	require.Len(t, candidates, 1)
+	assert.Equal(t, []string{domain.DuplicateReasonPhone}, candidates[0].Reasons)
+
+	// Merging the pair, either way round, resolves it
+	require.NoError(t, resolveDuplicateCandidate(db, &domain.AccountMerge{PrimaryUserID: phoneB, SecondaryUserID: phoneA, MergedAt: now}))
+	merged, _, err := repo.List(ctx, domain.DuplicateStatusMerged, 1, 10)
+	require.NoError(t, err)
+	require.Len(t, merged, 1)
+	assert.Equal(t, candidates[0].ID, merged[0].ID)
+}
+
+func TestDuplicateMatchKeys(t *testing.T) {
+	for input, want := range map[string]string{
+		"+60 12-345 6789": "60123456789",
+		"012-345 6789":    "60123456789",
+		"0065 9123 4567":  "6591234567",
+		"12345":           "",
+		"not a phone":     "",
+	} {
+		assert.Equal(t, want, domain.NormalizeDuplicatePhone(input), input)
+	}
+	for input, want := range map[string]string{
+		"Jo.Tan+news@GoogleMail.com": "jotan@gmail.com",
+		"jo.tan+news@example.com":    "jo.tan@example.com",
+		"+tag@example.com":           "",
+		"no-at-sign":                 "",
+	} {
+		assert.Equal(t, want, domain.NormalizeDuplicateEmail(input), input)
+	}
+
+	customer := &domain.Customer{ID: uuid.New(), Email: "a@example.com", FirstName: "Siti", LastName: "Aminah"}
+	keys := domain.DuplicateMatchKeys(customer, []domain.Address{
+		{AddressLine1: "1 Jalan Tun Razak", Postcode: "50400", Country: "MY"},
+		{AddressLine1: "1, JALAN TUN RAZAK.", Postcode: "50400", Country: "Malaysia"},
+	})
+	assert.Equal(t, []domain.DuplicateMatchKey{
+		{CustomerID: customer.ID, Kind: domain.DuplicateReasonEmail, Key: "a@example.com"},
+		{CustomerID: customer.ID, Kind: domain.DuplicateReasonNameAddress, Key: "siti aminah|1 jalan tun razak|50400|MY"},
+	}, keys)
+}
@@ -0,0 +1,122 @@
+package persistence
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"gorm.io/gorm"
+)
+
+// EmailChangeRepository handles verified changes of a customer's email
+type EmailChangeRepository struct {
+	db  *gorm.DB
+	ttl time.Duration
+}
+
+// NewEmailChangeRepository creates a new email change repository
+func NewEmailChangeRepository(db *gorm.DB) *EmailChangeRepository {
+	return &EmailChangeRepository{db: db, ttl: domain.EmailChangeTTL}
+}
+
+// Request records newEmail as the pending email of a customer and returns
+// the verification token to email to it, replacing any earlier pending
+// change. Returns gorm.ErrRecordNotFound if the customer has no profile.
+func (r *EmailChangeRepository) Request(ctx context.Context, userID uuid.UUID, newEmail string) (*domain.EmailChange, string, error) {
+	email := normalizeEmail(newEmail)
+	token, tokenHash, err := newConfirmationToken()
+	if err != nil {
+		return nil, "", err
+	}
+
+	var change domain.EmailChange
+	err = r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var profile domain.Profile
+		if err := tx.Where("id = ?", userID).First(&profile).Error; err != nil {
+			return err
+		}
+		if normalizeEmail(profile.Email) == email {
+			return domain.ErrEmailUnchanged
+		}
+		if err := checkEmailFree(tx, userID, email); err != nil {
+			return err
+		}
+
+		if err := tx.Where("user_id = ?", userID).Delete(&domain.EmailChange{}).Error; err != nil {
+			return err
+		}
+		change = domain.EmailChange{
+			UserID:    userID,
+			OldEmail:  profile.Email,
+			NewEmail:  email,
+			TokenHash: tokenHash,
+			ExpiresAt: time.Now().Add(r.ttl),
+		}
+		return tx.Create(&change).Error
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	return &change, token, nil
+}
+
+// Confirm applies the pending change the token was issued for and returns it
+// with the updated profile; the change's OldEmail is the address replaced.
+// Returns domain.ErrEmailChangeTokenInvalid if the token is unknown, expired
+// or belongs to another customer.
+func (r *EmailChangeRepository) Confirm(ctx context.Context, userID uuid.UUID, token string) (*domain.EmailChange, *domain.Profile, error) {
+	var change domain.EmailChange
+	var profile domain.Profile
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("user_id = ? AND token_hash = ?", userID, hashConfirmationToken(token)).First(&change).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return domain.ErrEmailChangeTokenInvalid
+		}
+		if err != nil {
+			return err
+		}
+		if time.Now().After(change.ExpiresAt) {
+			return domain.ErrEmailChangeTokenInvalid
+		}
+		// The address may have been taken since the change was requested
+		if err := checkEmailFree(tx, userID, change.NewEmail); err != nil {
+			return err
+		}
+
+		if err := tx.Where("id = ?", userID).First(&profile).Error; err != nil {
+			return err
+		}
+		change.OldEmail = profile.Email
+		if err := tx.Model(&profile).Updates(map[string]interface{}{
+			"email":   change.NewEmail,
+			"version": bumpVersion,
+		}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("id = ?", userID).First(&profile).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&change).Error
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return &change, &profile, nil
+}
+
+// checkEmailFree returns domain.ErrEmailInUse if another customer's profile
+// has the email
+func checkEmailFree(tx *gorm.DB, userID uuid.UUID, email string) error {
+	var count int64
+	if err := tx.Model(&domain.Profile{}).
+		Where("LOWER(email) = ? AND id <> ?", email, userID).
+		Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return domain.ErrEmailInUse
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package persistence
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestEmailChangeRepository_RequestAndConfirm(t *testing.T) {
+	db := openTestDB(t, &domain.Profile{}, &domain.EmailChange{})
+	repo := NewEmailChangeRepository(db)
+	ctx := context.Background()
+
+	userID := uuid.New()
+	require.NoError(t, db.Create(&domain.Profile{ID: userID, Email: "aisyah@example.com"}).Error)
+
+	_, _, err := repo.Request(ctx, userID, "Aisyah@Example.com")
+	assert.ErrorIs(t, err, domain.ErrEmailUnchanged)
+	_, _, err = repo.Request(ctx, uuid.New(), "new@example.com")
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+	// Requesting again replaces the pending change; the first token stops working
+	_, firstToken, err := repo.Request(ctx, userID, "old-typo@example.com")
+	require.NoError(t, err)
+	change, secondToken, err := repo.Request(ctx, userID, " Aisyah.New@Example.com ")
+	require.NoError(t, err)
+	assert.Equal(t, "aisyah.new@example.com", change.NewEmail)
+	assert.Equal(t, "aisyah@example.com", change.OldEmail)
+
+	_, _, err = repo.Confirm(ctx, userID, firstToken)
+	assert.ErrorIs(t, err, domain.ErrEmailChangeTokenInvalid)
+	_, _, err = repo.Confirm(ctx, uuid.New(), secondToken)
+	assert.ErrorIs(t, err, domain.ErrEmailChangeTokenInvalid)
+
+	// The profile keeps the old email until the new one is verified
+	profile, err := NewProfileRepository(db).GetByUserID(ctx, userID)
+	require.NoError(t, err)
+	assert.Equal(t, "aisyah@example.com", profile.Email)
+
+	confirmed, profile, err := repo.Confirm(ctx, userID, secondToken)
+	require.NoError(t, err)
+	assert.Equal(t, "aisyah@example.com", confirmed.OldEmail)
+	assert.Equal(t, "aisyah.new@example.com", profile.Email)
+	assert.Equal(t, int64(2), profile.Version)
+
+	// A token works once
+	_, _, err = repo.Confirm(ctx, userID, secondToken)
+	assert.ErrorIs(t, err, domain.ErrEmailChangeTokenInvalid)
+}
+
+func TestEmailChangeRepository_EmailInUseAndExpiry(t *testing.T) {
+	db := openTestDB(t, &domain.Profile{}, &domain.EmailChange{})
+	repo := NewEmailChangeRepository(db)
+	ctx := context.Background()
+
+	userID, otherID := uuid.New(), uuid.New()
+	require.NoError(t, db.Create(&domain.Profile{ID: userID, Email: "aisyah@example.com"}).Error)
+	require.NoError(t, db.Create(&domain.Profile{ID: otherID, Email: "siti@example.com"}).Error)
+
+	_, _, err := repo.Request(ctx, userID, "Siti@example.com")
+	assert.ErrorIs(t, err, domain.ErrEmailInUse)
+
+	// Taken by another customer between request and confirmation
+	_, token, err := repo.Request(ctx, userID, "shared@example.com")
+	require.NoError(t, err)
+	require.NoError(t, db.Model(&domain.Profile{}).Where("id = ?", otherID).Update("email", "shared@example.com").Error)
+	_, _, err = repo.Confirm(ctx, userID, token)
+	assert.ErrorIs(t, err, domain.ErrEmailInUse)
+
+	_, token, err = repo.Request(ctx, userID, "later@example.com")
+	require.NoError(t, err)
+	require.NoError(t, db.Model(&domain.EmailChange{}).Where("user_id = ?", userID).
+		Update("expires_at", time.Now().Add(-time.Minute)).Error)
+	_, _, err = repo.Confirm(ctx, userID, token)
+	assert.ErrorIs(t, err, domain.ErrEmailChangeTokenInvalid)
+}
@@ -0,0 +1,152 @@
+package persistence
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/Ecom-micro-template/service-customer/internal/domain/shared"
+	"gorm.io/gorm"
+)
+
+// enumConstraint is a CHECK constraint keeping a column within a Go enum
+type enumConstraint struct {
+	Table  string
+	Column string
+	Values []string
+	// Nullable columns store an unset value as NULL rather than ''
+	Nullable bool
+	// Fallback replaces stored values outside the enum before the constraint
+	// is validated; empty leaves them for someone to fix by hand
+	Fallback string
+}
+
+// Name is the constraint name, e.g. chk_customers_status
+func (e enumConstraint) Name() string {
+	table := e.Table
+	if i := strings.LastIndex(table, "."); i >= 0 {
+		table = table[i+1:]
+	}
+	return "chk_" + table + "_" + e.Column
+}
+
+// enumConstraints lists the enum columns. The allowed values come from the
+// shared enum types so the database and Go can't disagree.
+func enumConstraints() []enumConstraint {
+	return []enumConstraint{
+		{Table: domain.Customer{}.TableName(), Column: "status", Values: shared.EnumValues(shared.AllCustomerStatuses())},
+		{Table: domain.SegmentRule{}.TableName(), Column: "status", Values: shared.EnumValues(shared.AllCustomerStatuses())},
+		{Table: domain.Profile{}.TableName(), Column: "gender", Values: shared.EnumValues(shared.AllProfileGenders()), Nullable: true, Fallback: string(shared.ProfileGenderOther)},
+		{Table: domain.CustomerMeasurement{}.TableName(), Column: "gender", Values: shared.EnumValues(shared.AllGenders())},
+		{Table: domain.Address{}.TableName(), Column: "label", Values: shared.EnumValues(shared.AllAddressLabels()), Nullable: true, Fallback: string(shared.AddressLabelOther)},
+	}
+}
+
+// quotedPattern matches the string literals in a constraint definition
+var quotedPattern = regexp.MustCompile(`'((?:[^']|'')*)'`)
+
+// EnsureEnumConstraints creates or updates the CHECK constraints on enum
+// columns (PostgreSQL only). Stored values differing only in case or spacing
+// are normalized first and blanks in nullable columns become NULL. A constraint is added NOT VALID, so new writes are
+// checked straight away, and then validated; if legacy rows still hold bad
+// values the validation error is returned and the constraint keeps guarding
+// new writes until they are fixed.
+func EnsureEnumConstraints(db *gorm.DB) error {
+	var failed []string
+	for _, e := range enumConstraints() {
+		if err := ensureEnumConstraint(db, e); err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %v", e.Name(), err))
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("enum constraints: %s", strings.Join(failed, "; "))
+	}
+	return nil
+}
+
+func ensureEnumConstraint(db *gorm.DB, e enumConstraint) error {
+	var existing struct {
+		Def          string
+		Convalidated bool
+	}
+	if err := db.Raw(
+		`SELECT pg_get_constraintdef(oid) AS def, convalidated FROM pg_constraint WHERE conname = ? AND conrelid = ?::regclass`,
+		e.Name(), e.Table,
+	).Scan(&existing).Error; err != nil {
+		return err
+	}
+	if existing.Def != "" && existing.Convalidated && sameValues(constraintValues(existing.Def), e.Values) {
+		return nil
+	}
+
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		if e.Nullable {
+			if err := tx.Exec(
+				fmt.Sprintf(`UPDATE %s SET %s = NULL WHERE TRIM(%[2]s) = ''`, e.Table, e.Column),
+			).Error; err != nil {
+				return err
+			}
+		}
+		for _, v := range e.Values {
+			if err := tx.Exec(
+				fmt.Sprintf(`UPDATE %s SET %s = ? WHERE LOWER(TRIM(%[2]s)) = LOWER(?) AND %[2]s <> ?`, e.Table, e.Column),
+				v, v, v,
+			).Error; err != nil {
+				return err
+			}
+		}
+		if e.Fallback != "" {
+			if err := tx.Exec(
+				fmt.Sprintf(`UPDATE %s SET %s = ? WHERE %[2]s NOT IN ?`, e.Table, e.Column),
+				e.Fallback, e.Values,
+			).Error; err != nil {
+				return err
+			}
+		}
+		if existing.Def != "" && sameValues(constraintValues(existing.Def), e.Values) {
+			return nil
+		}
+
+		quoted := make([]string, len(e.Values))
+		for i, v := range e.Values {
+			quoted[i] = "'" + strings.ReplaceAll(v, "'", "''") + "'"
+		}
+		return tx.Exec(fmt.Sprintf(
+			`ALTER TABLE %s DROP CONSTRAINT IF EXISTS %s, ADD CONSTRAINT %[2]s CHECK (%s IN (%s)) NOT VALID`,
+			e.Table, e.Name(), e.Column, strings.Join(quoted, ", "),
+		)).Error
+	}); err != nil {
+		return err
+	}
+
+	// Fails while legacy rows hold values outside the enum; the NOT VALID
+	// constraint still checks new writes meanwhile
+	return db.Exec(fmt.Sprintf(`ALTER TABLE %s VALIDATE CONSTRAINT %s`, e.Table, e.Name())).Error
+}
+
+// constraintValues extracts the allowed values from a CHECK definition such as
+// CHECK (((status)::text = ANY ((ARRAY['active'::character varying, ...])::text[])))
+func constraintValues(def string) []string {
+	var values []string
+	for _, m := range quotedPattern.FindAllStringSubmatch(def, -1) {
+		values = append(values, strings.ReplaceAll(m[1], "''", "'"))
+	}
+	return values
+}
+
+func sameValues(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	a, b = append([]string(nil), a...), append([]string(nil), b...)
+	sort.Strings(a)
+	sort.Strings(b)
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,70 @@
+package persistence
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/Ecom-micro-template/service-customer/internal/domain/shared"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnumColumnsRejectBadValues(t *testing.T) {
+	db := openTestDB(t, &domain.Customer{}, &domain.Profile{}, &domain.Address{})
+
+	customer := &domain.Customer{Email: "amir@example.com", Status: "banned"}
+	assert.ErrorIs(t, db.Create(customer).Error, shared.ErrInvalidCustomerStatus)
+	customer.Status = shared.StatusActive
+	require.NoError(t, db.Create(customer).Error)
+	err := db.Model(customer).Updates(map[string]interface{}{"status": shared.CustomerStatus("Active ")}).Error
+	assert.ErrorIs(t, err, shared.ErrInvalidCustomerStatus)
+
+	// Unset optional enums are stored as NULL
+	profile := &domain.Profile{ID: uuid.New(), Email: "amir@example.com"}
+	require.NoError(t, db.Create(profile).Error)
+	var gender *string
+	require.NoError(t, db.Model(&domain.Profile{}).Where("id = ?", profile.ID).Select("gender").Scan(&gender).Error)
+	assert.Nil(t, gender)
+	profile.Gender = "unknown"
+	assert.ErrorIs(t, db.Save(profile).Error, shared.ErrInvalidProfileGender)
+
+	// Legacy values still load
+	address := &domain.Address{UserID: uuid.New(), Label: shared.AddressLabelOffice, RecipientName: "Amir", Phone: "0123456789",
+		AddressLine1: "1 Jalan Ampang", City: "Kuala Lumpur", State: "WP", Postcode: "50450", Country: "Malaysia"}
+	require.NoError(t, db.Create(address).Error)
+	require.NoError(t, db.Exec("UPDATE customer_addresses SET label = ? WHERE id = ?", "Grandma's", address.ID).Error)
+	var loaded domain.Address
+	require.NoError(t, db.First(&loaded, "id = ?", address.ID).Error)
+	assert.Equal(t, shared.AddressLabel("Grandma's"), loaded.Label)
+}
+
+func TestParseEnums(t *testing.T) {
+	label, err := shared.ParseAddressLabel(" office ")
+	require.NoError(t, err)
+	assert.Equal(t, shared.AddressLabelOffice, label)
+	_, err = shared.ParseAddressLabel("Grandma's")
+	assert.ErrorIs(t, err, shared.ErrInvalidAddressLabel)
+
+	gender, err := shared.ParseProfileGender("Female")
+	require.NoError(t, err)
+	assert.Equal(t, shared.ProfileGenderFemale, gender)
+	_, err = shared.ParseGender("male")
+	assert.ErrorIs(t, err, shared.ErrInvalidGender)
+}
+
+func TestEnumConstraintDefinitions(t *testing.T) {
+	def := `CHECK (((status)::text = ANY ((ARRAY['active'::character varying, 'inactive'::character varying, 'suspended'::character varying, 'blocked'::character varying])::text[])))`
+	values := shared.EnumValues(shared.AllCustomerStatuses())
+	assert.True(t, sameValues(constraintValues(def), values))
+	assert.False(t, sameValues(constraintValues(def), values[:3]))
+	assert.Equal(t, []string{"it's"}, constraintValues(`CHECK ((label)::text = 'it''s'::text)`))
+
+	names := map[string]bool{}
+	for _, e := range enumConstraints() {
+		assert.NotEmpty(t, e.Values, e.Name())
+		assert.False(t, names[e.Name()], "duplicate constraint %s", e.Name())
+		names[e.Name()] = true
+	}
+	assert.True(t, names["chk_customers_status"])
+}
@@ -0,0 +1,294 @@
+package persistence
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"gorm.io/gorm"
+)
+
+// GuestBackInStockRepository handles email-only back-in-stock subscriptions
+type GuestBackInStockRepository struct {
+	db  *gorm.DB
+	ttl time.Duration
+}
+
+// NewGuestBackInStockRepository creates a new repository
+func NewGuestBackInStockRepository(db *gorm.DB) *GuestBackInStockRepository {
+	return &GuestBackInStockRepository{db: db, ttl: domain.DefaultBackInStockSubscriptionTTL}
+}
+
+// WithSubscriptionTTL sets how long new and renewed subscriptions stay active
+func (r *GuestBackInStockRepository) WithSubscriptionTTL(ttl time.Duration) *GuestBackInStockRepository {
+	if ttl > 0 {
+		r.ttl = ttl
+	}
+	return r
+}
+
+// Subscribe creates a pending guest subscription and returns the confirmation
+// token to email to the guest. Subscribing again to the same product before
+// confirming issues a fresh token. If the subscription is already confirmed,
+// the token is empty and no email needs to be sent.
+func (r *GuestBackInStockRepository) Subscribe(ctx context.Context, input domain.GuestBackInStockSubscribeInput) (*domain.GuestBackInStockSubscription, string, error) {
+	productID, err := uuid.Parse(input.ProductID)
+	if err != nil {
+		return nil, "", errors.New("invalid product ID")
+	}
+
+	var variantID *uuid.UUID
+	if input.VariantID != "" {
+		vid, err := uuid.Parse(input.VariantID)
+		if err != nil {
+			return nil, "", errors.New("invalid variant ID")
+		}
+		variantID = &vid
+	}
+
+	email := normalizeEmail(input.Email)
+	token, tokenHash, err := newConfirmationToken()
+	if err != nil {
+		return nil, "", err
+	}
+	expiresAt := time.Now().Add(domain.GuestConfirmationTTL)
+	subscriptionExpiresAt := time.Now().Add(r.ttl)
+
+	var subscription domain.GuestBackInStockSubscription
+	err = r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		query := tx.Where("email = ? AND product_id = ? AND is_notified = ?", email, productID, false)
+		if variantID != nil {
+			query = query.Where("variant_id = ?", variantID)
+		} else {
+			query = query.Where("variant_id IS NULL")
+		}
+
+		err := query.First(&subscription).Error
+		if err == nil {
+			subscription.ExpiresAt = &subscriptionExpiresAt
+			if subscription.IsConfirmed() {
+				token = ""
+			} else {
+				subscription.TokenHash = tokenHash
+				subscription.TokenExpiresAt = expiresAt
+			}
+			return tx.Save(&subscription).Error
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		var open int64
+		if err := tx.Model(&domain.GuestBackInStockSubscription{}).
+			Where("email = ? AND is_notified = ?", email, false).
+			Count(&open).Error; err != nil {
+			return err
+		}
+		if open >= domain.MaxGuestSubscriptionsPerEmail {
+			return domain.ErrGuestSubscriptionLimit
+		}
+
+		subscription = domain.GuestBackInStockSubscription{
+			Email:          email,
+			ProductID:      productID,
+			VariantID:      variantID,
+			ProductName:    input.ProductName,
+			ProductSlug:    input.ProductSlug,
+			ProductImage:   input.ProductImage,
+			VariantSKU:     input.VariantSKU,
+			VariantName:    input.VariantName,
+			TokenHash:      tokenHash,
+			TokenExpiresAt: expiresAt,
+			ExpiresAt:      &subscriptionExpiresAt,
+		}
+		return tx.Create(&subscription).Error
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	return &subscription, token, nil
+}
+
+// Confirm activates the subscription the token was issued for. Confirming an
+// already confirmed subscription again is not an error.
+func (r *GuestBackInStockRepository) Confirm(ctx context.Context, token string) (*domain.GuestBackInStockSubscription, error) {
+	var subscription domain.GuestBackInStockSubscription
+	err := r.db.WithContext(ctx).
+		Where("token_hash = ?", hashConfirmationToken(token)).
+		First(&subscription).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, domain.ErrGuestTokenInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if subscription.IsConfirmed() {
+		return &subscription, nil
+	}
+	if time.Now().After(subscription.TokenExpiresAt) {
+		return nil, domain.ErrGuestTokenInvalid
+	}
+
+	now := time.Now()
+	if err := r.db.WithContext(ctx).Model(&subscription).Update("confirmed_at", now).Error; err != nil {
+		return nil, err
+	}
+	subscription.ConfirmedAt = &now
+	return &subscription, nil
+}
+
+// GetByID returns a guest subscription
+func (r *GuestBackInStockRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.GuestBackInStockSubscription, error) {
+	var subscription domain.GuestBackInStockSubscription
+	if err := r.db.WithContext(ctx).First(&subscription, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &subscription, nil
+}
+
+// GetConfirmedByProduct returns confirmed, unexpired guest subscriptions for a
+// product that have not been notified yet, oldest first
+func (r *GuestBackInStockRepository) GetConfirmedByProduct(ctx context.Context, productID uuid.UUID, variantID *uuid.UUID) ([]domain.GuestBackInStockSubscription, error) {
+	var subscriptions []domain.GuestBackInStockSubscription
+	query := r.db.WithContext(ctx).
+		Where("product_id = ? AND is_notified = ? AND confirmed_at IS NOT NULL", productID, false).
+		Where("expires_at IS NULL OR expires_at > ?", time.Now())
+
+	if variantID != nil {
+		query = query.Where("variant_id = ?", variantID)
+	}
+
+	err := query.Order("created_at ASC, id ASC").Find(&subscriptions).Error
+	return subscriptions, err
+}
+
+// ConfirmedPendingAfter returns confirmed, unexpired guest subscriptions
+// that have not been notified, after the cursor (nil for the first page),
+// longest waiting first, and the next page's cursor
+func (r *GuestBackInStockRepository) ConfirmedPendingAfter(ctx context.Context, after *domain.Cursor, limit int, now time.Time) ([]domain.GuestBackInStockSubscription, string, error) {
+	var subscriptions []domain.GuestBackInStockSubscription
+
+	query := r.db.WithContext(ctx).Model(&domain.GuestBackInStockSubscription{}).
+		Where("is_notified = ? AND confirmed_at IS NOT NULL", false).
+		Where("expires_at IS NULL OR expires_at > ?", now)
+	if err := keysetOrder(query, after, false, limit).Find(&subscriptions).Error; err != nil {
+		return nil, "", err
+	}
+	subscriptions, next := cursorPage(subscriptions, limit, func(s *domain.GuestBackInStockSubscription) domain.Cursor {
+		return domain.Cursor{CreatedAt: s.CreatedAt, ID: s.ID}
+	})
+	return subscriptions, next, nil
+}
+
+// MarkMultipleAsNotified marks guest subscriptions as notified
+func (r *GuestBackInStockRepository) MarkMultipleAsNotified(ctx context.Context, subscriptionIDs []uuid.UUID) error {
+	return r.db.WithContext(ctx).
+		Model(&domain.GuestBackInStockSubscription{}).
+		Where("id IN ?", subscriptionIDs).
+		Updates(map[string]interface{}{
+			"is_notified":          true,
+			"notification_sent_at": time.Now(),
+		}).Error
+}
+
+// CountNotifiedSince returns how many back-in-stock notifications a guest
+// email has been sent since the given time
+func (r *GuestBackInStockRepository) CountNotifiedSince(ctx context.Context, email string, since time.Time) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).
+		Model(&domain.GuestBackInStockSubscription{}).
+		Unscoped().
+		Where("email = ? AND is_notified = ? AND notification_sent_at >= ?", normalizeEmail(email), true, since).
+		Count(&count).Error
+	return count, err
+}
+
+// ExpirePending removes pending guest subscriptions whose expiry has passed,
+// including unconfirmed ones. Subscriptions created before expiry was tracked
+// expire once they are older than the repository's TTL.
+func (r *GuestBackInStockRepository) ExpirePending(ctx context.Context, now time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).
+		Where("is_notified = ?", false).
+		Where("expires_at <= ? OR (expires_at IS NULL AND created_at <= ?)", now, now.Add(-r.ttl)).
+		Delete(&domain.GuestBackInStockSubscription{})
+	return result.RowsAffected, result.Error
+}
+
+// MergeIntoCustomer turns the confirmed, not yet notified guest subscriptions
+// of an email into subscriptions of the customer who registered with it.
+// Products the customer already follows are not duplicated. Returns the
+// number of subscriptions added to the customer.
+func (r *GuestBackInStockRepository) MergeIntoCustomer(ctx context.Context, email string, customerID uuid.UUID) (int, error) {
+	merged := 0
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var guests []domain.GuestBackInStockSubscription
+		if err := tx.Where("email = ? AND is_notified = ? AND confirmed_at IS NOT NULL", normalizeEmail(email), false).
+			Find(&guests).Error; err != nil {
+			return err
+		}
+
+		for _, guest := range guests {
+			var count int64
+			query := tx.Model(&domain.BackInStockSubscription{}).
+				Where("customer_id = ? AND product_id = ?", customerID, guest.ProductID)
+			if guest.VariantID != nil {
+				query = query.Where("variant_id = ?", guest.VariantID)
+			} else {
+				query = query.Where("variant_id IS NULL")
+			}
+			if err := query.Count(&count).Error; err != nil {
+				return err
+			}
+
+			if count == 0 {
+				if err := tx.Create(&domain.BackInStockSubscription{
+					CustomerID:   customerID,
+					ProductID:    guest.ProductID,
+					VariantID:    guest.VariantID,
+					ProductName:  guest.ProductName,
+					ProductSlug:  guest.ProductSlug,
+					ProductImage: guest.ProductImage,
+					VariantSKU:   guest.VariantSKU,
+					VariantName:  guest.VariantName,
+					ExpiresAt:    guest.ExpiresAt,
+				}).Error; err != nil {
+					return err
+				}
+				merged++
+			}
+
+			if err := tx.Delete(&guest).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return merged, err
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// newConfirmationToken returns a random token to email and the hash stored for it
+func newConfirmationToken() (token, tokenHash string, err error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", err
+	}
+	token = hex.EncodeToString(buf)
+	return token, hashConfirmationToken(token), nil
+}
+
+func hashConfirmationToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,141 @@
+package persistence
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func setupGuestBackInStockTestDB(t *testing.T) *gorm.DB {
+	return openTestDB(t,
+		&domain.Customer{},
+		&domain.BackInStockSubscription{},
+		&domain.GuestBackInStockSubscription{},
+	)
+}
+
+func guestInput(email string, productID uuid.UUID) domain.GuestBackInStockSubscribeInput {
+	return domain.GuestBackInStockSubscribeInput{
+		Email: email,
+		BackInStockSubscribeInput: domain.BackInStockSubscribeInput{
+			ProductID:   productID.String(),
+			ProductName: "Baju Kurung Moden",
+		},
+	}
+}
+
+func TestGuestBackInStockRepository_SubscribeAndConfirm(t *testing.T) {
+	db := setupGuestBackInStockTestDB(t)
+	repo := NewGuestBackInStockRepository(db)
+	ctx := context.Background()
+	productID := uuid.New()
+
+	subscription, firstToken, err := repo.Subscribe(ctx, guestInput(" Aisyah@Example.com ", productID))
+	require.NoError(t, err)
+	assert.NotEmpty(t, firstToken)
+	assert.Equal(t, "aisyah@example.com", subscription.Email)
+
+	// Unconfirmed subscriptions are not notified
+	pending, err := repo.GetConfirmedByProduct(ctx, productID, nil)
+	require.NoError(t, err)
+	assert.Empty(t, pending)
+
+	// Subscribing again resends with a new token; the old one stops working
+	again, secondToken, err := repo.Subscribe(ctx, guestInput("aisyah@example.com", productID))
+	require.NoError(t, err)
+	assert.Equal(t, subscription.ID, again.ID)
+	assert.NotEqual(t, firstToken, secondToken)
+
+	_, err = repo.Confirm(ctx, firstToken)
+	assert.ErrorIs(t, err, domain.ErrGuestTokenInvalid)
+
+	confirmed, err := repo.Confirm(ctx, secondToken)
+	require.NoError(t, err)
+	assert.True(t, confirmed.IsConfirmed())
+
+	pending, err = repo.GetConfirmedByProduct(ctx, productID, nil)
+	require.NoError(t, err)
+	assert.Len(t, pending, 1)
+
+	// Once confirmed no further email is needed
+	_, token, err := repo.Subscribe(ctx, guestInput("aisyah@example.com", productID))
+	require.NoError(t, err)
+	assert.Empty(t, token)
+}
+
+func TestGuestBackInStockRepository_ExpiredToken(t *testing.T) {
+	db := setupGuestBackInStockTestDB(t)
+	repo := NewGuestBackInStockRepository(db)
+	ctx := context.Background()
+
+	subscription, token, err := repo.Subscribe(ctx, guestInput("aisyah@example.com", uuid.New()))
+	require.NoError(t, err)
+	require.NoError(t, db.Model(subscription).Update("token_expires_at", time.Now().Add(-time.Hour)).Error)
+
+	_, err = repo.Confirm(ctx, token)
+	assert.ErrorIs(t, err, domain.ErrGuestTokenInvalid)
+}
+
+func TestGuestBackInStockRepository_MergeIntoCustomer(t *testing.T) {
+	db := setupGuestBackInStockTestDB(t)
+	repo := NewGuestBackInStockRepository(db)
+	ctx := context.Background()
+
+	customerID := uuid.New()
+	followed, unfollowed, unconfirmed := uuid.New(), uuid.New(), uuid.New()
+	require.NoError(t, db.Create(&domain.BackInStockSubscription{ID: uuid.New(), CustomerID: customerID, ProductID: followed}).Error)
+
+	for _, productID := range []uuid.UUID{followed, unfollowed} {
+		_, token, err := repo.Subscribe(ctx, guestInput("aisyah@example.com", productID))
+		require.NoError(t, err)
+		_, err = repo.Confirm(ctx, token)
+		require.NoError(t, err)
+	}
+	_, _, err := repo.Subscribe(ctx, guestInput("aisyah@example.com", unconfirmed))
+	require.NoError(t, err)
+
+	merged, err := repo.MergeIntoCustomer(ctx, "AISYAH@example.com", customerID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, merged)
+
+	var products []uuid.UUID
+	require.NoError(t, db.Model(&domain.BackInStockSubscription{}).
+		Where("customer_id = ?", customerID).
+		Pluck("product_id", &products).Error)
+	assert.ElementsMatch(t, []uuid.UUID{followed, unfollowed}, products)
+
+	// Only the unconfirmed guest subscription is left behind
+	var remaining int64
+	db.Model(&domain.GuestBackInStockSubscription{}).Count(&remaining)
+	assert.Equal(t, int64(1), remaining)
+}
+
+func TestGuestBackInStockRepository_ExpirePending(t *testing.T) {
+	db := setupGuestBackInStockTestDB(t)
+	repo := NewGuestBackInStockRepository(db)
+	ctx := context.Background()
+	productID := uuid.New()
+
+	_, token, err := repo.Subscribe(ctx, guestInput("aisyah@example.com", productID))
+	require.NoError(t, err)
+	subscription, err := repo.Confirm(ctx, token)
+	require.NoError(t, err)
+	_, _, err = repo.Subscribe(ctx, guestInput("aisyah@example.com", uuid.New()))
+	require.NoError(t, err)
+
+	require.NoError(t, db.Model(subscription).Update("expires_at", time.Now().Add(-time.Minute)).Error)
+
+	pending, err := repo.GetConfirmedByProduct(ctx, productID, nil)
+	require.NoError(t, err)
+	assert.Empty(t, pending)
+
+	expired, err := repo.ExpirePending(ctx, time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), expired)
+}
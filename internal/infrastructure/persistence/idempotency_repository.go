@@ -0,0 +1,92 @@
+package persistence
+
+import (
+	"context"
+	"time"
+
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DefaultIdempotencyLockTimeout is how long a key stays claimed by a request
+// that never completed, e.g. because its replica crashed, before a retry can
+// take it over
+const DefaultIdempotencyLockTimeout = time.Minute
+
+// IdempotencyRepository stores the responses of requests sent with an
+// Idempotency-Key header
+type IdempotencyRepository struct {
+	db          *gorm.DB
+	lockTimeout time.Duration
+}
+
+// NewIdempotencyRepository creates a new idempotency repository
+func NewIdempotencyRepository(db *gorm.DB) *IdempotencyRepository {
+	return &IdempotencyRepository{db: db, lockTimeout: DefaultIdempotencyLockTimeout}
+}
+
+// WithLockTimeout sets how long an uncompleted claim blocks retries
+func (r *IdempotencyRepository) WithLockTimeout(timeout time.Duration) *IdempotencyRepository {
+	r.lockTimeout = timeout
+	return r
+}
+
+// Reserve claims key.Key within key.Scope for a new request and returns nil.
+// If the key is already claimed, the stored key is returned instead: its
+// response when completed, or its claim while the request is in progress.
+// Expired keys and claims older than the lock timeout are replaced.
+func (r *IdempotencyRepository) Reserve(ctx context.Context, key *domain.IdempotencyKey) (*domain.IdempotencyKey, error) {
+	db := r.db.WithContext(ctx)
+	now := time.Now()
+	if err := db.
+		Where("scope = ? AND idempotency_key = ?", key.Scope, key.Key).
+		Where("expires_at <= ? OR (status_code = 0 AND created_at <= ?)", now, now.Add(-r.lockTimeout)).
+		Delete(&domain.IdempotencyKey{}).Error; err != nil {
+		return nil, err
+	}
+
+	result := db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "scope"}, {Name: "idempotency_key"}},
+		DoNothing: true,
+	}).Create(key)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected > 0 {
+		return nil, nil
+	}
+
+	var existing domain.IdempotencyKey
+	if err := db.Where("scope = ? AND idempotency_key = ?", key.Scope, key.Key).First(&existing).Error; err != nil {
+		return nil, err
+	}
+	return &existing, nil
+}
+
+// Complete stores the response of a reserved key
+func (r *IdempotencyRepository) Complete(ctx context.Context, key *domain.IdempotencyKey) error {
+	return r.db.WithContext(ctx).Model(&domain.IdempotencyKey{}).
+		Where("id = ?", key.ID).
+		Updates(map[string]interface{}{
+			"status_code":  key.StatusCode,
+			"content_type": key.ContentType,
+			"body":         key.Body,
+		}).Error
+}
+
+// Release drops the claim of a request that failed, so a retry with the same
+// key runs again
+func (r *IdempotencyRepository) Release(ctx context.Context, key *domain.IdempotencyKey) error {
+	return r.db.WithContext(ctx).
+		Where("id = ? AND status_code = 0", key.ID).
+		Delete(&domain.IdempotencyKey{}).Error
+}
+
+// DeleteExpired deletes the keys past their expiry and returns how many
+func (r *IdempotencyRepository) DeleteExpired(ctx context.Context) (int64, error) {
+	result := r.db.WithContext(ctx).
+		Where("expires_at <= ?", time.Now()).
+		Delete(&domain.IdempotencyKey{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,98 @@
+package persistence
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestIdempotencyKey(scope, key string) *domain.IdempotencyKey {
+	return &domain.IdempotencyKey{
+		Scope:       scope,
+		Key:         key,
+		Method:      http.MethodPost,
+		Path:        "/api/v1/customer/addresses",
+		RequestHash: "6b86b273ff34fce19d6b804eff5a3f5747ada4eaa22f1d49c01e52ddb7875b4b",
+		ExpiresAt:   time.Now().Add(time.Hour),
+	}
+}
+
+func TestIdempotencyRepository_ReserveAndComplete(t *testing.T) {
+	db := openTestDB(t, &domain.IdempotencyKey{})
+	repo := NewIdempotencyRepository(db)
+	ctx := context.Background()
+
+	first := newTestIdempotencyKey("customer-1", "retry-me")
+	existing, err := repo.Reserve(ctx, first)
+	require.NoError(t, err)
+	assert.Nil(t, existing)
+
+	// A retry while the first request runs sees its claim
+	existing, err = repo.Reserve(ctx, newTestIdempotencyKey("customer-1", "retry-me"))
+	require.NoError(t, err)
+	require.NotNil(t, existing)
+	assert.False(t, existing.Completed())
+
+	first.StatusCode = http.StatusCreated
+	first.ContentType = "application/json; charset=utf-8"
+	first.Body = []byte(`{"success":true}`)
+	require.NoError(t, repo.Complete(ctx, first))
+
+	existing, err = repo.Reserve(ctx, newTestIdempotencyKey("customer-1", "retry-me"))
+	require.NoError(t, err)
+	require.NotNil(t, existing)
+	assert.Equal(t, http.StatusCreated, existing.StatusCode)
+	assert.Equal(t, `{"success":true}`, string(existing.Body))
+
+	// Keys are scoped to the caller
+	existing, err = repo.Reserve(ctx, newTestIdempotencyKey("customer-2", "retry-me"))
+	require.NoError(t, err)
+	assert.Nil(t, existing)
+}
+
+func TestIdempotencyRepository_ReleaseAndExpiry(t *testing.T) {
+	db := openTestDB(t, &domain.IdempotencyKey{})
+	repo := NewIdempotencyRepository(db).WithLockTimeout(time.Hour)
+	ctx := context.Background()
+
+	// A released claim can be taken again
+	failed := newTestIdempotencyKey("customer-1", "failed")
+	_, err := repo.Reserve(ctx, failed)
+	require.NoError(t, err)
+	require.NoError(t, repo.Release(ctx, failed))
+	existing, err := repo.Reserve(ctx, newTestIdempotencyKey("customer-1", "failed"))
+	require.NoError(t, err)
+	assert.Nil(t, existing)
+
+	// An abandoned claim is taken over after the lock timeout
+	abandoned := newTestIdempotencyKey("customer-1", "abandoned")
+	_, err = repo.Reserve(ctx, abandoned)
+	require.NoError(t, err)
+	require.NoError(t, db.Model(abandoned).Update("created_at", time.Now().Add(-2*time.Hour)).Error)
+	existing, err = repo.Reserve(ctx, newTestIdempotencyKey("customer-1", "abandoned"))
+	require.NoError(t, err)
+	assert.Nil(t, existing)
+
+	// Expired keys are replaced on reserve and deleted by the cleanup
+	expired := newTestIdempotencyKey("customer-1", "expired")
+	expired.ExpiresAt = time.Now().Add(-time.Minute)
+	expired.StatusCode = http.StatusCreated
+	require.NoError(t, db.Create(expired).Error)
+	stale := newTestIdempotencyKey("customer-2", "expired")
+	stale.ExpiresAt = time.Now().Add(-time.Minute)
+	stale.StatusCode = http.StatusCreated
+	require.NoError(t, db.Create(stale).Error)
+
+	existing, err = repo.Reserve(ctx, newTestIdempotencyKey("customer-1", "expired"))
+	require.NoError(t, err)
+	assert.Nil(t, existing)
+
+	deleted, err := repo.DeleteExpired(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), deleted)
+}
@@ -0,0 +1,90 @@
+package persistence
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"gorm.io/gorm"
+)
+
+// ImpersonationRepository stores admin impersonation sessions and the
+// requests made with them
+type ImpersonationRepository struct {
+	db *gorm.DB
+}
+
+// NewImpersonationRepository creates a new impersonation repository
+func NewImpersonationRepository(db *gorm.DB) *ImpersonationRepository {
+	return &ImpersonationRepository{db: db}
+}
+
+// Create starts a new impersonation session
+func (r *ImpersonationRepository) Create(ctx context.Context, session *domain.ImpersonationSession) error {
+	return r.db.WithContext(ctx).Create(session).Error
+}
+
+// GetActive returns the session if it is neither expired nor revoked
+func (r *ImpersonationRepository) GetActive(ctx context.Context, id uuid.UUID) (*domain.ImpersonationSession, error) {
+	var session domain.ImpersonationSession
+	if err := r.db.WithContext(ctx).Where("id = ?", id).First(&session).Error; err != nil {
+		return nil, err
+	}
+	if !session.IsActive(time.Now()) {
+		return nil, domain.ErrImpersonationInactive
+	}
+	return &session, nil
+}
+
+// Revoke ends a session early. Revoking an expired or already revoked
+// session returns domain.ErrImpersonationInactive.
+func (r *ImpersonationRepository) Revoke(ctx context.Context, id, revokedBy uuid.UUID) (*domain.ImpersonationSession, error) {
+	session, err := r.GetActive(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if err := r.db.WithContext(ctx).Model(session).Updates(map[string]interface{}{
+		"revoked_at": now,
+		"revoked_by": revokedBy,
+	}).Error; err != nil {
+		return nil, err
+	}
+	session.RevokedAt = &now
+	session.RevokedBy = &revokedBy
+	return session, nil
+}
+
+// ListByCustomer returns a customer's impersonation sessions, newest first
+func (r *ImpersonationRepository) ListByCustomer(ctx context.Context, customerID uuid.UUID, page, limit int) ([]domain.ImpersonationSession, int64, error) {
+	var sessions []domain.ImpersonationSession
+	var total int64
+
+	query := r.db.WithContext(ctx).Model(&domain.ImpersonationSession{}).Where("customer_id = ?", customerID)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err := query.Order("created_at DESC").
+		Offset((page - 1) * limit).
+		Limit(limit).
+		Find(&sessions).Error
+	return sessions, total, err
+}
+
+// RecordRequest logs a request made with an impersonation token
+func (r *ImpersonationRepository) RecordRequest(ctx context.Context, request *domain.ImpersonationRequest) error {
+	return r.db.WithContext(ctx).Create(request).Error
+}
+
+// ListRequests returns the requests made in a session, oldest first
+func (r *ImpersonationRepository) ListRequests(ctx context.Context, sessionID uuid.UUID) ([]domain.ImpersonationRequest, error) {
+	var requests []domain.ImpersonationRequest
+	err := r.db.WithContext(ctx).
+		Where("session_id = ?", sessionID).
+		Order("created_at ASC").
+		Find(&requests).Error
+	return requests, err
+}
@@ -0,0 +1,72 @@
+package persistence
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImpersonationRepository_Sessions(t *testing.T) {
+	db := openTestDB(t, &domain.ImpersonationSession{}, &domain.ImpersonationRequest{})
+	repo := NewImpersonationRepository(db)
+	ctx := context.Background()
+	adminID, customerID := uuid.New(), uuid.New()
+
+	active := &domain.ImpersonationSession{
+		AdminID: adminID, CustomerID: customerID, Reason: "Checkout fails only for this customer",
+		Scope: domain.ImpersonationScopeRead, ExpiresAt: time.Now().Add(15 * time.Minute),
+	}
+	expired := &domain.ImpersonationSession{
+		AdminID: adminID, CustomerID: customerID, Reason: "Earlier look at the wishlist",
+		Scope: domain.ImpersonationScopeRead, ExpiresAt: time.Now().Add(-time.Minute),
+	}
+	require.NoError(t, repo.Create(ctx, active))
+	require.NoError(t, repo.Create(ctx, expired))
+
+	got, err := repo.GetActive(ctx, active.ID)
+	require.NoError(t, err)
+	assert.True(t, got.Allows(http.MethodGet))
+	assert.False(t, got.Allows(http.MethodPost))
+
+	_, err = repo.GetActive(ctx, expired.ID)
+	assert.ErrorIs(t, err, domain.ErrImpersonationInactive)
+
+	require.NoError(t, repo.RecordRequest(ctx, &domain.ImpersonationRequest{
+		SessionID: active.ID, AdminID: adminID, CustomerID: customerID,
+		Method: http.MethodGet, Path: "/api/v1/customer/wishlist", Status: http.StatusOK,
+	}))
+	requests, err := repo.ListRequests(ctx, active.ID)
+	require.NoError(t, err)
+	require.Len(t, requests, 1)
+	assert.Equal(t, "/api/v1/customer/wishlist", requests[0].Path)
+
+	// Revoked sessions stop working and cannot be revoked twice
+	revoked, err := repo.Revoke(ctx, active.ID, adminID)
+	require.NoError(t, err)
+	require.NotNil(t, revoked.RevokedAt)
+	_, err = repo.GetActive(ctx, active.ID)
+	assert.ErrorIs(t, err, domain.ErrImpersonationInactive)
+	_, err = repo.Revoke(ctx, active.ID, adminID)
+	assert.ErrorIs(t, err, domain.ErrImpersonationInactive)
+
+	sessions, total, err := repo.ListByCustomer(ctx, customerID, 1, 10)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), total)
+	assert.Len(t, sessions, 2)
+}
+
+func TestStartImpersonationRequest_Defaults(t *testing.T) {
+	req := domain.StartImpersonationRequest{Reason: "Debugging a checkout issue"}
+	assert.Equal(t, domain.ImpersonationScopeRead, req.Scope())
+	assert.Equal(t, domain.DefaultImpersonationTTL, req.TTL())
+
+	req = domain.StartImpersonationRequest{Reason: "Debugging a checkout issue", Write: true, DurationMinutes: 240}
+	assert.Equal(t, domain.ImpersonationScopeWrite, req.Scope())
+	assert.Equal(t, domain.MaxImpersonationTTL, req.TTL())
+}
@@ -0,0 +1,378 @@
+package persistence
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// MarketingSyncRepository stores marketing consent and the queue of customers
+// to push to each configured marketing platform
+type MarketingSyncRepository struct {
+	db        *gorm.DB
+	providers []string
+}
+
+// NewMarketingSyncRepository creates a new marketing sync repository.
+// Changes are only queued for the given providers; with none, nothing is.
+func NewMarketingSyncRepository(db *gorm.DB, providers []string) *MarketingSyncRepository {
+	return &MarketingSyncRepository{db: db, providers: providers}
+}
+
+// Providers returns the marketing platforms changes are queued for
+func (r *MarketingSyncRepository) Providers() []string {
+	return r.providers
+}
+
+// Consent returns a customer's marketing consent. Customers who never
+// answered have not opted in to anything.
+func (r *MarketingSyncRepository) Consent(ctx context.Context, customerID uuid.UUID) (*domain.MarketingConsent, error) {
+	var consent domain.MarketingConsent
+	err := r.db.WithContext(ctx).Where("customer_id = ?", customerID).First(&consent).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &domain.MarketingConsent{CustomerID: customerID, Source: domain.MarketingConsentSourceCustomer}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &consent, nil
+}
+
+// UpdateConsent applies req to a customer's consent and queues the customer
+// for every marketing platform
+func (r *MarketingSyncRepository) UpdateConsent(ctx context.Context, customerID uuid.UUID, req *domain.UpdateMarketingConsentRequest) (*domain.MarketingConsent, error) {
+	var consent *domain.MarketingConsent
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		if consent, err = NewMarketingSyncRepository(tx, r.providers).Consent(ctx, customerID); err != nil {
+			return err
+		}
+
+		if req.EmailOptIn != nil {
+			consent.EmailOptIn = *req.EmailOptIn
+		}
+		if req.SMSOptIn != nil {
+			consent.SMSOptIn = *req.SMSOptIn
+		}
+		consent.Source = domain.MarketingConsentSourceCustomer
+		if err := tx.Save(consent).Error; err != nil {
+			return err
+		}
+		return queueMarketingSync(tx, r.providers, []uuid.UUID{customerID}, time.Now())
+	})
+	if err != nil {
+		return nil, err
+	}
+	return consent, nil
+}
+
+// MarkChanged queues a customer to be pushed to every marketing platform
+func (r *MarketingSyncRepository) MarkChanged(ctx context.Context, customerID uuid.UUID) error {
+	return queueMarketingSync(r.db.WithContext(ctx), r.providers, []uuid.UUID{customerID}, time.Now())
+}
+
+// Claim takes up to limit customers due to be pushed to provider, leaving
+// them to this caller for lease. The returned states share a ClaimID.
+func (r *MarketingSyncRepository) Claim(ctx context.Context, provider string, now time.Time, limit int, lease time.Duration) ([]domain.MarketingSyncState, error) {
+	db := r.db.WithContext(ctx)
+	var customerIDs []uuid.UUID
+	if err := db.Model(&domain.MarketingSyncState{}).
+		Where("provider = ? AND status = ? AND next_attempt_at <= ?", provider, domain.MarketingSyncPending, now).
+		Order("next_attempt_at ASC").
+		Limit(limit).
+		Pluck("customer_id", &customerIDs).Error; err != nil {
+		return nil, err
+	}
+	if len(customerIDs) == 0 {
+		return nil, nil
+	}
+
+	// Another replica may have claimed some of them since
+	claimID := uuid.New()
+	if err := db.Model(&domain.MarketingSyncState{}).
+		Where("provider = ? AND customer_id IN ? AND status = ? AND next_attempt_at <= ?", provider, customerIDs, domain.MarketingSyncPending, now).
+		Updates(map[string]interface{}{
+			"claim_id":        claimID,
+			"next_attempt_at": now.Add(lease),
+		}).Error; err != nil {
+		return nil, err
+	}
+
+	var states []domain.MarketingSyncState
+	err := db.Where("provider = ? AND claim_id = ?", provider, claimID).
+		Order("changed_at ASC").
+		Find(&states).Error
+	return states, err
+}
+
+// Contacts builds the contacts for claimed states. Customers that were
+// deleted since they were queued are returned as skipped.
+func (r *MarketingSyncRepository) Contacts(ctx context.Context, states []domain.MarketingSyncState) ([]domain.MarketingContact, []uuid.UUID, error) {
+	db := r.db.WithContext(ctx)
+	customerIDs := make([]uuid.UUID, 0, len(states))
+	for _, state := range states {
+		customerIDs = append(customerIDs, state.CustomerID)
+	}
+
+	var customers []domain.Customer
+	if err := db.Where("id IN ?", customerIDs).Find(&customers).Error; err != nil {
+		return nil, nil, err
+	}
+	var consents []domain.MarketingConsent
+	if err := db.Where("customer_id IN ?", customerIDs).Find(&consents).Error; err != nil {
+		return nil, nil, err
+	}
+	segments, err := marketingSegmentNames(db, customerIDs)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	byID := make(map[uuid.UUID]*domain.Customer, len(customers))
+	for i := range customers {
+		byID[customers[i].ID] = &customers[i]
+	}
+	consentByID := make(map[uuid.UUID]*domain.MarketingConsent, len(consents))
+	for i := range consents {
+		consentByID[consents[i].CustomerID] = &consents[i]
+	}
+
+	var contacts []domain.MarketingContact
+	var skipped []uuid.UUID
+	for _, id := range customerIDs {
+		customer, consent := byID[id], consentByID[id]
+		if customer == nil || consent == nil {
+			skipped = append(skipped, id)
+			continue
+		}
+		contacts = append(contacts, domain.NewMarketingContact(customer, consent, segments[id]))
+	}
+	return contacts, skipped, nil
+}
+
+// Record stores the outcome of pushing a claimed batch. Customers that
+// changed while they were pushed are left pending to be pushed again.
+func (r *MarketingSyncRepository) Record(ctx context.Context, provider string, claimID uuid.UUID, outcomes []domain.MarketingSyncOutcome, now time.Time) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, outcome := range outcomes {
+			updates := map[string]interface{}{"claim_id": nil}
+			switch {
+			case outcome.Error == "":
+				updates["status"] = domain.MarketingSyncSynced
+				updates["attempts"] = 0
+				updates["last_error"] = ""
+				updates["synced_at"] = now
+			case outcome.NextAttemptAt != nil:
+				updates["attempts"] = gorm.Expr("attempts + 1")
+				updates["last_error"] = outcome.Error
+				updates["next_attempt_at"] = *outcome.NextAttemptAt
+			default:
+				updates["status"] = domain.MarketingSyncFailed
+				updates["attempts"] = gorm.Expr("attempts + 1")
+				updates["last_error"] = outcome.Error
+			}
+
+			if err := tx.Model(&domain.MarketingSyncState{}).
+				Where("provider = ? AND customer_id = ? AND claim_id = ?", provider, outcome.CustomerID, claimID).
+				Updates(updates).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Release hands a claimed batch back unpushed, to be claimed again at until
+func (r *MarketingSyncRepository) Release(ctx context.Context, provider string, claimID uuid.UUID, until time.Time) error {
+	return r.db.WithContext(ctx).
+		Model(&domain.MarketingSyncState{}).
+		Where("provider = ? AND claim_id = ?", provider, claimID).
+		Updates(map[string]interface{}{"claim_id": nil, "next_attempt_at": until}).Error
+}
+
+// Forget drops claimed customers that are no longer synced
+func (r *MarketingSyncRepository) Forget(ctx context.Context, provider string, claimID uuid.UUID, customerIDs []uuid.UUID) error {
+	return r.db.WithContext(ctx).
+		Where("provider = ? AND claim_id = ? AND customer_id IN ?", provider, claimID, customerIDs).
+		Delete(&domain.MarketingSyncState{}).Error
+}
+
+// Status summarizes the sync to every marketing platform
+func (r *MarketingSyncRepository) Status(ctx context.Context) ([]domain.MarketingSyncStatus, error) {
+	db := r.db.WithContext(ctx)
+	statuses := make([]domain.MarketingSyncStatus, 0, len(r.providers))
+	for _, provider := range r.providers {
+		status := domain.MarketingSyncStatus{Provider: provider}
+
+		var counts []struct {
+			Status string
+			Count  int64
+		}
+		if err := db.Model(&domain.MarketingSyncState{}).
+			Select("status, COUNT(*) AS count").
+			Where("provider = ?", provider).
+			Group("status").
+			Scan(&counts).Error; err != nil {
+			return nil, err
+		}
+		for _, c := range counts {
+			switch c.Status {
+			case domain.MarketingSyncPending:
+				status.Pending = c.Count
+			case domain.MarketingSyncSynced:
+				status.Synced = c.Count
+			case domain.MarketingSyncFailed:
+				status.Failed = c.Count
+			}
+		}
+
+		var last domain.MarketingSyncState
+		err := db.Where("provider = ? AND synced_at IS NOT NULL", provider).Order("synced_at DESC").First(&last).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+		status.LastSyncedAt = last.SyncedAt
+
+		var next domain.MarketingSyncState
+		err = db.Where("provider = ? AND status = ?", provider, domain.MarketingSyncPending).Order("next_attempt_at ASC").First(&next).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+		if err == nil {
+			status.NextAttemptAt = &next.NextAttemptAt
+		}
+
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// States returns a page of the customers synced to provider, most recently
+// changed first, optionally only those in status
+func (r *MarketingSyncRepository) States(ctx context.Context, provider, status string, page, limit int) ([]domain.MarketingSyncState, int64, error) {
+	var states []domain.MarketingSyncState
+	var total int64
+
+	query := r.db.WithContext(ctx).Model(&domain.MarketingSyncState{}).Where("provider = ?", provider)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err := query.Order("changed_at DESC").
+		Offset((page - 1) * limit).
+		Limit(limit).
+		Find(&states).Error
+	return states, total, err
+}
+
+// Resync queues customers to be pushed to provider again, or every failed
+// customer when customerIDs is empty. It returns how many were queued;
+// customers without consent are not.
+func (r *MarketingSyncRepository) Resync(ctx context.Context, provider string, customerIDs []uuid.UUID) (int64, error) {
+	db := r.db.WithContext(ctx)
+	now := time.Now()
+	if len(customerIDs) > 0 {
+		var consented int64
+		if err := db.Model(&domain.MarketingConsent{}).Where("customer_id IN ?", customerIDs).Count(&consented).Error; err != nil {
+			return 0, err
+		}
+		return consented, queueMarketingSync(db, []string{provider}, customerIDs, now)
+	}
+
+	result := db.Model(&domain.MarketingSyncState{}).
+		Where("provider = ? AND status = ?", provider, domain.MarketingSyncFailed).
+		Updates(map[string]interface{}{
+			"status":          domain.MarketingSyncPending,
+			"attempts":        0,
+			"next_attempt_at": now,
+		})
+	return result.RowsAffected, result.Error
+}
+
+// queueMarketingSync marks customers pending for every provider, skipping
+// those without consent. Customers being pushed lose their claim, so the push
+// in flight doesn't mark the newer change synced. Passing a transaction
+// queues them only if the change is committed.
+func queueMarketingSync(tx *gorm.DB, providers []string, customerIDs []uuid.UUID, now time.Time) error {
+	if len(providers) == 0 || len(customerIDs) == 0 {
+		return nil
+	}
+	var consented []uuid.UUID
+	if err := tx.Model(&domain.MarketingConsent{}).
+		Where("customer_id IN ?", customerIDs).
+		Pluck("customer_id", &consented).Error; err != nil {
+		return err
+	}
+	if len(consented) == 0 {
+		return nil
+	}
+
+	states := make([]domain.MarketingSyncState, 0, len(providers)*len(consented))
+	for _, provider := range providers {
+		for _, customerID := range consented {
+			states = append(states, domain.MarketingSyncState{
+				Provider:      provider,
+				CustomerID:    customerID,
+				Status:        domain.MarketingSyncPending,
+				NextAttemptAt: now,
+				ChangedAt:     now,
+			})
+		}
+	}
+	return tx.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "provider"}, {Name: "customer_id"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"status":          domain.MarketingSyncPending,
+			"attempts":        0,
+			"last_error":      "",
+			"next_attempt_at": now,
+			"changed_at":      now,
+			"claim_id":        nil,
+			"updated_at":      now,
+		}),
+	}).Create(&states).Error
+}
+
+// marketingSegmentNames returns the names of the marketing segments each
+// customer is in, sorted
+func marketingSegmentNames(db *gorm.DB, customerIDs []uuid.UUID) (map[uuid.UUID][]string, error) {
+	var assignments []domain.CustomerSegmentAssignment
+	if err := db.Where("customer_id IN ?", customerIDs).Find(&assignments).Error; err != nil {
+		return nil, err
+	}
+	if len(assignments) == 0 {
+		return nil, nil
+	}
+
+	segmentIDs := make([]uuid.UUID, 0, len(assignments))
+	for _, a := range assignments {
+		segmentIDs = append(segmentIDs, a.SegmentID)
+	}
+	var segments []domain.CustomerSegment
+	if err := db.Where("id IN ? AND is_marketing = ?", segmentIDs, true).Find(&segments).Error; err != nil {
+		return nil, err
+	}
+	names := make(map[uuid.UUID]string, len(segments))
+	for _, s := range segments {
+		names[s.ID] = s.Name
+	}
+
+	byCustomer := make(map[uuid.UUID][]string)
+	for _, a := range assignments {
+		if name, ok := names[a.SegmentID]; ok {
+			byCustomer[a.CustomerID] = append(byCustomer[a.CustomerID], name)
+		}
+	}
+	for _, segmentNames := range byCustomer {
+		sort.Strings(segmentNames)
+	}
+	return byCustomer, nil
+}
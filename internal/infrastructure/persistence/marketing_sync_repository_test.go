@@ -0,0 +1,135 @@
+package persistence
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestMarketingSyncRepository_QueuesConsentedCustomers(t *testing.T) {
+	db := openTestDB(t, &domain.Customer{}, &domain.CustomerSegment{}, &domain.CustomerSegmentAssignment{},
+		&domain.SegmentMembershipEvent{}, &domain.MarketingConsent{}, &domain.MarketingSyncState{})
+	sync := NewMarketingSyncRepository(db, []string{"mailchimp", "klaviyo"})
+	repo := NewMarketingCustomerRepository(NewCustomerRepository(db, DefaultQueryTimeouts()), sync, zap.NewNop())
+	ctx := context.Background()
+
+	optedIn, err := repo.Create(ctx, &domain.CreateCustomerRequest{Email: "aisyah@example.com", FirstName: "Aisyah", LastName: "Rahman"}, nil)
+	require.NoError(t, err)
+	never, err := repo.Create(ctx, &domain.CreateCustomerRequest{Email: "farid@example.com", FirstName: "Farid", LastName: "Hassan"}, nil)
+	require.NoError(t, err)
+
+	yes := true
+	consent, err := sync.UpdateConsent(ctx, optedIn.ID, &domain.UpdateMarketingConsentRequest{EmailOptIn: &yes})
+	require.NoError(t, err)
+	assert.True(t, consent.EmailOptIn)
+	assert.False(t, consent.SMSOptIn)
+
+	vip := &domain.CustomerSegment{Name: "VIP", IsMarketing: true}
+	staff := &domain.CustomerSegment{Name: "Staff"}
+	require.NoError(t, db.Create(vip).Error)
+	require.NoError(t, db.Create(staff).Error)
+	require.NoError(t, repo.AssignSegments(ctx, optedIn.ID, []uuid.UUID{vip.ID, staff.ID}))
+	require.NoError(t, repo.AssignSegments(ctx, never.ID, []uuid.UUID{vip.ID}))
+
+	var states []domain.MarketingSyncState
+	require.NoError(t, db.Order("provider").Find(&states).Error)
+	require.Len(t, states, 2, "customers without consent are not queued")
+	for _, state := range states {
+		assert.Equal(t, optedIn.ID, state.CustomerID)
+		assert.Equal(t, domain.MarketingSyncPending, state.Status)
+	}
+
+	claimed, err := sync.Claim(ctx, "mailchimp", time.Now(), 10, time.Minute)
+	require.NoError(t, err)
+	require.Len(t, claimed, 1)
+	contacts, skipped, err := sync.Contacts(ctx, claimed)
+	require.NoError(t, err)
+	assert.Empty(t, skipped)
+	require.Len(t, contacts, 1)
+	assert.Equal(t, "aisyah@example.com", contacts[0].Email)
+	assert.True(t, contacts[0].EmailOptIn)
+	assert.Equal(t, []string{"VIP"}, contacts[0].Segments, "only marketing segments are synced")
+
+	fields, err := domain.ParseMarketingFieldMap("FNAME=first_name, SEGMENTS=segments")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"FNAME": "Aisyah", "SEGMENTS": []string{"VIP"}}, fields.Values(contacts[0]))
+	_, err = domain.ParseMarketingFieldMap("FNAME=nickname")
+	assert.Error(t, err)
+}
+
+func TestMarketingSyncRepository_ClaimAndRecord(t *testing.T) {
+	db := openTestDB(t, &domain.Customer{}, &domain.CustomerSegment{}, &domain.CustomerSegmentAssignment{},
+		&domain.SegmentMembershipEvent{}, &domain.MarketingConsent{}, &domain.MarketingSyncState{})
+	sync := NewMarketingSyncRepository(db, []string{"mailchimp"})
+	customers := NewCustomerRepository(db, DefaultQueryTimeouts())
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	yes := true
+	var ids []uuid.UUID
+	for _, email := range []string{"a@example.com", "b@example.com", "c@example.com"} {
+		customer, err := customers.Create(ctx, &domain.CreateCustomerRequest{Email: email, FirstName: "A", LastName: "B"}, nil)
+		require.NoError(t, err)
+		_, err = sync.UpdateConsent(ctx, customer.ID, &domain.UpdateMarketingConsentRequest{EmailOptIn: &yes})
+		require.NoError(t, err)
+		ids = append(ids, customer.ID)
+	}
+
+	claimed, err := sync.Claim(ctx, "mailchimp", now.Add(time.Second), 10, time.Minute)
+	require.NoError(t, err)
+	require.Len(t, claimed, 3)
+	claimID := *claimed[0].ClaimID
+	again, err := sync.Claim(ctx, "mailchimp", now.Add(time.Second), 10, time.Minute)
+	require.NoError(t, err)
+	assert.Empty(t, again, "claimed customers are left to the claimer")
+
+	// The first customer changes while the batch is pushed
+	require.NoError(t, sync.MarkChanged(ctx, ids[0]))
+
+	retry := now.Add(time.Minute)
+	require.NoError(t, sync.Record(ctx, "mailchimp", claimID, []domain.MarketingSyncOutcome{
+		{CustomerID: ids[0]},
+		{CustomerID: ids[1], Error: "Invalid Resource", NextAttemptAt: &retry},
+		{CustomerID: ids[2], Error: "Member In Compliance State"},
+	}, now))
+
+	status, err := sync.Status(ctx)
+	require.NoError(t, err)
+	require.Len(t, status, 1)
+	assert.Equal(t, int64(2), status[0].Pending, "the changed customer is pushed again")
+	assert.Equal(t, int64(0), status[0].Synced)
+	assert.Equal(t, int64(1), status[0].Failed)
+
+	failed, total, err := sync.States(ctx, "mailchimp", domain.MarketingSyncFailed, 1, 10)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+	assert.Equal(t, "Member In Compliance State", failed[0].LastError)
+
+	queued, err := sync.Resync(ctx, "mailchimp", nil)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), queued)
+
+	// A rate-limited batch is handed back without counting an attempt
+	claimed, err = sync.Claim(ctx, "mailchimp", now.Add(2*time.Minute), 10, time.Minute)
+	require.NoError(t, err)
+	require.Len(t, claimed, 3)
+	require.NoError(t, sync.Release(ctx, "mailchimp", *claimed[0].ClaimID, now.Add(time.Hour)))
+	claimed, err = sync.Claim(ctx, "mailchimp", now.Add(time.Hour), 10, time.Minute)
+	require.NoError(t, err)
+	require.Len(t, claimed, 3)
+	require.NoError(t, sync.Record(ctx, "mailchimp", *claimed[0].ClaimID, []domain.MarketingSyncOutcome{
+		{CustomerID: ids[0]}, {CustomerID: ids[1]}, {CustomerID: ids[2]},
+	}, now.Add(time.Hour)))
+
+	status, err = sync.Status(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), status[0].Synced)
+	require.NotNil(t, status[0].LastSyncedAt)
+	assert.Nil(t, status[0].NextAttemptAt)
+}
@@ -3,16 +3,20 @@ package persistence
 import (
 	"time"
 
+	"github.com/Ecom-micro-template/service-customer/internal/domain/shared"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
 // MeasurementModel is the GORM persistence model for CustomerMeasurement.
 type MeasurementModel struct {
-	ID     uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
-	UserID uuid.UUID `gorm:"type:uuid;not null;index" json:"user_id"`
-	Name   *string   `gorm:"type:varchar(100)" json:"name,omitempty"`
-	Gender string    `gorm:"type:varchar(20);not null" json:"gender"`
+	ID     uuid.UUID     `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	UserID uuid.UUID     `gorm:"type:uuid;not null;index" json:"user_id"`
+	Name   *string       `gorm:"type:varchar(100)" json:"name,omitempty"`
+	Gender shared.Gender `gorm:"type:varchar(20);not null" json:"gender"`
+
+	// Who the measurements belong to ("self" or a family member label)
+	ProfilePerson string `gorm:"type:varchar(50);not null;default:'self';index" json:"profile_person"`
 
 	// Upper body measurements (cm)
 	Bust          *float64 `gorm:"type:decimal(5,1)" json:"bust,omitempty"`
@@ -33,6 +37,12 @@ type MeasurementModel struct {
 	Height *float64 `gorm:"type:decimal(5,1)" json:"height,omitempty"`
 	Weight *float64 `gorm:"type:decimal(5,1)" json:"weight,omitempty"`
 
+	// Ready-to-wear size derived from the measurements (XS..3XL)
+	StandardSize *string `gorm:"type:varchar(10)" json:"standard_size,omitempty"`
+
+	// Unit the customer entered the measurements in; stored lengths are always cm
+	Unit string `gorm:"type:varchar(10);not null;default:'cm'" json:"unit"`
+
 	Notes     *string   `gorm:"type:text" json:"notes,omitempty"`
 	IsDefault bool      `gorm:"default:false" json:"is_default"`
 	CreatedAt time.Time `json:"created_at"`
@@ -2,6 +2,7 @@ package persistence
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/google/uuid"
 	"github.com/Ecom-micro-template/service-customer/internal/domain"
@@ -10,7 +11,8 @@ import (
 
 // MeasurementRepository handles database operations for customer measurements
 type MeasurementRepository struct {
-	db *gorm.DB
+	db     *gorm.DB
+	limits *CustomerLimitRepository
 }
 
 // NewMeasurementRepository creates a new measurement repository
@@ -18,9 +20,28 @@ func NewMeasurementRepository(db *gorm.DB) *MeasurementRepository {
 	return &MeasurementRepository{db: db}
 }
 
-// Create creates a new customer measurement
+// WithLimits caps how many measurements each customer can store
+func (r *MeasurementRepository) WithLimits(limits *CustomerLimitRepository) *MeasurementRepository {
+	r.limits = limits
+	return r
+}
+
+// Create creates a new customer measurement, unsetting the person's other
+// defaults when it is the default. Returns domain.ErrMeasurementLimit if the
+// user already stores as many measurements as their limit allows.
 func (r *MeasurementRepository) Create(ctx context.Context, measurement *domain.CustomerMeasurement) error {
-	return r.db.WithContext(ctx).Create(measurement).Error
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := checkCustomerLimit(tx, r.limits, measurement.UserID, &domain.CustomerMeasurement{}, func(l domain.CustomerLimits) int { return l.Measurements }, domain.ErrMeasurementLimit); err != nil {
+			return err
+		}
+		if err := tx.Create(measurement).Error; err != nil {
+			return err
+		}
+		if !measurement.IsDefault {
+			return nil
+		}
+		return clearDefaultMeasurements(tx, measurement)
+	})
 }
 
 // GetByID retrieves a measurement by ID with user ownership check (IDOR protection)
@@ -35,21 +56,25 @@ func (r *MeasurementRepository) GetByID(ctx context.Context, id, userID uuid.UUI
 	return &measurement, nil
 }
 
-// GetByUserID retrieves all measurements for a user
-func (r *MeasurementRepository) GetByUserID(ctx context.Context, userID uuid.UUID) ([]domain.CustomerMeasurement, error) {
+// GetByUserID retrieves all measurements for a user, optionally limited to one
+// profile person (empty returns every person)
+func (r *MeasurementRepository) GetByUserID(ctx context.Context, userID uuid.UUID, person string) ([]domain.CustomerMeasurement, error) {
 	var measurements []domain.CustomerMeasurement
-	err := r.db.WithContext(ctx).
-		Where("user_id = ?", userID).
+	query := r.db.WithContext(ctx).Where("user_id = ?", userID)
+	if person != "" {
+		query = query.Where("profile_person = ?", person)
+	}
+	err := query.
 		Order("is_default DESC, created_at DESC").
 		Find(&measurements).Error
 	return measurements, err
 }
 
-// GetDefaultByUserID retrieves the default measurement for a user
-func (r *MeasurementRepository) GetDefaultByUserID(ctx context.Context, userID uuid.UUID) (*domain.CustomerMeasurement, error) {
+// GetDefaultByUserID retrieves the default measurement for one profile person of a user
+func (r *MeasurementRepository) GetDefaultByUserID(ctx context.Context, userID uuid.UUID, person string) (*domain.CustomerMeasurement, error) {
 	var measurement domain.CustomerMeasurement
 	err := r.db.WithContext(ctx).
-		Where("user_id = ? AND is_default = ?", userID, true).
+		Where("user_id = ? AND profile_person = ? AND is_default = ?", userID, person, true).
 		First(&measurement).Error
 	if err != nil {
 		return nil, err
@@ -57,9 +82,56 @@ func (r *MeasurementRepository) GetDefaultByUserID(ctx context.Context, userID u
 	return &measurement, nil
 }
 
-// Update updates a measurement
+// ListProfiles returns the distinct profile persons a user has measurements for
+func (r *MeasurementRepository) ListProfiles(ctx context.Context, userID uuid.UUID) ([]string, error) {
+	var people []string
+	err := r.db.WithContext(ctx).Model(&domain.CustomerMeasurement{}).
+		Distinct("profile_person").
+		Where("user_id = ?", userID).
+		Order("profile_person").
+		Pluck("profile_person", &people).Error
+	return people, err
+}
+
+// CheckProfileLimit returns domain.ErrMeasurementProfileLimit if storing a
+// measurement for person would add a profile beyond domain.MaxMeasurementProfiles
+func (r *MeasurementRepository) CheckProfileLimit(ctx context.Context, userID uuid.UUID, person string) error {
+	people, err := r.ListProfiles(ctx, userID)
+	if err != nil {
+		return err
+	}
+	for _, existing := range people {
+		if existing == person {
+			return nil
+		}
+	}
+	if len(people) >= domain.MaxMeasurementProfiles {
+		return domain.ErrMeasurementProfileLimit
+	}
+	return nil
+}
+
+// Update updates a measurement if it is still at measurement.Version, and
+// unsets the person's other defaults when it is the default. Returns
+// domain.ErrVersionConflict if it changed meanwhile.
 func (r *MeasurementRepository) Update(ctx context.Context, measurement *domain.CustomerMeasurement) error {
-	return r.db.WithContext(ctx).Save(measurement).Error
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := updateVersioned(tx, measurement, &measurement.Version); err != nil {
+			return err
+		}
+		if !measurement.IsDefault {
+			return nil
+		}
+		return clearDefaultMeasurements(tx, measurement)
+	})
+}
+
+// clearDefaultMeasurements unsets the defaults of the measurement's person
+// other than the measurement itself
+func clearDefaultMeasurements(tx *gorm.DB, measurement *domain.CustomerMeasurement) error {
+	return tx.Model(&domain.CustomerMeasurement{}).
+		Where("user_id = ? AND profile_person = ? AND id != ? AND is_default = ?", measurement.UserID, measurement.ProfilePerson, measurement.ID, true).
+		Updates(map[string]interface{}{"is_default": false, "version": bumpVersion}).Error
 }
 
 // Delete deletes a measurement with user ownership check (IDOR protection)
@@ -76,19 +148,46 @@ func (r *MeasurementRepository) Delete(ctx context.Context, id, userID uuid.UUID
 	return nil
 }
 
-// SetDefault sets a measurement as default and unsets others for the user
+// SetDefault sets a measurement as default and unsets the others for the
+// same profile person of the user
 func (r *MeasurementRepository) SetDefault(ctx context.Context, userID, measurementID uuid.UUID) error {
 	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
-		// Unset all default measurements for this user
+		var measurement domain.CustomerMeasurement
+		if err := tx.Where("id = ? AND user_id = ?", measurementID, userID).First(&measurement).Error; err != nil {
+			return err
+		}
+
+		// Unset the person's other default measurements
 		if err := tx.Model(&domain.CustomerMeasurement{}).
-			Where("user_id = ?", userID).
-			Update("is_default", false).Error; err != nil {
+			Where("user_id = ? AND profile_person = ? AND is_default = ?", userID, measurement.ProfilePerson, true).
+			Updates(map[string]interface{}{"is_default": false, "version": bumpVersion}).Error; err != nil {
 			return err
 		}
 
 		// Set the new default
 		return tx.Model(&domain.CustomerMeasurement{}).
 			Where("id = ? AND user_id = ?", measurementID, userID).
-			Update("is_default", true).Error
+			Updates(map[string]interface{}{"is_default": true, "version": bumpVersion}).Error
 	})
 }
+
+// RecordSizeChange logs a customer activity when a measurement update changes the derived standard size
+func (r *MeasurementRepository) RecordSizeChange(ctx context.Context, measurement *domain.CustomerMeasurement, previousSize string) error {
+	newSize := "none"
+	if measurement.StandardSize != nil {
+		newSize = *measurement.StandardSize
+	}
+
+	name := "measurement"
+	if measurement.Name != nil && *measurement.Name != "" {
+		name = *measurement.Name
+	}
+
+	activity := &domain.CustomerActivity{
+		CustomerID: measurement.UserID,
+		Type:       "measurement_size_changed",
+		Title:      "Standard size changed",
+		Details:    fmt.Sprintf("%s: %s -> %s", name, previousSize, newSize),
+	}
+	return r.db.WithContext(ctx).Create(activity).Error
+}
@@ -0,0 +1,130 @@
+package persistence
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func setupMeasurementTestDB(t *testing.T) *gorm.DB {
+	return openTestDB(t, &domain.CustomerMeasurement{})
+}
+
+func TestMeasurementRepository_DefaultPerProfilePerson(t *testing.T) {
+	db := setupMeasurementTestDB(t)
+	repo := NewMeasurementRepository(db)
+	ctx := context.Background()
+
+	userID := uuid.New()
+	own := &domain.CustomerMeasurement{UserID: userID, Gender: "men", IsDefault: true}
+	daughter := &domain.CustomerMeasurement{UserID: userID, Gender: "women", ProfilePerson: "Aisyah"}
+	daughterOld := &domain.CustomerMeasurement{UserID: userID, Gender: "women", ProfilePerson: "Aisyah", IsDefault: true}
+	for _, m := range []*domain.CustomerMeasurement{own, daughter, daughterOld} {
+		require.NoError(t, repo.Create(ctx, m))
+	}
+	assert.Equal(t, domain.MeasurementProfileSelf, own.ProfilePerson)
+
+	require.NoError(t, repo.SetDefault(ctx, userID, daughter.ID))
+
+	// The account holder's default is untouched
+	ownDefault, err := repo.GetDefaultByUserID(ctx, userID, domain.MeasurementProfileSelf)
+	require.NoError(t, err)
+	assert.Equal(t, own.ID, ownDefault.ID)
+
+	daughterDefault, err := repo.GetDefaultByUserID(ctx, userID, "Aisyah")
+	require.NoError(t, err)
+	assert.Equal(t, daughter.ID, daughterDefault.ID)
+
+	filtered, err := repo.GetByUserID(ctx, userID, "Aisyah")
+	require.NoError(t, err)
+	assert.Len(t, filtered, 2)
+
+	people, err := repo.ListProfiles(ctx, userID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Aisyah", domain.MeasurementProfileSelf}, people)
+
+	assert.ErrorIs(t, repo.SetDefault(ctx, uuid.New(), daughter.ID), gorm.ErrRecordNotFound)
+}
+
+func TestMeasurementRepository_CheckProfileLimit(t *testing.T) {
+	db := setupMeasurementTestDB(t)
+	repo := NewMeasurementRepository(db)
+	ctx := context.Background()
+
+	userID := uuid.New()
+	for i := 0; i < domain.MaxMeasurementProfiles; i++ {
+		require.NoError(t, repo.Create(ctx, &domain.CustomerMeasurement{
+			UserID:        userID,
+			Gender:        "women",
+			ProfilePerson: fmt.Sprintf("Child %d", i),
+		}))
+	}
+
+	// Existing people can still get more measurements
+	assert.NoError(t, repo.CheckProfileLimit(ctx, userID, "Child 0"))
+	assert.ErrorIs(t, repo.CheckProfileLimit(ctx, userID, "Grandma"), domain.ErrMeasurementProfileLimit)
+
+	// The cap is per account
+	assert.NoError(t, repo.CheckProfileLimit(ctx, uuid.New(), "Grandma"))
+}
+
+func TestMeasurementRepository_StoresLengthsInCentimetres(t *testing.T) {
+	db := setupMeasurementTestDB(t)
+	repo := NewMeasurementRepository(db)
+	ctx := context.Background()
+
+	waist, weight := 30.0, 70.0
+	measurement := &domain.CustomerMeasurement{UserID: uuid.New(), Gender: "men", Waist: &waist, Weight: &weight}
+	measurement.NormalizeLengths(domain.MeasurementUnitInch)
+	require.NoError(t, repo.Create(ctx, measurement))
+
+	stored, err := repo.GetByID(ctx, measurement.ID, measurement.UserID)
+	require.NoError(t, err)
+	assert.Equal(t, domain.MeasurementUnitInch, stored.Unit)
+	assert.InDelta(t, 76.2, *stored.Waist, 0.001)
+	assert.InDelta(t, 70.0, *stored.Weight, 0.001, "weight is not a length")
+
+	inches := stored.InUnit(domain.MeasurementUnitInch)
+	assert.InDelta(t, 30.0, *inches.Waist, 0.001)
+	assert.InDelta(t, 76.2, *stored.Waist, 0.001, "converting a copy leaves the original in cm")
+	assert.Equal(t, domain.MeasurementUnitCM, stored.InUnit(domain.MeasurementUnitCM).Unit)
+}
+
+func TestMeasurementRepository_UpdateVersionConflict(t *testing.T) {
+	db := setupMeasurementTestDB(t)
+	repo := NewMeasurementRepository(db)
+	ctx := context.Background()
+
+	userID := uuid.New()
+	first := &domain.CustomerMeasurement{UserID: userID, Gender: "women", IsDefault: true}
+	require.NoError(t, repo.Create(ctx, first))
+	second := &domain.CustomerMeasurement{UserID: userID, Gender: "women", IsDefault: true}
+	require.NoError(t, repo.Create(ctx, second))
+
+	stale, err := repo.GetByID(ctx, first.ID, userID)
+	require.NoError(t, err)
+	assert.False(t, stale.IsDefault)
+	assert.Equal(t, int64(2), stale.Version)
+
+	waist := 70.0
+	current := *stale
+	current.Waist = &waist
+	require.NoError(t, repo.Update(ctx, &current))
+	assert.Equal(t, int64(3), current.Version)
+
+	hip := 95.0
+	stale.Hip = &hip
+	assert.ErrorIs(t, repo.Update(ctx, stale), domain.ErrVersionConflict)
+
+	retrieved, err := repo.GetByID(ctx, first.ID, userID)
+	require.NoError(t, err)
+	require.NotNil(t, retrieved.Waist)
+	assert.Nil(t, retrieved.Hip)
+	assert.Equal(t, int64(3), retrieved.Version)
+}
@@ -0,0 +1,104 @@
+package persistence
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"gorm.io/gorm"
+)
+
+// NoteAttachmentRepository handles customer note attachment records. The
+// files themselves are kept in the file store.
+type NoteAttachmentRepository struct {
+	db *gorm.DB
+}
+
+// NewNoteAttachmentRepository creates a new note attachment repository
+func NewNoteAttachmentRepository(db *gorm.DB) *NoteAttachmentRepository {
+	return &NoteAttachmentRepository{db: db}
+}
+
+// Create records an attachment unless its note already has
+// domain.MaxNoteAttachments, in which case domain.ErrNoteAttachmentLimit is returned
+func (r *NoteAttachmentRepository) Create(ctx context.Context, attachment *domain.NoteAttachment) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&domain.NoteAttachment{}).
+			Where("note_id = ?", attachment.NoteID).
+			Count(&count).Error; err != nil {
+			return err
+		}
+		if count >= domain.MaxNoteAttachments {
+			return domain.ErrNoteAttachmentLimit
+		}
+		return tx.Create(attachment).Error
+	})
+}
+
+// CountByNote returns how many attachments a note has
+func (r *NoteAttachmentRepository) CountByNote(ctx context.Context, noteID uuid.UUID) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&domain.NoteAttachment{}).
+		Where("note_id = ?", noteID).
+		Count(&count).Error
+	return count, err
+}
+
+// Get retrieves an attachment of a note
+func (r *NoteAttachmentRepository) Get(ctx context.Context, noteID, id uuid.UUID) (*domain.NoteAttachment, error) {
+	var attachment domain.NoteAttachment
+	err := r.db.WithContext(ctx).
+		Where("id = ? AND note_id = ?", id, noteID).
+		First(&attachment).Error
+	if err != nil {
+		return nil, err
+	}
+	return &attachment, nil
+}
+
+// ListByNotes returns the attachments of the given notes keyed by note ID,
+// oldest first
+func (r *NoteAttachmentRepository) ListByNotes(ctx context.Context, noteIDs []uuid.UUID) (map[uuid.UUID][]domain.NoteAttachment, error) {
+	byNote := make(map[uuid.UUID][]domain.NoteAttachment)
+	if len(noteIDs) == 0 {
+		return byNote, nil
+	}
+
+	var attachments []domain.NoteAttachment
+	if err := r.db.WithContext(ctx).
+		Where("note_id IN ?", noteIDs).
+		Order("created_at ASC").
+		Find(&attachments).Error; err != nil {
+		return nil, err
+	}
+	for _, attachment := range attachments {
+		byNote[attachment.NoteID] = append(byNote[attachment.NoteID], attachment)
+	}
+	return byNote, nil
+}
+
+// Delete removes an attachment record
+func (r *NoteAttachmentRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	result := r.db.WithContext(ctx).Delete(&domain.NoteAttachment{}, "id = ?", id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// DeleteByNote removes the attachment records of a note and returns them so
+// their files can be removed from the file store
+func (r *NoteAttachmentRepository) DeleteByNote(ctx context.Context, noteID uuid.UUID) ([]domain.NoteAttachment, error) {
+	var attachments []domain.NoteAttachment
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("note_id = ?", noteID).Find(&attachments).Error; err != nil {
+			return err
+		}
+		return tx.Where("note_id = ?", noteID).Delete(&domain.NoteAttachment{}).Error
+	})
+	return attachments, err
+}
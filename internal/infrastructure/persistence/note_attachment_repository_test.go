@@ -0,0 +1,76 @@
+package persistence
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNoteAttachmentRepository(t *testing.T) {
+	db := openTestDB(t, &domain.NoteAttachment{})
+	repo := NewNoteAttachmentRepository(db)
+	ctx := context.Background()
+
+	customerID, noteID, otherNoteID := uuid.New(), uuid.New(), uuid.New()
+	newAttachment := func(noteID uuid.UUID) *domain.NoteAttachment {
+		a := &domain.NoteAttachment{
+			ID: uuid.New(), NoteID: noteID, CustomerID: customerID,
+			FileName: "invoice.pdf", ContentType: "application/pdf", Size: 1024,
+		}
+		a.StorageKey = domain.NoteAttachmentKey(a)
+		return a
+	}
+
+	for i := 0; i < domain.MaxNoteAttachments; i++ {
+		require.NoError(t, repo.Create(ctx, newAttachment(noteID)))
+	}
+	assert.ErrorIs(t, repo.Create(ctx, newAttachment(noteID)), domain.ErrNoteAttachmentLimit)
+	other := newAttachment(otherNoteID)
+	require.NoError(t, repo.Create(ctx, other))
+
+	byNote, err := repo.ListByNotes(ctx, []uuid.UUID{noteID, otherNoteID})
+	require.NoError(t, err)
+	assert.Len(t, byNote[noteID], domain.MaxNoteAttachments)
+	assert.Len(t, byNote[otherNoteID], 1)
+
+	_, err = repo.Get(ctx, noteID, other.ID)
+	assert.Error(t, err, "attachment must belong to the note")
+	require.NoError(t, repo.Delete(ctx, other.ID))
+
+	deleted, err := repo.DeleteByNote(ctx, noteID)
+	require.NoError(t, err)
+	assert.Len(t, deleted, domain.MaxNoteAttachments)
+	count, err := repo.CountByNote(ctx, noteID)
+	require.NoError(t, err)
+	assert.Zero(t, count)
+}
+
+func TestNoteAttachmentPolicy_Check(t *testing.T) {
+	policy := domain.NoteAttachmentPolicy{MaxSize: 1 << 20}
+	assert.NoError(t, policy.Check(1024, "image/png"))
+	assert.NoError(t, policy.Check(1024, "application/pdf"))
+	assert.ErrorIs(t, policy.Check(2<<20, "image/png"), domain.ErrNoteAttachmentTooLarge)
+	assert.ErrorIs(t, policy.Check(0, "image/png"), domain.ErrNoteAttachmentTooLarge)
+	assert.ErrorIs(t, policy.Check(1024, "text/html; charset=utf-8"), domain.ErrNoteAttachmentType)
+
+	policy.AllowedTypes = []string{"text/plain"}
+	assert.NoError(t, policy.Check(1024, "text/plain; charset=utf-8"))
+	assert.ErrorIs(t, policy.Check(1024, "image/png"), domain.ErrNoteAttachmentType)
+}
+
+func TestNoteAttachmentNames(t *testing.T) {
+	assert.Equal(t, "invoice.pdf", domain.CleanAttachmentName(`C:\Users\agent\invoice.pdf`))
+	assert.Equal(t, "shot.png", domain.CleanAttachmentName("../../shot.png"))
+	assert.Equal(t, "attachment", domain.CleanAttachmentName(""))
+	assert.Len(t, []rune(domain.CleanAttachmentName(strings.Repeat("é", 300))), 255)
+
+	a := &domain.NoteAttachment{ID: uuid.New(), NoteID: uuid.New(), CustomerID: uuid.New(), FileName: "Screen Shot.PNG"}
+	assert.True(t, strings.HasSuffix(domain.NoteAttachmentKey(a), a.ID.String()+".png"))
+	a.FileName = "notes.tar.gz;rm -rf"
+	assert.True(t, strings.HasSuffix(domain.NoteAttachmentKey(a), a.ID.String()))
+}
@@ -0,0 +1,72 @@
+package persistence
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"gorm.io/gorm"
+)
+
+// NotificationPreferenceRepository handles customers' notification schedules
+// and the deliveries counted against their weekly cap
+type NotificationPreferenceRepository struct {
+	db *gorm.DB
+}
+
+// NewNotificationPreferenceRepository creates a new notification preference repository
+func NewNotificationPreferenceRepository(db *gorm.DB) *NotificationPreferenceRepository {
+	return &NotificationPreferenceRepository{db: db}
+}
+
+// Get returns a customer's notification preferences, or the defaults if they
+// never changed them
+func (r *NotificationPreferenceRepository) Get(ctx context.Context, customerID uuid.UUID) (*domain.NotificationPreferences, error) {
+	var prefs domain.NotificationPreferences
+	err := r.db.WithContext(ctx).Where("customer_id = ?", customerID).First(&prefs).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return domain.DefaultNotificationPreferences(customerID), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if prefs.OptedOut == nil {
+		prefs.OptedOut = []string{}
+	}
+	return &prefs, nil
+}
+
+// Save creates or replaces a customer's notification preferences
+func (r *NotificationPreferenceRepository) Save(ctx context.Context, prefs *domain.NotificationPreferences) error {
+	return r.db.WithContext(ctx).Save(prefs).Error
+}
+
+// CountSentSince counts the notifications a customer was sent since the
+// given time
+func (r *NotificationPreferenceRepository) CountSentSince(ctx context.Context, customerID uuid.UUID, since time.Time) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).
+		Model(&domain.NotificationDelivery{}).
+		Where("customer_id = ? AND sent_at >= ?", customerID, since).
+		Count(&count).Error
+	return count, err
+}
+
+// RecordSent records a notification sent to a customer and prunes their
+// deliveries that no longer count toward the weekly cap
+func (r *NotificationPreferenceRepository) RecordSent(ctx context.Context, customerID uuid.UUID, notificationType, channel string, now time.Time) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("customer_id = ? AND sent_at < ?", customerID, now.Add(-domain.NotificationWeek)).
+			Delete(&domain.NotificationDelivery{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&domain.NotificationDelivery{
+			CustomerID: customerID,
+			Type:       notificationType,
+			Channel:    channel,
+			SentAt:     now,
+		}).Error
+	})
+}
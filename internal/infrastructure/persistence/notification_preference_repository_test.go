@@ -0,0 +1,74 @@
+package persistence
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNotificationPreferenceRepository(t *testing.T) {
+	db := openTestDB(t, &domain.NotificationPreferences{})
+	repo := NewNotificationPreferenceRepository(db)
+	ctx := context.Background()
+	customerID := uuid.New()
+
+	prefs, err := repo.Get(ctx, customerID)
+	require.NoError(t, err)
+	assert.Equal(t, domain.NotificationChannelEmail, prefs.Channel, "customers without preferences get the defaults")
+	assert.Equal(t, domain.DefaultNotificationTimezone, prefs.Timezone)
+	assert.Empty(t, prefs.OptedOut)
+
+	start, end, channel, weekly := "22:00", "08:00", "sms", 3
+	req := domain.UpdateNotificationPreferencesRequest{
+		Channel:         &channel,
+		QuietHoursStart: &start,
+		QuietHoursEnd:   &end,
+		MaxPerWeek:      &weekly,
+		OptedOut:        []string{"review_reminder", "REVIEW_REMINDER"},
+	}
+	require.NoError(t, req.Apply(prefs))
+	require.NoError(t, repo.Save(ctx, prefs))
+
+	got, err := repo.Get(ctx, customerID)
+	require.NoError(t, err)
+	assert.Equal(t, "sms", got.Channel)
+	assert.Equal(t, "22:00", got.QuietHoursStart)
+	assert.Equal(t, 3, got.MaxPerWeek)
+	assert.Equal(t, []string{domain.NotificationTypeReviewReminder}, got.OptedOut)
+
+	// Omitted fields are kept
+	weekly = 0
+	require.NoError(t, (&domain.UpdateNotificationPreferencesRequest{MaxPerWeek: &weekly}).Apply(got))
+	require.NoError(t, repo.Save(ctx, got))
+	got, err = repo.Get(ctx, customerID)
+	require.NoError(t, err)
+	assert.Equal(t, 0, got.MaxPerWeek)
+	assert.Equal(t, "sms", got.Channel)
+	assert.Equal(t, []string{domain.NotificationTypeReviewReminder}, got.OptedOut)
+}
+
+func TestNotificationPreferenceRepository_RecordSent(t *testing.T) {
+	db := openTestDB(t, &domain.NotificationDelivery{})
+	repo := NewNotificationPreferenceRepository(db)
+	ctx := context.Background()
+	customerID, other := uuid.New(), uuid.New()
+	now := time.Now()
+
+	require.NoError(t, repo.RecordSent(ctx, customerID, domain.NotificationTypeBackInStock, "email", now.Add(-10*24*time.Hour)))
+	require.NoError(t, repo.RecordSent(ctx, customerID, domain.NotificationTypeSavedSearch, "email", now.Add(-2*24*time.Hour)))
+	require.NoError(t, repo.RecordSent(ctx, other, domain.NotificationTypeSavedSearch, "email", now.Add(-time.Hour)))
+
+	count, err := repo.CountSentSince(ctx, customerID, now.Add(-domain.NotificationWeek))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count, "only the last week counts")
+
+	require.NoError(t, repo.RecordSent(ctx, customerID, domain.NotificationTypeBackInStock, "email", now))
+	var stored int64
+	require.NoError(t, db.Model(&domain.NotificationDelivery{}).Where("customer_id = ?", customerID).Count(&stored).Error)
+	assert.Equal(t, int64(2), stored, "deliveries older than a week are pruned")
+}
@@ -0,0 +1,130 @@
+package persistence
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// NotificationRetryRepository persists failed back-in-stock notification
+// sends until the retry job delivers them or gives up
+type NotificationRetryRepository struct {
+	db *gorm.DB
+}
+
+// NewNotificationRetryRepository creates a new notification retry repository
+func NewNotificationRetryRepository(db *gorm.DB) *NotificationRetryRepository {
+	return &NotificationRetryRepository{db: db}
+}
+
+// Enqueue queues a failed send. A subscription is queued at most once; if it
+// is already pending the existing retry and its schedule are kept. A retry
+// that failed for good is replaced, so a later send that fails is retried
+// rather than dropped.
+func (r *NotificationRetryRepository) Enqueue(ctx context.Context, retry *domain.NotificationRetry) error {
+	if retry.Status == "" {
+		retry.Status = domain.NotificationRetryPending
+	}
+	return r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "subscription_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{
+				"is_guest", "stock_quantity", "status", "attempts", "last_error", "next_attempt_at", "updated_at",
+			}),
+			Where: clause.Where{Exprs: []clause.Expression{clause.Eq{
+				Column: clause.Column{Table: clause.CurrentTable, Name: "status"},
+				Value:  domain.NotificationRetryFailed,
+			}}},
+		}).
+		Create(retry).Error
+}
+
+// Queued returns which of the subscriptions have a pending retry
+func (r *NotificationRetryRepository) Queued(ctx context.Context, subscriptionIDs []uuid.UUID) (map[uuid.UUID]bool, error) {
+	queued := make(map[uuid.UUID]bool)
+	if len(subscriptionIDs) == 0 {
+		return queued, nil
+	}
+	var ids []uuid.UUID
+	err := r.db.WithContext(ctx).
+		Model(&domain.NotificationRetry{}).
+		Where("status = ? AND subscription_id IN ?", domain.NotificationRetryPending, subscriptionIDs).
+		Pluck("subscription_id", &ids).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range ids {
+		queued[id] = true
+	}
+	return queued, nil
+}
+
+// Due returns pending retries whose next attempt is at or before now, oldest first
+func (r *NotificationRetryRepository) Due(ctx context.Context, now time.Time, limit int) ([]domain.NotificationRetry, error) {
+	var retries []domain.NotificationRetry
+	err := r.db.WithContext(ctx).
+		Where("status = ? AND next_attempt_at <= ?", domain.NotificationRetryPending, now).
+		Order("next_attempt_at ASC").
+		Limit(limit).
+		Find(&retries).Error
+	return retries, err
+}
+
+// RecordFailure counts a failed attempt and schedules the next one, or marks
+// the retry failed when next is nil
+func (r *NotificationRetryRepository) RecordFailure(ctx context.Context, id uuid.UUID, lastError string, next *time.Time) error {
+	updates := map[string]interface{}{
+		"attempts":   gorm.Expr("attempts + 1"),
+		"last_error": lastError,
+	}
+	if next != nil {
+		updates["next_attempt_at"] = *next
+	} else {
+		updates["status"] = domain.NotificationRetryFailed
+	}
+
+	return r.db.WithContext(ctx).
+		Model(&domain.NotificationRetry{}).
+		Where("id = ?", id).
+		Updates(updates).Error
+}
+
+// Reschedule moves a retry's next attempt without counting a failure, e.g.
+// past the customer's quiet hours
+func (r *NotificationRetryRepository) Reschedule(ctx context.Context, id uuid.UUID, next time.Time) error {
+	return r.db.WithContext(ctx).
+		Model(&domain.NotificationRetry{}).
+		Where("id = ?", id).
+		Update("next_attempt_at", next).Error
+}
+
+// Delete removes a retry once it is sent or no longer needed
+func (r *NotificationRetryRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	return r.db.WithContext(ctx).Delete(&domain.NotificationRetry{}, "id = ?", id).Error
+}
+
+// CountByStatus returns the number of retries per status
+func (r *NotificationRetryRepository) CountByStatus(ctx context.Context) (map[string]int64, error) {
+	var rows []struct {
+		Status string
+		Count  int64
+	}
+	err := r.db.WithContext(ctx).
+		Model(&domain.NotificationRetry{}).
+		Select("status, COUNT(*) AS count").
+		Group("status").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		counts[row.Status] = row.Count
+	}
+	return counts, nil
+}
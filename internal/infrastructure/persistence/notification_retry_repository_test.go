@@ -0,0 +1,127 @@
+package persistence
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNotificationRetryRepository_EnqueueAndDue(t *testing.T) {
+	db := openTestDB(t, &domain.NotificationRetry{})
+	repo := NewNotificationRetryRepository(db)
+	ctx := context.Background()
+	now := time.Now()
+	subscriptionID := uuid.New()
+
+	first := &domain.NotificationRetry{SubscriptionID: subscriptionID, Attempts: 1, NextAttemptAt: now.Add(-time.Minute)}
+	require.NoError(t, repo.Enqueue(ctx, first))
+	assert.Equal(t, domain.NotificationRetryPending, first.Status)
+
+	// A subscription is queued once; the original schedule is kept
+	require.NoError(t, repo.Enqueue(ctx, &domain.NotificationRetry{SubscriptionID: subscriptionID, Attempts: 1, NextAttemptAt: now.Add(time.Hour)}))
+	require.NoError(t, repo.Enqueue(ctx, &domain.NotificationRetry{SubscriptionID: uuid.New(), IsGuest: true, Attempts: 1, NextAttemptAt: now.Add(time.Hour)}))
+
+	due, err := repo.Due(ctx, now, 10)
+	require.NoError(t, err)
+	require.Len(t, due, 1)
+	assert.Equal(t, subscriptionID, due[0].SubscriptionID)
+
+	require.NoError(t, repo.Delete(ctx, first.ID))
+	due, err = repo.Due(ctx, now, 10)
+	require.NoError(t, err)
+	assert.Empty(t, due)
+}
+
+func TestNotificationRetryRepository_RecordFailure(t *testing.T) {
+	db := openTestDB(t, &domain.NotificationRetry{})
+	repo := NewNotificationRetryRepository(db)
+	ctx := context.Background()
+	now := time.Now()
+
+	retry := &domain.NotificationRetry{SubscriptionID: uuid.New(), Attempts: 1, NextAttemptAt: now.Add(-time.Minute)}
+	require.NoError(t, repo.Enqueue(ctx, retry))
+
+	next := now.Add(10 * time.Minute)
+	require.NoError(t, repo.RecordFailure(ctx, retry.ID, "timeout", &next))
+
+	var stored domain.NotificationRetry
+	require.NoError(t, db.First(&stored, "id = ?", retry.ID).Error)
+	assert.Equal(t, 2, stored.Attempts)
+	assert.Equal(t, "timeout", stored.LastError)
+	assert.Equal(t, domain.NotificationRetryPending, stored.Status)
+
+	due, err := repo.Due(ctx, now, 10)
+	require.NoError(t, err)
+	assert.Empty(t, due)
+
+	// Out of attempts: the retry is kept as failed and no longer due
+	require.NoError(t, repo.RecordFailure(ctx, retry.ID, "503", nil))
+	due, err = repo.Due(ctx, now.Add(time.Hour), 10)
+	require.NoError(t, err)
+	assert.Empty(t, due)
+
+	counts, err := repo.CountByStatus(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), counts[domain.NotificationRetryFailed])
+	assert.Zero(t, counts[domain.NotificationRetryPending])
+}
+
+func TestNotificationRetryRepository_EnqueueReplacesFailed(t *testing.T) {
+	db := openTestDB(t, &domain.NotificationRetry{})
+	repo := NewNotificationRetryRepository(db)
+	ctx := context.Background()
+	now := time.Now()
+	subscriptionID, other := uuid.New(), uuid.New()
+
+	retry := &domain.NotificationRetry{SubscriptionID: subscriptionID, Attempts: 1, NextAttemptAt: now.Add(-time.Minute)}
+	require.NoError(t, repo.Enqueue(ctx, retry))
+	require.NoError(t, repo.RecordFailure(ctx, retry.ID, "503", nil))
+	require.NoError(t, repo.Enqueue(ctx, &domain.NotificationRetry{SubscriptionID: other, Attempts: 1, NextAttemptAt: now.Add(time.Hour)}))
+
+	queued, err := repo.Queued(ctx, []uuid.UUID{subscriptionID, other})
+	require.NoError(t, err)
+	assert.Equal(t, map[uuid.UUID]bool{other: true}, queued, "a failed retry isn't queued")
+
+	// A later send that fails is queued again instead of being dropped
+	require.NoError(t, repo.Enqueue(ctx, &domain.NotificationRetry{
+		SubscriptionID: subscriptionID, StockQuantity: 3, Attempts: 1, LastError: "timeout", NextAttemptAt: now.Add(-time.Second),
+	}))
+	due, err := repo.Due(ctx, now, 10)
+	require.NoError(t, err)
+	require.Len(t, due, 1)
+	assert.Equal(t, subscriptionID, due[0].SubscriptionID)
+	assert.Equal(t, 1, due[0].Attempts)
+	assert.Equal(t, 3, due[0].StockQuantity)
+	assert.Equal(t, "timeout", due[0].LastError)
+
+	queued, err = repo.Queued(ctx, []uuid.UUID{subscriptionID, other})
+	require.NoError(t, err)
+	assert.Len(t, queued, 2)
+}
+
+func TestNotificationRetryPolicy_NextAttempt(t *testing.T) {
+	policy := domain.DefaultNotificationRetryPolicy
+	now := time.Now()
+
+	next, ok := policy.NextAttempt(1, now)
+	require.True(t, ok)
+	assert.Equal(t, time.Minute, next.Sub(now))
+
+	next, ok = policy.NextAttempt(3, now)
+	require.True(t, ok)
+	assert.Equal(t, 4*time.Minute, next.Sub(now))
+
+	// The delay stops growing at MaxBackoff
+	policy.MaxAttempts = 20
+	next, ok = policy.NextAttempt(15, now)
+	require.True(t, ok)
+	assert.Equal(t, policy.MaxBackoff, next.Sub(now))
+
+	_, ok = policy.NextAttempt(20, now)
+	assert.False(t, ok)
+}
@@ -0,0 +1,291 @@
+package persistence
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// OnlineIndex is an index built without blocking writes to its table. Large
+// tables (wishlists, back-in-stock subscriptions) get their indexes this way
+// from the migrate command instead of at server boot.
+type OnlineIndex struct {
+	Name    string // unqualified; created in the table's schema
+	Table   string // schema-qualified
+	Columns string // column list or expressions, without the parentheses
+	Unique  bool
+	Where   string // optional partial index predicate
+	// Replaces are indexes made redundant by this one, dropped once it is built
+	Replaces []string
+}
+
+// schema returns the schema of the index's table
+func (i OnlineIndex) schema() string {
+	if schema, _, ok := strings.Cut(i.Table, "."); ok {
+		return schema
+	}
+	return "public"
+}
+
+// qualified returns a schema-qualified index name
+func (i OnlineIndex) qualified(name string) string {
+	return i.schema() + "." + name
+}
+
+// statement returns the CREATE INDEX CONCURRENTLY statement
+func (i OnlineIndex) statement() string {
+	var b strings.Builder
+	b.WriteString("CREATE ")
+	if i.Unique {
+		b.WriteString("UNIQUE ")
+	}
+	fmt.Fprintf(&b, "INDEX CONCURRENTLY IF NOT EXISTS %s ON %s (%s)", i.Name, i.Table, i.Columns)
+	if i.Where != "" {
+		b.WriteString(" WHERE " + i.Where)
+	}
+	return b.String()
+}
+
+// OnlineIndexes are the indexes the migrate command builds
+func OnlineIndexes() []OnlineIndex {
+	return []OnlineIndex{
+		// CUS-001: one wishlist entry per product variant
+		{
+			Name:     "idx_wishlist_user_product_variant",
+			Table:    "customer.wishlist_items",
+			Columns:  "user_id, product_id, COALESCE(variant_id, '00000000-0000-0000-0000-000000000000')",
+			Unique:   true,
+			Replaces: []string{"idx_wishlist_user_product"},
+		},
+		// Wishlist retention archives nudged items past their deadline
+		{
+			Name:    "idx_wishlist_nudged_at",
+			Table:   "customer.wishlist_items",
+			Columns: "nudged_at",
+			Where:   "nudged_at IS NOT NULL",
+		},
+		// Keyset pagination of the admin subscription list
+		{
+			Name:    "idx_bis_created_at_id",
+			Table:   "customer.back_in_stock_subscriptions",
+			Columns: "created_at DESC, id DESC",
+		},
+		// Admin customer list filters on the scores set by the scoring jobs
+		{
+			Name:    "idx_customers_rfm_segment",
+			Table:   "public.customers",
+			Columns: "rfm_segment",
+		},
+		{
+			Name:    "idx_customers_churn_risk",
+			Table:   "public.customers",
+			Columns: "churn_risk",
+		},
+		// Customers are looked up by phone through its blind index, as
+		// the phone itself is encrypted
+		{
+			Name:    "idx_customers_phone_index",
+			Table:   "public.customers",
+			Columns: "phone_index",
+		},
+	}
+}
+
+// CreateIndexConcurrently builds the index without locking out writes and then
+// drops the indexes it replaces. It is a no-op if the index is already valid.
+// A failed concurrent build leaves an invalid index behind, which is dropped
+// and rebuilt. PostgreSQL only; it must not run inside a transaction.
+func CreateIndexConcurrently(ctx context.Context, db *gorm.DB, index OnlineIndex) error {
+	db = db.WithContext(ctx)
+
+	valid, exists, err := indexState(db, index.schema(), index.Name)
+	if err != nil {
+		return err
+	}
+	if exists && !valid {
+		if err := db.Exec("DROP INDEX CONCURRENTLY IF EXISTS " + index.qualified(index.Name)).Error; err != nil {
+			return fmt.Errorf("drop invalid index: %w", err)
+		}
+	}
+	if !valid {
+		if err := db.Exec(index.statement()).Error; err != nil {
+			return err
+		}
+	}
+
+	for _, old := range index.Replaces {
+		if err := db.Exec("DROP INDEX CONCURRENTLY IF EXISTS " + index.qualified(old)).Error; err != nil {
+			return fmt.Errorf("drop replaced index %s: %w", old, err)
+		}
+	}
+	return nil
+}
+
+// MissingOnlineIndexes returns the names of OnlineIndexes that don't exist or
+// are invalid, so the server can warn that the migrate command hasn't run
+func MissingOnlineIndexes(ctx context.Context, db *gorm.DB) ([]string, error) {
+	var missing []string
+	for _, index := range OnlineIndexes() {
+		valid, _, err := indexState(db.WithContext(ctx), index.schema(), index.Name)
+		if err != nil {
+			return nil, err
+		}
+		if !valid {
+			missing = append(missing, index.Name)
+		}
+	}
+	return missing, nil
+}
+
+func indexState(db *gorm.DB, schema, name string) (valid, exists bool, err error) {
+	var rows []struct{ Indisvalid bool }
+	err = db.Raw(`
+		SELECT i.indisvalid
+		FROM pg_index i
+		JOIN pg_class c ON c.oid = i.indexrelid
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE n.nspname = ? AND c.relname = ?`, schema, name).
+		Scan(&rows).Error
+	if err != nil || len(rows) == 0 {
+		return false, false, err
+	}
+	return rows[0].Indisvalid, true, nil
+}
+
+// Backfill updates a large table in primary key order, one short transaction
+// per batch, so it never holds locks on more than a batch of rows. Progress is
+// saved after every batch and an interrupted backfill resumes where it
+// stopped.
+type Backfill struct {
+	Name  string // identifies the progress record
+	Table string
+	Key   string // primary key column, "id" if empty
+	// Set is the assignment list of the UPDATE, e.g. "expires_at = created_at + ?"
+	Set     string
+	SetArgs []interface{}
+	// Where selects the rows still needing the backfill
+	Where     string
+	WhereArgs []interface{}
+}
+
+// Backfills are the backfills the migrate command runs. subscriptionTTL is the
+// configured back-in-stock subscription lifetime.
+func Backfills(subscriptionTTL time.Duration) []Backfill {
+	return []Backfill{
+		// Subscriptions from before expiry was tracked get the expiry they
+		// would have been given, so ExpirePending no longer needs its
+		// created_at fallback for them
+		{
+			Name:      "back_in_stock_expires_at",
+			Table:     "customer.back_in_stock_subscriptions",
+			Set:       "expires_at = created_at + make_interval(secs => ?)",
+			SetArgs:   []interface{}{subscriptionTTL.Seconds()},
+			Where:     "expires_at IS NULL AND is_notified = ?",
+			WhereArgs: []interface{}{false},
+		},
+		BirthdayBackfill(),
+	}
+}
+
+// BackfillProgress records how far a backfill has got
+type BackfillProgress struct {
+	Name        string     `gorm:"type:varchar(100);primaryKey" json:"name"`
+	LastKey     string     `gorm:"type:varchar(100)" json:"last_key"`
+	Rows        int64      `gorm:"not null;default:0" json:"rows"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+func (BackfillProgress) TableName() string {
+	return "customer.backfill_progress"
+}
+
+// BackfillRunner runs backfills in batches
+type BackfillRunner struct {
+	db        *gorm.DB
+	batchSize int
+	pause     time.Duration
+	onBatch   func(BackfillProgress)
+}
+
+// NewBackfillRunner creates a backfill runner updating batchSize rows at a
+// time and sleeping pause between batches to spare the database
+func NewBackfillRunner(db *gorm.DB, batchSize int, pause time.Duration) *BackfillRunner {
+	return &BackfillRunner{db: db, batchSize: batchSize, pause: pause}
+}
+
+// OnBatch registers a callback reporting progress after each batch
+func (r *BackfillRunner) OnBatch(fn func(BackfillProgress)) *BackfillRunner {
+	r.onBatch = fn
+	return r
+}
+
+// Run runs the backfill to completion, resuming from saved progress. A
+// completed backfill is skipped; delete its progress record to run it again.
+func (r *BackfillRunner) Run(ctx context.Context, b Backfill) (*BackfillProgress, error) {
+	if r.batchSize <= 0 {
+		return nil, errors.New("batch size must be positive")
+	}
+	key := b.Key
+	if key == "" {
+		key = "id"
+	}
+	db := r.db.WithContext(ctx)
+
+	progress := BackfillProgress{Name: b.Name}
+	if err := db.Where("name = ?", b.Name).FirstOrCreate(&progress).Error; err != nil {
+		return nil, err
+	}
+	if progress.CompletedAt != nil {
+		return &progress, nil
+	}
+
+	for {
+		query := db.Table(b.Table).Where(b.Where, b.WhereArgs...)
+		if progress.LastKey != "" {
+			query = query.Where(key+" > ?", progress.LastKey)
+		}
+		var keys []string
+		if err := query.Order(key).Limit(r.batchSize).Pluck(key, &keys).Error; err != nil {
+			return &progress, err
+		}
+		if len(keys) == 0 {
+			now := time.Now()
+			progress.CompletedAt = &now
+			return &progress, db.Save(&progress).Error
+		}
+
+		err := db.Transaction(func(tx *gorm.DB) error {
+			result := tx.Exec(
+				fmt.Sprintf("UPDATE %s SET %s WHERE %s IN ?", b.Table, b.Set, key),
+				append(append([]interface{}{}, b.SetArgs...), keys)...,
+			)
+			if result.Error != nil {
+				return result.Error
+			}
+			progress.LastKey = keys[len(keys)-1]
+			progress.Rows += result.RowsAffected
+			return tx.Clauses(clause.OnConflict{UpdateAll: true}).Create(&progress).Error
+		})
+		if err != nil {
+			return &progress, err
+		}
+		if r.onBatch != nil {
+			r.onBatch(progress)
+		}
+
+		if err := ctx.Err(); err != nil {
+			return &progress, err
+		}
+		select {
+		case <-ctx.Done():
+			return &progress, ctx.Err()
+		case <-time.After(r.pause):
+		}
+	}
+}
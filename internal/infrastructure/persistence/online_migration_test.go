@@ -0,0 +1,84 @@
+package persistence
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackfillRunner(t *testing.T) {
+	db := openTestDB(t, &domain.Customer{}, &domain.BackInStockSubscription{}, &BackfillProgress{})
+	ctx := context.Background()
+
+	productID := uuid.New()
+	subscriptions := make([]domain.BackInStockSubscription, 5)
+	for i := range subscriptions {
+		subscriptions[i] = domain.BackInStockSubscription{CustomerID: uuid.New(), ProductID: productID}
+	}
+	subscriptions[2].IsNotified = true
+	require.NoError(t, db.Create(&subscriptions).Error)
+
+	// The sqlite test tables drop the schema dot
+	backfill := Backfill{
+		Name:      "test_expires_at",
+		Table:     "customer_back_in_stock_subscriptions",
+		Set:       "expires_at = created_at",
+		Where:     "expires_at IS NULL AND is_notified = ?",
+		WhereArgs: []interface{}{false},
+	}
+
+	// Stop after the first batch, as an interrupted run would
+	var batches []int64
+	interrupted, cancel := context.WithCancel(ctx)
+	runner := NewBackfillRunner(db, 2, 0).OnBatch(func(p BackfillProgress) {
+		batches = append(batches, p.Rows)
+		cancel()
+	})
+	progress, err := runner.Run(interrupted, backfill)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.EqualValues(t, 2, progress.Rows)
+
+	runner.OnBatch(func(p BackfillProgress) { batches = append(batches, p.Rows) })
+	progress, err = runner.Run(ctx, backfill)
+	require.NoError(t, err)
+	assert.EqualValues(t, 4, progress.Rows, "resumes without redoing the first batch")
+	assert.NotNil(t, progress.CompletedAt)
+	assert.Equal(t, []int64{2, 4}, batches)
+
+	var pending int64
+	require.NoError(t, db.Model(&domain.BackInStockSubscription{}).Where("expires_at IS NULL").Count(&pending).Error)
+	assert.EqualValues(t, 1, pending, "only the notified subscription is left untouched")
+
+	// A completed backfill isn't run again
+	require.NoError(t, db.Create(&domain.BackInStockSubscription{CustomerID: uuid.New(), ProductID: productID}).Error)
+	progress, err = runner.Run(ctx, backfill)
+	require.NoError(t, err)
+	assert.EqualValues(t, 4, progress.Rows)
+	assert.Equal(t, []int64{2, 4}, batches)
+}
+
+func TestOnlineIndex_Statement(t *testing.T) {
+	index := OnlineIndex{
+		Name:    "idx_pending",
+		Table:   "customer.back_in_stock_subscriptions",
+		Columns: "product_id",
+		Unique:  true,
+		Where:   "is_notified = false",
+	}
+	assert.Equal(t,
+		"CREATE UNIQUE INDEX CONCURRENTLY IF NOT EXISTS idx_pending ON customer.back_in_stock_subscriptions (product_id) WHERE is_notified = false",
+		index.statement())
+	assert.Equal(t, "customer.idx_old", index.qualified("idx_old"))
+
+	for _, index := range OnlineIndexes() {
+		assert.Contains(t, index.Table, ".", "%s must name its schema", index.Name)
+	}
+	for _, b := range Backfills(time.Hour) {
+		assert.NotEmpty(t, b.Where, "%s must skip rows already backfilled", b.Name)
+	}
+}
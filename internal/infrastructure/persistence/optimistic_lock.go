@@ -0,0 +1,29 @@
+package persistence
+
+import (
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"gorm.io/gorm"
+)
+
+// bumpVersion is the assignment that advances the version of rows changed
+// by a bulk update, so clients holding the old version see a conflict
+var bumpVersion = gorm.Expr("version + 1")
+
+// updateVersioned writes every column of model if its row is still at
+// *version, then advances *version. Returns domain.ErrVersionConflict when
+// another request updated or deleted the row first.
+//
+// Save can't be used: when it matches no row it falls back to an insert,
+// which would resurrect the row with the stale values.
+func updateVersioned(tx *gorm.DB, model interface{}, version *int64) error {
+	expected := *version
+	*version = expected + 1
+	result := tx.Model(model).Where("version = ?", expected).Select("*").Updates(model)
+	if result.Error == nil && result.RowsAffected == 0 {
+		result.Error = domain.ErrVersionConflict
+	}
+	if result.Error != nil {
+		*version = expected
+	}
+	return result.Error
+}
@@ -0,0 +1,141 @@
+package persistence
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// OrderStatsRepository keeps the denormalized TotalOrders and TotalSpent of
+// customers in step with their orders
+type OrderStatsRepository struct {
+	db *gorm.DB
+}
+
+// NewOrderStatsRepository creates a new order stats repository
+func NewOrderStatsRepository(db *gorm.DB) *OrderStatsRepository {
+	return &OrderStatsRepository{db: db}
+}
+
+// Apply adds the entry to the customer's totals and records it on their
+// activity timeline, in one transaction. It returns false without changing
+// anything if an entry with the same event key was already applied, and
+// gorm.ErrRecordNotFound if the customer doesn't exist. Totals never go
+// below zero. A completed order moves the customer's first and last order
+// dates to the entry's CreatedAt, which defaults to now.
+func (r *OrderStatsRepository) Apply(ctx context.Context, entry *domain.OrderStatsEntry) (bool, error) {
+	applied := false
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "event_key"}},
+			DoNothing: true,
+		}).Create(entry)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+
+		// UpdateColumns skips the optimistic locking hook, so bump the version here
+		updates := map[string]interface{}{
+			"total_orders": gorm.Expr("CASE WHEN total_orders + ? > 0 THEN total_orders + ? ELSE 0 END", entry.Orders, entry.Orders),
+			"total_spent":  gorm.Expr("CASE WHEN total_spent + ? > 0 THEN total_spent + ? ELSE 0 END", entry.Amount, entry.Amount),
+			"version":      gorm.Expr("version + 1"),
+		}
+		if entry.Kind == domain.OrderStatsCompleted {
+			updates["first_order_at"] = gorm.Expr("CASE WHEN first_order_at IS NULL OR first_order_at > ? THEN ? ELSE first_order_at END", entry.CreatedAt, entry.CreatedAt)
+			updates["last_order_at"] = gorm.Expr("CASE WHEN last_order_at IS NULL OR last_order_at < ? THEN ? ELSE last_order_at END", entry.CreatedAt, entry.CreatedAt)
+		}
+		result = tx.Model(&domain.Customer{}).
+			Where("id = ?", entry.CustomerID).
+			UpdateColumns(updates)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		applied = true
+		return tx.Create(&domain.CustomerActivity{
+			CustomerID: entry.CustomerID,
+			Type:       domain.ActivityTypeOrder,
+			Title:      entry.ActivityTitle(),
+			Details:    "order_id=" + entry.OrderID,
+		}).Error
+	})
+	if err != nil {
+		return false, err
+	}
+	return applied, nil
+}
+
+// OrderTotalsFunc returns the paid orders of a customer, as recorded by
+// service-order
+type OrderTotalsFunc func(ctx context.Context, customerID uuid.UUID) (domain.OrderTotals, error)
+
+// Backfill recalculates the totals and order dates of up to limit customers
+// after the given customer ID with totals, and returns the last customer ID
+// handled, or uuid.Nil once every customer has been handled. With dryRun it
+// only counts the customers whose totals are wrong.
+func (r *OrderStatsRepository) Backfill(ctx context.Context, after uuid.UUID, limit int, dryRun bool, totals OrderTotalsFunc) (uuid.UUID, domain.OrderStatsBackfillResult, error) {
+	var result domain.OrderStatsBackfillResult
+	db := r.db.WithContext(ctx)
+
+	var customers []domain.Customer
+	if err := db.Select("id", "total_orders", "total_spent", "first_order_at", "last_order_at").
+		Where("id > ?", after).
+		Order("id").
+		Limit(limit).
+		Find(&customers).Error; err != nil {
+		return uuid.Nil, result, err
+	}
+	if len(customers) == 0 {
+		return uuid.Nil, result, nil
+	}
+
+	for _, c := range customers {
+		actual, err := totals(ctx, c.ID)
+		if err != nil {
+			return uuid.Nil, result, fmt.Errorf("customer %s: %w", c.ID, err)
+		}
+		result.Customers++
+		spent := math.Round(actual.Spent*100) / 100
+		if c.TotalOrders == actual.Orders && math.Abs(c.TotalSpent-spent) < 0.005 &&
+			sameTime(c.FirstOrderAt, actual.FirstOrderAt) && sameTime(c.LastOrderAt, actual.LastOrderAt) {
+			continue
+		}
+		result.Corrected++
+		if dryRun {
+			continue
+		}
+		if err := db.Model(&domain.Customer{}).
+			Where("id = ?", c.ID).
+			UpdateColumns(map[string]interface{}{
+				"total_orders":   actual.Orders,
+				"total_spent":    spent,
+				"first_order_at": actual.FirstOrderAt,
+				"last_order_at":  actual.LastOrderAt,
+				"version":        gorm.Expr("version + 1"),
+			}).Error; err != nil {
+			return uuid.Nil, result, err
+		}
+	}
+	return customers[len(customers)-1].ID, result, nil
+}
+
+// sameTime reports whether two optional times are both unset or the same to
+// the second
+func sameTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Truncate(time.Second).Equal(b.Truncate(time.Second))
+}
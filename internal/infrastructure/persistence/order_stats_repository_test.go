@@ -0,0 +1,142 @@
+package persistence
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestOrderStatsRepository_Apply(t *testing.T) {
+	db := openTestDB(t, &domain.Customer{}, &domain.OrderStatsEntry{}, &domain.CustomerActivity{})
+	repo := NewOrderStatsRepository(db)
+	ctx := context.Background()
+
+	customer := domain.Customer{Email: "aisyah@example.com", Version: 1}
+	require.NoError(t, db.Create(&customer).Error)
+
+	// Events can arrive out of order; the order dates still span both orders
+	march := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	january := time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC)
+	second := domain.OrderCompleted(customer.ID, "order-2", "ORD-1002", 80)
+	second.CreatedAt = march
+	applied, err := repo.Apply(ctx, second)
+	require.NoError(t, err)
+	assert.True(t, applied)
+	first := domain.OrderCompleted(customer.ID, "order-1", "ORD-1001", 120.50)
+	first.CreatedAt = january
+	_, err = repo.Apply(ctx, first)
+	require.NoError(t, err)
+
+	// A redelivered event is only counted once
+	applied, err = repo.Apply(ctx, domain.OrderCompleted(customer.ID, "order-1", "ORD-1001", 120.50))
+	require.NoError(t, err)
+	assert.False(t, applied)
+
+	// A partial refund only reduces the amount spent; a full one also the order count
+	_, err = repo.Apply(ctx, domain.OrderRefunded(customer.ID, "order-1", "ORD-1001", "refund-1", 20.50, false))
+	require.NoError(t, err)
+	_, err = repo.Apply(ctx, domain.OrderRefunded(customer.ID, "order-2", "ORD-1002", "", 80, true))
+	require.NoError(t, err)
+
+	var stored domain.Customer
+	require.NoError(t, db.First(&stored, "id = ?", customer.ID).Error)
+	assert.Equal(t, 1, stored.TotalOrders)
+	assert.InDelta(t, 100.0, stored.TotalSpent, 0.001)
+	assert.Equal(t, int64(5), stored.Version)
+	require.NotNil(t, stored.FirstOrderAt)
+	require.NotNil(t, stored.LastOrderAt)
+	assert.True(t, january.Equal(*stored.FirstOrderAt))
+	assert.True(t, march.Equal(*stored.LastOrderAt))
+
+	var activities []domain.CustomerActivity
+	require.NoError(t, db.Where("customer_id = ?", customer.ID).Order("created_at").Find(&activities).Error)
+	require.Len(t, activities, 4)
+	assert.Equal(t, domain.ActivityTypeOrder, activities[0].Type)
+	assert.Equal(t, "Order ORD-1002 completed", activities[0].Title)
+
+	// Unknown customers are rolled back, so the event can be applied once they exist
+	_, err = repo.Apply(ctx, domain.OrderCompleted(uuid.New(), "order-3", "", 10))
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	var entries int64
+	require.NoError(t, db.Model(&domain.OrderStatsEntry{}).Count(&entries).Error)
+	assert.Equal(t, int64(4), entries)
+}
+
+func TestOrderStatsRepository_Backfill(t *testing.T) {
+	db := openTestDB(t, &domain.Customer{})
+	repo := NewOrderStatsRepository(db)
+	ctx := context.Background()
+
+	buyer := domain.Customer{Email: "buyer@example.com", Version: 1}
+	stale := domain.Customer{Email: "stale@example.com", TotalOrders: 3, TotalSpent: 300, Version: 1}
+	browser := domain.Customer{Email: "browser@example.com", Version: 1}
+	for _, c := range []*domain.Customer{&buyer, &stale, &browser} {
+		require.NoError(t, db.Create(c).Error)
+	}
+
+	// Paid orders as service-order reports them
+	firstOrder := time.Date(2024, 6, 1, 9, 0, 0, 0, time.UTC)
+	lastOrder := time.Date(2025, 2, 1, 9, 0, 0, 0, time.UTC)
+	paid := map[uuid.UUID]domain.OrderTotals{
+		buyer.ID: {Orders: 2, Spent: 100.004, FirstOrderAt: &firstOrder, LastOrderAt: &lastOrder},
+		stale.ID: {Orders: 1, Spent: 30, FirstOrderAt: &lastOrder, LastOrderAt: &lastOrder},
+	}
+	totals := func(ctx context.Context, customerID uuid.UUID) (domain.OrderTotals, error) {
+		return paid[customerID], nil
+	}
+
+	// A dry run reports the wrong totals without fixing them
+	_, result, err := repo.Backfill(ctx, uuid.Nil, 10, true, totals)
+	require.NoError(t, err)
+	assert.Equal(t, domain.OrderStatsBackfillResult{Customers: 3, Corrected: 2}, result)
+
+	total := domain.OrderStatsBackfillResult{}
+	after := uuid.Nil
+	for pages := 0; ; pages++ {
+		require.Less(t, pages, 3)
+		var page domain.OrderStatsBackfillResult
+		after, page, err = repo.Backfill(ctx, after, 2, false, totals)
+		require.NoError(t, err)
+		total.Customers += page.Customers
+		total.Corrected += page.Corrected
+		if after == uuid.Nil {
+			break
+		}
+	}
+	assert.Equal(t, domain.OrderStatsBackfillResult{Customers: 3, Corrected: 2}, total)
+
+	stored := func(id uuid.UUID) domain.Customer {
+		var customer domain.Customer
+		require.NoError(t, db.First(&customer, "id = ?", id).Error)
+		return customer
+	}
+	customer := stored(buyer.ID)
+	assert.Equal(t, 2, customer.TotalOrders)
+	assert.InDelta(t, 100.0, customer.TotalSpent, 0.001)
+	require.NotNil(t, customer.FirstOrderAt)
+	assert.True(t, firstOrder.Equal(*customer.FirstOrderAt))
+	customer = stored(stale.ID)
+	assert.Equal(t, 1, customer.TotalOrders)
+	assert.InDelta(t, 30.0, customer.TotalSpent, 0.001)
+	customer = stored(browser.ID)
+	assert.Equal(t, 0, customer.TotalOrders)
+	assert.Nil(t, customer.LastOrderAt)
+
+	// A second run finds nothing to correct
+	_, result, err = repo.Backfill(ctx, uuid.Nil, 10, true, totals)
+	require.NoError(t, err)
+	assert.Equal(t, 0, result.Corrected)
+
+	// Errors stop the backfill
+	_, _, err = repo.Backfill(ctx, uuid.Nil, 10, false, func(context.Context, uuid.UUID) (domain.OrderTotals, error) {
+		return domain.OrderTotals{}, errors.New("service-order unavailable")
+	})
+	assert.Error(t, err)
+}
@@ -0,0 +1,148 @@
+package persistence
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/encryption"
+	"gorm.io/gorm"
+)
+
+// EncryptedColumn is a column stored with the encrypted serializer
+type EncryptedColumn struct {
+	Table  string // schema-qualified
+	Column string
+
+	// The blind index column kept next to the column, if any, and how it
+	// is computed from the plaintext
+	Index   string
+	IndexOf func(plaintext string) string
+}
+
+// EncryptedColumns are the PII columns encrypted at rest
+func EncryptedColumns() []EncryptedColumn {
+	return []EncryptedColumn{
+		{Table: domain.Customer{}.TableName(), Column: "phone", Index: "phone_index", IndexOf: domain.PhoneBlindIndex},
+		{Table: domain.Profile{}.TableName(), Column: "phone"},
+		{Table: domain.Profile{}.TableName(), Column: "date_of_birth"},
+		{Table: domain.Address{}.TableName(), Column: "phone"},
+		{Table: domain.CustomerMeasurement{}.TableName(), Column: "notes"},
+	}
+}
+
+// BirthdayBackfill sets the birthday of profiles saved before date_of_birth
+// was encrypted, while it can still be read in SQL. Profiles saved since get
+// it from domain.Profile.BeforeSave.
+func BirthdayBackfill() Backfill {
+	return Backfill{
+		Name:      "profiles_birthday",
+		Table:     domain.Profile{}.TableName(),
+		Set:       "birthday = to_char(date_of_birth::timestamptz, 'MM-DD')",
+		Where:     "birthday IS NULL AND date_of_birth IS NOT NULL AND date_of_birth NOT LIKE ?",
+		WhereArgs: []interface{}{encryption.Prefix + "%"},
+	}
+}
+
+// Reencryptor encrypts the values of an encrypted column still in plaintext
+// or encrypted with a key other than the primary, so that after a key
+// rotation the old key can be retired
+type Reencryptor struct {
+	db        *gorm.DB
+	keys      *encryption.Keyring
+	batchSize int
+	onBatch   func(column EncryptedColumn, rows int64)
+}
+
+// NewReencryptor creates a reencryptor rewriting batchSize rows per transaction
+func NewReencryptor(db *gorm.DB, keys *encryption.Keyring, batchSize int) *Reencryptor {
+	return &Reencryptor{db: db, keys: keys, batchSize: batchSize}
+}
+
+// OnBatch registers a callback reporting the rows rewritten so far after
+// each batch
+func (r *Reencryptor) OnBatch(fn func(column EncryptedColumn, rows int64)) *Reencryptor {
+	r.onBatch = fn
+	return r
+}
+
+// Run re-encrypts a column, filling in its blind index where it is missing,
+// and returns the number of rows rewritten. Each row is only rewritten if
+// its value is unchanged since it was read, so concurrent writes by the
+// server are never overwritten. It is safe to run repeatedly; rows already
+// encrypted with the primary key and indexed are skipped.
+func (r *Reencryptor) Run(ctx context.Context, column EncryptedColumn) (int64, error) {
+	if !r.keys.Enabled() {
+		return 0, errors.New("no encryption keys configured")
+	}
+	if r.batchSize <= 0 {
+		return 0, errors.New("batch size must be positive")
+	}
+	db := r.db.WithContext(ctx)
+	current := encryption.Prefix + r.keys.PrimaryKeyID() + ":%"
+
+	var rewritten int64
+	lastID := ""
+	for {
+		query := db.Table(column.Table).
+			Select("id", column.Column+" AS value").
+			Where(column.Column + " IS NOT NULL AND " + column.Column + " <> ''")
+		if column.Index != "" {
+			// Rows saved before the index column was added have NULL there;
+			// values too short to index have ""
+			query = query.Where("("+column.Column+" NOT LIKE ? OR "+column.Index+" IS NULL)", current)
+		} else {
+			query = query.Where(column.Column+" NOT LIKE ?", current)
+		}
+		if lastID != "" {
+			query = query.Where("id > ?", lastID)
+		}
+		var rows []struct {
+			ID    string
+			Value string
+		}
+		if err := query.Order("id").Limit(r.batchSize).Scan(&rows).Error; err != nil {
+			return rewritten, err
+		}
+		if len(rows) == 0 {
+			return rewritten, nil
+		}
+
+		err := db.Transaction(func(tx *gorm.DB) error {
+			for _, row := range rows {
+				binding := domain.EncryptionBinding(column.Table, column.Column, row.ID)
+				plaintext, err := r.keys.Decrypt(row.Value, binding)
+				if err != nil {
+					return fmt.Errorf("decrypt %s %s: %w", column.Table, row.ID, err)
+				}
+				encrypted, err := r.keys.Encrypt(plaintext, binding)
+				if err != nil {
+					return err
+				}
+				updates := map[string]interface{}{column.Column: encrypted}
+				if column.Index != "" {
+					updates[column.Index] = column.IndexOf(plaintext)
+				}
+				result := tx.Table(column.Table).
+					Where("id = ? AND "+column.Column+" = ?", row.ID, row.Value).
+					Updates(updates)
+				if result.Error != nil {
+					return result.Error
+				}
+				rewritten += result.RowsAffected
+			}
+			return nil
+		})
+		if err != nil {
+			return rewritten, err
+		}
+		lastID = rows[len(rows)-1].ID
+		if r.onBatch != nil {
+			r.onBatch(column, rewritten)
+		}
+		if err := ctx.Err(); err != nil {
+			return rewritten, err
+		}
+	}
+}
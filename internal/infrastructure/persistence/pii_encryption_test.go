@@ -0,0 +1,182 @@
+package persistence
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/Ecom-micro-template/service-customer/internal/domain/shared"
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/encryption"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testKeyring(t *testing.T, primary string, ids ...string) *encryption.Keyring {
+	t.Helper()
+	keys := make(map[string][]byte, len(ids))
+	for _, id := range ids {
+		keys[id] = bytes.Repeat([]byte(id[len(id)-1:]), encryption.KeySize)
+	}
+	keyring, err := encryption.NewKeyring(keys, primary)
+	require.NoError(t, err)
+	keyring, err = keyring.WithIndexKey(bytes.Repeat([]byte("i"), encryption.KeySize))
+	require.NoError(t, err)
+	return keyring
+}
+
+func storedProfile(t *testing.T, r *ProfileRepository, id uuid.UUID) (phone, dateOfBirth string) {
+	t.Helper()
+	row := r.db.Table("customer_profiles").Select("phone", "date_of_birth").Where("id = ?", id).Row()
+	require.NoError(t, row.Scan(&phone, &dateOfBirth))
+	return phone, dateOfBirth
+}
+
+func TestPIIEncryption_ReencryptAfterRotation(t *testing.T) {
+	t.Cleanup(func() { domain.UseColumnCipher(nil) })
+	db := setupTestDB(t)
+	repo := NewProfileRepository(db)
+	ctx := context.Background()
+	born := time.Date(1990, time.May, 1, 0, 0, 0, 0, time.UTC)
+
+	// Saved before encryption was enabled
+	legacy := &domain.Profile{ID: uuid.New(), Email: "legacy@example.com", Phone: "+60123456789", DateOfBirth: &born}
+	require.NoError(t, repo.Create(ctx, legacy))
+	phone, _ := storedProfile(t, repo, legacy.ID)
+	assert.Equal(t, "+60123456789", phone)
+
+	k1 := testKeyring(t, "k1", "k1")
+	domain.UseColumnCipher(k1)
+	fresh := &domain.Profile{ID: uuid.New(), Email: "fresh@example.com", Phone: "+60198765432", DateOfBirth: &born}
+	require.NoError(t, repo.Create(ctx, fresh))
+	phone, dateOfBirth := storedProfile(t, repo, fresh.ID)
+	assert.True(t, strings.HasPrefix(phone, "enc:k1:"), "new values are encrypted")
+	assert.True(t, strings.HasPrefix(dateOfBirth, "enc:k1:"))
+
+	got, err := repo.GetByUserID(ctx, legacy.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "+60123456789", got.Phone, "plaintext rows are still readable")
+
+	columns := []EncryptedColumn{
+		{Table: "customer_profiles", Column: "phone"},
+		{Table: "customer_profiles", Column: "date_of_birth"},
+	}
+	run := func(keys *encryption.Keyring) int64 {
+		var total int64
+		for _, column := range columns {
+			rows, err := NewReencryptor(db, keys, 1).Run(ctx, column)
+			require.NoError(t, err)
+			total += rows
+		}
+		return total
+	}
+	assert.Equal(t, int64(2), run(k1), "only the legacy row is rewritten")
+
+	rotated := testKeyring(t, "k2", "k1", "k2")
+	domain.UseColumnCipher(rotated)
+	assert.Equal(t, int64(4), run(rotated))
+	assert.Zero(t, run(rotated), "a second run has nothing to do")
+
+	domain.UseColumnCipher(testKeyring(t, "", "k2"))
+	for _, id := range []uuid.UUID{legacy.ID, fresh.ID} {
+		phone, dateOfBirth := storedProfile(t, repo, id)
+		assert.True(t, strings.HasPrefix(phone, "enc:k2:"))
+		assert.True(t, strings.HasPrefix(dateOfBirth, "enc:k2:"))
+
+		got, err := repo.GetByUserID(ctx, id)
+		require.NoError(t, err, "readable with the old key retired")
+		require.NotNil(t, got.DateOfBirth)
+		assert.True(t, born.Equal(*got.DateOfBirth))
+	}
+
+	birthdays, _, err := NewCustomerLookupRepository(db).Birthdays(ctx, time.Date(2025, time.May, 1, 0, 0, 0, 0, time.UTC), nil, 10)
+	require.NoError(t, err)
+	assert.Len(t, birthdays, 2, "the birthday feed doesn't need to decrypt")
+}
+
+func TestPIIEncryption_CiphertextBoundToRow(t *testing.T) {
+	t.Cleanup(func() { domain.UseColumnCipher(nil) })
+	db := setupTestDB(t)
+	repo := NewProfileRepository(db)
+	ctx := context.Background()
+	domain.UseColumnCipher(testKeyring(t, "k1", "k1"))
+
+	born := time.Date(1990, time.May, 1, 0, 0, 0, 0, time.UTC)
+	victim := &domain.Profile{ID: uuid.New(), Email: "victim@example.com", Phone: "+60123456789", DateOfBirth: &born}
+	attacker := &domain.Profile{ID: uuid.New(), Email: "attacker@example.com", Phone: "+60198765432", DateOfBirth: &born}
+	require.NoError(t, repo.Create(ctx, victim))
+	require.NoError(t, repo.Create(ctx, attacker))
+
+	// Copy the victim's encrypted phone onto the attacker's row
+	phone, _ := storedProfile(t, repo, victim.ID)
+	require.NoError(t, db.Table("customer_profiles").Where("id = ?", attacker.ID).Update("phone", phone).Error)
+
+	_, err := repo.GetByUserID(ctx, attacker.ID)
+	assert.ErrorIs(t, err, encryption.ErrMalformed)
+	got, err := repo.GetByUserID(ctx, victim.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "+60123456789", got.Phone)
+}
+
+func TestPIIEncryption_CustomerPhone(t *testing.T) {
+	t.Cleanup(func() { domain.UseColumnCipher(nil) })
+	db := openTestDB(t, &domain.Customer{}, &domain.CustomerActivity{}, &domain.Address{}, &domain.BlocklistEntry{})
+	repo := NewCustomerRepository(db, DefaultQueryTimeouts())
+	ctx := context.Background()
+	storedPhone := func(id uuid.UUID) (phone string, index *string) {
+		t.Helper()
+		row := db.Table("public_customers").Select("phone", "phone_index").Where("id = ?", id).Row()
+		require.NoError(t, row.Scan(&phone, &index))
+		return phone, index
+	}
+
+	// Saved before encryption, and before the index column was added
+	legacy, err := repo.Create(ctx, &domain.CreateCustomerRequest{Email: "legacy@example.com", Phone: "012-345 6789"}, nil)
+	require.NoError(t, err)
+	require.NoError(t, db.Table("public_customers").Where("id = ?", legacy.ID).Update("phone_index", nil).Error)
+
+	keys := testKeyring(t, "k1", "k1")
+	domain.UseColumnCipher(keys)
+	customer, err := repo.Create(ctx, &domain.CreateCustomerRequest{Email: "siti@example.com", Phone: "+60 12-345 6789"}, nil)
+	require.NoError(t, err)
+	phone, index := storedPhone(customer.ID)
+	assert.True(t, strings.HasPrefix(phone, "enc:k1:"))
+	require.NotNil(t, index)
+	assert.Equal(t, keys.BlindIndex("60123456789", domain.PhoneBlindIndexColumn), *index)
+
+	got, err := repo.GetByID(ctx, customer.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "+60 12-345 6789", got.Phone)
+
+	// Updating the phone re-encrypts it and moves its index
+	newPhone := "+60 19-876 5432"
+	_, err = repo.Update(ctx, customer.ID, &domain.UpdateCustomerRequest{Phone: &newPhone})
+	require.NoError(t, err)
+	phone, index = storedPhone(customer.ID)
+	assert.True(t, strings.HasPrefix(phone, "enc:k1:"))
+	assert.Equal(t, domain.PhoneBlindIndex(newPhone), *index)
+
+	// Re-encryption encrypts and indexes the legacy row
+	column := EncryptedColumn{Table: "public_customers", Column: "phone", Index: "phone_index", IndexOf: domain.PhoneBlindIndex}
+	rows, err := NewReencryptor(db, keys, 10).Run(ctx, column)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), rows)
+	phone, index = storedPhone(legacy.ID)
+	assert.True(t, strings.HasPrefix(phone, "enc:k1:"))
+	require.NotNil(t, index)
+	assert.Equal(t, domain.PhoneBlindIndex("+60123456789"), *index, "the same number in another format")
+
+	// The blocklist matches on the index, never storing the number
+	blocked := shared.StatusBlocked
+	_, err = repo.Update(ctx, legacy.ID, &domain.UpdateCustomerRequest{Status: &blocked})
+	require.NoError(t, err)
+	var entry domain.BlocklistEntry
+	require.NoError(t, db.Where("kind = ?", domain.BlocklistKindPhone).First(&entry).Error)
+	assert.Equal(t, *index, entry.Value)
+	result, err := NewBlocklistRepository(db).Check(ctx, &domain.FraudCheckRequest{Phone: "0123456789"}, time.Now())
+	require.NoError(t, err)
+	assert.True(t, result.Flagged)
+}
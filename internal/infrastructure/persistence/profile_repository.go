@@ -2,6 +2,7 @@ package persistence
 
 import (
 	"context"
+	"errors"
 
 	"github.com/google/uuid"
 	"github.com/Ecom-micro-template/service-customer/internal/domain"
@@ -29,20 +30,58 @@ func (r *ProfileRepository) GetByUserID(ctx context.Context, userID uuid.UUID) (
 	return &profile, nil
 }
 
-// Upsert creates or updates a profile
-func (r *ProfileRepository) Upsert(ctx context.Context, profile *domain.Profile) error {
-	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+// Create creates a new profile. Returns domain.ErrVersionConflict if the
+// profile was created meanwhile, e.g. by the customer's other device.
+func (r *ProfileRepository) Create(ctx context.Context, profile *domain.Profile) error {
+	result := r.db.WithContext(ctx).Clauses(clause.OnConflict{
 		Columns:   []clause.Column{{Name: "id"}},
-		DoUpdates: clause.AssignmentColumns([]string{"full_name", "email", "phone", "date_of_birth", "gender", "profile_picture", "updated_at"}),
-	}).Create(profile).Error
+		DoNothing: true,
+	}).Create(profile)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return domain.ErrVersionConflict
+	}
+	return nil
 }
 
-// Create creates a new profile
-func (r *ProfileRepository) Create(ctx context.Context, profile *domain.Profile) error {
-	return r.db.WithContext(ctx).Create(profile).Error
+// Update updates an existing profile if it is still at profile.Version.
+// Returns domain.ErrVersionConflict if it changed meanwhile.
+func (r *ProfileRepository) Update(ctx context.Context, profile *domain.Profile) error {
+	return updateVersioned(r.db.WithContext(ctx), profile, &profile.Version)
 }
 
-// Update updates an existing profile
-func (r *ProfileRepository) Update(ctx context.Context, profile *domain.Profile) error {
-	return r.db.WithContext(ctx).Save(profile).Error
+// SetAvatar points a customer's profile at a newly uploaded avatar, creating
+// the profile if needed, and returns the updated profile with the upload ID
+// of the avatar it replaced, whose files can then be deleted
+func (r *ProfileRepository) SetAvatar(ctx context.Context, userID, avatarID uuid.UUID, urls map[string]string) (*domain.Profile, *uuid.UUID, error) {
+	var profile domain.Profile
+	var previous *uuid.UUID
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", userID).First(&profile).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			profile = domain.Profile{ID: userID, AvatarID: &avatarID, AvatarURLs: urls, ProfilePicture: urls[domain.ProfilePictureSize]}
+			return tx.Create(&profile).Error
+		}
+		if err != nil {
+			return err
+		}
+
+		previous = profile.AvatarID
+		profile.AvatarID = &avatarID
+		profile.AvatarURLs = urls
+		profile.ProfilePicture = urls[domain.ProfilePictureSize]
+		if err := tx.Model(&profile).Select("avatar_id", "avatar_urls", "profile_picture").Updates(&profile).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&profile).Update("version", bumpVersion).Error; err != nil {
+			return err
+		}
+		return tx.Where("id = ?", userID).First(&profile).Error
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return &profile, previous, nil
 }
@@ -2,6 +2,7 @@ package persistence
 
 import (
 	"context"
+	"strings"
 	"testing"
 
 	"github.com/google/uuid"
@@ -13,11 +14,33 @@ import (
 )
 
 func setupTestDB(t *testing.T) *gorm.DB {
+	return openTestDB(t, &domain.Profile{})
+}
+
+// openTestDB opens an in-memory SQLite database and migrates the given models.
+// SQLite has no schemas and cannot evaluate Postgres defaults, so the cached
+// model schemas are adjusted first: "customer.addresses" becomes
+// "customer_addresses" and gen_random_uuid() defaults are dropped (BeforeCreate
+// hooks assign IDs anyway).
+func openTestDB(t *testing.T, models ...interface{}) *gorm.DB {
 	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
 	require.NoError(t, err)
 
+	for _, model := range models {
+		stmt := &gorm.Statement{DB: db}
+		require.NoError(t, stmt.Parse(model))
+		stmt.Schema.Table = strings.ReplaceAll(stmt.Schema.Table, ".", "_")
+		for _, field := range stmt.Schema.Fields {
+			if strings.HasSuffix(field.DefaultValue, "()") {
+				field.DefaultValue = ""
+				field.HasDefaultValue = false
+				field.DefaultValueInterface = nil
+			}
+		}
+	}
+
 	// Auto-migrate models
-	err = db.AutoMigrate(&domain.Profile{})
+	err = db.AutoMigrate(models...)
 	require.NoError(t, err)
 
 	return db
@@ -92,31 +115,64 @@ func TestProfileRepository_Update(t *testing.T) {
 	assert.Equal(t, "updated@example.com", retrieved.Email)
 }
 
-func TestProfileRepository_Upsert(t *testing.T) {
+func TestProfileRepository_VersionConflict(t *testing.T) {
 	db := setupTestDB(t)
 	repo := NewProfileRepository(db)
 	ctx := context.Background()
 
 	userID := uuid.New()
-	profile := &domain.Profile{
-		ID:       userID,
-		FullName: "First Insert",
-		Email:    "first@example.com",
-	}
+	require.NoError(t, repo.Create(ctx, &domain.Profile{ID: userID, FullName: "First Device", Email: "first@example.com"}))
+	// The other device created the profile at the same time
+	assert.ErrorIs(t, repo.Create(ctx, &domain.Profile{ID: userID, FullName: "Second Device", Email: "second@example.com"}), domain.ErrVersionConflict)
 
-	// First upsert (insert)
-	err := repo.Upsert(ctx, profile)
-	assert.NoError(t, err)
+	phone, err := repo.GetByUserID(ctx, userID)
+	require.NoError(t, err)
+	laptop, err := repo.GetByUserID(ctx, userID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), phone.Version)
 
-	// Second upsert (update)
-	profile.FullName = "Second Insert"
-	profile.Email = "second@example.com"
-	err = repo.Upsert(ctx, profile)
-	assert.NoError(t, err)
+	phone.FullName = "Edited On Phone"
+	require.NoError(t, repo.Update(ctx, phone))
+	assert.Equal(t, int64(2), phone.Version)
+
+	// The laptop still holds version 1, so its edit must not overwrite the phone's
+	laptop.Phone = "+60123456789"
+	assert.ErrorIs(t, repo.Update(ctx, laptop), domain.ErrVersionConflict)
+	assert.Equal(t, int64(1), laptop.Version)
 
-	// Verify only one record exists with updated data
 	retrieved, err := repo.GetByUserID(ctx, userID)
-	assert.NoError(t, err)
-	assert.Equal(t, "Second Insert", retrieved.FullName)
-	assert.Equal(t, "second@example.com", retrieved.Email)
+	require.NoError(t, err)
+	assert.Equal(t, "Edited On Phone", retrieved.FullName)
+	assert.Empty(t, retrieved.Phone)
+	assert.Equal(t, int64(2), retrieved.Version)
+}
+
+func TestProfileRepository_SetAvatar(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewProfileRepository(db)
+	ctx := context.Background()
+	userID := uuid.New()
+
+	// The first upload creates the profile
+	first := uuid.New()
+	profile, previous, err := repo.SetAvatar(ctx, userID, first, map[string]string{
+		"small":  "https://cdn.test/avatars/a/small.jpg",
+		"medium": "https://cdn.test/avatars/a/medium.jpg",
+	})
+	require.NoError(t, err)
+	assert.Nil(t, previous)
+	assert.Equal(t, "https://cdn.test/avatars/a/medium.jpg", profile.ProfilePicture)
+
+	second := uuid.New()
+	profile, previous, err = repo.SetAvatar(ctx, userID, second, map[string]string{"medium": "https://cdn.test/avatars/b/medium.jpg"})
+	require.NoError(t, err)
+	require.NotNil(t, previous)
+	assert.Equal(t, first, *previous)
+	assert.Equal(t, int64(2), profile.Version)
+
+	stored, err := repo.GetByUserID(ctx, userID)
+	require.NoError(t, err)
+	assert.Equal(t, second, *stored.AvatarID)
+	assert.Equal(t, map[string]string{"medium": "https://cdn.test/avatars/b/medium.jpg"}, stored.AvatarURLs)
+	assert.Equal(t, "https://cdn.test/avatars/b/medium.jpg", stored.ProfilePicture)
 }
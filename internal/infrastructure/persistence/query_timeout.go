@@ -0,0 +1,57 @@
+package persistence
+
+import (
+	"context"
+	"time"
+)
+
+// QueryClass groups queries by how long they may reasonably run
+type QueryClass int
+
+const (
+	QueryLookup QueryClass = iota // single rows and writes by key
+	QueryList                     // filtered, paginated lists
+	QueryReport                   // aggregates over many rows, like stats and timelines
+	QueryExport                   // exports of up to domain.MaxCustomerExportRows
+)
+
+// QueryTimeouts bounds how long each class of query may run. A query past
+// its deadline is cancelled on the server too, as is a query whose request
+// context is cancelled when the client disconnects. Zero leaves a class to
+// the caller's context.
+type QueryTimeouts struct {
+	Lookup time.Duration
+	List   time.Duration
+	Report time.Duration
+	Export time.Duration
+}
+
+// DefaultQueryTimeouts are the timeouts used unless configured otherwise
+func DefaultQueryTimeouts() QueryTimeouts {
+	return QueryTimeouts{
+		Lookup: 5 * time.Second,
+		List:   10 * time.Second,
+		Report: 30 * time.Second,
+		Export: 2 * time.Minute,
+	}
+}
+
+// Bound returns ctx with the deadline of the query class. A deadline ctx
+// already has that is earlier is kept.
+func (t QueryTimeouts) Bound(ctx context.Context, class QueryClass) (context.Context, context.CancelFunc) {
+	var timeout time.Duration
+	switch class {
+	case QueryLookup:
+		timeout = t.Lookup
+	case QueryList:
+		timeout = t.List
+	case QueryReport:
+		timeout = t.Report
+	case QueryExport:
+		timeout = t.Export
+	}
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
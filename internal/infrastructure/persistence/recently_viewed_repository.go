@@ -0,0 +1,91 @@
+package persistence
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// RecentlyViewedRepository handles the products customers viewed recently
+type RecentlyViewedRepository struct {
+	db    *gorm.DB
+	limit int
+}
+
+// NewRecentlyViewedRepository creates a new recently viewed repository
+// keeping domain.DefaultRecentlyViewedLimit products per customer
+func NewRecentlyViewedRepository(db *gorm.DB) *RecentlyViewedRepository {
+	return &RecentlyViewedRepository{db: db, limit: domain.DefaultRecentlyViewedLimit}
+}
+
+// WithLimit sets how many products are kept per customer
+func (r *RecentlyViewedRepository) WithLimit(limit int) *RecentlyViewedRepository {
+	if limit > 0 {
+		r.limit = limit
+	}
+	return r
+}
+
+// Limit returns how many products are kept per customer
+func (r *RecentlyViewedRepository) Limit() int {
+	return r.limit
+}
+
+// Record moves the product to the front of the user's recently viewed
+// products and drops the oldest views past the limit
+func (r *RecentlyViewedRepository) Record(ctx context.Context, userID uuid.UUID, input domain.RecordViewInput, viewedAt time.Time) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		view := domain.RecentlyViewedProduct{
+			UserID:    userID,
+			ProductID: input.ProductID,
+			VariantID: input.VariantID,
+			ViewedAt:  viewedAt,
+		}
+		err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "user_id"}, {Name: "product_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"variant_id", "viewed_at"}),
+		}).Create(&view).Error
+		if err != nil {
+			return err
+		}
+
+		kept := tx.Model(&domain.RecentlyViewedProduct{}).
+			Select("id").
+			Where("user_id = ?", userID).
+			Order("viewed_at DESC").
+			Limit(r.limit)
+		return tx.Where("user_id = ? AND id NOT IN (?)", userID, kept).
+			Delete(&domain.RecentlyViewedProduct{}).Error
+	})
+}
+
+// List returns up to limit of the user's recently viewed products, most
+// recent first
+func (r *RecentlyViewedRepository) List(ctx context.Context, userID uuid.UUID, limit int) ([]domain.RecentlyViewedProduct, error) {
+	var views []domain.RecentlyViewedProduct
+	err := r.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("viewed_at DESC").
+		Limit(limit).
+		Find(&views).Error
+	return views, err
+}
+
+// Clear forgets all of the user's recently viewed products
+func (r *RecentlyViewedRepository) Clear(ctx context.Context, userID uuid.UUID) error {
+	return r.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Delete(&domain.RecentlyViewedProduct{}).Error
+}
+
+// DeleteOlderThan deletes views older than olderThanDays (cleanup)
+func (r *RecentlyViewedRepository) DeleteOlderThan(ctx context.Context, olderThanDays int) (int64, error) {
+	result := r.db.WithContext(ctx).
+		Where("viewed_at < ?", time.Now().AddDate(0, 0, -olderThanDays)).
+		Delete(&domain.RecentlyViewedProduct{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,68 @@
+package persistence
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecentlyViewedRepository_RecordKeepsLatestViews(t *testing.T) {
+	db := openTestDB(t, &domain.RecentlyViewedProduct{})
+	repo := NewRecentlyViewedRepository(db).WithLimit(3)
+	ctx := context.Background()
+	userID := uuid.New()
+	base := time.Now().Add(-time.Hour)
+
+	products := []uuid.UUID{uuid.New(), uuid.New(), uuid.New(), uuid.New()}
+	for i, productID := range products {
+		require.NoError(t, repo.Record(ctx, userID, domain.RecordViewInput{ProductID: productID}, base.Add(time.Duration(i)*time.Minute)))
+	}
+	require.NoError(t, repo.Record(ctx, uuid.New(), domain.RecordViewInput{ProductID: products[0]}, base))
+
+	views, err := repo.List(ctx, userID, 10)
+	require.NoError(t, err)
+	require.Len(t, views, 3, "the oldest view is dropped past the limit")
+	assert.Equal(t, []uuid.UUID{products[3], products[2], products[1]},
+		[]uuid.UUID{views[0].ProductID, views[1].ProductID, views[2].ProductID})
+
+	// Viewing a product again moves it to the front instead of adding it twice
+	variantID := uuid.New()
+	require.NoError(t, repo.Record(ctx, userID, domain.RecordViewInput{ProductID: products[1], VariantID: &variantID}, base.Add(time.Hour)))
+	views, err = repo.List(ctx, userID, 10)
+	require.NoError(t, err)
+	require.Len(t, views, 3)
+	assert.Equal(t, products[1], views[0].ProductID)
+	assert.Equal(t, &variantID, views[0].VariantID)
+
+	views, err = repo.List(ctx, userID, 2)
+	require.NoError(t, err)
+	assert.Len(t, views, 2)
+}
+
+func TestRecentlyViewedRepository_ClearAndCleanup(t *testing.T) {
+	db := openTestDB(t, &domain.RecentlyViewedProduct{})
+	repo := NewRecentlyViewedRepository(db)
+	ctx := context.Background()
+	userID, otherID := uuid.New(), uuid.New()
+
+	require.NoError(t, repo.Record(ctx, userID, domain.RecordViewInput{ProductID: uuid.New()}, time.Now().AddDate(0, 0, -100)))
+	require.NoError(t, repo.Record(ctx, userID, domain.RecordViewInput{ProductID: uuid.New()}, time.Now()))
+	require.NoError(t, repo.Record(ctx, otherID, domain.RecordViewInput{ProductID: uuid.New()}, time.Now()))
+
+	deleted, err := repo.DeleteOlderThan(ctx, 90)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), deleted)
+
+	require.NoError(t, repo.Clear(ctx, userID))
+	views, err := repo.List(ctx, userID, 10)
+	require.NoError(t, err)
+	assert.Empty(t, views)
+	views, err = repo.List(ctx, otherID, 10)
+	require.NoError(t, err)
+	assert.Len(t, views, 1, "other customers' views are kept")
+}
@@ -0,0 +1,27 @@
+package persistence
+
+import (
+	"gorm.io/gorm"
+	"gorm.io/plugin/dbresolver"
+)
+
+// replicaResolver names the resolver of the read replica; queries only reach
+// it through Replica, so everything else stays on the primary
+const replicaResolver = "read_replica"
+
+// UseReplica registers a read replica for the queries marked with Replica.
+// Writes in those queries still go to the primary.
+func UseReplica(db *gorm.DB, replica gorm.Dialector) error {
+	return db.Use(dbresolver.Register(dbresolver.Config{
+		Replicas: []gorm.Dialector{replica},
+	}, replicaResolver))
+}
+
+// Replica routes the reads of db to the read replica, or leaves them on the
+// primary when none is registered. It is for heavy admin queries — listings,
+// exports, stats and analytics — that tolerate replication lag; reads that
+// must see a write just made stay on the primary. Like WithContext, the
+// result can be reused for several queries.
+func Replica(db *gorm.DB) *gorm.DB {
+	return db.Clauses(dbresolver.Use(replicaResolver)).Session(&gorm.Session{})
+}
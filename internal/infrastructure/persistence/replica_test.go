@@ -0,0 +1,46 @@
+package persistence
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+)
+
+func TestReplica_RoutesMarkedReads(t *testing.T) {
+	ctx := context.Background()
+	primary := openTestDB(t, &domain.Customer{})
+	replica := openTestDB(t, &domain.Customer{})
+	replicaSQL, err := replica.DB()
+	require.NoError(t, err)
+
+	repo := NewCustomerRepository(primary, DefaultQueryTimeouts())
+	filter := domain.CustomerListFilter{Page: 1, Limit: 10}
+
+	// Without a replica the marked reads go to the primary
+	existing := &domain.Customer{Email: "primary@example.com"}
+	require.NoError(t, primary.Create(existing).Error)
+	customers, total, err := repo.ListAdmin(ctx, filter)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+	require.Len(t, customers, 1)
+
+	// With one, listings read from it and lookups and writes stay on the primary
+	require.NoError(t, UseReplica(primary, sqlite.Dialector{Conn: replicaSQL}))
+	require.NoError(t, replica.Create(&domain.Customer{Email: "replica@example.com"}).Error)
+	customers, _, err = repo.ListAdmin(ctx, filter)
+	require.NoError(t, err)
+	require.Len(t, customers, 1)
+	assert.Equal(t, "replica@example.com", customers[0].Email)
+
+	_, err = repo.GetByID(ctx, existing.ID)
+	assert.NoError(t, err)
+	_, err = repo.Create(ctx, &domain.CreateCustomerRequest{Email: "new@example.com"}, nil)
+	require.NoError(t, err)
+	var count int64
+	require.NoError(t, replica.Model(&domain.Customer{}).Count(&count).Error)
+	assert.Equal(t, int64(1), count)
+}
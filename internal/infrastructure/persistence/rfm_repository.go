@@ -0,0 +1,66 @@
+package persistence
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"gorm.io/gorm"
+)
+
+// RFMRepository stores customers' RFM scores and lifetime value
+type RFMRepository struct {
+	db *gorm.DB
+}
+
+// NewRFMRepository creates a new RFM repository
+func NewRFMRepository(db *gorm.DB) *RFMRepository {
+	return &RFMRepository{db: db}
+}
+
+// Score scores up to limit customers after the given customer ID at now and
+// stores the scores on each. It returns the last customer ID handled, or
+// uuid.Nil once every customer has been handled, the number of customers
+// scored and those whose RFM segment changed.
+func (r *RFMRepository) Score(ctx context.Context, after uuid.UUID, limit int, thresholds domain.RFMThresholds, now time.Time) (uuid.UUID, int, []uuid.UUID, error) {
+	db := r.db.WithContext(ctx)
+
+	var customers []domain.Customer
+	if err := db.Select("id", "total_orders", "total_spent", "first_order_at", "last_order_at",
+		"recency_score", "frequency_score", "monetary_score", "rfm_segment", "lifetime_value").
+		Where("id > ?", after).
+		Order("id").
+		Limit(limit).
+		Find(&customers).Error; err != nil {
+		return uuid.Nil, 0, nil, err
+	}
+	if len(customers) == 0 {
+		return uuid.Nil, 0, nil, nil
+	}
+
+	var changed []uuid.UUID
+	for i := range customers {
+		c := &customers[i]
+		scores := thresholds.Score(c, now)
+		if c.RFMSegment != scores.Segment {
+			changed = append(changed, c.ID)
+		}
+
+		// Scores are derived from the order totals, so storing them doesn't
+		// bump the version and conflict with an admin editing the customer
+		if err := db.Model(&domain.Customer{}).
+			Where("id = ?", c.ID).
+			UpdateColumns(map[string]interface{}{
+				"recency_score":   scores.Recency,
+				"frequency_score": scores.Frequency,
+				"monetary_score":  scores.Monetary,
+				"rfm_segment":     scores.Segment,
+				"lifetime_value":  scores.LifetimeValue,
+				"scored_at":       now,
+			}).Error; err != nil {
+			return uuid.Nil, i, changed, err
+		}
+	}
+	return customers[len(customers)-1].ID, len(customers), changed, nil
+}
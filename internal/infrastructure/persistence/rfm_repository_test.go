@@ -0,0 +1,88 @@
+package persistence
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRFMRepository_Score(t *testing.T) {
+	db := openTestDB(t, &domain.Customer{})
+	repo := NewRFMRepository(db)
+	ctx := context.Background()
+
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	daysAgo := func(days int) *time.Time {
+		at := now.AddDate(0, 0, -days)
+		return &at
+	}
+	champion := domain.Customer{Email: "champion@example.com", TotalOrders: 12, TotalSpent: 6000,
+		FirstOrderAt: daysAgo(730), LastOrderAt: daysAgo(10), Version: 1}
+	newcomer := domain.Customer{Email: "new@example.com", TotalOrders: 1, TotalSpent: 150,
+		FirstOrderAt: daysAgo(5), LastOrderAt: daysAgo(5), Version: 1}
+	lapsed := domain.Customer{Email: "lapsed@example.com", TotalOrders: 4, TotalSpent: 1200,
+		FirstOrderAt: daysAgo(500), LastOrderAt: daysAgo(200), Version: 1}
+	browser := domain.Customer{Email: "browser@example.com", Version: 1}
+	for _, c := range []*domain.Customer{&champion, &newcomer, &lapsed, &browser} {
+		require.NoError(t, db.Create(c).Error)
+	}
+
+	score := func() []uuid.UUID {
+		t.Helper()
+		var changed []uuid.UUID
+		after := uuid.Nil
+		for pages := 0; ; pages++ {
+			require.Less(t, pages, 3)
+			next, _, page, err := repo.Score(ctx, after, 3, domain.DefaultRFMThresholds, now)
+			require.NoError(t, err)
+			changed = append(changed, page...)
+			if next == uuid.Nil {
+				return changed
+			}
+			after = next
+		}
+	}
+	assert.ElementsMatch(t, []uuid.UUID{champion.ID, newcomer.ID, lapsed.ID}, score())
+
+	stored := func(id uuid.UUID) domain.Customer {
+		var customer domain.Customer
+		require.NoError(t, db.First(&customer, "id = ?", id).Error)
+		return customer
+	}
+	c := stored(champion.ID)
+	assert.Equal(t, domain.RFMSegmentChampions, c.RFMSegment)
+	assert.Equal(t, []int{5, 5, 5}, []int{c.RecencyScore, c.FrequencyScore, c.MonetaryScore})
+	assert.InDelta(t, 15000.0, c.LifetimeValue, 0.01)
+	assert.Equal(t, int64(1), c.Version)
+	require.NotNil(t, c.ScoredAt)
+
+	c = stored(newcomer.ID)
+	assert.Equal(t, domain.RFMSegmentNew, c.RFMSegment)
+	assert.InDelta(t, 600.0, c.LifetimeValue, 0.01)
+
+	c = stored(lapsed.ID)
+	assert.Equal(t, domain.RFMSegmentAtRisk, c.RFMSegment)
+	assert.Equal(t, []int{2, 3, 3}, []int{c.RecencyScore, c.FrequencyScore, c.MonetaryScore})
+
+	c = stored(browser.ID)
+	assert.Empty(t, c.RFMSegment)
+	assert.Zero(t, c.RecencyScore)
+
+	// Scoring again changes no segments
+	assert.Empty(t, score())
+
+	// The scores can be filtered on in the admin customer list
+	customers := NewCustomerRepository(db, DefaultQueryTimeouts())
+	clvMin := 500.0
+	list, _, err := customers.ListAdmin(ctx, domain.CustomerListFilter{Page: 1, Limit: 10,
+		RFMSegments: []string{domain.RFMSegmentNew, domain.RFMSegmentAtRisk}, CLVMin: &clvMin})
+	require.NoError(t, err)
+	require.Len(t, list, 2)
+	emails := []string{list[0].Email, list[1].Email}
+	assert.ElementsMatch(t, []string{"new@example.com", "lapsed@example.com"}, emails)
+}
@@ -0,0 +1,101 @@
+package persistence
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"gorm.io/gorm"
+)
+
+// SavedSearchRepository handles customers' saved catalog searches
+type SavedSearchRepository struct {
+	db *gorm.DB
+}
+
+// NewSavedSearchRepository creates a new saved search repository
+func NewSavedSearchRepository(db *gorm.DB) *SavedSearchRepository {
+	return &SavedSearchRepository{db: db}
+}
+
+// List returns the customer's saved searches, newest first
+func (r *SavedSearchRepository) List(ctx context.Context, userID uuid.UUID) ([]domain.SavedSearch, error) {
+	var searches []domain.SavedSearch
+	err := r.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("created_at DESC").
+		Find(&searches).Error
+	return searches, err
+}
+
+// Get returns one of the customer's saved searches
+func (r *SavedSearchRepository) Get(ctx context.Context, id, userID uuid.UUID) (*domain.SavedSearch, error) {
+	var search domain.SavedSearch
+	err := r.db.WithContext(ctx).
+		Where("id = ? AND user_id = ?", id, userID).
+		First(&search).Error
+	if err != nil {
+		return nil, err
+	}
+	return &search, nil
+}
+
+// Create saves a new search, enforcing the per-customer limit
+func (r *SavedSearchRepository) Create(ctx context.Context, search *domain.SavedSearch) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var saved int64
+		if err := tx.Model(&domain.SavedSearch{}).
+			Where("user_id = ?", search.UserID).
+			Count(&saved).Error; err != nil {
+			return err
+		}
+		if saved >= domain.MaxSavedSearches {
+			return domain.ErrSavedSearchLimit
+		}
+		return tx.Create(search).Error
+	})
+}
+
+// Update saves changes to an existing search
+func (r *SavedSearchRepository) Update(ctx context.Context, search *domain.SavedSearch) error {
+	return r.db.WithContext(ctx).Save(search).Error
+}
+
+// Delete removes one of the customer's saved searches
+func (r *SavedSearchRepository) Delete(ctx context.Context, id, userID uuid.UUID) error {
+	result := r.db.WithContext(ctx).Delete(&domain.SavedSearch{}, "id = ? AND user_id = ?", id, userID)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// ListAlertable returns up to limit searches with alerts enabled that have
+// not alerted since notifiedBefore, in ID order after the given ID. Matching
+// against a product is done by the caller, as query terms can't be matched
+// in SQL.
+func (r *SavedSearchRepository) ListAlertable(ctx context.Context, after uuid.UUID, limit int, notifiedBefore time.Time) ([]domain.SavedSearch, error) {
+	var searches []domain.SavedSearch
+	err := r.db.WithContext(ctx).
+		Where("alerts_enabled = ? AND (last_notified_at IS NULL OR last_notified_at < ?)", true, notifiedBefore).
+		Where("id > ?", after).
+		Order("id ASC").
+		Limit(limit).
+		Find(&searches).Error
+	return searches, err
+}
+
+// MarkNotified records that the searches have just alerted their customers
+func (r *SavedSearchRepository) MarkNotified(ctx context.Context, ids []uuid.UUID, now time.Time) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).
+		Model(&domain.SavedSearch{}).
+		Where("id IN ?", ids).
+		Update("last_notified_at", now).Error
+}
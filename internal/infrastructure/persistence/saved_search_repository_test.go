@@ -0,0 +1,105 @@
+package persistence
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestSavedSearchRepository(t *testing.T) {
+	db := openTestDB(t, &domain.SavedSearch{})
+	repo := NewSavedSearchRepository(db)
+	ctx := context.Background()
+	alice, bob := uuid.New(), uuid.New()
+
+	maxPrice := 150.0
+	search := &domain.SavedSearch{
+		UserID:        alice,
+		Name:          "Baju kurung",
+		Query:         "baju kurung",
+		Filters:       domain.SavedSearchFilters{Categories: []string{"women"}, MaxPrice: &maxPrice},
+		AlertsEnabled: true,
+	}
+	require.NoError(t, repo.Create(ctx, search))
+
+	searches, err := repo.List(ctx, alice)
+	require.NoError(t, err)
+	require.Len(t, searches, 1)
+	assert.Equal(t, []string{"women"}, searches[0].Filters.Categories)
+	assert.Equal(t, 150.0, *searches[0].Filters.MaxPrice)
+
+	_, err = repo.Get(ctx, search.ID, bob)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound, "searches are only visible to their owner")
+	assert.ErrorIs(t, repo.Delete(ctx, search.ID, bob), gorm.ErrRecordNotFound)
+
+	search.Query = "baju kurung moden"
+	require.NoError(t, repo.Update(ctx, search))
+	got, err := repo.Get(ctx, search.ID, alice)
+	require.NoError(t, err)
+	assert.Equal(t, "baju kurung moden", got.Query)
+
+	require.NoError(t, repo.Delete(ctx, search.ID, alice))
+	searches, err = repo.List(ctx, alice)
+	require.NoError(t, err)
+	assert.Empty(t, searches)
+}
+
+func TestSavedSearchRepository_Limit(t *testing.T) {
+	db := openTestDB(t, &domain.SavedSearch{})
+	repo := NewSavedSearchRepository(db)
+	ctx := context.Background()
+	userID := uuid.New()
+
+	for i := 0; i < domain.MaxSavedSearches; i++ {
+		require.NoError(t, repo.Create(ctx, &domain.SavedSearch{UserID: userID, Name: "s", Query: "q"}))
+	}
+	err := repo.Create(ctx, &domain.SavedSearch{UserID: userID, Name: "s", Query: "q"})
+	assert.ErrorIs(t, err, domain.ErrSavedSearchLimit)
+	require.NoError(t, repo.Create(ctx, &domain.SavedSearch{UserID: uuid.New(), Name: "s", Query: "q"}),
+		"the limit is per customer")
+}
+
+func TestSavedSearchRepository_ListAlertable(t *testing.T) {
+	db := openTestDB(t, &domain.SavedSearch{})
+	repo := NewSavedSearchRepository(db)
+	ctx := context.Background()
+	now := time.Now()
+	recently, longAgo := now.Add(-time.Hour), now.Add(-48*time.Hour)
+
+	fresh := &domain.SavedSearch{UserID: uuid.New(), Name: "a", Query: "a", AlertsEnabled: true}
+	cooled := &domain.SavedSearch{UserID: uuid.New(), Name: "b", Query: "b", AlertsEnabled: true, LastNotifiedAt: &longAgo}
+	cooling := &domain.SavedSearch{UserID: uuid.New(), Name: "c", Query: "c", AlertsEnabled: true, LastNotifiedAt: &recently}
+	muted := &domain.SavedSearch{UserID: uuid.New(), Name: "d", Query: "d", AlertsEnabled: true}
+	for _, s := range []*domain.SavedSearch{fresh, cooled, cooling, muted} {
+		require.NoError(t, repo.Create(ctx, s))
+	}
+	// AlertsEnabled defaults to true, so switching it off needs an update
+	muted.AlertsEnabled = false
+	require.NoError(t, repo.Update(ctx, muted))
+
+	notifiedBefore := now.Add(-domain.SavedSearchAlertCooldown)
+	var ids []uuid.UUID
+	after := uuid.Nil
+	for {
+		batch, err := repo.ListAlertable(ctx, after, 1, notifiedBefore)
+		require.NoError(t, err)
+		if len(batch) == 0 {
+			break
+		}
+		ids = append(ids, batch[0].ID)
+		after = batch[0].ID
+	}
+	assert.ElementsMatch(t, []uuid.UUID{fresh.ID, cooled.ID}, ids)
+
+	require.NoError(t, repo.MarkNotified(ctx, []uuid.UUID{fresh.ID}, now))
+	batch, err := repo.ListAlertable(ctx, uuid.Nil, 10, notifiedBefore)
+	require.NoError(t, err)
+	require.Len(t, batch, 1)
+	assert.Equal(t, cooled.ID, batch[0].ID, "a search cools down once it has alerted")
+}
@@ -0,0 +1,190 @@
+package persistence
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ScheduledExportRepository stores scheduled exports and their run history
+type ScheduledExportRepository struct {
+	db *gorm.DB
+}
+
+// NewScheduledExportRepository creates a new scheduled export repository
+func NewScheduledExportRepository(db *gorm.DB) *ScheduledExportRepository {
+	return &ScheduledExportRepository{db: db}
+}
+
+// List returns a page of scheduled exports in the given order; the zero
+// Sort is oldest first
+func (r *ScheduledExportRepository) List(ctx context.Context, sort domain.Sort, page, limit int) ([]domain.ScheduledExport, int64, error) {
+	column, desc, err := domain.ScheduledExportSort.Resolve(sort)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var exports []domain.ScheduledExport
+	var total int64
+
+	query := r.db.WithContext(ctx).Model(&domain.ScheduledExport{})
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err = query.Order(clause.OrderByColumn{Column: clause.Column{Name: column}, Desc: desc}).
+		Order("id").
+		Offset((page - 1) * limit).
+		Limit(limit).
+		Find(&exports).Error
+	return exports, total, err
+}
+
+// Get retrieves a scheduled export
+func (r *ScheduledExportRepository) Get(ctx context.Context, id uuid.UUID) (*domain.ScheduledExport, error) {
+	var export domain.ScheduledExport
+	if err := r.db.WithContext(ctx).Where("id = ?", id).First(&export).Error; err != nil {
+		return nil, err
+	}
+	return &export, nil
+}
+
+// Create creates a scheduled export
+func (r *ScheduledExportRepository) Create(ctx context.Context, export *domain.ScheduledExport) error {
+	return r.db.WithContext(ctx).Create(export).Error
+}
+
+// Update saves a scheduled export's definition and next run. Its run state
+// is left alone.
+func (r *ScheduledExportRepository) Update(ctx context.Context, export *domain.ScheduledExport) error {
+	result := r.db.WithContext(ctx).
+		Select("name", "filter", "format", "columns", "schedule", "target_type", "target_recipients",
+			"target_bucket", "target_region", "target_endpoint", "target_access_key", "target_host",
+			"target_username", "target_host_key", "target_path", "target_secret", "target_private_key",
+			"alert_emails", "region", "mask_pii", "is_active", "next_run_at").
+		Updates(export)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// Delete removes a scheduled export with its run history
+func (r *ScheduledExportRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("id = ?", id).Delete(&domain.ScheduledExport{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return tx.Where("export_id = ?", id).Delete(&domain.ScheduledExportRun{}).Error
+	})
+}
+
+// Due returns active exports whose next run is at or before now, most
+// overdue first
+func (r *ScheduledExportRepository) Due(ctx context.Context, now time.Time, limit int) ([]domain.ScheduledExport, error) {
+	var exports []domain.ScheduledExport
+	err := r.db.WithContext(ctx).
+		Where("is_active = ? AND next_run_at <= ?", true, now).
+		Order("next_run_at ASC").
+		Limit(limit).
+		Find(&exports).Error
+	return exports, err
+}
+
+// Claim moves a due export's next run on to next, so it isn't run twice when
+// a run outlasts the scheduler tick. It reports false when the export
+// changed since it was read.
+func (r *ScheduledExportRepository) Claim(ctx context.Context, export *domain.ScheduledExport, next time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Model(&domain.ScheduledExport{}).
+		Where("id = ? AND next_run_at = ?", export.ID, export.NextRunAt).
+		Update("next_run_at", next)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected != 1 {
+		return false, nil
+	}
+	export.NextRunAt = &next
+	return true, nil
+}
+
+// StartRun records the start of a run
+func (r *ScheduledExportRepository) StartRun(ctx context.Context, export *domain.ScheduledExport, trigger string, now time.Time) (*domain.ScheduledExportRun, error) {
+	run := &domain.ScheduledExportRun{
+		ExportID:  export.ID,
+		Trigger:   trigger,
+		Status:    domain.ExportRunRunning,
+		StartedAt: now,
+	}
+	if err := r.db.WithContext(ctx).Create(run).Error; err != nil {
+		return nil, err
+	}
+	return run, nil
+}
+
+// FinishRun saves the outcome of a run and the export's last run state.
+// Failures are counted until a run succeeds again; the export's updated
+// failure count is set on it.
+func (r *ScheduledExportRepository) FinishRun(ctx context.Context, export *domain.ScheduledExport, run *domain.ScheduledExportRun) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(run).
+			Select("status", "rows", "bytes", "location", "error", "finished_at").
+			Updates(run).Error; err != nil {
+			return err
+		}
+
+		failures := gorm.Expr("consecutive_failures + 1")
+		if run.Status == domain.ExportRunSucceeded {
+			failures = gorm.Expr("0")
+		}
+		if err := tx.Model(&domain.ScheduledExport{}).
+			Where("id = ?", export.ID).
+			Updates(map[string]interface{}{
+				"last_run_at":          run.StartedAt,
+				"last_status":          run.Status,
+				"consecutive_failures": failures,
+			}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&domain.ScheduledExport{}).
+			Where("id = ?", export.ID).
+			Select("last_run_at", "last_status", "consecutive_failures").
+			Take(export).Error
+	})
+}
+
+// ListRuns returns a page of an export's runs in the given order; the zero
+// Sort is newest first
+func (r *ScheduledExportRepository) ListRuns(ctx context.Context, exportID uuid.UUID, sort domain.Sort, page, limit int) ([]domain.ScheduledExportRun, int64, error) {
+	column, desc, err := domain.ScheduledExportRunSort.Resolve(sort)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var runs []domain.ScheduledExportRun
+	var total int64
+
+	query := r.db.WithContext(ctx).Model(&domain.ScheduledExportRun{}).Where("export_id = ?", exportID)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err = query.Order(clause.OrderByColumn{Column: clause.Column{Name: column}, Desc: desc}).
+		Order("id").
+		Offset((page - 1) * limit).
+		Limit(limit).
+		Find(&runs).Error
+	return runs, total, err
+}
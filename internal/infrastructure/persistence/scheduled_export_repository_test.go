@@ -0,0 +1,122 @@
+package persistence
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestScheduledExportRepository_DueAndClaim(t *testing.T) {
+	db := openTestDB(t, &domain.ScheduledExport{}, &domain.ScheduledExportRun{})
+	repo := NewScheduledExportRepository(db)
+	ctx := context.Background()
+	now := time.Date(2025, 6, 2, 8, 0, 0, 0, time.UTC)
+
+	newExport := func(name string, active bool, next time.Time) *domain.ScheduledExport {
+		t.Helper()
+		export := &domain.ScheduledExport{Name: name, Format: "csv", Schedule: "0 8 * * 1", IsActive: true,
+			Target: domain.ExportTarget{Type: domain.ExportTargetEmail, Recipients: []string{"ops@example.com"}}, NextRunAt: &next}
+		require.NoError(t, repo.Create(ctx, export))
+		if !active {
+			require.NoError(t, db.Model(export).Update("is_active", false).Error)
+		}
+		return export
+	}
+	weekly := newExport("Weekly VIPs", true, now.Add(-time.Minute))
+	newExport("Paused", false, now.Add(-time.Hour))
+	newExport("Later", true, now.Add(time.Hour))
+
+	due, err := repo.Due(ctx, now, 10)
+	require.NoError(t, err)
+	require.Len(t, due, 1)
+	assert.Equal(t, weekly.ID, due[0].ID)
+
+	stale := due[0]
+	next := now.Add(7 * 24 * time.Hour)
+	claimed, err := repo.Claim(ctx, &due[0], next)
+	require.NoError(t, err)
+	assert.True(t, claimed)
+	claimed, err = repo.Claim(ctx, &stale, next)
+	require.NoError(t, err)
+	assert.False(t, claimed, "a second replica must not claim the same run")
+
+	due, err = repo.Due(ctx, now, 10)
+	require.NoError(t, err)
+	assert.Empty(t, due)
+}
+
+func TestScheduledExportRepository_Runs(t *testing.T) {
+	db := openTestDB(t, &domain.ScheduledExport{}, &domain.ScheduledExportRun{})
+	repo := NewScheduledExportRepository(db)
+	ctx := context.Background()
+	now := time.Date(2025, 6, 2, 8, 0, 0, 0, time.UTC)
+
+	export := &domain.ScheduledExport{Name: "Nightly", Format: "csv", Schedule: "@daily", IsActive: true,
+		Target: domain.ExportTarget{Type: domain.ExportTargetSFTP, Host: "sftp.example.com:22", Username: "crm", Secret: "s3cret"}}
+	require.NoError(t, repo.Create(ctx, export))
+
+	finish := func(at time.Time, status string) {
+		t.Helper()
+		run, err := repo.StartRun(ctx, export, domain.ExportRunScheduled, at)
+		require.NoError(t, err)
+		assert.Equal(t, domain.ExportRunRunning, run.Status)
+		finished := at.Add(time.Minute)
+		run.Status, run.FinishedAt = status, &finished
+		if status == domain.ExportRunFailed {
+			run.Error = "connection refused"
+		}
+		require.NoError(t, repo.FinishRun(ctx, export, run))
+	}
+	finish(now, domain.ExportRunFailed)
+	finish(now.Add(24*time.Hour), domain.ExportRunFailed)
+	assert.Equal(t, 2, export.ConsecutiveFailures)
+	assert.Equal(t, domain.ExportRunFailed, export.LastStatus)
+
+	finish(now.Add(48*time.Hour), domain.ExportRunSucceeded)
+	assert.Equal(t, 0, export.ConsecutiveFailures)
+	assert.Equal(t, domain.ExportRunSucceeded, export.LastStatus)
+	require.NotNil(t, export.LastRunAt)
+	assert.True(t, now.Add(48*time.Hour).Equal(*export.LastRunAt))
+
+	runs, total, err := repo.ListRuns(ctx, export.ID, domain.Sort{}, 1, 2)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), total)
+	require.Len(t, runs, 2)
+	assert.Equal(t, domain.ExportRunSucceeded, runs[0].Status)
+	assert.Equal(t, "connection refused", runs[1].Error)
+
+	byStatus, err := domain.ScheduledExportRunSort.Parse("status", "desc")
+	require.NoError(t, err)
+	runs, _, err = repo.ListRuns(ctx, export.ID, byStatus, 1, 10)
+	require.NoError(t, err)
+	require.Len(t, runs, 3)
+	assert.Equal(t, domain.ExportRunSucceeded, runs[0].Status)
+	assert.Equal(t, domain.ExportRunFailed, runs[2].Status)
+
+	export.Name = "Nightly CRM feed"
+	require.NoError(t, repo.Update(ctx, export))
+	saved, err := repo.Get(ctx, export.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Nightly CRM feed", saved.Name)
+	assert.Equal(t, "s3cret", saved.Target.Secret)
+	assert.Equal(t, domain.ExportRunSucceeded, saved.LastStatus)
+
+	byName, err := domain.ScheduledExportSort.Parse("name", "")
+	require.NoError(t, err)
+	exports, total, err := repo.List(ctx, byName, 1, 10)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+	require.Len(t, exports, 1)
+	assert.Equal(t, "Nightly CRM feed", exports[0].Name)
+
+	require.NoError(t, repo.Delete(ctx, export.ID))
+	_, total, err = repo.ListRuns(ctx, export.ID, domain.Sort{}, 1, 10)
+	require.NoError(t, err)
+	assert.Zero(t, total)
+	assert.ErrorIs(t, repo.Delete(ctx, export.ID), gorm.ErrRecordNotFound)
+}
@@ -0,0 +1,232 @@
+package persistence
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"gorm.io/gorm"
+)
+
+// SegmentBulkRepository assigns a segment to, or removes it from, many
+// customers at once and tracks the progress of each bulk change
+type SegmentBulkRepository struct {
+	db        *gorm.DB
+	batchSize int
+	webhooks  bool
+	marketing *MarketingSyncRepository
+}
+
+// NewSegmentBulkRepository creates a bulk segment repository changing
+// domain.BulkSegmentBatchSize customers per transaction
+func NewSegmentBulkRepository(db *gorm.DB) *SegmentBulkRepository {
+	return &SegmentBulkRepository{db: db, batchSize: domain.BulkSegmentBatchSize}
+}
+
+// WithBatchSize sets the number of customers changed per transaction
+func (r *SegmentBulkRepository) WithBatchSize(n int) *SegmentBulkRepository {
+	r.batchSize = n
+	return r
+}
+
+// WithWebhooks makes Run queue a customer.segment_changed webhook event for
+// every customer whose segments it changes
+func (r *SegmentBulkRepository) WithWebhooks() *SegmentBulkRepository {
+	r.webhooks = true
+	return r
+}
+
+// WithMarketingSync makes Run queue the customers whose segments it changes
+// for the marketing platforms
+func (r *SegmentBulkRepository) WithMarketingSync(marketing *MarketingSyncRepository) *SegmentBulkRepository {
+	r.marketing = marketing
+	return r
+}
+
+// GetSegment retrieves a segment
+func (r *SegmentBulkRepository) GetSegment(ctx context.Context, id uuid.UUID) (*domain.CustomerSegment, error) {
+	var segment domain.CustomerSegment
+	if err := r.db.WithContext(ctx).First(&segment, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &segment, nil
+}
+
+// CustomerIDsByEmail returns the IDs of the customers with the emails,
+// compared case-insensitively. Emails without a customer are left out.
+func (r *SegmentBulkRepository) CustomerIDsByEmail(ctx context.Context, emails []string) ([]uuid.UUID, error) {
+	ids := make([]uuid.UUID, 0, len(emails))
+	for start := 0; start < len(emails); start += r.batchSize {
+		var batch []uuid.UUID
+		if err := r.db.WithContext(ctx).Model(&domain.Customer{}).
+			Where("LOWER(email) IN ?", emails[start:min(start+r.batchSize, len(emails))]).
+			Pluck("id", &batch).Error; err != nil {
+			return nil, err
+		}
+		ids = append(ids, batch...)
+	}
+	return ids, nil
+}
+
+// CreateJob records a bulk change as pending
+func (r *SegmentBulkRepository) CreateJob(ctx context.Context, job *domain.SegmentBulkJob) error {
+	job.Status = domain.BulkSegmentPending
+	return r.db.WithContext(ctx).Create(job).Error
+}
+
+// GetJob retrieves a bulk change of the segment
+func (r *SegmentBulkRepository) GetJob(ctx context.Context, segmentID, jobID uuid.UUID) (*domain.SegmentBulkJob, error) {
+	var job domain.SegmentBulkJob
+	if err := r.db.WithContext(ctx).
+		Where("id = ? AND segment_id = ?", jobID, segmentID).
+		First(&job).Error; err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// Run applies a recorded bulk change to the customers, a batch per
+// transaction. Each batch's progress is saved with its changes, so the job
+// always reflects what has been applied; a failed batch is rolled back and
+// fails the job, leaving the batches before it applied. Run stops between
+// batches when ctx is done.
+func (r *SegmentBulkRepository) Run(ctx context.Context, job *domain.SegmentBulkJob, customerIDs []uuid.UUID) error {
+	db := r.db.WithContext(ctx)
+	job.Status = domain.BulkSegmentRunning
+	err := db.Model(job).Update("status", job.Status).Error
+	for start := 0; start < len(customerIDs) && err == nil; start += r.batchSize {
+		if err = ctx.Err(); err != nil {
+			break
+		}
+		batch := customerIDs[start:min(start+r.batchSize, len(customerIDs))]
+		err = db.Transaction(func(tx *gorm.DB) error {
+			progress := *job
+			if err := r.apply(tx, &progress, batch); err != nil {
+				return err
+			}
+			if err := tx.Model(job).Updates(map[string]interface{}{
+				"processed": progress.Processed,
+				"changed":   progress.Changed,
+				"unchanged": progress.Unchanged,
+				"not_found": progress.NotFound,
+			}).Error; err != nil {
+				return err
+			}
+			*job = progress
+			return nil
+		})
+	}
+
+	now := time.Now()
+	job.CompletedAt = &now
+	job.Status = domain.BulkSegmentCompleted
+	if err != nil {
+		job.Status = domain.BulkSegmentFailed
+		job.Error = err.Error()
+	}
+	// The outcome is saved even when ctx was cancelled
+	if saveErr := r.db.WithContext(context.WithoutCancel(ctx)).Model(job).Updates(map[string]interface{}{
+		"status":       job.Status,
+		"error":        job.Error,
+		"completed_at": job.CompletedAt,
+	}).Error; err == nil {
+		err = saveErr
+	}
+	return err
+}
+
+// apply changes the segment of one batch of customers, records their
+// membership events and counts the outcome on job
+func (r *SegmentBulkRepository) apply(tx *gorm.DB, job *domain.SegmentBulkJob, batch []uuid.UUID) error {
+	var found []uuid.UUID
+	if err := tx.Model(&domain.Customer{}).Where("id IN ?", batch).Pluck("id", &found).Error; err != nil {
+		return err
+	}
+	var assigned []uuid.UUID
+	if err := tx.Model(&domain.CustomerSegmentAssignment{}).
+		Where("segment_id = ? AND customer_id IN ?", job.SegmentID, found).
+		Pluck("customer_id", &assigned).Error; err != nil {
+		return err
+	}
+
+	var changed []uuid.UUID
+	if job.Action == domain.BulkSegmentAssign {
+		isAssigned := make(map[uuid.UUID]bool, len(assigned))
+		for _, id := range assigned {
+			isAssigned[id] = true
+		}
+		assignments := make([]domain.CustomerSegmentAssignment, 0, len(found))
+		for _, id := range found {
+			if isAssigned[id] {
+				continue
+			}
+			isAssigned[id] = true
+			assignments = append(assignments, domain.CustomerSegmentAssignment{CustomerID: id, SegmentID: job.SegmentID})
+			changed = append(changed, id)
+		}
+		if len(assignments) > 0 {
+			if err := tx.Create(&assignments).Error; err != nil {
+				return err
+			}
+		}
+	} else if len(assigned) > 0 {
+		if err := tx.Where("segment_id = ? AND customer_id IN ?", job.SegmentID, assigned).
+			Delete(&domain.CustomerSegmentAssignment{}).Error; err != nil {
+			return err
+		}
+		changed = assigned
+	}
+
+	change := domain.SegmentMembershipEntered
+	if job.Action == domain.BulkSegmentRemove {
+		change = domain.SegmentMembershipLeft
+	}
+	now := time.Now()
+	events := make([]domain.SegmentMembershipEvent, 0, len(changed))
+	for _, customerID := range changed {
+		events = append(events, domain.SegmentMembershipEvent{
+			CustomerID: customerID, SegmentID: job.SegmentID,
+			Change: change, Source: domain.SegmentSourceBulk,
+			JobID: &job.ID, ActorID: job.CreatedBy, OccurredAt: now,
+		})
+	}
+	if err := recordSegmentMembership(tx, events); err != nil {
+		return err
+	}
+
+	job.Processed += len(batch)
+	job.NotFound += len(batch) - len(found)
+	job.Changed += len(changed)
+	job.Unchanged += len(found) - len(changed)
+	return r.notify(tx, changed, now)
+}
+
+// notify queues the marketing sync and webhook events of the customers whose
+// segments changed
+func (r *SegmentBulkRepository) notify(tx *gorm.DB, changed []uuid.UUID, now time.Time) error {
+	if len(changed) == 0 {
+		return nil
+	}
+	if r.marketing != nil {
+		if err := queueMarketingSync(tx, r.marketing.Providers(), changed, now); err != nil {
+			return err
+		}
+	}
+	if !r.webhooks {
+		return nil
+	}
+	for _, customerID := range changed {
+		segmentIDs, err := customerSegmentIDs(tx, customerID)
+		if err != nil {
+			return err
+		}
+		if err := enqueueWebhookEvent(tx, domain.WebhookEventCustomerSegmentChanged, domain.CustomerSegmentsChanged{
+			CustomerID: customerID,
+			SegmentIDs: segmentIDs,
+		}, now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
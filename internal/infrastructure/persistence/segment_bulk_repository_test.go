@@ -0,0 +1,99 @@
+package persistence
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSegmentBulkRepository_Run(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t,
+		&domain.Customer{},
+		&domain.CustomerSegment{},
+		&domain.CustomerSegmentAssignment{},
+		&domain.SegmentBulkJob{},
+		&domain.SegmentMembershipEvent{},
+		&domain.MarketingConsent{},
+		&domain.MarketingSyncState{},
+	)
+	repo := NewSegmentBulkRepository(db).WithBatchSize(2).
+		WithMarketingSync(NewMarketingSyncRepository(db, []string{"klaviyo"}))
+
+	vip := &domain.CustomerSegment{Name: "VIP", IsActive: true}
+	require.NoError(t, db.Create(vip).Error)
+	var ids []uuid.UUID
+	for _, email := range []string{"a@example.com", "B@example.com", "c@example.com"} {
+		customer := &domain.Customer{Email: email}
+		require.NoError(t, db.Create(customer).Error)
+		ids = append(ids, customer.ID)
+	}
+	require.NoError(t, db.Create(&domain.CustomerSegmentAssignment{CustomerID: ids[0], SegmentID: vip.ID}).Error)
+	require.NoError(t, db.Create(&domain.MarketingConsent{CustomerID: ids[1]}).Error)
+
+	found, err := repo.CustomerIDsByEmail(ctx, []string{"b@example.com", "c@example.com", "nobody@example.com"})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, ids[1:], found)
+
+	// Assigning skips customers already in the segment and unknown IDs
+	job := &domain.SegmentBulkJob{SegmentID: vip.ID, Action: domain.BulkSegmentAssign, Source: domain.BulkSegmentByIDs}
+	customerIDs := append(ids, uuid.New())
+	job.Total = len(customerIDs)
+	require.NoError(t, repo.CreateJob(ctx, job))
+	require.NoError(t, repo.Run(ctx, job, customerIDs))
+
+	saved, err := repo.GetJob(ctx, vip.ID, job.ID)
+	require.NoError(t, err)
+	assert.Equal(t, domain.BulkSegmentCompleted, saved.Status)
+	assert.Equal(t, 4, saved.Processed)
+	assert.Equal(t, 2, saved.Changed)
+	assert.Equal(t, 1, saved.Unchanged)
+	assert.Equal(t, 1, saved.NotFound)
+	assert.NotNil(t, saved.CompletedAt)
+	for _, id := range ids {
+		assert.Equal(t, []uuid.UUID{vip.ID}, assignedSegments(t, db, id))
+	}
+	// Only the consenting customer whose segments changed is synced
+	var synced []uuid.UUID
+	require.NoError(t, db.Model(&domain.MarketingSyncState{}).Pluck("customer_id", &synced).Error)
+	assert.Equal(t, []uuid.UUID{ids[1]}, synced)
+
+	// Removing
+	job = &domain.SegmentBulkJob{SegmentID: vip.ID, Action: domain.BulkSegmentRemove, Source: domain.BulkSegmentByIDs, Total: 2}
+	require.NoError(t, repo.CreateJob(ctx, job))
+	require.NoError(t, repo.Run(ctx, job, ids[:2]))
+	assert.Equal(t, 2, job.Changed)
+	assert.Empty(t, assignedSegments(t, db, ids[0]))
+	assert.Equal(t, []uuid.UUID{vip.ID}, assignedSegments(t, db, ids[2]))
+
+	// Only the customers changed have membership events, tied to their job
+	var left []domain.SegmentMembershipEvent
+	require.NoError(t, db.Where("change = ?", domain.SegmentMembershipLeft).Find(&left).Error)
+	require.Len(t, left, 2)
+	for _, event := range left {
+		assert.Equal(t, domain.SegmentSourceBulk, event.Source)
+		assert.Equal(t, &job.ID, event.JobID)
+	}
+	var entered int64
+	require.NoError(t, db.Model(&domain.SegmentMembershipEvent{}).Where("change = ?", domain.SegmentMembershipEntered).Count(&entered).Error)
+	assert.Equal(t, int64(2), entered)
+
+	// A job of another segment isn't found
+	_, err = repo.GetJob(ctx, uuid.New(), job.ID)
+	assert.Error(t, err)
+
+	// A cancelled run fails the job
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	job = &domain.SegmentBulkJob{SegmentID: vip.ID, Action: domain.BulkSegmentAssign, Source: domain.BulkSegmentByIDs, Total: 3}
+	require.NoError(t, repo.CreateJob(ctx, job))
+	assert.ErrorIs(t, repo.Run(cancelled, job, ids), context.Canceled)
+	saved, err = repo.GetJob(ctx, vip.ID, job.ID)
+	require.NoError(t, err)
+	assert.Equal(t, domain.BulkSegmentFailed, saved.Status)
+	assert.Zero(t, saved.Processed)
+}
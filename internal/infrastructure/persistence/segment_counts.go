@@ -0,0 +1,64 @@
+package persistence
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/metrics"
+	"gorm.io/gorm"
+)
+
+// SegmentCounts caches the number of customers in each segment. All counts
+// are read in one query on the replica and kept for the TTL, so they may lag
+// assignments by up to that long.
+type SegmentCounts struct {
+	db  *gorm.DB
+	ttl time.Duration
+
+	mu        sync.Mutex
+	counts    map[uuid.UUID]int64
+	expiresAt time.Time
+}
+
+// NewSegmentCounts creates a segment count cache
+func NewSegmentCounts(db *gorm.DB, ttl time.Duration) *SegmentCounts {
+	return &SegmentCounts{db: db, ttl: ttl}
+}
+
+// Get returns the member count of every segment with members; segments
+// without any are left out
+func (s *SegmentCounts) Get(ctx context.Context) (map[uuid.UUID]int64, error) {
+	now := time.Now()
+	s.mu.Lock()
+	counts, hit := s.counts, s.counts != nil && now.Before(s.expiresAt)
+	s.mu.Unlock()
+	metrics.RecordCacheLookup("segment_counts", hit)
+	if hit {
+		return counts, nil
+	}
+
+	db := Replica(s.db.WithContext(ctx))
+	var rows []struct {
+		SegmentID uuid.UUID
+		Count     int64
+	}
+	if err := db.Model(&domain.CustomerSegmentAssignment{}).
+		Select("segment_id, COUNT(*) AS count").
+		Where("customer_id IN (?)", db.Model(&domain.Customer{}).Select("id")).
+		Group("segment_id").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	counts = make(map[uuid.UUID]int64, len(rows))
+	for _, row := range rows {
+		counts[row.SegmentID] = row.Count
+	}
+
+	s.mu.Lock()
+	s.counts, s.expiresAt = counts, now.Add(s.ttl)
+	s.mu.Unlock()
+	return counts, nil
+}
@@ -0,0 +1,48 @@
+package persistence
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSegmentCounts_Get(t *testing.T) {
+	db := openTestDB(t, &domain.Customer{}, &domain.CustomerSegment{}, &domain.CustomerSegmentAssignment{})
+	ctx := context.Background()
+
+	vip := &domain.CustomerSegment{Name: "VIP"}
+	staff := &domain.CustomerSegment{Name: "Staff"}
+	require.NoError(t, db.Create(vip).Error)
+	require.NoError(t, db.Create(staff).Error)
+	var customers []*domain.Customer
+	for _, email := range []string{"a@example.com", "b@example.com", "c@example.com"} {
+		customer := &domain.Customer{Email: email}
+		require.NoError(t, db.Create(customer).Error)
+		require.NoError(t, db.Create(&domain.CustomerSegmentAssignment{CustomerID: customer.ID, SegmentID: vip.ID}).Error)
+		customers = append(customers, customer)
+	}
+	require.NoError(t, db.Create(&domain.CustomerSegmentAssignment{CustomerID: customers[0].ID, SegmentID: staff.ID}).Error)
+	// Deleted customers aren't counted
+	require.NoError(t, db.Delete(customers[2]).Error)
+
+	counts := NewSegmentCounts(db, time.Hour)
+	got, err := counts.Get(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, map[uuid.UUID]int64{vip.ID: 2, staff.ID: 1}, got)
+
+	// Counts are served from the cache until they expire
+	require.NoError(t, db.Where("segment_id = ?", staff.ID).Delete(&domain.CustomerSegmentAssignment{}).Error)
+	got, err = counts.Get(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), got[staff.ID])
+
+	counts = NewSegmentCounts(db, 0)
+	got, err = counts.Get(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, map[uuid.UUID]int64{vip.ID: 2}, got)
+}
@@ -0,0 +1,78 @@
+package persistence
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/authctx"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"gorm.io/gorm"
+)
+
+// SegmentMembershipRepository reads the history of customers entering and
+// leaving segments. The events are recorded by the code changing the
+// assignments, in the same transaction.
+type SegmentMembershipRepository struct {
+	db *gorm.DB
+}
+
+// NewSegmentMembershipRepository creates a new segment membership repository
+func NewSegmentMembershipRepository(db *gorm.DB) *SegmentMembershipRepository {
+	return &SegmentMembershipRepository{db: db}
+}
+
+// SegmentExists reports whether a segment exists
+func (r *SegmentMembershipRepository) SegmentExists(ctx context.Context, id uuid.UUID) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&domain.CustomerSegment{}).Where("id = ?", id).Count(&count).Error
+	return count > 0, err
+}
+
+// ListBySegment returns a page of a segment's membership events, newest first
+func (r *SegmentMembershipRepository) ListBySegment(ctx context.Context, segmentID uuid.UUID, filter domain.SegmentHistoryFilter) ([]domain.SegmentMembershipEvent, int64, error) {
+	var events []domain.SegmentMembershipEvent
+	var total int64
+
+	query := r.db.WithContext(ctx).Model(&domain.SegmentMembershipEvent{}).Where("segment_id = ?", segmentID)
+	if filter.Change != "" {
+		query = query.Where("change = ?", filter.Change)
+	}
+	if filter.Source != "" {
+		query = query.Where("source = ?", filter.Source)
+	}
+	if filter.DateFrom != nil {
+		query = query.Where("occurred_at >= ?", *filter.DateFrom)
+	}
+	if filter.DateTo != nil {
+		query = query.Where("occurred_at <= ?", *filter.DateTo)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err := query.Order("occurred_at DESC").
+		Offset((filter.Page - 1) * filter.Limit).
+		Limit(filter.Limit).
+		Find(&events).Error
+	return events, total, err
+}
+
+// recordSegmentMembership saves membership events. Passing a transaction
+// keeps them only if the change they record is committed.
+func recordSegmentMembership(tx *gorm.DB, events []domain.SegmentMembershipEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+	return tx.Create(&events).Error
+}
+
+// segmentActor returns the ID of the user making the change ctx belongs to,
+// or nil outside a request
+func segmentActor(ctx context.Context) *uuid.UUID {
+	if p := authctx.FromContext(ctx); p != nil && p.ID != uuid.Nil {
+		id := p.ID
+		return &id
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package persistence
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/authctx"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSegmentMembership_AssignSegments(t *testing.T) {
+	db := openTestDB(t, &domain.Customer{}, &domain.CustomerSegment{}, &domain.CustomerSegmentAssignment{},
+		&domain.SegmentMembershipEvent{})
+	customers := NewCustomerRepository(db, DefaultQueryTimeouts())
+	repo := NewSegmentMembershipRepository(db)
+	admin := &authctx.Principal{ID: uuid.New(), Role: "admin"}
+	ctx := authctx.NewContext(context.Background(), admin)
+
+	customer := &domain.Customer{Email: "aisyah@example.com"}
+	require.NoError(t, db.Create(customer).Error)
+	vip := &domain.CustomerSegment{Name: "VIP"}
+	staff := &domain.CustomerSegment{Name: "Staff"}
+	require.NoError(t, db.Create(vip).Error)
+	require.NoError(t, db.Create(staff).Error)
+
+	require.NoError(t, customers.AssignSegments(ctx, customer.ID, []uuid.UUID{vip.ID, staff.ID}))
+	// Keeping VIP records only the segment left, and setting the same
+	// segments again records nothing
+	require.NoError(t, customers.AssignSegments(ctx, customer.ID, []uuid.UUID{vip.ID}))
+	require.NoError(t, customers.AssignSegments(ctx, customer.ID, []uuid.UUID{vip.ID, vip.ID}))
+	assert.Equal(t, []uuid.UUID{vip.ID}, assignedSegments(t, db, customer.ID))
+
+	page := domain.SegmentHistoryFilter{Page: 1, Limit: 20}
+	events, total, err := repo.ListBySegment(ctx, vip.ID, page)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+	require.Len(t, events, 1)
+	assert.Equal(t, domain.SegmentMembershipEntered, events[0].Change)
+	assert.Equal(t, domain.SegmentSourceManual, events[0].Source)
+	assert.Equal(t, &admin.ID, events[0].ActorID)
+
+	events, total, err = repo.ListBySegment(ctx, staff.ID, page)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), total)
+	require.Len(t, events, 2)
+
+	page.Change = domain.SegmentMembershipLeft
+	events, total, err = repo.ListBySegment(ctx, staff.ID, page)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+	require.Len(t, events, 1)
+	assert.Equal(t, customer.ID, events[0].CustomerID)
+
+	// Outside a request there is no actor
+	require.NoError(t, customers.AssignSegments(context.Background(), customer.ID, nil))
+	page.Source = domain.SegmentSourceManual
+	events, _, err = repo.ListBySegment(ctx, vip.ID, page)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Nil(t, events[0].ActorID)
+}
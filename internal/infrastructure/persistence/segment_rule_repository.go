@@ -0,0 +1,170 @@
+package persistence
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"gorm.io/gorm"
+)
+
+// SegmentRuleRepository stores segment rules and applies them to customers
+type SegmentRuleRepository struct {
+	db        *gorm.DB
+	webhooks  bool
+	marketing *MarketingSyncRepository
+}
+
+// NewSegmentRuleRepository creates a new segment rule repository
+func NewSegmentRuleRepository(db *gorm.DB) *SegmentRuleRepository {
+	return &SegmentRuleRepository{db: db}
+}
+
+// WithWebhooks makes Apply queue a customer.segment_changed webhook event
+// whenever it changes a customer's segments
+func (r *SegmentRuleRepository) WithWebhooks() *SegmentRuleRepository {
+	r.webhooks = true
+	return r
+}
+
+// WithMarketingSync makes Apply queue the customer for the marketing
+// platforms whenever it changes the customer's segments
+func (r *SegmentRuleRepository) WithMarketingSync(marketing *MarketingSyncRepository) *SegmentRuleRepository {
+	r.marketing = marketing
+	return r
+}
+
+// List retrieves all rules, highest priority first
+func (r *SegmentRuleRepository) List(ctx context.Context) ([]domain.SegmentRule, error) {
+	var rules []domain.SegmentRule
+	err := r.db.WithContext(ctx).
+		Order("priority DESC, created_at ASC").
+		Find(&rules).Error
+	return rules, err
+}
+
+// Create creates a new rule
+func (r *SegmentRuleRepository) Create(ctx context.Context, rule *domain.SegmentRule) error {
+	return r.db.WithContext(ctx).Create(rule).Error
+}
+
+// Delete deletes a rule
+func (r *SegmentRuleRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	result := r.db.WithContext(ctx).Where("id = ?", id).Delete(&domain.SegmentRule{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// Evaluate works out which rules would fire for the customer without changing anything
+func (r *SegmentRuleRepository) Evaluate(ctx context.Context, customerID uuid.UUID, trigger string) (*domain.SegmentEvaluation, error) {
+	return r.evaluate(r.db.WithContext(ctx), customerID, trigger)
+}
+
+// Apply evaluates the rules for the trigger and updates the customer's
+// segment assignments accordingly
+func (r *SegmentRuleRepository) Apply(ctx context.Context, customerID uuid.UUID, trigger string) (*domain.SegmentEvaluation, error) {
+	var evaluation *domain.SegmentEvaluation
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		evaluation, err = r.evaluate(tx, customerID, trigger)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		var events []domain.SegmentMembershipEvent
+		for _, decision := range evaluation.Decisions {
+			ruleID := decision.RuleID
+			if !decision.Assign {
+				result := tx.Where("customer_id = ? AND segment_id = ?", customerID, decision.SegmentID).
+					Delete(&domain.CustomerSegmentAssignment{})
+				if result.Error != nil {
+					return result.Error
+				}
+				if result.RowsAffected > 0 {
+					events = append(events, domain.SegmentMembershipEvent{
+						CustomerID: customerID, SegmentID: decision.SegmentID,
+						Change: domain.SegmentMembershipLeft, Source: domain.SegmentSourceRule,
+						RuleID: &ruleID, OccurredAt: now,
+					})
+				}
+				continue
+			}
+
+			var count int64
+			if err := tx.Model(&domain.CustomerSegmentAssignment{}).
+				Where("customer_id = ? AND segment_id = ?", customerID, decision.SegmentID).
+				Count(&count).Error; err != nil {
+				return err
+			}
+			if count > 0 {
+				continue
+			}
+			if err := tx.Create(&domain.CustomerSegmentAssignment{
+				CustomerID: customerID,
+				SegmentID:  decision.SegmentID,
+			}).Error; err != nil {
+				return err
+			}
+			events = append(events, domain.SegmentMembershipEvent{
+				CustomerID: customerID, SegmentID: decision.SegmentID,
+				Change: domain.SegmentMembershipEntered, Source: domain.SegmentSourceRule,
+				RuleID: &ruleID, OccurredAt: now,
+			})
+		}
+
+		if len(events) == 0 {
+			return nil
+		}
+		if err := recordSegmentMembership(tx, events); err != nil {
+			return err
+		}
+		if r.marketing != nil {
+			if err := queueMarketingSync(tx, r.marketing.Providers(), []uuid.UUID{customerID}, now); err != nil {
+				return err
+			}
+		}
+		if !r.webhooks {
+			return nil
+		}
+		segmentIDs, err := customerSegmentIDs(tx, customerID)
+		if err != nil {
+			return err
+		}
+		return enqueueWebhookEvent(tx, domain.WebhookEventCustomerSegmentChanged, domain.CustomerSegmentsChanged{
+			CustomerID: customerID,
+			SegmentIDs: segmentIDs,
+		}, now)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return evaluation, nil
+}
+
+func (r *SegmentRuleRepository) evaluate(db *gorm.DB, customerID uuid.UUID, trigger string) (*domain.SegmentEvaluation, error) {
+	var customer domain.Customer
+	if err := db.Where("id = ?", customerID).First(&customer).Error; err != nil {
+		return nil, err
+	}
+
+	var rules []domain.SegmentRule
+	if err := db.Where("trigger = ? AND is_active = ?", trigger, true).Find(&rules).Error; err != nil {
+		return nil, err
+	}
+
+	var marketingSegments []uuid.UUID
+	if err := db.Model(&domain.CustomerSegment{}).
+		Where("is_marketing = ?", true).
+		Pluck("id", &marketingSegments).Error; err != nil {
+		return nil, err
+	}
+
+	return domain.EvaluateSegmentRules(rules, trigger, &customer, marketingSegments), nil
+}
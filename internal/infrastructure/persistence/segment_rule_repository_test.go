@@ -0,0 +1,97 @@
+package persistence
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/Ecom-micro-template/service-customer/internal/domain/shared"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func setupSegmentRuleTestDB(t *testing.T) *gorm.DB {
+	return openTestDB(t,
+		&domain.Customer{},
+		&domain.CustomerSegment{},
+		&domain.CustomerSegmentAssignment{},
+		&domain.SegmentMembershipEvent{},
+		&domain.SegmentRule{},
+	)
+}
+
+func TestSegmentRuleRepository_Apply(t *testing.T) {
+	db := setupSegmentRuleTestDB(t)
+	repo := NewSegmentRuleRepository(db)
+	ctx := context.Background()
+
+	newBuyer := &domain.CustomerSegment{Name: "New buyer", IsActive: true}
+	gold := &domain.CustomerSegment{Name: "Gold", IsActive: true}
+	newsletter := &domain.CustomerSegment{Name: "Newsletter", IsActive: true, IsMarketing: true}
+	for _, segment := range []*domain.CustomerSegment{newBuyer, gold, newsletter} {
+		require.NoError(t, db.Create(segment).Error)
+	}
+
+	one := 1
+	threshold := 5000.0
+	suspended := shared.StatusSuspended
+	rules := []*domain.SegmentRule{
+		{Name: "First purchase", Trigger: domain.SegmentTriggerOrderCompleted, Action: domain.SegmentActionAssign,
+			SegmentID: &newBuyer.ID, MinOrders: &one, MaxOrders: &one, IsActive: true},
+		{Name: "Gold spender", Trigger: domain.SegmentTriggerOrderCompleted, Action: domain.SegmentActionAssign,
+			SegmentID: &gold.ID, MinTotalSpent: &threshold, IsActive: true},
+		{Name: "Blocked customers", Trigger: domain.SegmentTriggerStatusChanged, Action: domain.SegmentActionUnassignMarketing,
+			Status: &suspended, Priority: 100, IsActive: true},
+	}
+	for _, rule := range rules {
+		require.NoError(t, repo.Create(ctx, rule))
+	}
+
+	customer := &domain.Customer{Email: "aisyah@example.com", Status: "active", TotalOrders: 1, TotalSpent: 120}
+	require.NoError(t, db.Create(customer).Error)
+	require.NoError(t, db.Create(&domain.CustomerSegmentAssignment{CustomerID: customer.ID, SegmentID: newsletter.ID}).Error)
+
+	evaluation, err := repo.Apply(ctx, customer.ID, domain.SegmentTriggerOrderCompleted)
+	require.NoError(t, err)
+	require.Len(t, evaluation.MatchedRules, 1)
+	assert.Equal(t, "First purchase", evaluation.MatchedRules[0].Name)
+
+	// Applying twice does not duplicate the assignment
+	_, err = repo.Apply(ctx, customer.ID, domain.SegmentTriggerOrderCompleted)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []uuid.UUID{newBuyer.ID, newsletter.ID}, assignedSegments(t, db, customer.ID))
+
+	// Simulation does not change assignments
+	require.NoError(t, db.Model(customer).Updates(map[string]interface{}{"status": "suspended"}).Error)
+	simulated, err := repo.Evaluate(ctx, customer.ID, domain.SegmentTriggerStatusChanged)
+	require.NoError(t, err)
+	require.Len(t, simulated.Decisions, 1)
+	assert.False(t, simulated.Decisions[0].Assign)
+	assert.Len(t, assignedSegments(t, db, customer.ID), 2)
+
+	_, err = repo.Apply(ctx, customer.ID, domain.SegmentTriggerStatusChanged)
+	require.NoError(t, err)
+	assert.Equal(t, []uuid.UUID{newBuyer.ID}, assignedSegments(t, db, customer.ID))
+
+	// Each change is recorded once, with the rule that made it
+	var events []domain.SegmentMembershipEvent
+	require.NoError(t, db.Order("change").Find(&events).Error)
+	require.Len(t, events, 2)
+	assert.Equal(t, domain.SegmentMembershipEntered, events[0].Change)
+	assert.Equal(t, newBuyer.ID, events[0].SegmentID)
+	assert.Equal(t, &rules[0].ID, events[0].RuleID)
+	assert.Equal(t, domain.SegmentMembershipLeft, events[1].Change)
+	assert.Equal(t, newsletter.ID, events[1].SegmentID)
+	assert.Equal(t, &rules[2].ID, events[1].RuleID)
+	assert.Equal(t, domain.SegmentSourceRule, events[1].Source)
+}
+
+func assignedSegments(t *testing.T, db *gorm.DB, customerID uuid.UUID) []uuid.UUID {
+	var ids []uuid.UUID
+	require.NoError(t, db.Model(&domain.CustomerSegmentAssignment{}).
+		Where("customer_id = ?", customerID).
+		Pluck("segment_id", &ids).Error)
+	return ids
+}
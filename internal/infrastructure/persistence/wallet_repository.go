@@ -0,0 +1,258 @@
+package persistence
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// WalletRepository handles store credit wallets and their transaction ledger.
+// Every balance change runs in a transaction that locks the wallet row and
+// appends a ledger entry, so the ledger always reconciles with the balance.
+type WalletRepository struct {
+	db *gorm.DB
+}
+
+// NewWalletRepository creates a new wallet repository
+func NewWalletRepository(db *gorm.DB) *WalletRepository {
+	return &WalletRepository{db: db}
+}
+
+// GetOrCreate returns the customer's wallet, creating an empty one if needed
+func (r *WalletRepository) GetOrCreate(ctx context.Context, customerID uuid.UUID) (*domain.CustomerWallet, error) {
+	wallet := domain.CustomerWallet{CustomerID: customerID}
+	err := r.db.WithContext(ctx).
+		Where("customer_id = ?", customerID).
+		FirstOrCreate(&wallet).Error
+	if err != nil {
+		return nil, err
+	}
+	return &wallet, nil
+}
+
+// ListTransactions returns the ledger for a customer, newest first
+func (r *WalletRepository) ListTransactions(ctx context.Context, customerID uuid.UUID, page, limit int) ([]domain.WalletTransaction, int64, error) {
+	var transactions []domain.WalletTransaction
+	var total int64
+
+	query := r.db.WithContext(ctx).Model(&domain.WalletTransaction{}).Where("customer_id = ?", customerID)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * limit
+	err := query.Order("created_at DESC").Offset(offset).Limit(limit).Find(&transactions).Error
+	return transactions, total, err
+}
+
+// Credit adds store credit to a customer's wallet. Amounts must be in whole
+// cents.
+func (r *WalletRepository) Credit(ctx context.Context, customerID uuid.UUID, amount float64, reason string, createdBy *uuid.UUID) (*domain.WalletTransaction, error) {
+	if !domain.ValidWalletAmount(amount) {
+		return nil, domain.ErrInvalidWalletAmount
+	}
+
+	var txn *domain.WalletTransaction
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		wallet, err := r.lockWallet(tx, customerID)
+		if err != nil {
+			return err
+		}
+
+		wallet.Balance = domain.RoundCents(wallet.Balance + amount)
+		if err := tx.Save(wallet).Error; err != nil {
+			return err
+		}
+
+		txn = newWalletTransaction(wallet, domain.WalletTxnCredit, amount, reason, "", nil, createdBy)
+		return tx.Create(txn).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return txn, nil
+}
+
+// Debit removes store credit from a customer's wallet. Reserved credit cannot be debited.
+// Amounts must be in whole cents.
+func (r *WalletRepository) Debit(ctx context.Context, customerID uuid.UUID, amount float64, reason string, createdBy *uuid.UUID) (*domain.WalletTransaction, error) {
+	if !domain.ValidWalletAmount(amount) {
+		return nil, domain.ErrInvalidWalletAmount
+	}
+
+	var txn *domain.WalletTransaction
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		wallet, err := r.lockWallet(tx, customerID)
+		if err != nil {
+			return err
+		}
+		if wallet.Available() < amount {
+			return domain.ErrInsufficientStoreCredit
+		}
+
+		wallet.Balance = domain.RoundCents(wallet.Balance - amount)
+		if err := tx.Save(wallet).Error; err != nil {
+			return err
+		}
+
+		txn = newWalletTransaction(wallet, domain.WalletTxnDebit, amount, reason, "", nil, createdBy)
+		return tx.Create(txn).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return txn, nil
+}
+
+// Reserve holds credit for an order. Reserving again with the same reference
+// returns the existing reservation, whether still pending, captured or
+// released, so a retry never holds the credit twice. Amounts must be in
+// whole cents.
+func (r *WalletRepository) Reserve(ctx context.Context, customerID uuid.UUID, amount float64, reference string) (*domain.WalletReservation, error) {
+	if !domain.ValidWalletAmount(amount) {
+		return nil, domain.ErrInvalidWalletAmount
+	}
+
+	var reservation domain.WalletReservation
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		wallet, err := r.lockWallet(tx, customerID)
+		if err != nil {
+			return err
+		}
+
+		err = tx.Where("customer_id = ? AND reference = ?", customerID, reference).
+			Order("created_at").
+			First(&reservation).Error
+		if err == nil {
+			return nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		if wallet.Available() < amount {
+			return domain.ErrInsufficientStoreCredit
+		}
+
+		reservation = domain.WalletReservation{
+			WalletID:   wallet.ID,
+			CustomerID: customerID,
+			Reference:  reference,
+			Amount:     amount,
+			Status:     domain.ReservationPending,
+		}
+		if err := tx.Create(&reservation).Error; err != nil {
+			return err
+		}
+
+		wallet.Reserved = domain.RoundCents(wallet.Reserved + amount)
+		if err := tx.Save(wallet).Error; err != nil {
+			return err
+		}
+
+		return tx.Create(newWalletTransaction(wallet, domain.WalletTxnReserve, amount, "", reference, &reservation.ID, nil)).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &reservation, nil
+}
+
+// Capture spends a pending reservation. Capturing an already captured
+// reservation is a no-op.
+func (r *WalletRepository) Capture(ctx context.Context, reservationID uuid.UUID) (*domain.WalletReservation, error) {
+	return r.settle(ctx, reservationID, domain.ReservationCaptured)
+}
+
+// Release returns a pending reservation to the customer's available credit.
+// Releasing an already released reservation is a no-op.
+func (r *WalletRepository) Release(ctx context.Context, reservationID uuid.UUID) (*domain.WalletReservation, error) {
+	return r.settle(ctx, reservationID, domain.ReservationReleased)
+}
+
+// settle moves a pending reservation to its final status
+func (r *WalletRepository) settle(ctx context.Context, reservationID uuid.UUID, status string) (*domain.WalletReservation, error) {
+	var reservation domain.WalletReservation
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&reservation, "id = ?", reservationID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return domain.ErrReservationNotFound
+			}
+			return err
+		}
+
+		if reservation.Status == status {
+			return nil
+		}
+		if reservation.Status != domain.ReservationPending {
+			return domain.ErrReservationNotPending
+		}
+
+		wallet, err := r.lockWallet(tx, reservation.CustomerID)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		txnType := domain.WalletTxnRelease
+		wallet.Reserved = domain.RoundCents(wallet.Reserved - reservation.Amount)
+		if status == domain.ReservationCaptured {
+			txnType = domain.WalletTxnCapture
+			wallet.Balance = domain.RoundCents(wallet.Balance - reservation.Amount)
+			reservation.CapturedAt = &now
+		} else {
+			reservation.ReleasedAt = &now
+		}
+		reservation.Status = status
+
+		if err := tx.Save(wallet).Error; err != nil {
+			return err
+		}
+		if err := tx.Save(&reservation).Error; err != nil {
+			return err
+		}
+
+		return tx.Create(newWalletTransaction(wallet, txnType, reservation.Amount, "", reservation.Reference, &reservation.ID, nil)).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &reservation, nil
+}
+
+// lockWallet loads (or creates) the customer's wallet with a row lock
+func (r *WalletRepository) lockWallet(tx *gorm.DB, customerID uuid.UUID) (*domain.CustomerWallet, error) {
+	var wallet domain.CustomerWallet
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("customer_id = ?", customerID).
+		First(&wallet).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		wallet = domain.CustomerWallet{CustomerID: customerID}
+		err = tx.Create(&wallet).Error
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &wallet, nil
+}
+
+func newWalletTransaction(wallet *domain.CustomerWallet, txnType string, amount float64, reason, reference string, reservationID, createdBy *uuid.UUID) *domain.WalletTransaction {
+	return &domain.WalletTransaction{
+		WalletID:      wallet.ID,
+		CustomerID:    wallet.CustomerID,
+		Type:          txnType,
+		Amount:        amount,
+		BalanceAfter:  wallet.Balance,
+		ReservedAfter: wallet.Reserved,
+		Reason:        reason,
+		Reference:     reference,
+		ReservationID: reservationID,
+		CreatedBy:     createdBy,
+	}
+}
@@ -0,0 +1,169 @@
+package persistence
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func setupWalletTestDB(t *testing.T) *gorm.DB {
+	return openTestDB(t, &domain.CustomerWallet{}, &domain.WalletTransaction{}, &domain.WalletReservation{})
+}
+
+func TestWalletRepository_CreditAndDebit(t *testing.T) {
+	db := setupWalletTestDB(t)
+	repo := NewWalletRepository(db)
+	ctx := context.Background()
+
+	customerID := uuid.New()
+
+	txn, err := repo.Credit(ctx, customerID, 50, "Goodwill credit", nil)
+	require.NoError(t, err)
+	assert.Equal(t, domain.WalletTxnCredit, txn.Type)
+	assert.Equal(t, 50.0, txn.BalanceAfter)
+
+	_, err = repo.Debit(ctx, customerID, 80, "Too much", nil)
+	assert.ErrorIs(t, err, domain.ErrInsufficientStoreCredit)
+
+	txn, err = repo.Debit(ctx, customerID, 20, "Correction", nil)
+	require.NoError(t, err)
+	assert.Equal(t, 30.0, txn.BalanceAfter)
+
+	wallet, err := repo.GetOrCreate(ctx, customerID)
+	require.NoError(t, err)
+	assert.Equal(t, 30.0, wallet.Balance)
+
+	transactions, total, err := repo.ListTransactions(ctx, customerID, 1, 10)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), total)
+	assert.Len(t, transactions, 2)
+}
+
+func TestWalletRepository_ReserveCapture(t *testing.T) {
+	db := setupWalletTestDB(t)
+	repo := NewWalletRepository(db)
+	ctx := context.Background()
+
+	customerID := uuid.New()
+	_, err := repo.Credit(ctx, customerID, 100, "Refund", nil)
+	require.NoError(t, err)
+
+	reservation, err := repo.Reserve(ctx, customerID, 40, "ORD-1001")
+	require.NoError(t, err)
+	assert.Equal(t, domain.ReservationPending, reservation.Status)
+
+	// Retrying with the same reference returns the same reservation
+	retry, err := repo.Reserve(ctx, customerID, 40, "ORD-1001")
+	require.NoError(t, err)
+	assert.Equal(t, reservation.ID, retry.ID)
+
+	_, err = repo.Reserve(ctx, customerID, 70, "ORD-1002")
+	assert.ErrorIs(t, err, domain.ErrInsufficientStoreCredit)
+
+	captured, err := repo.Capture(ctx, reservation.ID)
+	require.NoError(t, err)
+	assert.Equal(t, domain.ReservationCaptured, captured.Status)
+
+	_, err = repo.Release(ctx, reservation.ID)
+	assert.ErrorIs(t, err, domain.ErrReservationNotPending)
+
+	wallet, err := repo.GetOrCreate(ctx, customerID)
+	require.NoError(t, err)
+	assert.Equal(t, 60.0, wallet.Balance)
+	assert.Equal(t, 0.0, wallet.Reserved)
+}
+
+func TestWalletRepository_ReserveRelease(t *testing.T) {
+	db := setupWalletTestDB(t)
+	repo := NewWalletRepository(db)
+	ctx := context.Background()
+
+	customerID := uuid.New()
+	_, err := repo.Credit(ctx, customerID, 25, "Promo", nil)
+	require.NoError(t, err)
+
+	reservation, err := repo.Reserve(ctx, customerID, 25, "ORD-2001")
+	require.NoError(t, err)
+
+	released, err := repo.Release(ctx, reservation.ID)
+	require.NoError(t, err)
+	assert.Equal(t, domain.ReservationReleased, released.Status)
+
+	wallet, err := repo.GetOrCreate(ctx, customerID)
+	require.NoError(t, err)
+	assert.Equal(t, 25.0, wallet.Balance)
+	assert.Equal(t, 25.0, wallet.Available())
+
+	_, err = repo.Capture(ctx, uuid.New())
+	assert.ErrorIs(t, err, domain.ErrReservationNotFound)
+}
+
+func TestWalletRepository_RejectsFractionalCents(t *testing.T) {
+	db := setupWalletTestDB(t)
+	repo := NewWalletRepository(db)
+	ctx := context.Background()
+	customerID := uuid.New()
+
+	for _, amount := range []float64{0, -5, 0.001, 10.005} {
+		_, err := repo.Credit(ctx, customerID, amount, "Refund", nil)
+		assert.ErrorIs(t, err, domain.ErrInvalidWalletAmount, "credit %v", amount)
+		_, err = repo.Debit(ctx, customerID, amount, "Correction", nil)
+		assert.ErrorIs(t, err, domain.ErrInvalidWalletAmount, "debit %v", amount)
+		_, err = repo.Reserve(ctx, customerID, amount, "ORD-3001")
+		assert.ErrorIs(t, err, domain.ErrInvalidWalletAmount, "reserve %v", amount)
+	}
+
+	// Cents add up without float drift
+	for _, amount := range []float64{0.1, 0.2, 19.99} {
+		_, err := repo.Credit(ctx, customerID, amount, "Refund", nil)
+		require.NoError(t, err)
+	}
+	txn, err := repo.Debit(ctx, customerID, 20.29, "Correction", nil)
+	require.NoError(t, err)
+	assert.Equal(t, 0.0, txn.BalanceAfter)
+}
+
+func TestWalletRepository_ReserveRetryAfterSettling(t *testing.T) {
+	db := setupWalletTestDB(t)
+	repo := NewWalletRepository(db)
+	ctx := context.Background()
+
+	customerID := uuid.New()
+	_, err := repo.Credit(ctx, customerID, 100, "Refund", nil)
+	require.NoError(t, err)
+
+	captured, err := repo.Reserve(ctx, customerID, 40, "ORD-4001")
+	require.NoError(t, err)
+	_, err = repo.Capture(ctx, captured.ID)
+	require.NoError(t, err)
+
+	released, err := repo.Reserve(ctx, customerID, 30, "ORD-4002")
+	require.NoError(t, err)
+	_, err = repo.Release(ctx, released.ID)
+	require.NoError(t, err)
+
+	// A late retry gets the settled reservation back instead of a new hold
+	retry, err := repo.Reserve(ctx, customerID, 40, "ORD-4001")
+	require.NoError(t, err)
+	assert.Equal(t, captured.ID, retry.ID)
+	assert.Equal(t, domain.ReservationCaptured, retry.Status)
+
+	retry, err = repo.Reserve(ctx, customerID, 30, "ORD-4002")
+	require.NoError(t, err)
+	assert.Equal(t, released.ID, retry.ID)
+	assert.Equal(t, domain.ReservationReleased, retry.Status)
+
+	wallet, err := repo.GetOrCreate(ctx, customerID)
+	require.NoError(t, err)
+	assert.Equal(t, 60.0, wallet.Balance)
+	assert.Equal(t, 0.0, wallet.Reserved)
+
+	var reservations int64
+	require.NoError(t, db.Model(&domain.WalletReservation{}).Count(&reservations).Error)
+	assert.Equal(t, int64(2), reservations)
+}
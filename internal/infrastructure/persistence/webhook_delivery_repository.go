@@ -0,0 +1,49 @@
+package persistence
+
+import (
+	"context"
+	"time"
+
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// WebhookDeliveryRepository remembers received webhook events for replay protection
+type WebhookDeliveryRepository struct {
+	db *gorm.DB
+}
+
+// NewWebhookDeliveryRepository creates a new webhook delivery repository
+func NewWebhookDeliveryRepository(db *gorm.DB) *WebhookDeliveryRepository {
+	return &WebhookDeliveryRepository{db: db}
+}
+
+// Claim records an event and reports whether this is its first delivery.
+// Concurrent deliveries of the same event race on the primary key, so only
+// one of them claims it.
+func (r *WebhookDeliveryRepository) Claim(ctx context.Context, source, eventID string) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&domain.WebhookDelivery{Source: source, EventID: eventID, ReceivedAt: time.Now()})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// Release forgets an event whose processing failed, so the sender's retry is
+// processed instead of being ignored as a replay
+func (r *WebhookDeliveryRepository) Release(ctx context.Context, source, eventID string) error {
+	return r.db.WithContext(ctx).
+		Where("source = ? AND event_id = ?", source, eventID).
+		Delete(&domain.WebhookDelivery{}).Error
+}
+
+// PurgeBefore removes events received before the cutoff
+func (r *WebhookDeliveryRepository) PurgeBefore(ctx context.Context, before time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).
+		Where("received_at < ?", before).
+		Delete(&domain.WebhookDelivery{})
+	return result.RowsAffected, result.Error
+}
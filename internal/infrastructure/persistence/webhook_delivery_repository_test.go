@@ -0,0 +1,69 @@
+package persistence
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhookDeliveryRepository(t *testing.T) {
+	db := openTestDB(t, &domain.WebhookDelivery{})
+	repo := NewWebhookDeliveryRepository(db)
+	ctx := context.Background()
+
+	claimed, err := repo.Claim(ctx, domain.WebhookSourceInventory, "evt-1")
+	require.NoError(t, err)
+	assert.True(t, claimed)
+
+	claimed, err = repo.Claim(ctx, domain.WebhookSourceInventory, "evt-1")
+	require.NoError(t, err)
+	assert.False(t, claimed, "a replay must not be claimed again")
+
+	claimed, err = repo.Claim(ctx, "other", "evt-1")
+	require.NoError(t, err)
+	assert.True(t, claimed, "event IDs are scoped to their source")
+
+	require.NoError(t, repo.Release(ctx, domain.WebhookSourceInventory, "evt-1"))
+	claimed, err = repo.Claim(ctx, domain.WebhookSourceInventory, "evt-1")
+	require.NoError(t, err)
+	assert.True(t, claimed, "a released event can be retried")
+
+	purged, err := repo.PurgeBefore(ctx, time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, purged)
+}
+
+func TestInventoryWebhookPayload_Validate(t *testing.T) {
+	valid := domain.InventoryWebhookPayload{
+		EventID:   "evt-1",
+		Event:     domain.InventoryEventRestocked,
+		ProductID: uuid.NewString(),
+		Quantity:  12,
+	}
+	assert.Empty(t, valid.Validate())
+
+	invalid := valid
+	invalid.EventID = ""
+	invalid.ProductID = "sku-123"
+	invalid.VariantID = "red"
+	invalid.Quantity = 0
+	assert.Len(t, invalid.Validate(), 4)
+
+	lowStock := domain.InventoryWebhookPayload{EventID: "evt-2", Event: "stock.low"}
+	assert.Empty(t, lowStock.Validate(), "product fields are only required for restocks")
+}
+
+func TestWebhookSignature(t *testing.T) {
+	secret := []byte("webhook-secret")
+	sig := domain.WebhookSignature(secret, "1700000000", []byte(`{"event_id":"evt-1"}`))
+	assert.Equal(t, "sha256=", sig[:7])
+	assert.Len(t, sig, 7+64)
+	assert.Equal(t, sig, domain.WebhookSignature(secret, "1700000000", []byte(`{"event_id":"evt-1"}`)))
+	assert.NotEqual(t, sig, domain.WebhookSignature(secret, "1700000001", []byte(`{"event_id":"evt-1"}`)))
+	assert.NotEqual(t, sig, domain.WebhookSignature([]byte("other"), "1700000000", []byte(`{"event_id":"evt-1"}`)))
+}
@@ -0,0 +1,259 @@
+package persistence
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"gorm.io/gorm"
+)
+
+// WebhookSubscriptionRepository stores webhook subscriptions and the queue of
+// deliveries sent to them
+type WebhookSubscriptionRepository struct {
+	db *gorm.DB
+}
+
+// NewWebhookSubscriptionRepository creates a new webhook subscription repository
+func NewWebhookSubscriptionRepository(db *gorm.DB) *WebhookSubscriptionRepository {
+	return &WebhookSubscriptionRepository{db: db}
+}
+
+// List returns all subscriptions, oldest first
+func (r *WebhookSubscriptionRepository) List(ctx context.Context) ([]domain.WebhookSubscription, error) {
+	var subscriptions []domain.WebhookSubscription
+	err := r.db.WithContext(ctx).Order("created_at ASC").Find(&subscriptions).Error
+	return subscriptions, err
+}
+
+// Get retrieves a subscription
+func (r *WebhookSubscriptionRepository) Get(ctx context.Context, id uuid.UUID) (*domain.WebhookSubscription, error) {
+	var subscription domain.WebhookSubscription
+	if err := r.db.WithContext(ctx).Where("id = ?", id).First(&subscription).Error; err != nil {
+		return nil, err
+	}
+	return &subscription, nil
+}
+
+// Create creates a subscription
+func (r *WebhookSubscriptionRepository) Create(ctx context.Context, subscription *domain.WebhookSubscription) error {
+	return r.db.WithContext(ctx).Create(subscription).Error
+}
+
+// Update applies req to a subscription
+func (r *WebhookSubscriptionRepository) Update(ctx context.Context, id uuid.UUID, req *domain.UpdateWebhookSubscriptionRequest) (*domain.WebhookSubscription, error) {
+	subscription, err := r.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != nil {
+		subscription.Name = *req.Name
+	}
+	if req.URL != nil {
+		subscription.URL = *req.URL
+	}
+	if req.EventTypes != nil {
+		subscription.EventTypes = req.EventTypes
+	}
+	if req.IsActive != nil {
+		subscription.IsActive = *req.IsActive
+	}
+	if err := r.db.WithContext(ctx).
+		Select("name", "url", "event_types", "is_active").
+		Updates(subscription).Error; err != nil {
+		return nil, err
+	}
+	return subscription, nil
+}
+
+// Delete removes a subscription with its deliveries and their logs
+func (r *WebhookSubscriptionRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("id = ?", id).Delete(&domain.WebhookSubscription{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		deliveries := tx.Model(&domain.OutboundWebhookDelivery{}).Select("id").Where("subscription_id = ?", id)
+		if err := tx.Where("delivery_id IN (?)", deliveries).Delete(&domain.OutboundWebhookAttempt{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("subscription_id = ?", id).Delete(&domain.OutboundWebhookDelivery{}).Error
+	})
+}
+
+// Enqueue queues an event for every active subscription to its type
+func (r *WebhookSubscriptionRepository) Enqueue(ctx context.Context, eventType string, data interface{}) error {
+	return enqueueWebhookEvent(r.db.WithContext(ctx), eventType, data, time.Now())
+}
+
+// Deliveries returns a page of a subscription's deliveries, newest first,
+// optionally only those in status
+func (r *WebhookSubscriptionRepository) Deliveries(ctx context.Context, subscriptionID uuid.UUID, status string, page, limit int) ([]domain.OutboundWebhookDelivery, int64, error) {
+	var deliveries []domain.OutboundWebhookDelivery
+	var total int64
+
+	query := r.db.WithContext(ctx).Model(&domain.OutboundWebhookDelivery{}).Where("subscription_id = ?", subscriptionID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err := query.Order("created_at DESC").
+		Offset((page - 1) * limit).
+		Limit(limit).
+		Find(&deliveries).Error
+	return deliveries, total, err
+}
+
+// Delivery retrieves one of a subscription's deliveries with its attempt log
+func (r *WebhookSubscriptionRepository) Delivery(ctx context.Context, subscriptionID, deliveryID uuid.UUID) (*domain.OutboundWebhookDeliveryDetail, error) {
+	db := r.db.WithContext(ctx)
+	var detail domain.OutboundWebhookDeliveryDetail
+	if err := db.Where("id = ? AND subscription_id = ?", deliveryID, subscriptionID).
+		First(&detail.OutboundWebhookDelivery).Error; err != nil {
+		return nil, err
+	}
+	if err := db.Where("delivery_id = ?", deliveryID).
+		Order("attempted_at DESC").
+		Find(&detail.Log).Error; err != nil {
+		return nil, err
+	}
+	return &detail, nil
+}
+
+// Redeliver queues a delivery to be sent again now with a fresh set of
+// attempts, whatever its status. Its log is kept.
+func (r *WebhookSubscriptionRepository) Redeliver(ctx context.Context, subscriptionID, deliveryID uuid.UUID, now time.Time) (*domain.OutboundWebhookDelivery, error) {
+	db := r.db.WithContext(ctx)
+	result := db.Model(&domain.OutboundWebhookDelivery{}).
+		Where("id = ? AND subscription_id = ?", deliveryID, subscriptionID).
+		Updates(map[string]interface{}{
+			"status":          domain.OutboundWebhookPending,
+			"attempts":        0,
+			"next_attempt_at": now,
+		})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	var delivery domain.OutboundWebhookDelivery
+	if err := db.Where("id = ?", deliveryID).First(&delivery).Error; err != nil {
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+// Due returns pending deliveries whose next attempt is at or before now,
+// oldest first. Deliveries to inactive subscriptions wait until they are
+// activated again.
+func (r *WebhookSubscriptionRepository) Due(ctx context.Context, now time.Time, limit int) ([]domain.OutboundWebhookDelivery, error) {
+	var deliveries []domain.OutboundWebhookDelivery
+	active := r.db.Model(&domain.WebhookSubscription{}).Select("id").Where("is_active = ?", true)
+	err := r.db.WithContext(ctx).
+		Where("status = ? AND next_attempt_at <= ?", domain.OutboundWebhookPending, now).
+		Where("subscription_id IN (?)", active).
+		Order("next_attempt_at ASC").
+		Limit(limit).
+		Find(&deliveries).Error
+	return deliveries, err
+}
+
+// Claim pushes a due delivery's next attempt back by lease so other replicas
+// leave it alone while it is sent. It reports false when another replica
+// claimed it first.
+func (r *WebhookSubscriptionRepository) Claim(ctx context.Context, delivery *domain.OutboundWebhookDelivery, lease time.Duration, now time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Model(&domain.OutboundWebhookDelivery{}).
+		Where("id = ? AND status = ? AND next_attempt_at = ?", delivery.ID, domain.OutboundWebhookPending, delivery.NextAttemptAt).
+		Update("next_attempt_at", now.Add(lease))
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// RecordAttempt logs an attempt and updates the delivery: delivered when the
+// attempt succeeded, otherwise retried at next, or failed when next is nil
+func (r *WebhookSubscriptionRepository) RecordAttempt(ctx context.Context, delivery *domain.OutboundWebhookDelivery, attempt *domain.OutboundWebhookAttempt, next *time.Time) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		attempt.DeliveryID = delivery.ID
+		if err := tx.Create(attempt).Error; err != nil {
+			return err
+		}
+
+		updates := map[string]interface{}{
+			"attempts":         gorm.Expr("attempts + 1"),
+			"last_status_code": attempt.StatusCode,
+			"last_error":       attempt.Error,
+		}
+		switch {
+		case attempt.Error == "":
+			updates["status"] = domain.OutboundWebhookDelivered
+			updates["delivered_at"] = attempt.AttemptedAt
+		case next != nil:
+			updates["next_attempt_at"] = *next
+		default:
+			updates["status"] = domain.OutboundWebhookFailed
+		}
+		return tx.Model(&domain.OutboundWebhookDelivery{}).
+			Where("id = ?", delivery.ID).
+			Updates(updates).Error
+	})
+}
+
+// enqueueWebhookEvent queues an event for every active subscription to its
+// type. Passing a transaction queues the event only if the change it reports
+// is committed.
+func enqueueWebhookEvent(tx *gorm.DB, eventType string, data interface{}, now time.Time) error {
+	var subscriptions []domain.WebhookSubscription
+	if err := tx.Where("is_active = ?", true).Find(&subscriptions).Error; err != nil {
+		return err
+	}
+
+	event := domain.WebhookEvent{ID: uuid.New(), Type: eventType, OccurredAt: now.UTC(), Data: data}
+	var payload []byte
+	for _, subscription := range subscriptions {
+		if !subscription.Subscribes(eventType) {
+			continue
+		}
+		if payload == nil {
+			var err error
+			if payload, err = json.Marshal(event); err != nil {
+				return err
+			}
+		}
+		if err := tx.Create(&domain.OutboundWebhookDelivery{
+			SubscriptionID: subscription.ID,
+			EventID:        event.ID,
+			EventType:      eventType,
+			Payload:        string(payload),
+			Status:         domain.OutboundWebhookPending,
+			NextAttemptAt:  now,
+		}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// customerSegmentIDs returns the segments a customer is in
+func customerSegmentIDs(tx *gorm.DB, customerID uuid.UUID) ([]uuid.UUID, error) {
+	segmentIDs := []uuid.UUID{}
+	err := tx.Model(&domain.CustomerSegmentAssignment{}).
+		Where("customer_id = ?", customerID).
+		Order("segment_id").
+		Pluck("segment_id", &segmentIDs).Error
+	return segmentIDs, err
+}
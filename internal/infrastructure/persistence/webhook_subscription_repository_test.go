@@ -0,0 +1,144 @@
+package persistence
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+func TestWebhookCustomerRepository_QueuesEvents(t *testing.T) {
+	db := openTestDB(t, &domain.Customer{}, &domain.CustomerSegmentAssignment{}, &domain.SegmentMembershipEvent{},
+		&domain.WebhookSubscription{}, &domain.OutboundWebhookDelivery{})
+	webhooks := NewWebhookSubscriptionRepository(db)
+	repo := NewWebhookCustomerRepository(NewCustomerRepository(db, DefaultQueryTimeouts()), webhooks, zap.NewNop())
+	ctx := context.Background()
+
+	crm := &domain.WebhookSubscription{Name: "CRM", URL: "https://crm.example.com/hook", Secret: "whsec_crm",
+		EventTypes: []string{domain.WebhookEventCustomerCreated, domain.WebhookEventCustomerSegmentChanged}, IsActive: true}
+	audit := &domain.WebhookSubscription{Name: "Audit", URL: "https://audit.example.com/hook", Secret: "whsec_audit",
+		EventTypes: domain.WebhookEventTypes, IsActive: true}
+	paused := &domain.WebhookSubscription{Name: "Paused", URL: "https://paused.example.com/hook", Secret: "whsec_paused",
+		EventTypes: domain.WebhookEventTypes, IsActive: true}
+	for _, s := range []*domain.WebhookSubscription{crm, audit, paused} {
+		require.NoError(t, webhooks.Create(ctx, s))
+	}
+	_, err := webhooks.Update(ctx, paused.ID, &domain.UpdateWebhookSubscriptionRequest{IsActive: new(bool)})
+	require.NoError(t, err)
+
+	customer, err := repo.Create(ctx, &domain.CreateCustomerRequest{Email: "aisyah@example.com", FirstName: "Aisyah", LastName: "Rahman"}, nil)
+	require.NoError(t, err)
+	name := "Aisyah Binti"
+	_, err = repo.Update(ctx, customer.ID, &domain.UpdateCustomerRequest{FirstName: &name})
+	require.NoError(t, err)
+	segmentID := uuid.New()
+	require.NoError(t, repo.AssignSegments(ctx, customer.ID, []uuid.UUID{segmentID}))
+
+	count := func(subscriptionID uuid.UUID) map[string]int {
+		t.Helper()
+		var deliveries []domain.OutboundWebhookDelivery
+		require.NoError(t, db.Where("subscription_id = ?", subscriptionID).Find(&deliveries).Error)
+		types := make(map[string]int)
+		for _, d := range deliveries {
+			types[d.EventType]++
+		}
+		return types
+	}
+	assert.Equal(t, map[string]int{domain.WebhookEventCustomerCreated: 1, domain.WebhookEventCustomerSegmentChanged: 1}, count(crm.ID))
+	assert.Equal(t, map[string]int{domain.WebhookEventCustomerCreated: 1, domain.WebhookEventCustomerUpdated: 1, domain.WebhookEventCustomerSegmentChanged: 1}, count(audit.ID))
+	assert.Empty(t, count(paused.ID))
+
+	var delivery domain.OutboundWebhookDelivery
+	require.NoError(t, db.Where("subscription_id = ? AND event_type = ?", crm.ID, domain.WebhookEventCustomerSegmentChanged).First(&delivery).Error)
+	var event struct {
+		ID   uuid.UUID                      `json:"id"`
+		Type string                         `json:"type"`
+		Data domain.CustomerSegmentsChanged `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(delivery.Payload), &event))
+	assert.Equal(t, delivery.EventID, event.ID)
+	assert.Equal(t, domain.WebhookEventCustomerSegmentChanged, event.Type)
+	assert.Equal(t, customer.ID, event.Data.CustomerID)
+	assert.Equal(t, []uuid.UUID{segmentID}, event.Data.SegmentIDs)
+}
+
+func TestWebhookSubscriptionRepository_Deliveries(t *testing.T) {
+	db := openTestDB(t, &domain.WebhookSubscription{}, &domain.OutboundWebhookDelivery{}, &domain.OutboundWebhookAttempt{})
+	repo := NewWebhookSubscriptionRepository(db)
+	ctx := context.Background()
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	subscription := &domain.WebhookSubscription{Name: "CRM", URL: "https://crm.example.com/hook", Secret: "whsec_crm",
+		EventTypes: []string{domain.WebhookEventCustomerUpdated}, IsActive: true}
+	require.NoError(t, repo.Create(ctx, subscription))
+	require.NoError(t, enqueueWebhookEvent(db, domain.WebhookEventCustomerUpdated, map[string]string{"id": "c1"}, now))
+	require.NoError(t, enqueueWebhookEvent(db, domain.WebhookEventCustomerCreated, map[string]string{"id": "c2"}, now))
+
+	due, err := repo.Due(ctx, now, 10)
+	require.NoError(t, err)
+	require.Len(t, due, 1, "only subscribed events are queued")
+	delivery := &due[0]
+
+	// Only one replica gets to send a delivery
+	claimed, err := repo.Claim(ctx, delivery, 5*time.Minute, now)
+	require.NoError(t, err)
+	assert.True(t, claimed)
+	claimed, err = repo.Claim(ctx, delivery, 5*time.Minute, now)
+	require.NoError(t, err)
+	assert.False(t, claimed)
+	due, err = repo.Due(ctx, now.Add(time.Minute), 10)
+	require.NoError(t, err)
+	assert.Empty(t, due)
+
+	// A failed attempt is retried, the last one fails the delivery
+	next := now.Add(30 * time.Second)
+	require.NoError(t, repo.RecordAttempt(ctx, delivery, &domain.OutboundWebhookAttempt{
+		StatusCode: 503, Error: "subscriber returned status 503", AttemptedAt: now,
+	}, &next))
+	due, err = repo.Due(ctx, next, 10)
+	require.NoError(t, err)
+	require.Len(t, due, 1)
+	assert.Equal(t, 1, due[0].Attempts)
+	require.NoError(t, repo.RecordAttempt(ctx, &due[0], &domain.OutboundWebhookAttempt{
+		Error: "connection refused", AttemptedAt: next,
+	}, nil))
+
+	failed, total, err := repo.Deliveries(ctx, subscription.ID, domain.OutboundWebhookFailed, 1, 10)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+	assert.Equal(t, "connection refused", failed[0].LastError)
+
+	// Redelivering sends the same event again and keeps the log
+	redelivered, err := repo.Redeliver(ctx, subscription.ID, delivery.ID, now.Add(time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, domain.OutboundWebhookPending, redelivered.Status)
+	assert.Zero(t, redelivered.Attempts)
+	require.NoError(t, repo.RecordAttempt(ctx, redelivered, &domain.OutboundWebhookAttempt{
+		StatusCode: 200, AttemptedAt: now.Add(time.Hour),
+	}, nil))
+
+	detail, err := repo.Delivery(ctx, subscription.ID, delivery.ID)
+	require.NoError(t, err)
+	assert.Equal(t, domain.OutboundWebhookDelivered, detail.Status)
+	assert.Equal(t, delivery.EventID, detail.EventID)
+	require.NotNil(t, detail.DeliveredAt)
+	require.Len(t, detail.Log, 3)
+	assert.Equal(t, 200, detail.Log[0].StatusCode)
+
+	_, err = repo.Redeliver(ctx, uuid.New(), delivery.ID, now)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound, "deliveries are looked up within their subscription")
+
+	// Deleting the subscription deletes its deliveries and log
+	require.NoError(t, repo.Delete(ctx, subscription.ID))
+	assert.ErrorIs(t, repo.Delete(ctx, subscription.ID), gorm.ErrRecordNotFound)
+	var left int64
+	require.NoError(t, db.Model(&domain.OutboundWebhookAttempt{}).Count(&left).Error)
+	assert.Zero(t, left)
+}
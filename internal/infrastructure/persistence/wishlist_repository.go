@@ -2,15 +2,26 @@ package persistence
 
 import (
 	"context"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/Ecom-micro-template/service-customer/internal/domain"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // WishlistRepository handles wishlist data operations
 type WishlistRepository struct {
-	db *gorm.DB
+	db     *gorm.DB
+	limits *CustomerLimitRepository
+	events WishlistEvents
+}
+
+// WishlistEvents is told about items added to and removed from wishlists,
+// after the change is committed. Implementations must not block.
+type WishlistEvents interface {
+	ItemAdded(ctx context.Context, item domain.WishlistItem)
+	ItemRemoved(ctx context.Context, item domain.WishlistItem)
 }
 
 // NewWishlistRepository creates a new wishlist repository
@@ -18,6 +29,18 @@ func NewWishlistRepository(db *gorm.DB) *WishlistRepository {
 	return &WishlistRepository{db: db}
 }
 
+// WithLimits caps how many items each customer's wishlist can hold
+func (r *WishlistRepository) WithLimits(limits *CustomerLimitRepository) *WishlistRepository {
+	r.limits = limits
+	return r
+}
+
+// WithEvents reports added and removed wishlist items to events
+func (r *WishlistRepository) WithEvents(events WishlistEvents) *WishlistRepository {
+	r.events = events
+	return r
+}
+
 // ListByUserID retrieves all wishlist items for a user
 func (r *WishlistRepository) ListByUserID(ctx context.Context, userID uuid.UUID) ([]domain.WishlistItem, error) {
 	var items []domain.WishlistItem
@@ -30,15 +53,16 @@ func (r *WishlistRepository) ListByUserID(ctx context.Context, userID uuid.UUID)
 
 // AddWishlistItemInput contains all fields for adding a wishlist item
 type AddWishlistItemInput struct {
-	ProductID    uuid.UUID
-	VariantID    *uuid.UUID
-	VariantSKU   *string
-	VariantName  *string
-	PriceAtAdd   float64
-	NotifyOnSale bool
-	ProductName  *string
-	ProductSlug  *string
-	ProductImage *string
+	ProductID     uuid.UUID
+	VariantID     *uuid.UUID
+	VariantSKU    *string
+	VariantName   *string
+	PriceAtAdd    float64
+	NotifyOnSale  bool
+	NotifyRestock bool
+	ProductName   *string
+	ProductSlug   *string
+	ProductImage  *string
 }
 
 // Add adds a product to the wishlist (handles duplicates)
@@ -49,94 +73,106 @@ func (r *WishlistRepository) Add(ctx context.Context, userID, productID uuid.UUI
 	})
 }
 
-// AddWithVariant adds a product/variant to the wishlist with full details
+// AddWithVariant adds a product/variant to the wishlist with full details.
+// Returns domain.ErrWishlistFull if the item is new and the wishlist already
+// holds as many items as the user's limit allows.
 func (r *WishlistRepository) AddWithVariant(ctx context.Context, userID uuid.UUID, input AddWishlistItemInput) error {
-	// Build query to check for existing item
-	query := r.db.WithContext(ctx).Model(&domain.WishlistItem{}).
-		Where("user_id = ? AND product_id = ?", userID, input.ProductID)
+	var added *domain.WishlistItem
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Build query to check for existing item
+		query := tx.Model(&domain.WishlistItem{}).
+			Where("user_id = ? AND product_id = ?", userID, input.ProductID)
 
-	// Check variant-specific or product-level
-	if input.VariantID != nil {
-		query = query.Where("variant_id = ?", *input.VariantID)
-	} else {
-		query = query.Where("variant_id IS NULL")
-	}
+		// Check variant-specific or product-level
+		if input.VariantID != nil {
+			query = query.Where("variant_id = ?", *input.VariantID)
+		} else {
+			query = query.Where("variant_id IS NULL")
+		}
 
-	var count int64
-	if err := query.Count(&count).Error; err != nil {
-		return err
-	}
+		var count int64
+		if err := query.Count(&count).Error; err != nil {
+			return err
+		}
 
-	// If already exists, just return success
-	if count > 0 {
-		return nil
-	}
+		// If already exists, just return success
+		if count > 0 {
+			return nil
+		}
+
+		if err := checkCustomerLimit(tx, r.limits, userID, &domain.WishlistItem{}, func(l domain.CustomerLimits) int { return l.WishlistItems }, domain.ErrWishlistFull); err != nil {
+			return err
+		}
 
-	// Create new wishlist item
-	item := &domain.WishlistItem{
-		UserID:       userID,
-		ProductID:    input.ProductID,
-		VariantID:    input.VariantID,
-		VariantSKU:   input.VariantSKU,
-		VariantName:  input.VariantName,
-		PriceAtAdd:   input.PriceAtAdd,
-		NotifyOnSale: input.NotifyOnSale,
-		ProductName:  input.ProductName,
-		ProductSlug:  input.ProductSlug,
-		ProductImage: input.ProductImage,
+		// Create new wishlist item
+		item := &domain.WishlistItem{
+			UserID:        userID,
+			ProductID:     input.ProductID,
+			VariantID:     input.VariantID,
+			VariantSKU:    input.VariantSKU,
+			VariantName:   input.VariantName,
+			PriceAtAdd:    input.PriceAtAdd,
+			NotifyOnSale:  input.NotifyOnSale,
+			NotifyRestock: input.NotifyRestock,
+			ProductName:   input.ProductName,
+			ProductSlug:   input.ProductSlug,
+			ProductImage:  input.ProductImage,
+		}
+		if err := tx.Create(item).Error; err != nil {
+			return err
+		}
+		added = item
+		return nil
+	})
+	if err == nil && added != nil && r.events != nil {
+		r.events.ItemAdded(ctx, *added)
 	}
-	return r.db.WithContext(ctx).Create(item).Error
+	return err
 }
 
 // Remove removes a product from the wishlist (any variant)
 func (r *WishlistRepository) Remove(ctx context.Context, userID, productID uuid.UUID) error {
-	result := r.db.WithContext(ctx).
-		Where("user_id = ? AND product_id = ?", userID, productID).
-		Delete(&domain.WishlistItem{})
-
-	if result.Error != nil {
-		return result.Error
-	}
-	if result.RowsAffected == 0 {
-		return gorm.ErrRecordNotFound
-	}
-	return nil
+	return r.remove(ctx, func(db *gorm.DB) *gorm.DB {
+		return db.Where("user_id = ? AND product_id = ?", userID, productID)
+	})
 }
 
 // RemoveWithVariant removes a specific product/variant from the wishlist
 func (r *WishlistRepository) RemoveWithVariant(ctx context.Context, userID, productID uuid.UUID, variantID *uuid.UUID) error {
-	query := r.db.WithContext(ctx).
-		Where("user_id = ? AND product_id = ?", userID, productID)
-
-	if variantID != nil {
-		query = query.Where("variant_id = ?", *variantID)
-	} else {
-		query = query.Where("variant_id IS NULL")
-	}
-
-	result := query.Delete(&domain.WishlistItem{})
-
-	if result.Error != nil {
-		return result.Error
-	}
-	if result.RowsAffected == 0 {
-		return gorm.ErrRecordNotFound
-	}
-	return nil
+	return r.remove(ctx, func(db *gorm.DB) *gorm.DB {
+		db = db.Where("user_id = ? AND product_id = ?", userID, productID)
+		if variantID != nil {
+			return db.Where("variant_id = ?", *variantID)
+		}
+		return db.Where("variant_id IS NULL")
+	})
 }
 
 // RemoveByID removes a wishlist item by its ID
 func (r *WishlistRepository) RemoveByID(ctx context.Context, userID, itemID uuid.UUID) error {
-	result := r.db.WithContext(ctx).
-		Where("id = ? AND user_id = ?", itemID, userID).
-		Delete(&domain.WishlistItem{})
+	return r.remove(ctx, func(db *gorm.DB) *gorm.DB {
+		return db.Where("id = ? AND user_id = ?", itemID, userID)
+	})
+}
 
+// remove deletes the wishlist items matched by scope, returning
+// gorm.ErrRecordNotFound if there are none. The DELETE returns the rows it
+// removed, so when two requests race only the one that deleted an item
+// reports its removal.
+func (r *WishlistRepository) remove(ctx context.Context, scope func(*gorm.DB) *gorm.DB) error {
+	var items []domain.WishlistItem
+	result := r.db.WithContext(ctx).Scopes(scope).Clauses(clause.Returning{}).Delete(&items)
 	if result.Error != nil {
 		return result.Error
 	}
 	if result.RowsAffected == 0 {
 		return gorm.ErrRecordNotFound
 	}
+	if r.events != nil {
+		for i := range items {
+			r.events.ItemRemoved(ctx, items[i])
+		}
+	}
 	return nil
 }
 
@@ -165,6 +201,31 @@ func (r *WishlistRepository) ExistsWithVariant(ctx context.Context, userID, prod
 	return count > 0, err
 }
 
+// InWishlist checks which of the products are in the user's wishlist with a
+// single query, keyed by ProductRef.Key. Like Exists, a product without a
+// variant matches any of its variants.
+func (r *WishlistRepository) InWishlist(ctx context.Context, userID uuid.UUID, refs []domain.ProductRef) (map[string]bool, error) {
+	var rows []domain.ProductRef
+	err := r.db.WithContext(ctx).Model(&domain.WishlistItem{}).
+		Select("product_id, variant_id").
+		Where("user_id = ? AND product_id IN ?", userID, domain.ProductIDs(refs)).
+		Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	found := make(map[string]bool, 2*len(rows))
+	for _, row := range rows {
+		found[row.ProductID.String()] = true
+		found[row.Key()] = true
+	}
+	memberships := make(map[string]bool, len(refs))
+	for _, ref := range refs {
+		memberships[ref.Key()] = found[ref.Key()]
+	}
+	return memberships, nil
+}
+
 // GetByProductID retrieves all wishlist items for a specific product (all variants)
 func (r *WishlistRepository) GetByProductID(ctx context.Context, userID, productID uuid.UUID) ([]domain.WishlistItem, error) {
 	var items []domain.WishlistItem
@@ -191,6 +252,38 @@ func (r *WishlistRepository) UpdateNotifyOnSale(ctx context.Context, userID, ite
 	return nil
 }
 
+// UpdateNotifyRestock updates whether the item subscribes to back-in-stock
+// when it goes out of stock
+func (r *WishlistRepository) UpdateNotifyRestock(ctx context.Context, userID, itemID uuid.UUID, notify bool) error {
+	result := r.db.WithContext(ctx).
+		Model(&domain.WishlistItem{}).
+		Where("id = ? AND user_id = ?", itemID, userID).
+		Update("notify_restock", notify)
+
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// ListRestockWatchers returns the items with notify_restock set for a product
+// that went out of stock: those for the variant, or for any variant of the
+// product when variantID is nil
+func (r *WishlistRepository) ListRestockWatchers(ctx context.Context, productID uuid.UUID, variantID *uuid.UUID) ([]domain.WishlistItem, error) {
+	query := r.db.WithContext(ctx).
+		Where("product_id = ? AND notify_restock = ?", productID, true)
+	if variantID != nil {
+		query = query.Where("variant_id = ?", *variantID)
+	}
+
+	var items []domain.WishlistItem
+	err := query.Order("created_at ASC").Find(&items).Error
+	return items, err
+}
+
 // GetItemsForPriceDropAlert retrieves items where notify_on_sale is true
 func (r *WishlistRepository) GetItemsForPriceDropAlert(ctx context.Context) ([]domain.WishlistItem, error) {
 	var items []domain.WishlistItem
@@ -208,3 +301,150 @@ func (r *WishlistRepository) CountByUserID(ctx context.Context, userID uuid.UUID
 		Count(&count).Error
 	return count, err
 }
+
+// ListProductIDsMissingInfo returns up to limit distinct product IDs, greater
+// than after, whose wishlist rows lack a denormalized name, slug or image.
+// Pass uuid.Nil to start from the beginning.
+func (r *WishlistRepository) ListProductIDsMissingInfo(ctx context.Context, after uuid.UUID, limit int) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	err := r.db.WithContext(ctx).Model(&domain.WishlistItem{}).
+		Distinct("product_id").
+		Where("product_id > ?", after).
+		Where("product_name IS NULL OR product_slug IS NULL OR product_image IS NULL").
+		Order("product_id").
+		Limit(limit).
+		Pluck("product_id", &ids).Error
+	return ids, err
+}
+
+// UpdateProductInfo sets the denormalized product fields on every wishlist
+// row for the product. Empty values leave the existing field untouched.
+func (r *WishlistRepository) UpdateProductInfo(ctx context.Context, productID uuid.UUID, name, slug, image string) (int64, error) {
+	updates := make(map[string]interface{})
+	if name != "" {
+		updates["product_name"] = name
+	}
+	if slug != "" {
+		updates["product_slug"] = slug
+	}
+	if image != "" {
+		updates["product_image"] = image
+	}
+	if len(updates) == 0 {
+		return 0, nil
+	}
+
+	result := r.db.WithContext(ctx).Model(&domain.WishlistItem{}).
+		Where("product_id = ?", productID).
+		Updates(updates)
+	return result.RowsAffected, result.Error
+}
+
+// staleWishlistItem matches items not yet nudged that were added or last
+// confirmed before the argument
+const staleWishlistItem = "nudged_at IS NULL AND COALESCE(confirmed_at, created_at) < ?"
+
+// ListStaleForNudge returns the stale items of up to limit customers with IDs
+// greater than after, ordered by customer. Only customers who opted in to
+// marketing email are included. It also returns the last customer ID to pass
+// as after for the next page, or uuid.Nil once there are no more customers.
+func (r *WishlistRepository) ListStaleForNudge(ctx context.Context, after uuid.UUID, limit int, staleBefore time.Time) ([]domain.WishlistItem, uuid.UUID, error) {
+	db := r.db.WithContext(ctx)
+	optedIn := db.Model(&domain.MarketingConsent{}).
+		Select("customer_id").
+		Where("email_opt_in = ?", true)
+
+	var userIDs []uuid.UUID
+	err := db.Model(&domain.WishlistItem{}).
+		Where(staleWishlistItem, staleBefore).
+		Where("user_id > ? AND user_id IN (?)", after, optedIn).
+		Distinct("user_id").
+		Order("user_id").
+		Limit(limit).
+		Pluck("user_id", &userIDs).Error
+	if err != nil || len(userIDs) == 0 {
+		return nil, uuid.Nil, err
+	}
+
+	var items []domain.WishlistItem
+	err = db.Where(staleWishlistItem, staleBefore).
+		Where("user_id IN ?", userIDs).
+		Order("user_id, created_at").
+		Find(&items).Error
+	if err != nil {
+		return nil, uuid.Nil, err
+	}
+
+	next := uuid.Nil
+	if len(userIDs) == limit {
+		next = userIDs[len(userIDs)-1]
+	}
+	return items, next, nil
+}
+
+// MarkNudged records that the customer was asked whether they still want the items
+func (r *WishlistRepository) MarkNudged(ctx context.Context, itemIDs []uuid.UUID, now time.Time) error {
+	return r.db.WithContext(ctx).Model(&domain.WishlistItem{}).
+		Where("id IN ? AND nudged_at IS NULL", itemIDs).
+		UpdateColumn("nudged_at", now).Error
+}
+
+// ConfirmNudged keeps the user's nudged items, restarting their retention
+// period. It returns the number of items confirmed.
+func (r *WishlistRepository) ConfirmNudged(ctx context.Context, userID uuid.UUID, now time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).Model(&domain.WishlistItem{}).
+		Where("user_id = ? AND nudged_at IS NOT NULL", userID).
+		UpdateColumns(map[string]interface{}{
+			"nudged_at":    nil,
+			"confirmed_at": now,
+			"updated_at":   now,
+		})
+	return result.RowsAffected, result.Error
+}
+
+// ArchiveUnconfirmed moves up to limit items nudged before nudgedBefore to
+// the archive and returns how many were moved. Archived items leave the
+// wishlist, so with events set their removal is reported once committed.
+func (r *WishlistRepository) ArchiveUnconfirmed(ctx context.Context, nudgedBefore time.Time, limit int, now time.Time) (int, error) {
+	var archived []domain.WishlistItem
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var ids []uuid.UUID
+		err := tx.Model(&domain.WishlistItem{}).
+			Where("nudged_at < ?", nudgedBefore).
+			Order("id").
+			Limit(limit).
+			Pluck("id", &ids).Error
+		if err != nil || len(ids) == 0 {
+			return err
+		}
+
+		// Archive what the DELETE actually removed, so an item a concurrent
+		// run or the customer got to first isn't archived twice
+		var items []domain.WishlistItem
+		if err := tx.Clauses(clause.Returning{}).Where("id IN ?", ids).Delete(&items).Error; err != nil {
+			return err
+		}
+		if len(items) == 0 {
+			return nil
+		}
+		rows := make([]domain.ArchivedWishlistItem, len(items))
+		for i, item := range items {
+			rows[i] = domain.NewArchivedWishlistItem(item, now)
+		}
+		if err := tx.Create(&rows).Error; err != nil {
+			return err
+		}
+		archived = items
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	if r.events != nil {
+		for i := range archived {
+			r.events.ItemRemoved(ctx, archived[i])
+		}
+	}
+	return len(archived), nil
+}
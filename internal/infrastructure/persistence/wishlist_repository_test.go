@@ -3,23 +3,17 @@ package persistence
 import (
 	"context"
 	"testing"
+	"time"
 
-	"github.com/google/uuid"
 	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
-	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 )
 
 func setupWishlistTestDB(t *testing.T) *gorm.DB {
-	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
-	require.NoError(t, err)
-
-	err = db.AutoMigrate(&domain.WishlistItem{})
-	require.NoError(t, err)
-
-	return db
+	return openTestDB(t, &domain.WishlistItem{})
 }
 
 func TestWishlistRepository_Add(t *testing.T) {
@@ -144,3 +138,225 @@ func TestWishlistRepository_RemoveNonExistent(t *testing.T) {
 	assert.Error(t, err)
 	assert.Equal(t, gorm.ErrRecordNotFound, err)
 }
+
+func TestWishlistRepository_BackfillProductInfo(t *testing.T) {
+	db := setupWishlistTestDB(t)
+	repo := NewWishlistRepository(db)
+	ctx := context.Background()
+
+	staleProduct := uuid.New()
+	freshProduct := uuid.New()
+	require.NoError(t, repo.Add(ctx, uuid.New(), staleProduct))
+	require.NoError(t, repo.Add(ctx, uuid.New(), staleProduct))
+
+	name, slug, image := "Tudung Bawal", "tudung-bawal", "https://cdn.example.com/tb.jpg"
+	require.NoError(t, db.Create(&domain.WishlistItem{
+		UserID:       uuid.New(),
+		ProductID:    freshProduct,
+		ProductName:  &name,
+		ProductSlug:  &slug,
+		ProductImage: &image,
+	}).Error)
+
+	ids, err := repo.ListProductIDsMissingInfo(ctx, uuid.Nil, 10)
+	require.NoError(t, err)
+	assert.Equal(t, []uuid.UUID{staleProduct}, ids)
+
+	updated, err := repo.UpdateProductInfo(ctx, staleProduct, "Baju Kurung", "baju-kurung", "https://cdn.example.com/bk.jpg")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), updated)
+
+	ids, err = repo.ListProductIDsMissingInfo(ctx, uuid.Nil, 10)
+	require.NoError(t, err)
+	assert.Empty(t, ids)
+}
+
+func TestWishlistRepository_InWishlist(t *testing.T) {
+	db := setupWishlistTestDB(t)
+	repo := NewWishlistRepository(db)
+	ctx := context.Background()
+
+	userID := uuid.New()
+	productID, otherProductID, missingID := uuid.New(), uuid.New(), uuid.New()
+	variantID, otherVariantID := uuid.New(), uuid.New()
+	require.NoError(t, db.Create(&domain.WishlistItem{UserID: userID, ProductID: productID, VariantID: &variantID}).Error)
+	require.NoError(t, db.Create(&domain.WishlistItem{UserID: userID, ProductID: otherProductID}).Error)
+	require.NoError(t, db.Create(&domain.WishlistItem{UserID: uuid.New(), ProductID: missingID}).Error)
+
+	refs := []domain.ProductRef{
+		{ProductID: productID},
+		{ProductID: productID, VariantID: &variantID},
+		{ProductID: productID, VariantID: &otherVariantID},
+		{ProductID: otherProductID},
+		{ProductID: missingID},
+	}
+	memberships, err := repo.InWishlist(ctx, userID, refs)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]bool{
+		productID.String(): true,
+		productID.String() + ":" + variantID.String():      true,
+		productID.String() + ":" + otherVariantID.String(): false,
+		otherProductID.String():                            true,
+		missingID.String():                                 false,
+	}, memberships)
+}
+
+func TestWishlistRepository_ListRestockWatchers(t *testing.T) {
+	db := setupWishlistTestDB(t)
+	repo := NewWishlistRepository(db)
+	ctx := context.Background()
+
+	productID, variantID, otherVariantID := uuid.New(), uuid.New(), uuid.New()
+	items := []domain.WishlistItem{
+		{UserID: uuid.New(), ProductID: productID, VariantID: &variantID, NotifyRestock: true},
+		{UserID: uuid.New(), ProductID: productID, VariantID: &otherVariantID, NotifyRestock: true},
+		{UserID: uuid.New(), ProductID: productID, NotifyRestock: true},
+		{UserID: uuid.New(), ProductID: productID, VariantID: &variantID},
+		{UserID: uuid.New(), ProductID: uuid.New(), NotifyRestock: true},
+	}
+	require.NoError(t, db.Create(&items).Error)
+
+	watchers, err := repo.ListRestockWatchers(ctx, productID, &variantID)
+	require.NoError(t, err)
+	require.Len(t, watchers, 1)
+	assert.Equal(t, items[0].ID, watchers[0].ID)
+
+	watchers, err = repo.ListRestockWatchers(ctx, productID, nil)
+	require.NoError(t, err)
+	assert.Len(t, watchers, 3)
+}
+
+type recordedWishlistEvents struct {
+	added, removed []domain.WishlistItem
+}
+
+func (e *recordedWishlistEvents) ItemAdded(_ context.Context, item domain.WishlistItem) {
+	e.added = append(e.added, item)
+}
+
+func (e *recordedWishlistEvents) ItemRemoved(_ context.Context, item domain.WishlistItem) {
+	e.removed = append(e.removed, item)
+}
+
+func TestWishlistRepository_Events(t *testing.T) {
+	db := setupWishlistTestDB(t)
+	events := &recordedWishlistEvents{}
+	repo := NewWishlistRepository(db).WithEvents(events)
+	ctx := context.Background()
+
+	userID, productID, variantID := uuid.New(), uuid.New(), uuid.New()
+	input := AddWishlistItemInput{ProductID: productID, VariantID: &variantID, PriceAtAdd: 89.9}
+	require.NoError(t, repo.AddWithVariant(ctx, userID, input))
+	require.NoError(t, repo.AddWithVariant(ctx, userID, input))
+	require.NoError(t, repo.Add(ctx, userID, productID))
+	require.Len(t, events.added, 2, "adding an item already on the wishlist is not reported")
+	assert.Equal(t, 89.9, events.added[0].PriceAtAdd)
+	assert.Equal(t, variantID, *events.added[0].VariantID)
+
+	require.NoError(t, repo.RemoveWithVariant(ctx, userID, productID, &variantID))
+	require.Len(t, events.removed, 1)
+	assert.Equal(t, events.added[0].ID, events.removed[0].ID)
+	assert.Equal(t, 89.9, events.removed[0].PriceAtAdd, "the removed row is returned by the delete")
+
+	assert.ErrorIs(t, repo.RemoveByID(ctx, userID, events.added[0].ID), gorm.ErrRecordNotFound)
+	require.NoError(t, repo.Remove(ctx, userID, productID))
+	require.Len(t, events.removed, 2)
+	assert.Equal(t, events.added[1].ID, events.removed[1].ID)
+}
+
+func TestWishlistRepository_RetentionNudgesOptedInCustomers(t *testing.T) {
+	db := openTestDB(t, &domain.WishlistItem{}, &domain.MarketingConsent{}, &domain.ArchivedWishlistItem{})
+	repo := NewWishlistRepository(db)
+	ctx := context.Background()
+	now := time.Now()
+	policy := domain.WishlistRetentionPolicy{StaleMonths: 6, ConfirmDays: 14}
+
+	optedIn, optedOut, noConsent := uuid.New(), uuid.New(), uuid.New()
+	require.NoError(t, db.Create(&domain.MarketingConsent{CustomerID: optedIn, EmailOptIn: true}).Error)
+	require.NoError(t, db.Create(&domain.MarketingConsent{CustomerID: optedOut, SMSOptIn: true}).Error)
+
+	old := now.AddDate(-1, 0, 0)
+	recentlyConfirmed := now.AddDate(0, -1, 0)
+	stale := domain.WishlistItem{UserID: optedIn, ProductID: uuid.New(), CreatedAt: old}
+	confirmed := domain.WishlistItem{UserID: optedIn, ProductID: uuid.New(), CreatedAt: old, ConfirmedAt: &recentlyConfirmed}
+	fresh := domain.WishlistItem{UserID: optedIn, ProductID: uuid.New(), CreatedAt: now}
+	for _, item := range []*domain.WishlistItem{
+		&stale, &confirmed, &fresh,
+		{UserID: optedOut, ProductID: uuid.New(), CreatedAt: old},
+		{UserID: noConsent, ProductID: uuid.New(), CreatedAt: old},
+	} {
+		require.NoError(t, db.Create(item).Error)
+	}
+
+	items, next, err := repo.ListStaleForNudge(ctx, uuid.Nil, 10, policy.StaleBefore(now))
+	require.NoError(t, err)
+	require.Len(t, items, 1, "only stale items of customers who opted in to email")
+	assert.Equal(t, stale.ID, items[0].ID)
+	assert.Equal(t, uuid.Nil, next)
+
+	require.NoError(t, repo.MarkNudged(ctx, []uuid.UUID{stale.ID}, now))
+	items, _, err = repo.ListStaleForNudge(ctx, uuid.Nil, 10, policy.StaleBefore(now))
+	require.NoError(t, err)
+	assert.Empty(t, items, "customers are nudged about an item once")
+}
+
+func TestWishlistRepository_ConfirmNudged(t *testing.T) {
+	db := setupWishlistTestDB(t)
+	repo := NewWishlistRepository(db)
+	ctx := context.Background()
+	now := time.Now()
+
+	userID := uuid.New()
+	nudged := domain.WishlistItem{UserID: userID, ProductID: uuid.New(), NudgedAt: &now}
+	other := domain.WishlistItem{UserID: userID, ProductID: uuid.New()}
+	require.NoError(t, db.Create(&nudged).Error)
+	require.NoError(t, db.Create(&other).Error)
+
+	confirmed, err := repo.ConfirmNudged(ctx, userID, now)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), confirmed)
+
+	var item domain.WishlistItem
+	require.NoError(t, db.First(&item, "id = ?", nudged.ID).Error)
+	assert.Nil(t, item.NudgedAt)
+	require.NotNil(t, item.ConfirmedAt)
+	assert.WithinDuration(t, now, *item.ConfirmedAt, time.Second)
+}
+
+func TestWishlistRepository_ArchiveUnconfirmed(t *testing.T) {
+	db := openTestDB(t, &domain.WishlistItem{}, &domain.ArchivedWishlistItem{})
+	events := &recordedWishlistEvents{}
+	repo := NewWishlistRepository(db).WithEvents(events)
+	ctx := context.Background()
+	now := time.Now()
+	policy := domain.WishlistRetentionPolicy{StaleMonths: 6, ConfirmDays: 14}
+
+	userID := uuid.New()
+	expiredNudge := now.AddDate(0, 0, -15)
+	recentNudge := now.AddDate(0, 0, -3)
+	name := "Baju Kurung Moden"
+	expired := domain.WishlistItem{UserID: userID, ProductID: uuid.New(), ProductName: &name, PriceAtAdd: 129, NudgedAt: &expiredNudge}
+	pending := domain.WishlistItem{UserID: userID, ProductID: uuid.New(), NudgedAt: &recentNudge}
+	require.NoError(t, db.Create(&expired).Error)
+	require.NoError(t, db.Create(&pending).Error)
+
+	archived, err := repo.ArchiveUnconfirmed(ctx, policy.ArchiveBefore(now), 10, now)
+	require.NoError(t, err)
+	assert.Equal(t, 1, archived)
+
+	items, err := repo.ListByUserID(ctx, userID)
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	assert.Equal(t, pending.ID, items[0].ID, "items still within the confirmation window stay")
+
+	var rows []domain.ArchivedWishlistItem
+	require.NoError(t, db.Find(&rows).Error)
+	require.Len(t, rows, 1)
+	assert.Equal(t, expired.ID, rows[0].ID)
+	assert.Equal(t, "Baju Kurung Moden", *rows[0].ProductName)
+	assert.Equal(t, 129.0, rows[0].PriceAtAdd)
+	assert.WithinDuration(t, expiredNudge, rows[0].NudgedAt, time.Second)
+
+	require.Len(t, events.removed, 1, "archived items are reported as removed")
+	assert.Equal(t, expired.ID, events.removed[0].ID)
+}
@@ -0,0 +1,349 @@
+// Package sftpclient uploads files to SFTP servers. It speaks just enough of
+// SFTP version 3 to write a file: open, write, close and rename.
+package sftpclient
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// SFTP packet types
+const (
+	fxpInit    = 1
+	fxpVersion = 2
+	fxpOpen    = 3
+	fxpClose   = 4
+	fxpWrite   = 6
+	fxpRemove  = 13
+	fxpRename  = 18
+	fxpStatus  = 101
+	fxpHandle  = 102
+)
+
+// SFTP open flags
+const (
+	fxfWrite = 0x02
+	fxfCreat = 0x08
+	fxfTrunc = 0x10
+)
+
+const (
+	protocolVersion = 3
+	fxOK            = 0
+
+	// chunkSize is the payload of each write; servers must accept 32KB
+	chunkSize = 32 * 1024
+	// maxPacket bounds the replies read from the server
+	maxPacket = 256 * 1024
+)
+
+// ErrHostKeyMismatch is returned when the server's host key doesn't have the
+// configured fingerprint
+var ErrHostKeyMismatch = errors.New("sftp host key does not match the configured fingerprint")
+
+// Config configures a connection to an SFTP server. HostKey is the SHA256
+// fingerprint of the server's key as printed by ssh-keygen -l, e.g.
+// "SHA256:nThbg6kXUpJWGl7E1IGOCspRomTxdCARLviKw6E5SY8". Password and
+// PrivateKey (PEM) may both be set.
+type Config struct {
+	Addr       string // host:port
+	User       string
+	Password   string
+	PrivateKey string
+	HostKey    string
+	Timeout    time.Duration // dialing and the SSH handshake
+}
+
+// Client uploads files to one SFTP server
+type Client struct {
+	addr string
+	ssh  *ssh.ClientConfig
+}
+
+// New creates a client for the server in cfg. Nothing is dialed until a file
+// is uploaded.
+func New(cfg Config) (*Client, error) {
+	if cfg.Addr == "" || cfg.User == "" || cfg.HostKey == "" {
+		return nil, errors.New("sftp needs an address, user and host key")
+	}
+	var auth []ssh.AuthMethod
+	if cfg.PrivateKey != "" {
+		signer, err := ssh.ParsePrivateKey([]byte(cfg.PrivateKey))
+		if err != nil {
+			return nil, fmt.Errorf("invalid sftp private key: %w", err)
+		}
+		auth = append(auth, ssh.PublicKeys(signer))
+	}
+	if cfg.Password != "" {
+		auth = append(auth, ssh.Password(cfg.Password))
+	}
+	if len(auth) == 0 {
+		return nil, errors.New("sftp needs a password or a private key")
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 30 * time.Second
+	}
+
+	fingerprint := cfg.HostKey
+	return &Client{
+		addr: cfg.Addr,
+		ssh: &ssh.ClientConfig{
+			User: cfg.User,
+			Auth: auth,
+			HostKeyCallback: func(_ string, _ net.Addr, key ssh.PublicKey) error {
+				if ssh.FingerprintSHA256(key) != fingerprint {
+					return ErrHostKeyMismatch
+				}
+				return nil
+			},
+			Timeout: cfg.Timeout,
+		},
+	}, nil
+}
+
+// Upload writes body to remotePath, replacing any file there. The file is
+// written under a temporary name and renamed once complete, so readers
+// polling the directory never pick up a partial file.
+func (c *Client) Upload(ctx context.Context, remotePath string, body io.Reader) (int64, error) {
+	dialer := net.Dialer{Timeout: c.ssh.Timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", c.addr)
+	if err != nil {
+		return 0, err
+	}
+	sshConn, chans, reqs, err := ssh.NewClientConn(conn, c.addr, c.ssh)
+	if err != nil {
+		conn.Close()
+		return 0, err
+	}
+	client := ssh.NewClient(sshConn, chans, reqs)
+	defer client.Close()
+
+	// Closing the connection unblocks the session when ctx ends mid-upload
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			client.Close()
+		case <-done:
+		}
+	}()
+
+	s, err := openSession(client)
+	if err != nil {
+		return 0, err
+	}
+	defer s.close()
+
+	partial := remotePath + ".part"
+	n, err := s.write(partial, body)
+	if err == nil {
+		rename := func(b []byte) []byte { return appendString(appendString(b, partial), remotePath) }
+		// SFTP v3 servers may refuse to rename over an existing file
+		if err = s.call(fxpRename, rename, nil); err != nil {
+			if s.call(fxpRemove, func(b []byte) []byte { return appendString(b, remotePath) }, nil) == nil {
+				err = s.call(fxpRename, rename, nil)
+			}
+		}
+	}
+	if ctx.Err() != nil {
+		return n, ctx.Err()
+	}
+	return n, err
+}
+
+// session is an SFTP subsystem on an SSH session. Requests are sent one at a
+// time, so replies need no matching beyond a sanity check of their ID.
+type session struct {
+	ssh *ssh.Session
+	in  io.WriteCloser
+	out *bufio.Reader
+	id  uint32
+}
+
+func openSession(client *ssh.Client) (*session, error) {
+	sshSession, err := client.NewSession()
+	if err != nil {
+		return nil, err
+	}
+	in, err := sshSession.StdinPipe()
+	if err != nil {
+		sshSession.Close()
+		return nil, err
+	}
+	out, err := sshSession.StdoutPipe()
+	if err != nil {
+		sshSession.Close()
+		return nil, err
+	}
+	if err := sshSession.RequestSubsystem("sftp"); err != nil {
+		sshSession.Close()
+		return nil, fmt.Errorf("sftp subsystem: %w", err)
+	}
+
+	s := &session{ssh: sshSession, in: in, out: bufio.NewReader(out)}
+	if err := s.send(fxpInit, binary.BigEndian.AppendUint32(nil, protocolVersion)); err != nil {
+		s.close()
+		return nil, err
+	}
+	typ, _, err := s.recv()
+	if err != nil {
+		s.close()
+		return nil, err
+	}
+	if typ != fxpVersion {
+		s.close()
+		return nil, fmt.Errorf("sftp: unexpected packet %d during init", typ)
+	}
+	return s, nil
+}
+
+func (s *session) close() {
+	s.in.Close()
+	s.ssh.Close()
+}
+
+// write creates or truncates path and streams body into it
+func (s *session) write(path string, body io.Reader) (int64, error) {
+	var handle string
+	err := s.call(fxpOpen, func(b []byte) []byte {
+		b = appendString(b, path)
+		b = binary.BigEndian.AppendUint32(b, fxfWrite|fxfCreat|fxfTrunc)
+		return binary.BigEndian.AppendUint32(b, 0) // no attributes
+	}, &handle)
+	if err != nil {
+		return 0, fmt.Errorf("open %s: %w", path, err)
+	}
+
+	var offset int64
+	buf := make([]byte, chunkSize)
+	for {
+		n, readErr := io.ReadFull(body, buf)
+		if n > 0 {
+			chunk := buf[:n]
+			if err := s.call(fxpWrite, func(b []byte) []byte {
+				b = appendString(b, handle)
+				b = binary.BigEndian.AppendUint64(b, uint64(offset))
+				return appendString(b, string(chunk))
+			}, nil); err != nil {
+				s.call(fxpClose, func(b []byte) []byte { return appendString(b, handle) }, nil)
+				return offset, fmt.Errorf("write %s: %w", path, err)
+			}
+			offset += int64(n)
+		}
+		if readErr == io.EOF || readErr == io.ErrUnexpectedEOF {
+			break
+		}
+		if readErr != nil {
+			s.call(fxpClose, func(b []byte) []byte { return appendString(b, handle) }, nil)
+			return offset, readErr
+		}
+	}
+
+	if err := s.call(fxpClose, func(b []byte) []byte { return appendString(b, handle) }, nil); err != nil {
+		return offset, fmt.Errorf("close %s: %w", path, err)
+	}
+	return offset, nil
+}
+
+// call sends a request built by body after its ID and waits for the reply.
+// A handle reply is stored in handle; a status reply is returned as an error
+// unless it is OK.
+func (s *session) call(typ byte, body func([]byte) []byte, handle *string) error {
+	s.id++
+	id := s.id
+	if err := s.send(typ, body(binary.BigEndian.AppendUint32(nil, id))); err != nil {
+		return err
+	}
+
+	replyType, payload, err := s.recv()
+	if err != nil {
+		return err
+	}
+	if len(payload) < 4 || binary.BigEndian.Uint32(payload) != id {
+		return errors.New("sftp: reply does not match the request")
+	}
+	payload = payload[4:]
+
+	switch replyType {
+	case fxpStatus:
+		if len(payload) < 4 {
+			return errors.New("sftp: short status reply")
+		}
+		code := binary.BigEndian.Uint32(payload)
+		if code == fxOK {
+			return nil
+		}
+		msg, _ := readString(payload[4:])
+		return &StatusError{Code: code, Message: msg}
+	case fxpHandle:
+		h, ok := readString(payload)
+		if !ok || handle == nil {
+			return errors.New("sftp: unexpected handle reply")
+		}
+		*handle = h
+		return nil
+	default:
+		return fmt.Errorf("sftp: unexpected packet %d", replyType)
+	}
+}
+
+func (s *session) send(typ byte, payload []byte) error {
+	packet := binary.BigEndian.AppendUint32(make([]byte, 0, 5+len(payload)), uint32(1+len(payload)))
+	packet = append(packet, typ)
+	_, err := s.in.Write(append(packet, payload...))
+	return err
+}
+
+func (s *session) recv() (byte, []byte, error) {
+	var header [5]byte
+	if _, err := io.ReadFull(s.out, header[:]); err != nil {
+		return 0, nil, err
+	}
+	length := binary.BigEndian.Uint32(header[:4])
+	if length < 1 || length > maxPacket {
+		return 0, nil, fmt.Errorf("sftp: invalid packet length %d", length)
+	}
+	payload := make([]byte, length-1)
+	if _, err := io.ReadFull(s.out, payload); err != nil {
+		return 0, nil, err
+	}
+	return header[4], payload, nil
+}
+
+// StatusError is a failure reported by the server
+type StatusError struct {
+	Code    uint32
+	Message string
+}
+
+func (e *StatusError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("sftp status %d", e.Code)
+	}
+	return fmt.Sprintf("sftp status %d: %s", e.Code, e.Message)
+}
+
+func appendString(b []byte, s string) []byte {
+	b = binary.BigEndian.AppendUint32(b, uint32(len(s)))
+	return append(b, s...)
+}
+
+func readString(b []byte) (string, bool) {
+	if len(b) < 4 {
+		return "", false
+	}
+	n := binary.BigEndian.Uint32(b)
+	if uint32(len(b)-4) < n {
+		return "", false
+	}
+	return string(b[4 : 4+n]), true
+}
@@ -0,0 +1,207 @@
+package sftpclient
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/binary"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+)
+
+// testServer is an SSH server whose SFTP subsystem keeps files in memory
+type testServer struct {
+	addr        string
+	fingerprint string
+
+	mu    sync.Mutex
+	files map[string][]byte
+}
+
+func newTestServer(t *testing.T, password string) *testServer {
+	t.Helper()
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	signer, err := ssh.NewSignerFromKey(key)
+	require.NoError(t, err)
+
+	config := &ssh.ServerConfig{
+		PasswordCallback: func(conn ssh.ConnMetadata, pass []byte) (*ssh.Permissions, error) {
+			if conn.User() == "crm" && string(pass) == password {
+				return nil, nil
+			}
+			return nil, io.EOF
+		},
+	}
+	config.AddHostKey(signer)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+
+	srv := &testServer{
+		addr:        listener.Addr().String(),
+		fingerprint: ssh.FingerprintSHA256(signer.PublicKey()),
+		files:       make(map[string][]byte),
+	}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go srv.serveConn(conn, config)
+		}
+	}()
+	return srv
+}
+
+func (s *testServer) serveConn(conn net.Conn, config *ssh.ServerConfig) {
+	_, chans, reqs, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		conn.Close()
+		return
+	}
+	go ssh.DiscardRequests(reqs)
+	for newChannel := range chans {
+		channel, requests, err := newChannel.Accept()
+		if err != nil {
+			continue
+		}
+		go func() {
+			for req := range requests {
+				ok := req.Type == "subsystem" && string(req.Payload[4:]) == "sftp"
+				req.Reply(ok, nil)
+				if ok {
+					go s.serveSFTP(channel)
+				}
+			}
+		}()
+	}
+}
+
+func (s *testServer) serveSFTP(channel ssh.Channel) {
+	defer channel.Close()
+	open := make(map[string]string) // handle -> path
+	reply := func(typ byte, payload []byte) {
+		packet := binary.BigEndian.AppendUint32(nil, uint32(1+len(payload)))
+		channel.Write(append(append(packet, typ), payload...))
+	}
+	status := func(id []byte, code uint32) {
+		reply(fxpStatus, appendString(appendString(binary.BigEndian.AppendUint32(id, code), ""), ""))
+	}
+
+	for {
+		var header [5]byte
+		if _, err := io.ReadFull(channel, header[:]); err != nil {
+			return
+		}
+		payload := make([]byte, binary.BigEndian.Uint32(header[:4])-1)
+		if _, err := io.ReadFull(channel, payload); err != nil {
+			return
+		}
+		if header[4] == fxpInit {
+			reply(fxpVersion, binary.BigEndian.AppendUint32(nil, protocolVersion))
+			continue
+		}
+		id, body := append([]byte(nil), payload[:4]...), payload[4:]
+		first, _ := readString(body)
+		rest := body[4+len(first):]
+
+		s.mu.Lock()
+		switch header[4] {
+		case fxpOpen:
+			handle := "h" + first
+			open[handle] = first
+			s.files[first] = nil
+			reply(fxpHandle, appendString(id, handle))
+		case fxpWrite:
+			path := open[first]
+			offset := binary.BigEndian.Uint64(rest)
+			data, _ := readString(rest[8:])
+			file := s.files[path]
+			if uint64(len(file)) < offset+uint64(len(data)) {
+				file = append(file, make([]byte, int(offset)+len(data)-len(file))...)
+			}
+			copy(file[offset:], data)
+			s.files[path] = file
+			status(id, fxOK)
+		case fxpClose:
+			delete(open, first)
+			status(id, fxOK)
+		case fxpRemove:
+			delete(s.files, first)
+			status(id, fxOK)
+		case fxpRename:
+			target, _ := readString(rest)
+			if _, exists := s.files[target]; exists {
+				status(id, 4) // SSH_FX_FAILURE, as OpenSSH answers
+				break
+			}
+			s.files[target] = s.files[first]
+			delete(s.files, first)
+			status(id, fxOK)
+		default:
+			status(id, 8) // SSH_FX_OP_UNSUPPORTED
+		}
+		s.mu.Unlock()
+	}
+}
+
+func (s *testServer) file(path string) ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	file, ok := s.files[path]
+	return file, ok
+}
+
+func TestClient_Upload(t *testing.T) {
+	srv := newTestServer(t, "s3cret")
+	client, err := New(Config{Addr: srv.addr, User: "crm", Password: "s3cret", HostKey: srv.fingerprint, Timeout: 5 * time.Second})
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	// Larger than one write so the offsets are exercised
+	content := bytes.Repeat([]byte("id,email\n1,aisyah@example.com\n"), 3000)
+	n, err := client.Upload(ctx, "/exports/customers.csv", bytes.NewReader(content))
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(content)), n)
+	file, ok := srv.file("/exports/customers.csv")
+	require.True(t, ok)
+	assert.Equal(t, content, file)
+	_, partial := srv.file("/exports/customers.csv.part")
+	assert.False(t, partial)
+
+	_, err = client.Upload(ctx, "/exports/customers.csv", strings.NewReader("id,email\n"))
+	require.NoError(t, err, "an existing file is replaced")
+	file, _ = srv.file("/exports/customers.csv")
+	assert.Equal(t, "id,email\n", string(file))
+}
+
+func TestClient_RejectsUnknownHostKey(t *testing.T) {
+	srv := newTestServer(t, "s3cret")
+	client, err := New(Config{Addr: srv.addr, User: "crm", Password: "s3cret",
+		HostKey: "SHA256:nThbg6kXUpJWGl7E1IGOCspRomTxdCARLviKw6E5SY8", Timeout: 5 * time.Second})
+	require.NoError(t, err)
+
+	_, err = client.Upload(context.Background(), "/exports/customers.csv", strings.NewReader("id\n"))
+	assert.ErrorIs(t, err, ErrHostKeyMismatch)
+	_, ok := srv.file("/exports/customers.csv.part")
+	assert.False(t, ok)
+}
+
+func TestNew_NeedsCredentials(t *testing.T) {
+	_, err := New(Config{Addr: "sftp.example.com:22", User: "crm", HostKey: "SHA256:x"})
+	assert.Error(t, err)
+	_, err = New(Config{Addr: "sftp.example.com:22", User: "crm", HostKey: "SHA256:x", PrivateKey: "not a key"})
+	assert.Error(t, err)
+}
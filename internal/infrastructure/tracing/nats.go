@@ -0,0 +1,62 @@
+package tracing
+
+import (
+	"context"
+
+	"github.com/nats-io/nats.go"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// natsCarrier adapts NATS headers for the propagator. Unlike HTTP headers
+// they are case-sensitive, and other services read the lowercase W3C names.
+type natsCarrier nats.Header
+
+func (h natsCarrier) Get(key string) string { return nats.Header(h).Get(key) }
+func (h natsCarrier) Set(key, value string) { nats.Header(h).Set(key, value) }
+
+func (h natsCarrier) Keys() []string {
+	keys := make([]string, 0, len(h))
+	for key := range h {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// NewMsg creates a message carrying the trace context of ctx in its headers
+func NewMsg(ctx context.Context, subject string, data []byte) *nats.Msg {
+	msg := nats.NewMsg(subject)
+	msg.Data = data
+	otel.GetTextMapPropagator().Inject(ctx, natsCarrier(msg.Header))
+	return msg
+}
+
+// Publish publishes data on subject in a producer span, with the trace context
+// in the message headers so subscribers continue the trace
+func Publish(ctx context.Context, nc *nats.Conn, subject string, data []byte) error {
+	ctx, span := tracer().Start(ctx, "publish "+subject,
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			attribute.String("messaging.system", "nats"),
+			attribute.String("messaging.destination.name", subject),
+		))
+	err := nc.PublishMsg(NewMsg(ctx, subject, data))
+	End(span, err)
+	return err
+}
+
+// StartConsume starts a consumer span for a received message, continuing the
+// publisher's trace. End it with End once the message is handled.
+func StartConsume(msg *nats.Msg) (context.Context, trace.Span) {
+	ctx := context.Background()
+	if msg.Header != nil {
+		ctx = otel.GetTextMapPropagator().Extract(ctx, natsCarrier(msg.Header))
+	}
+	return tracer().Start(ctx, "process "+msg.Subject,
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			attribute.String("messaging.system", "nats"),
+			attribute.String("messaging.destination.name", msg.Subject),
+		))
+}
@@ -0,0 +1,163 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+const incomingTraceparent = "00-4bf92f3577b34da6a3ce929b0e0e4736-00f067aa0ba902b7-01"
+
+// setupTracing installs a recording tracer provider for the test
+func setupTracing(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	_, err := Init(context.Background(), Config{})
+	require.NoError(t, err)
+
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+	return recorder
+}
+
+// spanNamed returns the last ended span with the given name
+func spanNamed(t *testing.T, recorder *tracetest.SpanRecorder, name string) sdktrace.ReadOnlySpan {
+	t.Helper()
+	ended := recorder.Ended()
+	for i := len(ended) - 1; i >= 0; i-- {
+		if ended[i].Name() == name {
+			return ended[i]
+		}
+	}
+	require.FailNow(t, "span not found", name)
+	return nil
+}
+
+func TestMiddleware_ContinuesIncomingTraceAcrossOutgoingCalls(t *testing.T) {
+	recorder := setupTracing(t)
+
+	var downstreamTraceparent string
+	orderService := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		downstreamTraceparent = r.Header.Get("traceparent")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer orderService.Close()
+	client := &http.Client{Transport: Transport(nil)}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(Middleware())
+	router.GET("/customers/:id", func(c *gin.Context) {
+		req, err := http.NewRequestWithContext(c.Request.Context(), http.MethodGet, orderService.URL+"/api/v1/orders", nil)
+		require.NoError(t, err)
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		c.Status(http.StatusInternalServerError)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/customers/1", nil)
+	req.Header.Set("traceparent", incomingTraceparent)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	server := spanNamed(t, recorder, "GET /customers/:id")
+	assert.Equal(t, "4bf92f3577b34da6a3ce929b0e0e4736", server.SpanContext().TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", server.Parent().SpanID().String())
+	assert.Equal(t, trace.SpanKindServer, server.SpanKind())
+	assert.Equal(t, codes.Error, server.Status().Code)
+	assert.Equal(t, server.SpanContext().TraceID().String(), rec.Header().Get(HeaderTraceID))
+
+	outgoing := spanNamed(t, recorder, "HTTP GET")
+	assert.Equal(t, server.SpanContext().SpanID(), outgoing.Parent().SpanID())
+	assert.Equal(t, trace.SpanKindClient, outgoing.SpanKind())
+	assert.Equal(t, "00-4bf92f3577b34da6a3ce929b0e0e4736-"+outgoing.SpanContext().SpanID().String()+"-01", downstreamTraceparent)
+}
+
+func TestTransport_DoesNotModifyCallerRequest(t *testing.T) {
+	setupTracing(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	ctx, span := tracer().Start(context.Background(), "parent")
+	defer span.End()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	require.NoError(t, err)
+
+	resp, err := (&http.Client{Transport: Transport(nil)}).Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Empty(t, req.Header.Get("traceparent"))
+}
+
+func TestGORMPlugin_SpansUnderStatementContext(t *testing.T) {
+	recorder := setupTracing(t)
+
+	type tracingWidget struct {
+		ID   uint
+		Name string
+	}
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.Use(GORMPlugin{}))
+	require.NoError(t, db.AutoMigrate(&tracingWidget{}))
+
+	ctx, parent := tracer().Start(context.Background(), "request")
+	require.NoError(t, db.WithContext(ctx).Create(&tracingWidget{Name: "a"}).Error)
+	var found tracingWidget
+	assert.ErrorIs(t, db.WithContext(ctx).First(&found, 99).Error, gorm.ErrRecordNotFound)
+	assert.Error(t, db.WithContext(ctx).Exec("SELECT * FROM missing_table").Error)
+	parent.End()
+
+	create := spanNamed(t, recorder, "db.create tracing_widgets")
+	assert.Equal(t, parent.SpanContext().SpanID(), create.Parent().SpanID())
+	assert.Contains(t, create.Attributes(), attribute.String("db.system.name", "sqlite"))
+	assert.Equal(t, codes.Unset, create.Status().Code)
+
+	// Not found is an answer, not a failure
+	query := spanNamed(t, recorder, "db.query tracing_widgets")
+	assert.Equal(t, codes.Unset, query.Status().Code)
+
+	raw := spanNamed(t, recorder, "db.raw")
+	assert.Equal(t, parent.SpanContext().SpanID(), raw.Parent().SpanID())
+	assert.Equal(t, codes.Error, raw.Status().Code)
+}
+
+func TestNATS_ConsumerContinuesPublisherTrace(t *testing.T) {
+	recorder := setupTracing(t)
+
+	ctx, publisher := tracer().Start(context.Background(), "publisher")
+	msg := NewMsg(ctx, "customer.note.mentioned", []byte(`{}`))
+	publisher.End()
+
+	// W3C header names, which other services read case-sensitively
+	assert.NotEmpty(t, msg.Header.Get("traceparent"))
+
+	_, consumer := StartConsume(msg)
+	End(consumer, nil)
+
+	span := spanNamed(t, recorder, "process customer.note.mentioned")
+	assert.Equal(t, publisher.SpanContext().TraceID(), span.SpanContext().TraceID())
+	assert.Equal(t, publisher.SpanContext().SpanID(), span.Parent().SpanID())
+	assert.Equal(t, trace.SpanKindConsumer, span.SpanKind())
+}
+
+func TestInit_RejectsInvalidEndpoint(t *testing.T) {
+	_, err := Init(context.Background(), Config{Endpoint: "not a url"})
+	assert.Error(t, err)
+}
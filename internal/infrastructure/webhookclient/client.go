@@ -0,0 +1,96 @@
+// Package webhookclient sends signed customer events to webhook subscribers.
+package webhookclient
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/tracing"
+)
+
+// Delivery request headers. The signature is domain.WebhookSignature of the
+// timestamp and body, as for the inventory webhook.
+const (
+	HeaderEvent      = "X-Webhook-Event"
+	HeaderDeliveryID = "X-Webhook-Delivery"
+	HeaderTimestamp  = "X-Webhook-Timestamp" // unix seconds
+	HeaderSignature  = "X-Webhook-Signature"
+)
+
+// maxResponseLog bounds the response body kept in the delivery log
+const maxResponseLog = 2048
+
+// Client POSTs deliveries to subscriber URLs. It makes one attempt per call;
+// retries are scheduled by the delivery job.
+type Client struct {
+	httpClient *http.Client
+	timeout    time.Duration
+	now        func() time.Time
+}
+
+// New creates a webhook client with a per-attempt timeout
+func New(timeout time.Duration) *Client {
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	return &Client{
+		httpClient: &http.Client{
+			Transport: tracing.Transport(nil),
+			// A subscriber redirecting elsewhere would get the signed body
+			// re-sent to a URL no admin registered
+			CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+		},
+		timeout: timeout,
+		now:     time.Now,
+	}
+}
+
+// Send makes one attempt to deliver to subscription and returns its log
+// entry. A 2xx response is a success; anything else sets the entry's Error.
+func (c *Client) Send(ctx context.Context, subscription *domain.WebhookSubscription, delivery *domain.OutboundWebhookDelivery) *domain.OutboundWebhookAttempt {
+	start := c.now()
+	attempt := &domain.OutboundWebhookAttempt{AttemptedAt: start}
+	statusCode, body, err := c.post(ctx, subscription, delivery, start)
+	attempt.DurationMs = c.now().Sub(start).Milliseconds()
+	attempt.StatusCode = statusCode
+	attempt.ResponseBody = body
+	if err != nil {
+		attempt.Error = err.Error()
+	}
+	return attempt
+}
+
+func (c *Client) post(ctx context.Context, subscription *domain.WebhookSubscription, delivery *domain.OutboundWebhookDelivery, now time.Time) (int, string, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	body := []byte(delivery.Payload)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, subscription.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, "", err
+	}
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEvent, delivery.EventType)
+	req.Header.Set(HeaderDeliveryID, delivery.ID.String())
+	req.Header.Set(HeaderTimestamp, timestamp)
+	req.Header.Set(HeaderSignature, domain.WebhookSignature([]byte(subscription.Secret), timestamp, body))
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+
+	logged, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseLog))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, string(logged), fmt.Errorf("subscriber returned status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, string(logged), nil
+}
@@ -0,0 +1,69 @@
+package webhookclient
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_Send(t *testing.T) {
+	delivery := &domain.OutboundWebhookDelivery{
+		ID:        uuid.New(),
+		EventType: domain.WebhookEventCustomerCreated,
+		Payload:   `{"type":"customer.created"}`,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		assert.Equal(t, delivery.Payload, string(body))
+		assert.Equal(t, domain.WebhookEventCustomerCreated, r.Header.Get(HeaderEvent))
+		assert.Equal(t, delivery.ID.String(), r.Header.Get(HeaderDeliveryID))
+		assert.Equal(t, "1748779200", r.Header.Get(HeaderTimestamp))
+		assert.Equal(t, domain.WebhookSignature([]byte("whsec_test"), "1748779200", body), r.Header.Get(HeaderSignature))
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	client := New(time.Second)
+	client.now = func() time.Time { return time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC) }
+	attempt := client.Send(context.Background(), &domain.WebhookSubscription{URL: server.URL, Secret: "whsec_test"}, delivery)
+	assert.Empty(t, attempt.Error)
+	assert.Equal(t, http.StatusOK, attempt.StatusCode)
+	assert.Equal(t, "ok", attempt.ResponseBody)
+}
+
+func TestClient_SendFailures(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/redirect" {
+			http.Redirect(w, r, "/elsewhere", http.StatusFound)
+			return
+		}
+		http.Error(w, "down for maintenance", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	client := New(time.Second)
+	delivery := &domain.OutboundWebhookDelivery{ID: uuid.New(), Payload: "{}"}
+
+	attempt := client.Send(context.Background(), &domain.WebhookSubscription{URL: server.URL}, delivery)
+	assert.Equal(t, http.StatusServiceUnavailable, attempt.StatusCode)
+	assert.Equal(t, "subscriber returned status 503", attempt.Error)
+	assert.Contains(t, attempt.ResponseBody, "down for maintenance")
+
+	attempt = client.Send(context.Background(), &domain.WebhookSubscription{URL: server.URL + "/redirect"}, delivery)
+	assert.Equal(t, http.StatusFound, attempt.StatusCode, "redirects are not followed")
+	assert.NotEmpty(t, attempt.Error)
+
+	server.Close()
+	attempt = client.Send(context.Background(), &domain.WebhookSubscription{URL: server.URL}, delivery)
+	assert.Zero(t, attempt.StatusCode)
+	assert.NotEmpty(t, attempt.Error)
+}
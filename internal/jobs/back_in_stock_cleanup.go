@@ -0,0 +1,47 @@
+package jobs
+
+import (
+	"context"
+
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/persistence"
+	"go.uber.org/zap"
+)
+
+// BackInStockCleanupJob deletes subscriptions that were notified longer ago
+// than the retention period
+type BackInStockCleanupJob struct {
+	repo          *persistence.BackInStockRepository
+	retentionDays int
+	batchSize     int
+	logger        *zap.Logger
+}
+
+// NewBackInStockCleanupJob creates a new cleanup job deleting batchSize
+// subscriptions at a time
+func NewBackInStockCleanupJob(repo *persistence.BackInStockRepository, retentionDays, batchSize int, logger *zap.Logger) *BackInStockCleanupJob {
+	return &BackInStockCleanupJob{
+		repo:          repo,
+		retentionDays: retentionDays,
+		batchSize:     batchSize,
+		logger:        logger,
+	}
+}
+
+// RunOnce deletes the old notified subscriptions once
+func (j *BackInStockCleanupJob) RunOnce(ctx context.Context) error {
+	deleted, err := j.repo.DeleteOldNotified(ctx, j.retentionDays, j.batchSize, func(batch persistence.CleanupBatch) {
+		j.logger.Debug("Deleted batch of notified back-in-stock subscriptions",
+			zap.Int("batch", batch.Batch), zap.Int64("count", batch.Deleted))
+	})
+	if err != nil {
+		if deleted > 0 {
+			j.logger.Warn("Back-in-stock cleanup stopped part way", zap.Int64("deleted", deleted))
+		}
+		return err
+	}
+	if deleted > 0 {
+		j.logger.Info("Deleted notified back-in-stock subscriptions",
+			zap.Int64("count", deleted), zap.Int("older_than_days", j.retentionDays))
+	}
+	return nil
+}
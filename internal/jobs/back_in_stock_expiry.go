@@ -0,0 +1,54 @@
+// Package jobs contains background jobs that run inside the customer service.
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/persistence"
+	"go.uber.org/zap"
+)
+
+// BackInStockExpiryJob removes back-in-stock subscriptions that were never
+// notified before their expiry
+type BackInStockExpiryJob struct {
+	repo      *persistence.BackInStockRepository
+	guestRepo *persistence.GuestBackInStockRepository
+	logger    *zap.Logger
+}
+
+// NewBackInStockExpiryJob creates a new expiry job
+func NewBackInStockExpiryJob(
+	repo *persistence.BackInStockRepository,
+	guestRepo *persistence.GuestBackInStockRepository,
+	logger *zap.Logger,
+) *BackInStockExpiryJob {
+	return &BackInStockExpiryJob{
+		repo:      repo,
+		guestRepo: guestRepo,
+		logger:    logger,
+	}
+}
+
+// RunOnce expires customer and guest subscriptions once
+func (j *BackInStockExpiryJob) RunOnce(ctx context.Context) error {
+	now := time.Now()
+	var errs []error
+
+	if expired, err := j.repo.ExpirePending(ctx, now); err != nil {
+		errs = append(errs, fmt.Errorf("expire back-in-stock subscriptions: %w", err))
+	} else if expired > 0 {
+		j.logger.Info("Expired back-in-stock subscriptions", zap.Int64("count", expired))
+	}
+
+	if j.guestRepo != nil {
+		if expired, err := j.guestRepo.ExpirePending(ctx, now); err != nil {
+			errs = append(errs, fmt.Errorf("expire guest back-in-stock subscriptions: %w", err))
+		} else if expired > 0 {
+			j.logger.Info("Expired guest back-in-stock subscriptions", zap.Int64("count", expired))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,61 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/persistence"
+	"go.uber.org/zap"
+)
+
+// ChurnRiskJob flags frequent buyers who stopped ordering as at risk of
+// churning
+type ChurnRiskJob struct {
+	repo      *persistence.ChurnRepository
+	policy    domain.ChurnPolicy
+	batchSize int
+	logger    *zap.Logger
+}
+
+// NewChurnRiskJob creates a churn risk job applying the policy, 500
+// customers at a time
+func NewChurnRiskJob(repo *persistence.ChurnRepository, policy domain.ChurnPolicy, logger *zap.Logger) *ChurnRiskJob {
+	return &ChurnRiskJob{
+		repo:      repo,
+		policy:    policy,
+		batchSize: 500,
+		logger:    logger,
+	}
+}
+
+// RunOnce updates every customer's churn risk once
+func (j *ChurnRiskJob) RunOnce(ctx context.Context) error {
+	var result domain.ChurnRiskResult
+	now := time.Now()
+	after := uuid.Nil
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		next, page, err := j.repo.Flag(ctx, after, j.batchSize, j.policy, now)
+		if err != nil {
+			return fmt.Errorf("flag customers: %w", err)
+		}
+		result.Customers += page.Customers
+		result.Flagged += page.Flagged
+		result.Cleared += page.Cleared
+		if next == uuid.Nil {
+			break
+		}
+		after = next
+	}
+
+	j.logger.Info("Updated customer churn risk",
+		zap.Int("customers", result.Customers),
+		zap.Int("flagged", result.Flagged),
+		zap.Int("cleared", result.Cleared))
+	return nil
+}
@@ -0,0 +1,34 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/persistence"
+	"go.uber.org/zap"
+)
+
+// CohortRefreshJob refreshes the materialized views behind the customer
+// cohort analytics
+type CohortRefreshJob struct {
+	repo   *persistence.AnalyticsRepository
+	logger *zap.Logger
+}
+
+// NewCohortRefreshJob creates a new cohort refresh job
+func NewCohortRefreshJob(repo *persistence.AnalyticsRepository, logger *zap.Logger) *CohortRefreshJob {
+	return &CohortRefreshJob{
+		repo:   repo,
+		logger: logger,
+	}
+}
+
+// RunOnce refreshes the cohort views once
+func (j *CohortRefreshJob) RunOnce(ctx context.Context) error {
+	started := time.Now()
+	if err := j.repo.RefreshCohorts(ctx); err != nil {
+		return err
+	}
+	j.logger.Info("Refreshed customer cohorts", zap.Duration("took", time.Since(started)))
+	return nil
+}
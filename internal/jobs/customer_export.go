@@ -0,0 +1,246 @@
+package jobs
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/persistence"
+	"go.uber.org/zap"
+)
+
+// customerExportBatch is how many queued jobs one run picks up
+const customerExportBatch = 5
+
+// customerExportLease is how long a running job may go without reporting
+// progress before another worker takes it over
+const customerExportLease = 5 * time.Minute
+
+// customerExportMaxAttempts is how many times a job is started before it is
+// given up on
+const customerExportMaxAttempts = 3
+
+// ExportFileStore keeps finished export files
+type ExportFileStore interface {
+	Put(ctx context.Context, key, contentType string, body io.Reader, size int64) error
+}
+
+// CustomerExportJob runs queued background customer exports: it pages
+// through the matching customers into a temporary file, reporting progress
+// as it goes, and uploads the file to the file store
+type CustomerExportJob struct {
+	repo      *persistence.CustomerExportJobRepository
+	customers persistence.CustomerRepository
+	tags      *persistence.CustomerTagRepository
+	files     ExportFileStore
+	pageSize  int
+	interval  time.Duration
+	wake      chan struct{}
+	logger    *zap.Logger
+}
+
+// NewCustomerExportJob creates a new customer export job
+func NewCustomerExportJob(
+	repo *persistence.CustomerExportJobRepository,
+	customers persistence.CustomerRepository,
+	tags *persistence.CustomerTagRepository,
+	files ExportFileStore,
+	pageSize int,
+	interval time.Duration,
+	logger *zap.Logger,
+) *CustomerExportJob {
+	return &CustomerExportJob{
+		repo:      repo,
+		customers: customers,
+		tags:      tags,
+		files:     files,
+		pageSize:  pageSize,
+		interval:  interval,
+		wake:      make(chan struct{}, 1),
+		logger:    logger,
+	}
+}
+
+// Wake makes Run look for queued jobs now rather than at its next tick
+func (j *CustomerExportJob) Wake() {
+	select {
+	case j.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run runs queued jobs immediately, then every interval and whenever woken,
+// until ctx is done
+func (j *CustomerExportJob) Run(ctx context.Context) {
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	for {
+		j.RunOnce(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-j.wake:
+		}
+	}
+}
+
+// RunOnce runs the queued jobs and takes over those whose worker went away
+func (j *CustomerExportJob) RunOnce(ctx context.Context) {
+	runnable, err := j.repo.Runnable(ctx, time.Now().Add(-customerExportLease), customerExportBatch)
+	if err != nil {
+		j.logger.Error("Failed to load customer export jobs", zap.Error(err))
+		return
+	}
+
+	for i := range runnable {
+		if ctx.Err() != nil {
+			return
+		}
+		job := &runnable[i]
+		logger := j.logger.With(zap.String("job_id", job.ID.String()))
+
+		claimed, err := j.repo.Claim(ctx, job, time.Now())
+		if err != nil {
+			logger.Error("Failed to claim customer export job", zap.Error(err))
+			continue
+		}
+		if !claimed {
+			continue
+		}
+
+		if job.Attempts > customerExportMaxAttempts {
+			err = fmt.Errorf("export stopped %d times before finishing", customerExportMaxAttempts)
+		} else {
+			err = j.export(ctx, job)
+		}
+		if errors.Is(err, context.Canceled) && ctx.Err() != nil {
+			// Shutting down; the lease runs out and another worker starts over
+			logger.Info("Customer export interrupted", zap.Int64("processed", job.Processed))
+			return
+		}
+
+		completed := time.Now()
+		job.CompletedAt = &completed
+		job.Status = domain.CustomerExportCompleted
+		if err != nil {
+			job.Status, job.Error = domain.CustomerExportFailed, err.Error()
+		}
+		if err := j.repo.Finish(context.WithoutCancel(ctx), job); err != nil {
+			logger.Error("Failed to record customer export job", zap.Error(err))
+			continue
+		}
+		if job.Status == domain.CustomerExportFailed {
+			logger.Warn("Customer export failed", zap.String("error", job.Error))
+			continue
+		}
+		logger.Info("Customer export completed", zap.Int64("rows", job.Processed), zap.Int64("bytes", job.Bytes))
+	}
+}
+
+// export writes the job's customers to a temporary file and uploads it
+func (j *CustomerExportJob) export(ctx context.Context, job *domain.CustomerExportJob) error {
+	filter, err := job.CustomerFilter()
+	if err != nil {
+		return err
+	}
+	filter.Page, filter.Limit = 1, 1
+	filter.Sort = domain.Sort{Field: "created_at", Direction: domain.SortAsc}
+	if _, job.Total, err = j.customers.ListAdmin(ctx, filter); err != nil {
+		return fmt.Errorf("count customers: %w", err)
+	}
+	if err := j.repo.Progress(ctx, job); err != nil {
+		return err
+	}
+
+	file, err := os.CreateTemp("", "customer-export-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	buffered := bufio.NewWriter(file)
+	writer := domain.NewCustomerWriter(buffered, job.Format, job.Columns)
+	filter.Limit = j.pageSize
+	var after *domain.Cursor
+	for {
+		page, next, err := j.customers.ListAdminAfter(ctx, filter, after)
+		if err != nil {
+			return fmt.Errorf("list customers: %w", err)
+		}
+		if err := j.prepare(ctx, job, page); err != nil {
+			return err
+		}
+		for i := range page {
+			if err := writer.Write(&page[i]); err != nil {
+				return err
+			}
+		}
+
+		// Customers created since it was counted are exported too
+		job.Processed += int64(len(page))
+		job.Total = max(job.Total, job.Processed)
+		if err := j.repo.Progress(ctx, job); err != nil {
+			return err
+		}
+		if next == "" {
+			break
+		}
+		if after, err = domain.DecodeCursor(next); err != nil {
+			return err
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+	if err := buffered.Flush(); err != nil {
+		return err
+	}
+
+	if job.Bytes, err = file.Seek(0, io.SeekCurrent); err != nil {
+		return err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	key := job.Key()
+	if err := j.files.Put(ctx, key, job.ContentType(), file, job.Bytes); err != nil {
+		return fmt.Errorf("upload export: %w", err)
+	}
+	job.FileKey = key
+	return nil
+}
+
+// prepare loads the tags of a page of customers when they're exported and
+// masks their PII when the requesting admin couldn't see it
+func (j *CustomerExportJob) prepare(ctx context.Context, job *domain.CustomerExportJob, page []domain.Customer) error {
+	if slices.Contains(job.Columns, "tags") && len(page) > 0 {
+		ids := make([]uuid.UUID, len(page))
+		for i := range page {
+			ids[i] = page[i].ID
+		}
+		byCustomer, err := j.tags.NamesByCustomers(ctx, ids)
+		if err != nil {
+			return fmt.Errorf("load customer tags: %w", err)
+		}
+		for i := range page {
+			page[i].Tags = byCustomer[page[i].ID]
+		}
+	}
+	if job.MaskPII {
+		for i := range page {
+			page[i].MaskPII()
+		}
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/persistence"
+	"go.uber.org/zap"
+)
+
+// customerExportRetentionBatch is the most expired exports one run deletes;
+// the rest are left to the next run
+const customerExportRetentionBatch = 1000
+
+// ExportFileRemover deletes export files from the file store
+type ExportFileRemover interface {
+	Delete(ctx context.Context, key string) error
+}
+
+// CustomerExportRetentionJob deletes the files of background customer
+// exports finished more than the retention period ago, and then their jobs,
+// so exported PII doesn't stay in the file store forever
+type CustomerExportRetentionJob struct {
+	repo      *persistence.CustomerExportJobRepository
+	files     ExportFileRemover
+	retention time.Duration
+	now       func() time.Time
+	logger    *zap.Logger
+}
+
+// NewCustomerExportRetentionJob creates a new export retention job
+func NewCustomerExportRetentionJob(repo *persistence.CustomerExportJobRepository, files ExportFileRemover, retentionDays int, logger *zap.Logger) *CustomerExportRetentionJob {
+	return &CustomerExportRetentionJob{
+		repo:      repo,
+		files:     files,
+		retention: time.Duration(retentionDays) * 24 * time.Hour,
+		now:       time.Now,
+		logger:    logger,
+	}
+}
+
+// RunOnce deletes the expired exports once. A job whose file can't be
+// deleted is kept, so its file is tried again on the next run.
+func (j *CustomerExportRetentionJob) RunOnce(ctx context.Context) error {
+	expired, err := j.repo.Expired(ctx, j.now().Add(-j.retention), customerExportRetentionBatch)
+	if err != nil {
+		return err
+	}
+
+	deleted := 0
+	for _, job := range expired {
+		if job.FileKey != "" {
+			if err := j.files.Delete(ctx, job.FileKey); err != nil {
+				j.logger.Warn("Failed to delete customer export file",
+					zap.String("job_id", job.ID.String()), zap.Error(err))
+				continue
+			}
+		}
+		if err := j.repo.Delete(ctx, job.ID); err != nil {
+			return err
+		}
+		deleted++
+	}
+	if deleted > 0 {
+		j.logger.Info("Deleted expired customer exports",
+			zap.Int("count", deleted), zap.Duration("older_than", j.retention))
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/persistence"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// openTestDB opens an in-memory SQLite database for the models, with the
+// same adjustments as the persistence tests: no schemas in table names and
+// no Postgres defaults
+func openTestDB(t *testing.T, models ...interface{}) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+
+	for _, model := range models {
+		stmt := &gorm.Statement{DB: db}
+		require.NoError(t, stmt.Parse(model))
+		stmt.Schema.Table = strings.ReplaceAll(stmt.Schema.Table, ".", "_")
+		for _, field := range stmt.Schema.Fields {
+			if strings.HasSuffix(field.DefaultValue, "()") {
+				field.DefaultValue = ""
+				field.HasDefaultValue = false
+				field.DefaultValueInterface = nil
+			}
+		}
+	}
+	require.NoError(t, db.AutoMigrate(models...))
+	return db
+}
+
+// memoryFileStore keeps the keys of stored files and fails to delete those
+// in fail
+type memoryFileStore struct {
+	files map[string]bool
+	fail  map[string]bool
+}
+
+func (s *memoryFileStore) Delete(_ context.Context, key string) error {
+	if s.fail[key] {
+		return errors.New("bucket unavailable")
+	}
+	delete(s.files, key)
+	return nil
+}
+
+func TestCustomerExportRetentionJob(t *testing.T) {
+	db := openTestDB(t, &domain.CustomerExportJob{})
+	repo := persistence.NewCustomerExportJobRepository(db)
+	files := &memoryFileStore{files: map[string]bool{}, fail: map[string]bool{}}
+	now := time.Date(2026, 3, 20, 4, 30, 0, 0, time.UTC)
+	ctx := context.Background()
+
+	// export queues a job that finished daysAgo with status, and its file
+	// when it completed
+	export := func(status string, daysAgo int) *domain.CustomerExportJob {
+		job := &domain.CustomerExportJob{Format: "csv"}
+		require.NoError(t, repo.Create(ctx, job))
+		completed := now.AddDate(0, 0, -daysAgo)
+		job.Status, job.CompletedAt = status, &completed
+		if status == domain.CustomerExportCompleted {
+			job.FileKey = job.Key()
+			files.files[job.FileKey] = true
+		}
+		require.NoError(t, repo.Finish(ctx, job))
+		return job
+	}
+	expired := export(domain.CustomerExportCompleted, 8)
+	failed := export(domain.CustomerExportFailed, 8)
+	recent := export(domain.CustomerExportCompleted, 6)
+	undeletable := export(domain.CustomerExportCompleted, 9)
+	files.fail[undeletable.FileKey] = true
+	running := &domain.CustomerExportJob{Format: "csv"}
+	require.NoError(t, repo.Create(ctx, running))
+
+	job := NewCustomerExportRetentionJob(repo, files, 7, zap.NewNop())
+	job.now = func() time.Time { return now }
+	require.NoError(t, job.RunOnce(ctx))
+
+	for _, gone := range []*domain.CustomerExportJob{expired, failed} {
+		_, err := repo.Get(ctx, gone.ID)
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	}
+	assert.NotContains(t, files.files, expired.FileKey)
+
+	for _, kept := range []*domain.CustomerExportJob{recent, undeletable, running} {
+		_, err := repo.Get(ctx, kept.ID)
+		assert.NoError(t, err, "job %s is kept", kept.ID)
+	}
+	assert.Contains(t, files.files, recent.FileKey)
+	assert.Contains(t, files.files, undeletable.FileKey, "retried on the next run")
+
+	// Once the file can be deleted, so is the job
+	delete(files.fail, undeletable.FileKey)
+	require.NoError(t, job.RunOnce(ctx))
+	_, err := repo.Get(ctx, undeletable.ID)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	assert.Equal(t, map[string]bool{recent.FileKey: true}, files.files)
+}
@@ -0,0 +1,75 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/persistence"
+	"go.uber.org/zap"
+)
+
+// DuplicateDetectionJob queues probable duplicate customers for review:
+// customers sharing a phone number, an email up to tags and aliases, or a
+// name and address
+type DuplicateDetectionJob struct {
+	repo      *persistence.DuplicateRepository
+	batchSize int
+	logger    *zap.Logger
+}
+
+// NewDuplicateDetectionJob creates a duplicate detection job, refreshing
+// match keys 500 customers at a time
+func NewDuplicateDetectionJob(repo *persistence.DuplicateRepository, logger *zap.Logger) *DuplicateDetectionJob {
+	return &DuplicateDetectionJob{
+		repo:      repo,
+		batchSize: 500,
+		logger:    logger,
+	}
+}
+
+// RunOnce refreshes every customer's match keys and queues new duplicates
+func (j *DuplicateDetectionJob) RunOnce(ctx context.Context) error {
+	result, err := j.Detect(ctx, time.Now())
+	if err != nil {
+		return err
+	}
+	j.logger.Info("Detected duplicate customers",
+		zap.Int("customers", result.Customers),
+		zap.Int("pairs", result.Pairs),
+		zap.Int("queued", result.Queued))
+	return nil
+}
+
+// Detect pages through the customers refreshing their match keys, then
+// pairs the customers sharing one. It stops between pages when ctx is done.
+func (j *DuplicateDetectionJob) Detect(ctx context.Context, now time.Time) (domain.DuplicateDetectionResult, error) {
+	var result domain.DuplicateDetectionResult
+	after := uuid.Nil
+	for {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+		next, customers, err := j.repo.RefreshKeys(ctx, after, j.batchSize)
+		if err != nil {
+			return result, fmt.Errorf("refresh match keys: %w", err)
+		}
+		result.Customers += customers
+		if next == uuid.Nil {
+			break
+		}
+		after = next
+	}
+	if err := j.repo.PruneKeys(ctx); err != nil {
+		return result, fmt.Errorf("prune match keys: %w", err)
+	}
+
+	pairs, queued, err := j.repo.Detect(ctx, now)
+	if err != nil {
+		return result, fmt.Errorf("detect duplicates: %w", err)
+	}
+	result.Pairs, result.Queued = pairs, queued
+	return result, nil
+}
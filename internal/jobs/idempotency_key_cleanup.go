@@ -0,0 +1,35 @@
+package jobs
+
+import (
+	"context"
+
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/persistence"
+	"go.uber.org/zap"
+)
+
+// IdempotencyKeyCleanupJob deletes stored idempotent responses past their
+// expiry
+type IdempotencyKeyCleanupJob struct {
+	repo   *persistence.IdempotencyRepository
+	logger *zap.Logger
+}
+
+// NewIdempotencyKeyCleanupJob creates a new cleanup job
+func NewIdempotencyKeyCleanupJob(repo *persistence.IdempotencyRepository, logger *zap.Logger) *IdempotencyKeyCleanupJob {
+	return &IdempotencyKeyCleanupJob{
+		repo:   repo,
+		logger: logger,
+	}
+}
+
+// RunOnce deletes the expired idempotency keys once
+func (j *IdempotencyKeyCleanupJob) RunOnce(ctx context.Context) error {
+	deleted, err := j.repo.DeleteExpired(ctx)
+	if err != nil {
+		return err
+	}
+	if deleted > 0 {
+		j.logger.Info("Deleted expired idempotency keys", zap.Int64("count", deleted))
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// SchedulerLockKey is the Postgres advisory lock held by the replica that
+// runs the scheduled jobs
+const SchedulerLockKey int64 = 0x637573746f6d6572 // "customer"
+
+// AdvisoryLockLeader elects one replica as leader by holding a session-level
+// Postgres advisory lock on a dedicated connection. Followers retry every
+// interval, so if the leader exits or its connection drops, another replica
+// takes over.
+type AdvisoryLockLeader struct {
+	db       *sql.DB
+	key      int64
+	interval time.Duration
+	logger   *zap.Logger
+	leader   atomic.Bool
+	conn     *sql.Conn
+}
+
+// NewAdvisoryLockLeader creates a leader elector for the lock key
+func NewAdvisoryLockLeader(db *sql.DB, key int64, interval time.Duration, logger *zap.Logger) *AdvisoryLockLeader {
+	return &AdvisoryLockLeader{
+		db:       db,
+		key:      key,
+		interval: interval,
+		logger:   logger,
+	}
+}
+
+// IsLeader implements Leader
+func (l *AdvisoryLockLeader) IsLeader() bool {
+	return l.leader.Load()
+}
+
+// Run campaigns for leadership immediately and then every interval until ctx
+// is done, when the lock is released
+func (l *AdvisoryLockLeader) Run(ctx context.Context) {
+	ticker := time.NewTicker(l.interval)
+	defer ticker.Stop()
+	defer l.release()
+
+	for {
+		l.check(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// check confirms the leader's connection is still alive, or tries to take
+// the lock if this replica isn't leading
+func (l *AdvisoryLockLeader) check(ctx context.Context) {
+	if l.conn != nil {
+		err := l.conn.PingContext(ctx)
+		if err == nil {
+			return
+		}
+		if ctx.Err() == nil {
+			l.logger.Warn("Lost scheduler leadership", zap.Error(err))
+		}
+		l.release()
+		return
+	}
+
+	conn, err := l.db.Conn(ctx)
+	if err != nil {
+		l.logger.Warn("Failed to get connection for scheduler leader election", zap.Error(err))
+		return
+	}
+	var acquired bool
+	if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", l.key).Scan(&acquired); err != nil || !acquired {
+		if err != nil {
+			l.logger.Warn("Failed to try scheduler lock", zap.Error(err))
+		}
+		conn.Close()
+		return
+	}
+	l.conn = conn
+	l.leader.Store(true)
+	l.logger.Info("Elected scheduler leader")
+}
+
+func (l *AdvisoryLockLeader) release() {
+	if l.conn == nil {
+		return
+	}
+	l.leader.Store(false)
+	// Closing the connection would release the lock too, but the pool may
+	// keep it open
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	l.conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", l.key)
+	l.conn.Close()
+	l.conn = nil
+}
@@ -0,0 +1,179 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/marketingsync"
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/persistence"
+	"go.uber.org/zap"
+)
+
+// marketingSyncMaxBatches bounds the batches one run pushes to a platform,
+// so a large backlog doesn't hold up the other platforms
+const marketingSyncMaxBatches = 20
+
+// marketingSyncLease is how long a claimed batch is left to the replica
+// pushing it before another may pick it up
+const marketingSyncLease = 10 * time.Minute
+
+// MarketingSyncJob pushes changed customers to the marketing platforms in
+// batches sized for each platform, pausing between batches and backing off
+// when a platform reports its rate limit
+type MarketingSyncJob struct {
+	repo      *persistence.MarketingSyncRepository
+	providers []marketingsync.Provider
+	policy    domain.NotificationRetryPolicy
+	interval  time.Duration
+	pause     time.Duration
+	logger    *zap.Logger
+
+	// Rate-limited platforms are skipped until then; only touched by RunOnce
+	limitedUntil map[string]time.Time
+}
+
+// NewMarketingSyncJob creates a new marketing sync job. pause is the wait
+// between two batches to the same platform.
+func NewMarketingSyncJob(
+	repo *persistence.MarketingSyncRepository,
+	providers []marketingsync.Provider,
+	policy domain.NotificationRetryPolicy,
+	interval time.Duration,
+	pause time.Duration,
+	logger *zap.Logger,
+) *MarketingSyncJob {
+	return &MarketingSyncJob{
+		repo:         repo,
+		providers:    providers,
+		policy:       policy,
+		interval:     interval,
+		pause:        pause,
+		logger:       logger,
+		limitedUntil: make(map[string]time.Time),
+	}
+}
+
+// Run pushes due customers immediately and then every interval until ctx is done
+func (j *MarketingSyncJob) Run(ctx context.Context) {
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	for {
+		j.RunOnce(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce pushes the customers that are due to every platform
+func (j *MarketingSyncJob) RunOnce(ctx context.Context) {
+	for _, provider := range j.providers {
+		if time.Now().Before(j.limitedUntil[provider.Name()]) {
+			continue
+		}
+		j.sync(ctx, provider)
+	}
+}
+
+// sync pushes due customers to one platform, batch by batch
+func (j *MarketingSyncJob) sync(ctx context.Context, provider marketingsync.Provider) {
+	logger := j.logger.With(zap.String("provider", provider.Name()))
+	for i := 0; i < marketingSyncMaxBatches; i++ {
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(j.pause):
+			}
+		}
+
+		states, err := j.repo.Claim(ctx, provider.Name(), time.Now(), provider.BatchSize(), marketingSyncLease)
+		if err != nil {
+			logger.Error("Failed to claim customers for marketing sync", zap.Error(err))
+			return
+		}
+		if len(states) == 0 {
+			return
+		}
+		if !j.push(ctx, provider, states, logger) || len(states) < provider.BatchSize() {
+			return
+		}
+	}
+}
+
+// push sends one claimed batch and records the outcome. It reports whether
+// the next batch may follow.
+func (j *MarketingSyncJob) push(ctx context.Context, provider marketingsync.Provider, states []domain.MarketingSyncState, logger *zap.Logger) bool {
+	claimID := *states[0].ClaimID
+	// Not ctx: a batch that was pushed should be recorded even on shutdown
+	recordCtx := context.WithoutCancel(ctx)
+
+	contacts, skipped, err := j.repo.Contacts(ctx, states)
+	if err != nil {
+		logger.Error("Failed to load customers for marketing sync", zap.Error(err))
+		return false
+	}
+	if len(skipped) > 0 {
+		if err := j.repo.Forget(recordCtx, provider.Name(), claimID, skipped); err != nil {
+			logger.Error("Failed to drop deleted customers from marketing sync", zap.Error(err))
+		}
+	}
+	if len(contacts) == 0 {
+		return true
+	}
+
+	rejected, err := provider.Push(ctx, contacts)
+	var rateLimited *marketingsync.RateLimitError
+	if errors.As(err, &rateLimited) {
+		until := time.Now().Add(rateLimited.RetryAfter)
+		j.limitedUntil[provider.Name()] = until
+		logger.Warn("Marketing platform rate limit reached", zap.Time("retry_at", until))
+		if err := j.repo.Release(recordCtx, provider.Name(), claimID, until); err != nil {
+			logger.Error("Failed to release marketing sync batch", zap.Error(err))
+		}
+		return false
+	}
+
+	attempts := make(map[uuid.UUID]int, len(states))
+	for _, state := range states {
+		attempts[state.CustomerID] = state.Attempts
+	}
+	now := time.Now()
+	outcomes := make([]domain.MarketingSyncOutcome, 0, len(contacts))
+	failed := 0
+	for _, contact := range contacts {
+		outcome := domain.MarketingSyncOutcome{CustomerID: contact.CustomerID}
+		switch {
+		case err != nil:
+			outcome.Error = err.Error()
+		case rejected[contact.CustomerID] != "":
+			outcome.Error = rejected[contact.CustomerID]
+		}
+		if outcome.Error != "" {
+			failed++
+			if at, ok := j.policy.NextAttempt(attempts[contact.CustomerID]+1, now); ok {
+				outcome.NextAttemptAt = &at
+			}
+		}
+		outcomes = append(outcomes, outcome)
+	}
+
+	if recordErr := j.repo.Record(recordCtx, provider.Name(), claimID, outcomes, now); recordErr != nil {
+		// The lease runs out and the batch is pushed again
+		logger.Error("Failed to record marketing sync batch", zap.Error(recordErr))
+		return false
+	}
+	if err != nil {
+		logger.Warn("Marketing sync batch failed", zap.Int("contacts", len(contacts)), zap.Error(err))
+		return false
+	}
+	logger.Info("Marketing sync batch pushed", zap.Int("contacts", len(contacts)), zap.Int("rejected", failed))
+	return true
+}
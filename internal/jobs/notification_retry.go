@@ -0,0 +1,221 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/metrics"
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/persistence"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// notificationRetryBatch is how many due retries one run sends
+const notificationRetryBatch = 100
+
+// NotificationSender sends back-in-stock notifications
+type NotificationSender interface {
+	SendBackInStockNotification(ctx context.Context, notification domain.BackInStockNotification) error
+}
+
+// NotificationPreferencePolicy applies customers' notification preferences;
+// *events.NotificationPolicy implements it
+type NotificationPreferencePolicy interface {
+	ShouldNotify(ctx context.Context, customerID uuid.UUID, notificationType string) (domain.NotificationDecision, error)
+	Sent(ctx context.Context, customerID uuid.UUID, notificationType, channel string)
+}
+
+// NotificationRetryJob periodically resends back-in-stock notifications that
+// failed, backing off exponentially until the retry policy gives up
+type NotificationRetryJob struct {
+	retries     *persistence.NotificationRetryRepository
+	repo        *persistence.BackInStockRepository
+	guestRepo   *persistence.GuestBackInStockRepository
+	sender      NotificationSender
+	policy      domain.NotificationRetryPolicy
+	preferences NotificationPreferencePolicy
+	interval    time.Duration
+	logger      *zap.Logger
+}
+
+// NewNotificationRetryJob creates a new notification retry job
+func NewNotificationRetryJob(
+	retries *persistence.NotificationRetryRepository,
+	repo *persistence.BackInStockRepository,
+	guestRepo *persistence.GuestBackInStockRepository,
+	sender NotificationSender,
+	policy domain.NotificationRetryPolicy,
+	interval time.Duration,
+	logger *zap.Logger,
+) *NotificationRetryJob {
+	return &NotificationRetryJob{
+		retries:   retries,
+		repo:      repo,
+		guestRepo: guestRepo,
+		sender:    sender,
+		policy:    policy,
+		interval:  interval,
+		logger:    logger,
+	}
+}
+
+// WithNotificationPolicy applies customers' notification preferences to the
+// retries of customer subscriptions, as when they were first sent
+func (j *NotificationRetryJob) WithNotificationPolicy(preferences NotificationPreferencePolicy) *NotificationRetryJob {
+	j.preferences = preferences
+	return j
+}
+
+// Run processes due retries immediately and then every interval until ctx is done
+func (j *NotificationRetryJob) Run(ctx context.Context) {
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	for {
+		j.RunOnce(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce resends the retries that are due
+func (j *NotificationRetryJob) RunOnce(ctx context.Context) {
+	due, err := j.retries.Due(ctx, time.Now(), notificationRetryBatch)
+	if err != nil {
+		j.logger.Error("Failed to load notification retries", zap.Error(err))
+		return
+	}
+
+	for _, retry := range due {
+		if ctx.Err() != nil {
+			return
+		}
+		j.process(ctx, retry)
+	}
+}
+
+// process resends one retry. Retries for subscriptions that were deleted,
+// expired or notified in the meantime are dropped.
+func (j *NotificationRetryJob) process(ctx context.Context, retry domain.NotificationRetry) {
+	logger := j.logger.With(
+		zap.String("subscription_id", retry.SubscriptionID.String()),
+		zap.Bool("guest", retry.IsGuest),
+		zap.Int("attempt", retry.Attempts+1))
+
+	notification, customerID, markNotified, err := j.load(ctx, retry)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		j.drop(ctx, retry, logger)
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to load subscription for notification retry", zap.Error(err))
+		return
+	}
+
+	if customerID != uuid.Nil && j.preferences != nil {
+		decision, err := j.preferences.ShouldNotify(ctx, customerID, domain.NotificationTypeBackInStock)
+		if err != nil {
+			logger.Error("Failed to check notification preferences", zap.Error(err))
+			return
+		}
+		if !decision.Allowed {
+			j.holdBack(ctx, retry, decision, logger)
+			return
+		}
+		notification.Channel = decision.Channel
+	}
+
+	if err := j.sender.SendBackInStockNotification(ctx, notification); err != nil {
+		metrics.RecordNotification(retry.IsGuest, metrics.NotificationFailed)
+		next, ok := j.policy.NextAttempt(retry.Attempts+1, time.Now())
+		var nextAttempt *time.Time
+		if ok {
+			nextAttempt = &next
+			logger.Warn("Notification retry failed", zap.Time("next_attempt_at", next), zap.Error(err))
+		} else {
+			logger.Error("Notification retry gave up", zap.Error(err))
+		}
+		if err := j.retries.RecordFailure(ctx, retry.ID, err.Error(), nextAttempt); err != nil {
+			logger.Error("Failed to record notification retry failure", zap.Error(err))
+		}
+		return
+	}
+	metrics.RecordNotification(retry.IsGuest, metrics.NotificationSent)
+	if customerID != uuid.Nil && j.preferences != nil {
+		j.preferences.Sent(ctx, customerID, domain.NotificationTypeBackInStock, notification.Channel)
+	}
+
+	if err := markNotified(); err != nil {
+		// The retry stays queued, so the customer may get the notification twice
+		logger.Error("Failed to mark retried subscription as notified", zap.Error(err))
+		return
+	}
+	j.drop(ctx, retry, logger)
+	logger.Info("Notification retry sent")
+}
+
+// holdBack handles a retry the customer's preferences hold back. In quiet
+// hours it waits for them to end; otherwise (opted out or over the weekly
+// cap) it is dropped and the subscription stays pending for the next
+// restock, as when a first send is held back.
+func (j *NotificationRetryJob) holdBack(ctx context.Context, retry domain.NotificationRetry, decision domain.NotificationDecision, logger *zap.Logger) {
+	metrics.RecordNotification(false, metrics.NotificationDeferred)
+	if decision.RetryAt != nil {
+		if err := j.retries.Reschedule(ctx, retry.ID, *decision.RetryAt); err != nil {
+			logger.Error("Failed to reschedule notification retry", zap.Error(err))
+			return
+		}
+		logger.Info("Notification retry waits for the quiet hours to end", zap.Time("next_attempt_at", *decision.RetryAt))
+		return
+	}
+	j.drop(ctx, retry, logger)
+	logger.Info("Held back notification retry", zap.String("reason", decision.Reason))
+}
+
+// load returns the notification to resend, the customer it goes to (uuid.Nil
+// for guests) and how to mark the subscription notified, or
+// gorm.ErrRecordNotFound if it no longer needs a notification
+func (j *NotificationRetryJob) load(ctx context.Context, retry domain.NotificationRetry) (domain.BackInStockNotification, uuid.UUID, func() error, error) {
+	now := time.Now()
+	ids := []uuid.UUID{retry.SubscriptionID}
+
+	if retry.IsGuest {
+		if j.guestRepo == nil {
+			return domain.BackInStockNotification{}, uuid.Nil, nil, gorm.ErrRecordNotFound
+		}
+		sub, err := j.guestRepo.GetByID(ctx, retry.SubscriptionID)
+		if err != nil {
+			return domain.BackInStockNotification{}, uuid.Nil, nil, err
+		}
+		if sub.IsNotified || (sub.ExpiresAt != nil && sub.ExpiresAt.Before(now)) {
+			return domain.BackInStockNotification{}, uuid.Nil, nil, gorm.ErrRecordNotFound
+		}
+		return sub.Notification(retry.StockQuantity), uuid.Nil, func() error {
+			return j.guestRepo.MarkMultipleAsNotified(ctx, ids)
+		}, nil
+	}
+
+	sub, err := j.repo.GetByID(ctx, retry.SubscriptionID)
+	if err != nil {
+		return domain.BackInStockNotification{}, uuid.Nil, nil, err
+	}
+	if sub.IsNotified || (sub.ExpiresAt != nil && sub.ExpiresAt.Before(now)) {
+		return domain.BackInStockNotification{}, uuid.Nil, nil, gorm.ErrRecordNotFound
+	}
+	return sub.Notification(retry.StockQuantity), sub.CustomerID, func() error {
+		return j.repo.MarkMultipleAsNotified(ctx, ids)
+	}, nil
+}
+
+func (j *NotificationRetryJob) drop(ctx context.Context, retry domain.NotificationRetry, logger *zap.Logger) {
+	if err := j.retries.Delete(ctx, retry.ID); err != nil {
+		logger.Error("Failed to delete notification retry", zap.Error(err))
+	}
+}
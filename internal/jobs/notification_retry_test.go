@@ -0,0 +1,105 @@
+package jobs
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/persistence"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// recordingSender records the notifications it sends
+type recordingSender struct {
+	sent []domain.BackInStockNotification
+}
+
+func (s *recordingSender) SendBackInStockNotification(_ context.Context, notification domain.BackInStockNotification) error {
+	s.sent = append(s.sent, notification)
+	return nil
+}
+
+// fixedPreferences returns the same decision for every customer and records
+// the channels sends were counted on
+type fixedPreferences struct {
+	decision domain.NotificationDecision
+	counted  []string
+}
+
+func (p *fixedPreferences) ShouldNotify(context.Context, uuid.UUID, string) (domain.NotificationDecision, error) {
+	return p.decision, nil
+}
+
+func (p *fixedPreferences) Sent(_ context.Context, _ uuid.UUID, _ string, channel string) {
+	p.counted = append(p.counted, channel)
+}
+
+func TestNotificationRetryJob_AppliesPreferences(t *testing.T) {
+	quietUntil := time.Now().Add(6 * time.Hour).UTC().Truncate(time.Second)
+
+	tests := []struct {
+		name     string
+		decision domain.NotificationDecision
+		sent     []string // channels sent on
+		queued   bool
+		next     time.Time
+	}{
+		{
+			name:     "preferred channel",
+			decision: domain.NotificationDecision{Allowed: true, Channel: domain.NotificationChannelPush},
+			sent:     []string{domain.NotificationChannelPush},
+		},
+		{
+			name:     "quiet hours",
+			decision: domain.NotificationDecision{Channel: domain.NotificationChannelEmail, Reason: "quiet hours", RetryAt: &quietUntil},
+			queued:   true,
+			next:     quietUntil,
+		},
+		{
+			name:     "weekly cap",
+			decision: domain.NotificationDecision{Channel: domain.NotificationChannelEmail, Reason: "weekly cap reached"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := openTestDB(t, &domain.Customer{}, &domain.BackInStockSubscription{}, &domain.NotificationRetry{})
+			ctx := context.Background()
+			sub := &domain.BackInStockSubscription{ID: uuid.New(), CustomerID: uuid.New(), ProductID: uuid.New(), ProductName: "Baju Kurung"}
+			require.NoError(t, db.Create(sub).Error)
+
+			retries := persistence.NewNotificationRetryRepository(db)
+			retry := &domain.NotificationRetry{SubscriptionID: sub.ID, StockQuantity: 5, Attempts: 1, NextAttemptAt: time.Now().Add(-time.Minute)}
+			require.NoError(t, retries.Enqueue(ctx, retry))
+
+			sender := &recordingSender{}
+			preferences := &fixedPreferences{decision: tt.decision}
+			NewNotificationRetryJob(retries, persistence.NewBackInStockRepository(db), nil, sender,
+				domain.DefaultNotificationRetryPolicy, time.Minute, zap.NewNop()).
+				WithNotificationPolicy(preferences).
+				RunOnce(ctx)
+
+			var channels []string
+			for _, notification := range sender.sent {
+				channels = append(channels, notification.Channel)
+			}
+			assert.Equal(t, tt.sent, channels)
+			assert.Equal(t, tt.sent, preferences.counted, "sends count towards the weekly cap")
+
+			var stored domain.NotificationRetry
+			err := db.First(&stored, "id = ?", retry.ID).Error
+			if !tt.queued {
+				assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, 1, stored.Attempts, "waiting out quiet hours is not a failed attempt")
+			assert.True(t, tt.next.Equal(stored.NextAttemptAt), "next attempt %s", stored.NextAttemptAt)
+		})
+	}
+}
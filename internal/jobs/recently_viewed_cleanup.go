@@ -0,0 +1,38 @@
+package jobs
+
+import (
+	"context"
+
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/persistence"
+	"go.uber.org/zap"
+)
+
+// RecentlyViewedCleanupJob deletes product views older than the retention
+// period, so customers who stopped visiting don't keep a history forever
+type RecentlyViewedCleanupJob struct {
+	repo          *persistence.RecentlyViewedRepository
+	retentionDays int
+	logger        *zap.Logger
+}
+
+// NewRecentlyViewedCleanupJob creates a new cleanup job
+func NewRecentlyViewedCleanupJob(repo *persistence.RecentlyViewedRepository, retentionDays int, logger *zap.Logger) *RecentlyViewedCleanupJob {
+	return &RecentlyViewedCleanupJob{
+		repo:          repo,
+		retentionDays: retentionDays,
+		logger:        logger,
+	}
+}
+
+// RunOnce deletes the old views once
+func (j *RecentlyViewedCleanupJob) RunOnce(ctx context.Context) error {
+	deleted, err := j.repo.DeleteOlderThan(ctx, j.retentionDays)
+	if err != nil {
+		return err
+	}
+	if deleted > 0 {
+		j.logger.Info("Deleted old recently viewed products",
+			zap.Int64("count", deleted), zap.Int("older_than_days", j.retentionDays))
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/persistence"
+	"go.uber.org/zap"
+)
+
+// RFMScoringJob scores every customer's recency, frequency and monetary value
+// and predicts their lifetime value from their order totals, then applies
+// the rfm_scored segment rules to customers who moved to another RFM segment
+type RFMScoringJob struct {
+	rfm        *persistence.RFMRepository
+	rules      *persistence.SegmentRuleRepository
+	thresholds domain.RFMThresholds
+	batchSize  int
+	logger     *zap.Logger
+}
+
+// NewRFMScoringJob creates a scoring job with the default thresholds, 500
+// customers at a time
+func NewRFMScoringJob(rfm *persistence.RFMRepository, rules *persistence.SegmentRuleRepository, logger *zap.Logger) *RFMScoringJob {
+	return &RFMScoringJob{
+		rfm:        rfm,
+		rules:      rules,
+		thresholds: domain.DefaultRFMThresholds,
+		batchSize:  500,
+		logger:     logger,
+	}
+}
+
+// WithThresholds sets the score thresholds
+func (j *RFMScoringJob) WithThresholds(thresholds domain.RFMThresholds) *RFMScoringJob {
+	j.thresholds = thresholds
+	return j
+}
+
+// RunOnce scores every customer once
+func (j *RFMScoringJob) RunOnce(ctx context.Context) error {
+	result, err := j.Score(ctx, time.Now())
+	if err != nil {
+		return err
+	}
+	j.logger.Info("Scored customers",
+		zap.Int("customers", result.Customers),
+		zap.Int("changed", result.Changed),
+		zap.Int("decisions", result.Decisions))
+	return nil
+}
+
+// Score pages through the customers, scoring each at now. It stops between
+// pages when ctx is done.
+func (j *RFMScoringJob) Score(ctx context.Context, now time.Time) (domain.RFMScoringResult, error) {
+	var result domain.RFMScoringResult
+	after := uuid.Nil
+	for {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+		next, scored, changed, err := j.rfm.Score(ctx, after, j.batchSize, j.thresholds, now)
+		if err != nil {
+			return result, fmt.Errorf("score customers: %w", err)
+		}
+		if next == uuid.Nil {
+			return result, nil
+		}
+
+		for _, customerID := range changed {
+			evaluation, err := j.rules.Apply(ctx, customerID, domain.SegmentTriggerRFMScored)
+			if err != nil {
+				return result, fmt.Errorf("customer %s: %w", customerID, err)
+			}
+			result.Decisions += len(evaluation.Decisions)
+		}
+		result.Changed += len(changed)
+		result.Customers += scored
+		after = next
+	}
+}
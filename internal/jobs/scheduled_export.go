@@ -0,0 +1,206 @@
+package jobs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/persistence"
+	"go.uber.org/zap"
+)
+
+// scheduledExportBatch is how many due exports one run goes through
+const scheduledExportBatch = 10
+
+// ExportDeliverer sends an export file to its destination and returns where
+// it was delivered
+type ExportDeliverer interface {
+	Deliver(ctx context.Context, export *domain.ScheduledExport, run *domain.ScheduledExportRun, file domain.ExportFile) (string, error)
+}
+
+// ExportAlerter tells the export's alert recipients that a run failed
+type ExportAlerter interface {
+	SendScheduledExportFailure(ctx context.Context, runID string, failure domain.ScheduledExportFailure) error
+}
+
+// ScheduledExportJob runs the scheduled customer exports that are due,
+// delivers their files and records every run. A failed run alerts the
+// export's alert recipients and waits for the next scheduled time.
+type ScheduledExportJob struct {
+	repo      *persistence.ScheduledExportRepository
+	customers persistence.CustomerRepository
+	tags      *persistence.CustomerTagRepository
+	deliverer ExportDeliverer
+	alerter   ExportAlerter
+	logger    *zap.Logger
+}
+
+// NewScheduledExportJob creates a new scheduled export job
+func NewScheduledExportJob(
+	repo *persistence.ScheduledExportRepository,
+	customers persistence.CustomerRepository,
+	tags *persistence.CustomerTagRepository,
+	deliverer ExportDeliverer,
+	alerter ExportAlerter,
+	logger *zap.Logger,
+) *ScheduledExportJob {
+	return &ScheduledExportJob{
+		repo:      repo,
+		customers: customers,
+		tags:      tags,
+		deliverer: deliverer,
+		alerter:   alerter,
+		logger:    logger,
+	}
+}
+
+// RunOnce runs the exports that are due. Each is moved on to its next
+// scheduled time before it runs, so a missed or failed run is not retried
+// until then.
+func (j *ScheduledExportJob) RunOnce(ctx context.Context) error {
+	now := time.Now()
+	due, err := j.repo.Due(ctx, now, scheduledExportBatch)
+	if err != nil {
+		return fmt.Errorf("load due scheduled exports: %w", err)
+	}
+
+	for i := range due {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		export := &due[i]
+		logger := j.logger.With(zap.String("export_id", export.ID.String()))
+
+		schedule, err := domain.ParseExportSchedule(export.Schedule)
+		if err != nil {
+			// Validated when saved, so only a hand-edited row gets here
+			logger.Error("Scheduled export has an invalid schedule", zap.Error(err))
+			continue
+		}
+		claimed, err := j.repo.Claim(ctx, export, schedule.Next(now))
+		if err != nil {
+			logger.Error("Failed to claim scheduled export", zap.Error(err))
+			continue
+		}
+		if !claimed {
+			continue
+		}
+
+		run, err := j.repo.StartRun(ctx, export, domain.ExportRunScheduled, time.Now())
+		if err != nil {
+			logger.Error("Failed to record scheduled export run", zap.Error(err))
+			continue
+		}
+		j.Execute(ctx, export, run)
+	}
+	return nil
+}
+
+// RunNow starts a run of the export outside its schedule. The run is
+// recorded before RunNow returns and carried out in the background.
+func (j *ScheduledExportJob) RunNow(ctx context.Context, export *domain.ScheduledExport) (*domain.ScheduledExportRun, error) {
+	run, err := j.repo.StartRun(ctx, export, domain.ExportRunManual, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	started := *run
+	go j.Execute(context.WithoutCancel(ctx), export, run)
+	return &started, nil
+}
+
+// Execute builds and delivers the file of a started run and records the
+// outcome
+func (j *ScheduledExportJob) Execute(ctx context.Context, export *domain.ScheduledExport, run *domain.ScheduledExportRun) {
+	logger := j.logger.With(zap.String("export_id", export.ID.String()), zap.String("run_id", run.ID.String()))
+
+	file, err := j.build(ctx, export, run.StartedAt)
+	if err == nil {
+		run.Rows, run.Bytes = file.Rows, int64(len(file.Body))
+		run.Location, err = j.deliverer.Deliver(ctx, export, run, *file)
+	}
+	finished := time.Now()
+	run.FinishedAt = &finished
+	run.Status = domain.ExportRunSucceeded
+	if err != nil {
+		run.Status, run.Error = domain.ExportRunFailed, err.Error()
+	}
+
+	// Not ctx: a file that went out should be recorded even on shutdown
+	if err := j.repo.FinishRun(context.WithoutCancel(ctx), export, run); err != nil {
+		logger.Error("Failed to record scheduled export run", zap.Error(err))
+		return
+	}
+	if run.Status == domain.ExportRunSucceeded {
+		logger.Info("Scheduled export delivered", zap.Int("rows", run.Rows), zap.String("location", run.Location))
+		return
+	}
+
+	logger.Warn("Scheduled export failed", zap.String("error", run.Error), zap.Int("consecutive_failures", export.ConsecutiveFailures))
+	if len(export.AlertEmails) == 0 {
+		return
+	}
+	if err := j.alerter.SendScheduledExportFailure(ctx, run.ID.String(), domain.ScheduledExportFailure{
+		Recipients:          export.AlertEmails,
+		ExportID:            export.ID.String(),
+		ExportName:          export.Name,
+		Error:               run.Error,
+		ConsecutiveFailures: export.ConsecutiveFailures,
+		FailedAt:            finished.UTC(),
+	}); err != nil {
+		logger.Error("Failed to send scheduled export failure alert", zap.Error(err))
+	}
+}
+
+// build exports the customers matching the export's filter in its format
+// and columns
+func (j *ScheduledExportJob) build(ctx context.Context, export *domain.ScheduledExport, startedAt time.Time) (*domain.ExportFile, error) {
+	filter, err := export.CustomerFilter()
+	if err != nil {
+		return nil, err
+	}
+	data, err := j.customers.Export(ctx, filter, export.Format)
+	if err != nil {
+		return nil, err
+	}
+	customers, _ := data.([]domain.Customer)
+
+	if slices.Contains(export.Columns, "tags") && len(customers) > 0 {
+		ids := make([]uuid.UUID, len(customers))
+		for i := range customers {
+			ids[i] = customers[i].ID
+		}
+		byCustomer, err := j.tags.NamesByCustomers(ctx, ids)
+		if err != nil {
+			return nil, err
+		}
+		for i := range customers {
+			customers[i].Tags = byCustomer[customers[i].ID]
+		}
+	}
+	if export.MaskPII {
+		for i := range customers {
+			customers[i].MaskPII()
+		}
+	}
+
+	var body bytes.Buffer
+	if export.Format == "json" {
+		err = json.NewEncoder(&body).Encode(domain.NewCustomerExport(customers, export.Columns))
+	} else {
+		err = domain.WriteCustomersCSV(&body, customers, export.Columns)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &domain.ExportFile{
+		Name:        export.Filename(startedAt),
+		ContentType: export.ContentType(),
+		Body:        body.Bytes(),
+		Rows:        len(customers),
+	}, nil
+}
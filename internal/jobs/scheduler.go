@@ -0,0 +1,71 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/metrics"
+	"go.uber.org/zap"
+)
+
+// Leader reports whether this replica runs the scheduled jobs
+type Leader interface {
+	IsLeader() bool
+}
+
+// Scheduler runs maintenance jobs on cron schedules. With several replicas
+// every replica schedules the jobs but only the leader runs them, and a run
+// still going when the next one is due is skipped.
+type Scheduler struct {
+	cron   *cron.Cron
+	leader Leader
+	logger *zap.Logger
+	ctx    context.Context
+}
+
+// NewScheduler creates a scheduler whose jobs run with ctx; a nil leader runs
+// every job on this replica
+func NewScheduler(ctx context.Context, leader Leader, logger *zap.Logger) *Scheduler {
+	return &Scheduler{
+		cron:   cron.New(cron.WithChain(cron.SkipIfStillRunning(cron.DiscardLogger))),
+		leader: leader,
+		logger: logger,
+		ctx:    ctx,
+	}
+}
+
+// Add schedules a job. spec is a five-field cron expression or a descriptor
+// such as "@daily" or "@every 1h".
+func (s *Scheduler) Add(name, spec string, run func(ctx context.Context) error) error {
+	if _, err := s.cron.AddFunc(spec, func() { s.run(name, run) }); err != nil {
+		return fmt.Errorf("schedule %s: %w", name, err)
+	}
+	s.logger.Info("Scheduled job", zap.String("job", name), zap.String("schedule", spec))
+	return nil
+}
+
+// Run starts the scheduler and blocks until the scheduler's context is done,
+// then waits for running jobs to return
+func (s *Scheduler) Run() {
+	s.cron.Start()
+	<-s.ctx.Done()
+	<-s.cron.Stop().Done()
+}
+
+func (s *Scheduler) run(name string, run func(ctx context.Context) error) {
+	if s.leader != nil && !s.leader.IsLeader() {
+		return
+	}
+
+	start := time.Now()
+	err := run(s.ctx)
+	elapsed := time.Since(start)
+	metrics.RecordJobRun(name, elapsed, err)
+	if err != nil {
+		s.logger.Error("Scheduled job failed", zap.String("job", name), zap.Duration("elapsed", elapsed), zap.Error(err))
+		return
+	}
+	s.logger.Info("Scheduled job completed", zap.String("job", name), zap.Duration("elapsed", elapsed))
+}
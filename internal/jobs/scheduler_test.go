@@ -0,0 +1,80 @@
+package jobs
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// fakeLeader is a Leader whose leadership the test sets
+type fakeLeader struct{ leader atomic.Bool }
+
+func (l *fakeLeader) IsLeader() bool { return l.leader.Load() }
+
+// trigger runs the job scheduled as entry i as cron would when it is due,
+// through the scheduler's job wrappers
+func trigger(s *Scheduler, i int) {
+	s.cron.Entries()[i].WrappedJob.Run()
+}
+
+func TestScheduler_OnlyLeaderRunsJobs(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	leader := &fakeLeader{}
+	scheduler := NewScheduler(ctx, leader, zap.NewNop())
+
+	var runs int
+	require.NoError(t, scheduler.Add("cleanup", "@daily", func(jobCtx context.Context) error {
+		assert.Equal(t, ctx, jobCtx, "jobs run with the scheduler's context")
+		runs++
+		return nil
+	}))
+
+	trigger(scheduler, 0)
+	assert.Zero(t, runs, "followers skip the job")
+
+	leader.leader.Store(true)
+	trigger(scheduler, 0)
+	assert.Equal(t, 1, runs)
+
+	leader.leader.Store(false)
+	trigger(scheduler, 0)
+	assert.Equal(t, 1, runs, "a replica that lost leadership stops running jobs")
+}
+
+func TestScheduler_SkipsRunStillGoing(t *testing.T) {
+	scheduler := NewScheduler(context.Background(), nil, zap.NewNop())
+
+	var runs atomic.Int32
+	started, release := make(chan struct{}), make(chan struct{})
+	require.NoError(t, scheduler.Add("export", "@hourly", func(context.Context) error {
+		runs.Add(1)
+		close(started)
+		<-release
+		return nil
+	}))
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		trigger(scheduler, 0)
+	}()
+	<-started
+
+	// Due again while the first run is going
+	trigger(scheduler, 0)
+	close(release)
+	wg.Wait()
+	assert.EqualValues(t, 1, runs.Load())
+}
+
+func TestScheduler_RejectsInvalidSchedule(t *testing.T) {
+	scheduler := NewScheduler(context.Background(), nil, zap.NewNop())
+	assert.ErrorContains(t, scheduler.Add("cleanup", "every day", func(context.Context) error { return nil }), "schedule cleanup")
+}
@@ -0,0 +1,119 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/persistence"
+	"go.uber.org/zap"
+)
+
+// SegmentRecomputeResult counts the customers a recompute went through and
+// the segment decisions the rules made for them
+type SegmentRecomputeResult struct {
+	Customers int
+	Decisions int
+}
+
+// SegmentRecomputeJob re-applies the segment rules to every customer, so
+// rules added or changed since take effect without waiting for each
+// customer's next order or status change
+type SegmentRecomputeJob struct {
+	customers persistence.CustomerRepository
+	rules     *persistence.SegmentRuleRepository
+	triggers  []string
+	batchSize int
+	dryRun    bool
+	onPage    func(SegmentRecomputeResult)
+	logger    *zap.Logger
+}
+
+// NewSegmentRecomputeJob creates a recompute job evaluating the rules of
+// every trigger, 500 customers at a time
+func NewSegmentRecomputeJob(customers persistence.CustomerRepository, rules *persistence.SegmentRuleRepository, logger *zap.Logger) *SegmentRecomputeJob {
+	return &SegmentRecomputeJob{
+		customers: customers,
+		rules:     rules,
+		triggers:  []string{domain.SegmentTriggerOrderCompleted, domain.SegmentTriggerStatusChanged, domain.SegmentTriggerRFMScored},
+		batchSize: 500,
+		logger:    logger,
+	}
+}
+
+// WithTriggers limits the rules evaluated to those of the triggers, in order
+func (j *SegmentRecomputeJob) WithTriggers(triggers []string) *SegmentRecomputeJob {
+	j.triggers = triggers
+	return j
+}
+
+// WithBatchSize sets the number of customers loaded per page
+func (j *SegmentRecomputeJob) WithBatchSize(n int) *SegmentRecomputeJob {
+	j.batchSize = n
+	return j
+}
+
+// WithDryRun evaluates the rules without changing segment assignments
+func (j *SegmentRecomputeJob) WithDryRun(dryRun bool) *SegmentRecomputeJob {
+	j.dryRun = dryRun
+	return j
+}
+
+// OnPage sets a callback invoked with the running totals after each page
+func (j *SegmentRecomputeJob) OnPage(fn func(SegmentRecomputeResult)) *SegmentRecomputeJob {
+	j.onPage = fn
+	return j
+}
+
+// RunOnce recomputes every customer's segments once
+func (j *SegmentRecomputeJob) RunOnce(ctx context.Context) error {
+	result, err := j.Recompute(ctx)
+	if err != nil {
+		return err
+	}
+	j.logger.Info("Recomputed customer segments",
+		zap.Int("customers", result.Customers), zap.Int("decisions", result.Decisions))
+	return nil
+}
+
+// Recompute pages through the customers in creation order and evaluates, or
+// applies, the rules for each. It stops between pages when ctx is done.
+func (j *SegmentRecomputeJob) Recompute(ctx context.Context) (SegmentRecomputeResult, error) {
+	var result SegmentRecomputeResult
+	filter := domain.CustomerListFilter{Limit: j.batchSize, Sort: domain.Sort{Field: "created_at", Direction: domain.SortAsc}}
+	var after *domain.Cursor
+	for {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+		page, next, err := j.customers.ListAdminAfter(ctx, filter, after)
+		if err != nil {
+			return result, fmt.Errorf("list customers: %w", err)
+		}
+		for _, customer := range page {
+			for _, trigger := range j.triggers {
+				var evaluation *domain.SegmentEvaluation
+				if j.dryRun {
+					evaluation, err = j.rules.Evaluate(ctx, customer.ID, trigger)
+				} else {
+					evaluation, err = j.rules.Apply(ctx, customer.ID, trigger)
+				}
+				if err != nil {
+					return result, fmt.Errorf("customer %s: %w", customer.ID, err)
+				}
+				result.Decisions += len(evaluation.Decisions)
+			}
+		}
+		result.Customers += len(page)
+		if j.onPage != nil {
+			j.onPage(result)
+		}
+
+		if next == "" {
+			return result, nil
+		}
+		if after, err = domain.DecodeCursor(next); err != nil {
+			return result, err
+		}
+	}
+}
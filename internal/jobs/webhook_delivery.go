@@ -0,0 +1,138 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/persistence"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// webhookDeliveryBatch is how many due deliveries one run sends
+const webhookDeliveryBatch = 100
+
+// webhookDeliveryLease is how long a claimed delivery is left to the replica
+// sending it before another may pick it up
+const webhookDeliveryLease = 5 * time.Minute
+
+// WebhookSender makes one attempt to send a delivery
+type WebhookSender interface {
+	Send(ctx context.Context, subscription *domain.WebhookSubscription, delivery *domain.OutboundWebhookDelivery) *domain.OutboundWebhookAttempt
+}
+
+// WebhookDeliveryJob sends queued customer events to webhook subscriptions,
+// backing off exponentially after failures until the retry policy gives up
+type WebhookDeliveryJob struct {
+	repo     *persistence.WebhookSubscriptionRepository
+	sender   WebhookSender
+	policy   domain.NotificationRetryPolicy
+	interval time.Duration
+	logger   *zap.Logger
+}
+
+// NewWebhookDeliveryJob creates a new webhook delivery job
+func NewWebhookDeliveryJob(
+	repo *persistence.WebhookSubscriptionRepository,
+	sender WebhookSender,
+	policy domain.NotificationRetryPolicy,
+	interval time.Duration,
+	logger *zap.Logger,
+) *WebhookDeliveryJob {
+	return &WebhookDeliveryJob{
+		repo:     repo,
+		sender:   sender,
+		policy:   policy,
+		interval: interval,
+		logger:   logger,
+	}
+}
+
+// Run sends due deliveries immediately and then every interval until ctx is done
+func (j *WebhookDeliveryJob) Run(ctx context.Context) {
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	for {
+		j.RunOnce(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce sends the deliveries that are due
+func (j *WebhookDeliveryJob) RunOnce(ctx context.Context) {
+	due, err := j.repo.Due(ctx, time.Now(), webhookDeliveryBatch)
+	if err != nil {
+		j.logger.Error("Failed to load due webhook deliveries", zap.Error(err))
+		return
+	}
+
+	subscriptions := make(map[uuid.UUID]*domain.WebhookSubscription)
+	for i := range due {
+		if ctx.Err() != nil {
+			return
+		}
+		delivery := &due[i]
+		subscription, ok := subscriptions[delivery.SubscriptionID]
+		if !ok {
+			subscription, err = j.repo.Get(ctx, delivery.SubscriptionID)
+			if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+				j.logger.Error("Failed to load webhook subscription", zap.Error(err))
+				return
+			}
+			subscriptions[delivery.SubscriptionID] = subscription
+		}
+		if subscription == nil {
+			// Deleted meanwhile, along with its deliveries
+			continue
+		}
+		j.process(ctx, subscription, delivery)
+	}
+}
+
+// process claims and sends one delivery and records the attempt
+func (j *WebhookDeliveryJob) process(ctx context.Context, subscription *domain.WebhookSubscription, delivery *domain.OutboundWebhookDelivery) {
+	logger := j.logger.With(
+		zap.String("subscription_id", subscription.ID.String()),
+		zap.String("delivery_id", delivery.ID.String()),
+		zap.String("event_type", delivery.EventType),
+		zap.Int("attempt", delivery.Attempts+1))
+
+	claimed, err := j.repo.Claim(ctx, delivery, webhookDeliveryLease, time.Now())
+	if err != nil {
+		logger.Error("Failed to claim webhook delivery", zap.Error(err))
+		return
+	}
+	if !claimed {
+		return
+	}
+
+	attempt := j.sender.Send(ctx, subscription, delivery)
+	var next *time.Time
+	if attempt.Error != "" {
+		if at, ok := j.policy.NextAttempt(delivery.Attempts+1, time.Now()); ok {
+			next = &at
+			logger.Warn("Webhook delivery failed", zap.Time("next_attempt_at", at), zap.String("error", attempt.Error))
+		} else {
+			logger.Error("Webhook delivery gave up", zap.String("error", attempt.Error))
+		}
+	}
+
+	// Not ctx: a send that made it out should be recorded even on shutdown
+	if err := j.repo.RecordAttempt(context.WithoutCancel(ctx), delivery, attempt, next); err != nil {
+		// The lease runs out and the delivery is sent again
+		logger.Error("Failed to record webhook delivery attempt", zap.Error(err))
+		return
+	}
+	if attempt.Error == "" {
+		logger.Info("Webhook delivered", zap.Int("status_code", attempt.StatusCode))
+	}
+}
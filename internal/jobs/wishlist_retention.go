@@ -0,0 +1,131 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/persistence"
+	"go.uber.org/zap"
+)
+
+// WishlistNudger asks a customer whether they still want stale wishlist items
+type WishlistNudger interface {
+	NotifyStillInterested(ctx context.Context, customerID uuid.UUID, items []domain.WishlistItem, archiveAt time.Time) error
+}
+
+// WishlistRetentionJob asks customers whether they still want wishlist items
+// saved long ago and archives the items they don't confirm. Customers who
+// haven't opted in to marketing email aren't asked, so their items stay.
+type WishlistRetentionJob struct {
+	repo      *persistence.WishlistRepository
+	nudger    WishlistNudger
+	policy    domain.WishlistRetentionPolicy
+	batchSize int
+	logger    *zap.Logger
+}
+
+// NewWishlistRetentionJob creates a wishlist retention job applying the
+// policy, 500 customers at a time. Without a nudger no one is asked, and
+// only items already nudged are archived.
+func NewWishlistRetentionJob(
+	repo *persistence.WishlistRepository,
+	nudger WishlistNudger,
+	policy domain.WishlistRetentionPolicy,
+	logger *zap.Logger,
+) *WishlistRetentionJob {
+	return &WishlistRetentionJob{
+		repo:      repo,
+		nudger:    nudger,
+		policy:    policy,
+		batchSize: 500,
+		logger:    logger,
+	}
+}
+
+// RunOnce nudges customers about stale items and archives expired nudges once
+func (j *WishlistRetentionJob) RunOnce(ctx context.Context) error {
+	var result domain.WishlistRetentionResult
+	now := time.Now()
+
+	failed := 0
+	if j.nudger != nil {
+		after := uuid.Nil
+		for {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			items, next, err := j.repo.ListStaleForNudge(ctx, after, j.batchSize, j.policy.StaleBefore(now))
+			if err != nil {
+				return fmt.Errorf("list stale wishlist items: %w", err)
+			}
+			for start := 0; start < len(items); {
+				end := start
+				for end < len(items) && items[end].UserID == items[start].UserID {
+					end++
+				}
+				nudged, err := j.nudge(ctx, items[start:end], now)
+				if err != nil {
+					return err
+				}
+				if nudged {
+					result.Customers++
+					result.Nudged += end - start
+				} else {
+					failed++
+				}
+				start = end
+			}
+			if next == uuid.Nil {
+				break
+			}
+			after = next
+		}
+	}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		archived, err := j.repo.ArchiveUnconfirmed(ctx, j.policy.ArchiveBefore(now), j.batchSize, now)
+		if err != nil {
+			return fmt.Errorf("archive unconfirmed wishlist items: %w", err)
+		}
+		result.Archived += archived
+		if archived < j.batchSize {
+			break
+		}
+	}
+
+	j.logger.Info("Applied wishlist retention",
+		zap.Int("customers", result.Customers),
+		zap.Int("nudged", result.Nudged),
+		zap.Int("archived", result.Archived))
+	if failed > 0 {
+		return fmt.Errorf("nudge %d customers failed", failed)
+	}
+	return nil
+}
+
+// nudge asks one customer about their stale items and marks them nudged. A
+// failed notification is logged and retried on the next run.
+func (j *WishlistRetentionJob) nudge(ctx context.Context, items []domain.WishlistItem, now time.Time) (bool, error) {
+	customerID := items[0].UserID
+	if err := j.nudger.NotifyStillInterested(ctx, customerID, items, j.policy.ArchiveAt(now)); err != nil {
+		j.logger.Warn("Failed to nudge customer about stale wishlist items",
+			zap.String("customer_id", customerID.String()),
+			zap.Error(err))
+		return false, nil
+	}
+
+	ids := make([]uuid.UUID, len(items))
+	for i, item := range items {
+		ids[i] = item.ID
+	}
+	if err := j.repo.MarkNudged(ctx, ids, now); err != nil {
+		return false, fmt.Errorf("mark wishlist items nudged: %w", err)
+	}
+	return true, nil
+}
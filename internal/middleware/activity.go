@@ -0,0 +1,122 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/authctx"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+)
+
+const (
+	activityDetailsKey = "activity_details"
+	activitySkipKey    = "activity_skip"
+)
+
+// ActivityRecorder stores customer activity timeline entries
+type ActivityRecorder interface {
+	Record(ctx context.Context, activity *domain.CustomerActivity) error
+}
+
+type trackedAction struct {
+	activityType string
+	title        string
+}
+
+// ActivityTracker logs successful customer actions to the activity timeline
+// so the admin timeline doesn't depend on every handler remembering to do it
+type ActivityTracker struct {
+	recorder ActivityRecorder
+	actions  map[string]trackedAction // keyed by "METHOD /route/template"
+}
+
+// NewActivityTracker creates an activity tracker. No route is tracked until
+// registered with Track.
+func NewActivityTracker(recorder ActivityRecorder) *ActivityTracker {
+	return &ActivityTracker{
+		recorder: recorder,
+		actions:  make(map[string]trackedAction),
+	}
+}
+
+// Track logs an activity of the given type and title whenever a request to the
+// route (e.g. "PUT", "/api/v1/customer/addresses/:id") succeeds
+func (t *ActivityTracker) Track(method, route, activityType, title string) *ActivityTracker {
+	t.actions[method+" "+route] = trackedAction{activityType: activityType, title: title}
+	return t
+}
+
+// SetActivityDetails sets the details of the activity tracked for this
+// request, e.g. the product name of a wishlist add. Without it the details
+// are the route parameters.
+func SetActivityDetails(c *gin.Context, details string) {
+	c.Set(activityDetailsKey, details)
+}
+
+// SkipActivity stops the activity tracked for this request from being
+// recorded, e.g. for a dry run that changed nothing
+func SkipActivity(c *gin.Context) {
+	c.Set(activitySkipKey, true)
+}
+
+// Middleware returns the gin middleware recording tracked actions. It must run
+// after AuthMiddleware so the customer is known.
+func (t *ActivityTracker) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		action, ok := t.actions[c.Request.Method+" "+c.FullPath()]
+		if !ok || c.Writer.Status() < 200 || c.Writer.Status() >= 300 || c.GetBool(activitySkipKey) {
+			return
+		}
+		customerID := authctx.UserID(c)
+		if customerID == uuid.Nil {
+			return
+		}
+
+		activity := &domain.CustomerActivity{
+			CustomerID: customerID,
+			Type:       action.activityType,
+			Title:      action.title,
+			Details:    activityDetails(c),
+			IPAddress:  c.ClientIP(),
+			UserAgent:  truncate(c.Request.UserAgent(), 500),
+			RequestID:  truncate(c.GetHeader("X-Request-ID"), 100),
+		}
+		if session, ok := GetImpersonation(c); ok {
+			activity.ActorID = &session.AdminID
+		}
+
+		// The response is already written; a failure here must not affect it,
+		// nor should a client disconnecting cancel the write
+		ctx := context.WithoutCancel(c.Request.Context())
+		if err := t.recorder.Record(ctx, activity); err != nil {
+			log.Printf("⚠️  Failed to record %s activity for customer %s: %v", action.activityType, customerID, err)
+		}
+	}
+}
+
+// activityDetails returns the details set by the handler, or the route
+// parameters, e.g. "id=3f2a..."
+func activityDetails(c *gin.Context) string {
+	if details := c.GetString(activityDetailsKey); details != "" {
+		return details
+	}
+
+	params := make([]string, 0, len(c.Params))
+	for _, param := range c.Params {
+		params = append(params, fmt.Sprintf("%s=%s", param.Key, param.Value))
+	}
+	return strings.Join(params, " ")
+}
+
+func truncate(s string, n int) string {
+	if len(s) > n {
+		return s[:n]
+	}
+	return s
+}
@@ -0,0 +1,243 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/authctx"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+)
+
+// maxAuditBody bounds how much of a request or response body is kept for the audit log
+const maxAuditBody = 64 << 10
+
+// AuditRecorder stores admin audit log entries
+type AuditRecorder interface {
+	Record(ctx context.Context, entry *domain.AuditLog) error
+}
+
+// AuditSnapshotFunc loads an entity as it currently is, or nil if it does not exist
+type AuditSnapshotFunc func(ctx context.Context, id uuid.UUID) (interface{}, error)
+
+type auditedRoute struct {
+	ignored    bool
+	entityType string
+	action     string
+	idParam    string
+}
+
+// AuditTrail records every successful admin mutation to the audit log, with
+// the entity before and after the change when a snapshot loader is registered
+// for its type
+type AuditTrail struct {
+	recorder  AuditRecorder
+	snapshots map[string]AuditSnapshotFunc // keyed by entity type
+	routes    map[string]auditedRoute      // keyed by "METHOD /route/template"
+}
+
+// NewAuditTrail creates an audit trail. Routes not registered with Audit are
+// still recorded, named after their path.
+func NewAuditTrail(recorder AuditRecorder) *AuditTrail {
+	return &AuditTrail{
+		recorder:  recorder,
+		snapshots: make(map[string]AuditSnapshotFunc),
+		routes:    make(map[string]auditedRoute),
+	}
+}
+
+// Entity registers how to load snapshots of an entity type for before/after diffs
+func (a *AuditTrail) Entity(entityType string, snapshot AuditSnapshotFunc) *AuditTrail {
+	a.snapshots[entityType] = snapshot
+	return a
+}
+
+// Audit names the entity type and action of a route. idParam is the route
+// parameter holding the entity ID; when empty (creates) the ID is taken from
+// the response.
+func (a *AuditTrail) Audit(method, route, entityType, action, idParam string) *AuditTrail {
+	a.routes[method+" "+route] = auditedRoute{entityType: entityType, action: action, idParam: idParam}
+	return a
+}
+
+// Ignore excludes a route that changes nothing despite its method, e.g. a preview
+func (a *AuditTrail) Ignore(method, route string) *AuditTrail {
+	a.routes[method+" "+route] = auditedRoute{ignored: true}
+	return a
+}
+
+// Middleware returns the gin middleware recording admin mutations. It must run
+// after AuthMiddleware so the acting admin is known.
+func (a *AuditTrail) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			c.Next()
+			return
+		}
+
+		route := a.route(c)
+		if route.ignored {
+			c.Next()
+			return
+		}
+		ctx := context.WithoutCancel(c.Request.Context())
+		snapshot := a.snapshots[route.entityType]
+
+		var entityID *uuid.UUID
+		if id, err := uuid.Parse(c.Param(route.idParam)); err == nil {
+			entityID = &id
+		}
+
+		requestBody := captureRequestBody(c)
+		var before interface{}
+		if snapshot != nil && entityID != nil {
+			var err error
+			if before, err = snapshot(ctx, *entityID); err != nil {
+				log.Printf("⚠️  Failed to load %s %s for the audit log: %v", route.entityType, *entityID, err)
+			}
+		}
+
+		writer := &auditWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+		c.Next()
+
+		if c.Writer.Status() < 200 || c.Writer.Status() >= 300 || c.GetBool(idempotentReplayKey) {
+			return
+		}
+
+		responseData, _ := toAuditMap(json.RawMessage(writer.body.Bytes()))["data"].(map[string]interface{})
+		if entityID == nil {
+			if id, err := uuid.Parse(stringField(responseData, "id")); err == nil {
+				entityID = &id
+			}
+		}
+
+		var after interface{}
+		switch {
+		case route.action == domain.AuditActionDelete:
+		case snapshot != nil && entityID != nil:
+			var err error
+			if after, err = snapshot(ctx, *entityID); err != nil {
+				log.Printf("⚠️  Failed to load %s %s for the audit log: %v", route.entityType, *entityID, err)
+			}
+		case responseData != nil:
+			after = responseData
+		}
+
+		beforeMap, afterMap := toAuditMap(before), toAuditMap(after)
+		entry := &domain.AuditLog{
+			ActorID:    authctx.UserID(c),
+			ActorRole:  authctx.Role(c),
+			Action:     route.action,
+			EntityType: route.entityType,
+			EntityID:   entityID,
+			Method:     c.Request.Method,
+			Path:       truncate(c.Request.URL.Path, 255),
+			Status:     c.Writer.Status(),
+			Before:     beforeMap,
+			After:      afterMap,
+			Changes:    domain.DiffAuditSnapshots(beforeMap, afterMap),
+			Request:    toAuditMap(requestBody),
+			IPAddress:  c.ClientIP(),
+			RequestID:  truncate(c.GetHeader("X-Request-ID"), 100),
+		}
+
+		// The response is already written; a failure here must not affect it
+		if err := a.recorder.Record(ctx, entry); err != nil {
+			log.Printf("⚠️  Failed to record audit log for %s %s: %v", c.Request.Method, c.FullPath(), err)
+		}
+	}
+}
+
+// route returns the registered route, or one named after the path: the
+// first segment after /admin/ is the entity type and the first parameter
+// the entity ID
+func (a *AuditTrail) route(c *gin.Context) auditedRoute {
+	if route, ok := a.routes[c.Request.Method+" "+c.FullPath()]; ok {
+		return route
+	}
+
+	route := auditedRoute{action: methodAuditAction(c.Request.Method)}
+	if _, rest, ok := strings.Cut(c.FullPath(), "/admin/"); ok {
+		route.entityType, _, _ = strings.Cut(rest, "/")
+	}
+	if len(c.Params) > 0 {
+		route.idParam = c.Params[0].Key
+	}
+	return route
+}
+
+func methodAuditAction(method string) string {
+	switch method {
+	case http.MethodPost:
+		return domain.AuditActionCreate
+	case http.MethodDelete:
+		return domain.AuditActionDelete
+	}
+	return domain.AuditActionUpdate
+}
+
+// captureRequestBody reads a JSON request body and puts it back for the handler
+func captureRequestBody(c *gin.Context) json.RawMessage {
+	if c.Request.Body == nil || c.Request.ContentLength > maxAuditBody ||
+		!strings.HasPrefix(c.ContentType(), "application/json") {
+		return nil
+	}
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxAuditBody))
+	if err != nil {
+		return nil
+	}
+	c.Request.Body = io.NopCloser(io.MultiReader(bytes.NewReader(body), c.Request.Body))
+	return body
+}
+
+// toAuditMap converts a value to its JSON object form, or nil if it is not one
+func toAuditMap(value interface{}) map[string]interface{} {
+	if value == nil {
+		return nil
+	}
+	if raw, ok := value.(json.RawMessage); ok && len(raw) == 0 {
+		return nil
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil
+	}
+	return fields
+}
+
+func stringField(fields map[string]interface{}, key string) string {
+	value, _ := fields[key].(string)
+	return value
+}
+
+// auditWriter keeps a copy of the response body for the audit log
+type auditWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *auditWriter) Write(data []byte) (int, error) {
+	if w.body.Len()+len(data) <= maxAuditBody {
+		w.body.Write(data)
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *auditWriter) WriteString(s string) (int, error) {
+	if w.body.Len()+len(s) <= maxAuditBody {
+		w.body.WriteString(s)
+	}
+	return w.ResponseWriter.WriteString(s)
+}
@@ -1,59 +1,102 @@
 package middleware
 
 import (
+	"context"
+	"crypto/rsa"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v4"
 	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/authctx"
+	"github.com/Ecom-micro-template/service-customer/internal/response"
 )
 
-// JWTConfig holds JWT configuration
+// JWTConfig holds JWT configuration. Tokens signed with the shared HMAC
+// secret and RSA tokens from the identity provider are both accepted, so
+// auth can move to the identity provider without a cutover.
 type JWTConfig struct {
-	Secret string
+	Secret     string     // HMAC secret; empty rejects HMAC tokens
+	Keys       PublicKeys // identity provider keys; nil rejects RSA tokens
+	Algorithms []string   // accepted signing algorithms; empty accepts HS256 and RS256
+	Issuer     string     // required iss claim; empty skips the check
+	Audience   string     // required aud claim; empty skips the check
+
+	// ClockSkew is tolerated when checking exp, nbf and iat, for clocks that
+	// drift from the identity provider's
+	ClockSkew time.Duration
 }
 
-// AuthMiddleware validates JWT tokens and extracts user ID
+// PublicKeys looks up the identity provider's RSA signing keys by key ID
+type PublicKeys interface {
+	PublicKey(ctx context.Context, kid string) (*rsa.PublicKey, error)
+}
+
+// AuthMiddleware validates HMAC-signed JWT tokens and sets the user's
+// authctx.Principal
 func AuthMiddleware(jwtSecret string) gin.HandlerFunc {
+	return JWTAuthMiddleware(JWTConfig{
+		Secret:     jwtSecret,
+		Algorithms: []string{"HS256", "HS384", "HS512"},
+	})
+}
+
+// JWTAuthMiddleware validates JWT tokens as configured and sets the user's
+// authctx.Principal. User IDs are only ever taken from the verified token,
+// never from request headers a client could set.
+func JWTAuthMiddleware(cfg JWTConfig) gin.HandlerFunc {
+	algorithms := cfg.Algorithms
+	if len(algorithms) == 0 {
+		algorithms = []string{jwt.SigningMethodHS256.Alg(), jwt.SigningMethodRS256.Alg()}
+	}
+	parser := jwt.NewParser(jwt.WithValidMethods(algorithms), jwt.WithoutClaimsValidation())
+
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header required"})
-			c.Abort()
+			response.Abort(c, http.StatusUnauthorized, "Authorization header required")
 			return
 		}
 
 		// Extract token from "Bearer <token>"
 		parts := strings.Split(authHeader, " ")
 		if len(parts) != 2 || parts[0] != "Bearer" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid authorization header format"})
-			c.Abort()
+			response.Abort(c, http.StatusUnauthorized, "Invalid authorization header format")
 			return
 		}
 
 		tokenString := parts[1]
 
-		// Parse and validate token
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			// Validate signing method
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, jwt.ErrSignatureInvalid
+		// Parse and verify the signature; claims are checked below with the
+		// configured clock skew
+		token, err := parser.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+			switch token.Method.(type) {
+			case *jwt.SigningMethodHMAC:
+				if cfg.Secret == "" {
+					return nil, jwt.ErrSignatureInvalid
+				}
+				return []byte(cfg.Secret), nil
+			case *jwt.SigningMethodRSA:
+				if cfg.Keys == nil {
+					return nil, jwt.ErrSignatureInvalid
+				}
+				kid, _ := token.Header["kid"].(string)
+				return cfg.Keys.PublicKey(c.Request.Context(), kid)
 			}
-			return []byte(jwtSecret), nil
+			return nil, jwt.ErrSignatureInvalid
 		})
 
 		if err != nil || !token.Valid {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
-			c.Abort()
+			response.Abort(c, http.StatusUnauthorized, "Invalid or expired token")
 			return
 		}
 
 		// Extract claims
 		claims, ok := token.Claims.(jwt.MapClaims)
-		if !ok {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token claims"})
-			c.Abort()
+		if !ok || !cfg.validClaims(claims, time.Now()) {
+			response.Abort(c, http.StatusUnauthorized, "Invalid token claims")
 			return
 		}
 
@@ -63,8 +106,7 @@ func AuthMiddleware(jwtSecret string) gin.HandlerFunc {
 			// Try alternative claim name
 			userIDStr, ok = claims["sub"].(string)
 			if !ok {
-				c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found in token"})
-				c.Abort()
+				response.Abort(c, http.StatusUnauthorized, "User ID not found in token")
 				return
 			}
 		}
@@ -72,38 +114,34 @@ func AuthMiddleware(jwtSecret string) gin.HandlerFunc {
 		// Parse UUID
 		userID, err := uuid.Parse(userIDStr)
 		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid user ID format"})
-			c.Abort()
+			response.Abort(c, http.StatusUnauthorized, "Invalid user ID format")
 			return
 		}
 
-		// Set user ID in context
-		c.Set("user_id", userID)
+		principal := &authctx.Principal{ID: userID}
+		principal.Role, _ = claims["role"].(string)
+		principal.Email, _ = claims["email"].(string)
+		principal.Impersonation = impersonationFromClaims(claims)
+		principal.Regions = regionsFromClaims(claims)
+		authctx.Set(c, principal)
 
 		// Set claims for RequireAdmin middleware compatibility
 		c.Set("claims", claims)
 
-		// Set individual claim values for backward compatibility
-		if email, ok := claims["email"].(string); ok {
-			c.Set("email", email)
-			c.Set("user_email", email)
-		}
-		if role, ok := claims["role"].(string); ok {
-			c.Set("role", role)
-			c.Set("user_role", role)
-		}
-
 		c.Next()
 	}
 }
 
-// GetUserID extracts the user ID from the Gin context
-func GetUserID(c *gin.Context) (uuid.UUID, bool) {
-	userID, exists := c.Get("user_id")
-	if !exists {
-		return uuid.Nil, false
+// validClaims checks the token's validity period, allowing for clock skew,
+// and its issuer and audience
+func (cfg JWTConfig) validClaims(claims jwt.MapClaims, now time.Time) bool {
+	if !claims.VerifyExpiresAt(now.Add(-cfg.ClockSkew).Unix(), false) ||
+		!claims.VerifyNotBefore(now.Add(cfg.ClockSkew).Unix(), false) ||
+		!claims.VerifyIssuedAt(now.Add(cfg.ClockSkew).Unix(), false) {
+		return false
 	}
-
-	uid, ok := userID.(uuid.UUID)
-	return uid, ok
+	if cfg.Issuer != "" && !claims.VerifyIssuer(cfg.Issuer, true) {
+		return false
+	}
+	return cfg.Audience == "" || claims.VerifyAudience(cfg.Audience, true)
 }
@@ -0,0 +1,253 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/authctx"
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/jwks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+const testSecret = "test-secret"
+
+// authenticatedRouter serves GET /me behind AuthMiddleware, echoing the
+// principal it sets on both the Gin and the request context
+func authenticatedRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/me", AuthMiddleware(testSecret), func(c *gin.Context) {
+		userID, ok := authctx.AuthenticatedUserID(c)
+		if !ok {
+			return
+		}
+		principal := authctx.FromContext(c.Request.Context())
+		c.JSON(http.StatusOK, gin.H{
+			"id":    userID,
+			"role":  principal.Role,
+			"email": principal.Email,
+		})
+	})
+	return router
+}
+
+func signedToken(t *testing.T, claims jwt.MapClaims) string {
+	t.Helper()
+	claims["exp"] = time.Now().Add(time.Hour).Unix()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(testSecret))
+	require.NoError(t, err)
+	return token
+}
+
+func TestAuthMiddleware_SetsPrincipal(t *testing.T) {
+	user := uuid.New()
+	req := httptest.NewRequest(http.MethodGet, "/me", nil)
+	req.Header.Set("Authorization", "Bearer "+signedToken(t, jwt.MapClaims{
+		"user_id": user.String(),
+		"role":    "MANAGER",
+		"email":   "ops@example.com",
+	}))
+	// A client-supplied header must not override the token's user
+	req.Header.Set("X-User-ID", uuid.NewString())
+
+	w := httptest.NewRecorder()
+	authenticatedRouter().ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"id":"`+user.String()+`","role":"MANAGER","email":"ops@example.com"}`, w.Body.String())
+}
+
+func TestAuthMiddleware_SubjectClaim(t *testing.T) {
+	user := uuid.New()
+	req := httptest.NewRequest(http.MethodGet, "/me", nil)
+	req.Header.Set("Authorization", "Bearer "+signedToken(t, jwt.MapClaims{"sub": user.String()}))
+
+	w := httptest.NewRecorder()
+	authenticatedRouter().ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), user.String())
+}
+
+func TestAuthMiddleware_ImpersonationAndRegionClaims(t *testing.T) {
+	session := uuid.New()
+	tests := []struct {
+		name          string
+		claims        jwt.MapClaims
+		impersonation *uuid.UUID
+		regions       []string
+	}{
+		{"regular token", jwt.MapClaims{}, nil, nil},
+		{"impersonation token", jwt.MapClaims{"impersonation_id": session.String()}, &session, nil},
+		{"unparsable impersonation claim", jwt.MapClaims{"impersonation_id": 42}, &uuid.Nil, nil},
+		{"regions list", jwt.MapClaims{"regions": []string{"Selangor", "Johor"}}, nil, []string{"Selangor", "Johor"}},
+		{"regions string", jwt.MapClaims{"regions": "Selangor,Johor"}, nil, []string{"Selangor", "Johor"}},
+		{"empty regions list", jwt.MapClaims{"regions": []string{}}, nil, []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var principal *authctx.Principal
+			router := gin.New()
+			router.GET("/me", AuthMiddleware(testSecret), func(c *gin.Context) {
+				principal = authctx.Get(c)
+			})
+			tt.claims["user_id"] = uuid.NewString()
+			req := httptest.NewRequest(http.MethodGet, "/me", nil)
+			req.Header.Set("Authorization", "Bearer "+signedToken(t, tt.claims))
+			router.ServeHTTP(httptest.NewRecorder(), req)
+
+			require.NotNil(t, principal)
+			assert.Equal(t, tt.impersonation, principal.Impersonation)
+			assert.Equal(t, tt.regions, principal.Regions)
+		})
+	}
+}
+
+// identityProvider publishes RSA signing keys as a JWKS and signs tokens
+// with them
+type identityProvider struct {
+	server    *httptest.Server
+	keys      map[string]*rsa.PrivateKey
+	published []string
+	fetches   int
+}
+
+func newIdentityProvider(t *testing.T, kids ...string) *identityProvider {
+	p := &identityProvider{keys: map[string]*rsa.PrivateKey{}}
+	for _, kid := range kids {
+		p.addKey(t, kid)
+	}
+	p.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p.fetches++
+		keys := make([]map[string]string, 0, len(p.published))
+		for _, kid := range p.published {
+			key := p.keys[kid]
+			keys = append(keys, map[string]string{
+				"kty": "RSA", "kid": kid, "use": "sig",
+				"n": base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e": base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			})
+		}
+		json.NewEncoder(w).Encode(map[string]any{"keys": keys})
+	}))
+	t.Cleanup(p.server.Close)
+	return p
+}
+
+// addKey generates and publishes a signing key
+func (p *identityProvider) addKey(t *testing.T, kid string) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	p.keys[kid] = key
+	p.published = append(p.published, kid)
+}
+
+func (p *identityProvider) token(t *testing.T, kid string, claims jwt.MapClaims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = kid
+	signed, err := token.SignedString(p.keys[kid])
+	require.NoError(t, err)
+	return signed
+}
+
+func serveJWT(cfg JWTConfig, token string) int {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/me", JWTAuthMiddleware(cfg), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	req := httptest.NewRequest(http.MethodGet, "/me", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w.Code
+}
+
+func identityClaims() jwt.MapClaims {
+	return jwt.MapClaims{
+		"sub": uuid.NewString(),
+		"iss": "https://id.example.com",
+		"aud": "customer-service",
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}
+}
+
+func TestJWTAuthMiddleware_RS256FromJWKS(t *testing.T) {
+	provider := newIdentityProvider(t, "2024-01")
+	cfg := JWTConfig{
+		Keys:     jwks.NewKeySet(provider.server.URL, time.Hour, zap.NewNop()),
+		Issuer:   "https://id.example.com",
+		Audience: "customer-service",
+	}
+
+	assert.Equal(t, http.StatusOK, serveJWT(cfg, provider.token(t, "2024-01", identityClaims())))
+
+	claims := identityClaims()
+	claims["iss"] = "https://evil.example.com"
+	assert.Equal(t, http.StatusUnauthorized, serveJWT(cfg, provider.token(t, "2024-01", claims)), "issuer mismatch")
+
+	claims = identityClaims()
+	claims["aud"] = "order-service"
+	assert.Equal(t, http.StatusUnauthorized, serveJWT(cfg, provider.token(t, "2024-01", claims)), "audience mismatch")
+
+	hmac := signedToken(t, jwt.MapClaims{"sub": uuid.NewString()})
+	assert.Equal(t, http.StatusUnauthorized, serveJWT(cfg, hmac), "no HMAC secret configured")
+	assert.Equal(t, 1, provider.fetches)
+}
+
+func TestJWTAuthMiddleware_UnknownKeyIDRefetches(t *testing.T) {
+	provider := newIdentityProvider(t, "2024-01")
+	cfg := JWTConfig{Keys: jwks.NewKeySet(provider.server.URL, time.Hour, zap.NewNop()).WithRefreshInterval(0)}
+	require.Equal(t, http.StatusOK, serveJWT(cfg, provider.token(t, "2024-01", identityClaims())))
+
+	// The provider rotates to a key the service hasn't seen yet
+	provider.addKey(t, "2024-07")
+	assert.Equal(t, http.StatusOK, serveJWT(cfg, provider.token(t, "2024-07", identityClaims())))
+	assert.Equal(t, 2, provider.fetches)
+
+	// A key that isn't published at all is refused after the refetch
+	provider.keys["made-up"] = provider.keys["2024-01"]
+	assert.Equal(t, http.StatusUnauthorized, serveJWT(cfg, provider.token(t, "made-up", identityClaims())))
+	assert.Equal(t, 3, provider.fetches)
+}
+
+func TestJWTConfig_ValidClaimsClockSkew(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	cfg := JWTConfig{ClockSkew: time.Minute}
+	// Decoded tokens carry numeric dates as float64
+	at := func(offset time.Duration) float64 { return float64(now.Add(offset).Unix()) }
+
+	tests := []struct {
+		name   string
+		claims jwt.MapClaims
+		want   bool
+	}{
+		{"expired less than the skew ago", jwt.MapClaims{"exp": at(-time.Minute + time.Second)}, true},
+		{"expired the skew ago", jwt.MapClaims{"exp": at(-time.Minute)}, false},
+		{"valid from the skew ahead", jwt.MapClaims{"nbf": at(time.Minute)}, true},
+		{"valid from beyond the skew", jwt.MapClaims{"nbf": at(time.Minute + time.Second)}, false},
+		{"issued the skew ahead", jwt.MapClaims{"iat": at(time.Minute)}, true},
+		{"issued beyond the skew", jwt.MapClaims{"iat": at(time.Minute + time.Second)}, false},
+		{"no validity claims", jwt.MapClaims{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, cfg.validClaims(tt.claims, now))
+		})
+	}
+
+	assert.False(t, JWTConfig{}.validClaims(jwt.MapClaims{"exp": at(-time.Second)}, now), "no skew by default")
+}
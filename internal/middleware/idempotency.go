@@ -0,0 +1,211 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/authctx"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/Ecom-micro-template/service-customer/internal/response"
+)
+
+// IdempotencyKeyHeader is the header a client sets to make retrying an unsafe
+// request safe
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// IdempotentReplayedHeader is set on responses replayed from a stored key
+const IdempotentReplayedHeader = "Idempotent-Replayed"
+
+// idempotentReplayKey marks a request answered with a stored response, so
+// the activity and audit trails don't record the action twice
+const idempotentReplayKey = "idempotent_replay"
+
+// maxIdempotentBody bounds the request body hashed and the response body
+// stored for a key
+const maxIdempotentBody = 1 << 20
+
+// IdempotencyStore claims idempotency keys and stores their responses
+type IdempotencyStore interface {
+	Reserve(ctx context.Context, key *domain.IdempotencyKey) (*domain.IdempotencyKey, error)
+	Complete(ctx context.Context, key *domain.IdempotencyKey) error
+	Release(ctx context.Context, key *domain.IdempotencyKey) error
+}
+
+// Idempotency answers a request repeating the Idempotency-Key of an earlier
+// one with the earlier response, so a mobile client retrying on a flaky
+// network doesn't create duplicates. Requests without the header are not
+// affected.
+type Idempotency struct {
+	store IdempotencyStore
+	ttl   time.Duration
+}
+
+// NewIdempotency creates the idempotency middleware; responses are replayed
+// for ttl after the first request
+func NewIdempotency(store IdempotencyStore, ttl time.Duration) *Idempotency {
+	return &Idempotency{store: store, ttl: ttl}
+}
+
+// Middleware returns the gin middleware, mounted on the routes that honour
+// the header. It must run after AuthMiddleware so keys are scoped to the
+// caller.
+//
+// Responses below 500 are stored, errors included, so a retry sees what the
+// first request saw. After a 5xx the key is released and a retry runs again.
+func (i *Idempotency) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		raw := c.GetHeader(IdempotencyKeyHeader)
+		if raw == "" {
+			c.Next()
+			return
+		}
+		if len(raw) > domain.MaxIdempotencyKeyLength {
+			response.Fail(c, response.CodeIdempotencyKeyInvalid,
+				fmt.Sprintf("%s must be at most %d characters", IdempotencyKeyHeader, domain.MaxIdempotencyKeyLength))
+			c.Abort()
+			return
+		}
+
+		hash, ok := requestHash(c)
+		if !ok {
+			response.PayloadTooLarge(c, "Request body too large for an idempotent request")
+			c.Abort()
+			return
+		}
+
+		key := &domain.IdempotencyKey{
+			Scope:       idempotencyScope(c),
+			Key:         raw,
+			Method:      c.Request.Method,
+			Path:        truncate(c.Request.URL.Path, 255),
+			RequestHash: hash,
+			ExpiresAt:   time.Now().Add(i.ttl),
+		}
+		// A client disconnecting must not leave the key claimed
+		ctx := context.WithoutCancel(c.Request.Context())
+		existing, err := i.store.Reserve(ctx, key)
+		if err != nil {
+			// Without the store the request runs as if it had no key rather than failing
+			log.Printf("⚠️  Failed to reserve idempotency key for %s %s: %v", c.Request.Method, c.FullPath(), err)
+			c.Next()
+			return
+		}
+		if existing != nil {
+			replay(c, existing, hash)
+			return
+		}
+
+		// A panicking handler must not leave the key claimed either; the
+		// panic carries on to the recovery middleware, which answers 500
+		defer func() {
+			if r := recover(); r != nil {
+				i.release(ctx, c, key)
+				panic(r)
+			}
+		}()
+
+		writer := &idempotencyWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+		c.Next()
+
+		if c.Writer.Status() >= 500 || writer.overflow {
+			i.release(ctx, c, key)
+			return
+		}
+		key.StatusCode = c.Writer.Status()
+		key.ContentType = c.Writer.Header().Get("Content-Type")
+		key.Body = writer.body.Bytes()
+		if err := i.store.Complete(ctx, key); err != nil {
+			log.Printf("⚠️  Failed to store idempotent response for %s %s: %v", c.Request.Method, c.FullPath(), err)
+		}
+	}
+}
+
+// release frees a key so a retry runs the request again
+func (i *Idempotency) release(ctx context.Context, c *gin.Context, key *domain.IdempotencyKey) {
+	if err := i.store.Release(ctx, key); err != nil {
+		log.Printf("⚠️  Failed to release idempotency key for %s %s: %v", c.Request.Method, c.FullPath(), err)
+	}
+}
+
+// replay answers a request whose key is already claimed
+func replay(c *gin.Context, existing *domain.IdempotencyKey, hash string) {
+	defer c.Abort()
+	switch {
+	case existing.RequestHash != hash:
+		response.Fail(c, response.CodeIdempotencyKeyReused, "Idempotency key was already used for a different request")
+	case !existing.Completed():
+		c.Header("Retry-After", "1")
+		response.Fail(c, response.CodeIdempotencyKeyInProgress, "A request with this idempotency key is still in progress")
+	default:
+		c.Set(idempotentReplayKey, true)
+		SkipActivity(c)
+		c.Header(IdempotentReplayedHeader, "true")
+		c.Data(existing.StatusCode, existing.ContentType, existing.Body)
+	}
+}
+
+// idempotencyScope is who a key belongs to: the customer or admin, the
+// calling service on internal routes, or the client IP on public routes
+func idempotencyScope(c *gin.Context) string {
+	if userID := authctx.UserID(c); userID != uuid.Nil {
+		return userID.String()
+	}
+	if service, ok := GetService(c); ok {
+		return "service:" + service
+	}
+	return "ip:" + c.ClientIP()
+}
+
+// requestHash fingerprints the method, path and body of a request, putting
+// the body back for the handler. Returns false if the body is too large.
+func requestHash(c *gin.Context) (string, bool) {
+	var body []byte
+	if c.Request.Body != nil {
+		var err error
+		if body, err = io.ReadAll(io.LimitReader(c.Request.Body, maxIdempotentBody+1)); err != nil || len(body) > maxIdempotentBody {
+			return "", false
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	h := sha256.New()
+	fmt.Fprintf(h, "%s %s\n", c.Request.Method, c.Request.URL.Path)
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil)), true
+}
+
+// idempotencyWriter keeps a copy of the response body to store for the key
+type idempotencyWriter struct {
+	gin.ResponseWriter
+	body     bytes.Buffer
+	overflow bool
+}
+
+func (w *idempotencyWriter) Write(data []byte) (int, error) {
+	w.keep(len(data), func() { w.body.Write(data) })
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *idempotencyWriter) WriteString(s string) (int, error) {
+	w.keep(len(s), func() { w.body.WriteString(s) })
+	return w.ResponseWriter.WriteString(s)
+}
+
+// keep copies n more bytes unless the body outgrows maxIdempotentBody, in
+// which case the response isn't stored at all
+func (w *idempotencyWriter) keep(n int, write func()) {
+	if w.overflow || w.body.Len()+n > maxIdempotentBody {
+		w.overflow = true
+		return
+	}
+	write()
+}
@@ -0,0 +1,91 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryIdempotencyStore keeps claimed keys in memory
+type memoryIdempotencyStore struct {
+	keys     map[string]*domain.IdempotencyKey
+	released int
+}
+
+func (s *memoryIdempotencyStore) Reserve(_ context.Context, key *domain.IdempotencyKey) (*domain.IdempotencyKey, error) {
+	if existing, ok := s.keys[key.Scope+"|"+key.Key]; ok {
+		return existing, nil
+	}
+	s.keys[key.Scope+"|"+key.Key] = key
+	return nil, nil
+}
+
+func (s *memoryIdempotencyStore) Complete(context.Context, *domain.IdempotencyKey) error {
+	return nil
+}
+
+func (s *memoryIdempotencyStore) Release(_ context.Context, key *domain.IdempotencyKey) error {
+	delete(s.keys, key.Scope+"|"+key.Key)
+	s.released++
+	return nil
+}
+
+// idempotentRouter serves POST /orders through the idempotency middleware
+// behind gin's recovery, calling handle for each request that runs
+func idempotentRouter(store IdempotencyStore, handle gin.HandlerFunc) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(gin.CustomRecovery(func(c *gin.Context, _ any) {
+		c.AbortWithStatus(http.StatusInternalServerError)
+	}))
+	router.POST("/orders", NewIdempotency(store, time.Hour).Middleware(), handle)
+	return router
+}
+
+func postOrder(router *gin.Engine, key string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(`{"sku":"BAJU-01"}`))
+	req.Header.Set(IdempotencyKeyHeader, key)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestIdempotency_PanicReleasesKey(t *testing.T) {
+	store := &memoryIdempotencyStore{keys: map[string]*domain.IdempotencyKey{}}
+	calls := 0
+	router := idempotentRouter(store, func(c *gin.Context) {
+		if calls++; calls == 1 {
+			panic("order service exploded")
+		}
+		c.JSON(http.StatusCreated, gin.H{"id": "order-1"})
+	})
+
+	w := postOrder(router, "retry-me")
+	assert.Equal(t, http.StatusInternalServerError, w.Code, "the panic still reaches the recovery middleware")
+	assert.Equal(t, 1, store.released)
+	assert.Empty(t, store.keys)
+
+	// The retry runs again instead of being told the first is in progress
+	w = postOrder(router, "retry-me")
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	assert.Equal(t, 2, calls)
+}
+
+func TestIdempotency_ServerErrorReleasesKey(t *testing.T) {
+	store := &memoryIdempotencyStore{keys: map[string]*domain.IdempotencyKey{}}
+	router := idempotentRouter(store, func(c *gin.Context) {
+		c.Status(http.StatusServiceUnavailable)
+	})
+
+	assert.Equal(t, http.StatusServiceUnavailable, postOrder(router, "retry-me").Code)
+	assert.Equal(t, 1, store.released)
+	assert.Empty(t, store.keys)
+}
@@ -0,0 +1,142 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/authctx"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/Ecom-micro-template/service-customer/internal/response"
+	"gorm.io/gorm"
+)
+
+// Claims that mark a token as issued for an admin impersonation session
+const (
+	impersonatorClaim  = "impersonator_id"
+	impersonationClaim = "impersonation_id"
+)
+
+const impersonationKey = "impersonation"
+
+// ImpersonationStore looks up impersonation sessions and audit-logs their requests
+type ImpersonationStore interface {
+	GetActive(ctx context.Context, id uuid.UUID) (*domain.ImpersonationSession, error)
+	RecordRequest(ctx context.Context, request *domain.ImpersonationRequest) error
+}
+
+// IssueImpersonationToken signs a customer token for the session. The token
+// authenticates as the customer and expires with the session.
+func IssueImpersonationToken(secret string, session *domain.ImpersonationSession) (string, error) {
+	claims := jwt.MapClaims{
+		"user_id":          session.CustomerID.String(),
+		"sub":              session.CustomerID.String(),
+		"role":             "customer",
+		"scope":            session.Scope,
+		impersonatorClaim:  session.AdminID.String(),
+		impersonationClaim: session.ID.String(),
+		"iat":              session.CreatedAt.Unix(),
+		"exp":              session.ExpiresAt.Unix(),
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secret))
+}
+
+// ImpersonationMiddleware checks requests made with an impersonation token:
+// the session must still be active and belong to the token's customer, and
+// read-only sessions may only use safe methods. Every such request is
+// audit-logged with its status. Regular customer tokens pass through.
+// It must run after AuthMiddleware.
+func ImpersonationMiddleware(store ImpersonationStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		sessionID, ok := impersonation(c)
+		if !ok {
+			c.Next()
+			return
+		}
+
+		session, err := store.GetActive(c.Request.Context(), sessionID)
+		if errors.Is(err, gorm.ErrRecordNotFound) || errors.Is(err, domain.ErrImpersonationInactive) {
+			response.Abort(c, http.StatusUnauthorized, "Impersonation session expired or revoked")
+			return
+		}
+		if err != nil {
+			log.Printf("⚠️  Failed to load impersonation session: %v", err)
+			response.Abort(c, http.StatusInternalServerError, "Failed to verify impersonation session")
+			return
+		}
+		if authctx.UserID(c) != session.CustomerID {
+			response.Abort(c, http.StatusUnauthorized, "Invalid impersonation token")
+			return
+		}
+
+		if !session.Allows(c.Request.Method) {
+			response.Abort(c, http.StatusForbidden, "Impersonation session is read-only")
+		} else {
+			c.Set(impersonationKey, session)
+			c.Next()
+		}
+
+		request := &domain.ImpersonationRequest{
+			SessionID:  session.ID,
+			AdminID:    session.AdminID,
+			CustomerID: session.CustomerID,
+			Method:     c.Request.Method,
+			Path:       c.Request.URL.Path,
+			Status:     c.Writer.Status(),
+			IPAddress:  c.ClientIP(),
+		}
+		if err := store.RecordRequest(context.WithoutCancel(c.Request.Context()), request); err != nil {
+			log.Printf("⚠️  Failed to audit impersonated request: %v", err)
+		}
+	}
+}
+
+// BlockImpersonation rejects impersonation tokens, for routes that must only
+// be reached with the caller's own credentials (admin and internal APIs).
+// It must run after AuthMiddleware.
+func BlockImpersonation() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := impersonation(c); ok {
+			response.Abort(c, http.StatusForbidden, "Impersonation tokens cannot access this endpoint")
+			return
+		}
+		c.Next()
+	}
+}
+
+// GetImpersonation returns the impersonation session of the current request,
+// if it was made with an impersonation token
+func GetImpersonation(c *gin.Context) (*domain.ImpersonationSession, bool) {
+	value, exists := c.Get(impersonationKey)
+	if !exists {
+		return nil, false
+	}
+	session, ok := value.(*domain.ImpersonationSession)
+	return session, ok
+}
+
+// impersonationFromClaims returns the session ID of an impersonation token.
+// A token carrying the claim with an unparsable value is treated as one, so
+// it is rejected rather than let through as a regular token.
+func impersonationFromClaims(claims jwt.MapClaims) *uuid.UUID {
+	raw, ok := claims[impersonationClaim]
+	if !ok {
+		return nil
+	}
+	str, _ := raw.(string)
+	id, _ := uuid.Parse(str)
+	return &id
+}
+
+// impersonation returns the session ID of the request's impersonation token
+func impersonation(c *gin.Context) (uuid.UUID, bool) {
+	principal := authctx.Get(c)
+	if principal == nil || principal.Impersonation == nil {
+		return uuid.Nil, false
+	}
+	return *principal.Impersonation, true
+}
@@ -0,0 +1,209 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime"
+	"net/http"
+	"strings"
+	"unicode"
+
+	"github.com/gin-gonic/gin"
+)
+
+// JSON key casing conventions
+const (
+	CaseSnake = "snake" // product_id (canonical)
+	CaseCamel = "camel" // productId
+)
+
+// JSONCaseHeader lets clients pick the response key casing during the migration
+// to a single convention. "Accept: application/json; case=camel" works too.
+const JSONCaseHeader = "X-JSON-Case"
+
+// JSONCaseMiddleware rewrites JSON response keys to the casing the client asks
+// for. Models still mix camelCase (back-in-stock) and snake_case tags, so
+// without a requested casing responses are passed through unchanged; see
+// CanonicalJSONAlias for the snake_case default of v2.
+func JSONCaseMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		casing := requestedCase(c.Request)
+		c.Writer.Header().Add("Vary", "Accept, "+JSONCaseHeader)
+		if casing == "" {
+			c.Next()
+			return
+		}
+
+		writer := &jsonCaseWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+		c.Next()
+		c.Writer = writer.ResponseWriter
+
+		if !writer.buffering {
+			return
+		}
+		body := writer.body.Bytes()
+		if converted, err := convertJSONKeys(body, casing); err == nil {
+			body = converted
+			c.Writer.Header().Set(JSONCaseHeader, casing)
+		}
+		c.Writer.Header().Del("Content-Length")
+		c.Writer.Write(body)
+	}
+}
+
+// CanonicalJSONAlias serves requests under alias (e.g. "/api/v2") from the
+// routes under target ("/api/v1"), with snake_case keys unless the client asks
+// for another casing. It wraps the router so routes, permissions and load
+// shedding priorities are shared by both versions.
+func CanonicalJSONAlias(next http.Handler, alias, target string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rest, ok := strings.CutPrefix(r.URL.Path, alias)
+		if !ok || (rest != "" && !strings.HasPrefix(rest, "/")) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		r = r.Clone(r.Context())
+		r.URL.Path = target + rest
+		r.URL.RawPath = ""
+		if requestedCase(r) == "" {
+			r.Header.Set(JSONCaseHeader, CaseSnake)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// requestedCase reads the casing from the X-JSON-Case header or the Accept
+// header's "case" parameter
+func requestedCase(r *http.Request) string {
+	casing := strings.ToLower(strings.TrimSpace(r.Header.Get(JSONCaseHeader)))
+	if casing == "" {
+		for _, accept := range strings.Split(r.Header.Get("Accept"), ",") {
+			if _, params, err := mime.ParseMediaType(strings.TrimSpace(accept)); err == nil && params["case"] != "" {
+				casing = strings.ToLower(params["case"])
+				break
+			}
+		}
+	}
+
+	switch casing {
+	case CaseSnake, "snake_case":
+		return CaseSnake
+	case CaseCamel, "camelcase":
+		return CaseCamel
+	}
+	return ""
+}
+
+// isJSON reports whether a content type is JSON, including "+json" types such
+// as application/problem+json
+func isJSON(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && (mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"))
+}
+
+// jsonCaseWriter holds JSON response bodies so keys can be rewritten before
+// they are sent. Other content (CSV exports, files) is streamed as written.
+// Status codes and headers still go straight to the wrapped writer.
+type jsonCaseWriter struct {
+	gin.ResponseWriter
+	body      bytes.Buffer
+	decided   bool
+	buffering bool
+}
+
+// buffer decides on the first write, once the handler set the content type,
+// whether the body is held for conversion
+func (w *jsonCaseWriter) buffer() bool {
+	if !w.decided {
+		w.decided = true
+		w.buffering = isJSON(w.Header().Get("Content-Type"))
+	}
+	return w.buffering
+}
+
+func (w *jsonCaseWriter) Write(data []byte) (int, error) {
+	if w.buffer() {
+		return w.body.Write(data)
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *jsonCaseWriter) WriteString(s string) (int, error) {
+	if w.buffer() {
+		return w.body.WriteString(s)
+	}
+	return w.ResponseWriter.WriteString(s)
+}
+
+func (w *jsonCaseWriter) Flush() {
+	if !w.buffer() {
+		w.ResponseWriter.Flush()
+	}
+}
+
+// convertJSONKeys re-encodes a JSON document with every object key converted
+func convertJSONKeys(body []byte, casing string) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+
+	convert := toSnakeCase
+	if casing == CaseCamel {
+		convert = toCamelCase
+	}
+	return json.Marshal(convertKeys(value, convert))
+}
+
+func convertKeys(value interface{}, convert func(string) string) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		converted := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			converted[convert(key)] = convertKeys(item, convert)
+		}
+		return converted
+	case []interface{}:
+		for i, item := range v {
+			v[i] = convertKeys(item, convert)
+		}
+		return v
+	}
+	return value
+}
+
+// toSnakeCase converts "variantSku" and "userID" to "variant_sku" and "user_id"
+func toSnakeCase(key string) string {
+	runes := []rune(key)
+	var b strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			prevLower := i > 0 && (unicode.IsLower(runes[i-1]) || unicode.IsDigit(runes[i-1]))
+			nextLower := i > 0 && i+1 < len(runes) && unicode.IsLower(runes[i+1]) && unicode.IsUpper(runes[i-1])
+			if prevLower || nextLower {
+				b.WriteRune('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// toCamelCase converts "variant_sku" to "variantSku". Leading underscores, as
+// in GraphQL's "__typename", are kept.
+func toCamelCase(key string) string {
+	name := strings.TrimLeft(key, "_")
+	parts := strings.Split(name, "_")
+	for i := 1; i < len(parts); i++ {
+		if parts[i] != "" {
+			parts[i] = strings.ToUpper(parts[i][:1]) + parts[i][1:]
+		}
+	}
+	return key[:len(key)-len(name)] + strings.Join(parts, "")
+}
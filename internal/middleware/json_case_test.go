@@ -0,0 +1,156 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestToSnakeCase(t *testing.T) {
+	tests := map[string]string{
+		"variantSku":    "variant_sku",
+		"productId":     "product_id",
+		"userID":        "user_id",
+		"HTTPStatus":    "http_status",
+		"parseURLQuery": "parse_url_query",
+		"ID":            "id",
+		"address2Line":  "address2_line",
+		"line2":         "line2",
+		"top10Items":    "top10_items",
+		"variant_sku":   "variant_sku",
+		"__typename":    "__typename",
+		"":              "",
+	}
+	for key, want := range tests {
+		assert.Equal(t, want, toSnakeCase(key), key)
+	}
+}
+
+func TestToCamelCase(t *testing.T) {
+	tests := map[string]string{
+		"variant_sku":    "variantSku",
+		"user_id":        "userId",
+		"address_line_2": "addressLine2",
+		"top10_items":    "top10Items",
+		"variantSku":     "variantSku",
+		"id":             "id",
+		"__typename":     "__typename",
+		"_private_field": "_privateField",
+		"":               "",
+	}
+	for key, want := range tests {
+		assert.Equal(t, want, toCamelCase(key), key)
+	}
+}
+
+// jsonCaseRouter serves mixed-case JSON, a problem document and a CSV export
+// behind the JSON case middleware and the v2 alias
+func jsonCaseRouter(t *testing.T) http.Handler {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(JSONCaseMiddleware())
+	router.GET("/api/v1/items", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"data": []gin.H{{"productId": "p1", "variant_sku": "S1"}}})
+	})
+	router.GET("/api/v1/missing", func(c *gin.Context) {
+		c.Data(http.StatusNotFound, "application/problem+json", []byte(`{"title":"Not found","invalidParams":[{"fieldName":"id"}]}`))
+	})
+	router.GET("/api/v1/export", func(c *gin.Context) {
+		c.Header("Content-Type", "text/csv")
+		c.Status(http.StatusOK)
+		_, err := c.Writer.WriteString("customerId,firstName\n")
+		require.NoError(t, err)
+		c.Writer.Flush()
+		// The header row reached the client before the export finished
+		recorder := c.Writer.(*jsonCaseWriter).ResponseWriter
+		assert.Equal(t, len("customerId,firstName\n"), recorder.Size())
+		_, err = c.Writer.WriteString("c1,Aminah\n")
+		require.NoError(t, err)
+	})
+	return CanonicalJSONAlias(router, "/api/v2", "/api/v1")
+}
+
+func TestJSONCaseMiddleware(t *testing.T) {
+	tests := []struct {
+		name   string
+		path   string
+		header string // X-JSON-Case
+		accept string
+		want   string
+		casing string // X-JSON-Case response header
+	}{
+		{
+			name: "v1 unchanged by default",
+			path: "/api/v1/items",
+			want: `{"data":[{"productId":"p1","variant_sku":"S1"}]}`,
+		},
+		{
+			name:   "camel requested by header",
+			path:   "/api/v1/items",
+			header: "camel",
+			want:   `{"data":[{"productId":"p1","variantSku":"S1"}]}`,
+			casing: CaseCamel,
+		},
+		{
+			name:   "snake requested by accept profile",
+			path:   "/api/v1/items",
+			accept: "application/json; case=snake",
+			want:   `{"data":[{"product_id":"p1","variant_sku":"S1"}]}`,
+			casing: CaseSnake,
+		},
+		{
+			name:   "problem documents converted",
+			path:   "/api/v1/missing",
+			header: "snake",
+			want:   `{"invalid_params":[{"field_name":"id"}],"title":"Not found"}`,
+			casing: CaseSnake,
+		},
+		{
+			name:   "v2 defaults to snake",
+			path:   "/api/v2/items",
+			want:   `{"data":[{"product_id":"p1","variant_sku":"S1"}]}`,
+			casing: CaseSnake,
+		},
+		{
+			name:   "v2 honours a requested casing",
+			path:   "/api/v2/items",
+			header: "camel",
+			want:   `{"data":[{"productId":"p1","variantSku":"S1"}]}`,
+			casing: CaseCamel,
+		},
+		{
+			name:   "csv streamed unchanged",
+			path:   "/api/v2/export",
+			header: "snake",
+			want:   "customerId,firstName\nc1,Aminah\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.header != "" {
+				req.Header.Set(JSONCaseHeader, tt.header)
+			}
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			w := httptest.NewRecorder()
+			jsonCaseRouter(t).ServeHTTP(w, req)
+
+			assert.Equal(t, tt.want, w.Body.String())
+			assert.Equal(t, tt.casing, w.Header().Get(JSONCaseHeader))
+			assert.Contains(t, w.Header().Values("Vary"), "Accept, "+JSONCaseHeader)
+		})
+	}
+}
+
+func TestCanonicalJSONAlias_OnlyMatchesWholeSegments(t *testing.T) {
+	w := httptest.NewRecorder()
+	jsonCaseRouter(t).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v2items", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
@@ -0,0 +1,158 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/Ecom-micro-template/service-customer/internal/response"
+)
+
+// Priority decides how early a route is shed under load
+type Priority int
+
+const (
+	// PriorityLow routes (analytics, exports) are shed first, once the
+	// service is past its low-priority threshold, and are never queued
+	PriorityLow Priority = iota
+	// PriorityNormal routes wait in a bounded queue for a free slot
+	PriorityNormal
+	// PriorityCritical routes (health, checkout-critical internal calls) are never shed
+	PriorityCritical
+)
+
+// LoadShedConfig configures the load shedder
+type LoadShedConfig struct {
+	MaxConcurrent int           // requests served at once; 0 disables shedding
+	MaxQueue      int           // normal-priority requests allowed to wait for a slot
+	QueueTimeout  time.Duration // how long a queued request waits before being shed
+	LowPriorityAt float64       // fraction of MaxConcurrent above which low-priority requests are shed
+	RetryAfter    time.Duration // advertised in the Retry-After header
+}
+
+type routePriority struct {
+	prefix   string
+	priority Priority
+}
+
+// LoadShedder limits concurrent requests to protect the database during
+// traffic spikes, rejecting excess requests with 503 and Retry-After.
+type LoadShedder struct {
+	cfg      LoadShedConfig
+	slots    chan struct{}
+	queued   atomic.Int64
+	shed     atomic.Int64
+	routes   []routePriority
+	lowLimit int
+}
+
+// NewLoadShedder creates a load shedder. Routes default to PriorityNormal.
+func NewLoadShedder(cfg LoadShedConfig) *LoadShedder {
+	if cfg.LowPriorityAt <= 0 || cfg.LowPriorityAt > 1 {
+		cfg.LowPriorityAt = 0.75
+	}
+	if cfg.RetryAfter <= 0 {
+		cfg.RetryAfter = 5 * time.Second
+	}
+
+	s := &LoadShedder{cfg: cfg}
+	if cfg.MaxConcurrent > 0 {
+		s.slots = make(chan struct{}, cfg.MaxConcurrent)
+		// A small pool still serves low-priority requests while idle
+		s.lowLimit = max(1, int(float64(cfg.MaxConcurrent)*cfg.LowPriorityAt))
+	}
+	return s
+}
+
+// SetPriority assigns a priority to every route whose path template starts
+// with prefix (e.g. "/api/v1/admin/customers/export"). The longest matching
+// prefix wins.
+func (s *LoadShedder) SetPriority(prefix string, priority Priority) *LoadShedder {
+	s.routes = append(s.routes, routePriority{prefix: prefix, priority: priority})
+	return s
+}
+
+// Shed returns the number of requests rejected so far
+func (s *LoadShedder) Shed() int64 {
+	return s.shed.Load()
+}
+
+// priorityFor resolves the priority of a route path template
+func (s *LoadShedder) priorityFor(path string) Priority {
+	priority, longest := PriorityNormal, -1
+	for _, route := range s.routes {
+		if strings.HasPrefix(path, route.prefix) && len(route.prefix) > longest {
+			priority, longest = route.priority, len(route.prefix)
+		}
+	}
+	return priority
+}
+
+// Middleware returns the gin middleware enforcing the limits
+func (s *LoadShedder) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if s.slots == nil {
+			c.Next()
+			return
+		}
+
+		switch s.priorityFor(c.FullPath()) {
+		case PriorityCritical:
+			c.Next()
+			return
+		case PriorityLow:
+			if len(s.slots) >= s.lowLimit || !s.tryAcquire() {
+				s.reject(c)
+				return
+			}
+		default:
+			if !s.tryAcquire() && !s.waitForSlot(c) {
+				s.reject(c)
+				return
+			}
+		}
+
+		defer func() { <-s.slots }()
+		c.Next()
+	}
+}
+
+func (s *LoadShedder) tryAcquire() bool {
+	select {
+	case s.slots <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+// waitForSlot queues the request until a slot frees up, the queue timeout
+// passes or the client goes away
+func (s *LoadShedder) waitForSlot(c *gin.Context) bool {
+	if s.queued.Add(1) > int64(s.cfg.MaxQueue) {
+		s.queued.Add(-1)
+		return false
+	}
+	defer s.queued.Add(-1)
+
+	timer := time.NewTimer(s.cfg.QueueTimeout)
+	defer timer.Stop()
+
+	select {
+	case s.slots <- struct{}{}:
+		return true
+	case <-timer.C:
+		return false
+	case <-c.Request.Context().Done():
+		return false
+	}
+}
+
+func (s *LoadShedder) reject(c *gin.Context) {
+	s.shed.Add(1)
+	c.Header("Retry-After", strconv.Itoa(int(s.cfg.RetryAfter.Seconds())))
+	response.Abort(c, http.StatusServiceUnavailable, "Service is busy, please retry shortly")
+}
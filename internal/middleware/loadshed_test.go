@@ -0,0 +1,81 @@
+package middleware
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// loadShedRouter serves a route of each priority through shedder
+func loadShedRouter(shedder *LoadShedder) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	shedder.SetPriority("/health", PriorityCritical).SetPriority("/export", PriorityLow)
+	router := gin.New()
+	router.Use(shedder.Middleware())
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	router.GET("/health", ok)
+	router.GET("/items", ok)
+	router.GET("/export", ok)
+	return router
+}
+
+func TestLoadShedder_Priorities(t *testing.T) {
+	tests := []struct {
+		name          string
+		maxConcurrent int
+		lowPriorityAt float64
+		maxQueue      int
+		busy          int // slots already taken
+		path          string
+		want          int
+	}{
+		{"critical served when full", 4, 0.75, 1, 4, "/health", http.StatusOK},
+		{"normal served with a free slot", 4, 0.75, 0, 3, "/items", http.StatusOK},
+		{"normal shed when full and queue full", 4, 0.75, 0, 4, "/items", http.StatusServiceUnavailable},
+		{"normal shed when queue times out", 4, 0.75, 1, 4, "/items", http.StatusServiceUnavailable},
+		{"low served below threshold", 4, 0.75, 1, 2, "/export", http.StatusOK},
+		{"low shed at threshold", 4, 0.75, 1, 3, "/export", http.StatusServiceUnavailable},
+		{"low served by an idle single slot", 1, 0.75, 1, 0, "/export", http.StatusOK},
+		{"low served by an idle small pool", 3, 0.1, 1, 0, "/export", http.StatusOK},
+		{"low shed by a busy small pool", 3, 0.1, 1, 1, "/export", http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shedder := NewLoadShedder(LoadShedConfig{
+				MaxConcurrent: tt.maxConcurrent,
+				MaxQueue:      tt.maxQueue,
+				QueueTimeout:  10 * time.Millisecond,
+				LowPriorityAt: tt.lowPriorityAt,
+				RetryAfter:    2 * time.Second,
+			})
+			for i := 0; i < tt.busy; i++ {
+				shedder.slots <- struct{}{}
+			}
+
+			w := request(loadShedRouter(shedder), http.MethodGet, tt.path, "")
+			assert.Equal(t, tt.want, w.Code)
+			if tt.want == http.StatusServiceUnavailable {
+				assert.Equal(t, "2", w.Header().Get("Retry-After"))
+				assert.EqualValues(t, 1, shedder.Shed())
+			}
+			assert.Len(t, shedder.slots, tt.busy, "served requests release their slot")
+		})
+	}
+}
+
+func TestLoadShedder_QueuedRequestGetsFreedSlot(t *testing.T) {
+	shedder := NewLoadShedder(LoadShedConfig{MaxConcurrent: 1, MaxQueue: 1, QueueTimeout: time.Second})
+	shedder.slots <- struct{}{}
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		<-shedder.slots
+	}()
+
+	w := request(loadShedRouter(shedder), http.MethodGet, "/items", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Zero(t, shedder.Shed())
+}
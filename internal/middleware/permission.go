@@ -0,0 +1,131 @@
+package middleware
+
+import (
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/Ecom-micro-template/service-customer/internal/authctx"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/Ecom-micro-template/service-customer/internal/response"
+)
+
+// PermissionPolicy is the central table of the permission each admin route
+// requires and the permissions each role grants. Routes missing from the
+// table are denied, so a new route can't be left open by mistake.
+type PermissionPolicy struct {
+	roles  map[string][]string // by upper-cased role
+	routes map[string]string   // keyed by "METHOD /route/template"
+}
+
+// NewPermissionPolicy creates a policy granting roles their permissions
+func NewPermissionPolicy(roles map[string][]string) *PermissionPolicy {
+	p := &PermissionPolicy{
+		roles:  make(map[string][]string, len(roles)),
+		routes: make(map[string]string),
+	}
+	for role, permissions := range roles {
+		p.roles[strings.ToUpper(role)] = permissions
+	}
+	return p
+}
+
+// Require sets the permission a route requires
+func (p *PermissionPolicy) Require(method, route, permission string) *PermissionPolicy {
+	p.routes[method+" "+route] = permission
+	return p
+}
+
+// Allow lets every admin use a route
+func (p *PermissionPolicy) Allow(method, route string) *PermissionPolicy {
+	return p.Require(method, route, "")
+}
+
+// Permissions returns the permissions a role grants, with PermissionAll
+// expanded
+func (p *PermissionPolicy) Permissions(role string) []string {
+	granted := p.roles[strings.ToUpper(role)]
+	if slices.Contains(granted, domain.PermissionAll) {
+		return slices.Clone(domain.Permissions)
+	}
+	return append([]string{}, granted...)
+}
+
+// Roles returns the permissions of every role
+func (p *PermissionPolicy) Roles() map[string][]string {
+	roles := make(map[string][]string, len(p.roles))
+	for role, permissions := range p.roles {
+		roles[role] = slices.Clone(permissions)
+	}
+	return roles
+}
+
+// Routes returns the permission of every route, sorted by route
+func (p *PermissionPolicy) Routes() []domain.RoutePermission {
+	routes := make([]domain.RoutePermission, 0, len(p.routes))
+	for key, permission := range p.routes {
+		method, route, _ := strings.Cut(key, " ")
+		routes = append(routes, domain.RoutePermission{Method: method, Route: route, Permission: permission})
+	}
+	sort.Slice(routes, func(i, j int) bool {
+		if routes[i].Route != routes[j].Route {
+			return routes[i].Route < routes[j].Route
+		}
+		return routes[i].Method < routes[j].Method
+	})
+	return routes
+}
+
+// Unmapped returns the registered routes under prefix missing from the
+// table, which are denied to everyone
+func (p *PermissionPolicy) Unmapped(routes gin.RoutesInfo, prefix string) []string {
+	var missing []string
+	for _, route := range routes {
+		key := route.Method + " " + route.Path
+		if _, ok := p.routes[key]; !ok && strings.HasPrefix(route.Path, prefix) {
+			missing = append(missing, key)
+		}
+	}
+	return missing
+}
+
+// Resolve sets the permissions of the user's role on their principal,
+// without requiring any
+func (p *PermissionPolicy) Resolve() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		p.grant(c)
+		c.Next()
+	}
+}
+
+// Middleware enforces the table. It must run after AuthMiddleware so the
+// user's role is known.
+func (p *PermissionPolicy) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		granted := p.grant(c)
+
+		permission, ok := p.routes[c.Request.Method+" "+c.FullPath()]
+		if !ok {
+			response.Abort(c, http.StatusForbidden, "Forbidden: Route has no permission")
+			return
+		}
+		if permission != "" && !slices.Contains(granted, permission) {
+			response.Abort(c, http.StatusForbidden, "Forbidden: Missing required permission: "+permission)
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// grant sets the permissions of the user's role on their principal
+func (p *PermissionPolicy) grant(c *gin.Context) []string {
+	principal := authctx.Get(c)
+	if principal == nil {
+		return nil
+	}
+	principal.Permissions = p.Permissions(principal.Role)
+	return principal.Permissions
+}
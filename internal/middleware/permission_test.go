@@ -0,0 +1,62 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/authctx"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+// permissionRouter serves admin routes under policy to an admin with role
+func permissionRouter(policy *PermissionPolicy, role string) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	admin := router.Group("/admin")
+	admin.Use(func(c *gin.Context) {
+		authctx.Set(c, &authctx.Principal{ID: uuid.New(), Role: role})
+	})
+	admin.Use(policy.Middleware())
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	admin.GET("/customers/:id", ok)
+	admin.DELETE("/customers/:id", ok)
+	admin.GET("/permissions", ok)
+	admin.GET("/unlisted", ok)
+	return router
+}
+
+func TestPermissionPolicy_Middleware(t *testing.T) {
+	policy := NewPermissionPolicy(map[string][]string{
+		"ADMIN":   {domain.PermissionAll},
+		"SUPPORT": {domain.PermissionCustomersRead},
+	}).
+		Require(http.MethodGet, "/admin/customers/:id", domain.PermissionCustomersRead).
+		Require(http.MethodDelete, "/admin/customers/:id", domain.PermissionCustomersDelete).
+		Allow(http.MethodGet, "/admin/permissions")
+
+	tests := []struct {
+		name   string
+		role   string
+		method string
+		path   string
+		want   int
+	}{
+		{"role has the permission", "support", http.MethodGet, "/admin/customers/" + uuid.NewString(), http.StatusOK},
+		{"role lacks the permission", "SUPPORT", http.MethodDelete, "/admin/customers/" + uuid.NewString(), http.StatusForbidden},
+		{"wildcard role", "ADMIN", http.MethodDelete, "/admin/customers/" + uuid.NewString(), http.StatusOK},
+		{"unknown role", "INTERN", http.MethodGet, "/admin/customers/" + uuid.NewString(), http.StatusForbidden},
+		{"route every admin may use", "INTERN", http.MethodGet, "/admin/permissions", http.StatusOK},
+		{"route missing from the policy", "ADMIN", http.MethodGet, "/admin/unlisted", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			permissionRouter(policy, tt.role).ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+			assert.Equal(t, tt.want, w.Code, w.Body.String())
+		})
+	}
+}
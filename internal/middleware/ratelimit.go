@@ -0,0 +1,190 @@
+package middleware
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/authctx"
+	"github.com/Ecom-micro-template/service-customer/internal/response"
+)
+
+// RateLimit is a token bucket: PerMinute requests a minute on average with
+// bursts of up to Burst. A zero PerMinute means no limit.
+type RateLimit struct {
+	PerMinute int
+	Burst     int
+}
+
+// RateLimitConfig configures the rate limiter. Reads are GET, HEAD and
+// OPTIONS requests; everything else is a write.
+type RateLimitConfig struct {
+	Read  RateLimit
+	Write RateLimit
+	// IdleTTL is how long an unused bucket is kept before Run drops it
+	IdleTTL time.Duration
+}
+
+type routeLimit struct {
+	prefix string
+	limit  RateLimit
+	exempt bool
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// RateLimiter limits requests per client: per user once AuthMiddleware has
+// identified one, otherwise per IP. Reads and writes draw on separate
+// buckets so browsing can't use up a client's writes, and routes can be given
+// their own limit. Rejected requests get 429 with Retry-After.
+type RateLimiter struct {
+	cfg    RateLimitConfig
+	routes []routeLimit
+	now    func() time.Time
+
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+}
+
+// NewRateLimiter creates a rate limiter
+func NewRateLimiter(cfg RateLimitConfig) *RateLimiter {
+	if cfg.IdleTTL <= 0 {
+		cfg.IdleTTL = 10 * time.Minute
+	}
+	return &RateLimiter{
+		cfg:     cfg,
+		now:     time.Now,
+		buckets: make(map[string]*tokenBucket),
+	}
+}
+
+// SetLimit gives every route whose path template starts with prefix its own
+// bucket with the given limit, for both reads and writes. The longest
+// matching prefix wins.
+func (l *RateLimiter) SetLimit(prefix string, limit RateLimit) *RateLimiter {
+	l.routes = append(l.routes, routeLimit{prefix: prefix, limit: limit})
+	return l
+}
+
+// Exempt excludes routes whose path template starts with prefix
+func (l *RateLimiter) Exempt(prefix string) *RateLimiter {
+	l.routes = append(l.routes, routeLimit{prefix: prefix, exempt: true})
+	return l
+}
+
+// Middleware returns the gin middleware enforcing the limits. Mount it after
+// AuthMiddleware on authenticated groups so limits are per user.
+func (l *RateLimiter) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		bucket, limit, ok := l.bucketFor(c)
+		if !ok || limit.PerMinute <= 0 {
+			c.Next()
+			return
+		}
+
+		allowed, remaining, retryAfter := l.take(bucket, limit)
+		c.Header("X-RateLimit-Limit", strconv.Itoa(limit.PerMinute))
+		c.Header("X-RateLimit-Remaining", strconv.Itoa(remaining))
+		if !allowed {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+			response.Abort(c, http.StatusTooManyRequests, "Too many requests, please retry later")
+			return
+		}
+		c.Next()
+	}
+}
+
+// bucketFor returns the bucket key and limit of a request; ok is false for
+// exempt routes
+func (l *RateLimiter) bucketFor(c *gin.Context) (string, RateLimit, bool) {
+	client := "ip:" + c.ClientIP()
+	if userID := authctx.UserID(c); userID != uuid.Nil {
+		client = "user:" + userID.String()
+	}
+
+	path := c.FullPath()
+	var route *routeLimit
+	for i := range l.routes {
+		if strings.HasPrefix(path, l.routes[i].prefix) && (route == nil || len(l.routes[i].prefix) > len(route.prefix)) {
+			route = &l.routes[i]
+		}
+	}
+	if route != nil {
+		if route.exempt {
+			return "", RateLimit{}, false
+		}
+		return client + "|route:" + route.prefix, route.limit, true
+	}
+
+	switch c.Request.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return client + "|read", l.cfg.Read, true
+	default:
+		return client + "|write", l.cfg.Write, true
+	}
+}
+
+// take spends a token from the bucket, returning whether one was available,
+// the whole tokens left and, when none was, how long until the next one
+func (l *RateLimiter) take(key string, limit RateLimit) (bool, int, time.Duration) {
+	burst := float64(max(limit.Burst, 1))
+	perSecond := float64(limit.PerMinute) / 60
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &tokenBucket{tokens: burst, last: now}
+		l.buckets[key] = b
+	}
+	b.tokens = math.Min(burst, b.tokens+now.Sub(b.last).Seconds()*perSecond)
+	b.last = now
+
+	if b.tokens < 1 {
+		wait := time.Duration((1 - b.tokens) / perSecond * float64(time.Second))
+		return false, 0, wait
+	}
+	b.tokens--
+	return true, int(b.tokens), 0
+}
+
+// Run drops buckets idle for longer than IdleTTL, checking every IdleTTL,
+// until ctx is done. Idle buckets have usually refilled anyway, so dropping
+// them only bounds the memory held for clients that have gone away.
+func (l *RateLimiter) Run(ctx context.Context) {
+	ticker := time.NewTicker(l.cfg.IdleTTL)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			l.sweep()
+		}
+	}
+}
+
+// sweep drops the buckets idle for longer than IdleTTL
+func (l *RateLimiter) sweep() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	for key, b := range l.buckets {
+		if now.Sub(b.last) > l.cfg.IdleTTL {
+			delete(l.buckets, key)
+		}
+	}
+}
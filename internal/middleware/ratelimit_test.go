@@ -0,0 +1,141 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/authctx"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeClock is a clock that only moves when advanced
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) now() time.Time          { return c.t }
+func (c *fakeClock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+// rateLimitedRouter serves /items and /export through limiter, as the user
+// in the X-User header when there is one
+func rateLimitedRouter(limiter *RateLimiter) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		if id, err := uuid.Parse(c.GetHeader("X-User")); err == nil {
+			authctx.Set(c, &authctx.Principal{ID: id, Role: "CUSTOMER"})
+		}
+	})
+	router.Use(limiter.Middleware())
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	router.GET("/items", ok)
+	router.POST("/items", ok)
+	router.GET("/export", ok)
+	return router
+}
+
+func newTestRateLimiter(cfg RateLimitConfig) (*RateLimiter, *fakeClock) {
+	clock := &fakeClock{t: time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)}
+	limiter := NewRateLimiter(cfg)
+	limiter.now = clock.now
+	return limiter, clock
+}
+
+func request(router *gin.Engine, method, path, user string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	if user != "" {
+		req.Header.Set("X-User", user)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestRateLimiter_BurstThenRefill(t *testing.T) {
+	limiter, clock := newTestRateLimiter(RateLimitConfig{Read: RateLimit{PerMinute: 60, Burst: 3}})
+	router := rateLimitedRouter(limiter)
+
+	for i, remaining := range []string{"2", "1", "0"} {
+		w := request(router, http.MethodGet, "/items", "")
+		assert.Equal(t, http.StatusOK, w.Code, "request %d of the burst", i+1)
+		assert.Equal(t, "60", w.Header().Get("X-RateLimit-Limit"))
+		assert.Equal(t, remaining, w.Header().Get("X-RateLimit-Remaining"))
+	}
+
+	w := request(router, http.MethodGet, "/items", "")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code, "burst used up")
+	assert.Equal(t, "1", w.Header().Get("Retry-After"))
+
+	// One token a second at 60 a minute
+	clock.advance(time.Second)
+	assert.Equal(t, http.StatusOK, request(router, http.MethodGet, "/items", "").Code)
+	assert.Equal(t, http.StatusTooManyRequests, request(router, http.MethodGet, "/items", "").Code)
+
+	// Refilling stops at the burst
+	clock.advance(time.Hour)
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, http.StatusOK, request(router, http.MethodGet, "/items", "").Code)
+	}
+	assert.Equal(t, http.StatusTooManyRequests, request(router, http.MethodGet, "/items", "").Code)
+}
+
+func TestRateLimiter_RetryAfterRoundsUp(t *testing.T) {
+	limiter, clock := newTestRateLimiter(RateLimitConfig{Write: RateLimit{PerMinute: 6, Burst: 1}})
+	router := rateLimitedRouter(limiter)
+
+	assert.Equal(t, http.StatusOK, request(router, http.MethodPost, "/items", "").Code)
+
+	// A token every 10 seconds
+	w := request(router, http.MethodPost, "/items", "")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "10", w.Header().Get("Retry-After"))
+
+	clock.advance(2500 * time.Millisecond)
+	w = request(router, http.MethodPost, "/items", "")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "8", w.Header().Get("Retry-After"), "7.5s left, rounded up")
+}
+
+func TestRateLimiter_SeparateBuckets(t *testing.T) {
+	limiter, _ := newTestRateLimiter(RateLimitConfig{
+		Read:  RateLimit{PerMinute: 60, Burst: 1},
+		Write: RateLimit{PerMinute: 60, Burst: 1},
+	})
+	limiter.SetLimit("/export", RateLimit{PerMinute: 60, Burst: 1})
+	router := rateLimitedRouter(limiter)
+	alice, bob := uuid.NewString(), uuid.NewString()
+
+	assert.Equal(t, http.StatusOK, request(router, http.MethodGet, "/items", alice).Code)
+	assert.Equal(t, http.StatusTooManyRequests, request(router, http.MethodGet, "/items", alice).Code)
+
+	tests := []struct {
+		name         string
+		method, path string
+		user         string
+	}{
+		{"another user", http.MethodGet, "/items", bob},
+		{"anonymous, by IP", http.MethodGet, "/items", ""},
+		{"writes", http.MethodPost, "/items", alice},
+		{"a route with its own limit", http.MethodGet, "/export", alice},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, http.StatusOK, request(router, tt.method, tt.path, tt.user).Code, tt.name)
+	}
+}
+
+func TestRateLimiter_SweepDropsIdleBuckets(t *testing.T) {
+	limiter, clock := newTestRateLimiter(RateLimitConfig{Read: RateLimit{PerMinute: 60, Burst: 5}, IdleTTL: time.Minute})
+	router := rateLimitedRouter(limiter)
+	idle, active := uuid.NewString(), uuid.NewString()
+
+	request(router, http.MethodGet, "/items", idle)
+	clock.advance(50 * time.Second)
+	request(router, http.MethodGet, "/items", active)
+	clock.advance(20 * time.Second)
+
+	limiter.sweep()
+	assert.NotContains(t, limiter.buckets, "user:"+idle+"|read")
+	assert.Contains(t, limiter.buckets, "user:"+active+"|read")
+}
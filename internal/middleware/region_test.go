@@ -0,0 +1,120 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/Ecom-micro-template/service-customer/internal/authctx"
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+type fixedRegions []string
+
+func (r fixedRegions) GetStates(context.Context, uuid.UUID) ([]string, error) {
+	return r, nil
+}
+
+// customerStates is a CustomerRegionChecker over customers' states
+type customerStates map[uuid.UUID]string
+
+func (s customerStates) InRegion(_ context.Context, id uuid.UUID, region *domain.RegionScope) (bool, error) {
+	if region == nil {
+		return true, nil
+	}
+	return slices.Contains(region.States, s[id]), nil
+}
+
+// regionScopedRouter serves customer routes to an admin with role, scoped to
+// Selangor when the role is SALES_AGENT
+func regionScopedRouter(role string, customers customerStates) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	group := router.Group("/admin/customers")
+	group.Use(func(c *gin.Context) {
+		authctx.Set(c, &authctx.Principal{ID: uuid.New(), Role: role})
+	})
+	group.Use(RegionScopeMiddleware(fixedRegions{"Selangor"}, []string{"SALES_AGENT"}))
+	group.Use(CustomerInRegion(customers))
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	group.PUT("/:id", ok)
+	group.DELETE("/:id", ok)
+	group.POST("/:id/wallet/credit", ok)
+	group.GET("/stats", ok)
+	return router
+}
+
+func TestCustomerInRegion(t *testing.T) {
+	inRegion, outOfRegion := uuid.New(), uuid.New()
+	customers := customerStates{inRegion: "Selangor", outOfRegion: "Johor"}
+
+	requests := []struct {
+		method, suffix string
+	}{
+		{http.MethodPut, ""},
+		{http.MethodDelete, ""},
+		{http.MethodPost, "/wallet/credit"},
+	}
+	tests := []struct {
+		name     string
+		role     string
+		customer uuid.UUID
+		want     int
+	}{
+		{"agent, customer in region", "SALES_AGENT", inRegion, http.StatusOK},
+		{"agent, customer out of region", "SALES_AGENT", outOfRegion, http.StatusNotFound},
+		{"unscoped role", "ADMIN", outOfRegion, http.StatusOK},
+	}
+	for _, tt := range tests {
+		router := regionScopedRouter(tt.role, customers)
+		for _, r := range requests {
+			path := "/admin/customers/" + tt.customer.String() + r.suffix
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(r.method, path, nil))
+			assert.Equal(t, tt.want, w.Code, "%s: %s %s", tt.name, r.method, path)
+		}
+	}
+}
+
+func TestCustomerInRegion_RoutesWithoutCustomerID(t *testing.T) {
+	router := regionScopedRouter("SALES_AGENT", customerStates{})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/customers/stats", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestRegionScopeMiddleware_TokenRegionsOverrideAssignments(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		name    string
+		regions []string
+		want    []string
+	}{
+		{"no regions claim", nil, []string{"Selangor"}},
+		{"regions claim", []string{"Johor"}, []string{"Johor"}},
+		{"empty regions claim", []string{}, []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var scope *domain.RegionScope
+			router := gin.New()
+			router.GET("/admin/customers", func(c *gin.Context) {
+				authctx.Set(c, &authctx.Principal{ID: uuid.New(), Role: "sales_agent", Regions: tt.regions})
+			}, RegionScopeMiddleware(fixedRegions{"Selangor"}, []string{"SALES_AGENT"}), func(c *gin.Context) {
+				scope = GetRegionScope(c)
+			})
+			router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/admin/customers", nil))
+
+			if assert.NotNil(t, scope) {
+				assert.Equal(t, tt.want, scope.States)
+			}
+		})
+	}
+}
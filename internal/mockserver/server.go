@@ -0,0 +1,286 @@
+// Package mockserver serves the customer API from its OpenAPI spec, answering
+// every documented operation with example payloads so frontends can be built
+// without the database, NATS or other services.
+//
+// Responses default to the operation's first success status. A client can ask
+// for any other documented status with the "Prefer: code=404" header, the
+// convention used by common OpenAPI mock tools. Operations that require a
+// bearer token answer 401 when the Authorization header is missing.
+package mockserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Ecom-micro-template/service-customer/internal/response"
+)
+
+var methods = []string{"get", "post", "put", "patch", "delete"}
+
+type document struct {
+	Servers []struct {
+		URL string `json:"url"`
+	} `json:"servers"`
+	Security   []map[string][]string                 `json:"security"`
+	Paths      map[string]map[string]json.RawMessage `json:"paths"`
+	Components struct {
+		Responses map[string]responseSpec `json:"responses"`
+	} `json:"components"`
+}
+
+type operationSpec struct {
+	OperationID string                   `json:"operationId"`
+	Security    *[]map[string][]string   `json:"security"`
+	RequestBody *struct{ Required bool } `json:"requestBody"`
+	Responses   map[string]responseSpec  `json:"responses"`
+}
+
+type responseSpec struct {
+	Ref         string `json:"$ref"`
+	Description string `json:"description"`
+	Content     map[string]struct {
+		Example json.RawMessage `json:"example"`
+	} `json:"content"`
+}
+
+// Operation is one mocked endpoint
+type Operation struct {
+	ID           string
+	Method       string
+	Path         string // gin-style path including the server base path
+	AuthRequired bool
+	BodyRequired bool
+	DefaultCode  int
+	Responses    map[int]json.RawMessage
+}
+
+// Server is a mock of the API described by an OpenAPI document
+type Server struct {
+	Operations []Operation
+}
+
+// New builds a mock server from an OpenAPI 3 JSON document and a directory of
+// <operationId>.json fixtures. Each fixture maps status codes to bodies; a
+// status without a fixture falls back to the spec's inline example, and error
+// statuses fall back to a problem carrying the response description.
+// Every operation must have an example for its success response.
+func New(spec []byte, fixtures fs.FS) (*Server, error) {
+	var doc document
+	if err := json.Unmarshal(spec, &doc); err != nil {
+		return nil, fmt.Errorf("parse spec: %w", err)
+	}
+
+	basePath := ""
+	if len(doc.Servers) > 0 {
+		basePath = serverPath(doc.Servers[0].URL)
+	}
+
+	paths := make([]string, 0, len(doc.Paths))
+	for path := range doc.Paths {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	server := &Server{}
+	for _, path := range paths {
+		for _, method := range methods {
+			raw, ok := doc.Paths[path][method]
+			if !ok {
+				continue
+			}
+
+			var spec operationSpec
+			if err := json.Unmarshal(raw, &spec); err != nil {
+				return nil, fmt.Errorf("parse %s %s: %w", strings.ToUpper(method), path, err)
+			}
+
+			op, err := buildOperation(&doc, spec, fixtures)
+			if err != nil {
+				return nil, fmt.Errorf("%s %s: %w", strings.ToUpper(method), path, err)
+			}
+			op.Method = strings.ToUpper(method)
+			op.Path = basePath + ginPath(path)
+			server.Operations = append(server.Operations, *op)
+		}
+	}
+	return server, nil
+}
+
+func buildOperation(doc *document, spec operationSpec, fixtures fs.FS) (*Operation, error) {
+	if spec.OperationID == "" {
+		return nil, fmt.Errorf("operationId is required")
+	}
+
+	examples := map[string]json.RawMessage{}
+	if data, err := fs.ReadFile(fixtures, spec.OperationID+".json"); err == nil {
+		if err := json.Unmarshal(data, &examples); err != nil {
+			return nil, fmt.Errorf("parse fixture %s.json: %w", spec.OperationID, err)
+		}
+	}
+
+	security := doc.Security
+	if spec.Security != nil {
+		security = *spec.Security
+	}
+
+	op := &Operation{
+		ID:           spec.OperationID,
+		AuthRequired: len(security) > 0,
+		BodyRequired: spec.RequestBody != nil && spec.RequestBody.Required,
+		Responses:    map[int]json.RawMessage{},
+	}
+
+	for status, resp := range spec.Responses {
+		code, err := strconv.Atoi(status)
+		if err != nil {
+			return nil, fmt.Errorf("unsupported response status %q", status)
+		}
+		if resp.Ref != "" {
+			name := strings.TrimPrefix(resp.Ref, "#/components/responses/")
+			resolved, ok := doc.Components.Responses[name]
+			if !ok {
+				return nil, fmt.Errorf("unknown response %s", resp.Ref)
+			}
+			resp = resolved
+		}
+
+		body := examples[status]
+		if body == nil {
+			body = resp.Content["application/json"].Example
+		}
+		if body == nil {
+			body = resp.Content[response.ContentType].Example
+		}
+		if body == nil && code >= 400 {
+			body, _ = json.Marshal(problem(code, resp.Description))
+		}
+		if body == nil {
+			return nil, fmt.Errorf("no example for %d response", code)
+		}
+		var compact bytes.Buffer
+		if err := json.Compact(&compact, body); err != nil {
+			return nil, fmt.Errorf("example for %d response: %w", code, err)
+		}
+		op.Responses[code] = compact.Bytes()
+
+		if code < 300 && (op.DefaultCode == 0 || code < op.DefaultCode) {
+			op.DefaultCode = code
+		}
+	}
+
+	if op.DefaultCode == 0 {
+		return nil, fmt.Errorf("no success response documented")
+	}
+	return op, nil
+}
+
+// Handler returns the HTTP handler serving every operation
+func (s *Server) Handler() http.Handler {
+	router := gin.New()
+	router.Use(gin.Logger(), gin.Recovery(), allowAllOrigins())
+
+	for _, op := range s.Operations {
+		router.Handle(op.Method, op.Path, op.handle)
+	}
+
+	router.NoRoute(func(c *gin.Context) {
+		response.Write(c, problem(http.StatusNotFound, "No mocked operation for "+c.Request.Method+" "+c.Request.URL.Path))
+	})
+	return router
+}
+
+func (op Operation) handle(c *gin.Context) {
+	code := op.DefaultCode
+	if preferred, ok := preferredCode(c.GetHeader("Prefer")); ok {
+		if _, documented := op.Responses[preferred]; !documented {
+			response.Write(c, problem(http.StatusBadRequest, fmt.Sprintf("%s does not document a %d response", op.ID, preferred)))
+			return
+		}
+		code = preferred
+	} else if op.AuthRequired && c.GetHeader("Authorization") == "" {
+		code = http.StatusUnauthorized
+	} else if op.BodyRequired && c.Request.ContentLength == 0 {
+		code = http.StatusBadRequest
+	}
+
+	body, ok := op.Responses[code]
+	if !ok {
+		// 401 and 400 are mocked even when the spec leaves them out
+		body, _ = json.Marshal(problem(code, http.StatusText(code)))
+	}
+
+	contentType := "application/json; charset=utf-8"
+	if code >= 400 {
+		contentType = response.ContentType
+	}
+	c.Header("X-Mock-Operation", op.ID)
+	c.Data(code, contentType, body)
+}
+
+// problem is the generic problem the real service answers a status with
+func problem(status int, detail string) response.Problem {
+	p := response.NewProblem(response.CodeFor(status), detail)
+	p.Status = status
+	return p
+}
+
+// preferredCode parses a "Prefer: code=404" header
+func preferredCode(prefer string) (int, bool) {
+	for _, part := range strings.Split(prefer, ",") {
+		key, value, found := strings.Cut(strings.TrimSpace(part), "=")
+		if !found || key != "code" {
+			continue
+		}
+		code, err := strconv.Atoi(value)
+		if err != nil {
+			return 0, false
+		}
+		return code, true
+	}
+	return 0, false
+}
+
+// allowAllOrigins lets storefront dev servers on any port call the mock
+func allowAllOrigins() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Access-Control-Allow-Origin", "*")
+		c.Header("Access-Control-Allow-Headers", "Authorization, Content-Type, Prefer, X-JSON-Case")
+		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+		if c.Request.Method == http.MethodOptions {
+			c.AbortWithStatus(http.StatusNoContent)
+			return
+		}
+		c.Next()
+	}
+}
+
+// serverPath returns the path part of a server URL, e.g. "/api/v1"
+func serverPath(url string) string {
+	if i := strings.Index(url, "://"); i >= 0 {
+		url = url[i+3:]
+		if j := strings.Index(url, "/"); j >= 0 {
+			return strings.TrimSuffix(url[j:], "/")
+		}
+		return ""
+	}
+	return strings.TrimSuffix(url, "/")
+}
+
+// ginPath converts OpenAPI path templates ({id}) to gin parameters (:id)
+func ginPath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			segments[i] = ":" + strings.Trim(segment, "{}")
+		}
+	}
+	return strings.Join(segments, "/")
+}
@@ -0,0 +1,138 @@
+package mockserver
+
+import (
+	"encoding/json"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/Ecom-micro-template/service-customer/api"
+)
+
+func newTestServer(t *testing.T) *Server {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	fixtures, err := fs.Sub(api.Fixtures, "fixtures")
+	require.NoError(t, err)
+	server, err := New(api.Spec, fixtures)
+	require.NoError(t, err)
+	return server
+}
+
+func TestNew_LoadsEveryOperation(t *testing.T) {
+	server := newTestServer(t)
+	require.NotEmpty(t, server.Operations)
+
+	for _, op := range server.Operations {
+		assert.True(t, strings.HasPrefix(op.Path, "/api/v1/"), op.ID)
+		assert.Contains(t, op.Responses, op.DefaultCode, op.ID)
+		for code, body := range op.Responses {
+			assert.True(t, json.Valid(body), "%s %d", op.ID, code)
+		}
+	}
+}
+
+func TestServer_Handler(t *testing.T) {
+	handler := newTestServer(t).Handler()
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		header     map[string]string
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name:       "default success response",
+			method:     http.MethodGet,
+			path:       "/api/v1/customer/wishlist/count",
+			header:     map[string]string{"Authorization": "Bearer token"},
+			wantStatus: http.StatusOK,
+			wantBody:   `"success":true`,
+		},
+		{
+			name:       "missing token",
+			method:     http.MethodGet,
+			path:       "/api/v1/customer/profile",
+			wantStatus: http.StatusUnauthorized,
+			wantBody:   `"code":"UNAUTHORIZED"`,
+		},
+		{
+			name:       "public operation without token",
+			method:     http.MethodPost,
+			path:       "/api/v1/public/back-in-stock",
+			body:       `{"email": "guest@example.com"}`,
+			wantStatus: http.StatusAccepted,
+		},
+		{
+			name:       "missing required body",
+			method:     http.MethodPost,
+			path:       "/api/v1/customer/addresses",
+			header:     map[string]string{"Authorization": "Bearer token"},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "preferred error response",
+			method:     http.MethodDelete,
+			path:       "/api/v1/customer/wishlist/3f6c1a52-8d4e-4b7a-9c1e-2a5b6d7e8f90",
+			header:     map[string]string{"Authorization": "Bearer token", "Prefer": "code=404"},
+			wantStatus: http.StatusNotFound,
+			wantBody:   `"code":"WISHLIST_ITEM_NOT_FOUND"`,
+		},
+		{
+			name:       "undocumented preferred response",
+			method:     http.MethodGet,
+			path:       "/api/v1/customer/wallet",
+			header:     map[string]string{"Authorization": "Bearer token", "Prefer": "code=409"},
+			wantStatus: http.StatusBadRequest,
+			wantBody:   "does not document a 409 response",
+		},
+		{
+			name:       "unknown route",
+			method:     http.MethodGet,
+			path:       "/api/v1/customer/unknown",
+			wantStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			for key, value := range tt.header {
+				req.Header.Set(key, value)
+			}
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.wantStatus, rec.Code)
+			assert.True(t, json.Valid(rec.Body.Bytes()))
+			if tt.wantStatus >= http.StatusBadRequest {
+				assert.Equal(t, "application/problem+json", rec.Header().Get("Content-Type"))
+			}
+			if tt.wantBody != "" {
+				assert.Contains(t, rec.Body.String(), tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestPreferredCode(t *testing.T) {
+	code, ok := preferredCode("respond-async, code=422")
+	assert.True(t, ok)
+	assert.Equal(t, 422, code)
+
+	_, ok = preferredCode("code=abc")
+	assert.False(t, ok)
+
+	_, ok = preferredCode("")
+	assert.False(t, ok)
+}
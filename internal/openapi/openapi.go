@@ -0,0 +1,491 @@
+// Package openapi builds an OpenAPI 3 document in code: operations are
+// declared next to the routes they describe, and request and response
+// schemas are derived from the Go types the handlers bind and render, so the
+// document can't drift from the structs.
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Version is the OpenAPI version of generated documents
+const Version = "3.0.3"
+
+// Info describes the API
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// SecurityScheme is a way of authenticating requests
+type SecurityScheme struct {
+	Type         string `json:"type"`
+	Scheme       string `json:"scheme,omitempty"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+	Name         string `json:"name,omitempty"`
+	In           string `json:"in,omitempty"`
+	Description  string `json:"description,omitempty"`
+}
+
+// BearerAuth is a JWT bearer token scheme
+func BearerAuth() SecurityScheme {
+	return SecurityScheme{Type: "http", Scheme: "bearer", BearerFormat: "JWT"}
+}
+
+// APIKeyHeader is an API key passed in the header
+func APIKeyHeader(header string) SecurityScheme {
+	return SecurityScheme{Type: "apiKey", Name: header, In: "header"}
+}
+
+// Document is an OpenAPI document under construction. Add operations with
+// Operation, then serve the result of JSON.
+type Document struct {
+	info            Info
+	servers         []string
+	securitySchemes map[string]SecurityScheme
+	defaultSecurity []string
+	operations      []*Operation
+	errorBody       any
+	errorMediaType  string
+	schemas         *schemaRegistry
+}
+
+// New creates an empty document
+func New(info Info) *Document {
+	return &Document{
+		info:            info,
+		securitySchemes: map[string]SecurityScheme{},
+		errorMediaType:  "application/json",
+		schemas:         newSchemaRegistry(),
+	}
+}
+
+// Server adds a server base URL
+func (d *Document) Server(url string) *Document {
+	d.servers = append(d.servers, url)
+	return d
+}
+
+// SecurityScheme registers a security scheme under name. Operations
+// require the default schemes unless they set their own.
+func (d *Document) SecurityScheme(name string, scheme SecurityScheme, isDefault bool) *Document {
+	d.securitySchemes[name] = scheme
+	if isDefault {
+		d.defaultSecurity = append(d.defaultSecurity, name)
+	}
+	return d
+}
+
+// ErrorBody sets the schema of error responses, for APIs that don't fail
+// with ErrorResponse
+func (d *Document) ErrorBody(v any) *Document {
+	d.errorBody = v
+	return d
+}
+
+// ErrorMediaType sets the content type of error responses, such as
+// application/problem+json
+func (d *Document) ErrorMediaType(mediaType string) *Document {
+	d.errorMediaType = mediaType
+	return d
+}
+
+// Enum documents the values of a string type, such as a status, wherever a
+// field of that type appears
+func (d *Document) Enum(example any, values []string) *Document {
+	d.schemas.enums[reflect.TypeOf(example)] = values
+	return d
+}
+
+// Operation adds an operation. Path parameters may be written gin style
+// (/customers/:id) or OpenAPI style (/customers/{id}); they are documented
+// as required string parameters.
+func (d *Document) Operation(method, path string) *Operation {
+	op := &Operation{method: strings.ToLower(method), path: openAPIPath(path), responses: map[string]response{}}
+	d.operations = append(d.operations, op)
+	return op
+}
+
+// Group returns a helper adding operations under a common path prefix and tag
+func (d *Document) Group(prefix, tag string) *Group {
+	return &Group{doc: d, prefix: strings.TrimRight(prefix, "/"), tag: tag}
+}
+
+// Group adds operations under a common path prefix and tag
+type Group struct {
+	doc       *Document
+	prefix    string
+	tag       string
+	errorBody any
+	security  []string
+}
+
+// ErrorBody sets the schema of the group's error responses, for routes that
+// fail differently from the rest of the document
+func (g *Group) ErrorBody(v any) *Group {
+	g.errorBody = v
+	return g
+}
+
+// Security requires the named schemes on the group's operations instead of
+// the document's defaults
+func (g *Group) Security(schemes ...string) *Group {
+	g.security = schemes
+	return g
+}
+
+// Operation adds an operation at prefix+path, tagged with the group's tag
+func (g *Group) Operation(method, path string) *Operation {
+	op := g.doc.Operation(method, g.prefix+path).Tags(g.tag)
+	op.errorBody = g.errorBody
+	op.security = g.security
+	return op
+}
+
+// GET adds a GET operation
+func (g *Group) GET(path, summary string) *Operation {
+	return g.Operation(http.MethodGet, path).Summary(summary)
+}
+
+// POST adds a POST operation
+func (g *Group) POST(path, summary string) *Operation {
+	return g.Operation(http.MethodPost, path).Summary(summary)
+}
+
+// PUT adds a PUT operation
+func (g *Group) PUT(path, summary string) *Operation {
+	return g.Operation(http.MethodPut, path).Summary(summary)
+}
+
+// PATCH adds a PATCH operation
+func (g *Group) PATCH(path, summary string) *Operation {
+	return g.Operation(http.MethodPatch, path).Summary(summary)
+}
+
+// DELETE adds a DELETE operation
+func (g *Group) DELETE(path, summary string) *Operation {
+	return g.Operation(http.MethodDelete, path).Summary(summary)
+}
+
+// Operation describes one method on one path
+type Operation struct {
+	method      string
+	path        string
+	id          string
+	summary     string
+	description string
+	tags        []string
+	params      []parameter
+	body        any
+	bodyType    string
+	responses   map[string]response
+	errorBody   any
+	security    []string
+	public      bool
+	deprecated  bool
+}
+
+type parameter struct {
+	in          string // "query" or "header"
+	name        string
+	description string
+	example     any
+}
+
+type response struct {
+	description string
+	body        any
+	contentType string
+}
+
+// ID sets the operationId
+func (o *Operation) ID(id string) *Operation {
+	o.id = id
+	return o
+}
+
+// Summary sets the one-line summary
+func (o *Operation) Summary(summary string) *Operation {
+	o.summary = summary
+	return o
+}
+
+// Description sets the longer description
+func (o *Operation) Description(description string) *Operation {
+	o.description = description
+	return o
+}
+
+// Tags adds tags grouping the operation
+func (o *Operation) Tags(tags ...string) *Operation {
+	o.tags = append(o.tags, tags...)
+	return o
+}
+
+// Query documents an optional query parameter; example's type sets the
+// parameter's schema
+func (o *Operation) Query(name, description string, example any) *Operation {
+	o.params = append(o.params, parameter{in: "query", name: name, description: description, example: example})
+	return o
+}
+
+// Header documents an optional string request header
+func (o *Operation) Header(name, description string) *Operation {
+	o.params = append(o.params, parameter{in: "header", name: name, description: description, example: ""})
+	return o
+}
+
+// Body documents the JSON request body as the schema of v's type
+func (o *Operation) Body(v any) *Operation {
+	o.body = v
+	o.bodyType = "application/json"
+	return o
+}
+
+// Upload documents a multipart/form-data request body with a file field
+func (o *Operation) Upload(field string) *Operation {
+	o.body = field
+	o.bodyType = "multipart/form-data"
+	return o
+}
+
+// Returns documents a JSON response with the schema of v's type; nil means
+// no body
+func (o *Operation) Returns(status int, description string, v any) *Operation {
+	o.responses[strconv.Itoa(status)] = response{description: description, body: v, contentType: "application/json"}
+	return o
+}
+
+// ReturnsFile documents a response that is a file of the content type
+func (o *Operation) ReturnsFile(status int, description, contentType string) *Operation {
+	o.responses[strconv.Itoa(status)] = response{description: description, contentType: contentType}
+	return o
+}
+
+// Errors documents error responses with the error schema of the group or
+// document (ErrorResponse unless set), using the standard status text as
+// description
+func (o *Operation) Errors(statuses ...int) *Operation {
+	for _, status := range statuses {
+		o.Fails(status, http.StatusText(status))
+	}
+	return o
+}
+
+// Fails documents an error response like Errors, with a description saying
+// when the operation fails that way
+func (o *Operation) Fails(status int, description string) *Operation {
+	o.responses[strconv.Itoa(status)] = response{description: description, body: errorRef{}}
+	return o
+}
+
+// Security requires the named schemes instead of the document's defaults
+func (o *Operation) Security(schemes ...string) *Operation {
+	o.security = schemes
+	return o
+}
+
+// Public marks the operation as needing no authentication
+func (o *Operation) Public() *Operation {
+	o.public = true
+	return o
+}
+
+// Deprecated marks the operation as superseded; it still works
+func (o *Operation) Deprecated() *Operation {
+	o.deprecated = true
+	return o
+}
+
+// errorRef marks a response using the document's error schema
+type errorRef struct{}
+
+// ErrorResponse is the body of failed requests
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+// JSON renders the document
+func (d *Document) JSON() ([]byte, error) {
+	paths := map[string]map[string]any{}
+	ids := map[string]bool{}
+	for _, op := range d.operations {
+		if op.id != "" {
+			if ids[op.id] {
+				return nil, fmt.Errorf("duplicate operationId %q", op.id)
+			}
+			ids[op.id] = true
+		}
+		if paths[op.path] == nil {
+			paths[op.path] = map[string]any{}
+		}
+		if _, ok := paths[op.path][op.method]; ok {
+			return nil, fmt.Errorf("duplicate operation %s %s", strings.ToUpper(op.method), op.path)
+		}
+		paths[op.path][op.method] = d.renderOperation(op)
+	}
+
+	doc := map[string]any{
+		"openapi": Version,
+		"info":    d.info,
+		"paths":   paths,
+	}
+	if len(d.servers) > 0 {
+		servers := make([]map[string]string, len(d.servers))
+		for i, url := range d.servers {
+			servers[i] = map[string]string{"url": url}
+		}
+		doc["servers"] = servers
+	}
+	if len(d.defaultSecurity) > 0 {
+		doc["security"] = securityRequirement(d.defaultSecurity)
+	}
+	components := map[string]any{"schemas": d.schemas.components}
+	if len(d.securitySchemes) > 0 {
+		components["securitySchemes"] = d.securitySchemes
+	}
+	doc["components"] = components
+	return json.MarshalIndent(doc, "", "  ")
+}
+
+func (d *Document) renderOperation(op *Operation) map[string]any {
+	out := map[string]any{}
+	if op.id != "" {
+		out["operationId"] = op.id
+	}
+	if op.summary != "" {
+		out["summary"] = op.summary
+	}
+	if op.description != "" {
+		out["description"] = op.description
+	}
+	if len(op.tags) > 0 {
+		out["tags"] = op.tags
+	}
+	if op.deprecated {
+		out["deprecated"] = true
+	}
+
+	var params []map[string]any
+	for _, name := range pathParams(op.path) {
+		params = append(params, map[string]any{
+			"name": name, "in": "path", "required": true, "schema": &Schema{Type: "string"},
+		})
+	}
+	for _, p := range op.params {
+		param := map[string]any{"name": p.name, "in": p.in, "schema": d.schemas.schemaOf(reflect.TypeOf(p.example), true)}
+		if p.description != "" {
+			param["description"] = p.description
+		}
+		params = append(params, param)
+	}
+	if len(params) > 0 {
+		out["parameters"] = params
+	}
+
+	switch op.bodyType {
+	case "application/json":
+		out["requestBody"] = map[string]any{
+			"required": true,
+			"content":  map[string]any{op.bodyType: map[string]any{"schema": d.schemas.schemaOf(reflect.TypeOf(op.body), true)}},
+		}
+	case "multipart/form-data":
+		field, _ := op.body.(string)
+		out["requestBody"] = map[string]any{
+			"required": true,
+			"content": map[string]any{op.bodyType: map[string]any{"schema": &Schema{
+				Type:       "object",
+				Properties: map[string]*Schema{field: {Type: "string", Format: "binary"}},
+				Required:   []string{field},
+			}}},
+		}
+	}
+
+	responses := map[string]any{}
+	for status, resp := range op.responses {
+		rendered := map[string]any{"description": resp.description}
+		switch {
+		case resp.body == errorRef{}:
+			rendered["content"] = map[string]any{d.errorMediaType: map[string]any{"schema": d.responseSchema(op, resp.body)}}
+		case resp.body != nil:
+			rendered["content"] = map[string]any{resp.contentType: map[string]any{"schema": d.responseSchema(op, resp.body)}}
+		case resp.contentType != "application/json":
+			rendered["content"] = map[string]any{resp.contentType: map[string]any{"schema": &Schema{Type: "string", Format: "binary"}}}
+		}
+		responses[status] = rendered
+	}
+	if len(responses) == 0 {
+		responses["200"] = map[string]any{"description": "OK"}
+	}
+	out["responses"] = responses
+
+	switch {
+	case op.public:
+		out["security"] = []map[string][]string{}
+	case op.security != nil:
+		out["security"] = securityRequirement(op.security)
+	}
+	return out
+}
+
+func (d *Document) responseSchema(op *Operation, body any) *Schema {
+	if _, ok := body.(errorRef); ok {
+		switch {
+		case op.errorBody != nil:
+			body = op.errorBody
+		case d.errorBody != nil:
+			body = d.errorBody
+		default:
+			body = ErrorResponse{}
+		}
+	}
+	return d.schemas.schemaOf(reflect.TypeOf(body), false)
+}
+
+// securityRequirement requires any one of the schemes
+func securityRequirement(schemes []string) []map[string][]string {
+	out := make([]map[string][]string, len(schemes))
+	for i, name := range schemes {
+		out[i] = map[string][]string{name: {}}
+	}
+	return out
+}
+
+// openAPIPath converts gin path parameters (:id, *path) to {id}
+func openAPIPath(path string) string {
+	parts := strings.Split(path, "/")
+	for i, part := range parts {
+		if strings.HasPrefix(part, ":") || strings.HasPrefix(part, "*") {
+			parts[i] = "{" + part[1:] + "}"
+		}
+	}
+	return strings.Join(parts, "/")
+}
+
+// pathParams lists the {name} parameters of a path in order
+func pathParams(path string) []string {
+	var names []string
+	for _, part := range strings.Split(path, "/") {
+		if strings.HasPrefix(part, "{") && strings.HasSuffix(part, "}") {
+			names = append(names, part[1:len(part)-1])
+		}
+	}
+	return names
+}
+
+// Paths lists the documented "METHOD /path" pairs, sorted
+func (d *Document) Paths() []string {
+	out := make([]string, len(d.operations))
+	for i, op := range d.operations {
+		out[i] = strings.ToUpper(op.method) + " " + op.path
+	}
+	sort.Strings(out)
+	return out
+}
@@ -0,0 +1,153 @@
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type status string
+
+type base struct {
+	ID        uuid.UUID `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	Note      string    `json:"note"`
+}
+
+type item struct {
+	base
+	Note     string         `json:"note,omitempty"`
+	Status   status         `json:"status"`
+	Price    *float64       `json:"price"`
+	Tags     []string       `json:"tags,omitempty"`
+	Attrs    map[string]int `json:"attrs,omitempty"`
+	Parent   *item          `json:"parent,omitempty"`
+	Internal string         `json:"-"`
+}
+
+type createItem struct {
+	Name  string `json:"name" binding:"required,max=50"`
+	Notes string `json:"notes"`
+}
+
+type envelope[T any] struct {
+	Data T `json:"data"`
+}
+
+func render(t *testing.T, doc *Document) map[string]any {
+	t.Helper()
+	raw, err := doc.JSON()
+	require.NoError(t, err)
+	var out map[string]any
+	require.NoError(t, json.Unmarshal(raw, &out))
+	return out
+}
+
+func dig(t *testing.T, v any, keys ...string) any {
+	t.Helper()
+	for _, k := range keys {
+		m, ok := v.(map[string]any)
+		require.True(t, ok, "no object at %q", k)
+		v = m[k]
+	}
+	return v
+}
+
+func TestDocument_Operations(t *testing.T) {
+	doc := New(Info{Title: "Test", Version: "1"}).
+		SecurityScheme("bearerAuth", BearerAuth(), true).
+		Enum(status(""), []string{"active", "inactive"})
+	items := doc.Group("/api/v1/items", "Items")
+	items.GET("/:id", "Get an item").
+		ID("getItem").
+		Query("expand", "Expand relations", false).
+		Returns(http.StatusOK, "Item", envelope[item]{}).
+		Errors(http.StatusNotFound)
+	items.POST("", "Create an item").
+		ID("createItem").Public().
+		Header("Idempotency-Key", "Replays the first response").
+		Body(createItem{}).
+		Returns(http.StatusCreated, "Created", nil)
+
+	assert.Equal(t, []string{"GET /api/v1/items/{id}", "POST /api/v1/items"}, doc.Paths())
+	spec := render(t, doc)
+	assert.Equal(t, Version, spec["openapi"])
+	assert.Equal(t, []any{map[string]any{"bearerAuth": []any{}}}, spec["security"])
+
+	get := dig(t, spec, "paths", "/api/v1/items/{id}", "get").(map[string]any)
+	assert.Equal(t, []any{"Items"}, get["tags"])
+	params := get["parameters"].([]any)
+	require.Len(t, params, 2)
+	assert.Equal(t, map[string]any{"name": "id", "in": "path", "required": true, "schema": map[string]any{"type": "string"}}, params[0])
+	assert.Equal(t, "boolean", dig(t, params[1], "schema", "type"))
+	assert.Equal(t, "#/components/schemas/ErrorResponse", dig(t, get, "responses", "404", "content", "application/json", "schema", "$ref"))
+	// generic instantiations are inlined
+	assert.Equal(t, "#/components/schemas/item", dig(t, get, "responses", "200", "content", "application/json", "schema", "properties", "data", "$ref"))
+
+	post := dig(t, spec, "paths", "/api/v1/items", "post").(map[string]any)
+	assert.Equal(t, []any{}, post["security"])
+	assert.Equal(t, []any{map[string]any{"name": "Idempotency-Key", "in": "header", "description": "Replays the first response", "schema": map[string]any{"type": "string"}}}, post["parameters"])
+	assert.Nil(t, dig(t, post, "responses", "201", "content"))
+}
+
+func TestDocument_Schemas(t *testing.T) {
+	doc := New(Info{Title: "Test", Version: "1"}).Enum(status(""), []string{"active", "inactive"})
+	doc.Operation(http.MethodPost, "/items").ID("createItem").Body(createItem{}).Returns(http.StatusOK, "Item", item{})
+	spec := render(t, doc)
+
+	it := dig(t, spec, "components", "schemas", "item").(map[string]any)
+	props := it["properties"].(map[string]any)
+	assert.ElementsMatch(t, []string{"id", "created_at", "note", "status", "price", "tags", "attrs", "parent"}, keys(props))
+	assert.Equal(t, "uuid", dig(t, props, "id", "format"))
+	assert.Equal(t, "date-time", dig(t, props, "created_at", "format"))
+	assert.Equal(t, []any{"active", "inactive"}, dig(t, props, "status", "enum"))
+	assert.Equal(t, true, dig(t, props, "price", "nullable"))
+	assert.Equal(t, "string", dig(t, props, "tags", "items", "type"))
+	assert.Equal(t, "integer", dig(t, props, "attrs", "additionalProperties", "type"))
+	assert.Equal(t, "#/components/schemas/item", dig(t, props, "parent", "$ref"))
+	// the outer omitempty note shadows the embedded one
+	assert.ElementsMatch(t, []any{"id", "created_at", "status"}, it["required"])
+
+	create := dig(t, spec, "components", "schemas", "createItem").(map[string]any)
+	assert.Equal(t, []any{"name"}, create["required"])
+}
+
+func TestDocument_ErrorMediaType(t *testing.T) {
+	doc := New(Info{Title: "Test", Version: "1"}).ErrorBody(item{}).ErrorMediaType("application/problem+json")
+	doc.Operation(http.MethodPost, "/items").ID("createItem").
+		Returns(http.StatusCreated, "Created", envelope[item]{}).
+		Fails(http.StatusUnprocessableEntity, "Item rejected")
+	spec := render(t, doc)
+
+	responses := dig(t, spec, "paths", "/items", "post", "responses")
+	assert.Equal(t, "Item rejected", dig(t, responses, "422", "description"))
+	assert.Equal(t, "#/components/schemas/item", dig(t, responses, "422", "content", "application/problem+json", "schema", "$ref"))
+	assert.NotNil(t, dig(t, responses, "201", "content", "application/json"))
+}
+
+func TestDocument_DuplicateOperations(t *testing.T) {
+	doc := New(Info{Title: "Test", Version: "1"})
+	doc.Operation(http.MethodGet, "/a").ID("same")
+	doc.Operation(http.MethodGet, "/b").ID("same")
+	_, err := doc.JSON()
+	assert.ErrorContains(t, err, "same")
+
+	doc = New(Info{Title: "Test", Version: "1"})
+	doc.Operation(http.MethodGet, "/items/:id").ID("a")
+	doc.Operation(http.MethodGet, "/items/*id").ID("b")
+	_, err = doc.JSON()
+	assert.Error(t, err)
+}
+
+func keys(m map[string]any) []string {
+	out := make([]string, 0, len(m))
+	for k := range m {
+		out = append(out, k)
+	}
+	return out
+}
@@ -0,0 +1,194 @@
+package openapi
+
+import (
+	"encoding/json"
+	"path"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Schema is the subset of the OpenAPI schema object the reflector produces
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+}
+
+var (
+	timeType      = reflect.TypeOf(time.Time{})
+	uuidType      = reflect.TypeOf(uuid.UUID{})
+	rawJSONType   = reflect.TypeOf(json.RawMessage{})
+	marshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+)
+
+// schemaRegistry turns Go types into schemas, collecting named structs as
+// components so each is described once and referenced elsewhere
+type schemaRegistry struct {
+	components map[string]*Schema
+	names      map[reflect.Type]string
+	taken      map[string]reflect.Type
+	enums      map[reflect.Type][]string
+}
+
+func newSchemaRegistry() *schemaRegistry {
+	return &schemaRegistry{
+		components: map[string]*Schema{},
+		names:      map[reflect.Type]string{},
+		taken:      map[string]reflect.Type{},
+		enums:      map[reflect.Type][]string{},
+	}
+}
+
+// schemaOf describes t as it encodes with encoding/json. In request bodies
+// fields are required only when bound as such; in responses they are unless
+// omitted when empty or nil.
+func (r *schemaRegistry) schemaOf(t reflect.Type, request bool) *Schema {
+	if t == nil {
+		return &Schema{}
+	}
+	if t.Kind() == reflect.Pointer {
+		s := r.schemaOf(t.Elem(), request)
+		if s.Ref != "" {
+			// siblings of $ref are ignored in 3.0, so nullable refs stay plain
+			return s
+		}
+		s.Nullable = true
+		return s
+	}
+	if values, ok := r.enums[t]; ok {
+		return &Schema{Type: "string", Enum: values}
+	}
+
+	switch t {
+	case timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case uuidType:
+		return &Schema{Type: "string", Format: "uuid"}
+	case rawJSONType:
+		return &Schema{}
+	}
+	if t.Implements(marshalerType) || reflect.PointerTo(t).Implements(marshalerType) {
+		// custom encodings such as decimal types are opaque to reflection
+		return &Schema{}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int64, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: r.schemaOf(t.Elem(), request)}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: r.schemaOf(t.Elem(), request)}
+	case reflect.Struct:
+		return r.structSchema(t, request)
+	default:
+		// interfaces and anything else may hold any value
+		return &Schema{}
+	}
+}
+
+// structSchema references the component of a named struct, describing it on
+// first use. Anonymous structs and generic instantiations are inlined.
+func (r *schemaRegistry) structSchema(t reflect.Type, request bool) *Schema {
+	if t.Name() == "" || strings.Contains(t.Name(), "[") {
+		return r.objectSchema(t, request)
+	}
+	if name, ok := r.names[t]; ok {
+		return &Schema{Ref: "#/components/schemas/" + name}
+	}
+
+	name := t.Name()
+	if other, ok := r.taken[name]; ok && other != t {
+		name = exportedName(path.Base(t.PkgPath())) + name
+	}
+	r.names[t] = name
+	r.taken[name] = t
+	// registered before describing the fields so recursive types terminate
+	r.components[name] = &Schema{}
+	*r.components[name] = *r.objectSchema(t, request)
+	return &Schema{Ref: "#/components/schemas/" + name}
+}
+
+// objectSchema describes the exported fields of a struct, flattening
+// embedded structs the way encoding/json does
+func (r *schemaRegistry) objectSchema(t reflect.Type, request bool) *Schema {
+	s := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	r.addFields(s, t, request)
+	return s
+}
+
+func (r *schemaRegistry) addFields(s *Schema, t reflect.Type, request bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				r.addFields(s, embedded, request)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		// fields of an outer struct shadow embedded ones, as in encoding/json
+		s.Required = slices.DeleteFunc(s.Required, func(n string) bool { return n == name })
+		s.Properties[name] = r.schemaOf(field.Type, request)
+		if required(field, opts, request) {
+			s.Required = append(s.Required, name)
+		}
+	}
+}
+
+// required reports whether a field is always present
+func required(field reflect.StructField, jsonOpts string, request bool) bool {
+	if request {
+		return slices.Contains(strings.Split(field.Tag.Get("binding"), ","), "required")
+	}
+	if slices.Contains(strings.Split(jsonOpts, ","), "omitempty") {
+		return false
+	}
+	return field.Type.Kind() != reflect.Pointer
+}
+
+func exportedName(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
@@ -0,0 +1,277 @@
+package response
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Code identifies the kind of failure independently of the detail, which is
+// meant for people and may change. Every code is listed in the catalog.
+type Code string
+
+// Generic codes, for failures no more specific code describes
+const (
+	CodeBadRequest       Code = "BAD_REQUEST"
+	CodeValidationFailed Code = "VALIDATION_FAILED"
+	CodeUnauthorized     Code = "UNAUTHORIZED"
+	CodeForbidden        Code = "FORBIDDEN"
+	CodeNotFound         Code = "NOT_FOUND"
+	CodeConflict         Code = "CONFLICT"
+	CodeVersionConflict  Code = "VERSION_CONFLICT"
+	CodePayloadTooLarge  Code = "PAYLOAD_TOO_LARGE"
+	CodeUnprocessable    Code = "UNPROCESSABLE"
+	CodeRateLimited      Code = "RATE_LIMITED"
+	CodeInternal         Code = "INTERNAL_ERROR"
+	CodeBadGateway       Code = "BAD_GATEWAY"
+	CodeUnavailable      Code = "SERVICE_UNAVAILABLE"
+	CodeQueryTimeout     Code = "QUERY_TIMEOUT"
+
+	CodeIdempotencyKeyInvalid    Code = "IDEMPOTENCY_KEY_INVALID"
+	CodeIdempotencyKeyInProgress Code = "IDEMPOTENCY_KEY_IN_PROGRESS"
+	CodeIdempotencyKeyReused     Code = "IDEMPOTENCY_KEY_REUSED"
+)
+
+// Customer codes
+const (
+	CodeCustomerNotFound         Code = "CUSTOMER_NOT_FOUND"
+	CodeEmailAlreadyExists       Code = "EMAIL_ALREADY_EXISTS"
+	CodeEmailUnchanged           Code = "EMAIL_UNCHANGED"
+	CodeEmailChangeRequired      Code = "EMAIL_CHANGE_REQUIRED"
+	CodeEmailChangeTokenInvalid  Code = "EMAIL_CHANGE_TOKEN_INVALID"
+	CodeAvatarTooLarge           Code = "AVATAR_TOO_LARGE"
+	CodeAvatarInvalid            Code = "AVATAR_INVALID"
+	CodeCustomerAlreadyMerged    Code = "CUSTOMER_ALREADY_MERGED"
+	CodeDuplicateNotFound        Code = "DUPLICATE_CANDIDATE_NOT_FOUND"
+	CodeDuplicateReviewed        Code = "DUPLICATE_CANDIDATE_REVIEWED"
+	CodeBlocklistEntryNotFound   Code = "BLOCKLIST_ENTRY_NOT_FOUND"
+	CodeInvalidBlocklistEntry    Code = "INVALID_BLOCKLIST_ENTRY"
+	CodeWebhookNotFound          Code = "WEBHOOK_NOT_FOUND"
+	CodeWebhookDeliveryNotFound  Code = "WEBHOOK_DELIVERY_NOT_FOUND"
+	CodeMarketingSyncDisabled    Code = "MARKETING_SYNC_DISABLED"
+	CodeLimitOverrideNotFound    Code = "LIMIT_OVERRIDE_NOT_FOUND"
+	CodeCustomerTagNotFound      Code = "CUSTOMER_TAG_NOT_FOUND"
+	CodeCustomerTagLimitReached  Code = "CUSTOMER_TAG_LIMIT_REACHED"
+	CodeInvalidTagName           Code = "INVALID_TAG_NAME"
+	CodeNoteNotFound             Code = "NOTE_NOT_FOUND"
+	CodeNoteAttachmentNotFound   Code = "NOTE_ATTACHMENT_NOT_FOUND"
+	CodeNoteAttachmentTooLarge   Code = "NOTE_ATTACHMENT_TOO_LARGE"
+	CodeNoteAttachmentType       Code = "NOTE_ATTACHMENT_TYPE_NOT_ALLOWED"
+	CodeNoteAttachmentLimit      Code = "NOTE_ATTACHMENT_LIMIT_REACHED"
+	CodeActivityNotFound         Code = "ACTIVITY_NOT_FOUND"
+	CodePinnedActivityLimit      Code = "PINNED_ACTIVITY_LIMIT_REACHED"
+	CodeCustomerViewNotFound     Code = "CUSTOMER_VIEW_NOT_FOUND"
+	CodeCustomerViewNameTaken    Code = "CUSTOMER_VIEW_NAME_TAKEN"
+	CodeCustomerViewLimitReached Code = "CUSTOMER_VIEW_LIMIT_REACHED"
+	CodeInvalidCustomerColumns   Code = "INVALID_CUSTOMER_COLUMNS"
+	CodeSegmentRuleNotFound      Code = "SEGMENT_RULE_NOT_FOUND"
+	CodeSegmentNotFound          Code = "SEGMENT_NOT_FOUND"
+	CodeBulkSegmentInvalid       Code = "BULK_SEGMENT_INVALID"
+	CodeBulkSegmentJobNotFound   Code = "BULK_SEGMENT_JOB_NOT_FOUND"
+	CodeImpersonationNotFound    Code = "IMPERSONATION_NOT_FOUND"
+	CodeImpersonationInactive    Code = "IMPERSONATION_INACTIVE"
+	CodeInvalidCursor            Code = "INVALID_CURSOR"
+	CodeInvalidSort              Code = "INVALID_SORT"
+	CodeUnsupportedDataExport    Code = "UNSUPPORTED_DATA_EXPORT"
+	CodeScheduledExportNotFound  Code = "SCHEDULED_EXPORT_NOT_FOUND"
+	CodeScheduledExportInvalid   Code = "SCHEDULED_EXPORT_INVALID"
+	CodeCustomerExportNotFound   Code = "CUSTOMER_EXPORT_NOT_FOUND"
+)
+
+// Address and order codes
+const (
+	CodeAddressNotFound         Code = "ADDRESS_NOT_FOUND"
+	CodeAddressLimitReached     Code = "ADDRESS_LIMIT_REACHED"
+	CodeAddressInvalid          Code = "ADDRESS_INVALID"
+	CodeAddressRejected         Code = "ADDRESS_REJECTED"
+	CodeAddressRestoreExpired   Code = "ADDRESS_RESTORE_EXPIRED"
+	CodeOrderNotFound           Code = "ORDER_NOT_FOUND"
+	CodeOrderAccessDenied       Code = "ORDER_ACCESS_DENIED"
+	CodeOrderNoShippingAddress  Code = "ORDER_NO_SHIPPING_ADDRESS"
+	CodeMeasurementNotFound     Code = "MEASUREMENT_NOT_FOUND"
+	CodeMeasurementProfileLimit Code = "MEASUREMENT_PROFILE_LIMIT_REACHED"
+	CodeMeasurementLimit        Code = "MEASUREMENT_LIMIT_REACHED"
+)
+
+// Wishlist, back-in-stock, saved search and wallet codes
+const (
+	CodeWishlistItemNotFound       Code = "WISHLIST_ITEM_NOT_FOUND"
+	CodeWishlistItemExists         Code = "WISHLIST_ITEM_EXISTS"
+	CodeWishlistFull               Code = "WISHLIST_FULL"
+	CodeWishlistImportInvalid      Code = "WISHLIST_IMPORT_INVALID"
+	CodeSubscriptionNotFound       Code = "SUBSCRIPTION_NOT_FOUND"
+	CodeGuestTokenInvalid          Code = "GUEST_TOKEN_INVALID"
+	CodeGuestSubscriptionLimit     Code = "GUEST_SUBSCRIPTION_LIMIT_REACHED"
+	CodeSavedSearchNotFound        Code = "SAVED_SEARCH_NOT_FOUND"
+	CodeSavedSearchLimitReached    Code = "SAVED_SEARCH_LIMIT_REACHED"
+	CodeInvalidWalletAmount        Code = "INVALID_WALLET_AMOUNT"
+	CodeInsufficientStoreCredit    Code = "INSUFFICIENT_STORE_CREDIT"
+	CodeWalletReservationNotFound  Code = "WALLET_RESERVATION_NOT_FOUND"
+	CodeWalletReservationNotActive Code = "WALLET_RESERVATION_NOT_PENDING"
+)
+
+// Entry documents a code: the status it is returned with and the type and
+// title of its problems
+type Entry struct {
+	Code   Code   `json:"code"`
+	Status int    `json:"status"`
+	Title  string `json:"title"`
+	Type   string `json:"type"`
+}
+
+// TypeBase prefixes the type URI of every problem; GET on the URI describes
+// the code
+const TypeBase = "/api/v1/problems/"
+
+var catalog = []Entry{
+	{Code: CodeBadRequest, Status: http.StatusBadRequest, Title: "Bad request"},
+	{Code: CodeValidationFailed, Status: http.StatusBadRequest, Title: "Validation failed"},
+	{Code: CodeUnauthorized, Status: http.StatusUnauthorized, Title: "Unauthorized"},
+	{Code: CodeForbidden, Status: http.StatusForbidden, Title: "Forbidden"},
+	{Code: CodeNotFound, Status: http.StatusNotFound, Title: "Not found"},
+	{Code: CodeConflict, Status: http.StatusConflict, Title: "Conflict"},
+	{Code: CodeVersionConflict, Status: http.StatusConflict, Title: "Modified by another request"},
+	{Code: CodePayloadTooLarge, Status: http.StatusRequestEntityTooLarge, Title: "Payload too large"},
+	{Code: CodeUnprocessable, Status: http.StatusUnprocessableEntity, Title: "Unprocessable request"},
+	{Code: CodeRateLimited, Status: http.StatusTooManyRequests, Title: "Too many requests"},
+	{Code: CodeInternal, Status: http.StatusInternalServerError, Title: "Internal server error"},
+	{Code: CodeBadGateway, Status: http.StatusBadGateway, Title: "Upstream service failed"},
+	{Code: CodeUnavailable, Status: http.StatusServiceUnavailable, Title: "Service unavailable"},
+	{Code: CodeQueryTimeout, Status: http.StatusServiceUnavailable, Title: "Query took too long; narrow the filters or retry"},
+	{Code: CodeIdempotencyKeyInvalid, Status: http.StatusBadRequest, Title: "Invalid idempotency key"},
+	{Code: CodeIdempotencyKeyInProgress, Status: http.StatusConflict, Title: "Request with this idempotency key in progress"},
+	{Code: CodeIdempotencyKeyReused, Status: http.StatusUnprocessableEntity, Title: "Idempotency key reused for a different request"},
+
+	{Code: CodeCustomerNotFound, Status: http.StatusNotFound, Title: "Customer not found"},
+	{Code: CodeEmailAlreadyExists, Status: http.StatusConflict, Title: "Email already registered"},
+	{Code: CodeEmailUnchanged, Status: http.StatusBadRequest, Title: "Email unchanged"},
+	{Code: CodeEmailChangeRequired, Status: http.StatusBadRequest, Title: "Email change requires verification"},
+	{Code: CodeEmailChangeTokenInvalid, Status: http.StatusBadRequest, Title: "Invalid or expired verification link"},
+	{Code: CodeAvatarTooLarge, Status: http.StatusRequestEntityTooLarge, Title: "Avatar too large"},
+	{Code: CodeAvatarInvalid, Status: http.StatusUnsupportedMediaType, Title: "Unsupported avatar image"},
+	{Code: CodeCustomerAlreadyMerged, Status: http.StatusConflict, Title: "Customer already merged"},
+	{Code: CodeDuplicateNotFound, Status: http.StatusNotFound, Title: "Duplicate candidate not found"},
+	{Code: CodeDuplicateReviewed, Status: http.StatusConflict, Title: "Duplicate candidate already reviewed"},
+	{Code: CodeBlocklistEntryNotFound, Status: http.StatusNotFound, Title: "Blocklist entry not found"},
+	{Code: CodeInvalidBlocklistEntry, Status: http.StatusBadRequest, Title: "Invalid blocklist entry"},
+	{Code: CodeWebhookNotFound, Status: http.StatusNotFound, Title: "Webhook not found"},
+	{Code: CodeWebhookDeliveryNotFound, Status: http.StatusNotFound, Title: "Webhook delivery not found"},
+	{Code: CodeMarketingSyncDisabled, Status: http.StatusNotFound, Title: "Marketing sync is not configured for this platform"},
+	{Code: CodeLimitOverrideNotFound, Status: http.StatusNotFound, Title: "Customer has no limit override"},
+	{Code: CodeCustomerTagNotFound, Status: http.StatusNotFound, Title: "Customer does not have this tag"},
+	{Code: CodeCustomerTagLimitReached, Status: http.StatusConflict, Title: "Customer tag limit reached"},
+	{Code: CodeInvalidTagName, Status: http.StatusBadRequest, Title: "Invalid tag name"},
+	{Code: CodeNoteNotFound, Status: http.StatusNotFound, Title: "Note not found"},
+	{Code: CodeNoteAttachmentNotFound, Status: http.StatusNotFound, Title: "Attachment not found"},
+	{Code: CodeNoteAttachmentTooLarge, Status: http.StatusRequestEntityTooLarge, Title: "Attachment too large"},
+	{Code: CodeNoteAttachmentType, Status: http.StatusUnsupportedMediaType, Title: "Attachment type not allowed"},
+	{Code: CodeNoteAttachmentLimit, Status: http.StatusConflict, Title: "Attachment limit reached"},
+	{Code: CodeActivityNotFound, Status: http.StatusNotFound, Title: "Activity not found"},
+	{Code: CodePinnedActivityLimit, Status: http.StatusConflict, Title: "Pinned activity limit reached"},
+	{Code: CodeCustomerViewNotFound, Status: http.StatusNotFound, Title: "View not found"},
+	{Code: CodeCustomerViewNameTaken, Status: http.StatusConflict, Title: "View name already taken"},
+	{Code: CodeCustomerViewLimitReached, Status: http.StatusConflict, Title: "View limit reached"},
+	{Code: CodeInvalidCustomerColumns, Status: http.StatusBadRequest, Title: "Invalid export columns"},
+	{Code: CodeSegmentRuleNotFound, Status: http.StatusNotFound, Title: "Segment rule not found"},
+	{Code: CodeSegmentNotFound, Status: http.StatusNotFound, Title: "Segment not found"},
+	{Code: CodeBulkSegmentInvalid, Status: http.StatusBadRequest, Title: "Invalid bulk segment change"},
+	{Code: CodeBulkSegmentJobNotFound, Status: http.StatusNotFound, Title: "Bulk segment job not found"},
+	{Code: CodeImpersonationNotFound, Status: http.StatusNotFound, Title: "Impersonation session not found"},
+	{Code: CodeImpersonationInactive, Status: http.StatusConflict, Title: "Impersonation session expired or revoked"},
+	{Code: CodeInvalidCursor, Status: http.StatusBadRequest, Title: "Invalid cursor"},
+	{Code: CodeInvalidSort, Status: http.StatusBadRequest, Title: "Invalid sort"},
+	{Code: CodeUnsupportedDataExport, Status: http.StatusBadRequest, Title: "Unsupported data export"},
+	{Code: CodeScheduledExportNotFound, Status: http.StatusNotFound, Title: "Scheduled export not found"},
+	{Code: CodeScheduledExportInvalid, Status: http.StatusBadRequest, Title: "Invalid scheduled export"},
+	{Code: CodeCustomerExportNotFound, Status: http.StatusNotFound, Title: "Customer export not found"},
+
+	{Code: CodeAddressNotFound, Status: http.StatusNotFound, Title: "Address not found"},
+	{Code: CodeAddressLimitReached, Status: http.StatusUnprocessableEntity, Title: "Address limit reached"},
+	{Code: CodeAddressInvalid, Status: http.StatusUnprocessableEntity, Title: "Invalid address"},
+	{Code: CodeAddressRejected, Status: http.StatusUnprocessableEntity, Title: "Address could not be validated"},
+	{Code: CodeAddressRestoreExpired, Status: http.StatusGone, Title: "Address can no longer be restored"},
+	{Code: CodeOrderNotFound, Status: http.StatusNotFound, Title: "Order not found"},
+	{Code: CodeOrderAccessDenied, Status: http.StatusForbidden, Title: "Order access denied"},
+	{Code: CodeOrderNoShippingAddress, Status: http.StatusUnprocessableEntity, Title: "Order has no shipping address"},
+	{Code: CodeMeasurementNotFound, Status: http.StatusNotFound, Title: "Measurement not found"},
+	{Code: CodeMeasurementProfileLimit, Status: http.StatusUnprocessableEntity, Title: "Measurement profile limit reached"},
+	{Code: CodeMeasurementLimit, Status: http.StatusUnprocessableEntity, Title: "Measurement limit reached"},
+
+	{Code: CodeWishlistItemNotFound, Status: http.StatusNotFound, Title: "Item not in wishlist"},
+	{Code: CodeWishlistItemExists, Status: http.StatusConflict, Title: "Item already in wishlist"},
+	{Code: CodeWishlistFull, Status: http.StatusUnprocessableEntity, Title: "Wishlist is full"},
+	{Code: CodeWishlistImportInvalid, Status: http.StatusBadRequest, Title: "Invalid wishlist import"},
+	{Code: CodeSubscriptionNotFound, Status: http.StatusNotFound, Title: "Subscription not found"},
+	{Code: CodeGuestTokenInvalid, Status: http.StatusBadRequest, Title: "Invalid or expired confirmation link"},
+	{Code: CodeGuestSubscriptionLimit, Status: http.StatusTooManyRequests, Title: "Too many back-in-stock subscriptions"},
+	{Code: CodeSavedSearchNotFound, Status: http.StatusNotFound, Title: "Saved search not found"},
+	{Code: CodeSavedSearchLimitReached, Status: http.StatusUnprocessableEntity, Title: "Saved search limit reached"},
+	{Code: CodeInvalidWalletAmount, Status: http.StatusBadRequest, Title: "Invalid amount"},
+	{Code: CodeInsufficientStoreCredit, Status: http.StatusConflict, Title: "Insufficient store credit"},
+	{Code: CodeWalletReservationNotFound, Status: http.StatusNotFound, Title: "Wallet reservation not found"},
+	{Code: CodeWalletReservationNotActive, Status: http.StatusConflict, Title: "Wallet reservation no longer pending"},
+}
+
+var entries = make(map[Code]Entry, len(catalog))
+
+func init() {
+	for i := range catalog {
+		catalog[i].Type = TypeBase + Slug(catalog[i].Code)
+		entries[catalog[i].Code] = catalog[i]
+	}
+}
+
+// Catalog lists every code
+func Catalog() []Entry {
+	return append([]Entry(nil), catalog...)
+}
+
+// Lookup returns the entry of a code. Codes missing from the catalog are
+// reported as internal errors.
+func Lookup(code Code) Entry {
+	if entry, ok := entries[code]; ok {
+		return entry
+	}
+	return entries[CodeInternal]
+}
+
+// LookupSlug returns the entry whose type URI ends in slug
+func LookupSlug(slug string) (Entry, bool) {
+	entry, ok := entries[Code(strings.ToUpper(strings.ReplaceAll(slug, "-", "_")))]
+	return entry, ok
+}
+
+// Slug is the last segment of a code's type URI, e.g. customer-not-found
+func Slug(code Code) string {
+	return strings.ToLower(strings.ReplaceAll(string(code), "_", "-"))
+}
+
+// CodeFor returns the generic code of a status, for failures raised outside
+// handlers such as by middleware
+func CodeFor(status int) Code {
+	switch status {
+	case http.StatusBadRequest:
+		return CodeBadRequest
+	case http.StatusUnauthorized:
+		return CodeUnauthorized
+	case http.StatusForbidden:
+		return CodeForbidden
+	case http.StatusNotFound:
+		return CodeNotFound
+	case http.StatusConflict:
+		return CodeConflict
+	case http.StatusRequestEntityTooLarge:
+		return CodePayloadTooLarge
+	case http.StatusUnprocessableEntity:
+		return CodeUnprocessable
+	case http.StatusTooManyRequests:
+		return CodeRateLimited
+	case http.StatusBadGateway:
+		return CodeBadGateway
+	case http.StatusServiceUnavailable:
+		return CodeUnavailable
+	}
+	if status >= http.StatusInternalServerError {
+		return CodeInternal
+	}
+	return CodeBadRequest
+}
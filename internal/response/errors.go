@@ -0,0 +1,129 @@
+package response
+
+import "github.com/gin-gonic/gin"
+
+// ContentType is the media type of failed requests (RFC 7807)
+const ContentType = "application/problem+json"
+
+// Problem is the body of failed requests: an RFC 7807 problem details object
+// extended with the catalog code and the request fields that failed
+// validation
+type Problem struct {
+	Type     string       `json:"type"`
+	Title    string       `json:"title"`
+	Status   int          `json:"status"`
+	Detail   string       `json:"detail,omitempty"`
+	Instance string       `json:"instance,omitempty"`
+	Code     Code         `json:"code"`
+	Errors   []FieldError `json:"errors,omitempty"`
+	Details  any          `json:"details,omitempty"`
+}
+
+// FieldError is a request field that failed validation. Field is the JSON
+// path of the field (e.g. "items[0].sku") and rule the check it failed.
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule,omitempty"`
+	Message string `json:"message"`
+}
+
+// NewProblem describes a failure with the type, title and status the catalog
+// documents for code
+func NewProblem(code Code, detail string) Problem {
+	entry := Lookup(code)
+	return Problem{Type: entry.Type, Title: entry.Title, Status: entry.Status, Detail: detail, Code: code}
+}
+
+// Write writes a problem, identifying the request path as its instance
+func Write(c *gin.Context, problem Problem) {
+	if problem.Instance == "" && c.Request != nil {
+		problem.Instance = c.Request.URL.Path
+	}
+	c.Header("Content-Type", ContentType)
+	c.JSON(problem.Status, problem)
+}
+
+// Fail writes the problem of a catalog code
+func Fail(c *gin.Context, code Code, detail string) {
+	Write(c, NewProblem(code, detail))
+}
+
+// FailWith writes the problem of a catalog code with extra context, such as
+// allowed values, in details
+func FailWith(c *gin.Context, code Code, detail string, details any) {
+	problem := NewProblem(code, detail)
+	problem.Details = details
+	Write(c, problem)
+}
+
+// Fields writes the problem of a catalog code caused by individual request
+// fields
+func Fields(c *gin.Context, code Code, detail string, fields []FieldError) {
+	problem := NewProblem(code, detail)
+	problem.Errors = fields
+	Write(c, problem)
+}
+
+// Abort writes the generic problem of a status and stops the remaining
+// handlers, for middleware
+func Abort(c *gin.Context, status int, detail string) {
+	problem := NewProblem(CodeFor(status), detail)
+	problem.Status = status
+	Write(c, problem)
+	c.Abort()
+}
+
+// BadRequest writes a 400; details is omitted when nil
+func BadRequest(c *gin.Context, detail string, details any) {
+	FailWith(c, CodeBadRequest, detail, details)
+}
+
+// Unauthorized writes a 401
+func Unauthorized(c *gin.Context, detail string) {
+	Fail(c, CodeUnauthorized, detail)
+}
+
+// Forbidden writes a 403
+func Forbidden(c *gin.Context, detail string) {
+	Fail(c, CodeForbidden, detail)
+}
+
+// NotFound writes a 404
+func NotFound(c *gin.Context, detail string) {
+	Fail(c, CodeNotFound, detail)
+}
+
+// Conflict writes a 409
+func Conflict(c *gin.Context, detail string) {
+	Fail(c, CodeConflict, detail)
+}
+
+// PayloadTooLarge writes a 413
+func PayloadTooLarge(c *gin.Context, detail string) {
+	Fail(c, CodePayloadTooLarge, detail)
+}
+
+// Unprocessable writes a 422; details is omitted when nil
+func Unprocessable(c *gin.Context, detail string, details any) {
+	FailWith(c, CodeUnprocessable, detail, details)
+}
+
+// TooManyRequests writes a 429
+func TooManyRequests(c *gin.Context, detail string) {
+	Fail(c, CodeRateLimited, detail)
+}
+
+// InternalServerError writes a 500
+func InternalServerError(c *gin.Context, detail string) {
+	Fail(c, CodeInternal, detail)
+}
+
+// BadGateway writes a 502
+func BadGateway(c *gin.Context, detail string) {
+	Fail(c, CodeBadGateway, detail)
+}
+
+// ServiceUnavailable writes a 503
+func ServiceUnavailable(c *gin.Context, detail string) {
+	Fail(c, CodeUnavailable, detail)
+}
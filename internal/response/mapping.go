@@ -0,0 +1,109 @@
+package response
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/Ecom-micro-template/service-customer/internal/domain/address"
+	"github.com/Ecom-micro-template/service-customer/internal/domain/customer"
+	"github.com/Ecom-micro-template/service-customer/internal/domain/measurement"
+	"github.com/Ecom-micro-template/service-customer/internal/domain/wishlist"
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/orderclient"
+	"gorm.io/gorm"
+)
+
+// domainCodes maps the sentinel errors of the domain and clients to the code
+// they are reported with
+var domainCodes = []struct {
+	err  error
+	code Code
+}{
+	{customer.ErrCustomerNotFound, CodeCustomerNotFound},
+	{customer.ErrEmailAlreadyExists, CodeEmailAlreadyExists},
+	{domain.ErrEmailInUse, CodeEmailAlreadyExists},
+	{domain.ErrEmailUnchanged, CodeEmailUnchanged},
+	{domain.ErrEmailChangeRequired, CodeEmailChangeRequired},
+	{domain.ErrEmailChangeTokenInvalid, CodeEmailChangeTokenInvalid},
+	{domain.ErrAvatarTooLarge, CodeAvatarTooLarge},
+	{domain.ErrAvatarInvalid, CodeAvatarInvalid},
+	{domain.ErrDuplicateCandidateReviewed, CodeDuplicateReviewed},
+	{domain.ErrInvalidBlocklistEmail, CodeInvalidBlocklistEntry},
+	{domain.ErrInvalidBlocklistPhone, CodeInvalidBlocklistEntry},
+	{domain.ErrBlocklistAddressMissing, CodeInvalidBlocklistEntry},
+	{domain.ErrBlocklistExpiryPast, CodeInvalidBlocklistEntry},
+	{domain.ErrCustomerTagLimit, CodeCustomerTagLimitReached},
+	{domain.ErrInvalidTagName, CodeInvalidTagName},
+	{domain.ErrNoteAttachmentTooLarge, CodeNoteAttachmentTooLarge},
+	{domain.ErrNoteAttachmentType, CodeNoteAttachmentType},
+	{domain.ErrNoteAttachmentLimit, CodeNoteAttachmentLimit},
+	{domain.ErrPinnedActivityLimit, CodePinnedActivityLimit},
+	{domain.ErrCustomerViewNameTaken, CodeCustomerViewNameTaken},
+	{domain.ErrCustomerViewLimit, CodeCustomerViewLimitReached},
+	{domain.ErrNoCustomerColumns, CodeInvalidCustomerColumns},
+	{domain.ErrUnknownCustomerColumn, CodeInvalidCustomerColumns},
+	{domain.ErrBulkSegmentEmpty, CodeBulkSegmentInvalid},
+	{domain.ErrBulkSegmentTooLarge, CodeBulkSegmentInvalid},
+	{domain.ErrImpersonationInactive, CodeImpersonationInactive},
+	{domain.ErrInvalidCursor, CodeInvalidCursor},
+	{domain.ErrInvalidSort, CodeInvalidSort},
+	{domain.ErrUnsupportedDataExport, CodeUnsupportedDataExport},
+	{domain.ErrInvalidExportSchedule, CodeScheduledExportInvalid},
+	{domain.ErrInvalidExportTarget, CodeScheduledExportInvalid},
+	{domain.ErrVersionConflict, CodeVersionConflict},
+
+	{address.ErrAddressNotFound, CodeAddressNotFound},
+	{address.ErrMaxAddresses, CodeAddressLimitReached},
+	{domain.ErrAddressRestoreExpired, CodeAddressRestoreExpired},
+	{orderclient.ErrOrderNotFound, CodeOrderNotFound},
+	{orderclient.ErrOrderAccessDenied, CodeOrderAccessDenied},
+	{measurement.ErrMeasurementNotFound, CodeMeasurementNotFound},
+	{domain.ErrMeasurementProfileLimit, CodeMeasurementProfileLimit},
+	{domain.ErrMeasurementLimit, CodeMeasurementLimit},
+
+	{wishlist.ErrItemNotFound, CodeWishlistItemNotFound},
+	{wishlist.ErrItemAlreadyExists, CodeWishlistItemExists},
+	{domain.ErrWishlistFull, CodeWishlistFull},
+	{domain.ErrWishlistImportEmpty, CodeWishlistImportInvalid},
+	{domain.ErrWishlistImportTooLarge, CodeWishlistImportInvalid},
+	{domain.ErrGuestTokenInvalid, CodeGuestTokenInvalid},
+	{domain.ErrGuestSubscriptionLimit, CodeGuestSubscriptionLimit},
+	{domain.ErrSavedSearchLimit, CodeSavedSearchLimitReached},
+	{domain.ErrInvalidWalletAmount, CodeInvalidWalletAmount},
+	{domain.ErrInsufficientStoreCredit, CodeInsufficientStoreCredit},
+	{domain.ErrReservationNotFound, CodeWalletReservationNotFound},
+	{domain.ErrReservationNotPending, CodeWalletReservationNotActive},
+}
+
+// CodeOf returns the code of a domain or client error
+func CodeOf(err error) (Code, bool) {
+	for _, mapping := range domainCodes {
+		if errors.Is(err, mapping.err) {
+			return mapping.code, true
+		}
+	}
+	return "", false
+}
+
+// FromError writes the problem of a repository or domain error: its catalog
+// code with the error as detail, notFound when a record is missing, a 503
+// when the query ran past its timeout, or a 500 with fallback as detail so
+// unexpected errors aren't leaked. notFound may be empty when the operation
+// can't miss a record.
+func FromError(c *gin.Context, err error, notFound Code, fallback string) {
+	if code, ok := CodeOf(err); ok {
+		Fail(c, code, err.Error())
+		return
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		Fail(c, CodeQueryTimeout, Lookup(CodeQueryTimeout).Title)
+		return
+	}
+	if notFound != "" && errors.Is(err, gorm.ErrRecordNotFound) {
+		Fail(c, notFound, Lookup(notFound).Title)
+		return
+	}
+	InternalServerError(c, fallback)
+}
@@ -0,0 +1,123 @@
+// Package response writes the bodies every endpoint answers with.
+// Successful requests render
+//
+//	{"success": true, "message": "...", "data": ..., "meta": {...}}
+//
+// where meta only accompanies paginated listings, and failed ones render an
+// RFC 7807 problem as application/problem+json
+//
+//	{"type": "/api/v1/problems/address-not-found", "title": "Address not found", "status": 404, "code": "ADDRESS_NOT_FOUND", ...}
+//
+// where code is one of the catalog and stable for clients to branch on, and
+// errors lists the request fields that failed validation.
+package response
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Data is the body of successful requests that return a payload
+type Data[T any] struct {
+	Success bool   `json:"success"`
+	Message string `json:"message,omitempty"`
+	Data    T      `json:"data"`
+}
+
+// Message is the body of successful requests that only report their outcome
+type Message struct {
+	Success bool   `json:"success"`
+	Message string `json:"message"`
+}
+
+// Page is the body of page-based listings
+type Page[T any] struct {
+	Success bool       `json:"success"`
+	Message string     `json:"message,omitempty"`
+	Data    T          `json:"data"`
+	Meta    Pagination `json:"meta"`
+}
+
+// Pagination describes a page of a page-based listing
+type Pagination struct {
+	Page       int   `json:"page"`
+	Limit      int   `json:"limit"`
+	Total      int64 `json:"total"`
+	TotalPages int   `json:"total_pages"`
+}
+
+// NewPagination describes the page of a listing with total items
+func NewPagination(page, limit int, total int64) Pagination {
+	totalPages := 0
+	if limit > 0 {
+		totalPages = int((total + int64(limit) - 1) / int64(limit))
+	}
+	return Pagination{Page: page, Limit: limit, Total: total, TotalPages: totalPages}
+}
+
+// CursorPage is the body of cursor-paginated listings
+type CursorPage[T any] struct {
+	Success bool             `json:"success"`
+	Message string           `json:"message,omitempty"`
+	Data    T                `json:"data"`
+	Meta    CursorPagination `json:"meta"`
+}
+
+// CursorPagination describes a page of a cursor-paginated listing;
+// next_cursor is omitted on the last page
+type CursorPagination struct {
+	Limit      int    `json:"limit"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// OK writes a 200 with data
+func OK(c *gin.Context, message string, data any) {
+	c.JSON(http.StatusOK, Data[any]{Success: true, Message: message, Data: data})
+}
+
+// Created writes a 201 with the created resource
+func Created(c *gin.Context, message string, data any) {
+	c.JSON(http.StatusCreated, Data[any]{Success: true, Message: message, Data: data})
+}
+
+// Updated writes a 200 with the updated resource
+func Updated(c *gin.Context, message string, data any) {
+	OK(c, message, data)
+}
+
+// Deleted writes a 200 without data
+func Deleted(c *gin.Context, message string) {
+	Done(c, http.StatusOK, message)
+}
+
+// Accepted writes a 202 for work that completes later
+func Accepted(c *gin.Context, message string) {
+	Done(c, http.StatusAccepted, message)
+}
+
+// Queued writes a 202 with the resource tracking work that completes later
+func Queued(c *gin.Context, message string, data any) {
+	c.JSON(http.StatusAccepted, Data[any]{Success: true, Message: message, Data: data})
+}
+
+// Done writes a successful status without data
+func Done(c *gin.Context, status int, message string) {
+	c.JSON(status, Message{Success: true, Message: message})
+}
+
+// Paginated writes a page of a page-based listing
+func Paginated(c *gin.Context, data any, page, limit int, total int64) {
+	c.JSON(http.StatusOK, Page[any]{Success: true, Data: data, Meta: NewPagination(page, limit, total)})
+}
+
+// CursorPaginated writes a page of a cursor-paginated listing. nextCursor is
+// empty on the last page.
+func CursorPaginated(c *gin.Context, message string, data any, limit int, nextCursor string) {
+	c.JSON(http.StatusOK, CursorPage[any]{
+		Success: true,
+		Message: message,
+		Data:    data,
+		Meta:    CursorPagination{Limit: limit, NextCursor: nextCursor},
+	})
+}
@@ -0,0 +1,161 @@
+package response
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Ecom-micro-template/service-customer/internal/domain"
+	"github.com/Ecom-micro-template/service-customer/internal/infrastructure/orderclient"
+	"gorm.io/gorm"
+)
+
+type createItem struct {
+	Name  string   `json:"name" binding:"required,max=5"`
+	Email string   `json:"email" binding:"omitempty,email"`
+	Tags  []string `json:"tags" binding:"omitempty,dive,oneof=a b"`
+	Count int      `json:"count"`
+}
+
+func bind(t *testing.T, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	rec := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(rec)
+	c.Request = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+
+	var req createItem
+	if err := c.ShouldBindJSON(&req); err != nil {
+		Invalid(c, err)
+	} else {
+		Created(c, "Created", req)
+	}
+	return rec
+}
+
+func decode(t *testing.T, rec *httptest.ResponseRecorder) Problem {
+	t.Helper()
+	assert.Equal(t, ContentType, rec.Header().Get("Content-Type"))
+	var body Problem
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	return body
+}
+
+func TestInvalid_FieldErrors(t *testing.T) {
+	rec := bind(t, `{"name":"too long","email":"nope","tags":["a","c"]}`)
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+
+	body := decode(t, rec)
+	assert.Equal(t, http.StatusBadRequest, body.Status)
+	assert.Equal(t, "/api/v1/problems/validation-failed", body.Type)
+	assert.Equal(t, "/", body.Instance)
+	assert.Equal(t, CodeValidationFailed, body.Code)
+	assert.Equal(t, []FieldError{
+		{Field: "name", Rule: "max", Message: "must have at most 5 characters"},
+		{Field: "email", Rule: "email", Message: "must be a valid email address"},
+		{Field: "tags[1]", Rule: "oneof", Message: "must be one of: a, b"},
+	}, body.Errors)
+}
+
+func TestInvalid_DecodeErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		code    Code
+		message string
+		fields  []FieldError
+	}{
+		{"empty", ``, CodeBadRequest, "Request body is required", nil},
+		{"malformed", `{"name":`, CodeBadRequest, "Malformed JSON body", nil},
+		{"wrong type", `{"name":"a","count":"x"}`, CodeValidationFailed, "Validation failed",
+			[]FieldError{{Field: "count", Rule: "type", Message: "must be a number"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := bind(t, tt.body)
+			require.Equal(t, http.StatusBadRequest, rec.Code)
+			body := decode(t, rec)
+			assert.Equal(t, tt.code, body.Code)
+			assert.Equal(t, tt.message, body.Detail)
+			assert.Equal(t, tt.fields, body.Errors)
+		})
+	}
+}
+
+func TestCodeFor(t *testing.T) {
+	assert.Equal(t, CodeNotFound, CodeFor(http.StatusNotFound))
+	assert.Equal(t, CodeRateLimited, CodeFor(http.StatusTooManyRequests))
+	assert.Equal(t, CodeBadRequest, CodeFor(http.StatusTeapot))
+	assert.Equal(t, CodeInternal, CodeFor(http.StatusGatewayTimeout))
+}
+
+func TestCatalog(t *testing.T) {
+	seen := map[Code]bool{}
+	for _, entry := range Catalog() {
+		assert.False(t, seen[entry.Code], "duplicate code %s", entry.Code)
+		seen[entry.Code] = true
+		assert.NotEmpty(t, entry.Title, entry.Code)
+		assert.GreaterOrEqual(t, entry.Status, 400, entry.Code)
+
+		found, ok := LookupSlug(Slug(entry.Code))
+		require.True(t, ok, entry.Code)
+		assert.Equal(t, entry, found)
+	}
+	for _, mapping := range domainCodes {
+		assert.True(t, seen[mapping.code], "%s is not in the catalog", mapping.code)
+	}
+	assert.Equal(t, "/api/v1/problems/customer-not-found", Lookup(CodeCustomerNotFound).Type)
+	assert.Equal(t, CodeInternal, Lookup("NO_SUCH_CODE").Code)
+}
+
+func TestFromError(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		status int
+		code   Code
+		detail string
+	}{
+		{"domain error", fmt.Errorf("reserve: %w", domain.ErrInsufficientStoreCredit), http.StatusConflict, CodeInsufficientStoreCredit, "reserve: insufficient store credit"},
+		{"client error", orderclient.ErrOrderNotFound, http.StatusNotFound, CodeOrderNotFound, "order not found"},
+		{"missing record", gorm.ErrRecordNotFound, http.StatusNotFound, CodeAddressNotFound, "Address not found"},
+		{"query timeout", fmt.Errorf("list: %w", context.DeadlineExceeded), http.StatusServiceUnavailable, CodeQueryTimeout, "Query took too long; narrow the filters or retry"},
+		{"unexpected", errors.New("connection refused"), http.StatusInternalServerError, CodeInternal, "Failed to load"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			rec := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(rec)
+			c.Request = httptest.NewRequest(http.MethodGet, "/api/v1/customer/addresses", nil)
+			FromError(c, tt.err, CodeAddressNotFound, "Failed to load")
+
+			require.Equal(t, tt.status, rec.Code)
+			body := decode(t, rec)
+			assert.Equal(t, tt.code, body.Code)
+			assert.Equal(t, tt.status, body.Status)
+			assert.Equal(t, tt.detail, body.Detail)
+			assert.Equal(t, "/api/v1/customer/addresses", body.Instance)
+		})
+	}
+}
+
+func TestPaginated(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	rec := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(rec)
+	Paginated(c, []int{1, 2}, 2, 10, 21)
+
+	assert.JSONEq(t, `{"success":true,"data":[1,2],"meta":{"page":2,"limit":10,"total":21,"total_pages":3}}`, rec.Body.String())
+	assert.Equal(t, 0, NewPagination(1, 0, 5).TotalPages)
+}
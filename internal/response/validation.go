@@ -0,0 +1,149 @@
+package response
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+func init() {
+	// report fields by their JSON name rather than the Go field name
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(func(field reflect.StructField) string {
+			for _, tag := range []string{"json", "form"} {
+				name, _, _ := strings.Cut(field.Tag.Get(tag), ",")
+				if name == "-" {
+					return ""
+				}
+				if name != "" {
+					return name
+				}
+			}
+			return field.Name
+		})
+	}
+}
+
+// Invalid writes the failure of binding a request: a 400 listing the fields
+// that failed validation, or describing why the body could not be decoded
+func Invalid(c *gin.Context, err error) {
+	if fields := FieldErrors(err); len(fields) > 0 {
+		Fields(c, CodeValidationFailed, "Validation failed", fields)
+		return
+	}
+	var syntaxErr *json.SyntaxError
+	switch {
+	case errors.Is(err, io.EOF):
+		BadRequest(c, "Request body is required", nil)
+	case errors.As(err, &syntaxErr), errors.Is(err, io.ErrUnexpectedEOF):
+		BadRequest(c, "Malformed JSON body", nil)
+	default:
+		BadRequest(c, "Invalid request", err.Error())
+	}
+}
+
+// FieldErrors lists the fields behind a binding error, or nil when the error
+// is not about individual fields
+func FieldErrors(err error) []FieldError {
+	var validationErrs validator.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		fields := make([]FieldError, 0, len(validationErrs))
+		for _, fe := range validationErrs {
+			fields = append(fields, FieldError{
+				Field:   fieldPath(fe.Namespace()),
+				Rule:    fe.Tag(),
+				Message: ruleMessage(fe),
+			})
+		}
+		return fields
+	}
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		return []FieldError{{
+			Field:   typeErr.Field,
+			Rule:    "type",
+			Message: fmt.Sprintf("must be %s", article(typeErr.Type.Kind().String())),
+		}}
+	}
+	return nil
+}
+
+// fieldPath drops the struct name the validator prefixes namespaces with
+func fieldPath(namespace string) string {
+	if _, path, ok := strings.Cut(namespace, "."); ok {
+		return path
+	}
+	return namespace
+}
+
+func ruleMessage(fe validator.FieldError) string {
+	param := fe.Param()
+	switch fe.Tag() {
+	case "required", "required_if", "required_with", "required_without":
+		return "is required"
+	case "email":
+		return "must be a valid email address"
+	case "uuid", "uuid4":
+		return "must be a valid UUID"
+	case "url", "http_url":
+		return "must be a valid URL"
+	case "oneof":
+		return "must be one of: " + strings.Join(strings.Fields(param), ", ")
+	case "min", "gte":
+		if isSized(fe.Kind()) {
+			return fmt.Sprintf("must have at least %s %s", param, unit(fe.Kind()))
+		}
+		return "must be at least " + param
+	case "max", "lte":
+		if isSized(fe.Kind()) {
+			return fmt.Sprintf("must have at most %s %s", param, unit(fe.Kind()))
+		}
+		return "must be at most " + param
+	case "gt":
+		return "must be greater than " + param
+	case "lt":
+		return "must be less than " + param
+	case "len":
+		return fmt.Sprintf("must have exactly %s %s", param, unit(fe.Kind()))
+	case "numeric", "number":
+		return "must be numeric"
+	case "dive":
+		return "is invalid"
+	}
+	if param != "" {
+		return fmt.Sprintf("failed the %s=%s rule", fe.Tag(), param)
+	}
+	return fmt.Sprintf("failed the %s rule", fe.Tag())
+}
+
+func isSized(kind reflect.Kind) bool {
+	return kind == reflect.String || kind == reflect.Slice || kind == reflect.Map || kind == reflect.Array
+}
+
+func unit(kind reflect.Kind) string {
+	if kind == reflect.String {
+		return "characters"
+	}
+	return "items"
+}
+
+func article(kind string) string {
+	switch kind {
+	case "int", "int8", "int16", "int32", "int64", "uint", "uint8", "uint16", "uint32", "uint64", "float32", "float64":
+		return "a number"
+	case "slice", "array":
+		return "an array"
+	case "map", "struct":
+		return "an object"
+	case "bool":
+		return "a boolean"
+	}
+	return "a " + kind
+}
@@ -0,0 +1,160 @@
+// Package warmup primes connections and caches after startup so the first
+// requests after a deploy don't pay for cold pools, plans and lookups. The
+// readiness probe reports not ready until warm-up has finished.
+package warmup
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Warm-up states
+const (
+	StatePending = "pending"
+	StateRunning = "running"
+	StateDone    = "done"
+)
+
+// Step is one warm-up task
+type Step struct {
+	Name string
+	Run  func(ctx context.Context) error
+}
+
+// StepResult is the outcome of a step
+type StepResult struct {
+	Name     string `json:"name"`
+	Duration string `json:"duration"`
+	Error    string `json:"error,omitempty"`
+}
+
+// Status is the warm-up progress reported by the readiness probe
+type Status struct {
+	State string       `json:"state"`
+	Steps []StepResult `json:"steps"`
+}
+
+// Warmer runs warm-up steps once and tracks whether they are done
+type Warmer struct {
+	steps   []Step
+	timeout time.Duration
+	logger  *zap.Logger
+
+	mu      sync.RWMutex
+	state   string
+	results []StepResult
+}
+
+// New creates a warmer. timeout bounds the whole warm-up so a slow dependency
+// can't keep the service out of rotation.
+func New(timeout time.Duration, logger *zap.Logger) *Warmer {
+	return &Warmer{
+		timeout: timeout,
+		logger:  logger,
+		state:   StatePending,
+	}
+}
+
+// Add registers a step; steps run in the order they are added
+func (w *Warmer) Add(name string, run func(ctx context.Context) error) *Warmer {
+	w.steps = append(w.steps, Step{Name: name, Run: run})
+	return w
+}
+
+// Run executes the steps in order. A failed step is logged and recorded but
+// does not stop the remaining steps or keep the service unready; warm-up only
+// makes the first requests faster.
+func (w *Warmer) Run(ctx context.Context) {
+	w.mu.Lock()
+	w.state = StateRunning
+	w.mu.Unlock()
+
+	if w.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, w.timeout)
+		defer cancel()
+	}
+
+	started := time.Now()
+	for _, step := range w.steps {
+		stepStarted := time.Now()
+		err := step.Run(ctx)
+
+		result := StepResult{Name: step.Name, Duration: time.Since(stepStarted).Round(time.Millisecond).String()}
+		if err != nil {
+			result.Error = err.Error()
+			w.logger.Warn("Warm-up step failed", zap.String("step", step.Name), zap.Error(err))
+		} else {
+			w.logger.Info("Warm-up step completed", zap.String("step", step.Name), zap.String("duration", result.Duration))
+		}
+
+		w.mu.Lock()
+		w.results = append(w.results, result)
+		w.mu.Unlock()
+	}
+
+	w.mu.Lock()
+	w.state = StateDone
+	w.mu.Unlock()
+	w.logger.Info("Warm-up finished", zap.Duration("duration", time.Since(started)))
+}
+
+// Ready reports whether warm-up has finished
+func (w *Warmer) Ready() bool {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.state == StateDone
+}
+
+// Status returns the current warm-up progress
+func (w *Warmer) Status() Status {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return Status{
+		State: w.state,
+		Steps: append([]StepResult{}, w.results...),
+	}
+}
+
+// ConnectionPool opens up to n database connections at once and returns them
+// to the idle pool, so the first burst of requests doesn't wait on handshakes
+func ConnectionPool(db *sql.DB, n int) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		conns := make([]*sql.Conn, 0, n)
+		defer func() {
+			for _, conn := range conns {
+				conn.Close()
+			}
+		}()
+
+		for i := 0; i < n; i++ {
+			conn, err := db.Conn(ctx)
+			if err != nil {
+				return fmt.Errorf("open connection %d of %d: %w", i+1, n, err)
+			}
+			conns = append(conns, conn)
+			if err := conn.PingContext(ctx); err != nil {
+				return fmt.Errorf("ping connection %d of %d: %w", i+1, n, err)
+			}
+		}
+		return nil
+	}
+}
+
+// All runs every function and returns the first error, so one step can prime
+// several related lookups
+func All(fns ...func(ctx context.Context) error) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		for _, fn := range fns {
+			if err := fn(ctx); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
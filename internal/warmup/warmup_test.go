@@ -0,0 +1,82 @@
+package warmup
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestWarmer_Run(t *testing.T) {
+	var ran []string
+	warmer := New(time.Second, zap.NewNop()).
+		Add("first", func(ctx context.Context) error {
+			ran = append(ran, "first")
+			return nil
+		}).
+		Add("failing", func(ctx context.Context) error {
+			ran = append(ran, "failing")
+			return errors.New("segment table missing")
+		}).
+		Add("last", func(ctx context.Context) error {
+			ran = append(ran, "last")
+			return nil
+		})
+
+	assert.False(t, warmer.Ready())
+	assert.Equal(t, StatePending, warmer.Status().State)
+
+	warmer.Run(context.Background())
+
+	// A failed step is reported but doesn't hold readiness back
+	assert.True(t, warmer.Ready())
+	assert.Equal(t, []string{"first", "failing", "last"}, ran)
+
+	status := warmer.Status()
+	assert.Equal(t, StateDone, status.State)
+	require.Len(t, status.Steps, 3)
+	assert.Empty(t, status.Steps[0].Error)
+	assert.Equal(t, "segment table missing", status.Steps[1].Error)
+}
+
+func TestWarmer_RunTimeout(t *testing.T) {
+	warmer := New(10*time.Millisecond, zap.NewNop()).
+		Add("slow", func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		})
+
+	warmer.Run(context.Background())
+
+	assert.True(t, warmer.Ready())
+	assert.Equal(t, context.DeadlineExceeded.Error(), warmer.Status().Steps[0].Error)
+}
+
+func TestConnectionPool(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	sqlDB.SetMaxIdleConns(3)
+
+	require.NoError(t, ConnectionPool(sqlDB, 3)(context.Background()))
+	assert.Equal(t, 3, sqlDB.Stats().Idle)
+}
+
+func TestAll(t *testing.T) {
+	calls := 0
+	step := All(
+		func(ctx context.Context) error { calls++; return nil },
+		func(ctx context.Context) error { calls++; return errors.New("boom") },
+		func(ctx context.Context) error { calls++; return nil },
+	)
+
+	assert.EqualError(t, step(context.Background()), "boom")
+	assert.Equal(t, 2, calls)
+}